@@ -0,0 +1,311 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/client/web"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb/bundle"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ops"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// viewCaller calls the views of a node
+type viewCaller interface {
+	CallView(fid string, in []byte) (interface{}, error)
+}
+
+// connection holds the flags to reach the node
+type connection struct {
+	Host        string
+	CACertPath  string
+	TLSCertPath string
+	TLSKeyPath  string
+}
+
+func newWebCaller(c connection) (viewCaller, error) {
+	return web.NewClient(&web.Config{
+		Host:        c.Host,
+		CACertPath:  c.CACertPath,
+		TLSCertPath: c.TLSCertPath,
+		TLSKeyPath:  c.TLSKeyPath,
+	})
+}
+
+// newMainCmd returns the tokenctl command, whose subcommands call the views of the node through the passed caller
+func newMainCmd(newCaller func(connection) (viewCaller, error)) *cobra.Command {
+	var conn connection
+	var tmsID token.TMSID
+	var output string
+
+	mainCmd := &cobra.Command{
+		Use:   "tokenctl",
+		Short: "Run routine operations on the token services of a node.",
+		Long: `Run routine operations on the token services of a node, through its REST API.
+The node must enable the operations views with token.ops.enabled.`,
+	}
+	flags := mainCmd.PersistentFlags()
+	flags.StringVar(&conn.Host, "host", "localhost:9000", "host and port of the REST API of the node")
+	flags.StringVar(&conn.CACertPath, "cacert", "", "CA certificate of the node, enables TLS")
+	flags.StringVar(&conn.TLSCertPath, "tls-cert", "", "TLS client certificate")
+	flags.StringVar(&conn.TLSKeyPath, "tls-key", "", "TLS client key")
+	flags.StringVar(&tmsID.Network, "network", "", "network of the TMS, the default TMS if not set")
+	flags.StringVar(&tmsID.Channel, "channel", "", "channel of the TMS")
+	flags.StringVar(&tmsID.Namespace, "namespace", "", "namespace of the TMS")
+	flags.StringVarP(&output, "output", "o", "", "file to write the result to, the standard output if not set")
+
+	// write writes the passed JSON result, indented
+	write := func(cmd *cobra.Command, raw []byte) error {
+		var out bytes.Buffer
+		if err := json.Indent(&out, raw, "", "  "); err != nil {
+			out.Reset()
+			out.Write(raw)
+		}
+		out.WriteString("\n")
+		if len(output) == 0 {
+			_, err := cmd.OutOrStdout().Write(out.Bytes())
+			return err
+		}
+		return os.WriteFile(output, out.Bytes(), 0o644)
+	}
+
+	// call invokes the passed view on the passed request, and writes the result
+	call := func(cmd *cobra.Command, fid string, request *ops.Request) error {
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+		request.TMSID = tmsID
+		in, err := json.Marshal(request)
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal request")
+		}
+		caller, err := newCaller(conn)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to connect to [%s]", conn.Host)
+		}
+		res, err := caller.CallView(fid, in)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to call [%s]", fid)
+		}
+		raw, ok := res.([]byte)
+		if !ok {
+			return errors.Errorf("unexpected result [%T] from [%s]", res, fid)
+		}
+		return write(cmd, raw)
+	}
+
+	mainCmd.AddCommand(&cobra.Command{
+		Use:   "wallets",
+		Short: "List the owner wallets.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return call(cmd, ops.WalletsView, &ops.Request{})
+		},
+	})
+
+	balances := &ops.Request{}
+	balancesCmd := &cobra.Command{
+		Use:   "balances",
+		Short: "Show the balances of the owner wallets, by token type.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return call(cmd, ops.BalancesView, balances)
+		},
+	}
+	balancesCmd.Flags().StringVar(&balances.Wallet, "wallet", "", "wallet to show, all if not set")
+	balancesCmd.Flags().StringVar(&balances.TokenType, "type", "", "token type to show, all if not set")
+	mainCmd.AddCommand(balancesCmd)
+
+	mainCmd.AddCommand(&cobra.Command{
+		Use:   "pending",
+		Short: "List the records of the pending transactions.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return call(cmd, ops.PendingView, &ops.Request{})
+		},
+	})
+
+	mainCmd.AddCommand(&cobra.Command{
+		Use:   "check",
+		Short: "Run the consistency checks of the local state.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return call(cmd, ops.CheckView, &ops.Request{})
+		},
+	})
+
+	prune := &ops.Request{}
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the unspent tokens that are not valid on the ledger.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return call(cmd, ops.PruneView, prune)
+		},
+	}
+	pruneCmd.Flags().BoolVar(&prune.DryRun, "dry-run", false, "report the invalid tokens without deleting them")
+	mainCmd.AddCommand(pruneCmd)
+
+	history := &ops.Request{}
+	var from, to string
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the history of the transactions.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if history.From, err = parseTime(from); err != nil {
+				return errors.WithMessagef(err, "invalid --from")
+			}
+			if history.To, err = parseTime(to); err != nil {
+				return errors.WithMessagef(err, "invalid --to")
+			}
+			return call(cmd, ops.HistoryView, history)
+		},
+	}
+	exportCmd.Flags().StringVar(&history.Wallet, "wallet", "", "wallet whose transactions are exported, all if not set")
+	exportCmd.Flags().StringVar(&from, "from", "", "start of the history, in RFC 3339")
+	exportCmd.Flags().StringVar(&to, "to", "", "end of the history, in RFC 3339")
+	mainCmd.AddCommand(exportCmd)
+
+	auditBundle := &ops.Request{}
+	var bundleFrom, bundleTo, recipient string
+	auditBundleCmd := &cobra.Command{
+		Use:   "audit-bundle",
+		Short: "Export the audit records, encrypted for an external auditor.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if auditBundle.From, err = parseTime(bundleFrom); err != nil {
+				return errors.WithMessagef(err, "invalid --from")
+			}
+			if auditBundle.To, err = parseTime(bundleTo); err != nil {
+				return errors.WithMessagef(err, "invalid --to")
+			}
+			key, err := os.ReadFile(recipient)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read recipient key")
+			}
+			if _, err := bundle.ParsePublicKey(key); err != nil {
+				return errors.WithMessagef(err, "invalid recipient key [%s]", recipient)
+			}
+			auditBundle.RecipientKey = string(key)
+			return call(cmd, ops.AuditBundleView, auditBundle)
+		},
+	}
+	auditBundleCmd.Flags().StringVar(&recipient, "recipient", "", "PEM file with the P-256 public key, or certificate, of the external auditor")
+	auditBundleCmd.Flags().StringVar(&bundleFrom, "from", "", "start of the audit records, in RFC 3339")
+	auditBundleCmd.Flags().StringVar(&bundleTo, "to", "", "end of the audit records, in RFC 3339")
+	_ = auditBundleCmd.MarkFlagRequired("recipient")
+	mainCmd.AddCommand(auditBundleCmd)
+
+	var keyPath string
+	var auditorPath string
+	var decrypted bool
+	verifyBundleCmd := &cobra.Command{
+		Use:   "verify-bundle <file>",
+		Short: "Check the signature of an audit bundle, decrypt and verify it, without connecting to a node.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			summary, err := verifyBundle(args[0], keyPath, auditorPath, decrypted)
+			if err != nil {
+				return err
+			}
+			raw, err := json.Marshal(summary)
+			if err != nil {
+				return errors.Wrapf(err, "failed to marshal result")
+			}
+			return write(cmd, raw)
+		},
+	}
+	verifyBundleCmd.Flags().StringVar(&keyPath, "key", "", "PEM file with the P-256 private key of the external auditor")
+	verifyBundleCmd.Flags().StringVar(&auditorPath, "auditor", "", "PEM file with the P-256 public key, or certificate, of the auditor that signed the bundle")
+	verifyBundleCmd.Flags().BoolVar(&decrypted, "decrypt", false, "output the decrypted bundle instead of its summary")
+	_ = verifyBundleCmd.MarkFlagRequired("key")
+	_ = verifyBundleCmd.MarkFlagRequired("auditor")
+	mainCmd.AddCommand(verifyBundleCmd)
+
+	return mainCmd
+}
+
+// bundleSummary describes a verified audit bundle
+type bundleSummary struct {
+	TMSID        token.TMSID `json:"tmsID"`
+	From         *time.Time  `json:"from,omitempty"`
+	To           *time.Time  `json:"to,omitempty"`
+	CreatedAt    time.Time   `json:"createdAt"`
+	Transactions int         `json:"transactions"`
+	Movements    int         `json:"movements"`
+	Requests     int         `json:"requests"`
+}
+
+// verifyBundle checks the signature of the sealed bundle in the passed file with the auditor key in the passed file,
+// then decrypts and verifies it with the key in the passed file.
+// It returns the bundle if decrypted is set, its summary otherwise.
+func verifyBundle(path string, keyPath string, auditorPath string, decrypted bool) (any, error) {
+	rawKey, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read key")
+	}
+	key, err := bundle.ParsePrivateKey(rawKey)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid key [%s]", keyPath)
+	}
+	rawAuditor, err := os.ReadFile(auditorPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read auditor key")
+	}
+	auditor, err := bundle.ParsePublicKey(rawAuditor)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid auditor key [%s]", auditorPath)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read bundle")
+	}
+	sealed := &bundle.SealedBundle{}
+	if err := json.Unmarshal(raw, sealed); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal bundle")
+	}
+	b, err := bundle.Open(sealed, key, bundle.NewVerifier(auditor))
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Verify(); err != nil {
+		return nil, errors.WithMessagef(err, "invalid audit bundle")
+	}
+	if decrypted {
+		return b, nil
+	}
+	return &bundleSummary{
+		TMSID:        b.TMSID,
+		From:         b.From,
+		To:           b.To,
+		CreatedAt:    b.CreatedAt,
+		Transactions: len(b.Transactions),
+		Movements:    len(b.Movements),
+		Requests:     len(b.Requests),
+	}, nil
+}
+
+func parseTime(s string) (*time.Time, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("expected RFC 3339 time: %w", err)
+	}
+	return &t, nil
+}
@@ -0,0 +1,19 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	// On failure Cobra prints the usage message and error string, so we only
+	// need to exit with a non-0 status
+	if newMainCmd(newWebCaller).Execute() != nil {
+		os.Exit(1)
+	}
+}
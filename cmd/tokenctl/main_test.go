@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb/bundle"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ops"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCaller struct {
+	fid string
+	in  *ops.Request
+	out []byte
+}
+
+func (f *fakeCaller) CallView(fid string, in []byte) (interface{}, error) {
+	f.fid = fid
+	f.in = &ops.Request{}
+	if err := json.Unmarshal(in, f.in); err != nil {
+		return nil, err
+	}
+	return f.out, nil
+}
+
+func run(t *testing.T, caller *fakeCaller, args ...string) (string, error) {
+	cmd := newMainCmd(func(connection) (viewCaller, error) { return caller, nil })
+	out := &bytes.Buffer{}
+	cmd.SetOut(out)
+	cmd.SetErr(out)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestCommands(t *testing.T) {
+	caller := &fakeCaller{out: []byte(`["alice","bob"]`)}
+	out, err := run(t, caller, "wallets", "--network", "n1", "--namespace", "token")
+	assert.NoError(t, err)
+	assert.Equal(t, ops.WalletsView, caller.fid)
+	assert.Equal(t, "n1", caller.in.TMSID.Network)
+	assert.Equal(t, "token", caller.in.TMSID.Namespace)
+	assert.Equal(t, "[\n  \"alice\",\n  \"bob\"\n]\n", out)
+
+	_, err = run(t, caller, "balances", "--wallet", "alice", "--type", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, ops.BalancesView, caller.fid)
+	assert.Equal(t, "alice", caller.in.Wallet)
+	assert.Equal(t, "USD", caller.in.TokenType)
+
+	_, err = run(t, caller, "prune", "--dry-run")
+	assert.NoError(t, err)
+	assert.Equal(t, ops.PruneView, caller.fid)
+	assert.True(t, caller.in.DryRun)
+
+	output := filepath.Join(t.TempDir(), "history.json")
+	_, err = run(t, caller, "export", "--from", "2024-01-01T00:00:00Z", "-o", output)
+	assert.NoError(t, err)
+	assert.Equal(t, ops.HistoryView, caller.fid)
+	assert.Equal(t, 2024, caller.in.From.Year())
+	assert.Nil(t, caller.in.To)
+	raw, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Contains(t, string(raw), "alice")
+
+	_, err = run(t, caller, "export", "--to", "yesterday")
+	assert.Error(t, err)
+}
+
+func TestAuditBundle(t *testing.T) {
+	dir := t.TempDir()
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	rawPK, err := x509.MarshalPKIXPublicKey(&sk.PublicKey)
+	assert.NoError(t, err)
+	pkPath := filepath.Join(dir, "pk.pem")
+	assert.NoError(t, os.WriteFile(pkPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rawPK}), 0o600))
+	rawSK, err := x509.MarshalECPrivateKey(sk)
+	assert.NoError(t, err)
+	skPath := filepath.Join(dir, "sk.pem")
+	assert.NoError(t, os.WriteFile(skPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawSK}), 0o600))
+
+	auditor, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	rawAuditor, err := x509.MarshalPKIXPublicKey(&auditor.PublicKey)
+	assert.NoError(t, err)
+	auditorPath := filepath.Join(dir, "auditor.pem")
+	assert.NoError(t, os.WriteFile(auditorPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rawAuditor}), 0o600))
+
+	// the node seals the bundle for the recipient key, and signs it with the auditor key
+	request, err := token.NewRequest(nil, "tx1").Bytes()
+	assert.NoError(t, err)
+	sealed, err := bundle.Seal(&bundle.Bundle{
+		TMSID:        token.TMSID{Network: "n1"},
+		Transactions: []*driver.TransactionRecord{{TxID: "tx1"}},
+		Requests:     []*bundle.Request{{TxID: "tx1", Raw: request}},
+	}, &sk.PublicKey, []byte("auditor"), bundle.NewSigner(auditor))
+	assert.NoError(t, err)
+	rawSealed, err := json.Marshal(sealed)
+	assert.NoError(t, err)
+
+	caller := &fakeCaller{out: rawSealed}
+	bundlePath := filepath.Join(dir, "bundle.json")
+	_, err = run(t, caller, "audit-bundle", "--recipient", pkPath, "--from", "2024-01-01T00:00:00Z", "-o", bundlePath)
+	assert.NoError(t, err)
+	assert.Equal(t, ops.AuditBundleView, caller.fid)
+	assert.Equal(t, 2024, caller.in.From.Year())
+	assert.Contains(t, caller.in.RecipientKey, "PUBLIC KEY")
+
+	_, err = run(t, caller, "audit-bundle", "--recipient", skPath)
+	assert.Error(t, err)
+
+	// the external auditor verifies it
+	out, err := run(t, caller, "verify-bundle", "--key", skPath, "--auditor", auditorPath, bundlePath)
+	assert.NoError(t, err)
+	summary := map[string]any{}
+	assert.NoError(t, json.Unmarshal([]byte(out), &summary))
+	assert.Equal(t, float64(1), summary["transactions"])
+	assert.Equal(t, float64(1), summary["requests"])
+
+	// the auditor key is required and must match the signer
+	_, err = run(t, caller, "verify-bundle", "--key", skPath, bundlePath)
+	assert.Error(t, err)
+	_, err = run(t, caller, "verify-bundle", "--key", skPath, "--auditor", pkPath, bundlePath)
+	assert.Error(t, err)
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	rawSK, err = x509.MarshalECPrivateKey(other)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(skPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawSK}), 0o600))
+	_, err = run(t, caller, "verify-bundle", "--key", skPath, "--auditor", auditorPath, bundlePath)
+	assert.Error(t, err)
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/msp"
@@ -143,3 +144,34 @@ func GetCertificatesFromDir(dir string) ([][]byte, error) {
 
 	return content, nil
 }
+
+// ParseRedemptionWindows parses the passed entries formatted as [<TokenType>=]<NotBefore>/<NotAfter>,
+// where the bounds are RFC3339 times and an empty bound means no bound.
+// The entries with no token type apply to the token types with no window of their own.
+func ParseRedemptionWindows(entries []string) (driver.RedemptionWindows, error) {
+	var windows driver.RedemptionWindows
+	for _, entry := range entries {
+		var w driver.RedemptionWindow
+		bounds := entry
+		if i := strings.Index(entry, "="); i >= 0 {
+			w.TokenType, bounds = entry[:i], entry[i+1:]
+		}
+		notBefore, notAfter, found := strings.Cut(bounds, "/")
+		if !found {
+			return nil, errors.Errorf("invalid redemption window [%s], expected [<TokenType>=]<NotBefore>/<NotAfter>", entry)
+		}
+		var err error
+		if len(notBefore) != 0 {
+			if w.NotBefore, err = time.Parse(time.RFC3339, notBefore); err != nil {
+				return nil, errors.Wrapf(err, "invalid start of redemption window [%s]", entry)
+			}
+		}
+		if len(notAfter) != 0 {
+			if w.NotAfter, err = time.Parse(time.RFC3339, notAfter); err != nil {
+				return nil, errors.Wrapf(err, "invalid end of redemption window [%s]", entry)
+			}
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
@@ -17,6 +17,7 @@ import (
 
 	math3 "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -38,6 +39,14 @@ type GeneratorArgs struct {
 	Exponent uint
 	// Aries is a flag to indicate that aries should be used as backend for idemix
 	Aries bool
+	// MaxInputs is the maximum number of inputs per token request, 0 means no limit
+	MaxInputs uint64
+	// MaxOutputs is the maximum number of outputs per token request, 0 means no limit
+	MaxOutputs uint64
+	// RedemptionWindows are the periods of time during which tokens can be redeemed, formatted as [<TokenType>=]<NotBefore>/<NotAfter>
+	RedemptionWindows []string
+	// HashAlgorithm is the hash algorithm of the public parameters and of the token requests, sha256 if empty
+	HashAlgorithm string
 }
 
 var (
@@ -59,6 +68,14 @@ var (
 	Exponent uint
 	// Aries is a flag to indicate that aries should be used as backend for idemix
 	Aries bool
+	// MaxInputs is the maximum number of inputs per token request, 0 means no limit
+	MaxInputs uint64
+	// MaxOutputs is the maximum number of outputs per token request, 0 means no limit
+	MaxOutputs uint64
+	// RedemptionWindows are the periods of time during which tokens can be redeemed, formatted as [<TokenType>=]<NotBefore>/<NotAfter>
+	RedemptionWindows []string
+	// HashAlgorithm is the hash algorithm of the public parameters and of the token requests, sha256 if empty
+	HashAlgorithm string
 )
 
 // Cmd returns the Cobra Command for Version
@@ -73,6 +90,10 @@ func Cmd() *cobra.Command {
 	flags.UintVarP(&Base, "base", "b", 100, "base is used to define the maximum quantity a token can contain as Base^Exponent")
 	flags.UintVarP(&Exponent, "exponent", "e", 2, "exponent is used to define the maximum quantity a token can contain as Base^Exponent")
 	flags.BoolVarP(&Aries, "aries", "r", false, "flag to indicate that aries should be used as backend for idemix")
+	flags.Uint64VarP(&MaxInputs, "max-inputs", "", 0, "maximum number of inputs per token request, 0 means no limit")
+	flags.Uint64VarP(&MaxOutputs, "max-outputs", "", 0, "maximum number of outputs per token request, 0 means no limit")
+	flags.StringSliceVarP(&RedemptionWindows, "redemption-windows", "", nil, "periods of time during which tokens can be redeemed, formatted as [<TokenType>=]<NotBefore>/<NotAfter> with RFC3339 bounds, empty means at any time")
+	flags.StringVarP(&HashAlgorithm, "hash-algorithm", "", "", "hash algorithm of the public parameters and of the token requests, sha256 if empty")
 
 	return cobraCommand
 }
@@ -96,6 +117,10 @@ var cobraCommand = &cobra.Command{
 			Base:              Base,
 			Exponent:          Exponent,
 			Aries:             Aries,
+			MaxInputs:         MaxInputs,
+			MaxOutputs:        MaxOutputs,
+			RedemptionWindows: RedemptionWindows,
+			HashAlgorithm:     HashAlgorithm,
 		})
 		if err != nil {
 			return errors.Wrap(err, "failed to generate public parameters")
@@ -136,6 +161,16 @@ func Gen(args *GeneratorArgs) ([]byte, error) {
 	if err := common.SetupIssuersAndAuditors(pp, args.Auditors, args.Issuers); err != nil {
 		return nil, err
 	}
+	pp.MaxInputs = args.MaxInputs
+	pp.MaxOutputs = args.MaxOutputs
+	pp.Redemptions, err = common.ParseRedemptionWindows(args.RedemptionWindows)
+	if err != nil {
+		return nil, err
+	}
+	pp.Hashing = driver.HashAlgorithm(args.HashAlgorithm)
+	if err := pp.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "failed to validate public parameters")
+	}
 
 	// Store Public Params
 	raw, err := pp.Serialize()
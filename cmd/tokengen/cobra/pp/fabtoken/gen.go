@@ -15,6 +15,7 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/cmd/tokengen/cobra/pp/common"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -30,6 +31,14 @@ var (
 	Issuers []string
 	// Auditors is the list of auditor MSP directories containing the corresponding auditor certificate
 	Auditors []string
+	// MaxInputs is the maximum number of inputs per token request, 0 means no limit
+	MaxInputs uint64
+	// MaxOutputs is the maximum number of outputs per token request, 0 means no limit
+	MaxOutputs uint64
+	// RedemptionWindows are the periods of time during which tokens can be redeemed, formatted as [<TokenType>=]<NotBefore>/<NotAfter>
+	RedemptionWindows []string
+	// HashAlgorithm is the hash algorithm of the public parameters and of the token requests, sha256 if empty
+	HashAlgorithm string
 )
 
 // Cmd returns the Cobra Command for Version
@@ -40,6 +49,10 @@ func Cmd() *cobra.Command {
 	flags.BoolVarP(&GenerateCCPackage, "cc", "", false, "generate chaincode package")
 	flags.StringSliceVarP(&Auditors, "auditors", "a", nil, "list of auditor MSP directories containing the corresponding auditor certificate")
 	flags.StringSliceVarP(&Issuers, "issuers", "s", nil, "list of issuer MSP directories containing the corresponding issuer certificate")
+	flags.Uint64VarP(&MaxInputs, "max-inputs", "", 0, "maximum number of inputs per token request, 0 means no limit")
+	flags.Uint64VarP(&MaxOutputs, "max-outputs", "", 0, "maximum number of outputs per token request, 0 means no limit")
+	flags.StringSliceVarP(&RedemptionWindows, "redemption-windows", "", nil, "periods of time during which tokens can be redeemed, formatted as [<TokenType>=]<NotBefore>/<NotAfter> with RFC3339 bounds, empty means at any time")
+	flags.StringVarP(&HashAlgorithm, "hash-algorithm", "", "", "hash algorithm of the public parameters and of the token requests, sha256 if empty")
 	return cobraCommand
 }
 
@@ -58,6 +71,10 @@ var cobraCommand = &cobra.Command{
 			GenerateCCPackage: GenerateCCPackage,
 			Issuers:           Issuers,
 			Auditors:          Auditors,
+			MaxInputs:         MaxInputs,
+			MaxOutputs:        MaxOutputs,
+			RedemptionWindows: RedemptionWindows,
+			HashAlgorithm:     HashAlgorithm,
 		})
 		if err != nil {
 			return errors.Wrap(err, "failed to generate public parameters")
@@ -82,6 +99,14 @@ type GeneratorArgs struct {
 	Issuers []string
 	// Auditors is the list of auditor MSP directories containing the corresponding auditor certificate
 	Auditors []string
+	// MaxInputs is the maximum number of inputs per token request, 0 means no limit
+	MaxInputs uint64
+	// MaxOutputs is the maximum number of outputs per token request, 0 means no limit
+	MaxOutputs uint64
+	// RedemptionWindows are the periods of time during which tokens can be redeemed, formatted as [<TokenType>=]<NotBefore>/<NotAfter>
+	RedemptionWindows []string
+	// HashAlgorithm is the hash algorithm of the public parameters and of the token requests, sha256 if empty
+	HashAlgorithm string
 }
 
 // Gen generates the public parameters for the FabToken driver
@@ -94,6 +119,16 @@ func Gen(args *GeneratorArgs) ([]byte, error) {
 	if err := common.SetupIssuersAndAuditors(pp, args.Auditors, args.Issuers); err != nil {
 		return nil, err
 	}
+	pp.MaxInputs = args.MaxInputs
+	pp.MaxOutputs = args.MaxOutputs
+	pp.Redemptions, err = common.ParseRedemptionWindows(args.RedemptionWindows)
+	if err != nil {
+		return nil, err
+	}
+	pp.Hashing = driver.HashAlgorithm(args.HashAlgorithm)
+	if err := pp.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "failed to validate public parameters")
+	}
 	// Store Public Params
 	raw, err := pp.Serialize()
 	if err != nil {
@@ -53,6 +53,7 @@ var _ = Describe("EndToEnd", func() {
 			It("Test Identity Revocation", Label("T3"), func() { fungible.TestRevokeIdentity(ts.II, "auditor", selector) })
 			It("Test Remote Wallet (GRPC)", Label("T4"), func() { fungible.TestRemoteOwnerWallet(ts.II, "auditor", selector, false) })
 			It("Test Remote Wallet (WebSocket)", Label("T5"), func() { fungible.TestRemoteOwnerWallet(ts.II, "auditor", selector, true) })
+			It("Atomic Payout", Label("T12"), func() { fungible.TestAtomicPayout(ts.II, "auditor", selector) })
 		})
 
 		Describe("Fungible with Auditor = Issuer", t.Label, func() {
@@ -26,6 +26,8 @@ import (
 	common2 "github.com/hyperledger-labs/fabric-token-sdk/integration/token/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/integration/token/fungible/views"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	network2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
@@ -367,6 +369,9 @@ func TransferCashForTMSID(network *integration.Infrastructure, sender *token3.No
 			Expect(sigma).ToNot(BeNil(), "endorsement ack sigma is nil for identity %s", identity)
 		}
 		Expect(len(txInfo.EndorsementAcks)).To(BeEquivalentTo(len(signers)))
+		description := DescribeTransaction(network, sender, txID)
+		Expect(description.Anchor).To(Equal(txID))
+		Expect(description.Transfers).NotTo(BeEmpty())
 		return txID
 	}
 
@@ -610,6 +615,46 @@ func TransferCashMultiActions(network *integration.Infrastructure, sender *token
 	return strErr[s+4 : e]
 }
 
+// TransferCashAtomicPayout pays all the passed receivers with a single transfer action
+func TransferCashAtomicPayout(network *integration.Infrastructure, sender *token3.NodeReference, wallet string, typ string, amounts []uint64, receivers []*token3.NodeReference, auditor *token3.NodeReference, expectedErrorMsgs ...string) string {
+	Expect(len(amounts) > 1).To(BeTrue())
+	Expect(len(receivers)).To(BeEquivalentTo(len(amounts)))
+	transfer := &views.Transfer{
+		Auditor:      auditor.Id(),
+		Wallet:       wallet,
+		Type:         typ,
+		Amount:       amounts[0],
+		Recipient:    network.Identity(receivers[0].Id()),
+		RecipientEID: receivers[0].Id(),
+		AtomicPayout: true,
+	}
+	for i := 1; i < len(amounts); i++ {
+		transfer.TransferAction = append(transfer.TransferAction, views.TransferAction{
+			Amount:       amounts[i],
+			Recipient:    network.Identity(receivers[i].Id()),
+			RecipientEID: receivers[i].Id(),
+		})
+	}
+
+	txidBoxed, err := network.Client(sender.ReplicaName()).CallView("transfer", common.JSONMarshall(transfer))
+	if len(expectedErrorMsgs) == 0 {
+		Expect(err).NotTo(HaveOccurred())
+		txID := common.JSONUnmarshalString(txidBoxed)
+		for _, receiver := range receivers {
+			common2.CheckFinality(network, receiver, txID, nil, false)
+		}
+		common2.CheckFinality(network, auditor, txID, nil, false)
+		return txID
+	}
+
+	Expect(err).To(HaveOccurred())
+	for _, msg := range expectedErrorMsgs {
+		Expect(err.Error()).To(ContainSubstring(msg), "err [%s] should contain [%s]", err.Error(), msg)
+	}
+	time.Sleep(5 * time.Second)
+	return ""
+}
+
 func MaliciousTransferCash(network *integration.Infrastructure, id *token3.NodeReference, wallet string, typ string, amount uint64, receiver *token3.NodeReference, auditor *token3.NodeReference, tmsId *token2.TMSID, expectedErrorMsgs ...string) string {
 	txidBoxed, err := network.Client(id.ReplicaName()).CallView("MaliciousTransfer", common.JSONMarshall(&views.Transfer{
 		Auditor:      auditor.Id(),
@@ -732,6 +777,16 @@ func GetTransactionInfoForTMSID(network *integration.Infrastructure, id *token3.
 	return info
 }
 
+func DescribeTransaction(network *integration.Infrastructure, id *token3.NodeReference, txnId string) *token2.RequestDescription {
+	boxed, err := network.Client(id.ReplicaName()).CallView("describeTransaction", common.JSONMarshall(&views.DescribeTransaction{
+		TransactionID: txnId,
+	}))
+	Expect(err).NotTo(HaveOccurred())
+	description := &token2.RequestDescription{}
+	common.JSONUnmarshal(boxed.([]byte), description)
+	return description
+}
+
 func TransferCashByIDs(network *integration.Infrastructure, ref *token3.NodeReference, wallet string, ids []*token.ID, amount uint64, receiver *token3.NodeReference, auditor *token3.NodeReference, failToRelease bool, expectedErrorMsgs ...string) string {
 	txIDBoxed, err := network.Client(ref.ReplicaName()).CallView("transfer", common.JSONMarshall(&views.Transfer{
 		Auditor:       auditor.Id(),
@@ -957,6 +1012,13 @@ func CheckAuditorDB(network *integration.Infrastructure, auditor *token3.NodeRef
 
 func PruneInvalidUnspentTokens(network *integration.Infrastructure, ids ...*token3.NodeReference) {
 	for _, id := range ids {
+		reportBoxed, err := network.Client(id.ReplicaName()).CallView("PruneInvalidUnspentTokens", common.JSONMarshall(&views.PruneInvalidUnspentTokens{DryRun: true}))
+		Expect(err).NotTo(HaveOccurred())
+		report := &network2.PruneReport{}
+		common.JSONUnmarshal(reportBoxed.([]byte), report)
+		Expect(report.DryRun).To(BeTrue())
+		Expect(report.Tokens).To(BeEmpty(), "expected 0 tokens to be reported at [%s], got [%v]", id, report.Tokens)
+
 		eIDBoxed, err := network.Client(id.ReplicaName()).CallView("PruneInvalidUnspentTokens", common.JSONMarshall(&views.PruneInvalidUnspentTokens{}))
 		Expect(err).NotTo(HaveOccurred())
 
@@ -966,6 +1028,20 @@ func PruneInvalidUnspentTokens(network *integration.Infrastructure, ids ...*toke
 	}
 }
 
+func ExplainTokenDBQueries(network *integration.Infrastructure, ids ...*token3.NodeReference) {
+	for _, id := range ids {
+		boxed, err := network.Client(id.ReplicaName()).CallView("ExplainTokenDBQueries", common.JSONMarshall(&views.ExplainTokenDBQueries{}))
+		Expect(err).NotTo(HaveOccurred())
+
+		var plans []driver.QueryPlan
+		common.JSONUnmarshal(boxed.([]byte), &plans)
+		Expect(plans).To(HaveLen(3), "expected the plans of the canonical queries at [%s]", id)
+		for _, plan := range plans {
+			Expect(plan.Plan).NotTo(BeEmpty(), "expected a plan for [%s] at [%s]", plan.Name, id)
+		}
+	}
+}
+
 func ListVaultUnspentTokens(network *integration.Infrastructure, id *token3.NodeReference) []*token.ID {
 	res, err := network.Client(id.ReplicaName()).CallView("ListVaultUnspentTokens", common.JSONMarshall(&views.ListVaultUnspentTokens{}))
 	Expect(err).NotTo(HaveOccurred())
@@ -981,6 +1057,46 @@ func ListVaultUnspentTokens(network *integration.Infrastructure, id *token3.Node
 	return IDs
 }
 
+// ListVaultUnspentTokensPaged lists the unspent tokens in the vault of the passed node one page of the passed size at a time
+func ListVaultUnspentTokensPaged(network *integration.Infrastructure, id *token3.NodeReference, pageSize int) []*token.ID {
+	var IDs []*token.ID
+	for offset, more := 0, true; more; {
+		res, err := network.Client(id.ReplicaName()).CallView("ListVaultUnspentTokens", common.JSONMarshall(&views.ListVaultUnspentTokens{
+			Paging: views.Paging{Offset: offset, Limit: pageSize},
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		page := &views.Page[token.UnspentToken]{}
+		common.JSONUnmarshal(res.([]byte), page)
+		Expect(len(page.Items)).To(BeNumerically("<=", pageSize))
+		for _, tok := range page.Items {
+			IDs = append(IDs, tok.Id)
+		}
+		offset, more = page.Next, page.More
+	}
+	return IDs
+}
+
+// StreamListView calls the passed List* view as a stream and collects the chunks it sends.
+// The input must set the chunk size of its paging.
+func StreamListView[T any](network *integration.Infrastructure, id *token3.NodeReference, viewName string, input any) []*T {
+	stream, err := network.Client(id.ReplicaName()).StreamCallView(viewName, common.JSONMarshall(input))
+	Expect(err).NotTo(HaveOccurred())
+
+	var items []*T
+	for {
+		chunk := &views.Chunk[T]{}
+		Expect(stream.Recv(chunk)).NotTo(HaveOccurred())
+		items = append(items, chunk.Items...)
+		if chunk.Last {
+			break
+		}
+	}
+	res, err := stream.Result()
+	Expect(err).NotTo(HaveOccurred())
+	Expect(common.JSONUnmarshalInt(res)).To(Equal(len(items)))
+	return items
+}
+
 func CheckIfExistsInVault(network *integration.Infrastructure, id *token3.NodeReference, tokenIDs []*token.ID) {
 	_, err := network.Client(id.ReplicaName()).CallView("CheckIfExistsInVault", common.JSONMarshall(&views.CheckIfExistsInVault{IDs: tokenIDs}))
 	Expect(err).NotTo(HaveOccurred())
@@ -618,6 +618,7 @@ func TestAll(network *integration.Infrastructure, auditorId string, onRestart On
 	CheckBalanceAndHolding(network, bob, "", "EUR", 20, auditor)
 
 	PruneInvalidUnspentTokens(network, issuer, auditor, alice, bob, charlie, manager)
+	ExplainTokenDBQueries(network, issuer, auditor, alice, bob, charlie, manager)
 
 	TransferCash(network, alice, "", "EUR", 200, bob, auditor)
 	TransferCash(network, alice, "", "EUR", 200, bob, auditor)
@@ -771,6 +772,9 @@ func TestAll(network *integration.Infrastructure, auditorId string, onRestart On
 	for _, ref := range []*token3.NodeReference{alice, bob, charlie, manager} {
 		IDs := ListVaultUnspentTokens(network, ref)
 		CheckIfExistsInVault(network, auditor, IDs)
+		Expect(ListVaultUnspentTokensPaged(network, ref, 2)).To(Equal(IDs))
+		streamed := StreamListView[token2.UnspentToken](network, ref, "ListVaultUnspentTokens", &views.ListVaultUnspentTokens{Paging: views.Paging{ChunkSize: 2}})
+		Expect(streamed).To(HaveLen(len(IDs)))
 	}
 
 	// Check double spending by multiple action in the same transaction
@@ -816,11 +820,40 @@ func TestSelector(network *integration.Infrastructure, auditorId string, sel *to
 
 	IssueCash(network, "", "USD", 100, alice, auditor, true, issuer)
 	IssueCash(network, "", "USD", 50, alice, auditor, true, issuer)
-	TransferCash(network, alice, "", "USD", 160, bob, auditor, "insufficient funds, only [150] tokens of type [USD] are available")
+	TransferCash(network, alice, "", "USD", 160, bob, auditor, "insufficient funds: [160] tokens of type [USD] requested, [150] available")
 	time.Sleep(10 * time.Second)
-	TransferCash(network, alice, "", "USD", 160, bob, auditor, "insufficient funds, only [150] tokens of type [USD] are available")
+	TransferCash(network, alice, "", "USD", 160, bob, auditor, "insufficient funds: [160] tokens of type [USD] requested, [150] available")
 	time.Sleep(2 * time.Minute)
-	TransferCash(network, alice, "", "USD", 160, bob, auditor, "insufficient funds, only [150] tokens of type [USD] are available")
+	TransferCash(network, alice, "", "USD", 160, bob, auditor, "insufficient funds: [160] tokens of type [USD] requested, [150] available")
+}
+
+// TestAtomicPayout pays several recipients with a single transfer action, all or none of them
+func TestAtomicPayout(network *integration.Infrastructure, auditorId string, sel *token3.ReplicaSelector) {
+	auditor := sel.Get(auditorId)
+	issuer := sel.Get("issuer")
+	alice := sel.Get("alice")
+	bob := sel.Get("bob")
+	charlie := sel.Get("charlie")
+	RegisterAuditor(network, auditor)
+
+	// give some time to the nodes to get the public parameters
+	time.Sleep(10 * time.Second)
+
+	SetKVSEntry(network, issuer, "auditor", auditor.Id())
+	CheckPublicParams(network, issuer, auditor, alice, bob, charlie)
+	Eventually(DoesWalletExist).WithArguments(network, alice, "", views.OwnerWallet).WithTimeout(1 * time.Minute).WithPolling(15 * time.Second).Should(Equal(true))
+
+	IssueCash(network, "", "USD", 10, alice, auditor, true, issuer)
+	TransferCashAtomicPayout(network, alice, "", "USD", []uint64{3, 4}, []*token3.NodeReference{bob, charlie}, auditor)
+	CheckBalanceAndHolding(network, alice, "", "USD", 3, auditor)
+	CheckBalanceAndHolding(network, bob, "", "USD", 3, auditor)
+	CheckBalanceAndHolding(network, charlie, "", "USD", 4, auditor)
+
+	// the funds cover the first payout only, no recipient is paid
+	TransferCashAtomicPayout(network, alice, "", "USD", []uint64{2, 2}, []*token3.NodeReference{bob, charlie}, auditor, "insufficient funds: [4] tokens of type [USD] requested, [3] available")
+	CheckBalanceAndHolding(network, alice, "", "USD", 3, auditor)
+	CheckBalanceAndHolding(network, bob, "", "USD", 3, auditor)
+	CheckBalanceAndHolding(network, charlie, "", "USD", 4, auditor)
 }
 
 func TestPublicParamsUpdate(network *integration.Infrastructure, auditorId string, ppBytes []byte, networkName string, issuerAsAuditor bool, sel *token3.ReplicaSelector) {
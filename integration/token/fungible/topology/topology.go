@@ -72,10 +72,12 @@ func Topology(opts common.Opts) []api.Topology {
 	issuer.RegisterViewFactory("GetEnrollmentID", &views.GetEnrollmentIDViewFactory{})
 	issuer.RegisterViewFactory("acceptedTransactionHistory", &views.ListAcceptedTransactionsViewFactory{})
 	issuer.RegisterViewFactory("transactionInfo", &views.TransactionInfoViewFactory{})
+	issuer.RegisterViewFactory("describeTransaction", &views.DescribeTransactionViewFactory{})
 	issuer.RegisterViewFactory("CheckPublicParamsMatch", &views.CheckPublicParamsMatchViewFactory{})
 	issuer.RegisterViewFactory("CheckTTXDB", &views.CheckTTXDBViewFactory{})
 	issuer.RegisterViewFactory("RegisterIssuerIdentity", &views.RegisterIssuerIdentityViewFactory{})
 	issuer.RegisterViewFactory("PruneInvalidUnspentTokens", &views.PruneInvalidUnspentTokensViewFactory{})
+	issuer.RegisterViewFactory("ExplainTokenDBQueries", &views.ExplainTokenDBQueriesViewFactory{})
 	issuer.RegisterViewFactory("WhoDeletedToken", &views.WhoDeletedTokenViewFactory{})
 	issuer.RegisterViewFactory("GetPublicParams", &views.GetPublicParamsViewFactory{})
 	issuer.RegisterViewFactory("GetPublicParams", &views.GetPublicParamsViewFactory{})
@@ -163,6 +165,7 @@ func Topology(opts common.Opts) []api.Topology {
 		auditor.RegisterViewFactory("SetTransactionAuditStatus", &views.SetTransactionAuditStatusViewFactory{})
 		auditor.RegisterViewFactory("CheckTTXDB", &views.CheckTTXDBViewFactory{})
 		auditor.RegisterViewFactory("PruneInvalidUnspentTokens", &views.PruneInvalidUnspentTokensViewFactory{})
+		auditor.RegisterViewFactory("ExplainTokenDBQueries", &views.ExplainTokenDBQueriesViewFactory{})
 		auditor.RegisterViewFactory("WhoDeletedToken", &views.WhoDeletedTokenViewFactory{})
 		auditor.RegisterViewFactory("ListVaultUnspentTokens", &views.ListVaultUnspentTokensViewFactory{})
 		auditor.RegisterViewFactory("CheckIfExistsInVault", &views.CheckIfExistsInVaultViewFactory{})
@@ -194,12 +197,14 @@ func Topology(opts common.Opts) []api.Topology {
 	alice.RegisterViewFactory("GetEnrollmentID", &views.GetEnrollmentIDViewFactory{})
 	alice.RegisterViewFactory("acceptedTransactionHistory", &views.ListAcceptedTransactionsViewFactory{})
 	alice.RegisterViewFactory("transactionInfo", &views.TransactionInfoViewFactory{})
+	alice.RegisterViewFactory("describeTransaction", &views.DescribeTransactionViewFactory{})
 	alice.RegisterViewFactory("prepareTransfer", &views.PrepareTransferViewFactory{})
 	alice.RegisterViewFactory("broadcastPreparedTransfer", &views.BroadcastPreparedTransferViewFactory{})
 	alice.RegisterViewFactory("CheckPublicParamsMatch", &views.CheckPublicParamsMatchViewFactory{})
 	alice.RegisterViewFactory("CheckTTXDB", &views.CheckTTXDBViewFactory{})
 	alice.RegisterViewFactory("SetTransactionOwnerStatus", &views.SetTransactionOwnerStatusViewFactory{})
 	alice.RegisterViewFactory("PruneInvalidUnspentTokens", &views.PruneInvalidUnspentTokensViewFactory{})
+	alice.RegisterViewFactory("ExplainTokenDBQueries", &views.ExplainTokenDBQueriesViewFactory{})
 	alice.RegisterViewFactory("WhoDeletedToken", &views.WhoDeletedTokenViewFactory{})
 	alice.RegisterViewFactory("ListVaultUnspentTokens", &views.ListVaultUnspentTokensViewFactory{})
 	alice.RegisterViewFactory("withdrawal", &views.WithdrawalInitiatorViewFactory{})
@@ -233,6 +238,7 @@ func Topology(opts common.Opts) []api.Topology {
 	bob.RegisterViewFactory("GetEnrollmentID", &views.GetEnrollmentIDViewFactory{})
 	bob.RegisterViewFactory("acceptedTransactionHistory", &views.ListAcceptedTransactionsViewFactory{})
 	bob.RegisterViewFactory("transactionInfo", &views.TransactionInfoViewFactory{})
+	bob.RegisterViewFactory("describeTransaction", &views.DescribeTransactionViewFactory{})
 	bob.RegisterViewFactory("CheckPublicParamsMatch", &views.CheckPublicParamsMatchViewFactory{})
 	bob.RegisterViewFactory("prepareTransfer", &views.PrepareTransferViewFactory{})
 	bob.RegisterViewFactory("TokenSelectorUnlock", &views.TokenSelectorUnlockViewFactory{})
@@ -240,6 +246,7 @@ func Topology(opts common.Opts) []api.Topology {
 	bob.RegisterViewFactory("CheckTTXDB", &views.CheckTTXDBViewFactory{})
 	bob.RegisterViewFactory("SetTransactionOwnerStatus", &views.SetTransactionOwnerStatusViewFactory{})
 	bob.RegisterViewFactory("PruneInvalidUnspentTokens", &views.PruneInvalidUnspentTokensViewFactory{})
+	bob.RegisterViewFactory("ExplainTokenDBQueries", &views.ExplainTokenDBQueriesViewFactory{})
 	bob.RegisterViewFactory("WhoDeletedToken", &views.WhoDeletedTokenViewFactory{})
 	bob.RegisterViewFactory("ListVaultUnspentTokens", &views.ListVaultUnspentTokensViewFactory{})
 	bob.RegisterViewFactory("GetRevocationHandle", &views.GetRevocationHandleViewFactory{})
@@ -269,9 +276,11 @@ func Topology(opts common.Opts) []api.Topology {
 	charlie.RegisterViewFactory("GetEnrollmentID", &views.GetEnrollmentIDViewFactory{})
 	charlie.RegisterViewFactory("acceptedTransactionHistory", &views.ListAcceptedTransactionsViewFactory{})
 	charlie.RegisterViewFactory("transactionInfo", &views.TransactionInfoViewFactory{})
+	charlie.RegisterViewFactory("describeTransaction", &views.DescribeTransactionViewFactory{})
 	charlie.RegisterViewFactory("CheckPublicParamsMatch", &views.CheckPublicParamsMatchViewFactory{})
 	charlie.RegisterViewFactory("CheckTTXDB", &views.CheckTTXDBViewFactory{})
 	charlie.RegisterViewFactory("PruneInvalidUnspentTokens", &views.PruneInvalidUnspentTokensViewFactory{})
+	charlie.RegisterViewFactory("ExplainTokenDBQueries", &views.ExplainTokenDBQueriesViewFactory{})
 	charlie.RegisterViewFactory("WhoDeletedToken", &views.WhoDeletedTokenViewFactory{})
 	charlie.RegisterViewFactory("ListVaultUnspentTokens", &views.ListVaultUnspentTokensViewFactory{})
 	charlie.RegisterViewFactory("RegisterOwnerIdentity", &views.RegisterOwnerIdentityViewFactory{})
@@ -299,9 +308,11 @@ func Topology(opts common.Opts) []api.Topology {
 	manager.RegisterViewFactory("GetEnrollmentID", &views.GetEnrollmentIDViewFactory{})
 	manager.RegisterViewFactory("acceptedTransactionHistory", &views.ListAcceptedTransactionsViewFactory{})
 	manager.RegisterViewFactory("transactionInfo", &views.TransactionInfoViewFactory{})
+	manager.RegisterViewFactory("describeTransaction", &views.DescribeTransactionViewFactory{})
 	manager.RegisterViewFactory("CheckPublicParamsMatch", &views.CheckPublicParamsMatchViewFactory{})
 	manager.RegisterViewFactory("CheckTTXDB", &views.CheckTTXDBViewFactory{})
 	manager.RegisterViewFactory("PruneInvalidUnspentTokens", &views.PruneInvalidUnspentTokensViewFactory{})
+	manager.RegisterViewFactory("ExplainTokenDBQueries", &views.ExplainTokenDBQueriesViewFactory{})
 	manager.RegisterViewFactory("WhoDeletedToken", &views.WhoDeletedTokenViewFactory{})
 	manager.RegisterViewFactory("ListVaultUnspentTokens", &views.ListVaultUnspentTokensViewFactory{})
 	manager.RegisterViewFactory("ListOwnerWalletIDsView", &views.ListOwnerWalletIDsViewFactory{})
@@ -27,6 +27,9 @@ type BalanceQuery struct {
 type Balance struct {
 	Type     string
 	Quantity string
+	// DisplayAmount is the balance as shown to users, like "10.50 EURt".
+	// It is empty if the token type is not registered for the TMS.
+	DisplayAmount string
 }
 
 type BalanceView struct {
@@ -71,7 +74,16 @@ func (b *BalanceView) Call(context view.Context) (interface{}, error) {
 		}
 	}
 
-	return Balance{Quantity: sum.Decimal(), Type: b.Type}, nil
+	res := Balance{Quantity: sum.Decimal(), Type: b.Type}
+	registry, err := tms.TypeRegistry()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting the token type registry")
+	}
+	res.DisplayAmount, err = registry.FormatAmount(b.Type, sum)
+	if err != nil && !errors.Is(err, token.ErrUnknownTokenType) {
+		return nil, errors.WithMessagef(err, "failed formatting balance")
+	}
+	return res, nil
 }
 
 type BalanceViewFactory struct{}
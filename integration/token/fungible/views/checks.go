@@ -16,13 +16,14 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
-	"github.com/pkg/errors"
 )
 
 type TokenTransactionDB interface {
@@ -56,18 +57,21 @@ func (m *CheckTTXDBView) Call(context view.Context) (interface{}, error) {
 	l, err := net.Ledger()
 	assert.NoError(err, "failed to get ledger [%s:%s:%s]", tms.Network(), tms.Channel(), tms.Namespace())
 
-	var tokenDB TokenTransactionDB
+	// the owner ttxdb is always checked, together with the auditdb when the node is also an auditor
+	var tokenDBs []TokenTransactionDB
 	if m.Auditor {
 		auditorWallet := tms.WalletManager().AuditorWallet(m.AuditorWalletID)
 		assert.NotNil(auditorWallet, "cannot find auditor wallet [%s]", m.AuditorWalletID)
-		db, err := ttx.NewAuditor(context, auditorWallet)
+		auditor, err := ttx.NewAuditor(context, auditorWallet)
 		assert.NoError(err, "failed to get auditor instance")
-		tokenDB = db
-	} else {
-		db := ttx.NewOwner(context, tms)
-		tokenDB = db
+		tokenDBs = append(tokenDBs, auditor)
+	}
+	tokenDBs = append(tokenDBs, ttx.NewOwner(context, tms))
+	queriers := make([]db.TransactionQuerier, len(tokenDBs))
+	for i, tokenDB := range tokenDBs {
+		queriers[i] = tokenDB
 	}
-	it, err := tokenDB.Transactions(driver.QueryTransactionsParams{})
+	it, err := db.NewMultiQuery(queriers...).Transactions(driver.QueryTransactionsParams{})
 	assert.NoError(err, "failed to get transaction iterators")
 	defer it.Close()
 	for {
@@ -105,8 +109,14 @@ func (m *CheckTTXDBView) Call(context view.Context) (interface{}, error) {
 		//	errorMessages = append(errorMessages, fmt.Sprintf("no envelope found for transaction record [%s]", transactionRecord.TxID))
 		//}
 
-		tokenRequest, err := tokenDB.GetTokenRequest(transactionRecord.TxID)
-		assert.NoError(err, "failed to retrieve token request for [%s]", transactionRecord.TxID)
+		var tokenRequest []byte
+		for _, tokenDB := range tokenDBs {
+			tokenRequest, err = tokenDB.GetTokenRequest(transactionRecord.TxID)
+			assert.NoError(err, "failed to retrieve token request for [%s]", transactionRecord.TxID)
+			if tokenRequest != nil {
+				break
+			}
+		}
 		assert.NotNil(tokenRequest, "token requests must not be nil")
 
 		// check the ledger
@@ -200,6 +210,8 @@ func (p *CheckTTXDBViewFactory) NewView(in []byte) (view.View, error) {
 
 type PruneInvalidUnspentTokens struct {
 	TMSID token.TMSID
+	// DryRun, if true, makes the view return the network.PruneReport of the invalid tokens, without deleting them
+	DryRun bool
 }
 
 type PruneInvalidUnspentTokensView struct {
@@ -212,6 +224,9 @@ func (p *PruneInvalidUnspentTokensView) Call(context view.Context) (interface{},
 	vault, err := net.TokenVault(p.TMSID.Namespace)
 	assert.NoError(err, "failed to get vault for [%s:%s:%s]", p.TMSID.Network, p.TMSID.Channel, p.TMSID.Namespace)
 
+	if p.DryRun {
+		return vault.PruneInvalidUnspentTokensWithReport(context, true)
+	}
 	return vault.PruneInvalidUnspentTokens(context)
 }
 
@@ -225,8 +240,45 @@ func (p *PruneInvalidUnspentTokensViewFactory) NewView(in []byte) (view.View, er
 	return f, nil
 }
 
+type ExplainTokenDBQueries struct {
+	TMSID token.TMSID
+}
+
+// ExplainTokenDBQueriesView is a maintenance view that reports the execution plans of the canonical
+// token db queries, highlighting the sequential scans that indicate a misconfigured database.
+type ExplainTokenDBQueriesView struct {
+	*ExplainTokenDBQueries
+}
+
+func (e *ExplainTokenDBQueriesView) Call(context view.Context) (interface{}, error) {
+	tms := token.GetManagementService(context, token.WithTMSID(e.TMSID))
+	assert.NotNil(tms, "failed to get tms [%s]", e.TMSID)
+	db, err := tokendb.GetByTMSId(context, tms.ID())
+	assert.NoError(err, "failed to get token db for [%s]", tms.ID())
+
+	plans, err := db.ExplainQueries()
+	assert.NoError(err, "failed to explain token db queries for [%s]", tms.ID())
+	for _, plan := range plans {
+		if len(plan.SequentialScans) != 0 {
+			logger.Warnf("query [%s] scans sequentially %v, consider adding an index", plan.Name, plan.SequentialScans)
+		}
+	}
+	return plans, nil
+}
+
+type ExplainTokenDBQueriesViewFactory struct{}
+
+func (e *ExplainTokenDBQueriesViewFactory) NewView(in []byte) (view.View, error) {
+	f := &ExplainTokenDBQueriesView{ExplainTokenDBQueries: &ExplainTokenDBQueries{}}
+	err := json.Unmarshal(in, f.ExplainTokenDBQueries)
+	assert.NoError(err, "failed unmarshalling input")
+
+	return f, nil
+}
+
 type ListVaultUnspentTokens struct {
 	TMSID token.TMSID
+	Paging
 }
 
 type ListVaultUnspentTokensView struct {
@@ -239,7 +291,12 @@ func (l *ListVaultUnspentTokensView) Call(context view.Context) (interface{}, er
 	vault, err := net.TokenVault(l.TMSID.Namespace)
 	assert.NoError(err, "failed to get vault for [%s:%s:%s]", l.TMSID.Network, l.TMSID.Channel, l.TMSID.Namespace)
 
-	return vault.QueryEngine().ListUnspentTokens()
+	if l.Paging.IsZero() {
+		return vault.QueryEngine().ListUnspentTokens()
+	}
+	it, err := vault.QueryEngine().UnspentTokensIterator()
+	assert.NoError(err, "failed to get unspent tokens iterator")
+	return Paginate[token2.UnspentToken](context, it, l.Paging)
 }
 
 type ListVaultUnspentTokensViewFactory struct{}
@@ -267,18 +324,19 @@ func (c *CheckIfExistsInVaultView) Call(context view.Context) (interface{}, erro
 	vault, err := net.TokenVault(c.TMSID.Namespace)
 	assert.NoError(err, "failed to get vault for [%s:%s:%s]", c.TMSID.Network, c.TMSID.Channel, c.TMSID.Namespace)
 	qe := vault.QueryEngine()
+	exist, err := qe.ExistAll(c.IDs)
+	assert.NoError(err, "failed to check tokens existence")
 	var IDs []*token2.ID
-	count := 0
-	assert.NoError(qe.GetTokenOutputs(c.IDs, func(id *token2.ID, tokenRaw []byte) error {
-		if len(tokenRaw) == 0 {
-			return errors.Errorf("token id [%s] is nil", id)
+	var missing []*token2.ID
+	for i, id := range c.IDs {
+		if !exist[i] {
+			missing = append(missing, id)
+			continue
 		}
 		IDs = append(IDs, id)
-		count++
-		return nil
-	}), "failed to match tokens")
-	assert.Equal(len(c.IDs), count, "got a mismatch; count is [%d] while there are [%d] ids", count, len(c.IDs))
-	return IDs, err
+	}
+	assert.Equal(0, len(missing), "got a mismatch; tokens [%v] do not exist in the vault", missing)
+	return IDs, nil
 }
 
 type CheckIfExistsInVaultViewFactory struct {
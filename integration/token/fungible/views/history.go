@@ -15,6 +15,7 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 )
 
@@ -26,6 +27,7 @@ type ListIssuedTokens struct {
 	TokenType string
 	// The TMS to pick in case of multiple TMSIDs
 	TMSID *token.TMSID
+	Paging
 }
 
 type ListIssuedTokensView struct {
@@ -40,7 +42,11 @@ func (p *ListIssuedTokensView) Call(context view.Context) (interface{}, error) {
 	}
 
 	// Return the list of issued tokens by type
-	return wallet.ListIssuedTokens(ttx.WithType(p.TokenType))
+	issued, err := wallet.ListIssuedTokens(ttx.WithType(p.TokenType))
+	if err != nil || p.Paging.IsZero() {
+		return issued, err
+	}
+	return Paginate[token2.IssuedToken](context, collections.NewSliceIterator(issued.Tokens), p.Paging)
 }
 
 type ListIssuedTokensViewFactory struct{}
@@ -56,6 +62,7 @@ func (i *ListIssuedTokensViewFactory) NewView(in []byte) (view.View, error) {
 type ListAuditedTransactions struct {
 	From *time.Time
 	To   *time.Time
+	Paging
 }
 
 type ListAuditedTransactionsView struct {
@@ -79,7 +86,7 @@ func (p *ListAuditedTransactionsView) Call(context view.Context) (interface{}, e
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed querying transactions")
 	}
-	return ToSlice(it)
+	return Paginate(context, it, p.Paging)
 }
 
 type ListAuditedTransactionsViewFactory struct{}
@@ -101,6 +108,7 @@ type ListAcceptedTransactions struct {
 	ActionTypes     []ttxdb.ActionType
 	Statuses        []ttxdb.TxStatus
 	TMSID           *token.TMSID
+	Paging
 }
 
 type ListAcceptedTransactionsView struct {
@@ -122,7 +130,7 @@ func (p *ListAcceptedTransactionsView) Call(context view.Context) (interface{},
 		return nil, errors.Wrapf(err, "failed querying transactions")
 	}
 
-	return ToSlice(it)
+	return Paginate(context, it, p.Paging)
 }
 
 type ListAcceptedTransactionsViewFactory struct{}
@@ -165,6 +173,50 @@ func (p *TransactionInfoViewFactory) NewView(in []byte) (view.View, error) {
 	return f, nil
 }
 
+// DescribeTransaction contains the input information to describe the token request of a transaction
+type DescribeTransaction struct {
+	TransactionID string
+	TMSID         *token.TMSID
+}
+
+// DescribeTransactionView returns a human-readable breakdown of the token request of a transaction known to this node
+type DescribeTransactionView struct {
+	*DescribeTransaction
+}
+
+func (t *DescribeTransactionView) Call(context view.Context) (interface{}, error) {
+	tms := token.GetManagementService(context, ServiceOpts(t.TMSID)...)
+	if tms == nil {
+		return nil, errors.Errorf("tms not found [%s]", t.TMSID)
+	}
+	raw, err := ttx.NewOwner(context, tms).GetTokenRequest(t.TransactionID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting token request for [%s]", t.TransactionID)
+	}
+	if len(raw) == 0 {
+		return nil, errors.Errorf("token request for [%s] not found", t.TransactionID)
+	}
+	request := token.NewRequest(tms, t.TransactionID)
+	if err := request.FromBytes(raw); err != nil {
+		return nil, errors.Wrapf(err, "failed unmarshalling token request for [%s]", t.TransactionID)
+	}
+	description, err := request.Describe()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed describing token request for [%s]", t.TransactionID)
+	}
+	return description, nil
+}
+
+type DescribeTransactionViewFactory struct{}
+
+func (p *DescribeTransactionViewFactory) NewView(in []byte) (view.View, error) {
+	f := &DescribeTransactionView{DescribeTransaction: &DescribeTransaction{}}
+	if err := json.Unmarshal(in, f.DescribeTransaction); err != nil {
+		return nil, errors.Wrapf(err, "failed unmarshalling input")
+	}
+	return f, nil
+}
+
 func ToSlice[T any](it collections.Iterator[*T]) ([]*T, error) {
 	defer it.Close()
 	var items []*T
@@ -32,6 +32,9 @@ type IssueCash struct {
 	TokenType string
 	// Quantity represent the number of units of a certain token type stored in the token
 	Quantity uint64
+	// DisplayAmount, if set, is the amount to issue as shown to users, like "10.50 EURt".
+	// It replaces TokenType and Quantity, using the token types registered for the TMS.
+	DisplayAmount string
 	// Recipient is the identity of the recipient's FSC node
 	Recipient view.Identity
 	// RecipientEID is the expected enrolment id of the recipient
@@ -43,6 +46,12 @@ type IssueCashView struct {
 }
 
 func (p *IssueCashView) Call(context view.Context) (interface{}, error) {
+	if len(p.DisplayAmount) != 0 {
+		var err error
+		p.TokenType, p.Quantity, err = ParseDisplayAmount(token.GetManagementService(context, ServiceOpts(p.TMSID)...), p.DisplayAmount)
+		assert.NoError(err, "failed parsing amount [%s]", p.DisplayAmount)
+	}
+
 	// As a first step operation, the issuer contacts the recipient's FSC node
 	// to ask for the identity to use to assign ownership of the freshly created token.
 	// Notice that, this step would not be required if the issuer knew already which
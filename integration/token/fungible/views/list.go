@@ -14,6 +14,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/assert"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 // ListUnspentTokens contains the input to query the list of unspent tokens
@@ -24,6 +25,7 @@ type ListUnspentTokens struct {
 	TokenType string
 	// The TMS to pick in case of multiple TMSIDs
 	TMSID *token.TMSID
+	Paging
 }
 
 type ListUnspentTokensView struct {
@@ -36,7 +38,12 @@ func (p *ListUnspentTokensView) Call(context view.Context) (interface{}, error)
 	assert.NotNil(wallet, "wallet [%s] not found", p.Wallet)
 
 	// Return the list of unspent tokens by type
-	return wallet.ListUnspentTokens(ttx.WithType(p.TokenType), token.WithContext(context.Context()))
+	if p.Paging.IsZero() {
+		return wallet.ListUnspentTokens(ttx.WithType(p.TokenType), token.WithContext(context.Context()))
+	}
+	it, err := wallet.ListUnspentTokensIterator(ttx.WithType(p.TokenType), token.WithContext(context.Context()))
+	assert.NoError(err, "failed to get unspent tokens iterator")
+	return Paginate[token2.UnspentToken](context, it, p.Paging)
 }
 
 type ListUnspentTokensViewFactory struct{}
@@ -0,0 +1,114 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package views
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/common/utils/collections"
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+)
+
+// Paging selects the results returned by the List* views.
+// With the zero value, the views return all the results in one response, as a list.
+type Paging struct {
+	// Offset is the number of results to skip
+	Offset int
+	// Limit, if positive, makes the view return a Page with at most this number of results
+	Limit int
+	// ChunkSize, if positive, makes the view send the results over the stream of the call, in Chunks of at most this size.
+	// The view must then be called with StreamCallView, and it returns the number of results sent.
+	ChunkSize int
+}
+
+// IsZero returns true if no paging is requested
+func (p Paging) IsZero() bool {
+	return p == Paging{}
+}
+
+// Page is the response of a List* view called with a limit
+type Page[T any] struct {
+	Items []*T
+	// Next is the offset of the next page
+	Next int
+	// More is true if there are results after this page
+	More bool
+}
+
+// Chunk is a part of the results sent over the stream of a List* view
+type Chunk[T any] struct {
+	Items []*T
+	// Last is true for the last chunk, it may have no items
+	Last bool
+}
+
+// Paginate returns the results of the passed iterator as selected by the passed paging
+func Paginate[T any](context view.Context, it collections.Iterator[*T], p Paging) (interface{}, error) {
+	defer it.Close()
+	for skipped := 0; skipped < p.Offset; skipped++ {
+		if item, err := it.Next(); err != nil {
+			return nil, errors.Wrapf(err, "failed skipping results")
+		} else if item == nil {
+			break
+		}
+	}
+
+	switch {
+	case p.ChunkSize > 0:
+		stream, err := view2.GetStreamIfExists(context)
+		if err != nil {
+			return nil, errors.Wrapf(err, "chunked results require a stream, call the view with StreamCallView")
+		}
+		return streamChunks(stream, it, p)
+	case p.Limit > 0:
+		page := &Page[T]{Next: p.Offset}
+		for {
+			item, err := it.Next()
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed iterating over results")
+			}
+			if item == nil {
+				return page, nil
+			}
+			if len(page.Items) == p.Limit {
+				page.More = true
+				return page, nil
+			}
+			page.Items = append(page.Items, item)
+			page.Next++
+		}
+	default:
+		return ToSlice(it)
+	}
+}
+
+func streamChunks[T any](stream view2.Stream, it collections.Iterator[*T], p Paging) (int, error) {
+	sent := 0
+	chunk := &Chunk[T]{}
+	for p.Limit <= 0 || sent+len(chunk.Items) < p.Limit {
+		item, err := it.Next()
+		if err != nil {
+			return sent, errors.Wrapf(err, "failed iterating over results")
+		}
+		if item == nil {
+			break
+		}
+		chunk.Items = append(chunk.Items, item)
+		if len(chunk.Items) == p.ChunkSize {
+			if err := stream.Send(chunk); err != nil {
+				return sent, errors.Wrapf(err, "failed sending chunk")
+			}
+			sent += len(chunk.Items)
+			chunk = &Chunk[T]{}
+		}
+	}
+	chunk.Last = true
+	if err := stream.Send(chunk); err != nil {
+		return sent, errors.Wrapf(err, "failed sending last chunk")
+	}
+	return sent + len(chunk.Items), nil
+}
@@ -43,6 +43,9 @@ type Transfer struct {
 	Type string
 	// Amount to transfer
 	Amount uint64
+	// DisplayAmount, if set, is the amount to transfer as shown to users, like "10.50 EURt".
+	// It replaces Type and Amount, using the token types registered for the TMS.
+	DisplayAmount string
 	// Recipient is the identity of the recipient's FSC node
 	Recipient view.Identity
 	// RecipientEID is the expected enrolment id of the recipient
@@ -53,6 +56,9 @@ type Transfer struct {
 	FailToRelease bool
 	// For additional transfer actions
 	TransferAction []TransferAction
+	// AtomicPayout if true, the main transfer and the additional transfer actions are assembled
+	// into a single transfer action, with one token selection pass for the total amount.
+	AtomicPayout bool
 	// SenderChangeRecipientData contains the recipient data that needs to be used by sender to receive the change of the transfer operation, if needed.
 	// If this field is set to nil, then the token sdk generates this information as needed.
 	SenderChangeRecipientData *token2.RecipientData
@@ -75,6 +81,10 @@ type TransferView struct {
 func (t *TransferView) Call(context view.Context) (txID interface{}, err error) {
 	span := context.StartSpan("transfer_view")
 	defer span.End()
+	if len(t.DisplayAmount) != 0 {
+		t.Type, t.Amount, err = ParseDisplayAmount(token2.GetManagementService(context, ServiceOpts(t.TMSID)...), t.DisplayAmount)
+		assert.NoError(err, "failed parsing amount [%s]", t.DisplayAmount)
+	}
 	// As a first step operation, the sender contacts the recipient's FSC node
 	// to ask for the identity to use to assign ownership of the freshly created token.
 	// Notice that, this step would not be required if the sender knew already which
@@ -136,28 +146,45 @@ func (t *TransferView) Call(context view.Context) (txID interface{}, err error)
 	// selector.Select(wallet, amount, tokenType)
 	// It is also possible to pass a custom token selector to the Transfer function by using the relative opt:
 	// token2.WithTokenSelector(selector).
-	span.AddEvent("append_transfer")
-	err = tx.Transfer(
-		senderWallet,
-		t.Type,
-		[]uint64{t.Amount},
-		[]view.Identity{recipient},
-		token2.WithTokenIDs(t.TokenIDs...),
-		token2.WithRestRecipientIdentity(t.SenderChangeRecipientData),
-	)
-	assert.NoError(err, "failed adding transfer action [%d:%s]", t.Amount, t.Recipient)
-
-	// add additional transfers
-	span.AddEvent("add_additional_transfers")
-	for i, action := range t.TransferAction {
+	if t.AtomicPayout {
+		// All recipients are paid by the same transfer action.
+		span.AddEvent("append_payouts")
+		payouts := []ttx.Payout{{Recipient: recipient, Amount: t.Amount}}
+		for i, action := range t.TransferAction {
+			payouts = append(payouts, ttx.Payout{Recipient: additionalRecipients[i], Amount: action.Amount})
+		}
+		err = tx.Payouts(
+			senderWallet,
+			t.Type,
+			payouts,
+			token2.WithTokenIDs(t.TokenIDs...),
+			token2.WithRestRecipientIdentity(t.SenderChangeRecipientData),
+		)
+		assert.NoError(err, "failed adding payouts [%d]", len(payouts))
+	} else {
+		span.AddEvent("append_transfer")
 		err = tx.Transfer(
 			senderWallet,
 			t.Type,
-			[]uint64{action.Amount},
-			[]view.Identity{additionalRecipients[i]},
+			[]uint64{t.Amount},
+			[]view.Identity{recipient},
 			token2.WithTokenIDs(t.TokenIDs...),
+			token2.WithRestRecipientIdentity(t.SenderChangeRecipientData),
 		)
-		assert.NoError(err, "failed adding transfer action [%d:%s]", action.Amount, action.Recipient)
+		assert.NoError(err, "failed adding transfer action [%d:%s]", t.Amount, t.Recipient)
+
+		// add additional transfers
+		span.AddEvent("add_additional_transfers")
+		for i, action := range t.TransferAction {
+			err = tx.Transfer(
+				senderWallet,
+				t.Type,
+				[]uint64{action.Amount},
+				[]view.Identity{additionalRecipients[i]},
+				token2.WithTokenIDs(t.TokenIDs...),
+			)
+			assert.NoError(err, "failed adding transfer action [%d:%s]", action.Amount, action.Recipient)
+		}
 	}
 
 	if t.FailToRelease {
@@ -18,6 +18,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	"github.com/pkg/errors"
 )
 
 // AssertTokens checks that the tokens are or are not in the tokendb
@@ -42,6 +43,23 @@ func AssertTokens(sp token.ServiceProvider, tx *ttx.Transaction, outputs *token.
 	}
 }
 
+// ParseDisplayAmount converts a display amount, like "10.50 EURt", to its token type and quantity,
+// using the token types registered for the passed TMS
+func ParseDisplayAmount(tms *token.ManagementService, amount string) (string, uint64, error) {
+	registry, err := tms.TypeRegistry()
+	if err != nil {
+		return "", 0, err
+	}
+	tokenType, q, err := registry.ParseAmount(amount)
+	if err != nil {
+		return "", 0, err
+	}
+	if !q.ToBigInt().IsUint64() {
+		return "", 0, errors.Errorf("amount [%s] out of range", amount)
+	}
+	return tokenType, q.ToBigInt().Uint64(), nil
+}
+
 type KVSEntry struct {
 	Key   string
 	Value string
@@ -0,0 +1,140 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"math/big"
+	"strings"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// TokenTypesConfigKey is the TMS configuration key listing the token types known to the TMS and their display precision
+const TokenTypesConfigKey = "tokenTypes"
+
+// ErrUnknownTokenType is returned when converting amounts of a token type that is not registered
+var ErrUnknownTokenType = errors.New("unknown token type")
+
+// TokenTypeInfo describes how the quantities of a token type are displayed
+type TokenTypeInfo struct {
+	// Type is the token type
+	Type string `yaml:"type"`
+	// Decimals is the number of decimal digits of the display amounts.
+	// A quantity of 1050 of a type with 2 decimals is displayed as 10.50.
+	Decimals uint `yaml:"decimals"`
+}
+
+// TypeRegistry converts between display amounts, like "10.50 EURt", and quantities
+type TypeRegistry struct {
+	precision uint64
+	types     map[string]TokenTypeInfo
+}
+
+// NewTypeRegistry returns a registry for the passed token types whose quantities have the passed precision, in bits
+func NewTypeRegistry(precision uint64, types ...TokenTypeInfo) (*TypeRegistry, error) {
+	r := &TypeRegistry{precision: precision, types: make(map[string]TokenTypeInfo, len(types))}
+	for _, info := range types {
+		if len(info.Type) == 0 {
+			return nil, errors.Errorf("token type not specified")
+		}
+		if _, ok := r.types[info.Type]; ok {
+			return nil, errors.Errorf("token type [%s] registered twice", info.Type)
+		}
+		r.types[info.Type] = info
+	}
+	return r, nil
+}
+
+// TypeRegistry returns the registry of the token types configured for this TMS.
+// The precision of the quantities is taken from the public parameters.
+func (t *ManagementService) TypeRegistry() (*TypeRegistry, error) {
+	pp := t.PublicParametersManager().PublicParameters()
+	if pp == nil {
+		return nil, errors.Errorf("public parameters not set yet for [%s]", t.ID())
+	}
+	var types []TokenTypeInfo
+	if err := t.Configuration().UnmarshalKey(TokenTypesConfigKey, &types); err != nil {
+		return nil, errors.WithMessagef(err, "failed to load [%s]", TokenTypesConfigKey)
+	}
+	return NewTypeRegistry(pp.Precision(), types...)
+}
+
+// Lookup returns the information about the passed token type
+func (r *TypeRegistry) Lookup(tokenType string) (TokenTypeInfo, error) {
+	info, ok := r.types[tokenType]
+	if !ok {
+		return TokenTypeInfo{}, errors.Wrapf(ErrUnknownTokenType, "type [%s]", tokenType)
+	}
+	return info, nil
+}
+
+// ParseAmount converts a display amount, like "10.50 EURt", to its token type and quantity.
+// Amounts with more decimal digits than those of the type are rejected rather than rounded.
+func (r *TypeRegistry) ParseAmount(amount string) (string, token2.Quantity, error) {
+	fields := strings.Fields(amount)
+	if len(fields) != 2 {
+		return "", nil, errors.Errorf("invalid amount [%s], expected value and token type", amount)
+	}
+	tokenType := fields[1]
+	q, err := r.ParseValue(tokenType, fields[0])
+	if err != nil {
+		return "", nil, err
+	}
+	return tokenType, q, nil
+}
+
+// ParseValue converts a display value of the passed token type, like "10.50", to a quantity
+func (r *TypeRegistry) ParseValue(tokenType, value string) (token2.Quantity, error) {
+	info, err := r.Lookup(tokenType)
+	if err != nil {
+		return nil, err
+	}
+	integer, fraction, _ := strings.Cut(value, ".")
+	if len(integer) == 0 || strings.ContainsAny(value, "+-") {
+		return nil, errors.Errorf("invalid value [%s]", value)
+	}
+	if uint(len(fraction)) > info.Decimals {
+		return nil, errors.Errorf("invalid value [%s], type [%s] allows at most [%d] decimals", value, tokenType, info.Decimals)
+	}
+	digits := integer + fraction + strings.Repeat("0", int(info.Decimals)-len(fraction))
+	v, ok := big.NewInt(0).SetString(digits, 10)
+	if !ok {
+		return nil, errors.Errorf("invalid value [%s]", value)
+	}
+	q, err := token2.ToQuantity(v.String(), r.precision)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid value [%s]", value)
+	}
+	return q, nil
+}
+
+// FormatAmount converts a quantity of the passed token type to its display amount, like "10.50 EURt"
+func (r *TypeRegistry) FormatAmount(tokenType string, q token2.Quantity) (string, error) {
+	value, err := r.FormatValue(tokenType, q)
+	if err != nil {
+		return "", err
+	}
+	return value + " " + tokenType, nil
+}
+
+// FormatValue converts a quantity of the passed token type to its display value, like "10.50"
+func (r *TypeRegistry) FormatValue(tokenType string, q token2.Quantity) (string, error) {
+	info, err := r.Lookup(tokenType)
+	if err != nil {
+		return "", err
+	}
+	digits := q.ToBigInt().String()
+	if info.Decimals == 0 {
+		return digits, nil
+	}
+	if pad := int(info.Decimals) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	split := len(digits) - int(info.Decimals)
+	return digits[:split] + "." + digits[split:], nil
+}
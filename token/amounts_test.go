@@ -0,0 +1,62 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"testing"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeRegistry(t *testing.T) {
+	r, err := NewTypeRegistry(64, TokenTypeInfo{Type: "EURt", Decimals: 2}, TokenTypeInfo{Type: "PTS"})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		amount   string
+		quantity uint64
+		display  string
+		err      bool
+	}{
+		{amount: "10.50 EURt", quantity: 1050, display: "10.50 EURt"},
+		{amount: "10.5 EURt", quantity: 1050, display: "10.50 EURt"},
+		{amount: "10 EURt", quantity: 1000, display: "10.00 EURt"},
+		{amount: "0.07 EURt", quantity: 7, display: "0.07 EURt"},
+		{amount: "42 PTS", quantity: 42, display: "42 PTS"},
+		{amount: "10.505 EURt", err: true},
+		{amount: "1.5 PTS", err: true},
+		{amount: "-1 EURt", err: true},
+		{amount: ".5 EURt", err: true},
+		{amount: "1e3 EURt", err: true},
+		{amount: "10.50", err: true},
+		{amount: "18446744073709551616 PTS", err: true},
+	}
+	for _, c := range cases {
+		t.Run(c.amount, func(t *testing.T) {
+			tokenType, q, err := r.ParseAmount(c.amount)
+			if c.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 0, q.Cmp(token2.NewQuantityFromUInt64(c.quantity)))
+			display, err := r.FormatAmount(tokenType, token2.NewQuantityFromUInt64(c.quantity))
+			assert.NoError(t, err)
+			assert.Equal(t, c.display, display)
+		})
+	}
+
+	_, _, err = r.ParseAmount("1 USDt")
+	assert.True(t, errors.Is(err, ErrUnknownTokenType))
+	_, err = r.FormatAmount("USDt", token2.NewQuantityFromUInt64(1))
+	assert.True(t, errors.Is(err, ErrUnknownTokenType))
+
+	_, err = NewTypeRegistry(64, TokenTypeInfo{Type: "EURt"}, TokenTypeInfo{Type: "EURt"})
+	assert.Error(t, err)
+}
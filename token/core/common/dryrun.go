@@ -0,0 +1,53 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+)
+
+// DryRunTokenRequestFromRaw verifies the passed token request as VerifyTokenRequestFromRaw does,
+// but it tolerates the missing signatures and returns the identities expected to produce them.
+// The checks that need the content of a missing signature, such as the pre-image of an htlc claim, fail.
+func (v *Validator[P, T, TA, IA, DS]) DryRunTokenRequestFromRaw(ctx context.Context, getState driver.GetStateFnc, anchor string, raw []byte) ([]interface{}, []driver.Identity, error) {
+	if _, ok := driver.TxTimestampFrom(ctx); !ok {
+		// a dry run tells if the request would be valid now
+		ctx = driver.WithTxTimestamp(ctx, time.Now())
+	}
+	tr, backend, attributes, err := v.prepare(ctx, getState, anchor, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(v.PublicParams.Auditors()) != 0 && len(tr.AuditorSignatures) == 0 {
+		// keep the slot of the auditor's signature, the signatures of the senders follow it
+		backend.Sigs = append([][]byte{nil}, backend.Sigs...)
+	}
+	signatureProvider := &dryRunSignatureProvider{Backend: backend}
+	actions, _, err := v.VerifyTokenRequest(backend, signatureProvider, anchor, tr, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return actions, signatureProvider.missing, nil
+}
+
+// dryRunSignatureProvider verifies the signatures present and records the identities whose signatures are missing
+type dryRunSignatureProvider struct {
+	*Backend
+	missing []driver.Identity
+}
+
+func (p *dryRunSignatureProvider) HasBeenSignedBy(id driver.Identity, verifier driver.Verifier) ([]byte, error) {
+	if p.Cursor >= len(p.Sigs) || len(p.Sigs[p.Cursor]) == 0 {
+		p.Cursor++
+		p.missing = append(p.missing, id)
+		return nil, nil
+	}
+	return p.Backend.HasBeenSignedBy(id, verifier)
+}
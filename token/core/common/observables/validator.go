@@ -12,6 +12,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracing"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -50,3 +51,16 @@ func (o *ObservableValidator) VerifyTokenRequestFromRaw(ctx context.Context, get
 	span.SetAttributes(attribute.Bool(SuccessfulLabel, err == nil))
 	return action, meta, err
 }
+
+func (o *ObservableValidator) DryRunTokenRequestFromRaw(ctx context.Context, getState driver.GetStateFnc, anchor string, raw []byte) ([]interface{}, []driver.Identity, error) {
+	dr, ok := o.Validator.(driver.DryRunValidator)
+	if !ok {
+		return nil, nil, errors.Errorf("validator [%T] does not support dry runs", o.Validator)
+	}
+	newContext, span := o.Metrics.validatorTracer.Start(ctx, "dry_run")
+	defer span.End()
+
+	actions, missing, err := dr.DryRunTokenRequestFromRaw(newContext, getState, anchor, raw)
+	span.SetAttributes(attribute.Bool(SuccessfulLabel, err == nil))
+	return actions, missing, err
+}
@@ -41,7 +41,10 @@ func NewPublicParamsManager[T driver.PublicParameters](
 		return nil, errors.WithMessage(err, "invalid public parameters")
 	}
 	ppm.publicParameters = pp
-	ppm.ppHash = Hashable(ppRaw).Raw()
+	ppm.ppHash, err = driver.HashAlgorithmOf(pp).Hash(ppRaw)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to hash public parameters")
+	}
 
 	return ppm, nil
 }
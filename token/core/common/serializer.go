@@ -19,6 +19,7 @@ func (s Serializer) MarshalTokenRequestToSign(request *driver.TokenRequest, meta
 	newReq := &driver.TokenRequest{
 		Issues:    request.Issues,
 		Transfers: request.Transfers,
+		Reference: request.Reference,
 	}
 	return newReq.Bytes()
 }
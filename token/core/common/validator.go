@@ -8,9 +8,11 @@ package common
 
 import (
 	"context"
+	"time"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/logging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 )
 
@@ -34,6 +36,20 @@ type Context[P driver.PublicParameters, T any, TA driver.TransferAction, IA driv
 	Attributes        driver.ValidationAttributes
 }
 
+// TxTimestamp returns the timestamp of the transaction being validated.
+// It returns an error if the caller of the validator did not provide it, see driver.WithTxTimestamp.
+func (c *Context[P, T, TA, IA, DS]) TxTimestamp() (time.Time, error) {
+	raw, ok := c.Attributes[driver.TxTimestamp]
+	if !ok {
+		return time.Time{}, errors.New("transaction timestamp not available")
+	}
+	timestamp := time.Time{}
+	if err := timestamp.UnmarshalBinary(raw); err != nil {
+		return time.Time{}, errors.Wrap(err, "invalid transaction timestamp")
+	}
+	return timestamp, nil
+}
+
 func (c *Context[P, T, TA, IA, DS]) CountMetadataKey(key string) {
 	c.MetadataCounter[key] = c.MetadataCounter[key] + 1
 }
@@ -77,22 +93,33 @@ func NewValidator[P driver.PublicParameters, T any, TA driver.TransferAction, IA
 }
 
 func (v *Validator[P, T, TA, IA, DS]) VerifyTokenRequestFromRaw(ctx context.Context, getState driver.GetStateFnc, anchor string, raw []byte) ([]interface{}, driver.ValidationAttributes, error) {
+	tr, backend, attributes, err := v.prepare(ctx, getState, anchor, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v.VerifyTokenRequest(backend, backend, anchor, tr, attributes)
+}
+
+// prepare unmarshals the passed token request and returns a backend with the message expected to be signed and the signatures.
+// The timestamp of the transaction carried by the passed context, if any, is added to the returned attributes.
+func (v *Validator[P, T, TA, IA, DS]) prepare(ctx context.Context, getState driver.GetStateFnc, anchor string, raw []byte) (*driver.TokenRequest, *Backend, driver.ValidationAttributes, error) {
 	if len(raw) == 0 {
-		return nil, nil, errors.New("empty token request")
+		return nil, nil, nil, errors.New("empty token request")
 	}
 	tr := &driver.TokenRequest{}
-	err := tr.FromBytes(raw)
+	err := tr.FromCanonicalBytes(raw)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to unmarshal token request")
+		return nil, nil, nil, errors.Wrap(err, "failed to unmarshal token request")
 	}
 
 	// Prepare message expected to be signed
 	req := &driver.TokenRequest{}
 	req.Transfers = tr.Transfers
 	req.Issues = tr.Issues
+	req.Reference = tr.Reference
 	raqRaw, err := req.Bytes()
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to marshal signed token request")
+		return nil, nil, nil, errors.Wrap(err, "failed to marshal signed token request")
 	}
 
 	v.Logger.Debugf("cc tx-id [%s][%s]", Hashable(raqRaw), anchor)
@@ -108,28 +135,54 @@ func (v *Validator[P, T, TA, IA, DS]) VerifyTokenRequestFromRaw(ctx context.Cont
 	attributes := make(driver.ValidationAttributes)
 	attributes[TokenRequestToSign], err = v.Serializer.MarshalTokenRequestToSign(req, nil)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to marshal signed token request")
+		return nil, nil, nil, errors.Wrap(err, "failed to marshal signed token request")
+	}
+	if timestamp, ok := driver.TxTimestampFrom(ctx); ok {
+		attributes[driver.TxTimestamp], err = timestamp.MarshalBinary()
+		if err != nil {
+			return nil, nil, nil, errors.Wrap(err, "failed to marshal transaction timestamp")
+		}
 	}
 
-	backend := NewBackend(getState, signed, signatures)
-	return v.VerifyTokenRequest(backend, backend, anchor, tr, attributes)
+	return tr, NewBackend(getState, signed, signatures), attributes, nil
 }
 
 func (v *Validator[P, T, TA, IA, DS]) VerifyTokenRequest(ledger driver.Ledger, signatureProvider driver.SignatureProvider, anchor string, tr *driver.TokenRequest, attributes driver.ValidationAttributes) ([]interface{}, driver.ValidationAttributes, error) {
+	start := time.Now()
+	timedLedger := &timedLedger{Ledger: ledger}
+	timedSignatureProvider := &timedSignatureProvider{SignatureProvider: signatureProvider}
+	actions, err := v.verifyTokenRequest(timedLedger, timedSignatureProvider, anchor, tr, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+	profile := &driver.ValidationProfile{
+		SignatureChecks: timedSignatureProvider.elapsed,
+		LedgerLookups:   timedLedger.elapsed,
+		Total:           time.Since(start),
+	}
+	profile.ProofVerification = profile.Total - profile.SignatureChecks - profile.LedgerLookups
+	profile.SetTo(attributes)
+	return actions, attributes, nil
+}
+
+func (v *Validator[P, T, TA, IA, DS]) verifyTokenRequest(ledger driver.Ledger, signatureProvider driver.SignatureProvider, anchor string, tr *driver.TokenRequest, attributes driver.ValidationAttributes) ([]interface{}, error) {
 	if err := v.verifyAuditorSignature(signatureProvider, attributes); err != nil {
-		return nil, nil, errors.Wrapf(err, "failed to verifier auditor's signature [%s]", anchor)
+		return nil, errors.Wrapf(err, "failed to verifier auditor's signature [%s]", anchor)
 	}
 	ia, ta, err := v.ActionDeserializer.DeserializeActions(tr)
 	if err != nil {
-		return nil, nil, errors.Wrapf(err, "failed to unmarshal actions [%s]", anchor)
+		return nil, errors.Wrapf(err, "failed to unmarshal actions [%s]", anchor)
+	}
+	if err := v.verifyLimits(ia, ta); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify request limits [%s]", anchor)
 	}
 	err = v.verifyIssues(ledger, ia, signatureProvider, attributes)
 	if err != nil {
-		return nil, nil, errors.Wrapf(err, "failed to verify issuers' signatures [%s]", anchor)
+		return nil, errors.Wrapf(err, "failed to verify issuers' signatures [%s]", anchor)
 	}
 	err = v.verifyTransfers(ledger, ta, signatureProvider, attributes)
 	if err != nil {
-		return nil, nil, errors.Wrapf(err, "failed to verify senders' signatures [%s]", anchor)
+		return nil, errors.Wrapf(err, "failed to verify senders' signatures [%s]", anchor)
 	}
 
 	var actions []interface{}
@@ -139,7 +192,7 @@ func (v *Validator[P, T, TA, IA, DS]) VerifyTokenRequest(ledger driver.Ledger, s
 	for _, action := range ta {
 		actions = append(actions, action)
 	}
-	return actions, attributes, nil
+	return actions, nil
 }
 
 func (v *Validator[P, T, TA, IA, DS]) UnmarshalActions(raw []byte) ([]interface{}, error) {
@@ -176,6 +229,25 @@ func (v *Validator[P, T, TA, IA, DS]) verifyAuditorSignature(signatureProvider d
 	return nil
 }
 
+// verifyLimits checks that the request does not spend or create more tokens than the public parameters allow
+func (v *Validator[P, T, TA, IA, DS]) verifyLimits(issues []IA, transfers []TA) error {
+	numInputs, numOutputs := 0, 0
+	for _, issue := range issues {
+		numOutputs += issue.NumOutputs()
+	}
+	for _, transfer := range transfers {
+		numInputs += len(transfer.GetInputs())
+		numOutputs += transfer.NumOutputs()
+	}
+	if maxInputs := v.PublicParams.MaxInputsPerRequest(); maxInputs != 0 && uint64(numInputs) > maxInputs {
+		return errors.Errorf("too many inputs [%d], max [%d]", numInputs, maxInputs)
+	}
+	if maxOutputs := v.PublicParams.MaxOutputsPerRequest(); maxOutputs != 0 && uint64(numOutputs) > maxOutputs {
+		return errors.Errorf("too many outputs [%d], max [%d]", numOutputs, maxOutputs)
+	}
+	return nil
+}
+
 func (v *Validator[P, T, TA, IA, DS]) verifyIssues(ledger driver.Ledger, issues []IA, signatureProvider driver.SignatureProvider, attributes driver.ValidationAttributes) error {
 	for _, issue := range issues {
 		if err := v.verifyIssue(issue, ledger, signatureProvider, attributes); err != nil {
@@ -259,3 +331,27 @@ func (v *Validator[P, T, TA, IA, DS]) verifyTransfer(tr TA, ledger driver.Ledger
 
 	return nil
 }
+
+// timedLedger accumulates the time spent reading the ledger
+type timedLedger struct {
+	driver.Ledger
+	elapsed time.Duration
+}
+
+func (l *timedLedger) GetState(id token.ID) ([]byte, error) {
+	start := time.Now()
+	defer func() { l.elapsed += time.Since(start) }()
+	return l.Ledger.GetState(id)
+}
+
+// timedSignatureProvider accumulates the time spent verifying signatures
+type timedSignatureProvider struct {
+	driver.SignatureProvider
+	elapsed time.Duration
+}
+
+func (s *timedSignatureProvider) HasBeenSignedBy(id driver.Identity, verifier driver.Verifier) ([]byte, error) {
+	start := time.Now()
+	defer func() { s.elapsed += time.Since(start) }()
+	return s.SignatureProvider.HasBeenSignedBy(id, verifier)
+}
@@ -8,6 +8,7 @@ package common
 
 import (
 	"context"
+	"sync"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/logging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -61,12 +62,21 @@ type IssuerTokenVault interface {
 	ListHistoryIssuedTokens() (*token.IssuedTokens, error)
 }
 
+// IdentityBinder binds an identity to the wallet it belongs to
+type IdentityBinder = func(identity driver.Identity) error
+
 type IssuerWallet struct {
 	Logger           logging.Logger
 	IdentityProvider driver.IdentityProvider
 	TokenVault       IssuerTokenVault
 	WalletID         string
 	IssuerIdentity   driver.Identity
+	// Binder, if set, binds the keys added to this wallet, so that they can be looked up by identity
+	Binder IdentityBinder
+
+	keysLock  sync.RWMutex
+	keys      []*driver.IssuerKey
+	keyPolicy driver.IssuerKeyPolicy
 }
 
 func NewIssuerWallet(Logger logging.Logger, IdentityProvider driver.IdentityProvider, TokenVault IssuerTokenVault, id string, identity driver.Identity) *IssuerWallet {
@@ -76,6 +86,8 @@ func NewIssuerWallet(Logger logging.Logger, IdentityProvider driver.IdentityProv
 		TokenVault:       TokenVault,
 		WalletID:         id,
 		IssuerIdentity:   identity,
+		keys:             []*driver.IssuerKey{{Identity: identity}},
+		keyPolicy:        &DefaultIssuerKeyPolicy{},
 	}
 }
 
@@ -84,15 +96,92 @@ func (w *IssuerWallet) ID() string {
 }
 
 func (w *IssuerWallet) Contains(identity driver.Identity) bool {
-	return w.IssuerIdentity.Equal(identity)
+	w.keysLock.RLock()
+	defer w.keysLock.RUnlock()
+	for _, key := range w.keys {
+		if key.Identity.Equal(identity) {
+			return true
+		}
+	}
+	return false
 }
 
 func (w *IssuerWallet) ContainsToken(token *token.UnspentToken) bool {
 	return w.Contains(token.Owner)
 }
 
+// GetIssuerIdentity returns the identity the policy of this wallet selects for the passed token type
 func (w *IssuerWallet) GetIssuerIdentity(tokenType string) (driver.Identity, error) {
-	return w.IssuerIdentity, nil
+	return w.SelectIssuerIdentity(tokenType, nil)
+}
+
+// AddIssuerKey adds the passed key to this wallet, ahead of the existing ones.
+// The identity provider must hold the signer of the key.
+func (w *IssuerWallet) AddIssuerKey(key *driver.IssuerKey) error {
+	if key == nil || key.Identity.IsNone() {
+		return errors.Errorf("invalid issuer key for wallet [%s]", w.ID())
+	}
+	if w.Contains(key.Identity) {
+		return errors.Errorf("issuer key [%s] already in wallet [%s]", key.Identity, w.ID())
+	}
+	if _, err := w.IdentityProvider.GetSigner(key.Identity); err != nil {
+		return errors.WithMessagef(err, "no signer found for issuer key [%s]", key.Identity)
+	}
+	if w.Binder != nil {
+		if err := w.Binder(key.Identity); err != nil {
+			return errors.WithMessagef(err, "failed binding issuer key [%s] to wallet [%s]", key.Identity, w.ID())
+		}
+	}
+
+	w.keysLock.Lock()
+	defer w.keysLock.Unlock()
+	w.keys = append([]*driver.IssuerKey{{
+		Identity:   key.Identity,
+		TokenTypes: append([]string{}, key.TokenTypes...),
+	}}, w.keys...)
+	return nil
+}
+
+// IssuerKeys returns the keys of this wallet, the most recently added first
+func (w *IssuerWallet) IssuerKeys() []*driver.IssuerKey {
+	w.keysLock.RLock()
+	defer w.keysLock.RUnlock()
+	return append([]*driver.IssuerKey{}, w.keys...)
+}
+
+// SetIssuerKeyPolicy sets the policy selecting the key at issuance time, nil restores the default one
+func (w *IssuerWallet) SetIssuerKeyPolicy(policy driver.IssuerKeyPolicy) {
+	if policy == nil {
+		policy = &DefaultIssuerKeyPolicy{}
+	}
+	w.keysLock.Lock()
+	defer w.keysLock.Unlock()
+	w.keyPolicy = policy
+}
+
+// SelectIssuerIdentity returns the identity of the key to issue the passed token type with.
+// If registered is not empty, only the keys whose identity is in it are candidates.
+func (w *IssuerWallet) SelectIssuerIdentity(tokenType string, registered []driver.Identity) (driver.Identity, error) {
+	w.keysLock.RLock()
+	policy := w.keyPolicy
+	candidates := make([]*driver.IssuerKey, 0, len(w.keys))
+	for _, key := range w.keys {
+		if len(registered) != 0 && !containsIdentity(registered, key.Identity) {
+			w.Logger.Debugf("issuer wallet [%s]: key [%s] not registered, skipping", w.ID(), key.Identity)
+			continue
+		}
+		candidates = append(candidates, key)
+	}
+	w.keysLock.RUnlock()
+
+	if len(candidates) == 0 {
+		return nil, errors.Errorf("no registered issuer key in wallet [%s]", w.ID())
+	}
+	id, err := policy.Select(tokenType, candidates)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed selecting issuer key for type [%s] in wallet [%s]", tokenType, w.ID())
+	}
+	return id, nil
 }
 
 func (w *IssuerWallet) GetSigner(identity driver.Identity) (driver.Signer, error) {
@@ -129,6 +218,40 @@ func (w *IssuerWallet) HistoryTokens(opts *driver.ListTokensOptions) (*token.Iss
 	return unspentTokens, nil
 }
 
+// DefaultIssuerKeyPolicy selects the most recent key dedicated to the token type,
+// or, if there is none, the most recent key not dedicated to any type.
+type DefaultIssuerKeyPolicy struct{}
+
+func (p *DefaultIssuerKeyPolicy) Select(tokenType string, keys []*driver.IssuerKey) (driver.Identity, error) {
+	var general driver.Identity
+	for _, key := range keys {
+		if len(key.TokenTypes) == 0 {
+			if general == nil {
+				general = key.Identity
+			}
+			continue
+		}
+		for _, typ := range key.TokenTypes {
+			if typ == tokenType {
+				return key.Identity, nil
+			}
+		}
+	}
+	if general == nil {
+		return nil, errors.Errorf("no issuer key for type [%s]", tokenType)
+	}
+	return general, nil
+}
+
+func containsIdentity(ids []driver.Identity, id driver.Identity) bool {
+	for _, other := range ids {
+		if other.Equal(id) {
+			return true
+		}
+	}
+	return false
+}
+
 type CertifierWallet struct {
 	IdentityProvider  driver.IdentityProvider
 	WalletID          string
@@ -196,7 +319,10 @@ func (w *LongTermOwnerWallet) ContainsToken(token *token.UnspentToken) bool {
 	return w.Contains(token.Owner)
 }
 
-func (w *LongTermOwnerWallet) GetRecipientIdentity() (driver.Identity, error) {
+func (w *LongTermOwnerWallet) GetRecipientIdentity(opts *driver.RecipientIdentityOptions) (driver.Identity, error) {
+	if opts != nil && opts.AnonymityLevel == driver.FreshPseudonym {
+		return nil, errors.Errorf("wallet [%s] cannot derive fresh pseudonyms", w.WalletID)
+	}
 	return w.OwnerIdentity, nil
 }
 
@@ -275,6 +401,11 @@ type AnonymousOwnerWallet struct {
 	Deserializer   driver.Deserializer
 	WalletRegistry WalletRegistry
 	IdentityCache  *WalletIdentityCache
+
+	// reusedIdentity caches the pseudonym returned when the long-term anonymity level is requested.
+	// The pseudonym is stored in the wallet registry, so that it survives restarts.
+	reusedIdentityLock sync.Mutex
+	reusedIdentity     driver.Identity
 }
 
 func NewAnonymousOwnerWallet(
@@ -312,10 +443,49 @@ func (w *AnonymousOwnerWallet) ContainsToken(token *token.UnspentToken) bool {
 	return w.Contains(token.Owner)
 }
 
-func (w *AnonymousOwnerWallet) GetRecipientIdentity() (driver.Identity, error) {
+// GetRecipientIdentity returns a fresh pseudonym by default.
+// If the long-term anonymity level is requested, the same pseudonym is returned, also after a restart.
+func (w *AnonymousOwnerWallet) GetRecipientIdentity(opts *driver.RecipientIdentityOptions) (driver.Identity, error) {
+	if opts != nil && opts.AnonymityLevel == driver.LongTermIdentity {
+		w.reusedIdentityLock.Lock()
+		defer w.reusedIdentityLock.Unlock()
+		if w.reusedIdentity.IsNone() {
+			id, err := w.longTermIdentity()
+			if err != nil {
+				return nil, err
+			}
+			w.reusedIdentity = id
+		}
+		return w.reusedIdentity, nil
+	}
 	return w.IdentityCache.Identity()
 }
 
+// longTermIdentity returns the pseudonym stored in the wallet registry for the long-term anonymity level,
+// it derives and stores a new one if there is none
+func (w *AnonymousOwnerWallet) longTermIdentity() (driver.Identity, error) {
+	id, err := w.WalletRegistry.LongTermIdentity(w.WalletID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed loading long-term identity of wallet [%s]", w.WalletID)
+	}
+	if !id.IsNone() {
+		return id, nil
+	}
+	id, err = w.getRecipientIdentity()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.WalletRegistry.BindLongTermIdentity(id, w.WalletID); err != nil {
+		return nil, errors.WithMessagef(err, "failed storing long-term identity of wallet [%s]", w.WalletID)
+	}
+	// another replica sharing the registry might have stored its pseudonym first, that one is kept
+	id, err = w.WalletRegistry.LongTermIdentity(w.WalletID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed loading long-term identity of wallet [%s]", w.WalletID)
+	}
+	return id, nil
+}
+
 func (w *AnonymousOwnerWallet) RegisterRecipient(data *driver.RecipientData) error {
 	if data == nil {
 		return errors.WithStack(ErrNilRecipientData)
@@ -0,0 +1,110 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/logging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssuerWalletKeySelection(t *testing.T) {
+	ip := &mock.IdentityProvider{}
+	ip.GetSignerReturns(&mock.Signer{}, nil)
+	var bound []driver.Identity
+	w := NewIssuerWallet(logging.DriverLoggerFromPP("test", "test"), ip, nil, "issuer", driver.Identity("default"))
+	w.Binder = func(identity driver.Identity) error {
+		bound = append(bound, identity)
+		return nil
+	}
+
+	assert.NoError(t, w.AddIssuerKey(&driver.IssuerKey{Identity: driver.Identity("usd"), TokenTypes: []string{"USD"}}))
+	assert.NoError(t, w.AddIssuerKey(&driver.IssuerKey{Identity: driver.Identity("rotated")}))
+	assert.Error(t, w.AddIssuerKey(&driver.IssuerKey{Identity: driver.Identity("usd")}))
+	assert.Equal(t, []driver.Identity{driver.Identity("usd"), driver.Identity("rotated")}, bound)
+	assert.Len(t, w.IssuerKeys(), 3)
+	assert.True(t, w.Contains(driver.Identity("default")))
+	assert.True(t, w.Contains(driver.Identity("usd")))
+
+	// dedicated key first, then the most recent general key
+	id, err := w.GetIssuerIdentity("USD")
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Identity("usd"), id)
+	id, err = w.GetIssuerIdentity("EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Identity("rotated"), id)
+
+	// keys not registered in the public parameters are skipped
+	id, err = w.SelectIssuerIdentity("EUR", []driver.Identity{driver.Identity("default"), driver.Identity("usd")})
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Identity("default"), id)
+	_, err = w.SelectIssuerIdentity("EUR", []driver.Identity{driver.Identity("usd")})
+	assert.Error(t, err)
+	_, err = w.SelectIssuerIdentity("EUR", []driver.Identity{driver.Identity("unknown")})
+	assert.Error(t, err)
+}
+
+type pseudonymInfo struct {
+	driver.IdentityInfo
+	derived int
+}
+
+func (i *pseudonymInfo) EnrollmentID() string { return "alice" }
+
+func (i *pseudonymInfo) Get() (driver.Identity, []byte, error) {
+	i.derived++
+	return driver.Identity(fmt.Sprintf("pseudonym%d", i.derived)), nil, nil
+}
+
+type longTermRegistry struct {
+	WalletRegistry
+	longTerm map[string]driver.Identity
+}
+
+func (r *longTermRegistry) BindIdentity(driver.Identity, string, string, any) error { return nil }
+
+func (r *longTermRegistry) BindLongTermIdentity(identity driver.Identity, wID string) error {
+	if _, ok := r.longTerm[wID]; !ok {
+		r.longTerm[wID] = identity
+	}
+	return nil
+}
+
+func (r *longTermRegistry) LongTermIdentity(wID string) (driver.Identity, error) {
+	return r.longTerm[wID], nil
+}
+
+func TestAnonymousOwnerWalletLongTermIdentity(t *testing.T) {
+	registry := &longTermRegistry{longTerm: map[string]driver.Identity{}}
+	longTerm := &driver.RecipientIdentityOptions{AnonymityLevel: driver.LongTermIdentity}
+	newWallet := func() *AnonymousOwnerWallet {
+		w, err := NewAnonymousOwnerWallet(logging.DriverLoggerFromPP("test", "test"), nil, nil, nil, registry, "alice", &pseudonymInfo{}, 0)
+		assert.NoError(t, err)
+		return w
+	}
+
+	w := newWallet()
+	id, err := w.GetRecipientIdentity(longTerm)
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Identity("pseudonym1"), id)
+	id, err = w.GetRecipientIdentity(longTerm)
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Identity("pseudonym1"), id)
+	fresh, err := w.GetRecipientIdentity(nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, id, fresh)
+
+	// the long-term identity survives a restart
+	restarted := newWallet()
+	id, err = restarted.GetRecipientIdentity(longTerm)
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Identity("pseudonym1"), id)
+}
@@ -35,11 +35,14 @@ type TokenVault interface {
 type WalletRegistry interface {
 	WalletIDs() ([]string, error)
 	RegisterIdentity(config driver.IdentityConfiguration) error
+	IdentityConfiguration(id string) (driver.IdentityConfiguration, error)
 	Lookup(id driver.WalletLookupID) (driver.Wallet, driver.IdentityInfo, string, error)
 	RegisterWallet(id string, wallet driver.Wallet) error
 	BindIdentity(identity driver.Identity, eID string, wID string, meta any) error
 	ContainsIdentity(i driver.Identity, id string) bool
 	GetIdentityMetadata(identity driver.Identity, wID string, meta any) error
+	BindLongTermIdentity(identity driver.Identity, wID string) error
+	LongTermIdentity(wID string) (driver.Identity, error)
 }
 
 type WalletFactory interface {
@@ -89,6 +92,10 @@ func (s *WalletService) RegisterOwnerIdentity(config driver.IdentityConfiguratio
 	return s.Registries[driver.OwnerRole].Registry.RegisterIdentity(config)
 }
 
+func (s *WalletService) OwnerIdentityConfiguration(id string) (driver.IdentityConfiguration, error) {
+	return s.Registries[driver.OwnerRole].Registry.IdentityConfiguration(id)
+}
+
 func (s *WalletService) RegisterIssuerIdentity(config driver.IdentityConfiguration) error {
 	return s.Registries[driver.IssuerRole].Registry.RegisterIdentity(config)
 }
@@ -112,6 +112,17 @@ func (i *IssueAction) GetMetadata() map[string][]byte {
 	return i.Metadata
 }
 
+// GetIssuedSerials returns the serial ranges carried by the outputs of the IssueAction
+func (i *IssueAction) GetIssuedSerials() []token.SerialRange {
+	var ranges []token.SerialRange
+	for _, output := range i.Outputs {
+		if r, ok := token.ParseSerialType(output.Output.Type); ok {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
 // IsGraphHiding returns false, indicating that fabtoken does not hide the transaction graph
 func (i *IssueAction) IsGraphHiding() bool {
 	return false
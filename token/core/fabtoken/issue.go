@@ -66,6 +66,10 @@ func (s *IssueService) Issue(ctx context.Context, issuerIdentity driver.Identity
 	}
 
 	action := &IssueAction{Issuer: issuerIdentity, Outputs: outs}
+	if token2.IsNFT(tokenType) {
+		// the translator fails if the key is already on the ledger, see IssueNFTValidate
+		action.Metadata = map[string][]byte{token2.NFTIssueKey(tokenType): []byte(tokenType)}
+	}
 	outputs, err := action.GetSerializedOutputs()
 	if err != nil {
 		return nil, nil, err
@@ -33,6 +33,14 @@ type PublicParams struct {
 	Issuers [][]byte
 	// MaxToken is the maximum quantity a token can hold
 	MaxToken uint64
+	// MaxInputs is the maximum number of inputs a token request can spend, 0 means no limit
+	MaxInputs uint64 `json:",omitempty"`
+	// MaxOutputs is the maximum number of outputs a token request can create, 0 means no limit
+	MaxOutputs uint64 `json:",omitempty"`
+	// Redemptions are the periods of time during which tokens can be redeemed, empty means at any time
+	Redemptions driver.RedemptionWindows `json:",omitempty"`
+	// Hashing is the hash algorithm of the public parameters and of the token requests, SHA256 if empty
+	Hashing driver.HashAlgorithm `json:",omitempty"`
 }
 
 // NewPublicParamsFromBytes deserializes the raw bytes into public parameters
@@ -75,6 +83,26 @@ func (pp *PublicParams) MaxTokenValue() uint64 {
 	return pp.MaxToken
 }
 
+// MaxInputsPerRequest returns the maximum number of inputs a token request can spend, 0 means no limit
+func (pp *PublicParams) MaxInputsPerRequest() uint64 {
+	return pp.MaxInputs
+}
+
+// MaxOutputsPerRequest returns the maximum number of outputs a token request can create, 0 means no limit
+func (pp *PublicParams) MaxOutputsPerRequest() uint64 {
+	return pp.MaxOutputs
+}
+
+// RedemptionWindows returns the periods of time during which tokens can be redeemed, empty means at any time
+func (pp *PublicParams) RedemptionWindows() driver.RedemptionWindows {
+	return pp.Redemptions
+}
+
+// HashAlgorithm returns the hash algorithm of the public parameters and of the token requests
+func (pp *PublicParams) HashAlgorithm() driver.HashAlgorithm {
+	return pp.Hashing
+}
+
 // Bytes marshals PublicParams
 func (pp *PublicParams) Bytes() ([]byte, error) {
 	return json.Marshal(pp)
@@ -121,6 +149,15 @@ func (pp *PublicParams) AddIssuer(issuer driver.Identity) {
 
 // Auditors returns the list of authorized auditors
 // fabtoken only supports a single auditor
+// IssuerIDs returns the identities allowed to issue tokens
+func (pp *PublicParams) IssuerIDs() []driver.Identity {
+	ids := make([]driver.Identity, len(pp.Issuers))
+	for i, issuer := range pp.Issuers {
+		ids[i] = issuer
+	}
+	return ids
+}
+
 func (pp *PublicParams) Auditors() []driver.Identity {
 	if len(pp.Auditor) == 0 {
 		return []driver.Identity{}
@@ -138,6 +175,12 @@ func (pp *PublicParams) Validate() error {
 	if pp.MaxToken > pp.ComputeMaxTokenValue() {
 		return errors.Errorf("max token value is invalid [%d]>[%d]", pp.MaxToken, pp.ComputeMaxTokenValue())
 	}
+	if err := pp.Redemptions.Validate(); err != nil {
+		return err
+	}
+	if !pp.Hashing.Available() {
+		return errors.Errorf("hash algorithm [%s] not available", pp.Hashing)
+	}
 	return nil
 }
 
@@ -50,11 +50,14 @@ func NewValidator(logger logging.Logger, pp *PublicParams, deserializer driver.D
 		TransferSignatureValidate,
 		TransferBalanceValidate,
 		TransferHTLCValidate,
+		TransferRedemptionWindowValidate,
 	}
 	transferValidators = append(transferValidators, extraValidators...)
 
 	issueValidators := []ValidateIssueFunc{
 		IssueValidate,
+		IssueNFTValidate,
+		IssueSerialValidate,
 	}
 
 	return common.NewValidator[*PublicParams, *token.Token, *TransferAction, *IssueAction, driver.Deserializer](
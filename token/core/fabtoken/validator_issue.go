@@ -58,3 +58,59 @@ func IssueValidate(ctx *Context) error {
 	}
 	return nil
 }
+
+// IssueNFTValidate checks that the non-fungible tokens created by the issue action are whole and unique within the action.
+// A non-fungible token has the base64 encoding of a JSON object as type, see token.NFTAttributes.
+// It also checks that the action carries the metadata key token.NFTIssueKey of each non-fungible token:
+// the translator writes the key to the ledger and fails if it exists, then a non-fungible token is issued only once.
+func IssueNFTValidate(ctx *Context) error {
+	one := token.NewOneQuantity(ctx.PP.QuantityPrecision)
+	types := map[string]struct{}{}
+	metadata := ctx.IssueAction.GetMetadata()
+	for i, output := range ctx.IssueAction.GetOutputs() {
+		out := output.(*Output).Output
+		if !token.IsNFT(out.Type) {
+			continue
+		}
+		q, err := token.ToQuantity(out.Quantity, ctx.PP.QuantityPrecision)
+		if err != nil {
+			return errors.Wrapf(err, "failed parsing quantity [%s]", out.Quantity)
+		}
+		if q.Cmp(one) != 0 {
+			return errors.Errorf("non-fungible token at index [%d] must have quantity one, got [%s]", i, out.Quantity)
+		}
+		if _, ok := types[out.Type]; ok {
+			return errors.Errorf("non-fungible token at index [%d] is issued more than once", i)
+		}
+		types[out.Type] = struct{}{}
+		key := token.NFTIssueKey(out.Type)
+		if _, ok := metadata[key]; !ok {
+			return errors.Errorf("non-fungible token at index [%d] has no issue key [%s] in the metadata", i, key)
+		}
+		ctx.CountMetadataKey(key)
+	}
+	return nil
+}
+
+// IssueSerialValidate checks that the serial tokens created by the issue action have the quantity of their range,
+// and that their ranges do not overlap.
+// Whether the serials were already issued by another transaction is checked by the translator against the ledger,
+// see IssueAction.GetIssuedSerials.
+func IssueSerialValidate(ctx *Context) error {
+	var ranges []token.SerialRange
+	for i, output := range ctx.IssueAction.GetOutputs() {
+		out := output.(*Output).Output
+		r, ok := token.ParseSerialType(out.Type)
+		if !ok {
+			continue
+		}
+		if _, err := serialRangeOf(&out, r.Class, ctx.PP.QuantityPrecision); err != nil {
+			return errors.WithMessagef(err, "invalid output %d", i)
+		}
+		ranges = append(ranges, r)
+	}
+	if _, err := token.MergeSerialRanges(ranges); err != nil {
+		return errors.WithMessage(err, "serials issued more than once")
+	}
+	return nil
+}
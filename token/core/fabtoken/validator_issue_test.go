@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabtoken
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/mock"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func issueContext(outputs []token.Token, metadata map[string][]byte) *Context {
+	action := &IssueAction{Issuer: []byte("issuer"), Metadata: metadata}
+	for _, output := range outputs {
+		action.Outputs = append(action.Outputs, &Output{Output: output})
+	}
+	return &Context{
+		PP:              &PublicParams{QuantityPrecision: 64},
+		IssueAction:     action,
+		MetadataCounter: map[string]int{},
+	}
+}
+
+func TestIssueNFTValidate(t *testing.T) {
+	house := base64.StdEncoding.EncodeToString([]byte(`{"LinearID":"house-1"}`))
+	car := base64.StdEncoding.EncodeToString([]byte(`{"LinearID":"car-1"}`))
+	keys := map[string][]byte{
+		token.NFTIssueKey(house): []byte(house),
+		token.NFTIssueKey(car):   []byte(car),
+	}
+
+	// each non-fungible token carries its issue key, the key is counted as validated
+	ctx := issueContext([]token.Token{
+		{Owner: []byte("alice"), Type: house, Quantity: "0x1"},
+		{Owner: []byte("bob"), Type: car, Quantity: "0x1"},
+		{Owner: []byte("bob"), Type: "USD", Quantity: "0x10"},
+	}, keys)
+	assert.NoError(t, IssueNFTValidate(ctx))
+	assert.Equal(t, map[string]int{token.NFTIssueKey(house): 1, token.NFTIssueKey(car): 1}, ctx.MetadataCounter)
+
+	// without the issue key the translator could not check that the token is not on the ledger yet
+	ctx = issueContext([]token.Token{{Owner: []byte("alice"), Type: house, Quantity: "0x1"}}, nil)
+	assert.ErrorContains(t, IssueNFTValidate(ctx), "non-fungible token at index [0] has no issue key")
+	ctx = issueContext([]token.Token{
+		{Owner: []byte("alice"), Type: house, Quantity: "0x1"},
+		{Owner: []byte("bob"), Type: car, Quantity: "0x1"},
+	}, map[string][]byte{token.NFTIssueKey(house): []byte(house)})
+	assert.ErrorContains(t, IssueNFTValidate(ctx), "non-fungible token at index [1] has no issue key")
+
+	// a non-fungible token is whole and issued once
+	ctx = issueContext([]token.Token{{Owner: []byte("alice"), Type: house, Quantity: "0x2"}}, keys)
+	assert.ErrorContains(t, IssueNFTValidate(ctx), "non-fungible token at index [0] must have quantity one, got [0x2]")
+	ctx = issueContext([]token.Token{
+		{Owner: []byte("alice"), Type: house, Quantity: "0x1"},
+		{Owner: []byte("bob"), Type: house, Quantity: "0x1"},
+	}, keys)
+	assert.ErrorContains(t, IssueNFTValidate(ctx), "non-fungible token at index [1] is issued more than once")
+}
+
+func TestIssueServiceNFTIssueKey(t *testing.T) {
+	ppm := &mock.PublicParamsManager{}
+	ppm.PublicParametersReturns(&PublicParams{QuantityPrecision: 64})
+	service := NewIssueService(ppm, nil, &mock.Deserializer{})
+	house := base64.StdEncoding.EncodeToString([]byte(`{"LinearID":"house-1"}`))
+
+	// the issue action of a non-fungible token passes the validator
+	action, _, err := service.Issue(context.TODO(), []byte("issuer"), house, []uint64{1}, [][]byte{[]byte("alice")}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{token.NFTIssueKey(house): []byte(house)}, action.GetMetadata())
+	ctx := issueContext([]token.Token{{Owner: []byte("alice"), Type: house, Quantity: "0x1"}}, action.GetMetadata())
+	assert.NoError(t, IssueNFTValidate(ctx))
+
+	// fungible tokens carry no issue key
+	action, _, err = service.Issue(context.TODO(), []byte("issuer"), "USD", []uint64{10}, [][]byte{[]byte("alice")}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, action.GetMetadata())
+}
+
+func TestIssueSerialValidate(t *testing.T) {
+	// the ranges of the action have the quantity of their serials and do not overlap
+	ctx := issueContext([]token.Token{
+		{Owner: []byte("alice"), Type: "TICKET#1-10", Quantity: "0xa"},
+		{Owner: []byte("bob"), Type: "TICKET#11-11", Quantity: "0x1"},
+		{Owner: []byte("bob"), Type: "PASS#1-10", Quantity: "0xa"},
+		{Owner: []byte("bob"), Type: "USD", Quantity: "0x10"},
+	}, nil)
+	assert.NoError(t, IssueSerialValidate(ctx))
+	// the translator records the same ranges against the ledger
+	assert.Equal(t, []token.SerialRange{
+		{Class: "TICKET", From: 1, To: 10},
+		{Class: "TICKET", From: 11, To: 11},
+		{Class: "PASS", From: 1, To: 10},
+	}, ctx.IssueAction.GetIssuedSerials())
+
+	ctx = issueContext([]token.Token{{Owner: []byte("alice"), Type: "TICKET#1-10", Quantity: "0x9"}}, nil)
+	assert.ErrorContains(t, IssueSerialValidate(ctx), "invalid output 0: quantity 0x9 does not match the serials of TICKET#1-10")
+
+	ctx = issueContext([]token.Token{
+		{Owner: []byte("alice"), Type: "TICKET#1-10", Quantity: "0xa"},
+		{Owner: []byte("bob"), Type: "TICKET#10-11", Quantity: "0x2"},
+	}, nil)
+	assert.ErrorContains(t, IssueSerialValidate(ctx), "serials issued more than once: serial ranges [TICKET#1-10] and [TICKET#10-11] overlap")
+
+	// an issue without serials has nothing for the translator to record
+	ctx = issueContext([]token.Token{{Owner: []byte("alice"), Type: "USD", Quantity: "0x10"}}, nil)
+	assert.NoError(t, IssueSerialValidate(ctx))
+	assert.Empty(t, ctx.IssueAction.GetIssuedSerials())
+}
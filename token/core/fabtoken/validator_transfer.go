@@ -37,7 +37,8 @@ func TransferSignatureValidate(ctx *Context) error {
 	return nil
 }
 
-// TransferBalanceValidate checks that the sum of the inputs is equal to the sum of the outputs
+// TransferBalanceValidate checks that the sum of the inputs is equal to the sum of the outputs.
+// For serial tokens, it checks instead that the outputs carry the serials of the inputs, see transferSerialsValidate.
 func TransferBalanceValidate(ctx *Context) error {
 	if ctx.TransferAction.NumOutputs() == 0 {
 		return errors.New("there is no output")
@@ -48,6 +49,9 @@ func TransferBalanceValidate(ctx *Context) error {
 	if ctx.InputTokens[0] == nil {
 		return errors.New("first input is nil")
 	}
+	if token.IsSerial(ctx.InputTokens[0].Type) {
+		return transferSerialsValidate(ctx)
+	}
 	typ := ctx.InputTokens[0].Type
 	inputSum := token.NewZeroQuantity(ctx.PP.QuantityPrecision)
 	outputSum := token.NewZeroQuantity(ctx.PP.QuantityPrecision)
@@ -85,6 +89,69 @@ func TransferBalanceValidate(ctx *Context) error {
 	return nil
 }
 
+// transferSerialsValidate checks that the inputs and the outputs are serial tokens of the same class,
+// whose quantities match their ranges, and that the outputs carry exactly the serials of the inputs
+func transferSerialsValidate(ctx *Context) error {
+	class, _ := token.ParseSerialType(ctx.InputTokens[0].Type)
+	inputs := make([]token.SerialRange, 0, len(ctx.InputTokens))
+	for i, input := range ctx.InputTokens {
+		if input == nil {
+			return errors.Errorf("input %d is nil", i)
+		}
+		r, err := serialRangeOf(input, class.Class, ctx.PP.QuantityPrecision)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid input %d", i)
+		}
+		inputs = append(inputs, r)
+	}
+	outputs := make([]token.SerialRange, 0, ctx.TransferAction.NumOutputs())
+	for i, output := range ctx.TransferAction.GetOutputs() {
+		r, err := serialRangeOf(&output.(*Output).Output, class.Class, ctx.PP.QuantityPrecision)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid output %d", i)
+		}
+		outputs = append(outputs, r)
+	}
+	mergedInputs, err := token.MergeSerialRanges(inputs)
+	if err != nil {
+		return errors.WithMessage(err, "invalid inputs")
+	}
+	mergedOutputs, err := token.MergeSerialRanges(outputs)
+	if err != nil {
+		return errors.WithMessage(err, "invalid outputs")
+	}
+	if len(mergedInputs) != len(mergedOutputs) {
+		return errors.Errorf("serials of the outputs %v do not match serials of the inputs %v", mergedOutputs, mergedInputs)
+	}
+	for i := range mergedInputs {
+		if mergedInputs[i] != mergedOutputs[i] {
+			return errors.Errorf("serials of the outputs %v do not match serials of the inputs %v", mergedOutputs, mergedInputs)
+		}
+	}
+	return nil
+}
+
+// serialRangeOf returns the serial range of the passed token, which must be of the passed class,
+// and whose quantity must be the number of serials in the range
+func serialRangeOf(tok *token.Token, class string, precision uint64) (token.SerialRange, error) {
+	r, ok := token.ParseSerialType(tok.Type)
+	if !ok || r.Class != class {
+		return token.SerialRange{}, errors.Errorf("type %s is not a serial type of class %s", tok.Type, class)
+	}
+	q, err := token.ToQuantity(tok.Quantity, precision)
+	if err != nil {
+		return token.SerialRange{}, errors.Wrapf(err, "failed parsing quantity [%s]", tok.Quantity)
+	}
+	count, err := token.UInt64ToQuantity(r.Count(), precision)
+	if err != nil {
+		return token.SerialRange{}, errors.Wrapf(err, "failed converting serial count [%d]", r.Count())
+	}
+	if q.Cmp(count) != 0 {
+		return token.SerialRange{}, errors.Errorf("quantity %s does not match the serials of %s", tok.Quantity, tok.Type)
+	}
+	return r, nil
+}
+
 // TransferHTLCValidate checks the validity of the HTLC scripts, if any
 func TransferHTLCValidate(ctx *Context) error {
 	now := time.Now()
@@ -165,3 +232,28 @@ func TransferHTLCValidate(ctx *Context) error {
 	}
 	return nil
 }
+
+// TransferRedemptionWindowValidate checks that the redeemed tokens, if any, can be redeemed at the timestamp of the transaction
+func TransferRedemptionWindowValidate(ctx *Context) error {
+	windows := ctx.PP.RedemptionWindows()
+	if len(windows) == 0 {
+		return nil
+	}
+	for _, o := range ctx.TransferAction.GetOutputs() {
+		out, ok := o.(*Output)
+		if !ok {
+			return errors.New("invalid output")
+		}
+		if !out.IsRedeem() {
+			continue
+		}
+		timestamp, err := ctx.TxTimestamp()
+		if err != nil {
+			return errors.WithMessagef(err, "cannot check the redemption windows")
+		}
+		if err := windows.Check(out.Output.Type, timestamp); err != nil {
+			return errors.WithMessagef(err, "invalid redeem")
+		}
+	}
+	return nil
+}
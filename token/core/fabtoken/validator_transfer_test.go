@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fabtoken
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func transferContext(inputs []*token.Token, outputs []token.Token) *Context {
+	action := &TransferAction{InputTokens: inputs}
+	for _, output := range outputs {
+		action.Outputs = append(action.Outputs, &Output{Output: output})
+	}
+	return &Context{
+		PP:             &PublicParams{QuantityPrecision: 64},
+		InputTokens:    inputs,
+		TransferAction: action,
+	}
+}
+
+func TestTransferSerialsValidate(t *testing.T) {
+	inputs := []*token.Token{
+		{Owner: []byte("alice"), Type: "TICKET#1-10", Quantity: "0xa"},
+		{Owner: []byte("alice"), Type: "TICKET#11-20", Quantity: "0xa"},
+	}
+
+	// the outputs split and merge the serials of the inputs
+	ctx := transferContext(inputs, []token.Token{
+		{Owner: []byte("bob"), Type: "TICKET#5-15", Quantity: "0xb"},
+		{Owner: []byte("alice"), Type: "TICKET#1-4", Quantity: "0x4"},
+		{Owner: []byte("alice"), Type: "TICKET#16-20", Quantity: "0x5"},
+	})
+	assert.NoError(t, TransferBalanceValidate(ctx))
+
+	// serials cannot be created or dropped
+	ctx = transferContext(inputs, []token.Token{{Owner: []byte("bob"), Type: "TICKET#1-21", Quantity: "0x15"}})
+	assert.ErrorContains(t, TransferBalanceValidate(ctx), "serials of the outputs [TICKET#1-21] do not match serials of the inputs [TICKET#1-20]")
+	ctx = transferContext(inputs, []token.Token{{Owner: []byte("bob"), Type: "TICKET#1-19", Quantity: "0x13"}})
+	assert.ErrorContains(t, TransferBalanceValidate(ctx), "do not match serials of the inputs")
+	ctx = transferContext(inputs, []token.Token{
+		{Owner: []byte("bob"), Type: "TICKET#1-10", Quantity: "0xa"},
+		{Owner: []byte("bob"), Type: "TICKET#12-20", Quantity: "0x9"},
+	})
+	assert.ErrorContains(t, TransferBalanceValidate(ctx), "do not match serials of the inputs")
+
+	// a serial cannot be spent or transferred twice
+	ctx = transferContext([]*token.Token{inputs[0], inputs[0]}, []token.Token{{Owner: []byte("bob"), Type: "TICKET#1-10", Quantity: "0xa"}})
+	assert.ErrorContains(t, TransferBalanceValidate(ctx), "invalid inputs: serial ranges [TICKET#1-10] and [TICKET#1-10] overlap")
+	ctx = transferContext(inputs, []token.Token{
+		{Owner: []byte("bob"), Type: "TICKET#1-15", Quantity: "0xf"},
+		{Owner: []byte("bob"), Type: "TICKET#10-20", Quantity: "0xb"},
+	})
+	assert.ErrorContains(t, TransferBalanceValidate(ctx), "invalid outputs: serial ranges [TICKET#1-15] and [TICKET#10-20] overlap")
+
+	// all the tokens are of the class of the first input
+	ctx = transferContext(inputs, []token.Token{{Owner: []byte("bob"), Type: "PASS#1-20", Quantity: "0x14"}})
+	assert.ErrorContains(t, TransferBalanceValidate(ctx), "invalid output 0: type PASS#1-20 is not a serial type of class TICKET")
+	ctx = transferContext([]*token.Token{inputs[0], {Owner: []byte("alice"), Type: "USD", Quantity: "0xa"}}, []token.Token{{Owner: []byte("bob"), Type: "TICKET#1-10", Quantity: "0xa"}})
+	assert.ErrorContains(t, TransferBalanceValidate(ctx), "invalid input 1: type USD is not a serial type of class TICKET")
+	ctx = transferContext([]*token.Token{inputs[0], nil}, []token.Token{{Owner: []byte("bob"), Type: "TICKET#1-10", Quantity: "0xa"}})
+	assert.ErrorContains(t, TransferBalanceValidate(ctx), "input 1 is nil")
+}
+
+func TestSerialRangeOf(t *testing.T) {
+	r, err := serialRangeOf(&token.Token{Type: "TICKET#3-7", Quantity: "0x5"}, "TICKET", 64)
+	assert.NoError(t, err)
+	assert.Equal(t, token.SerialRange{Class: "TICKET", From: 3, To: 7}, r)
+
+	// the class separator is the last one
+	r, err = serialRangeOf(&token.Token{Type: "A#B#1-1", Quantity: "0x1"}, "A#B", 64)
+	assert.NoError(t, err)
+	assert.Equal(t, token.SerialRange{Class: "A#B", From: 1, To: 1}, r)
+
+	_, err = serialRangeOf(&token.Token{Type: "TICKET#3-7", Quantity: "0x5"}, "PASS", 64)
+	assert.ErrorContains(t, err, "type TICKET#3-7 is not a serial type of class PASS")
+	_, err = serialRangeOf(&token.Token{Type: "TICKET", Quantity: "0x5"}, "TICKET", 64)
+	assert.ErrorContains(t, err, "type TICKET is not a serial type of class TICKET")
+	_, err = serialRangeOf(&token.Token{Type: "TICKET#3-7", Quantity: "0x6"}, "TICKET", 64)
+	assert.ErrorContains(t, err, "quantity 0x6 does not match the serials of TICKET#3-7")
+	_, err = serialRangeOf(&token.Token{Type: "TICKET#3-7", Quantity: "five"}, "TICKET", 64)
+	assert.ErrorContains(t, err, "failed parsing quantity [five]")
+	// the count of the range must fit the precision
+	_, err = serialRangeOf(&token.Token{Type: "TICKET#1-256", Quantity: "0xff"}, "TICKET", 8)
+	assert.ErrorContains(t, err, "failed converting serial count [256]")
+}
@@ -44,7 +44,11 @@ func (w *WalletFactory) NewWallet(role driver.IdentityRole, walletRegistry commo
 	case driver.OwnerRole:
 		newWallet = common.NewLongTermOwnerWallet(w.identityProvider, w.tokenVault, idInfoIdentity, id, info)
 	case driver.IssuerRole:
-		newWallet = common.NewIssuerWallet(w.logger, w.identityProvider, w.tokenVault, id, idInfoIdentity)
+		issuerWallet := common.NewIssuerWallet(w.logger, w.identityProvider, w.tokenVault, id, idInfoIdentity)
+		issuerWallet.Binder = func(identity driver.Identity) error {
+			return walletRegistry.BindIdentity(identity, info.EnrollmentID(), id, nil)
+		}
+		newWallet = issuerWallet
 	case driver.AuditorRole:
 		newWallet = common.NewAuditorWallet(w.identityProvider, id, idInfoIdentity)
 	case driver.CertifierRole:
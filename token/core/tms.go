@@ -12,7 +12,6 @@ import (
 	"runtime/debug"
 	"sync"
 
-	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/logging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/pkg/errors"
@@ -135,7 +134,11 @@ func (m *TMSProvider) Update(opts driver.ServiceOptions) (err error) {
 		m.logger.Debugf("no service found, instantiate token management system for [%s:%s:%s] for key [%s]", opts.Network, opts.Channel, opts.Namespace, key)
 	} else {
 		// update only if the public params are different from the current
-		if bytes.Equal(service.PublicParamsManager().PublicParamsHash(), hash.Hashable(opts.PublicParams).Raw()) {
+		newHash, err := driver.HashAlgorithmOf(service.PublicParamsManager().PublicParameters()).Hash(opts.PublicParams)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to hash public params")
+		}
+		if bytes.Equal(service.PublicParamsManager().PublicParamsHash(), newHash) {
 			m.logger.Debugf("service found, no need to update token management system for [%s:%s:%s] for key [%s], public params are the same", opts.Network, opts.Channel, opts.Namespace, key)
 			return nil
 		}
@@ -14,6 +14,7 @@ import (
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/logging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
@@ -22,6 +23,7 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/interop/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/msp"
 	htlc2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/predicate"
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -126,7 +128,7 @@ func (a *Auditor) Endorse(tokenRequest *driver.TokenRequest, txID string) ([]byt
 		return nil, errors.Errorf("audit of tx [%s] failed: : token request is nil", txID)
 	}
 	// Marshal tokenRequest
-	bytes, err := asn1.Marshal(driver.TokenRequest{Issues: tokenRequest.Issues, Transfers: tokenRequest.Transfers})
+	bytes, err := asn1.Marshal(driver.TokenRequest{Issues: tokenRequest.Issues, Transfers: tokenRequest.Transfers, Reference: tokenRequest.Reference})
 	if err != nil {
 		return nil, errors.Errorf("audit of tx [%s] failed: error marshal token request for signature", txID)
 	}
@@ -280,6 +282,8 @@ func InspectTokenOwner(des Deserializer, token *AuditableToken, index int) error
 		return nil
 	case htlc2.ScriptType:
 		return inspectTokenOwnerOfScript(des, token, index)
+	case predicate.ScriptType:
+		return inspectTokenOwnerOfPredicate(des, token, index)
 	default:
 		return errors.Errorf("identity type [%s] not recognized", ro.Type)
 	}
@@ -327,6 +331,26 @@ func inspectTokenOwnerOfScript(des Deserializer, token *AuditableToken, index in
 	return nil
 }
 
+// inspectTokenOwnerOfPredicate matches the audit info against the owner in the predicate script
+func inspectTokenOwnerOfPredicate(des Deserializer, token *AuditableToken, index int) error {
+	script, err := predicate.Unwrap(token.Token.Owner)
+	if err != nil {
+		return errors.Wrapf(err, "owner at index [%d] cannot be unwrapped", index)
+	}
+	ro, err := identity.UnmarshalTypedIdentity(script.Owner)
+	if err != nil {
+		return errors.Wrapf(err, "failed to retrieve raw owner from predicate script at index [%d]", index)
+	}
+	matcher, err := des.GetOwnerMatcher(token.Owner.OwnerInfo)
+	if err != nil {
+		return errors.Errorf("failed to get owner matcher for output [%d]", index)
+	}
+	if err := matcher.Match(ro.Identity); err != nil {
+		return errors.Wrapf(err, "owner at index [%d] does not match the provided opening", index)
+	}
+	return nil
+}
+
 // GetAuditInfoForIssues returns an array of AuditableToken for each issue action
 // It takes a deserializer, an array of serialized issue actions and an array of issue metadata.
 func GetAuditInfoForIssues(issues [][]byte, metadata []driver.IssueMetadata) ([][]*AuditableToken, error) {
@@ -427,7 +451,7 @@ func GetAuditInfoForTransfers(transfers [][]byte, metadata []driver.TransferMeta
 func commit(vector []*math.Zr, generators []*math.G1, c *math.Curve) *math.G1 {
 	com := c.NewG1()
 	for i := range vector {
-		com.Add(generators[i].Mul(vector[i]))
+		com.Add(common.MulG1(generators[i], vector[i]))
 	}
 	return com
 }
@@ -0,0 +1,229 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"sync"
+
+	math "github.com/IBM/mathlib"
+	"github.com/pkg/errors"
+)
+
+// supportedWindows are the window sizes, in bits, of the fixed-base tables, from the largest
+var supportedWindows = []int{8, 4, 2}
+
+// FixedBaseTable multiplies a generator by a scalar with additions of precomputed multiples of the generator only.
+// For a window of w bits, the table holds, for each window i of the scalar, the points j*2^(w*i)*G, for j in [1, 2^w).
+type FixedBaseTable interface {
+	// Mul returns k*G
+	Mul(k *math.Zr) *math.G1
+	// Size returns the number of points in the table
+	Size() int
+}
+
+// scalarBytes is the size of the serialized scalars of the supported curves
+const scalarBytes = 32
+
+// NewFixedBaseTable precomputes the multiples of the passed generator.
+// The points are computed and added with the group operations of mathlib, then any of its curves is supported.
+func NewFixedBaseTable(g *math.G1, window int) (FixedBaseTable, error) {
+	if window < 1 || window > 8 || 8%window != 0 {
+		return nil, errors.Errorf("invalid window size [%d], it must divide 8", window)
+	}
+	if g == nil || g.IsInfinity() {
+		return nil, errors.New("invalid generator")
+	}
+	windows := scalarBytes * 8 / window
+	entries := 1<<window - 1
+	t := &fixedBaseTable{g: g, window: window, points: make([][]*math.G1, windows)}
+	base := g.Copy()
+	for i := 0; i < windows; i++ {
+		row := make([]*math.G1, entries)
+		row[0] = base
+		for j := 1; j < entries; j++ {
+			row[j] = row[j-1].Copy()
+			row[j].Add(base)
+		}
+		// the base of the next window is 2^w times the base of this window
+		base = row[entries-1].Copy()
+		base.Add(row[0])
+		t.points[i] = row
+	}
+	return t, nil
+}
+
+// forEachDigit calls f with the index and the value of each non-zero window of the passed big-endian scalar,
+// starting from the least significant one
+func forEachDigit(raw []byte, window int, f func(i int, digit byte)) {
+	perByte := 8 / window
+	mask := byte(1<<window - 1)
+	i := 0
+	for b := len(raw) - 1; b >= 0; b-- {
+		for s := 0; s < perByte; s++ {
+			if digit := (raw[b] >> (s * window)) & mask; digit != 0 {
+				f(i, digit)
+			}
+			i++
+		}
+	}
+}
+
+type fixedBaseTable struct {
+	g      *math.G1
+	window int
+	points [][]*math.G1
+}
+
+func (t *fixedBaseTable) Mul(k *math.Zr) *math.G1 {
+	raw := k.Bytes()
+	if len(raw) > scalarBytes {
+		// not expected, the scalars of the supported curves fit
+		return t.g.Mul(k)
+	}
+	var acc *math.G1
+	forEachDigit(raw, t.window, func(i int, digit byte) {
+		if acc == nil {
+			acc = t.points[i][digit-1].Copy()
+			return
+		}
+		acc.Add(t.points[i][digit-1])
+	})
+	if acc == nil {
+		// k is zero
+		return t.g.Mul(k)
+	}
+	return acc
+}
+
+func (t *fixedBaseTable) Size() int {
+	return len(t.points) * len(t.points[0])
+}
+
+// precomputation contains the fixed-base tables of the generators of some public parameters
+type precomputation struct {
+	tables map[*math.G1]FixedBaseTable
+	window int
+	// size is the estimated memory taken by the tables, in bytes
+	size int
+}
+
+var (
+	// precompLock serializes the changes to the precomputations
+	precompLock sync.Mutex
+	// precomputations are the tables of the public parameters, by hash of the public parameters
+	precomputations = map[string]*precomputation{}
+	// owners binds each owner of precomputations to the hash of the public parameters it uses
+	owners = map[string]string{}
+	// used is the estimated memory taken by all the precomputations, in bytes
+	used int
+
+	// tablesLock guards tables
+	tablesLock sync.RWMutex
+	// tables indexes the tables of all the precomputations by generator
+	tables = map[*math.G1]FixedBaseTable{}
+)
+
+// Precompute computes, unless already done, the fixed-base tables of the passed generators of the public parameters
+// with the passed hash, and binds them to the passed owner, for instance a TMS.
+// If the owner was bound to other public parameters, the tables of those are released, unless other owners use them.
+// The window is the largest that keeps the tables of all the public parameters within the passed memory budget, in bytes.
+// Therefore, the budget is global: the tables computed for other public parameters take part of it.
+// It returns the window chosen, 0 if no window fits the budget left.
+// The memory is estimated from the size of the serialized points.
+func Precompute(owner string, ppHash []byte, generators []*math.G1, budget int) (int, error) {
+	precompLock.Lock()
+	defer precompLock.Unlock()
+
+	key := string(ppHash)
+	if previous, ok := owners[owner]; ok && previous != key {
+		delete(owners, owner)
+		releaseUnused(previous)
+	}
+	if p, ok := precomputations[key]; ok {
+		owners[owner] = key
+		return p.window, nil
+	}
+	if len(generators) == 0 || budget <= 0 {
+		return 0, nil
+	}
+	pointBytes := len(generators[0].Bytes())
+	window, size := 0, 0
+	for _, w := range supportedWindows {
+		size = scalarBytes * 8 / w * (1<<w - 1) * pointBytes * len(generators)
+		if used+size <= budget {
+			window = w
+			break
+		}
+	}
+	if window == 0 {
+		return 0, nil
+	}
+	p := &precomputation{tables: map[*math.G1]FixedBaseTable{}, window: window, size: size}
+	for _, g := range generators {
+		t, err := NewFixedBaseTable(g, window)
+		if err != nil {
+			return 0, err
+		}
+		p.tables[g] = t
+	}
+
+	precomputations[key] = p
+	owners[owner] = key
+	used += p.size
+	tablesLock.Lock()
+	for g, t := range p.tables {
+		tables[g] = t
+	}
+	tablesLock.Unlock()
+	return window, nil
+}
+
+// ReleasePrecomputation unbinds the passed owner from its public parameters,
+// and releases their tables, unless other owners use them
+func ReleasePrecomputation(owner string) {
+	precompLock.Lock()
+	defer precompLock.Unlock()
+
+	key, ok := owners[owner]
+	if !ok {
+		return
+	}
+	delete(owners, owner)
+	releaseUnused(key)
+}
+
+// releaseUnused drops the tables of the public parameters with the passed hash, if no owner uses them.
+// It must be called with precompLock held.
+func releaseUnused(key string) {
+	for _, k := range owners {
+		if k == key {
+			return
+		}
+	}
+	p, ok := precomputations[key]
+	if !ok {
+		return
+	}
+	delete(precomputations, key)
+	used -= p.size
+	tablesLock.Lock()
+	for g := range p.tables {
+		delete(tables, g)
+	}
+	tablesLock.Unlock()
+}
+
+// MulG1 returns k*g, using the fixed-base table of g, if precomputed
+func MulG1(g *math.G1, k *math.Zr) *math.G1 {
+	tablesLock.RLock()
+	t, ok := tables[g]
+	tablesLock.RUnlock()
+	if ok {
+		return t.Mul(k)
+	}
+	return g.Mul(k)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	math "github.com/IBM/mathlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixedBaseTable(t *testing.T) {
+	for _, curveID := range []math.CurveID{math.FP256BN_AMCL, math.BN254, math.BLS12_381_GURVY, math.BLS12_381_BBS_GURVY} {
+		c := math.Curves[curveID]
+		g := c.GenG1.Mul(c.NewRandomZr(rand.Reader))
+		scalars := []*math.Zr{c.NewZrFromInt(0), c.NewZrFromInt(1), c.NewZrFromInt(255), c.NewZrFromInt(256)}
+		minusOne := c.NewZrFromInt(1)
+		minusOne.Neg()
+		scalars = append(scalars, minusOne)
+		for i := 0; i < 10; i++ {
+			scalars = append(scalars, c.NewRandomZr(rand.Reader))
+		}
+		for _, window := range supportedWindows {
+			table, err := NewFixedBaseTable(g, window)
+			assert.NoError(t, err)
+			for _, k := range scalars {
+				assert.True(t, g.Mul(k).Equals(table.Mul(k)), "curve [%d], window [%d], scalar [%s]", curveID, window, k)
+			}
+		}
+		_, err := NewFixedBaseTable(g, 3)
+		assert.Error(t, err)
+	}
+}
+
+func TestPrecompute(t *testing.T) {
+	c := math.Curves[math.BN254]
+	newGenerators := func() []*math.G1 {
+		return []*math.G1{c.GenG1.Mul(c.NewRandomZr(rand.Reader)), c.GenG1.Mul(c.NewRandomZr(rand.Reader))}
+	}
+	hasTable := func(g *math.G1) bool {
+		tablesLock.RLock()
+		defer tablesLock.RUnlock()
+		_, ok := tables[g]
+		return ok
+	}
+	generators := newGenerators()
+	defer ReleasePrecomputation("tms1")
+	defer ReleasePrecomputation("tms2")
+
+	// no window fits
+	window, err := Precompute("tms1", []byte("pp1"), generators, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, window)
+	assert.False(t, hasTable(generators[0]))
+
+	const budget = 3 * 1024 * 1024 / 2
+	window, err = Precompute("tms1", []byte("pp1"), generators, budget)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, window)
+	k := c.NewRandomZr(rand.Reader)
+	for _, g := range generators {
+		assert.True(t, hasTable(g))
+		assert.True(t, g.Mul(k).Equals(MulG1(g, k)))
+	}
+
+	// the tables are shared by the owners of the same public parameters
+	window, err = Precompute("tms2", []byte("pp1"), newGenerators(), budget)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, window)
+	assert.Len(t, precomputations, 1)
+
+	// the budget is global, other public parameters get what is left
+	others := newGenerators()
+	window, err = Precompute("tms2", []byte("pp2"), others, budget)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, window)
+	assert.True(t, hasTable(others[0]))
+	// pp1 is still used by tms1
+	assert.True(t, hasTable(generators[0]))
+
+	// when the public parameters of tms1 change, the tables of pp1 are released
+	updated := newGenerators()
+	window, err = Precompute("tms1", []byte("pp3"), updated, budget)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, window)
+	assert.False(t, hasTable(generators[0]))
+	assert.True(t, hasTable(updated[0]))
+	assert.True(t, generators[0].Mul(k).Equals(MulG1(generators[0], k)))
+
+	ReleasePrecomputation("tms2")
+	assert.False(t, hasTable(others[0]))
+	ReleasePrecomputation("tms1")
+	assert.False(t, hasTable(updated[0]))
+	assert.Empty(t, precomputations)
+	assert.Equal(t, 0, used)
+}
+
+func BenchmarkMulG1(b *testing.B) {
+	for _, curveID := range []math.CurveID{math.BN254, math.BLS12_381_BBS_GURVY} {
+		c := math.Curves[curveID]
+		g := c.GenG1.Mul(c.NewRandomZr(rand.Reader))
+		k := c.NewRandomZr(rand.Reader)
+		b.Run(fmt.Sprintf("curve-%d/cold", curveID), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				MulG1(g, k)
+			}
+		})
+		for _, window := range supportedWindows {
+			table, err := NewFixedBaseTable(g, window)
+			assert.NoError(b, err)
+			b.Run(fmt.Sprintf("curve-%d/precomputed-w%d", curveID, window), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					table.Mul(k)
+				}
+			})
+		}
+	}
+}
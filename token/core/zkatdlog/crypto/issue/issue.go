@@ -11,6 +11,7 @@ import (
 
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/rp"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -154,14 +155,14 @@ func NewProver(tw []*token.TokenDataWitness, tokens []*math.G1, pp *crypto.Publi
 	c := math.Curves[pp.Curve]
 	p := &Prover{}
 	tokenType := c.HashToZr([]byte(tw[0].Type))
-	commitmentToType := pp.PedersenGenerators[0].Mul(tokenType)
+	commitmentToType := common.MulG1(pp.PedersenGenerators[0], tokenType)
 
 	rand, err := c.Rand()
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot get issue prover")
 	}
 	typeBF := c.NewRandomZr(rand)
-	commitmentToType.Add(pp.PedersenGenerators[2].Mul(typeBF))
+	commitmentToType.Add(common.MulG1(pp.PedersenGenerators[2], typeBF))
 	p.SameType = NewSameTypeProver(tw[0].Type, typeBF, commitmentToType, pp.PedersenGenerators, c)
 
 	values := make([]uint64, len(tw))
@@ -247,13 +247,13 @@ func (v *rangeVerifier) Verify(rp *RangeProof) error {
 	polEval = v.Curve.ModSub(polEval, zCube, v.Curve.GroupOrder)
 
 	// com is should be equal to v.Commitment^{z^2} if p.Value falls within range
-	com := v.CommitmentGenerators[0].Mul(rp.InnerProduct)
-	com.Add(v.CommitmentGenerators[1].Mul(rp.Tau))
+	com := common.MulG1(v.CommitmentGenerators[0], rp.InnerProduct)
+	com.Add(common.MulG1(v.CommitmentGenerators[1], rp.Tau))
 	com.Sub(rp.T1.Mul(x))
 	com.Sub(rp.T2.Mul(xSquare))
 
 	comPrime := v.Commitment.Mul(zSquare)
-	comPrime.Add(v.CommitmentGenerators[0].Mul(polEval))
+	comPrime.Add(common.MulG1(v.CommitmentGenerators[0], polEval))
 
 	if !com.Equals(comPrime) {
 		return errors.New("invalid range proof")
@@ -350,15 +350,15 @@ func (p *rangeProver) preprocess() ([]*math.Zr, []*math.Zr, *math.Zr, *RangeProo
 	t1 = p.Curve.ModAdd(t1, innerProduct(zPrime, randomLeft, p.Curve), p.Curve.GroupOrder)
 	// commit to t1
 	tau1 := p.Curve.NewRandomZr(rand)
-	T1 := p.CommitmentGenerators[0].Mul(t1)
-	T1.Add(p.CommitmentGenerators[1].Mul(tau1))
+	T1 := common.MulG1(p.CommitmentGenerators[0], t1)
+	T1.Add(common.MulG1(p.CommitmentGenerators[1], tau1))
 
 	// compute = \sum y^iU_iV_i
 	t2 := innerProduct(randomLeft, randRightPrime, p.Curve)
 	// commit to t2
 	tau2 := p.Curve.NewRandomZr(rand)
-	T2 := p.CommitmentGenerators[0].Mul(t2)
-	T2.Add(p.CommitmentGenerators[1].Mul(tau2))
+	T2 := common.MulG1(p.CommitmentGenerators[0], t2)
+	T2.Add(common.MulG1(p.CommitmentGenerators[1], tau2))
 
 	// compute challenge x
 	array = common.GetG1Array([]*math.G1{T1, T2})
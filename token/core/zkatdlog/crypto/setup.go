@@ -98,6 +98,15 @@ type PublicParams struct {
 	MaxToken uint64
 	// QuantityPrecision is the precision used to represent quantities
 	QuantityPrecision uint64
+	// MaxInputs is the maximum number of inputs a token request can spend, 0 means no limit
+	MaxInputs uint64 `json:",omitempty"`
+	// MaxOutputs is the maximum number of outputs a token request can create, 0 means no limit
+	MaxOutputs uint64 `json:",omitempty"`
+	// Redemptions are the periods of time during which tokens can be redeemed, empty means at any time.
+	// Token types are hidden, therefore, the windows cannot be specific to a token type.
+	Redemptions driver.RedemptionWindows `json:",omitempty"`
+	// Hashing is the hash algorithm of the public parameters and of the token requests, SHA256 if empty
+	Hashing driver.HashAlgorithm `json:",omitempty"`
 }
 
 func Setup(bitLength int, idemixIssuerPK []byte, idemixCurveID mathlib.CurveID) (*PublicParams, error) {
@@ -146,10 +155,35 @@ func (pp *PublicParams) MaxTokenValue() uint64 {
 	return pp.MaxToken
 }
 
+func (pp *PublicParams) MaxInputsPerRequest() uint64 {
+	return pp.MaxInputs
+}
+
+func (pp *PublicParams) MaxOutputsPerRequest() uint64 {
+	return pp.MaxOutputs
+}
+
+func (pp *PublicParams) RedemptionWindows() driver.RedemptionWindows {
+	return pp.Redemptions
+}
+
+func (pp *PublicParams) HashAlgorithm() driver.HashAlgorithm {
+	return pp.Hashing
+}
+
 func (pp *PublicParams) Bytes() ([]byte, error) {
 	return pp.Serialize()
 }
 
+// IssuerIDs returns the identities allowed to issue tokens
+func (pp *PublicParams) IssuerIDs() []driver.Identity {
+	ids := make([]driver.Identity, len(pp.Issuers))
+	for i, issuer := range pp.Issuers {
+		ids[i] = issuer
+	}
+	return ids
+}
+
 func (pp *PublicParams) Auditors() []driver.Identity {
 	if len(pp.Auditor) == 0 {
 		return []driver.Identity{}
@@ -293,6 +327,15 @@ func (pp *PublicParams) Validate() error {
 	if maxToken != pp.MaxToken {
 		return errors.Errorf("invalid maxt token, [%d]!=[%d]", maxToken, pp.MaxToken)
 	}
+	if pp.Redemptions.Typed() {
+		return errors.New("invalid public parameters: redemption windows cannot be specific to a token type, token types are hidden")
+	}
+	if err := pp.Redemptions.Validate(); err != nil {
+		return errors.Wrap(err, "invalid public parameters")
+	}
+	if !pp.Hashing.Available() {
+		return errors.Errorf("invalid public parameters: hash algorithm [%s] not available", pp.Hashing)
+	}
 	//if len(pp.Issuers) == 0 {
 	//	return errors.New("invalid public parameters: empty list of issuers")
 	//}
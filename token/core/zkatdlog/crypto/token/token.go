@@ -11,6 +11,7 @@ import (
 
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 )
@@ -157,7 +158,7 @@ func commit(vector []*math.Zr, generators []*math.G1, c *math.Curve) (*math.G1,
 		if vector[i] == nil {
 			return nil, errors.New("cannot commit a nil element")
 		}
-		com.Add(generators[i].Mul(vector[i]))
+		com.Add(common.MulG1(generators[i], vector[i]))
 	}
 	return com, nil
 }
@@ -12,6 +12,7 @@ import (
 
 	math "github.com/IBM/mathlib"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/rp"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/pkg/errors"
@@ -54,7 +55,7 @@ func NewProver(inputWitness, outputWitness []*token.TokenDataWitness, inputs, ou
 	values := make([]uint64, len(outputWitness))
 	blindingFactors := make([]*math.Zr, len(outputWitness))
 	// commit to the type of inputs and outputs
-	commitmentToType := pp.PedersenGenerators[0].Mul(c.HashToZr([]byte(inputWitness[0].Type)))
+	commitmentToType := common.MulG1(pp.PedersenGenerators[0], c.HashToZr([]byte(inputWitness[0].Type)))
 
 	rand, err := c.Rand()
 	if err != nil {
@@ -69,7 +70,7 @@ func NewProver(inputWitness, outputWitness []*token.TokenDataWitness, inputs, ou
 		values[i] = outW[i].Value
 		blindingFactors[i] = c.ModSub(outW[i].BlindingFactor, typeBF, c.GroupOrder)
 	}
-	commitmentToType.Add(pp.PedersenGenerators[2].Mul(typeBF))
+	commitmentToType.Add(common.MulG1(pp.PedersenGenerators[2], typeBF))
 
 	p.TypeAndSum = NewTypeAndSumProver(NewTypeAndSumWitness(typeBF, inW, outW, c), pp.PedersenGenerators, inputs, outputs, commitmentToType, c)
 	// check if this is an ownership transfer
@@ -53,6 +53,7 @@ func New(logger logging.Logger, pp *crypto.PublicParams, deserializer driver.Des
 		TransferSignatureValidate,
 		TransferZKProofValidate,
 		TransferHTLCValidate,
+		TransferRedemptionWindowValidate,
 	}
 	transferValidators = append(transferValidators, extraValidators...)
 
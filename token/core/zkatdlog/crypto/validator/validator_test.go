@@ -41,6 +41,7 @@ import (
 	msp2 "github.com/hyperledger/fabric/msp"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/trace/noop"
 )
 
@@ -176,6 +177,40 @@ var _ = Describe("validator", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(len(actions)).To(Equal(1))
 			})
+			It("fails when the request spends more inputs than allowed", func() {
+				pp.MaxInputs = 1
+				_, _, err := engine.VerifyTokenRequestFromRaw(context.TODO(), getState, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("too many inputs [2], max [1]"))
+			})
+			It("fails when the request creates more outputs than allowed", func() {
+				pp.MaxOutputs = 1
+				_, _, err := engine.VerifyTokenRequestFromRaw(context.TODO(), getState, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("too many outputs"))
+			})
+			It("dry runs before the signatures are collected", func() {
+				unsigned, err := asn1.Marshal(driver.TokenRequest{Transfers: tr.Transfers})
+				Expect(err).NotTo(HaveOccurred())
+				actions, missing, err := engine.DryRunTokenRequestFromRaw(context.TODO(), getState, "1", unsigned)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(actions)).To(Equal(1))
+				// the auditor and the owners of the two inputs
+				Expect(missing).To(HaveLen(3))
+				Expect(missing[0]).To(BeEquivalentTo(pp.Auditor))
+
+				_, missing, err = engine.DryRunTokenRequestFromRaw(context.TODO(), getState, "1", raw)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(missing).To(BeEmpty())
+			})
+			It("dry runs fail on policy violations", func() {
+				pp.MaxInputs = 1
+				unsigned, err := asn1.Marshal(driver.TokenRequest{Transfers: tr.Transfers})
+				Expect(err).NotTo(HaveOccurred())
+				_, _, err = engine.DryRunTokenRequestFromRaw(context.TODO(), getState, "1", unsigned)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("too many inputs [2], max [1]"))
+			})
 		})
 		Context("validator is called correctly with a redeem action", func() {
 			var (
@@ -211,6 +246,26 @@ var _ = Describe("validator", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(len(actions)).To(Equal(1))
 			})
+			It("succeeds within the redemption window", func() {
+				// the window is checked at the timestamp of the transaction, not at the local time
+				timestamp := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+				pp.Redemptions = driver.RedemptionWindows{{NotBefore: timestamp.Add(-time.Hour), NotAfter: timestamp.Add(time.Hour)}}
+				_, _, err := engine.VerifyTokenRequestFromRaw(driver.WithTxTimestamp(context.TODO(), timestamp), getState, "1", raw)
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("fails outside the redemption window", func() {
+				timestamp := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+				pp.Redemptions = driver.RedemptionWindows{{NotBefore: timestamp.Add(time.Hour)}}
+				_, _, err := engine.VerifyTokenRequestFromRaw(driver.WithTxTimestamp(context.TODO(), timestamp), getState, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(errors.Is(err, driver.ErrRedemptionNotAllowed)).To(BeTrue())
+			})
+			It("fails without the timestamp of the transaction", func() {
+				pp.Redemptions = driver.RedemptionWindows{{NotBefore: time.Now().Add(-time.Hour)}}
+				_, _, err := engine.VerifyTokenRequestFromRaw(context.TODO(), getState, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("transaction timestamp not available"))
+			})
 		})
 		Context("enginve is called correctly with atomic swap", func() {
 			var (
@@ -128,3 +128,29 @@ func TransferHTLCValidate(ctx *Context) error {
 	}
 	return nil
 }
+
+// TransferRedemptionWindowValidate checks that the redeemed tokens, if any, can be redeemed at the timestamp of the transaction.
+// Token types are hidden, therefore, only the windows applying to all token types are checked.
+func TransferRedemptionWindowValidate(ctx *Context) error {
+	windows := ctx.PP.RedemptionWindows()
+	if len(windows) == 0 {
+		return nil
+	}
+	for _, o := range ctx.TransferAction.GetOutputs() {
+		out, ok := o.(*token.Token)
+		if !ok {
+			return errors.Errorf("invalid output")
+		}
+		if !out.IsRedeem() {
+			continue
+		}
+		timestamp, err := ctx.TxTimestamp()
+		if err != nil {
+			return errors.WithMessagef(err, "cannot check the redemption windows")
+		}
+		if err := windows.Check("", timestamp); err != nil {
+			return errors.WithMessagef(err, "invalid redeem")
+		}
+	}
+	return nil
+}
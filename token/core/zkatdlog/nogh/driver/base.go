@@ -10,6 +10,7 @@ import (
 	view3 "github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	common3 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator"
 	zkatdlog "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -126,3 +127,42 @@ func (d *base) newWalletService(
 		nil,
 	), nil
 }
+
+// PrecomputationConfig is the configuration of the fixed-base tables of the Pedersen generators
+type PrecomputationConfig struct {
+	// MemoryBudget is the memory, in MiB, the tables can take. If zero, no table is computed.
+	MemoryBudget int `yaml:"memoryBudget,omitempty"`
+}
+
+// precompute computes the fixed-base tables of the Pedersen generators of the passed public parameters for the passed TMS,
+// if the configuration under the key `zkatdlog.precomputation` assigns a memory budget.
+// The tables are bound to the hash of the public parameters: when the public parameters of the TMS change,
+// the tables of the previous ones are released.
+func (d *base) precompute(logger logging.Logger, tmsID driver.TMSID, tmsConfig driver.Config, pp *crypto.PublicParams) error {
+	config := &PrecomputationConfig{}
+	if err := tmsConfig.UnmarshalKey("zkatdlog.precomputation", config); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal precomputation configuration")
+	}
+	if config.MemoryBudget <= 0 {
+		common3.ReleasePrecomputation(tmsID.String())
+		return nil
+	}
+	raw, err := pp.Bytes()
+	if err != nil {
+		return errors.Wrapf(err, "failed to serialize public parameters")
+	}
+	ppHash, err := driver.HashAlgorithmOf(pp).Hash(raw)
+	if err != nil {
+		return errors.Wrapf(err, "failed to hash public parameters")
+	}
+	window, err := common3.Precompute(tmsID.String(), ppHash, pp.PedersenGenerators, config.MemoryBudget<<20)
+	if err != nil {
+		return errors.Wrapf(err, "failed to precompute the Pedersen generators")
+	}
+	if window == 0 {
+		logger.Warnf("memory budget of [%d] MiB too small to precompute the Pedersen generators", config.MemoryBudget)
+		return nil
+	}
+	logger.Infof("Pedersen generators precomputed with window [%d]", window)
+	return nil
+}
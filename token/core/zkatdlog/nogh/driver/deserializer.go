@@ -12,10 +12,12 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/deserializer"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/interop/htlc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/interop/predicate"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/msp"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/msp/idemix"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/msp/x509"
 	htlc2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
+	predicate2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/predicate"
 	"github.com/pkg/errors"
 )
 
@@ -36,6 +38,7 @@ func NewDeserializer(pp *crypto.PublicParams) (*Deserializer, error) {
 	m := deserializer.NewTypedVerifierDeserializerMultiplex(idemixDes)
 	m.AddTypedVerifierDeserializer(msp.IdemixIdentity, deserializer.NewTypedIdentityVerifierDeserializer(idemixDes))
 	m.AddTypedVerifierDeserializer(htlc2.ScriptType, htlc.NewTypedIdentityDeserializer(m))
+	m.AddTypedVerifierDeserializer(predicate2.ScriptType, predicate.NewTypedIdentityDeserializer(m, msp.IdemixIdentity, idemixDes))
 
 	return &Deserializer{
 		Deserializer: common.NewDeserializer(
@@ -81,5 +84,6 @@ func NewEIDRHDeserializer() *EIDRHDeserializer {
 	d := deserializer.NewEIDRHDeserializer()
 	d.AddDeserializer(msp.IdemixIdentity, &idemix.AuditInfoDeserializer{})
 	d.AddDeserializer(htlc2.ScriptType, htlc.NewAuditDeserializer(&idemix.AuditInfoDeserializer{}))
+	d.AddDeserializer(predicate2.ScriptType, &idemix.AuditInfoDeserializer{})
 	return d
 }
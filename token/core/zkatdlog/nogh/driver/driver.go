@@ -22,6 +22,7 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/config"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/predicate"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/trace"
@@ -96,6 +97,9 @@ func (d *Driver) NewTokenService(_ driver.ServiceProvider, networkID string, cha
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to initiliaze public params manager")
 	}
+	if err := d.precompute(logger, driver.TMSID{Network: networkID, Channel: channel, Namespace: namespace}, tmsConfig, ppm.PublicParams()); err != nil {
+		return nil, err
+	}
 
 	qe := v.QueryEngine()
 	ws, err := d.newWalletService(tmsConfig, d.endpointService, d.storageProvider, qe, logger, d.identityProvider.DefaultIdentity(), networkLocalMembership.DefaultIdentity(), ppm.PublicParams(), false)
@@ -109,6 +113,7 @@ func (d *Driver) NewTokenService(_ driver.ServiceProvider, networkID string, cha
 	authorization := common.NewAuthorizationMultiplexer(
 		common.NewTMSAuthorization(logger, ppm.PublicParams(), ws),
 		htlc.NewScriptAuth(ws),
+		predicate.NewScriptAuth(ws),
 	)
 
 	metricsProvider := metrics.NewTMSProvider(tmsConfig.ID(), d.metricsProvider)
@@ -165,6 +170,14 @@ func (d *Driver) NewValidator(_ driver.ServiceProvider, tmsID driver.TMSID, para
 		return nil, errors.Errorf("invalid public parameters type [%T]", params)
 	}
 
+	// the validator might run for a TMS that is not configured locally, in that case no table is computed
+	if tmsConfig, err := d.configService.ConfigurationFor(tmsID.Network, tmsID.Channel, tmsID.Namespace); err == nil {
+		logger := logging.DriverLogger("token-sdk.driver.zkatdlog", tmsID.Network, tmsID.Channel, tmsID.Namespace)
+		if err := d.precompute(logger, tmsID, tmsConfig, pp); err != nil {
+			return nil, err
+		}
+	}
+
 	defaultValidator, err := d.DefaultValidator(pp)
 	if err != nil {
 		return nil, err
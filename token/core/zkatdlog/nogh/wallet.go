@@ -75,6 +75,9 @@ func (w *WalletFactory) NewWallet(role driver.IdentityRole, walletRegistry commo
 			return nil, errors.WithMessagef(err, "failed to get issuer wallet identity for [%s]", id)
 		}
 		newWallet := common.NewIssuerWallet(w.Logger, w.IdentityProvider, w.TokenVault, id, idInfoIdentity)
+		newWallet.Binder = func(identity driver.Identity) error {
+			return walletRegistry.BindIdentity(identity, info.EnrollmentID(), id, nil)
+		}
 		if err := walletRegistry.BindIdentity(idInfoIdentity, info.EnrollmentID(), id, nil); err != nil {
 			return nil, errors.WithMessagef(err, "programming error, failed to register recipient identity [%s]", id)
 		}
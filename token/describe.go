@@ -0,0 +1,165 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// RequestDescription is a structured, human-readable breakdown of a token request.
+// Identities are reported by their unique identifier. Enrollment IDs, token types, and amounts
+// are reported only when the metadata available to this node permits to see them.
+type RequestDescription struct {
+	// Anchor is the anchor of the request
+	Anchor string `json:"anchor"`
+	// Issues describes the issue actions
+	Issues []*ActionDescription `json:"issues,omitempty"`
+	// Transfers describes the transfer actions
+	Transfers []*ActionDescription `json:"transfers,omitempty"`
+	// Signatures is the number of signatures collected so far
+	Signatures int `json:"signatures"`
+	// AuditorSignatures is the number of auditor signatures collected so far
+	AuditorSignatures int `json:"auditor_signatures"`
+	// ApplicationMetadataKeys lists the keys of the application metadata, sorted
+	ApplicationMetadataKeys []string `json:"application_metadata_keys,omitempty"`
+}
+
+// ActionDescription describes a single issue or transfer action
+type ActionDescription struct {
+	// Index is the index of the action among the actions of the same kind
+	Index int `json:"index"`
+	// Issuer is the issuer of an issue action
+	Issuer string `json:"issuer,omitempty"`
+	// Senders are the owners of the inputs of a transfer action
+	Senders []string `json:"senders,omitempty"`
+	// ExtraSigners are the additional identities that must sign the request
+	ExtraSigners []string `json:"extra_signers,omitempty"`
+	// Inputs are the inputs spent by the action
+	Inputs []*InputDescription `json:"inputs,omitempty"`
+	// Outputs are the outputs created by the action
+	Outputs []*OutputDescription `json:"outputs,omitempty"`
+	// AnonymityLevel is the anonymity level recorded for a transfer action, if any
+	AnonymityLevel string `json:"anonymity_level,omitempty"`
+}
+
+// InputDescription describes an input of a transfer action
+type InputDescription struct {
+	ID           string `json:"id,omitempty"`
+	Owner        string `json:"owner"`
+	EnrollmentID string `json:"enrollment_id,omitempty"`
+}
+
+// OutputDescription describes an output of an action
+type OutputDescription struct {
+	Index        uint64 `json:"index"`
+	Owner        string `json:"owner,omitempty"`
+	EnrollmentID string `json:"enrollment_id,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Quantity     string `json:"quantity,omitempty"`
+	// Redeem is true if the output redeems tokens
+	Redeem bool `json:"redeem,omitempty"`
+}
+
+// String returns the indented JSON representation of the description
+func (d *RequestDescription) String() string {
+	raw, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(raw)
+}
+
+// Describe returns a structured, human-readable breakdown of the request.
+// Inputs and outputs whose metadata has been filtered out are omitted.
+func (r *Request) Describe() (*RequestDescription, error) {
+	inputs, outputs, err := r.InputsAndOutputs()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting inputs and outputs of request [%s]", r.Anchor)
+	}
+
+	d := &RequestDescription{Anchor: r.Anchor}
+	if r.Actions != nil {
+		d.Signatures = len(r.Actions.Signatures)
+		d.AuditorSignatures = len(r.Actions.AuditorSignatures)
+	}
+	if r.Metadata != nil {
+		for k := range r.Metadata.Application {
+			d.ApplicationMetadataKeys = append(d.ApplicationMetadataKeys, k)
+		}
+		sort.Strings(d.ApplicationMetadataKeys)
+	}
+
+	// issue outputs come first in the output stream
+	numIssueOutputs := uint64(0)
+	for i, issue := range r.Issues() {
+		numIssueOutputs += uint64(len(issue.Receivers))
+		d.Issues = append(d.Issues, &ActionDescription{
+			Index:        i,
+			Issuer:       issue.Issuer.String(),
+			ExtraSigners: identityStrings(issue.ExtraSigners),
+		})
+	}
+	for i, transfer := range r.Transfers() {
+		action := &ActionDescription{
+			Index:        i,
+			Senders:      identityStrings(transfer.Senders),
+			ExtraSigners: identityStrings(transfer.ExtraSigners),
+		}
+		if level := r.TransferAnonymityLevel(i); level != DefaultAnonymity {
+			action.AnonymityLevel = level.String()
+		}
+		d.Transfers = append(d.Transfers, action)
+	}
+
+	for _, input := range inputs.Inputs() {
+		if input.ActionIndex >= len(d.Transfers) {
+			continue
+		}
+		in := &InputDescription{
+			Owner:        input.Owner.String(),
+			EnrollmentID: input.EnrollmentID,
+		}
+		if input.Id != nil {
+			in.ID = input.Id.String()
+		}
+		d.Transfers[input.ActionIndex].Inputs = append(d.Transfers[input.ActionIndex].Inputs, in)
+	}
+	for _, output := range outputs.Outputs() {
+		out := &OutputDescription{
+			Index:        output.Index,
+			EnrollmentID: output.EnrollmentID,
+			Type:         output.Type,
+			Redeem:       output.Owner.IsNone(),
+		}
+		if !output.Owner.IsNone() {
+			out.Owner = output.Owner.String()
+		}
+		if output.Quantity != nil {
+			out.Quantity = output.Quantity.Decimal()
+		}
+		actions := d.Transfers
+		if output.Index < numIssueOutputs {
+			actions = d.Issues
+		}
+		if output.ActionIndex >= len(actions) {
+			continue
+		}
+		actions[output.ActionIndex].Outputs = append(actions[output.ActionIndex].Outputs, out)
+	}
+	return d, nil
+}
+
+func identityStrings(ids []Identity) []string {
+	var res []string
+	for _, id := range ids {
+		res = append(res, id.String())
+	}
+	return res
+}
@@ -0,0 +1,107 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package driver
+
+import (
+	"crypto/sha256"
+	"hash"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// HashAlgorithm identifies the hash function used to compute the hash of the public parameters and of the token requests.
+// The identifier is serialized in the public parameters, therefore, all the parties of a network use the same function.
+type HashAlgorithm string
+
+const (
+	// SHA256 is the default hash algorithm
+	SHA256 HashAlgorithm = "sha256"
+)
+
+var (
+	hashFunctionsLock sync.RWMutex
+	hashFunctions     = map[HashAlgorithm]func() hash.Hash{
+		SHA256: sha256.New,
+	}
+)
+
+// RegisterHashAlgorithm makes available the passed hash function under the passed identifier,
+// for instance, SM3 in the deployments with national-crypto requirements.
+// It must be called by all the parties of a network, validators included, before the public parameters are loaded.
+func RegisterHashAlgorithm(algorithm HashAlgorithm, newHash func() hash.Hash) error {
+	if len(algorithm) == 0 {
+		return errors.New("hash algorithm identifier must be specified")
+	}
+	if newHash == nil {
+		return errors.Errorf("hash function of [%s] must be specified", algorithm)
+	}
+	hashFunctionsLock.Lock()
+	defer hashFunctionsLock.Unlock()
+	if _, ok := hashFunctions[algorithm]; ok {
+		return errors.Errorf("hash algorithm [%s] already registered", algorithm)
+	}
+	hashFunctions[algorithm] = newHash
+	return nil
+}
+
+// Available returns true if the hash function of this algorithm is registered.
+// The empty algorithm is SHA256.
+func (a HashAlgorithm) Available() bool {
+	hashFunctionsLock.RLock()
+	defer hashFunctionsLock.RUnlock()
+	_, ok := hashFunctions[a.orDefault()]
+	return ok
+}
+
+// New returns a new instance of the hash function of this algorithm
+func (a HashAlgorithm) New() (hash.Hash, error) {
+	hashFunctionsLock.RLock()
+	newHash, ok := hashFunctions[a.orDefault()]
+	hashFunctionsLock.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("hash algorithm [%s] not available", a)
+	}
+	return newHash(), nil
+}
+
+// Hash returns the hash of the passed bytes, nil if there are no bytes
+func (a HashAlgorithm) Hash(raw []byte) ([]byte, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	h, err := a.New()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := h.Write(raw); err != nil {
+		return nil, errors.Wrapf(err, "failed to compute [%s] hash", a)
+	}
+	return h.Sum(nil), nil
+}
+
+func (a HashAlgorithm) orDefault() HashAlgorithm {
+	if len(a) == 0 {
+		return SHA256
+	}
+	return a
+}
+
+// HashingPublicParameters is implemented by the public parameters that select the hash algorithm.
+// The public parameters that do not implement it use SHA256.
+type HashingPublicParameters interface {
+	// HashAlgorithm returns the hash algorithm of the public parameters and of the token requests
+	HashAlgorithm() HashAlgorithm
+}
+
+// HashAlgorithmOf returns the hash algorithm selected by the passed public parameters, SHA256 by default
+func HashAlgorithmOf(pp PublicParameters) HashAlgorithm {
+	if hpp, ok := pp.(HashingPublicParameters); ok {
+		return hpp.HashAlgorithm().orDefault()
+	}
+	return SHA256
+}
@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package driver
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type hashingPP struct {
+	PublicParameters
+	algorithm HashAlgorithm
+}
+
+func (pp *hashingPP) HashAlgorithm() HashAlgorithm {
+	return pp.algorithm
+}
+
+func TestHashAlgorithm(t *testing.T) {
+	raw := []byte("public parameters")
+	expected := sha256.Sum256(raw)
+
+	// the empty algorithm is SHA256
+	h, err := HashAlgorithm("").Hash(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, expected[:], h)
+	h, err = SHA256.Hash(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, h)
+
+	// an algorithm must be registered before use
+	algorithm := HashAlgorithm("sha384-test")
+	assert.False(t, algorithm.Available())
+	_, err = algorithm.Hash(raw)
+	assert.EqualError(t, err, "hash algorithm [sha384-test] not available")
+	assert.NoError(t, RegisterHashAlgorithm(algorithm, sha512.New384))
+	assert.Error(t, RegisterHashAlgorithm(algorithm, sha512.New384))
+	assert.Error(t, RegisterHashAlgorithm("", sha512.New384))
+	assert.True(t, algorithm.Available())
+	h, err = algorithm.Hash(raw)
+	assert.NoError(t, err)
+	expected384 := sha512.Sum384(raw)
+	assert.Equal(t, expected384[:], h)
+
+	// the public parameters select the algorithm
+	assert.Equal(t, SHA256, HashAlgorithmOf(nil))
+	assert.Equal(t, SHA256, HashAlgorithmOf(&hashingPP{}))
+	assert.Equal(t, algorithm, HashAlgorithmOf(&hashingPP{algorithm: algorithm}))
+}
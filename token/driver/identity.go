@@ -49,6 +49,18 @@ type IdentityInfo interface {
 	Get() (Identity, []byte, error)
 }
 
+// RecipientIdentity models a recipient identity learned from a counterparty
+type RecipientIdentity struct {
+	// Identity is the recipient identity
+	Identity Identity
+	// EnrollmentID is the enrollment ID extracted from the audit info of the identity
+	EnrollmentID string
+	// WalletID is the identifier of the counterparty wallet the identity has been obtained from, if known
+	WalletID string
+	// AuditInfo is the audit info bound to the identity
+	AuditInfo []byte
+}
+
 //go:generate counterfeiter -o mock/ip.go -fake-name IdentityProvider . IdentityProvider
 
 // IdentityProvider manages identity-related concepts like signature signers, verifiers, audit information, and so on.
@@ -86,4 +98,11 @@ type IdentityProvider interface {
 
 	// RegisterRecipientIdentity register the passed identity as a third-party recipient identity.
 	RegisterRecipientIdentity(id Identity) error
+
+	// BindRecipientIdentity persists the passed third-party recipient identity together with the enrollment ID
+	// extracted from its audit info, and binds it to the passed counterparty wallet, if not empty.
+	BindRecipientIdentity(id Identity, walletID string) error
+
+	// RecipientIdentities returns the third-party recipient identities persisted for the passed enrollment ID
+	RecipientIdentities(enrollmentID string) ([]RecipientIdentity, error)
 }
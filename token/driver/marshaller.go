@@ -11,6 +11,7 @@ import (
 	"sort"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
 )
 
 func Marshal(v interface{}) ([]byte, error) {
@@ -47,6 +48,16 @@ func UnmarshalMeta(raw []byte) (map[string][]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(metaSer.Keys) != len(metaSer.Vals) {
+		return nil, errors.Errorf("number of keys [%d] and values [%d] do not match", len(metaSer.Keys), len(metaSer.Vals))
+	}
+	// MarshalMeta sorts the keys, any other order, or a duplicate key, would give a different serialization
+	// of the same map
+	for i := 1; i < len(metaSer.Keys); i++ {
+		if metaSer.Keys[i-1] >= metaSer.Keys[i] {
+			return nil, errors.Errorf("keys not sorted or duplicated at [%s]", metaSer.Keys[i])
+		}
+	}
 	v := make(map[string][]byte, len(metaSer.Keys))
 	for i, k := range metaSer.Keys {
 		v[k] = metaSer.Vals[i]
@@ -4,16 +4,15 @@ package mock
 import (
 	"sync"
 
-	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 )
 
 type IdentityProvider struct {
-	BindStub        func(view.Identity, view.Identity, bool) error
+	BindStub        func(driver.Identity, driver.Identity, bool) error
 	bindMutex       sync.RWMutex
 	bindArgsForCall []struct {
-		arg1 view.Identity
-		arg2 view.Identity
+		arg1 driver.Identity
+		arg2 driver.Identity
 		arg3 bool
 	}
 	bindReturns struct {
@@ -22,10 +21,22 @@ type IdentityProvider struct {
 	bindReturnsOnCall map[int]struct {
 		result1 error
 	}
-	GetAuditInfoStub        func(view.Identity) ([]byte, error)
+	BindRecipientIdentityStub        func(driver.Identity, string) error
+	bindRecipientIdentityMutex       sync.RWMutex
+	bindRecipientIdentityArgsForCall []struct {
+		arg1 driver.Identity
+		arg2 string
+	}
+	bindRecipientIdentityReturns struct {
+		result1 error
+	}
+	bindRecipientIdentityReturnsOnCall map[int]struct {
+		result1 error
+	}
+	GetAuditInfoStub        func(driver.Identity) ([]byte, error)
 	getAuditInfoMutex       sync.RWMutex
 	getAuditInfoArgsForCall []struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 	}
 	getAuditInfoReturns struct {
 		result1 []byte
@@ -35,10 +46,10 @@ type IdentityProvider struct {
 		result1 []byte
 		result2 error
 	}
-	GetEIDAndRHStub        func(view.Identity, []byte) (string, string, error)
+	GetEIDAndRHStub        func(driver.Identity, []byte) (string, string, error)
 	getEIDAndRHMutex       sync.RWMutex
 	getEIDAndRHArgsForCall []struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 		arg2 []byte
 	}
 	getEIDAndRHReturns struct {
@@ -51,10 +62,10 @@ type IdentityProvider struct {
 		result2 string
 		result3 error
 	}
-	GetEnrollmentIDStub        func(view.Identity, []byte) (string, error)
+	GetEnrollmentIDStub        func(driver.Identity, []byte) (string, error)
 	getEnrollmentIDMutex       sync.RWMutex
 	getEnrollmentIDArgsForCall []struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 		arg2 []byte
 	}
 	getEnrollmentIDReturns struct {
@@ -65,10 +76,10 @@ type IdentityProvider struct {
 		result1 string
 		result2 error
 	}
-	GetRevocationHandlerStub        func(view.Identity, []byte) (string, error)
+	GetRevocationHandlerStub        func(driver.Identity, []byte) (string, error)
 	getRevocationHandlerMutex       sync.RWMutex
 	getRevocationHandlerArgsForCall []struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 		arg2 []byte
 	}
 	getRevocationHandlerReturns struct {
@@ -79,10 +90,10 @@ type IdentityProvider struct {
 		result1 string
 		result2 error
 	}
-	GetSignerStub        func(view.Identity) (driver.Signer, error)
+	GetSignerStub        func(driver.Identity) (driver.Signer, error)
 	getSignerMutex       sync.RWMutex
 	getSignerArgsForCall []struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 	}
 	getSignerReturns struct {
 		result1 driver.Signer
@@ -92,10 +103,10 @@ type IdentityProvider struct {
 		result1 driver.Signer
 		result2 error
 	}
-	IsMeStub        func(view.Identity) bool
+	IsMeStub        func(driver.Identity) bool
 	isMeMutex       sync.RWMutex
 	isMeArgsForCall []struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 	}
 	isMeReturns struct {
 		result1 bool
@@ -103,6 +114,19 @@ type IdentityProvider struct {
 	isMeReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	RecipientIdentitiesStub        func(string) ([]driver.RecipientIdentity, error)
+	recipientIdentitiesMutex       sync.RWMutex
+	recipientIdentitiesArgsForCall []struct {
+		arg1 string
+	}
+	recipientIdentitiesReturns struct {
+		result1 []driver.RecipientIdentity
+		result2 error
+	}
+	recipientIdentitiesReturnsOnCall map[int]struct {
+		result1 []driver.RecipientIdentity
+		result2 error
+	}
 	RegisterRecipientDataStub        func(*driver.RecipientData) error
 	registerRecipientDataMutex       sync.RWMutex
 	registerRecipientDataArgsForCall []struct {
@@ -114,10 +138,10 @@ type IdentityProvider struct {
 	registerRecipientDataReturnsOnCall map[int]struct {
 		result1 error
 	}
-	RegisterRecipientIdentityStub        func(view.Identity) error
+	RegisterRecipientIdentityStub        func(driver.Identity) error
 	registerRecipientIdentityMutex       sync.RWMutex
 	registerRecipientIdentityArgsForCall []struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 	}
 	registerRecipientIdentityReturns struct {
 		result1 error
@@ -125,10 +149,10 @@ type IdentityProvider struct {
 	registerRecipientIdentityReturnsOnCall map[int]struct {
 		result1 error
 	}
-	RegisterSignerStub        func(view.Identity, driver.Signer, driver.Verifier, []byte) error
+	RegisterSignerStub        func(driver.Identity, driver.Signer, driver.Verifier, []byte) error
 	registerSignerMutex       sync.RWMutex
 	registerSignerArgsForCall []struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 		arg2 driver.Signer
 		arg3 driver.Verifier
 		arg4 []byte
@@ -139,10 +163,10 @@ type IdentityProvider struct {
 	registerSignerReturnsOnCall map[int]struct {
 		result1 error
 	}
-	RegisterVerifierStub        func(view.Identity, driver.Verifier) error
+	RegisterVerifierStub        func(driver.Identity, driver.Verifier) error
 	registerVerifierMutex       sync.RWMutex
 	registerVerifierArgsForCall []struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 		arg2 driver.Verifier
 	}
 	registerVerifierReturns struct {
@@ -155,12 +179,12 @@ type IdentityProvider struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *IdentityProvider) Bind(arg1 view.Identity, arg2 view.Identity, arg3 bool) error {
+func (fake *IdentityProvider) Bind(arg1 driver.Identity, arg2 driver.Identity, arg3 bool) error {
 	fake.bindMutex.Lock()
 	ret, specificReturn := fake.bindReturnsOnCall[len(fake.bindArgsForCall)]
 	fake.bindArgsForCall = append(fake.bindArgsForCall, struct {
-		arg1 view.Identity
-		arg2 view.Identity
+		arg1 driver.Identity
+		arg2 driver.Identity
 		arg3 bool
 	}{arg1, arg2, arg3})
 	stub := fake.BindStub
@@ -182,13 +206,13 @@ func (fake *IdentityProvider) BindCallCount() int {
 	return len(fake.bindArgsForCall)
 }
 
-func (fake *IdentityProvider) BindCalls(stub func(view.Identity, view.Identity, bool) error) {
+func (fake *IdentityProvider) BindCalls(stub func(driver.Identity, driver.Identity, bool) error) {
 	fake.bindMutex.Lock()
 	defer fake.bindMutex.Unlock()
 	fake.BindStub = stub
 }
 
-func (fake *IdentityProvider) BindArgsForCall(i int) (view.Identity, view.Identity, bool) {
+func (fake *IdentityProvider) BindArgsForCall(i int) (driver.Identity, driver.Identity, bool) {
 	fake.bindMutex.RLock()
 	defer fake.bindMutex.RUnlock()
 	argsForCall := fake.bindArgsForCall[i]
@@ -218,11 +242,73 @@ func (fake *IdentityProvider) BindReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *IdentityProvider) GetAuditInfo(arg1 view.Identity) ([]byte, error) {
+func (fake *IdentityProvider) BindRecipientIdentity(arg1 driver.Identity, arg2 string) error {
+	fake.bindRecipientIdentityMutex.Lock()
+	ret, specificReturn := fake.bindRecipientIdentityReturnsOnCall[len(fake.bindRecipientIdentityArgsForCall)]
+	fake.bindRecipientIdentityArgsForCall = append(fake.bindRecipientIdentityArgsForCall, struct {
+		arg1 driver.Identity
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.BindRecipientIdentityStub
+	fakeReturns := fake.bindRecipientIdentityReturns
+	fake.recordInvocation("BindRecipientIdentity", []interface{}{arg1, arg2})
+	fake.bindRecipientIdentityMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *IdentityProvider) BindRecipientIdentityCallCount() int {
+	fake.bindRecipientIdentityMutex.RLock()
+	defer fake.bindRecipientIdentityMutex.RUnlock()
+	return len(fake.bindRecipientIdentityArgsForCall)
+}
+
+func (fake *IdentityProvider) BindRecipientIdentityCalls(stub func(driver.Identity, string) error) {
+	fake.bindRecipientIdentityMutex.Lock()
+	defer fake.bindRecipientIdentityMutex.Unlock()
+	fake.BindRecipientIdentityStub = stub
+}
+
+func (fake *IdentityProvider) BindRecipientIdentityArgsForCall(i int) (driver.Identity, string) {
+	fake.bindRecipientIdentityMutex.RLock()
+	defer fake.bindRecipientIdentityMutex.RUnlock()
+	argsForCall := fake.bindRecipientIdentityArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *IdentityProvider) BindRecipientIdentityReturns(result1 error) {
+	fake.bindRecipientIdentityMutex.Lock()
+	defer fake.bindRecipientIdentityMutex.Unlock()
+	fake.BindRecipientIdentityStub = nil
+	fake.bindRecipientIdentityReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *IdentityProvider) BindRecipientIdentityReturnsOnCall(i int, result1 error) {
+	fake.bindRecipientIdentityMutex.Lock()
+	defer fake.bindRecipientIdentityMutex.Unlock()
+	fake.BindRecipientIdentityStub = nil
+	if fake.bindRecipientIdentityReturnsOnCall == nil {
+		fake.bindRecipientIdentityReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.bindRecipientIdentityReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *IdentityProvider) GetAuditInfo(arg1 driver.Identity) ([]byte, error) {
 	fake.getAuditInfoMutex.Lock()
 	ret, specificReturn := fake.getAuditInfoReturnsOnCall[len(fake.getAuditInfoArgsForCall)]
 	fake.getAuditInfoArgsForCall = append(fake.getAuditInfoArgsForCall, struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 	}{arg1})
 	stub := fake.GetAuditInfoStub
 	fakeReturns := fake.getAuditInfoReturns
@@ -243,13 +329,13 @@ func (fake *IdentityProvider) GetAuditInfoCallCount() int {
 	return len(fake.getAuditInfoArgsForCall)
 }
 
-func (fake *IdentityProvider) GetAuditInfoCalls(stub func(view.Identity) ([]byte, error)) {
+func (fake *IdentityProvider) GetAuditInfoCalls(stub func(driver.Identity) ([]byte, error)) {
 	fake.getAuditInfoMutex.Lock()
 	defer fake.getAuditInfoMutex.Unlock()
 	fake.GetAuditInfoStub = stub
 }
 
-func (fake *IdentityProvider) GetAuditInfoArgsForCall(i int) view.Identity {
+func (fake *IdentityProvider) GetAuditInfoArgsForCall(i int) driver.Identity {
 	fake.getAuditInfoMutex.RLock()
 	defer fake.getAuditInfoMutex.RUnlock()
 	argsForCall := fake.getAuditInfoArgsForCall[i]
@@ -282,7 +368,7 @@ func (fake *IdentityProvider) GetAuditInfoReturnsOnCall(i int, result1 []byte, r
 	}{result1, result2}
 }
 
-func (fake *IdentityProvider) GetEIDAndRH(arg1 view.Identity, arg2 []byte) (string, string, error) {
+func (fake *IdentityProvider) GetEIDAndRH(arg1 driver.Identity, arg2 []byte) (string, string, error) {
 	var arg2Copy []byte
 	if arg2 != nil {
 		arg2Copy = make([]byte, len(arg2))
@@ -291,7 +377,7 @@ func (fake *IdentityProvider) GetEIDAndRH(arg1 view.Identity, arg2 []byte) (stri
 	fake.getEIDAndRHMutex.Lock()
 	ret, specificReturn := fake.getEIDAndRHReturnsOnCall[len(fake.getEIDAndRHArgsForCall)]
 	fake.getEIDAndRHArgsForCall = append(fake.getEIDAndRHArgsForCall, struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 		arg2 []byte
 	}{arg1, arg2Copy})
 	stub := fake.GetEIDAndRHStub
@@ -313,13 +399,13 @@ func (fake *IdentityProvider) GetEIDAndRHCallCount() int {
 	return len(fake.getEIDAndRHArgsForCall)
 }
 
-func (fake *IdentityProvider) GetEIDAndRHCalls(stub func(view.Identity, []byte) (string, string, error)) {
+func (fake *IdentityProvider) GetEIDAndRHCalls(stub func(driver.Identity, []byte) (string, string, error)) {
 	fake.getEIDAndRHMutex.Lock()
 	defer fake.getEIDAndRHMutex.Unlock()
 	fake.GetEIDAndRHStub = stub
 }
 
-func (fake *IdentityProvider) GetEIDAndRHArgsForCall(i int) (view.Identity, []byte) {
+func (fake *IdentityProvider) GetEIDAndRHArgsForCall(i int) (driver.Identity, []byte) {
 	fake.getEIDAndRHMutex.RLock()
 	defer fake.getEIDAndRHMutex.RUnlock()
 	argsForCall := fake.getEIDAndRHArgsForCall[i]
@@ -355,7 +441,7 @@ func (fake *IdentityProvider) GetEIDAndRHReturnsOnCall(i int, result1 string, re
 	}{result1, result2, result3}
 }
 
-func (fake *IdentityProvider) GetEnrollmentID(arg1 view.Identity, arg2 []byte) (string, error) {
+func (fake *IdentityProvider) GetEnrollmentID(arg1 driver.Identity, arg2 []byte) (string, error) {
 	var arg2Copy []byte
 	if arg2 != nil {
 		arg2Copy = make([]byte, len(arg2))
@@ -364,7 +450,7 @@ func (fake *IdentityProvider) GetEnrollmentID(arg1 view.Identity, arg2 []byte) (
 	fake.getEnrollmentIDMutex.Lock()
 	ret, specificReturn := fake.getEnrollmentIDReturnsOnCall[len(fake.getEnrollmentIDArgsForCall)]
 	fake.getEnrollmentIDArgsForCall = append(fake.getEnrollmentIDArgsForCall, struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 		arg2 []byte
 	}{arg1, arg2Copy})
 	stub := fake.GetEnrollmentIDStub
@@ -386,13 +472,13 @@ func (fake *IdentityProvider) GetEnrollmentIDCallCount() int {
 	return len(fake.getEnrollmentIDArgsForCall)
 }
 
-func (fake *IdentityProvider) GetEnrollmentIDCalls(stub func(view.Identity, []byte) (string, error)) {
+func (fake *IdentityProvider) GetEnrollmentIDCalls(stub func(driver.Identity, []byte) (string, error)) {
 	fake.getEnrollmentIDMutex.Lock()
 	defer fake.getEnrollmentIDMutex.Unlock()
 	fake.GetEnrollmentIDStub = stub
 }
 
-func (fake *IdentityProvider) GetEnrollmentIDArgsForCall(i int) (view.Identity, []byte) {
+func (fake *IdentityProvider) GetEnrollmentIDArgsForCall(i int) (driver.Identity, []byte) {
 	fake.getEnrollmentIDMutex.RLock()
 	defer fake.getEnrollmentIDMutex.RUnlock()
 	argsForCall := fake.getEnrollmentIDArgsForCall[i]
@@ -425,7 +511,7 @@ func (fake *IdentityProvider) GetEnrollmentIDReturnsOnCall(i int, result1 string
 	}{result1, result2}
 }
 
-func (fake *IdentityProvider) GetRevocationHandler(arg1 view.Identity, arg2 []byte) (string, error) {
+func (fake *IdentityProvider) GetRevocationHandler(arg1 driver.Identity, arg2 []byte) (string, error) {
 	var arg2Copy []byte
 	if arg2 != nil {
 		arg2Copy = make([]byte, len(arg2))
@@ -434,7 +520,7 @@ func (fake *IdentityProvider) GetRevocationHandler(arg1 view.Identity, arg2 []by
 	fake.getRevocationHandlerMutex.Lock()
 	ret, specificReturn := fake.getRevocationHandlerReturnsOnCall[len(fake.getRevocationHandlerArgsForCall)]
 	fake.getRevocationHandlerArgsForCall = append(fake.getRevocationHandlerArgsForCall, struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 		arg2 []byte
 	}{arg1, arg2Copy})
 	stub := fake.GetRevocationHandlerStub
@@ -456,13 +542,13 @@ func (fake *IdentityProvider) GetRevocationHandlerCallCount() int {
 	return len(fake.getRevocationHandlerArgsForCall)
 }
 
-func (fake *IdentityProvider) GetRevocationHandlerCalls(stub func(view.Identity, []byte) (string, error)) {
+func (fake *IdentityProvider) GetRevocationHandlerCalls(stub func(driver.Identity, []byte) (string, error)) {
 	fake.getRevocationHandlerMutex.Lock()
 	defer fake.getRevocationHandlerMutex.Unlock()
 	fake.GetRevocationHandlerStub = stub
 }
 
-func (fake *IdentityProvider) GetRevocationHandlerArgsForCall(i int) (view.Identity, []byte) {
+func (fake *IdentityProvider) GetRevocationHandlerArgsForCall(i int) (driver.Identity, []byte) {
 	fake.getRevocationHandlerMutex.RLock()
 	defer fake.getRevocationHandlerMutex.RUnlock()
 	argsForCall := fake.getRevocationHandlerArgsForCall[i]
@@ -495,11 +581,11 @@ func (fake *IdentityProvider) GetRevocationHandlerReturnsOnCall(i int, result1 s
 	}{result1, result2}
 }
 
-func (fake *IdentityProvider) GetSigner(arg1 view.Identity) (driver.Signer, error) {
+func (fake *IdentityProvider) GetSigner(arg1 driver.Identity) (driver.Signer, error) {
 	fake.getSignerMutex.Lock()
 	ret, specificReturn := fake.getSignerReturnsOnCall[len(fake.getSignerArgsForCall)]
 	fake.getSignerArgsForCall = append(fake.getSignerArgsForCall, struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 	}{arg1})
 	stub := fake.GetSignerStub
 	fakeReturns := fake.getSignerReturns
@@ -520,13 +606,13 @@ func (fake *IdentityProvider) GetSignerCallCount() int {
 	return len(fake.getSignerArgsForCall)
 }
 
-func (fake *IdentityProvider) GetSignerCalls(stub func(view.Identity) (driver.Signer, error)) {
+func (fake *IdentityProvider) GetSignerCalls(stub func(driver.Identity) (driver.Signer, error)) {
 	fake.getSignerMutex.Lock()
 	defer fake.getSignerMutex.Unlock()
 	fake.GetSignerStub = stub
 }
 
-func (fake *IdentityProvider) GetSignerArgsForCall(i int) view.Identity {
+func (fake *IdentityProvider) GetSignerArgsForCall(i int) driver.Identity {
 	fake.getSignerMutex.RLock()
 	defer fake.getSignerMutex.RUnlock()
 	argsForCall := fake.getSignerArgsForCall[i]
@@ -559,11 +645,11 @@ func (fake *IdentityProvider) GetSignerReturnsOnCall(i int, result1 driver.Signe
 	}{result1, result2}
 }
 
-func (fake *IdentityProvider) IsMe(arg1 view.Identity) bool {
+func (fake *IdentityProvider) IsMe(arg1 driver.Identity) bool {
 	fake.isMeMutex.Lock()
 	ret, specificReturn := fake.isMeReturnsOnCall[len(fake.isMeArgsForCall)]
 	fake.isMeArgsForCall = append(fake.isMeArgsForCall, struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 	}{arg1})
 	stub := fake.IsMeStub
 	fakeReturns := fake.isMeReturns
@@ -584,13 +670,13 @@ func (fake *IdentityProvider) IsMeCallCount() int {
 	return len(fake.isMeArgsForCall)
 }
 
-func (fake *IdentityProvider) IsMeCalls(stub func(view.Identity) bool) {
+func (fake *IdentityProvider) IsMeCalls(stub func(driver.Identity) bool) {
 	fake.isMeMutex.Lock()
 	defer fake.isMeMutex.Unlock()
 	fake.IsMeStub = stub
 }
 
-func (fake *IdentityProvider) IsMeArgsForCall(i int) view.Identity {
+func (fake *IdentityProvider) IsMeArgsForCall(i int) driver.Identity {
 	fake.isMeMutex.RLock()
 	defer fake.isMeMutex.RUnlock()
 	argsForCall := fake.isMeArgsForCall[i]
@@ -620,6 +706,70 @@ func (fake *IdentityProvider) IsMeReturnsOnCall(i int, result1 bool) {
 	}{result1}
 }
 
+func (fake *IdentityProvider) RecipientIdentities(arg1 string) ([]driver.RecipientIdentity, error) {
+	fake.recipientIdentitiesMutex.Lock()
+	ret, specificReturn := fake.recipientIdentitiesReturnsOnCall[len(fake.recipientIdentitiesArgsForCall)]
+	fake.recipientIdentitiesArgsForCall = append(fake.recipientIdentitiesArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.RecipientIdentitiesStub
+	fakeReturns := fake.recipientIdentitiesReturns
+	fake.recordInvocation("RecipientIdentities", []interface{}{arg1})
+	fake.recipientIdentitiesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *IdentityProvider) RecipientIdentitiesCallCount() int {
+	fake.recipientIdentitiesMutex.RLock()
+	defer fake.recipientIdentitiesMutex.RUnlock()
+	return len(fake.recipientIdentitiesArgsForCall)
+}
+
+func (fake *IdentityProvider) RecipientIdentitiesCalls(stub func(string) ([]driver.RecipientIdentity, error)) {
+	fake.recipientIdentitiesMutex.Lock()
+	defer fake.recipientIdentitiesMutex.Unlock()
+	fake.RecipientIdentitiesStub = stub
+}
+
+func (fake *IdentityProvider) RecipientIdentitiesArgsForCall(i int) string {
+	fake.recipientIdentitiesMutex.RLock()
+	defer fake.recipientIdentitiesMutex.RUnlock()
+	argsForCall := fake.recipientIdentitiesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *IdentityProvider) RecipientIdentitiesReturns(result1 []driver.RecipientIdentity, result2 error) {
+	fake.recipientIdentitiesMutex.Lock()
+	defer fake.recipientIdentitiesMutex.Unlock()
+	fake.RecipientIdentitiesStub = nil
+	fake.recipientIdentitiesReturns = struct {
+		result1 []driver.RecipientIdentity
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *IdentityProvider) RecipientIdentitiesReturnsOnCall(i int, result1 []driver.RecipientIdentity, result2 error) {
+	fake.recipientIdentitiesMutex.Lock()
+	defer fake.recipientIdentitiesMutex.Unlock()
+	fake.RecipientIdentitiesStub = nil
+	if fake.recipientIdentitiesReturnsOnCall == nil {
+		fake.recipientIdentitiesReturnsOnCall = make(map[int]struct {
+			result1 []driver.RecipientIdentity
+			result2 error
+		})
+	}
+	fake.recipientIdentitiesReturnsOnCall[i] = struct {
+		result1 []driver.RecipientIdentity
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *IdentityProvider) RegisterRecipientData(arg1 *driver.RecipientData) error {
 	fake.registerRecipientDataMutex.Lock()
 	ret, specificReturn := fake.registerRecipientDataReturnsOnCall[len(fake.registerRecipientDataArgsForCall)]
@@ -681,11 +831,11 @@ func (fake *IdentityProvider) RegisterRecipientDataReturnsOnCall(i int, result1
 	}{result1}
 }
 
-func (fake *IdentityProvider) RegisterRecipientIdentity(arg1 view.Identity) error {
+func (fake *IdentityProvider) RegisterRecipientIdentity(arg1 driver.Identity) error {
 	fake.registerRecipientIdentityMutex.Lock()
 	ret, specificReturn := fake.registerRecipientIdentityReturnsOnCall[len(fake.registerRecipientIdentityArgsForCall)]
 	fake.registerRecipientIdentityArgsForCall = append(fake.registerRecipientIdentityArgsForCall, struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 	}{arg1})
 	stub := fake.RegisterRecipientIdentityStub
 	fakeReturns := fake.registerRecipientIdentityReturns
@@ -706,13 +856,13 @@ func (fake *IdentityProvider) RegisterRecipientIdentityCallCount() int {
 	return len(fake.registerRecipientIdentityArgsForCall)
 }
 
-func (fake *IdentityProvider) RegisterRecipientIdentityCalls(stub func(view.Identity) error) {
+func (fake *IdentityProvider) RegisterRecipientIdentityCalls(stub func(driver.Identity) error) {
 	fake.registerRecipientIdentityMutex.Lock()
 	defer fake.registerRecipientIdentityMutex.Unlock()
 	fake.RegisterRecipientIdentityStub = stub
 }
 
-func (fake *IdentityProvider) RegisterRecipientIdentityArgsForCall(i int) view.Identity {
+func (fake *IdentityProvider) RegisterRecipientIdentityArgsForCall(i int) driver.Identity {
 	fake.registerRecipientIdentityMutex.RLock()
 	defer fake.registerRecipientIdentityMutex.RUnlock()
 	argsForCall := fake.registerRecipientIdentityArgsForCall[i]
@@ -742,7 +892,7 @@ func (fake *IdentityProvider) RegisterRecipientIdentityReturnsOnCall(i int, resu
 	}{result1}
 }
 
-func (fake *IdentityProvider) RegisterSigner(arg1 view.Identity, arg2 driver.Signer, arg3 driver.Verifier, arg4 []byte) error {
+func (fake *IdentityProvider) RegisterSigner(arg1 driver.Identity, arg2 driver.Signer, arg3 driver.Verifier, arg4 []byte) error {
 	var arg4Copy []byte
 	if arg4 != nil {
 		arg4Copy = make([]byte, len(arg4))
@@ -751,7 +901,7 @@ func (fake *IdentityProvider) RegisterSigner(arg1 view.Identity, arg2 driver.Sig
 	fake.registerSignerMutex.Lock()
 	ret, specificReturn := fake.registerSignerReturnsOnCall[len(fake.registerSignerArgsForCall)]
 	fake.registerSignerArgsForCall = append(fake.registerSignerArgsForCall, struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 		arg2 driver.Signer
 		arg3 driver.Verifier
 		arg4 []byte
@@ -775,13 +925,13 @@ func (fake *IdentityProvider) RegisterSignerCallCount() int {
 	return len(fake.registerSignerArgsForCall)
 }
 
-func (fake *IdentityProvider) RegisterSignerCalls(stub func(view.Identity, driver.Signer, driver.Verifier, []byte) error) {
+func (fake *IdentityProvider) RegisterSignerCalls(stub func(driver.Identity, driver.Signer, driver.Verifier, []byte) error) {
 	fake.registerSignerMutex.Lock()
 	defer fake.registerSignerMutex.Unlock()
 	fake.RegisterSignerStub = stub
 }
 
-func (fake *IdentityProvider) RegisterSignerArgsForCall(i int) (view.Identity, driver.Signer, driver.Verifier, []byte) {
+func (fake *IdentityProvider) RegisterSignerArgsForCall(i int) (driver.Identity, driver.Signer, driver.Verifier, []byte) {
 	fake.registerSignerMutex.RLock()
 	defer fake.registerSignerMutex.RUnlock()
 	argsForCall := fake.registerSignerArgsForCall[i]
@@ -811,11 +961,11 @@ func (fake *IdentityProvider) RegisterSignerReturnsOnCall(i int, result1 error)
 	}{result1}
 }
 
-func (fake *IdentityProvider) RegisterVerifier(arg1 view.Identity, arg2 driver.Verifier) error {
+func (fake *IdentityProvider) RegisterVerifier(arg1 driver.Identity, arg2 driver.Verifier) error {
 	fake.registerVerifierMutex.Lock()
 	ret, specificReturn := fake.registerVerifierReturnsOnCall[len(fake.registerVerifierArgsForCall)]
 	fake.registerVerifierArgsForCall = append(fake.registerVerifierArgsForCall, struct {
-		arg1 view.Identity
+		arg1 driver.Identity
 		arg2 driver.Verifier
 	}{arg1, arg2})
 	stub := fake.RegisterVerifierStub
@@ -837,13 +987,13 @@ func (fake *IdentityProvider) RegisterVerifierCallCount() int {
 	return len(fake.registerVerifierArgsForCall)
 }
 
-func (fake *IdentityProvider) RegisterVerifierCalls(stub func(view.Identity, driver.Verifier) error) {
+func (fake *IdentityProvider) RegisterVerifierCalls(stub func(driver.Identity, driver.Verifier) error) {
 	fake.registerVerifierMutex.Lock()
 	defer fake.registerVerifierMutex.Unlock()
 	fake.RegisterVerifierStub = stub
 }
 
-func (fake *IdentityProvider) RegisterVerifierArgsForCall(i int) (view.Identity, driver.Verifier) {
+func (fake *IdentityProvider) RegisterVerifierArgsForCall(i int) (driver.Identity, driver.Verifier) {
 	fake.registerVerifierMutex.RLock()
 	defer fake.registerVerifierMutex.RUnlock()
 	argsForCall := fake.registerVerifierArgsForCall[i]
@@ -878,6 +1028,8 @@ func (fake *IdentityProvider) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.bindMutex.RLock()
 	defer fake.bindMutex.RUnlock()
+	fake.bindRecipientIdentityMutex.RLock()
+	defer fake.bindRecipientIdentityMutex.RUnlock()
 	fake.getAuditInfoMutex.RLock()
 	defer fake.getAuditInfoMutex.RUnlock()
 	fake.getEIDAndRHMutex.RLock()
@@ -890,6 +1042,8 @@ func (fake *IdentityProvider) Invocations() map[string][][]interface{} {
 	defer fake.getSignerMutex.RUnlock()
 	fake.isMeMutex.RLock()
 	defer fake.isMeMutex.RUnlock()
+	fake.recipientIdentitiesMutex.RLock()
+	defer fake.recipientIdentitiesMutex.RUnlock()
 	fake.registerRecipientDataMutex.RLock()
 	defer fake.registerRecipientDataMutex.RUnlock()
 	fake.registerRecipientIdentityMutex.RLock()
@@ -4,20 +4,19 @@ package mock
 import (
 	"sync"
 
-	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 )
 
 type PublicParameters struct {
-	AuditorsStub        func() []view.Identity
+	AuditorsStub        func() []driver.Identity
 	auditorsMutex       sync.RWMutex
 	auditorsArgsForCall []struct {
 	}
 	auditorsReturns struct {
-		result1 []view.Identity
+		result1 []driver.Identity
 	}
 	auditorsReturnsOnCall map[int]struct {
-		result1 []view.Identity
+		result1 []driver.Identity
 	}
 	BytesStub        func() ([]byte, error)
 	bytesMutex       sync.RWMutex
@@ -61,6 +60,26 @@ type PublicParameters struct {
 	identifierReturnsOnCall map[int]struct {
 		result1 string
 	}
+	MaxInputsPerRequestStub        func() uint64
+	maxInputsPerRequestMutex       sync.RWMutex
+	maxInputsPerRequestArgsForCall []struct {
+	}
+	maxInputsPerRequestReturns struct {
+		result1 uint64
+	}
+	maxInputsPerRequestReturnsOnCall map[int]struct {
+		result1 uint64
+	}
+	MaxOutputsPerRequestStub        func() uint64
+	maxOutputsPerRequestMutex       sync.RWMutex
+	maxOutputsPerRequestArgsForCall []struct {
+	}
+	maxOutputsPerRequestReturns struct {
+		result1 uint64
+	}
+	maxOutputsPerRequestReturnsOnCall map[int]struct {
+		result1 uint64
+	}
 	MaxTokenValueStub        func() uint64
 	maxTokenValueMutex       sync.RWMutex
 	maxTokenValueArgsForCall []struct {
@@ -81,6 +100,16 @@ type PublicParameters struct {
 	precisionReturnsOnCall map[int]struct {
 		result1 uint64
 	}
+	RedemptionWindowsStub        func() driver.RedemptionWindows
+	redemptionWindowsMutex       sync.RWMutex
+	redemptionWindowsArgsForCall []struct {
+	}
+	redemptionWindowsReturns struct {
+		result1 driver.RedemptionWindows
+	}
+	redemptionWindowsReturnsOnCall map[int]struct {
+		result1 driver.RedemptionWindows
+	}
 	SerializeStub        func() ([]byte, error)
 	serializeMutex       sync.RWMutex
 	serializeArgsForCall []struct {
@@ -127,7 +156,7 @@ type PublicParameters struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *PublicParameters) Auditors() []view.Identity {
+func (fake *PublicParameters) Auditors() []driver.Identity {
 	fake.auditorsMutex.Lock()
 	ret, specificReturn := fake.auditorsReturnsOnCall[len(fake.auditorsArgsForCall)]
 	fake.auditorsArgsForCall = append(fake.auditorsArgsForCall, struct {
@@ -151,32 +180,32 @@ func (fake *PublicParameters) AuditorsCallCount() int {
 	return len(fake.auditorsArgsForCall)
 }
 
-func (fake *PublicParameters) AuditorsCalls(stub func() []view.Identity) {
+func (fake *PublicParameters) AuditorsCalls(stub func() []driver.Identity) {
 	fake.auditorsMutex.Lock()
 	defer fake.auditorsMutex.Unlock()
 	fake.AuditorsStub = stub
 }
 
-func (fake *PublicParameters) AuditorsReturns(result1 []view.Identity) {
+func (fake *PublicParameters) AuditorsReturns(result1 []driver.Identity) {
 	fake.auditorsMutex.Lock()
 	defer fake.auditorsMutex.Unlock()
 	fake.AuditorsStub = nil
 	fake.auditorsReturns = struct {
-		result1 []view.Identity
+		result1 []driver.Identity
 	}{result1}
 }
 
-func (fake *PublicParameters) AuditorsReturnsOnCall(i int, result1 []view.Identity) {
+func (fake *PublicParameters) AuditorsReturnsOnCall(i int, result1 []driver.Identity) {
 	fake.auditorsMutex.Lock()
 	defer fake.auditorsMutex.Unlock()
 	fake.AuditorsStub = nil
 	if fake.auditorsReturnsOnCall == nil {
 		fake.auditorsReturnsOnCall = make(map[int]struct {
-			result1 []view.Identity
+			result1 []driver.Identity
 		})
 	}
 	fake.auditorsReturnsOnCall[i] = struct {
-		result1 []view.Identity
+		result1 []driver.Identity
 	}{result1}
 }
 
@@ -395,6 +424,112 @@ func (fake *PublicParameters) IdentifierReturnsOnCall(i int, result1 string) {
 	}{result1}
 }
 
+func (fake *PublicParameters) MaxInputsPerRequest() uint64 {
+	fake.maxInputsPerRequestMutex.Lock()
+	ret, specificReturn := fake.maxInputsPerRequestReturnsOnCall[len(fake.maxInputsPerRequestArgsForCall)]
+	fake.maxInputsPerRequestArgsForCall = append(fake.maxInputsPerRequestArgsForCall, struct {
+	}{})
+	stub := fake.MaxInputsPerRequestStub
+	fakeReturns := fake.maxInputsPerRequestReturns
+	fake.recordInvocation("MaxInputsPerRequest", []interface{}{})
+	fake.maxInputsPerRequestMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *PublicParameters) MaxInputsPerRequestCallCount() int {
+	fake.maxInputsPerRequestMutex.RLock()
+	defer fake.maxInputsPerRequestMutex.RUnlock()
+	return len(fake.maxInputsPerRequestArgsForCall)
+}
+
+func (fake *PublicParameters) MaxInputsPerRequestCalls(stub func() uint64) {
+	fake.maxInputsPerRequestMutex.Lock()
+	defer fake.maxInputsPerRequestMutex.Unlock()
+	fake.MaxInputsPerRequestStub = stub
+}
+
+func (fake *PublicParameters) MaxInputsPerRequestReturns(result1 uint64) {
+	fake.maxInputsPerRequestMutex.Lock()
+	defer fake.maxInputsPerRequestMutex.Unlock()
+	fake.MaxInputsPerRequestStub = nil
+	fake.maxInputsPerRequestReturns = struct {
+		result1 uint64
+	}{result1}
+}
+
+func (fake *PublicParameters) MaxInputsPerRequestReturnsOnCall(i int, result1 uint64) {
+	fake.maxInputsPerRequestMutex.Lock()
+	defer fake.maxInputsPerRequestMutex.Unlock()
+	fake.MaxInputsPerRequestStub = nil
+	if fake.maxInputsPerRequestReturnsOnCall == nil {
+		fake.maxInputsPerRequestReturnsOnCall = make(map[int]struct {
+			result1 uint64
+		})
+	}
+	fake.maxInputsPerRequestReturnsOnCall[i] = struct {
+		result1 uint64
+	}{result1}
+}
+
+func (fake *PublicParameters) MaxOutputsPerRequest() uint64 {
+	fake.maxOutputsPerRequestMutex.Lock()
+	ret, specificReturn := fake.maxOutputsPerRequestReturnsOnCall[len(fake.maxOutputsPerRequestArgsForCall)]
+	fake.maxOutputsPerRequestArgsForCall = append(fake.maxOutputsPerRequestArgsForCall, struct {
+	}{})
+	stub := fake.MaxOutputsPerRequestStub
+	fakeReturns := fake.maxOutputsPerRequestReturns
+	fake.recordInvocation("MaxOutputsPerRequest", []interface{}{})
+	fake.maxOutputsPerRequestMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *PublicParameters) MaxOutputsPerRequestCallCount() int {
+	fake.maxOutputsPerRequestMutex.RLock()
+	defer fake.maxOutputsPerRequestMutex.RUnlock()
+	return len(fake.maxOutputsPerRequestArgsForCall)
+}
+
+func (fake *PublicParameters) MaxOutputsPerRequestCalls(stub func() uint64) {
+	fake.maxOutputsPerRequestMutex.Lock()
+	defer fake.maxOutputsPerRequestMutex.Unlock()
+	fake.MaxOutputsPerRequestStub = stub
+}
+
+func (fake *PublicParameters) MaxOutputsPerRequestReturns(result1 uint64) {
+	fake.maxOutputsPerRequestMutex.Lock()
+	defer fake.maxOutputsPerRequestMutex.Unlock()
+	fake.MaxOutputsPerRequestStub = nil
+	fake.maxOutputsPerRequestReturns = struct {
+		result1 uint64
+	}{result1}
+}
+
+func (fake *PublicParameters) MaxOutputsPerRequestReturnsOnCall(i int, result1 uint64) {
+	fake.maxOutputsPerRequestMutex.Lock()
+	defer fake.maxOutputsPerRequestMutex.Unlock()
+	fake.MaxOutputsPerRequestStub = nil
+	if fake.maxOutputsPerRequestReturnsOnCall == nil {
+		fake.maxOutputsPerRequestReturnsOnCall = make(map[int]struct {
+			result1 uint64
+		})
+	}
+	fake.maxOutputsPerRequestReturnsOnCall[i] = struct {
+		result1 uint64
+	}{result1}
+}
+
 func (fake *PublicParameters) MaxTokenValue() uint64 {
 	fake.maxTokenValueMutex.Lock()
 	ret, specificReturn := fake.maxTokenValueReturnsOnCall[len(fake.maxTokenValueArgsForCall)]
@@ -501,6 +636,59 @@ func (fake *PublicParameters) PrecisionReturnsOnCall(i int, result1 uint64) {
 	}{result1}
 }
 
+func (fake *PublicParameters) RedemptionWindows() driver.RedemptionWindows {
+	fake.redemptionWindowsMutex.Lock()
+	ret, specificReturn := fake.redemptionWindowsReturnsOnCall[len(fake.redemptionWindowsArgsForCall)]
+	fake.redemptionWindowsArgsForCall = append(fake.redemptionWindowsArgsForCall, struct {
+	}{})
+	stub := fake.RedemptionWindowsStub
+	fakeReturns := fake.redemptionWindowsReturns
+	fake.recordInvocation("RedemptionWindows", []interface{}{})
+	fake.redemptionWindowsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *PublicParameters) RedemptionWindowsCallCount() int {
+	fake.redemptionWindowsMutex.RLock()
+	defer fake.redemptionWindowsMutex.RUnlock()
+	return len(fake.redemptionWindowsArgsForCall)
+}
+
+func (fake *PublicParameters) RedemptionWindowsCalls(stub func() driver.RedemptionWindows) {
+	fake.redemptionWindowsMutex.Lock()
+	defer fake.redemptionWindowsMutex.Unlock()
+	fake.RedemptionWindowsStub = stub
+}
+
+func (fake *PublicParameters) RedemptionWindowsReturns(result1 driver.RedemptionWindows) {
+	fake.redemptionWindowsMutex.Lock()
+	defer fake.redemptionWindowsMutex.Unlock()
+	fake.RedemptionWindowsStub = nil
+	fake.redemptionWindowsReturns = struct {
+		result1 driver.RedemptionWindows
+	}{result1}
+}
+
+func (fake *PublicParameters) RedemptionWindowsReturnsOnCall(i int, result1 driver.RedemptionWindows) {
+	fake.redemptionWindowsMutex.Lock()
+	defer fake.redemptionWindowsMutex.Unlock()
+	fake.RedemptionWindowsStub = nil
+	if fake.redemptionWindowsReturnsOnCall == nil {
+		fake.redemptionWindowsReturnsOnCall = make(map[int]struct {
+			result1 driver.RedemptionWindows
+		})
+	}
+	fake.redemptionWindowsReturnsOnCall[i] = struct {
+		result1 driver.RedemptionWindows
+	}{result1}
+}
+
 func (fake *PublicParameters) Serialize() ([]byte, error) {
 	fake.serializeMutex.Lock()
 	ret, specificReturn := fake.serializeReturnsOnCall[len(fake.serializeArgsForCall)]
@@ -729,10 +917,16 @@ func (fake *PublicParameters) Invocations() map[string][][]interface{} {
 	defer fake.graphHidingMutex.RUnlock()
 	fake.identifierMutex.RLock()
 	defer fake.identifierMutex.RUnlock()
+	fake.maxInputsPerRequestMutex.RLock()
+	defer fake.maxInputsPerRequestMutex.RUnlock()
+	fake.maxOutputsPerRequestMutex.RLock()
+	defer fake.maxOutputsPerRequestMutex.RUnlock()
 	fake.maxTokenValueMutex.RLock()
 	defer fake.maxTokenValueMutex.RUnlock()
 	fake.precisionMutex.RLock()
 	defer fake.precisionMutex.RUnlock()
+	fake.redemptionWindowsMutex.RLock()
+	defer fake.redemptionWindowsMutex.RUnlock()
 	fake.serializeMutex.RLock()
 	defer fake.serializeMutex.RUnlock()
 	fake.stringMutex.RLock()
@@ -24,18 +24,31 @@ type QueryEngine struct {
 		result1 uint64
 		result2 error
 	}
-	GetStatusStub        func(string) (int, string, error)
+	ExistAllStub        func([]*token.ID) ([]bool, error)
+	existAllMutex       sync.RWMutex
+	existAllArgsForCall []struct {
+		arg1 []*token.ID
+	}
+	existAllReturns struct {
+		result1 []bool
+		result2 error
+	}
+	existAllReturnsOnCall map[int]struct {
+		result1 []bool
+		result2 error
+	}
+	GetStatusStub        func(string) (driver.TxStatus, string, error)
 	getStatusMutex       sync.RWMutex
 	getStatusArgsForCall []struct {
 		arg1 string
 	}
 	getStatusReturns struct {
-		result1 int
+		result1 driver.TxStatus
 		result2 string
 		result3 error
 	}
 	getStatusReturnsOnCall map[int]struct {
-		result1 int
+		result1 driver.TxStatus
 		result2 string
 		result3 error
 	}
@@ -279,7 +292,76 @@ func (fake *QueryEngine) BalanceReturnsOnCall(i int, result1 uint64, result2 err
 	}{result1, result2}
 }
 
-func (fake *QueryEngine) GetStatus(arg1 string) (int, string, error) {
+func (fake *QueryEngine) ExistAll(arg1 []*token.ID) ([]bool, error) {
+	var arg1Copy []*token.ID
+	if arg1 != nil {
+		arg1Copy = make([]*token.ID, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.existAllMutex.Lock()
+	ret, specificReturn := fake.existAllReturnsOnCall[len(fake.existAllArgsForCall)]
+	fake.existAllArgsForCall = append(fake.existAllArgsForCall, struct {
+		arg1 []*token.ID
+	}{arg1Copy})
+	stub := fake.ExistAllStub
+	fakeReturns := fake.existAllReturns
+	fake.recordInvocation("ExistAll", []interface{}{arg1Copy})
+	fake.existAllMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *QueryEngine) ExistAllCallCount() int {
+	fake.existAllMutex.RLock()
+	defer fake.existAllMutex.RUnlock()
+	return len(fake.existAllArgsForCall)
+}
+
+func (fake *QueryEngine) ExistAllCalls(stub func([]*token.ID) ([]bool, error)) {
+	fake.existAllMutex.Lock()
+	defer fake.existAllMutex.Unlock()
+	fake.ExistAllStub = stub
+}
+
+func (fake *QueryEngine) ExistAllArgsForCall(i int) []*token.ID {
+	fake.existAllMutex.RLock()
+	defer fake.existAllMutex.RUnlock()
+	argsForCall := fake.existAllArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *QueryEngine) ExistAllReturns(result1 []bool, result2 error) {
+	fake.existAllMutex.Lock()
+	defer fake.existAllMutex.Unlock()
+	fake.ExistAllStub = nil
+	fake.existAllReturns = struct {
+		result1 []bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *QueryEngine) ExistAllReturnsOnCall(i int, result1 []bool, result2 error) {
+	fake.existAllMutex.Lock()
+	defer fake.existAllMutex.Unlock()
+	fake.ExistAllStub = nil
+	if fake.existAllReturnsOnCall == nil {
+		fake.existAllReturnsOnCall = make(map[int]struct {
+			result1 []bool
+			result2 error
+		})
+	}
+	fake.existAllReturnsOnCall[i] = struct {
+		result1 []bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *QueryEngine) GetStatus(arg1 string) (driver.TxStatus, string, error) {
 	fake.getStatusMutex.Lock()
 	ret, specificReturn := fake.getStatusReturnsOnCall[len(fake.getStatusArgsForCall)]
 	fake.getStatusArgsForCall = append(fake.getStatusArgsForCall, struct {
@@ -304,7 +386,7 @@ func (fake *QueryEngine) GetStatusCallCount() int {
 	return len(fake.getStatusArgsForCall)
 }
 
-func (fake *QueryEngine) GetStatusCalls(stub func(string) (int, string, error)) {
+func (fake *QueryEngine) GetStatusCalls(stub func(string) (driver.TxStatus, string, error)) {
 	fake.getStatusMutex.Lock()
 	defer fake.getStatusMutex.Unlock()
 	fake.GetStatusStub = stub
@@ -317,30 +399,30 @@ func (fake *QueryEngine) GetStatusArgsForCall(i int) string {
 	return argsForCall.arg1
 }
 
-func (fake *QueryEngine) GetStatusReturns(result1 int, result2 string, result3 error) {
+func (fake *QueryEngine) GetStatusReturns(result1 driver.TxStatus, result2 string, result3 error) {
 	fake.getStatusMutex.Lock()
 	defer fake.getStatusMutex.Unlock()
 	fake.GetStatusStub = nil
 	fake.getStatusReturns = struct {
-		result1 int
+		result1 driver.TxStatus
 		result2 string
 		result3 error
 	}{result1, result2, result3}
 }
 
-func (fake *QueryEngine) GetStatusReturnsOnCall(i int, result1 int, result2 string, result3 error) {
+func (fake *QueryEngine) GetStatusReturnsOnCall(i int, result1 driver.TxStatus, result2 string, result3 error) {
 	fake.getStatusMutex.Lock()
 	defer fake.getStatusMutex.Unlock()
 	fake.GetStatusStub = nil
 	if fake.getStatusReturnsOnCall == nil {
 		fake.getStatusReturnsOnCall = make(map[int]struct {
-			result1 int
+			result1 driver.TxStatus
 			result2 string
 			result3 error
 		})
 	}
 	fake.getStatusReturnsOnCall[i] = struct {
-		result1 int
+		result1 driver.TxStatus
 		result2 string
 		result3 error
 	}{result1, result2, result3}
@@ -1173,6 +1255,8 @@ func (fake *QueryEngine) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.balanceMutex.RLock()
 	defer fake.balanceMutex.RUnlock()
+	fake.existAllMutex.RLock()
+	defer fake.existAllMutex.RUnlock()
 	fake.getStatusMutex.RLock()
 	defer fake.getStatusMutex.RUnlock()
 	fake.getTokenInfoAndOutputsMutex.RLock()
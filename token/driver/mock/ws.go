@@ -119,6 +119,19 @@ type WalletService struct {
 		result1 driver.OwnerWallet
 		result2 error
 	}
+	OwnerIdentityConfigurationStub        func(string) (driver.IdentityConfiguration, error)
+	ownerIdentityConfigurationMutex       sync.RWMutex
+	ownerIdentityConfigurationArgsForCall []struct {
+		arg1 string
+	}
+	ownerIdentityConfigurationReturns struct {
+		result1 driver.IdentityConfiguration
+		result2 error
+	}
+	ownerIdentityConfigurationReturnsOnCall map[int]struct {
+		result1 driver.IdentityConfiguration
+		result2 error
+	}
 	OwnerWalletIDsStub        func() ([]string, error)
 	ownerWalletIDsMutex       sync.RWMutex
 	ownerWalletIDsArgsForCall []struct {
@@ -725,6 +738,70 @@ func (fake *WalletService) OwnerWalletReturnsOnCall(i int, result1 driver.OwnerW
 	}{result1, result2}
 }
 
+func (fake *WalletService) OwnerIdentityConfiguration(arg1 string) (driver.IdentityConfiguration, error) {
+	fake.ownerIdentityConfigurationMutex.Lock()
+	ret, specificReturn := fake.ownerIdentityConfigurationReturnsOnCall[len(fake.ownerIdentityConfigurationArgsForCall)]
+	fake.ownerIdentityConfigurationArgsForCall = append(fake.ownerIdentityConfigurationArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.OwnerIdentityConfigurationStub
+	fakeReturns := fake.ownerIdentityConfigurationReturns
+	fake.recordInvocation("OwnerIdentityConfiguration", []interface{}{arg1})
+	fake.ownerIdentityConfigurationMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *WalletService) OwnerIdentityConfigurationCallCount() int {
+	fake.ownerIdentityConfigurationMutex.RLock()
+	defer fake.ownerIdentityConfigurationMutex.RUnlock()
+	return len(fake.ownerIdentityConfigurationArgsForCall)
+}
+
+func (fake *WalletService) OwnerIdentityConfigurationCalls(stub func(string) (driver.IdentityConfiguration, error)) {
+	fake.ownerIdentityConfigurationMutex.Lock()
+	defer fake.ownerIdentityConfigurationMutex.Unlock()
+	fake.OwnerIdentityConfigurationStub = stub
+}
+
+func (fake *WalletService) OwnerIdentityConfigurationArgsForCall(i int) string {
+	fake.ownerIdentityConfigurationMutex.RLock()
+	defer fake.ownerIdentityConfigurationMutex.RUnlock()
+	argsForCall := fake.ownerIdentityConfigurationArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *WalletService) OwnerIdentityConfigurationReturns(result1 driver.IdentityConfiguration, result2 error) {
+	fake.ownerIdentityConfigurationMutex.Lock()
+	defer fake.ownerIdentityConfigurationMutex.Unlock()
+	fake.OwnerIdentityConfigurationStub = nil
+	fake.ownerIdentityConfigurationReturns = struct {
+		result1 driver.IdentityConfiguration
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *WalletService) OwnerIdentityConfigurationReturnsOnCall(i int, result1 driver.IdentityConfiguration, result2 error) {
+	fake.ownerIdentityConfigurationMutex.Lock()
+	defer fake.ownerIdentityConfigurationMutex.Unlock()
+	fake.OwnerIdentityConfigurationStub = nil
+	if fake.ownerIdentityConfigurationReturnsOnCall == nil {
+		fake.ownerIdentityConfigurationReturnsOnCall = make(map[int]struct {
+			result1 driver.IdentityConfiguration
+			result2 error
+		})
+	}
+	fake.ownerIdentityConfigurationReturnsOnCall[i] = struct {
+		result1 driver.IdentityConfiguration
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *WalletService) OwnerWalletIDs() ([]string, error) {
 	fake.ownerWalletIDsMutex.Lock()
 	ret, specificReturn := fake.ownerWalletIDsReturnsOnCall[len(fake.ownerWalletIDsArgsForCall)]
@@ -0,0 +1,139 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package driver
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/metrics"
+)
+
+// DefaultPublicParamsCacheSize is the default number of deserialized public parameters kept in memory
+const DefaultPublicParamsCacheSize = 16
+
+var (
+	ppCacheHits = metrics.CounterOpts{
+		Namespace: "token_sdk",
+		Name:      "public_params_cache_hits",
+		Help:      "The number of public parameters served by the cache.",
+	}
+	ppCacheMisses = metrics.CounterOpts{
+		Namespace: "token_sdk",
+		Name:      "public_params_cache_misses",
+		Help:      "The number of public parameters deserialized because not in the cache.",
+	}
+	ppCacheSize = metrics.GaugeOpts{
+		Namespace: "token_sdk",
+		Name:      "public_params_cache_size",
+		Help:      "The number of public parameters in the cache.",
+	}
+)
+
+// PublicParamsCacheMetrics are the metrics of a PublicParamsCache
+type PublicParamsCacheMetrics struct {
+	Hits   metrics.Counter
+	Misses metrics.Counter
+	Size   metrics.Gauge
+}
+
+func NewPublicParamsCacheMetrics(p metrics.Provider) *PublicParamsCacheMetrics {
+	return &PublicParamsCacheMetrics{
+		Hits:   p.NewCounter(ppCacheHits),
+		Misses: p.NewCounter(ppCacheMisses),
+		Size:   p.NewGauge(ppCacheSize),
+	}
+}
+
+// DefaultPublicParamsCache is the cache shared by the factories of this package,
+// therefore by the validators, the wallet services, and the token services.
+var DefaultPublicParamsCache = NewPublicParamsCache(DefaultPublicParamsCacheSize)
+
+// PublicParamsCache keeps the most recently used deserialized public parameters, by the hash of their serialization.
+// The cached instances are shared by all callers and must not be modified.
+type PublicParamsCache struct {
+	lock    sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	metrics *PublicParamsCacheMetrics
+}
+
+type ppCacheEntry struct {
+	key string
+	pp  PublicParameters
+}
+
+// NewPublicParamsCache returns a cache holding at most size public parameters. A non-positive size disables the cache.
+func NewPublicParamsCache(size int) *PublicParamsCache {
+	return &PublicParamsCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// SetMetrics sets the metrics updated by this cache
+func (c *PublicParamsCache) SetMetrics(m *PublicParamsCacheMetrics) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.metrics = m
+}
+
+// Get returns the public parameters serialized as raw, calling load if they are not in the cache
+func (c *PublicParamsCache) Get(raw []byte, load func(raw []byte) (PublicParameters, error)) (PublicParameters, error) {
+	if c.size <= 0 {
+		return load(raw)
+	}
+	h := sha256.Sum256(raw)
+	key := string(h[:])
+
+	c.lock.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		if c.metrics != nil {
+			c.metrics.Hits.Add(1)
+		}
+		c.lock.Unlock()
+		return e.Value.(*ppCacheEntry).pp, nil
+	}
+	c.lock.Unlock()
+
+	// deserialize outside the lock, concurrent misses on the same parameters load them more than once
+	pp, err := load(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.metrics != nil {
+		c.metrics.Misses.Add(1)
+	}
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*ppCacheEntry).pp, nil
+	}
+	c.entries[key] = c.order.PushFront(&ppCacheEntry{key: key, pp: pp})
+	for c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.entries, last.Value.(*ppCacheEntry).key)
+	}
+	if c.metrics != nil {
+		c.metrics.Size.Set(float64(c.order.Len()))
+	}
+	return pp, nil
+}
+
+// Len returns the number of public parameters in the cache
+func (c *PublicParamsCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package driver_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicParamsCache(t *testing.T) {
+	loads := 0
+	load := func(raw []byte) (driver.PublicParameters, error) {
+		loads++
+		pp := &mock.PublicParameters{}
+		pp.IdentifierReturns(string(raw))
+		return pp, nil
+	}
+	c := driver.NewPublicParamsCache(2)
+
+	pp1, err := c.Get([]byte("pp1"), load)
+	assert.NoError(t, err)
+	again, err := c.Get([]byte("pp1"), load)
+	assert.NoError(t, err)
+	assert.Same(t, pp1, again)
+	assert.Equal(t, 1, loads)
+
+	// pp2 is evicted, pp1 being the most recently used
+	_, err = c.Get([]byte("pp2"), load)
+	assert.NoError(t, err)
+	_, err = c.Get([]byte("pp1"), load)
+	assert.NoError(t, err)
+	_, err = c.Get([]byte("pp3"), load)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, 3, loads)
+	_, err = c.Get([]byte("pp1"), load)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, loads)
+	_, err = c.Get([]byte("pp2"), load)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, loads)
+
+	// disabled
+	c = driver.NewPublicParamsCache(0)
+	_, _ = c.Get([]byte("pp1"), load)
+	_, _ = c.Get([]byte("pp1"), load)
+	assert.Equal(t, 6, loads)
+	assert.Equal(t, 0, c.Len())
+}
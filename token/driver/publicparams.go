@@ -54,6 +54,12 @@ type PublicParameters interface {
 	GraphHiding() bool
 	// MaxTokenValue returns the maximum token value
 	MaxTokenValue() uint64
+	// MaxInputsPerRequest returns the maximum number of inputs a token request can spend, 0 means no limit
+	MaxInputsPerRequest() uint64
+	// MaxOutputsPerRequest returns the maximum number of outputs a token request can create, 0 means no limit
+	MaxOutputsPerRequest() uint64
+	// RedemptionWindows returns the periods of time during which tokens can be redeemed, empty means at any time
+	RedemptionWindows() RedemptionWindows
 	// CertificationDriver returns the certification driver identifier
 	CertificationDriver() string
 	// Bytes returns the marshalled version of the public parameters.
@@ -70,6 +76,31 @@ type PublicParameters interface {
 	Validate() error
 }
 
+// IssuersPublicParameters is implemented by the public parameters that list the identities allowed to issue
+type IssuersPublicParameters interface {
+	// IssuerIDs returns the identities allowed to issue, empty means that anyone can issue
+	IssuerIDs() []Identity
+}
+
+// LedgerFormatPublicParameters is implemented by the public parameters of the drivers that version the encoding of the tokens on the ledger.
+// A driver changing the encoding of its outputs returns a new format, so that the tokens stored with the old one can be told apart.
+type LedgerFormatPublicParameters interface {
+	// LedgerFormat returns the format of the tokens created under these public parameters
+	LedgerFormat() string
+}
+
+// LedgerFormatOf returns the format of the tokens on the ledger created under the passed public parameters.
+// It defaults to the identifier of the public parameters.
+func LedgerFormatOf(pp PublicParameters) string {
+	if pp == nil {
+		return ""
+	}
+	if lpp, ok := pp.(LedgerFormatPublicParameters); ok && len(lpp.LedgerFormat()) != 0 {
+		return lpp.LedgerFormat()
+	}
+	return pp.Identifier()
+}
+
 //go:generate counterfeiter -o mock/ppm.go -fake-name PublicParamsManager . PublicParamsManager
 
 // PublicParamsManager is the interface that must be implemented by the driver public parameters manager.
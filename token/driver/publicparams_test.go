@@ -0,0 +1,40 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type identifiedPP struct {
+	PublicParameters
+	identifier string
+}
+
+func (pp *identifiedPP) Identifier() string {
+	return pp.identifier
+}
+
+type formattedPP struct {
+	identifiedPP
+	format string
+}
+
+func (pp *formattedPP) LedgerFormat() string {
+	return pp.format
+}
+
+func TestLedgerFormatOf(t *testing.T) {
+	assert.Equal(t, "", LedgerFormatOf(nil))
+	// the identifier by default
+	assert.Equal(t, "zkatdlog", LedgerFormatOf(&identifiedPP{identifier: "zkatdlog"}))
+	assert.Equal(t, "zkatdlog", LedgerFormatOf(&formattedPP{identifiedPP: identifiedPP{identifier: "zkatdlog"}}))
+	// the format of the driver, if any
+	assert.Equal(t, "zkatdlog/v2", LedgerFormatOf(&formattedPP{identifiedPP: identifiedPP{identifier: "zkatdlog"}, format: "zkatdlog/v2"}))
+}
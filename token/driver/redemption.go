@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package driver
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRedemptionNotAllowed is returned when a redemption happens outside the redemption windows of the token type
+var ErrRedemptionNotAllowed = errors.New("redemption not allowed")
+
+// RedemptionWindow is a period of time during which tokens can be redeemed
+type RedemptionWindow struct {
+	// TokenType is the token type the window applies to.
+	// If empty, the window applies to the token types with no window of their own.
+	TokenType string `json:",omitempty"`
+	// NotBefore is the time from which tokens can be redeemed, the zero time means no lower bound
+	NotBefore time.Time
+	// NotAfter is the time until which tokens can be redeemed, the zero time means no upper bound
+	NotAfter time.Time
+}
+
+// Contains returns true if the passed time falls within the window
+func (w *RedemptionWindow) Contains(at time.Time) bool {
+	return (w.NotBefore.IsZero() || !at.Before(w.NotBefore)) && (w.NotAfter.IsZero() || !at.After(w.NotAfter))
+}
+
+// RedemptionWindows is the redemption policy of the public parameters.
+// If empty, tokens can be redeemed at any time.
+type RedemptionWindows []RedemptionWindow
+
+// Check returns ErrRedemptionNotAllowed if tokens of the passed type cannot be redeemed at the passed time,
+// that is, the type has windows, its own or the default ones, and none of them contains the passed time.
+func (ws RedemptionWindows) Check(tokenType string, at time.Time) error {
+	windows := ws.windowsOf(tokenType)
+	if len(windows) == 0 {
+		return nil
+	}
+	for _, w := range windows {
+		if w.Contains(at) {
+			return nil
+		}
+	}
+	return errors.Wrapf(ErrRedemptionNotAllowed, "tokens of type [%s] cannot be redeemed at [%s]", tokenType, at.UTC().Format(time.RFC3339))
+}
+
+// Typed returns true if some window applies to a specific token type
+func (ws RedemptionWindows) Typed() bool {
+	for _, w := range ws {
+		if len(w.TokenType) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that every window ends after it starts
+func (ws RedemptionWindows) Validate() error {
+	for i, w := range ws {
+		if !w.NotBefore.IsZero() && !w.NotAfter.IsZero() && w.NotAfter.Before(w.NotBefore) {
+			return errors.Errorf("invalid redemption window [%d] for type [%s]: it ends before it starts", i, w.TokenType)
+		}
+	}
+	return nil
+}
+
+func (ws RedemptionWindows) windowsOf(tokenType string) []RedemptionWindow {
+	var own, defaults []RedemptionWindow
+	for _, w := range ws {
+		switch w.TokenType {
+		case tokenType:
+			own = append(own, w)
+		case "":
+			defaults = append(defaults, w)
+		}
+	}
+	if len(own) != 0 {
+		return own
+	}
+	return defaults
+}
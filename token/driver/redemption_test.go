@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package driver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedemptionWindows(t *testing.T) {
+	now := time.Now()
+	maturity := now.Add(24 * time.Hour)
+
+	// no windows, redemption is always allowed
+	assert.NoError(t, RedemptionWindows{}.Check("BOND", now))
+
+	windows := RedemptionWindows{
+		{TokenType: "BOND", NotBefore: maturity},
+		{NotAfter: now.Add(time.Hour)},
+	}
+	assert.NoError(t, windows.Validate())
+	assert.True(t, windows.Typed())
+
+	// the windows of a type replace the default ones
+	err := windows.Check("BOND", now)
+	assert.True(t, errors.Is(err, ErrRedemptionNotAllowed))
+	assert.NoError(t, windows.Check("BOND", maturity))
+	assert.NoError(t, windows.Check("BOND", maturity.Add(time.Hour)))
+
+	// the other types get the default windows
+	assert.NoError(t, windows.Check("USD", now))
+	err = windows.Check("USD", now.Add(2*time.Hour))
+	assert.True(t, errors.Is(err, ErrRedemptionNotAllowed))
+
+	// a window must end after it starts
+	assert.Error(t, RedemptionWindows{{NotBefore: maturity, NotAfter: now}}.Validate())
+	assert.False(t, RedemptionWindows{{NotBefore: maturity}}.Typed())
+}
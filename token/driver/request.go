@@ -25,6 +25,10 @@ type TokenRequest struct {
 	Transfers         [][]byte
 	Signatures        [][]byte
 	AuditorSignatures [][]byte
+	// Reference is the hash of an external document, such as a trade confirmation, bound to the request.
+	// It is part of the message signed by the parties and the auditor.
+	// It is omitted from the serialization when empty, so that requests without reference serialize as before.
+	Reference []byte `asn1:"optional,omitempty"`
 }
 
 func (r *TokenRequest) Bytes() ([]byte, error) {
@@ -36,6 +40,32 @@ func (r *TokenRequest) FromBytes(raw []byte) error {
 	return err
 }
 
+// ErrNonCanonicalTokenRequest is returned when a serialized token request is not in canonical form
+var ErrNonCanonicalTokenRequest = errors.New("token request not in canonical form")
+
+// FromCanonicalBytes unmarshals the token request like FromBytes, and, in addition, checks
+// that the passed bytes are the canonical serialization of the request, that is
+// they carry no trailing data and marshalling the request gives back the same bytes.
+// This guarantees that the message signed by the parties, and its hash anchored on the ledger,
+// do not depend on how the request has been encoded.
+func (r *TokenRequest) FromCanonicalBytes(raw []byte) error {
+	rest, err := asn1.Unmarshal(raw, r)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return errors.Wrapf(ErrNonCanonicalTokenRequest, "[%d] trailing bytes", len(rest))
+	}
+	canonical, err := r.Bytes()
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal token request")
+	}
+	if !bytes.Equal(raw, canonical) {
+		return errors.Wrapf(ErrNonCanonicalTokenRequest, "encoding differs from the canonical one")
+	}
+	return nil
+}
+
 // IssueMetadata contains the metadata of an issue action.
 // In more details, there is an issuer and a list of outputs.
 // For each output, there is a token info and a list of receivers with their audit info to recover their enrollment ID.
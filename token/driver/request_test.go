@@ -7,6 +7,8 @@ SPDX-License-Identifier: Apache-2.0
 package driver
 
 import (
+	"encoding/asn1"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -124,3 +126,92 @@ func TestTokenRequestMetadataSerialization(t *testing.T) {
 	assert.Equal(t, reqMeta, *reqMeta2)
 	assert.Equal(t, raw, raw2)
 }
+
+func TestTokenRequestCanonicalSerialization(t *testing.T) {
+	for _, req := range []*TokenRequest{
+		{},
+		{
+			Issues:            [][]byte{[]byte("issue1"), []byte("issue2")},
+			Transfers:         [][]byte{[]byte("transfer1")},
+			Signatures:        [][]byte{[]byte("signature1")},
+			AuditorSignatures: [][]byte{[]byte("auditor_signature1")},
+		},
+		{
+			Transfers: [][]byte{[]byte("transfer1"), []byte("transfer2")},
+			Reference: []byte("reference"),
+		},
+	} {
+		raw, err := req.Bytes()
+		assert.NoError(t, err)
+
+		req2 := &TokenRequest{}
+		assert.NoError(t, req2.FromCanonicalBytes(raw))
+		raw2, err := req2.Bytes()
+		assert.NoError(t, err)
+		assert.Equal(t, raw, raw2)
+
+		// trailing data is accepted by FromBytes only
+		tampered := append(append([]byte{}, raw...), 0x00, 0x00)
+		assert.NoError(t, (&TokenRequest{}).FromBytes(tampered))
+		assert.ErrorIs(t, (&TokenRequest{}).FromCanonicalBytes(tampered), ErrNonCanonicalTokenRequest)
+	}
+}
+
+func TestTokenRequestNonCanonicalEncoding(t *testing.T) {
+	req := &TokenRequest{
+		Issues: [][]byte{[]byte("issue1")},
+	}
+	raw, err := req.Bytes()
+	assert.NoError(t, err)
+	assert.NoError(t, (&TokenRequest{}).FromCanonicalBytes(raw))
+
+	// an empty reference is omitted by the canonical serialization
+	type withReference struct {
+		Issues            [][]byte
+		Transfers         [][]byte
+		Signatures        [][]byte
+		AuditorSignatures [][]byte
+		Reference         []byte
+	}
+	raw, err = asn1.Marshal(withReference{Issues: req.Issues, Reference: []byte{}})
+	assert.NoError(t, err)
+	req2 := &TokenRequest{}
+	assert.NoError(t, req2.FromBytes(raw))
+	assert.ErrorIs(t, (&TokenRequest{}).FromCanonicalBytes(raw), ErrNonCanonicalTokenRequest)
+}
+
+func TestApplicationMetadataCanonicalSerialization(t *testing.T) {
+	meta := map[string][]byte{}
+	for i := 0; i < 32; i++ {
+		meta[fmt.Sprintf("key%d", i)] = []byte(fmt.Sprintf("value%d", i))
+	}
+	raw, err := MarshalMeta(meta)
+	assert.NoError(t, err)
+	// the serialization does not depend on the iteration order of the map
+	for i := 0; i < 10; i++ {
+		raw2, err := MarshalMeta(meta)
+		assert.NoError(t, err)
+		assert.Equal(t, raw, raw2)
+	}
+	meta2, err := UnmarshalMeta(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, meta, meta2)
+
+	// unsorted keys
+	raw, err = asn1.Marshal(metaSer{Keys: []string{"b", "a"}, Vals: [][]byte{[]byte("2"), []byte("1")}})
+	assert.NoError(t, err)
+	_, err = UnmarshalMeta(raw)
+	assert.Error(t, err)
+
+	// duplicated keys
+	raw, err = asn1.Marshal(metaSer{Keys: []string{"a", "a"}, Vals: [][]byte{[]byte("1"), []byte("2")}})
+	assert.NoError(t, err)
+	_, err = UnmarshalMeta(raw)
+	assert.Error(t, err)
+
+	// missing values
+	raw, err = asn1.Marshal(metaSer{Keys: []string{"a", "b"}, Vals: [][]byte{[]byte("1")}})
+	assert.NoError(t, err)
+	_, err = UnmarshalMeta(raw)
+	assert.Error(t, err)
+}
@@ -30,6 +30,7 @@ func newFactoryDirectory[T PPReader](fs ...NamedFactory[T]) *factoryDirectory[T]
 // PublicParametersFromBytes unmarshals the bytes to a driver.PublicParameters instance.
 // The passed bytes are expected to encode a driver.SerializedPublicParameters instance.
 // If no driver is registered for the public params' identifier, it returns an error.
+// The deserialized public parameters are kept in DefaultPublicParamsCache.
 func (s *factoryDirectory[T]) PublicParametersFromBytes(params []byte) (PublicParameters, error) {
 	pp, err := serializedPublicParametersFromBytes(params)
 	if err != nil {
@@ -37,7 +38,7 @@ func (s *factoryDirectory[T]) PublicParametersFromBytes(params []byte) (PublicPa
 	}
 
 	if f, ok := s.factories[TokenDriverName(pp.Identifier)]; ok {
-		return f.PublicParametersFromBytes(params)
+		return DefaultPublicParamsCache.Get(params, f.PublicParametersFromBytes)
 	}
 	return nil, errors.Errorf("cannot load public paramenters, driver [%s] not found", pp.Identifier)
 }
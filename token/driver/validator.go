@@ -8,8 +8,11 @@ package driver
 
 import (
 	"context"
+	"strconv"
+	"time"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
 )
 
 // ValidationAttributeID is the type of validation attribute identifier
@@ -18,6 +21,73 @@ type ValidationAttributeID = string
 // ValidationAttributes is a map containing attributes generated during validation
 type ValidationAttributes = map[ValidationAttributeID][]byte
 
+const (
+	// SignatureChecksTiming is the validation attribute carrying the time spent verifying signatures
+	SignatureChecksTiming ValidationAttributeID = "timing.signatures"
+	// ProofVerificationTiming is the validation attribute carrying the time spent validating the actions,
+	// signature checks and ledger lookups excluded
+	ProofVerificationTiming ValidationAttributeID = "timing.proofs"
+	// LedgerLookupsTiming is the validation attribute carrying the time spent reading the ledger
+	LedgerLookupsTiming ValidationAttributeID = "timing.ledger"
+	// TotalValidationTiming is the validation attribute carrying the overall validation time
+	TotalValidationTiming ValidationAttributeID = "timing.total"
+	// TxTimestamp is the validation attribute carrying the timestamp of the transaction, see WithTxTimestamp
+	TxTimestamp ValidationAttributeID = "tx.timestamp"
+)
+
+type txTimestampKey struct{}
+
+// WithTxTimestamp returns a context carrying the timestamp the creator of the transaction being validated set in it.
+// The validators check the rules depending on time, like the redemption windows, at this time,
+// so that all the validators of the transaction reach the same result.
+func WithTxTimestamp(ctx context.Context, timestamp time.Time) context.Context {
+	return context.WithValue(ctx, txTimestampKey{}, timestamp)
+}
+
+// TxTimestampFrom returns the timestamp of the transaction carried by the passed context, if any
+func TxTimestampFrom(ctx context.Context) (time.Time, bool) {
+	timestamp, ok := ctx.Value(txTimestampKey{}).(time.Time)
+	return timestamp, ok
+}
+
+// ValidationProfile reports where the time spent validating a token request went
+type ValidationProfile struct {
+	SignatureChecks   time.Duration
+	ProofVerification time.Duration
+	LedgerLookups     time.Duration
+	Total             time.Duration
+}
+
+// SetTo stores the profile in the passed validation attributes, as nanoseconds
+func (p *ValidationProfile) SetTo(attributes ValidationAttributes) {
+	attributes[SignatureChecksTiming] = []byte(strconv.FormatInt(int64(p.SignatureChecks), 10))
+	attributes[ProofVerificationTiming] = []byte(strconv.FormatInt(int64(p.ProofVerification), 10))
+	attributes[LedgerLookupsTiming] = []byte(strconv.FormatInt(int64(p.LedgerLookups), 10))
+	attributes[TotalValidationTiming] = []byte(strconv.FormatInt(int64(p.Total), 10))
+}
+
+// ValidationProfileFromAttributes extracts the validation profile from the passed attributes.
+// It returns false if the attributes carry no profile.
+func ValidationProfileFromAttributes(attributes ValidationAttributes) (*ValidationProfile, bool, error) {
+	if _, ok := attributes[TotalValidationTiming]; !ok {
+		return nil, false, nil
+	}
+	p := &ValidationProfile{}
+	for key, d := range map[ValidationAttributeID]*time.Duration{
+		SignatureChecksTiming:   &p.SignatureChecks,
+		ProofVerificationTiming: &p.ProofVerification,
+		LedgerLookupsTiming:     &p.LedgerLookups,
+		TotalValidationTiming:   &p.Total,
+	} {
+		v, err := strconv.ParseInt(string(attributes[key]), 10, 64)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "invalid validation timing [%s]", key)
+		}
+		*d = time.Duration(v)
+	}
+	return p, true, nil
+}
+
 // GetStateFnc models a function that returns the value for the given key from the ledger
 type GetStateFnc = func(id token.ID) ([]byte, error)
 
@@ -47,6 +117,15 @@ type Validator interface {
 	UnmarshalActions(raw []byte) ([]interface{}, error)
 	// VerifyTokenRequestFromRaw verifies the passed marshalled token request against the passed ledger and anchor.
 	// The function returns additionally a map that contains information about the token request. The content of this map
-	// is driver-dependant
+	// is driver-dependant.
+	// The passed context should carry the timestamp of the transaction, see WithTxTimestamp.
 	VerifyTokenRequestFromRaw(ctx context.Context, getState GetStateFnc, anchor string, raw []byte) ([]interface{}, ValidationAttributes, error)
 }
+
+// DryRunValidator is implemented by the validators that can validate a token request before its signatures are collected
+type DryRunValidator interface {
+	// DryRunTokenRequestFromRaw verifies the passed marshalled token request as VerifyTokenRequestFromRaw does,
+	// except that missing signatures are not an error: the function returns the identities whose signatures are still expected,
+	// in the order the validator checks them. The signatures already present must be valid.
+	DryRunTokenRequestFromRaw(ctx context.Context, getState GetStateFnc, anchor string, raw []byte) ([]interface{}, []Identity, error)
+}
@@ -82,6 +82,9 @@ type QueryEngine interface {
 	// GetTokenOutputs retrieves the token output as stored on the ledger for the passed ids.
 	// For each id, the callback is invoked to unmarshal the output
 	GetTokenOutputs(ids []*token.ID, callback QueryCallbackFunc) error
+	// ExistAll returns, for each of the passed ids, true if the token is in the vault, false otherwise.
+	// Unlike GetTokenOutputs, it does not fail if some of the tokens are missing and does not fetch their content.
+	ExistAll(ids []*token.ID) ([]bool, error)
 	// GetTokenInfoAndOutputs retrieves both the token output and information for the passed ids.
 	GetTokenInfoAndOutputs(ctx context.Context, ids []*token.ID) ([][]byte, [][]byte, error)
 	// GetTokens returns the list of tokens with their respective vault keys
@@ -24,6 +24,41 @@ type RecipientData struct {
 	TokenMetadataAuditInfo []byte
 }
 
+// AnonymityLevel selects how an owner wallet derives a recipient identity
+type AnonymityLevel int
+
+const (
+	// DefaultAnonymity lets the wallet apply its default behaviour
+	DefaultAnonymity AnonymityLevel = iota
+	// LongTermIdentity reuses the same identity across transfers.
+	// This is useful when the counterparty is regulated and must be able to recognize the owner.
+	LongTermIdentity
+	// FreshPseudonym derives a fresh pseudonym, unlinkable to the ones used in other transfers
+	FreshPseudonym
+)
+
+var (
+	AnonymityLevelStrings = map[AnonymityLevel]string{
+		DefaultAnonymity: "default",
+		LongTermIdentity: "long-term",
+		FreshPseudonym:   "fresh",
+	}
+)
+
+func (l AnonymityLevel) String() string {
+	s, ok := AnonymityLevelStrings[l]
+	if !ok {
+		return "unknown"
+	}
+	return s
+}
+
+// RecipientIdentityOptions contains options that can be used to derive a recipient identity from a wallet
+type RecipientIdentityOptions struct {
+	// AnonymityLevel selects whether the identity is reused or freshly derived
+	AnonymityLevel AnonymityLevel
+}
+
 // ListTokensOptions contains options that can be used to list tokens from a wallet
 type ListTokensOptions struct {
 	// TokenType is the type of token to list
@@ -52,12 +87,13 @@ type OwnerWallet interface {
 	Wallet
 
 	// GetRecipientIdentity returns a recipient identity.
-	// Depending on the underlying wallet implementation, this can be a long-term or ephemeral identity.
+	// Depending on the underlying wallet implementation and the requested anonymity level, this can be a long-term or ephemeral identity.
+	// A nil opts selects the default behaviour of the wallet.
 	// Using the returned identity as an index, one can retrieve the following information:
 	// - Identity audit info via GetAuditInfo;
 	// - TokenMetadata via GetTokenMetadata;
 	// - TokenIdentityMetadata via GetTokenMetadataAuditInfo.
-	GetRecipientIdentity() (Identity, error)
+	GetRecipientIdentity(opts *RecipientIdentityOptions) (Identity, error)
 
 	// GetAuditInfo returns auditing information for the passed identity
 	GetAuditInfo(id Identity) ([]byte, error)
@@ -99,6 +135,40 @@ type IssuerWallet interface {
 	HistoryTokens(opts *ListTokensOptions) (*token.IssuedTokens, error)
 }
 
+// IssuerKey is an issuance key of an issuer wallet
+type IssuerKey struct {
+	// Identity is the issuer identity of the key
+	Identity Identity
+	// TokenTypes are the token types the key is dedicated to, empty means any type
+	TokenTypes []string
+}
+
+// IssuerKeyPolicy selects the key an issuer wallet with several keys issues a token type with
+type IssuerKeyPolicy interface {
+	// Select returns the identity of one of the passed keys to issue the passed token type with.
+	// The keys are the candidates of the wallet, the most recently added first.
+	Select(tokenType string, keys []*IssuerKey) (Identity, error)
+}
+
+// MultiKeyIssuerWallet models an issuer wallet holding several issuance keys,
+// for instance one per token type, or a new one and the one it rotates out.
+type MultiKeyIssuerWallet interface {
+	IssuerWallet
+
+	// AddIssuerKey adds the passed key to this wallet. The signer of the key must be known to the wallet.
+	AddIssuerKey(key *IssuerKey) error
+
+	// IssuerKeys returns the keys of this wallet, the most recently added first
+	IssuerKeys() []*IssuerKey
+
+	// SetIssuerKeyPolicy sets the policy selecting the key at issuance time
+	SetIssuerKeyPolicy(policy IssuerKeyPolicy)
+
+	// SelectIssuerIdentity returns the identity of the key to issue the passed token type with.
+	// If registered is not empty, only the keys whose identity is in it are candidates.
+	SelectIssuerIdentity(tokenType string, registered []Identity) (Identity, error)
+}
+
 // AuditorWallet models the wallet of an auditor
 type AuditorWallet interface {
 	Wallet
@@ -172,6 +242,9 @@ type WalletService interface {
 	// RegisterOwnerIdentity registers an owner long-term identity
 	RegisterOwnerIdentity(config IdentityConfiguration) error
 
+	// OwnerIdentityConfiguration returns the configuration, credentials included, of the owner long-term identity with the passed identifier
+	OwnerIdentityConfiguration(id string) (IdentityConfiguration, error)
+
 	// RegisterIssuerIdentity registers an issuer long-term wallet
 	RegisterIssuerIdentity(config IdentityConfiguration) error
 
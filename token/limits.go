@@ -0,0 +1,52 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxInputsConfigKey is the TMS configuration key that can lower the maximum number of inputs per token request
+	MaxInputsConfigKey = "requestLimits.maxInputs"
+	// MaxOutputsConfigKey is the TMS configuration key that can lower the maximum number of outputs per token request
+	MaxOutputsConfigKey = "requestLimits.maxOutputs"
+)
+
+// RequestLimits bounds the number of inputs and outputs of a token request. Zero means no limit.
+type RequestLimits struct {
+	MaxInputs  uint64
+	MaxOutputs uint64
+}
+
+// RequestLimits returns the limits token requests assembled by this node must respect.
+// The limits set by the public parameters are enforced at validation time, the configuration can only tighten them.
+func (t *ManagementService) RequestLimits() (*RequestLimits, error) {
+	pp := t.PublicParametersManager().PublicParameters()
+	if pp == nil {
+		return nil, errors.Errorf("public parameters not set yet for [%s]", t.ID())
+	}
+	var maxInputs, maxOutputs uint64
+	if err := t.Configuration().UnmarshalKey(MaxInputsConfigKey, &maxInputs); err != nil {
+		return nil, errors.WithMessagef(err, "failed to load [%s]", MaxInputsConfigKey)
+	}
+	if err := t.Configuration().UnmarshalKey(MaxOutputsConfigKey, &maxOutputs); err != nil {
+		return nil, errors.WithMessagef(err, "failed to load [%s]", MaxOutputsConfigKey)
+	}
+	return &RequestLimits{
+		MaxInputs:  tighterLimit(pp.MaxInputsPerRequest(), maxInputs),
+		MaxOutputs: tighterLimit(pp.MaxOutputsPerRequest(), maxOutputs),
+	}, nil
+}
+
+// tighterLimit returns the smallest of the passed limits, zero meaning no limit
+func tighterLimit(a, b uint64) uint64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
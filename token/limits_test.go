@@ -0,0 +1,22 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTighterLimit(t *testing.T) {
+	assert.Equal(t, uint64(0), tighterLimit(0, 0))
+	assert.Equal(t, uint64(5), tighterLimit(0, 5))
+	assert.Equal(t, uint64(5), tighterLimit(5, 0))
+	assert.Equal(t, uint64(3), tighterLimit(5, 3))
+	// the configuration cannot relax the public parameters
+	assert.Equal(t, uint64(5), tighterLimit(5, 8))
+}
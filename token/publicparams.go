@@ -42,6 +42,31 @@ func (c *PublicParameters) MaxTokenValue() uint64 {
 	return c.PublicParameters.MaxTokenValue()
 }
 
+// MaxInputsPerRequest returns the maximum number of inputs a token request can spend, 0 means no limit
+func (c *PublicParameters) MaxInputsPerRequest() uint64 {
+	return c.PublicParameters.MaxInputsPerRequest()
+}
+
+// MaxOutputsPerRequest returns the maximum number of outputs a token request can create, 0 means no limit
+func (c *PublicParameters) MaxOutputsPerRequest() uint64 {
+	return c.PublicParameters.MaxOutputsPerRequest()
+}
+
+// RedemptionWindows returns the periods of time during which tokens can be redeemed, empty means at any time
+func (c *PublicParameters) RedemptionWindows() driver.RedemptionWindows {
+	return c.PublicParameters.RedemptionWindows()
+}
+
+// HashAlgorithm returns the hash algorithm of the public parameters and of the token requests
+func (c *PublicParameters) HashAlgorithm() driver.HashAlgorithm {
+	return driver.HashAlgorithmOf(c.PublicParameters)
+}
+
+// LedgerFormat returns the format of the tokens on the ledger created under the public parameters
+func (c *PublicParameters) LedgerFormat() string {
+	return driver.LedgerFormatOf(c.PublicParameters)
+}
+
 // Serialize returns the public parameters in their serialized form
 func (c *PublicParameters) Serialize() ([]byte, error) {
 	return c.PublicParameters.Serialize()
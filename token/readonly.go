@@ -0,0 +1,27 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ReadOnlyConfigKey is the TMS configuration key that makes the TMS read-only.
+// A read-only TMS serves queries, but it does not assemble token requests and its databases reject writes.
+const ReadOnlyConfigKey = "readOnly"
+
+// ErrReadOnly is returned when a read-only TMS is asked to assemble a token request
+var ErrReadOnly = errors.New("tms is read-only")
+
+// ReadOnly returns true if this TMS is configured to be read-only
+func (t *ManagementService) ReadOnly() (bool, error) {
+	var readOnly bool
+	if err := t.Configuration().UnmarshalKey(ReadOnlyConfigKey, &readOnly); err != nil {
+		return false, errors.WithMessagef(err, "failed to load [%s]", ReadOnlyConfigKey)
+	}
+	return readOnly, nil
+}
@@ -7,8 +7,11 @@ SPDX-License-Identifier: Apache-2.0
 package token
 
 import (
+	"bytes"
 	"context"
 	"encoding/asn1"
+	"strconv"
+	"time"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/meta"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -19,6 +22,8 @@ import (
 
 const (
 	TransferMetadataPrefix = meta.TransferMetadataPrefix
+	// AnonymityLevelMetadataPrefix is the prefix of the application metadata keys recording the anonymity level of a transfer action
+	AnonymityLevelMetadataPrefix = "token.anonymity_level."
 )
 
 type Binder interface {
@@ -68,6 +73,9 @@ type TransferOptions struct {
 	TokenIDs []*token.ID
 	// RestRecipientIdentity TODO:
 	RestRecipientIdentity *RecipientData
+	// AnonymityLevel selects how the identity receiving the rest, if any, is derived from the sender wallet.
+	// A non-default level is recorded in the application metadata of the request.
+	AnonymityLevel AnonymityLevel
 }
 
 func compileTransferOptions(opts ...TransferOption) (*TransferOptions, error) {
@@ -123,6 +131,14 @@ func WithRestRecipientIdentity(recipientData *RecipientData) TransferOption {
 	}
 }
 
+// WithTransferAnonymityLevel sets the anonymity level of the transfer
+func WithTransferAnonymityLevel(level AnonymityLevel) TransferOption {
+	return func(o *TransferOptions) error {
+		o.AnonymityLevel = level
+		return nil
+	}
+}
+
 // AuditRecord models the audit record returned by the audit command
 // It contains the token request's anchor, inputs (with Type and Quantity), and outputs
 type AuditRecord struct {
@@ -294,7 +310,78 @@ func (r *Request) Transfer(ctx context.Context, wallet *OwnerWallet, typ string,
 	}
 
 	r.TokenService.logger.Debugf("Prepare Transfer Action [id:%s,ins:%d,outs:%d]", r.Anchor, len(tokenIDs), len(outputTokens))
+	return r.appendTransfer(ctx, wallet, tokenIDs, outputTokens, opt)
+}
+
+// TransferSerials appends a transfer action to the request that transfers the serials of the passed range to the passed owner.
+// The inputs are the serial tokens with the passed ids, they must be of the class of the range and carry all its serials.
+// The serials of the inputs outside the range go back to the wallet, one output per contiguous range.
+// Serial tokens are described by token.SerialRange, they require a driver whose token types are in the clear, like fabtoken.
+func (r *Request) TransferSerials(ctx context.Context, wallet *OwnerWallet, ids []*token.ID, serials token.SerialRange, owner Identity, opts ...TransferOption) (*TransferAction, error) {
+	if err := serials.Validate(); err != nil {
+		return nil, errors.WithMessagef(err, "invalid serials")
+	}
+	if owner.IsNone() {
+		return nil, errors.Errorf("all recipients should be defined")
+	}
+	opt, err := compileTransferOptions(opts...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed compiling options [%v]", opts)
+	}
+	ids = r.cleanupInputIDs(ids)
+	if len(ids) == 0 {
+		return nil, errors.Errorf("no input tokens for serials [%s]", serials)
+	}
+	inputTokens, err := r.TokenService.Vault().NewQueryEngine().GetTokens(ids...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying tokens ids")
+	}
+	inputs := make([]token.SerialRange, len(inputTokens))
+	for i, tok := range inputTokens {
+		in, ok := token.ParseSerialType(tok.Type)
+		if !ok || in.Class != serials.Class {
+			return nil, errors.Errorf("input [%s] is not a serial token of class [%s]", ids[i], serials.Class)
+		}
+		inputs[i] = in
+	}
+	rest, err := token.SubtractSerialRange(inputs, serials)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "inputs do not carry serials [%s]", serials)
+	}
+
+	pp := r.TokenService.PublicParametersManager().PublicParameters()
+	outputs := append([]token.SerialRange{serials}, rest...)
+	outputTokens := make([]*token.Token, len(outputs))
+	for i, out := range outputs {
+		if out.Count() > pp.MaxTokenValue() {
+			return nil, errors.Errorf("cannot create output for serials [%s], max token value [%d]", out, pp.MaxTokenValue())
+		}
+		q, err := token.UInt64ToQuantity(out.Count(), pp.Precision())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to convert [%d] to quantity of precision [%d]", out.Count(), pp.Precision())
+		}
+		outputTokens[i] = &token.Token{
+			Owner:    owner,
+			Type:     out.Type(),
+			Quantity: q.Hex(),
+		}
+	}
+	if len(rest) != 0 {
+		restIdentity, err := wallet.GetRecipientIdentity(WithAnonymityLevel(opt.AnonymityLevel))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting recipient identity for the rest, wallet [%s]", wallet.ID())
+		}
+		for _, out := range outputTokens[1:] {
+			out.Owner = restIdentity
+		}
+	}
 
+	r.TokenService.logger.Debugf("Prepare Transfer Serials Action [id:%s,ins:%d,outs:%d]", r.Anchor, len(ids), len(outputTokens))
+	return r.appendTransfer(ctx, wallet, ids, outputTokens, opt)
+}
+
+// appendTransfer computes the transfer action of the passed inputs and outputs, and appends it to the request
+func (r *Request) appendTransfer(ctx context.Context, wallet *OwnerWallet, tokenIDs []*token.ID, outputTokens []*token.Token, opt *TransferOptions) (*TransferAction, error) {
 	ts := r.TokenService.tms.TransferService()
 
 	// Compute transfer
@@ -323,6 +410,7 @@ func (r *Request) Transfer(ctx context.Context, wallet *OwnerWallet, typ string,
 	if err != nil {
 		return nil, errors.Wrap(err, "failed serializing transfer action")
 	}
+	r.recordAnonymityLevel(len(r.Actions.Transfers), opt.AnonymityLevel)
 	r.Actions.Transfers = append(r.Actions.Transfers, raw)
 	r.Metadata.Transfers = append(r.Metadata.Transfers, *transferMetadata)
 
@@ -331,12 +419,18 @@ func (r *Request) Transfer(ctx context.Context, wallet *OwnerWallet, typ string,
 
 // Redeem appends a redeem action to the request. The action will be prepared using the provided owner wallet.
 // The action redeems tokens of the passed type for a total amount matching the passed value.
+// It fails with driver.ErrRedemptionNotAllowed if the redemption windows of the public parameters do not allow it now.
 // Additional options can be passed to customize the action.
 func (r *Request) Redeem(ctx context.Context, wallet *OwnerWallet, typ string, value uint64, opts ...TransferOption) error {
 	opt, err := compileTransferOptions(opts...)
 	if err != nil {
 		return errors.WithMessagef(err, "failed compiling options [%v]", opts)
 	}
+	if pp := r.TokenService.tms.PublicParamsManager().PublicParameters(); pp != nil {
+		if err := pp.RedemptionWindows().Check(typ, time.Now()); err != nil {
+			return err
+		}
+	}
 	tokenIDs, outputTokens, err := r.prepareTransfer(true, wallet, typ, []uint64{value}, []Identity{nil}, opt)
 	if err != nil {
 		return errors.Wrap(err, "failed preparing transfer")
@@ -374,6 +468,7 @@ func (r *Request) Redeem(ctx context.Context, wallet *OwnerWallet, typ string, v
 		return errors.Wrap(err, "failed serializing transfer action")
 	}
 
+	r.recordAnonymityLevel(len(r.Actions.Transfers), opt.AnonymityLevel)
 	r.Actions.Transfers = append(r.Actions.Transfers, raw)
 	r.Metadata.Transfers = append(r.Metadata.Transfers, *transferMetadata)
 
@@ -777,7 +872,7 @@ func (r *Request) MarshalToAudit() ([]byte, error) {
 	if r.Actions == nil {
 		return nil, errors.Errorf("failed to marshal request in tx [%s] for audit", r.Anchor)
 	}
-	bytes, err := asn1.Marshal(driver.TokenRequest{Issues: r.Actions.Issues, Transfers: r.Actions.Transfers})
+	bytes, err := asn1.Marshal(driver.TokenRequest{Issues: r.Actions.Issues, Transfers: r.Actions.Transfers, Reference: r.Actions.Reference})
 	if err != nil {
 		return nil, errors.Wrapf(err, "audit of tx [%s] failed: error marshal token request for signature", r.Anchor)
 	}
@@ -960,6 +1055,29 @@ func (r *Request) Transfers() []*Transfer {
 	return transfers
 }
 
+// SigningWallet returns the identifier of the first wallet of this node that signs the request,
+// looking at the issuers first and then at the senders, in the order of the actions.
+// It returns an empty string if no wallet of this node signs the request.
+func (r *Request) SigningWallet() string {
+	if r.Metadata == nil {
+		return ""
+	}
+	wm := r.TokenService.WalletManager()
+	for _, issue := range r.Metadata.Issues {
+		if w := wm.IssuerWallet(issue.Issuer); w != nil {
+			return w.ID()
+		}
+	}
+	for _, transfer := range r.Metadata.Transfers {
+		for _, sender := range transfer.Senders {
+			if w := wm.OwnerWallet(sender); w != nil {
+				return w.ID()
+			}
+		}
+	}
+	return ""
+}
+
 // AuditCheck performs the audit check of the request in addition to
 // the checks of the token request itself via IsValid.
 func (r *Request) AuditCheck(ctx context.Context) error {
@@ -1026,6 +1144,28 @@ func (r *Request) AuditRecord() (*AuditRecord, error) {
 	}, nil
 }
 
+// BindReference binds the request to the passed hash of an external document, such as a trade confirmation.
+// The reference is part of the message signed by the parties and the auditor, therefore it must be bound
+// before the signatures are collected.
+func (r *Request) BindReference(reference []byte) error {
+	if len(reference) == 0 {
+		return errors.Errorf("empty reference for request [%s]", r.Anchor)
+	}
+	if len(r.Actions.Reference) != 0 && !bytes.Equal(r.Actions.Reference, reference) {
+		return errors.Errorf("request [%s] is already bound to another reference", r.Anchor)
+	}
+	r.Actions.Reference = reference
+	return nil
+}
+
+// Reference returns the hash of the external document the request is bound to, if any
+func (r *Request) Reference() []byte {
+	if r.Actions == nil {
+		return nil
+	}
+	return r.Actions.Reference
+}
+
 // ApplicationMetadata returns the application metadata corresponding to the given key
 func (r *Request) ApplicationMetadata(k string) []byte {
 	if len(r.Metadata.Application) == 0 {
@@ -1046,6 +1186,25 @@ func (r *Request) SetApplicationMetadata(k string, v []byte) {
 	r.Metadata.Application[k] = v
 }
 
+// TransferAnonymityLevel returns the anonymity level recorded for the transfer action at the passed index.
+// It returns DefaultAnonymity if no level has been recorded.
+func (r *Request) TransferAnonymityLevel(index int) AnonymityLevel {
+	v := r.ApplicationMetadata(AnonymityLevelMetadataPrefix + strconv.Itoa(index))
+	for level, s := range driver.AnonymityLevelStrings {
+		if s == string(v) {
+			return level
+		}
+	}
+	return DefaultAnonymity
+}
+
+func (r *Request) recordAnonymityLevel(index int, level AnonymityLevel) {
+	if level == DefaultAnonymity {
+		return
+	}
+	r.SetApplicationMetadata(AnonymityLevelMetadataPrefix+strconv.Itoa(index), []byte(level.String()))
+}
+
 // FilterMetadataBy returns a new Request with the metadata filtered by the given enrollment IDs.
 func (r *Request) FilterMetadataBy(eIDs ...string) (*Request, error) {
 	meta := &Metadata{
@@ -1177,7 +1336,7 @@ func (r *Request) prepareTransfer(redeem bool, wallet *OwnerWallet, tokenType st
 			}
 			restIdentity = transferOpts.RestRecipientIdentity.Identity
 		} else {
-			restIdentity, err = wallet.GetRecipientIdentity()
+			restIdentity, err = wallet.GetRecipientIdentity(WithAnonymityLevel(transferOpts.AnonymityLevel))
 			if err != nil {
 				return nil, nil, errors.WithMessagef(err, "failed getting recipient identity for the rest, wallet [%s]", wallet.ID())
 			}
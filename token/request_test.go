@@ -43,6 +43,30 @@ func TestRequestSerialization(t *testing.T) {
 	assert.Equal(t, mRaw, mRaw2)
 }
 
+func TestRequest_BindReference(t *testing.T) {
+	r := NewRequest(nil, "hello world")
+	r.Actions = &driver.TokenRequest{Transfers: [][]byte{[]byte("transfer1")}}
+	unbound, err := r.MarshalToAudit()
+	assert.NoError(t, err)
+	assert.Nil(t, r.Reference())
+
+	assert.Error(t, r.BindReference(nil))
+	assert.NoError(t, r.BindReference([]byte("confirm")))
+	assert.NoError(t, r.BindReference([]byte("confirm")))
+	assert.Error(t, r.BindReference([]byte("another")))
+	assert.Equal(t, []byte("confirm"), r.Reference())
+
+	// the reference is signed and survives serialization
+	bound, err := r.MarshalToAudit()
+	assert.NoError(t, err)
+	assert.NotEqual(t, unbound, bound)
+	raw, err := r.Bytes()
+	assert.NoError(t, err)
+	r2 := NewRequest(nil, "")
+	assert.NoError(t, r2.FromBytes(raw))
+	assert.Equal(t, []byte("confirm"), r2.Reference())
+}
+
 func TestRequest_ApplicationMetadata(t *testing.T) {
 	// Test case: No application metadata set
 	request := &Request{
@@ -104,3 +128,21 @@ func TestRequest_SetApplicationMetadata(t *testing.T) {
 	assert.Equal(t, []byte("value1"), request.Metadata.Application["key1"])
 	assert.Equal(t, []byte("value2"), request.Metadata.Application["key2"])
 }
+
+func TestRequest_TransferAnonymityLevel(t *testing.T) {
+	request := &Request{Metadata: &driver.TokenRequestMetadata{}}
+
+	// nothing recorded
+	assert.Equal(t, DefaultAnonymity, request.TransferAnonymityLevel(0))
+
+	// the default level is not recorded
+	request.recordAnonymityLevel(0, DefaultAnonymity)
+	assert.Empty(t, request.Metadata.Application)
+
+	request.recordAnonymityLevel(0, LongTermIdentity)
+	request.recordAnonymityLevel(1, FreshPseudonym)
+	assert.Equal(t, []byte("long-term"), request.ApplicationMetadata(AnonymityLevelMetadataPrefix+"0"))
+	assert.Equal(t, LongTermIdentity, request.TransferAnonymityLevel(0))
+	assert.Equal(t, FreshPseudonym, request.TransferAnonymityLevel(1))
+	assert.Equal(t, DefaultAnonymity, request.TransferAnonymityLevel(2))
+}
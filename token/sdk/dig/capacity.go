@@ -0,0 +1,55 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdk
+
+import (
+	"context"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/capacity"
+	config2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/config"
+	"github.com/pkg/errors"
+	"go.uber.org/dig"
+)
+
+type capacityServices struct {
+	dig.In
+	ConfigService   driver.ConfigService
+	ConfigProvider  *config2.Service
+	CapacityService *capacity.Service
+}
+
+// startCapacitySampling samples in background, when enabled, the disk usage of the databases of each TMS.
+// Each sample updates the capacity metrics.
+func startCapacitySampling(ctx context.Context, in capacityServices) error {
+	if in.ConfigService.IsSet("token.capacity.horizon") {
+		horizon := in.ConfigService.GetDuration("token.capacity.horizon")
+		if horizon <= 0 {
+			return errors.Errorf("invalid capacity horizon [%s], expected a positive duration", horizon)
+		}
+		in.CapacityService.SetHorizon(horizon)
+	}
+	if !in.ConfigService.IsSet("token.capacity.interval") {
+		return nil
+	}
+	interval := in.ConfigService.GetDuration("token.capacity.interval")
+	if interval <= 0 {
+		return errors.Errorf("invalid capacity interval [%s], expected a positive duration", interval)
+	}
+	configurations, err := in.ConfigProvider.Configurations()
+	if err != nil {
+		return err
+	}
+	tmsIDs := make([]token.TMSID, len(configurations))
+	for i, c := range configurations {
+		tmsIDs[i] = c.ID()
+	}
+	logger.Infof("sampling the disk usage of [%d] tms every [%s]", len(tmsIDs), interval)
+	go in.CapacityService.Run(ctx, interval, tmsIDs)
+	return nil
+}
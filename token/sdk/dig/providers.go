@@ -9,6 +9,7 @@ package sdk
 import (
 	driver2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/driver"
 	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	dbconfig "github.com/hyperledger-labs/fabric-token-sdk/token/sdk/db"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
@@ -31,8 +32,11 @@ func NewTokenLockDBManager(in struct {
 	ConfigService  driver2.ConfigService
 	ConfigProvider *config2.Service
 	Drivers        []db.NamedDriver[dbdriver.TokenLockDBDriver] `group:"tokenlockdb-drivers"`
+	Metrics        *db.StatementMetrics
 }) *tokenlockdb.Manager {
-	return tokenlockdb.NewHolder(in.Drivers).NewManager(in.ConfigService, dbconfig.NewConfig(in.ConfigProvider, "tokenlockdb.persistence.type", "db.persistence.type"))
+	m := tokenlockdb.NewHolder(in.Drivers).NewManager(in.ConfigService, dbconfig.NewConfig(in.ConfigProvider, "tokenlockdb.persistence.type", "db.persistence.type"))
+	m.EnableStatementMetrics("tokenlockdb", in.Metrics)
+	return m
 }
 
 // Audit DB
@@ -42,8 +46,11 @@ func NewAuditDBManager(in struct {
 	ConfigService  driver2.ConfigService
 	ConfigProvider *config2.Service
 	Drivers        []db.NamedDriver[dbdriver.AuditDBDriver] `group:"auditdb-drivers"`
+	Metrics        *db.StatementMetrics
 }) *auditdb.Manager {
-	return auditdb.NewHolder(in.Drivers).NewManager(in.ConfigService, dbconfig.NewConfig(in.ConfigProvider, "auditdb.persistence.type", "db.persistence.type"))
+	m := auditdb.NewHolder(in.Drivers).NewManager(in.ConfigService, dbconfig.NewConfig(in.ConfigProvider, "auditdb.persistence.type", "db.persistence.type"))
+	m.EnableStatementMetrics("auditdb", in.Metrics)
+	return m
 }
 
 // Transaction DB
@@ -53,8 +60,11 @@ func NewTTXDBManager(in struct {
 	ConfigService  driver2.ConfigService
 	ConfigProvider *config2.Service
 	Drivers        []db.NamedDriver[dbdriver.TTXDBDriver] `group:"ttxdb-drivers"`
+	Metrics        *db.StatementMetrics
 }) *ttxdb.Manager {
-	return ttxdb.NewHolder(in.Drivers).NewManager(in.ConfigService, dbconfig.NewConfig(in.ConfigProvider, "ttxdb.persistence.type", "db.persistence.type"))
+	m := ttxdb.NewHolder(in.Drivers).NewManager(in.ConfigService, dbconfig.NewConfig(in.ConfigProvider, "ttxdb.persistence.type", "db.persistence.type"))
+	m.EnableStatementMetrics("ttxdb", in.Metrics)
+	return m
 }
 
 // Identity DB
@@ -64,8 +74,11 @@ func NewIdentityDBManager(in struct {
 	ConfigService  driver2.ConfigService
 	ConfigProvider *config2.Service
 	Drivers        []db.NamedDriver[dbdriver.IdentityDBDriver] `group:"identitydb-drivers"`
+	Metrics        *db.StatementMetrics
 }) *identitydb.Manager {
-	return identitydb.NewManager(in.Drivers, in.ConfigService, dbconfig.NewConfig(in.ConfigProvider, "identitydb.persistence.type", "db.persistence.type"))
+	m := identitydb.NewManager(in.Drivers, in.ConfigService, dbconfig.NewConfig(in.ConfigProvider, "identitydb.persistence.type", "db.persistence.type"))
+	m.EnableStatementMetrics(in.Metrics)
+	return m
 }
 
 // Token DB
@@ -89,10 +102,12 @@ func NewTokenManagers(in struct {
 	ConfigProvider  *config2.Service
 	DBDrivers       []db.NamedDriver[dbdriver.TokenDBDriver]       `group:"tokendb-drivers"`
 	NotifierDrivers []db.NamedDriver[dbdriver.TokenNotifierDriver] `group:"tokennotifier-drivers"`
+	Metrics         *db.StatementMetrics
 }) (*tokendb.Manager, *tokendb.NotifierManager) {
 	dbConfig := dbconfig.NewConfig(in.ConfigProvider, "tokendb.persistence.type", "db.persistence.type")
-	return tokendb.NewHolder(in.DBDrivers).NewManager(in.ConfigService, dbConfig),
-		tokendb.NewNotifierHolder(in.NotifierDrivers).NewManager(in.ConfigService, dbConfig)
+	m := tokendb.NewHolder(in.DBDrivers).NewManager(in.ConfigService, dbConfig)
+	m.EnableStatementMetrics("tokendb", in.Metrics)
+	return m, tokendb.NewNotifierHolder(in.NotifierDrivers).NewManager(in.ConfigService, dbConfig)
 }
 
 // Unity
@@ -123,7 +138,9 @@ func NewDBDrivers() DBDriverResult {
 
 func newTokenDriverService(in struct {
 	dig.In
-	Drivers []driver.NamedFactory[driver.Driver] `group:"token-drivers"`
+	Drivers         []driver.NamedFactory[driver.Driver] `group:"token-drivers"`
+	MetricsProvider metrics.Provider
 }) *driver.TokenDriverService {
+	driver.DefaultPublicParamsCache.SetMetrics(driver.NewPublicParamsCacheMetrics(in.MetricsProvider))
 	return driver.NewTokenDriverService(in.Drivers)
 }
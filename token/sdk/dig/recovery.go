@@ -0,0 +1,59 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdk
+
+import (
+	"context"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	config2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokens"
+	"github.com/pkg/errors"
+	"go.uber.org/dig"
+)
+
+type recoveryServices struct {
+	dig.In
+	ConfigProvider *config2.Service
+	TMSProvider    *token.ManagementServiceProvider
+	TokensManager  *tokens.Manager
+}
+
+// recoverTokens applies again, for each TMS, the token requests left in the intent log of the tokendb
+// by a node stopped while applying them. Read-only TMSs are skipped.
+func recoverTokens(ctx context.Context, in recoveryServices) error {
+	configurations, err := in.ConfigProvider.Configurations()
+	if err != nil {
+		return err
+	}
+	for _, tmsConfig := range configurations {
+		tmsID := tmsConfig.ID()
+		tms, err := in.TMSProvider.GetManagementService(token.WithTMSID(tmsID))
+		if err != nil {
+			return errors.WithMessagef(err, "failed to get tms [%s]", tmsID)
+		}
+		readOnly, err := tms.ReadOnly()
+		if err != nil {
+			return err
+		}
+		if readOnly {
+			continue
+		}
+		tks, err := in.TokensManager.Tokens(tmsID)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to get tokens for [%s]", tmsID)
+		}
+		recovered, err := tks.Recover(ctx, tmsID)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to recover tokens for [%s]", tmsID)
+		}
+		if len(recovered) != 0 {
+			logger.Infof("recovered the tokens of [%d] transactions for [%s]: %v", len(recovered), tmsID, recovered)
+		}
+	}
+	return nil
+}
@@ -31,20 +31,30 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
 	auditdriver "github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb/db/sql"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/capacity"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/certifier/dummy"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/compression"
 	config2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/erasure"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/features"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/freeze"
 	identity2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/identity"
 	kvs2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/kvs"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identitydb"
 	identitydriver "github.com/hyperledger-labs/fabric-token-sdk/token/services/identitydb/db/sql"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/htlc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/common"
 	driver3 "github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ops"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/provenance"
 	sdriver "github.com/hyperledger-labs/fabric-token-sdk/token/services/selector/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selector/sherdlock"
 	selector "github.com/hyperledger-labs/fabric-token-sdk/token/services/selector/simple"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokenlockdb"
 	tokenlockdriver "github.com/hyperledger-labs/fabric-token-sdk/token/services/tokenlockdb/db/sql"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokens"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
@@ -57,6 +67,9 @@ import (
 
 var logger = logging.MustGetLogger("token-sdk")
 
+// defaultShutdownTimeout bounds the time spent draining the in-flight writes when the node stops
+const defaultShutdownTimeout = 30 * time.Second
+
 var selectorProviders = map[sdriver.Driver]any{
 	sdriver.Simple:    selector.NewService,
 	sdriver.Sherdlock: sherdlock.NewService,
@@ -130,6 +143,16 @@ func (p *SDK) Install() error {
 		p.Container().Provide(vault.NewVaultProvider),
 		p.Container().Provide(tms.NewPostInitializer),
 		p.Container().Provide(ttx.NewMetrics),
+		p.Container().Provide(ttx.NewRateLimiter),
+		p.Container().Provide(htlc.NewMetrics),
+		p.Container().Provide(compression.NewMetrics),
+		p.Container().Provide(compression.NewService),
+		p.Container().Provide(capacity.NewMetrics),
+		p.Container().Provide(db.NewStatementMetrics),
+		p.Container().Provide(capacity.NewService),
+		p.Container().Provide(freeze.NewService),
+		p.Container().Provide(erasure.NewService),
+		p.Container().Provide(provenance.NewService),
 		p.Container().Provide(func(tracerProvider trace.TracerProvider) *tracing.TracerProvider {
 			return tracing.NewTracerProvider(tracerProvider)
 		}),
@@ -139,8 +162,9 @@ func (p *SDK) Install() error {
 		p.Container().Provide(ttxdriver.NewDriver, dig.Group("ttxdb-drivers")),
 		p.Container().Provide(identitydriver.NewDriver, dig.Group("identitydb-drivers")),
 		p.Container().Provide(NewDBDrivers),
-		p.Container().Provide(func(dbManager *tokendb.Manager, notifierManager *tokendb.NotifierManager, metricsProvider metrics.Provider) sherdlock.FetcherProvider {
-			return sherdlock.NewFetcherProvider(dbManager, notifierManager, metricsProvider, sherdlock.Mixed)
+		p.Container().Provide(func(configService *config2.Service) *features.Service { return features.NewService(configService) }),
+		p.Container().Provide(func(dbManager *tokendb.Manager, notifierManager *tokendb.NotifierManager, metricsProvider metrics.Provider, flags *features.Service) sherdlock.FetcherProvider {
+			return sherdlock.NewFetcherProvider(dbManager, notifierManager, metricsProvider, sherdlock.Mixed, flags)
 		}),
 	)
 	if err != nil {
@@ -154,6 +178,13 @@ func (p *SDK) Install() error {
 	if err != nil {
 		return errors.WithMessagef(err, "failed setting up decorator")
 	}
+	// the selectors refuse the frozen token types
+	err = p.Container().Decorate(func(provider token.SelectorManagerProvider, service *freeze.Service) token.SelectorManagerProvider {
+		return freeze.NewSelectorManagerProvider(provider, service)
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "failed setting up selector decorator")
+	}
 
 	if err := p.SDK.Install(); err != nil {
 		return errors.WithMessagef(err, "failed installing dig chain")
@@ -173,8 +204,16 @@ func (p *SDK) Install() error {
 		digutils.Register[driver.ConfigService](p.Container()),
 		digutils.Register[*identity.DBStorageProvider](p.Container()),
 		digutils.Register[*ttx.Metrics](p.Container()),
+		digutils.Register[*ttx.RateLimiter](p.Container()),
+		digutils.Register[*htlc.Metrics](p.Container()),
+		digutils.Register[*compression.Service](p.Container()),
+		digutils.Register[*capacity.Service](p.Container()),
+		digutils.Register[*freeze.Service](p.Container()),
+		digutils.Register[*erasure.Service](p.Container()),
+		digutils.Register[*provenance.Service](p.Container()),
 		digutils.Register[*auditor.Manager](p.Container()),
 		digutils.Register[*config2.Service](p.Container()),
+		digutils.Register[*features.Service](p.Container()),
 		digutils.Register[*ttx.Manager](p.Container()),
 		digutils.Register[*tokens.Manager](p.Container()),
 		digutils.Register[trace.TracerProvider](p.Container()),
@@ -205,10 +244,97 @@ func (p *SDK) Start(ctx context.Context) error {
 	}
 	logger.Infof("Token platform enabled, starting...")
 
-	return errors2.Join(
+	if err := errors2.Join(
 		p.Container().Invoke(registerNetworkDrivers),
 		p.Container().Invoke(connectNetworks),
-	)
+		p.Container().Invoke(func(r driver.Registry) error { return ttx.InstallViews(r) }),
+		p.Container().Invoke(installOpsViews),
+	); err != nil {
+		return err
+	}
+	if err := p.Container().Invoke(func(in recoveryServices) error { return recoverTokens(ctx, in) }); err != nil {
+		return errors.WithMessagef(err, "recovery failed")
+	}
+	if err := p.Container().Invoke(func(in selfCheckServices) error { return runSelfCheck(ctx, in) }); err != nil {
+		return errors.WithMessagef(err, "self-check failed")
+	}
+	if err := p.Container().Invoke(func(in capacityServices) error { return startCapacitySampling(ctx, in) }); err != nil {
+		return errors.WithMessagef(err, "failed starting capacity sampling")
+	}
+
+	go func() {
+		<-ctx.Done()
+		timeout := defaultShutdownTimeout
+		if p.ConfigService().IsSet("token.shutdown.timeout") {
+			timeout = p.ConfigService().GetDuration("token.shutdown.timeout")
+		}
+		stopCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := p.Container().Invoke(func(in stoppableServices) error { return stopServices(stopCtx, in) }); err != nil {
+			logger.Errorf("failed stopping token services [%s]", err)
+		}
+	}()
+	return nil
+}
+
+type stoppableServices struct {
+	dig.In
+	TokensManager      *tokens.Manager
+	TTXDBManager       *ttxdb.Manager
+	AuditDBManager     *auditdb.Manager
+	TokenDBManager     *tokendb.Manager
+	TokenLockDBManager *tokenlockdb.Manager
+	IdentityDBManager  *identitydb.Manager
+}
+
+// stopServices shuts the token services down so that a node stopping during block processing does not leave local state half-written.
+// First, the token services stop accepting new writes and wait for the in-flight ones,
+// the tokens and transaction records of the transactions being committed, to complete.
+// Then, the databases are closed. Draining all of them before closing any is needed
+// because databases configured on the same data source share the connection pool.
+func stopServices(ctx context.Context, in stoppableServices) error {
+	logger.Infof("stopping token services...")
+	var errs []error
+	if err := in.TokensManager.Stop(ctx); err != nil {
+		errs = append(errs, errors.WithMessagef(err, "failed stopping tokens"))
+	}
+	type dbManager interface {
+		Drain(ctx context.Context) error
+		Stop(ctx context.Context) error
+	}
+	dbManagers := []struct {
+		name    string
+		manager dbManager
+	}{
+		{name: "ttxdb", manager: in.TTXDBManager},
+		{name: "auditdb", manager: in.AuditDBManager},
+		{name: "tokendb", manager: in.TokenDBManager},
+		{name: "tokenlockdb", manager: in.TokenLockDBManager},
+	}
+	for _, m := range dbManagers {
+		if err := m.manager.Drain(ctx); err != nil {
+			errs = append(errs, errors.WithMessagef(err, "failed draining %s", m.name))
+		}
+	}
+	for _, m := range dbManagers {
+		if err := m.manager.Stop(ctx); err != nil {
+			errs = append(errs, errors.WithMessagef(err, "failed stopping %s", m.name))
+		}
+	}
+	if err := in.IdentityDBManager.Stop(ctx); err != nil {
+		errs = append(errs, errors.WithMessagef(err, "failed stopping identitydb"))
+	}
+	logger.Infof("stopping token services...done")
+	return errors2.Join(errs...)
+}
+
+// installOpsViews registers, when enabled, the views of the routine operations called by tokenctl
+func installOpsViews(configService driver.ConfigService, r driver.Registry) error {
+	if !configService.GetBool(ops.EnabledKey) {
+		return nil
+	}
+	logger.Infof("operations views enabled")
+	return ops.InstallViews(r)
 }
 
 func connectNetworks(configService *config2.Service, networkProvider *network.Provider, tmsProvider *token.ManagementServiceProvider) error {
@@ -229,11 +355,43 @@ func connectNetworks(configService *config2.Service, networkProvider *network.Pr
 		if err != nil {
 			return errors.WithMessagef(err, "failed to connect to connect backend to tms [%s]", tmsID)
 		}
+		if err := checkNamespaceCompatibility(net, tmsProvider, tmsID); err != nil {
+			return errors.WithMessagef(err, "incompatible namespace for tms [%s]", tmsID)
+		}
 	}
 	logger.Infof("Token platform enabled, starting...done")
 	return nil
 }
 
+// namespaceInfoProvider returns the info of the token namespaces deployed on a network
+type namespaceInfoProvider interface {
+	NamespaceInfo(namespace string) (*network.NamespaceInfo, error)
+}
+
+// checkNamespaceCompatibility verifies that the namespace deployed on the network supports the public parameters of the tms.
+// The startup fails if the namespace info cannot be fetched.
+func checkNamespaceCompatibility(net namespaceInfoProvider, tmsProvider *token.ManagementServiceProvider, tmsID token.TMSID) error {
+	tms, err := tmsProvider.GetManagementService(token.WithTMSID(tmsID))
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get tms [%s]", tmsID)
+	}
+	pp := tms.PublicParametersManager().PublicParameters()
+	if pp == nil {
+		return errors.Errorf("public parameters not set for tms [%s]", tmsID)
+	}
+	return checkPublicParamsSupported(net, tmsID.Namespace, pp.Identifier())
+}
+
+// checkPublicParamsSupported returns an error if the passed namespace does not support the public parameters with the passed identifier
+func checkPublicParamsSupported(net namespaceInfoProvider, namespace string, ppIdentifier string) error {
+	info, err := net.NamespaceInfo(namespace)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to fetch info of namespace [%s]", namespace)
+	}
+	logger.Infof("namespace [%s] at version [%s] supports public parameters [%v]", namespace, info.Version, info.PublicParamsIdentifiers)
+	return info.CheckCompatibility(ppIdentifier)
+}
+
 func registerNetworkDrivers(in struct {
 	dig.In
 	NetworkProvider *network.Provider
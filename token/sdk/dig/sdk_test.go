@@ -13,6 +13,8 @@ import (
 	fabricsdk "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/sdk/dig"
 	orionsdk "github.com/hyperledger-labs/fabric-smart-client/platform/orion/sdk/dig"
 	sdk "github.com/hyperledger-labs/fabric-smart-client/platform/view/sdk/dig"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,3 +51,24 @@ func TestOrionFabricWiring(t *testing.T) {
 		sdk.WithBool("fabric.enabled", true),
 	))
 }
+
+type namespaceInfoFunc func(namespace string) (*network.NamespaceInfo, error)
+
+func (f namespaceInfoFunc) NamespaceInfo(namespace string) (*network.NamespaceInfo, error) {
+	return f(namespace)
+}
+
+func TestCheckPublicParamsSupported(t *testing.T) {
+	supported := namespaceInfoFunc(func(string) (*network.NamespaceInfo, error) {
+		return &network.NamespaceInfo{Version: "v2", PublicParamsIdentifiers: []string{"zkatdlog"}}, nil
+	})
+	assert.NoError(t, checkPublicParamsSupported(supported, "ns", "zkatdlog"))
+	assert.ErrorContains(t, checkPublicParamsSupported(supported, "ns", "fabtoken"), "public parameters [fabtoken] not supported by the namespace version [v2]")
+
+	// the startup fails fast if the namespace info cannot be fetched
+	unreachable := errors.New("peer unreachable")
+	failing := namespaceInfoFunc(func(string) (*network.NamespaceInfo, error) { return nil, unreachable })
+	err := checkPublicParamsSupported(failing, "ns", "zkatdlog")
+	assert.ErrorIs(t, err, unreachable)
+	assert.ErrorContains(t, err, "failed to fetch info of namespace [ns]")
+}
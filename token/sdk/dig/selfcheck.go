@@ -0,0 +1,142 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdk
+
+import (
+	"context"
+	errors2 "errors"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	config2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selfcheck"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokenlockdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/pkg/errors"
+	"go.uber.org/dig"
+)
+
+const (
+	// defaultSelfCheckSampleSize is the default number of unspent tokens checked against the ttxdb
+	defaultSelfCheckSampleSize = 100
+	// selfCheckRefuse makes the node fail to start when the self-check finds critical mismatches
+	selfCheckRefuse = "refuse"
+	// selfCheckReadOnly makes the node serve read-only the TMSs with critical mismatches
+	selfCheckReadOnly = "readonly"
+)
+
+type selfCheckServices struct {
+	dig.In
+	ConfigService      driver.ConfigService
+	ConfigProvider     *config2.Service
+	NetworkProvider    *network.Provider
+	TMSProvider        *token.ManagementServiceProvider
+	TTXDBManager       *ttxdb.Manager
+	AuditDBManager     *auditdb.Manager
+	TokenDBManager     *tokendb.Manager
+	TokenLockDBManager *tokenlockdb.Manager
+}
+
+// runSelfCheck verifies, when enabled, the consistency of the local state of each TMS.
+// When critical mismatches are found, depending on the configuration,
+// the node either refuses to start or serves the affected TMSs read-only.
+func runSelfCheck(ctx context.Context, in selfCheckServices) error {
+	if !in.ConfigService.GetBool("token.selfCheck.enabled") {
+		return nil
+	}
+	sampleSize := defaultSelfCheckSampleSize
+	if in.ConfigService.IsSet("token.selfCheck.sampleSize") {
+		sampleSize = in.ConfigService.GetInt("token.selfCheck.sampleSize")
+	}
+	onCritical := in.ConfigService.GetString("token.selfCheck.onCritical")
+	switch onCritical {
+	case "":
+		onCritical = selfCheckRefuse
+	case selfCheckRefuse, selfCheckReadOnly:
+	default:
+		return errors.Errorf("invalid self-check action [%s], expected [%s] or [%s]", onCritical, selfCheckRefuse, selfCheckReadOnly)
+	}
+
+	configurations, err := in.ConfigProvider.Configurations()
+	if err != nil {
+		return err
+	}
+	checker := &selfcheck.Checker{SampleSize: sampleSize}
+	for _, tmsConfig := range configurations {
+		tmsID := tmsConfig.ID()
+		report, err := checkTMS(checker, in, tmsID)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to run self-check for tms [%s]", tmsID)
+		}
+		if !report.Critical() {
+			logger.Infof("%s", report)
+			continue
+		}
+		if onCritical == selfCheckRefuse {
+			return errors.Errorf("critical self-check failures, refusing to serve. %s", report)
+		}
+		logger.Errorf("critical self-check failures, serving tms [%s] read-only. %s", tmsID, report)
+		if err := makeReadOnly(ctx, in, tmsID); err != nil {
+			return errors.WithMessagef(err, "failed to make tms [%s] read-only", tmsID)
+		}
+	}
+	return nil
+}
+
+func checkTMS(checker *selfcheck.Checker, in selfCheckServices, tmsID token.TMSID) (*selfcheck.Report, error) {
+	tms, err := in.TMSProvider.GetManagementService(token.WithTMSID(tmsID))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get tms")
+	}
+	net, err := in.NetworkProvider.GetNetwork(tmsID.Network, tmsID.Channel)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get network")
+	}
+	tokenDB, err := in.TokenDBManager.DBByTMSId(tmsID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get tokendb")
+	}
+	ttxDB, err := in.TTXDBManager.DBByTMSId(tmsID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get ttxdb")
+	}
+	return checker.Check(tmsID, tms.PublicParametersManager().PublicParamsHash(), tms.PublicParametersManager().PublicParameters().HashAlgorithm(), tokenDB, ttxDB, net)
+}
+
+// makeReadOnly makes the databases of the passed TMS reject writes.
+// The tokens service is not drained, so that the queries of the wallets keep being served;
+// its writes are rejected by the tokendb.
+func makeReadOnly(ctx context.Context, in selfCheckServices, tmsID token.TMSID) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultShutdownTimeout)
+	defer cancel()
+
+	ttxDB, err := in.TTXDBManager.DBByTMSId(tmsID)
+	if err != nil {
+		return err
+	}
+	auditDB, err := in.AuditDBManager.DBByTMSId(tmsID)
+	if err != nil {
+		return err
+	}
+	tokenDB, err := in.TokenDBManager.DBByTMSId(tmsID)
+	if err != nil {
+		return err
+	}
+	tokenLockDB, err := in.TokenLockDBManager.DBByTMSId(tmsID)
+	if err != nil {
+		return err
+	}
+	return errors2.Join(
+		ttxDB.Drain(ctx),
+		auditDB.Drain(ctx),
+		tokenDB.Drain(ctx),
+		tokenLockDB.Drain(ctx),
+	)
+}
@@ -6,6 +6,10 @@ SPDX-License-Identifier: Apache-2.0
 package token
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/pkg/errors"
 
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
@@ -25,12 +29,111 @@ var (
 	SelectorSufficientFundsButConcurrencyIssue = errors.New("sufficient funds but concurrency issue")
 )
 
+// InsufficientFundsError describes the funds of a wallet when a selection cannot cover the requested quantity,
+// so that applications can tell the user why (e.g. "you have 50 locked in pending transaction X").
+// It matches, with errors.Is, the error returned by the selector before, either SelectorInsufficientFunds or SelectorSufficientButLockedFunds.
+type InsufficientFundsError struct {
+	// TokenType is the type of the requested tokens
+	TokenType string
+	// Requested is the requested quantity
+	Requested token2.Quantity
+	// Available is the sum of the tokens that are not locked by other transactions
+	Available token2.Quantity
+	// Locked is the sum of the tokens locked by other transactions
+	Locked token2.Quantity
+	// LockedBy maps the ids of the transactions locking tokens to the quantity they lock.
+	// It is empty if the selector does not know who holds the locks.
+	LockedBy map[string]token2.Quantity
+	// PendingIncoming is the sum of the tokens the wallet is going to receive from pending transactions, nil if unknown.
+	// The selectors do not know about pending transactions, ttx.AddPendingIncoming sets it.
+	PendingIncoming token2.Quantity
+	// Spendable is the largest set of tokens that can be spent now, their sum is Available
+	Spendable []*token2.ID
+
+	cause error
+}
+
+// NewInsufficientFundsError returns a new InsufficientFundsError matching the passed cause
+func NewInsufficientFundsError(cause error, tokenType string, requested, available, locked token2.Quantity, spendable []*token2.ID) *InsufficientFundsError {
+	return &InsufficientFundsError{
+		TokenType: tokenType,
+		Requested: requested,
+		Available: available,
+		Locked:    locked,
+		LockedBy:  map[string]token2.Quantity{},
+		Spendable: spendable,
+		cause:     cause,
+	}
+}
+
+func (e *InsufficientFundsError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s: [%s] tokens of type [%s] requested, [%s] available", e.cause, e.Requested.Decimal(), e.TokenType, e.Available.Decimal()))
+	if e.Locked != nil && e.Locked.ToBigInt().Sign() > 0 {
+		sb.WriteString(fmt.Sprintf(", [%s] locked by other transactions", e.Locked.Decimal()))
+		if len(e.LockedBy) != 0 {
+			txIDs := make([]string, 0, len(e.LockedBy))
+			for txID := range e.LockedBy {
+				txIDs = append(txIDs, txID)
+			}
+			sort.Strings(txIDs)
+			locks := make([]string, len(txIDs))
+			for i, txID := range txIDs {
+				locks[i] = fmt.Sprintf("[%s] by [%s]", e.LockedBy[txID].Decimal(), txID)
+			}
+			sb.WriteString(" (" + strings.Join(locks, ", ") + ")")
+		}
+	}
+	if e.PendingIncoming != nil && e.PendingIncoming.ToBigInt().Sign() > 0 {
+		sb.WriteString(fmt.Sprintf(", [%s] incoming from pending transactions", e.PendingIncoming.Decimal()))
+	}
+	return sb.String()
+}
+
+func (e *InsufficientFundsError) Is(target error) bool {
+	return target == e.cause
+}
+
 // OwnerFilter tells if a passed identity is recognized
 type OwnerFilter interface {
 	// ID is the wallet identifier of the owner
 	ID() string
 }
 
+// SelectionPreview describes the outcome of a token selection, without any token being locked
+type SelectionPreview struct {
+	// Tokens are the identifiers of the tokens that would be selected
+	Tokens []*token2.ID
+	// Total is the sum of the quantities of the selected tokens
+	Total token2.Quantity
+	// Change is the quantity that would be transferred back to the owner, that is, Total minus the requested quantity
+	Change token2.Quantity
+	// Inputs is the estimated number of inputs of the transfer
+	Inputs int
+}
+
+// NewSelectionPreview returns the preview of the selection of the passed tokens, whose quantities sum up to total, to cover the requested quantity
+func NewSelectionPreview(tokens []*token2.ID, total, requested token2.Quantity, precision uint64) (*SelectionPreview, error) {
+	if total.Cmp(requested) < 0 {
+		return nil, errors.Wrapf(SelectorInsufficientFunds, "only [%s] available, but [%s] were requested", total.Decimal(), requested.Decimal())
+	}
+	change := token2.NewZeroQuantity(precision).Add(total).Sub(requested)
+	return &SelectionPreview{
+		Tokens: tokens,
+		Total:  total,
+		Change: change,
+		Inputs: len(tokens),
+	}, nil
+}
+
+// SelectedTokens are the tokens selected for a token type
+type SelectedTokens struct {
+	// Tokens are the identifiers of the selected tokens
+	Tokens []*token2.ID
+	// Total is the sum of the quantities of the selected tokens
+	Total token2.Quantity
+}
+
 // Selector is the interface of token selectors
 type Selector interface {
 	// Select returns the list of token identifiers where
@@ -41,6 +144,16 @@ type Selector interface {
 	// Notice that, the quantity selected might exceed the quantity requested due to the amounts
 	// stored in each token.
 	Select(ownerFilter OwnerFilter, q, tokenType string) ([]*token2.ID, token2.Quantity, error)
+	// Preview returns the tokens Select would return for the same arguments, without locking them.
+	// Applications can use it to show a quote before committing to a transfer.
+	// Tokens might be locked or spent by other transactions meanwhile, therefore, Select might return different tokens.
+	Preview(ownerFilter OwnerFilter, q, tokenType string) (*SelectionPreview, error)
+	// SelectMany selects, for each token type in the passed map, tokens whose quantities sum up to at least the
+	// corresponding quantity, in decimal format. All the tokens must match the passed owner filter.
+	// The tokens of all types are fetched in a single pass and locked together:
+	// either all the requested quantities are covered, or no token stays locked.
+	// This is needed, for instance, by exchanges spending two token types in the same transaction.
+	SelectMany(ownerFilter OwnerFilter, quantities map[string]string) (map[string]*SelectedTokens, error)
 	// Close closes the selector and releases its memory/cpu resources
 	Close() error
 }
@@ -0,0 +1,53 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"testing"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSelectionPreview(t *testing.T) {
+	ids := []*token2.ID{{TxId: "a", Index: 0}, {TxId: "b", Index: 1}}
+	total := token2.NewQuantityFromUInt64(15)
+
+	preview, err := NewSelectionPreview(ids, total, token2.NewQuantityFromUInt64(10), 64)
+	assert.NoError(t, err)
+	assert.Equal(t, ids, preview.Tokens)
+	assert.Equal(t, 2, preview.Inputs)
+	assert.Equal(t, "15", preview.Total.Decimal())
+	assert.Equal(t, "5", preview.Change.Decimal())
+
+	_, err = NewSelectionPreview(ids, total, token2.NewQuantityFromUInt64(20), 64)
+	assert.True(t, errors.Is(err, SelectorInsufficientFunds))
+}
+
+func TestInsufficientFundsError(t *testing.T) {
+	spendable := []*token2.ID{{TxId: "a", Index: 0}}
+	err := NewInsufficientFundsError(SelectorSufficientButLockedFunds, "USD", token2.NewQuantityFromUInt64(100), token2.NewQuantityFromUInt64(30), token2.NewQuantityFromUInt64(70), spendable)
+	err.LockedBy["tx2"] = token2.NewQuantityFromUInt64(20)
+	err.LockedBy["tx1"] = token2.NewQuantityFromUInt64(50)
+	err.PendingIncoming = token2.NewQuantityFromUInt64(10)
+
+	wrapped := errors.WithMessage(err, "failed selecting tokens")
+	assert.True(t, errors.Is(wrapped, SelectorSufficientButLockedFunds))
+	assert.False(t, errors.Is(wrapped, SelectorInsufficientFunds))
+	var fundsErr *InsufficientFundsError
+	assert.True(t, errors.As(wrapped, &fundsErr))
+	assert.Equal(t, spendable, fundsErr.Spendable)
+	assert.Equal(t,
+		"sufficient but partially locked funds: [100] tokens of type [USD] requested, [30] available, [70] locked by other transactions ([50] by [tx1], [20] by [tx2]), [10] incoming from pending transactions",
+		fundsErr.Error(),
+	)
+
+	// nothing locked nor incoming
+	err = NewInsufficientFundsError(SelectorInsufficientFunds, "USD", token2.NewQuantityFromUInt64(100), token2.NewQuantityFromUInt64(30), token2.NewQuantityFromUInt64(0), spendable)
+	assert.Equal(t, "insufficient funds: [100] tokens of type [USD] requested, [30] available", err.Error())
+}
@@ -0,0 +1,415 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package bundle exports the audit records of a time range as a bundle encrypted for an external auditor
+// and signed by the auditor, and lets the external auditor verify, decrypt and check it.
+package bundle
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/msp/x509/msp"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Version is the version of the format of the sealed bundles
+const Version = 2
+
+// hkdfInfo binds the keys derived for the bundles to their use
+var hkdfInfo = []byte("fabric-token-sdk audit bundle")
+
+// ErrCannotOpen is returned when a sealed bundle cannot be decrypted with the passed key
+var ErrCannotOpen = errors.New("invalid key or corrupted audit bundle")
+
+// ErrInvalidSignature is returned when the signature of a sealed bundle does not verify against the key of the auditor
+var ErrInvalidSignature = errors.New("invalid signature of the audit bundle")
+
+// Signer signs the sealed bundles, like the signer of the auditor identity
+type Signer interface {
+	Sign(message []byte) ([]byte, error)
+}
+
+// Verifier verifies the signature of the sealed bundles
+type Verifier interface {
+	Verify(message, sigma []byte) error
+}
+
+// NewSigner returns a signer of low-S ECDSA signatures, over SHA-256, with the passed key,
+// as verified by the verifier of an x509 identity
+func NewSigner(sk *ecdsa.PrivateKey) Signer {
+	return &ecdsaSigner{sk: sk}
+}
+
+type ecdsaSigner struct {
+	sk *ecdsa.PrivateKey
+}
+
+func (s *ecdsaSigner) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	r, ss, err := ecdsa.Sign(rand.Reader, s.sk, digest[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign")
+	}
+	n := s.sk.Curve.Params().N
+	if ss.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		ss.Sub(n, ss)
+	}
+	return asn1.Marshal(struct{ R, S *big.Int }{r, ss})
+}
+
+// NewVerifier returns a verifier of the ECDSA signatures, over SHA-256, of the holder of the passed key,
+// as produced by the signer of an x509 auditor identity
+func NewVerifier(pk *ecdsa.PublicKey) Verifier {
+	return msp.NewECDSAVerifier(pk)
+}
+
+// Request is a token request of a bundle
+type Request struct {
+	TxID string
+	// Raw is the token request as stored in the auditdb, as returned by token.Request.Bytes.
+	// Its metadata carries the material to decrypt the tokens and the identities of the request.
+	Raw []byte
+}
+
+// Bundle contains the audit records of the transactions stored by the auditor in a time range,
+// together with their token requests
+type Bundle struct {
+	TMSID token.TMSID
+	// From and To bound the time range, if not nil
+	From *time.Time
+	To   *time.Time
+	// CreatedAt is the time the bundle was created
+	CreatedAt time.Time
+	// PublicParams are the public parameters of the TMS at the time the bundle was created
+	PublicParams []byte
+	Transactions []*driver.TransactionRecord
+	Movements    []*driver.MovementRecord
+	Requests     []*Request
+}
+
+// Source is where the records of a bundle come from, like the auditdb
+type Source interface {
+	Transactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error)
+	Movements(params driver.QueryMovementsParams) ([]*driver.MovementRecord, error)
+	GetTokenRequests(txIDs []string) (map[string][]byte, error)
+}
+
+// New returns a bundle with the transaction and movement records of the passed source stored in the passed time range,
+// and the token requests they refer to
+func New(source Source, tmsID token.TMSID, publicParams []byte, from, to *time.Time) (*Bundle, error) {
+	b := &Bundle{
+		TMSID:        tmsID,
+		From:         from,
+		To:           to,
+		CreatedAt:    time.Now().UTC(),
+		PublicParams: publicParams,
+	}
+	it, err := source.Transactions(driver.QueryTransactionsParams{From: from, To: to})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query transactions")
+	}
+	defer it.Close()
+	for {
+		record, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to read transactions")
+		}
+		if record == nil {
+			break
+		}
+		b.Transactions = append(b.Transactions, record)
+	}
+	b.Movements, err = source.Movements(driver.QueryMovementsParams{
+		MovementDirection: driver.All,
+		SearchDirection:   driver.FromBeginning,
+		From:              from,
+		To:                to,
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query movements")
+	}
+
+	txIDs := b.txIDs()
+	requests, err := source.GetTokenRequests(txIDs)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get token requests")
+	}
+	for _, txID := range txIDs {
+		raw, ok := requests[txID]
+		if !ok {
+			return nil, errors.Errorf("token request of [%s] not found", txID)
+		}
+		b.Requests = append(b.Requests, &Request{TxID: txID, Raw: raw})
+	}
+	return b, nil
+}
+
+// txIDs returns the ids of the transactions the records of the bundle refer to, in order of appearance
+func (b *Bundle) txIDs() []string {
+	var txIDs []string
+	seen := map[string]bool{}
+	add := func(txID string) {
+		if !seen[txID] {
+			seen[txID] = true
+			txIDs = append(txIDs, txID)
+		}
+	}
+	for _, t := range b.Transactions {
+		add(t.TxID)
+	}
+	for _, m := range b.Movements {
+		add(m.TxID)
+	}
+	return txIDs
+}
+
+// Verify checks that the records of the bundle are in its time range,
+// and that each of them refers to a token request of the bundle bound to the same transaction
+func (b *Bundle) Verify() error {
+	requests := map[string]bool{}
+	for _, r := range b.Requests {
+		request := token.NewRequest(nil, "")
+		if err := request.FromBytes(r.Raw); err != nil {
+			return errors.WithMessagef(err, "invalid token request [%s]", r.TxID)
+		}
+		if request.Anchor != r.TxID {
+			return errors.Errorf("token request [%s] is bound to transaction [%s]", r.TxID, request.Anchor)
+		}
+		requests[r.TxID] = true
+	}
+	for _, t := range b.Transactions {
+		if !inRange(t.Timestamp, b.From, b.To) {
+			return errors.Errorf("transaction record of [%s] stored at [%s] is out of range", t.TxID, t.Timestamp)
+		}
+		if !requests[t.TxID] {
+			return errors.Errorf("token request of [%s] not found", t.TxID)
+		}
+	}
+	for _, m := range b.Movements {
+		if !inRange(m.Timestamp, b.From, b.To) {
+			return errors.Errorf("movement record of [%s] stored at [%s] is out of range", m.TxID, m.Timestamp)
+		}
+		if !requests[m.TxID] {
+			return errors.Errorf("token request of [%s] not found", m.TxID)
+		}
+	}
+	return nil
+}
+
+func inRange(t time.Time, from, to *time.Time) bool {
+	if from != nil && !from.IsZero() && t.Before(*from) {
+		return false
+	}
+	if to != nil && !to.IsZero() && t.After(*to) {
+		return false
+	}
+	return true
+}
+
+// SealedBundle is a bundle encrypted for the holder of a P-256 key, and signed by the auditor.
+// The key encrypting the bundle is derived, with HKDF-SHA256, from the ECDH of an ephemeral key and the key of the recipient.
+// The bundle is encrypted with AES-256-GCM, the version, the ephemeral key and the signer are authenticated as well.
+// The signature covers all the other fields, so that the recipient can check who sealed the bundle.
+type SealedBundle struct {
+	Version int
+	// EphemeralKey is the uncompressed point of the ephemeral public key
+	EphemeralKey []byte
+	Nonce        []byte
+	Ciphertext   []byte
+	// Signer is the identity of the auditor that sealed the bundle
+	Signer []byte
+	// Signature is the signature of the auditor on the sealed bundle
+	Signature []byte
+}
+
+// Seal encrypts the passed bundle for the holder of the private key of the passed public key,
+// and signs it with the passed signer of the passed identity
+func Seal(b *Bundle, recipient *ecdsa.PublicKey, signerID []byte, signer Signer) (*SealedBundle, error) {
+	if len(signerID) == 0 || signer == nil {
+		return nil, errors.New("the signer of the audit bundle must be set")
+	}
+	pk, err := ecdhPublicKey(recipient)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate ephemeral key")
+	}
+	secret, err := ephemeral.ECDH(pk)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute shared secret")
+	}
+	sealed := &SealedBundle{Version: Version, EphemeralKey: ephemeral.PublicKey().Bytes(), Signer: signerID}
+	aead, err := newAEAD(secret, sealed.EphemeralKey)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal bundle")
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, plaintext, sealed.header())
+	if sealed.Signature, err = signer.Sign(sealed.signedMessage()); err != nil {
+		return nil, errors.Wrap(err, "failed to sign audit bundle")
+	}
+	return sealed, nil
+}
+
+// Open checks the signature of the passed sealed bundle with the passed verifier of the auditor,
+// and decrypts it with the passed private key. It does not verify the records of the bundle.
+func Open(sealed *SealedBundle, key *ecdsa.PrivateKey, verifier Verifier) (*Bundle, error) {
+	if sealed.Version != Version {
+		return nil, errors.Errorf("unsupported audit bundle version [%d]", sealed.Version)
+	}
+	if verifier == nil {
+		return nil, errors.New("the verifier of the auditor must be set")
+	}
+	if len(sealed.Signer) == 0 || len(sealed.Signature) == 0 {
+		return nil, ErrInvalidSignature
+	}
+	if err := verifier.Verify(sealed.signedMessage(), sealed.Signature); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	sk, err := key.ECDH()
+	if err != nil || sk.Curve() != ecdh.P256() {
+		return nil, errors.New("the key of the recipient must be a P-256 key")
+	}
+	ephemeral, err := ecdh.P256().NewPublicKey(sealed.EphemeralKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid ephemeral key")
+	}
+	secret, err := sk.ECDH(ephemeral)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute shared secret")
+	}
+	aead, err := newAEAD(secret, sealed.EphemeralKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, ErrCannotOpen
+	}
+	plaintext, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, sealed.header())
+	if err != nil {
+		return nil, ErrCannotOpen
+	}
+	b := &Bundle{}
+	if err := json.Unmarshal(plaintext, b); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal bundle")
+	}
+	return b, nil
+}
+
+// header is the additional data authenticated with the bundle
+func (s *SealedBundle) header() []byte {
+	return append(append([]byte{byte(s.Version)}, s.EphemeralKey...), s.Signer...)
+}
+
+// signedMessage is the message signed by the auditor, the length prefixed fields of the sealed bundle but the signature
+func (s *SealedBundle) signedMessage() []byte {
+	var msg []byte
+	for _, field := range [][]byte{{byte(s.Version)}, s.EphemeralKey, s.Signer, s.Nonce, s.Ciphertext} {
+		msg = binary.BigEndian.AppendUint32(msg, uint32(len(field)))
+		msg = append(msg, field...)
+	}
+	return msg
+}
+
+func newAEAD(secret []byte, salt []byte) (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, hkdfInfo), key); err != nil {
+		return nil, errors.Wrap(err, "failed to derive key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create gcm")
+	}
+	return aead, nil
+}
+
+func ecdhPublicKey(pk *ecdsa.PublicKey) (*ecdh.PublicKey, error) {
+	if pk == nil || pk.Curve != elliptic.P256() {
+		return nil, errors.New("the key of the recipient must be a P-256 key")
+	}
+	res, err := pk.ECDH()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid key of the recipient")
+	}
+	return res, nil
+}
+
+// ParsePublicKey parses a PEM encoded P-256 public key, in PKIX form, or the public key of a PEM encoded certificate
+func ParsePublicKey(raw []byte) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var pk any
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse certificate")
+		}
+		pk = cert.PublicKey
+	default:
+		var err error
+		if pk, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, errors.Wrap(err, "failed to parse public key")
+		}
+	}
+	ecPK, ok := pk.(*ecdsa.PublicKey)
+	if !ok || ecPK.Curve != elliptic.P256() {
+		return nil, errors.New("the key must be a P-256 key")
+	}
+	return ecPK, nil
+}
+
+// ParsePrivateKey parses a PEM encoded P-256 private key, in SEC 1 or PKCS #8 form
+func ParsePrivateKey(raw []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var key any
+	var err error
+	if key, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+		if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+			return nil, errors.Wrap(err, "failed to parse private key")
+		}
+	}
+	sk, ok := key.(*ecdsa.PrivateKey)
+	if !ok || sk.Curve != elliptic.P256() {
+		return nil, errors.New("the key must be a P-256 key")
+	}
+	return sk, nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bundle
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/common/utils/collections"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+type sourceMock struct {
+	transactions []*driver.TransactionRecord
+	movements    []*driver.MovementRecord
+	requests     map[string][]byte
+}
+
+func (s *sourceMock) Transactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error) {
+	var res []*driver.TransactionRecord
+	for _, t := range s.transactions {
+		if inRange(t.Timestamp, params.From, params.To) {
+			res = append(res, t)
+		}
+	}
+	return collections.NewSliceIterator(res), nil
+}
+
+func (s *sourceMock) Movements(params driver.QueryMovementsParams) ([]*driver.MovementRecord, error) {
+	var res []*driver.MovementRecord
+	for _, m := range s.movements {
+		if inRange(m.Timestamp, params.From, params.To) {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+func (s *sourceMock) GetTokenRequests(txIDs []string) (map[string][]byte, error) {
+	res := map[string][]byte{}
+	for _, txID := range txIDs {
+		if raw, ok := s.requests[txID]; ok {
+			res[txID] = raw
+		}
+	}
+	return res, nil
+}
+
+func request(t *testing.T, txID string) []byte {
+	raw, err := token.NewRequest(nil, txID).Bytes()
+	assert.NoError(t, err)
+	return raw
+}
+
+func TestBundle(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := &sourceMock{
+		transactions: []*driver.TransactionRecord{
+			{TxID: "tx1", Timestamp: t0},
+			{TxID: "tx2", Timestamp: t0.Add(time.Hour)},
+			{TxID: "tx3", Timestamp: t0.Add(2 * time.Hour)},
+		},
+		movements: []*driver.MovementRecord{
+			{TxID: "tx1", EnrollmentID: "alice", Timestamp: t0},
+			{TxID: "tx2", EnrollmentID: "bob", Timestamp: t0.Add(time.Hour)},
+			{TxID: "tx3", EnrollmentID: "alice", Timestamp: t0.Add(2 * time.Hour)},
+		},
+		requests: map[string][]byte{},
+	}
+	for _, txID := range []string{"tx1", "tx2", "tx3"} {
+		source.requests[txID] = request(t, txID)
+	}
+	from, to := t0.Add(time.Hour), t0.Add(2*time.Hour)
+	b, err := New(source, token.TMSID{Network: "n1", Namespace: "token"}, []byte("pp"), &from, &to)
+	assert.NoError(t, err)
+	assert.Len(t, b.Transactions, 2)
+	assert.Len(t, b.Movements, 2)
+	assert.Len(t, b.Requests, 2)
+	assert.Equal(t, "tx2", b.Requests[0].TxID)
+	assert.NoError(t, b.Verify())
+
+	// seal and open
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	auditor, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	verifier := NewVerifier(&auditor.PublicKey)
+	_, err = Seal(b, &sk.PublicKey, nil, nil)
+	assert.Error(t, err)
+	sealed, err := Seal(b, &sk.PublicKey, []byte("auditor"), NewSigner(auditor))
+	assert.NoError(t, err)
+	raw, err := json.Marshal(sealed)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(raw), "tx2")
+	received := &SealedBundle{}
+	assert.NoError(t, json.Unmarshal(raw, received))
+	opened, err := Open(received, sk, verifier)
+	assert.NoError(t, err)
+	assert.Equal(t, b.TMSID, opened.TMSID)
+	assert.Equal(t, []byte("pp"), opened.PublicParams)
+	assert.Len(t, opened.Transactions, 2)
+	assert.NoError(t, opened.Verify())
+
+	// wrong key, wrong auditor and tampering
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, err = Open(received, other, verifier)
+	assert.ErrorIs(t, err, ErrCannotOpen)
+	_, err = Open(received, sk, NewVerifier(&other.PublicKey))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	received.Signer = []byte("someone else")
+	_, err = Open(received, sk, verifier)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	received.Signer = []byte("auditor")
+	received.Signature[len(received.Signature)-1] ^= 1
+	_, err = Open(received, sk, verifier)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	received.Signature[len(received.Signature)-1] ^= 1
+	received.Ciphertext[0] ^= 1
+	_, err = Open(received, sk, verifier)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// a bundle signed again after tampering still fails to decrypt
+	received.Signature, err = NewSigner(auditor).Sign(received.signedMessage())
+	assert.NoError(t, err)
+	_, err = Open(received, sk, verifier)
+	assert.ErrorIs(t, err, ErrCannotOpen)
+
+	// a request bound to another transaction, a record out of range, a missing request
+	opened.Requests[0].Raw = request(t, "tx1")
+	assert.Error(t, opened.Verify())
+	opened.Requests[0].Raw = request(t, "tx2")
+	opened.Transactions[0].Timestamp = t0
+	assert.Error(t, opened.Verify())
+	opened.Transactions[0].Timestamp = from
+	opened.Requests = opened.Requests[1:]
+	assert.Error(t, opened.Verify())
+
+	// a missing request fails the export
+	delete(source.requests, "tx3")
+	_, err = New(source, token.TMSID{}, nil, &from, &to)
+	assert.Error(t, err)
+}
+
+func TestParseKeys(t *testing.T) {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	rawPK, err := x509.MarshalPKIXPublicKey(&sk.PublicKey)
+	assert.NoError(t, err)
+	pk, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rawPK}))
+	assert.NoError(t, err)
+	assert.True(t, pk.Equal(&sk.PublicKey))
+
+	rawSK, err := x509.MarshalECPrivateKey(sk)
+	assert.NoError(t, err)
+	parsed, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawSK}))
+	assert.NoError(t, err)
+	assert.True(t, parsed.Equal(sk))
+
+	rawSK, err = x509.MarshalPKCS8PrivateKey(sk)
+	assert.NoError(t, err)
+	parsed, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rawSK}))
+	assert.NoError(t, err)
+	assert.True(t, parsed.Equal(sk))
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	rawPK, err = x509.MarshalPKIXPublicKey(&p384.PublicKey)
+	assert.NoError(t, err)
+	_, err = ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rawPK}))
+	assert.Error(t, err)
+	_, err = ParsePublicKey([]byte("not a key"))
+	assert.Error(t, err)
+}
@@ -8,9 +8,11 @@ package auditdb
 
 import (
 	"context"
+	"encoding/json"
 	"reflect"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/common/utils/collections"
@@ -120,6 +122,64 @@ type QueryTransactionsParams = driver.QueryTransactionsParams
 // QueryTokenRequestsParams defines the parameters for querying token requests
 type QueryTokenRequestsParams = driver.QueryTokenRequestsParams
 
+// IssuerAttributionRecord attributes the tokens created by an issue action to an issuer
+type IssuerAttributionRecord = driver.IssuerAttributionRecord
+
+// QueryIssuerAttributionsParams defines the parameters for querying issuer attributions
+type QueryIssuerAttributionsParams = driver.QueryIssuerAttributionsParams
+
+// ValidationRecord is the record of the validation of a token request, it holds, for instance, the results of the inspectors of the auditor
+type ValidationRecord = driver.ValidationRecord
+
+// QueryValidationRecordsParams defines the parameters for querying validation records
+type QueryValidationRecordsParams = driver.QueryValidationRecordsParams
+
+// StatusOverride describes who overrides the status of a transaction, and why
+type StatusOverride = driver.StatusOverride
+
+// StatusOverrideRecord is the audit record of a status override
+type StatusOverrideRecord = driver.StatusOverrideRecord
+
+// QueryStatusOverridesParams defines the parameters for querying status overrides
+type QueryStatusOverridesParams = driver.QueryStatusOverridesParams
+
+// ErasedRecords counts the records in which the erasure of an enrollment ID replaced it with a pseudonym
+type ErasedRecords = driver.ErasedRecords
+
+// QueryMovementsParams defines the parameters for querying movements
+type QueryMovementsParams = driver.QueryMovementsParams
+
+// CorrectionType is the type of correction of a movement
+type CorrectionType = driver.CorrectionType
+
+const (
+	// Reversal cancels the amount of a movement, with the corrections applied so far
+	Reversal = driver.Reversal
+	// Adjustment adds an amount, positive or negative, to the amount of a movement
+	Adjustment = driver.Adjustment
+)
+
+// MovementCorrection describes a correction of a movement, who performs it, and why
+type MovementCorrection = driver.MovementCorrection
+
+// MovementCorrectionRecord is the record of a correction of a movement
+type MovementCorrectionRecord = driver.MovementCorrectionRecord
+
+// QueryMovementCorrectionsParams defines the parameters for querying movement corrections
+type QueryMovementCorrectionsParams = driver.QueryMovementCorrectionsParams
+
+// AuditResponseRecord is the response of the auditor to an audit request, kept until it is delivered to the requester
+type AuditResponseRecord = driver.AuditResponseRecord
+
+// ExportRecord is an entry of the export outbox, kept until it is published to an external system
+type ExportRecord = driver.ExportRecord
+
+// ExportPayload is the content of an entry of the export outbox: the records of a transaction as stored in the auditdb
+type ExportPayload struct {
+	Transactions []TransactionRecord
+	Movements    []MovementRecord
+}
+
 // Wallet models a wallet
 type Wallet interface {
 	// ID returns the wallet ID
@@ -133,6 +193,16 @@ type DB struct {
 	*db.StatusSupport
 	db        driver.AuditTransactionDB
 	eIDsLocks sync.Map
+	// writes tracks the in-flight writes to drain on shutdown
+	writes db.WriteGate
+	// pseudonymizer, if set, replaces the enrollment IDs in the stored records
+	pseudonymizer atomic.Pointer[Pseudonymizer]
+	// sampler, if set, selects the token requests that get full records
+	sampler atomic.Pointer[Sampler]
+	// backfills serializes the backfills, so that the same records are not added twice
+	backfills sync.Mutex
+	// export, if true, makes the records appended go to the export outbox as well
+	export atomic.Bool
 
 	// status related fields
 	pendingTXs []string
@@ -147,8 +217,22 @@ func newDB(p driver.AuditTransactionDB) *DB {
 	}
 }
 
-// Append appends send and receive movements, and transaction records corresponding to the passed token request
-func (d *DB) Append(req *token.Request) error {
+// Append appends send and receive movements, and transaction records corresponding to the passed token request.
+// The passed issuer attributions, if any, are stored in the same database transaction.
+// Their transaction id and timestamp are set to those of the request.
+func (d *DB) Append(req *token.Request, attributions ...*IssuerAttributionRecord) error {
+	return d.AppendWithResponse(req, nil, nil, attributions...)
+}
+
+// AppendWithResponse appends the records of the passed token request as Append does.
+// The passed audit response, if not nil, is stored in the same database transaction, and it is marked as not sent.
+// Its transaction id and timestamp are set to those of the request.
+// The passed validation metadata, if not empty, is stored in the same database transaction as the validation record of the request.
+func (d *DB) AppendWithResponse(req *token.Request, response *AuditResponseRecord, validation map[string][]byte, attributions ...*IssuerAttributionRecord) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot append record [%s]", req.Anchor)
+	}
+	defer d.writes.Exit()
 	logger.Debugf("appending new record... [%s]", req.Anchor)
 
 	record, err := req.AuditRecord()
@@ -166,9 +250,29 @@ func (d *DB) Append(req *token.Request) error {
 	if err != nil {
 		return errors.WithMessage(err, "failed parsing movements from audit record")
 	}
-	txs, err := ttxdb.TransactionRecords(record, now)
-	if err != nil {
-		return errors.WithMessage(err, "failed parsing transactions from audit record")
+	var txs []TransactionRecord
+	if s := d.sampler.Load(); s == nil || s.Sampled(record.Anchor) {
+		txs, err = ttxdb.TransactionRecords(record, now)
+		if err != nil {
+			return errors.WithMessage(err, "failed parsing transactions from audit record")
+		}
+	} else {
+		logger.Debugf("storing aggregate-only records for [%s]", record.Anchor)
+	}
+
+	if p := d.pseudonymizer.Load(); p != nil {
+		for i := range mov {
+			if mov[i].EnrollmentID, err = d.pseudonym(p, mov[i].EnrollmentID); err != nil {
+				return err
+			}
+		}
+		if err := d.pseudonymize(p, txs); err != nil {
+			return err
+		}
+		// the token request carries the enrollment IDs in its audit metadata
+		if raw, err = p.SealRequest(record.Anchor, raw); err != nil {
+			return errors.WithMessagef(err, "failed to seal token request [%s]", record.Anchor)
+		}
 	}
 
 	logger.Debugf("storing new records... [%d,%d,%d]", len(raw), len(mov), len(txs))
@@ -197,6 +301,46 @@ func (d *DB) Append(req *token.Request) error {
 			return errors.WithMessagef(err, "append transactions for txid [%s] failed", record.Anchor)
 		}
 	}
+	if reference := req.Reference(); len(reference) != 0 {
+		if err := w.AddReference(record.Anchor, reference); err != nil {
+			w.Rollback()
+			return errors.WithMessagef(err, "append reference for txid [%s] failed", record.Anchor)
+		}
+	}
+	if len(validation) != 0 {
+		if err := w.AddValidationRecord(record.Anchor, validation); err != nil {
+			w.Rollback()
+			return errors.WithMessagef(err, "append validation record for txid [%s] failed", record.Anchor)
+		}
+	}
+	for _, attribution := range attributions {
+		attribution.TxID = record.Anchor
+		attribution.Timestamp = now
+		if err := w.AddIssuerAttribution(attribution); err != nil {
+			w.Rollback()
+			return errors.WithMessagef(err, "append issuer attributions for txid [%s] failed", record.Anchor)
+		}
+	}
+	if d.export.Load() {
+		payload, err := json.Marshal(&ExportPayload{Transactions: txs, Movements: mov})
+		if err != nil {
+			w.Rollback()
+			return errors.Wrapf(err, "failed to marshal export record for txid [%s]", record.Anchor)
+		}
+		if err := w.AddExportRecord(record.Anchor, payload); err != nil {
+			w.Rollback()
+			return errors.WithMessagef(err, "append export record for txid [%s] failed", record.Anchor)
+		}
+	}
+	if response != nil {
+		response.TxID = record.Anchor
+		response.Sent = false
+		response.Timestamp = now
+		if err := w.AddAuditResponse(response); err != nil {
+			w.Rollback()
+			return errors.WithMessagef(err, "append audit response for txid [%s] failed", record.Anchor)
+		}
+	}
 	if err := w.Commit(); err != nil {
 		return errors.WithMessagef(err, "committing tx for txid [%s] failed", record.Anchor)
 	}
@@ -205,6 +349,164 @@ func (d *DB) Append(req *token.Request) error {
 	return nil
 }
 
+// Backfill stores the transaction records of the passed token request, if they are missing.
+// It is used to get full detail on demand for the token requests that got aggregate-only records.
+// The token request must have been appended already, and the backfilled records are timestamped with the time of the backfill.
+func (d *DB) Backfill(req *token.Request) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot backfill record [%s]", req.Anchor)
+	}
+	defer d.writes.Exit()
+	d.backfills.Lock()
+	defer d.backfills.Unlock()
+
+	it, err := d.db.QueryTransactions(QueryTransactionsParams{IDs: []string{req.Anchor}})
+	if err != nil {
+		return errors.WithMessagef(err, "failed querying transactions of [%s]", req.Anchor)
+	}
+	existing, err := it.Next()
+	it.Close()
+	if err != nil {
+		return errors.WithMessagef(err, "failed querying transactions of [%s]", req.Anchor)
+	}
+	if existing != nil {
+		logger.Debugf("records of [%s] already complete, nothing to backfill", req.Anchor)
+		return nil
+	}
+
+	record, err := req.AuditRecord()
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting audit records for request [%s]", req.Anchor)
+	}
+	txs, err := ttxdb.TransactionRecords(record, time.Now().UTC())
+	if err != nil {
+		return errors.WithMessage(err, "failed parsing transactions from audit record")
+	}
+	if p := d.pseudonymizer.Load(); p != nil {
+		if err := d.pseudonymize(p, txs); err != nil {
+			return err
+		}
+	}
+
+	w, err := d.db.BeginAtomicWrite()
+	if err != nil {
+		return errors.WithMessagef(err, "begin update for txid [%s] failed", record.Anchor)
+	}
+	for _, tx := range txs {
+		if err := w.AddTransaction(&tx); err != nil {
+			w.Rollback()
+			return errors.WithMessagef(err, "backfill transactions for txid [%s] failed", record.Anchor)
+		}
+	}
+	if err := w.Commit(); err != nil {
+		return errors.WithMessagef(err, "committing tx for txid [%s] failed", record.Anchor)
+	}
+	logger.Debugf("backfilled [%d] transaction records of [%s]", len(txs), record.Anchor)
+	return nil
+}
+
+func (d *DB) pseudonymize(p *Pseudonymizer, txs []TransactionRecord) error {
+	var err error
+	for i := range txs {
+		if txs[i].SenderEID, err = d.pseudonym(p, txs[i].SenderEID); err != nil {
+			return err
+		}
+		if txs[i].RecipientEID, err = d.pseudonym(p, txs[i].RecipientEID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pseudonym returns the pseudonym of the passed enrollment ID.
+// The enrollment ID is stored sealed the first time its pseudonym is seen, so that ResolveEnrollmentID can find it.
+func (d *DB) pseudonym(p *Pseudonymizer, eID string) (string, error) {
+	pseudonym := p.Pseudonym(eID)
+	if len(eID) == 0 || p.known(pseudonym) {
+		return pseudonym, nil
+	}
+	sealed, err := p.Seal(pseudonym, eID)
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed to seal enrollment ID of [%s]", pseudonym)
+	}
+	if err := d.db.AddPseudonym(pseudonym, sealed); err != nil {
+		return "", errors.WithMessagef(err, "failed to store pseudonym [%s]", pseudonym)
+	}
+	p.remember(pseudonym, eID)
+	return pseudonym, nil
+}
+
+// ResolveEnrollmentID returns the enrollment ID the passed pseudonym has been computed from.
+// If no pseudonymizer is in use, the passed value is returned as is.
+// It returns an error if the pseudonym is unknown to the database.
+func (d *DB) ResolveEnrollmentID(pseudonym string) (string, error) {
+	p := d.Pseudonymizer()
+	if p == nil || len(pseudonym) == 0 {
+		return pseudonym, nil
+	}
+	if eID, ok := p.EnrollmentID(pseudonym); ok {
+		return eID, nil
+	}
+	sealed, err := d.db.GetPseudonym(pseudonym)
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed to get pseudonym [%s]", pseudonym)
+	}
+	if sealed == nil {
+		return "", errors.Errorf("unknown pseudonym [%s]", pseudonym)
+	}
+	return p.Open(pseudonym, sealed)
+}
+
+// openRequest decrypts the passed token request if sealed by the pseudonymizer
+func (d *DB) openRequest(txID string, request []byte) ([]byte, error) {
+	p := d.Pseudonymizer()
+	if p == nil || request == nil {
+		return request, nil
+	}
+	return p.OpenRequest(txID, request)
+}
+
+// SetSampler makes the database store full records only for the token requests selected by the passed sampler,
+// and aggregate-only records for the others. Nil restores full records for all token requests.
+// The auditor still signs all the token requests.
+func (d *DB) SetSampler(s *Sampler) {
+	d.sampler.Store(s)
+}
+
+// Sampler returns the sampler in use, nil if all token requests get full records
+func (d *DB) Sampler() *Sampler {
+	return d.sampler.Load()
+}
+
+// SetPseudonymizer makes the database store pseudonyms in place of the enrollment IDs
+// of the records appended from now on, and store their token requests sealed.
+// Enrollment IDs passed to the payments and holdings filters are pseudonymized as well.
+func (d *DB) SetPseudonymizer(p *Pseudonymizer) {
+	d.pseudonymizer.Store(p)
+}
+
+// Pseudonymizer returns the pseudonymizer in use, nil if enrollment IDs are stored in clear
+func (d *DB) Pseudonymizer() *Pseudonymizer {
+	return d.pseudonymizer.Load()
+}
+
+// SetExport makes the records appended from now on go, or not, to the export outbox as well,
+// in the same database transaction. See PendingExports.
+func (d *DB) SetExport(enabled bool) {
+	d.export.Store(enabled)
+}
+
+// PendingExports returns the entries of the export outbox not exported yet whose transaction is final,
+// that is, either confirmed or deleted, the oldest first. If limit is positive, at most limit entries are returned.
+func (d *DB) PendingExports(limit int) ([]*ExportRecord, error) {
+	return d.db.QueryPendingExportRecords(limit)
+}
+
+// MarkExported records that the entries of the export outbox for the passed transaction ids have been exported
+func (d *DB) MarkExported(txIDs []string) error {
+	return d.db.MarkExported(txIDs)
+}
+
 // Transactions returns an iterators of transaction records filtered by the given params.
 func (d *DB) Transactions(params QueryTransactionsParams) (driver.TransactionIterator, error) {
 	return d.db.QueryTransactions(params)
@@ -212,7 +514,45 @@ func (d *DB) Transactions(params QueryTransactionsParams) (driver.TransactionIte
 
 // TokenRequests returns an iterator over the token requests matching the passed params
 func (d *DB) TokenRequests(params QueryTokenRequestsParams) (driver.TokenRequestIterator, error) {
-	return d.db.QueryTokenRequests(params)
+	it, err := d.db.QueryTokenRequests(params)
+	if err != nil || d.Pseudonymizer() == nil {
+		return it, err
+	}
+	return collections.Map(it, func(r *driver.TokenRequestRecord) (*driver.TokenRequestRecord, error) {
+		if r == nil {
+			return nil, nil
+		}
+		request, err := d.openRequest(r.TxID, r.TokenRequest)
+		if err != nil {
+			return nil, err
+		}
+		r.TokenRequest = request
+		return r, nil
+	}), nil
+}
+
+// ValidationRecords returns an iterator over the validation records matching the passed params
+func (d *DB) ValidationRecords(params QueryValidationRecordsParams) (driver.ValidationRecordsIterator, error) {
+	it, err := d.db.QueryValidations(params)
+	if err != nil || d.Pseudonymizer() == nil {
+		return it, err
+	}
+	return collections.Map(it, func(r *driver.ValidationRecord) (*driver.ValidationRecord, error) {
+		if r == nil {
+			return nil, nil
+		}
+		request, err := d.openRequest(r.TxID, r.TokenRequest)
+		if err != nil {
+			return nil, err
+		}
+		r.TokenRequest = request
+		return r, nil
+	}), nil
+}
+
+// IssuerAttributions returns the issuer attribution records matching the passed params
+func (d *DB) IssuerAttributions(params QueryIssuerAttributionsParams) ([]*IssuerAttributionRecord, error) {
+	return d.db.QueryIssuerAttributions(params)
 }
 
 // NewPaymentsFilter returns a programmable filter over the payments sent or received by enrollment IDs.
@@ -231,8 +571,90 @@ func (d *DB) NewHoldingsFilter() *HoldingsFilter {
 
 // SetStatus sets the status of the audit records with the passed transaction id to the passed status
 func (d *DB) SetStatus(ctx context.Context, txID string, status driver.TxStatus, message string) error {
+	return d.setStatus(ctx, txID, status, message, func() error {
+		return d.db.SetStatus(ctx, txID, status, message)
+	})
+}
+
+// OverrideStatus sets the status of the audit records as SetStatus does, and records the override in the status overrides.
+// It must be used, instead of SetStatus, to change the status outside the finality listener, for instance to repair a transaction.
+func (d *DB) OverrideStatus(ctx context.Context, txID string, status driver.TxStatus, message string, override StatusOverride) error {
+	logger.Infof("override status [%s][%s] by [%s]: %s", txID, status, override.Operator, override.Reason)
+	return d.setStatus(ctx, txID, status, message, func() error {
+		return d.db.OverrideStatus(ctx, txID, status, message, override)
+	})
+}
+
+// StatusOverrides returns the status overrides matching the passed params, the oldest first
+func (d *DB) StatusOverrides(params QueryStatusOverridesParams) ([]*StatusOverrideRecord, error) {
+	return d.db.QueryStatusOverrides(params)
+}
+
+// Movements returns the movements matching the passed params, with their ids to refer to them in corrections.
+// The amounts are those recorded, or the corrected ones if params.Corrected is true.
+func (d *DB) Movements(params QueryMovementsParams) ([]*MovementRecord, error) {
+	if p := d.Pseudonymizer(); p != nil {
+		params.EnrollmentIDs = p.Pseudonyms(params.EnrollmentIDs)
+	}
+	return d.db.QueryMovements(params)
+}
+
+// CorrectMovement appends the passed correction to the movement it refers to.
+// The audit records are never edited, the corrections are applied when the movements are queried with Corrected.
+func (d *DB) CorrectMovement(correction MovementCorrection) (*MovementCorrectionRecord, error) {
+	if err := d.writes.Enter(); err != nil {
+		return nil, errors.WithMessagef(err, "cannot correct movement [%s]", correction.MovementID)
+	}
+	defer d.writes.Exit()
+	logger.Infof("correct movement [%s][%d] by [%s]: %s", correction.MovementID, correction.Type, correction.Operator, correction.Reason)
+	return d.db.CorrectMovement(correction)
+}
+
+// MovementCorrections returns the movement corrections matching the passed params, the oldest first
+func (d *DB) MovementCorrections(params QueryMovementCorrectionsParams) ([]*MovementCorrectionRecord, error) {
+	return d.db.QueryMovementCorrections(params)
+}
+
+// TxIDsByReference returns the ids of the audited transactions bound to the passed hash of an external document, the oldest first
+func (d *DB) TxIDsByReference(reference []byte) ([]string, error) {
+	return d.db.GetTxIDsByReference(reference)
+}
+
+// EraseEnrollmentID replaces the passed enrollment ID with the passed pseudonym in the transaction and movement records.
+// If a pseudonymizer is in use, the records storing the pseudonymizer's pseudonym of the enrollment ID are replaced as well,
+// and the pseudonymizer's pseudonym no longer resolves to the enrollment ID.
+// The amounts and the token requests are kept.
+func (d *DB) EraseEnrollmentID(eID string, pseudonym string) (*ErasedRecords, error) {
+	if err := d.writes.Enter(); err != nil {
+		return nil, errors.WithMessagef(err, "cannot erase enrollment ID")
+	}
+	defer d.writes.Exit()
+	res, err := d.db.EraseEnrollmentID(eID, pseudonym)
+	if err != nil {
+		return nil, err
+	}
+	if p := d.Pseudonymizer(); p != nil {
+		erased, err := d.db.EraseEnrollmentID(p.Pseudonym(eID), pseudonym)
+		if err != nil {
+			return nil, err
+		}
+		res.Transactions += erased.Transactions
+		res.Movements += erased.Movements
+		if err := d.db.DeletePseudonym(p.Pseudonym(eID)); err != nil {
+			return nil, err
+		}
+		p.forget(p.Pseudonym(eID))
+	}
+	return res, nil
+}
+
+func (d *DB) setStatus(ctx context.Context, txID string, status driver.TxStatus, message string, update func() error) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot set status [%s]", txID)
+	}
+	defer d.writes.Exit()
 	logger.Debugf("set status [%s][%s]...", txID, status)
-	if err := d.db.SetStatus(ctx, txID, status, message); err != nil {
+	if err := update(); err != nil {
 		return errors.Wrapf(err, "failed setting status [%s][%s]", txID, driver.TxStatusMessage[status])
 	}
 
@@ -260,7 +682,86 @@ func (d *DB) GetStatus(txID string) (TxStatus, string, error) {
 
 // GetTokenRequest returns the token request bound to the passed transaction id, if available.
 func (d *DB) GetTokenRequest(txID string) ([]byte, error) {
-	return d.db.GetTokenRequest(txID)
+	request, err := d.db.GetTokenRequest(txID)
+	if err != nil {
+		return nil, err
+	}
+	return d.openRequest(txID, request)
+}
+
+// GetTokenRequests returns the token requests bound to the passed transaction ids, indexed by transaction id.
+// The transaction ids that are not found are missing in the returned map.
+func (d *DB) GetTokenRequests(txIDs []string) (map[string][]byte, error) {
+	requests, err := d.db.GetTokenRequests(txIDs)
+	if err != nil {
+		return nil, err
+	}
+	for txID, request := range requests {
+		if requests[txID], err = d.openRequest(txID, request); err != nil {
+			return nil, err
+		}
+	}
+	return requests, nil
+}
+
+// AuditResponse returns the audit response stored for the passed transaction id, or nil if there is none.
+func (d *DB) AuditResponse(txID string) (*AuditResponseRecord, error) {
+	return d.db.GetAuditResponse(txID)
+}
+
+// PendingAuditResponses returns the audit responses not yet delivered to their requesters, the oldest first.
+func (d *DB) PendingAuditResponses() ([]*AuditResponseRecord, error) {
+	return d.db.QueryPendingAuditResponses()
+}
+
+// MarkAuditResponseSent records that the audit response for the passed transaction id has been delivered.
+func (d *DB) MarkAuditResponseSent(txID string) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot mark audit response [%s] as sent", txID)
+	}
+	defer d.writes.Exit()
+	return d.db.MarkAuditResponseSent(txID)
+}
+
+// Drain makes the database reject new writes and waits for the in-flight ones to complete or for the context to expire
+func (d *DB) Drain(ctx context.Context) error {
+	return d.writes.Drain(ctx)
+}
+
+// Resume makes the database accept writes again after a Drain
+func (d *DB) Resume() {
+	d.writes.Resume()
+}
+
+// SetReadOnly makes the database reject writes, or accept them again
+func (d *DB) SetReadOnly(readOnly bool) {
+	d.writes.SetReadOnly(readOnly)
+}
+
+// SetStatementObserver makes the database notify the passed observer of the interrupted statements
+func (d *DB) SetStatementObserver(o driver.StatementObserver) {
+	db.SetStatementObserver(d.db, o)
+}
+
+// EnableEncryption makes the database encrypt the token requests with the passed cipher
+func (d *DB) EnableEncryption(c driver.ColumnCipher) error {
+	return db.SetColumnCipher(d.db, c)
+}
+
+// TableSizes returns the disk usage of the tables of the database.
+// It returns an error wrapping driver.ErrTableSizesNotSupported if the database cannot report it.
+func (d *DB) TableSizes() ([]driver.TableSize, error) {
+	return db.TableSizes(d.db)
+}
+
+// ReEncrypt re-encrypts with the active key the token requests stored in clear or with a retired key
+func (d *DB) ReEncrypt(ctx context.Context, batchSize int) (int, error) {
+	return db.ReEncrypt(ctx, d.db, &d.writes, batchSize)
+}
+
+// Close closes the database
+func (d *DB) Close() error {
+	return d.db.Close()
 }
 
 // AcquireLocks acquires locks for the passed anchor and enrollment ids.
@@ -0,0 +1,162 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package export
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TransactionKind is the kind of the events carrying a transaction record
+	TransactionKind = "transaction"
+	// MovementKind is the kind of the events carrying a movement record
+	MovementKind = "movement"
+
+	// JSONFormat encodes the events in JSON
+	JSONFormat = "json"
+	// AvroFormat encodes the events in the Avro binary encoding, with schema AvroSchema
+	AvroFormat = "avro"
+)
+
+var actionTypes = map[driver.ActionType]string{
+	driver.Issue:    "issue",
+	driver.Transfer: "transfer",
+	driver.Redeem:   "redeem",
+}
+
+// Event is a transaction record or a movement record of a committed transaction, as published
+type Event struct {
+	// ID identifies the event: the transaction id, the kind, and the index of the record
+	ID string `json:"id"`
+	// Kind is either TransactionKind or MovementKind
+	Kind string `json:"kind"`
+	// TxID is the transaction id
+	TxID string `json:"tx_id"`
+	// ActionType is the type of action of a transaction record: issue, transfer, or redeem
+	ActionType string `json:"action_type,omitempty"`
+	// SenderEID is the enrollment ID of the sender of a transaction record
+	SenderEID string `json:"sender_eid,omitempty"`
+	// RecipientEID is the enrollment ID of the recipient of a transaction record
+	RecipientEID string `json:"recipient_eid,omitempty"`
+	// EnrollmentID is the enrollment ID of a movement record
+	EnrollmentID string `json:"enrollment_id,omitempty"`
+	// TokenType is the type of token
+	TokenType string `json:"token_type"`
+	// Amount is the amount, in decimal, negative for the tokens sent in a movement record
+	Amount string `json:"amount"`
+	// Timestamp is the time the record was stored in the auditdb
+	Timestamp time.Time `json:"timestamp"`
+	// Status is the status of the transaction
+	Status string `json:"status"`
+}
+
+func eventID(txID string, kind string, index int) string {
+	return fmt.Sprintf("%s/%s/%d", txID, kind, index)
+}
+
+func amount(v *big.Int) string {
+	if v == nil {
+		return "0"
+	}
+	return v.String()
+}
+
+// Encoder encodes events
+type Encoder interface {
+	// Encode returns the encoding of the passed event
+	Encode(event *Event) ([]byte, error)
+	// ContentType returns the content type of the encoded events
+	ContentType() string
+}
+
+// NewEncoder returns the encoder for the passed format, JSONFormat if empty.
+// The schema id is used by the AvroFormat only.
+func NewEncoder(format string, schemaID int32) (Encoder, error) {
+	switch format {
+	case "", JSONFormat:
+		return &JSONEncoder{}, nil
+	case AvroFormat:
+		return &AvroEncoder{SchemaID: schemaID}, nil
+	default:
+		return nil, errors.Errorf("unknown export format [%s]", format)
+	}
+}
+
+// JSONEncoder encodes the events in JSON
+type JSONEncoder struct{}
+
+func (e *JSONEncoder) Encode(event *Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+func (e *JSONEncoder) ContentType() string {
+	return "application/json"
+}
+
+// AvroSchema is the Avro schema of the events encoded by the AvroEncoder
+const AvroSchema = `{
+  "type": "record",
+  "name": "AuditEvent",
+  "namespace": "org.hyperledger.labs.tokensdk",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "kind", "type": "string"},
+    {"name": "tx_id", "type": "string"},
+    {"name": "action_type", "type": "string"},
+    {"name": "sender_eid", "type": "string"},
+    {"name": "recipient_eid", "type": "string"},
+    {"name": "enrollment_id", "type": "string"},
+    {"name": "token_type", "type": "string"},
+    {"name": "amount", "type": "string"},
+    {"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+    {"name": "status", "type": "string"}
+  ]
+}`
+
+// AvroEncoder encodes the events in the Avro binary encoding, with schema AvroSchema.
+// If SchemaID is not zero, the encoding is prefixed by the schema registry wire format header:
+// a zero byte followed by the schema id, in big-endian.
+type AvroEncoder struct {
+	SchemaID int32
+}
+
+func (e *AvroEncoder) Encode(event *Event) ([]byte, error) {
+	var buf []byte
+	if e.SchemaID != 0 {
+		buf = append(buf, 0)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(e.SchemaID))
+	}
+	// the fields in the order of the schema
+	for _, s := range []string{event.ID, event.Kind, event.TxID, event.ActionType, event.SenderEID, event.RecipientEID, event.EnrollmentID, event.TokenType, event.Amount} {
+		buf = appendAvroString(buf, s)
+	}
+	buf = appendAvroLong(buf, event.Timestamp.UnixMicro())
+	buf = appendAvroString(buf, event.Status)
+	return buf, nil
+}
+
+func (e *AvroEncoder) ContentType() string {
+	return "avro/binary"
+}
+
+// appendAvroLong appends the zig-zag, variable-length, encoding of the passed long
+func appendAvroLong(buf []byte, v int64) []byte {
+	return binary.AppendUvarint(buf, uint64((v<<1)^(v>>63)))
+}
+
+// appendAvroString appends the length of the passed string, as a long, followed by its UTF-8 bytes
+func appendAvroString(buf []byte, s string) []byte {
+	buf = appendAvroLong(buf, int64(len(s)))
+	return append(buf, s...)
+}
@@ -0,0 +1,221 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/pkg/errors"
+)
+
+var logger = logging.MustGetLogger("token-sdk.auditdb.export")
+
+const (
+	// DefaultInterval is the default time between two flushes of the export outbox
+	DefaultInterval = 5 * time.Second
+	// DefaultBatchSize is the default maximum number of transactions published by a flush
+	DefaultBatchSize = 100
+
+	// EventIDHeader is the header carrying the identifier of the event, consumers use it to discard duplicates
+	EventIDHeader = "event-id"
+	// ContentTypeHeader is the header carrying the encoding of the event
+	ContentTypeHeader = "content-type"
+)
+
+// Config is the configuration of the export of the audit records
+type Config struct {
+	// Enabled makes the auditdb store the records of the audited transactions in the export outbox
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Topic is the topic the records are published to
+	Topic string `yaml:"topic,omitempty"`
+	// Format is the encoding of the records, `json` (default) or `avro`
+	Format string `yaml:"format,omitempty"`
+	// SchemaID is the identifier, in the schema registry, of AvroSchema.
+	// If set, the avro records are framed with the schema registry wire format.
+	SchemaID int32 `yaml:"schemaID,omitempty"`
+	// Interval is the time between two flushes of the export outbox, default: DefaultInterval
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// BatchSize is the maximum number of transactions published by a flush, default: DefaultBatchSize
+	BatchSize int `yaml:"batchSize,omitempty"`
+}
+
+// Message is a message to publish
+type Message struct {
+	// Key is the key of the message, the transaction id, so that the records of a transaction go to the same partition
+	Key []byte
+	// Value is the encoded event
+	Value []byte
+	// Headers are the headers of the message
+	Headers map[string]string
+}
+
+// Producer publishes messages to a Kafka cluster, or any other message broker.
+// Applications adapt the client of their choice to this interface.
+type Producer interface {
+	// Produce publishes the passed messages, in order, to the passed topic.
+	// It returns nil only once all the messages have been acknowledged by the broker.
+	Produce(ctx context.Context, topic string, messages []Message) error
+}
+
+// Outbox is the export outbox of the auditdb
+type Outbox interface {
+	// PendingExports returns the entries not exported yet whose transaction is final, the oldest first
+	PendingExports(limit int) ([]*auditdb.ExportRecord, error)
+	// MarkExported records that the entries for the passed transaction ids have been exported
+	MarkExported(txIDs []string) error
+}
+
+// Sink publishes the records of the committed transactions found in the export outbox.
+// The entries are marked as exported only after the producer acknowledges their messages, therefore,
+// after a failure, the messages of an entry might be published again, with the same event identifiers.
+type Sink struct {
+	outbox   Outbox
+	producer Producer
+	encoder  Encoder
+	config   Config
+}
+
+// NewSink returns a new Sink for the passed outbox, producer, and configuration
+func NewSink(outbox Outbox, producer Producer, config Config) (*Sink, error) {
+	if producer == nil {
+		return nil, errors.New("no producer")
+	}
+	if len(config.Topic) == 0 {
+		return nil, errors.New("no topic")
+	}
+	encoder, err := NewEncoder(config.Format, config.SchemaID)
+	if err != nil {
+		return nil, err
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	return &Sink{
+		outbox:   outbox,
+		producer: producer,
+		encoder:  encoder,
+		config:   config,
+	}, nil
+}
+
+// Run flushes the export outbox every configured interval until the passed context is done
+func (s *Sink) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := s.Flush(ctx)
+			if err != nil {
+				logger.Errorf("failed to export audit records to [%s]: [%s]", s.config.Topic, err)
+				break
+			}
+			if n < s.config.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush publishes the records of a batch of entries of the export outbox, and marks the entries as exported.
+// The entries of deleted transactions are marked as exported without publishing anything.
+// It returns the number of entries processed.
+func (s *Sink) Flush(ctx context.Context) (int, error) {
+	records, err := s.outbox.PendingExports(s.config.BatchSize)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed to get pending exports")
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+	var messages []Message
+	txIDs := make([]string, len(records))
+	for i, record := range records {
+		txIDs[i] = record.TxID
+		if record.Status != driver.Confirmed {
+			continue
+		}
+		events, err := Events(record)
+		if err != nil {
+			return 0, err
+		}
+		for _, event := range events {
+			value, err := s.encoder.Encode(event)
+			if err != nil {
+				return 0, errors.WithMessagef(err, "failed to encode event [%s]", event.ID)
+			}
+			messages = append(messages, Message{
+				Key:   []byte(event.TxID),
+				Value: value,
+				Headers: map[string]string{
+					EventIDHeader:     event.ID,
+					ContentTypeHeader: s.encoder.ContentType(),
+				},
+			})
+		}
+	}
+	if len(messages) != 0 {
+		if err := s.producer.Produce(ctx, s.config.Topic, messages); err != nil {
+			return 0, errors.Wrapf(err, "failed to publish [%d] messages", len(messages))
+		}
+	}
+	if err := s.outbox.MarkExported(txIDs); err != nil {
+		return 0, errors.WithMessagef(err, "failed to mark [%d] entries as exported", len(txIDs))
+	}
+	logger.Debugf("exported [%d] transactions, [%d] messages", len(records), len(messages))
+	return len(records), nil
+}
+
+// Events returns the events of the records in the passed entry of the export outbox.
+// The status of the events is the status of the entry.
+func Events(record *auditdb.ExportRecord) ([]*Event, error) {
+	payload := &auditdb.ExportPayload{}
+	if err := json.Unmarshal(record.Payload, payload); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal export record [%s]", record.TxID)
+	}
+	status := driver.TxStatusMessage[record.Status]
+	events := make([]*Event, 0, len(payload.Transactions)+len(payload.Movements))
+	for i, tx := range payload.Transactions {
+		events = append(events, &Event{
+			ID:           eventID(record.TxID, TransactionKind, i),
+			Kind:         TransactionKind,
+			TxID:         record.TxID,
+			ActionType:   actionTypes[tx.ActionType],
+			SenderEID:    tx.SenderEID,
+			RecipientEID: tx.RecipientEID,
+			TokenType:    tx.TokenType,
+			Amount:       amount(tx.Amount),
+			Timestamp:    tx.Timestamp,
+			Status:       status,
+		})
+	}
+	for i, mv := range payload.Movements {
+		events = append(events, &Event{
+			ID:           eventID(record.TxID, MovementKind, i),
+			Kind:         MovementKind,
+			TxID:         record.TxID,
+			EnrollmentID: mv.EnrollmentID,
+			TokenType:    mv.TokenType,
+			Amount:       amount(mv.Amount),
+			Timestamp:    mv.Timestamp,
+			Status:       status,
+		})
+	}
+	return events, nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type outbox struct {
+	records  []*auditdb.ExportRecord
+	exported []string
+}
+
+func (o *outbox) PendingExports(limit int) ([]*auditdb.ExportRecord, error) {
+	if len(o.records) > limit {
+		return o.records[:limit], nil
+	}
+	return o.records, nil
+}
+
+func (o *outbox) MarkExported(txIDs []string) error {
+	o.exported = append(o.exported, txIDs...)
+	o.records = o.records[len(txIDs):]
+	return nil
+}
+
+type producer struct {
+	err      error
+	topic    string
+	messages []Message
+}
+
+func (p *producer) Produce(_ context.Context, topic string, messages []Message) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.topic = topic
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+func record(t *testing.T, txID string, status driver.TxStatus) *auditdb.ExportRecord {
+	now := time.Now()
+	payload, err := json.Marshal(&auditdb.ExportPayload{
+		Transactions: []auditdb.TransactionRecord{{
+			TxID:         txID,
+			ActionType:   driver.Transfer,
+			SenderEID:    "alice",
+			RecipientEID: "bob",
+			TokenType:    "USD",
+			Amount:       big.NewInt(10),
+			Timestamp:    now,
+			Status:       status,
+		}},
+		Movements: []auditdb.MovementRecord{
+			{TxID: txID, EnrollmentID: "alice", TokenType: "USD", Amount: big.NewInt(-10), Timestamp: now, Status: status},
+			{TxID: txID, EnrollmentID: "bob", TokenType: "USD", Amount: big.NewInt(10), Timestamp: now, Status: status},
+		},
+	})
+	assert.NoError(t, err)
+	return &auditdb.ExportRecord{TxID: txID, Payload: payload, Status: status, Timestamp: now}
+}
+
+func TestNewSink(t *testing.T) {
+	_, err := NewSink(&outbox{}, nil, Config{Topic: "audit"})
+	assert.Error(t, err)
+	_, err = NewSink(&outbox{}, &producer{}, Config{})
+	assert.Error(t, err)
+	_, err = NewSink(&outbox{}, &producer{}, Config{Topic: "audit", Format: "xml"})
+	assert.Error(t, err)
+
+	sink, err := NewSink(&outbox{}, &producer{}, Config{Topic: "audit"})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultInterval, sink.config.Interval)
+	assert.Equal(t, DefaultBatchSize, sink.config.BatchSize)
+}
+
+func TestFlush(t *testing.T) {
+	o := &outbox{records: []*auditdb.ExportRecord{
+		record(t, "tx1", driver.Confirmed),
+		record(t, "tx2", driver.Deleted),
+		record(t, "tx3", driver.Confirmed),
+	}}
+	p := &producer{}
+	sink, err := NewSink(o, p, Config{Topic: "audit", BatchSize: 2})
+	assert.NoError(t, err)
+
+	// the records of deleted transactions are not published
+	n, err := sink.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"tx1", "tx2"}, o.exported)
+	assert.Equal(t, "audit", p.topic)
+	assert.Len(t, p.messages, 3)
+	ids := make([]string, len(p.messages))
+	for i, m := range p.messages {
+		assert.Equal(t, "tx1", string(m.Key))
+		assert.Equal(t, "application/json", m.Headers[ContentTypeHeader])
+		ids[i] = m.Headers[EventIDHeader]
+	}
+	assert.Equal(t, []string{"tx1/transaction/0", "tx1/movement/0", "tx1/movement/1"}, ids)
+
+	event := &Event{}
+	assert.NoError(t, json.Unmarshal(p.messages[1].Value, event))
+	assert.Equal(t, MovementKind, event.Kind)
+	assert.Equal(t, "alice", event.EnrollmentID)
+	assert.Equal(t, "-10", event.Amount)
+	assert.Equal(t, "Confirmed", event.Status)
+
+	// a failure of the producer leaves the entries in the outbox
+	p.err = errors.New("broker unavailable")
+	_, err = sink.Flush(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, o.records, 1)
+
+	p.err = nil
+	n, err = sink.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, p.messages, 6)
+
+	n, err = sink.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestAvroEncoder(t *testing.T) {
+	event := &Event{
+		ID:        "tx1/movement/0",
+		Kind:      MovementKind,
+		TxID:      "tx1",
+		TokenType: "USD",
+		Amount:    "-10",
+		Timestamp: time.UnixMicro(-2),
+		Status:    "Confirmed",
+	}
+
+	raw, err := (&AvroEncoder{}).Encode(event)
+	assert.NoError(t, err)
+	framed, err := (&AvroEncoder{SchemaID: 258}).Encode(event)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 1, 2}, framed[:5])
+	assert.Equal(t, raw, framed[5:])
+
+	// strings are prefixed by their zig-zag encoded length
+	assert.Equal(t, byte(len(event.ID)*2), raw[0])
+	assert.Equal(t, event.ID, string(raw[1:1+len(event.ID)]))
+	// -2 is zig-zag encoded as 3, followed by the status
+	tail := append([]byte{3, byte(len(event.Status) * 2)}, event.Status...)
+	assert.Equal(t, tail, raw[len(raw)-len(tail):])
+
+	assert.Equal(t, []byte{0x80, 0x01}, appendAvroLong(nil, 64))
+	assert.Equal(t, []byte{0x7f}, appendAvroLong(nil, -64))
+}
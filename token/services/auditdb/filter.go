@@ -35,11 +35,21 @@ func (f *PaymentsFilter) Last(num int) *PaymentsFilter {
 	return f
 }
 
+// Corrected makes the filter sum the amounts of the payments with their corrections applied
+func (f *PaymentsFilter) Corrected() *PaymentsFilter {
+	f.params.Corrected = true
+	return f
+}
+
 func (f *PaymentsFilter) Execute() (*PaymentsFilter, error) {
 	f.params.TxStatuses = []driver.TxStatus{driver.Pending, driver.Confirmed}
 	f.params.MovementDirection = driver.Sent
 	f.params.SearchDirection = driver.FromLast
-	records, err := f.db.db.QueryMovements(f.params)
+	params := f.params
+	if p := f.db.Pseudonymizer(); p != nil {
+		params.EnrollmentIDs = p.Pseudonyms(params.EnrollmentIDs)
+	}
+	records, err := f.db.db.QueryMovements(params)
 	if err != nil {
 		return nil, err
 	}
@@ -72,11 +82,21 @@ func (f *HoldingsFilter) ByType(tokenType string) *HoldingsFilter {
 	return f
 }
 
+// Corrected makes the filter sum the amounts of the movements with their corrections applied
+func (f *HoldingsFilter) Corrected() *HoldingsFilter {
+	f.params.Corrected = true
+	return f
+}
+
 func (f *HoldingsFilter) Execute() (*HoldingsFilter, error) {
 	f.params.TxStatuses = []driver.TxStatus{driver.Pending, driver.Confirmed}
 	f.params.MovementDirection = driver.All
 	f.params.SearchDirection = driver.FromBeginning
-	records, err := f.db.db.QueryMovements(f.params)
+	params := f.params
+	if p := f.db.Pseudonymizer(); p != nil {
+		params.EnrollmentIDs = p.Pseudonyms(params.EnrollmentIDs)
+	}
+	records, err := f.db.db.QueryMovements(params)
 	if err != nil {
 		return nil, err
 	}
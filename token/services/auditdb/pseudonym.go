@@ -0,0 +1,172 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditdb
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// MinPseudonymizationKeySize is the minimum size in bytes of a pseudonymization key
+const MinPseudonymizationKeySize = 32
+
+// sealingKeyLabel derives, from the pseudonymization key, the key sealing the enrollment IDs and the token requests
+const sealingKeyLabel = "fabric-token-sdk audit sealing"
+
+// sealedRequestPrefix marks the token requests sealed by a pseudonymizer
+var sealedRequestPrefix = []byte{0x00, 'p', 's', 'r'}
+
+// Pseudonymizer replaces enrollment IDs with keyed HMACs, so that who transacted with whom
+// cannot be read from the database without the key.
+// Pseudonyms are deterministic, therefore records can still be filtered by enrollment ID.
+// The enrollment IDs are sealed with a key derived from the same key, so that the database can
+// store the mapping from pseudonyms back to enrollment IDs, and the token requests carrying them.
+// The index caches the mapping of the pseudonyms seen by this pseudonymizer.
+type Pseudonymizer struct {
+	key  []byte
+	aead cipher.AEAD
+
+	lock  sync.RWMutex
+	index map[string]string
+}
+
+// NewPseudonymizer returns a new Pseudonymizer for the passed key
+func NewPseudonymizer(key []byte) (*Pseudonymizer, error) {
+	if len(key) < MinPseudonymizationKeySize {
+		return nil, errors.Errorf("pseudonymization key must be at least [%d] bytes long, got [%d]", MinPseudonymizationKeySize, len(key))
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(sealingKeyLabel))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create sealing cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create sealing cipher")
+	}
+	return &Pseudonymizer{
+		key:   key,
+		aead:  aead,
+		index: map[string]string{},
+	}, nil
+}
+
+// Pseudonym returns the pseudonym of the passed enrollment ID.
+// The empty enrollment ID is left as is.
+func (p *Pseudonymizer) Pseudonym(eID string) string {
+	if len(eID) == 0 {
+		return eID
+	}
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(eID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Pseudonyms returns the pseudonyms of the passed enrollment IDs
+func (p *Pseudonymizer) Pseudonyms(eIDs []string) []string {
+	res := make([]string, len(eIDs))
+	for i, eID := range eIDs {
+		res[i] = p.Pseudonym(eID)
+	}
+	return res
+}
+
+// EnrollmentID returns the enrollment ID the passed pseudonym has been computed from.
+// It returns false if the pseudonym is not in the index of this pseudonymizer.
+func (p *Pseudonymizer) EnrollmentID(pseudonym string) (string, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	eID, ok := p.index[pseudonym]
+	return eID, ok
+}
+
+// Seal encrypts the passed enrollment ID, bound to its pseudonym
+func (p *Pseudonymizer) Seal(pseudonym string, eID string) ([]byte, error) {
+	return p.seal([]byte(eID), []byte(pseudonym), nil)
+}
+
+// Open decrypts the enrollment ID sealed for the passed pseudonym, and adds it to the index.
+// It returns an error if the enrollment ID has not been sealed by this pseudonymizer, or for another pseudonym.
+func (p *Pseudonymizer) Open(pseudonym string, sealed []byte) (string, error) {
+	raw, err := p.open(sealed, []byte(pseudonym))
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed to open enrollment ID of [%s]", pseudonym)
+	}
+	eID := string(raw)
+	if p.Pseudonym(eID) != pseudonym {
+		return "", errors.Errorf("enrollment ID sealed for [%s] does not match", pseudonym)
+	}
+	p.remember(pseudonym, eID)
+	return eID, nil
+}
+
+// SealRequest encrypts the passed token request, bound to its transaction id,
+// so that the enrollment IDs it carries are not stored in clear
+func (p *Pseudonymizer) SealRequest(txID string, request []byte) ([]byte, error) {
+	return p.seal(request, []byte(txID), sealedRequestPrefix)
+}
+
+// OpenRequest decrypts the passed token request if sealed by SealRequest, and returns it as is otherwise
+func (p *Pseudonymizer) OpenRequest(txID string, request []byte) ([]byte, error) {
+	if !bytes.HasPrefix(request, sealedRequestPrefix) {
+		return request, nil
+	}
+	raw, err := p.open(request[len(sealedRequestPrefix):], []byte(txID))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to open token request [%s]", txID)
+	}
+	return raw, nil
+}
+
+func (p *Pseudonymizer) seal(plaintext, ad, prefix []byte) ([]byte, error) {
+	nonce := make([]byte, p.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrapf(err, "failed to generate nonce")
+	}
+	res := make([]byte, 0, len(prefix)+len(nonce)+len(plaintext)+p.aead.Overhead())
+	res = append(res, prefix...)
+	res = append(res, nonce...)
+	return p.aead.Seal(res, nonce, plaintext, ad), nil
+}
+
+func (p *Pseudonymizer) open(sealed, ad []byte) ([]byte, error) {
+	if len(sealed) < p.aead.NonceSize() {
+		return nil, errors.New("sealed value too short")
+	}
+	nonce, ciphertext := sealed[:p.aead.NonceSize()], sealed[p.aead.NonceSize():]
+	raw, err := p.aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decrypt sealed value")
+	}
+	return raw, nil
+}
+
+func (p *Pseudonymizer) known(pseudonym string) bool {
+	_, ok := p.EnrollmentID(pseudonym)
+	return ok
+}
+
+func (p *Pseudonymizer) remember(pseudonym string, eID string) {
+	p.lock.Lock()
+	p.index[pseudonym] = eID
+	p.lock.Unlock()
+}
+
+func (p *Pseudonymizer) forget(pseudonym string) {
+	p.lock.Lock()
+	delete(p.index, pseudonym)
+	p.lock.Unlock()
+}
@@ -0,0 +1,135 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditdb
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"testing"
+	"time"
+
+	sqlite2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/sqlite"
+	tdriver "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPseudonymizer(t *testing.T) {
+	_, err := NewPseudonymizer([]byte("short"))
+	assert.Error(t, err)
+
+	p, err := NewPseudonymizer(bytes.Repeat([]byte{1}, MinPseudonymizationKeySize))
+	assert.NoError(t, err)
+	other, err := NewPseudonymizer(bytes.Repeat([]byte{2}, MinPseudonymizationKeySize))
+	assert.NoError(t, err)
+
+	assert.Empty(t, p.Pseudonym(""))
+	alice := p.Pseudonym("alice")
+	assert.NotEqual(t, "alice", alice)
+	assert.Equal(t, alice, p.Pseudonym("alice"))
+	assert.NotEqual(t, alice, p.Pseudonym("bob"))
+	assert.NotEqual(t, alice, other.Pseudonym("alice"))
+
+	// the enrollment IDs are sealed for their pseudonym
+	_, ok := p.EnrollmentID(alice)
+	assert.False(t, ok)
+	sealed, err := p.Seal(alice, "alice")
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(sealed, []byte("alice")))
+	eID, err := p.Open(alice, sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", eID)
+	eID, ok = p.EnrollmentID(alice)
+	assert.True(t, ok)
+	assert.Equal(t, "alice", eID)
+	_, err = p.Open(p.Pseudonym("bob"), sealed)
+	assert.Error(t, err)
+	_, err = other.Open(alice, sealed)
+	assert.Error(t, err)
+	// a sealed enrollment ID not matching its pseudonym is rejected
+	sealed, err = p.Seal(alice, "bob")
+	assert.NoError(t, err)
+	_, err = p.Open(alice, sealed)
+	assert.Error(t, err)
+
+	// the token requests are sealed for their transaction id
+	sealed, err = p.SealRequest("tx1", []byte("request of alice"))
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(sealed, []byte("alice")))
+	request, err := p.OpenRequest("tx1", sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("request of alice"), request)
+	_, err = p.OpenRequest("tx2", sealed)
+	assert.Error(t, err)
+	_, err = other.OpenRequest("tx1", sealed)
+	assert.Error(t, err)
+	// the requests stored before the pseudonymization are returned as they are
+	request, err = p.OpenRequest("tx1", []byte("request"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("request"), request)
+}
+
+func TestPersistedPseudonyms(t *testing.T) {
+	sqlDB, err := sqlite2.OpenDB(fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", path.Join(t.TempDir(), "audit.sqlite")), 1, 1, time.Minute, false)
+	assert.NoError(t, err)
+	adb, err := sqlite.NewAuditTransactionDB(sqlDB, common.NewDBOpts{TablePrefix: "test", CreateSchema: true})
+	assert.NoError(t, err)
+	key := bytes.Repeat([]byte{1}, MinPseudonymizationKeySize)
+
+	d := newDB(adb)
+	p, err := NewPseudonymizer(key)
+	assert.NoError(t, err)
+	d.SetPseudonymizer(p)
+	alice, err := d.pseudonym(p, "alice")
+	assert.NoError(t, err)
+	sealed, err := p.SealRequest("tx1", []byte("request of alice"))
+	assert.NoError(t, err)
+	w, err := adb.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("tx1", sealed, map[string][]byte{}, tdriver.PPHash("pp")))
+	assert.NoError(t, w.Commit())
+
+	// after a restart, the pseudonyms are resolved and the requests opened with the mapping stored in the database
+	d = newDB(adb)
+	p, err = NewPseudonymizer(key)
+	assert.NoError(t, err)
+	d.SetPseudonymizer(p)
+	eID, err := d.ResolveEnrollmentID(alice)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", eID)
+	_, err = d.ResolveEnrollmentID(p.Pseudonym("bob"))
+	assert.Error(t, err)
+	request, err := d.GetTokenRequest("tx1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("request of alice"), request)
+	requests, err := d.GetTokenRequests([]string{"tx1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("request of alice"), requests["tx1"])
+	it, err := d.TokenRequests(QueryTokenRequestsParams{})
+	assert.NoError(t, err)
+	record, err := it.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("request of alice"), record.TokenRequest)
+	it.Close()
+	stored, err := adb.GetTokenRequest("tx1")
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(stored, []byte("alice")))
+
+	// the erased enrollment IDs no longer resolve
+	_, err = d.EraseEnrollmentID("alice", "erased")
+	assert.NoError(t, err)
+	_, err = d.ResolveEnrollmentID(alice)
+	assert.Error(t, err)
+
+	// without pseudonymizer, the values are returned as they are
+	d = newDB(adb)
+	eID, err = d.ResolveEnrollmentID("bob")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", eID)
+}
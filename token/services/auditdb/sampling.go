@@ -0,0 +1,42 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditdb
+
+import (
+	"hash/fnv"
+
+	"github.com/pkg/errors"
+)
+
+// Sampler decides which token requests get full audit records.
+// The others get aggregate-only records: the token request, the movements, the issuer attributions and the audit response,
+// but not the transaction records, which can be backfilled on demand.
+// The decision depends on the transaction id only, therefore, it is the same across restarts.
+type Sampler struct {
+	percentage uint32
+}
+
+// NewSampler returns a Sampler keeping full records for the passed percentage of the token requests.
+// The percentage must be in [0, 100].
+func NewSampler(percentage int) (*Sampler, error) {
+	if percentage < 0 || percentage > 100 {
+		return nil, errors.Errorf("sampling percentage must be in [0, 100], got [%d]", percentage)
+	}
+	return &Sampler{percentage: uint32(percentage)}, nil
+}
+
+// Percentage returns the percentage of the token requests that get full records
+func (s *Sampler) Percentage() int {
+	return int(s.percentage)
+}
+
+// Sampled returns true if the token request with the passed transaction id gets full records
+func (s *Sampler) Sampled(txID string) bool {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(txID))
+	return h.Sum32()%100 < s.percentage
+}
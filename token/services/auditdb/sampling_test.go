@@ -0,0 +1,40 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditdb
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampler(t *testing.T) {
+	_, err := NewSampler(-1)
+	assert.Error(t, err)
+	_, err = NewSampler(101)
+	assert.Error(t, err)
+
+	none, err := NewSampler(0)
+	assert.NoError(t, err)
+	all, err := NewSampler(100)
+	assert.NoError(t, err)
+	half, err := NewSampler(50)
+	assert.NoError(t, err)
+
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		txID := fmt.Sprintf("tx%d", i)
+		assert.False(t, none.Sampled(txID))
+		assert.True(t, all.Sampled(txID))
+		assert.Equal(t, half.Sampled(txID), half.Sampled(txID))
+		if half.Sampled(txID) {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 500, sampled, 100)
+}
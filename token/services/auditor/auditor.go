@@ -8,10 +8,12 @@ package auditor
 
 import (
 	"context"
+	"sync"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracing"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb/export"
 	db "github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
@@ -40,6 +42,9 @@ const txIdLabel tracing.LabelName = "tx_id"
 
 var TxStatusMessage = auditdb.TxStatusMessage
 
+// AuditResponseRecord is the response of the auditor to an audit request, kept until it is delivered to the requester
+type AuditResponseRecord = auditdb.AuditResponseRecord
+
 // Transaction models a generic token transaction
 type Transaction interface {
 	ID() string
@@ -61,6 +66,46 @@ type Auditor struct {
 	tokenDB        *tokens.Tokens
 	tmsProvider    TokenManagementServiceProvider
 	finalityTracer trace.Tracer
+	exportConfig   *export.Config
+
+	issuerResolverLock sync.RWMutex
+	issuerResolver     IssuerResolver
+
+	inspectorsLock sync.RWMutex
+	inspectors     []MetadataInspector
+	// inspections holds the results of the inspectors, by anchor, from Audit to Append
+	inspections sync.Map
+}
+
+// NewAuditor returns a new Auditor for the passed TMS over the passed auditdb and tokens service.
+// Unlike New, it does not resolve its dependencies through a service provider,
+// therefore, it is suited to applications wiring the token services by themselves.
+// The caller is in charge of configuring the auditdb, for instance its pseudonymizer and its sampler.
+func NewAuditor(np NetworkProvider, tmsProvider TokenManagementServiceProvider, tmsID token.TMSID, auditDB *auditdb.DB, tokenDB *tokens.Tokens, finalityTracer trace.Tracer) *Auditor {
+	return &Auditor{
+		np:             np,
+		tmsID:          tmsID,
+		auditDB:        auditDB,
+		tokenDB:        tokenDB,
+		tmsProvider:    tmsProvider,
+		issuerResolver: NewIssuerRegistry(),
+		finalityTracer: finalityTracer,
+	}
+}
+
+// StartExport starts publishing, with the passed producer, the records of the committed transactions
+// to the topic configured under ExportKey. The export stops when the passed context is done.
+// It returns an error if the export is not enabled for the TMS of this auditor.
+func (a *Auditor) StartExport(ctx context.Context, producer export.Producer) (*export.Sink, error) {
+	if a.exportConfig == nil {
+		return nil, errors.Errorf("export not enabled for [%s], see [%s]", a.tmsID, ExportKey)
+	}
+	sink, err := export.NewSink(a.auditDB, producer, *a.exportConfig)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to create export sink for [%s]", a.tmsID)
+	}
+	go sink.Run(ctx)
+	return sink, nil
 }
 
 // Validate validates the passed token request
@@ -68,10 +113,11 @@ func (a *Auditor) Validate(context context.Context, request *token.Request) erro
 	return request.AuditCheck(context)
 }
 
-// Audit extracts the list of inputs and outputs from the passed transaction.
+// Audit extracts the list of inputs and outputs from the passed transaction, and runs the registered inspectors over them
+// with the passed context.
 // In addition, the Audit locks the enrollment named ids.
 // Release must be invoked in case
-func (a *Auditor) Audit(tx Transaction) (*token.InputStream, *token.OutputStream, error) {
+func (a *Auditor) Audit(ctx context.Context, tx Transaction) (*token.InputStream, *token.OutputStream, error) {
 	logger.Debugf("audit transaction [%s]....", tx.ID())
 	request := tx.Request()
 	record, err := request.AuditRecord()
@@ -79,6 +125,11 @@ func (a *Auditor) Audit(tx Transaction) (*token.InputStream, *token.OutputStream
 		return nil, nil, errors.WithMessagef(err, "failed getting transaction audit record")
 	}
 
+	inspections, err := a.inspect(ctx, request, record)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed inspecting transaction [%s]", tx.ID())
+	}
+
 	var eids []string
 	eids = append(eids, record.Inputs.EnrollmentIDs()...)
 	eids = append(eids, record.Outputs.EnrollmentIDs()...)
@@ -87,17 +138,38 @@ func (a *Auditor) Audit(tx Transaction) (*token.InputStream, *token.OutputStream
 		return nil, nil, err
 	}
 	logger.Debugf("audit transaction [%s], acquire locks done", tx.ID())
+	if len(inspections) != 0 {
+		a.inspections.Store(request.Anchor, inspections)
+	}
 
 	return record.Inputs, record.Outputs, nil
 }
 
 // Append adds the passed transaction to the auditor database.
+// Issuance, if any, is attributed to the registered issuers using the configured IssuerResolver.
 // It also releases the locks acquired by Audit.
 func (a *Auditor) Append(tx Transaction) error {
+	return a.AppendWithResponse(tx, nil)
+}
+
+// AppendWithResponse adds the passed transaction to the auditor database as Append does.
+// The passed response, if not nil, is stored atomically with the transaction, so that it can be delivered again
+// to the requester after a failure.
+// The results of the inspectors run by Audit, if any, are stored in the validation record of the transaction.
+func (a *Auditor) AppendWithResponse(tx Transaction, response *AuditResponseRecord) error {
 	defer a.Release(tx)
 
+	attributions, err := a.issuerAttributions(context.Background(), tx.Request())
+	if err != nil {
+		return errors.WithMessagef(err, "failed attributing issuance of request %s", tx.ID())
+	}
+	var inspections map[string][]byte
+	if v, ok := a.inspections.Load(tx.Request().Anchor); ok {
+		inspections = v.(map[string][]byte)
+	}
+
 	// append request to audit db
-	if err := a.auditDB.Append(tx.Request()); err != nil {
+	if err := a.auditDB.AppendWithResponse(tx.Request(), response, inspections, attributions...); err != nil {
 		return errors.WithMessagef(err, "failed appending request %s", tx.ID())
 	}
 
@@ -115,8 +187,52 @@ func (a *Auditor) Append(tx Transaction) error {
 	return nil
 }
 
+// AuditResponse returns the audit response stored for the passed transaction id, or nil if there is none
+func (a *Auditor) AuditResponse(txID string) (*AuditResponseRecord, error) {
+	return a.auditDB.AuditResponse(txID)
+}
+
+// PendingAuditResponses returns the audit responses not yet delivered to their requesters
+func (a *Auditor) PendingAuditResponses() ([]*AuditResponseRecord, error) {
+	return a.auditDB.PendingAuditResponses()
+}
+
+// MarkAuditResponseSent records that the audit response for the passed transaction id has been delivered
+func (a *Auditor) MarkAuditResponseSent(txID string) error {
+	return a.auditDB.MarkAuditResponseSent(txID)
+}
+
+// Backfill stores the transaction records of the passed transaction id, if the auditdb kept aggregate-only records for it.
+// The records are rebuilt from the token request stored in the auditdb.
+func (a *Auditor) Backfill(txID string) error {
+	raw, err := a.auditDB.GetTokenRequest(txID)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get token request [%s]", txID)
+	}
+	if len(raw) == 0 {
+		return errors.Errorf("token request [%s] not found", txID)
+	}
+	tms, err := a.tmsProvider.GetManagementService(token.WithTMSID(a.tmsID))
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get tms for [%s]", a.tmsID)
+	}
+	req, err := tms.NewFullRequestFromBytes(raw)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to unmarshal token request [%s]", txID)
+	}
+	return a.auditDB.Backfill(req)
+}
+
+// ResolveEnrollmentID returns the enrollment ID behind the passed enrollment ID, as found in the audit records.
+// If the auditdb stores enrollment IDs in clear, the passed value is returned as is.
+// Otherwise, the passed value is a pseudonym and it is resolved with the mapping stored in the auditdb.
+func (a *Auditor) ResolveEnrollmentID(eID string) (string, error) {
+	return a.auditDB.ResolveEnrollmentID(eID)
+}
+
 // Release releases the lock acquired of the passed transaction.
 func (a *Auditor) Release(tx Transaction) {
+	a.inspections.Delete(tx.Request().Anchor)
 	a.auditDB.ReleaseLocks(tx.Request().Anchor)
 }
 
@@ -137,3 +253,15 @@ func (a *Auditor) GetStatus(txID string) (TxStatus, string, error) {
 func (a *Auditor) GetTokenRequest(txID string) ([]byte, error) {
 	return a.auditDB.GetTokenRequest(txID)
 }
+
+// TokenRequests returns an iterator over the token requests matching the passed params, with their application metadata.
+// Compliance can look up, for instance, the transactions carrying a given business reference.
+func (a *Auditor) TokenRequests(params auditdb.QueryTokenRequestsParams) (db.TokenRequestIterator, error) {
+	return a.auditDB.TokenRequests(params)
+}
+
+// GetTokenRequests returns the token requests bound to the passed transaction ids, indexed by transaction id.
+// The transaction ids that are not found are missing in the returned map.
+func (a *Auditor) GetTokenRequests(txIDs []string) (map[string][]byte, error) {
+	return a.auditDB.GetTokenRequests(txIDs)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditor
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/pkg/errors"
+)
+
+// InspectionKeyPrefix prefixes the keys, in the metadata of the validation record of a transaction,
+// under which the results of the inspectors are stored
+const InspectionKeyPrefix = "inspection."
+
+// InspectionResult is the structured result of an inspector
+type InspectionResult struct {
+	// Passed tells whether the request passed the inspection
+	Passed bool `json:"passed"`
+	// Message is a human-readable description of the result
+	Message string `json:"message,omitempty"`
+	// Details are inspector-specific values, for instance, the hash of an attached invoice
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// MetadataInspector inspects the token requests under audit, once the auditor has decrypted their metadata.
+// For instance, an inspector can check that the hash of an invoice attached as application metadata matches the amounts transferred.
+type MetadataInspector interface {
+	// Name identifies the inspector, its results are stored under InspectionKeyPrefix followed by the name
+	Name() string
+	// Inspect inspects the passed request, whose inputs and outputs, with their metadata in the clear, are in the passed audit record.
+	// An error makes the audit fail. A result, passed or not, is stored with the records of the request.
+	Inspect(ctx context.Context, request *token.Request, record *token.AuditRecord) (*InspectionResult, error)
+}
+
+// RegisterInspector adds the passed inspector to those run by Audit.
+// The inspectors run in the order they are registered.
+func (a *Auditor) RegisterInspector(inspector MetadataInspector) error {
+	name := inspector.Name()
+	if len(name) == 0 {
+		return errors.New("inspector name must be specified")
+	}
+	a.inspectorsLock.Lock()
+	defer a.inspectorsLock.Unlock()
+	for _, i := range a.inspectors {
+		if i.Name() == name {
+			return errors.Errorf("inspector [%s] already registered", name)
+		}
+	}
+	a.inspectors = append(a.inspectors, inspector)
+	return nil
+}
+
+// inspect runs the registered inspectors over the passed request, and returns their marshalled results by key
+func (a *Auditor) inspect(ctx context.Context, request *token.Request, record *token.AuditRecord) (map[string][]byte, error) {
+	a.inspectorsLock.RLock()
+	inspectors := a.inspectors
+	a.inspectorsLock.RUnlock()
+	if len(inspectors) == 0 {
+		return nil, nil
+	}
+	results := make(map[string][]byte, len(inspectors))
+	for _, inspector := range inspectors {
+		result, err := inspector.Inspect(ctx, request, record)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "inspector [%s] failed", inspector.Name())
+		}
+		if result == nil {
+			continue
+		}
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal the result of inspector [%s]", inspector.Name())
+		}
+		logger.Debugf("inspector [%s] on [%s]: passed [%v]", inspector.Name(), record.Anchor, result.Passed)
+		results[InspectionKeyPrefix+inspector.Name()] = raw
+	}
+	return results, nil
+}
+
+// Inspections returns the results of the inspectors stored with the records of the passed transaction, by inspector name
+func (a *Auditor) Inspections(txID string) (map[string]*InspectionResult, error) {
+	it, err := a.auditDB.ValidationRecords(auditdb.QueryValidationRecordsParams{
+		TxIDs: []string{txID},
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query validation records of [%s]", txID)
+	}
+	defer it.Close()
+	results := map[string]*InspectionResult{}
+	for {
+		record, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to read validation records of [%s]", txID)
+		}
+		if record == nil {
+			return results, nil
+		}
+		for k, v := range record.Metadata {
+			name, ok := strings.CutPrefix(k, InspectionKeyPrefix)
+			if !ok {
+				continue
+			}
+			result := &InspectionResult{}
+			if err := json.Unmarshal(v, result); err != nil {
+				return nil, errors.Wrapf(err, "failed to unmarshal the result of inspector [%s]", name)
+			}
+			results[name] = result
+		}
+	}
+}
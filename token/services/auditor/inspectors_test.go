@@ -0,0 +1,73 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+// recordingInspector returns its result and records the value bound to ctxKey in the context it runs with
+type recordingInspector struct {
+	name   string
+	result *InspectionResult
+	err    error
+	seen   interface{}
+}
+
+func (i *recordingInspector) Name() string { return i.name }
+
+func (i *recordingInspector) Inspect(ctx context.Context, _ *token.Request, _ *token.AuditRecord) (*InspectionResult, error) {
+	i.seen = ctx.Value(ctxKey{})
+	return i.result, i.err
+}
+
+func TestRegisterInspector(t *testing.T) {
+	a := &Auditor{}
+	assert.ErrorContains(t, a.RegisterInspector(&recordingInspector{}), "inspector name must be specified")
+	assert.NoError(t, a.RegisterInspector(&recordingInspector{name: "invoice"}))
+	assert.ErrorContains(t, a.RegisterInspector(&recordingInspector{name: "invoice"}), "inspector [invoice] already registered")
+	assert.NoError(t, a.RegisterInspector(&recordingInspector{name: "limits"}))
+	assert.Len(t, a.inspectors, 2)
+}
+
+func TestInspect(t *testing.T) {
+	record := &token.AuditRecord{Anchor: "tx1"}
+
+	// no inspectors, no results
+	results, err := (&Auditor{}).inspect(context.Background(), nil, record)
+	assert.NoError(t, err)
+	assert.Nil(t, results)
+
+	// the inspectors run with the context of the caller, a nil result is not stored
+	a := &Auditor{}
+	invoice := &recordingInspector{name: "invoice", result: &InspectionResult{Passed: true, Details: map[string]string{"hash": "0a"}}}
+	skipped := &recordingInspector{name: "skipped"}
+	assert.NoError(t, a.RegisterInspector(invoice))
+	assert.NoError(t, a.RegisterInspector(skipped))
+	ctx := context.WithValue(context.Background(), ctxKey{}, "caller")
+	results, err = a.inspect(ctx, nil, record)
+	assert.NoError(t, err)
+	assert.Equal(t, "caller", invoice.seen)
+	assert.Equal(t, "caller", skipped.seen)
+	assert.Len(t, results, 1)
+	result := &InspectionResult{}
+	assert.NoError(t, json.Unmarshal(results[InspectionKeyPrefix+"invoice"], result))
+	assert.Equal(t, invoice.result, result)
+
+	// a failing inspector fails the inspection
+	assert.NoError(t, a.RegisterInspector(&recordingInspector{name: "limits", err: errors.New("limit exceeded")}))
+	_, err = a.inspect(ctx, nil, record)
+	assert.ErrorContains(t, err, "inspector [limits] failed: limit exceeded")
+}
@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditor
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/pkg/errors"
+)
+
+// IssuerAttributionRecord attributes the tokens created by an issue action to an issuer
+type IssuerAttributionRecord = auditdb.IssuerAttributionRecord
+
+// QueryIssuerAttributionsParams defines the parameters for querying issuer attributions
+type QueryIssuerAttributionsParams = auditdb.QueryIssuerAttributionsParams
+
+// IssuerResolver resolves the issuer identity found in an issue action to a registered issuer.
+// This allows the auditor to attribute issuance even when the issuers are anonymous.
+type IssuerResolver interface {
+	// ResolveIssuer returns the identifier of the registered issuer the passed identity belongs to.
+	// It returns false if the policy does not permit to attribute the issuance or the issuer is unknown.
+	ResolveIssuer(ctx context.Context, issuer token.Identity, tokenType string) (string, bool, error)
+}
+
+// IssuerRegistry is an IssuerResolver that attributes issuance to the issuers explicitly registered with it.
+type IssuerRegistry struct {
+	lock    sync.RWMutex
+	issuers map[string]string
+}
+
+// NewIssuerRegistry returns a new empty IssuerRegistry
+func NewIssuerRegistry() *IssuerRegistry {
+	return &IssuerRegistry{issuers: map[string]string{}}
+}
+
+// RegisterIssuer binds the passed identities to the passed issuer identifier
+func (r *IssuerRegistry) RegisterIssuer(issuerID string, identities ...token.Identity) error {
+	if len(issuerID) == 0 {
+		return errors.New("issuer id must be specified")
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, id := range identities {
+		if id.IsNone() {
+			return errors.Errorf("empty identity for issuer [%s]", issuerID)
+		}
+		r.issuers[id.UniqueID()] = issuerID
+	}
+	return nil
+}
+
+// ResolveIssuer returns the issuer identifier the passed identity has been registered with, if any
+func (r *IssuerRegistry) ResolveIssuer(_ context.Context, issuer token.Identity, _ string) (string, bool, error) {
+	if issuer.IsNone() {
+		return "", false, nil
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	issuerID, ok := r.issuers[issuer.UniqueID()]
+	return issuerID, ok, nil
+}
+
+// SetIssuerResolver sets the resolver used to attribute issuance to registered issuers.
+// It can be called while transactions are being appended, they use the resolver set when their attribution starts.
+func (a *Auditor) SetIssuerResolver(resolver IssuerResolver) {
+	a.issuerResolverLock.Lock()
+	defer a.issuerResolverLock.Unlock()
+	a.issuerResolver = resolver
+}
+
+func (a *Auditor) getIssuerResolver() IssuerResolver {
+	a.issuerResolverLock.RLock()
+	defer a.issuerResolverLock.RUnlock()
+	return a.issuerResolver
+}
+
+// IssuerAttributions returns the issuer attribution records matching the passed params
+func (a *Auditor) IssuerAttributions(params QueryIssuerAttributionsParams) ([]*IssuerAttributionRecord, error) {
+	return a.auditDB.IssuerAttributions(params)
+}
+
+// issuerAttributions returns, for each issue action in the passed request and each token type it issues,
+// a record attributing the issued amount to the issuer resolved by the configured resolver.
+// Issuance that cannot be attributed is still returned with an empty issuer id.
+func (a *Auditor) issuerAttributions(ctx context.Context, request *token.Request) ([]*IssuerAttributionRecord, error) {
+	issues := request.Issues()
+	if len(issues) == 0 {
+		return nil, nil
+	}
+	outputs, err := request.Outputs()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting outputs of request [%s]", request.Anchor)
+	}
+	// issue outputs come first in the output stream
+	numIssueOutputs := 0
+	for _, issue := range issues {
+		numIssueOutputs += len(issue.Receivers)
+	}
+	resolver := a.getIssuerResolver()
+	var records []*IssuerAttributionRecord
+	for i, issue := range issues {
+		actionOutputs := outputs.Filter(func(o *token.Output) bool {
+			return o.ActionIndex == i && o.Index < uint64(numIssueOutputs)
+		})
+		for _, tokenType := range actionOutputs.TokenTypes() {
+			var issuerID string
+			if resolver != nil {
+				id, ok, err := resolver.ResolveIssuer(ctx, issue.Issuer, tokenType)
+				if err != nil {
+					return nil, errors.WithMessagef(err, "failed resolving issuer of action [%d] in request [%s]", i, request.Anchor)
+				}
+				if ok {
+					issuerID = id
+				}
+			}
+			records = append(records, &IssuerAttributionRecord{
+				ActionIndex: i,
+				Issuer:      issue.Issuer,
+				IssuerID:    issuerID,
+				TokenType:   tokenType,
+				Amount:      new(big.Int).Set(actionOutputs.ByType(tokenType).Sum()),
+			})
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,54 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditor
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssuerRegistry(t *testing.T) {
+	r := NewIssuerRegistry()
+	assert.ErrorContains(t, r.RegisterIssuer("", token.Identity("alice")), "issuer id must be specified")
+	assert.ErrorContains(t, r.RegisterIssuer("bank", nil), "empty identity for issuer [bank]")
+	assert.NoError(t, r.RegisterIssuer("bank", token.Identity("alice"), token.Identity("bob")))
+
+	id, ok, err := r.ResolveIssuer(context.Background(), token.Identity("bob"), "USD")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "bank", id)
+	_, ok, err = r.ResolveIssuer(context.Background(), token.Identity("charlie"), "USD")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestSetIssuerResolver(t *testing.T) {
+	a := &Auditor{issuerResolver: NewIssuerRegistry()}
+
+	// the resolver can be replaced while transactions are attributed
+	replacement := NewIssuerRegistry()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			a.SetIssuerResolver(replacement)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			assert.NotNil(t, a.getIssuerResolver())
+		}
+	}()
+	wg.Wait()
+	assert.Same(t, replacement, a.getIssuerResolver())
+}
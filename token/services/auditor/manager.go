@@ -7,12 +7,15 @@ SPDX-License-Identifier: Apache-2.0
 package auditor
 
 import (
+	"os"
 	"reflect"
 	"sync"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracing"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb/export"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokens"
@@ -108,18 +111,92 @@ func (cm *Manager) newAuditor(tmsID token.TMSID) (*Auditor, error) {
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to get network instance for [%s]", tmsID)
 	}
-	auditor := &Auditor{
-		np:          cm.networkProvider,
-		tmsID:       tmsID,
-		auditDB:     auditDB,
-		tokenDB:     tokenDB,
-		tmsProvider: cm.tmsProvider,
-		finalityTracer: cm.tracerProvider.Tracer("auditor", tracing.WithMetricsOpts(tracing.MetricsOpts{
-			Namespace:  "tokensdk",
-			LabelNames: []tracing.LabelName{txIdLabel},
-		})),
+	if err := cm.enablePseudonymization(tmsID, auditDB); err != nil {
+		return nil, errors.WithMessagef(err, "failed to enable pseudonymization for [%s]", tmsID)
 	}
-	return auditor, nil
+	if err := cm.enableSampling(tmsID, auditDB); err != nil {
+		return nil, errors.WithMessagef(err, "failed to enable sampling for [%s]", tmsID)
+	}
+	exportConfig, err := cm.enableExport(tmsID, auditDB)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to enable export for [%s]", tmsID)
+	}
+	a := NewAuditor(cm.networkProvider, cm.tmsProvider, tmsID, auditDB, tokenDB, cm.tracerProvider.Tracer("auditor", tracing.WithMetricsOpts(tracing.MetricsOpts{
+		Namespace:  "tokensdk",
+		LabelNames: []tracing.LabelName{txIdLabel},
+	})))
+	a.exportConfig = exportConfig
+	return a, nil
+}
+
+// enablePseudonymization sets the auditdb pseudonymizer, if a pseudonymization key is configured for the TMS
+func (cm *Manager) enablePseudonymization(tmsID token.TMSID, auditDB *auditdb.DB) error {
+	tms, err := cm.tmsProvider.GetManagementService(token.WithTMSID(tmsID))
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get tms for [%s]", tmsID)
+	}
+	var keyFile string
+	if err := tms.Configuration().UnmarshalKey(PseudonymizationKeyFileKey, &keyFile); err != nil {
+		return errors.WithMessagef(err, "failed to load [%s]", PseudonymizationKeyFileKey)
+	}
+	if len(keyFile) == 0 {
+		return nil
+	}
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read pseudonymization key")
+	}
+	p, err := auditdb.NewPseudonymizer(key)
+	if err != nil {
+		return err
+	}
+	logger.Infof("enrollment ids in the auditdb of [%s] are pseudonymized", tmsID)
+	auditDB.SetPseudonymizer(p)
+	return nil
+}
+
+// enableSampling sets the auditdb sampler, if a sampling percentage is configured for the TMS
+func (cm *Manager) enableSampling(tmsID token.TMSID, auditDB *auditdb.DB) error {
+	tms, err := cm.tmsProvider.GetManagementService(token.WithTMSID(tmsID))
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get tms for [%s]", tmsID)
+	}
+	if !tms.Configuration().IsSet(SamplingPercentageKey) {
+		return nil
+	}
+	var percentage int
+	if err := tms.Configuration().UnmarshalKey(SamplingPercentageKey, &percentage); err != nil {
+		return errors.WithMessagef(err, "failed to load [%s]", SamplingPercentageKey)
+	}
+	s, err := auditdb.NewSampler(percentage)
+	if err != nil {
+		return err
+	}
+	logger.Infof("the auditdb of [%s] keeps full records for [%d%%] of the token requests", tmsID, percentage)
+	auditDB.SetSampler(s)
+	return nil
+}
+
+// enableExport makes the auditdb fill the export outbox, if the export is enabled for the TMS.
+// It returns the export configuration, nil if the export is not enabled.
+func (cm *Manager) enableExport(tmsID token.TMSID, auditDB *auditdb.DB) (*export.Config, error) {
+	tms, err := cm.tmsProvider.GetManagementService(token.WithTMSID(tmsID))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get tms for [%s]", tmsID)
+	}
+	if !tms.Configuration().IsSet(ExportKey) {
+		return nil, nil
+	}
+	config := &export.Config{}
+	if err := tms.Configuration().UnmarshalKey(ExportKey, config); err != nil {
+		return nil, errors.WithMessagef(err, "failed to load [%s]", ExportKey)
+	}
+	if !config.Enabled {
+		return nil, nil
+	}
+	logger.Infof("the records of the transactions audited for [%s] are exported to [%s]", tmsID, config.Topic)
+	auditDB.SetExport(true)
+	return config, nil
 }
 
 func (cm *Manager) restore(tmsID token.TMSID) error {
@@ -157,6 +234,15 @@ func (cm *Manager) restore(tmsID token.TMSID) error {
 		counter++
 	}
 	logger.Debugf("checked [%d] token requests", counter)
+
+	// the transactions of the blocks rolled back by the ledger become pending again,
+	// the token db is reverted by the listener of the ttx manager
+	rollbackListener := common.NewRollbackListener(logger, tmsID, auditor.auditDB, nil, func(txID string) error {
+		return net.AddFinalityListener(tmsID.Namespace, txID, common.NewFinalityListener(logger, cm.tmsProvider, tmsID, auditor.auditDB, tokenDB, auditor.finalityTracer))
+	})
+	if err := net.AddRollbackListener(tmsID.Namespace, rollbackListener); err != nil && !errors.Is(err, network.ErrRollbackNotSupported) {
+		return errors.WithMessagef(err, "failed to subscribe rollback listener to network [%s]", tmsID)
+	}
 	return nil
 }
 
@@ -164,6 +250,20 @@ var (
 	managerType = reflect.TypeOf((*Manager)(nil))
 )
 
+// PseudonymizationKeyFileKey is the TMS configuration key holding the path to the file containing the key
+// used to pseudonymize the enrollment IDs stored in the auditdb.
+// If not set, enrollment IDs are stored in clear.
+const PseudonymizationKeyFileKey = "services.auditor.pseudonymization.keyFile"
+
+// SamplingPercentageKey is the TMS configuration key holding the percentage of the token requests
+// for which the auditdb keeps full records. The others get aggregate-only records.
+// If not set, all token requests get full records.
+const SamplingPercentageKey = "services.auditor.sampling.percentage"
+
+// ExportKey is the TMS configuration key holding the configuration of the export of the audit records, see export.Config.
+// If not set, the audit records are not exported.
+const ExportKey = "services.auditor.export"
+
 // Get returns the Auditor instance for the passed auditor wallet
 func Get(sp token.ServiceProvider, w *token.AuditorWallet) *Auditor {
 	if w == nil {
@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditreplica
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+// ErrBrokenChain is returned when an entry does not extend the chain it is appended to
+var ErrBrokenChain = errors.New("broken hash chain")
+
+// Entry is a finalized transaction of the auditdb, as replicated.
+// Entries form a hash chain: each entry carries the hash of the previous one,
+// therefore, a replica detects missing, reordered, or altered entries.
+type Entry struct {
+	// Seq is the position of the entry in the chain, starting from 1
+	Seq uint64
+	// TxID is the transaction id
+	TxID string
+	// Status is the final status of the transaction, either Confirmed or Deleted
+	Status driver.TxStatus
+	// TokenRequest is the marshalled token request
+	TokenRequest []byte
+	// Records are the transaction records of the auditdb for the transaction
+	Records []driver.TransactionRecord
+	// PrevHash is the hash of the previous entry, nil for the first entry
+	PrevHash []byte
+	// Hash is the hash of this entry, see Digest
+	Hash []byte
+}
+
+// Head is the last entry of a chain. The head of an empty chain has Seq 0 and no hash.
+type Head struct {
+	Seq  uint64
+	Hash []byte
+}
+
+// Digest returns the hash of the entry over all its fields but Hash
+func (e *Entry) Digest() ([]byte, error) {
+	raw, err := json.Marshal(&Entry{
+		Seq:          e.Seq,
+		TxID:         e.TxID,
+		Status:       e.Status,
+		TokenRequest: e.TokenRequest,
+		Records:      e.Records,
+		PrevHash:     e.PrevHash,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling entry [%d]", e.Seq)
+	}
+	h := sha256.Sum256(raw)
+	return h[:], nil
+}
+
+// NewEntry returns a new entry extending the chain with the passed head
+func NewEntry(head Head, txID string, status driver.TxStatus, tokenRequest []byte, records []driver.TransactionRecord) (*Entry, error) {
+	e := &Entry{
+		Seq:          head.Seq + 1,
+		TxID:         txID,
+		Status:       status,
+		TokenRequest: tokenRequest,
+		Records:      records,
+		PrevHash:     head.Hash,
+	}
+	h, err := e.Digest()
+	if err != nil {
+		return nil, err
+	}
+	e.Hash = h
+	return e, nil
+}
+
+// Verify checks that the passed entries extend, in order, the chain with the passed head.
+// It returns the new head.
+func Verify(head Head, entries []*Entry) (Head, error) {
+	for _, e := range entries {
+		if e.Seq != head.Seq+1 {
+			return head, errors.Wrapf(ErrBrokenChain, "expected entry [%d], got [%d]", head.Seq+1, e.Seq)
+		}
+		if !bytes.Equal(e.PrevHash, head.Hash) {
+			return head, errors.Wrapf(ErrBrokenChain, "entry [%d] does not link to the previous one", e.Seq)
+		}
+		h, err := e.Digest()
+		if err != nil {
+			return head, err
+		}
+		if !bytes.Equal(e.Hash, h) {
+			return head, errors.Wrapf(ErrBrokenChain, "entry [%d] has been altered", e.Seq)
+		}
+		head = Head{Seq: e.Seq, Hash: e.Hash}
+	}
+	return head, nil
+}
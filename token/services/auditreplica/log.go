@@ -0,0 +1,142 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditreplica
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+// AuditDB is the auditdb as seen by the log
+type AuditDB interface {
+	TokenRequests(params driver.QueryTokenRequestsParams) (driver.TokenRequestIterator, error)
+	Transactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error)
+}
+
+// Log is the hash chain of the finalized transactions of an auditdb, kept by the auditor for its replicas.
+// Pending transactions are appended once they get confirmed or deleted.
+type Log struct {
+	auditDB AuditDB
+	store   *Store
+	lock    sync.Mutex
+}
+
+// NewLog returns a new Log of the passed auditdb of the passed TMS, persisted in the passed KVS
+func NewLog(auditDB AuditDB, kvs KVS, tmsID token.TMSID) *Log {
+	return &Log{auditDB: auditDB, store: NewStore(kvs, logPrefix, tmsID)}
+}
+
+// Extend appends to the log the finalized transactions of the auditdb not yet in it.
+// Only the token requests stored since the cursor of the log are queried.
+// The cursor moves to the oldest token request found still pending, if any, otherwise to the newest one found.
+// It returns the number of appended entries.
+func (l *Log) Extend() (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	cursor, err := l.store.Cursor()
+	if err != nil {
+		return 0, err
+	}
+	params := driver.QueryTokenRequestsParams{}
+	if !cursor.IsZero() {
+		params.From = &cursor
+	}
+	it, err := l.auditDB.TokenRequests(params)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to query token requests")
+	}
+	var requests []*driver.TokenRequestRecord
+	var newest, oldestPending *time.Time
+	for {
+		record, err := it.Next()
+		if err != nil {
+			it.Close()
+			return 0, errors.WithMessage(err, "failed to get next token request")
+		}
+		if record == nil {
+			break
+		}
+		if record.Status != driver.Confirmed && record.Status != driver.Deleted {
+			if oldestPending == nil || record.Timestamp.Before(*oldestPending) {
+				oldestPending = &record.Timestamp
+			}
+			continue
+		}
+		if newest == nil || record.Timestamp.After(*newest) {
+			newest = &record.Timestamp
+		}
+		found, err := l.store.Contains(record.TxID)
+		if err != nil {
+			it.Close()
+			return 0, err
+		}
+		if !found {
+			requests = append(requests, record)
+		}
+	}
+	it.Close()
+
+	head, err := l.store.Head()
+	if err != nil {
+		return 0, err
+	}
+	for i, request := range requests {
+		records, err := l.records(request.TxID, request.Status)
+		if err != nil {
+			return i, err
+		}
+		e, err := NewEntry(head, request.TxID, request.Status, request.TokenRequest, records)
+		if err != nil {
+			return i, err
+		}
+		if err := l.store.Append(e); err != nil {
+			return i, errors.WithMessagef(err, "failed to append [%s]", request.TxID)
+		}
+		head = Head{Seq: e.Seq, Hash: e.Hash}
+	}
+
+	// the requests at the cursor are queried again, those already in the log are skipped
+	switch {
+	case oldestPending != nil:
+		cursor = *oldestPending
+	case newest != nil:
+		cursor = *newest
+	}
+	if err := l.store.SetCursor(cursor); err != nil {
+		return len(requests), err
+	}
+	return len(requests), nil
+}
+
+func (l *Log) records(txID string, status driver.TxStatus) ([]driver.TransactionRecord, error) {
+	it, err := l.auditDB.Transactions(driver.QueryTransactionsParams{IDs: []string{txID}, Statuses: []driver.TxStatus{status}})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query records of [%s]", txID)
+	}
+	defer it.Close()
+	var records []driver.TransactionRecord
+	for {
+		record, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get next record of [%s]", txID)
+		}
+		if record == nil {
+			return records, nil
+		}
+		records = append(records, *record)
+	}
+}
+
+// Entries returns at most limit entries of the log starting from the passed sequence number
+func (l *Log) Entries(from uint64, limit int) ([]*Entry, error) {
+	return l.store.Entries(from, limit)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditreplica
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/pkg/errors"
+)
+
+var logger = logging.MustGetLogger("token-sdk.auditreplica")
+
+// Replica is the read-only copy of the log of an auditor, kept by a regulator node.
+// Only entries verified against the hash chain are appended.
+type Replica struct {
+	store *Store
+}
+
+// NewReplica returns a new Replica of the log of the passed TMS, persisted in the passed KVS
+func NewReplica(kvs KVS, tmsID token.TMSID) *Replica {
+	return &Replica{store: NewStore(kvs, replicaPrefix, tmsID)}
+}
+
+// Head returns the head of the replicated chain
+func (r *Replica) Head() (Head, error) {
+	return r.store.Head()
+}
+
+// Append verifies that the passed entries extend the replicated chain, and appends them
+func (r *Replica) Append(entries ...*Entry) error {
+	return r.store.Append(entries...)
+}
+
+// Query returns the replicated entries matching the passed query
+func (r *Replica) Query(q Query) ([]*Entry, error) {
+	return r.store.Query(q)
+}
+
+// ViewManager initiates views
+type ViewManager interface {
+	InitiateView(view view.View, ctx context.Context) (interface{}, error)
+}
+
+// Replicator keeps a replica in sync with the log of an auditor, pulling the new entries periodically
+type Replicator struct {
+	viewManager ViewManager
+	auditor     view.Identity
+	tmsID       token.TMSID
+	pageSize    int
+	interval    time.Duration
+}
+
+// NewReplicator returns a new Replicator pulling, every interval, the log of the passed TMS from the passed auditor,
+// in pages of the passed size
+func NewReplicator(viewManager ViewManager, auditor view.Identity, tmsID token.TMSID, pageSize int, interval time.Duration) *Replicator {
+	return &Replicator{
+		viewManager: viewManager,
+		auditor:     auditor,
+		tmsID:       tmsID,
+		pageSize:    pageSize,
+		interval:    interval,
+	}
+}
+
+// Sync pulls the entries of the log of the auditor not yet replicated.
+// It returns the number of replicated entries.
+func (r *Replicator) Sync(ctx context.Context) (int, error) {
+	res, err := r.viewManager.InitiateView(NewPullView(r.auditor, r.tmsID, r.pageSize), ctx)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed to pull log of [%s]", r.tmsID)
+	}
+	return res.(int), nil
+}
+
+// Start syncs the replica every interval, until the passed context is done
+func (r *Replicator) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			n, err := r.Sync(ctx)
+			if err != nil {
+				logger.Warnf("failed to sync replica of [%s]: [%s]", r.tmsID, err)
+			} else if n > 0 {
+				logger.Debugf("replicated [%d] entries of [%s]", n, r.tmsID)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
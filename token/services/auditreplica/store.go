@@ -0,0 +1,242 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditreplica
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+const (
+	logPrefix     = "auditreplica.log"
+	replicaPrefix = "auditreplica.replica"
+)
+
+// KVS models the key-value store the chains are persisted in
+type KVS interface {
+	Exists(id string) bool
+	Put(id string, state interface{}) error
+	Get(id string, state interface{}) error
+}
+
+// Query selects the entries of a chain.
+// Empty fields accept any value.
+type Query struct {
+	// FromSeq is the sequence number of the first entry to consider
+	FromSeq uint64
+	// TxIDs is the list of transaction ids to accept
+	TxIDs []string
+	// EnrollmentIDs selects the entries having a record whose sender or recipient has one of the passed enrollment ids
+	EnrollmentIDs []string
+	// TokenTypes selects the entries having a record of one of the passed token types
+	TokenTypes []string
+	// Statuses is the list of statuses to accept
+	Statuses []driver.TxStatus
+	// From selects the entries having a record stored at or after the passed time
+	From *time.Time
+	// To selects the entries having a record stored at or before the passed time
+	To *time.Time
+	// Limit is the maximum number of entries to return, if positive
+	Limit int
+}
+
+func (q *Query) match(e *Entry) bool {
+	if len(q.TxIDs) != 0 && !slices.Contains(q.TxIDs, e.TxID) {
+		return false
+	}
+	if len(q.Statuses) != 0 && !slices.Contains(q.Statuses, e.Status) {
+		return false
+	}
+	if len(q.EnrollmentIDs) == 0 && len(q.TokenTypes) == 0 && q.From == nil && q.To == nil {
+		return true
+	}
+	for _, r := range e.Records {
+		if len(q.EnrollmentIDs) != 0 && !slices.Contains(q.EnrollmentIDs, r.SenderEID) && !slices.Contains(q.EnrollmentIDs, r.RecipientEID) {
+			continue
+		}
+		if len(q.TokenTypes) != 0 && !slices.Contains(q.TokenTypes, r.TokenType) {
+			continue
+		}
+		if q.From != nil && r.Timestamp.Before(*q.From) {
+			continue
+		}
+		if q.To != nil && r.Timestamp.After(*q.To) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// Store persists a hash chain of entries of a TMS
+type Store struct {
+	kvs    KVS
+	prefix string
+	tmsID  token.TMSID
+	lock   sync.RWMutex
+}
+
+// NewStore returns a new Store for the chain of the passed TMS, under the passed key prefix
+func NewStore(kvs KVS, prefix string, tmsID token.TMSID) *Store {
+	return &Store{kvs: kvs, prefix: prefix, tmsID: tmsID}
+}
+
+func (s *Store) key(attrs ...string) (string, error) {
+	k, err := kvs.CreateCompositeKey(s.prefix, append([]string{s.tmsID.String()}, attrs...))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to generate key for [%v]", attrs)
+	}
+	return k, nil
+}
+
+func (s *Store) entryKey(seq uint64) (string, error) {
+	return s.key("entry", fmt.Sprintf("%020d", seq))
+}
+
+// Head returns the head of the chain
+func (s *Store) Head() (Head, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.head()
+}
+
+func (s *Store) head() (Head, error) {
+	k, err := s.key("head")
+	if err != nil {
+		return Head{}, err
+	}
+	head := Head{}
+	if !s.kvs.Exists(k) {
+		return head, nil
+	}
+	if err := s.kvs.Get(k, &head); err != nil {
+		return Head{}, errors.Wrapf(err, "failed to load head of [%s]", s.tmsID)
+	}
+	return head, nil
+}
+
+// Cursor returns the time of storage of the token requests the chain is extended from.
+// It is zero if the chain has not been extended yet.
+func (s *Store) Cursor() (time.Time, error) {
+	k, err := s.key("cursor")
+	if err != nil {
+		return time.Time{}, err
+	}
+	var cursor time.Time
+	if !s.kvs.Exists(k) {
+		return cursor, nil
+	}
+	if err := s.kvs.Get(k, &cursor); err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to load cursor of [%s]", s.tmsID)
+	}
+	return cursor, nil
+}
+
+// SetCursor stores the time of storage of the token requests the chain is extended from
+func (s *Store) SetCursor(cursor time.Time) error {
+	k, err := s.key("cursor")
+	if err != nil {
+		return err
+	}
+	if err := s.kvs.Put(k, cursor); err != nil {
+		return errors.Wrapf(err, "failed to store cursor of [%s]", s.tmsID)
+	}
+	return nil
+}
+
+// Contains returns true if the chain contains an entry for the passed transaction
+func (s *Store) Contains(txID string) (bool, error) {
+	k, err := s.key("tx", txID)
+	if err != nil {
+		return false, err
+	}
+	return s.kvs.Exists(k), nil
+}
+
+// Append verifies that the passed entries extend the chain, and appends them.
+// Nothing is appended if the verification fails.
+func (s *Store) Append(entries ...*Entry) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	head, err := s.head()
+	if err != nil {
+		return err
+	}
+	newHead, err := Verify(head, entries)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		k, err := s.entryKey(e.Seq)
+		if err != nil {
+			return err
+		}
+		if err := s.kvs.Put(k, e); err != nil {
+			return errors.Wrapf(err, "failed to store entry [%d] of [%s]", e.Seq, s.tmsID)
+		}
+		k, err = s.key("tx", e.TxID)
+		if err != nil {
+			return err
+		}
+		if err := s.kvs.Put(k, e.Seq); err != nil {
+			return errors.Wrapf(err, "failed to index entry [%d] of [%s]", e.Seq, s.tmsID)
+		}
+	}
+	k, err := s.key("head")
+	if err != nil {
+		return err
+	}
+	if err := s.kvs.Put(k, newHead); err != nil {
+		return errors.Wrapf(err, "failed to store head of [%s]", s.tmsID)
+	}
+	return nil
+}
+
+// Entries returns at most limit entries starting from the passed sequence number.
+// All the remaining entries are returned, if limit is not positive.
+func (s *Store) Entries(from uint64, limit int) ([]*Entry, error) {
+	return s.Query(Query{FromSeq: from, Limit: limit})
+}
+
+// Query returns the entries matching the passed query, in chain order.
+// The entries are scanned from q.FromSeq, then a narrow FromSeq keeps the query cheap.
+func (s *Store) Query(q Query) ([]*Entry, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	head, err := s.head()
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for seq := max(q.FromSeq, 1); seq <= head.Seq; seq++ {
+		k, err := s.entryKey(seq)
+		if err != nil {
+			return nil, err
+		}
+		e := &Entry{}
+		if err := s.kvs.Get(k, e); err != nil {
+			return nil, errors.Wrapf(err, "failed to load entry [%d] of [%s]", seq, s.tmsID)
+		}
+		if !q.match(e) {
+			continue
+		}
+		entries = append(entries, e)
+		if q.Limit > 0 && len(entries) >= q.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditreplica
+
+import (
+	"encoding/json"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/common/utils/collections"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type memKVS map[string][]byte
+
+func (m memKVS) Exists(id string) bool {
+	_, ok := m[id]
+	return ok
+}
+
+func (m memKVS) Put(id string, state interface{}) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	m[id] = raw
+	return nil
+}
+
+func (m memKVS) Get(id string, state interface{}) error {
+	raw, ok := m[id]
+	if !ok {
+		return errors.Errorf("[%s] not found", id)
+	}
+	return json.Unmarshal(raw, state)
+}
+
+type auditDB struct {
+	requests []*driver.TokenRequestRecord
+	records  []*driver.TransactionRecord
+	// queried holds the token requests returned by the last query
+	queried []string
+}
+
+func (db *auditDB) TokenRequests(params driver.QueryTokenRequestsParams) (driver.TokenRequestIterator, error) {
+	var requests []*driver.TokenRequestRecord
+	db.queried = nil
+	for _, r := range db.requests {
+		if len(params.Statuses) != 0 && !slices.Contains(params.Statuses, r.Status) {
+			continue
+		}
+		if params.From != nil && r.Timestamp.Before(*params.From) {
+			continue
+		}
+		requests = append(requests, r)
+		db.queried = append(db.queried, r.TxID)
+	}
+	return collections.NewSliceIterator(requests), nil
+}
+
+func (db *auditDB) Transactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error) {
+	var records []*driver.TransactionRecord
+	for _, r := range db.records {
+		if slices.Contains(params.IDs, r.TxID) && slices.Contains(params.Statuses, r.Status) {
+			records = append(records, r)
+		}
+	}
+	return collections.NewSliceIterator(records), nil
+}
+
+func (db *auditDB) add(txID string, status driver.TxStatus, eID string, at time.Time) {
+	db.requests = append(db.requests, &driver.TokenRequestRecord{TxID: txID, TokenRequest: []byte(txID), Status: status, Timestamp: at})
+	db.records = append(db.records, &driver.TransactionRecord{
+		TxID:         txID,
+		ActionType:   driver.Transfer,
+		SenderEID:    "issuer",
+		RecipientEID: eID,
+		TokenType:    "USD",
+		Amount:       big.NewInt(10),
+		Timestamp:    at,
+		Status:       status,
+	})
+}
+
+func TestLogAndReplica(t *testing.T) {
+	tmsID := token.TMSID{Network: "n", Channel: "c", Namespace: "ns"}
+	t0 := time.Now().UTC()
+	db := &auditDB{}
+	db.add("tx1", driver.Confirmed, "alice", t0)
+	db.add("tx2", driver.Pending, "bob", t0.Add(time.Minute))
+	db.add("tx3", driver.Deleted, "bob", t0.Add(2*time.Minute))
+
+	l := NewLog(db, memKVS{}, tmsID)
+	n, err := l.Extend()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"tx1", "tx2", "tx3"}, db.queried)
+
+	// the log is extended from the oldest pending request, tx2
+	n, err = l.Extend()
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Equal(t, []string{"tx2", "tx3"}, db.queried)
+
+	// tx2 gets confirmed, only tx2 is appended
+	db.requests[1].Status = driver.Confirmed
+	db.records[1].Status = driver.Confirmed
+	n, err = l.Extend()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// nothing is pending, the log is extended from the newest request, tx3
+	db.add("tx4", driver.Confirmed, "alice", t0.Add(3*time.Minute))
+	n, err = l.Extend()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"tx3", "tx4"}, db.queried)
+
+	entries, err := l.Entries(1, 3)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "tx2", entries[2].TxID)
+	assert.Len(t, entries[2].Records, 1)
+
+	replica := NewReplica(memKVS{}, tmsID)
+	assert.NoError(t, replica.Append(entries[:2]...))
+	// entries must extend the chain in order
+	assert.ErrorIs(t, replica.Append(entries[0]), ErrBrokenChain)
+	assert.NoError(t, replica.Append(entries[2]))
+	head, err := replica.Head()
+	assert.NoError(t, err)
+	assert.Equal(t, Head{Seq: 3, Hash: entries[2].Hash}, head)
+
+	res, err := replica.Query(Query{EnrollmentIDs: []string{"bob"}, Statuses: []driver.TxStatus{driver.Confirmed}})
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.Equal(t, "tx2", res[0].TxID)
+	from := t0.Add(time.Minute)
+	res, err = replica.Query(Query{From: &from, Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.Equal(t, "tx3", res[0].TxID)
+}
+
+func TestVerify(t *testing.T) {
+	e1, err := NewEntry(Head{}, "tx1", driver.Confirmed, []byte("tx1"), nil)
+	assert.NoError(t, err)
+	e2, err := NewEntry(Head{Seq: e1.Seq, Hash: e1.Hash}, "tx2", driver.Confirmed, []byte("tx2"), nil)
+	assert.NoError(t, err)
+
+	head, err := Verify(Head{}, []*Entry{e1, e2})
+	assert.NoError(t, err)
+	assert.Equal(t, Head{Seq: 2, Hash: e2.Hash}, head)
+
+	_, err = Verify(Head{}, []*Entry{e2})
+	assert.ErrorIs(t, err, ErrBrokenChain)
+
+	altered := *e2
+	altered.TokenRequest = []byte("forged")
+	_, err = Verify(Head{}, []*Entry{e1, &altered})
+	assert.ErrorIs(t, err, ErrBrokenChain)
+	assert.ErrorContains(t, err, "entry [2] has been altered")
+}
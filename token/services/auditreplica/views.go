@@ -0,0 +1,290 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditreplica
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	"github.com/pkg/errors"
+)
+
+const messageTimeout = time.Minute
+
+// ErrUnauthorized is returned when the caller of a view is not authorized
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Authorizer decides which identities can call a view
+type Authorizer interface {
+	Authorized(id view.Identity) bool
+}
+
+// Identities authorizes the identities in the list
+type Identities []view.Identity
+
+func (l Identities) Authorized(id view.Identity) bool {
+	for _, allowed := range l {
+		if allowed.Equal(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// PullRequest asks an auditor for the entries of its log starting from FromSeq
+type PullRequest struct {
+	TMSID   token.TMSID
+	FromSeq uint64
+	Limit   int
+}
+
+// QueryRequest asks a replica for the entries matching Query
+type QueryRequest struct {
+	TMSID token.TMSID
+	Query Query
+}
+
+// EntriesResponse carries the entries returned to a PullRequest or to a QueryRequest
+type EntriesResponse struct {
+	Entries []*Entry
+}
+
+// ResponderRegistry registers responder views
+type ResponderRegistry interface {
+	RegisterResponder(responder view.View, initiatedBy interface{}) error
+}
+
+// InstallAuditorViews registers, on the auditor node, the view serving the log to the replicas authorized by the passed authorizer
+func InstallAuditorViews(viewRegistry ResponderRegistry, authorizer Authorizer) error {
+	return viewRegistry.RegisterResponder(NewServeView(authorizer), &PullView{})
+}
+
+// InstallReplicaViews registers, on the regulator node, the view answering the queries of the regulators authorized by the passed authorizer
+func InstallReplicaViews(viewRegistry ResponderRegistry, authorizer Authorizer) error {
+	return viewRegistry.RegisterResponder(NewQueryView(authorizer), &RegulatorQueryView{})
+}
+
+func getKVS(context view.Context) (KVS, error) {
+	kvss, err := context.GetService(&kvs.KVS{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get KVS from context")
+	}
+	return kvss.(*kvs.KVS), nil
+}
+
+func authorize(context view.Context, authorizer Authorizer) error {
+	caller := context.Session().Info().Caller
+	if authorizer == nil || !authorizer.Authorized(caller) {
+		if err := context.Session().SendError([]byte(ErrUnauthorized.Error())); err != nil {
+			logger.Warnf("failed to notify [%s] of the failed authorization: [%s]", caller, err)
+		}
+		return errors.Wrapf(ErrUnauthorized, "caller [%s]", caller)
+	}
+	return nil
+}
+
+func send(context view.Context, session view.Session, msg interface{}) error {
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed marshalling message")
+	}
+	return session.SendWithContext(context.Context(), raw)
+}
+
+func receive(session view.Session, msg interface{}) error {
+	raw, err := ttx.ReadMessage(session, messageTimeout)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, msg)
+}
+
+// PullView pulls the entries of the log of an auditor not yet replicated, and appends them to the local replica.
+// It returns the number of replicated entries.
+type PullView struct {
+	auditor  view.Identity
+	tmsID    token.TMSID
+	pageSize int
+}
+
+// NewPullView returns a new PullView for the log of the passed TMS kept by the passed auditor.
+// The entries are pulled in pages of the passed size, or all at once if the size is not positive.
+func NewPullView(auditor view.Identity, tmsID token.TMSID, pageSize int) *PullView {
+	return &PullView{auditor: auditor, tmsID: tmsID, pageSize: pageSize}
+}
+
+func (p *PullView) Call(context view.Context) (interface{}, error) {
+	kvss, err := getKVS(context)
+	if err != nil {
+		return nil, err
+	}
+	replica := NewReplica(kvss, p.tmsID)
+	head, err := replica.Head()
+	if err != nil {
+		return nil, err
+	}
+	session, err := context.GetSession(p, p.auditor)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting session with [%s]", p.auditor)
+	}
+	defer session.Close()
+
+	replicated := 0
+	for {
+		if err := send(context, session, &PullRequest{TMSID: p.tmsID, FromSeq: head.Seq + 1, Limit: p.pageSize}); err != nil {
+			return replicated, errors.WithMessagef(err, "failed sending pull request to [%s]", p.auditor)
+		}
+		res := &EntriesResponse{}
+		if err := receive(session, res); err != nil {
+			return replicated, errors.WithMessagef(err, "failed receiving entries from [%s]", p.auditor)
+		}
+		if err := replica.Append(res.Entries...); err != nil {
+			return replicated, errors.WithMessagef(err, "failed appending entries from [%s]", p.auditor)
+		}
+		replicated += len(res.Entries)
+		if len(res.Entries) > 0 {
+			last := res.Entries[len(res.Entries)-1]
+			head = Head{Seq: last.Seq, Hash: last.Hash}
+		}
+		if p.pageSize <= 0 || len(res.Entries) < p.pageSize {
+			return replicated, nil
+		}
+	}
+}
+
+// ServeView is the auditor side of PullView.
+// It extends the log with the finalized transactions of the auditdb, and sends the requested entries.
+type ServeView struct {
+	authorizer Authorizer
+	lock       sync.Mutex
+	logs       map[token.TMSID]*Log
+}
+
+// NewServeView returns a new ServeView serving the replicas authorized by the passed authorizer
+func NewServeView(authorizer Authorizer) *ServeView {
+	return &ServeView{authorizer: authorizer, logs: map[token.TMSID]*Log{}}
+}
+
+func (s *ServeView) log(context view.Context, tmsID token.TMSID) (*Log, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if l, ok := s.logs[tmsID]; ok {
+		return l, nil
+	}
+	auditDB, err := auditdb.GetByTMSId(context, tmsID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get auditdb for [%s]", tmsID)
+	}
+	kvss, err := getKVS(context)
+	if err != nil {
+		return nil, err
+	}
+	l := NewLog(auditDB, kvss, tmsID)
+	s.logs[tmsID] = l
+	return l, nil
+}
+
+func (s *ServeView) Call(context view.Context) (interface{}, error) {
+	if err := authorize(context, s.authorizer); err != nil {
+		return nil, err
+	}
+	session := context.Session()
+	extended := false
+	for {
+		req := &PullRequest{}
+		if err := receive(session, req); err != nil {
+			return nil, errors.WithMessage(err, "failed receiving pull request")
+		}
+		l, err := s.log(context, req.TMSID)
+		if err != nil {
+			return nil, err
+		}
+		if !extended {
+			if _, err := l.Extend(); err != nil {
+				return nil, errors.WithMessagef(err, "failed extending log of [%s]", req.TMSID)
+			}
+			extended = true
+		}
+		entries, err := l.Entries(req.FromSeq, req.Limit)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed loading entries of [%s]", req.TMSID)
+		}
+		if err := send(context, session, &EntriesResponse{Entries: entries}); err != nil {
+			return nil, errors.WithMessagef(err, "failed sending entries of [%s]", req.TMSID)
+		}
+		if req.Limit <= 0 || len(entries) < req.Limit {
+			return nil, nil
+		}
+	}
+}
+
+// RegulatorQueryView queries the replica kept by a regulator node.
+// It returns the matching entries.
+type RegulatorQueryView struct {
+	replica view.Identity
+	request *QueryRequest
+}
+
+// NewRegulatorQueryView returns a new RegulatorQueryView asking the passed replica node for the entries of the passed TMS matching the passed query
+func NewRegulatorQueryView(replica view.Identity, tmsID token.TMSID, query Query) *RegulatorQueryView {
+	return &RegulatorQueryView{replica: replica, request: &QueryRequest{TMSID: tmsID, Query: query}}
+}
+
+func (r *RegulatorQueryView) Call(context view.Context) (interface{}, error) {
+	session, err := context.GetSession(r, r.replica)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting session with [%s]", r.replica)
+	}
+	defer session.Close()
+	if err := send(context, session, r.request); err != nil {
+		return nil, errors.WithMessagef(err, "failed sending query to [%s]", r.replica)
+	}
+	res := &EntriesResponse{}
+	if err := receive(session, res); err != nil {
+		return nil, errors.WithMessagef(err, "failed receiving entries from [%s]", r.replica)
+	}
+	return res.Entries, nil
+}
+
+// QueryView is the replica side of RegulatorQueryView
+type QueryView struct {
+	authorizer Authorizer
+}
+
+// NewQueryView returns a new QueryView answering the regulators authorized by the passed authorizer
+func NewQueryView(authorizer Authorizer) *QueryView {
+	return &QueryView{authorizer: authorizer}
+}
+
+func (q *QueryView) Call(context view.Context) (interface{}, error) {
+	if err := authorize(context, q.authorizer); err != nil {
+		return nil, err
+	}
+	session := context.Session()
+	req := &QueryRequest{}
+	if err := receive(session, req); err != nil {
+		return nil, errors.WithMessage(err, "failed receiving query")
+	}
+	kvss, err := getKVS(context)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := NewReplica(kvss, req.TMSID).Query(req.Query)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying replica of [%s]", req.TMSID)
+	}
+	if err := send(context, session, &EntriesResponse{Entries: entries}); err != nil {
+		return nil, errors.WithMessagef(err, "failed sending entries of [%s]", req.TMSID)
+	}
+	return nil, nil
+}
@@ -0,0 +1,349 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package capacity
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/metrics"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/pkg/errors"
+)
+
+var (
+	logger      = logging.MustGetLogger("token-sdk.capacity")
+	serviceType = reflect.TypeOf((*Service)(nil))
+
+	labels = []string{"network", "channel", "namespace", "database", "table"}
+
+	tableSize = metrics.GaugeOpts{
+		Namespace:    "capacity",
+		Name:         "table_size_bytes",
+		Help:         "The space taken by a table, its indexes included.",
+		LabelNames:   labels,
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}.%{database}.%{table}",
+	}
+	tableRows = metrics.GaugeOpts{
+		Namespace:    "capacity",
+		Name:         "table_rows",
+		Help:         "The number of rows of a table, estimated by some databases.",
+		LabelNames:   labels,
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}.%{database}.%{table}",
+	}
+	tableGrowth = metrics.GaugeOpts{
+		Namespace:    "capacity",
+		Name:         "table_growth_bytes_per_day",
+		Help:         "The growth rate of a table, over the samples taken so far.",
+		LabelNames:   labels,
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}.%{database}.%{table}",
+	}
+	tableProjectedSize = metrics.GaugeOpts{
+		Namespace:    "capacity",
+		Name:         "table_projected_size_bytes",
+		Help:         "The space a table is projected to take at the end of the projection horizon.",
+		LabelNames:   labels,
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}.%{database}.%{table}",
+	}
+)
+
+const (
+	// DefaultHorizon is the default time ahead the table sizes are projected to
+	DefaultHorizon = 30 * 24 * time.Hour
+	// MaxSamples is the number of samples kept per TMS to compute the growth rates
+	MaxSamples = 100
+
+	day = 24 * time.Hour
+)
+
+// Database identifies one of the databases of a TMS
+type Database string
+
+const (
+	// TokenDB is the token db
+	TokenDB Database = "tokendb"
+	// TransactionDB is the ttxdb
+	TransactionDB Database = "ttxdb"
+	// AuditDB is the auditdb
+	AuditDB Database = "auditdb"
+)
+
+// SizedDB is a database that can report the disk usage of its tables
+type SizedDB interface {
+	TableSizes() ([]driver.TableSize, error)
+}
+
+// TableReport is the disk usage of a table, with its growth rate and projection
+type TableReport struct {
+	Database Database
+	Table    string
+	Rows     int64
+	Bytes    int64
+	// BytesPerDay and RowsPerDay are the growth rates between the oldest sample kept and the current one.
+	// They are zero until a second sample is taken.
+	BytesPerDay float64
+	RowsPerDay  float64
+	// ProjectedBytes is the space the table is projected to take at the end of the horizon, at the current growth rate
+	ProjectedBytes int64
+}
+
+// Report is the disk usage of the databases of a TMS
+type Report struct {
+	TMSID   token.TMSID
+	Time    time.Time
+	Horizon time.Duration
+	// Since is the time of the oldest sample the growth rates are computed from
+	Since  time.Time
+	Tables []TableReport
+	// Unsupported lists the databases that cannot report the disk usage of their tables
+	Unsupported []Database
+}
+
+// Bytes returns the total space taken by the tables of the report
+func (r *Report) Bytes() int64 {
+	var total int64
+	for _, t := range r.Tables {
+		total += t.Bytes
+	}
+	return total
+}
+
+// ProjectedBytes returns the total space the tables of the report are projected to take at the end of the horizon
+func (r *Report) ProjectedBytes() int64 {
+	var total int64
+	for _, t := range r.Tables {
+		total += t.ProjectedBytes
+	}
+	return total
+}
+
+// Metrics exports the reports as gauges
+type Metrics struct {
+	TableSize          metrics.Gauge
+	TableRows          metrics.Gauge
+	TableGrowth        metrics.Gauge
+	TableProjectedSize metrics.Gauge
+}
+
+func NewMetrics(p metrics.Provider) *Metrics {
+	return &Metrics{
+		TableSize:          p.NewGauge(tableSize),
+		TableRows:          p.NewGauge(tableRows),
+		TableGrowth:        p.NewGauge(tableGrowth),
+		TableProjectedSize: p.NewGauge(tableProjectedSize),
+	}
+}
+
+// DBProvider returns the databases of a TMS. Databases that are not available are omitted.
+type DBProvider = func(tmsID token.TMSID) (map[Database]SizedDB, error)
+
+type tableKey struct {
+	database Database
+	table    string
+}
+
+type sample struct {
+	time  time.Time
+	sizes map[tableKey]driver.TableSize
+}
+
+// Service reports the disk usage of the token db, the ttxdb and the auditdb of the TMSs.
+// Each report is a sample, the growth rates are computed over the samples kept for the TMS.
+type Service struct {
+	dbs     DBProvider
+	metrics *Metrics
+	horizon time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	history map[token.TMSID][]sample
+}
+
+// NewService returns a new Service over the databases of the passed managers
+func NewService(tokenDBs *tokendb.Manager, ttxDBs *ttxdb.Manager, auditDBs *auditdb.Manager, metrics *Metrics) *Service {
+	return newService(func(tmsID token.TMSID) (map[Database]SizedDB, error) {
+		dbs := map[Database]SizedDB{}
+		tokenDB, err := tokenDBs.DBByTMSId(tmsID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get token db for [%s]", tmsID)
+		}
+		dbs[TokenDB] = tokenDB
+		ttxDB, err := ttxDBs.DBByTMSId(tmsID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get ttxdb for [%s]", tmsID)
+		}
+		dbs[TransactionDB] = ttxDB
+		auditDB, err := auditDBs.DBByTMSId(tmsID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get auditdb for [%s]", tmsID)
+		}
+		dbs[AuditDB] = auditDB
+		return dbs, nil
+	}, metrics)
+}
+
+func newService(dbs DBProvider, metrics *Metrics) *Service {
+	return &Service{
+		dbs:     dbs,
+		metrics: metrics,
+		horizon: DefaultHorizon,
+		now:     time.Now,
+		history: map[token.TMSID][]sample{},
+	}
+}
+
+// GetService returns the Service registered in the passed service provider
+func GetService(sp token.ServiceProvider) (*Service, error) {
+	s, err := sp.GetService(serviceType)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting capacity service")
+	}
+	return s.(*Service), nil
+}
+
+// SetHorizon sets how far ahead the table sizes are projected
+func (s *Service) SetHorizon(horizon time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.horizon = horizon
+}
+
+// Report samples the disk usage of the databases of the passed TMS and returns it,
+// together with the growth rates and the projections computed over the samples kept so far.
+func (s *Service) Report(tmsID token.TMSID) (*Report, error) {
+	dbs, err := s.dbs(tmsID)
+	if err != nil {
+		return nil, err
+	}
+	current := sample{time: s.now(), sizes: map[tableKey]driver.TableSize{}}
+	var unsupported []Database
+	for _, database := range []Database{TokenDB, TransactionDB, AuditDB} {
+		db, ok := dbs[database]
+		if !ok || db == nil {
+			continue
+		}
+		sizes, err := db.TableSizes()
+		if errors.Is(err, driver.ErrTableSizesNotSupported) {
+			unsupported = append(unsupported, database)
+			continue
+		}
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get table sizes of [%s]", database)
+		}
+		for _, size := range sizes {
+			current.sizes[tableKey{database: database, table: size.Table}] = size
+		}
+	}
+
+	s.mu.Lock()
+	history := append(s.history[tmsID], current)
+	if len(history) > MaxSamples {
+		history = history[len(history)-MaxSamples:]
+	}
+	s.history[tmsID] = history
+	horizon := s.horizon
+	s.mu.Unlock()
+
+	report := newReport(tmsID, history[0], current, horizon)
+	report.Unsupported = unsupported
+	s.export(report)
+	return report, nil
+}
+
+// Run samples the disk usage of the passed TMSs every interval, until the context is done
+func (s *Service) Run(ctx context.Context, interval time.Duration, tmsIDs []token.TMSID) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, tmsID := range tmsIDs {
+			if _, err := s.Report(tmsID); err != nil {
+				logger.Warnf("failed to sample the disk usage of tms [%s]: [%s]", tmsID, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func newReport(tmsID token.TMSID, oldest, current sample, horizon time.Duration) *Report {
+	report := &Report{TMSID: tmsID, Time: current.time, Horizon: horizon, Since: oldest.time}
+	elapsed := current.time.Sub(oldest.time)
+	for key, size := range current.sizes {
+		t := TableReport{
+			Database:       key.database,
+			Table:          key.table,
+			Rows:           size.Rows,
+			Bytes:          size.Bytes,
+			ProjectedBytes: size.Bytes,
+		}
+		if old, ok := oldest.sizes[key]; ok && elapsed > 0 {
+			days := float64(elapsed) / float64(day)
+			t.BytesPerDay = float64(size.Bytes-old.Bytes) / days
+			t.RowsPerDay = float64(size.Rows-old.Rows) / days
+			t.ProjectedBytes = size.Bytes + int64(t.BytesPerDay*float64(horizon)/float64(day))
+			if t.ProjectedBytes < 0 {
+				t.ProjectedBytes = 0
+			}
+		}
+		report.Tables = append(report.Tables, t)
+	}
+	sort.Slice(report.Tables, func(i, j int) bool {
+		if report.Tables[i].Database != report.Tables[j].Database {
+			return report.Tables[i].Database < report.Tables[j].Database
+		}
+		return report.Tables[i].Table < report.Tables[j].Table
+	})
+	return report
+}
+
+func (s *Service) export(r *Report) {
+	if s.metrics == nil {
+		return
+	}
+	for _, t := range r.Tables {
+		l := []string{
+			"network", r.TMSID.Network,
+			"channel", r.TMSID.Channel,
+			"namespace", r.TMSID.Namespace,
+			"database", string(t.Database),
+			"table", t.Table,
+		}
+		s.metrics.TableSize.With(l...).Set(float64(t.Bytes))
+		s.metrics.TableRows.With(l...).Set(float64(t.Rows))
+		s.metrics.TableGrowth.With(l...).Set(t.BytesPerDay)
+		s.metrics.TableProjectedSize.With(l...).Set(float64(t.ProjectedBytes))
+	}
+}
+
+// ReportView is a maintenance view returning the disk usage Report of a TMS
+type ReportView struct {
+	TMSID token.TMSID
+}
+
+func NewReportView(tmsID token.TMSID) *ReportView {
+	return &ReportView{TMSID: tmsID}
+}
+
+func (r *ReportView) Call(context view.Context) (interface{}, error) {
+	s, err := GetService(context)
+	if err != nil {
+		return nil, err
+	}
+	return s.Report(r.TMSID)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package capacity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type sizedDB struct {
+	sizes []driver.TableSize
+	err   error
+}
+
+func (db *sizedDB) TableSizes() ([]driver.TableSize, error) { return db.sizes, db.err }
+
+func TestReport(t *testing.T) {
+	tmsID := token.TMSID{Network: "n", Channel: "c", Namespace: "ns"}
+	tokenDB := &sizedDB{sizes: []driver.TableSize{{Table: "tokens", Rows: 10, Bytes: 1000}}}
+	ttxDB := &sizedDB{sizes: []driver.TableSize{{Table: "requests", Rows: 5, Bytes: 500}}}
+	auditDB := &sizedDB{err: errors.Wrapf(driver.ErrTableSizesNotSupported, "audit")}
+	s := newService(func(token.TMSID) (map[Database]SizedDB, error) {
+		return map[Database]SizedDB{TokenDB: tokenDB, TransactionDB: ttxDB, AuditDB: auditDB}, nil
+	}, nil)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	s.SetHorizon(10 * day)
+
+	// with a single sample there is no growth
+	r, err := s.Report(tmsID)
+	assert.NoError(t, err)
+	assert.Equal(t, []Database{AuditDB}, r.Unsupported)
+	assert.Equal(t, []TableReport{
+		{Database: TokenDB, Table: "tokens", Rows: 10, Bytes: 1000, ProjectedBytes: 1000},
+		{Database: TransactionDB, Table: "requests", Rows: 5, Bytes: 500, ProjectedBytes: 500},
+	}, r.Tables)
+	assert.Equal(t, int64(1500), r.Bytes())
+
+	// two days later the tokens grew by 200 bytes and 4 rows
+	now = now.Add(2 * day)
+	tokenDB.sizes = []driver.TableSize{{Table: "tokens", Rows: 14, Bytes: 1200}}
+	r, err = s.Report(tmsID)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(-2*day), r.Since)
+	assert.Equal(t, TableReport{Database: TokenDB, Table: "tokens", Rows: 14, Bytes: 1200, BytesPerDay: 100, RowsPerDay: 2, ProjectedBytes: 2200}, r.Tables[0])
+	assert.Equal(t, TableReport{Database: TransactionDB, Table: "requests", Rows: 5, Bytes: 500, ProjectedBytes: 500}, r.Tables[1])
+	assert.Equal(t, int64(2700), r.ProjectedBytes())
+
+	// other errors are returned
+	ttxDB.err = errors.New("boom")
+	_, err = s.Report(tmsID)
+	assert.Error(t, err)
+}
+
+func TestReportKeepsMaxSamples(t *testing.T) {
+	tmsID := token.TMSID{Network: "n", Channel: "c", Namespace: "ns"}
+	tokenDB := &sizedDB{}
+	s := newService(func(token.TMSID) (map[Database]SizedDB, error) {
+		return map[Database]SizedDB{TokenDB: tokenDB}, nil
+	}, nil)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < MaxSamples+5; i++ {
+		now := start.Add(time.Duration(i) * day)
+		s.now = func() time.Time { return now }
+		tokenDB.sizes = []driver.TableSize{{Table: "tokens", Bytes: int64(i * 10)}}
+		r, err := s.Report(tmsID)
+		assert.NoError(t, err)
+		if i > 0 {
+			assert.Equal(t, float64(10), r.Tables[0].BytesPerDay)
+		}
+	}
+	assert.Len(t, s.history[tmsID], MaxSamples)
+	assert.Equal(t, start.Add(5*day), s.history[tmsID][0].time)
+}
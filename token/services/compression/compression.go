@@ -0,0 +1,209 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/metrics"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/features"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/pkg/errors"
+)
+
+var (
+	logger      = logging.MustGetLogger("token-sdk.compression")
+	serviceType = reflect.TypeOf((*Service)(nil))
+
+	// ErrTooLarge is returned when a compressed payload expands beyond MaxDecompressedSize
+	ErrTooLarge = errors.New("decompressed payload too large")
+
+	// header prefixes the compressed payloads. A serialized token request never starts with a zero byte,
+	// therefore, the payloads without the header are returned as they are by Decompress.
+	header = []byte{0x00, 'T', 'K', 'Z', gzipAlgorithm}
+
+	requestSize = metrics.HistogramOpts{
+		Namespace:    "compression",
+		Name:         "request_size_bytes",
+		Help:         "The size of the token requests before compression.",
+		Buckets:      sizeBuckets,
+		LabelNames:   []string{"network", "channel", "namespace"},
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}",
+	}
+	compressedRequestSize = metrics.HistogramOpts{
+		Namespace:    "compression",
+		Name:         "compressed_request_size_bytes",
+		Help:         "The size of the token requests after compression.",
+		Buckets:      sizeBuckets,
+		LabelNames:   []string{"network", "channel", "namespace"},
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}",
+	}
+	// sizeBuckets go from 1KB to 64MB
+	sizeBuckets = []float64{1 << 10, 1 << 12, 1 << 14, 1 << 16, 1 << 18, 1 << 20, 1 << 22, 1 << 24, 1 << 26}
+)
+
+const (
+	// Capability is advertised by the token namespaces that decompress the token requests, see network.NamespaceInfo
+	Capability = "request.compression"
+
+	gzipAlgorithm byte = 1
+	// MaxDecompressedSize is the maximum size of a decompressed payload
+	MaxDecompressedSize = 128 << 20
+)
+
+// Compress returns the compressed version of the passed payload, prefixed with a header
+func Compress(raw []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(header)
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(raw); err != nil {
+		return nil, errors.Wrapf(err, "failed compressing payload")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrapf(err, "failed compressing payload")
+	}
+	return buf.Bytes(), nil
+}
+
+// IsCompressed returns true if the passed payload has been produced by Compress
+func IsCompressed(raw []byte) bool {
+	return bytes.HasPrefix(raw, header)
+}
+
+// Decompress returns the decompressed version of the passed payload, if it has been produced by Compress.
+// Otherwise, the payload is returned as it is.
+func Decompress(raw []byte) ([]byte, error) {
+	if !IsCompressed(raw) {
+		return raw, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(raw[len(header):]))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed decompressing payload")
+	}
+	defer r.Close()
+	// read one more byte than allowed to detect the payloads that are too large
+	res, err := io.ReadAll(io.LimitReader(r, MaxDecompressedSize+1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed decompressing payload")
+	}
+	if len(res) > MaxDecompressedSize {
+		return nil, ErrTooLarge
+	}
+	return res, nil
+}
+
+// Metrics tracks the size of the token requests before and after compression
+type Metrics struct {
+	RequestSize           metrics.Histogram
+	CompressedRequestSize metrics.Histogram
+}
+
+func NewMetrics(p metrics.Provider) *Metrics {
+	return &Metrics{
+		RequestSize:           p.NewHistogram(requestSize),
+		CompressedRequestSize: p.NewHistogram(compressedRequestSize),
+	}
+}
+
+// FlagService tells which features are on for a TMS
+type FlagService interface {
+	Enabled(tmsID token.TMSID, flag features.Flag) bool
+}
+
+// NamespaceService returns the info of the token namespace of a TMS, as deployed on the network
+type NamespaceService interface {
+	NamespaceInfo(tmsID token.TMSID) (*network.NamespaceInfo, error)
+}
+
+// networkNamespaces queries the token namespaces through their network
+type networkNamespaces struct {
+	networks *network.Provider
+}
+
+func (n *networkNamespaces) NamespaceInfo(tmsID token.TMSID) (*network.NamespaceInfo, error) {
+	net, err := n.networks.GetNetwork(tmsID.Network, tmsID.Channel)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting network [%s:%s]", tmsID.Network, tmsID.Channel)
+	}
+	return net.NamespaceInfo(tmsID.Namespace)
+}
+
+// Service compresses the token requests sent by the node, for the TMSs with the RequestCompression feature on
+// whose token namespace advertises the Capability
+type Service struct {
+	flags      FlagService
+	namespaces NamespaceService
+	metrics    *Metrics
+
+	// capable caches, for each TMS, whether its token namespace advertises the Capability
+	capable sync.Map
+}
+
+func NewService(flags *features.Service, networks *network.Provider, metrics *Metrics) *Service {
+	return &Service{flags: flags, namespaces: &networkNamespaces{networks: networks}, metrics: metrics}
+}
+
+// GetService returns the Service registered in the passed service provider
+func GetService(sp token.ServiceProvider) (*Service, error) {
+	s, err := sp.GetService(serviceType)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting compression service")
+	}
+	return s.(*Service), nil
+}
+
+// Enabled returns true if the token requests of the passed TMS are compressed
+func (s *Service) Enabled(tmsID token.TMSID) bool {
+	return s.flags.Enabled(tmsID, features.RequestCompression)
+}
+
+// CompressRequest compresses the passed serialized token request, if compression is on for the passed TMS,
+// and its token namespace advertises the Capability.
+// The request is returned as it is if compression is off, not supported, or if it does not make it smaller.
+func (s *Service) CompressRequest(tmsID token.TMSID, raw []byte) ([]byte, error) {
+	if len(raw) == 0 || !s.Enabled(tmsID) || !s.supported(tmsID) {
+		return raw, nil
+	}
+	compressed, err := Compress(raw)
+	if err != nil {
+		return nil, err
+	}
+	if s.metrics != nil {
+		s.metrics.RequestSize.With("network", tmsID.Network, "channel", tmsID.Channel, "namespace", tmsID.Namespace).Observe(float64(len(raw)))
+		s.metrics.CompressedRequestSize.With("network", tmsID.Network, "channel", tmsID.Channel, "namespace", tmsID.Namespace).Observe(float64(len(compressed)))
+	}
+	if len(compressed) >= len(raw) {
+		logger.Debugf("compressed request is not smaller [%d]>=[%d], send it uncompressed", len(compressed), len(raw))
+		return raw, nil
+	}
+	return compressed, nil
+}
+
+// supported returns true if the token namespace of the passed TMS advertises the Capability.
+// The answer is cached, but when the namespace info cannot be fetched: the next request asks again.
+func (s *Service) supported(tmsID token.TMSID) bool {
+	if capable, ok := s.capable.Load(tmsID); ok {
+		return capable.(bool)
+	}
+	info, err := s.namespaces.NamespaceInfo(tmsID)
+	if err != nil {
+		logger.Warnf("failed getting info of the namespace of [%s], token request sent uncompressed: [%s]", tmsID, err)
+		return false
+	}
+	capable := info.HasCapability(Capability)
+	if !capable {
+		logger.Infof("namespace of [%s] at version [%s] does not advertise [%s], token requests are sent uncompressed", tmsID, info.Version, Capability)
+	}
+	s.capable.Store(tmsID, capable)
+	return capable
+}
@@ -0,0 +1,118 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/features"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type flags map[token.TMSID]bool
+
+func (f flags) Enabled(tmsID token.TMSID, flag features.Flag) bool {
+	return flag == features.RequestCompression && f[tmsID]
+}
+
+func TestCompress(t *testing.T) {
+	raw := bytes.Repeat([]byte("token request "), 1000)
+	compressed, err := Compress(raw)
+	assert.NoError(t, err)
+	assert.True(t, IsCompressed(compressed))
+	assert.Less(t, len(compressed), len(raw))
+
+	decompressed, err := Decompress(compressed)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, decompressed)
+
+	// payloads without the header are returned as they are
+	decompressed, err = Decompress(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, decompressed)
+
+	// a corrupted payload is rejected
+	_, err = Decompress(compressed[:len(header)+5])
+	assert.Error(t, err)
+}
+
+// namespaces returns the info of the namespaces, and counts the queries
+type namespaces struct {
+	infos   map[token.TMSID]*network.NamespaceInfo
+	queries int
+}
+
+func (n *namespaces) NamespaceInfo(tmsID token.TMSID) (*network.NamespaceInfo, error) {
+	n.queries++
+	info, ok := n.infos[tmsID]
+	if !ok {
+		return nil, errors.Errorf("namespace [%s] not reachable", tmsID.Namespace)
+	}
+	return info, nil
+}
+
+func TestCompressRequest(t *testing.T) {
+	on := token.TMSID{Network: "n", Channel: "c", Namespace: "on"}
+	off := token.TMSID{Network: "n", Channel: "c", Namespace: "off"}
+	s := &Service{
+		flags:      flags{on: true},
+		namespaces: &namespaces{infos: map[token.TMSID]*network.NamespaceInfo{on: {Capabilities: []string{Capability}}}},
+	}
+
+	raw := bytes.Repeat([]byte("token request "), 1000)
+	res, err := s.CompressRequest(off, raw)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, res)
+
+	res, err = s.CompressRequest(on, raw)
+	assert.NoError(t, err)
+	assert.True(t, IsCompressed(res))
+	decompressed, err := Decompress(res)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, decompressed)
+
+	// requests that do not get smaller are sent uncompressed
+	small := []byte{1, 2, 3}
+	res, err = s.CompressRequest(on, small)
+	assert.NoError(t, err)
+	assert.Equal(t, small, res)
+}
+
+func TestCompressRequestCapability(t *testing.T) {
+	old := token.TMSID{Network: "n", Channel: "c", Namespace: "old"}
+	unreachable := token.TMSID{Network: "n", Channel: "c", Namespace: "unreachable"}
+	ns := &namespaces{infos: map[token.TMSID]*network.NamespaceInfo{old: {Version: "v1"}}}
+	s := &Service{flags: flags{old: true, unreachable: true}, namespaces: ns}
+	raw := bytes.Repeat([]byte("token request "), 1000)
+
+	// a namespace that does not advertise the capability cannot decompress, even if the flag is on
+	for i := 0; i < 2; i++ {
+		res, err := s.CompressRequest(old, raw)
+		assert.NoError(t, err)
+		assert.Equal(t, raw, res)
+	}
+	// the answer is cached
+	assert.Equal(t, 1, ns.queries)
+
+	// if the namespace cannot be queried, the request is sent uncompressed, and the namespace queried again next time
+	for i := 0; i < 2; i++ {
+		res, err := s.CompressRequest(unreachable, raw)
+		assert.NoError(t, err)
+		assert.Equal(t, raw, res)
+	}
+	assert.Equal(t, 3, ns.queries)
+
+	// once the namespace advertises the capability, the requests are compressed
+	ns.infos[unreachable] = &network.NamespaceInfo{Version: "v2", Capabilities: []string{Capability}}
+	res, err := s.CompressRequest(unreachable, raw)
+	assert.NoError(t, err)
+	assert.True(t, IsCompressed(res))
+}
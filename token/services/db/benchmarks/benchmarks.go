@@ -0,0 +1,141 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package benchmarks measures the throughput of the token db drivers against realistic token populations.
+// The measurements are Go benchmarks, the drivers plug in via an Opener.
+package benchmarks
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// Opener returns an empty token db. Each call must return a database isolated from those returned before.
+type Opener func(tb testing.TB) driver.TokenDB
+
+// Driver is a token db driver under benchmark
+type Driver struct {
+	Name string
+	Open Opener
+}
+
+// Run runs the passed benchmark for each driver and each workload, as sub-benchmarks named after them
+func Run(b *testing.B, drivers []Driver, workloads []Workload, benchmark func(b *testing.B, open Opener, w Workload)) {
+	for _, d := range drivers {
+		for _, w := range workloads {
+			b.Run(d.Name+"/"+w.String(), func(b *testing.B) {
+				benchmark(b, d.Open, w)
+			})
+		}
+	}
+}
+
+// StoreToken measures the throughput of storing new tokens on top of the population of the workload.
+// The tokens are stored in transactions of Workload.TokensPerTx tokens.
+func StoreToken(b *testing.B, open Opener, w Workload) {
+	db, p := setup(b, open, w)
+	tokens := p.Generate(b.N)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	if err := Store(db, tokens, p.Workload.TokensPerTx); err != nil {
+		b.Fatal(err)
+	}
+	b.StopTimer()
+	reportThroughput(b, "tokens/s")
+}
+
+// Selection measures the throughput of selecting, in a random wallet, spendable tokens covering Workload.SelectionAmount.
+// A selection stops at the first tokens covering the amount, or when the wallet has no more tokens of the type.
+func Selection(b *testing.B, open Opener, w Workload) {
+	db, p := setup(b, open, w)
+	target := new(big.Int).SetUint64(p.Workload.SelectionAmount)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		it, err := db.SpendableTokensIteratorBy(context.Background(), p.RandomWallet(), p.RandomType())
+		if err != nil {
+			b.Fatal(err)
+		}
+		sum := new(big.Int)
+		for sum.Cmp(target) < 0 {
+			t, err := it.Next()
+			if err != nil {
+				it.Close()
+				b.Fatal(err)
+			}
+			if t == nil {
+				break
+			}
+			q, err := token.ToQuantity(t.Quantity, 64)
+			if err != nil {
+				it.Close()
+				b.Fatal(err)
+			}
+			sum.Add(sum, q.ToBigInt())
+		}
+		it.Close()
+	}
+	b.StopTimer()
+	reportThroughput(b, "selections/s")
+}
+
+// Balance measures the throughput of computing the balance of a random wallet for a random type
+func Balance(b *testing.B, open Opener, w Workload) {
+	db, p := setup(b, open, w)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.Balance(p.RandomWallet(), p.RandomType()); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	reportThroughput(b, "balances/s")
+}
+
+// DeleteTokens measures the throughput of deleting tokens, Workload.TokensPerTx tokens per call.
+// The tokens to delete are stored, untimed, on top of the population of the workload.
+func DeleteTokens(b *testing.B, open Opener, w Workload) {
+	db, p := setup(b, open, w)
+	tokens := p.Generate(b.N)
+	if err := Store(db, tokens, p.Workload.TokensPerTx); err != nil {
+		b.Fatal(err)
+	}
+	ids := IDs(tokens)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for start := 0; start < len(ids); start += p.Workload.TokensPerTx {
+		if err := db.DeleteTokens("bench", ids[start:min(start+p.Workload.TokensPerTx, len(ids))]...); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	reportThroughput(b, "tokens/s")
+}
+
+func setup(b *testing.B, open Opener, w Workload) (driver.TokenDB, *Population) {
+	b.Helper()
+	db := open(b)
+	p := NewPopulation(w)
+	if err := p.Populate(db); err != nil {
+		b.Fatal(err)
+	}
+	return db, p
+}
+
+func reportThroughput(b *testing.B, unit string) {
+	if s := b.Elapsed().Seconds(); s > 0 {
+		b.ReportMetric(float64(b.N)/s, unit)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package benchmarks
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+
+	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
+	common2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/postgres"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	workloads = []Workload{
+		DefaultWorkload(),
+		func() Workload {
+			w := DefaultWorkload()
+			w.Distribution = Uniform
+			return w
+		}(),
+	}
+	tables atomic.Int32
+)
+
+func BenchmarkStoreToken(b *testing.B) {
+	Run(b, drivers(b), workloads, StoreToken)
+}
+
+func BenchmarkSelection(b *testing.B) {
+	Run(b, drivers(b), workloads, Selection)
+}
+
+func BenchmarkBalance(b *testing.B) {
+	Run(b, drivers(b), workloads, Balance)
+}
+
+func BenchmarkDeleteTokens(b *testing.B) {
+	Run(b, drivers(b), workloads, DeleteTokens)
+}
+
+// TestPopulation checks that the population stored in the token db is the one generated from the workload
+func TestPopulation(t *testing.T) {
+	w := DefaultWorkload()
+	w.Wallets, w.Tokens = 10, 100
+
+	p := NewPopulation(w)
+	assert.Equal(t, p.Generate(w.Tokens), NewPopulation(w).Generate(w.Tokens))
+
+	db := sqliteOpener(t.TempDir())(t)
+	p = NewPopulation(w)
+	assert.NoError(t, p.Populate(db))
+	assert.Len(t, p.Tokens, w.Tokens)
+
+	expected := map[[2]string]uint64{}
+	for _, tok := range p.Tokens {
+		expected[[2]string{tok.Record.OwnerWalletID, tok.Record.Type}] += tok.Record.Amount
+	}
+	for _, wallet := range p.Wallets {
+		for _, typ := range w.Types {
+			balance, err := db.Balance(wallet, typ)
+			assert.NoError(t, err)
+			assert.Equal(t, expected[[2]string{wallet, typ}], balance)
+		}
+	}
+
+	assert.NoError(t, db.DeleteTokens("test", IDs(p.Tokens)...))
+	for _, wallet := range p.Wallets {
+		balance, err := db.Balance(wallet, w.Types[0])
+		assert.NoError(t, err)
+		assert.Zero(t, balance)
+	}
+}
+
+// drivers returns the sqlite driver and, if containers are enabled, the postgres driver
+func drivers(b *testing.B) []Driver {
+	ds := []Driver{{Name: "sqlite", Open: sqliteOpener(b.TempDir())}}
+	if os.Getenv("TESTCONTAINERS") == "true" && !testing.Short() {
+		terminate, dataSource := common.StartPostgresContainer(b)
+		b.Cleanup(terminate)
+		ds = append(ds, Driver{Name: "postgres", Open: postgresOpener(dataSource)})
+	}
+	return ds
+}
+
+func sqliteOpener(dir string) Opener {
+	return func(tb testing.TB) driver.TokenDB {
+		return open(tb, sql2.SQLite, fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", path.Join(dir, "db.sqlite")), sqlite.NewTokenDB)
+	}
+}
+
+func postgresOpener(dataSource string) Opener {
+	return func(tb testing.TB) driver.TokenDB {
+		return open(tb, sql2.Postgres, dataSource, postgres.NewTokenDB)
+	}
+}
+
+// open returns a token db whose tables have a prefix not used before, to isolate it from the others
+func open(tb testing.TB, driverName common2.SQLDriverType, dataSource string, newTokenDB func(*sql.DB, common.NewDBOpts) (driver.TokenDB, error)) driver.TokenDB {
+	tb.Helper()
+	sqlDB, err := common.NewSQLDBOpener("", "").OpenSQLDB(driverName, dataSource, 10, false)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	db, err := newTokenDB(sqlDB, common.NewDBOpts{
+		DataSource:   dataSource,
+		TablePrefix:  tablePrefix(tables.Add(1)),
+		CreateSchema: true,
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if c, ok := db.(io.Closer); ok {
+		tb.Cleanup(func() { _ = c.Close() })
+	}
+	return db
+}
+
+// tablePrefix returns a prefix made of letters only, as required by the drivers
+func tablePrefix(n int32) string {
+	prefix := []byte("bench_")
+	for ; n > 0; n /= 26 {
+		prefix = append(prefix, byte('a'+n%26))
+	}
+	return string(prefix)
+}
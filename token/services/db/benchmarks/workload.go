@@ -0,0 +1,215 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package benchmarks
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Distribution is the way the tokens of a population are spread across the wallets
+type Distribution int
+
+const (
+	// Uniform gives each wallet about the same number of tokens
+	Uniform Distribution = iota
+	// Zipf gives a few wallets most of the tokens, as it happens with exchanges and merchants
+	Zipf
+)
+
+func (d Distribution) String() string {
+	switch d {
+	case Uniform:
+		return "uniform"
+	case Zipf:
+		return "zipf"
+	default:
+		return fmt.Sprintf("distribution(%d)", int(d))
+	}
+}
+
+// Workload describes a token population and the operations run against it
+type Workload struct {
+	// Wallets is the number of wallets owning the tokens
+	Wallets int
+	// Tokens is the number of unspent tokens in the database before the measurement starts
+	Tokens int
+	// Distribution spreads the tokens across the wallets
+	Distribution Distribution
+	// Types are the token types, each token gets one of them at random
+	Types []string
+	// MaxAmount is the maximum amount of a token, amounts are uniform in [1, MaxAmount]
+	MaxAmount uint64
+	// TokensPerTx is the number of tokens stored or deleted in a single database transaction
+	TokensPerTx int
+	// SelectionAmount is the amount a selection must cover
+	SelectionAmount uint64
+	// MetadataSize is the size in bytes of the ledger metadata of a token, as the openings of a zkat-dlog token
+	MetadataSize int
+	// Seed makes the population reproducible
+	Seed int64
+}
+
+// DefaultWorkload returns a workload of a thousand wallets sharing ten thousand tokens of two types,
+// skewed towards a few wallets, with transactions of two outputs
+func DefaultWorkload() Workload {
+	return Workload{
+		Wallets:         1000,
+		Tokens:          10000,
+		Distribution:    Zipf,
+		Types:           []string{"USD", "EUR"},
+		MaxAmount:       100,
+		TokensPerTx:     2,
+		SelectionAmount: 150,
+		MetadataSize:    512,
+		Seed:            42,
+	}
+}
+
+func (w Workload) String() string {
+	return fmt.Sprintf("wallets=%d,tokens=%d,%s", w.Wallets, w.Tokens, w.Distribution)
+}
+
+// Token is a token of a population, together with the wallets owning it
+type Token struct {
+	Record driver.TokenRecord
+	Owners []string
+}
+
+// Population is a set of tokens generated from a workload
+type Population struct {
+	Workload Workload
+	Wallets  []string
+	Tokens   []Token
+
+	rnd  *rand.Rand
+	zipf *rand.Zipf
+	next int
+}
+
+// NewPopulation returns a new population for the passed workload, with no tokens yet
+func NewPopulation(w Workload) *Population {
+	if w.Wallets <= 0 {
+		w.Wallets = 1
+	}
+	if len(w.Types) == 0 {
+		w.Types = []string{"USD"}
+	}
+	if w.MaxAmount == 0 {
+		w.MaxAmount = 1
+	}
+	if w.TokensPerTx <= 0 {
+		w.TokensPerTx = 1
+	}
+	p := &Population{
+		Workload: w,
+		Wallets:  make([]string, w.Wallets),
+		rnd:      rand.New(rand.NewSource(w.Seed)),
+	}
+	for i := range p.Wallets {
+		p.Wallets[i] = fmt.Sprintf("wallet%d", i)
+	}
+	if w.Distribution == Zipf {
+		p.zipf = rand.NewZipf(p.rnd, 1.1, 1, uint64(w.Wallets-1))
+	}
+	return p
+}
+
+// Generate returns n new tokens, with ids never returned before by this population.
+// The tokens are not added to the population.
+func (p *Population) Generate(n int) []Token {
+	tokens := make([]Token, n)
+	for i := range tokens {
+		wallet := p.Wallets[p.wallet()]
+		typ := p.Workload.Types[p.rnd.Intn(len(p.Workload.Types))]
+		amount := uint64(p.rnd.Int63n(int64(p.Workload.MaxAmount))) + 1
+		ownerRaw := make([]byte, 64)
+		p.rnd.Read(ownerRaw)
+		metadata := make([]byte, p.Workload.MetadataSize)
+		p.rnd.Read(metadata)
+		tokens[i] = Token{
+			Record: driver.TokenRecord{
+				TxID:           fmt.Sprintf("tx%d", p.next/p.Workload.TokensPerTx),
+				Index:          uint64(p.next % p.Workload.TokensPerTx),
+				OwnerRaw:       ownerRaw,
+				OwnerType:      "idemix",
+				OwnerIdentity:  ownerRaw,
+				OwnerWalletID:  wallet,
+				Ledger:         ownerRaw,
+				LedgerMetadata: metadata,
+				Quantity:       fmt.Sprintf("0x%x", amount),
+				Type:           typ,
+				Amount:         amount,
+				Owner:          true,
+			},
+			Owners: []string{wallet},
+		}
+		p.next++
+	}
+	return tokens
+}
+
+// Populate generates the tokens of the workload, stores them in the passed database and adds them to the population
+func (p *Population) Populate(db driver.TokenDB) error {
+	tokens := p.Generate(p.Workload.Tokens)
+	if err := Store(db, tokens, p.Workload.TokensPerTx); err != nil {
+		return errors.WithMessage(err, "failed populating the token db")
+	}
+	p.Tokens = append(p.Tokens, tokens...)
+	return nil
+}
+
+// RandomWallet returns a wallet, chosen with the distribution of the workload
+func (p *Population) RandomWallet() string {
+	return p.Wallets[p.wallet()]
+}
+
+// RandomType returns one of the token types of the workload
+func (p *Population) RandomType() string {
+	return p.Workload.Types[p.rnd.Intn(len(p.Workload.Types))]
+}
+
+func (p *Population) wallet() int {
+	if p.zipf != nil {
+		return int(p.zipf.Uint64())
+	}
+	return p.rnd.Intn(len(p.Wallets))
+}
+
+// Store stores the passed tokens, tokensPerTx tokens in each database transaction
+func Store(db driver.TokenDB, tokens []Token, tokensPerTx int) error {
+	for start := 0; start < len(tokens); start += tokensPerTx {
+		tx, err := db.NewTokenDBTransaction(context.Background())
+		if err != nil {
+			return errors.WithMessage(err, "failed to begin transaction")
+		}
+		for _, t := range tokens[start:min(start+tokensPerTx, len(tokens))] {
+			if err := tx.StoreToken(context.Background(), t.Record, t.Owners); err != nil {
+				_ = tx.Rollback()
+				return errors.WithMessagef(err, "failed to store token [%s:%d]", t.Record.TxID, t.Record.Index)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return errors.WithMessage(err, "failed to commit transaction")
+		}
+	}
+	return nil
+}
+
+// IDs returns the ids of the passed tokens
+func IDs(tokens []Token) []*token.ID {
+	ids := make([]*token.ID, len(tokens))
+	for i, t := range tokens {
+		ids[i] = &token.ID{TxId: t.Record.TxID, Index: t.Record.Index}
+	}
+	return ids
+}
@@ -0,0 +1,36 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+const (
+	// CommitPipelineConfigKey is the key, in the TMS configuration, of the commit pipeline configuration
+	CommitPipelineConfigKey = "commitPipeline"
+
+	defaultCommitPipelineMaxBatch = 100
+)
+
+// CommitPipelineOpts configures the single-writer commit pipeline of a database
+type CommitPipelineOpts struct {
+	// Enabled makes a single worker apply all the writes to the database
+	Enabled bool `yaml:"enabled,omitempty"`
+	// MaxBatch is the maximum number of writes applied in the same db transaction. The default is 100.
+	MaxBatch int `yaml:"maxBatch,omitempty"`
+}
+
+// GetMaxBatch returns the maximum number of writes applied in the same db transaction
+func (o CommitPipelineOpts) GetMaxBatch() int {
+	if o.MaxBatch > 0 {
+		return o.MaxBatch
+	}
+	return defaultCommitPipelineMaxBatch
+}
+
+// CommitPipelineEnabler is implemented by the services whose writes can be applied by a single-writer pipeline
+type CommitPipelineEnabler interface {
+	// EnableCommitPipeline makes a single worker apply the writes to the service, as configured by the passed options
+	EnableCommitPipeline(opts CommitPipelineOpts)
+}
@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -32,11 +33,25 @@ var TokenTransactionDBCases = []struct {
 	{"Movements", TMovements},
 	{"Transaction", TTransaction},
 	{"TokenRequest", TTokenRequest},
+	{"TokenRequestApplicationMetadata", TTokenRequestApplicationMetadata},
 	{"AllowsSameTxID", TAllowsSameTxID},
 	{"Rollback", TRollback},
 	{"TransactionQueries", TTransactionQueries},
 	{"ValidationRecordQueries", TValidationRecordQueries},
 	{"TEndorserAcks", TEndorserAcks},
+	{"IssuerAttributions", TIssuerAttributions},
+	{"StatusOverrides", TStatusOverrides},
+	{"CompensationFailures", TCompensationFailures},
+	{"MovementCorrections", TMovementCorrections},
+	{"IdempotencyKeys", TIdempotencyKeys},
+	{"AuditResponses", TAuditResponses},
+	{"FundsWitnesses", TFundsWitnesses},
+	{"References", TReferences},
+	{"Wallets", TWallets},
+	{"ExportRecords", TExportRecords},
+	{"Pseudonyms", TPseudonyms},
+	{"RecipientAnonymityLevels", TRecipientAnonymityLevels},
+	{"EraseEnrollmentID", TEraseEnrollmentID},
 }
 
 func TFailsIfRequestDoesNotExist(t *testing.T, db driver.TokenTransactionDB) {
@@ -226,6 +241,19 @@ func TMovements(t *testing.T, db driver.TokenTransactionDB) {
 	records, err = db.QueryMovements(driver.QueryMovementsParams{TxStatuses: []driver.TxStatus{driver.Confirmed}})
 	assert.NoError(t, err)
 	assert.Len(t, records, 1)
+	assert.False(t, records[0].Timestamp.IsZero())
+
+	// time range
+	before, after := records[0].Timestamp.Add(-time.Hour), records[0].Timestamp.Add(time.Hour)
+	records, err = db.QueryMovements(driver.QueryMovementsParams{MovementDirection: driver.All, From: &before, To: &after})
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	records, err = db.QueryMovements(driver.QueryMovementsParams{MovementDirection: driver.All, From: &after})
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+	records, err = db.QueryMovements(driver.QueryMovementsParams{MovementDirection: driver.All, To: &before})
+	assert.NoError(t, err)
+	assert.Empty(t, records)
 }
 
 func TTransaction(t *testing.T, db driver.TokenTransactionDB) {
@@ -386,6 +414,14 @@ func TTokenRequest(t *testing.T, db driver.TokenTransactionDB) {
 	assert.NoError(t, err)
 	assert.Equal(t, tr2, trq)
 
+	// batch, unknown ids are missing
+	trqs, err := db.GetTokenRequests([]string{"id1", "id2", "id3"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"id1": tr1, "id2": tr2}, trqs)
+	trqs, err = db.GetTokenRequests(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, trqs)
+
 	// iterate over all
 	it, err := db.QueryTokenRequests(driver.QueryTokenRequestsParams{})
 	assert.NoError(t, err)
@@ -471,6 +507,58 @@ func TTokenRequest(t *testing.T, db driver.TokenTransactionDB) {
 	it.Close()
 }
 
+func TTokenRequestApplicationMetadata(t *testing.T, db driver.TokenTransactionDB) {
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("id1", []byte("request 1"), map[string][]byte{"invoice": []byte("INV-1"), "desk": []byte("fx")}, []byte("tr")))
+	assert.NoError(t, w.AddTokenRequest("id2", []byte("request 2"), map[string][]byte{"invoice": []byte("INV-2")}, []byte("tr")))
+	assert.NoError(t, w.AddTokenRequest("id3", []byte("request 3"), nil, []byte("tr")))
+	assert.NoError(t, w.Commit())
+
+	query := func(params driver.QueryTokenRequestsParams) []*driver.TokenRequestRecord {
+		it, err := db.QueryTokenRequests(params)
+		assert.NoError(t, err)
+		defer it.Close()
+		var records []*driver.TokenRequestRecord
+		for {
+			record, err := it.Next()
+			assert.NoError(t, err)
+			if record == nil {
+				return records
+			}
+			records = append(records, record)
+		}
+	}
+	txIDs := func(records []*driver.TokenRequestRecord) []string {
+		res := make([]string, len(records))
+		for i, r := range records {
+			res[i] = r.TxID
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	records := query(driver.QueryTokenRequestsParams{TxIDs: []string{"id1"}})
+	assert.Len(t, records, 1)
+	assert.Equal(t, map[string][]byte{"invoice": []byte("INV-1"), "desk": []byte("fx")}, records[0].ApplicationMetadata)
+	assert.Empty(t, query(driver.QueryTokenRequestsParams{TxIDs: []string{"id3"}})[0].ApplicationMetadata)
+
+	// by key and value
+	assert.Equal(t, []string{"id2"}, txIDs(query(driver.QueryTokenRequestsParams{ApplicationMetadata: map[string][]byte{"invoice": []byte("INV-2")}})))
+	// by key only
+	assert.Equal(t, []string{"id1", "id2"}, txIDs(query(driver.QueryTokenRequestsParams{ApplicationMetadata: map[string][]byte{"invoice": nil}})))
+	// all the pairs must match
+	assert.Empty(t, query(driver.QueryTokenRequestsParams{ApplicationMetadata: map[string][]byte{"invoice": []byte("INV-2"), "desk": nil}}))
+	assert.Equal(t, []string{"id1"}, txIDs(query(driver.QueryTokenRequestsParams{ApplicationMetadata: map[string][]byte{"invoice": nil, "desk": []byte("fx")}})))
+
+	// by time of storage
+	assert.WithinDuration(t, time.Now(), records[0].Timestamp, 5*time.Second)
+	from := records[0].Timestamp.Add(-time.Second)
+	assert.Equal(t, []string{"id1", "id2", "id3"}, txIDs(query(driver.QueryTokenRequestsParams{From: &from})))
+	from = time.Now().Add(time.Minute)
+	assert.Empty(t, query(driver.QueryTokenRequestsParams{From: &from}))
+}
+
 func TAllowsSameTxID(t *testing.T, db driver.TokenTransactionDB) {
 	// bob sends 10 to alice
 	tr1 := &driver.TransactionRecord{
@@ -748,6 +836,27 @@ func TTransactionQueries(t *testing.T, db driver.TokenTransactionDB) {
 			},
 			expectedLen: 2,
 		},
+		{
+			name: "Id prefix",
+			params: driver.QueryTransactionsParams{
+				IDPrefix: "1",
+			},
+			expectedLen: 2,
+		},
+		{
+			name: "Sender or recipient enrollment id",
+			params: driver.QueryTransactionsParams{
+				EnrollmentIDs: []string{"dan"},
+			},
+			expectedLen: 3,
+		},
+		{
+			name: "Token type",
+			params: driver.QueryTransactionsParams{
+				TokenTypes: []string{"magic"},
+			},
+			expectedLen: 6,
+		},
 	}
 
 	w, err := db.BeginAtomicWrite()
@@ -862,6 +971,13 @@ func TValidationRecordQueries(t *testing.T, db driver.TokenTransactionDB) {
 	})
 	assert.Len(t, confirmed, 1)
 
+	byTxID := getValidationRecords(t, db, driver.QueryValidationRecordsParams{
+		TxIDs: []string{"1", "3"},
+	})
+	assert.Len(t, byTxID, 2)
+	assert.Equal(t, "1", byTxID[0].TxID)
+	assert.Equal(t, "3", byTxID[1].TxID)
+
 	filtered := getValidationRecords(t, db, driver.QueryValidationRecordsParams{
 		Filter: func(r *driver.ValidationRecord) bool {
 			return r.Status == driver.Unknown
@@ -909,6 +1025,80 @@ func TEndorserAcks(t *testing.T, db driver.TokenTransactionDB) {
 	}
 }
 
+func TIssuerAttributions(t *testing.T, db driver.TokenTransactionDB) {
+	now := time.Now().UTC()
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.Error(t, w.AddIssuerAttribution(&driver.IssuerAttributionRecord{
+		TxID:      "missing",
+		TokenType: "magic",
+		Amount:    big.NewInt(10),
+		Timestamp: now,
+	}))
+	w.Rollback()
+
+	w, err = db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("1", []byte{}, map[string][]byte{}, driver2.PPHash("tr")))
+	assert.NoError(t, w.AddTokenRequest("2", []byte{}, map[string][]byte{}, driver2.PPHash("tr")))
+	assert.NoError(t, w.AddIssuerAttribution(&driver.IssuerAttributionRecord{
+		TxID:      "1",
+		Issuer:    []byte("issuer1"),
+		IssuerID:  "issuer1",
+		TokenType: "magic",
+		Amount:    big.NewInt(10),
+		Timestamp: now,
+	}))
+	assert.NoError(t, w.AddIssuerAttribution(&driver.IssuerAttributionRecord{
+		TxID:        "1",
+		ActionIndex: 1,
+		Issuer:      []byte("issuer1"),
+		IssuerID:    "issuer1",
+		TokenType:   "dollar",
+		Amount:      big.NewInt(20),
+		Timestamp:   now,
+	}))
+	// anonymous issuance, not attributed
+	assert.NoError(t, w.AddIssuerAttribution(&driver.IssuerAttributionRecord{
+		TxID:      "2",
+		TokenType: "magic",
+		Amount:    big.NewInt(30),
+		Timestamp: now,
+	}))
+	assert.NoError(t, w.Commit())
+
+	records, err := db.QueryIssuerAttributions(driver.QueryIssuerAttributionsParams{})
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	records, err = db.QueryIssuerAttributions(driver.QueryIssuerAttributionsParams{IssuerIDs: []string{"issuer1"}})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "1", records[0].TxID)
+	assert.Equal(t, []byte("issuer1"), records[0].Issuer)
+	assert.Equal(t, driver.Pending, records[0].Status)
+
+	records, err = db.QueryIssuerAttributions(driver.QueryIssuerAttributionsParams{Unattributed: true})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "2", records[0].TxID)
+	assert.Equal(t, int64(30), records[0].Amount.Int64())
+
+	records, err = db.QueryIssuerAttributions(driver.QueryIssuerAttributionsParams{TokenTypes: []string{"magic"}, TxIDs: []string{"1"}})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, int64(10), records[0].Amount.Int64())
+
+	// deleted transactions are not returned by default
+	assert.NoError(t, db.SetStatus(context.TODO(), "2", driver.Deleted, ""))
+	records, err = db.QueryIssuerAttributions(driver.QueryIssuerAttributionsParams{})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	records, err = db.QueryIssuerAttributions(driver.QueryIssuerAttributionsParams{Statuses: []driver.TxStatus{driver.Deleted}})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
 func createTestTransaction(t *testing.T, db driver.TokenTransactionDB, txID string) {
 	w, err := db.BeginAtomicWrite()
 	if err != nil {
@@ -934,3 +1124,518 @@ func createTestTransaction(t *testing.T, db driver.TokenTransactionDB, txID stri
 		t.Fatalf("error committing transaction while trying to test something else: %s", err)
 	}
 }
+
+func TCompensationFailures(t *testing.T, db driver.TokenTransactionDB) {
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("tx1", []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+	assert.NoError(t, w.AddTokenRequest("tx2", []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+	assert.NoError(t, w.Commit())
+
+	failures, err := db.QueryCompensationFailures()
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	assert.NoError(t, db.AddCompensationFailure("tx1", "ledger unreachable"))
+	assert.NoError(t, db.AddCompensationFailure("tx2", "ledger unreachable"))
+	// a new failure replaces the previous one
+	assert.NoError(t, db.AddCompensationFailure("tx1", "wallet locked"))
+	// the transaction must exist
+	assert.Error(t, db.AddCompensationFailure("tx3", "ledger unreachable"))
+	failures, err = db.QueryCompensationFailures()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"tx1": "wallet locked", "tx2": "ledger unreachable"}, failures)
+
+	assert.NoError(t, db.DeleteCompensationFailure("tx1"))
+	assert.NoError(t, db.DeleteCompensationFailure("tx3"))
+	failures, err = db.QueryCompensationFailures()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"tx2": "ledger unreachable"}, failures)
+}
+
+func TStatusOverrides(t *testing.T, db driver.TokenTransactionDB) {
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("tx1", []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+	assert.NoError(t, w.AddTokenRequest("tx2", []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+	assert.NoError(t, w.Commit())
+
+	// the finality listener does not leave a trail
+	assert.NoError(t, db.SetStatus(context.TODO(), "tx1", driver.Confirmed, ""))
+	overrides, err := db.QueryStatusOverrides(driver.QueryStatusOverridesParams{})
+	assert.NoError(t, err)
+	assert.Empty(t, overrides)
+
+	// an override requires an operator and a reason, and a known transaction
+	assert.Error(t, db.OverrideStatus(context.TODO(), "tx1", driver.Deleted, "", driver.StatusOverride{Operator: "admin"}))
+	err = db.OverrideStatus(context.TODO(), "tx3", driver.Deleted, "", driver.StatusOverride{Operator: "admin", Reason: "repair"})
+	assert.True(t, errors.Is(err, driver.ErrTokenRequestDoesNotExist))
+
+	assert.NoError(t, db.OverrideStatus(context.TODO(), "tx1", driver.Deleted, "double spend", driver.StatusOverride{Operator: "admin", Reason: "ticket 42"}))
+	assert.NoError(t, db.OverrideStatus(context.TODO(), "tx2", driver.Confirmed, "", driver.StatusOverride{Operator: "repair-tool", Reason: "committed on the ledger"}))
+	s, mess, err := db.GetStatus("tx1")
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Deleted, s)
+	assert.Equal(t, "double spend", mess)
+
+	overrides, err = db.QueryStatusOverrides(driver.QueryStatusOverridesParams{})
+	assert.NoError(t, err)
+	assert.Len(t, overrides, 2)
+	assert.Equal(t, "tx1", overrides[0].TxID)
+	assert.Equal(t, driver.Confirmed, overrides[0].PreviousStatus)
+	assert.Equal(t, driver.Deleted, overrides[0].Status)
+	assert.Equal(t, "admin", overrides[0].Operator)
+	assert.Equal(t, "ticket 42", overrides[0].Reason)
+	assert.False(t, overrides[0].Timestamp.IsZero())
+	assert.Equal(t, driver.Pending, overrides[1].PreviousStatus)
+
+	overrides, err = db.QueryStatusOverrides(driver.QueryStatusOverridesParams{Operators: []string{"repair-tool"}})
+	assert.NoError(t, err)
+	assert.Len(t, overrides, 1)
+	assert.Equal(t, "tx2", overrides[0].TxID)
+	overrides, err = db.QueryStatusOverrides(driver.QueryStatusOverridesParams{TxIDs: []string{"tx1"}})
+	assert.NoError(t, err)
+	assert.Len(t, overrides, 1)
+	assert.Equal(t, "admin", overrides[0].Operator)
+}
+
+func TMovementCorrections(t *testing.T, db driver.TokenTransactionDB) {
+	adb, ok := db.(driver.AuditTransactionDB)
+	if !ok {
+		t.Skip("the database does not store movement corrections")
+	}
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("tx1", []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+	assert.NoError(t, w.AddMovement(&driver.MovementRecord{TxID: "tx1", EnrollmentID: "alice", TokenType: "USD", Amount: big.NewInt(-30)}))
+	assert.NoError(t, w.AddMovement(&driver.MovementRecord{TxID: "tx1", EnrollmentID: "bob", TokenType: "USD", Amount: big.NewInt(30)}))
+	assert.NoError(t, w.Commit())
+
+	movements, err := adb.QueryMovements(driver.QueryMovementsParams{MovementDirection: driver.All})
+	assert.NoError(t, err)
+	assert.Len(t, movements, 2)
+	ids := map[string]string{}
+	for _, m := range movements {
+		assert.NotEmpty(t, m.ID)
+		ids[m.EnrollmentID] = m.ID
+	}
+
+	// a correction requires an operator, a reason, and a known movement
+	_, err = adb.CorrectMovement(driver.MovementCorrection{MovementID: ids["bob"], Type: driver.Reversal, Operator: "admin"})
+	assert.Error(t, err)
+	_, err = adb.CorrectMovement(driver.MovementCorrection{MovementID: ids["bob"], Type: driver.Adjustment, Operator: "admin", Reason: "fee"})
+	assert.Error(t, err, "an adjustment requires an amount")
+	_, err = adb.CorrectMovement(driver.MovementCorrection{MovementID: "unknown", Type: driver.Reversal, Operator: "admin", Reason: "duplicate"})
+	assert.True(t, errors.Is(err, driver.ErrMovementDoesNotExist))
+
+	adjustment, err := adb.CorrectMovement(driver.MovementCorrection{MovementID: ids["bob"], Type: driver.Adjustment, Amount: big.NewInt(-5), Operator: "admin", Reason: "fee"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, adjustment.ID)
+	assert.Equal(t, "tx1", adjustment.TxID)
+	assert.Equal(t, "bob", adjustment.EnrollmentID)
+	assert.Equal(t, int64(-5), adjustment.Amount.Int64())
+
+	// the raw view is unchanged, the corrected view applies the corrections
+	sum := func(corrected bool) map[string]int64 {
+		movements, err := adb.QueryMovements(driver.QueryMovementsParams{MovementDirection: driver.All, Corrected: corrected})
+		assert.NoError(t, err)
+		res := map[string]int64{}
+		for _, m := range movements {
+			res[m.EnrollmentID] += m.Amount.Int64()
+		}
+		return res
+	}
+	assert.Equal(t, map[string]int64{"alice": -30, "bob": 30}, sum(false))
+	assert.Equal(t, map[string]int64{"alice": -30, "bob": 25}, sum(true))
+
+	// a reversal cancels what is left of the movement, once
+	reversal, err := adb.CorrectMovement(driver.MovementCorrection{MovementID: ids["bob"], Type: driver.Reversal, Operator: "repair-tool", Reason: "duplicate"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-25), reversal.Amount.Int64())
+	_, err = adb.CorrectMovement(driver.MovementCorrection{MovementID: ids["bob"], Type: driver.Reversal, Operator: "repair-tool", Reason: "duplicate"})
+	assert.Error(t, err)
+	assert.Equal(t, map[string]int64{"alice": -30, "bob": 30}, sum(false))
+	assert.Equal(t, map[string]int64{"alice": -30, "bob": 0}, sum(true))
+
+	corrections, err := adb.QueryMovementCorrections(driver.QueryMovementCorrectionsParams{})
+	assert.NoError(t, err)
+	assert.Len(t, corrections, 2)
+	assert.Equal(t, adjustment.ID, corrections[0].ID)
+	assert.Equal(t, driver.Adjustment, corrections[0].Type)
+	assert.Equal(t, "fee", corrections[0].Reason)
+	assert.Equal(t, driver.Reversal, corrections[1].Type)
+	assert.Equal(t, "bob", corrections[1].EnrollmentID)
+	assert.Equal(t, "USD", corrections[1].TokenType)
+	assert.False(t, corrections[1].Timestamp.IsZero())
+
+	corrections, err = adb.QueryMovementCorrections(driver.QueryMovementCorrectionsParams{Operators: []string{"repair-tool"}, TxIDs: []string{"tx1"}})
+	assert.NoError(t, err)
+	assert.Len(t, corrections, 1)
+	assert.Equal(t, reversal.ID, corrections[0].ID)
+	corrections, err = adb.QueryMovementCorrections(driver.QueryMovementCorrectionsParams{MovementIDs: []string{ids["alice"]}})
+	assert.NoError(t, err)
+	assert.Empty(t, corrections)
+}
+
+func TFundsWitnesses(t *testing.T, db driver.TokenTransactionDB) {
+	witnesses, err := db.QueryFundsWitnesses(driver.QueryFundsWitnessesParams{})
+	assert.NoError(t, err)
+	assert.Empty(t, witnesses)
+
+	// a witness does not require a token request, the selection failed before the transaction was assembled
+	w1 := &driver.FundsWitnessRecord{TxID: "tx1", WalletID: "alice", TokenType: "USD", Snapshot: []byte("snapshot1"), Signer: []byte("node"), Signature: []byte("sigma1")}
+	assert.NoError(t, db.AddFundsWitness(w1))
+	assert.NotEmpty(t, w1.ID)
+	assert.False(t, w1.Timestamp.IsZero())
+	w2 := &driver.FundsWitnessRecord{WalletID: "bob", TokenType: "EUR", Snapshot: []byte("snapshot2"), Signer: []byte("node"), Signature: []byte("sigma2")}
+	assert.NoError(t, db.AddFundsWitness(w2))
+
+	witnesses, err = db.QueryFundsWitnesses(driver.QueryFundsWitnessesParams{})
+	assert.NoError(t, err)
+	assert.Len(t, witnesses, 2)
+	assert.Equal(t, w1.ID, witnesses[0].ID)
+	assert.Equal(t, "tx1", witnesses[0].TxID)
+	assert.Equal(t, []byte("snapshot1"), witnesses[0].Snapshot)
+	assert.Equal(t, []byte("node"), witnesses[0].Signer)
+	assert.Equal(t, []byte("sigma1"), witnesses[0].Signature)
+
+	witnesses, err = db.QueryFundsWitnesses(driver.QueryFundsWitnessesParams{WalletIDs: []string{"bob"}})
+	assert.NoError(t, err)
+	assert.Len(t, witnesses, 1)
+	assert.Equal(t, "EUR", witnesses[0].TokenType)
+	witnesses, err = db.QueryFundsWitnesses(driver.QueryFundsWitnessesParams{TokenTypes: []string{"USD"}, TxIDs: []string{"tx1"}})
+	assert.NoError(t, err)
+	assert.Len(t, witnesses, 1)
+	assert.Equal(t, "alice", witnesses[0].WalletID)
+}
+
+func TIdempotencyKeys(t *testing.T, db driver.TokenTransactionDB) {
+	txID, err := db.GetTxIDByIdempotencyKey("payment-1")
+	assert.NoError(t, err)
+	assert.Empty(t, txID)
+
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("tx1", []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+	assert.NoError(t, w.AddIdempotencyKey("tx1", "payment-1"))
+	assert.NoError(t, w.Commit())
+
+	// the key is bound to a pending transaction
+	txID, err = db.GetTxIDByIdempotencyKey("payment-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "tx1", txID)
+	assert.NoError(t, db.SetStatus(context.TODO(), "tx1", driver.Confirmed, ""))
+	txID, err = db.GetTxIDByIdempotencyKey("payment-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "tx1", txID)
+
+	// the key cannot be bound to another transaction, even if the check at creation was passed
+	w, err = db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("tx5", []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+	err = w.AddIdempotencyKey("tx5", "payment-1")
+	assert.True(t, errors.Is(err, driver.ErrIdempotencyKeyInUse))
+	var keyErr *driver.IdempotencyKeyError
+	assert.True(t, errors.As(err, &keyErr))
+	assert.Equal(t, "tx1", keyErr.TxID)
+	w.Rollback()
+	txID, err = db.GetTxIDByIdempotencyKey("payment-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "tx1", txID)
+
+	// a key requires an existing request
+	w, err = db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	err = w.AddIdempotencyKey("tx3", "payment-3")
+	assert.True(t, errors.Is(err, driver.ErrTokenRequestDoesNotExist))
+	w.Rollback()
+
+	// once the transaction is deleted, the key can be used again
+	w, err = db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("tx2", []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+	assert.NoError(t, w.AddIdempotencyKey("tx2", "payment-2"))
+	assert.NoError(t, w.Commit())
+	assert.NoError(t, db.SetStatus(context.TODO(), "tx2", driver.Deleted, "failed"))
+	txID, err = db.GetTxIDByIdempotencyKey("payment-2")
+	assert.NoError(t, err)
+	assert.Empty(t, txID)
+
+	w, err = db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("tx4", []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+	assert.NoError(t, w.AddIdempotencyKey("tx4", "payment-2"))
+	assert.NoError(t, w.Commit())
+	txID, err = db.GetTxIDByIdempotencyKey("payment-2")
+	assert.NoError(t, err)
+	assert.Equal(t, "tx4", txID)
+}
+
+func TAuditResponses(t *testing.T, db driver.TokenTransactionDB) {
+	adb, ok := db.(driver.AuditTransactionDB)
+	if !ok {
+		t.Skip("the database does not store audit responses")
+	}
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	// the response requires the token request
+	assert.Error(t, w.AddAuditResponse(&driver.AuditResponseRecord{TxID: "tx0", Requester: []byte("alice"), MessageHash: []byte("h0"), Response: []byte("s0")}))
+	w.Rollback()
+
+	w, err = db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	for _, txID := range []string{"tx1", "tx2"} {
+		assert.NoError(t, w.AddTokenRequest(txID, []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+		assert.NoError(t, w.AddAuditResponse(&driver.AuditResponseRecord{TxID: txID, Requester: []byte("alice"), MessageHash: []byte("h" + txID), Response: []byte("s" + txID)}))
+	}
+	assert.NoError(t, w.Commit())
+
+	r, err := adb.GetAuditResponse("tx3")
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+	r, err = adb.GetAuditResponse("tx1")
+	assert.NoError(t, err)
+	assert.Equal(t, "tx1", r.TxID)
+	assert.Equal(t, []byte("alice"), []byte(r.Requester))
+	assert.Equal(t, []byte("htx1"), r.MessageHash)
+	assert.Equal(t, []byte("stx1"), r.Response)
+	assert.False(t, r.Sent)
+	assert.False(t, r.Timestamp.IsZero())
+
+	pending, err := adb.QueryPendingAuditResponses()
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	assert.NoError(t, adb.MarkAuditResponseSent("tx1"))
+	assert.Error(t, adb.MarkAuditResponseSent("tx3"))
+	pending, err = adb.QueryPendingAuditResponses()
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, "tx2", pending[0].TxID)
+	r, err = adb.GetAuditResponse("tx1")
+	assert.NoError(t, err)
+	assert.True(t, r.Sent)
+}
+
+func TPseudonyms(t *testing.T, db driver.TokenTransactionDB) {
+	adb, ok := db.(driver.AuditTransactionDB)
+	if !ok {
+		t.Skip("the database does not store pseudonyms")
+	}
+	sealed, err := adb.GetPseudonym("p1")
+	assert.NoError(t, err)
+	assert.Nil(t, sealed)
+
+	assert.NoError(t, adb.AddPseudonym("p1", []byte("alice")))
+	assert.NoError(t, adb.AddPseudonym("p2", []byte("bob")))
+	// the pseudonym already stored is kept
+	assert.NoError(t, adb.AddPseudonym("p1", []byte("another")))
+	sealed, err = adb.GetPseudonym("p1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("alice"), sealed)
+
+	assert.NoError(t, adb.DeletePseudonym("p1"))
+	sealed, err = adb.GetPseudonym("p1")
+	assert.NoError(t, err)
+	assert.Nil(t, sealed)
+	sealed, err = adb.GetPseudonym("p2")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bob"), sealed)
+}
+
+func TRecipientAnonymityLevels(t *testing.T, db driver.TokenTransactionDB) {
+	alice, bob, charlie := token.Identity("alice"), token.Identity("bob"), token.Identity("charlie")
+	levels, err := db.GetRecipientAnonymityLevels(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, levels)
+
+	assert.NoError(t, db.AddRecipientAnonymityLevel(alice, "w1", "long-term"))
+	assert.NoError(t, db.AddRecipientAnonymityLevel(bob, "w2", "fresh"))
+	// the level already recorded is kept
+	assert.NoError(t, db.AddRecipientAnonymityLevel(alice, "w1", "fresh"))
+
+	levels, err = db.GetRecipientAnonymityLevels([]token.Identity{alice, bob, charlie})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{alice.UniqueID(): "long-term", bob.UniqueID(): "fresh"}, levels)
+}
+
+func TReferences(t *testing.T, db driver.TokenTransactionDB) {
+	txIDs, err := db.GetTxIDsByReference([]byte("confirm-1"))
+	assert.NoError(t, err)
+	assert.Empty(t, txIDs)
+
+	// several transactions can settle the same document
+	for _, txID := range []string{"tx1", "tx2"} {
+		w, err := db.BeginAtomicWrite()
+		assert.NoError(t, err)
+		assert.NoError(t, w.AddTokenRequest(txID, []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+		assert.NoError(t, w.AddReference(txID, []byte("confirm-1")))
+		assert.NoError(t, w.Commit())
+	}
+	txIDs, err = db.GetTxIDsByReference([]byte("confirm-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx1", "tx2"}, txIDs)
+	txIDs, err = db.GetTxIDsByReference([]byte("confirm-2"))
+	assert.NoError(t, err)
+	assert.Empty(t, txIDs)
+
+	// a reference requires an existing request
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	err = w.AddReference("tx3", []byte("confirm-3"))
+	assert.True(t, errors.Is(err, driver.ErrTokenRequestDoesNotExist))
+	w.Rollback()
+
+	w, err = db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.Error(t, w.AddReference("tx1", nil))
+	w.Rollback()
+}
+
+func TWallets(t *testing.T, db driver.TokenTransactionDB) {
+	for i, walletID := range []string{"alice", "bob", ""} {
+		txID := fmt.Sprintf("tx%d", i+1)
+		w, err := db.BeginAtomicWrite()
+		assert.NoError(t, err)
+		assert.NoError(t, w.AddTokenRequest(txID, []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+		assert.NoError(t, w.AddTransaction(&driver.TransactionRecord{
+			TxID:         txID,
+			ActionType:   driver.Transfer,
+			SenderEID:    "alice",
+			RecipientEID: "bob",
+			TokenType:    "magic",
+			Amount:       big.NewInt(10),
+			Timestamp:    time.Now(),
+		}))
+		if len(walletID) != 0 {
+			assert.NoError(t, w.AddWallet(txID, walletID))
+		}
+		assert.NoError(t, w.Commit())
+	}
+
+	records := getTransactions(t, db, driver.QueryTransactionsParams{})
+	assert.Len(t, records, 3)
+	assert.Equal(t, "alice", records[0].WalletID)
+	assert.Equal(t, "bob", records[1].WalletID)
+	assert.Empty(t, records[2].WalletID)
+
+	records = getTransactions(t, db, driver.QueryTransactionsParams{WalletID: "alice"})
+	assert.Len(t, records, 1)
+	assert.Equal(t, "tx1", records[0].TxID)
+	records = getTransactions(t, db, driver.QueryTransactionsParams{WalletID: "charlie"})
+	assert.Empty(t, records)
+
+	// a wallet requires an existing request
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	err = w.AddWallet("tx4", "alice")
+	assert.True(t, errors.Is(err, driver.ErrTokenRequestDoesNotExist))
+	w.Rollback()
+
+	w, err = db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.Error(t, w.AddWallet("tx3", ""))
+	w.Rollback()
+}
+
+func TExportRecords(t *testing.T, db driver.TokenTransactionDB) {
+	adb, ok := db.(driver.AuditTransactionDB)
+	if !ok {
+		t.Skip("the database does not store export records")
+	}
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	// the record requires the token request
+	assert.Error(t, w.AddExportRecord("tx0", []byte("p0")))
+	w.Rollback()
+
+	for i := 1; i <= 3; i++ {
+		txID := fmt.Sprintf("tx%d", i)
+		w, err := db.BeginAtomicWrite()
+		assert.NoError(t, err)
+		assert.NoError(t, w.AddTokenRequest(txID, []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+		assert.NoError(t, w.AddExportRecord(txID, []byte(fmt.Sprintf("p%d", i))))
+		assert.NoError(t, w.Commit())
+	}
+
+	// pending transactions are not exported yet
+	records, err := adb.QueryPendingExportRecords(0)
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	assert.NoError(t, db.SetStatus(context.TODO(), "tx1", driver.Confirmed, ""))
+	assert.NoError(t, db.SetStatus(context.TODO(), "tx2", driver.Deleted, ""))
+	assert.NoError(t, db.SetStatus(context.TODO(), "tx3", driver.Confirmed, ""))
+	records, err = adb.QueryPendingExportRecords(0)
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, "tx1", records[0].TxID)
+	assert.Equal(t, []byte("p1"), records[0].Payload)
+	assert.Equal(t, driver.Confirmed, records[0].Status)
+	assert.Equal(t, driver.Deleted, records[1].Status)
+
+	records, err = adb.QueryPendingExportRecords(2)
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	assert.NoError(t, adb.MarkExported([]string{"tx1", "tx2"}))
+	records, err = adb.QueryPendingExportRecords(0)
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "tx3", records[0].TxID)
+
+	assert.NoError(t, adb.MarkExported(nil))
+}
+
+func TEraseEnrollmentID(t *testing.T, db driver.TokenTransactionDB) {
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("tx1", []byte("request"), map[string][]byte{}, driver2.PPHash("tr")))
+	for _, r := range []struct{ sender, recipient string }{{"alice", "bob"}, {"bob", "alice"}, {"alice", "alice"}, {"bob", "charlie"}} {
+		assert.NoError(t, w.AddTransaction(&driver.TransactionRecord{
+			TxID:         "tx1",
+			ActionType:   driver.Transfer,
+			SenderEID:    r.sender,
+			RecipientEID: r.recipient,
+			TokenType:    "magic",
+			Amount:       big.NewInt(10),
+			Timestamp:    time.Now(),
+		}))
+	}
+	assert.NoError(t, w.AddMovement(&driver.MovementRecord{TxID: "tx1", EnrollmentID: "alice", TokenType: "magic", Amount: big.NewInt(-10)}))
+	assert.NoError(t, w.AddMovement(&driver.MovementRecord{TxID: "tx1", EnrollmentID: "bob", TokenType: "magic", Amount: big.NewInt(10)}))
+	assert.NoError(t, w.Commit())
+
+	_, err = db.EraseEnrollmentID("alice", "")
+	assert.Error(t, err)
+	erased, err := db.EraseEnrollmentID("alice", "erased-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &driver.ErasedRecords{Transactions: 3, Movements: 1}, erased)
+
+	// the records keep their amounts, the enrollment ID is replaced
+	records := getTransactions(t, db, driver.QueryTransactionsParams{})
+	assert.Len(t, records, 4)
+	parties := map[string]int64{}
+	for _, r := range records {
+		assert.Equal(t, int64(10), r.Amount.Int64())
+		parties[r.SenderEID+">"+r.RecipientEID]++
+	}
+	assert.Equal(t, map[string]int64{"erased-1>bob": 1, "bob>erased-1": 1, "erased-1>erased-1": 1, "bob>charlie": 1}, parties)
+	movements, err := db.QueryMovements(driver.QueryMovementsParams{MovementDirection: driver.All})
+	assert.NoError(t, err)
+	assert.Len(t, movements, 2)
+	for _, m := range movements {
+		assert.NotEqual(t, "alice", m.EnrollmentID)
+		if m.EnrollmentID == "erased-1" {
+			assert.Equal(t, int64(-10), m.Amount.Int64())
+		}
+	}
+	request, err := db.GetTokenRequest("tx1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("request"), request)
+
+	// erasing again finds nothing
+	erased, err = db.EraseEnrollmentID("alice", "erased-2")
+	assert.NoError(t, err)
+	assert.Equal(t, &driver.ErasedRecords{}, erased)
+}
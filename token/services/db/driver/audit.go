@@ -8,10 +8,14 @@ package driver
 
 import (
 	"context"
+	"errors"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 )
 
+// ErrMovementDoesNotExist is returned when the movement to correct is not found
+var ErrMovementDoesNotExist = errors.New("movement does not exist")
+
 // AuditTransactionDB defines the interface for a database to store the audit records of token transactions.
 type AuditTransactionDB interface {
 	// Close closes the database
@@ -24,6 +28,22 @@ type AuditTransactionDB interface {
 	// (and with that, the associated ValidationRecord, Movement and Transaction)
 	SetStatus(ctx context.Context, txID string, status TxStatus, message string) error
 
+	// OverrideStatus sets the status of a TokenRequest as SetStatus does,
+	// and records, atomically, the override with the previous status in the status overrides.
+	// It must be used instead of SetStatus outside the finality listener.
+	OverrideStatus(ctx context.Context, txID string, status TxStatus, message string, override StatusOverride) error
+
+	// QueryStatusOverrides returns the status overrides matching the passed params, the oldest first
+	QueryStatusOverrides(params QueryStatusOverridesParams) ([]*StatusOverrideRecord, error)
+
+	// GetTxIDsByReference returns the ids of the transactions bound to the passed hash of an external document,
+	// the oldest first
+	GetTxIDsByReference(reference []byte) ([]string, error)
+
+	// EraseEnrollmentID replaces the passed enrollment ID with the passed pseudonym in the transaction and movement records,
+	// in a single db transaction. The amounts and the token requests are kept.
+	EraseEnrollmentID(eID string, pseudonym string) (*ErasedRecords, error)
+
 	// GetStatus returns the status of a given transaction.
 	// It returns an error if the transaction is not found
 	GetStatus(txID string) (TxStatus, string, error)
@@ -34,15 +54,59 @@ type AuditTransactionDB interface {
 	// QueryMovements returns a list of movement records
 	QueryMovements(params QueryMovementsParams) ([]*MovementRecord, error)
 
+	// CorrectMovement appends the passed correction to the movement it refers to, and returns its record.
+	// The movement is never modified, the corrected amount is computed by QueryMovements on request.
+	// It returns an error wrapping ErrMovementDoesNotExist if the movement is not found.
+	CorrectMovement(correction MovementCorrection) (*MovementCorrectionRecord, error)
+
+	// QueryMovementCorrections returns the movement corrections matching the passed params, the oldest first
+	QueryMovementCorrections(params QueryMovementCorrectionsParams) ([]*MovementCorrectionRecord, error)
+
 	// QueryValidations returns an iterator over the validation records matching the passed params
 	QueryValidations(params QueryValidationRecordsParams) (ValidationRecordsIterator, error)
 
 	// QueryTokenRequests returns an iterator over the token requests matching the passed params
 	QueryTokenRequests(params QueryTokenRequestsParams) (TokenRequestIterator, error)
 
+	// QueryIssuerAttributions returns the issuer attribution records matching the passed params
+	QueryIssuerAttributions(params QueryIssuerAttributionsParams) ([]*IssuerAttributionRecord, error)
+
 	// GetTokenRequest returns the token request bound to the passed transaction id, if available.
 	// It returns nil without error if the key is not found.
 	GetTokenRequest(txID string) ([]byte, error)
+
+	// GetTokenRequests returns the token requests bound to the passed transaction ids, indexed by transaction id.
+	// The transaction ids that are not found are missing in the returned map.
+	GetTokenRequests(txIDs []string) (map[string][]byte, error)
+
+	// GetAuditResponse returns the audit response for the passed transaction id, sent or not.
+	// It returns nil without error if there is none.
+	GetAuditResponse(txID string) (*AuditResponseRecord, error)
+
+	// QueryPendingAuditResponses returns the audit responses not sent yet, the oldest first
+	QueryPendingAuditResponses() ([]*AuditResponseRecord, error)
+
+	// MarkAuditResponseSent records that the audit response for the passed transaction id has been sent to the requester
+	MarkAuditResponseSent(txID string) error
+
+	// QueryPendingExportRecords returns the entries of the export outbox not exported yet
+	// whose transaction is either confirmed or deleted, the oldest first.
+	// If limit is positive, at most limit entries are returned.
+	QueryPendingExportRecords(limit int) ([]*ExportRecord, error)
+
+	// MarkExported records that the entries of the export outbox for the passed transaction ids have been exported
+	MarkExported(txIDs []string) error
+
+	// AddPseudonym stores the sealed enrollment ID the passed pseudonym has been computed from.
+	// A pseudonym already stored is kept.
+	AddPseudonym(pseudonym string, sealed []byte) error
+
+	// GetPseudonym returns the sealed enrollment ID stored for the passed pseudonym.
+	// It returns nil without error if the pseudonym is not found.
+	GetPseudonym(pseudonym string) ([]byte, error)
+
+	// DeletePseudonym deletes the sealed enrollment ID stored for the passed pseudonym
+	DeletePseudonym(pseudonym string) error
 }
 
 // AuditDBDriver is the interface for an audit database driver
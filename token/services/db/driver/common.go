@@ -80,13 +80,16 @@ var (
 // The movement record contains the total amount of the token type that was transferred to/from the enrollment ID
 // in a given token transaction.
 type MovementRecord struct {
+	// ID identifies the movement, it is set by QueryMovements
+	ID string
 	// TxID is the transaction ID
 	TxID string
 	// EnrollmentID is the enrollment ID of the account that is receiving or sending
 	EnrollmentID string
 	// TokenType is the type of token
 	TokenType string
-	// Amount is positive if tokens are received. Negative otherwise
+	// Amount is positive if tokens are received. Negative otherwise.
+	// It includes the corrections of the movement if they are requested with QueryMovementsParams.Corrected.
 	Amount *big.Int
 	// Timestamp is the time the transaction was submitted to the db
 	Timestamp time.Time
@@ -94,6 +97,55 @@ type MovementRecord struct {
 	Status TxStatus
 }
 
+// CorrectionType is the type of correction of a movement
+type CorrectionType int
+
+const (
+	// Reversal cancels the amount of a movement, with the corrections applied so far
+	Reversal CorrectionType = iota
+	// Adjustment adds an amount, positive or negative, to the amount of a movement
+	Adjustment
+)
+
+// MovementCorrection describes a correction of a movement.
+// A movement is never modified, the correction is appended next to it.
+type MovementCorrection struct {
+	// MovementID identifies the corrected movement, as in MovementRecord.ID
+	MovementID string
+	// Type is the type of correction
+	Type CorrectionType
+	// Amount is added to the amount of the movement by an Adjustment. It is ignored by a Reversal.
+	Amount *big.Int
+	// Operator identifies who, or what tool, performs the correction
+	Operator string
+	// Reason explains why the movement is corrected
+	Reason string
+}
+
+// MovementCorrectionRecord is the record of a correction of a movement
+type MovementCorrectionRecord struct {
+	// ID identifies the correction
+	ID string
+	// MovementID identifies the corrected movement
+	MovementID string
+	// TxID is the transaction ID of the corrected movement
+	TxID string
+	// EnrollmentID is the enrollment ID of the corrected movement
+	EnrollmentID string
+	// TokenType is the token type of the corrected movement
+	TokenType string
+	// Type is the type of correction
+	Type CorrectionType
+	// Amount is the amount added to the movement, the opposite of the amount cancelled by a Reversal
+	Amount *big.Int
+	// Operator identifies who, or what tool, performed the correction
+	Operator string
+	// Reason explains why the movement was corrected
+	Reason string
+	// Timestamp is the time the correction was stored
+	Timestamp time.Time
+}
+
 // TransactionRecord is a more finer-grained version of a movement record.
 // Given a Token Transaction, for each token action in the Token Request,
 // a transaction record is created for each unique enrollment ID found in the outputs.
@@ -119,6 +171,8 @@ type TransactionRecord struct {
 	// ApplicationMetadata is the metadata sent by the application in the
 	// transient field. It is not validated or recorded on the ledger.
 	ApplicationMetadata map[string][]byte
+	// WalletID is the identifier of the local wallet that signed the transaction, if any
+	WalletID string
 }
 
 func (t *TransactionRecord) String() string {
@@ -157,13 +211,125 @@ type ValidationRecord struct {
 	Status TxStatus
 }
 
+// IssuerAttributionRecord attributes the tokens created by an issue action to an issuer.
+// When the issuer is anonymous and the auditor's policy does not permit to resolve it,
+// IssuerID is empty but the record is stored anyway so that supply reports stay complete.
+type IssuerAttributionRecord struct {
+	// TxID is the transaction ID
+	TxID string
+	// ActionIndex is the index of the issue action in the token request
+	ActionIndex int
+	// Issuer is the identity of the issuer as found in the token request
+	Issuer []byte
+	// IssuerID is the registered identifier the issuance has been attributed to.
+	// It is empty if the issuance could not be attributed.
+	IssuerID string
+	// TokenType is the type of token
+	TokenType string
+	// Amount is the total amount issued by the action for the token type
+	Amount *big.Int
+	// Timestamp is the time the transaction was submitted to the db
+	Timestamp time.Time
+	// Status is the status of the transaction
+	Status TxStatus
+}
+
+// StatusOverride describes a change of the status of a transaction made outside the finality listener,
+// like a manual repair or an administration tool
+type StatusOverride struct {
+	// Operator identifies who, or what tool, performs the override
+	Operator string
+	// Reason explains why the status is overridden
+	Reason string
+}
+
+// StatusOverrideRecord is the audit record of a status override
+type StatusOverrideRecord struct {
+	// TxID is the transaction ID
+	TxID string
+	// PreviousStatus is the status of the transaction before the override
+	PreviousStatus TxStatus
+	// Status is the status set by the override
+	Status TxStatus
+	// Operator identifies who, or what tool, performed the override
+	Operator string
+	// Reason explains why the status was overridden
+	Reason string
+	// Timestamp is the time the override was performed
+	Timestamp time.Time
+}
+
+// AuditResponseRecord is the response of the auditor to an audit request, the auditor's signature on the token request.
+// It is kept in the outbox of the auditor until it has been sent to the requester.
+type AuditResponseRecord struct {
+	// TxID is the transaction ID of the audited token request
+	TxID string
+	// Requester is the identity of the node that requested the audit
+	Requester driver2.Identity
+	// MessageHash is the hash of the signed message, it binds the response to the content of the token request
+	MessageHash []byte
+	// Response is the auditor's signature
+	Response []byte
+	// Sent is true once the response has been sent to the requester
+	Sent bool
+	// Timestamp is the time the response was stored
+	Timestamp time.Time
+}
+
+// ExportRecord is an entry of the export outbox of the auditor.
+// It holds the serialized records of a transaction until they are published to an external system.
+type ExportRecord struct {
+	// TxID is the transaction ID
+	TxID string
+	// Payload is the serialized records of the transaction
+	Payload []byte
+	// Status is the status of the transaction
+	Status TxStatus
+	// Timestamp is the time the entry was stored
+	Timestamp time.Time
+}
+
+// FundsWitnessRecord is a signed snapshot of the funds of a wallet,
+// taken when a token selection concluded that the wallet had insufficient funds
+type FundsWitnessRecord struct {
+	// ID identifies the witness, it is assigned when the witness is stored
+	ID string
+	// TxID is the id of the transaction whose selection failed, it might be empty
+	TxID string
+	// WalletID is the id of the wallet whose funds were insufficient
+	WalletID string
+	// TokenType is the type of the requested tokens
+	TokenType string
+	// Snapshot is the marshalled snapshot of the funds
+	Snapshot []byte
+	// Signer is the identity that signed the snapshot
+	Signer []byte
+	// Signature is the signature of Signer on Snapshot
+	Signature []byte
+	// Timestamp is the time the witness was stored
+	Timestamp time.Time
+}
+
+// ErasedRecords counts the records in which the erasure of an enrollment ID replaced it with a pseudonym
+type ErasedRecords struct {
+	// Transactions is the number of transaction records whose sender or recipient was replaced
+	Transactions int64
+	// Movements is the number of movement records whose enrollment ID was replaced
+	Movements int64
+}
+
 type TokenRequestRecord struct {
 	// TxID is the transaction ID
 	TxID string
 	// TokenRequest is the token request marshalled
 	TokenRequest []byte
+	// ApplicationMetadata is the application metadata attached to the token request
+	ApplicationMetadata map[string][]byte
 	// Status is the status of the transaction
 	Status TxStatus
+	// Timestamp is the time the token request was stored.
+	// It is zero for the token requests stored before it was recorded.
+	Timestamp time.Time
 }
 
 // TransactionIterator is an iterator for transactions
@@ -194,6 +360,15 @@ type QueryMovementsParams struct {
 	// NumRecords is the number of records to return
 	// If 0, all records are returned
 	NumRecords int
+	// Corrected, if true, returns the amounts of the movements with their corrections applied.
+	// The other parameters, the MovementDirection included, select the movements by their original records.
+	Corrected bool
+	// From is the start time of the query
+	// If nil, the query starts from the first movement
+	From *time.Time
+	// To is the end time of the query
+	// If nil, the query ends at the last movement
+	To *time.Time
 }
 
 // QueryTransactionsParams defines the parameters for querying transactions.
@@ -224,6 +399,19 @@ type QueryTransactionsParams struct {
 	// Statuses is the list of transaction status to accept
 	// If empty, any status is accepted
 	Statuses []TxStatus
+	// IDPrefix selects the transactions whose id starts with the passed prefix.
+	// It is matched with LIKE, therefore, '%' and '_' in the prefix act as wildcards.
+	// If empty, any id is accepted
+	IDPrefix string
+	// EnrollmentIDs selects the transactions whose sender or recipient has one of the passed enrollment ids
+	// If empty, any enrollment id is accepted
+	EnrollmentIDs []string
+	// TokenTypes is the list of token types to accept
+	// If empty, any token type is accepted
+	TokenTypes []string
+	// WalletID selects the transactions signed by the local wallet with the passed identifier
+	// If empty, any transaction is accepted
+	WalletID string
 }
 
 // QueryValidationRecordsParams defines the parameters for querying validation records.
@@ -237,6 +425,9 @@ type QueryValidationRecordsParams struct {
 	// Statuses is the list of transaction status to accept
 	// If empty, any status is accepted
 	Statuses []TxStatus
+	// TxIDs is the list of transaction ids to accept
+	// If empty, any transaction is accepted
+	TxIDs []string
 	// Filter defines a custom filter function.
 	// If specified, this filter will be applied.
 	// the filter returns true if the record must be selected, false otherwise.
@@ -248,4 +439,86 @@ type QueryTokenRequestsParams struct {
 	// Statuses is the list of transaction status to accept
 	// If empty, any status is accepted
 	Statuses []TxStatus
+	// TxIDs is the list of transaction ids to accept
+	// If empty, any transaction is accepted
+	TxIDs []string
+	// From selects the token requests stored at or after the passed time.
+	// If nil, any token request is accepted, the token requests stored before their time was recorded are accepted only in this case
+	From *time.Time
+	// ApplicationMetadata selects the token requests whose application metadata holds all the passed keys, with the passed values.
+	// A nil value accepts any value of its key.
+	// If empty, any token request is accepted
+	ApplicationMetadata map[string][]byte
+}
+
+// QueryStatusOverridesParams defines the parameters for querying status overrides
+type QueryStatusOverridesParams struct {
+	// TxIDs is the list of transaction ids to accept
+	// If empty, any transaction is accepted
+	TxIDs []string
+	// Operators is the list of operators to accept
+	// If empty, any operator is accepted
+	Operators []string
+	// From is the start time of the query
+	// If nil, the query starts from the first override
+	From *time.Time
+	// To is the end time of the query
+	// If nil, the query ends at the last override
+	To *time.Time
+}
+
+// QueryFundsWitnessesParams defines the parameters for querying funds witnesses
+type QueryFundsWitnessesParams struct {
+	// TxIDs is the list of transaction ids to accept
+	// If empty, any transaction is accepted
+	TxIDs []string
+	// WalletIDs is the list of wallet ids to accept
+	// If empty, any wallet is accepted
+	WalletIDs []string
+	// TokenTypes is the list of token types to accept
+	// If empty, any token type is accepted
+	TokenTypes []string
+	// From is the start time of the query
+	// If nil, the query starts from the first witness
+	From *time.Time
+	// To is the end time of the query
+	// If nil, the query ends at the last witness
+	To *time.Time
+}
+
+// QueryMovementCorrectionsParams defines the parameters for querying movement corrections
+type QueryMovementCorrectionsParams struct {
+	// TxIDs is the list of transaction ids of the corrected movements to accept
+	// If empty, any transaction is accepted
+	TxIDs []string
+	// MovementIDs is the list of ids of the corrected movements to accept
+	// If empty, any movement is accepted
+	MovementIDs []string
+	// Operators is the list of operators to accept
+	// If empty, any operator is accepted
+	Operators []string
+	// From is the start time of the query
+	// If nil, the query starts from the first correction
+	From *time.Time
+	// To is the end time of the query
+	// If nil, the query ends at the last correction
+	To *time.Time
+}
+
+// QueryIssuerAttributionsParams defines the parameters for querying issuer attributions
+type QueryIssuerAttributionsParams struct {
+	// TxIDs is the list of transaction ids to accept
+	// If empty, any transaction is accepted
+	TxIDs []string
+	// IssuerIDs is the list of issuer ids to accept
+	// If empty, any issuer is accepted
+	IssuerIDs []string
+	// Unattributed, if true, restricts the result to the records not attributed to any issuer
+	Unattributed bool
+	// TokenTypes is the list of token types to accept
+	// If empty, any token type is accepted
+	TokenTypes []string
+	// Statuses is the list of transaction status to accept
+	// If empty, any status but Deleted is accepted
+	Statuses []TxStatus
 }
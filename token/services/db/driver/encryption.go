@@ -0,0 +1,28 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package driver
+
+import "context"
+
+// ColumnCipher encrypts the values of the sensitive columns of a database
+type ColumnCipher interface {
+	// Encrypt encrypts the passed value with the active key
+	Encrypt(value []byte) ([]byte, error)
+	// Decrypt decrypts the passed value. Values stored in clear, before encryption was enabled, are returned as they are.
+	Decrypt(value []byte) ([]byte, error)
+	// Stale returns true if the passed value is stored in clear or encrypted with a key other than the active one
+	Stale(value []byte) bool
+}
+
+// EncryptedDB is implemented by the databases that can encrypt their sensitive columns
+type EncryptedDB interface {
+	// SetColumnCipher makes the database encrypt the sensitive columns of the rows it writes and decrypt those it reads
+	SetColumnCipher(c ColumnCipher)
+	// ReEncrypt re-encrypts with the active key the stale values of the sensitive columns, in transactions of at most batchSize rows.
+	// It stops when the context is done and returns the number of re-encrypted rows.
+	ReEncrypt(ctx context.Context, batchSize int) (int, error)
+}
@@ -8,6 +8,7 @@ package driver
 
 import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 )
 
 type Iterator[T any] interface {
@@ -26,6 +27,9 @@ type IdentityConfiguration struct {
 	Raw    []byte
 }
 
+// RecipientIdentity models a recipient identity learned from a counterparty
+type RecipientIdentity = driver.RecipientIdentity
+
 type WalletDB interface {
 	// GetWalletID fetches a walletID that is bound to the identity passed
 	GetWalletID(identity token.Identity, roleID int) (WalletID, error)
@@ -37,6 +41,12 @@ type WalletDB interface {
 	IdentityExists(identity token.Identity, wID WalletID, roleID int) bool
 	// LoadMeta returns the metadata stored for a specific identity
 	LoadMeta(identity token.Identity, wID WalletID, roleID int) ([]byte, error)
+	// StoreLongTermIdentity stores the identity a wallet reuses across transfers when the long-term anonymity level is requested.
+	// An identity already stored for the wallet is kept.
+	StoreLongTermIdentity(identity token.Identity, wID WalletID, roleID int) error
+	// GetLongTermIdentity returns the identity stored by StoreLongTermIdentity for the passed wallet.
+	// It returns nil without error if there is none.
+	GetLongTermIdentity(wID WalletID, roleID int) (token.Identity, error)
 }
 
 type IdentityDB interface {
@@ -58,6 +68,11 @@ type IdentityDB interface {
 	SignerInfoExists(id []byte) (bool, error)
 	// GetSignerInfo returns the signer info bound to the given identity
 	GetSignerInfo(id []byte) ([]byte, error)
+	// StoreRecipientIdentity binds the passed recipient identity to the given enrollment ID and counterparty wallet.
+	// If the identity is already stored, an empty walletID does not overwrite the one already stored.
+	StoreRecipientIdentity(id []byte, enrollmentID string, walletID WalletID) error
+	// GetRecipientIdentities returns the recipient identities bound to the given enrollment ID, together with their audit info
+	GetRecipientIdentities(enrollmentID string) ([]RecipientIdentity, error)
 }
 
 // IdentityDBDriver is the interface for an identity database driver
@@ -0,0 +1,27 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package driver
+
+import "github.com/pkg/errors"
+
+// ErrTableSizesNotSupported is returned when a database cannot report the disk usage of its tables
+var ErrTableSizesNotSupported = errors.New("table sizes not supported")
+
+// TableSize is the disk usage of a table, as reported by the catalog of the database
+type TableSize struct {
+	Table string
+	// Rows is the number of rows. Some databases report an estimate, refreshed when the table is analyzed.
+	Rows int64
+	// Bytes is the space taken by the table, its indexes included
+	Bytes int64
+}
+
+// SizedDB is implemented by the databases that can report the disk usage of their tables
+type SizedDB interface {
+	// TableSizes returns the disk usage of the tables of the database
+	TableSizes() ([]TableSize, error)
+}
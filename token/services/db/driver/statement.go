@@ -0,0 +1,21 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package driver
+
+// StatementObserver is notified of the statements interrupted before completion
+type StatementObserver interface {
+	// TimedOut is called when a statement runs longer than the statement timeout of the database
+	TimedOut()
+	// Cancelled is called when a statement is cancelled by its caller
+	Cancelled()
+}
+
+// ObservedDB is implemented by the databases reporting their interrupted statements
+type ObservedDB interface {
+	// SetStatementObserver makes the database notify the passed observer of the interrupted statements
+	SetStatementObserver(o StatementObserver)
+}
@@ -38,6 +38,9 @@ type TokenRecord struct {
 	Ledger []byte
 	// LedgerMetadata is the metadata associated to the content of Ledger
 	LedgerMetadata []byte
+	// LedgerFormat identifies the encoding of Ledger, as returned by driver.LedgerFormatOf.
+	// It is empty for the tokens stored before the format was recorded.
+	LedgerFormat string
 	// Quantity is the number of units of Type carried in the token.
 	// It is encoded as a string containing a number in base 16. The string has prefix ``0x''.
 	Quantity string
@@ -51,6 +54,15 @@ type TokenRecord struct {
 	Auditor bool
 	// Issuer issued to mark this token as issued by this node
 	Issuer bool
+	// Pending is used to mark the token as created by a transaction of this node that is not final yet.
+	// Pending tokens are returned only by SpendablePendingTokensIteratorBy and when loaded by id.
+	Pending bool
+	// Attributes are the attributes of a non-fungible token, as returned by token.NFTAttributes.
+	// They are stored alongside the token to query it by attribute.
+	Attributes map[string]string
+	// Serials is the serial range carried by a serial token, as returned by token.ParseSerialType.
+	// It is stored alongside the token to query it by serial range.
+	Serials *token.SerialRange
 }
 
 // TokenDetails provides details about an owned (spent or unspent) token
@@ -75,6 +87,8 @@ type TokenDetails struct {
 	SpentBy string
 	// StoredAt is the moment the token was stored by this wallet
 	StoredAt time.Time
+	// LedgerFormat identifies the encoding of the token on the ledger, empty if unknown
+	LedgerFormat string
 }
 
 // QueryTokenDetailsParams defines the parameters for querying token details
@@ -89,8 +103,75 @@ type QueryTokenDetailsParams struct {
 	IDs []*token.ID
 	// TransactionIDs selects tokens that are the output of the provided transaction ids.
 	TransactionIDs []string
+	// SpentBy selects the tokens spent by the provided transaction ids, it requires IncludeDeleted.
+	SpentBy []string
+	// LedgerFormat (optional) selects the tokens encoded on the ledger with this format
+	LedgerFormat string
 	// IncludeDeleted determines whether to include spent tokens. It defaults to false.
 	IncludeDeleted bool
+	// Pending selects the tokens created by transactions not final yet, instead of the others.
+	// It defaults to false.
+	Pending bool
+	// AnyOf selects the tokens that match at least one of the filters, on top of the other parameters.
+	// A filter without fields matches all tokens.
+	AnyOf []TokenFilter
+	// NoneOf excludes the tokens that match any of the filters.
+	// For instance, the tokens of type A or B, excluding those of wallet X, are selected with
+	// AnyOf: {{TokenType: "A"}, {TokenType: "B"}} and NoneOf: {{WalletID: "X"}}.
+	NoneOf []TokenFilter
+}
+
+// TokenFilter selects tokens by their details, a token matches a filter if it matches all the non-empty fields
+type TokenFilter struct {
+	// WalletID is the identifier of the wallet owning the token
+	WalletID string
+	// OwnerType is the type of owner, for instance 'idemix' or 'htlc'
+	OwnerType string
+	// TokenType is the type of token
+	TokenType string
+	// TransactionIDs selects tokens that are the output of one of the provided transaction ids
+	TransactionIDs []string
+	// LedgerFormat is the format of the token on the ledger.
+	// The tokens stored with an older format, or before the format was recorded, are excluded with NoneOf: {{LedgerFormat: current}}.
+	LedgerFormat string
+}
+
+// IsEmpty returns true if the filter has no field set, and therefore matches all tokens
+func (f TokenFilter) IsEmpty() bool {
+	return len(f.WalletID) == 0 && len(f.OwnerType) == 0 && len(f.TokenType) == 0 && len(f.TransactionIDs) == 0 && len(f.LedgerFormat) == 0
+}
+
+// QueryPlan reports how the database executes one of the canonical queries of the token database
+type QueryPlan struct {
+	// Name identifies the canonical query
+	Name string
+	// Query is the analyzed statement
+	Query string
+	// Plan is the execution plan as reported by the database, one step per entry
+	Plan []string
+	// SequentialScans lists the tables the database reads entirely to answer the query.
+	// A non-empty list usually indicates a missing index.
+	SequentialScans []string
+}
+
+// CertificationRecord describes the certification of a token
+type CertificationRecord struct {
+	// TokenID is the id of the token
+	TokenID *token.ID
+	// Certification is the certification, nil if the token is not certified
+	Certification []byte
+	// StoredAt is the time the certification was stored, zero if the token is not certified
+	StoredAt time.Time
+}
+
+// QueryCertificationsParams selects the certifications to list, in the order of the token ids
+type QueryCertificationsParams struct {
+	// Missing lists, instead of the certifications, the unspent tokens owned by this node that are not certified
+	Missing bool
+	// After, if not nil, lists the records of the tokens following this one, to fetch the next page
+	After *token.ID
+	// Limit bounds the number of records listed, zero means no limit
+	Limit int
 }
 
 // CertificationDB defines a database to manager token certifications
@@ -103,9 +184,16 @@ type CertificationDB interface {
 	StoreCertifications(certifications map[*token.ID][]byte) error
 
 	// GetCertifications returns the certifications of the passed tokens.
-	// For each token, the callback function is invoked.
+	// The result respects the order of the passed ids.
 	// If a token doesn't have a certification, the function returns an error
 	GetCertifications(ids []*token.ID) ([][]byte, error)
+
+	// GetCertificationsByID returns the certifications of the passed tokens, by token id.
+	// The tokens that are not certified are missing from the map.
+	GetCertificationsByID(ids []*token.ID) (map[token.ID][]byte, error)
+
+	// ListCertifications returns the records selected by the passed params, ordered by transaction id and index
+	ListCertifications(params QueryCertificationsParams) ([]*CertificationRecord, error)
 }
 
 type TokenDBTransaction interface {
@@ -115,15 +203,62 @@ type TokenDBTransaction interface {
 	Delete(ctx context.Context, txID string, index uint64, deletedBy string) error
 	// StoreToken stores the passed token record in relation to the passed owner identifiers, if any
 	StoreToken(ctx context.Context, tr TokenRecord, owners []string) error
+	// DeletePendingTokens removes the pending tokens created by the passed transaction.
+	// It returns, by index, the ids of the transactions that spent them, if any.
+	DeletePendingTokens(ctx context.Context, txID string) (map[uint64]string, error)
+	// RevertDeltas reverses the deltas the passed transaction applied: the tokens it created at the passed indexes are removed,
+	// with their ownership, certifications, attributes, and serials, and the passed tokens it spent are unspent again
+	RevertDeltas(ctx context.Context, txID string, created []uint64, spent []*token.ID) error
 	// Commit commits this transaction
 	Commit() error
 	// Rollback rollbacks this transaction
 	Rollback() error
 }
 
+// TokenIntent records that the token request of a transaction is about to be applied to the token db
+type TokenIntent struct {
+	// TxID is the id of the transaction
+	TxID string
+	// Request is the token request of the transaction, as returned by token.Request.Bytes
+	Request []byte
+	// StoredAt is the time the intent was recorded
+	StoredAt time.Time
+	// Applied is true if the request was applied and the intent is kept as a journal entry
+	Applied bool
+}
+
+// IntentLog is a write-ahead log of the token requests being applied to the token db.
+// An intent not applied after a crash identifies a transaction whose application may not have been committed.
+// An applied intent is a journal entry, it tells the deltas a transaction applied, to reverse them if its block is rolled back.
+type IntentLog interface {
+	// AddIntent records the intent to apply the passed token request. An existing intent for the same transaction is replaced.
+	AddIntent(txID string, request []byte) error
+	// MarkIntentApplied marks the intent of the passed transaction as applied, keeping it as a journal entry
+	MarkIntentApplied(txID string) error
+	// DeleteIntent clears the intent of the passed transaction, if any
+	DeleteIntent(txID string) error
+	// Intent returns the intent of the passed transaction, applied or not, nil if there is none
+	Intent(txID string) (*TokenIntent, error)
+	// Intents returns the intents not applied and not cleared yet, the oldest first
+	Intents() ([]TokenIntent, error)
+}
+
+// ErasedOwnership counts the tokens of a wallet affected by its erasure
+type ErasedOwnership struct {
+	// Removed is the number of spent tokens whose ownership by the wallet was removed
+	Removed int64
+	// Retained is the number of unspent tokens still owned by the wallet, because the ledger still refers to them as spendable
+	Retained int64
+}
+
 // TokenDB defines a database to store token related info
 type TokenDB interface {
 	CertificationDB
+	IntentLog
+	// EraseOwnership removes the ownership by the passed wallet of the spent tokens, in a single db transaction.
+	// The spent tokens owned by that wallet only are not marked as owned anymore.
+	// The tokens themselves, their amounts and ledger outputs, are kept. The unspent tokens are not affected.
+	EraseOwnership(walletID string) (*ErasedOwnership, error)
 	// DeleteTokens marks the passsed tokens as deleted
 	DeleteTokens(deletedBy string, toDelete ...*token.ID) error
 	// IsMine return true if the passed token was stored before
@@ -132,19 +267,33 @@ type TokenDB interface {
 	UnspentTokensIterator() (driver.UnspentTokensIterator, error)
 	// UnspentTokensIteratorBy returns an iterator over all tokens owned by the passed wallet identifier and of a given type
 	UnspentTokensIteratorBy(ctx context.Context, walletID, tokenType string) (driver.UnspentTokensIterator, error)
+	// UnspentTokensIteratorByAttribute returns an iterator over the unspent tokens owned by the passed wallet identifier
+	// and having the passed attribute with the passed value. If the wallet identifier is empty, the tokens of any wallet are returned.
+	UnspentTokensIteratorByAttribute(ctx context.Context, walletID, key, value string) (driver.UnspentTokensIterator, error)
+	// ExistsTokenWithAttribute returns true if a token, spent or not, having the passed attribute with the passed value exists in the db
+	ExistsTokenWithAttribute(ctx context.Context, key, value string) (bool, error)
+	// UnspentTokensIteratorBySerialRange returns an iterator over the unspent tokens owned by the passed wallet identifier
+	// and carrying serials of the passed range. If the wallet identifier is empty, the tokens of any wallet are returned.
+	UnspentTokensIteratorBySerialRange(ctx context.Context, walletID string, serials token.SerialRange) (driver.UnspentTokensIterator, error)
+	// ExistsSerials returns true if a token, spent or not, carrying serials of the passed range exists in the db
+	ExistsSerials(ctx context.Context, serials token.SerialRange) (bool, error)
 	// SpendableTokensIteratorBy returns an iterator over all tokens owned solely by the passed wallet identifier and of a given type
 	SpendableTokensIteratorBy(ctx context.Context, walletID string, typ string) (driver.SpendableTokensIterator, error)
 	// ListUnspentTokensBy returns the list of all tokens owned by the passed identifier of a given type
 	ListUnspentTokensBy(walletID, typ string) (*token.UnspentTokens, error)
 	// ListUnspentTokens returns the list of all owned tokens
 	ListUnspentTokens() (*token.UnspentTokens, error)
-	// ListAuditTokens returns the audited tokens for the passed ids
+	// ListAuditTokens returns the audited tokens for the passed ids.
+	// The result respects the order of the passed ids. If a token is not found, the function returns an error.
 	ListAuditTokens(ids ...*token.ID) ([]*token.Token, error)
 	// ListHistoryIssuedTokens returns the list of all issued tokens
 	ListHistoryIssuedTokens() (*token.IssuedTokens, error)
 	// GetTokenOutputs returns the value of the tokens as they appear on the ledger for the passed ids.
 	// For each token, the call-back function is invoked. The call-back function is invoked respecting the order of the passed ids.
 	GetTokenOutputs(ids []*token.ID, callback driver.QueryCallbackFunc) error
+	// ExistAll returns, for each passed id, true if the token is stored in the db, false otherwise.
+	// The result respects the order of the passed ids.
+	ExistAll(ids []*token.ID) ([]bool, error)
 	// GetTokenInfos returns the metadata of the tokens for the passed ids.
 	// For each token, the call-back function is invoked. The call-back function is invoked respecting the order of the passed ids.
 	GetTokenInfos(ids []*token.ID) ([][]byte, error)
@@ -154,11 +303,28 @@ type TokenDB interface {
 	// GetAllTokenInfos returns the token metadata for the passed ids
 	GetAllTokenInfos(ids []*token.ID) ([][]byte, error)
 	// GetTokens returns the owned tokens and their identifier keys for the passed ids.
+	// The result respects the order of the passed ids.
+	// If a token is not found, or is spent, the function returns an error.
 	GetTokens(inputs ...*token.ID) ([]*token.Token, error)
-	// WhoDeletedTokens for each id, the function return if it was deleted and by who as per the Delete function
+	// GetTokensByID returns the owned tokens for the passed ids, by token id.
+	// The ids not found, or whose tokens are spent, are missing from the map.
+	GetTokensByID(inputs ...*token.ID) (map[token.ID]*token.Token, error)
+	// WhoDeletedTokens for each id, the function return if it was deleted and by who as per the Delete function.
+	// The result respects the order of the passed ids. If a token is not found, the function returns an error.
 	WhoDeletedTokens(inputs ...*token.ID) ([]string, []bool, error)
-	// TransactionExists returns true if a token with that transaction id exists in the db
+	// TransactionExists returns true if a token with that transaction id exists in the db.
+	// Pending tokens are not considered.
 	TransactionExists(ctx context.Context, id string) (bool, error)
+	// PendingTransactionExists returns true if a pending token with that transaction id exists in the db
+	PendingTransactionExists(ctx context.Context, id string) (bool, error)
+	// SpendablePendingTokensIteratorBy returns an iterator over the pending tokens owned solely by the passed wallet identifier and of a given type
+	SpendablePendingTokensIteratorBy(ctx context.Context, walletID string, typ string) (driver.SpendableTokensIterator, error)
+	// SpendPendingTokens marks the passed tokens, if pending, as spent by the passed transaction.
+	// The other tokens are not modified.
+	SpendPendingTokens(spentBy string, ids ...*token.ID) error
+	// DeletePendingTokens removes the pending tokens created by the passed transaction.
+	// It returns the ids of the transactions that spent them, if any.
+	DeletePendingTokens(txID string) ([]string, error)
 	// StorePublicParams stores the public parameters.
 	// If they already exist, the function return with no error. No changes are applied.
 	StorePublicParams(raw []byte) error
@@ -166,6 +332,8 @@ type TokenDB interface {
 	// If not public parameters are available, it returns nil with no error
 	PublicParams() ([]byte, error)
 	// PublicParamsByHash returns the public parameters whose hash matches the passed one.
+	// The hash is always SHA-256, even if the public parameters select another hash algorithm,
+	// therefore, it can differ from the PPHash of the public parameters manager.
 	// If not public parameters are available for that hash, it returns an error
 	PublicParamsByHash(rawHash driver.PPHash) ([]byte, error)
 	// NewTokenDBTransaction returns a new Transaction to commit atomically multiple operations
@@ -174,6 +342,9 @@ type TokenDB interface {
 	QueryTokenDetails(params QueryTokenDetailsParams) ([]TokenDetails, error)
 	// Balance returns the sun of the amounts of the tokens with type and EID equal to those passed as arguments.
 	Balance(ownerEID, typ string) (uint64, error)
+	// ExplainQueries returns the execution plans of the canonical queries (spendable tokens, balance, and token details)
+	// against the live schema.
+	ExplainQueries() ([]QueryPlan, error)
 }
 
 // TokenDBDriver is the interface for a token database driver
@@ -216,4 +387,6 @@ type TokenLockDBDriver interface {
 
 var (
 	ErrTokenDoesNotExist = errors.New("token does not exist")
+	// ErrQueryPlansNotSupported is returned by ExplainQueries when the database cannot report execution plans
+	ErrQueryPlansNotSupported = errors.New("query plans not supported")
 )
@@ -9,6 +9,7 @@ package driver
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
@@ -45,6 +46,31 @@ type AtomicWrite interface {
 	// AddValidationRecord adds a new validation records for the given params
 	// This operation _requires_ a TokenRequest with the same tx_id to exist
 	AddValidationRecord(txID string, meta map[string][]byte) error
+
+	// AddIssuerAttribution adds an issuer attribution record to the database transaction.
+	// This operation _requires_ a TokenRequest with the same tx_id to exist
+	AddIssuerAttribution(record *IssuerAttributionRecord) error
+
+	// AddIdempotencyKey binds the passed idempotency key, chosen by the application, to the passed transaction id.
+	// This operation _requires_ a TokenRequest with the same tx_id to exist.
+	// It returns an IdempotencyKeyError if the key is bound to another transaction not deleted.
+	AddIdempotencyKey(txID string, key string) error
+
+	// AddReference binds the passed hash of an external document to the passed transaction id.
+	// This operation _requires_ a TokenRequest with the same tx_id to exist
+	AddReference(txID string, reference []byte) error
+
+	// AddWallet records the local wallet that signed the transaction with the passed id.
+	// This operation _requires_ a TokenRequest with the same tx_id to exist
+	AddWallet(txID string, walletID string) error
+
+	// AddExportRecord adds the passed serialized records, not exported yet, to the export outbox.
+	// This operation _requires_ a TokenRequest with the same tx_id to exist
+	AddExportRecord(txID string, payload []byte) error
+
+	// AddAuditResponse adds the passed audit response, not sent yet, to the outbox of the auditor.
+	// This operation _requires_ a TokenRequest with the same tx_id to exist
+	AddAuditResponse(record *AuditResponseRecord) error
 }
 
 type TransactionDB interface {
@@ -58,6 +84,25 @@ type TransactionDB interface {
 	// (and with that, the associated ValidationRecord, Movement and Transaction)
 	SetStatus(ctx context.Context, txID string, status TxStatus, message string) error
 
+	// OverrideStatus sets the status of a TokenRequest as SetStatus does,
+	// and records, atomically, the override with the previous status in the status overrides.
+	// It must be used instead of SetStatus outside the finality listener.
+	OverrideStatus(ctx context.Context, txID string, status TxStatus, message string, override StatusOverride) error
+
+	// QueryStatusOverrides returns the status overrides matching the passed params, the oldest first
+	QueryStatusOverrides(params QueryStatusOverridesParams) ([]*StatusOverrideRecord, error)
+
+	// AddCompensationFailure records that the compensation of the passed transaction failed with the passed message,
+	// replacing the failure already recorded for the transaction, if any.
+	// This operation _requires_ a TokenRequest with the same tx_id to exist
+	AddCompensationFailure(txID string, message string) error
+
+	// DeleteCompensationFailure removes the compensation failure recorded for the passed transaction, if any
+	DeleteCompensationFailure(txID string) error
+
+	// QueryCompensationFailures returns the messages of the recorded compensation failures, indexed by transaction id
+	QueryCompensationFailures() (map[string]string, error)
+
 	// GetStatus returns the status of a given transaction.
 	// It returns an error if the transaction is not found
 	GetStatus(txID string) (TxStatus, string, error)
@@ -74,9 +119,42 @@ type TransactionDB interface {
 	// QueryTokenRequests returns an iterator over the token requests matching the passed params
 	QueryTokenRequests(params QueryTokenRequestsParams) (TokenRequestIterator, error)
 
+	// QueryIssuerAttributions returns the issuer attribution records matching the passed params
+	QueryIssuerAttributions(params QueryIssuerAttributionsParams) ([]*IssuerAttributionRecord, error)
+
 	// GetTokenRequest returns the token request bound to the passed transaction id, if available.
 	// It returns nil without error if the key is not found.
 	GetTokenRequest(txID string) ([]byte, error)
+
+	// GetTokenRequests returns the token requests bound to the passed transaction ids, indexed by transaction id.
+	// The transaction ids that are not found are missing in the returned map.
+	GetTokenRequests(txIDs []string) (map[string][]byte, error)
+
+	// AddFundsWitness stores the passed funds witness, and sets its ID and Timestamp
+	AddFundsWitness(record *FundsWitnessRecord) error
+
+	// QueryFundsWitnesses returns the funds witnesses matching the passed params, the oldest first
+	QueryFundsWitnesses(params QueryFundsWitnessesParams) ([]*FundsWitnessRecord, error)
+
+	// GetTxIDByIdempotencyKey returns the id of the pending or confirmed transaction bound to the passed idempotency key.
+	// It returns an empty string without error if there is no such transaction.
+	GetTxIDByIdempotencyKey(key string) (string, error)
+
+	// GetTxIDsByReference returns the ids of the transactions bound to the passed hash of an external document,
+	// the oldest first
+	GetTxIDsByReference(reference []byte) ([]string, error)
+
+	// EraseEnrollmentID replaces the passed enrollment ID with the passed pseudonym in the transaction and movement records,
+	// in a single db transaction. The amounts and the token requests are kept.
+	EraseEnrollmentID(eID string, pseudonym string) (*ErasedRecords, error)
+
+	// AddRecipientAnonymityLevel records the anonymity level the passed recipient identity has been derived with,
+	// by the passed local wallet. A level already recorded for the identity is kept.
+	AddRecipientAnonymityLevel(identity token.Identity, walletID string, level string) error
+
+	// GetRecipientAnonymityLevels returns the anonymity levels recorded for the passed recipient identities,
+	// indexed by the unique id of the identity. The identities without a level are missing in the returned map.
+	GetRecipientAnonymityLevels(identities []token.Identity) (map[string]string, error)
 }
 
 type TransactionEndorsementAckDB interface {
@@ -95,4 +173,24 @@ type TTXDBDriver interface {
 
 var (
 	ErrTokenRequestDoesNotExist = errors.New("token request does not exist")
+	// ErrIdempotencyKeyInUse is returned when a pending or confirmed transaction is already bound to an idempotency key
+	ErrIdempotencyKeyInUse = errors.New("idempotency key already in use")
 )
+
+// IdempotencyKeyError tells which transaction is already bound to an idempotency key.
+// TxID is empty if the transaction is not known, as when two transactions race for the key.
+type IdempotencyKeyError struct {
+	Key  string
+	TxID string
+}
+
+func (e *IdempotencyKeyError) Error() string {
+	if len(e.TxID) == 0 {
+		return fmt.Sprintf("%s: key [%s] is bound to another transaction", ErrIdempotencyKeyInUse, e.Key)
+	}
+	return fmt.Sprintf("%s: key [%s] is bound to transaction [%s]", ErrIdempotencyKeyInUse, e.Key, e.TxID)
+}
+
+func (e *IdempotencyKeyError) Is(target error) bool {
+	return target == ErrIdempotencyKeyInUse
+}
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package db
 
 import (
+	"context"
 	"reflect"
 
 	driver3 "github.com/hyperledger-labs/fabric-smart-client/platform/common/driver"
@@ -77,6 +78,7 @@ func (h *DriverHolder[S, D, O]) Register(name drivers.DriverName, driver O) {
 
 // NewManager creates a new DB manager.
 func (h *DriverHolder[S, D, O]) NewManager(cp ConfigProvider, config Config) *Manager[S, D, O] {
+	background, cancel := context.WithCancel(context.Background())
 	return &Manager[S, D, O]{
 		logger:  h.Logger,
 		drivers: h.Drivers,
@@ -84,6 +86,9 @@ func (h *DriverHolder[S, D, O]) NewManager(cp ConfigProvider, config Config) *Ma
 		config:  config,
 		dbs:     map[string]S{},
 
+		background:       background,
+		cancelBackground: cancel,
+
 		zero: h.zero,
 	}
 }
@@ -0,0 +1,202 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"os"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+const (
+	// EncryptionConfigKey is the key, in the TMS configuration, of the column encryption configuration
+	EncryptionConfigKey = "encryption"
+
+	// EncryptionKeySize is the size in bytes of a column encryption key, keys are used with AES-256-GCM
+	EncryptionKeySize = 32
+
+	defaultReEncryptBatchSize = 100
+)
+
+// encryptedMagic prefixes the encrypted values, the values without it are stored in clear
+var encryptedMagic = []byte{0x00, 't', 'e', 'k'}
+
+// EncryptionKeyOpts configures a column encryption key
+type EncryptionKeyOpts struct {
+	// ID identifies the key, it is stored with the values the key encrypts
+	ID string `yaml:"id"`
+	// KeyFile is the path to the file containing the key
+	KeyFile string `yaml:"keyFile"`
+}
+
+// EncryptionOpts configures the encryption of the sensitive columns of the databases of a TMS
+type EncryptionOpts struct {
+	// ActiveKey is the id of the key encrypting the new values
+	ActiveKey string `yaml:"activeKey"`
+	// Keys are the keys that can decrypt the stored values: the active key and the retired ones still in use
+	Keys []EncryptionKeyOpts `yaml:"keys"`
+	// ReEncryptBatchSize is the maximum number of rows re-encrypted in a transaction. The default is 100.
+	ReEncryptBatchSize int `yaml:"reEncryptBatchSize,omitempty"`
+}
+
+// GetReEncryptBatchSize returns the maximum number of rows re-encrypted in a transaction
+func (o *EncryptionOpts) GetReEncryptBatchSize() int {
+	if o.ReEncryptBatchSize > 0 {
+		return o.ReEncryptBatchSize
+	}
+	return defaultReEncryptBatchSize
+}
+
+// EncryptionEnabler is implemented by the services that can encrypt their sensitive columns
+type EncryptionEnabler interface {
+	// EnableEncryption makes the service encrypt its sensitive columns with the passed cipher
+	EnableEncryption(c driver.ColumnCipher) error
+	// ReEncrypt re-encrypts with the active key the values stored in clear or with a retired key
+	ReEncrypt(ctx context.Context, batchSize int) (int, error)
+}
+
+// ColumnCipher encrypts column values with AES-256-GCM.
+// The values are bound to the tenant, the TMS, owning the cipher:
+// a value copied to the tables of another tenant does not decrypt, even under the same key.
+type ColumnCipher struct {
+	tenant []byte
+	active string
+	aeads  map[string]cipher.AEAD
+}
+
+// NewColumnCipher returns a ColumnCipher for the passed tenant encrypting with the active key and
+// decrypting with any of the passed keys, indexed by key id
+func NewColumnCipher(tenant string, active string, keys map[string][]byte) (*ColumnCipher, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, errors.Errorf("active key [%s] not found", active)
+	}
+	aeads := make(map[string]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		if len(id) == 0 || len(id) > 255 {
+			return nil, errors.Errorf("invalid key id [%s], it must be between 1 and 255 bytes long", id)
+		}
+		if len(key) != EncryptionKeySize {
+			return nil, errors.Errorf("key [%s] must be [%d] bytes long, got [%d]", id, EncryptionKeySize, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create cipher for key [%s]", id)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create aead for key [%s]", id)
+		}
+		aeads[id] = aead
+	}
+	return &ColumnCipher{tenant: []byte(tenant), active: active, aeads: aeads}, nil
+}
+
+// LoadColumnCipher returns a ColumnCipher for the passed tenant with the keys read from the files in the passed options
+func LoadColumnCipher(tenant string, opts *EncryptionOpts) (*ColumnCipher, error) {
+	keys := make(map[string][]byte, len(opts.Keys))
+	for _, k := range opts.Keys {
+		if _, ok := keys[k.ID]; ok {
+			return nil, errors.Errorf("duplicate key id [%s]", k.ID)
+		}
+		key, err := os.ReadFile(k.KeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read key [%s]", k.ID)
+		}
+		keys[k.ID] = key
+	}
+	return NewColumnCipher(tenant, opts.ActiveKey, keys)
+}
+
+// Encrypt encrypts the passed value with the active key.
+// The encrypted value is the magic prefix, the length and the id of the key, the nonce, and the sealed value.
+func (c *ColumnCipher) Encrypt(value []byte) ([]byte, error) {
+	aead := c.aeads[c.active]
+	header := make([]byte, 0, len(encryptedMagic)+1+len(c.active)+aead.NonceSize())
+	header = append(header, encryptedMagic...)
+	header = append(header, byte(len(c.active)))
+	header = append(header, c.active...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrapf(err, "failed to generate nonce")
+	}
+	return aead.Seal(append(header, nonce...), nonce, value, c.additionalData(header)), nil
+}
+
+// Decrypt decrypts the passed value with the key it has been encrypted with.
+// Values stored in clear are returned as they are.
+func (c *ColumnCipher) Decrypt(value []byte) ([]byte, error) {
+	keyID, header, ok := parseEncrypted(value)
+	if !ok {
+		return value, nil
+	}
+	aead, ok := c.aeads[keyID]
+	if !ok {
+		return nil, errors.Errorf("value encrypted with unknown key [%s]", keyID)
+	}
+	rest := value[len(header):]
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("invalid encrypted value, nonce missing")
+	}
+	plain, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], c.additionalData(header))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decrypt value with key [%s]", keyID)
+	}
+	return plain, nil
+}
+
+// Stale returns true if the passed value is stored in clear or encrypted with a key other than the active one
+func (c *ColumnCipher) Stale(value []byte) bool {
+	keyID, _, ok := parseEncrypted(value)
+	return !ok || keyID != c.active
+}
+
+func (c *ColumnCipher) additionalData(header []byte) []byte {
+	return append(append([]byte{}, c.tenant...), header...)
+}
+
+// parseEncrypted returns the id of the key the passed value has been encrypted with and the header of the value,
+// or false if the value is stored in clear
+func parseEncrypted(value []byte) (string, []byte, bool) {
+	if !bytes.HasPrefix(value, encryptedMagic) || len(value) <= len(encryptedMagic) {
+		return "", nil, false
+	}
+	l := int(value[len(encryptedMagic)])
+	end := len(encryptedMagic) + 1 + l
+	if l == 0 || len(value) < end {
+		return "", nil, false
+	}
+	return string(value[len(encryptedMagic)+1 : end]), value[:end], true
+}
+
+// SetColumnCipher sets the passed cipher on the passed database driver, if it supports column encryption
+func SetColumnCipher(d any, c driver.ColumnCipher) error {
+	e, ok := d.(driver.EncryptedDB)
+	if !ok {
+		return errors.Errorf("database [%T] does not support column encryption", d)
+	}
+	e.SetColumnCipher(c)
+	return nil
+}
+
+// ReEncrypt re-encrypts the stale values of the passed database driver as a write registered with the passed gate
+func ReEncrypt(ctx context.Context, d any, writes *WriteGate, batchSize int) (int, error) {
+	e, ok := d.(driver.EncryptedDB)
+	if !ok {
+		return 0, errors.Errorf("database [%T] does not support column encryption", d)
+	}
+	if err := writes.Enter(); err != nil {
+		return 0, errors.WithMessagef(err, "cannot re-encrypt")
+	}
+	defer writes.Exit()
+	return e.ReEncrypt(ctx, batchSize)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnCipher(t *testing.T) {
+	k1 := bytes.Repeat([]byte{1}, EncryptionKeySize)
+	k2 := bytes.Repeat([]byte{2}, EncryptionKeySize)
+
+	c1, err := NewColumnCipher("tms1", "k1", map[string][]byte{"k1": k1})
+	assert.NoError(t, err)
+
+	// round trip
+	encrypted, err := c1.Encrypt([]byte("opening"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(encrypted), "opening")
+	assert.False(t, c1.Stale(encrypted))
+	plain, err := c1.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("opening"), plain)
+
+	// values in clear pass through and are stale
+	plain, err = c1.Decrypt([]byte("clear"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("clear"), plain)
+	assert.True(t, c1.Stale([]byte("clear")))
+
+	// rotation: the retired key still decrypts, its values are stale
+	c2, err := NewColumnCipher("tms1", "k2", map[string][]byte{"k1": k1, "k2": k2})
+	assert.NoError(t, err)
+	assert.True(t, c2.Stale(encrypted))
+	plain, err = c2.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("opening"), plain)
+	reEncrypted, err := c2.Encrypt(plain)
+	assert.NoError(t, err)
+	assert.False(t, c2.Stale(reEncrypted))
+	_, err = c1.Decrypt(reEncrypted)
+	assert.ErrorContains(t, err, "unknown key [k2]")
+
+	// another tenant cannot decrypt, even under the same key
+	other, err := NewColumnCipher("tms2", "k1", map[string][]byte{"k1": k1})
+	assert.NoError(t, err)
+	_, err = other.Decrypt(encrypted)
+	assert.Error(t, err)
+
+	// invalid configurations
+	_, err = NewColumnCipher("tms1", "k3", map[string][]byte{"k1": k1})
+	assert.ErrorContains(t, err, "active key [k3] not found")
+	_, err = NewColumnCipher("tms1", "k1", map[string][]byte{"k1": k1[:16]})
+	assert.ErrorContains(t, err, "must be [32] bytes long")
+}
+
+func TestLoadColumnCipher(t *testing.T) {
+	keyFile := path.Join(t.TempDir(), "k1")
+	assert.NoError(t, os.WriteFile(keyFile, bytes.Repeat([]byte{1}, EncryptionKeySize), 0600))
+
+	c, err := LoadColumnCipher("tms1", &EncryptionOpts{ActiveKey: "k1", Keys: []EncryptionKeyOpts{{ID: "k1", KeyFile: keyFile}}})
+	assert.NoError(t, err)
+	encrypted, err := c.Encrypt([]byte("request"))
+	assert.NoError(t, err)
+	plain, err := c.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("request"), plain)
+
+	_, err = LoadColumnCipher("tms1", &EncryptionOpts{ActiveKey: "k1", Keys: []EncryptionKeyOpts{{ID: "k1", KeyFile: keyFile}, {ID: "k1", KeyFile: keyFile}}})
+	assert.ErrorContains(t, err, "duplicate key id [k1]")
+}
@@ -0,0 +1,325 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package fixtures generates deterministic populations of tokens, transactions, and movements,
+// and loads them into the token db and the ttxdb of any driver.
+// Integration tests use them to start from a known state, developers to reproduce performance issues locally.
+package fixtures
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// ppHash is the hash of the public parameters the token requests of the populations refer to
+var ppHash = sha256.Sum256([]byte("fixtures"))
+
+// Spec describes a population. The same spec generates the same population.
+type Spec struct {
+	// Seed makes the population reproducible
+	Seed int64
+	// Wallets is the number of wallets, their IDs are also their enrollment IDs
+	Wallets int
+	// Types are the token types
+	Types []string
+	// Transactions is the number of transactions, issues and transfers
+	Transactions int
+	// IssueRatio is the fraction of the transactions that are issues, the others are transfers
+	IssueRatio float64
+	// MaxAmount is the maximum amount of an issued token, amounts are uniform in [1, MaxAmount]
+	MaxAmount uint64
+	// RequestSize is the size in bytes of the token request of a transaction
+	RequestSize int
+	// Start is the time of the first transaction, the following ones are Interval apart
+	Start time.Time
+	// Interval is the time between two transactions
+	Interval time.Duration
+}
+
+// DefaultSpec returns a spec of a hundred wallets exchanging two token types over a thousand transactions
+func DefaultSpec() Spec {
+	return Spec{
+		Seed:         42,
+		Wallets:      100,
+		Types:        []string{"USD", "EUR"},
+		Transactions: 1000,
+		IssueRatio:   0.2,
+		MaxAmount:    1000,
+		RequestSize:  1024,
+		Start:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Interval:     time.Minute,
+	}
+}
+
+// Token is a token created by a transaction of the population
+type Token struct {
+	Record driver.TokenRecord
+	Owners []string
+	// SpentBy is the id of the transaction spending the token, empty if unspent
+	SpentBy string
+}
+
+// ID returns the id of the token
+func (t *Token) ID() *token2.ID {
+	return &token2.ID{TxId: t.Record.TxID, Index: t.Record.Index}
+}
+
+// Transaction is a transaction of the population, with its records as the ttxdb stores them
+type Transaction struct {
+	TxID    string
+	Request []byte
+	// Inputs are the ids of the tokens spent by the transaction
+	Inputs       []*token2.ID
+	Transactions []*driver.TransactionRecord
+	Movements    []*driver.MovementRecord
+}
+
+// Population is the set of tokens and transactions generated from a spec
+type Population struct {
+	Spec         Spec
+	Wallets      []string
+	Tokens       []*Token
+	Transactions []*Transaction
+}
+
+// Generate returns the population of the passed spec.
+// Issues create a token for a wallet. Transfers spend a token of a wallet, pay part of it to another wallet,
+// and give the rest back to the sender. All the transactions are confirmed.
+func Generate(spec Spec) *Population {
+	if spec.Wallets < 2 {
+		spec.Wallets = 2
+	}
+	if len(spec.Types) == 0 {
+		spec.Types = []string{"USD"}
+	}
+	if spec.MaxAmount == 0 {
+		spec.MaxAmount = 1
+	}
+	rnd := rand.New(rand.NewSource(spec.Seed))
+	p := &Population{
+		Spec:    spec,
+		Wallets: make([]string, spec.Wallets),
+	}
+	identities := make([][]byte, spec.Wallets)
+	for i := range p.Wallets {
+		p.Wallets[i] = fmt.Sprintf("wallet%d", i)
+		identities[i] = make([]byte, 32)
+		rnd.Read(identities[i])
+	}
+	// unspent holds the indexes, in p.Tokens, of the unspent tokens
+	var unspent []int
+	owner := map[int]int{}
+	for i := 0; i < spec.Transactions; i++ {
+		tx := &Transaction{TxID: fmt.Sprintf("tx%d", i), Request: make([]byte, spec.RequestSize)}
+		rnd.Read(tx.Request)
+		timestamp := spec.Start.Add(time.Duration(i) * spec.Interval)
+		output := func(wallet int, typ string, amount uint64) {
+			p.Tokens = append(p.Tokens, &Token{
+				Record: driver.TokenRecord{
+					TxID:           tx.TxID,
+					Index:          uint64(len(p.Tokens) - firstOutput(p.Tokens, tx.TxID)),
+					OwnerRaw:       identities[wallet],
+					OwnerType:      "idemix",
+					OwnerIdentity:  identities[wallet],
+					OwnerWalletID:  p.Wallets[wallet],
+					Ledger:         identities[wallet],
+					LedgerMetadata: []byte{},
+					Quantity:       fmt.Sprintf("0x%x", amount),
+					Type:           typ,
+					Amount:         amount,
+					Owner:          true,
+				},
+				Owners: []string{p.Wallets[wallet]},
+			})
+			unspent = append(unspent, len(p.Tokens)-1)
+			owner[len(p.Tokens)-1] = wallet
+		}
+		record := func(actionType driver.ActionType, sender, recipient string, typ string, amount uint64) {
+			tx.Transactions = append(tx.Transactions, &driver.TransactionRecord{
+				TxID:         tx.TxID,
+				ActionType:   actionType,
+				SenderEID:    sender,
+				RecipientEID: recipient,
+				TokenType:    typ,
+				Amount:       new(big.Int).SetUint64(amount),
+				Timestamp:    timestamp,
+				Status:       driver.Confirmed,
+			})
+		}
+		movement := func(wallet string, typ string, amount int64) {
+			tx.Movements = append(tx.Movements, &driver.MovementRecord{
+				TxID:         tx.TxID,
+				EnrollmentID: wallet,
+				TokenType:    typ,
+				Amount:       big.NewInt(amount),
+				Timestamp:    timestamp,
+				Status:       driver.Confirmed,
+			})
+		}
+
+		if len(unspent) == 0 || rnd.Float64() < spec.IssueRatio {
+			recipient := rnd.Intn(spec.Wallets)
+			typ := spec.Types[rnd.Intn(len(spec.Types))]
+			amount := uint64(rnd.Int63n(int64(spec.MaxAmount))) + 1
+			output(recipient, typ, amount)
+			record(driver.Issue, "", p.Wallets[recipient], typ, amount)
+			movement(p.Wallets[recipient], typ, int64(amount))
+		} else {
+			// spend a random unspent token
+			k := rnd.Intn(len(unspent))
+			input := unspent[k]
+			unspent[k] = unspent[len(unspent)-1]
+			unspent = unspent[:len(unspent)-1]
+			in := p.Tokens[input]
+			in.SpentBy = tx.TxID
+			tx.Inputs = []*token2.ID{in.ID()}
+
+			sender := owner[input]
+			recipient := rnd.Intn(spec.Wallets - 1)
+			if recipient >= sender {
+				recipient++
+			}
+			typ := in.Record.Type
+			value := uint64(rnd.Int63n(int64(in.Record.Amount))) + 1
+			output(recipient, typ, value)
+			record(driver.Transfer, p.Wallets[sender], p.Wallets[recipient], typ, value)
+			if rest := in.Record.Amount - value; rest > 0 {
+				output(sender, typ, rest)
+				record(driver.Transfer, p.Wallets[sender], p.Wallets[sender], typ, rest)
+			}
+			movement(p.Wallets[sender], typ, -int64(value))
+			movement(p.Wallets[recipient], typ, int64(value))
+		}
+		p.Transactions = append(p.Transactions, tx)
+	}
+	return p
+}
+
+// firstOutput returns the position, in the passed tokens, of the first token created by the passed transaction
+func firstOutput(tokens []*Token, txID string) int {
+	i := len(tokens)
+	for i > 0 && tokens[i-1].Record.TxID == txID {
+		i--
+	}
+	return i
+}
+
+// Balances returns the balance of each wallet, by wallet and token type
+func (p *Population) Balances() map[string]map[string]uint64 {
+	balances := map[string]map[string]uint64{}
+	for _, t := range p.Tokens {
+		if len(t.SpentBy) != 0 {
+			continue
+		}
+		if _, ok := balances[t.Record.OwnerWalletID]; !ok {
+			balances[t.Record.OwnerWalletID] = map[string]uint64{}
+		}
+		balances[t.Record.OwnerWalletID][t.Record.Type] += t.Record.Amount
+	}
+	return balances
+}
+
+// LoadTokens stores the tokens of the population in the passed token db, transaction after transaction,
+// and deletes the spent ones
+func (p *Population) LoadTokens(db driver.TokenDB) error {
+	next := 0
+	for _, tx := range p.Transactions {
+		w, err := db.NewTokenDBTransaction(context.Background())
+		if err != nil {
+			return errors.WithMessagef(err, "failed to begin transaction [%s]", tx.TxID)
+		}
+		for ; next < len(p.Tokens) && p.Tokens[next].Record.TxID == tx.TxID; next++ {
+			t := p.Tokens[next]
+			if err := w.StoreToken(context.Background(), t.Record, t.Owners); err != nil {
+				_ = w.Rollback()
+				return errors.WithMessagef(err, "failed to store token [%s:%d]", t.Record.TxID, t.Record.Index)
+			}
+		}
+		if err := w.Commit(); err != nil {
+			return errors.WithMessagef(err, "failed to commit transaction [%s]", tx.TxID)
+		}
+		if len(tx.Inputs) == 0 {
+			continue
+		}
+		if err := db.DeleteTokens(tx.TxID, tx.Inputs...); err != nil {
+			return errors.WithMessagef(err, "failed to delete the inputs of transaction [%s]", tx.TxID)
+		}
+	}
+	return nil
+}
+
+// LoadTransactions stores the token requests, the transaction records, and the movements of the population
+// in the passed ttxdb, and confirms the transactions
+func (p *Population) LoadTransactions(db driver.TransactionDB) error {
+	for _, tx := range p.Transactions {
+		w, err := db.BeginAtomicWrite()
+		if err != nil {
+			return errors.WithMessagef(err, "failed to begin transaction [%s]", tx.TxID)
+		}
+		if err := addTransaction(w, tx); err != nil {
+			w.Rollback()
+			return errors.WithMessagef(err, "failed to store transaction [%s]", tx.TxID)
+		}
+		if err := w.Commit(); err != nil {
+			return errors.WithMessagef(err, "failed to commit transaction [%s]", tx.TxID)
+		}
+		if err := db.SetStatus(context.Background(), tx.TxID, driver.Confirmed, ""); err != nil {
+			return errors.WithMessagef(err, "failed to confirm transaction [%s]", tx.TxID)
+		}
+	}
+	return nil
+}
+
+func addTransaction(w driver.AtomicWrite, tx *Transaction) error {
+	if err := w.AddTokenRequest(tx.TxID, tx.Request, nil, ppHash[:]); err != nil {
+		return err
+	}
+	for _, r := range tx.Transactions {
+		if err := w.AddTransaction(r); err != nil {
+			return err
+		}
+	}
+	for _, r := range tx.Movements {
+		if err := w.AddMovement(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load opens, with the passed drivers, the token db and the ttxdb of the passed TMS, and loads the population in them.
+// The drivers are those registered with the token SDK, for instance the `sql` and the `unity` ones.
+// Either driver can be nil, to skip the corresponding db.
+func (p *Population) Load(cp driver.ConfigProvider, tmsID token.TMSID, tokenDriver driver.TokenDBDriver, ttxDriver driver.TTXDBDriver) error {
+	if tokenDriver != nil {
+		db, err := tokenDriver.Open(cp, tmsID)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to open the token db of [%s]", tmsID)
+		}
+		if err := p.LoadTokens(db); err != nil {
+			return errors.WithMessagef(err, "failed to load the tokens of [%s]", tmsID)
+		}
+	}
+	if ttxDriver != nil {
+		db, err := ttxDriver.Open(cp, tmsID)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to open the ttxdb of [%s]", tmsID)
+		}
+		if err := p.LoadTransactions(db); err != nil {
+			return errors.WithMessagef(err, "failed to load the transactions of [%s]", tmsID)
+		}
+	}
+	return nil
+}
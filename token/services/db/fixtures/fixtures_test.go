@@ -0,0 +1,89 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fixtures
+
+import (
+	"fmt"
+	"math/big"
+	"path"
+	"testing"
+
+	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	spec := DefaultSpec()
+	spec.Wallets, spec.Transactions = 10, 200
+	p := Generate(spec)
+	assert.Equal(t, p, Generate(spec))
+	assert.Len(t, p.Transactions, spec.Transactions)
+
+	spec.Seed++
+	assert.NotEqual(t, p.Tokens, Generate(spec).Tokens)
+
+	// transfers preserve the supply of each type
+	issued := map[string]uint64{}
+	for _, tx := range p.Transactions {
+		for _, r := range tx.Transactions {
+			if r.ActionType == driver.Issue {
+				issued[r.TokenType] += r.Amount.Uint64()
+			}
+		}
+	}
+	supply := map[string]uint64{}
+	for _, balances := range p.Balances() {
+		for typ, amount := range balances {
+			supply[typ] += amount
+		}
+	}
+	assert.Equal(t, issued, supply)
+}
+
+func TestLoad(t *testing.T) {
+	spec := DefaultSpec()
+	spec.Wallets, spec.Transactions = 10, 200
+	p := Generate(spec)
+
+	dataSource := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", path.Join(t.TempDir(), "db.sqlite"))
+	sqlDB, err := common.NewSQLDBOpener("", "").OpenSQLDB(sql2.SQLite, dataSource, 10, false)
+	assert.NoError(t, err)
+	opts := common.NewDBOpts{DataSource: dataSource, TablePrefix: "fixtures", CreateSchema: true}
+	tokenDB, err := sqlite.NewTokenDB(sqlDB, opts)
+	assert.NoError(t, err)
+	ttxDB, err := sqlite.NewTransactionDB(sqlDB, opts)
+	assert.NoError(t, err)
+
+	assert.NoError(t, p.LoadTokens(tokenDB))
+	assert.NoError(t, p.LoadTransactions(ttxDB))
+
+	balances := p.Balances()
+	for _, wallet := range p.Wallets {
+		for _, typ := range spec.Types {
+			balance, err := tokenDB.Balance(wallet, typ)
+			assert.NoError(t, err)
+			assert.Equal(t, balances[wallet][typ], balance, "balance of [%s] for [%s]", wallet, typ)
+		}
+	}
+
+	// the movements of a wallet sum up to its balance
+	movements, err := ttxDB.QueryMovements(driver.QueryMovementsParams{
+		EnrollmentIDs:     []string{p.Wallets[0]},
+		TokenTypes:        []string{spec.Types[0]},
+		TxStatuses:        []driver.TxStatus{driver.Confirmed},
+		MovementDirection: driver.All,
+	})
+	assert.NoError(t, err)
+	sum := new(big.Int)
+	for _, m := range movements {
+		sum.Add(sum, m.Amount)
+	}
+	assert.Equal(t, new(big.Int).SetUint64(balances[p.Wallets[0]][spec.Types[0]]), sum)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrStopped is returned by the services that no longer accept writes because they are being stopped
+	ErrStopped = errors.New("service stopped")
+	// ErrReadOnly is returned by the services that do not accept writes because they are read-only
+	ErrReadOnly = errors.New("service is read-only")
+)
+
+// Drainer is implemented by the services that can complete their in-flight writes before being closed
+type Drainer interface {
+	// Drain makes the service reject new writes and waits for the in-flight ones to complete or for the context to expire
+	Drain(ctx context.Context) error
+	// Resume makes the service accept writes again after a Drain
+	Resume()
+}
+
+// ReadOnlySetter is implemented by the services that can be made read-only
+type ReadOnlySetter interface {
+	// SetReadOnly makes the service reject writes, or accept them again
+	SetReadOnly(readOnly bool)
+}
+
+// Closer is implemented by the services that hold resources, like connection pools, to be released on shutdown
+type Closer interface {
+	Close() error
+}
+
+// WriteGate tracks the in-flight writes of a service so that they can be drained on shutdown.
+// The zero value is ready to use.
+type WriteGate struct {
+	mutex    sync.RWMutex
+	draining bool
+	readOnly bool
+	inFlight sync.WaitGroup
+}
+
+// Enter registers a new write. It returns ErrReadOnly if the gate is read-only, ErrStopped if it is draining.
+// Each successful call must be matched by a call to Exit.
+func (g *WriteGate) Enter() error {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	if g.readOnly {
+		return ErrReadOnly
+	}
+	if g.draining {
+		return ErrStopped
+	}
+	g.inFlight.Add(1)
+	return nil
+}
+
+// Exit marks the end of a write registered with Enter
+func (g *WriteGate) Exit() {
+	g.inFlight.Done()
+}
+
+// Drain rejects new writes and waits for the in-flight ones to complete or for the context to expire
+func (g *WriteGate) Drain(ctx context.Context) error {
+	g.mutex.Lock()
+	g.draining = true
+	g.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		g.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "in-flight writes not drained")
+	}
+}
+
+// Resume makes the gate accept writes again after a Drain
+func (g *WriteGate) Resume() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.draining = false
+}
+
+// SetReadOnly makes the gate reject writes, or accept them again.
+// Unlike Drain, it does not wait for the in-flight writes and Resume does not lift it.
+func (g *WriteGate) SetReadOnly(readOnly bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.readOnly = readOnly
+}
@@ -0,0 +1,36 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteGate(t *testing.T) {
+	g := &WriteGate{}
+	assert.NoError(t, g.Enter())
+
+	// the in-flight write prevents the drain from completing
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, g.Drain(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, g.Enter(), ErrStopped)
+
+	// once the in-flight write completes, the drain succeeds
+	drained := make(chan error)
+	go func() { drained <- g.Drain(context.Background()) }()
+	g.Exit()
+	assert.NoError(t, <-drained)
+
+	g.Resume()
+	assert.NoError(t, g.Enter())
+	g.Exit()
+}
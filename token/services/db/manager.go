@@ -7,9 +7,12 @@ SPDX-License-Identifier: Apache-2.0
 package db
 
 import (
+	"context"
+	errors2 "errors"
 	"sync"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/config"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/drivers"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
 	"github.com/pkg/errors"
@@ -21,8 +24,18 @@ type Manager[S any, D any, O DBDriver[D]] struct {
 	cp      ConfigProvider
 	config  Config
 
-	mutex sync.Mutex
-	dbs   map[string]S
+	mutex   sync.Mutex
+	dbs     map[string]S
+	stopped bool
+
+	// store labels the statement metrics, if set
+	store            string
+	statementMetrics *StatementMetrics
+
+	// background is the context of the background re-encryptions, cancelled on Drain
+	background       context.Context
+	cancelBackground context.CancelFunc
+	reEncryptions    sync.WaitGroup
 
 	zero S
 }
@@ -33,6 +46,9 @@ func (m *Manager[S, D, O]) DBByTMSId(id token.TMSID) (S, error) {
 	defer m.mutex.Unlock()
 
 	m.logger.Debugf("get service for [%s]", id)
+	if m.stopped {
+		return m.zero, errors.Wrapf(ErrStopped, "cannot get service for [%s]", id)
+	}
 	c, ok := m.dbs[id.String()]
 	if ok {
 		return c, nil
@@ -46,11 +62,206 @@ func (m *Manager[S, D, O]) DBByTMSId(id token.TMSID) (S, error) {
 		return m.zero, errors.Errorf("no driver found for [%s]", driverName)
 	}
 
+	readOnly, err := m.readOnly(id)
+	if err != nil {
+		return m.zero, errors.WithMessagef(err, "failed to check if [%s] is read-only", id)
+	}
+	encryption, err := m.encryptionOpts(id)
+	if err != nil {
+		return m.zero, errors.WithMessagef(err, "failed to load encryption configuration for [%s]", id)
+	}
+	queryCache, err := m.queryCacheOpts(id)
+	if err != nil {
+		return m.zero, errors.WithMessagef(err, "failed to load query cache configuration for [%s]", id)
+	}
+	pipeline, err := m.commitPipelineOpts(id)
+	if err != nil {
+		return m.zero, errors.WithMessagef(err, "failed to load commit pipeline configuration for [%s]", id)
+	}
 	c, err = d.New(m.cp, id)
 	if err != nil {
 		return m.zero, errors.Wrapf(err, "failed instantiating service driver [%s]", driverName)
 	}
+	if ro, ok := any(c).(ReadOnlySetter); ok && readOnly {
+		m.logger.Infof("service for [%s] is read-only", id)
+		ro.SetReadOnly(true)
+	}
+	if e, ok := any(c).(EncryptionEnabler); ok && encryption != nil {
+		if err := m.enableEncryption(id, e, encryption, readOnly); err != nil {
+			return m.zero, errors.WithMessagef(err, "failed to enable encryption for [%s]", id)
+		}
+	}
+	if m.statementMetrics != nil && !SetStatementObserver(c, m.statementMetrics.Observer(m.store, id)) {
+		m.logger.Debugf("service for [%s] does not report its interrupted statements", id)
+	}
+	if qc, ok := any(c).(QueryCacheEnabler); ok && queryCache != nil {
+		m.logger.Infof("service for [%s] caches query results [%+v]", id, *queryCache)
+		qc.EnableQueryCache(*queryCache)
+	}
+	if cp, ok := any(c).(CommitPipelineEnabler); ok && pipeline != nil && pipeline.Enabled {
+		m.logger.Infof("service for [%s] applies its writes through a commit pipeline [%+v]", id, *pipeline)
+		cp.EnableCommitPipeline(*pipeline)
+	}
 	m.dbs[id.String()] = c
 
 	return c, nil
 }
+
+// EnableStatementMetrics makes the services opened afterwards count their interrupted statements in the passed metrics,
+// labelled with the passed store name
+func (m *Manager[S, D, O]) EnableStatementMetrics(store string, metrics *StatementMetrics) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.store = store
+	m.statementMetrics = metrics
+}
+
+// readOnly returns true if the TMS with the passed id is configured to be read-only
+func (m *Manager[S, D, O]) readOnly(id token.TMSID) (bool, error) {
+	c, err := config.NewService(m.cp).ConfigurationFor(id.Network, id.Channel, id.Namespace)
+	if err != nil {
+		return false, err
+	}
+	return c.GetBool(token.ReadOnlyConfigKey), nil
+}
+
+// queryCacheOpts returns the query cache configuration of the TMS with the passed id, or nil if there is none
+func (m *Manager[S, D, O]) queryCacheOpts(id token.TMSID) (*QueryCacheOpts, error) {
+	c, err := config.NewService(m.cp).ConfigurationFor(id.Network, id.Channel, id.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if !c.IsSet(QueryCacheConfigKey) {
+		return nil, nil
+	}
+	opts := &QueryCacheOpts{}
+	if err := c.UnmarshalKey(QueryCacheConfigKey, opts); err != nil {
+		return nil, errors.Wrapf(err, "invalid config for key [%s]", QueryCacheConfigKey)
+	}
+	return opts, nil
+}
+
+// commitPipelineOpts returns the commit pipeline configuration of the TMS with the passed id, or nil if there is none
+func (m *Manager[S, D, O]) commitPipelineOpts(id token.TMSID) (*CommitPipelineOpts, error) {
+	c, err := config.NewService(m.cp).ConfigurationFor(id.Network, id.Channel, id.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if !c.IsSet(CommitPipelineConfigKey) {
+		return nil, nil
+	}
+	opts := &CommitPipelineOpts{}
+	if err := c.UnmarshalKey(CommitPipelineConfigKey, opts); err != nil {
+		return nil, errors.Wrapf(err, "invalid config for key [%s]", CommitPipelineConfigKey)
+	}
+	return opts, nil
+}
+
+// encryptionOpts returns the column encryption configuration of the TMS with the passed id, or nil if there is none
+func (m *Manager[S, D, O]) encryptionOpts(id token.TMSID) (*EncryptionOpts, error) {
+	c, err := config.NewService(m.cp).ConfigurationFor(id.Network, id.Channel, id.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if !c.IsSet(EncryptionConfigKey) {
+		return nil, nil
+	}
+	opts := &EncryptionOpts{}
+	if err := c.UnmarshalKey(EncryptionConfigKey, opts); err != nil {
+		return nil, errors.Wrapf(err, "invalid config for key [%s]", EncryptionConfigKey)
+	}
+	return opts, nil
+}
+
+// enableEncryption makes the passed service encrypt its sensitive columns with the keys of the TMS with the passed id,
+// and, unless the service is read-only, re-encrypts in background the values stored in clear or with a retired key
+func (m *Manager[S, D, O]) enableEncryption(id token.TMSID, e EncryptionEnabler, opts *EncryptionOpts, readOnly bool) error {
+	cipher, err := LoadColumnCipher(id.String(), opts)
+	if err != nil {
+		return err
+	}
+	if err := e.EnableEncryption(cipher); err != nil {
+		return err
+	}
+	m.logger.Infof("service for [%s] encrypts sensitive columns with key [%s]", id, opts.ActiveKey)
+	if readOnly {
+		return nil
+	}
+	m.reEncryptions.Add(1)
+	go func() {
+		defer m.reEncryptions.Done()
+		n, err := e.ReEncrypt(m.background, opts.GetReEncryptBatchSize())
+		if err != nil {
+			m.logger.Errorf("re-encryption of [%s] interrupted after [%d] rows, it resumes at the next start: [%s]", id, n, err)
+			return
+		}
+		m.logger.Infof("re-encryption of [%s] completed, [%d] rows re-encrypted with key [%s]", id, n, opts.ActiveKey)
+	}()
+	return nil
+}
+
+// Start makes the databases accept writes again after a Drain.
+// A stopped manager cannot be started again because its connection pools are closed.
+func (m *Manager[S, D, O]) Start(context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stopped {
+		return ErrStopped
+	}
+	for _, c := range m.dbs {
+		if d, ok := any(c).(Drainer); ok {
+			d.Resume()
+		}
+	}
+	return nil
+}
+
+// Drain makes the databases reject new writes and waits for the in-flight ones to complete or for the context to expire
+func (m *Manager[S, D, O]) Drain(ctx context.Context) error {
+	m.cancelBackground()
+	m.reEncryptions.Wait()
+
+	// the lock is not held while draining, in-flight writes might need to get other databases from the manager
+	m.mutex.Lock()
+	dbs := make(map[string]S, len(m.dbs))
+	for id, c := range m.dbs {
+		dbs[id] = c
+	}
+	m.mutex.Unlock()
+
+	var errs []error
+	for id, c := range dbs {
+		if d, ok := any(c).(Drainer); ok {
+			m.logger.Debugf("draining service for [%s]", id)
+			if err := d.Drain(ctx); err != nil {
+				errs = append(errs, errors.WithMessagef(err, "failed draining service for [%s]", id))
+			}
+		}
+	}
+	return errors2.Join(errs...)
+}
+
+// Stop drains the databases and closes them.
+// Afterwards, no database can be obtained from the manager anymore.
+// Databases opened on the same data source share the connection pool, therefore,
+// when managers share data sources, all of them should be drained before any is stopped.
+func (m *Manager[S, D, O]) Stop(ctx context.Context) error {
+	drainErr := m.Drain(ctx)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stopped = true
+	errs := []error{drainErr}
+	for id, c := range m.dbs {
+		if d, ok := any(c).(Closer); ok {
+			m.logger.Debugf("closing service for [%s]", id)
+			if err := d.Close(); err != nil {
+				errs = append(errs, errors.WithMessagef(err, "failed closing service for [%s]", id))
+			}
+		}
+	}
+	m.dbs = map[string]S{}
+	return errors2.Join(errs...)
+}
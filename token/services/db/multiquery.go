@@ -0,0 +1,120 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"fmt"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+// TransactionQuerier is implemented by the databases storing transaction records, like the ttxdb and the auditdb
+type TransactionQuerier interface {
+	Transactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error)
+}
+
+// MultiQuery executes the same transaction query across multiple databases,
+// like the owner ttxdb and the auditor auditdb of a node playing both roles,
+// and merges their results.
+type MultiQuery struct {
+	dbs []TransactionQuerier
+}
+
+// NewMultiQuery returns a MultiQuery over the passed databases. Nil databases are skipped.
+// When the same record is stored in multiple databases, the one of the database passed first is returned.
+// Identical records stored in the same database, like two equal payments of a transaction, are all returned.
+func NewMultiQuery(dbs ...TransactionQuerier) *MultiQuery {
+	m := &MultiQuery{}
+	for _, db := range dbs {
+		if db != nil {
+			m.dbs = append(m.dbs, db)
+		}
+	}
+	return m
+}
+
+// Transactions returns the records matching the passed parameters in all the databases,
+// ordered by timestamp and without duplicates.
+func (m *MultiQuery) Transactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error) {
+	it := &mergedTransactionIterator{counts: map[string][]int{}, returned: map[string]int{}}
+	for i, db := range m.dbs {
+		dbIt, err := db.Transactions(params)
+		if err != nil {
+			it.Close()
+			return nil, errors.WithMessagef(err, "failed to query transactions from db [%d]", i)
+		}
+		it.its = append(it.its, dbIt)
+		it.heads = append(it.heads, nil)
+	}
+	return it, nil
+}
+
+// mergedTransactionIterator merges iterators each returning records ordered by timestamp.
+// A record is a duplicate only if another database has already returned as many records with its key.
+type mergedTransactionIterator struct {
+	its   []driver.TransactionIterator
+	heads []*driver.TransactionRecord
+	// counts is the number of records read from each database, by key
+	counts map[string][]int
+	// returned is the number of records returned, by key
+	returned map[string]int
+}
+
+func (it *mergedTransactionIterator) Next() (*driver.TransactionRecord, error) {
+	for {
+		next := -1
+		for i := range it.its {
+			if it.heads[i] == nil && it.its[i] != nil {
+				r, err := it.its[i].Next()
+				if err != nil {
+					return nil, errors.WithMessagef(err, "failed to get next transaction record from db [%d]", i)
+				}
+				if r == nil {
+					it.its[i].Close()
+					it.its[i] = nil
+					continue
+				}
+				it.heads[i] = r
+			}
+			if it.heads[i] != nil && (next == -1 || it.heads[i].Timestamp.Before(it.heads[next].Timestamp)) {
+				next = i
+			}
+		}
+		if next == -1 {
+			return nil, nil
+		}
+		r := it.heads[next]
+		it.heads[next] = nil
+		key := recordKey(r)
+		counts, ok := it.counts[key]
+		if !ok {
+			counts = make([]int, len(it.its))
+			it.counts[key] = counts
+		}
+		counts[next]++
+		if counts[next] <= it.returned[key] {
+			continue
+		}
+		it.returned[key]++
+		return r, nil
+	}
+}
+
+func (it *mergedTransactionIterator) Close() {
+	for i, dbIt := range it.its {
+		if dbIt != nil {
+			dbIt.Close()
+			it.its[i] = nil
+		}
+	}
+}
+
+// recordKey identifies a movement of a transaction, independently of the database storing it
+func recordKey(r *driver.TransactionRecord) string {
+	return fmt.Sprintf("%s|%d|%s|%s|%s|%s", r.TxID, r.ActionType, r.SenderEID, r.RecipientEID, r.TokenType, r.Amount)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/common/utils/collections"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+type transactionDB []*driver.TransactionRecord
+
+func (db transactionDB) Transactions(driver.QueryTransactionsParams) (driver.TransactionIterator, error) {
+	return collections.NewSliceIterator[*driver.TransactionRecord](db), nil
+}
+
+func TestMultiQuery(t *testing.T) {
+	now := time.Now()
+	record := func(txID string, offset int, status driver.TxStatus) *driver.TransactionRecord {
+		return &driver.TransactionRecord{
+			TxID:         txID,
+			ActionType:   driver.Transfer,
+			SenderEID:    "alice",
+			RecipientEID: "bob",
+			TokenType:    "USD",
+			Amount:       big.NewInt(10),
+			Timestamp:    now.Add(time.Duration(offset) * time.Second),
+			Status:       status,
+		}
+	}
+	owner := transactionDB{record("tx1", 1, driver.Confirmed), record("tx3", 3, driver.Pending)}
+	auditor := transactionDB{record("tx1", 1, driver.Pending), record("tx2", 2, driver.Confirmed), record("tx3", 3, driver.Confirmed)}
+
+	it, err := NewMultiQuery(owner, nil, auditor).Transactions(driver.QueryTransactionsParams{})
+	assert.NoError(t, err)
+	records, err := collections.ReadAll(it)
+	assert.NoError(t, err)
+
+	// records are ordered by timestamp, and duplicates are taken from the first database
+	assert.Len(t, records, 3)
+	assert.Equal(t, "tx1", records[0].TxID)
+	assert.Equal(t, driver.Confirmed, records[0].Status)
+	assert.Equal(t, "tx2", records[1].TxID)
+	assert.Equal(t, "tx3", records[2].TxID)
+	assert.Equal(t, driver.Pending, records[2].Status)
+
+	// records of the same transaction with different movements are kept
+	auditor = append(auditor, &driver.TransactionRecord{TxID: "tx3", ActionType: driver.Transfer, SenderEID: "alice", RecipientEID: "alice", TokenType: "USD", Amount: big.NewInt(5), Timestamp: now.Add(3 * time.Second)})
+	it, err = NewMultiQuery(owner, auditor).Transactions(driver.QueryTransactionsParams{})
+	assert.NoError(t, err)
+	records, err = collections.ReadAll(it)
+	assert.NoError(t, err)
+	assert.Len(t, records, 4)
+
+	// identical records of the same database are kept, only those of other databases are dropped
+	owner = transactionDB{record("tx1", 1, driver.Confirmed), record("tx1", 1, driver.Confirmed)}
+	auditor = transactionDB{record("tx1", 1, driver.Pending), record("tx1", 1, driver.Pending), record("tx1", 1, driver.Pending)}
+	it, err = NewMultiQuery(owner, auditor).Transactions(driver.QueryTransactionsParams{})
+	assert.NoError(t, err)
+	records, err = collections.ReadAll(it)
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, []driver.TxStatus{driver.Confirmed, driver.Confirmed, driver.Pending}, []driver.TxStatus{records[0].Status, records[1].Status, records[2].Status})
+}
@@ -0,0 +1,184 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// QueryCacheConfigKey is the key, in the TMS configuration, of the query cache configuration
+	QueryCacheConfigKey = "queryCache"
+
+	defaultQueryCacheSize = 1000
+)
+
+// MethodCacheOpts configures the caching of the results of a read method
+type MethodCacheOpts struct {
+	// Enabled enables the caching of the results of the method
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Size is the maximum number of cached results. The default is 1000.
+	Size int `yaml:"size,omitempty"`
+	// TTL is how long a result is served from the cache.
+	// Zero means until a write invalidates it. A positive TTL bounds the staleness due to writes of other replicas sharing the database.
+	TTL time.Duration `yaml:"ttl,omitempty"`
+}
+
+// GetSize returns the maximum number of cached results
+func (o MethodCacheOpts) GetSize() int {
+	if o.Size > 0 {
+		return o.Size
+	}
+	return defaultQueryCacheSize
+}
+
+// QueryCacheOpts configures, for each cacheable read method, the query cache of a database
+type QueryCacheOpts struct {
+	// Balance configures the cache of the token db Balance method
+	Balance MethodCacheOpts `yaml:"balance,omitempty"`
+	// PublicParams configures the cache of the token db PublicParams method
+	PublicParams MethodCacheOpts `yaml:"publicParams,omitempty"`
+	// GetStatus configures the cache of the transaction db GetStatus method
+	GetStatus MethodCacheOpts `yaml:"getStatus,omitempty"`
+	// GetTokenRequest configures the cache of the transaction db GetTokenRequest method
+	GetTokenRequest MethodCacheOpts `yaml:"getTokenRequest,omitempty"`
+}
+
+// QueryCacheEnabler is implemented by the services whose read methods can be cached
+type QueryCacheEnabler interface {
+	// EnableQueryCache makes the service cache the results of the read methods enabled in the passed options
+	EnableQueryCache(opts QueryCacheOpts)
+}
+
+// QueryCache caches the results of a read method, indexed by the method arguments.
+// A nil QueryCache caches nothing, therefore, a disabled method needs no special handling.
+type QueryCache[K comparable, V any] struct {
+	mutex   sync.Mutex
+	entries map[K]queryCacheEntry[V]
+	size    int
+	ttl     time.Duration
+	// generation changes at every invalidation, results read before an invalidation are not cached
+	generation uint64
+}
+
+type queryCacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// NewQueryCache returns a QueryCache configured with the passed options, or nil if the method is not enabled
+func NewQueryCache[K comparable, V any](opts MethodCacheOpts) *QueryCache[K, V] {
+	if !opts.Enabled {
+		return nil
+	}
+	return &QueryCache[K, V]{
+		entries: map[K]queryCacheEntry[V]{},
+		size:    opts.GetSize(),
+		ttl:     opts.TTL,
+	}
+}
+
+// Load returns the cached result for the passed key or, if missing, the result of the passed function.
+// The result of the function is cached if cacheable returns true for it and no invalidation happened meanwhile.
+func (c *QueryCache[K, V]) Load(key K, load func() (V, error), cacheable func(V) bool) (V, error) {
+	if c == nil {
+		return load()
+	}
+	c.mutex.Lock()
+	e, ok := c.entries[key]
+	if ok && (c.ttl <= 0 || time.Now().Before(e.expiresAt)) {
+		c.mutex.Unlock()
+		return e.value, nil
+	}
+	generation := c.generation
+	c.mutex.Unlock()
+
+	v, err := load()
+	if err != nil || !cacheable(v) {
+		return v, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.generation == generation {
+		c.add(key, v)
+	}
+	return v, nil
+}
+
+// LoadAll returns the results for the passed keys, as Load does, loading all the missing ones with a single invocation of the passed function.
+// Keys for which the function returns no result are missing in the returned map.
+func (c *QueryCache[K, V]) LoadAll(keys []K, load func(missing []K) (map[K]V, error), cacheable func(V) bool) (map[K]V, error) {
+	if c == nil {
+		return load(keys)
+	}
+	res := make(map[K]V, len(keys))
+	missing := make([]K, 0, len(keys))
+	c.mutex.Lock()
+	now := time.Now()
+	for _, key := range keys {
+		if e, ok := c.entries[key]; ok && (c.ttl <= 0 || now.Before(e.expiresAt)) {
+			res[key] = e.value
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	generation := c.generation
+	c.mutex.Unlock()
+	if len(missing) == 0 {
+		return res, nil
+	}
+
+	loaded, err := load(missing)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, v := range loaded {
+		res[key] = v
+		if c.generation == generation && cacheable(v) {
+			c.add(key, v)
+		}
+	}
+	return res, nil
+}
+
+func (c *QueryCache[K, V]) add(key K, v V) {
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		// evict an arbitrary entry
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[key] = queryCacheEntry[V]{value: v, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// Invalidate removes the cached result for the passed key
+func (c *QueryCache[K, V]) Invalidate(key K) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	delete(c.entries, key)
+}
+
+// InvalidateAll removes all the cached results
+func (c *QueryCache[K, V]) InvalidateAll() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	c.entries = map[K]queryCacheEntry[V]{}
+}
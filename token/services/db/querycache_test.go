@@ -0,0 +1,90 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryCache(t *testing.T) {
+	loads := 0
+	load := func(v int) func() (int, error) {
+		return func() (int, error) {
+			loads++
+			return v, nil
+		}
+	}
+	always := func(int) bool { return true }
+
+	// a disabled cache always loads
+	var disabled *QueryCache[string, int]
+	assert.Nil(t, NewQueryCache[string, int](MethodCacheOpts{}))
+	v, err := disabled.Load("a", load(1), always)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+	disabled.Invalidate("a")
+	disabled.InvalidateAll()
+
+	c := NewQueryCache[string, int](MethodCacheOpts{Enabled: true, Size: 2})
+	loads = 0
+	v, _ = c.Load("a", load(1), always)
+	assert.Equal(t, 1, v)
+	v, _ = c.Load("a", load(2), always)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 1, loads)
+
+	// invalidation
+	c.Invalidate("a")
+	v, _ = c.Load("a", load(2), always)
+	assert.Equal(t, 2, v)
+	c.InvalidateAll()
+	v, _ = c.Load("a", load(3), always)
+	assert.Equal(t, 3, v)
+
+	// results that are not cacheable are loaded every time
+	loads = 0
+	c.Load("b", load(0), func(v int) bool { return v != 0 })
+	c.Load("b", load(0), func(v int) bool { return v != 0 })
+	assert.Equal(t, 2, loads)
+
+	// a result loaded across an invalidation is not cached
+	v, _ = c.Load("c", func() (int, error) {
+		c.Invalidate("c")
+		return 4, nil
+	}, always)
+	assert.Equal(t, 4, v)
+	v, _ = c.Load("c", load(5), always)
+	assert.Equal(t, 5, v)
+
+	// the size is bounded
+	c.Load("d", load(6), always)
+	assert.Len(t, c.entries, 2)
+
+	// expired results are loaded again
+	c = NewQueryCache[string, int](MethodCacheOpts{Enabled: true, TTL: 10 * time.Millisecond})
+	c.Load("a", load(1), always)
+	time.Sleep(20 * time.Millisecond)
+	v, _ = c.Load("a", load(2), always)
+	assert.Equal(t, 2, v)
+
+	// batch loads only load the missing keys
+	c = NewQueryCache[string, int](MethodCacheOpts{Enabled: true})
+	c.Load("a", load(1), always)
+	var requested []string
+	res, err := c.LoadAll([]string{"a", "b", "c"}, func(missing []string) (map[string]int, error) {
+		requested = missing
+		return map[string]int{"b": 2}, nil
+	}, always)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, requested)
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, res)
+	v, _ = c.Load("b", load(3), always)
+	assert.Equal(t, 2, v)
+}
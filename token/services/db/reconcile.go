@@ -0,0 +1,165 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"fmt"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/common/utils/collections"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+// FindingType is the type of divergence between the transaction records of an owner and those of an auditor
+type FindingType string
+
+const (
+	// MissingInAuditor is reported for an owner record with no matching auditor record
+	MissingInAuditor FindingType = "missing_in_auditor"
+	// MissingInOwner is reported for an auditor record of a transaction the owner has no records of
+	MissingInOwner FindingType = "missing_in_owner"
+	// AmountMismatch is reported when matching records have different amounts
+	AmountMismatch FindingType = "amount_mismatch"
+	// StatusMismatch is reported, once per transaction, when the owner and the auditor disagree on its status
+	StatusMismatch FindingType = "status_mismatch"
+)
+
+// Finding is a divergence between the transaction records of an owner and those of an auditor
+type Finding struct {
+	Type FindingType `json:"type"`
+	TxID string      `json:"tx_id"`
+	// Owner is the record of the owner, nil if missing
+	Owner *driver.TransactionRecord `json:"owner,omitempty"`
+	// Auditor is the record of the auditor, nil if missing
+	Auditor *driver.TransactionRecord `json:"auditor,omitempty"`
+}
+
+func (f *Finding) String() string {
+	switch f.Type {
+	case AmountMismatch:
+		return fmt.Sprintf("[%s] %s: owner [%s], auditor [%s]", f.TxID, f.Type, f.Owner.Amount, f.Auditor.Amount)
+	case StatusMismatch:
+		return fmt.Sprintf("[%s] %s: owner [%s], auditor [%s]", f.TxID, f.Type, driver.TxStatusMessage[f.Owner.Status], driver.TxStatusMessage[f.Auditor.Status])
+	default:
+		return fmt.Sprintf("[%s] %s", f.TxID, f.Type)
+	}
+}
+
+// ReconcileOpts configures the reconciliation of the transaction records of an owner and an auditor
+type ReconcileOpts struct {
+	// Params selects the records to compare in both databases
+	Params driver.QueryTransactionsParams
+	// OwnerEnrollmentIDs restricts the MissingInOwner findings to the transactions involving these enrollment IDs,
+	// the auditor sees the transactions of every owner. If empty, all the transactions missing in the owner records are reported.
+	OwnerEnrollmentIDs []string
+	// AuditorEnrollmentID maps the enrollment IDs of the owner to those stored by the auditor,
+	// for instance to their pseudonyms. If nil, enrollment IDs are compared as they are.
+	AuditorEnrollmentID func(eID string) string
+}
+
+// TransactionRecords are exported transaction records, for instance an export of the auditdb.
+// The query parameters are not applied, the records are returned as they are.
+type TransactionRecords []*driver.TransactionRecord
+
+// Transactions returns an iterator over the records
+func (r TransactionRecords) Transactions(driver.QueryTransactionsParams) (driver.TransactionIterator, error) {
+	return collections.NewSliceIterator[*driver.TransactionRecord](r), nil
+}
+
+// movementKey identifies a movement within a transaction, independently of its amount and status
+type movementKey struct {
+	actionType   driver.ActionType
+	senderEID    string
+	recipientEID string
+	tokenType    string
+}
+
+// Reconcile compares the transaction records of an owner, as stored in its ttxdb, with those of an auditor,
+// as stored in its auditdb or exported, and returns the divergences.
+// Records are matched by transaction id, action type, sender, recipient, and token type.
+// The owner might not see all the records of a transaction, therefore, for the transactions both know,
+// only the owner records are checked.
+// Status mismatches might be transient: the owner and the auditor learn about the finality of a transaction independently.
+func Reconcile(owner, auditor TransactionQuerier, opts ReconcileOpts) ([]*Finding, error) {
+	auditorEID := opts.AuditorEnrollmentID
+	if auditorEID == nil {
+		auditorEID = func(eID string) string { return eID }
+	}
+	ownerTxIDs, ownerRecords, err := loadRecords(owner, opts.Params)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to load owner records")
+	}
+	auditorTxIDs, auditorRecords, err := loadRecords(auditor, opts.Params)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to load auditor records")
+	}
+
+	var findings []*Finding
+	for _, txID := range ownerTxIDs {
+		audited := map[movementKey]*driver.TransactionRecord{}
+		for _, r := range auditorRecords[txID] {
+			audited[movementKey{r.ActionType, r.SenderEID, r.RecipientEID, r.TokenType}] = r
+		}
+		statusChecked := false
+		for _, o := range ownerRecords[txID] {
+			a, ok := audited[movementKey{o.ActionType, auditorEID(o.SenderEID), auditorEID(o.RecipientEID), o.TokenType}]
+			if !ok {
+				findings = append(findings, &Finding{Type: MissingInAuditor, TxID: txID, Owner: o})
+				continue
+			}
+			if o.Amount.Cmp(a.Amount) != 0 {
+				findings = append(findings, &Finding{Type: AmountMismatch, TxID: txID, Owner: o, Auditor: a})
+			}
+			if !statusChecked && o.Status != a.Status {
+				findings = append(findings, &Finding{Type: StatusMismatch, TxID: txID, Owner: o, Auditor: a})
+			}
+			statusChecked = true
+		}
+	}
+
+	involved := map[string]struct{}{}
+	for _, eID := range opts.OwnerEnrollmentIDs {
+		involved[auditorEID(eID)] = struct{}{}
+	}
+	for _, txID := range auditorTxIDs {
+		if _, ok := ownerRecords[txID]; ok {
+			continue
+		}
+		for _, a := range auditorRecords[txID] {
+			_, sender := involved[a.SenderEID]
+			_, recipient := involved[a.RecipientEID]
+			if len(involved) == 0 || sender || recipient {
+				findings = append(findings, &Finding{Type: MissingInOwner, TxID: txID, Auditor: a})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// loadRecords returns the records matching the passed params grouped by transaction id, and the transaction ids in the order they are found
+func loadRecords(db TransactionQuerier, params driver.QueryTransactionsParams) ([]string, map[string][]*driver.TransactionRecord, error) {
+	it, err := db.Transactions(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer it.Close()
+	var txIDs []string
+	records := map[string][]*driver.TransactionRecord{}
+	for {
+		r, err := it.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if r == nil {
+			return txIDs, records, nil
+		}
+		if _, ok := records[r.TxID]; !ok {
+			txIDs = append(txIDs, r.TxID)
+		}
+		records[r.TxID] = append(records[r.TxID], r)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcile(t *testing.T) {
+	record := func(txID, sender, recipient string, amount int64, status driver.TxStatus) *driver.TransactionRecord {
+		return &driver.TransactionRecord{
+			TxID:         txID,
+			ActionType:   driver.Transfer,
+			SenderEID:    sender,
+			RecipientEID: recipient,
+			TokenType:    "USD",
+			Amount:       big.NewInt(amount),
+			Status:       status,
+		}
+	}
+	owner := transactionDB{
+		record("tx1", "alice", "bob", 10, driver.Confirmed),
+		record("tx2", "alice", "bob", 10, driver.Confirmed),
+		record("tx3", "alice", "bob", 10, driver.Confirmed),
+		record("tx4", "alice", "bob", 10, driver.Confirmed),
+		record("tx4", "alice", "alice", 5, driver.Confirmed),
+	}
+	auditor := TransactionRecords{
+		record("tx1", "ALICE", "BOB", 10, driver.Confirmed),
+		record("tx2", "ALICE", "BOB", 20, driver.Confirmed),
+		record("tx3", "ALICE", "BOB", 10, driver.Pending),
+		record("tx4", "ALICE", "BOB", 10, driver.Confirmed),
+		record("tx5", "ALICE", "BOB", 10, driver.Confirmed),
+		record("tx6", "CHARLIE", "DAVE", 10, driver.Confirmed),
+	}
+
+	findings, err := Reconcile(owner, auditor, ReconcileOpts{
+		OwnerEnrollmentIDs:  []string{"alice"},
+		AuditorEnrollmentID: strings.ToUpper,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, findings, 4)
+	assert.Equal(t, AmountMismatch, findings[0].Type)
+	assert.Equal(t, "tx2", findings[0].TxID)
+	assert.Equal(t, "[tx2] amount_mismatch: owner [10], auditor [20]", findings[0].String())
+	assert.Equal(t, StatusMismatch, findings[1].Type)
+	assert.Equal(t, "tx3", findings[1].TxID)
+	assert.Equal(t, MissingInAuditor, findings[2].Type)
+	assert.Equal(t, "tx4", findings[2].TxID)
+	assert.Equal(t, "alice", findings[2].Owner.RecipientEID)
+	assert.Nil(t, findings[2].Auditor)
+	assert.Equal(t, MissingInOwner, findings[3].Type)
+	assert.Equal(t, "tx5", findings[3].TxID)
+
+	// without restrictions, the transactions of other owners are reported too
+	findings, err = Reconcile(owner, auditor, ReconcileOpts{AuditorEnrollmentID: strings.ToUpper})
+	assert.NoError(t, err)
+	assert.Len(t, findings, 5)
+	assert.Equal(t, "tx6", findings[4].TxID)
+
+	// without the enrollment ID mapping, nothing matches
+	findings, err = Reconcile(owner, auditor, ReconcileOpts{})
+	assert.NoError(t, err)
+	assert.Len(t, findings, 7)
+}
@@ -0,0 +1,21 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+// TableSizes returns the disk usage of the tables of the passed database driver, if it supports it
+func TableSizes(d any) ([]driver.TableSize, error) {
+	s, ok := d.(driver.SizedDB)
+	if !ok {
+		return nil, errors.Wrapf(driver.ErrTableSizesNotSupported, "database [%T]", d)
+	}
+	return s.TableSizes()
+}
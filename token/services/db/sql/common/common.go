@@ -18,7 +18,7 @@ const (
 	ResultRowsLabel tracing.LabelName = "result_rows"
 )
 
-func QueryUnique[T any](db *sql.DB, query string, args ...any) (T, error) {
+func QueryUnique[T any](db *DB, query string, args ...any) (T, error) {
 	logger.Debug(query, args)
 	row := db.QueryRow(query, args...)
 	var result T
@@ -0,0 +1,441 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/common"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/postgres"
+	"github.com/pkg/errors"
+)
+
+const (
+	Oracle    common.SQLDriverType = "oracle"
+	SQLServer common.SQLDriverType = "sqlserver"
+)
+
+// Dialect rewrites the queries and the schemas of this package, written for Postgres and SQLite,
+// for a database that does not understand them as they are.
+// A Dialect is applied by the DB running the statements of a store, see NewDBOpts.Dialect,
+// and by the TokenInterpreter it returns, for the conditions and the upserts.
+// The database must be opened with OpenDialectDB, that converts the values read from the database.
+type Dialect struct {
+	driver common.SQLDriverType
+	// placeholder returns the i-th positional placeholder, starting from 1
+	placeholder func(i int) string
+	// types maps the column types used by the schemas to the types of this dialect
+	types map[string]string
+	// keyBinaryType is the binary type for primary key columns, where large objects are not allowed
+	keyBinaryType string
+	// reserved quotes the column names that are reserved words
+	reserved []reservedWord
+	// emptyIsNull is true if the database stores the empty string as NULL
+	emptyIsNull bool
+	limit       func(query string, n string) string
+	schema      func(statements []ddlStatement) string
+	// upsert returns the statement inserting a row, or updating the row with the same keys
+	upsert func(table string, keys []string, columns []string) string
+}
+
+// OracleDialect returns the dialect for Oracle Database 19c or later.
+// Oracle stores the empty string as NULL, therefore text and binary columns are nullable and NULL text is read back as the empty string.
+func OracleDialect() *Dialect {
+	return &Dialect{
+		driver:      Oracle,
+		placeholder: func(i int) string { return fmt.Sprintf(":%d", i) },
+		types: map[string]string{
+			"TEXT":      "VARCHAR2(1000)",
+			"BYTEA":     "BLOB",
+			"BOOL":      "NUMBER(1)",
+			"BIGINT":    "NUMBER(19)",
+			"TIMESTAMP": "TIMESTAMP",
+		},
+		keyBinaryType: "RAW(2000)",
+		reserved:      []reservedWord{newReservedWord("raw", `"raw"`)},
+		emptyIsNull:   true,
+		limit: func(query string, n string) string {
+			return query + " FETCH FIRST " + n + " ROWS ONLY"
+		},
+		schema: oracleSchema,
+		upsert: func(table string, keys []string, columns []string) string {
+			return mergeStatement(table, keys, columns, " FROM dual")
+		},
+	}
+}
+
+// SQLServerDialect returns the dialect for Microsoft SQL Server 2016 or later
+func SQLServerDialect() *Dialect {
+	return &Dialect{
+		driver:      SQLServer,
+		placeholder: func(i int) string { return fmt.Sprintf("@p%d", i) },
+		types: map[string]string{
+			"TEXT":      "NVARCHAR(450)",
+			"BYTEA":     "VARBINARY(MAX)",
+			"BOOL":      "BIT",
+			"BIGINT":    "BIGINT",
+			"TIMESTAMP": "DATETIME2",
+		},
+		keyBinaryType: "VARBINARY(900)",
+		reserved:      []reservedWord{newReservedWord("identity", "[identity]")},
+		limit: func(query string, n string) string {
+			return selectPrefix.ReplaceAllString(query, "${1}TOP "+n+" ")
+		},
+		schema: sqlServerSchema,
+		upsert: func(table string, keys []string, columns []string) string {
+			return mergeStatement(table, keys, columns, "")
+		},
+	}
+}
+
+// DialectOf returns the dialect of the passed driver, nil for Postgres and SQLite, whose statements need no rewriting
+func DialectOf(driver common.SQLDriverType) *Dialect {
+	switch driver {
+	case Oracle:
+		return OracleDialect()
+	case SQLServer:
+		return SQLServerDialect()
+	default:
+		return nil
+	}
+}
+
+// Driver returns the driver type of this dialect
+func (d *Dialect) Driver() common.SQLDriverType {
+	return d.driver
+}
+
+// Interpreter returns the condition interpreter for this dialect.
+// Tuples are expanded into disjunctions, because not all dialects support row values and
+// Oracle limits the number of elements of an IN list.
+func (d *Dialect) Interpreter() common.Interpreter {
+	return postgres.NewInterpreter()
+}
+
+// TokenInterpreter returns the token interpreter for this dialect.
+// It renders the comparisons with the empty string and the upserts as this dialect requires.
+func (d *Dialect) TokenInterpreter() TokenInterpreter {
+	return &tokenInterpreter{Interpreter: d.Interpreter(), emptyIsNull: d.emptyIsNull, upsert: d.upsert}
+}
+
+// QueryPlanner returns the query planner for this dialect
+func (d *Dialect) QueryPlanner() QueryPlanner {
+	if d.driver == Oracle {
+		return NewOracleQueryPlanner()
+	}
+	return NewSQLServerQueryPlanner()
+}
+
+// SizeReporter returns the size reporter for this dialect
+func (d *Dialect) SizeReporter() SizeReporter {
+	if d.driver == Oracle {
+		return NewOracleSizeReporter()
+	}
+	return NewSQLServerSizeReporter()
+}
+
+var (
+	commentLine  = regexp.MustCompile(`(?m)^\s*--.*$`)
+	placeholder  = regexp.MustCompile(`\$(\d+)`)
+	boolLiteral  = regexp.MustCompile(`(?i)(=|!=|<>|DEFAULT)\s*(true|false)\b`)
+	limitClause  = regexp.MustCompile(`(?i)\s+LIMIT\s+(\d+)$`)
+	selectPrefix = regexp.MustCompile(`(?i)^(SELECT\s+(?:DISTINCT\s+)?)`)
+	emptyCmp     = regexp.MustCompile(`\s*(=|!=|<>)\s*''`)
+	paramCmp     = regexp.MustCompile(`([\w.]+)\s*(=|!=|<>)\s*\$(\d+)\b`)
+	whereKeyword = regexp.MustCompile(`(?i)\sWHERE\s`)
+	createTable  = regexp.MustCompile(`(?is)^CREATE\s+TABLE\s+IF\s+NOT\s+EXISTS\s+(\S+)\s*(.*)$`)
+	createIndex  = regexp.MustCompile(`(?is)^CREATE\s+INDEX\s+IF\s+NOT\s+EXISTS\s+(\S+)\s+ON\s+(\S+)\s*(.*)$`)
+	keyBinary    = regexp.MustCompile(`\bBYTEA(\s+PRIMARY\s+KEY)`)
+	notNull      = regexp.MustCompile(`\s+NOT NULL\b`)
+	notNullFirst = regexp.MustCompile(`NOT NULL DEFAULT ('[^']*'|[^\s,]+)`)
+	columnTypes  = regexp.MustCompile(`\b(TEXT|BYTEA|BOOL|BIGINT|TIMESTAMP)\b`)
+)
+
+// Statement returns the passed statement and arguments in this dialect, a nil dialect returns them as they are.
+// The comparisons with a bound empty string are rewritten as well, if the database stores the empty string as NULL,
+// because a comparison with NULL never matches.
+func (d *Dialect) Statement(query string, args []any) (string, []any) {
+	if d == nil {
+		return query, args
+	}
+	if d.emptyIsNull && len(args) != 0 {
+		if loc := whereKeyword.FindStringIndex(query); loc != nil {
+			query = query[:loc[0]] + paramCmp.ReplaceAllStringFunc(query[loc[0]:], func(s string) string {
+				m := paramCmp.FindStringSubmatch(s)
+				i, err := strconv.Atoi(m[3])
+				if err != nil || i < 1 || i > len(args) {
+					return s
+				}
+				if v, ok := args[i-1].(string); !ok || len(v) != 0 {
+					return s
+				}
+				// the placeholder is kept, so that the arguments still match the placeholders
+				if m[2] == "=" {
+					return fmt.Sprintf("(%s IS NULL AND $%s IS NULL)", m[1], m[3])
+				}
+				return fmt.Sprintf("(%s IS NOT NULL AND $%s IS NULL)", m[1], m[3])
+			})
+		}
+	}
+	return d.Rewrite(query), d.normalizeArgs(args)
+}
+
+// Rewrite returns the passed statement in this dialect.
+// Statements starting with `CREATE` are treated as schemas and can contain multiple statements separated by semicolons.
+func (d *Dialect) Rewrite(query string) string {
+	q := strings.TrimSpace(commentLine.ReplaceAllString(query, ""))
+	if strings.HasPrefix(strings.ToUpper(q), "CREATE ") {
+		return d.rewriteSchema(q)
+	}
+	if strings.HasPrefix(strings.ToUpper(q), "ALTER TABLE ") {
+		// both Oracle and SQL Server add a column without the COLUMN keyword
+		return strings.Replace(d.rewriteColumns(q), " ADD COLUMN ", " ADD ", 1)
+	}
+	return d.rewriteQuery(q)
+}
+
+func (d *Dialect) rewriteQuery(q string) string {
+	q = strings.TrimSpace(strings.TrimRight(q, "; \t\n"))
+	q = placeholder.ReplaceAllStringFunc(q, func(s string) string {
+		var i int
+		_, _ = fmt.Sscanf(s, "$%d", &i)
+		return d.placeholder(i)
+	})
+	q = rewriteBooleans(q)
+	if d.emptyIsNull {
+		if loc := whereKeyword.FindStringIndex(q); loc != nil {
+			q = q[:loc[0]] + emptyCmp.ReplaceAllStringFunc(q[loc[0]:], func(s string) string {
+				if strings.Contains(s, "!=") || strings.Contains(s, "<>") {
+					return " IS NOT NULL"
+				}
+				return " IS NULL"
+			})
+		}
+	}
+	if m := limitClause.FindStringSubmatchIndex(q); m != nil {
+		q = d.limit(q[:m[0]], q[m[2]:m[3]])
+	}
+	if d.driver == SQLServer && strings.HasPrefix(strings.ToUpper(q), "MERGE ") {
+		// a MERGE must be terminated by a semicolon
+		q += ";"
+	}
+	return d.quoteReserved(q)
+}
+
+// ddlStatement is a statement of a schema with the `IF NOT EXISTS` clause stripped.
+// Name and Table are set only if the statement had the clause.
+type ddlStatement struct {
+	Statement string
+	Index     bool
+	Name      string
+	Table     string
+}
+
+func (d *Dialect) rewriteSchema(schema string) string {
+	var statements []ddlStatement
+	for _, s := range strings.Split(schema, ";") {
+		s = strings.TrimSpace(s)
+		if len(s) == 0 {
+			continue
+		}
+		s = d.rewriteColumns(s)
+		if m := createTable.FindStringSubmatch(s); m != nil {
+			statements = append(statements, ddlStatement{
+				Statement: fmt.Sprintf("CREATE TABLE %s %s", m[1], m[2]),
+				Name:      m[1],
+				Table:     m[1],
+			})
+		} else if m := createIndex.FindStringSubmatch(s); m != nil {
+			statements = append(statements, ddlStatement{
+				Statement: fmt.Sprintf("CREATE INDEX %s ON %s %s", m[1], m[2], m[3]),
+				Index:     true,
+				Name:      m[1],
+				Table:     m[2],
+			})
+		} else {
+			statements = append(statements, ddlStatement{Statement: s})
+		}
+	}
+	return d.schema(statements)
+}
+
+func (d *Dialect) rewriteColumns(s string) string {
+	s = keyBinary.ReplaceAllString(s, d.keyBinaryType+"$1")
+	if d.emptyIsNull {
+		// NOT NULL would reject empty strings and empty byte slices.
+		// Primary key columns are still NOT NULL by definition.
+		lines := strings.Split(s, "\n")
+		for i, line := range lines {
+			if strings.Contains(line, "TEXT") || strings.Contains(line, "BYTEA") {
+				line = strings.Replace(line, " DEFAULT ''", "", 1)
+				lines[i] = notNull.ReplaceAllString(line, "")
+			}
+		}
+		s = strings.Join(lines, "\n")
+		// Oracle wants the default before the constraints
+		s = notNullFirst.ReplaceAllString(s, "DEFAULT $1 NOT NULL")
+	}
+	s = columnTypes.ReplaceAllStringFunc(s, func(t string) string {
+		return d.types[t]
+	})
+	s = rewriteBooleans(s)
+	return d.quoteReserved(s)
+}
+
+type reservedWord struct {
+	re     *regexp.Regexp
+	quoted string
+}
+
+func newReservedWord(word, quoted string) reservedWord {
+	return reservedWord{re: regexp.MustCompile(`([^\w"\[]|^)` + word + `\b`), quoted: "${1}" + quoted}
+}
+
+func (d *Dialect) quoteReserved(q string) string {
+	for _, r := range d.reserved {
+		q = r.re.ReplaceAllString(q, r.quoted)
+	}
+	return q
+}
+
+func rewriteBooleans(q string) string {
+	return boolLiteral.ReplaceAllStringFunc(q, func(s string) string {
+		m := boolLiteral.FindStringSubmatch(s)
+		if strings.EqualFold(m[2], "true") {
+			return m[1] + " 1"
+		}
+		return m[1] + " 0"
+	})
+}
+
+// sqlServerSchema guards each statement with an existence check, in a single batch
+func sqlServerSchema(statements []ddlStatement) string {
+	sb := strings.Builder{}
+	for _, s := range statements {
+		switch {
+		case s.Index:
+			sb.WriteString(fmt.Sprintf("IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'%s' AND object_id = OBJECT_ID(N'%s')) ", s.Name, s.Table))
+		case len(s.Name) > 0:
+			sb.WriteString(fmt.Sprintf("IF OBJECT_ID(N'%s', N'U') IS NULL ", s.Name))
+		}
+		sb.WriteString(s.Statement)
+		sb.WriteString(";\n")
+	}
+	return sb.String()
+}
+
+// oracleSchema runs each statement in a PL/SQL block that ignores the errors raised when the object already exists
+// (ORA-00955 name already used, ORA-01408 column list already indexed)
+func oracleSchema(statements []ddlStatement) string {
+	sb := strings.Builder{}
+	sb.WriteString("BEGIN\n")
+	for _, s := range statements {
+		sb.WriteString("BEGIN EXECUTE IMMEDIATE '")
+		sb.WriteString(strings.ReplaceAll(s.Statement, "'", "''"))
+		sb.WriteString("';")
+		if len(s.Name) > 0 {
+			sb.WriteString(" EXCEPTION WHEN OTHERS THEN IF SQLCODE NOT IN (-955, -1408) THEN RAISE; END IF;")
+		}
+		sb.WriteString(" END;\n")
+	}
+	sb.WriteString("END;")
+	return sb.String()
+}
+
+// normalizeArgs converts the arguments the driver of this dialect cannot bind.
+// The empty strings are bound as NULL, as the database stores them, if the database stores the empty string as NULL.
+// The passed arguments are not modified.
+func (d *Dialect) normalizeArgs(args []any) []any {
+	if (d.driver != Oracle && !d.emptyIsNull) || len(args) == 0 {
+		return args
+	}
+	normalized := slices.Clone(args)
+	for i, arg := range normalized {
+		switch v := arg.(type) {
+		case bool:
+			if d.driver == Oracle {
+				normalized[i] = int64(0)
+				if v {
+					normalized[i] = int64(1)
+				}
+			}
+		case time.Time:
+			if d.driver == Oracle {
+				normalized[i] = v.UTC()
+			}
+		case string:
+			if d.emptyIsNull && len(v) == 0 {
+				normalized[i] = nil
+			}
+		}
+	}
+	return normalized
+}
+
+// normalizeRow reads NULL text back as the empty string, if the database stores the empty string as NULL
+func (d *Dialect) normalizeRow(types func(i int) string, dest []driver.Value) {
+	if !d.emptyIsNull || types == nil {
+		return
+	}
+	for i, v := range dest {
+		if v != nil {
+			continue
+		}
+		if t := strings.ToUpper(types(i)); strings.Contains(t, "CHAR") || t == "LONG" {
+			dest[i] = ""
+		}
+	}
+}
+
+// mergeStatement returns the MERGE statement inserting a row, or updating the row with the same keys.
+// The values are bound to the positional placeholders in the order of the columns, from is appended to their selection.
+func mergeStatement(table string, keys []string, columns []string, from string) string {
+	values := make([]string, len(columns))
+	inserted := make([]string, len(columns))
+	var matches, updates []string
+	for i, c := range columns {
+		values[i] = fmt.Sprintf("$%d AS %s", i+1, c)
+		inserted[i] = "s." + c
+		if slices.Contains(keys, c) {
+			matches = append(matches, fmt.Sprintf("t.%s = s.%s", c, c))
+		} else {
+			updates = append(updates, fmt.Sprintf("t.%s = s.%s", c, c))
+		}
+	}
+	merge := fmt.Sprintf("MERGE INTO %s t USING (SELECT %s%s) s ON (%s)", table, strings.Join(values, ", "), from, strings.Join(matches, " AND "))
+	if len(updates) != 0 {
+		merge += " WHEN MATCHED THEN UPDATE SET " + strings.Join(updates, ", ")
+	}
+	return merge + fmt.Sprintf(" WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)", strings.Join(columns, ", "), strings.Join(inserted, ", "))
+}
+
+// OpenDialectDB opens a database whose values are converted by the passed dialect.
+// The statements are not rewritten, the stores rewrite them with the dialect, see NewDBOpts.Dialect.
+func OpenDialectDB(d *Dialect, dataSourceName string, maxOpenConns, maxIdleConns int, maxIdleTime time.Duration) (*sql.DB, error) {
+	connector, err := openConnector(string(d.driver), dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	db := sql.OpenDB(&dialectConnector{Connector: connector, dialect: d})
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxIdleTime(maxIdleTime)
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, "can't connect to %s database", d.driver)
+	}
+	logger.Infof("connected to [%s], max open connections: %d", d.driver, maxOpenConns)
+	return db, nil
+}
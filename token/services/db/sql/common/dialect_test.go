@@ -0,0 +1,195 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialectRewriteQueries(t *testing.T) {
+	cases := []struct {
+		query     string
+		oracle    string
+		sqlServer string
+	}{
+		{
+			query:     "SELECT tx_id FROM tokens WHERE tx_id = $1 AND idx = $2 AND is_deleted = false AND owner = true LIMIT 1;",
+			oracle:    "SELECT tx_id FROM tokens WHERE tx_id = :1 AND idx = :2 AND is_deleted = 0 AND owner = 1 FETCH FIRST 1 ROWS ONLY",
+			sqlServer: "SELECT TOP 1 tx_id FROM tokens WHERE tx_id = @p1 AND idx = @p2 AND is_deleted = 0 AND owner = 1",
+		},
+		{
+			query:     "SELECT raw FROM public_params ORDER BY stored_at DESC LIMIT 1;",
+			oracle:    `SELECT "raw" FROM public_params ORDER BY stored_at DESC FETCH FIRST 1 ROWS ONLY`,
+			sqlServer: "SELECT TOP 1 raw FROM public_params ORDER BY stored_at DESC",
+		},
+		{
+			query:     "UPDATE tokens SET is_deleted = true, spent_by = $1, spent_at = $2 WHERE (tx_id = $10 AND idx = $11)",
+			oracle:    "UPDATE tokens SET is_deleted = 1, spent_by = :1, spent_at = :2 WHERE (tx_id = :10 AND idx = :11)",
+			sqlServer: "UPDATE tokens SET is_deleted = 1, spent_by = @p1, spent_at = @p2 WHERE (tx_id = @p10 AND idx = @p11)",
+		},
+		{
+			query:     "SELECT raw_hash FROM t WHERE issuer_id = '' AND spent_by != ''",
+			oracle:    "SELECT raw_hash FROM t WHERE issuer_id IS NULL AND spent_by IS NOT NULL",
+			sqlServer: "SELECT raw_hash FROM t WHERE issuer_id = '' AND spent_by != ''",
+		},
+		{
+			query:     "SELECT t.identity, owner_identity FROM t",
+			oracle:    "SELECT t.identity, owner_identity FROM t",
+			sqlServer: "SELECT t.[identity], owner_identity FROM t",
+		},
+		{
+			query:     "ALTER TABLE tokens ADD COLUMN pending BOOL NOT NULL DEFAULT false",
+			oracle:    "ALTER TABLE tokens ADD pending NUMBER(1) DEFAULT 0 NOT NULL",
+			sqlServer: "ALTER TABLE tokens ADD pending BIT NOT NULL DEFAULT 0",
+		},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.oracle, OracleDialect().Rewrite(c.query))
+		assert.Equal(t, c.sqlServer, SQLServerDialect().Rewrite(c.query))
+	}
+}
+
+func TestDialectRewriteSchema(t *testing.T) {
+	db := newTokenDB(nil, tokenTables{Tokens: "tokens", Ownership: "ownership", PublicParams: "pp", Certifications: "certs"}, nil, nil)
+
+	oracle := OracleDialect().Rewrite(db.GetSchema())
+	assert.True(t, strings.HasPrefix(oracle, "BEGIN\n"))
+	assert.True(t, strings.HasSuffix(oracle, "END;"))
+	assert.Contains(t, oracle, "BEGIN EXECUTE IMMEDIATE 'CREATE TABLE tokens (")
+	assert.Contains(t, oracle, "'CREATE INDEX idx_spent_tokens ON tokens ( is_deleted, owner )'")
+	assert.Contains(t, oracle, "IF SQLCODE NOT IN (-955, -1408) THEN RAISE;")
+	assert.Contains(t, oracle, "tx_id VARCHAR2(1000),")
+	assert.Contains(t, oracle, "amount NUMBER(19) NOT NULL,")
+	assert.Contains(t, oracle, "ledger_metadata BLOB,")
+	assert.Contains(t, oracle, "is_deleted NUMBER(1) DEFAULT 0 NOT NULL,")
+	assert.Contains(t, oracle, "spent_by VARCHAR2(1000),")
+	assert.Contains(t, oracle, "raw_hash RAW(2000) PRIMARY KEY,")
+	assert.Contains(t, oracle, `"raw" BLOB,`)
+	assert.NotContains(t, oracle, "IF NOT EXISTS")
+	assert.NotContains(t, oracle, "--")
+
+	sqlServer := SQLServerDialect().Rewrite(db.GetSchema())
+	assert.Contains(t, sqlServer, "IF OBJECT_ID(N'tokens', N'U') IS NULL CREATE TABLE tokens (")
+	assert.Contains(t, sqlServer, "IF NOT EXISTS (SELECT 1 FROM sys.indexes WHERE name = N'idx_tx_id_tokens' AND object_id = OBJECT_ID(N'tokens')) CREATE INDEX idx_tx_id_tokens ON tokens ( tx_id );")
+	assert.Contains(t, sqlServer, "tx_id NVARCHAR(450) NOT NULL,")
+	assert.Contains(t, sqlServer, "ledger VARBINARY(MAX) NOT NULL,")
+	assert.Contains(t, sqlServer, "stored_at DATETIME2 NOT NULL,")
+	assert.Contains(t, sqlServer, "owner BIT NOT NULL DEFAULT 0,")
+	assert.Contains(t, sqlServer, "raw_hash VARBINARY(900) PRIMARY KEY,")
+	assert.NotContains(t, sqlServer, "IF NOT EXISTS pp")
+}
+
+func TestDialectNormalize(t *testing.T) {
+	now := time.Now()
+	in := []any{true, false, now, "a", ""}
+	assert.Equal(t, []any{int64(1), int64(0), now.UTC(), "a", nil}, OracleDialect().normalizeArgs(in))
+	assert.Equal(t, []any{true, false, now, "a", ""}, in)
+	assert.Equal(t, []any{true, ""}, SQLServerDialect().normalizeArgs([]any{true, ""}))
+
+	types := []string{"NCHAR", "BLOB", "NUMBER", "CHAR"}
+	row := []driver.Value{nil, nil, nil, "x"}
+	OracleDialect().normalizeRow(func(i int) string { return types[i] }, row)
+	assert.Equal(t, []driver.Value{"", nil, nil, "x"}, row)
+}
+
+func TestDialectStatement(t *testing.T) {
+	// the comparisons with a bound empty string never match on Oracle, the placeholders are kept in place
+	query, args := OracleDialect().Statement("UPDATE t SET a = $1 WHERE b = $2 AND c != $3 AND d = $4", []any{"", "", "", "x"})
+	assert.Equal(t, "UPDATE t SET a = :1 WHERE (b IS NULL AND :2 IS NULL) AND (c IS NOT NULL AND :3 IS NULL) AND d = :4", query)
+	assert.Equal(t, []any{nil, nil, nil, "x"}, args)
+
+	query, args = SQLServerDialect().Statement("SELECT a FROM t WHERE b = $1", []any{""})
+	assert.Equal(t, "SELECT a FROM t WHERE b = @p1", query)
+	assert.Equal(t, []any{""}, args)
+
+	var d *Dialect
+	query, args = d.Statement("SELECT a FROM t WHERE b = $1", []any{""})
+	assert.Equal(t, "SELECT a FROM t WHERE b = $1", query)
+	assert.Equal(t, []any{""}, args)
+}
+
+func TestDialectTokenInterpreter(t *testing.T) {
+	columns := []string{"tx_id", "request", "applied"}
+	assert.Equal(t,
+		"INSERT INTO intents (tx_id, request, applied) VALUES ($1, $2, $3) ON CONFLICT (tx_id) DO UPDATE SET request = excluded.request, applied = excluded.applied",
+		NewTokenInterpreter(OracleDialect().Interpreter()).Upsert("intents", []string{"tx_id"}, columns))
+	assert.Equal(t,
+		"INSERT INTO acks (tx_id) VALUES ($1) ON CONFLICT (tx_id) DO NOTHING",
+		NewTokenInterpreter(OracleDialect().Interpreter()).Upsert("acks", []string{"tx_id"}, []string{"tx_id"}))
+
+	oracle := OracleDialect().TokenInterpreter()
+	assert.Equal(t,
+		"MERGE INTO intents t USING (SELECT :1 AS tx_id, :2 AS request, :3 AS applied FROM dual) s ON (t.tx_id = s.tx_id)"+
+			" WHEN MATCHED THEN UPDATE SET t.request = s.request, t.applied = s.applied"+
+			" WHEN NOT MATCHED THEN INSERT (tx_id, request, applied) VALUES (s.tx_id, s.request, s.applied)",
+		OracleDialect().Rewrite(oracle.Upsert("intents", []string{"tx_id"}, columns)))
+	assert.Equal(t, common.ConstCondition("issuer_id IS NULL"), oracle.IsEmptyString("issuer_id"))
+
+	sqlServer := SQLServerDialect().TokenInterpreter()
+	assert.Equal(t,
+		"MERGE INTO acks t USING (SELECT @p1 AS tx_id) s ON (t.tx_id = s.tx_id)"+
+			" WHEN NOT MATCHED THEN INSERT (tx_id) VALUES (s.tx_id);",
+		SQLServerDialect().Rewrite(sqlServer.Upsert("acks", []string{"tx_id"}, []string{"tx_id"})))
+	assert.Equal(t, common.ConstCondition("issuer_id = ''"), sqlServer.IsEmptyString("issuer_id"))
+}
+
+func TestInlineArgs(t *testing.T) {
+	assert.Equal(t,
+		"SELECT a FROM t WHERE w = 'wal''let' AND n = 3 AND b = true AND x = $4",
+		inlineArgs("SELECT a FROM t WHERE w = $1 AND n = $2 AND b = $3 AND x = $4", []any{"wal'let", 3, true}),
+	)
+}
+
+// sqliteTestDialect behaves like Oracle on SQLite: numbered placeholders, no `IF NOT EXISTS`, and the empty string stored as NULL
+func sqliteTestDialect() *Dialect {
+	d := OracleDialect()
+	d.driver = "sqlite"
+	d.placeholder = func(i int) string { return fmt.Sprintf("?%d", i) }
+	d.types = map[string]string{"TEXT": "VARCHAR(1000)", "BYTEA": "BLOB", "BOOL": "BOOLEAN", "BIGINT": "BIGINT", "TIMESTAMP": "TIMESTAMP"}
+	d.keyBinaryType = "BLOB"
+	// SQLite has no MERGE
+	d.upsert = nil
+	d.limit = func(query string, n string) string { return query + " LIMIT " + n }
+	d.schema = func(statements []ddlStatement) string {
+		sb := strings.Builder{}
+		for _, s := range statements {
+			stmt := strings.Replace(s.Statement, "CREATE TABLE ", "CREATE TABLE IF NOT EXISTS ", 1)
+			sb.WriteString(strings.Replace(stmt, "CREATE INDEX ", "CREATE INDEX IF NOT EXISTS ", 1))
+			sb.WriteString(";\n")
+		}
+		return sb.String()
+	}
+	return d
+}
+
+func TestTokensSqliteDialect(t *testing.T) {
+	tempDir := t.TempDir()
+	d := sqliteTestDialect()
+	for _, c := range TokensCases {
+		sqlDB, err := OpenDialectDB(d, fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", path.Join(tempDir, "db.sqlite")), 10, 2, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokenDB, err := NewTokenDB(sqlDB, NewDBOpts{TablePrefix: c.Name, CreateSchema: true, Dialect: d}, d.TokenInterpreter(), NewSQLiteQueryPlanner(), NewSQLiteSizeReporter())
+		if err != nil {
+			t.Fatal(err)
+		}
+		db := tokenDB.(*TokenDB)
+		t.Run(c.Name, func(xt *testing.T) {
+			defer db.Close()
+			c.Fn(xt, db)
+		})
+	}
+}
@@ -8,6 +8,7 @@ package common
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 
 	utils2 "github.com/hyperledger-labs/fabric-smart-client/platform/common/utils"
@@ -15,13 +16,14 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db"
 	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/common"
-	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/postgres"
-	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/sqlite"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/config"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/features"
 	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/microsoft/go-mssqldb"
 	"github.com/pkg/errors"
+	_ "github.com/sijms/go-ora/v2"
 	_ "modernc.org/sqlite"
 )
 
@@ -35,6 +37,37 @@ type NewDBOpts struct {
 	DataSource   string
 	TablePrefix  string
 	CreateSchema bool
+	// Shards is the number of shards the token tables are split into by owner wallet. Less than two means no sharding.
+	Shards int
+	// Isolation is the isolation level of the db transactions of the store, sql.LevelDefault to use the one of the database
+	Isolation sql.IsolationLevel
+	// Schema configures the creation of the schema, if CreateSchema is set
+	Schema SchemaOpts
+	// TTL, if positive, is the time after which the rows of the store expire.
+	// The expired rows are deleted when the store is opened. It is meant for test networks sharing long-lived databases.
+	TTL time.Duration
+	// StatementTimeout, if positive, is the time after which the queries of the store are interrupted.
+	// It protects the connections shared with the commit path from runaway analytical queries.
+	StatementTimeout time.Duration
+	// Dialect, if set, rewrites the statements of the store for a database other than Postgres and SQLite
+	Dialect *Dialect
+}
+
+var isolationLevels = map[string]sql.IsolationLevel{
+	"":               sql.LevelDefault,
+	"default":        sql.LevelDefault,
+	"readCommitted":  sql.LevelReadCommitted,
+	"repeatableRead": sql.LevelRepeatableRead,
+	"serializable":   sql.LevelSerializable,
+}
+
+// ParseIsolationLevel returns the isolation level with the passed name: default, readCommitted, repeatableRead, or serializable
+func ParseIsolationLevel(name string) (sql.IsolationLevel, error) {
+	level, ok := isolationLevels[name]
+	if !ok {
+		return sql.LevelDefault, errors.Errorf("invalid isolation level [%s], expected one of default, readCommitted, repeatableRead, serializable", name)
+	}
+	return level, nil
 }
 
 type Opener[V any] struct {
@@ -69,14 +102,7 @@ func NewOpener[V any](optsKey, envVarKey string, constructors OpenDBFunc[V]) *Op
 }
 
 func NewSQLDBOpener(optsKey, envVarKey string) *DBOpener {
-	return NewOpenerFromMap(optsKey, envVarKey, map[common.SQLDriverType]OpenDBFunc[*sql.DB]{
-		sql2.SQLite: func(k Opts) (*sql.DB, error) {
-			return sqlite.OpenDB(k.DataSource, k.MaxOpenConns, k.MaxIdleConns, k.MaxIdleTime, k.SkipPragmas)
-		},
-		sql2.Postgres: func(k Opts) (*sql.DB, error) {
-			return postgres.OpenDB(k.DataSource, k.MaxOpenConns, k.MaxIdleConns, k.MaxIdleTime)
-		},
-	})
+	return NewOpener(optsKey, envVarKey, OpenDB)
 }
 
 func (d *Opener[V]) Open(cp driver.ConfigProvider, tmsID token.TMSID) (V, error) {
@@ -96,6 +122,59 @@ func (d *Opener[V]) OpenWithOpts(cp driver.ConfigProvider, tmsID token.TMSID) (V
 	return sqlDB, opts, nil
 }
 
+// DBOpts returns the options to create the db opened with the passed options.
+// The number of shards, the isolation level, the schema options, the ttl, and the statement timeout are read from the
+// `shards`, `isolation`, `schema`, `ttl`, and `statementTimeout` keys next to the `opts` key.
+func (d *Opener[V]) DBOpts(cp driver.ConfigProvider, tmsID token.TMSID, opts *Opts) (NewDBOpts, error) {
+	dbOpts := NewDBOptsFromOpts(*opts)
+	tmsConfig, err := config.NewService(cp).ConfigurationFor(tmsID.Network, tmsID.Channel, tmsID.Namespace)
+	if err != nil {
+		return NewDBOpts{}, errors.WithMessagef(err, "failed to load configuration for tms [%s]", tmsID)
+	}
+	shardsKey := strings.TrimSuffix(d.optsKey, "opts") + "shards"
+	if tmsConfig.IsSet(shardsKey) {
+		if err := tmsConfig.UnmarshalKey(shardsKey, &dbOpts.Shards); err != nil {
+			return NewDBOpts{}, errors.WithMessagef(err, "failed to load [%s]", shardsKey)
+		}
+	}
+	isolationKey := strings.TrimSuffix(d.optsKey, "opts") + "isolation"
+	if tmsConfig.IsSet(isolationKey) {
+		var isolation string
+		if err := tmsConfig.UnmarshalKey(isolationKey, &isolation); err != nil {
+			return NewDBOpts{}, errors.WithMessagef(err, "failed to load [%s]", isolationKey)
+		}
+		if dbOpts.Isolation, err = ParseIsolationLevel(isolation); err != nil {
+			return NewDBOpts{}, err
+		}
+	}
+	schemaKey := strings.TrimSuffix(d.optsKey, "opts") + "schema"
+	if tmsConfig.IsSet(schemaKey) {
+		if err := tmsConfig.UnmarshalKey(schemaKey, &dbOpts.Schema); err != nil {
+			return NewDBOpts{}, errors.WithMessagef(err, "failed to load [%s]", schemaKey)
+		}
+		if err := dbOpts.Schema.Validate(opts.Driver); err != nil {
+			return NewDBOpts{}, errors.WithMessagef(err, "invalid [%s]", schemaKey)
+		}
+	}
+	ttlKey := strings.TrimSuffix(d.optsKey, "opts") + "ttl"
+	if tmsConfig.IsSet(ttlKey) {
+		if dbOpts.TTL, err = time.ParseDuration(tmsConfig.GetString(ttlKey)); err != nil {
+			return NewDBOpts{}, errors.Wrapf(err, "failed to parse [%s]", ttlKey)
+		}
+		if !features.EnabledIn(tmsConfig, features.Pruning) {
+			logger.Infof("pruning disabled for tms [%s], ignoring [%s]", tmsID, ttlKey)
+			dbOpts.TTL = 0
+		}
+	}
+	statementTimeoutKey := strings.TrimSuffix(d.optsKey, "opts") + "statementTimeout"
+	if tmsConfig.IsSet(statementTimeoutKey) {
+		if dbOpts.StatementTimeout, err = time.ParseDuration(tmsConfig.GetString(statementTimeoutKey)); err != nil {
+			return NewDBOpts{}, errors.Wrapf(err, "failed to parse [%s]", statementTimeoutKey)
+		}
+	}
+	return dbOpts, nil
+}
+
 func (d *Opener[V]) compileOpts(cp driver.ConfigProvider, tmsID token.TMSID) (*Opts, error) {
 	tmsConfig, err := config.NewService(cp).ConfigurationFor(tmsID.Network, tmsID.Channel, tmsID.Namespace)
 	if err != nil {
@@ -106,6 +185,17 @@ func (d *Opener[V]) compileOpts(cp driver.ConfigProvider, tmsID token.TMSID) (*O
 		return nil, err
 	}
 	opts.TablePrefix = db.EscapeForTableName(tmsID.Network, tmsID.Channel, tmsID.Namespace)
+	failoverKey := strings.TrimSuffix(d.optsKey, "opts") + "failover"
+	if tmsConfig.IsSet(failoverKey) {
+		failover := &FailoverOpts{}
+		if err := tmsConfig.UnmarshalKey(failoverKey, failover); err != nil {
+			return nil, errors.WithMessagef(err, "failed to load [%s]", failoverKey)
+		}
+		if opts.Driver == sql2.SQLite && len(failover.DataSources) > 0 {
+			return nil, errors.Errorf("[%s] not supported by [%s]", failoverKey, opts.Driver)
+		}
+		registerFailover(*opts, failover)
+	}
 	return opts, nil
 }
 
@@ -0,0 +1,37 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIsolationLevel(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		expected sql.IsolationLevel
+		fails    bool
+	}{
+		{name: "", expected: sql.LevelDefault},
+		{name: "default", expected: sql.LevelDefault},
+		{name: "readCommitted", expected: sql.LevelReadCommitted},
+		{name: "repeatableRead", expected: sql.LevelRepeatableRead},
+		{name: "serializable", expected: sql.LevelSerializable},
+		{name: "snapshot", fails: true},
+	} {
+		level, err := ParseIsolationLevel(c.name)
+		if c.fails {
+			assert.Error(t, err, c.name)
+			continue
+		}
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, c.expected, level, c.name)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+// SetColumnCipher makes the token db encrypt the token metadata, the openings of the tokens, with the passed cipher
+func (db *TokenDB) SetColumnCipher(c driver.ColumnCipher) {
+	db.cipher = c
+}
+
+// ReEncrypt re-encrypts with the active key the token metadata stored in clear or with a retired key
+func (db *TokenDB) ReEncrypt(ctx context.Context, batchSize int) (int, error) {
+	return reEncryptColumn(ctx, db.db, db.cipher, db.table.Tokens, []string{"tx_id", "idx"}, "ledger_metadata", batchSize)
+}
+
+// SetColumnCipher makes all the shards encrypt the token metadata with the passed cipher
+func (db *ShardedTokenDB) SetColumnCipher(c driver.ColumnCipher) {
+	for _, shard := range db.shards {
+		shard.SetColumnCipher(c)
+	}
+}
+
+// ReEncrypt re-encrypts the token metadata of all the shards
+func (db *ShardedTokenDB) ReEncrypt(ctx context.Context, batchSize int) (int, error) {
+	total := 0
+	for i, shard := range db.shards {
+		n, err := shard.ReEncrypt(ctx, batchSize)
+		total += n
+		if err != nil {
+			return total, errors.WithMessagef(err, "failed to re-encrypt shard [%d]", i)
+		}
+	}
+	return total, nil
+}
+
+// SetColumnCipher makes the transaction db encrypt the token requests with the passed cipher
+func (db *TransactionDB) SetColumnCipher(c driver.ColumnCipher) {
+	db.cipher = c
+}
+
+// ReEncrypt re-encrypts with the active key the token requests stored in clear or with a retired key
+func (db *TransactionDB) ReEncrypt(ctx context.Context, batchSize int) (int, error) {
+	return reEncryptColumn(ctx, db.db, db.cipher, db.table.Requests, []string{"tx_id"}, "request", batchSize)
+}
+
+// encryptColumn encrypts the passed value, if a cipher is set
+func encryptColumn(c driver.ColumnCipher, value []byte) ([]byte, error) {
+	if c == nil {
+		return value, nil
+	}
+	return c.Encrypt(value)
+}
+
+// decryptColumn decrypts the passed value, if a cipher is set
+func decryptColumn(c driver.ColumnCipher, value []byte) ([]byte, error) {
+	if c == nil || value == nil {
+		return value, nil
+	}
+	return c.Decrypt(value)
+}
+
+// reEncryptColumn re-encrypts the stale values of the passed column.
+// The stale rows are collected first, then they are updated in transactions of at most batchSize rows.
+// The values of the sensitive columns are never updated otherwise, therefore, no write is lost in between.
+func reEncryptColumn(ctx context.Context, db *DB, c driver.ColumnCipher, table string, keys []string, column string, batchSize int) (int, error) {
+	if c == nil {
+		return 0, errors.New("no column cipher set")
+	}
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	type staleRow struct {
+		key   []any
+		value []byte
+	}
+
+	query := fmt.Sprintf("SELECT %s, %s FROM %s", strings.Join(keys, ", "), column, table)
+	logger.Debug(query)
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to query [%s]", table)
+	}
+	var stale []staleRow
+	for rows.Next() {
+		key := make([]any, len(keys))
+		dest := make([]any, len(keys)+1)
+		for i := range key {
+			dest[i] = &key[i]
+		}
+		var value []byte
+		dest[len(keys)] = &value
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, errors.Wrapf(err, "failed to scan [%s]", table)
+		}
+		if c.Stale(value) {
+			stale = append(stale, staleRow{key: key, value: value})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, errors.Wrapf(err, "failed to scan [%s]", table)
+	}
+	rows.Close()
+
+	conditions := make([]string, len(keys))
+	for i, k := range keys {
+		conditions[i] = fmt.Sprintf("%s = $%d", k, i+2)
+	}
+	update := fmt.Sprintf("UPDATE %s SET %s = $1 WHERE %s", table, column, strings.Join(conditions, " AND "))
+
+	done := 0
+	for start := 0; start < len(stale); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return done, err
+		}
+		batch := stale[start:min(start+batchSize, len(stale))]
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return done, errors.Wrapf(err, "failed to begin transaction")
+		}
+		for _, r := range batch {
+			plain, err := c.Decrypt(r.value)
+			if err != nil {
+				_ = tx.Rollback()
+				return done, errors.WithMessagef(err, "failed to decrypt [%s] of [%v]", column, r.key)
+			}
+			encrypted, err := c.Encrypt(plain)
+			if err != nil {
+				_ = tx.Rollback()
+				return done, errors.WithMessagef(err, "failed to encrypt [%s] of [%v]", column, r.key)
+			}
+			if _, err := tx.ExecContext(ctx, update, append([]any{encrypted}, r.key...)...); err != nil {
+				_ = tx.Rollback()
+				return done, errors.Wrapf(err, "failed to update [%s] of [%v]", column, r.key)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return done, errors.Wrapf(err, "failed to commit re-encryption")
+		}
+		done += len(batch)
+	}
+	return done, nil
+}
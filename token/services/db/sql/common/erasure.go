@@ -0,0 +1,80 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"fmt"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+// EraseEnrollmentID replaces the passed enrollment ID with the passed pseudonym in the transaction and movement records,
+// in a single db transaction. The amounts, the token requests, and the movement corrections are kept.
+func (db *TransactionDB) EraseEnrollmentID(eID string, pseudonym string) (*driver.ErasedRecords, error) {
+	if len(eID) == 0 || len(pseudonym) == 0 {
+		return nil, errors.New("the erasure requires an enrollment ID and a pseudonym")
+	}
+	counts, err := execInTx(db.db, []deleteQuery{
+		{
+			fmt.Sprintf("UPDATE %s SET "+
+				"sender_eid = CASE WHEN sender_eid = $1 THEN $2 ELSE sender_eid END, "+
+				"recipient_eid = CASE WHEN recipient_eid = $1 THEN $2 ELSE recipient_eid END "+
+				"WHERE sender_eid = $1 OR recipient_eid = $1;", db.table.Transactions),
+			[]any{eID, pseudonym},
+		},
+		{fmt.Sprintf("UPDATE %s SET enrollment_id = $2 WHERE enrollment_id = $1;", db.table.Movements), []any{eID, pseudonym}},
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed erasing enrollment ID")
+	}
+	return &driver.ErasedRecords{Transactions: counts[0], Movements: counts[1]}, nil
+}
+
+// EraseOwnership removes the ownership by the passed wallet of the spent tokens, in a single db transaction.
+// The tokens, their amounts and ledger outputs, are kept. The unspent tokens are counted as retained.
+func (db *TokenDB) EraseOwnership(walletID string) (*driver.ErasedOwnership, error) {
+	if len(walletID) == 0 {
+		return nil, errors.New("the erasure requires a wallet ID")
+	}
+	spent := fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s.tx_id = %s.tx_id AND %s.idx = %s.idx AND %s.is_deleted = %%s)",
+		db.table.Tokens, db.table.Tokens, db.table.Ownership, db.table.Tokens, db.table.Ownership, db.table.Tokens)
+	owned := fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s.tx_id = %s.tx_id AND %s.idx = %s.idx AND %s.wallet_id %%s $1)",
+		db.table.Ownership, db.table.Ownership, db.table.Tokens, db.table.Ownership, db.table.Tokens, db.table.Ownership)
+	counts, err := execInTx(db.db, []deleteQuery{
+		// the spent tokens owned by the wallet only are not owned by this node anymore
+		{fmt.Sprintf("UPDATE %s SET owner = false WHERE is_deleted = true AND owner = true AND %s AND NOT %s;",
+			db.table.Tokens, fmt.Sprintf(owned, "="), fmt.Sprintf(owned, "!=")), []any{walletID}},
+		{fmt.Sprintf("DELETE FROM %s WHERE wallet_id = $1 AND %s;", db.table.Ownership, fmt.Sprintf(spent, "true")), []any{walletID}},
+		{fmt.Sprintf("UPDATE %s SET owner_wallet_id = NULL WHERE owner_wallet_id = $1 AND is_deleted = true;", db.table.Tokens), []any{walletID}},
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed erasing the ownership of [%s]", walletID)
+	}
+	res := &driver.ErasedOwnership{Removed: counts[1]}
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE wallet_id = $1 AND %s;", db.table.Ownership, fmt.Sprintf(spent, "false"))
+	logger.Debug(query, walletID)
+	if err := db.db.QueryRow(query, walletID).Scan(&res.Retained); err != nil {
+		return nil, errors.Wrapf(err, "failed counting the unspent tokens of [%s]", walletID)
+	}
+	return res, nil
+}
+
+// EraseOwnership removes the ownership by the passed wallet of the spent tokens of every shard.
+// The counts of the shards are summed.
+func (db *ShardedTokenDB) EraseOwnership(walletID string) (*driver.ErasedOwnership, error) {
+	res := &driver.ErasedOwnership{}
+	for i, shard := range db.shards {
+		erased, err := shard.EraseOwnership(walletID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed erasing ownership in shard [%d]", i)
+		}
+		res.Removed += erased.Removed
+		res.Retained += erased.Retained
+	}
+	return res, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DeleteExpired deletes the tokens, with their ownership, certifications, attributes, and serials, and the intents stored before the passed time.
+// The public parameters are kept. It returns the number of deleted rows.
+func (db *TokenDB) DeleteExpired(before time.Time) (int64, error) {
+	before = before.UTC()
+	queries := make([]deleteQuery, 0, 6)
+	// the ownership, the certifications, the attributes, and the serials go with their tokens
+	for _, table := range []string{db.table.Ownership, db.table.Certifications, db.table.Attributes, db.table.Serials} {
+		queries = append(queries, deleteQuery{fmt.Sprintf("DELETE FROM %s WHERE EXISTS (SELECT 1 FROM %s WHERE %s.tx_id = %s.tx_id AND %s.idx = %s.idx AND %s.stored_at < $1);",
+			table, db.table.Tokens, db.table.Tokens, table, db.table.Tokens, table, db.table.Tokens), []any{before}})
+	}
+	queries = append(queries,
+		deleteQuery{fmt.Sprintf("DELETE FROM %s WHERE stored_at < $1;", db.table.Tokens), []any{before}},
+		deleteQuery{fmt.Sprintf("DELETE FROM %s WHERE stored_at < $1;", db.table.Intents), []any{before}},
+	)
+	return deleteInTx(db.db, queries)
+}
+
+// DeleteExpired deletes, from every shard, the rows stored before the passed time, as TokenDB.DeleteExpired does
+func (db *ShardedTokenDB) DeleteExpired(before time.Time) (int64, error) {
+	var deleted int64
+	for i, shard := range db.shards {
+		n, err := shard.DeleteExpired(before)
+		if err != nil {
+			return deleted, errors.WithMessagef(err, "failed deleting expired rows of shard [%d]", i)
+		}
+		deleted += n
+	}
+	return deleted, nil
+}
+
+// DeleteExpired deletes the records stored before the passed time.
+// The token requests are deleted once no record refers to them anymore. It returns the number of deleted rows.
+func (db *TransactionDB) DeleteExpired(before time.Time) (int64, error) {
+	children := []string{
+		db.table.StatusOverrides,
+		db.table.CompensationFailures,
+		db.table.IdempotencyKeys,
+		db.table.AuditResponses,
+		db.table.FundsWitnesses,
+		db.table.References,
+		db.table.ExportOutbox,
+		db.table.IssuerAttributions,
+		db.table.TransactionEndorseAck,
+		db.table.Transactions,
+		db.table.Movements,
+		db.table.Validations,
+	}
+	before = before.UTC()
+	queries := make([]deleteQuery, 0, len(children)+3)
+	conditions := make([]string, 0, len(children)+1)
+	// the wallets go with their transactions
+	queries = append(queries, deleteQuery{fmt.Sprintf("DELETE FROM %s WHERE EXISTS (SELECT 1 FROM %s WHERE %s.tx_id = %s.tx_id AND %s.stored_at < $1);",
+		db.table.TxWallets, db.table.Transactions, db.table.Transactions, db.table.TxWallets, db.table.Transactions), []any{before}})
+	conditions = append(conditions, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s.tx_id = %s.tx_id)", db.table.TxWallets, db.table.TxWallets, db.table.Requests))
+	// the corrections go with their movements
+	queries = append(queries, deleteQuery{fmt.Sprintf("DELETE FROM %s WHERE EXISTS (SELECT 1 FROM %s WHERE %s.id = %s.movement_id AND %s.stored_at < $1);",
+		db.table.MovementCorrections, db.table.Movements, db.table.Movements, db.table.MovementCorrections, db.table.Movements), []any{before}})
+	for _, table := range children {
+		queries = append(queries, deleteQuery{fmt.Sprintf("DELETE FROM %s WHERE stored_at < $1;", table), []any{before}})
+		if table != db.table.TransactionEndorseAck && table != db.table.FundsWitnesses {
+			// endorsement acks and funds witnesses do not reference the requests
+			conditions = append(conditions, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s.tx_id = %s.tx_id)", table, table, db.table.Requests))
+		}
+	}
+	queries = append(queries, deleteQuery{fmt.Sprintf("DELETE FROM %s WHERE %s;", db.table.Requests, strings.Join(conditions, " AND ")), nil})
+	return deleteInTx(db.db, queries)
+}
+
+// deleteExpiredOnOpen deletes the rows older than the passed ttl, if positive, using the passed function
+func deleteExpiredOnOpen(name string, ttl time.Duration, deleteExpired func(before time.Time) (int64, error)) error {
+	if ttl <= 0 {
+		return nil
+	}
+	deleted, err := deleteExpired(time.Now().Add(-ttl))
+	if err != nil {
+		return errors.WithMessagef(err, "failed deleting expired rows of [%s]", name)
+	}
+	logger.Infof("deleted [%d] rows of [%s] older than [%s]", deleted, name, ttl)
+	return nil
+}
+
+type deleteQuery struct {
+	query string
+	args  []any
+}
+
+// deleteInTx executes the passed delete queries in a single db transaction
+func deleteInTx(db *DB, queries []deleteQuery) (deleted int64, err error) {
+	counts, err := execInTx(db, queries)
+	if err != nil {
+		return 0, err
+	}
+	for _, n := range counts {
+		deleted += n
+	}
+	return deleted, nil
+}
+
+// execInTx executes the passed queries in a single db transaction, and returns the number of rows affected by each
+func execInTx(db *DB, queries []deleteQuery) (counts []int64, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed starting a db transaction")
+	}
+	defer func() {
+		if err != nil {
+			if err1 := tx.Rollback(); err1 != nil {
+				logger.Errorf("error rolling back: %s", err1.Error())
+			}
+		}
+	}()
+	counts = make([]int64, len(queries))
+	for i, q := range queries {
+		logger.Debug(q.query, q.args)
+		var res sql.Result
+		if res, err = tx.Exec(q.query, q.args...); err != nil {
+			return nil, errors.Wrapf(err, "error executing [%s]", q.query)
+		}
+		if counts[i], err = res.RowsAffected(); err != nil {
+			return nil, errors.Wrapf(err, "error getting the number of affected rows")
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed committing the db transaction")
+	}
+	return counts, nil
+}
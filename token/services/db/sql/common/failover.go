@@ -0,0 +1,201 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	errors2 "errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/postgres"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/sqlite"
+	"github.com/pkg/errors"
+)
+
+const defaultHealthCheckInterval = 5 * time.Second
+
+// FailoverOpts configures the standby databases of a store
+type FailoverOpts struct {
+	// DataSources are the data sources of the standby databases, tried in order when the database in use is unreachable
+	DataSources []string `yaml:"dataSources,omitempty"`
+	// HealthCheckInterval is the interval between two checks of the database in use. The default is 5s.
+	HealthCheckInterval time.Duration `yaml:"healthCheckInterval,omitempty"`
+}
+
+// GetHealthCheckInterval returns the interval between two checks of the database in use
+func (o FailoverOpts) GetHealthCheckInterval() time.Duration {
+	if o.HealthCheckInterval > 0 {
+		return o.HealthCheckInterval
+	}
+	return defaultHealthCheckInterval
+}
+
+// failovers holds the failover options of the opened databases, by key of their options
+var failovers sync.Map
+
+// OpenDB opens the database of the passed options.
+// If standby databases are configured for its data source, the database fails over to them, see OpenFailoverDB.
+func OpenDB(k Opts) (*sql.DB, error) {
+	var failover *FailoverOpts
+	if f, ok := failovers.Load(key(k)); ok {
+		failover = f.(*FailoverOpts)
+	}
+	switch k.Driver {
+	case sql2.SQLite:
+		return sqlite.OpenDB(k.DataSource, k.MaxOpenConns, k.MaxIdleConns, k.MaxIdleTime, k.SkipPragmas)
+	case sql2.Postgres:
+		if failover == nil {
+			return postgres.OpenDB(k.DataSource, k.MaxOpenConns, k.MaxIdleConns, k.MaxIdleTime)
+		}
+		return OpenFailoverDB(nil, "pgx", append([]string{k.DataSource}, failover.DataSources...), failover.GetHealthCheckInterval(), k.MaxOpenConns, k.MaxIdleConns, k.MaxIdleTime)
+	case Oracle, SQLServer:
+		d := DialectOf(k.Driver)
+		if failover == nil {
+			return OpenDialectDB(d, k.DataSource, k.MaxOpenConns, k.MaxIdleConns, k.MaxIdleTime)
+		}
+		return OpenFailoverDB(d, string(k.Driver), append([]string{k.DataSource}, failover.DataSources...), failover.GetHealthCheckInterval(), k.MaxOpenConns, k.MaxIdleConns, k.MaxIdleTime)
+	}
+	return nil, errors.Errorf("driver [%s] not supported", k.Driver)
+}
+
+// OpenFailoverDB opens a database reachable at the passed data sources, the primary first and then the standbys.
+// New connections go to the data source in use. When it is unreachable, the next reachable one, in circular order, is used instead.
+// The database in use is checked at the passed interval, so that the connections to a failed database are replaced
+// without waiting for a request to fail. The values are converted by the passed dialect, if any.
+func OpenFailoverDB(d *Dialect, driverName string, dataSources []string, interval time.Duration, maxOpenConns, maxIdleConns int, maxIdleTime time.Duration) (*sql.DB, error) {
+	if len(dataSources) == 0 {
+		return nil, errors.New("no data source")
+	}
+	connectors := make([]driver.Connector, len(dataSources))
+	for i, dataSource := range dataSources {
+		c, err := openConnector(driverName, dataSource)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "data source [%d]", i)
+		}
+		connectors[i] = c
+	}
+	fc := newFailoverConnector(connectors)
+	var connector driver.Connector = fc
+	if d != nil {
+		connector = &dialectConnector{Connector: fc, dialect: d}
+	}
+
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxIdleTime(maxIdleTime)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, "can't connect to %s database", driverName)
+	}
+	logger.Infof("connected to [%s] data source [%d] of [%d], max open connections: %d", driverName, fc.active.Load(), len(dataSources), maxOpenConns)
+	go fc.healthCheck(db, interval, maxIdleConns)
+	return db, nil
+}
+
+// failoverConnector connects to the first reachable of a list of databases, starting from the one in use
+type failoverConnector struct {
+	connectors []driver.Connector
+	// active is the index of the connector in use
+	active atomic.Int32
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+func newFailoverConnector(connectors []driver.Connector) *failoverConnector {
+	return &failoverConnector{connectors: connectors, stop: make(chan struct{})}
+}
+
+func (c *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	active := int(c.active.Load())
+	var errs []error
+	for i := range c.connectors {
+		index := (active + i) % len(c.connectors)
+		conn, err := c.connectors[index].Connect(ctx)
+		if err != nil {
+			errs = append(errs, errors.WithMessagef(err, "data source [%d]", index))
+			continue
+		}
+		if index != active && c.active.CompareAndSwap(int32(active), int32(index)) {
+			logger.Warnf("data source [%d] unreachable, failed over to data source [%d]", active, index)
+		}
+		return conn, nil
+	}
+	return nil, errors.WithMessagef(errors2.Join(errs...), "no data source reachable")
+}
+
+func (c *failoverConnector) Driver() driver.Driver {
+	return c.connectors[0].Driver()
+}
+
+// Close stops the health check, it is called by sql.DB.Close
+func (c *failoverConnector) Close() error {
+	c.stopOnce.Do(func() { close(c.stop) })
+	var errs []error
+	for _, connector := range c.connectors {
+		if closer, ok := connector.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors2.Join(errs...)
+}
+
+// healthCheck pings the database at the passed interval.
+// A ping on a broken connection makes the pool open a new one, failing over if needed.
+// After a failover, the idle connections to the previous database are closed.
+func (c *failoverConnector) healthCheck(db *sql.DB, interval time.Duration, maxIdleConns int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	active := c.active.Load()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		if err := db.PingContext(ctx); err != nil {
+			logger.Errorf("health check failed: [%s]", err)
+		}
+		cancel()
+		if current := c.active.Load(); current != active {
+			// shrinking the pool closes the idle connections
+			db.SetMaxIdleConns(0)
+			db.SetMaxIdleConns(maxIdleConns)
+			active = current
+		}
+	}
+}
+
+// openConnector returns the connector of the passed data source of the passed database driver
+func openConnector(driverName, dataSource string) (driver.Connector, error) {
+	raw, err := sql.Open(driverName, dataSource)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't open %s database", driverName)
+	}
+	defer raw.Close()
+	if dc, ok := raw.Driver().(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dataSource)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't open %s database", driverName)
+		}
+		return connector, nil
+	}
+	return &dsnConnector{driver: raw.Driver(), dataSource: dataSource}, nil
+}
+
+// registerFailover records the failover options of the database of the passed options, to be used by OpenDB
+func registerFailover(k Opts, opts *FailoverOpts) {
+	failovers.Store(key(k), opts)
+}
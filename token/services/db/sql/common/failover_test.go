@@ -0,0 +1,65 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubConnector connects if its database is up
+type stubConnector struct {
+	driver.Connector
+	up bool
+}
+
+func (c *stubConnector) Connect(context.Context) (driver.Conn, error) {
+	if !c.up {
+		return nil, errors.New("connection refused")
+	}
+	return nil, nil
+}
+
+func TestFailoverConnector(t *testing.T) {
+	primary := &stubConnector{up: true}
+	standby1 := &stubConnector{}
+	standby2 := &stubConnector{up: true}
+	c := newFailoverConnector([]driver.Connector{primary, standby1, standby2})
+
+	_, err := c.Connect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), c.active.Load())
+
+	// the unreachable standby is skipped
+	primary.up = false
+	_, err = c.Connect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), c.active.Load())
+
+	// the connector stays on the standby in use, even if the primary is back
+	primary.up = true
+	_, err = c.Connect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), c.active.Load())
+
+	// the data sources are tried in circular order
+	standby2.up = false
+	_, err = c.Connect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), c.active.Load())
+
+	primary.up = false
+	_, err = c.Connect(context.Background())
+	assert.ErrorContains(t, err, "no data source reachable")
+	assert.Equal(t, int32(0), c.active.Load())
+
+	assert.NoError(t, c.Close())
+}
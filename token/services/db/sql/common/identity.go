@@ -31,17 +31,18 @@ type identityTables struct {
 	IdentityConfigurations string
 	IdentityInfo           string
 	Signers                string
+	Recipients             string
 }
 
 type IdentityDB struct {
-	db    *sql.DB
+	db    *DB
 	table identityTables
 
 	signerInfoCache cache[bool]
 	auditInfoCache  cache[[]byte]
 }
 
-func newIdentityDB(db *sql.DB, tables identityTables, singerInfoCache cache[bool], auditInfoCache cache[[]byte]) *IdentityDB {
+func newIdentityDB(db *DB, tables identityTables, singerInfoCache cache[bool], auditInfoCache cache[[]byte]) *IdentityDB {
 	return &IdentityDB{
 		db:              db,
 		table:           tables,
@@ -51,13 +52,18 @@ func newIdentityDB(db *sql.DB, tables identityTables, singerInfoCache cache[bool
 }
 
 func NewCachedIdentityDB(db *sql.DB, opts NewDBOpts) (driver.IdentityDB, error) {
-	return NewIdentityDB(
+	identityDB, err := NewIdentityDB(
 		db,
 		opts.TablePrefix,
 		opts.CreateSchema,
 		secondcache.NewTyped[bool](1000),
 		secondcache.NewTyped[[]byte](1000),
 	)
+	if err != nil {
+		return nil, err
+	}
+	identityDB.db.timeout = opts.StatementTimeout
+	return identityDB, nil
 }
 
 func NewIdentityDB(db *sql.DB, tablePrefix string, createSchema bool, signerInfoCache cache[bool], auditInfoCache cache[[]byte]) (*IdentityDB, error) {
@@ -67,11 +73,12 @@ func NewIdentityDB(db *sql.DB, tablePrefix string, createSchema bool, signerInfo
 	}
 
 	identityDB := newIdentityDB(
-		db,
+		NewDB(db, 0),
 		identityTables{
 			IdentityConfigurations: tables.IdentityConfigurations,
 			IdentityInfo:           tables.IdentityInfo,
 			Signers:                tables.Signers,
+			Recipients:             tables.Recipients,
 		},
 		signerInfoCache,
 		auditInfoCache,
@@ -232,8 +239,67 @@ func (db *IdentityDB) GetSignerInfo(identity []byte) ([]byte, error) {
 	return info, nil
 }
 
+func (db *IdentityDB) StoreRecipientIdentity(id []byte, enrollmentID string, walletID driver.WalletID) error {
+	h := token.Identity(id).String()
+	query := fmt.Sprintf("INSERT INTO %s (identity_hash, identity, enrollment_id, wallet_id) VALUES ($1, $2, $3, $4)", db.table.Recipients)
+	logger.Debug(query, h, enrollmentID, walletID)
+	_, err := db.db.Exec(query, h, id, enrollmentID, walletID)
+	if err == nil {
+		return nil
+	}
+	if !isUniqueViolation(err) {
+		return errors.Wrapf(err, "failed storing recipient identity [%s]", h)
+	}
+
+	// the record already exists, refresh the binding without losing a known wallet
+	var args []any
+	if len(walletID) == 0 {
+		query = fmt.Sprintf("UPDATE %s SET enrollment_id = $1 WHERE identity_hash = $2", db.table.Recipients)
+		args = []any{enrollmentID, h}
+	} else {
+		query = fmt.Sprintf("UPDATE %s SET enrollment_id = $1, wallet_id = $2 WHERE identity_hash = $3", db.table.Recipients)
+		args = []any{enrollmentID, walletID, h}
+	}
+	logger.Debug(query, args)
+	res, err := db.db.Exec(query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "failed updating recipient identity [%s]", h)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "failed updating recipient identity [%s]", h)
+	}
+	if updated != 1 {
+		return errors.Errorf("failed updating recipient identity [%s], [%d] rows affected", h, updated)
+	}
+	return nil
+}
+
+func (db *IdentityDB) GetRecipientIdentities(enrollmentID string) ([]driver.RecipientIdentity, error) {
+	query := fmt.Sprintf(
+		"SELECT %s.identity, enrollment_id, wallet_id, identity_audit_info FROM %s LEFT JOIN %s ON %s.identity_hash = %s.identity_hash WHERE enrollment_id = $1",
+		db.table.Recipients, db.table.Recipients, db.table.IdentityInfo, db.table.Recipients, db.table.IdentityInfo,
+	)
+	logger.Debug(query, enrollmentID)
+	rows, err := db.db.Query(query, enrollmentID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying db")
+	}
+	defer rows.Close()
+
+	var res []driver.RecipientIdentity
+	for rows.Next() {
+		var r driver.RecipientIdentity
+		if err := rows.Scan(&r.Identity, &r.EnrollmentID, &r.WalletID, &r.AuditInfo); err != nil {
+			return nil, errors.Wrapf(err, "failed scanning recipient identity")
+		}
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
 type IdentityConfigurationIterator struct {
-	rows              *sql.Rows
+	rows              *Rows
 	configurationType string
 }
 
@@ -283,6 +349,16 @@ func (db *IdentityDB) GetSchema() string {
 			info BYTEA
 		);
 		CREATE INDEX IF NOT EXISTS idx_signers_%s ON %s ( identity_hash );
+
+		-- Recipients
+		CREATE TABLE IF NOT EXISTS %s (
+            identity_hash TEXT NOT NULL PRIMARY KEY,
+			identity BYTEA NOT NULL,
+			enrollment_id TEXT NOT NULL,
+			wallet_id TEXT NOT NULL,
+			stored_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_recipients_eid_%s ON %s ( enrollment_id );
 		`,
 		db.table.IdentityConfigurations,
 		db.table.IdentityConfigurations, db.table.IdentityConfigurations,
@@ -291,5 +367,7 @@ func (db *IdentityDB) GetSchema() string {
 		db.table.IdentityInfo, db.table.IdentityInfo,
 		db.table.Signers,
 		db.table.Signers, db.table.Signers,
+		db.table.Recipients,
+		db.table.Recipients, db.table.Recipients,
 	)
 }
@@ -78,6 +78,7 @@ var IdentityCases = []struct {
 	{"SignerInfo", TSignerInfo},
 	{"Configurations", TConfigurations},
 	{"SignerInfoConcurrent", TSignerInfoConcurrent},
+	{"RecipientIdentities", TRecipientIdentities},
 }
 
 func TConfigurations(t *testing.T, db *IdentityDB) {
@@ -150,6 +151,49 @@ func TSignerInfo(t *testing.T, db *IdentityDB) {
 	assert.False(t, exists)
 }
 
+func TRecipientIdentities(t *testing.T, db *IdentityDB) {
+	bob1 := []byte("bob_1")
+	bob2 := []byte("bob_2")
+	charlie := []byte("charlie")
+	assert.NoError(t, db.StoreIdentityData(bob1, []byte("bob_1_audit_info"), nil, nil))
+	assert.NoError(t, db.StoreRecipientIdentity(bob1, "bob", "bob_wallet"))
+	assert.NoError(t, db.StoreRecipientIdentity(bob2, "bob", ""))
+	assert.NoError(t, db.StoreRecipientIdentity(charlie, "charlie", "charlie_wallet"))
+
+	// storing again without a wallet keeps the wallet already known
+	assert.NoError(t, db.StoreRecipientIdentity(bob1, "bob", ""))
+
+	recipients, err := db.GetRecipientIdentities("bob")
+	assert.NoError(t, err)
+	assert.Len(t, recipients, 2)
+	byIdentity := map[string]driver.RecipientIdentity{}
+	for _, r := range recipients {
+		byIdentity[string(r.Identity)] = r
+	}
+	assert.Equal(t, "bob", byIdentity["bob_1"].EnrollmentID)
+	assert.Equal(t, "bob_wallet", byIdentity["bob_1"].WalletID)
+	assert.Equal(t, []byte("bob_1_audit_info"), byIdentity["bob_1"].AuditInfo)
+	assert.Equal(t, "", byIdentity["bob_2"].WalletID)
+	assert.Empty(t, byIdentity["bob_2"].AuditInfo)
+
+	// the wallet binding can be set later
+	assert.NoError(t, db.StoreRecipientIdentity(bob2, "bob", "bob_wallet"))
+	recipients, err = db.GetRecipientIdentities("bob")
+	assert.NoError(t, err)
+	for _, r := range recipients {
+		assert.Equal(t, "bob_wallet", r.WalletID)
+	}
+
+	recipients, err = db.GetRecipientIdentities("alice")
+	assert.NoError(t, err)
+	assert.Empty(t, recipients)
+
+	// the errors other than a duplicate key are returned
+	_, err = db.db.Exec(fmt.Sprintf("DROP TABLE %s", db.table.Recipients))
+	assert.NoError(t, err)
+	assert.Error(t, db.StoreRecipientIdentity(bob1, "bob", "bob_wallet"))
+}
+
 func TSignerInfoConcurrent(t *testing.T, db *IdentityDB) {
 	wg := sync.WaitGroup{}
 	n := 100
@@ -15,21 +15,41 @@ var logger = logging.MustGetLogger("token-sdk.sql")
 
 type tableNames struct {
 	Movements              string
+	MovementCorrections    string
 	Transactions           string
 	Requests               string
 	Validations            string
 	TransactionEndorseAck  string
+	IssuerAttributions     string
+	StatusOverrides        string
+	CompensationFailures   string
+	IdempotencyKeys        string
+	AuditResponses         string
+	FundsWitnesses         string
+	References             string
+	TxWallets              string
+	ExportOutbox           string
+	Pseudonyms             string
+	AnonymityLevels        string
 	Certifications         string
+	TokenAttributes        string
+	TokenSerials           string
 	Tokens                 string
 	Ownership              string
 	PublicParams           string
 	Wallets                string
+	LongTermIdentities     string
 	IdentityConfigurations string
 	IdentityInfo           string
 	Signers                string
+	Recipients             string
 	TokenLocks             string
+	TokenIntents           string
 }
 
+// TableNames are the names of the tables of the stores sharing a table prefix
+type TableNames = tableNames
+
 func GetTableNames(prefix string) (tableNames, error) {
 	nc, err := common.NewTableNameCreator(prefix)
 	if err != nil {
@@ -38,18 +58,35 @@ func GetTableNames(prefix string) (tableNames, error) {
 
 	return tableNames{
 		Movements:              nc.MustGetTableName("movements"),
+		MovementCorrections:    nc.MustGetTableName("movement_corrections"),
 		Transactions:           nc.MustGetTableName("transactions"),
 		TransactionEndorseAck:  nc.MustGetTableName("transaction_endorsements"),
 		Requests:               nc.MustGetTableName("requests"),
+		IssuerAttributions:     nc.MustGetTableName("issuer_attributions"),
+		StatusOverrides:        nc.MustGetTableName("status_overrides"),
+		CompensationFailures:   nc.MustGetTableName("compensation_failures"),
+		IdempotencyKeys:        nc.MustGetTableName("idempotency_keys"),
+		AuditResponses:         nc.MustGetTableName("audit_responses"),
+		FundsWitnesses:         nc.MustGetTableName("funds_witnesses"),
+		References:             nc.MustGetTableName("external_references"),
+		TxWallets:              nc.MustGetTableName("tx_wallets"),
+		ExportOutbox:           nc.MustGetTableName("export_outbox"),
+		Pseudonyms:             nc.MustGetTableName("pseudonyms"),
+		AnonymityLevels:        nc.MustGetTableName("recipient_anonymity_levels"),
 		Validations:            nc.MustGetTableName("request_validations"),
 		Tokens:                 nc.MustGetTableName("tokens"),
 		Ownership:              nc.MustGetTableName("token_ownership"),
 		Certifications:         nc.MustGetTableName("token_certifications"),
+		TokenAttributes:        nc.MustGetTableName("token_attributes"),
+		TokenSerials:           nc.MustGetTableName("token_serials"),
 		TokenLocks:             nc.MustGetTableName("token_locks"),
+		TokenIntents:           nc.MustGetTableName("token_intents"),
 		PublicParams:           nc.MustGetTableName("public_params"),
 		Wallets:                nc.MustGetTableName("wallets"),
+		LongTermIdentities:     nc.MustGetTableName("wallet_long_term_identities"),
 		IdentityConfigurations: nc.MustGetTableName("identity_configurations"),
 		IdentityInfo:           nc.MustGetTableName("identity_information"),
 		Signers:                nc.MustGetTableName("identity_signers"),
+		Recipients:             nc.MustGetTableName("identity_recipients"),
 	}, nil
 }
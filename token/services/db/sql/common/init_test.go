@@ -20,19 +20,36 @@ func TestGetTableNames(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, tableNames{
 		Movements:              "movements",
+		MovementCorrections:    "movement_corrections",
 		Transactions:           "transactions",
 		Requests:               "requests",
 		Validations:            "request_validations",
 		TransactionEndorseAck:  "transaction_endorsements",
+		IssuerAttributions:     "issuer_attributions",
+		StatusOverrides:        "status_overrides",
+		CompensationFailures:   "compensation_failures",
+		IdempotencyKeys:        "idempotency_keys",
+		AuditResponses:         "audit_responses",
+		FundsWitnesses:         "funds_witnesses",
+		References:             "external_references",
+		TxWallets:              "tx_wallets",
+		ExportOutbox:           "export_outbox",
+		Pseudonyms:             "pseudonyms",
+		AnonymityLevels:        "recipient_anonymity_levels",
 		Certifications:         "token_certifications",
+		TokenAttributes:        "token_attributes",
+		TokenSerials:           "token_serials",
 		Tokens:                 "tokens",
 		Ownership:              "token_ownership",
 		PublicParams:           "public_params",
 		Wallets:                "wallets",
+		LongTermIdentities:     "wallet_long_term_identities",
 		IdentityConfigurations: "identity_configurations",
 		IdentityInfo:           "identity_information",
 		Signers:                "identity_signers",
+		Recipients:             "identity_recipients",
 		TokenLocks:             "token_locks",
+		TokenIntents:           "token_intents",
 	}, names)
 
 	names, err = GetTableNames("valid_prefix")
@@ -107,6 +107,25 @@ func TestTransactionSql(t *testing.T) {
 			expectedSql:  "WHERE ((tbl.tx_id) IN (($1), ($2), ($3)) AND (sender_eid = $4 OR recipient_eid = $5))",
 			expectedArgs: []interface{}{"transactionID1", "transactionID2", "transactionID3", "alice", "bob"},
 		},
+		{
+			name: "Id prefix, enrollment ids and token types",
+			params: driver.QueryTransactionsParams{
+				IDPrefix:      "abc",
+				EnrollmentIDs: []string{"alice", "bob"},
+				TokenTypes:    []string{"USD"},
+			},
+			expectedSql:  "WHERE (tbl.tx_id LIKE $1 AND ((sender_eid) IN (($2), ($3)) OR (recipient_eid) IN (($4), ($5))) AND token_type = $6)",
+			expectedArgs: []interface{}{"abc%", "alice", "bob", "alice", "bob", "USD"},
+		},
+		{
+			name: "Wallet and status",
+			params: driver.QueryTransactionsParams{
+				WalletID: "alice",
+				Statuses: []driver.TxStatus{driver.Confirmed},
+			},
+			expectedSql:  "WHERE (status = $1 AND wallet_id = $2)",
+			expectedArgs: []interface{}{driver.Confirmed, "alice"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -235,6 +254,18 @@ func TestMovementConditions(t *testing.T) {
 	}
 }
 
+func TestIssuerAttributionsSql(t *testing.T) {
+	where, args := common.Where(b.HasIssuerAttributionsParams(driver.QueryIssuerAttributionsParams{
+		TokenTypes:   []string{"USD"},
+		Unattributed: true,
+	}, "attributions"))
+	assert.Equal(t, "WHERE (token_type = $1 AND attributions.issuer_id = '' AND status != 3)", where)
+	compareArgs(t, []any{"USD"}, args)
+
+	// the empty issuer id is rewritten by the dialects storing the empty string as NULL
+	assert.Contains(t, OracleDialect().Rewrite("SELECT tx_id FROM attributions "+where), "attributions.issuer_id IS NULL")
+}
+
 func TestTokenSql(t *testing.T) {
 	testCases := []struct {
 		name         string
@@ -245,7 +276,7 @@ func TestTokenSql(t *testing.T) {
 		{
 			name:         "no filter",
 			params:       driver.QueryTokenDetailsParams{},
-			expectedSql:  "WHERE (owner = true AND is_deleted = false)",
+			expectedSql:  "WHERE (owner = true AND is_deleted = false AND pending = false)",
 			expectedArgs: []interface{}{},
 		},
 		{
@@ -253,13 +284,13 @@ func TestTokenSql(t *testing.T) {
 			params: driver.QueryTokenDetailsParams{
 				IncludeDeleted: true,
 			},
-			expectedSql:  "WHERE (owner = true)",
+			expectedSql:  "WHERE (owner = true AND pending = false)",
 			expectedArgs: []interface{}{},
 		},
 		{
 			name:         "owner unspent",
 			params:       driver.QueryTokenDetailsParams{WalletID: "me"},
-			expectedSql:  "WHERE (owner = true AND owner_wallet_id = $1 AND is_deleted = false)",
+			expectedSql:  "WHERE (owner = true AND owner_wallet_id = $1 AND is_deleted = false AND pending = false)",
 			expectedArgs: []interface{}{"me"},
 		},
 		{
@@ -268,7 +299,7 @@ func TestTokenSql(t *testing.T) {
 				WalletID:       "me",
 				IncludeDeleted: true,
 			},
-			expectedSql:  "WHERE (owner = true AND owner_wallet_id = $1)",
+			expectedSql:  "WHERE (owner = true AND owner_wallet_id = $1 AND pending = false)",
 			expectedArgs: []interface{}{"me"},
 		},
 		{
@@ -278,13 +309,13 @@ func TestTokenSql(t *testing.T) {
 				OwnerType:      "htlc",
 				IncludeDeleted: true,
 			},
-			expectedSql:  "WHERE (owner = true AND owner_type = $1 AND owner_wallet_id = $2)",
+			expectedSql:  "WHERE (owner = true AND owner_type = $1 AND owner_wallet_id = $2 AND pending = false)",
 			expectedArgs: []interface{}{"htlc", "me"},
 		},
 		{
 			name:         "owner and type",
 			params:       driver.QueryTokenDetailsParams{TokenType: "tok", WalletID: "me"},
-			expectedSql:  "WHERE (owner = true AND token_type = $1 AND owner_wallet_id = $2 AND is_deleted = false)",
+			expectedSql:  "WHERE (owner = true AND token_type = $1 AND owner_wallet_id = $2 AND is_deleted = false AND pending = false)",
 			expectedArgs: []interface{}{"tok", "me"},
 		},
 		{
@@ -294,7 +325,7 @@ func TestTokenSql(t *testing.T) {
 				WalletID:  "me",
 				IDs:       []*token.ID{{TxId: "a", Index: 1}},
 			},
-			expectedSql:  "WHERE (owner = true AND token_type = $1 AND (tx_id, idx) IN (($2, $3)) AND owner_wallet_id = $4 AND is_deleted = false)",
+			expectedSql:  "WHERE (owner = true AND token_type = $1 AND (tx_id, idx) IN (($2, $3)) AND owner_wallet_id = $4 AND is_deleted = false AND pending = false)",
 			expectedArgs: []interface{}{"tok", "a", 1, "me"},
 		},
 		{
@@ -304,9 +335,19 @@ func TestTokenSql(t *testing.T) {
 				IDs:            []*token.ID{{TxId: "a", Index: 1}, {TxId: "b", Index: 2}},
 				IncludeDeleted: true,
 			},
-			expectedSql:  "WHERE (owner = true AND token_type = $1 AND (tx_id, idx) IN (($2, $3), ($4, $5)))",
+			expectedSql:  "WHERE (owner = true AND token_type = $1 AND (tx_id, idx) IN (($2, $3), ($4, $5)) AND pending = false)",
 			expectedArgs: []interface{}{"tok", "a", uint64(1), "b", uint64(2)},
 		},
+		{
+			name: "pending",
+			params: driver.QueryTokenDetailsParams{
+				WalletID:  "me",
+				TokenType: "tok",
+				Pending:   true,
+			},
+			expectedSql:  "WHERE (owner = true AND token_type = $1 AND owner_wallet_id = $2 AND is_deleted = false AND pending = true)",
+			expectedArgs: []interface{}{"tok", "me"},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -321,9 +362,17 @@ func TestTokenSql(t *testing.T) {
 		WalletID: "me",
 	}, "A"))
 	join := joinOnTokenID("A", "B")
-	assert.Equal(t, "WHERE (owner = true AND (A.tx_id, A.idx) IN (($1, $2)) AND (wallet_id = $3 OR owner_wallet_id = $4) AND is_deleted = false)", where, "join")
+	assert.Equal(t, "WHERE (owner = true AND (A.tx_id, A.idx) IN (($1, $2)) AND (wallet_id = $3 OR owner_wallet_id = $4) AND is_deleted = false AND pending = false)", where, "join")
 	assert.Equal(t, "LEFT JOIN B ON A.tx_id = B.tx_id AND A.idx = B.idx", join, "join")
 	assert.Len(t, args, 4)
+
+	// or and not
+	where, args = common.Where(b.HasTokenDetails(driver.QueryTokenDetailsParams{
+		AnyOf:  []driver.TokenFilter{{TokenType: "A"}, {TokenType: "B", OwnerType: "htlc"}},
+		NoneOf: []driver.TokenFilter{{WalletID: "X"}},
+	}, "A"))
+	assert.Equal(t, "WHERE (owner = true AND ((token_type = $1) OR (owner_type = $2 AND token_type = $3)) AND (CASE WHEN (((wallet_id = $4 OR owner_wallet_id = $5))) THEN 1 ELSE 0 END) = 0 AND is_deleted = false AND pending = false)", where, "or and not")
+	compareArgs(t, []any{"A", "htlc", "B", "X", "X"}, args)
 }
 
 func TestTokenSqlNoJoin(t *testing.T) {
@@ -336,7 +385,7 @@ func TestTokenSqlNoJoin(t *testing.T) {
 		{
 			name:         "no filter",
 			params:       driver.QueryTokenDetailsParams{},
-			expectedSql:  "WHERE (owner = true AND is_deleted = false)",
+			expectedSql:  "WHERE (owner = true AND is_deleted = false AND pending = false)",
 			expectedArgs: []interface{}{},
 		},
 		{
@@ -344,13 +393,13 @@ func TestTokenSqlNoJoin(t *testing.T) {
 			params: driver.QueryTokenDetailsParams{
 				IncludeDeleted: true,
 			},
-			expectedSql:  "WHERE (owner = true)",
+			expectedSql:  "WHERE (owner = true AND pending = false)",
 			expectedArgs: []interface{}{},
 		},
 		{
 			name:         "owner unspent",
 			params:       driver.QueryTokenDetailsParams{WalletID: "me"},
-			expectedSql:  "WHERE (owner = true AND owner_wallet_id = $1 AND is_deleted = false)",
+			expectedSql:  "WHERE (owner = true AND owner_wallet_id = $1 AND is_deleted = false AND pending = false)",
 			expectedArgs: []interface{}{"me"},
 		},
 		{
@@ -359,7 +408,7 @@ func TestTokenSqlNoJoin(t *testing.T) {
 				WalletID:       "me",
 				IncludeDeleted: true,
 			},
-			expectedSql:  "WHERE (owner = true AND owner_wallet_id = $1)",
+			expectedSql:  "WHERE (owner = true AND owner_wallet_id = $1 AND pending = false)",
 			expectedArgs: []interface{}{"me"},
 		},
 		{
@@ -369,13 +418,13 @@ func TestTokenSqlNoJoin(t *testing.T) {
 				OwnerType:      "htlc",
 				IncludeDeleted: true,
 			},
-			expectedSql:  "WHERE (owner = true AND owner_type = $1 AND owner_wallet_id = $2)",
+			expectedSql:  "WHERE (owner = true AND owner_type = $1 AND owner_wallet_id = $2 AND pending = false)",
 			expectedArgs: []interface{}{"htlc", "me"},
 		},
 		{
 			name:         "owner and type",
 			params:       driver.QueryTokenDetailsParams{TokenType: "tok", WalletID: "me"},
-			expectedSql:  "WHERE (owner = true AND token_type = $1 AND owner_wallet_id = $2 AND is_deleted = false)",
+			expectedSql:  "WHERE (owner = true AND token_type = $1 AND owner_wallet_id = $2 AND is_deleted = false AND pending = false)",
 			expectedArgs: []interface{}{"tok", "me"},
 		},
 		{
@@ -385,7 +434,7 @@ func TestTokenSqlNoJoin(t *testing.T) {
 				WalletID:  "me",
 				IDs:       []*token.ID{{TxId: "a", Index: 1}},
 			},
-			expectedSql:  "WHERE (owner = true AND token_type = $1 AND (tx_id, idx) IN (($2, $3)) AND owner_wallet_id = $4 AND is_deleted = false)",
+			expectedSql:  "WHERE (owner = true AND token_type = $1 AND (tx_id, idx) IN (($2, $3)) AND owner_wallet_id = $4 AND is_deleted = false AND pending = false)",
 			expectedArgs: []interface{}{"tok", "a", 1, "me"},
 		},
 		{
@@ -395,7 +444,7 @@ func TestTokenSqlNoJoin(t *testing.T) {
 				IDs:            []*token.ID{{TxId: "a", Index: 1}, {TxId: "b", Index: 2}},
 				IncludeDeleted: true,
 			},
-			expectedSql:  "WHERE (owner = true AND token_type = $1 AND (tx_id, idx) IN (($2, $3), ($4, $5)))",
+			expectedSql:  "WHERE (owner = true AND token_type = $1 AND (tx_id, idx) IN (($2, $3), ($4, $5)) AND pending = false)",
 			expectedArgs: []interface{}{"tok", "a", uint64(1), "b", uint64(2)},
 		},
 	}
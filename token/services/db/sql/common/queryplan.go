@@ -0,0 +1,235 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/pkg/utils/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+)
+
+// QueryPlanner returns the execution plan of a query as the database would execute it
+type QueryPlanner interface {
+	Explain(db *DB, name, query string, args ...any) (*driver.QueryPlan, error)
+}
+
+// SQLiteQueryPlanner relies on `EXPLAIN QUERY PLAN`.
+// Each step whose detail reads `SCAN <table>` with no index is reported as a sequential scan.
+type SQLiteQueryPlanner struct{}
+
+func NewSQLiteQueryPlanner() *SQLiteQueryPlanner {
+	return &SQLiteQueryPlanner{}
+}
+
+func (p *SQLiteQueryPlanner) Explain(db *DB, name, query string, args ...any) (*driver.QueryPlan, error) {
+	logger.Debug("EXPLAIN QUERY PLAN "+query, args)
+	rows, err := db.Query("EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed explaining query [%s]", name)
+	}
+	defer rows.Close()
+
+	plan := &driver.QueryPlan{Name: name, Query: query}
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			return nil, errors.Wrapf(err, "failed reading plan of query [%s]", name)
+		}
+		plan.Plan = append(plan.Plan, detail)
+		if table, ok := sqliteSequentialScan(detail); ok {
+			plan.SequentialScans = append(plan.SequentialScans, table)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed reading plan of query [%s]", name)
+	}
+	return plan, nil
+}
+
+// sqliteSequentialScan parses details like `SCAN tokens` or, in older versions, `SCAN TABLE tokens`
+func sqliteSequentialScan(detail string) (string, bool) {
+	fields := strings.Fields(detail)
+	if len(fields) < 2 || fields[0] != "SCAN" || strings.Contains(detail, " USING ") {
+		return "", false
+	}
+	if fields[1] == "TABLE" && len(fields) > 2 {
+		return fields[2], true
+	}
+	return fields[1], true
+}
+
+// PostgresQueryPlanner relies on `EXPLAIN`.
+// Each `Seq Scan on <table>` node is reported as a sequential scan.
+// Notice that Postgres prefers sequential scans on small tables, therefore
+// the plans are meaningful on populated databases only.
+type PostgresQueryPlanner struct{}
+
+func NewPostgresQueryPlanner() *PostgresQueryPlanner {
+	return &PostgresQueryPlanner{}
+}
+
+var postgresSeqScan = regexp.MustCompile(`Seq Scan on (\S+)`)
+
+func (p *PostgresQueryPlanner) Explain(db *DB, name, query string, args ...any) (*driver.QueryPlan, error) {
+	logger.Debug("EXPLAIN "+query, args)
+	rows, err := db.Query("EXPLAIN "+query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed explaining query [%s]", name)
+	}
+	defer rows.Close()
+
+	plan := &driver.QueryPlan{Name: name, Query: query}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, errors.Wrapf(err, "failed reading plan of query [%s]", name)
+		}
+		plan.Plan = append(plan.Plan, line)
+		if m := postgresSeqScan.FindStringSubmatch(line); m != nil {
+			plan.SequentialScans = append(plan.SequentialScans, m[1])
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed reading plan of query [%s]", name)
+	}
+	return plan, nil
+}
+
+// OracleQueryPlanner relies on `EXPLAIN PLAN` and `DBMS_XPLAN`.
+// Each `TABLE ACCESS FULL` step is reported as a sequential scan.
+// The arguments are inlined, since `EXPLAIN PLAN` does not bind values.
+type OracleQueryPlanner struct{}
+
+func NewOracleQueryPlanner() *OracleQueryPlanner {
+	return &OracleQueryPlanner{}
+}
+
+var oracleFullScan = regexp.MustCompile(`TABLE ACCESS FULL\s*\|\s*(\S+)`)
+
+func (p *OracleQueryPlanner) Explain(db *DB, name, query string, args ...any) (*driver.QueryPlan, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed explaining query [%s]", name)
+	}
+	defer conn.Close()
+
+	// the statements run on a single connection, the plan table is per session
+	explain, _ := db.Statement(fmt.Sprintf("EXPLAIN PLAN SET STATEMENT_ID = '%s' FOR %s", name, inlineArgs(query, args)))
+	logger.Debug(explain)
+	if _, err := conn.ExecContext(ctx, explain); err != nil {
+		return nil, errors.Wrapf(err, "failed explaining query [%s]", name)
+	}
+	display, displayArgs := db.Statement("SELECT plan_table_output FROM TABLE(DBMS_XPLAN.DISPLAY('PLAN_TABLE', $1, 'BASIC'))", name)
+	rows, err := conn.QueryContext(ctx, display, displayArgs...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading plan of query [%s]", name)
+	}
+	defer rows.Close()
+
+	plan := &driver.QueryPlan{Name: name, Query: query}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, errors.Wrapf(err, "failed reading plan of query [%s]", name)
+		}
+		plan.Plan = append(plan.Plan, line)
+		if m := oracleFullScan.FindStringSubmatch(line); m != nil {
+			plan.SequentialScans = append(plan.SequentialScans, strings.ToLower(m[1]))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed reading plan of query [%s]", name)
+	}
+	return plan, nil
+}
+
+// SQLServerQueryPlanner relies on `SET SHOWPLAN_TEXT`.
+// Each `Table Scan` and `Clustered Index Scan` operator is reported as a sequential scan.
+// The arguments are inlined, since the estimated plan of a parameterized query would be the one of `sp_executesql`.
+type SQLServerQueryPlanner struct{}
+
+func NewSQLServerQueryPlanner() *SQLServerQueryPlanner {
+	return &SQLServerQueryPlanner{}
+}
+
+var sqlServerScan = regexp.MustCompile(`(?:Table Scan|Clustered Index Scan)\(OBJECT:\(\[[^\]]+\]\.\[[^\]]+\]\.\[([^\]]+)\]`)
+
+func (p *SQLServerQueryPlanner) Explain(db *DB, name, query string, args ...any) (*driver.QueryPlan, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed explaining query [%s]", name)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SET SHOWPLAN_TEXT ON"); err != nil {
+		return nil, errors.Wrapf(err, "failed explaining query [%s]", name)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SET SHOWPLAN_TEXT OFF"); err != nil {
+			logger.Warnf("failed resetting showplan: %v", err)
+		}
+	}()
+
+	inlined, _ := db.Statement(inlineArgs(query, args))
+	logger.Debug(inlined)
+	rows, err := conn.QueryContext(ctx, inlined)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed explaining query [%s]", name)
+	}
+	defer rows.Close()
+
+	plan := &driver.QueryPlan{Name: name, Query: query}
+	for {
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				return nil, errors.Wrapf(err, "failed reading plan of query [%s]", name)
+			}
+			plan.Plan = append(plan.Plan, line)
+			if m := sqlServerScan.FindStringSubmatch(line); m != nil {
+				plan.SequentialScans = append(plan.SequentialScans, m[1])
+			}
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed reading plan of query [%s]", name)
+	}
+	return plan, nil
+}
+
+// inlineArgs replaces the positional placeholders of the query with the literals of the passed arguments
+func inlineArgs(query string, args []any) string {
+	return placeholder.ReplaceAllStringFunc(query, func(s string) string {
+		var i int
+		if _, err := fmt.Sscanf(s, "$%d", &i); err != nil || i < 1 || i > len(args) {
+			return s
+		}
+		switch v := args[i-1].(type) {
+		case string:
+			return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+		case bool:
+			if v {
+				return "true"
+			}
+			return "false"
+		case int, int32, int64, uint, uint32, uint64:
+			return fmt.Sprintf("%d", v)
+		default:
+			return "'" + strings.ReplaceAll(fmt.Sprintf("%v", v), "'", "''") + "'"
+		}
+	})
+}
@@ -0,0 +1,224 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/common"
+	"github.com/pkg/errors"
+)
+
+// SchemaOpts configures how a schema is created
+type SchemaOpts struct {
+	// SkipForeignKeys omits the foreign keys, to speed up the ingestion of tokens
+	SkipForeignKeys bool `yaml:"skipForeignKeys"`
+	// ConcurrentIndexes creates the indexes without blocking the writes to their tables (Postgres only).
+	// The indexes are created one by one, outside of the transaction creating the tables.
+	ConcurrentIndexes bool `yaml:"concurrentIndexes"`
+}
+
+// Validate returns an error if the passed driver does not support these options
+func (o SchemaOpts) Validate(driver common.SQLDriverType) error {
+	if o.ConcurrentIndexes && driver != sql2.Postgres {
+		return errors.Errorf("concurrent indexes not supported by [%s], only by [%s]", driver, sql2.Postgres)
+	}
+	return nil
+}
+
+// ForeignKey makes the passed columns of a table reference the primary key of another table
+type ForeignKey struct {
+	Columns    []string
+	References string
+}
+
+// Table describes a table.
+// Each column is a definition, like `tx_id TEXT NOT NULL`.
+type Table struct {
+	Name    string
+	Columns []string
+	// Added are the columns added after the first release of the table, defined as the columns.
+	// They must be nullable or have a default. UpgradeSchema adds them to the existing tables missing them.
+	Added       []string
+	PrimaryKey  []string
+	ForeignKeys []ForeignKey
+}
+
+// Statement returns the statement creating the table, if it does not exist
+func (t Table) Statement(opts SchemaOpts) string {
+	definitions := append(append([]string{}, t.Columns...), t.Added...)
+	if len(t.PrimaryKey) != 0 {
+		definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(t.PrimaryKey, ", ")))
+	}
+	if !opts.SkipForeignKeys {
+		for _, fk := range t.ForeignKeys {
+			definitions = append(definitions, fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s", strings.Join(fk.Columns, ", "), fk.References))
+		}
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", t.Name, strings.Join(definitions, ",\n\t"))
+}
+
+// UpgradeStatements returns the statements adding the columns added after the first release of the table
+func (t Table) UpgradeStatements() []string {
+	statements := make([]string, len(t.Added))
+	for i, column := range t.Added {
+		statements[i] = fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", t.Name, column)
+	}
+	return statements
+}
+
+// Index describes an index
+type Index struct {
+	Name    string
+	Table   string
+	Columns []string
+}
+
+// Statement returns the statement creating the index, if it does not exist
+func (i Index) Statement(opts SchemaOpts) string {
+	create := "CREATE INDEX"
+	if opts.ConcurrentIndexes {
+		create = "CREATE INDEX CONCURRENTLY"
+	}
+	return fmt.Sprintf("%s IF NOT EXISTS %s ON %s ( %s )", create, i.Name, i.Table, strings.Join(i.Columns, ", "))
+}
+
+// Schema is the list of tables and indexes of a db
+type Schema struct {
+	Tables  []Table
+	Indexes []Index
+}
+
+// TableNames returns the names of the tables
+func (s Schema) TableNames() []string {
+	names := make([]string, len(s.Tables))
+	for i, t := range s.Tables {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// Merge returns the tables and the indexes of both schemas. The tables and indexes with the same name are taken once.
+func (s Schema) Merge(other Schema) Schema {
+	merged := Schema{Tables: append([]Table{}, s.Tables...), Indexes: append([]Index{}, s.Indexes...)}
+	for _, t := range other.Tables {
+		if !merged.hasTable(t.Name) {
+			merged.Tables = append(merged.Tables, t)
+		}
+	}
+	for _, i := range other.Indexes {
+		if !merged.hasIndex(i.Name) {
+			merged.Indexes = append(merged.Indexes, i)
+		}
+	}
+	return merged
+}
+
+func (s Schema) hasTable(name string) bool {
+	for _, t := range s.Tables {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Schema) hasIndex(name string) bool {
+	for _, i := range s.Indexes {
+		if i.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Statements returns the statements creating the schema, the tables first.
+// They can be reviewed, or executed by hand, instead of letting the db create the schema.
+func (s Schema) Statements(opts SchemaOpts) []string {
+	statements := make([]string, 0, len(s.Tables)+len(s.Indexes))
+	for _, t := range s.Tables {
+		statements = append(statements, t.Statement(opts))
+	}
+	for _, i := range s.Indexes {
+		statements = append(statements, i.Statement(opts))
+	}
+	return statements
+}
+
+// UpgradeStatements returns the statements adding to the tables of a db created by a previous release the columns added since.
+// They can be executed by hand, only the columns missing from a table must be added.
+func (s Schema) UpgradeStatements() []string {
+	var statements []string
+	for _, t := range s.Tables {
+		statements = append(statements, t.UpgradeStatements()...)
+	}
+	return statements
+}
+
+// String returns the statements creating the schema with the default options, separated by semicolons
+func (s Schema) String() string {
+	return strings.Join(s.Statements(SchemaOpts{}), ";\n") + ";\n"
+}
+
+// CreateSchema creates the passed schema, if it does not exist, and upgrades the existing tables.
+// The tables, and the indexes unless they are created concurrently, are created in a single transaction.
+func CreateSchema(db *DB, schema Schema, opts SchemaOpts) error {
+	if err := UpgradeSchema(db, schema); err != nil {
+		return err
+	}
+	if !opts.ConcurrentIndexes {
+		statements, _ := db.Statement(strings.Join(schema.Statements(opts), ";\n"))
+		return common.InitSchema(db.DB, statements)
+	}
+	statements, _ := db.Statement(strings.Join(Schema{Tables: schema.Tables}.Statements(opts), ";\n"))
+	if err := common.InitSchema(db.DB, statements); err != nil {
+		return err
+	}
+	for _, i := range schema.Indexes {
+		statement := i.Statement(opts)
+		logger.Debug(statement)
+		if _, err := db.Exec(statement); err != nil {
+			return errors.Wrapf(err, "error creating index: %s", statement)
+		}
+	}
+	return nil
+}
+
+// UpgradeSchema adds to the existing tables of the passed schema the added columns they miss.
+// The tables that do not exist are left to CreateSchema.
+func UpgradeSchema(db *DB, schema Schema) error {
+	for _, t := range schema.Tables {
+		if len(t.Added) == 0 || !queryable(db, fmt.Sprintf("SELECT 1 FROM %s WHERE 1 = 0", t.Name)) {
+			continue
+		}
+		statements := t.UpgradeStatements()
+		for i, column := range t.Added {
+			name := strings.Fields(column)[0]
+			if queryable(db, fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", name, t.Name)) {
+				continue
+			}
+			logger.Infof("upgrading table [%s]: %s", t.Name, statements[i])
+			if _, err := db.Exec(statements[i]); err != nil {
+				return errors.Wrapf(err, "error upgrading table: %s", statements[i])
+			}
+		}
+	}
+	return nil
+}
+
+// queryable returns true if the passed query, that returns no rows, succeeds.
+// It tells whether a table or a column exists, in any database.
+func queryable(db *DB, query string) bool {
+	logger.Debug(query)
+	rows, err := db.Query(query)
+	if err != nil {
+		return false
+	}
+	return rows.Close() == nil
+}
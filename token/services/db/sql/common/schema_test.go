@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+
+	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaStatements(t *testing.T) {
+	table := Table{
+		Name:        "ownership",
+		Columns:     []string{"tx_id TEXT NOT NULL", "idx INT NOT NULL"},
+		PrimaryKey:  []string{"tx_id", "idx"},
+		ForeignKeys: []ForeignKey{{Columns: []string{"tx_id", "idx"}, References: "tokens"}},
+	}
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS ownership (\n\ttx_id TEXT NOT NULL,\n\tidx INT NOT NULL,\n\tPRIMARY KEY (tx_id, idx),\n\tFOREIGN KEY (tx_id, idx) REFERENCES tokens\n)", table.Statement(SchemaOpts{}))
+	assert.NotContains(t, table.Statement(SchemaOpts{SkipForeignKeys: true}), "FOREIGN KEY")
+
+	index := Index{Name: "idx_tx_id", Table: "tokens", Columns: []string{"tx_id"}}
+	assert.Equal(t, "CREATE INDEX IF NOT EXISTS idx_tx_id ON tokens ( tx_id )", index.Statement(SchemaOpts{}))
+	assert.Equal(t, "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_tx_id ON tokens ( tx_id )", index.Statement(SchemaOpts{ConcurrentIndexes: true}))
+
+	// concurrent indexes are created by Postgres only
+	assert.NoError(t, SchemaOpts{ConcurrentIndexes: true}.Validate(sql2.Postgres))
+	assert.NoError(t, SchemaOpts{}.Validate(sql2.SQLite))
+	assert.ErrorContains(t, SchemaOpts{ConcurrentIndexes: true}.Validate(sql2.SQLite), "concurrent indexes not supported by [sqlite]")
+
+	// the tables are created before the indexes
+	statements := Schema{Tables: []Table{table}, Indexes: []Index{index}}.Statements(SchemaOpts{})
+	assert.Len(t, statements, 2)
+	assert.True(t, strings.HasPrefix(statements[0], "CREATE TABLE"))
+
+	// the shared tables of the shards are created once
+	schema, err := TokenDBSchema(NewDBOpts{TablePrefix: "test", Shards: 2})
+	assert.NoError(t, err)
+	assert.Len(t, schema.Tables, 2*5+2)
+	assert.Len(t, schema.Indexes, 2*4+1)
+}
+
+func TestCreateSchemaWithoutForeignKeys(t *testing.T) {
+	sqlDB, err := NewSQLDBOpener("", "").OpenSQLDB(sql2.SQLite, fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", path.Join(t.TempDir(), "db.sqlite")), 10, false)
+	assert.NoError(t, err)
+	defer sqlDB.Close()
+
+	schema, err := TokenDBSchema(NewDBOpts{TablePrefix: "nofk"})
+	assert.NoError(t, err)
+	assert.NoError(t, CreateSchema(NewDB(sqlDB, 0), schema, SchemaOpts{SkipForeignKeys: true}))
+
+	// without foreign keys, the ownership of a token can be stored before the token
+	_, err = sqlDB.Exec("INSERT INTO nofk_token_ownership (tx_id, idx, wallet_id) VALUES ('tx1', 0, 'alice')")
+	assert.NoError(t, err)
+
+	// with foreign keys, it cannot
+	schema, err = TokenDBSchema(NewDBOpts{TablePrefix: "fk"})
+	assert.NoError(t, err)
+	assert.NoError(t, CreateSchema(NewDB(sqlDB, 0), schema, SchemaOpts{}))
+	_, err = sqlDB.Exec("INSERT INTO fk_token_ownership (tx_id, idx, wallet_id) VALUES ('tx1', 0, 'alice')")
+	assert.Error(t, err)
+}
+
+func TestUpgradeSchema(t *testing.T) {
+	sqlDB, err := NewSQLDBOpener("", "").OpenSQLDB(sql2.SQLite, fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", path.Join(t.TempDir(), "db.sqlite")), 10, false)
+	assert.NoError(t, err)
+	defer sqlDB.Close()
+
+	schema, err := TokenDBSchema(NewDBOpts{TablePrefix: "old"})
+	assert.NoError(t, err)
+	tokens := schema.Tables[0]
+	added := 0
+	for _, table := range schema.Tables {
+		added += len(table.Added)
+	}
+	assert.Len(t, schema.UpgradeStatements(), added)
+
+	// a tokens table created before the columns were added, with a token
+	old := Schema{Tables: []Table{{Name: tokens.Name, Columns: tokens.Columns, PrimaryKey: tokens.PrimaryKey}}}
+	assert.NoError(t, CreateSchema(NewDB(sqlDB, 0), old, SchemaOpts{}))
+	_, err = sqlDB.Exec(fmt.Sprintf("INSERT INTO %s (tx_id, idx, amount, token_type, quantity, owner_raw, owner_type, owner_identity, ledger, ledger_metadata, stored_at) VALUES ('tx1', 0, 10, 'USD', '0x0a', x'01', 'idemix', x'01', x'01', x'01', CURRENT_TIMESTAMP)", tokens.Name))
+	assert.NoError(t, err)
+	_, err = sqlDB.Exec(fmt.Sprintf("SELECT pending FROM %s", tokens.Name))
+	assert.Error(t, err)
+
+	// the columns are added with their defaults, twice is fine
+	assert.NoError(t, CreateSchema(NewDB(sqlDB, 0), schema, SchemaOpts{}))
+	assert.NoError(t, CreateSchema(NewDB(sqlDB, 0), schema, SchemaOpts{}))
+	var pending bool
+	var ledgerFormat string
+	assert.NoError(t, sqlDB.QueryRow(fmt.Sprintf("SELECT pending, ledger_format FROM %s WHERE tx_id = 'tx1'", tokens.Name)).Scan(&pending, &ledgerFormat))
+	assert.False(t, pending)
+	assert.Empty(t, ledgerFormat)
+}
@@ -0,0 +1,785 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/pkg/utils/errors"
+	driver2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver"
+	tdriver "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// ShardTableName returns the name of the passed table for the passed shard
+func ShardTableName(table string, shard int) string {
+	return fmt.Sprintf("%s_s%d", table, shard)
+}
+
+// TokenTableNames returns the names of the tokens tables, one per shard.
+// With less than two shards, the tokens table is not sharded.
+func TokenTableNames(table string, shards int) []string {
+	if shards < 2 {
+		return []string{table}
+	}
+	names := make([]string, shards)
+	for i := range names {
+		names[i] = ShardTableName(table, i)
+	}
+	return names
+}
+
+// ShardedTokenDB is a token db whose tokens are split across shards by the hash of the owner wallet id.
+// Each shard has its own tokens, ownership, certifications, attributes, and serials tables, the public parameters and the intents are shared.
+// A token is stored in the shard of each of its owner wallets, together with the ownership of the wallets of that shard.
+// Therefore, the queries by wallet hit a single shard, while the queries by token id hit all of them.
+type ShardedTokenDB struct {
+	db     *DB
+	shards []*TokenDB
+	// isolation is the isolation level of the transactions returned by NewTokenDBTransaction
+	isolation sql.IsolationLevel
+	// sr, if set, reports the disk usage of the tables
+	sr SizeReporter
+}
+
+func newShardedTokenDB(db *DB, tables tableNames, shards int, ci TokenInterpreter, qp QueryPlanner) *ShardedTokenDB {
+	s := &ShardedTokenDB{db: db, shards: make([]*TokenDB, shards)}
+	for i := range s.shards {
+		s.shards[i] = newTokenDB(db, tokenTables{
+			Tokens:         ShardTableName(tables.Tokens, i),
+			Ownership:      ShardTableName(tables.Ownership, i),
+			PublicParams:   tables.PublicParams,
+			Certifications: ShardTableName(tables.Certifications, i),
+			Attributes:     ShardTableName(tables.TokenAttributes, i),
+			Serials:        ShardTableName(tables.TokenSerials, i),
+			Intents:        tables.TokenIntents,
+		}, ci, qp)
+	}
+	return s
+}
+
+// shardOf returns the shard of the passed wallet
+func (db *ShardedTokenDB) shardOf(walletID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(walletID))
+	return int(h.Sum32() % uint32(len(db.shards)))
+}
+
+// route returns the shards storing the tokens of the passed wallet, all of them if no wallet is passed
+func (db *ShardedTokenDB) route(walletID string) []*TokenDB {
+	if len(walletID) == 0 {
+		return db.shards
+	}
+	return []*TokenDB{db.shards[db.shardOf(walletID)]}
+}
+
+// placement returns, for each shard storing the passed token, the owners of that shard
+func (db *ShardedTokenDB) placement(tr driver.TokenRecord, owners []string) map[int][]string {
+	placement := map[int][]string{}
+	for _, owner := range owners {
+		s := db.shardOf(owner)
+		placement[s] = append(placement[s], owner)
+	}
+	if len(tr.OwnerWalletID) != 0 {
+		if s := db.shardOf(tr.OwnerWalletID); placement[s] == nil {
+			placement[s] = []string{}
+		}
+	}
+	if len(placement) == 0 {
+		placement[0] = []string{}
+	}
+	return placement
+}
+
+// locate returns, for each shard, the positions of the passed ids stored in it.
+// Each id is assigned to the first shard storing it. The missing ids are assigned to the first shard,
+// so that they are reported as they would be without sharding.
+func (db *ShardedTokenDB) locate(ids []*token.ID) ([][]int, error) {
+	positions := make([][]int, len(db.shards))
+	assigned := make([]bool, len(ids))
+	for s, shard := range db.shards {
+		exist, err := shard.ExistAll(ids)
+		if err != nil {
+			return nil, err
+		}
+		for i, ok := range exist {
+			if ok && !assigned[i] {
+				assigned[i] = true
+				positions[s] = append(positions[s], i)
+			}
+		}
+	}
+	for i, ok := range assigned {
+		if !ok {
+			positions[0] = append(positions[0], i)
+		}
+	}
+	return positions, nil
+}
+
+// byShard runs the passed function on each shard with the ids it stores, and returns the results in the order of the ids
+func byShard[T any](db *ShardedTokenDB, ids []*token.ID, f func(shard *TokenDB, ids []*token.ID) ([]T, error)) ([]T, error) {
+	positions, err := db.locate(ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]T, len(ids))
+	for s, pos := range positions {
+		if len(pos) == 0 {
+			continue
+		}
+		sub := make([]*token.ID, len(pos))
+		for j, i := range pos {
+			sub[j] = ids[i]
+		}
+		out, err := f(db.shards[s], sub)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range pos {
+			res[i] = out[j]
+		}
+	}
+	return res, nil
+}
+
+// mergeByID runs the passed function on each shard, and merges the results, keeping the first value found for each id
+func mergeByID[V any](db *ShardedTokenDB, f func(shard *TokenDB) (map[token.ID]V, error)) (map[token.ID]V, error) {
+	res := map[token.ID]V{}
+	for i, shard := range db.shards {
+		values, err := f(shard)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed querying shard [%d]", i)
+		}
+		for id, v := range values {
+			if _, ok := res[id]; !ok {
+				res[id] = v
+			}
+		}
+	}
+	return res, nil
+}
+
+func (db *ShardedTokenDB) StoreToken(tr driver.TokenRecord, owners []string) (err error) {
+	tx, err := db.NewTokenDBTransaction(context.TODO())
+	if err != nil {
+		return
+	}
+	if err = tx.StoreToken(context.TODO(), tr, owners); err != nil {
+		if err1 := tx.Rollback(); err1 != nil {
+			logger.Errorf("error rolling back: %s", err1.Error())
+		}
+		return
+	}
+	return tx.Commit()
+}
+
+func (db *ShardedTokenDB) DeleteTokens(deletedBy string, ids ...*token.ID) error {
+	// a token can be stored in more than one shard
+	for _, shard := range db.shards {
+		if err := shard.DeleteTokens(deletedBy, ids...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *ShardedTokenDB) IsMine(txID string, index uint64) (bool, error) {
+	for _, shard := range db.shards {
+		mine, err := shard.IsMine(txID, index)
+		if err != nil || mine {
+			return mine, err
+		}
+	}
+	return false, nil
+}
+
+func (db *ShardedTokenDB) UnspentTokensIterator() (tdriver.UnspentTokensIterator, error) {
+	return db.UnspentTokensIteratorBy(context.TODO(), "", "")
+}
+
+func (db *ShardedTokenDB) UnspentTokensIteratorBy(ctx context.Context, walletID, tokenType string) (tdriver.UnspentTokensIterator, error) {
+	// the query joins the ownership, each shard returns the rows of its wallets
+	return concat(db.route(walletID), nil, func(shard *TokenDB) (tdriver.UnspentTokensIterator, error) {
+		return shard.UnspentTokensIteratorBy(ctx, walletID, tokenType)
+	})
+}
+
+func (db *ShardedTokenDB) UnspentTokensIteratorByAttribute(ctx context.Context, walletID, key, value string) (tdriver.UnspentTokensIterator, error) {
+	// without a wallet, a token owned by wallets of different shards is returned once
+	return concat(db.route(walletID), func(t *token.UnspentToken) string { return t.Id.String() }, func(shard *TokenDB) (tdriver.UnspentTokensIterator, error) {
+		return shard.UnspentTokensIteratorByAttribute(ctx, walletID, key, value)
+	})
+}
+
+func (db *ShardedTokenDB) ExistsTokenWithAttribute(ctx context.Context, key, value string) (bool, error) {
+	for _, shard := range db.shards {
+		exists, err := shard.ExistsTokenWithAttribute(ctx, key, value)
+		if err != nil || exists {
+			return exists, err
+		}
+	}
+	return false, nil
+}
+
+func (db *ShardedTokenDB) UnspentTokensIteratorBySerialRange(ctx context.Context, walletID string, serials token.SerialRange) (tdriver.UnspentTokensIterator, error) {
+	return concat(db.route(walletID), func(t *token.UnspentToken) string { return t.Id.String() }, func(shard *TokenDB) (tdriver.UnspentTokensIterator, error) {
+		return shard.UnspentTokensIteratorBySerialRange(ctx, walletID, serials)
+	})
+}
+
+func (db *ShardedTokenDB) ExistsSerials(ctx context.Context, serials token.SerialRange) (bool, error) {
+	for _, shard := range db.shards {
+		exists, err := shard.ExistsSerials(ctx, serials)
+		if err != nil || exists {
+			return exists, err
+		}
+	}
+	return false, nil
+}
+
+func (db *ShardedTokenDB) SpendableTokensIteratorBy(ctx context.Context, walletID string, typ string) (tdriver.SpendableTokensIterator, error) {
+	return concat(db.route(walletID), func(t *token.UnspentTokenInWallet) string { return t.Id.String() }, func(shard *TokenDB) (tdriver.SpendableTokensIterator, error) {
+		return shard.SpendableTokensIteratorBy(ctx, walletID, typ)
+	})
+}
+
+func (db *ShardedTokenDB) ListUnspentTokensBy(walletID, typ string) (*token.UnspentTokens, error) {
+	it, err := db.UnspentTokensIteratorBy(context.TODO(), walletID, typ)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	tokens := make([]*token.UnspentToken, 0)
+	for {
+		next, err := it.Next()
+		switch {
+		case err != nil:
+			return nil, err
+		case next == nil:
+			return &token.UnspentTokens{Tokens: tokens}, nil
+		default:
+			tokens = append(tokens, next)
+		}
+	}
+}
+
+func (db *ShardedTokenDB) ListUnspentTokens() (*token.UnspentTokens, error) {
+	return db.ListUnspentTokensBy("", "")
+}
+
+func (db *ShardedTokenDB) ListAuditTokens(ids ...*token.ID) ([]*token.Token, error) {
+	return byShard(db, ids, func(shard *TokenDB, ids []*token.ID) ([]*token.Token, error) {
+		return shard.ListAuditTokens(ids...)
+	})
+}
+
+func (db *ShardedTokenDB) ListHistoryIssuedTokens() (*token.IssuedTokens, error) {
+	seen := map[string]struct{}{}
+	tokens := []*token.IssuedToken{}
+	for _, shard := range db.shards {
+		issued, err := shard.ListHistoryIssuedTokens()
+		if err != nil {
+			return nil, err
+		}
+		for _, tok := range issued.Tokens {
+			if _, ok := seen[tok.Id.String()]; ok {
+				continue
+			}
+			seen[tok.Id.String()] = struct{}{}
+			tokens = append(tokens, tok)
+		}
+	}
+	return &token.IssuedTokens{Tokens: tokens}, nil
+}
+
+func (db *ShardedTokenDB) GetTokenOutputs(ids []*token.ID, callback tdriver.QueryCallbackFunc) error {
+	tokens, err := byShard(db, ids, (*TokenDB).getLedgerToken)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(ids); i++ {
+		if err := callback(ids[i], tokens[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *ShardedTokenDB) ExistAll(ids []*token.ID) ([]bool, error) {
+	res := make([]bool, len(ids))
+	for _, shard := range db.shards {
+		exist, err := shard.ExistAll(ids)
+		if err != nil {
+			return nil, err
+		}
+		for i, ok := range exist {
+			res[i] = res[i] || ok
+		}
+	}
+	return res, nil
+}
+
+func (db *ShardedTokenDB) GetTokenInfos(ids []*token.ID) ([][]byte, error) {
+	return db.GetAllTokenInfos(ids)
+}
+
+func (db *ShardedTokenDB) GetTokenInfoAndOutputs(ctx context.Context, ids []*token.ID) ([][]byte, [][]byte, error) {
+	pairs, err := byShard(db, ids, func(shard *TokenDB, ids []*token.ID) ([][2][]byte, error) {
+		tokens, metas, err := shard.getLedgerTokenAndMeta(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		pairs := make([][2][]byte, len(ids))
+		for i := range ids {
+			pairs[i] = [2][]byte{tokens[i], metas[i]}
+		}
+		return pairs, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	tokens, metas := make([][]byte, len(ids)), make([][]byte, len(ids))
+	for i, pair := range pairs {
+		tokens[i], metas[i] = pair[0], pair[1]
+	}
+	return tokens, metas, nil
+}
+
+func (db *ShardedTokenDB) GetAllTokenInfos(ids []*token.ID) ([][]byte, error) {
+	_, metas, err := db.GetTokenInfoAndOutputs(context.TODO(), ids)
+	return metas, err
+}
+
+func (db *ShardedTokenDB) GetTokens(inputs ...*token.ID) ([]*token.Token, error) {
+	return byShard(db, inputs, func(shard *TokenDB, ids []*token.ID) ([]*token.Token, error) {
+		return shard.GetTokens(ids...)
+	})
+}
+
+// GetTokensByID merges the tokens found by the shards, a token stored in more than one shard is taken from the first
+func (db *ShardedTokenDB) GetTokensByID(inputs ...*token.ID) (map[token.ID]*token.Token, error) {
+	return mergeByID(db, func(shard *TokenDB) (map[token.ID]*token.Token, error) {
+		return shard.GetTokensByID(inputs...)
+	})
+}
+
+func (db *ShardedTokenDB) WhoDeletedTokens(inputs ...*token.ID) ([]string, []bool, error) {
+	type deletion struct {
+		spentBy string
+		isSpent bool
+	}
+	deletions, err := byShard(db, inputs, func(shard *TokenDB, ids []*token.ID) ([]deletion, error) {
+		spentBy, isSpent, err := shard.WhoDeletedTokens(ids...)
+		if err != nil {
+			return nil, err
+		}
+		deletions := make([]deletion, len(ids))
+		for i := range ids {
+			deletions[i] = deletion{spentBy: spentBy[i], isSpent: isSpent[i]}
+		}
+		return deletions, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	spentBy, isSpent := make([]string, len(inputs)), make([]bool, len(inputs))
+	for i, d := range deletions {
+		spentBy[i], isSpent[i] = d.spentBy, d.isSpent
+	}
+	return spentBy, isSpent, nil
+}
+
+func (db *ShardedTokenDB) TransactionExists(ctx context.Context, id string) (bool, error) {
+	for _, shard := range db.shards {
+		exists, err := shard.TransactionExists(ctx, id)
+		if err != nil || exists {
+			return exists, err
+		}
+	}
+	return false, nil
+}
+
+func (db *ShardedTokenDB) PendingTransactionExists(ctx context.Context, id string) (bool, error) {
+	for _, shard := range db.shards {
+		exists, err := shard.PendingTransactionExists(ctx, id)
+		if err != nil || exists {
+			return exists, err
+		}
+	}
+	return false, nil
+}
+
+func (db *ShardedTokenDB) SpendablePendingTokensIteratorBy(ctx context.Context, walletID string, typ string) (tdriver.SpendableTokensIterator, error) {
+	return concat(db.route(walletID), func(t *token.UnspentTokenInWallet) string { return t.Id.String() }, func(shard *TokenDB) (tdriver.SpendableTokensIterator, error) {
+		return shard.SpendablePendingTokensIteratorBy(ctx, walletID, typ)
+	})
+}
+
+// SpendPendingTokens marks the passed tokens as spent in every shard, since a token can be stored in more than one
+func (db *ShardedTokenDB) SpendPendingTokens(spentBy string, ids ...*token.ID) error {
+	for i, shard := range db.shards {
+		if err := shard.SpendPendingTokens(spentBy, ids...); err != nil {
+			return errors.WithMessagef(err, "failed spending pending tokens in shard [%d]", i)
+		}
+	}
+	return nil
+}
+
+// DeletePendingTokens deletes the pending tokens of the passed transaction from every shard
+func (db *ShardedTokenDB) DeletePendingTokens(txID string) ([]string, error) {
+	var spentBy []string
+	for i, shard := range db.shards {
+		ids, err := shard.DeletePendingTokens(txID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed deleting pending tokens in shard [%d]", i)
+		}
+		spentBy = append(spentBy, ids...)
+	}
+	slices.Sort(spentBy)
+	return slices.Compact(spentBy), nil
+}
+
+func (db *ShardedTokenDB) StorePublicParams(raw []byte) error {
+	return db.shards[0].StorePublicParams(raw)
+}
+
+func (db *ShardedTokenDB) PublicParams() ([]byte, error) {
+	return db.shards[0].PublicParams()
+}
+
+func (db *ShardedTokenDB) PublicParamsByHash(rawHash tdriver.PPHash) ([]byte, error) {
+	return db.shards[0].PublicParamsByHash(rawHash)
+}
+
+func (db *ShardedTokenDB) AddIntent(txID string, request []byte) error {
+	return db.shards[0].AddIntent(txID, request)
+}
+
+func (db *ShardedTokenDB) MarkIntentApplied(txID string) error {
+	return db.shards[0].MarkIntentApplied(txID)
+}
+
+func (db *ShardedTokenDB) DeleteIntent(txID string) error {
+	return db.shards[0].DeleteIntent(txID)
+}
+
+func (db *ShardedTokenDB) Intent(txID string) (*driver.TokenIntent, error) {
+	return db.shards[0].Intent(txID)
+}
+
+func (db *ShardedTokenDB) Intents() ([]driver.TokenIntent, error) {
+	return db.shards[0].Intents()
+}
+
+func (db *ShardedTokenDB) StoreCertifications(certifications map[*token.ID][]byte) error {
+	ids := make([]*token.ID, 0, len(certifications))
+	for id := range certifications {
+		ids = append(ids, id)
+	}
+	positions, err := db.locate(ids)
+	if err != nil {
+		return err
+	}
+	for s, pos := range positions {
+		if len(pos) == 0 {
+			continue
+		}
+		sub := make(map[*token.ID][]byte, len(pos))
+		for _, i := range pos {
+			sub[ids[i]] = certifications[ids[i]]
+		}
+		if err := db.shards[s].StoreCertifications(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *ShardedTokenDB) ExistsCertification(tokenID *token.ID) bool {
+	for _, shard := range db.shards {
+		if shard.ExistsCertification(tokenID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *ShardedTokenDB) GetCertifications(ids []*token.ID) ([][]byte, error) {
+	return byShard(db, ids, (*TokenDB).GetCertifications)
+}
+
+// GetCertificationsByID merges the certifications found by the shards
+func (db *ShardedTokenDB) GetCertificationsByID(ids []*token.ID) (map[token.ID][]byte, error) {
+	return mergeByID(db, func(shard *TokenDB) (map[token.ID][]byte, error) {
+		return shard.GetCertificationsByID(ids)
+	})
+}
+
+// ListCertifications merges the records listed by the shards, keeping their order and the limit
+func (db *ShardedTokenDB) ListCertifications(params driver.QueryCertificationsParams) ([]*driver.CertificationRecord, error) {
+	var records []*driver.CertificationRecord
+	for i, shard := range db.shards {
+		shardRecords, err := shard.ListCertifications(params)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed listing certifications of shard [%d]", i)
+		}
+		records = append(records, shardRecords...)
+	}
+	slices.SortFunc(records, func(a, b *driver.CertificationRecord) int {
+		if c := strings.Compare(a.TokenID.TxId, b.TokenID.TxId); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.TokenID.Index, b.TokenID.Index)
+	})
+	if params.Limit > 0 && len(records) > params.Limit {
+		records = records[:params.Limit]
+	}
+	return records, nil
+}
+
+func (db *ShardedTokenDB) QueryTokenDetails(params driver.QueryTokenDetailsParams) ([]driver.TokenDetails, error) {
+	deets := []driver.TokenDetails{}
+	for _, shard := range db.route(params.WalletID) {
+		shardDeets, err := shard.QueryTokenDetails(params)
+		if err != nil {
+			return nil, err
+		}
+		deets = append(deets, shardDeets...)
+	}
+	return deets, nil
+}
+
+func (db *ShardedTokenDB) Balance(walletID, typ string) (uint64, error) {
+	var sum uint64
+	for _, shard := range db.route(walletID) {
+		balance, err := shard.Balance(walletID, typ)
+		if err != nil {
+			return 0, err
+		}
+		sum += balance
+	}
+	return sum, nil
+}
+
+// ExplainQueries returns the execution plans of the canonical queries on the first shard, all shards have the same schema
+func (db *ShardedTokenDB) ExplainQueries() ([]driver.QueryPlan, error) {
+	return db.shards[0].ExplainQueries()
+}
+
+// TableSizes returns the disk usage of the tables of all the shards, the shared tables are taken once
+func (db *ShardedTokenDB) TableSizes() ([]driver.TableSize, error) {
+	if db.sr == nil {
+		return nil, driver.ErrTableSizesNotSupported
+	}
+	return db.sr.TableSizes(db.db, db.Schema().TableNames())
+}
+
+// Schema returns the tables and indexes of all the shards, the shared tables are taken once
+func (db *ShardedTokenDB) Schema() Schema {
+	schema := Schema{}
+	for _, shard := range db.shards {
+		schema = schema.Merge(shard.Schema())
+	}
+	return schema
+}
+
+func (db *ShardedTokenDB) GetSchema() string {
+	return db.Schema().String()
+}
+
+func (db *ShardedTokenDB) Close() {
+	db.db.Close()
+}
+
+func (db *ShardedTokenDB) NewTokenDBTransaction(ctx context.Context) (driver.TokenDBTransaction, error) {
+	tx, err := db.db.BeginTx(ctx, &sql.TxOptions{Isolation: db.isolation})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed starting a db transaction with isolation [%s]", db.isolation)
+	}
+	shards := make([]*TokenTransaction, len(db.shards))
+	for i, shard := range db.shards {
+		shards[i] = &TokenTransaction{db: shard, tx: tx}
+	}
+	return &ShardedTokenTransaction{db: db, tx: tx, shards: shards}, nil
+}
+
+// ShardedTokenTransaction spans all the shards of a ShardedTokenDB with a single db transaction
+type ShardedTokenTransaction struct {
+	db     *ShardedTokenDB
+	tx     *Tx
+	shards []*TokenTransaction
+}
+
+func (t *ShardedTokenTransaction) GetToken(ctx context.Context, txID string, index uint64, includeDeleted bool) (*token.Token, []string, error) {
+	// the owners of a token are split across the shards storing it
+	var tok *token.Token
+	owners := []string{}
+	for _, shard := range t.shards {
+		shardTok, shardOwners, err := shard.GetToken(ctx, txID, index, includeDeleted)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tok == nil {
+			tok = shardTok
+		}
+		for _, owner := range shardOwners {
+			if !slices.Contains(owners, owner) {
+				owners = append(owners, owner)
+			}
+		}
+	}
+	return tok, owners, nil
+}
+
+func (t *ShardedTokenTransaction) Delete(ctx context.Context, txID string, index uint64, deletedBy string) error {
+	for _, shard := range t.shards {
+		if err := shard.Delete(ctx, txID, index, deletedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *ShardedTokenTransaction) StoreToken(ctx context.Context, tr driver.TokenRecord, owners []string) error {
+	for s, shardOwners := range t.db.placement(tr, owners) {
+		if err := t.shards[s].StoreToken(ctx, tr, shardOwners); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *ShardedTokenTransaction) DeletePendingTokens(ctx context.Context, txID string) (map[uint64]string, error) {
+	spent := map[uint64]string{}
+	for i, shard := range t.shards {
+		shardSpent, err := shard.DeletePendingTokens(ctx, txID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed deleting pending tokens in shard [%d]", i)
+		}
+		for index, spentBy := range shardSpent {
+			spent[index] = spentBy
+		}
+	}
+	return spent, nil
+}
+
+// RevertDeltas reverts the deltas in every shard, since the tokens can be stored in more than one
+func (t *ShardedTokenTransaction) RevertDeltas(ctx context.Context, txID string, created []uint64, spent []*token.ID) error {
+	for i, shard := range t.shards {
+		if err := shard.RevertDeltas(ctx, txID, created, spent); err != nil {
+			return errors.WithMessagef(err, "failed reverting deltas in shard [%d]", i)
+		}
+	}
+	return nil
+}
+
+func (t *ShardedTokenTransaction) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *ShardedTokenTransaction) Rollback() error {
+	return t.tx.Rollback()
+}
+
+type iterator[T any] interface {
+	Next() (T, error)
+	Close()
+}
+
+// concat returns an iterator over the results of the passed query on each shard, one shard after the other.
+// If key is not nil, the items with the same key are returned once.
+func concat[T comparable, I iterator[T]](shards []*TokenDB, key func(T) string, query func(*TokenDB) (I, error)) (*concatIterator[T, I], error) {
+	it := &concatIterator[T, I]{shards: shards, query: query, key: key, seen: map[string]struct{}{}}
+	if err := it.advance(); err != nil {
+		return nil, err
+	}
+	return it, nil
+}
+
+type concatIterator[T comparable, I iterator[T]] struct {
+	shards  []*TokenDB
+	query   func(*TokenDB) (I, error)
+	key     func(T) string
+	seen    map[string]struct{}
+	current I
+	open    bool
+}
+
+// advance closes the current iterator and opens the one of the next shard, if any
+func (it *concatIterator[T, I]) advance() error {
+	it.Close()
+	if len(it.shards) == 0 {
+		return nil
+	}
+	next, err := it.query(it.shards[0])
+	if err != nil {
+		return err
+	}
+	it.shards, it.current, it.open = it.shards[1:], next, true
+	return nil
+}
+
+func (it *concatIterator[T, I]) Next() (T, error) {
+	var zero T
+	for it.open {
+		next, err := it.current.Next()
+		if err != nil {
+			return zero, err
+		}
+		if next == zero {
+			if err := it.advance(); err != nil {
+				return zero, err
+			}
+			continue
+		}
+		if it.key != nil {
+			k := it.key(next)
+			if _, ok := it.seen[k]; ok {
+				continue
+			}
+			it.seen[k] = struct{}{}
+		}
+		return next, nil
+	}
+	return zero, nil
+}
+
+func (it *concatIterator[T, I]) Close() {
+	if it.open {
+		it.current.Close()
+		it.open = false
+	}
+}
+
+// ShardedNotifier notifies the changes of all the shards of a tokens table
+type ShardedNotifier []driver2.Notifier
+
+func (n ShardedNotifier) Subscribe(callback driver2.TriggerCallback) error {
+	for _, notifier := range n {
+		if err := notifier.Subscribe(callback); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n ShardedNotifier) UnsubscribeAll() error {
+	for _, notifier := range n {
+		if err := notifier.UnsubscribeAll(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
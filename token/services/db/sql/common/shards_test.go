@@ -0,0 +1,145 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"testing"
+
+	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedTokensSqlite(t *testing.T) {
+	sqlDB, err := NewSQLDBOpener("", "").OpenSQLDB(sql2.SQLite, fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", path.Join(t.TempDir(), "db.sqlite")), 10, false)
+	assert.NoError(t, err)
+	tokenDB, err := NewTokenDB(sqlDB, NewDBOpts{TablePrefix: "sharded", CreateSchema: true, Shards: 4}, NewTokenInterpreter(common.NewInterpreter()), NewSQLiteQueryPlanner(), NewSQLiteSizeReporter())
+	assert.NoError(t, err)
+	db, ok := tokenDB.(*ShardedTokenDB)
+	assert.True(t, ok)
+	defer db.Close()
+
+	// alice and dan are in different shards
+	assert.NotEqual(t, db.shardOf("alice"), db.shardOf("dan"))
+
+	record := func(txID string, index uint64) driver.TokenRecord {
+		return driver.TokenRecord{
+			TxID:           txID,
+			Index:          index,
+			IssuerRaw:      []byte{},
+			OwnerRaw:       []byte{1, 2, 3},
+			OwnerType:      "idemix",
+			OwnerIdentity:  []byte{},
+			Ledger:         []byte("ledger"),
+			LedgerMetadata: []byte("meta"),
+			Quantity:       "0x02",
+			Type:           "TST",
+			Amount:         2,
+			Owner:          true,
+		}
+	}
+	assert.NoError(t, db.StoreToken(record("tx1", 0), []string{"alice"}))
+	assert.NoError(t, db.StoreToken(record("tx1", 1), []string{"alice"}))
+	assert.NoError(t, db.StoreToken(record("tx2", 0), []string{"dan"}))
+	assert.NoError(t, db.StoreToken(record("tx3", 0), []string{"alice", "dan"}))
+
+	// each token is stored in the shards of its owners only
+	count := func(shard int) int {
+		var n int
+		assert.NoError(t, sqlDB.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", db.shards[shard].table.Tokens)).Scan(&n))
+		return n
+	}
+	assert.Equal(t, 3, count(db.shardOf("alice")))
+	assert.Equal(t, 2, count(db.shardOf("dan")))
+
+	// queries by wallet hit the shard of the wallet
+	balance, err := db.Balance("alice", "TST")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), balance)
+	balance, err = db.Balance("dan", "TST")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), balance)
+	unspent, err := db.ListUnspentTokensBy("dan", "")
+	assert.NoError(t, err)
+	assert.Len(t, unspent.Tokens, 2)
+
+	// queries without a wallet merge all the shards
+	unspent, err = db.ListUnspentTokens()
+	assert.NoError(t, err)
+	assert.Len(t, unspent.Tokens, 5)
+	spendable, err := db.SpendableTokensIteratorBy(context.TODO(), "", "TST")
+	assert.NoError(t, err)
+	n := 0
+	for tok, err := spendable.Next(); tok != nil; tok, err = spendable.Next() {
+		assert.NoError(t, err)
+		n++
+	}
+	spendable.Close()
+	assert.Equal(t, 4, n)
+
+	// queries by id find the shard storing each token
+	ids := []*token.ID{{TxId: "tx2", Index: 0}, {TxId: "tx1", Index: 1}, {TxId: "tx3", Index: 0}}
+	tokens, err := db.GetTokens(ids...)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 3)
+	metas, err := db.GetAllTokenInfos(ids)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("meta"), []byte("meta"), []byte("meta")}, metas)
+	exist, err := db.ExistAll(append(ids, &token.ID{TxId: "tx4", Index: 0}))
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true, false}, exist)
+	_, err = db.GetTokens(&token.ID{TxId: "tx4", Index: 0})
+	assert.Error(t, err)
+
+	// the owners of a token are merged across its shards
+	tx, err := db.NewTokenDBTransaction(context.TODO())
+	assert.NoError(t, err)
+	tok, owners, err := tx.GetToken(context.TODO(), "tx3", 0, false)
+	assert.NoError(t, err)
+	assert.NotNil(t, tok)
+	assert.ElementsMatch(t, []string{"alice", "dan"}, owners)
+	assert.NoError(t, tx.Delete(context.TODO(), "tx3", 0, "me"))
+	assert.NoError(t, tx.Commit())
+
+	// deletions apply to all the shards storing the token
+	assert.NoError(t, db.DeleteTokens("me", &token.ID{TxId: "tx2", Index: 0}))
+	balance, err = db.Balance("alice", "TST")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), balance)
+	balance, err = db.Balance("dan", "TST")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), balance)
+	spentBy, isSpent, err := db.WhoDeletedTokens(ids...)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"me", "", "me"}, spentBy)
+	assert.Equal(t, []bool{true, false, true}, isSpent)
+
+	// a token with attributes is returned once, whatever the shards storing it
+	nft := record("tx5", 0)
+	nft.Attributes = map[string]string{"LinearID": "house"}
+	assert.NoError(t, db.StoreToken(nft, []string{"alice", "dan"}))
+	for _, walletID := range []string{"", "alice", "dan"} {
+		it, err := db.UnspentTokensIteratorByAttribute(context.TODO(), walletID, "LinearID", "house")
+		assert.NoError(t, err)
+		n := 0
+		for tok, err := it.Next(); tok != nil; tok, err = it.Next() {
+			assert.NoError(t, err)
+			assert.Equal(t, "tx5", tok.Id.TxId)
+			n++
+		}
+		it.Close()
+		assert.Equal(t, 1, n, walletID)
+	}
+	exists, err := db.ExistsTokenWithAttribute(context.TODO(), "LinearID", "house")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
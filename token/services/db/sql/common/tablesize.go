@@ -0,0 +1,153 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+// SizeReporter returns the disk usage of tables, as reported by the catalog of the database
+type SizeReporter interface {
+	TableSizes(db *DB, tables []string) ([]driver.TableSize, error)
+}
+
+// SQLiteSizeReporter relies on the `dbstat` virtual table, for the bytes, and on `COUNT(*)`, for the rows.
+// The pages of the indexes are attributed to their tables.
+type SQLiteSizeReporter struct{}
+
+func NewSQLiteSizeReporter() *SQLiteSizeReporter {
+	return &SQLiteSizeReporter{}
+}
+
+func (r *SQLiteSizeReporter) TableSizes(db *DB, tables []string) ([]driver.TableSize, error) {
+	query := fmt.Sprintf(
+		"SELECT m.tbl_name, SUM(s.pgsize) FROM dbstat s JOIN sqlite_master m ON s.name = m.name WHERE m.tbl_name IN (%s) GROUP BY m.tbl_name",
+		placeholders(len(tables)),
+	)
+	sizes, err := queryTableSizes(db, query, toArgs(tables), func(rows *sql.Rows, size *driver.TableSize) error {
+		return rows.Scan(&size.Table, &size.Bytes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range sizes {
+		// the table names come from the catalog and are not user input
+		if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", sizes[i].Table)).Scan(&sizes[i].Rows); err != nil {
+			return nil, errors.Wrapf(err, "failed counting rows of [%s]", sizes[i].Table)
+		}
+	}
+	return sizes, nil
+}
+
+// PostgresSizeReporter relies on `pg_total_relation_size`, for the bytes, and on the `reltuples` estimate, for the rows.
+// The estimate is refreshed by `VACUUM` and `ANALYZE`.
+type PostgresSizeReporter struct{}
+
+func NewPostgresSizeReporter() *PostgresSizeReporter {
+	return &PostgresSizeReporter{}
+}
+
+func (r *PostgresSizeReporter) TableSizes(db *DB, tables []string) ([]driver.TableSize, error) {
+	query := fmt.Sprintf(
+		"SELECT c.relname, GREATEST(c.reltuples, 0)::BIGINT, pg_total_relation_size(c.oid) FROM pg_class c WHERE c.relkind = 'r' AND c.relname IN (%s)",
+		placeholders(len(tables)),
+	)
+	return queryTableSizes(db, query, toArgs(tables), func(rows *sql.Rows, size *driver.TableSize) error {
+		return rows.Scan(&size.Table, &size.Rows, &size.Bytes)
+	})
+}
+
+// OracleSizeReporter relies on `USER_SEGMENTS`, for the bytes, and on the `NUM_ROWS` statistics, for the rows.
+// Oracle stores the names in upper case, the reported names are in lower case as those of the schema.
+type OracleSizeReporter struct{}
+
+func NewOracleSizeReporter() *OracleSizeReporter {
+	return &OracleSizeReporter{}
+}
+
+func (r *OracleSizeReporter) TableSizes(db *DB, tables []string) ([]driver.TableSize, error) {
+	upper := make([]string, len(tables))
+	for i, t := range tables {
+		upper[i] = strings.ToUpper(t)
+	}
+	query := fmt.Sprintf(
+		"SELECT t.table_name, NVL(t.num_rows, 0), "+
+			"NVL((SELECT SUM(s.bytes) FROM user_segments s WHERE s.segment_name = t.table_name OR s.segment_name IN (SELECT i.index_name FROM user_indexes i WHERE i.table_name = t.table_name)), 0) "+
+			"FROM user_tables t WHERE t.table_name IN (%s)",
+		placeholders(len(tables)),
+	)
+	sizes, err := queryTableSizes(db, query, toArgs(upper), func(rows *sql.Rows, size *driver.TableSize) error {
+		return rows.Scan(&size.Table, &size.Rows, &size.Bytes)
+	})
+	for i := range sizes {
+		sizes[i].Table = strings.ToLower(sizes[i].Table)
+	}
+	return sizes, err
+}
+
+// SQLServerSizeReporter relies on `sys.dm_db_partition_stats`, for both the bytes and the rows
+type SQLServerSizeReporter struct{}
+
+func NewSQLServerSizeReporter() *SQLServerSizeReporter {
+	return &SQLServerSizeReporter{}
+}
+
+func (r *SQLServerSizeReporter) TableSizes(db *DB, tables []string) ([]driver.TableSize, error) {
+	query := fmt.Sprintf(
+		"SELECT t.name, SUM(CASE WHEN p.index_id < 2 THEN p.row_count ELSE 0 END), SUM(p.reserved_page_count) * 8192 "+
+			"FROM sys.dm_db_partition_stats p JOIN sys.tables t ON p.object_id = t.object_id WHERE t.name IN (%s) GROUP BY t.name",
+		placeholders(len(tables)),
+	)
+	return queryTableSizes(db, query, toArgs(tables), func(rows *sql.Rows, size *driver.TableSize) error {
+		return rows.Scan(&size.Table, &size.Rows, &size.Bytes)
+	})
+}
+
+func queryTableSizes(db *DB, query string, args []any, scan func(rows *sql.Rows, size *driver.TableSize) error) ([]driver.TableSize, error) {
+	logger.Debug(query, args)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed querying table sizes")
+	}
+	defer rows.Close()
+
+	var sizes []driver.TableSize
+	for rows.Next() {
+		var size driver.TableSize
+		if err := scan(rows.Rows, &size); err != nil {
+			return nil, errors.Wrapf(err, "failed reading table sizes")
+		}
+		sizes = append(sizes, size)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed reading table sizes")
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i].Table < sizes[j].Table })
+	return sizes, nil
+}
+
+func placeholders(n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return strings.Join(ps, ", ")
+}
+
+func toArgs(values []string) []any {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
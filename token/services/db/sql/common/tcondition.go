@@ -8,6 +8,8 @@ package common
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
@@ -19,8 +21,16 @@ type TokenInterpreter interface {
 	HasTokens(colTxID, colIdx common.FieldName, ids ...*token.ID) common.Condition
 	HasTokenDetails(params driver.QueryTokenDetailsParams, tokenTable string) common.Condition
 	HasMovementsParams(params driver.QueryMovementsParams) common.Condition
-	HasValidationParams(params driver.QueryValidationRecordsParams) common.Condition
+	HasValidationParams(params driver.QueryValidationRecordsParams, table string) common.Condition
 	HasTransactionParams(params driver.QueryTransactionsParams, table string) common.Condition
+	HasIssuerAttributionsParams(params driver.QueryIssuerAttributionsParams, table string) common.Condition
+	HasStatusOverridesParams(params driver.QueryStatusOverridesParams) common.Condition
+	HasMovementCorrectionsParams(params driver.QueryMovementCorrectionsParams, table string) common.Condition
+	HasFundsWitnessesParams(params driver.QueryFundsWitnessesParams) common.Condition
+	IsEmptyString(field common.FieldName) common.Condition
+	// Upsert returns the statement inserting a row in the passed table, or updating the row with the same keys.
+	// The values of the columns are bound to the positional placeholders, in the order of the columns.
+	Upsert(table string, keys []string, columns []string) string
 }
 
 func NewTokenInterpreter(ci common.Interpreter) TokenInterpreter {
@@ -29,6 +39,10 @@ func NewTokenInterpreter(ci common.Interpreter) TokenInterpreter {
 
 type tokenInterpreter struct {
 	common.Interpreter
+	// emptyIsNull is true if the database stores the empty string as NULL
+	emptyIsNull bool
+	// upsert, if set, replaces the `INSERT ... ON CONFLICT` of Postgres and SQLite
+	upsert func(table string, keys []string, columns []string) string
 }
 
 func (c *tokenInterpreter) HasTokens(colTxID, colIdx common.FieldName, ids ...*token.ID) common.Condition {
@@ -48,17 +62,173 @@ func (c *tokenInterpreter) HasTokenDetails(params driver.QueryTokenDetailsParams
 		common.ConstCondition("owner = true"),
 		c.Cmp("owner_type", "=", params.OwnerType),
 		c.Cmp("token_type", "=", params.TokenType),
+		c.Cmp("ledger_format", "=", params.LedgerFormat),
 		c.InStrings(common.JoinCol(tokenTable, "tx_id"), params.TransactionIDs),
+		c.InStrings(common.JoinCol(tokenTable, "spent_by"), params.SpentBy),
 		c.HasTokens(common.JoinCol(tokenTable, "tx_id"), common.JoinCol(tokenTable, "idx"), params.IDs...),
-	}
-	if len(tokenTable) > 0 {
-		conds = append(conds, c.Or(c.Cmp("wallet_id", "=", params.WalletID), c.Cmp("owner_wallet_id", "=", params.WalletID)))
-	} else {
-		conds = append(conds, c.Cmp("owner_wallet_id", "=", params.WalletID))
+		c.hasWallet(params.WalletID, tokenTable),
+		c.anyOf(params.AnyOf, tokenTable),
+		c.noneOf(params.NoneOf, tokenTable),
 	}
 	if !params.IncludeDeleted {
 		conds = append(conds, common.ConstCondition("is_deleted = false"))
 	}
+	if params.Pending {
+		conds = append(conds, common.ConstCondition("pending = true"))
+	} else {
+		conds = append(conds, common.ConstCondition("pending = false"))
+	}
+	return c.And(conds...)
+}
+
+func (c *tokenInterpreter) hasWallet(walletID string, tokenTable string) common.Condition {
+	if len(tokenTable) > 0 {
+		return c.Or(c.Cmp("wallet_id", "=", walletID), c.Cmp("owner_wallet_id", "=", walletID))
+	}
+	return c.Cmp("owner_wallet_id", "=", walletID)
+}
+
+// hasTokenFilter returns the condition matched by the tokens that match all the non-empty fields of the filter
+func (c *tokenInterpreter) hasTokenFilter(f driver.TokenFilter, tokenTable string) common.Condition {
+	return c.And(
+		c.Cmp("owner_type", "=", f.OwnerType),
+		c.Cmp("token_type", "=", f.TokenType),
+		c.Cmp("ledger_format", "=", f.LedgerFormat),
+		c.InStrings(common.JoinCol(tokenTable, "tx_id"), f.TransactionIDs),
+		c.hasWallet(f.WalletID, tokenTable),
+	)
+}
+
+func (c *tokenInterpreter) anyOf(filters []driver.TokenFilter, tokenTable string) common.Condition {
+	conds := make([]common.Condition, len(filters))
+	for i, f := range filters {
+		if f.IsEmpty() {
+			// matches all tokens
+			return common.EmptyCondition
+		}
+		conds[i] = c.hasTokenFilter(f, tokenTable)
+	}
+	return c.Or(conds...)
+}
+
+func (c *tokenInterpreter) noneOf(filters []driver.TokenFilter, tokenTable string) common.Condition {
+	conds := make([]common.Condition, len(filters))
+	for i, f := range filters {
+		if f.IsEmpty() {
+			// excludes all tokens
+			return common.ConstCondition("1 = 0")
+		}
+		conds[i] = c.hasTokenFilter(f, tokenTable)
+	}
+	return Not(c.Or(conds...))
+}
+
+// Not returns the negation of the passed condition, or an empty condition if the passed one is empty.
+// A comparison with a NULL column, like the wallet of a token without owner wallet, counts as not matched,
+// therefore, its negation is matched.
+func Not(cond common.Condition) common.Condition {
+	if cond == common.EmptyCondition {
+		return common.EmptyCondition
+	}
+	return &notCondition{Condition: cond}
+}
+
+// notCondition embeds the negated condition for its parameters.
+// The negation is rendered as a CASE expression, that every dialect supports and that treats NULL as not matched.
+type notCondition struct {
+	common.Condition
+}
+
+func (c *notCondition) ToString(ctr *int) string {
+	return "(CASE WHEN " + c.Condition.ToString(ctr) + " THEN 1 ELSE 0 END) = 0"
+}
+
+func (c *tokenInterpreter) HasIssuerAttributionsParams(params driver.QueryIssuerAttributionsParams, table string) common.Condition {
+	conds := []common.Condition{
+		c.InStrings(common.JoinCol(table, "tx_id"), params.TxIDs),
+		c.InStrings("issuer_id", params.IssuerIDs),
+		c.InStrings("token_type", params.TokenTypes),
+		c.InInts("status", params.Statuses),
+	}
+	if params.Unattributed {
+		conds = append(conds, c.IsEmptyString(common.JoinCol(table, "issuer_id")))
+	}
+	if len(params.Statuses) == 0 {
+		conds = append(conds, common.ConstCondition(fmt.Sprintf("status != %d", driver.Deleted)))
+	}
+	return c.And(conds...)
+}
+
+// IsEmptyString returns the condition matching the rows whose passed text column is empty.
+// Cmp cannot express it, because it drops the comparisons with the empty string.
+func (c *tokenInterpreter) IsEmptyString(field common.FieldName) common.Condition {
+	if c.emptyIsNull {
+		return common.ConstCondition(field + " IS NULL")
+	}
+	return common.ConstCondition(field + " = ''")
+}
+
+func (c *tokenInterpreter) Upsert(table string, keys []string, columns []string) string {
+	if c.upsert != nil {
+		return c.upsert(table, keys, columns)
+	}
+	placeholders := make([]string, len(columns))
+	var updates []string
+	for i, column := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		if !slices.Contains(keys, column) {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", column, column))
+		}
+	}
+	conflict := "DO NOTHING"
+	if len(updates) != 0 {
+		conflict = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s",
+		table, strings.Join(columns, ", "), strings.Join(placeholders, ", "), strings.Join(keys, ", "), conflict)
+}
+
+func (c *tokenInterpreter) HasStatusOverridesParams(params driver.QueryStatusOverridesParams) common.Condition {
+	conds := []common.Condition{
+		c.InStrings("tx_id", params.TxIDs),
+		c.InStrings("operator", params.Operators),
+	}
+	if params.From != nil && !params.From.IsZero() {
+		conds = append(conds, c.Cmp("stored_at", ">=", params.From.UTC()))
+	}
+	if params.To != nil && !params.To.IsZero() {
+		conds = append(conds, c.Cmp("stored_at", "<=", params.To.UTC()))
+	}
+	return c.And(conds...)
+}
+
+func (c *tokenInterpreter) HasMovementCorrectionsParams(params driver.QueryMovementCorrectionsParams, table string) common.Condition {
+	conds := []common.Condition{
+		c.InStrings(common.JoinCol(table, "tx_id"), params.TxIDs),
+		c.InStrings("movement_id", params.MovementIDs),
+		c.InStrings("operator", params.Operators),
+	}
+	if params.From != nil && !params.From.IsZero() {
+		conds = append(conds, c.Cmp(common.JoinCol(table, "stored_at"), ">=", params.From.UTC()))
+	}
+	if params.To != nil && !params.To.IsZero() {
+		conds = append(conds, c.Cmp(common.JoinCol(table, "stored_at"), "<=", params.To.UTC()))
+	}
+	return c.And(conds...)
+}
+
+func (c *tokenInterpreter) HasFundsWitnessesParams(params driver.QueryFundsWitnessesParams) common.Condition {
+	conds := []common.Condition{
+		c.InStrings("tx_id", params.TxIDs),
+		c.InStrings("wallet_id", params.WalletIDs),
+		c.InStrings("token_type", params.TokenTypes),
+	}
+	if params.From != nil && !params.From.IsZero() {
+		conds = append(conds, c.Cmp("stored_at", ">=", params.From.UTC()))
+	}
+	if params.To != nil && !params.To.IsZero() {
+		conds = append(conds, c.Cmp("stored_at", "<=", params.To.UTC()))
+	}
 	return c.And(conds...)
 }
 
@@ -78,11 +248,19 @@ func (c *tokenInterpreter) HasMovementsParams(params driver.QueryMovementsParams
 	} else if params.MovementDirection == driver.Received {
 		conds = append(conds, common.ConstCondition("amount > 0"))
 	}
+	if params.From != nil && !params.From.IsZero() {
+		conds = append(conds, c.Cmp("stored_at", ">=", params.From.UTC()))
+	}
+	if params.To != nil && !params.To.IsZero() {
+		conds = append(conds, c.Cmp("stored_at", "<=", params.To.UTC()))
+	}
 	return c.And(conds...)
 }
 
-func (c *tokenInterpreter) HasValidationParams(params driver.QueryValidationRecordsParams) common.Condition {
-	var conds []common.Condition
+func (c *tokenInterpreter) HasValidationParams(params driver.QueryValidationRecordsParams, table string) common.Condition {
+	conds := []common.Condition{
+		c.InStrings(common.JoinCol(table, "tx_id"), params.TxIDs),
+	}
 
 	if params.From != nil && !params.From.IsZero() {
 		conds = append(conds, c.Cmp("stored_at", ">=", params.From.UTC()))
@@ -118,6 +296,22 @@ func (c *tokenInterpreter) HasTransactionParams(params driver.QueryTransactionsP
 		conds = append(conds, c.InInts("status", common.ToInts(params.Statuses)))
 	}
 
+	if len(params.IDPrefix) > 0 {
+		conds = append(conds, c.Cmp(common.JoinCol(table, "tx_id"), "LIKE", params.IDPrefix+"%"))
+	}
+	if len(params.EnrollmentIDs) > 0 {
+		conds = append(conds, c.Or(
+			c.InStrings("sender_eid", params.EnrollmentIDs),
+			c.InStrings("recipient_eid", params.EnrollmentIDs),
+		))
+	}
+	if len(params.TokenTypes) > 0 {
+		conds = append(conds, c.InStrings("token_type", params.TokenTypes))
+	}
+	if len(params.WalletID) > 0 {
+		conds = append(conds, c.Cmp("wallet_id", "=", params.WalletID))
+	}
+
 	// See QueryTransactionsParams for expected behavior. If only one of sender or
 	// recipient is set, we return all transactions. If both are set, we do an OR.
 	if params.SenderWallet != "" && params.RecipientWallet != "" {
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package common
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"sync"
@@ -15,6 +16,7 @@ import (
 
 	driver2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
+	tdriver "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	assert2 "github.com/stretchr/testify/assert"
@@ -116,7 +118,19 @@ var TokensCases = []struct {
 	{"DeleteMultiple", TDeleteMultiple},
 	{"PublicParams", TPublicParams},
 	{"Certification", TCertification},
+	{"ListCertifications", TListCertifications},
+	{"TokenOrder", TTokenOrder},
 	{"QueryTokenDetails", TQueryTokenDetails},
+	{"ExplainQueries", TExplainQueries},
+	{"TableSizes", TTableSizes},
+	{"Intents", TIntents},
+	{"DeleteExpired", TDeleteExpired},
+	{"RevertDeltas", TRevertDeltas},
+	{"EraseOwnership", TEraseOwnership},
+	{"PendingTokens", TPendingTokens},
+	{"Attributes", TAttributes},
+	{"Serials", TSerials},
+	{"Encryption", TEncryption},
 }
 
 func TTransaction(t *testing.T, db *TokenDB) {
@@ -670,6 +684,19 @@ func TGetTokenInfos(t *testing.T, db *TokenDB) {
 	assert.Equal(t, "tx102l", string(toks[0]))
 	assert.Equal(t, "tx102l", string(toks[1]))
 	assert.Equal(t, "tx101l", string(toks[2]))
+
+	// existence
+	exist, err := db.ExistAll([]*token.ID{
+		{TxId: "tx102", Index: 1},
+		{TxId: "non existent", Index: 0},
+		{TxId: "tx101", Index: 0},
+		{TxId: "tx101", Index: 1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false, true, false}, exist)
+	exist, err = db.ExistAll(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, exist)
 }
 
 func TDeleteMultiple(t *testing.T, db *TokenDB) {
@@ -835,6 +862,126 @@ func TCertification(t *testing.T, db *TokenDB) {
 	assert.Empty(t, certifications)
 }
 
+func TListCertifications(t *testing.T, db *TokenDB) {
+	ids := make([]*token.ID, 5)
+	for i := range ids {
+		ids[i] = &token.ID{TxId: fmt.Sprintf("tx_%d", i), Index: 0}
+		assert.NoError(t, db.StoreToken(driver.TokenRecord{
+			TxID:           ids[i].TxId,
+			Index:          ids[i].Index,
+			OwnerRaw:       []byte{1, 2, 3},
+			OwnerType:      "idemix",
+			OwnerIdentity:  []byte{},
+			Quantity:       "0x01",
+			Ledger:         []byte("ledger"),
+			LedgerMetadata: []byte{},
+			Type:           "ABC",
+			Owner:          true,
+		}, []string{"alice"}))
+	}
+	// tx_0, tx_1, and tx_3 are certified, tx_4 is spent
+	assert.NoError(t, db.StoreCertifications(map[*token.ID][]byte{
+		ids[0]: []byte("certification_0"),
+		ids[1]: []byte("certification_1"),
+		ids[3]: []byte("certification_3"),
+	}))
+	assert.NoError(t, db.DeleteTokens("tx_5", ids[4]))
+	txIDs := func(records []*driver.CertificationRecord) []string {
+		res := make([]string, len(records))
+		for i, r := range records {
+			res[i] = r.TokenID.TxId
+		}
+		return res
+	}
+
+	records, err := db.ListCertifications(driver.QueryCertificationsParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx_0", "tx_1", "tx_3"}, txIDs(records))
+	assert.Equal(t, "certification_0", string(records[0].Certification))
+	assert.False(t, records[0].StoredAt.IsZero())
+
+	// pages of two
+	records, err = db.ListCertifications(driver.QueryCertificationsParams{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx_0", "tx_1"}, txIDs(records))
+	records, err = db.ListCertifications(driver.QueryCertificationsParams{Limit: 2, After: records[1].TokenID})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx_3"}, txIDs(records))
+
+	// the unspent tokens that are not certified
+	records, err = db.ListCertifications(driver.QueryCertificationsParams{Missing: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx_2"}, txIDs(records))
+	assert.Nil(t, records[0].Certification)
+	assert.True(t, records[0].StoredAt.IsZero())
+}
+
+func TTokenOrder(t *testing.T, db *TokenDB) {
+	ids := make([]*token.ID, 10)
+	for i := range ids {
+		ids[i] = &token.ID{TxId: fmt.Sprintf("tx_%d", i%3), Index: uint64(i)}
+		assert.NoError(t, db.StoreToken(driver.TokenRecord{
+			TxID:           ids[i].TxId,
+			Index:          ids[i].Index,
+			OwnerRaw:       []byte{1, 2, 3},
+			OwnerType:      "idemix",
+			OwnerIdentity:  []byte{},
+			Quantity:       fmt.Sprintf("0x%x", i+1),
+			Ledger:         []byte(fmt.Sprintf("ledger_%d", i)),
+			LedgerMetadata: []byte{},
+			Type:           "ABC",
+			Owner:          true,
+			Auditor:        true,
+		}, []string{"alice"}))
+		assert.NoError(t, db.StoreCertifications(map[*token.ID][]byte{ids[i]: []byte(fmt.Sprintf("certification_%d", i))}))
+	}
+	// ask in an order different from the one of the storage, with a duplicate
+	asked := []*token.ID{ids[7], ids[2], ids[9], ids[0], ids[5], ids[2]}
+	positions := []int{7, 2, 9, 0, 5, 2}
+
+	tokens, err := db.GetTokens(asked...)
+	assert.NoError(t, err)
+	audited, err := db.ListAuditTokens(asked...)
+	assert.NoError(t, err)
+	certifications, err := db.GetCertifications(asked)
+	assert.NoError(t, err)
+	_, deleted, err := db.WhoDeletedTokens(asked...)
+	assert.NoError(t, err)
+	assert.Len(t, deleted, len(asked))
+	var outputs []string
+	assert.NoError(t, db.GetTokenOutputs(asked, func(id *token.ID, raw []byte) error {
+		outputs = append(outputs, string(raw))
+		return nil
+	}))
+	for i, p := range positions {
+		assert.Equal(t, fmt.Sprintf("0x%x", p+1), tokens[i].Quantity)
+		assert.Equal(t, fmt.Sprintf("0x%x", p+1), audited[i].Quantity)
+		assert.Equal(t, fmt.Sprintf("certification_%d", p), string(certifications[i]))
+		assert.Equal(t, fmt.Sprintf("ledger_%d", p), outputs[i])
+	}
+
+	// the map variants skip what is missing
+	assert.NoError(t, db.DeleteTokens("tx_10", ids[9]))
+	missing := &token.ID{TxId: "tx_11", Index: 0}
+	byID, err := db.GetTokensByID(ids[9], ids[1], missing)
+	assert.NoError(t, err)
+	assert.Len(t, byID, 1)
+	assert.Equal(t, "0x2", byID[*ids[1]].Quantity)
+	certificationsByID, err := db.GetCertificationsByID([]*token.ID{ids[1], missing})
+	assert.NoError(t, err)
+	assert.Equal(t, map[token.ID][]byte{*ids[1]: []byte("certification_1")}, certificationsByID)
+
+	// the ordered variants fail instead
+	_, err = db.GetTokens(ids[1], ids[9])
+	assert.EqualError(t, err, "token not found for key [tx_0:9]")
+	_, err = db.ListAuditTokens(ids[1], missing)
+	assert.EqualError(t, err, "token not found for key [tx_11:0]")
+	_, _, err = db.WhoDeletedTokens(missing, ids[1])
+	assert.EqualError(t, err, "token not found for key [tx_11:0]")
+	_, err = db.GetCertifications([]*token.ID{ids[1], missing})
+	assert.Error(t, err)
+}
+
 func TQueryTokenDetails(t *testing.T, db *TokenDB) {
 	tx, err := db.NewTokenDBTransaction(context.TODO())
 	if err != nil {
@@ -849,6 +996,7 @@ func TQueryTokenDetails(t *testing.T, db *TokenDB) {
 		OwnerIdentity:  []byte{},
 		Ledger:         []byte("ledger"),
 		LedgerMetadata: []byte{},
+		LedgerFormat:   "fabtoken",
 		Quantity:       "0x01",
 		Type:           "TST1",
 		Amount:         2,
@@ -865,6 +1013,7 @@ func TQueryTokenDetails(t *testing.T, db *TokenDB) {
 		OwnerIdentity:  []byte("{}"),
 		Ledger:         []byte("ledger"),
 		LedgerMetadata: []byte{},
+		LedgerFormat:   "fabtoken/v2",
 		Quantity:       "0x02",
 		Type:           "TST",
 		Amount:         2,
@@ -951,6 +1100,42 @@ func TQueryTokenDetails(t *testing.T, db *TokenDB) {
 	assert.NoError(t, err)
 	assert.Equal(t, res[0].Amount, balance)
 
+	// TST1 or htlc, excluding bob
+	res, err = db.QueryTokenDetails(driver.QueryTokenDetailsParams{
+		AnyOf:  []driver.TokenFilter{{TokenType: "TST1"}, {OwnerType: "htlc"}},
+		NoneOf: []driver.TokenFilter{{WalletID: "bob"}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+	assertEqual(t, tx1, res[0])
+	assertEqual(t, tx2, res[1])
+
+	// not of type TST of alice
+	res, err = db.QueryTokenDetails(driver.QueryTokenDetailsParams{NoneOf: []driver.TokenFilter{{WalletID: "alice", TokenType: "TST"}}})
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+	assertEqual(t, tx1, res[0])
+	assertEqual(t, tx21, res[1])
+
+	// an empty filter matches all tokens
+	res, err = db.QueryTokenDetails(driver.QueryTokenDetailsParams{AnyOf: []driver.TokenFilter{{TokenType: "TST1"}, {}}})
+	assert.NoError(t, err)
+	assert.Len(t, res, 3)
+	res, err = db.QueryTokenDetails(driver.QueryTokenDetailsParams{NoneOf: []driver.TokenFilter{{}}})
+	assert.NoError(t, err)
+	assert.Len(t, res, 0)
+
+	// by ledger format, the tokens without format are those stored before it was recorded
+	res, err = db.QueryTokenDetails(driver.QueryTokenDetailsParams{LedgerFormat: "fabtoken/v2"})
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assertEqual(t, tx2, res[0])
+	res, err = db.QueryTokenDetails(driver.QueryTokenDetailsParams{NoneOf: []driver.TokenFilter{{LedgerFormat: "fabtoken/v2"}}})
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+	assertEqual(t, tx1, res[0])
+	assertEqual(t, tx21, res[1])
+
 	// spent
 	assert.NoError(t, db.DeleteTokens("delby", &token.ID{TxId: "tx2", Index: 1}))
 	res, err = db.QueryTokenDetails(driver.QueryTokenDetailsParams{})
@@ -967,6 +1152,12 @@ func TQueryTokenDetails(t *testing.T, db *TokenDB) {
 	assert.Equal(t, true, res[2].IsSpent, "tx2-1 is spent")
 	assert.Equal(t, "delby", res[2].SpentBy)
 
+	// by spending transaction
+	res, err = db.QueryTokenDetails(driver.QueryTokenDetailsParams{SpentBy: []string{"delby"}, IncludeDeleted: true})
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assertEqual(t, tx21, res[0])
+
 	// by ids
 	res, err = db.QueryTokenDetails(driver.QueryTokenDetailsParams{IDs: []*token.ID{{TxId: "tx1", Index: 0}, {TxId: "tx2", Index: 0}}, IncludeDeleted: true})
 	assert.NoError(t, err)
@@ -980,4 +1171,537 @@ func assertEqual(t *testing.T, r driver.TokenRecord, d driver.TokenDetails) {
 	assert.Equal(t, r.Index, d.Index)
 	assert.Equal(t, r.Amount, d.Amount)
 	assert.Equal(t, r.OwnerType, d.OwnerType)
+	assert.Equal(t, r.LedgerFormat, d.LedgerFormat)
+}
+
+func TExplainQueries(t *testing.T, db *TokenDB) {
+	plans, err := db.ExplainQueries()
+	assert.NoError(t, err)
+	assert.Len(t, plans, 3)
+	for i, name := range []string{"spendable_tokens", "balance", "token_details"} {
+		assert.Equal(t, name, plans[i].Name)
+		assert.Contains(t, plans[i].Query, db.table.Tokens)
+		assert.NotEmpty(t, plans[i].Plan)
+	}
+}
+
+func TTableSizes(t *testing.T, db *TokenDB) {
+	assert.NoError(t, db.StoreToken(driver.TokenRecord{
+		TxID:           "tx_size",
+		Index:          0,
+		OwnerRaw:       []byte{1, 2, 3},
+		OwnerType:      "idemix",
+		OwnerIdentity:  []byte{},
+		Quantity:       "0x01",
+		Amount:         1,
+		Ledger:         []byte("ledger"),
+		LedgerMetadata: []byte{},
+		Type:           "ABC",
+		Owner:          true,
+	}, []string{"alice"}))
+
+	sizes, err := db.TableSizes()
+	assert.NoError(t, err)
+	bySize := map[string]driver.TableSize{}
+	for _, size := range sizes {
+		bySize[size.Table] = size
+	}
+	assert.Contains(t, bySize, db.table.Tokens)
+	assert.Contains(t, bySize, db.table.Ownership)
+	assert.True(t, bySize[db.table.Tokens].Bytes > 0)
+}
+
+func TIntents(t *testing.T, db *TokenDB) {
+	intents, err := db.Intents()
+	assert.NoError(t, err)
+	assert.Empty(t, intents)
+
+	assert.NoError(t, db.AddIntent("tx1", []byte("request1")))
+	assert.NoError(t, db.AddIntent("tx2", []byte("request2")))
+	// a new intent for the same transaction replaces the previous one
+	assert.NoError(t, db.AddIntent("tx1", []byte("request1bis")))
+	intents, err = db.Intents()
+	assert.NoError(t, err)
+	assert.Len(t, intents, 2)
+	requests := map[string][]byte{}
+	for _, intent := range intents {
+		requests[intent.TxID] = intent.Request
+	}
+	assert.Equal(t, map[string][]byte{"tx1": []byte("request1bis"), "tx2": []byte("request2")}, requests)
+
+	assert.NoError(t, db.DeleteIntent("tx1"))
+	assert.NoError(t, db.DeleteIntent("tx3"))
+	intents, err = db.Intents()
+	assert.NoError(t, err)
+	assert.Len(t, intents, 1)
+	assert.Equal(t, "tx2", intents[0].TxID)
+
+	// an applied intent is kept as a journal entry, it is not to be recovered
+	assert.NoError(t, db.MarkIntentApplied("tx2"))
+	intents, err = db.Intents()
+	assert.NoError(t, err)
+	assert.Empty(t, intents)
+	intent, err := db.Intent("tx2")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("request2"), intent.Request)
+	assert.True(t, intent.Applied)
+	intent, err = db.Intent("tx1")
+	assert.NoError(t, err)
+	assert.Nil(t, intent)
+	assert.NoError(t, db.DeleteIntent("tx2"))
+	intent, err = db.Intent("tx2")
+	assert.NoError(t, err)
+	assert.Nil(t, intent)
+}
+
+func TDeleteExpired(t *testing.T, db *TokenDB) {
+	assert.NoError(t, db.StorePublicParams([]byte("pp")))
+	tokenID := &token.ID{TxId: "tx1", Index: 0}
+	assert.NoError(t, db.StoreToken(driver.TokenRecord{
+		TxID:           tokenID.TxId,
+		Index:          tokenID.Index,
+		OwnerRaw:       []byte{1, 2, 3},
+		OwnerType:      "idemix",
+		OwnerIdentity:  []byte{},
+		Quantity:       "0x01",
+		Amount:         1,
+		Ledger:         []byte("ledger"),
+		LedgerMetadata: []byte{},
+		Type:           "ABC",
+		Owner:          true,
+		Attributes:     map[string]string{"LinearID": "abc"},
+	}, []string{"alice"}))
+	assert.NoError(t, db.StoreCertifications(map[*token.ID][]byte{tokenID: []byte("certification")}))
+	assert.NoError(t, db.AddIntent("tx2", []byte("request")))
+
+	// nothing is older than an hour ago
+	deleted, err := db.DeleteExpired(time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+	balance, err := db.Balance("alice", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), balance)
+
+	// token, ownership, certification, attribute and intent
+	deleted, err = db.DeleteExpired(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), deleted)
+	balance, err = db.Balance("alice", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), balance)
+	assert.False(t, db.ExistsCertification(tokenID))
+	intents, err := db.Intents()
+	assert.NoError(t, err)
+	assert.Empty(t, intents)
+
+	// the public parameters are kept
+	pp, err := db.PublicParams()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pp"), pp)
+}
+
+func TRevertDeltas(t *testing.T, db *TokenDB) {
+	record := func(txID string) driver.TokenRecord {
+		return driver.TokenRecord{
+			TxID:           txID,
+			Index:          0,
+			OwnerRaw:       []byte{1, 2, 3},
+			OwnerType:      "idemix",
+			OwnerIdentity:  []byte{},
+			Quantity:       "0x01",
+			Amount:         1,
+			Ledger:         []byte("ledger"),
+			LedgerMetadata: []byte{},
+			Type:           "ABC",
+			Owner:          true,
+			Attributes:     map[string]string{"LinearID": txID},
+		}
+	}
+	// tx2 spends the token of alice created by tx1 and gives it to bob
+	input := &token.ID{TxId: "tx1", Index: 0}
+	output := &token.ID{TxId: "tx2", Index: 0}
+	assert.NoError(t, db.StoreToken(record("tx1"), []string{"alice"}))
+	assert.NoError(t, db.StoreToken(record("tx2"), []string{"bob"}))
+	assert.NoError(t, db.StoreCertifications(map[*token.ID][]byte{output: []byte("certification")}))
+	assert.NoError(t, db.DeleteTokens("tx2", input))
+	// a token spent by another transaction is not unspent
+	assert.NoError(t, db.StoreToken(record("tx0"), []string{"alice"}))
+	assert.NoError(t, db.DeleteTokens("tx9", &token.ID{TxId: "tx0", Index: 0}))
+
+	revert := func(txID string, created []uint64, spent ...*token.ID) {
+		tx, err := db.NewTokenDBTransaction(context.TODO())
+		assert.NoError(t, err)
+		assert.NoError(t, tx.RevertDeltas(context.TODO(), txID, created, spent))
+		assert.NoError(t, tx.Commit())
+	}
+	revert("tx2", []uint64{0}, input, &token.ID{TxId: "tx0", Index: 0})
+	balance, err := db.Balance("alice", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), balance)
+	balance, err = db.Balance("bob", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), balance)
+	mine, err := db.IsMine(input.TxId, input.Index)
+	assert.NoError(t, err)
+	assert.True(t, mine)
+	assert.False(t, db.ExistsCertification(output))
+	exists, err := db.TransactionExists(context.TODO(), "tx2")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = db.ExistsTokenWithAttribute(context.TODO(), "LinearID", "tx2")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// the transaction can be applied again, once committed in a new block
+	assert.NoError(t, db.StoreToken(record("tx2"), []string{"bob"}))
+	assert.NoError(t, db.DeleteTokens("tx2", input))
+	balance, err = db.Balance("bob", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), balance)
+
+	// reverting an unknown transaction does nothing
+	revert("tx3", []uint64{0}, input)
+	balance, err = db.Balance("bob", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), balance)
+}
+
+func TEraseOwnership(t *testing.T, db *TokenDB) {
+	record := func(txID string, index uint64) driver.TokenRecord {
+		return driver.TokenRecord{
+			TxID:           txID,
+			Index:          index,
+			OwnerRaw:       []byte{1, 2, 3},
+			OwnerType:      "idemix",
+			OwnerIdentity:  []byte{},
+			OwnerWalletID:  "alice",
+			Quantity:       "0x01",
+			Amount:         1,
+			Ledger:         []byte("ledger"),
+			LedgerMetadata: []byte{},
+			Type:           "ABC",
+			Owner:          true,
+		}
+	}
+	// tx2 spends the first token of alice created by tx1 and gives it to bob
+	spent := &token.ID{TxId: "tx1", Index: 0}
+	assert.NoError(t, db.StoreToken(record("tx1", 0), []string{"alice"}))
+	assert.NoError(t, db.StoreToken(record("tx1", 1), []string{"alice"}))
+	bob := record("tx2", 0)
+	bob.OwnerWalletID = "bob"
+	assert.NoError(t, db.StoreToken(bob, []string{"bob"}))
+	assert.NoError(t, db.DeleteTokens("tx2", spent))
+
+	_, err := db.EraseOwnership("")
+	assert.Error(t, err)
+	erased, err := db.EraseOwnership("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, &driver.ErasedOwnership{Removed: 1, Retained: 1}, erased)
+
+	// the spent token is not bound to alice anymore, the unspent one still is
+	details, err := db.QueryTokenDetails(driver.QueryTokenDetailsParams{WalletID: "alice", IncludeDeleted: true})
+	assert.NoError(t, err)
+	assert.Len(t, details, 1)
+	assert.Equal(t, uint64(1), details[0].Index)
+	balance, err := db.Balance("alice", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), balance)
+	details, err = db.QueryTokenDetails(driver.QueryTokenDetailsParams{IDs: []*token.ID{spent}, IncludeDeleted: true})
+	assert.NoError(t, err)
+	assert.Empty(t, details, "the token is not owned by a wallet anymore")
+	infos, err := db.GetAllTokenInfos([]*token.ID{spent})
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1, "the token is kept")
+
+	// the other wallets are not affected
+	balance, err = db.Balance("bob", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), balance)
+	erased, err = db.EraseOwnership("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, &driver.ErasedOwnership{Retained: 1}, erased)
+}
+
+func TPendingTokens(t *testing.T, db *TokenDB) {
+	record := func(txID string, pending bool) driver.TokenRecord {
+		return driver.TokenRecord{
+			TxID:           txID,
+			Index:          0,
+			OwnerRaw:       []byte{1, 2, 3},
+			OwnerType:      "idemix",
+			OwnerIdentity:  []byte{},
+			OwnerWalletID:  "alice",
+			Quantity:       "0x01",
+			Amount:         1,
+			Ledger:         []byte("ledger"),
+			LedgerMetadata: []byte{},
+			Type:           "ABC",
+			Owner:          true,
+			Pending:        pending,
+		}
+	}
+	spendable := func(pending bool) []string {
+		var it tdriver.SpendableTokensIterator
+		var err error
+		if pending {
+			it, err = db.SpendablePendingTokensIteratorBy(context.TODO(), "alice", "ABC")
+		} else {
+			it, err = db.SpendableTokensIteratorBy(context.TODO(), "alice", "ABC")
+		}
+		assert.NoError(t, err)
+		defer it.Close()
+		var txIDs []string
+		for {
+			tok, err := it.Next()
+			assert.NoError(t, err)
+			if tok == nil {
+				return txIDs
+			}
+			txIDs = append(txIDs, tok.Id.TxId)
+		}
+	}
+	// tx1 is committed, tx2 is pending
+	assert.NoError(t, db.StoreToken(record("tx1", false), []string{"alice"}))
+	assert.NoError(t, db.StoreToken(record("tx2", true), []string{"alice"}))
+	pending := &token.ID{TxId: "tx2", Index: 0}
+
+	// the pending tokens are not spendable, and do not count in the balance
+	assert.Equal(t, []string{"tx1"}, spendable(false))
+	assert.Equal(t, []string{"tx2"}, spendable(true))
+	balance, err := db.Balance("alice", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), balance)
+	exists, err := db.TransactionExists(context.TODO(), "tx2")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = db.PendingTransactionExists(context.TODO(), "tx2")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = db.PendingTransactionExists(context.TODO(), "tx1")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	toks, err := db.GetTokens(pending)
+	assert.NoError(t, err)
+	assert.Len(t, toks, 1)
+
+	// tx3 spends the pending token of tx2
+	assert.NoError(t, db.SpendPendingTokens("tx3", pending))
+	assert.Empty(t, spendable(true))
+
+	// tx2 fails, its pending tokens go, and tx3 is returned to be discarded
+	children, err := db.DeletePendingTokens("tx2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx3"}, children)
+	exists, err = db.PendingTransactionExists(context.TODO(), "tx2")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	assert.Equal(t, []string{"tx1"}, spendable(false))
+
+	// discarding an unknown transaction does nothing
+	children, err = db.DeletePendingTokens("tx4")
+	assert.NoError(t, err)
+	assert.Empty(t, children)
+
+	// tx5 gets final, its pending token, spent by tx6, is replaced by the final one in a single db transaction
+	assert.NoError(t, db.StoreToken(record("tx5", true), []string{"alice"}))
+	assert.NoError(t, db.SpendPendingTokens("tx6", &token.ID{TxId: "tx5", Index: 0}))
+	tx, err := db.NewTokenDBTransaction(context.TODO())
+	assert.NoError(t, err)
+	spent, err := tx.DeletePendingTokens(context.TODO(), "tx5")
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint64]string{0: "tx6"}, spent)
+	assert.NoError(t, tx.StoreToken(context.TODO(), record("tx5", false), []string{"alice"}))
+	assert.NoError(t, tx.Delete(context.TODO(), "tx5", 0, spent[0]))
+	assert.NoError(t, tx.Commit())
+	exists, err = db.TransactionExists(context.TODO(), "tx5")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = db.PendingTransactionExists(context.TODO(), "tx5")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	spentBy, deleted, err := db.WhoDeletedTokens(&token.ID{TxId: "tx5", Index: 0})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx6"}, spentBy)
+	assert.Equal(t, []bool{true}, deleted)
+	assert.Equal(t, []string{"tx1"}, spendable(false))
+}
+
+func TAttributes(t *testing.T, db *TokenDB) {
+	record := func(txID string, attributes map[string]string) driver.TokenRecord {
+		return driver.TokenRecord{
+			TxID:           txID,
+			Index:          0,
+			OwnerRaw:       []byte{1, 2, 3},
+			OwnerType:      "idemix",
+			OwnerIdentity:  []byte{},
+			Quantity:       "0x01",
+			Amount:         1,
+			Ledger:         []byte("ledger"),
+			LedgerMetadata: []byte{},
+			Type:           txID,
+			Owner:          true,
+			Attributes:     attributes,
+		}
+	}
+	assert.NoError(t, db.StoreToken(record("tx1", map[string]string{"LinearID": "house1", "City": "Zurich"}), []string{"alice"}))
+	assert.NoError(t, db.StoreToken(record("tx2", map[string]string{"LinearID": "house2", "City": "Zurich"}), []string{"bob"}))
+	assert.NoError(t, db.StoreToken(record("tx3", nil), []string{"alice"}))
+
+	query := func(walletID, key, value string) []string {
+		it, err := db.UnspentTokensIteratorByAttribute(context.TODO(), walletID, key, value)
+		assert.NoError(t, err)
+		defer it.Close()
+		var txIDs []string
+		for {
+			tok, err := it.Next()
+			assert.NoError(t, err)
+			if tok == nil {
+				return txIDs
+			}
+			txIDs = append(txIDs, tok.Id.TxId)
+		}
+	}
+	assert.Equal(t, []string{"tx1"}, query("", "LinearID", "house1"))
+	assert2.ElementsMatch(t, []string{"tx1", "tx2"}, query("", "City", "Zurich"))
+	assert.Equal(t, []string{"tx2"}, query("bob", "City", "Zurich"))
+	assert.Empty(t, query("alice", "LinearID", "house2"))
+	assert.Empty(t, query("", "City", "Paris"))
+
+	// spent tokens are not returned, but they still exist
+	assert.NoError(t, db.DeleteTokens("tx4", &token.ID{TxId: "tx1", Index: 0}))
+	assert.Empty(t, query("", "LinearID", "house1"))
+	exists, err := db.ExistsTokenWithAttribute(context.TODO(), "LinearID", "house1")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = db.ExistsTokenWithAttribute(context.TODO(), "LinearID", "house3")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TSerials(t *testing.T, db *TokenDB) {
+	record := func(txID string, serials token.SerialRange) driver.TokenRecord {
+		return driver.TokenRecord{
+			TxID:           txID,
+			Index:          0,
+			OwnerRaw:       []byte{1, 2, 3},
+			OwnerType:      "idemix",
+			OwnerIdentity:  []byte{},
+			Quantity:       fmt.Sprintf("0x%x", serials.Count()),
+			Amount:         serials.Count(),
+			Ledger:         []byte("ledger"),
+			LedgerMetadata: []byte{},
+			Type:           serials.Type(),
+			Owner:          true,
+			Serials:        &serials,
+		}
+	}
+	assert.NoError(t, db.StoreToken(record("tx1", token.SerialRange{Class: "TICKET", From: 1, To: 10}), []string{"alice"}))
+	assert.NoError(t, db.StoreToken(record("tx2", token.SerialRange{Class: "TICKET", From: 11, To: 20}), []string{"bob"}))
+	assert.NoError(t, db.StoreToken(record("tx3", token.SerialRange{Class: "PASS", From: 1, To: 10}), []string{"alice"}))
+
+	query := func(walletID string, serials token.SerialRange) []string {
+		it, err := db.UnspentTokensIteratorBySerialRange(context.TODO(), walletID, serials)
+		assert.NoError(t, err)
+		defer it.Close()
+		var txIDs []string
+		for {
+			tok, err := it.Next()
+			assert.NoError(t, err)
+			if tok == nil {
+				return txIDs
+			}
+			txIDs = append(txIDs, tok.Id.TxId)
+		}
+	}
+	assert.Equal(t, []string{"tx1"}, query("", token.SerialRange{Class: "TICKET", From: 5, To: 5}))
+	assert.Equal(t, []string{"tx1"}, query("", token.SerialRange{Class: "TICKET", From: 10, To: 10}))
+	assert2.ElementsMatch(t, []string{"tx1", "tx2"}, query("", token.SerialRange{Class: "TICKET", From: 10, To: 11}))
+	assert.Equal(t, []string{"tx2"}, query("bob", token.SerialRange{Class: "TICKET", From: 1, To: 100}))
+	assert.Equal(t, []string{"tx3"}, query("alice", token.SerialRange{Class: "PASS", From: 1, To: 1}))
+	assert.Empty(t, query("", token.SerialRange{Class: "TICKET", From: 21, To: 30}))
+
+	// spent tokens are not returned, but their serials still exist
+	assert.NoError(t, db.DeleteTokens("tx4", &token.ID{TxId: "tx1", Index: 0}))
+	assert.Empty(t, query("alice", token.SerialRange{Class: "TICKET", From: 1, To: 10}))
+	exists, err := db.ExistsSerials(context.TODO(), token.SerialRange{Class: "TICKET", From: 3, To: 4})
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = db.ExistsSerials(context.TODO(), token.SerialRange{Class: "TICKET", From: 21, To: 40})
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+// testCipher is a driver.ColumnCipher tagging the values with the key that encrypts them
+type testCipher struct {
+	active string
+}
+
+func (c *testCipher) Encrypt(value []byte) ([]byte, error) {
+	return append([]byte("enc:"+c.active+":"), value...), nil
+}
+
+func (c *testCipher) Decrypt(value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, []byte("enc:")) {
+		return value, nil
+	}
+	_, plain, _ := bytes.Cut(value[len("enc:"):], []byte(":"))
+	return plain, nil
+}
+
+func (c *testCipher) Stale(value []byte) bool {
+	return !bytes.HasPrefix(value, []byte("enc:"+c.active+":"))
+}
+
+func TEncryption(t *testing.T, db *TokenDB) {
+	storedMetadata := func(txID string) []byte {
+		var metadata []byte
+		assert.NoError(t, db.db.QueryRow(fmt.Sprintf("SELECT ledger_metadata FROM %s WHERE tx_id = $1", db.table.Tokens), txID).Scan(&metadata))
+		return metadata
+	}
+	store := func(txID string) {
+		assert.NoError(t, db.StoreToken(driver.TokenRecord{
+			TxID:           txID,
+			IssuerRaw:      []byte{},
+			OwnerRaw:       []byte{1, 2, 3},
+			OwnerType:      "idemix",
+			OwnerIdentity:  []byte{},
+			Ledger:         []byte(txID + "l"),
+			LedgerMetadata: []byte(txID),
+			Quantity:       "0x01",
+			Type:           "ABC",
+			Owner:          true,
+		}, []string{"alice"}))
+	}
+	ids := []*token.ID{{TxId: "tx1"}, {TxId: "tx2"}}
+	checkInfos := func() {
+		infos, err := db.GetAllTokenInfos(ids)
+		assert.NoError(t, err)
+		assert.Equal(t, [][]byte{[]byte("tx1"), []byte("tx2")}, infos)
+	}
+
+	// tx1 is stored before encryption is enabled
+	store("tx1")
+	db.SetColumnCipher(&testCipher{active: "k1"})
+	store("tx2")
+	assert.Equal(t, []byte("tx1"), storedMetadata("tx1"))
+	assert.Equal(t, []byte("enc:k1:tx2"), storedMetadata("tx2"))
+	checkInfos()
+
+	n, err := db.ReEncrypt(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []byte("enc:k1:tx1"), storedMetadata("tx1"))
+
+	// key rotation
+	db.SetColumnCipher(&testCipher{active: "k2"})
+	n, err = db.ReEncrypt(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []byte("enc:k2:tx1"), storedMetadata("tx1"))
+	assert.Equal(t, []byte("enc:k2:tx2"), storedMetadata("tx2"))
+	checkInfos()
+
+	n, err = db.ReEncrypt(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
 }
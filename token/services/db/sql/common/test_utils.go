@@ -8,6 +8,7 @@ package common
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"runtime/debug"
 	"testing"
@@ -33,7 +34,7 @@ func StartPostgresWithFmt(configs map[string]*PostgresConfig) (func(), error) {
 // https://testcontainers.com/guides/getting-started-with-testcontainers-for-go/
 // Note: Before running tests: docker pull postgres:16.2-alpine
 // Test may time out if image is not present on machine.
-func StartPostgresContainer(t *testing.T) (func(), string) {
+func StartPostgresContainer(t testing.TB) (func(), string) {
 	if os.Getenv("TESTCONTAINERS") != "true" {
 		t.Skip("set environment variable TESTCONTAINERS to true to include postgres test")
 	}
@@ -63,3 +64,60 @@ func StartPostgresContainer(t *testing.T) (func(), string) {
 		}
 	}, pgConnStr
 }
+
+// StartSQLServerContainer starts a SQL Server container and returns the data source to connect to it.
+// Note: Before running tests: docker pull mcr.microsoft.com/mssql/server:2022-latest
+func StartSQLServerContainer(t *testing.T) (func(), string) {
+	return startContainer(t, testcontainers.ContainerRequest{
+		Image:        "mcr.microsoft.com/mssql/server:2022-latest",
+		ExposedPorts: []string{"1433/tcp"},
+		Env: map[string]string{
+			"ACCEPT_EULA":       "Y",
+			"MSSQL_SA_PASSWORD": "Example-Passw0rd",
+		},
+		WaitingFor: wait.ForLog("SQL Server is now ready for client connections").WithStartupTimeout(2 * time.Minute),
+	}, "sqlserver://sa:Example-Passw0rd@%s?database=master")
+}
+
+// StartOracleContainer starts an Oracle Database Free container and returns the data source to connect to it.
+// Note: Before running tests: docker pull gvenzl/oracle-free:23-slim
+func StartOracleContainer(t *testing.T) (func(), string) {
+	return startContainer(t, testcontainers.ContainerRequest{
+		Image:        "gvenzl/oracle-free:23-slim",
+		ExposedPorts: []string{"1521/tcp"},
+		Env: map[string]string{
+			"ORACLE_PASSWORD":   "example",
+			"APP_USER":          "testdb",
+			"APP_USER_PASSWORD": "example",
+		},
+		WaitingFor: wait.ForLog("DATABASE IS READY TO USE!").WithStartupTimeout(5 * time.Minute),
+	}, "oracle://testdb:example@%s/FREEPDB1")
+}
+
+func startContainer(t *testing.T, req testcontainers.ContainerRequest, dataSourceFmt string) (func(), string) {
+	if os.Getenv("TESTCONTAINERS") != "true" {
+		t.Skipf("set environment variable TESTCONTAINERS to true to include %s test", req.Image)
+	}
+	if testing.Short() {
+		t.Skipf("skipping %s test in short mode", req.Image)
+	}
+
+	ctx := context.Background()
+	c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint, err := c.Endpoint(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func() {
+		if err := c.Terminate(ctx); err != nil {
+			logger.Errorf("failed to terminate [%s][%s]", err, debug.Stack())
+		}
+	}, fmt.Sprintf(dataSourceFmt, endpoint)
+}
@@ -0,0 +1,256 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+// DB runs the statements of a store on a connection pool, possibly shared with other stores.
+// The queries and the executions running longer than the statement timeout are interrupted through their context,
+// so that a runaway query does not hold a connection needed by the other stores.
+// The statements of the db transactions are not interrupted, not to roll back the writes of the commit path.
+// The statements, of the db transactions too, are rewritten by the dialect of the DB, if any.
+type DB struct {
+	*sql.DB
+	// timeout is the statement timeout, no timeout if not positive
+	timeout time.Duration
+	// dialect rewrites the statements, nil for Postgres and SQLite
+	dialect *Dialect
+
+	observerLock sync.RWMutex
+	observer     driver.StatementObserver
+}
+
+// NewDB returns a DB running its statements on the passed connection pool with the passed statement timeout
+func NewDB(db *sql.DB, timeout time.Duration) *DB {
+	return &DB{DB: db, timeout: timeout}
+}
+
+// NewDialectDB returns a DB as NewDB does, whose statements are rewritten by the passed dialect, if not nil
+func NewDialectDB(db *sql.DB, timeout time.Duration, d *Dialect) *DB {
+	return &DB{DB: db, timeout: timeout, dialect: d}
+}
+
+// Statement returns the passed statement and arguments as they are sent to the database
+func (db *DB) Statement(query string, args ...any) (string, []any) {
+	return db.dialect.Statement(query, args)
+}
+
+// SetStatementObserver makes the db notify the passed observer of the interrupted statements
+func (db *DB) SetStatementObserver(o driver.StatementObserver) {
+	db.observerLock.Lock()
+	defer db.observerLock.Unlock()
+	db.observer = o
+}
+
+func (db *DB) context(parent context.Context) (context.Context, context.CancelFunc) {
+	if db.timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, db.timeout)
+}
+
+// observe notifies the observer if the passed error is due to the interruption of the statement run with the passed context
+func (db *DB) observe(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	db.observerLock.RLock()
+	o := db.observer
+	db.observerLock.RUnlock()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		if o != nil {
+			o.TimedOut()
+		}
+		return errors.Wrapf(err, "statement interrupted after [%s]", db.timeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		if o != nil {
+			o.Cancelled()
+		}
+	}
+	return err
+}
+
+// Query runs a query with the statement timeout
+func (db *DB) Query(query string, args ...any) (*Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext runs a query with the statement timeout, it is also interrupted when the passed context is done.
+// The rows must be closed to release the context.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
+	ctx, cancel := db.context(ctx)
+	query, args = db.dialect.Statement(query, args)
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+		return nil, db.observe(ctx, err)
+	}
+	return &Rows{Rows: rows, db: db, ctx: ctx, cancel: cancel}, nil
+}
+
+// QueryRow runs a query expected to return at most one row with the statement timeout
+func (db *DB) QueryRow(query string, args ...any) *Row {
+	rows, err := db.Query(query, args...)
+	return &Row{rows: rows, err: err}
+}
+
+// Exec runs a statement with the statement timeout
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext runs a statement with the statement timeout, it is also interrupted when the passed context is done
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, cancel := db.context(ctx)
+	defer cancel()
+	query, args = db.dialect.Statement(query, args)
+	res, err := db.DB.ExecContext(ctx, query, args...)
+	return res, db.observe(ctx, err)
+}
+
+// Begin starts a db transaction
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// BeginTx starts a db transaction with the passed options
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, dialect: db.dialect}, nil
+}
+
+// Tx is a db transaction started by a DB, its statements are rewritten by the dialect of the DB, if any
+type Tx struct {
+	*sql.Tx
+	dialect *Dialect
+}
+
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	query, args = tx.dialect.Statement(query, args)
+	return tx.Tx.ExecContext(ctx, query, args...)
+}
+
+func (tx *Tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return tx.QueryContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	query, args = tx.dialect.Statement(query, args)
+	return tx.Tx.QueryContext(ctx, query, args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.QueryRowContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	query, args = tx.dialect.Statement(query, args)
+	return tx.Tx.QueryRowContext(ctx, query, args...)
+}
+
+// Rows are the result of a query run by a DB.
+// The query can be interrupted while the rows are read, Err reports it.
+type Rows struct {
+	*sql.Rows
+	db       *DB
+	ctx      context.Context
+	cancel   context.CancelFunc
+	observed bool
+}
+
+// Err returns the error, if any, that interrupted the iteration
+func (r *Rows) Err() error {
+	err := r.Rows.Err()
+	if err == nil || r.observed {
+		return err
+	}
+	r.observed = true
+	return r.db.observe(r.ctx, err)
+}
+
+// Close closes the rows and releases the context of the query
+func (r *Rows) Close() error {
+	defer r.cancel()
+	return r.Rows.Close()
+}
+
+// Row is the result of DB.QueryRow
+type Row struct {
+	rows *Rows
+	err  error
+}
+
+// Scan copies the columns of the row into the passed values.
+// It returns sql.ErrNoRows if the query returned no row.
+func (r *Row) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	return r.rows.Close()
+}
+
+// Err returns the error, if any, of the query
+func (r *Row) Err() error {
+	return r.err
+}
+
+// SetStatementObserver makes the token db notify the passed observer of the interrupted statements
+func (db *TokenDB) SetStatementObserver(o driver.StatementObserver) {
+	db.db.SetStatementObserver(o)
+}
+
+// SetStatementObserver makes the token db notify the passed observer of the interrupted statements of all the shards
+func (db *ShardedTokenDB) SetStatementObserver(o driver.StatementObserver) {
+	// the shards share the db
+	db.db.SetStatementObserver(o)
+}
+
+// SetStatementObserver makes the transaction db notify the passed observer of the interrupted statements
+func (db *TransactionDB) SetStatementObserver(o driver.StatementObserver) {
+	db.db.SetStatementObserver(o)
+}
+
+// SetStatementObserver makes the identity db notify the passed observer of the interrupted statements
+func (db *IdentityDB) SetStatementObserver(o driver.StatementObserver) {
+	db.db.SetStatementObserver(o)
+}
+
+// SetStatementObserver makes the wallet db notify the passed observer of the interrupted statements
+func (db *WalletDB) SetStatementObserver(o driver.StatementObserver) {
+	db.db.SetStatementObserver(o)
+}
+
+// SetStatementObserver makes the token lock db notify the passed observer of the interrupted statements
+func (db *TokenLockDB) SetStatementObserver(o driver.StatementObserver) {
+	db.DB.SetStatementObserver(o)
+}
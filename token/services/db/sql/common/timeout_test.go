@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingObserver struct {
+	timedOut  atomic.Int32
+	cancelled atomic.Int32
+}
+
+func (o *countingObserver) TimedOut() { o.timedOut.Add(1) }
+
+func (o *countingObserver) Cancelled() { o.cancelled.Add(1) }
+
+// slowQuery counts to a number large enough not to complete within the timeouts of the test
+const slowQuery = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000000000) SELECT MAX(x) FROM c"
+
+func TestStatementTimeout(t *testing.T) {
+	sqlDB, err := NewSQLDBOpener("", "").OpenSQLDB(sql2.SQLite, fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)", path.Join(t.TempDir(), "db.sqlite")), 10, false)
+	assert.NoError(t, err)
+	defer sqlDB.Close()
+	_, err = sqlDB.Exec("CREATE TABLE t (id INTEGER)")
+	assert.NoError(t, err)
+
+	db := NewDB(sqlDB, 100*time.Millisecond)
+	observer := &countingObserver{}
+	db.SetStatementObserver(observer)
+
+	// statements completing within the timeout are not interrupted
+	_, err = db.Exec("INSERT INTO t (id) VALUES ($1)", 1)
+	assert.NoError(t, err)
+	var id int
+	assert.NoError(t, db.QueryRow("SELECT id FROM t WHERE id = $1", 1).Scan(&id))
+	assert.Equal(t, 1, id)
+	assert.ErrorIs(t, db.QueryRow("SELECT id FROM t WHERE id = $1", 2).Scan(&id), sql.ErrNoRows)
+	rows, err := db.Query("SELECT id FROM t")
+	assert.NoError(t, err)
+	assert.True(t, rows.Next())
+	assert.False(t, rows.Next())
+	assert.NoError(t, rows.Err())
+	assert.NoError(t, rows.Close())
+	assert.Equal(t, int32(0), observer.timedOut.Load())
+
+	// a runaway query is interrupted
+	start := time.Now()
+	var max int
+	err = db.QueryRow(slowQuery).Scan(&max)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "statement interrupted after [100ms]")
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Equal(t, int32(1), observer.timedOut.Load())
+
+	// a query cancelled by the caller is reported as such
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	noTimeout := NewDB(sqlDB, 0)
+	noTimeout.SetStatementObserver(observer)
+	rows, err = noTimeout.QueryContext(ctx, slowQuery)
+	if err == nil {
+		for rows.Next() {
+		}
+		err = rows.Err()
+		assert.NoError(t, rows.Close())
+	}
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), observer.cancelled.Load())
+	assert.Equal(t, int32(1), observer.timedOut.Load())
+}
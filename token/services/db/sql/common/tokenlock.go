@@ -24,12 +24,12 @@ type tokenLockTables struct {
 }
 
 type TokenLockDB struct {
-	DB     *sql.DB
+	DB     *DB
 	Table  tokenLockTables
 	Logger logging.Logger
 }
 
-func newTokenLockDB(db *sql.DB, tables tokenLockTables) *TokenLockDB {
+func newTokenLockDB(db *DB, tables tokenLockTables) *TokenLockDB {
 	return &TokenLockDB{
 		DB:     db,
 		Table:  tables,
@@ -44,7 +44,7 @@ func NewTokenLockDB(db *sql.DB, opts NewDBOpts) (*TokenLockDB, error) {
 	}
 
 	tokenLockDB := newTokenLockDB(
-		db,
+		NewDB(db, opts.StatementTimeout),
 		tokenLockTables{
 			TokenLocks: tables.TokenLocks,
 			Requests:   tables.Requests,
@@ -12,12 +12,13 @@ import (
 	"encoding/base64"
 	"fmt"
 	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/pkg/utils/errors"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/common"
-	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracing"
 	tdriver "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
@@ -30,39 +31,91 @@ type tokenTables struct {
 	Ownership      string
 	PublicParams   string
 	Certifications string
+	Attributes     string
+	Serials        string
+	Intents        string
 }
 
-func NewTokenDB(db *sql.DB, opts NewDBOpts, ci TokenInterpreter) (driver.TokenDB, error) {
+func NewTokenDB(db *sql.DB, opts NewDBOpts, ci TokenInterpreter, qp QueryPlanner, sr SizeReporter) (driver.TokenDB, error) {
 	tables, err := GetTableNames(opts.TablePrefix)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get table names")
 	}
 
-	tokenDB := newTokenDB(db, tokenTables{
-		Tokens:         tables.Tokens,
-		Ownership:      tables.Ownership,
-		PublicParams:   tables.PublicParams,
-		Certifications: tables.Certifications,
-	}, ci)
+	sdb := NewDialectDB(db, opts.StatementTimeout, opts.Dialect)
+	if opts.Shards > 1 {
+		shardedDB := newShardedTokenDB(sdb, tables, opts.Shards, ci, qp)
+		shardedDB.isolation = opts.Isolation
+		shardedDB.sr = sr
+		if opts.CreateSchema {
+			if err = CreateSchema(sdb, shardedDB.Schema(), opts.Schema); err != nil {
+				return nil, err
+			}
+		}
+		if err = deleteExpiredOnOpen(tables.Tokens, opts.TTL, shardedDB.DeleteExpired); err != nil {
+			return nil, err
+		}
+		return shardedDB, nil
+	}
+
+	tokenDB := newTokenDB(sdb, newTokenTables(tables), ci, qp)
+	tokenDB.isolation = opts.Isolation
+	tokenDB.sr = sr
 	if opts.CreateSchema {
-		if err = common.InitSchema(db, tokenDB.GetSchema()); err != nil {
+		if err = CreateSchema(sdb, tokenDB.Schema(), opts.Schema); err != nil {
 			return nil, err
 		}
 	}
+	if err = deleteExpiredOnOpen(tables.Tokens, opts.TTL, tokenDB.DeleteExpired); err != nil {
+		return nil, err
+	}
 	return tokenDB, nil
 }
 
+func newTokenTables(tables tableNames) tokenTables {
+	return tokenTables{
+		Tokens:         tables.Tokens,
+		Ownership:      tables.Ownership,
+		PublicParams:   tables.PublicParams,
+		Certifications: tables.Certifications,
+		Attributes:     tables.TokenAttributes,
+		Serials:        tables.TokenSerials,
+		Intents:        tables.TokenIntents,
+	}
+}
+
+// TokenDBSchema returns the schema of the token db with the passed options, without connecting to the db.
+// This allows to generate the statements creating the schema for review.
+func TokenDBSchema(opts NewDBOpts) (Schema, error) {
+	tables, err := GetTableNames(opts.TablePrefix)
+	if err != nil {
+		return Schema{}, errors.Wrapf(err, "failed to get table names")
+	}
+	if opts.Shards > 1 {
+		return newShardedTokenDB(nil, tables, opts.Shards, nil, nil).Schema(), nil
+	}
+	return newTokenDB(nil, newTokenTables(tables), nil, nil).Schema(), nil
+}
+
 type TokenDB struct {
-	db    *sql.DB
+	db    *DB
 	table tokenTables
 	ci    TokenInterpreter
+	qp    QueryPlanner
+	// sr, if set, reports the disk usage of the tables
+	sr SizeReporter
+	// isolation is the isolation level of the transactions returned by NewTokenDBTransaction
+	isolation sql.IsolationLevel
+	// cipher, if set, encrypts the token metadata
+	cipher driver.ColumnCipher
 }
 
-func newTokenDB(db *sql.DB, tables tokenTables, ci TokenInterpreter) *TokenDB {
+func newTokenDB(db *DB, tables tokenTables, ci TokenInterpreter, qp QueryPlanner) *TokenDB {
 	return &TokenDB{
 		db:    db,
 		table: tables,
 		ci:    ci,
+		qp:    qp,
 	}
 }
 
@@ -102,6 +155,51 @@ func (db *TokenDB) DeleteTokens(deletedBy string, ids ...*token.ID) error {
 	return nil
 }
 
+// SpendPendingTokens marks the passed tokens, if pending, as spent by the passed transaction
+func (db *TokenDB) SpendPendingTokens(spentBy string, ids ...*token.ID) error {
+	logger.Debugf("spend pending tokens [%s][%v]", spentBy, ids)
+	if len(ids) == 0 {
+		return nil
+	}
+	cond := db.ci.And(db.ci.HasTokens("tx_id", "idx", ids...), common.ConstCondition("pending = true"))
+	args := append([]any{spentBy, time.Now().UTC()}, cond.Params()...)
+	offset := 3
+	where := cond.ToString(&offset)
+
+	query := fmt.Sprintf("UPDATE %s SET is_deleted = true, spent_by = $1, spent_at = $2 WHERE %s", db.table.Tokens, where)
+	logger.Debug(query, args)
+	if _, err := db.db.Exec(query, args...); err != nil {
+		return errors.Wrapf(err, "error setting pending tokens to spent [%v]", ids)
+	}
+	return nil
+}
+
+// DeletePendingTokens removes the pending tokens created by the passed transaction, with their ownership, certifications, attributes, and serials,
+// in a single db transaction. It returns the ids of the transactions that spent them.
+func (db *TokenDB) DeletePendingTokens(txID string) ([]string, error) {
+	logger.Debugf("delete pending tokens of [%s]", txID)
+	tx, err := db.db.Begin()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed starting a db transaction")
+	}
+	spent, err := (&TokenTransaction{db: db, tx: tx}).DeletePendingTokens(context.Background(), txID)
+	if err != nil {
+		if err1 := tx.Rollback(); err1 != nil {
+			logger.Errorf("error rolling back: %s", err1.Error())
+		}
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "failed committing the db transaction")
+	}
+	spentBy := make([]string, 0, len(spent))
+	for _, id := range spent {
+		spentBy = append(spentBy, id)
+	}
+	slices.Sort(spentBy)
+	return slices.Compact(spentBy), nil
+}
+
 // IsMine just checks if the token is in the local storage and not deleted
 func (db *TokenDB) IsMine(txID string, index uint64) (bool, error) {
 	id := ""
@@ -144,17 +242,117 @@ func (db *TokenDB) UnspentTokensIteratorBy(ctx context.Context, walletID, tokenT
 	return &UnspentTokensIterator{txs: rows}, err
 }
 
+// UnspentTokensIteratorByAttribute returns an iterator of the unspent tokens owned by the passed wallet and
+// having the passed attribute with the passed value. The wallet can be empty. In that case, the tokens of any wallet are returned.
+func (db *TokenDB) UnspentTokensIteratorByAttribute(ctx context.Context, walletID, key, value string) (tdriver.UnspentTokensIterator, error) {
+	span := trace.SpanFromContext(ctx)
+	where, args := common.Where(db.ci.HasTokenDetails(driver.QueryTokenDetailsParams{
+		WalletID: walletID,
+	}, db.table.Tokens))
+	join := joinOnTokenID(db.table.Tokens, db.table.Ownership)
+	conj := "AND"
+	if len(where) == 0 {
+		conj = "WHERE"
+	}
+	offset := len(args)
+	args = append(args, key, value)
+
+	query := fmt.Sprintf("SELECT %s.tx_id, %s.idx, owner_raw, token_type, quantity FROM %s %s %s %s EXISTS (SELECT 1 FROM %s WHERE %s.tx_id = %s.tx_id AND %s.idx = %s.idx AND attr_key = $%d AND attr_value = $%d)",
+		db.table.Tokens, db.table.Tokens, db.table.Tokens, join, where, conj,
+		db.table.Attributes, db.table.Attributes, db.table.Tokens, db.table.Attributes, db.table.Tokens, offset+1, offset+2)
+
+	logger.Debug(query, args)
+	span.AddEvent("start_query", tracing.WithAttributes(tracing.String(QueryLabel, query)))
+	rows, err := db.db.Query(query, args...)
+	span.AddEvent("end_query")
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying tokens by attribute [%s]", key)
+	}
+	return &UnspentTokensIterator{txs: rows}, nil
+}
+
+// ExistsTokenWithAttribute returns true if a token, spent or not, with the passed attribute and value is stored
+func (db *TokenDB) ExistsTokenWithAttribute(ctx context.Context, key, value string) (bool, error) {
+	span := trace.SpanFromContext(ctx)
+	query := fmt.Sprintf("SELECT tx_id FROM %s WHERE attr_key = $1 AND attr_value = $2 LIMIT 1;", db.table.Attributes)
+	logger.Debug(query, key, value)
+
+	span.AddEvent("query", trace.WithAttributes(tracing.String(QueryLabel, query)))
+	var found string
+	if err := db.db.QueryRow(query, key, value).Scan(&found); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error checking tokens with attribute [%s]", key)
+	}
+	return true, nil
+}
+
+// UnspentTokensIteratorBySerialRange returns an iterator of the unspent tokens owned by the passed wallet and
+// carrying serials of the passed range. The wallet can be empty. In that case, the tokens of any wallet are returned.
+func (db *TokenDB) UnspentTokensIteratorBySerialRange(ctx context.Context, walletID string, serials token.SerialRange) (tdriver.UnspentTokensIterator, error) {
+	span := trace.SpanFromContext(ctx)
+	where, args := common.Where(db.ci.HasTokenDetails(driver.QueryTokenDetailsParams{
+		WalletID: walletID,
+	}, db.table.Tokens))
+	join := joinOnTokenID(db.table.Tokens, db.table.Ownership)
+	conj := "AND"
+	if len(where) == 0 {
+		conj = "WHERE"
+	}
+	offset := len(args)
+	args = append(args, serials.Class, int64(serials.To), int64(serials.From))
+
+	query := fmt.Sprintf("SELECT %s.tx_id, %s.idx, owner_raw, token_type, quantity FROM %s %s %s %s EXISTS (SELECT 1 FROM %s WHERE %s.tx_id = %s.tx_id AND %s.idx = %s.idx AND class = $%d AND serial_from <= $%d AND serial_to >= $%d)",
+		db.table.Tokens, db.table.Tokens, db.table.Tokens, join, where, conj,
+		db.table.Serials, db.table.Serials, db.table.Tokens, db.table.Serials, db.table.Tokens, offset+1, offset+2, offset+3)
+
+	logger.Debug(query, args)
+	span.AddEvent("start_query", tracing.WithAttributes(tracing.String(QueryLabel, query)))
+	rows, err := db.db.Query(query, args...)
+	span.AddEvent("end_query")
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying tokens by serial range [%s]", serials)
+	}
+	return &UnspentTokensIterator{txs: rows}, nil
+}
+
+// ExistsSerials returns true if a token, spent or not, carrying serials of the passed range is stored
+func (db *TokenDB) ExistsSerials(ctx context.Context, serials token.SerialRange) (bool, error) {
+	span := trace.SpanFromContext(ctx)
+	query := fmt.Sprintf("SELECT tx_id FROM %s WHERE class = $1 AND serial_from <= $2 AND serial_to >= $3 LIMIT 1;", db.table.Serials)
+	logger.Debug(query, serials.Class, serials.To, serials.From)
+
+	span.AddEvent("query", trace.WithAttributes(tracing.String(QueryLabel, query)))
+	var found string
+	if err := db.db.QueryRow(query, serials.Class, int64(serials.To), int64(serials.From)).Scan(&found); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error checking tokens with serials [%s]", serials)
+	}
+	return true, nil
+}
+
 // UnspentTokensInWalletIterator returns the minimum information about the tokens needed for the selector
 func (db *TokenDB) SpendableTokensIteratorBy(ctx context.Context, walletID string, typ string) (tdriver.SpendableTokensIterator, error) {
 	span := trace.SpanFromContext(ctx)
-	where, args := common.Where(db.ci.HasTokenDetails(driver.QueryTokenDetailsParams{
-		WalletID:  walletID,
-		TokenType: typ,
-	}, ""))
-	query := fmt.Sprintf(
-		"SELECT tx_id, idx, token_type, quantity, owner_wallet_id FROM %s %s",
-		db.table.Tokens, where,
-	)
+	query, args := db.spendableTokensQuery(walletID, typ, false)
+
+	logger.Debug(query, args)
+	span.AddEvent("start_query", tracing.WithAttributes(tracing.String(QueryLabel, query)))
+	rows, err := db.db.Query(query, args...)
+	span.AddEvent("end_query")
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying db")
+	}
+	return &UnspentTokensInWalletIterator{txs: rows}, nil
+}
+
+// SpendablePendingTokensIteratorBy returns the minimum information about the pending tokens needed for the selector
+func (db *TokenDB) SpendablePendingTokensIteratorBy(ctx context.Context, walletID string, typ string) (tdriver.SpendableTokensIterator, error) {
+	span := trace.SpanFromContext(ctx)
+	query, args := db.spendableTokensQuery(walletID, typ, true)
 
 	logger.Debug(query, args)
 	span.AddEvent("start_query", tracing.WithAttributes(tracing.String(QueryLabel, query)))
@@ -168,12 +366,7 @@ func (db *TokenDB) SpendableTokensIteratorBy(ctx context.Context, walletID strin
 
 // Balance returns the sun of the amounts, with 64 bits of precision, of the tokens with type and EID equal to those passed as arguments.
 func (db *TokenDB) Balance(walletID, typ string) (uint64, error) {
-	where, args := common.Where(db.ci.HasTokenDetails(driver.QueryTokenDetailsParams{
-		WalletID:  walletID,
-		TokenType: typ,
-	}, db.table.Tokens))
-	join := joinOnTokenID(db.table.Tokens, db.table.Ownership)
-	query := fmt.Sprintf("SELECT SUM(amount) FROM %s %s %s", db.table.Tokens, join, where)
+	query, args := db.balanceQuery(walletID, typ)
 
 	logger.Debug(query, args)
 	row := db.db.QueryRow(query, args...)
@@ -254,48 +447,19 @@ func (db *TokenDB) ListAuditTokens(ids ...*token.ID) ([]*token.Token, error) {
 	}
 	defer rows.Close()
 
-	tokens := make([]*token.Token, len(ids))
-	counter := 0
+	tokens := make(map[token.ID]*token.Token, len(ids))
 	for rows.Next() {
 		id := token.ID{}
-		tok := token.Token{
-			Owner:    []byte{},
-			Type:     "",
-			Quantity: "",
-		}
+		tok := &token.Token{}
 		if err := rows.Scan(&id.TxId, &id.Index, &tok.Owner, &tok.Type, &tok.Quantity); err != nil {
-			return tokens, err
-		}
-
-		// the result is expected to be in order of the ids
-		found := false
-		for i := 0; i < len(ids); i++ {
-			if ids[i].Equal(id) {
-				tokens[i] = &tok
-				found = true
-				counter++
-			}
-		}
-		if !found {
-			return nil, errors.Errorf("retrieved wrong token [%s]", id)
+			return nil, err
 		}
+		tokens[id] = tok
 	}
-
 	if rows.Err() != nil {
 		return nil, rows.Err()
 	}
-	if counter == 0 {
-		return nil, errors.Errorf("token not found for key [%s:%d]", ids[0].TxId, ids[0].Index)
-	}
-	if counter != len(ids) {
-		for j, t := range tokens {
-			if t == nil {
-				return nil, errors.Errorf("token not found for key [%s:%d]", ids[j].TxId, ids[j].Index)
-			}
-		}
-		panic("programming error: should not reach this point")
-	}
-	return tokens, nil
+	return inOrder(ids, tokens)
 }
 
 // ListHistoryIssuedTokens returns the list of issued tokens
@@ -341,6 +505,40 @@ func (db *TokenDB) GetTokenOutputs(ids []*token.ID, callback tdriver.QueryCallba
 	return nil
 }
 
+// ExistAll returns, for each of the passed ids, whether a token with that id is stored in the db.
+// It runs a single query and does not fetch the token content.
+func (db *TokenDB) ExistAll(ids []*token.ID) ([]bool, error) {
+	if len(ids) == 0 {
+		return []bool{}, nil
+	}
+	where, args := common.Where(db.ci.HasTokens("tx_id", "idx", ids...))
+	query := fmt.Sprintf("SELECT tx_id, idx FROM %s %s", db.table.Tokens, where)
+	logger.Debug(query, args)
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying db")
+	}
+	defer rows.Close()
+
+	found := make(map[string]struct{}, len(ids))
+	for rows.Next() {
+		var id token.ID
+		if err := rows.Scan(&id.TxId, &id.Index); err != nil {
+			return nil, err
+		}
+		found[id.String()] = struct{}{}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	res := make([]bool, len(ids))
+	for i, id := range ids {
+		_, res[i] = found[id.String()]
+	}
+	return res, nil
+}
+
 // GetTokenInfos retrieves the token metadata for the passed ids.
 // For each id, the callback is invoked to unmarshal the token metadata
 func (db *TokenDB) GetTokenInfos(ids []*token.ID) ([][]byte, error) {
@@ -383,28 +581,27 @@ func (db *TokenDB) getLedgerToken(ids []*token.ID) ([][]byte, error) {
 	}
 	defer rows.Close()
 
-	tokenMap := make(map[string][]byte, len(ids))
+	tokenMap := make(map[token.ID][]byte, len(ids))
 	for rows.Next() {
 		var tok []byte
 		var id token.ID
 		if err := rows.Scan(&id.TxId, &id.Index, &tok); err != nil {
 			return nil, err
 		}
-		tokenMap[id.String()] = tok
+		tokenMap[id] = tok
 	}
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 	logger.Debugf("retrieve ledger tokens for [%s], retrieved [%d] tokens", ids, len(tokenMap))
 
-	tokens := make([][]byte, len(ids))
-	for i, id := range ids {
-		if tok, ok := tokenMap[id.String()]; !ok || tok == nil {
-			return nil, errors.Errorf("token not found for key [%s]", id)
-		} else if len(tok) == 0 {
-			return nil, errors.Errorf("empty token found for key [%s]", id)
-		} else {
-			tokens[i] = tok
+	tokens, err := inOrder(ids, tokenMap)
+	if err != nil {
+		return nil, err
+	}
+	for i, tok := range tokens {
+		if len(tok) == 0 {
+			return nil, errors.Errorf("empty token found for key [%s]", ids[i])
 		}
 	}
 	return tokens, nil
@@ -435,6 +632,9 @@ func (db *TokenDB) getLedgerTokenAndMeta(ctx context.Context, ids []*token.ID) (
 		if err := rows.Scan(&id.TxId, &id.Index, &tok, &metadata); err != nil {
 			return nil, nil, err
 		}
+		if metadata, err = decryptColumn(db.cipher, metadata); err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed to decrypt metadata of token [%s]", id)
+		}
 		infoMap[id.String()] = [2][]byte{tok, metadata}
 	}
 	if err = rows.Err(); err != nil {
@@ -461,6 +661,19 @@ func (db *TokenDB) GetTokens(inputs ...*token.ID) ([]*token.Token, error) {
 	if len(inputs) == 0 {
 		return []*token.Token{}, nil
 	}
+	tokens, err := db.GetTokensByID(inputs...)
+	if err != nil {
+		return nil, err
+	}
+	return inOrder(inputs, tokens)
+}
+
+// GetTokensByID returns the owned tokens for the passed ids, by token id.
+// The ids not found, or whose tokens are spent, are missing from the map.
+func (db *TokenDB) GetTokensByID(inputs ...*token.ID) (map[token.ID]*token.Token, error) {
+	if len(inputs) == 0 {
+		return map[token.ID]*token.Token{}, nil
+	}
 	where, args := common.Where(db.ci.And(
 		db.ci.HasTokens("tx_id", "idx", inputs...),
 		common.ConstCondition("is_deleted = false"),
@@ -475,71 +688,38 @@ func (db *TokenDB) GetTokens(inputs ...*token.ID) ([]*token.Token, error) {
 	}
 	defer rows.Close()
 
-	tokens := make([]*token.Token, len(inputs))
-	counter := 0
+	tokens := make(map[token.ID]*token.Token, len(inputs))
 	for rows.Next() {
 		tokID := token.ID{}
-		var typ, quantity string
-		var ownerRaw []byte
-		err := rows.Scan(
-			&tokID.TxId,
-			&tokID.Index,
-			&ownerRaw,
-			&typ,
-			&quantity,
-		)
-		if err != nil {
-			return tokens, err
-		}
-		tok := &token.Token{
-			Owner:    ownerRaw,
-			Type:     typ,
-			Quantity: quantity,
-		}
-
-		// put in the right position
-		found := false
-		for j := 0; j < len(inputs); j++ {
-			if inputs[j].Equal(tokID) {
-				tokens[j] = tok
-				logger.Debugf("set token at location [%s:%s]-[%d]", tok.Type, tok.Quantity, j)
-				found = true
-				break
-			}
-		}
-		if !found {
-			return nil, errors.Errorf("retrieved wrong token [%v]", tokID)
+		tok := &token.Token{}
+		if err := rows.Scan(&tokID.TxId, &tokID.Index, &tok.Owner, &tok.Type, &tok.Quantity); err != nil {
+			return nil, err
 		}
-
-		counter++
-	}
-	logger.Debugf("found [%d] tokens, expected [%d]", counter, len(inputs))
-	if err = rows.Err(); err != nil {
-		return tokens, err
-	}
-	if counter == 0 {
-		return nil, errors.Errorf("token not found for key [%s:%d]", inputs[0].TxId, inputs[0].Index)
+		tokens[tokID] = tok
 	}
-	if counter != len(inputs) {
-		for j, t := range tokens {
-			if t == nil {
-				return nil, errors.Errorf("token not found for key [%s:%d]", inputs[j].TxId, inputs[j].Index)
-			}
+	logger.Debugf("found [%d] tokens, expected [%d]", len(tokens), len(inputs))
+	return tokens, rows.Err()
+}
+
+// inOrder returns the values of the passed ids, in the order of the ids.
+// It fails if any of the ids is missing from the passed map.
+func inOrder[V any](ids []*token.ID, byID map[token.ID]V) ([]V, error) {
+	res := make([]V, len(ids))
+	for i, id := range ids {
+		v, ok := byID[*id]
+		if !ok {
+			return nil, errors.Errorf("token not found for key [%s:%d]", id.TxId, id.Index)
 		}
-		panic("programming error: should not reach this point")
+		res[i] = v
 	}
-	return tokens, nil
+	return res, nil
 }
 
 // QueryTokenDetails returns details about owned tokens, regardless if they have been spent or not.
 // Filters work cumulatively and may be left empty. If a token is owned by two enrollmentIDs and there
 // is no filter on enrollmentID, the token will be returned twice (once for each owner).
 func (db *TokenDB) QueryTokenDetails(params driver.QueryTokenDetailsParams) ([]driver.TokenDetails, error) {
-	where, args := common.Where(db.ci.HasTokenDetails(params, db.table.Tokens))
-	join := joinOnTokenID(db.table.Tokens, db.table.Ownership)
-
-	query := fmt.Sprintf("SELECT %s.tx_id, %s.idx, owner_identity, owner_type, wallet_id, token_type, amount, is_deleted, spent_by, stored_at FROM %s %s %s",
-		db.table.Tokens, db.table.Tokens, db.table.Tokens, join, where)
+	query, args := db.tokenDetailsQuery(params)
 	logger.Debug(query, args)
 	rows, err := db.db.Query(query, args...)
 	if err != nil {
@@ -561,6 +741,7 @@ func (db *TokenDB) QueryTokenDetails(params driver.QueryTokenDetailsParams) ([]d
 			&td.IsSpent,
 			&td.SpentBy,
 			&td.StoredAt,
+			&td.LedgerFormat,
 		); err != nil {
 			return deets, err
 		}
@@ -573,6 +754,73 @@ func (db *TokenDB) QueryTokenDetails(params driver.QueryTokenDetailsParams) ([]d
 	return deets, nil
 }
 
+// ExplainQueries returns the execution plans of the canonical queries against the live schema.
+// The queries are the same used by SpendableTokensIteratorBy, Balance, and QueryTokenDetails.
+func (db *TokenDB) ExplainQueries() ([]driver.QueryPlan, error) {
+	if db.qp == nil {
+		return nil, driver.ErrQueryPlansNotSupported
+	}
+	spendableQuery, spendableArgs := db.spendableTokensQuery("wallet", "type", false)
+	balanceQuery, balanceArgs := db.balanceQuery("wallet", "type")
+	detailsQuery, detailsArgs := db.tokenDetailsQuery(driver.QueryTokenDetailsParams{WalletID: "wallet", TokenType: "type"})
+	queries := []struct {
+		name  string
+		query string
+		args  []any
+	}{
+		{name: "spendable_tokens", query: spendableQuery, args: spendableArgs},
+		{name: "balance", query: balanceQuery, args: balanceArgs},
+		{name: "token_details", query: detailsQuery, args: detailsArgs},
+	}
+	plans := make([]driver.QueryPlan, len(queries))
+	for i, q := range queries {
+		plan, err := db.qp.Explain(db.db, q.name, q.query, q.args...)
+		if err != nil {
+			return nil, err
+		}
+		plans[i] = *plan
+	}
+	return plans, nil
+}
+
+// TableSizes returns the disk usage of the token tables
+func (db *TokenDB) TableSizes() ([]driver.TableSize, error) {
+	if db.sr == nil {
+		return nil, driver.ErrTableSizesNotSupported
+	}
+	return db.sr.TableSizes(db.db, db.Schema().TableNames())
+}
+
+func (db *TokenDB) spendableTokensQuery(walletID, typ string, pending bool) (string, []any) {
+	where, args := common.Where(db.ci.HasTokenDetails(driver.QueryTokenDetailsParams{
+		WalletID:  walletID,
+		TokenType: typ,
+		Pending:   pending,
+	}, ""))
+	query := fmt.Sprintf(
+		"SELECT tx_id, idx, token_type, quantity, owner_wallet_id FROM %s %s",
+		db.table.Tokens, where,
+	)
+	return query, args
+}
+
+func (db *TokenDB) balanceQuery(walletID, typ string) (string, []any) {
+	where, args := common.Where(db.ci.HasTokenDetails(driver.QueryTokenDetailsParams{
+		WalletID:  walletID,
+		TokenType: typ,
+	}, db.table.Tokens))
+	join := joinOnTokenID(db.table.Tokens, db.table.Ownership)
+	return fmt.Sprintf("SELECT SUM(amount) FROM %s %s %s", db.table.Tokens, join, where), args
+}
+
+func (db *TokenDB) tokenDetailsQuery(params driver.QueryTokenDetailsParams) (string, []any) {
+	where, args := common.Where(db.ci.HasTokenDetails(params, db.table.Tokens))
+	join := joinOnTokenID(db.table.Tokens, db.table.Ownership)
+	query := fmt.Sprintf("SELECT %s.tx_id, %s.idx, owner_identity, owner_type, wallet_id, token_type, amount, is_deleted, spent_by, stored_at, ledger_format FROM %s %s %s",
+		db.table.Tokens, db.table.Tokens, db.table.Tokens, join, where)
+	return query, args
+}
+
 // WhoDeletedTokens returns information about which transaction deleted the passed tokens.
 // The bool array is an indicator used to tell if the token at a given position has been deleted or not
 func (db *TokenDB) WhoDeletedTokens(inputs ...*token.ID) ([]string, []bool, error) {
@@ -589,51 +837,55 @@ func (db *TokenDB) WhoDeletedTokens(inputs ...*token.ID) ([]string, []bool, erro
 	}
 	defer rows.Close()
 
-	spentBy := make([]string, len(inputs))
-	isSpent := make([]bool, len(inputs))
-	found := make([]bool, len(inputs))
-
-	counter := 0
+	type deletion struct {
+		spentBy string
+		isSpent bool
+	}
+	deletionMap := make(map[token.ID]deletion, len(inputs))
 	for rows.Next() {
-		var txid string
-		var idx uint64
-		var spBy string
-		var isSp bool
-		if err := rows.Scan(&txid, &idx, &spBy, &isSp); err != nil {
-			return spentBy, isSpent, err
-		}
-		// order is not necessarily the same, so we have to set it in a loop
-		for i, inp := range inputs {
-			if inp.TxId == txid && inp.Index == idx {
-				isSpent[i] = isSp
-				spentBy[i] = spBy
-				found[i] = true
-				break // stop searching for this id but continue looping over rows
-			}
+		var id token.ID
+		var d deletion
+		if err := rows.Scan(&id.TxId, &id.Index, &d.spentBy, &d.isSpent); err != nil {
+			return nil, nil, err
 		}
-		counter++
+		deletionMap[id] = d
 	}
-	logger.Debugf("found [%d] records, expected [%d]", counter, len(inputs))
+	logger.Debugf("found [%d] records, expected [%d]", len(deletionMap), len(inputs))
 	if err = rows.Err(); err != nil {
-		return nil, isSpent, err
+		return nil, nil, err
 	}
-	if counter == 0 {
-		return nil, nil, errors.Errorf("token not found for key [%s:%d]", inputs[0].TxId, inputs[0].Index)
+	deletions, err := inOrder(inputs, deletionMap)
+	if err != nil {
+		return nil, nil, err
 	}
-	if counter != len(inputs) {
-		for j, f := range found {
-			if !f {
-				return nil, nil, errors.Errorf("token not found for key [%s:%d]", inputs[j].TxId, inputs[j].Index)
-			}
-		}
-		panic("programming error: should not reach this point")
+	spentBy := make([]string, len(inputs))
+	isSpent := make([]bool, len(inputs))
+	for i, d := range deletions {
+		spentBy[i], isSpent[i] = d.spentBy, d.isSpent
 	}
 	return spentBy, isSpent, nil
 }
 
+// PendingTransactionExists returns true if a pending token created by the passed transaction is stored
+func (db *TokenDB) PendingTransactionExists(ctx context.Context, id string) (bool, error) {
+	span := trace.SpanFromContext(ctx)
+	query := fmt.Sprintf("SELECT tx_id FROM %s WHERE tx_id=$1 AND pending = true LIMIT 1;", db.table.Tokens)
+	logger.Debug(query, id)
+
+	span.AddEvent("query", trace.WithAttributes(tracing.String(QueryLabel, query)))
+	var found string
+	if err := db.db.QueryRow(query, id).Scan(&found); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error checking pending tokens of [%s]", id)
+	}
+	return true, nil
+}
+
 func (db *TokenDB) TransactionExists(ctx context.Context, id string) (bool, error) {
 	span := trace.SpanFromContext(ctx)
-	query := fmt.Sprintf("SELECT tx_id FROM %s WHERE tx_id=$1 LIMIT 1;", db.table.Tokens)
+	query := fmt.Sprintf("SELECT tx_id FROM %s WHERE tx_id=$1 AND pending = false LIMIT 1;", db.table.Tokens)
 	logger.Debug(query, id)
 
 	span.AddEvent("query", trace.WithAttributes(tracing.String(QueryLabel, query)))
@@ -650,9 +902,15 @@ func (db *TokenDB) TransactionExists(ctx context.Context, id string) (bool, erro
 	return true, nil
 }
 
+// StorePublicParams stores the passed public parameters, if not stored yet.
+// The public parameters are indexed by their SHA-256 hash, whatever hash algorithm they select:
+// the db stores them as received from the ledger, without parsing them, therefore, it does not know the algorithm.
 func (db *TokenDB) StorePublicParams(raw []byte) error {
-	rawHash := hash.Hashable(raw).Raw()
-	_, err := db.PublicParamsByHash(rawHash)
+	rawHash, err := tdriver.SHA256.Hash(raw)
+	if err != nil {
+		return errors.Wrapf(err, "failed to hash public parameters")
+	}
+	_, err = db.PublicParamsByHash(rawHash)
 	if err == nil {
 		logger.Debugf("public params [%s] already in the database", base64.StdEncoding.EncodeToString(rawHash))
 		// no need to update the public parameters
@@ -682,6 +940,7 @@ func (db *TokenDB) PublicParams() ([]byte, error) {
 	return params, nil
 }
 
+// PublicParamsByHash returns the public parameters whose SHA-256 hash is the passed one
 func (db *TokenDB) PublicParamsByHash(rawHash tdriver.PPHash) ([]byte, error) {
 	var params []byte
 	query := fmt.Sprintf("SELECT raw FROM %s WHERE raw_hash = $1;", db.table.PublicParams)
@@ -755,6 +1014,29 @@ func (db *TokenDB) GetCertifications(ids []*token.ID) ([][]byte, error) {
 	if len(ids) == 0 {
 		return nil, nil
 	}
+	certificationMap, err := db.GetCertificationsByID(ids)
+	if err != nil {
+		return nil, err
+	}
+	certifications := make([][]byte, len(ids))
+	for i, id := range ids {
+		if cert, ok := certificationMap[*id]; !ok {
+			return nil, errors.Errorf("token %s was not certified", id)
+		} else if len(cert) == 0 {
+			return nil, errors.Errorf("empty certification for [%s]", id)
+		} else {
+			certifications[i] = cert
+		}
+	}
+	return certifications, nil
+}
+
+// GetCertificationsByID returns the certifications of the passed tokens, by token id.
+// The tokens that are not certified are missing from the map.
+func (db *TokenDB) GetCertificationsByID(ids []*token.ID) (map[token.ID][]byte, error) {
+	if len(ids) == 0 {
+		return map[token.ID][]byte{}, nil
+	}
 	where, args := common.Where(db.ci.HasTokens("tx_id", "idx", ids...))
 	query := fmt.Sprintf("SELECT tx_id, idx, certification FROM %s %s ", db.table.Certifications, where)
 
@@ -764,94 +1046,214 @@ func (db *TokenDB) GetCertifications(ids []*token.ID) ([][]byte, error) {
 	}
 	defer rows.Close()
 
-	certificationMap := make(map[string][]byte, len(ids))
+	certifications := make(map[token.ID][]byte, len(ids))
 	for rows.Next() {
 		var certification []byte
 		var id token.ID
 		if err := rows.Scan(&id.TxId, &id.Index, &certification); err != nil {
 			return nil, err
 		}
-		certificationMap[id.String()] = certification
+		certifications[id] = certification
 	}
-	if err = rows.Err(); err != nil {
-		return nil, err
+	return certifications, rows.Err()
+}
+
+// ListCertifications returns the certifications, or the unspent tokens owned by this node that are not certified,
+// ordered by transaction id and index, starting after params.After, if set
+func (db *TokenDB) ListCertifications(params driver.QueryCertificationsParams) ([]*driver.CertificationRecord, error) {
+	var args []any
+	var conditions []string
+	table := db.table.Certifications
+	if params.Missing {
+		table = db.table.Tokens
+		conditions = append(conditions, "owner = true", "is_deleted = false", "pending = false",
+			fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s.tx_id = %s.tx_id AND %s.idx = %s.idx)",
+				db.table.Certifications, db.table.Certifications, db.table.Tokens, db.table.Certifications, db.table.Tokens))
+	}
+	if params.After != nil {
+		conditions = append(conditions, "(tx_id > $1 OR (tx_id = $1 AND idx > $2))")
+		args = append(args, params.After.TxId, params.After.Index)
+	}
+	columns := "tx_id, idx, certification, stored_at"
+	if params.Missing {
+		columns = "tx_id, idx"
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", columns, table)
+	if len(conditions) != 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY tx_id, idx"
+	if params.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(params.Limit)
 	}
+	logger.Debug(query, args)
 
-	certifications := make([][]byte, len(ids))
-	for i, id := range ids {
-		if cert, ok := certificationMap[id.String()]; !ok {
-			return nil, errors.Errorf("token %s was not certified", id)
-		} else if len(cert) == 0 {
-			return nil, errors.Errorf("empty certification for [%s]", id)
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query")
+	}
+	defer rows.Close()
+	var records []*driver.CertificationRecord
+	for rows.Next() {
+		r := &driver.CertificationRecord{TokenID: &token.ID{}}
+		if params.Missing {
+			err = rows.Scan(&r.TokenID.TxId, &r.TokenID.Index)
 		} else {
-			certifications[i] = cert
+			err = rows.Scan(&r.TokenID.TxId, &r.TokenID.Index, &r.Certification, &r.StoredAt)
+		}
+		if err != nil {
+			return nil, err
 		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// AddIntent records the intent to apply the token request of the passed transaction, replacing any previous one
+func (db *TokenDB) AddIntent(txID string, request []byte) error {
+	query := db.ci.Upsert(db.table.Intents, []string{"tx_id"}, []string{"tx_id", "request", "applied", "stored_at"})
+	logger.Debug(query, txID)
+	if _, err := db.db.Exec(query, txID, request, false, time.Now().UTC()); err != nil {
+		return errors.Wrapf(err, "failed to store intent of [%s]", txID)
+	}
+	return nil
+}
+
+// MarkIntentApplied marks the intent of the passed transaction as applied, keeping it as a journal entry
+func (db *TokenDB) MarkIntentApplied(txID string) error {
+	query := fmt.Sprintf("UPDATE %s SET applied = true WHERE tx_id = $1", db.table.Intents)
+	logger.Debug(query, txID)
+	if _, err := db.db.Exec(query, txID); err != nil {
+		return errors.Wrapf(err, "failed to mark intent of [%s] as applied", txID)
+	}
+	return nil
+}
+
+// DeleteIntent clears the intent of the passed transaction, if any
+func (db *TokenDB) DeleteIntent(txID string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE tx_id = $1", db.table.Intents)
+	logger.Debug(query, txID)
+	if _, err := db.db.Exec(query, txID); err != nil {
+		return errors.Wrapf(err, "failed to clear intent of [%s]", txID)
+	}
+	return nil
+}
+
+// Intent returns the intent of the passed transaction, applied or not, nil if there is none
+func (db *TokenDB) Intent(txID string) (*driver.TokenIntent, error) {
+	query := fmt.Sprintf("SELECT tx_id, request, stored_at, applied FROM %s WHERE tx_id = $1", db.table.Intents)
+	logger.Debug(query, txID)
+	intent := &driver.TokenIntent{}
+	err := db.db.QueryRow(query, txID).Scan(&intent.TxID, &intent.Request, &intent.StoredAt, &intent.Applied)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query intent of [%s]", txID)
+	}
+	return intent, nil
+}
+
+// Intents returns the intents not applied and not cleared yet, the oldest first
+func (db *TokenDB) Intents() ([]driver.TokenIntent, error) {
+	query := fmt.Sprintf("SELECT tx_id, request, stored_at FROM %s WHERE applied = false ORDER BY stored_at", db.table.Intents)
+	logger.Debug(query)
+	rows, err := db.db.Query(query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query intents")
+	}
+	defer rows.Close()
+	intents := []driver.TokenIntent{}
+	for rows.Next() {
+		var intent driver.TokenIntent
+		if err := rows.Scan(&intent.TxID, &intent.Request, &intent.StoredAt); err != nil {
+			return nil, errors.Wrapf(err, "failed to scan intent")
+		}
+		intents = append(intents, intent)
+	}
+	return intents, rows.Err()
+}
+
+// Schema returns the tables and indexes of the token db
+func (db *TokenDB) Schema() Schema {
+	tokenKey := []ForeignKey{{Columns: []string{"tx_id", "idx"}, References: db.table.Tokens}}
+	return Schema{
+		Tables: []Table{
+			{
+				Name: db.table.Tokens,
+				Columns: []string{
+					"tx_id TEXT NOT NULL",
+					"idx INT NOT NULL",
+					"amount BIGINT NOT NULL",
+					"token_type TEXT NOT NULL",
+					"quantity TEXT NOT NULL",
+					"issuer_raw BYTEA",
+					"owner_raw BYTEA NOT NULL",
+					"owner_type TEXT NOT NULL",
+					"owner_identity BYTEA NOT NULL",
+					"owner_wallet_id TEXT",
+					"ledger BYTEA NOT NULL",
+					"ledger_metadata BYTEA NOT NULL",
+					"stored_at TIMESTAMP NOT NULL",
+					"is_deleted BOOL NOT NULL DEFAULT false",
+					"spent_by TEXT NOT NULL DEFAULT ''",
+					"spent_at TIMESTAMP",
+					"owner BOOL NOT NULL DEFAULT false",
+					"auditor BOOL NOT NULL DEFAULT false",
+					"issuer BOOL NOT NULL DEFAULT false",
+				},
+				Added: []string{
+					"ledger_format TEXT NOT NULL DEFAULT ''",
+					"pending BOOL NOT NULL DEFAULT false",
+				},
+				PrimaryKey: []string{"tx_id", "idx"},
+			},
+			{
+				Name:        db.table.Ownership,
+				Columns:     []string{"tx_id TEXT NOT NULL", "idx INT NOT NULL", "wallet_id TEXT NOT NULL"},
+				PrimaryKey:  []string{"tx_id", "idx", "wallet_id"},
+				ForeignKeys: tokenKey,
+			},
+			{
+				Name:    db.table.PublicParams,
+				Columns: []string{"raw_hash BYTEA PRIMARY KEY", "raw BYTEA NOT NULL", "stored_at TIMESTAMP NOT NULL"},
+			},
+			{
+				Name:        db.table.Certifications,
+				Columns:     []string{"tx_id TEXT NOT NULL", "idx INT NOT NULL", "certification BYTEA NOT NULL", "stored_at TIMESTAMP NOT NULL"},
+				PrimaryKey:  []string{"tx_id", "idx"},
+				ForeignKeys: tokenKey,
+			},
+			{
+				Name:        db.table.Attributes,
+				Columns:     []string{"tx_id TEXT NOT NULL", "idx INT NOT NULL", "attr_key TEXT NOT NULL", "attr_value TEXT NOT NULL"},
+				PrimaryKey:  []string{"tx_id", "idx", "attr_key"},
+				ForeignKeys: tokenKey,
+			},
+			{
+				Name:        db.table.Serials,
+				Columns:     []string{"tx_id TEXT NOT NULL", "idx INT NOT NULL", "class TEXT NOT NULL", "serial_from BIGINT NOT NULL", "serial_to BIGINT NOT NULL"},
+				PrimaryKey:  []string{"tx_id", "idx"},
+				ForeignKeys: tokenKey,
+			},
+			{
+				Name:    db.table.Intents,
+				Columns: []string{"tx_id TEXT PRIMARY KEY", "request BYTEA NOT NULL", "stored_at TIMESTAMP NOT NULL"},
+				Added:   []string{"applied BOOL NOT NULL DEFAULT false"},
+			},
+		},
+		Indexes: []Index{
+			{Name: "idx_spent_" + db.table.Tokens, Table: db.table.Tokens, Columns: []string{"is_deleted", "owner"}},
+			{Name: "idx_tx_id_" + db.table.Tokens, Table: db.table.Tokens, Columns: []string{"tx_id"}},
+			{Name: "idx_attr_" + db.table.Attributes, Table: db.table.Attributes, Columns: []string{"attr_key", "attr_value"}},
+			{Name: "idx_class_" + db.table.Serials, Table: db.table.Serials, Columns: []string{"class", "serial_from"}},
+			{Name: "stored_at_" + db.table.PublicParams, Table: db.table.PublicParams, Columns: []string{"stored_at"}},
+		},
 	}
-	return certifications, nil
 }
 
 func (db *TokenDB) GetSchema() string {
-	return fmt.Sprintf(`
-		-- Tokens
-		CREATE TABLE IF NOT EXISTS %s (
-			tx_id TEXT NOT NULL,
-			idx INT NOT NULL,
-			amount BIGINT NOT NULL,
-			token_type TEXT NOT NULL,
-			quantity TEXT NOT NULL,
-			issuer_raw BYTEA,
-			owner_raw BYTEA NOT NULL,
-			owner_type TEXT NOT NULL,
-			owner_identity BYTEA NOT NULL,
-			owner_wallet_id TEXT, 
-			ledger BYTEA NOT NULL,
-			ledger_metadata BYTEA NOT NULL,
-			stored_at TIMESTAMP NOT NULL,
-			is_deleted BOOL NOT NULL DEFAULT false,
-			spent_by TEXT NOT NULL DEFAULT '',
-			spent_at TIMESTAMP,
-			owner BOOL NOT NULL DEFAULT false,
-			auditor BOOL NOT NULL DEFAULT false,
-			issuer BOOL NOT NULL DEFAULT false,
-			PRIMARY KEY (tx_id, idx)
-		);
-		CREATE INDEX IF NOT EXISTS idx_spent_%s ON %s ( is_deleted, owner );
-		CREATE INDEX IF NOT EXISTS idx_tx_id_%s ON %s ( tx_id );
-
-		-- Ownership
-		CREATE TABLE IF NOT EXISTS %s (
-			tx_id TEXT NOT NULL,
-			idx INT NOT NULL,
-			wallet_id TEXT NOT NULL,
-			PRIMARY KEY (tx_id, idx, wallet_id),
-			FOREIGN KEY (tx_id, idx) REFERENCES %s
-		);
-
-		-- Public Parameters
-		CREATE TABLE IF NOT EXISTS %s (
-			raw_hash BYTEA PRIMARY KEY,
-			raw BYTEA NOT NULL,
-			stored_at TIMESTAMP NOT NULL 
-		);
-		CREATE INDEX IF NOT EXISTS stored_at_%s ON %s ( stored_at );
-
-		-- Certifications
-		CREATE TABLE IF NOT EXISTS %s (
-			tx_id TEXT NOT NULL,
-			idx INT NOT NULL,
-			certification BYTEA NOT NULL,
-			stored_at TIMESTAMP NOT NULL,
-			PRIMARY KEY (tx_id, idx),
-			FOREIGN KEY (tx_id, idx) REFERENCES %s
-		);
-		`,
-		db.table.Tokens,
-		db.table.Tokens, db.table.Tokens,
-		db.table.Tokens, db.table.Tokens,
-		db.table.Ownership, db.table.Tokens,
-		db.table.PublicParams, db.table.PublicParams, db.table.PublicParams,
-		db.table.Certifications, db.table.Tokens,
-	)
+	return db.Schema().String()
 }
 
 func (db *TokenDB) Close() {
@@ -861,17 +1263,17 @@ func (db *TokenDB) Close() {
 func (db *TokenDB) NewTokenDBTransaction(ctx context.Context) (driver.TokenDBTransaction, error) {
 	span := trace.SpanFromContext(ctx)
 	span.AddEvent("start_begin_tx")
-	tx, err := db.db.Begin()
+	tx, err := db.db.BeginTx(ctx, &sql.TxOptions{Isolation: db.isolation})
 	span.AddEvent("end_begin_tx")
 	if err != nil {
-		return nil, errors.Errorf("failed starting a db transaction")
+		return nil, errors.Wrapf(err, "failed starting a db transaction with isolation [%s]", db.isolation)
 	}
 	return &TokenTransaction{db: db, tx: tx}, nil
 }
 
 type TokenTransaction struct {
 	db *TokenDB
-	tx *sql.Tx
+	tx *Tx
 }
 
 func (t *TokenTransaction) GetToken(ctx context.Context, txID string, index uint64, includeDeleted bool) (*token.Token, []string, error) {
@@ -952,8 +1354,12 @@ func (t *TokenTransaction) StoreToken(ctx context.Context, tr driver.TokenRecord
 	// logger.Debugf("store record [%s:%d,%v] in table [%s]", tr.TxID, tr.Index, owners, t.db.table.Tokens)
 
 	// Store token
+	ledgerMetadata, err := encryptColumn(t.db.cipher, tr.LedgerMetadata)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to encrypt metadata of token [%s:%d]", tr.TxID, tr.Index)
+	}
 	now := time.Now().UTC()
-	query := fmt.Sprintf("INSERT INTO %s (tx_id, idx, issuer_raw, owner_raw, owner_type, owner_identity, owner_wallet_id, ledger, ledger_metadata, token_type, quantity, amount, stored_at, owner, auditor, issuer) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)", t.db.table.Tokens)
+	query := fmt.Sprintf("INSERT INTO %s (tx_id, idx, issuer_raw, owner_raw, owner_type, owner_identity, owner_wallet_id, ledger, ledger_metadata, ledger_format, token_type, quantity, amount, stored_at, owner, auditor, issuer, pending) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)", t.db.table.Tokens)
 	logger.Debug(query,
 		tr.TxID,
 		tr.Index,
@@ -964,13 +1370,15 @@ func (t *TokenTransaction) StoreToken(ctx context.Context, tr driver.TokenRecord
 		tr.OwnerWalletID,
 		len(tr.Ledger),
 		len(tr.LedgerMetadata),
+		tr.LedgerFormat,
 		tr.Type,
 		tr.Quantity,
 		tr.Amount,
 		now,
 		tr.Owner,
 		tr.Auditor,
-		tr.Issuer)
+		tr.Issuer,
+		tr.Pending)
 	span.AddEvent("query", tracing.WithAttributes(tracing.String(QueryLabel, query)))
 	if _, err := t.tx.Exec(query,
 		tr.TxID,
@@ -981,14 +1389,16 @@ func (t *TokenTransaction) StoreToken(ctx context.Context, tr driver.TokenRecord
 		tr.OwnerIdentity,
 		tr.OwnerWalletID,
 		tr.Ledger,
-		tr.LedgerMetadata,
+		ledgerMetadata,
+		tr.LedgerFormat,
 		tr.Type,
 		tr.Quantity,
 		tr.Amount,
 		now,
 		tr.Owner,
 		tr.Auditor,
-		tr.Issuer); err != nil {
+		tr.Issuer,
+		tr.Pending); err != nil {
 		logger.Errorf("error storing token [%s] in table [%s]: [%s][%s]", tr.TxID, t.db.table.Tokens, err, string(debug.Stack()))
 		return errors.Wrapf(err, "error storing token [%s] in table [%s]", tr.TxID, t.db.table.Tokens)
 	}
@@ -1004,6 +1414,89 @@ func (t *TokenTransaction) StoreToken(ctx context.Context, tr driver.TokenRecord
 		}
 	}
 
+	// Store attributes
+	if len(tr.Attributes) > 0 {
+		span.AddEvent("store_attributes")
+		query = fmt.Sprintf("INSERT INTO %s (tx_id, idx, attr_key, attr_value) VALUES ($1, $2, $3, $4)", t.db.table.Attributes)
+		for key, value := range tr.Attributes {
+			logger.Debug(query, tr.TxID, tr.Index, key, value)
+			if _, err := t.tx.Exec(query, tr.TxID, tr.Index, key, value); err != nil {
+				return errors.Wrapf(err, "error storing token attribute [%s:%d:%s]", tr.TxID, tr.Index, key)
+			}
+		}
+	}
+
+	// Store serials
+	if tr.Serials != nil {
+		span.AddEvent("store_serials")
+		query = fmt.Sprintf("INSERT INTO %s (tx_id, idx, class, serial_from, serial_to) VALUES ($1, $2, $3, $4, $5)", t.db.table.Serials)
+		logger.Debug(query, tr.TxID, tr.Index, tr.Serials.Class, tr.Serials.From, tr.Serials.To)
+		if _, err := t.tx.Exec(query, tr.TxID, tr.Index, tr.Serials.Class, int64(tr.Serials.From), int64(tr.Serials.To)); err != nil {
+			return errors.Wrapf(err, "error storing token serials [%s:%d]", tr.TxID, tr.Index)
+		}
+	}
+
+	return nil
+}
+
+// DeletePendingTokens removes the pending tokens created by the passed transaction, with their ownership, certifications, attributes, and serials.
+// It returns, by index, the ids of the transactions that spent them.
+func (t *TokenTransaction) DeletePendingTokens(ctx context.Context, txID string) (map[uint64]string, error) {
+	query := fmt.Sprintf("SELECT idx, spent_by FROM %s WHERE tx_id = $1 AND pending = true AND spent_by != '';", t.db.table.Tokens)
+	logger.Debug(query, txID)
+	rows, err := t.tx.QueryContext(ctx, query, txID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying db")
+	}
+	defer rows.Close()
+	spent := map[uint64]string{}
+	for rows.Next() {
+		var index uint64
+		var spentBy string
+		if err := rows.Scan(&index, &spentBy); err != nil {
+			return nil, err
+		}
+		spent[index] = spentBy
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, table := range []string{t.db.table.Ownership, t.db.table.Certifications, t.db.table.Attributes, t.db.table.Serials} {
+		query = fmt.Sprintf("DELETE FROM %s WHERE EXISTS (SELECT 1 FROM %s WHERE %s.tx_id = %s.tx_id AND %s.idx = %s.idx AND %s.pending = true) AND tx_id = $1;",
+			table, t.db.table.Tokens, t.db.table.Tokens, table, t.db.table.Tokens, table, t.db.table.Tokens)
+		logger.Debug(query, txID)
+		if _, err := t.tx.ExecContext(ctx, query, txID); err != nil {
+			return nil, errors.Wrapf(err, "error deleting pending tokens of [%s]", txID)
+		}
+	}
+	query = fmt.Sprintf("DELETE FROM %s WHERE tx_id = $1 AND pending = true;", t.db.table.Tokens)
+	logger.Debug(query, txID)
+	if _, err := t.tx.ExecContext(ctx, query, txID); err != nil {
+		return nil, errors.Wrapf(err, "error deleting pending tokens of [%s]", txID)
+	}
+	return spent, nil
+}
+
+// RevertDeltas removes the tokens created by the passed transaction at the passed indexes, with their ownership, certifications, attributes, and serials,
+// and marks the passed tokens spent by the transaction as unspent again
+func (t *TokenTransaction) RevertDeltas(ctx context.Context, txID string, created []uint64, spent []*token.ID) error {
+	for _, index := range created {
+		for _, table := range []string{t.db.table.Ownership, t.db.table.Certifications, t.db.table.Attributes, t.db.table.Serials, t.db.table.Tokens} {
+			query := fmt.Sprintf("DELETE FROM %s WHERE tx_id = $1 AND idx = $2;", table)
+			logger.Debug(query, txID, index)
+			if _, err := t.tx.ExecContext(ctx, query, txID, index); err != nil {
+				return errors.Wrapf(err, "error removing token [%s:%d]", txID, index)
+			}
+		}
+	}
+	query := fmt.Sprintf("UPDATE %s SET is_deleted = false, spent_by = '', spent_at = NULL WHERE tx_id = $1 AND idx = $2 AND spent_by = $3;", t.db.table.Tokens)
+	for _, id := range spent {
+		logger.Debug(query, id.TxId, id.Index, txID)
+		if _, err := t.tx.ExecContext(ctx, query, id.TxId, id.Index, txID); err != nil {
+			return errors.Wrapf(err, "error unspending token [%s]", id)
+		}
+	}
 	return nil
 }
 
@@ -1016,7 +1509,7 @@ func (t *TokenTransaction) Rollback() error {
 }
 
 type UnspentTokensInWalletIterator struct {
-	txs *sql.Rows
+	txs *Rows
 }
 
 func (u *UnspentTokensInWalletIterator) Close() {
@@ -1041,7 +1534,7 @@ func (u *UnspentTokensInWalletIterator) Next() (*token.UnspentTokenInWallet, err
 }
 
 type UnspentTokensIterator struct {
-	txs *sql.Rows
+	txs *Rows
 }
 
 func (u *UnspentTokensIterator) Close() {
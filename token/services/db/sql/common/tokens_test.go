@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package common
 
 import (
+	"database/sql"
 	"fmt"
 	"path"
 	"testing"
@@ -21,11 +22,22 @@ func initTokenDB(driverName common.SQLDriverType, dataSourceName, tablePrefix st
 	if err != nil {
 		return nil, err
 	}
+	ci := NewTokenInterpreter(common.NewInterpreter())
+	var qp QueryPlanner = NewSQLiteQueryPlanner()
+	var sr SizeReporter = NewSQLiteSizeReporter()
+	switch driverName {
+	case sql2.Postgres:
+		qp, sr = NewPostgresQueryPlanner(), NewPostgresSizeReporter()
+	case Oracle:
+		ci, qp, sr = NewTokenInterpreter(OracleDialect().Interpreter()), OracleDialect().QueryPlanner(), OracleDialect().SizeReporter()
+	case SQLServer:
+		ci, qp, sr = NewTokenInterpreter(SQLServerDialect().Interpreter()), SQLServerDialect().QueryPlanner(), SQLServerDialect().SizeReporter()
+	}
 	tokenDB, err := NewTokenDB(sqlDB, NewDBOpts{
 		DataSource:   dataSourceName,
 		TablePrefix:  tablePrefix,
 		CreateSchema: true,
-	}, NewTokenInterpreter(common.NewInterpreter()))
+	}, ci, qp, sr)
 	if err != nil {
 		return nil, err
 	}
@@ -91,6 +103,25 @@ func TestTokensSqlite(t *testing.T) {
 	//}
 }
 
+func TestTokensSqliteSerializable(t *testing.T) {
+	d := NewSQLDBOpener("", "")
+	sqlDB, err := d.OpenSQLDB(sql2.SQLite, fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", path.Join(t.TempDir(), "db.sqlite")), 10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenDB, err := NewTokenDB(sqlDB, NewDBOpts{
+		TablePrefix:  "serializable",
+		CreateSchema: true,
+		Isolation:    sql.LevelSerializable,
+	}, NewTokenInterpreter(common.NewInterpreter()), NewSQLiteQueryPlanner(), NewSQLiteSizeReporter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := tokenDB.(*TokenDB)
+	defer db.Close()
+	TTransaction(t, db)
+}
+
 func TestTokensSqliteMemory(t *testing.T) {
 	for _, c := range TokensCases {
 		db, err := initTokenDB(sql2.SQLite, "file:tmp?_pragma=busy_timeout(20000)&_pragma=foreign_keys(1)&mode=memory&cache=shared", c.Name, 10)
@@ -139,3 +170,35 @@ func TestTokensPostgres(t *testing.T) {
 	//	})
 	//}
 }
+
+func TestTokensSQLServer(t *testing.T) {
+	terminate, connStr := StartSQLServerContainer(t)
+	defer terminate()
+
+	for _, c := range TokensCases {
+		db, err := initTokenDB(SQLServer, connStr, c.Name, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(c.Name, func(xt *testing.T) {
+			defer db.Close()
+			c.Fn(xt, db)
+		})
+	}
+}
+
+func TestTokensOracle(t *testing.T) {
+	terminate, connStr := StartOracleContainer(t)
+	defer terminate()
+
+	for _, c := range TokensCases {
+		db, err := initTokenDB(Oracle, connStr, c.Name, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Run(c.Name, func(xt *testing.T) {
+			defer db.Close()
+			c.Fn(xt, db)
+		})
+	}
+}
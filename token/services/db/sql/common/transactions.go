@@ -7,11 +7,13 @@ SPDX-License-Identifier: Apache-2.0
 package common
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,21 +26,40 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// maxInListSize is the maximum number of elements of an IN list, Oracle does not accept more than 1000
+const maxInListSize = 1000
+
 type transactionTables struct {
 	Movements             string
+	MovementCorrections   string
 	Transactions          string
 	Requests              string
 	Validations           string
 	TransactionEndorseAck string
+	IssuerAttributions    string
+	StatusOverrides       string
+	CompensationFailures  string
+	IdempotencyKeys       string
+	AuditResponses        string
+	FundsWitnesses        string
+	References            string
+	TxWallets             string
+	ExportOutbox          string
+	Pseudonyms            string
+	AnonymityLevels       string
 }
 
 type TransactionDB struct {
-	db    *sql.DB
+	db    *DB
 	table transactionTables
 	ci    TokenInterpreter
+	// cipher, if set, encrypts the token requests
+	cipher driver.ColumnCipher
+	// sr, if set, reports the disk usage of the tables
+	sr SizeReporter
 }
 
-func newTransactionDB(db *sql.DB, tables transactionTables, ci TokenInterpreter) *TransactionDB {
+func newTransactionDB(db *DB, tables transactionTables, ci TokenInterpreter) *TransactionDB {
 	return &TransactionDB{
 		db:    db,
 		table: tables,
@@ -46,30 +67,56 @@ func newTransactionDB(db *sql.DB, tables transactionTables, ci TokenInterpreter)
 	}
 }
 
-func NewAuditTransactionDB(sqlDB *sql.DB, opts NewDBOpts, ci TokenInterpreter) (driver.AuditTransactionDB, error) {
-	return NewTransactionDB(sqlDB, NewDBOpts{
+func NewAuditTransactionDB(sqlDB *sql.DB, opts NewDBOpts, ci TokenInterpreter, sr SizeReporter) (driver.AuditTransactionDB, error) {
+	db, err := NewTransactionDB(sqlDB, NewDBOpts{
 		DataSource:   opts.DataSource,
 		TablePrefix:  opts.TablePrefix + "_aud",
 		CreateSchema: opts.CreateSchema,
-	}, ci)
+		TTL:          opts.TTL,
+		// the audit queries are interrupted too
+		StatementTimeout: opts.StatementTimeout,
+	}, ci, sr)
+	if err != nil {
+		return nil, err
+	}
+	return db.(*TransactionDB), nil
 }
 
-func NewTransactionDB(db *sql.DB, opts NewDBOpts, ci TokenInterpreter) (driver.TokenTransactionDB, error) {
+func NewTransactionDB(db *sql.DB, opts NewDBOpts, ci TokenInterpreter, sr SizeReporter) (driver.TokenTransactionDB, error) {
 	tables, err := GetTableNames(opts.TablePrefix)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get table names")
 	}
-	transactionsDB := newTransactionDB(db, transactionTables{
+	transactionsDB := newTransactionDB(NewDB(db, opts.StatementTimeout), transactionTables{
 		Movements:             tables.Movements,
+		MovementCorrections:   tables.MovementCorrections,
 		Transactions:          tables.Transactions,
 		Requests:              tables.Requests,
 		Validations:           tables.Validations,
 		TransactionEndorseAck: tables.TransactionEndorseAck,
+		IssuerAttributions:    tables.IssuerAttributions,
+		StatusOverrides:       tables.StatusOverrides,
+		CompensationFailures:  tables.CompensationFailures,
+		IdempotencyKeys:       tables.IdempotencyKeys,
+		AuditResponses:        tables.AuditResponses,
+		FundsWitnesses:        tables.FundsWitnesses,
+		References:            tables.References,
+		TxWallets:             tables.TxWallets,
+		ExportOutbox:          tables.ExportOutbox,
+		Pseudonyms:            tables.Pseudonyms,
+		AnonymityLevels:       tables.AnonymityLevels,
 	}, ci)
+	transactionsDB.sr = sr
 	if opts.CreateSchema {
 		if err = common.InitSchema(db, []string{transactionsDB.GetSchema()}...); err != nil {
 			return nil, err
 		}
+		if err = UpgradeSchema(transactionsDB.db, transactionsDB.addedColumns()); err != nil {
+			return nil, err
+		}
+	}
+	if err = deleteExpiredOnOpen(tables.Requests, opts.TTL, transactionsDB.DeleteExpired); err != nil {
+		return nil, err
 	}
 	return transactionsDB, nil
 }
@@ -87,14 +134,57 @@ func (db *TransactionDB) GetTokenRequest(txID string) ([]byte, error) {
 		}
 		return nil, errors.Wrapf(err, "error querying db")
 	}
-	return tokenrequest, nil
+	return decryptColumn(db.cipher, tokenrequest)
+}
+
+// GetTokenRequests returns the token requests bound to the passed transaction ids.
+// The ids are queried in batches of at most maxInListSize elements.
+func (db *TransactionDB) GetTokenRequests(txIDs []string) (map[string][]byte, error) {
+	res := make(map[string][]byte, len(txIDs))
+	for start := 0; start < len(txIDs); start += maxInListSize {
+		end := min(start+maxInListSize, len(txIDs))
+		where, args := common.Where(db.ci.InStrings("tx_id", txIDs[start:end]))
+		query := fmt.Sprintf("SELECT tx_id, request FROM %s %s", db.table.Requests, where)
+		logger.Debug(query, args)
+
+		if err := db.queryTokenRequests(query, args, res); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (db *TransactionDB) queryTokenRequests(query string, args []any, res map[string][]byte) error {
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return errors.Wrapf(err, "error querying db")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var txID string
+		var request []byte
+		if err := rows.Scan(&txID, &request); err != nil {
+			return errors.Wrapf(err, "error scanning token request")
+		}
+		request, err := decryptColumn(db.cipher, request)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to decrypt token request [%s]", txID)
+		}
+		res[txID] = request
+	}
+	return rows.Err()
 }
 
 func (db *TransactionDB) QueryMovements(params driver.QueryMovementsParams) (res []*driver.MovementRecord, err error) {
 	where, args := common.Where(db.ci.HasMovementsParams(params))
 	conditions := where + movementConditionsSql(params)
-	query := fmt.Sprintf("SELECT %s.tx_id, enrollment_id, token_type, amount, %s.status FROM %s %s %s",
-		db.table.Movements, db.table.Requests,
+	amount := "amount"
+	if params.Corrected {
+		amount = fmt.Sprintf("%s.amount + COALESCE((SELECT SUM(%s.amount) FROM %s WHERE %s.movement_id = %s.id), 0)",
+			db.table.Movements, db.table.MovementCorrections, db.table.MovementCorrections, db.table.MovementCorrections, db.table.Movements)
+	}
+	query := fmt.Sprintf("SELECT %s.id, %s.tx_id, enrollment_id, token_type, %s, %s.status, %s.stored_at FROM %s %s %s",
+		db.table.Movements, db.table.Movements, amount, db.table.Requests, db.table.Movements,
 		db.table.Movements, joinOnTxID(db.table.Movements, db.table.Requests), conditions)
 
 	logger.Debug(query, args)
@@ -110,11 +200,13 @@ func (db *TransactionDB) QueryMovements(params driver.QueryMovementsParams) (res
 		var amount int64
 		var status int
 		err = rows.Scan(
+			&r.ID,
 			&r.TxID,
 			&r.EnrollmentID,
 			&r.TokenType,
 			&amount,
 			&status,
+			&r.Timestamp,
 		)
 		if err != nil {
 			return res, err
@@ -137,9 +229,9 @@ func (db *TransactionDB) QueryTransactions(params driver.QueryTransactionsParams
 		SearchDirection: driver.FromBeginning,
 	})
 	query := fmt.Sprintf(
-		"SELECT %s.tx_id, action_type, sender_eid, recipient_eid, token_type, amount, %s.status, %s.application_metadata, stored_at FROM %s %s %s",
+		"SELECT %s.tx_id, action_type, sender_eid, recipient_eid, token_type, amount, %s.status, %s.application_metadata, stored_at, COALESCE(wallet_id, '') FROM %s %s %s %s",
 		db.table.Transactions, db.table.Requests, db.table.Requests,
-		db.table.Transactions, joinOnTxID(db.table.Transactions, db.table.Requests), conditions)
+		db.table.Transactions, joinOnTxID(db.table.Transactions, db.table.Requests), joinOnTxID(db.table.Transactions, db.table.TxWallets), conditions)
 
 	logger.Debug(query, args)
 	rows, err := db.db.Query(query, args...)
@@ -168,7 +260,7 @@ func (db *TransactionDB) GetStatus(txID string) (driver.TxStatus, string, error)
 }
 
 func (db *TransactionDB) QueryValidations(params driver.QueryValidationRecordsParams) (driver.ValidationRecordsIterator, error) {
-	conditions, args := common.Where(db.ci.HasValidationParams(params))
+	conditions, args := common.Where(db.ci.HasValidationParams(params, db.table.Validations))
 	query := fmt.Sprintf("SELECT %s.tx_id, %s.request, metadata, %s.status, %s.stored_at FROM %s %s %s",
 		db.table.Validations, db.table.Requests, db.table.Requests, db.table.Validations,
 		db.table.Validations, joinOnTxID(db.table.Validations, db.table.Requests), conditions)
@@ -179,20 +271,68 @@ func (db *TransactionDB) QueryValidations(params driver.QueryValidationRecordsPa
 		return nil, err
 	}
 
-	return &ValidationRecordsIterator{txs: rows, filter: params.Filter}, nil
+	return &ValidationRecordsIterator{txs: rows, filter: params.Filter, cipher: db.cipher}, nil
 }
 
 // QueryTokenRequests returns an iterator over the token requests matching the passed params
 func (db *TransactionDB) QueryTokenRequests(params driver.QueryTokenRequestsParams) (driver.TokenRequestIterator, error) {
-	conditions, args := common.Where(db.ci.InInts("status", params.Statuses))
+	conds := []common.Condition{
+		db.ci.InInts("status", params.Statuses),
+		db.ci.InStrings("tx_id", params.TxIDs),
+	}
+	if params.From != nil && !params.From.IsZero() {
+		conds = append(conds, db.ci.Cmp("requested_at", ">=", params.From.UTC()))
+	}
+	conditions, args := common.Where(db.ci.And(conds...))
 
-	query := fmt.Sprintf("SELECT tx_id, request, status FROM %s %s", db.table.Requests, conditions)
+	query := fmt.Sprintf("SELECT tx_id, request, status, application_metadata, requested_at FROM %s %s", db.table.Requests, conditions)
 	logger.Debug(query, args)
 	rows, err := db.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
-	return &TokenRequestIterator{txs: rows}, nil
+	// the encoding of the application metadata depends on the database, therefore, it is filtered here
+	return &TokenRequestIterator{txs: rows, cipher: db.cipher, applicationMetadata: params.ApplicationMetadata}, nil
+}
+
+// QueryIssuerAttributions returns the issuer attribution records matching the passed params
+func (db *TransactionDB) QueryIssuerAttributions(params driver.QueryIssuerAttributionsParams) (res []*driver.IssuerAttributionRecord, err error) {
+	conditions, args := common.Where(db.ci.HasIssuerAttributionsParams(params, db.table.IssuerAttributions))
+	query := fmt.Sprintf("SELECT %s.tx_id, action_index, issuer, issuer_id, token_type, amount, stored_at, %s.status FROM %s %s %s ORDER BY stored_at ASC, action_index ASC",
+		db.table.IssuerAttributions, db.table.Requests,
+		db.table.IssuerAttributions, joinOnTxID(db.table.IssuerAttributions, db.table.Requests), conditions)
+
+	logger.Debug(query, args)
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r driver.IssuerAttributionRecord
+		var amount int64
+		var status int
+		if err := rows.Scan(
+			&r.TxID,
+			&r.ActionIndex,
+			&r.Issuer,
+			&r.IssuerID,
+			&r.TokenType,
+			&amount,
+			&r.Timestamp,
+			&status,
+		); err != nil {
+			return res, err
+		}
+		r.Amount = big.NewInt(amount)
+		r.Status = driver.TxStatus(status)
+		res = append(res, &r)
+	}
+	if err = rows.Err(); err != nil {
+		return res, err
+	}
+	return res, nil
 }
 
 func (db *TransactionDB) AddTransactionEndorsementAck(txID string, endorser token.Identity, sigma []byte) (err error) {
@@ -255,19 +395,578 @@ func (db *TransactionDB) SetStatus(ctx context.Context, txID string, status driv
 	span.AddEvent("start_db_update")
 	defer span.AddEvent("end_db_update")
 	var query string
+	var args []any
+	if len(message) != 0 {
+		query = fmt.Sprintf("UPDATE %s SET status = $1, status_message = $2 WHERE tx_id = $3;", db.table.Requests)
+		args = []any{status, message, txID}
+	} else {
+		query = fmt.Sprintf("UPDATE %s SET status = $1 WHERE tx_id = $2;", db.table.Requests)
+		args = []any{status, txID}
+	}
+	if status != driver.Deleted {
+		logger.Debug(query)
+		if _, err = db.db.Exec(query, args...); err != nil {
+			return errors.Wrapf(err, "error updating tx [%s]", txID)
+		}
+		return
+	}
+	// a deleted transaction releases its idempotency key
+	if _, err = execInTx(db.db, []deleteQuery{
+		{query: query, args: args},
+		db.releaseIdempotencyKeyQuery(txID),
+	}); err != nil {
+		return errors.Wrapf(err, "error updating tx [%s]", txID)
+	}
+	return
+}
+
+// releaseIdempotencyKeyQuery returns the query unbinding the idempotency key of the passed transaction, if any
+func (db *TransactionDB) releaseIdempotencyKeyQuery(txID string) deleteQuery {
+	return deleteQuery{
+		query: fmt.Sprintf("DELETE FROM %s WHERE tx_id = $1;", db.table.IdempotencyKeys),
+		args:  []any{txID},
+	}
+}
+
+// OverrideStatus sets the status of the passed transaction and records the override, in a single db transaction
+func (db *TransactionDB) OverrideStatus(ctx context.Context, txID string, status driver.TxStatus, message string, override driver.StatusOverride) (err error) {
+	if len(override.Operator) == 0 || len(override.Reason) == 0 {
+		return errors.Errorf("the override of the status of [%s] requires an operator and a reason", txID)
+	}
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("start_db_update")
+	defer span.AddEvent("end_db_update")
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed starting a db transaction")
+	}
+	defer func() {
+		if err != nil {
+			if err1 := tx.Rollback(); err1 != nil {
+				logger.Errorf("error rolling back: %s", err1.Error())
+			}
+		}
+	}()
+
+	var previous int
+	query := fmt.Sprintf("SELECT status FROM %s WHERE tx_id = $1;", db.table.Requests)
+	logger.Debug(query, txID)
+	if err = tx.QueryRow(query, txID).Scan(&previous); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.Wrapf(driver.ErrTokenRequestDoesNotExist, "cannot override the status of [%s]", txID)
+		}
+		return errors.Wrapf(err, "error querying status of [%s]", txID)
+	}
 	if len(message) != 0 {
 		query = fmt.Sprintf("UPDATE %s SET status = $1, status_message = $2 WHERE tx_id = $3;", db.table.Requests)
 		logger.Debug(query)
-		_, err = db.db.Exec(query, status, message, txID)
+		_, err = tx.Exec(query, status, message, txID)
 	} else {
 		query = fmt.Sprintf("UPDATE %s SET status = $1 WHERE tx_id = $2;", db.table.Requests)
 		logger.Debug(query)
-		_, err = db.db.Exec(query, status, txID)
+		_, err = tx.Exec(query, status, txID)
 	}
 	if err != nil {
 		return errors.Wrapf(err, "error updating tx [%s]", txID)
 	}
-	return
+	if status == driver.Deleted {
+		release := db.releaseIdempotencyKeyQuery(txID)
+		logger.Debug(release.query, txID)
+		if _, err = tx.Exec(release.query, release.args...); err != nil {
+			return errors.Wrapf(err, "error releasing the idempotency key of [%s]", txID)
+		}
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return errors.Wrapf(err, "error generating uuid")
+	}
+	query = fmt.Sprintf("INSERT INTO %s (id, tx_id, previous_status, status, operator, reason, stored_at) VALUES ($1, $2, $3, $4, $5, $6, $7)", db.table.StatusOverrides)
+	logger.Debug(query, txID, previous, status, override.Operator)
+	if _, err = tx.Exec(query, id, txID, previous, status, override.Operator, override.Reason, time.Now().UTC()); err != nil {
+		return errors.Wrapf(err, "error recording the override of the status of [%s]", txID)
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.Wrapf(err, "error committing the override of the status of [%s]", txID)
+	}
+	return nil
+}
+
+// QueryStatusOverrides returns the status overrides matching the passed params, the oldest first
+func (db *TransactionDB) QueryStatusOverrides(params driver.QueryStatusOverridesParams) (res []*driver.StatusOverrideRecord, err error) {
+	conditions, args := common.Where(db.ci.HasStatusOverridesParams(params))
+	query := fmt.Sprintf("SELECT tx_id, previous_status, status, operator, reason, stored_at FROM %s %s ORDER BY stored_at ASC", db.table.StatusOverrides, conditions)
+
+	logger.Debug(query, args)
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r driver.StatusOverrideRecord
+		var previous, status int
+		if err := rows.Scan(&r.TxID, &previous, &status, &r.Operator, &r.Reason, &r.Timestamp); err != nil {
+			return res, err
+		}
+		r.PreviousStatus = driver.TxStatus(previous)
+		r.Status = driver.TxStatus(status)
+		res = append(res, &r)
+	}
+	if err = rows.Err(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// CorrectMovement appends the passed correction to its movement, in a single db transaction.
+// A reversal cancels the amount of the movement with the corrections applied so far.
+func (db *TransactionDB) CorrectMovement(c driver.MovementCorrection) (res *driver.MovementCorrectionRecord, err error) {
+	if len(c.Operator) == 0 || len(c.Reason) == 0 {
+		return nil, errors.Errorf("the correction of the movement [%s] requires an operator and a reason", c.MovementID)
+	}
+	if c.Type != driver.Reversal && c.Type != driver.Adjustment {
+		return nil, errors.Errorf("invalid correction type [%d]", c.Type)
+	}
+	if c.Type == driver.Adjustment && (c.Amount == nil || c.Amount.Sign() == 0 || !c.Amount.IsInt64()) {
+		return nil, errors.Errorf("the adjustment of the movement [%s] requires a non-zero int64 amount", c.MovementID)
+	}
+	tx, err := db.db.Begin()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed starting a db transaction")
+	}
+	defer func() {
+		if err != nil {
+			if err1 := tx.Rollback(); err1 != nil {
+				logger.Errorf("error rolling back: %s", err1.Error())
+			}
+		}
+	}()
+
+	r := &driver.MovementCorrectionRecord{MovementID: c.MovementID, Type: c.Type, Operator: c.Operator, Reason: c.Reason}
+	var amount, corrections int64
+	query := fmt.Sprintf("SELECT tx_id, enrollment_id, token_type, amount FROM %s WHERE id = $1;", db.table.Movements)
+	logger.Debug(query, c.MovementID)
+	if err = tx.QueryRow(query, c.MovementID).Scan(&r.TxID, &r.EnrollmentID, &r.TokenType, &amount); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.Wrapf(driver.ErrMovementDoesNotExist, "cannot correct the movement [%s]", c.MovementID)
+		}
+		return nil, errors.Wrapf(err, "error querying movement [%s]", c.MovementID)
+	}
+	query = fmt.Sprintf("SELECT COALESCE(SUM(amount), 0) FROM %s WHERE movement_id = $1;", db.table.MovementCorrections)
+	logger.Debug(query, c.MovementID)
+	if err = tx.QueryRow(query, c.MovementID).Scan(&corrections); err != nil {
+		return nil, errors.Wrapf(err, "error querying the corrections of movement [%s]", c.MovementID)
+	}
+	if c.Type == driver.Reversal {
+		if amount+corrections == 0 {
+			return nil, errors.Errorf("the movement [%s] has no amount left to reverse", c.MovementID)
+		}
+		r.Amount = big.NewInt(-(amount + corrections))
+	} else {
+		r.Amount = new(big.Int).Set(c.Amount)
+	}
+
+	if r.ID, err = uuid.GenerateUUID(); err != nil {
+		return nil, errors.Wrapf(err, "error generating uuid")
+	}
+	r.Timestamp = time.Now().UTC()
+	query = fmt.Sprintf("INSERT INTO %s (id, movement_id, tx_id, correction_type, amount, operator, reason, stored_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", db.table.MovementCorrections)
+	logger.Debug(query, r.ID, r.MovementID, r.TxID, r.Type, r.Amount, r.Operator)
+	if _, err = tx.Exec(query, r.ID, r.MovementID, r.TxID, int(r.Type), r.Amount.Int64(), r.Operator, r.Reason, r.Timestamp); err != nil {
+		return nil, errors.Wrapf(err, "error recording the correction of the movement [%s]", c.MovementID)
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, errors.Wrapf(err, "error committing the correction of the movement [%s]", c.MovementID)
+	}
+	return r, nil
+}
+
+// QueryMovementCorrections returns the movement corrections matching the passed params, the oldest first
+func (db *TransactionDB) QueryMovementCorrections(params driver.QueryMovementCorrectionsParams) (res []*driver.MovementCorrectionRecord, err error) {
+	conditions, args := common.Where(db.ci.HasMovementCorrectionsParams(params, db.table.MovementCorrections))
+	query := fmt.Sprintf("SELECT %s.id, movement_id, %s.tx_id, enrollment_id, token_type, correction_type, %s.amount, operator, reason, %s.stored_at FROM %s LEFT JOIN %s ON %s.movement_id = %s.id %s ORDER BY %s.stored_at ASC",
+		db.table.MovementCorrections, db.table.MovementCorrections, db.table.MovementCorrections, db.table.MovementCorrections,
+		db.table.MovementCorrections, db.table.Movements, db.table.MovementCorrections, db.table.Movements, conditions, db.table.MovementCorrections)
+
+	logger.Debug(query, args)
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r driver.MovementCorrectionRecord
+		var correctionType int
+		var amount int64
+		if err := rows.Scan(&r.ID, &r.MovementID, &r.TxID, &r.EnrollmentID, &r.TokenType, &correctionType, &amount, &r.Operator, &r.Reason, &r.Timestamp); err != nil {
+			return res, err
+		}
+		r.Type = driver.CorrectionType(correctionType)
+		r.Amount = big.NewInt(amount)
+		res = append(res, &r)
+	}
+	if err = rows.Err(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// GetTxIDByIdempotencyKey returns the id of the pending or confirmed transaction bound to the passed idempotency key, if any
+func (db *TransactionDB) GetTxIDByIdempotencyKey(key string) (string, error) {
+	query := fmt.Sprintf("SELECT %s.tx_id FROM %s JOIN %s ON %s.tx_id = %s.tx_id WHERE idempotency_key = $1 AND status IN ($2, $3)",
+		db.table.IdempotencyKeys, db.table.IdempotencyKeys, db.table.Requests, db.table.IdempotencyKeys, db.table.Requests)
+	logger.Debug(query, key)
+
+	var txID string
+	if err := db.db.QueryRow(query, key, driver.Pending, driver.Confirmed).Scan(&txID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "error querying db")
+	}
+	return txID, nil
+}
+
+// GetTxIDsByReference returns the ids of the transactions bound to the passed hash of an external document, the oldest first
+func (db *TransactionDB) GetTxIDsByReference(reference []byte) ([]string, error) {
+	query := fmt.Sprintf("SELECT tx_id FROM %s WHERE reference = $1 ORDER BY stored_at ASC", db.table.References)
+	logger.Debug(query, reference)
+
+	rows, err := db.db.Query(query, reference)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying db")
+	}
+	defer rows.Close()
+	var txIDs []string
+	for rows.Next() {
+		var txID string
+		if err := rows.Scan(&txID); err != nil {
+			return nil, err
+		}
+		txIDs = append(txIDs, txID)
+	}
+	return txIDs, rows.Err()
+}
+
+// GetAuditResponse returns the audit response for the passed transaction id, nil if there is none
+func (db *TransactionDB) GetAuditResponse(txID string) (*driver.AuditResponseRecord, error) {
+	query := fmt.Sprintf("SELECT tx_id, requester, message_hash, response, sent, stored_at FROM %s WHERE tx_id = $1", db.table.AuditResponses)
+	logger.Debug(query, txID)
+
+	rows, err := db.db.Query(query, txID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying db")
+	}
+	records, err := scanAuditResponses(rows)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return records[0], nil
+}
+
+// QueryPendingAuditResponses returns the audit responses not sent yet, the oldest first
+func (db *TransactionDB) QueryPendingAuditResponses() ([]*driver.AuditResponseRecord, error) {
+	query := fmt.Sprintf("SELECT tx_id, requester, message_hash, response, sent, stored_at FROM %s WHERE sent = $1 ORDER BY stored_at ASC", db.table.AuditResponses)
+	logger.Debug(query)
+
+	rows, err := db.db.Query(query, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying db")
+	}
+	return scanAuditResponses(rows)
+}
+
+// MarkAuditResponseSent records that the audit response for the passed transaction id has been sent
+func (db *TransactionDB) MarkAuditResponseSent(txID string) error {
+	query := fmt.Sprintf("UPDATE %s SET sent = $1 WHERE tx_id = $2", db.table.AuditResponses)
+	logger.Debug(query, txID)
+
+	res, err := db.db.Exec(query, true, txID)
+	if err != nil {
+		return errors.Wrapf(err, "error updating db")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.Errorf("no audit response for [%s]", txID)
+	}
+	return nil
+}
+
+// QueryPendingExportRecords returns the entries of the export outbox not exported yet
+// whose transaction is either confirmed or deleted, the oldest first
+func (db *TransactionDB) QueryPendingExportRecords(limit int) ([]*driver.ExportRecord, error) {
+	query := fmt.Sprintf("SELECT %s.tx_id, payload, status, %s.stored_at FROM %s %s WHERE sent = $1 AND (status = $2 OR status = $3) ORDER BY %s.stored_at ASC",
+		db.table.ExportOutbox, db.table.ExportOutbox,
+		db.table.ExportOutbox, joinOnTxID(db.table.ExportOutbox, db.table.Requests),
+		db.table.ExportOutbox)
+	if limit > 0 {
+		query = query + " LIMIT " + strconv.Itoa(limit)
+	}
+	logger.Debug(query)
+
+	rows, err := db.db.Query(query, false, driver.Confirmed, driver.Deleted)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying db")
+	}
+	defer rows.Close()
+	var res []*driver.ExportRecord
+	for rows.Next() {
+		var r driver.ExportRecord
+		if err := rows.Scan(&r.TxID, &r.Payload, &r.Status, &r.Timestamp); err != nil {
+			return nil, err
+		}
+		res = append(res, &r)
+	}
+	return res, rows.Err()
+}
+
+// MarkExported records that the entries of the export outbox for the passed transaction ids have been exported
+func (db *TransactionDB) MarkExported(txIDs []string) error {
+	if len(txIDs) == 0 {
+		return nil
+	}
+	where, args := common.Where(db.ci.InStrings("tx_id", txIDs))
+	query := fmt.Sprintf("UPDATE %s SET sent = true %s", db.table.ExportOutbox, where)
+	logger.Debug(query, args)
+
+	if _, err := db.db.Exec(query, args...); err != nil {
+		return errors.Wrapf(err, "error updating db")
+	}
+	return nil
+}
+
+// AddPseudonym stores the sealed enrollment ID of the passed pseudonym, a pseudonym already stored is kept
+func (db *TransactionDB) AddPseudonym(pseudonym string, sealed []byte) error {
+	query := fmt.Sprintf("INSERT INTO %s (pseudonym, enrollment_id, stored_at) VALUES ($1, $2, $3)", db.table.Pseudonyms)
+	logger.Debug(query, pseudonym)
+
+	if _, err := db.db.Exec(query, pseudonym, sealed, time.Now().UTC()); err != nil {
+		if isUniqueViolation(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "error inserting pseudonym")
+	}
+	return nil
+}
+
+// GetPseudonym returns the sealed enrollment ID of the passed pseudonym, nil if the pseudonym is unknown
+func (db *TransactionDB) GetPseudonym(pseudonym string) ([]byte, error) {
+	query := fmt.Sprintf("SELECT enrollment_id FROM %s WHERE pseudonym = $1", db.table.Pseudonyms)
+	logger.Debug(query, pseudonym)
+
+	var sealed []byte
+	if err := db.db.QueryRow(query, pseudonym).Scan(&sealed); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "error querying db")
+	}
+	return sealed, nil
+}
+
+// DeletePseudonym deletes the sealed enrollment ID of the passed pseudonym
+func (db *TransactionDB) DeletePseudonym(pseudonym string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE pseudonym = $1", db.table.Pseudonyms)
+	logger.Debug(query, pseudonym)
+
+	if _, err := db.db.Exec(query, pseudonym); err != nil {
+		return errors.Wrapf(err, "error deleting pseudonym")
+	}
+	return nil
+}
+
+// AddRecipientAnonymityLevel records the anonymity level the passed recipient identity has been derived with,
+// by the passed local wallet. A level already recorded for the identity is kept.
+func (db *TransactionDB) AddRecipientAnonymityLevel(identity token.Identity, walletID string, level string) error {
+	query := fmt.Sprintf("INSERT INTO %s (identity_hash, wallet_id, anonymity_level, stored_at) VALUES ($1, $2, $3, $4)", db.table.AnonymityLevels)
+	logger.Debug(query, walletID, level)
+
+	if _, err := db.db.Exec(query, identity.UniqueID(), walletID, level, time.Now().UTC()); err != nil {
+		if isUniqueViolation(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "error inserting anonymity level")
+	}
+	return nil
+}
+
+// GetRecipientAnonymityLevels returns the anonymity levels recorded for the passed recipient identities,
+// indexed by the unique id of the identity. The identities without a level are missing in the returned map.
+func (db *TransactionDB) GetRecipientAnonymityLevels(identities []token.Identity) (map[string]string, error) {
+	res := map[string]string{}
+	if len(identities) == 0 {
+		return res, nil
+	}
+	ids := make([]string, len(identities))
+	for i, id := range identities {
+		ids[i] = id.UniqueID()
+	}
+	where, args := common.Where(db.ci.InStrings("identity_hash", ids))
+	query := fmt.Sprintf("SELECT identity_hash, anonymity_level FROM %s %s", db.table.AnonymityLevels, where)
+	logger.Debug(query, args)
+
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying db")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, level string
+		if err := rows.Scan(&id, &level); err != nil {
+			return nil, err
+		}
+		res[id] = level
+	}
+	return res, rows.Err()
+}
+
+func scanAuditResponses(rows *Rows) ([]*driver.AuditResponseRecord, error) {
+	defer rows.Close()
+	var res []*driver.AuditResponseRecord
+	for rows.Next() {
+		var r driver.AuditResponseRecord
+		if err := rows.Scan(&r.TxID, &r.Requester, &r.MessageHash, &r.Response, &r.Sent, &r.Timestamp); err != nil {
+			return nil, err
+		}
+		res = append(res, &r)
+	}
+	return res, rows.Err()
+}
+
+// AddFundsWitness stores the passed funds witness
+func (db *TransactionDB) AddFundsWitness(r *driver.FundsWitnessRecord) error {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return errors.Wrapf(err, "error generating uuid")
+	}
+	now := time.Now().UTC()
+	query := fmt.Sprintf("INSERT INTO %s (id, tx_id, wallet_id, token_type, snapshot, signer, signature, stored_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", db.table.FundsWitnesses)
+	logger.Debug(query, id, r.TxID, r.WalletID, r.TokenType, fmt.Sprintf("(%d bytes)", len(r.Snapshot)), now)
+	if _, err := db.db.Exec(query, id, r.TxID, r.WalletID, r.TokenType, r.Snapshot, r.Signer, r.Signature, now); err != nil {
+		return errors.Wrapf(err, "error storing funds witness of wallet [%s]", r.WalletID)
+	}
+	r.ID = id
+	r.Timestamp = now
+	return nil
+}
+
+// QueryFundsWitnesses returns the funds witnesses matching the passed params, the oldest first
+func (db *TransactionDB) QueryFundsWitnesses(params driver.QueryFundsWitnessesParams) ([]*driver.FundsWitnessRecord, error) {
+	conditions, args := common.Where(db.ci.HasFundsWitnessesParams(params))
+	query := fmt.Sprintf("SELECT id, tx_id, wallet_id, token_type, snapshot, signer, signature, stored_at FROM %s %s ORDER BY stored_at ASC", db.table.FundsWitnesses, conditions)
+	logger.Debug(query, args)
+
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying db")
+	}
+	defer rows.Close()
+	var res []*driver.FundsWitnessRecord
+	for rows.Next() {
+		var r driver.FundsWitnessRecord
+		if err := rows.Scan(&r.ID, &r.TxID, &r.WalletID, &r.TokenType, &r.Snapshot, &r.Signer, &r.Signature, &r.Timestamp); err != nil {
+			return nil, err
+		}
+		res = append(res, &r)
+	}
+	return res, rows.Err()
+}
+
+// TableSizes returns the disk usage of the transaction tables
+func (db *TransactionDB) TableSizes() ([]driver.TableSize, error) {
+	if db.sr == nil {
+		return nil, driver.ErrTableSizesNotSupported
+	}
+	return db.sr.TableSizes(db.db, []string{
+		db.table.Requests,
+		db.table.Transactions,
+		db.table.Movements,
+		db.table.MovementCorrections,
+		db.table.Validations,
+		db.table.TransactionEndorseAck,
+		db.table.IssuerAttributions,
+		db.table.StatusOverrides,
+		db.table.IdempotencyKeys,
+		db.table.AuditResponses,
+		db.table.FundsWitnesses,
+		db.table.References,
+		db.table.TxWallets,
+		db.table.ExportOutbox,
+		db.table.Pseudonyms,
+		db.table.AnonymityLevels,
+	})
+}
+
+// addedColumns returns the columns added to the tables of GetSchema after their first release.
+// The requested_at of the token requests stored before it was added is NULL.
+func (db *TransactionDB) addedColumns() Schema {
+	return Schema{Tables: []Table{
+		{Name: db.table.Requests, Added: []string{"requested_at TIMESTAMP"}},
+	}}
+}
+
+// AddCompensationFailure records the failed compensation of the passed transaction, replacing the one already recorded, if any
+func (db *TransactionDB) AddCompensationFailure(txID string, message string) (err error) {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return errors.Wrapf(err, "failed starting a db transaction")
+	}
+	defer func() {
+		if err != nil {
+			if err1 := tx.Rollback(); err1 != nil {
+				logger.Errorf("error rolling back: %s", err1.Error())
+			}
+		}
+	}()
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE tx_id = $1", db.table.CompensationFailures)
+	logger.Debug(query, txID)
+	if _, err = tx.Exec(query, txID); err != nil {
+		return errors.Wrapf(err, "error deleting the compensation failure of [%s]", txID)
+	}
+	query = fmt.Sprintf("INSERT INTO %s (tx_id, message, stored_at) VALUES ($1, $2, $3)", db.table.CompensationFailures)
+	logger.Debug(query, txID)
+	if _, err = tx.Exec(query, txID, message, time.Now().UTC()); err != nil {
+		return errors.Wrapf(err, "error recording the compensation failure of [%s]", txID)
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.Wrapf(err, "error committing the compensation failure of [%s]", txID)
+	}
+	return nil
+}
+
+// DeleteCompensationFailure removes the compensation failure recorded for the passed transaction, if any
+func (db *TransactionDB) DeleteCompensationFailure(txID string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE tx_id = $1", db.table.CompensationFailures)
+	logger.Debug(query, txID)
+
+	if _, err := db.db.Exec(query, txID); err != nil {
+		return errors.Wrapf(err, "error deleting the compensation failure of [%s]", txID)
+	}
+	return nil
+}
+
+// QueryCompensationFailures returns the messages of the recorded compensation failures, indexed by transaction id
+func (db *TransactionDB) QueryCompensationFailures() (map[string]string, error) {
+	query := fmt.Sprintf("SELECT tx_id, message FROM %s", db.table.CompensationFailures)
+	logger.Debug(query)
+
+	rows, err := db.db.Query(query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying db")
+	}
+	defer rows.Close()
+	res := map[string]string{}
+	for rows.Next() {
+		var txID, message string
+		if err := rows.Scan(&txID, &message); err != nil {
+			return nil, err
+		}
+		res[txID] = message
+	}
+	return res, rows.Err()
 }
 
 func (db *TransactionDB) GetSchema() string {
@@ -279,7 +978,8 @@ func (db *TransactionDB) GetSchema() string {
 			status INT NOT NULL,
 			status_message TEXT NOT NULL,
 			application_metadata JSONB NOT NULL,
-			pp_hash BYTEA NOT NULL
+			pp_hash BYTEA NOT NULL,
+			requested_at TIMESTAMP
 		);
 
 		-- transactions
@@ -306,6 +1006,19 @@ func (db *TransactionDB) GetSchema() string {
 		);
 		CREATE INDEX IF NOT EXISTS idx_tx_id_%s ON %s ( tx_id );
 
+		-- movement corrections
+		CREATE TABLE IF NOT EXISTS %s (
+			id CHAR(36) NOT NULL PRIMARY KEY,
+			movement_id CHAR(36) NOT NULL REFERENCES %s,
+			tx_id TEXT NOT NULL REFERENCES %s,
+			correction_type INT NOT NULL,
+			amount BIGINT NOT NULL,
+			operator TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			stored_at TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_movement_id_%s ON %s ( movement_id );
+
 		-- validations
 		CREATE TABLE IF NOT EXISTS %s (
 			tx_id TEXT NOT NULL PRIMARY KEY REFERENCES %s,
@@ -322,12 +1035,132 @@ func (db *TransactionDB) GetSchema() string {
 			stored_at TIMESTAMP NOT NULL
 		);
 		CREATE INDEX IF NOT EXISTS idx_tx_id_%s ON %s ( tx_id );
+
+		-- issuer attributions
+		CREATE TABLE IF NOT EXISTS %s (
+			id CHAR(36) NOT NULL PRIMARY KEY,
+			tx_id TEXT NOT NULL REFERENCES %s,
+			action_index INT NOT NULL,
+			issuer BYTEA NOT NULL,
+			issuer_id TEXT NOT NULL,
+			token_type TEXT NOT NULL,
+			amount BIGINT NOT NULL,
+			stored_at TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_tx_id_%s ON %s ( tx_id );
+		CREATE INDEX IF NOT EXISTS idx_issuer_id_%s ON %s ( issuer_id );
+
+		-- status overrides
+		CREATE TABLE IF NOT EXISTS %s (
+			id CHAR(36) NOT NULL PRIMARY KEY,
+			tx_id TEXT NOT NULL REFERENCES %s,
+			previous_status INT NOT NULL,
+			status INT NOT NULL,
+			operator TEXT NOT NULL,
+			reason TEXT NOT NULL,
+			stored_at TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_tx_id_%s ON %s ( tx_id );
+
+		-- compensation failures
+		CREATE TABLE IF NOT EXISTS %s (
+			tx_id TEXT NOT NULL PRIMARY KEY REFERENCES %s,
+			message TEXT NOT NULL,
+			stored_at TIMESTAMP NOT NULL
+		);
+
+		-- idempotency keys
+		CREATE TABLE IF NOT EXISTS %s (
+			idempotency_key TEXT NOT NULL,
+			tx_id TEXT NOT NULL REFERENCES %s,
+			stored_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (idempotency_key, tx_id)
+		);
+		-- the keys of the deleted transactions are released, databases created before the keys were unique may still have them
+		DELETE FROM %s WHERE tx_id IN (SELECT tx_id FROM %s WHERE status = %d);
+		CREATE UNIQUE INDEX IF NOT EXISTS uidx_idempotency_key_%s ON %s ( idempotency_key );
+
+		-- audit responses
+		CREATE TABLE IF NOT EXISTS %s (
+			tx_id TEXT NOT NULL PRIMARY KEY REFERENCES %s,
+			requester BYTEA NOT NULL,
+			message_hash BYTEA NOT NULL,
+			response BYTEA NOT NULL,
+			sent BOOLEAN NOT NULL,
+			stored_at TIMESTAMP NOT NULL
+		);
+
+		-- funds witnesses
+		CREATE TABLE IF NOT EXISTS %s (
+			id CHAR(36) NOT NULL PRIMARY KEY,
+			tx_id TEXT NOT NULL,
+			wallet_id TEXT NOT NULL,
+			token_type TEXT NOT NULL,
+			snapshot BYTEA NOT NULL,
+			signer BYTEA NOT NULL,
+			signature BYTEA NOT NULL,
+			stored_at TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_wallet_id_%s ON %s ( wallet_id );
+
+		-- external references
+		CREATE TABLE IF NOT EXISTS %s (
+			tx_id TEXT NOT NULL PRIMARY KEY REFERENCES %s,
+			reference BYTEA NOT NULL,
+			stored_at TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_reference_%s ON %s ( reference );
+
+		-- wallets
+		CREATE TABLE IF NOT EXISTS %s (
+			tx_id TEXT NOT NULL PRIMARY KEY REFERENCES %s,
+			wallet_id TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_wallet_id_%s ON %s ( wallet_id );
+
+		-- export outbox
+		CREATE TABLE IF NOT EXISTS %s (
+			tx_id TEXT NOT NULL PRIMARY KEY REFERENCES %s,
+			payload BYTEA NOT NULL,
+			sent BOOLEAN NOT NULL,
+			stored_at TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_sent_%s ON %s ( sent );
+
+		-- pseudonyms
+		CREATE TABLE IF NOT EXISTS %s (
+			pseudonym TEXT NOT NULL PRIMARY KEY,
+			enrollment_id BYTEA NOT NULL,
+			stored_at TIMESTAMP NOT NULL
+		);
+
+		-- recipient_anonymity_levels
+		CREATE TABLE IF NOT EXISTS %s (
+			identity_hash TEXT NOT NULL PRIMARY KEY,
+			wallet_id TEXT NOT NULL,
+			anonymity_level TEXT NOT NULL,
+			stored_at TIMESTAMP NOT NULL
+		);
 		`,
 		db.table.Requests,
 		db.table.Transactions, db.table.Requests, db.table.Transactions, db.table.Transactions,
 		db.table.Movements, db.table.Requests, db.table.Movements, db.table.Movements,
+		db.table.MovementCorrections, db.table.Movements, db.table.Requests, db.table.MovementCorrections, db.table.MovementCorrections,
 		db.table.Validations, db.table.Requests,
 		db.table.TransactionEndorseAck, db.table.TransactionEndorseAck, db.table.TransactionEndorseAck,
+		db.table.IssuerAttributions, db.table.Requests, db.table.IssuerAttributions, db.table.IssuerAttributions,
+		db.table.IssuerAttributions, db.table.IssuerAttributions,
+		db.table.StatusOverrides, db.table.Requests, db.table.StatusOverrides, db.table.StatusOverrides,
+		db.table.CompensationFailures, db.table.Requests,
+		db.table.IdempotencyKeys, db.table.Requests,
+		db.table.IdempotencyKeys, db.table.Requests, driver.Deleted, db.table.IdempotencyKeys, db.table.IdempotencyKeys,
+		db.table.AuditResponses, db.table.Requests,
+		db.table.FundsWitnesses, db.table.FundsWitnesses, db.table.FundsWitnesses,
+		db.table.References, db.table.Requests, db.table.References, db.table.References,
+		db.table.TxWallets, db.table.Requests, db.table.TxWallets, db.table.TxWallets,
+		db.table.ExportOutbox, db.table.Requests, db.table.ExportOutbox, db.table.ExportOutbox,
+		db.table.Pseudonyms,
+		db.table.AnonymityLevels,
 	)
 }
 
@@ -344,7 +1177,7 @@ func unmarshal(in []byte, out *map[string][]byte) error {
 }
 
 type TransactionIterator struct {
-	txs *sql.Rows
+	txs *Rows
 }
 
 func (t *TransactionIterator) Close() {
@@ -360,7 +1193,7 @@ func (t *TransactionIterator) Next() (*driver.TransactionRecord, error) {
 	var amount int64
 	var status int
 	var metadata []byte
-	// tx_id, action_type, sender_eid, recipient_eid, token_type, amount, status, stored_at
+	// tx_id, action_type, sender_eid, recipient_eid, token_type, amount, status, application_metadata, stored_at, wallet_id
 	err := t.txs.Scan(
 		&r.TxID,
 		&actionType,
@@ -371,6 +1204,7 @@ func (t *TransactionIterator) Next() (*driver.TransactionRecord, error) {
 		&status,
 		&metadata,
 		&r.Timestamp,
+		&r.WalletID,
 	)
 	if err := unmarshal(metadata, &r.ApplicationMetadata); err != nil {
 		logger.Errorf("error unmarshaling application metadata: %v", metadata)
@@ -385,11 +1219,12 @@ func (t *TransactionIterator) Next() (*driver.TransactionRecord, error) {
 }
 
 type ValidationRecordsIterator struct {
-	txs *sql.Rows
+	txs *Rows
 	// Filter defines a custom filter function.
 	// If specified, this filter will be applied.
 	// the filter returns true if the record must be selected, false otherwise.
 	filter func(record *driver.ValidationRecord) bool
+	cipher driver.ColumnCipher
 }
 
 func (t *ValidationRecordsIterator) Close() {
@@ -418,6 +1253,11 @@ func (t *ValidationRecordsIterator) Next() (*driver.ValidationRecord, error) {
 	if err := unmarshal(meta, &r.Metadata); err != nil {
 		return &r, err
 	}
+	request, err := decryptColumn(t.cipher, r.TokenRequest)
+	if err != nil {
+		return &r, errors.WithMessagef(err, "failed to decrypt token request [%s]", r.TxID)
+	}
+	r.TokenRequest = request
 	r.Timestamp = storedAt
 	r.Status = driver.TxStatus(status)
 
@@ -437,7 +1277,10 @@ func (t *ValidationRecordsIterator) Next() (*driver.ValidationRecord, error) {
 }
 
 type TokenRequestIterator struct {
-	txs *sql.Rows
+	txs    *Rows
+	cipher driver.ColumnCipher
+	// applicationMetadata, if not empty, is the application metadata the returned records must hold
+	applicationMetadata map[string][]byte
 }
 
 func (t *TokenRequestIterator) Close() {
@@ -445,20 +1288,55 @@ func (t *TokenRequestIterator) Close() {
 }
 
 func (t *TokenRequestIterator) Next() (*driver.TokenRequestRecord, error) {
+	for {
+		r, err := t.next()
+		if err != nil || r == nil || holds(r.ApplicationMetadata, t.applicationMetadata) {
+			return r, err
+		}
+	}
+}
+
+// holds returns true if the passed metadata holds all the passed keys with their values, a nil value matches any value
+func holds(metadata map[string][]byte, kvs map[string][]byte) bool {
+	for k, v := range kvs {
+		value, ok := metadata[k]
+		if !ok || (v != nil && !bytes.Equal(v, value)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *TokenRequestIterator) next() (*driver.TokenRequestRecord, error) {
 	var r driver.TokenRequestRecord
 	if !t.txs.Next() {
 		return nil, nil
 	}
 
 	var status int
-	// tx_id, request, metadata, status, stored_at
+	var metadata []byte
+	var requestedAt sql.NullTime
+	// tx_id, request, status, application_metadata, requested_at
 	if err := t.txs.Scan(
 		&r.TxID,
 		&r.TokenRequest,
 		&status,
+		&metadata,
+		&requestedAt,
 	); err != nil {
 		return nil, err
 	}
+	r.Timestamp = requestedAt.Time
+	if len(metadata) != 0 {
+		if err := unmarshal(metadata, &r.ApplicationMetadata); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal application metadata of [%s]", r.TxID)
+		}
+	}
+	request, err := decryptColumn(t.cipher, r.TokenRequest)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to decrypt token request [%s]", r.TxID)
+	}
+	r.TokenRequest = request
 	r.Status = driver.TxStatus(status)
 	// sqlite database returns nil for empty slice
 	if r.TokenRequest == nil {
@@ -480,7 +1358,7 @@ func (db *TransactionDB) BeginAtomicWrite() (driver.AtomicWrite, error) {
 }
 
 type AtomicWrite struct {
-	txn *sql.Tx
+	txn *Tx
 	db  *TransactionDB
 }
 
@@ -539,10 +1417,16 @@ func (w *AtomicWrite) AddTokenRequest(txID string, tr []byte, applicationMetadat
 		return errors.New("error marshaling application metadata")
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s (tx_id, request, status, status_message, application_metadata, pp_hash) VALUES ($1, $2, $3, $4, $5, $6)", w.db.table.Requests)
-	logger.Debug(query, txID, fmt.Sprintf("(%d bytes)", len(tr)), len(applicationMetadata), len(ppHash))
+	tr, err = encryptColumn(w.db.cipher, tr)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to encrypt token request [%s]", txID)
+	}
+
+	now := time.Now().UTC()
+	query := fmt.Sprintf("INSERT INTO %s (tx_id, request, status, status_message, application_metadata, pp_hash, requested_at) VALUES ($1, $2, $3, $4, $5, $6, $7)", w.db.table.Requests)
+	logger.Debug(query, txID, fmt.Sprintf("(%d bytes)", len(tr)), len(applicationMetadata), len(ppHash), now)
 
-	_, err = w.txn.Exec(query, txID, tr, driver.Pending, "", j, ppHash)
+	_, err = w.txn.Exec(query, txID, tr, driver.Pending, "", j, ppHash, now)
 	return ttxDBError(err)
 }
 
@@ -588,6 +1472,128 @@ func (w *AtomicWrite) AddValidationRecord(txID string, meta map[string][]byte) e
 	return ttxDBError(err)
 }
 
+func (w *AtomicWrite) AddIssuerAttribution(r *driver.IssuerAttributionRecord) error {
+	logger.Debugf("adding issuer attribution record [%s:%d:%s:%s:%s]", r.TxID, r.ActionIndex, r.IssuerID, r.TokenType, r.Amount)
+	if w.txn == nil {
+		return errors.New("no db transaction in progress")
+	}
+	if !r.Amount.IsInt64() {
+		return errors.New("the database driver does not support larger values than int64")
+	}
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return errors.Wrapf(err, "error generating uuid")
+	}
+	issuer := r.Issuer
+	if issuer == nil {
+		issuer = []byte{}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (id, tx_id, action_index, issuer, issuer_id, token_type, amount, stored_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);", w.db.table.IssuerAttributions)
+	args := []any{id, r.TxID, r.ActionIndex, issuer, r.IssuerID, r.TokenType, r.Amount.Int64(), r.Timestamp.UTC()}
+	logger.Debug(query, args)
+	_, err = w.txn.Exec(query, args...)
+
+	return ttxDBError(err)
+}
+
+func (w *AtomicWrite) AddAuditResponse(r *driver.AuditResponseRecord) error {
+	logger.Debugf("adding audit response [%s]", r.TxID)
+	if w.txn == nil {
+		return errors.New("no db transaction in progress")
+	}
+	requester := r.Requester
+	if requester == nil {
+		requester = []byte{}
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (tx_id, requester, message_hash, response, sent, stored_at) VALUES ($1, $2, $3, $4, $5, $6)", w.db.table.AuditResponses)
+	logger.Debug(query, r.TxID)
+
+	_, err := w.txn.Exec(query, r.TxID, requester, r.MessageHash, r.Response, false, time.Now().UTC())
+	return ttxDBError(err)
+}
+
+func (w *AtomicWrite) AddIdempotencyKey(txID string, key string) error {
+	logger.Debugf("adding idempotency key [%s:%s]", txID, key)
+	if w.txn == nil {
+		return errors.New("no db transaction in progress")
+	}
+	if len(key) == 0 {
+		return errors.New("empty idempotency key")
+	}
+
+	// only the keys of the transactions not deleted are stored, see SetStatus
+	query := fmt.Sprintf("SELECT tx_id FROM %s WHERE idempotency_key = $1", w.db.table.IdempotencyKeys)
+	logger.Debug(query, key)
+	var existing string
+	if err := w.txn.QueryRow(query, key).Scan(&existing); err == nil {
+		if existing != txID {
+			return &driver.IdempotencyKeyError{Key: key, TxID: existing}
+		}
+		return nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return errors.Wrapf(err, "error querying idempotency key [%s]", key)
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (idempotency_key, tx_id, stored_at) VALUES ($1, $2, $3)", w.db.table.IdempotencyKeys)
+	logger.Debug(query, key, txID)
+
+	if _, err := w.txn.Exec(query, key, txID, time.Now().UTC()); err != nil {
+		// another transaction stored the key after the query above
+		if isUniqueViolation(err) {
+			return &driver.IdempotencyKeyError{Key: key}
+		}
+		return ttxDBError(err)
+	}
+	return nil
+}
+
+func (w *AtomicWrite) AddReference(txID string, reference []byte) error {
+	logger.Debugf("adding reference [%s:%x]", txID, reference)
+	if w.txn == nil {
+		return errors.New("no db transaction in progress")
+	}
+	if len(reference) == 0 {
+		return errors.New("empty reference")
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (tx_id, reference, stored_at) VALUES ($1, $2, $3)", w.db.table.References)
+	logger.Debug(query, txID, reference)
+
+	_, err := w.txn.Exec(query, txID, reference, time.Now().UTC())
+	return ttxDBError(err)
+}
+
+func (w *AtomicWrite) AddWallet(txID string, walletID string) error {
+	logger.Debugf("adding wallet [%s:%s]", txID, walletID)
+	if w.txn == nil {
+		return errors.New("no db transaction in progress")
+	}
+	if len(walletID) == 0 {
+		return errors.New("empty wallet id")
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (tx_id, wallet_id) VALUES ($1, $2)", w.db.table.TxWallets)
+	logger.Debug(query, txID, walletID)
+
+	_, err := w.txn.Exec(query, txID, walletID)
+	return ttxDBError(err)
+}
+
+func (w *AtomicWrite) AddExportRecord(txID string, payload []byte) error {
+	logger.Debugf("adding export record [%s]", txID)
+	if w.txn == nil {
+		return errors.New("no db transaction in progress")
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (tx_id, payload, sent, stored_at) VALUES ($1, $2, $3, $4)", w.db.table.ExportOutbox)
+	logger.Debug(query, txID)
+
+	_, err := w.txn.Exec(query, txID, payload, false, time.Now().UTC())
+	return ttxDBError(err)
+}
+
 func ttxDBError(err error) error {
 	if err == nil {
 		return nil
@@ -600,3 +1606,10 @@ func ttxDBError(err error) error {
 	}
 	return err
 }
+
+// isUniqueViolation returns true if the passed error reports the violation of a unique constraint,
+// as reported by sqlite, postgres, oracle, and sql server
+func isUniqueViolation(err error) bool {
+	e := strings.ToLower(err.Error())
+	return strings.Contains(e, "unique constraint") || strings.Contains(e, "duplicate key")
+}
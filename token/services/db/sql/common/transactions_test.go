@@ -7,13 +7,19 @@ SPDX-License-Identifier: Apache-2.0
 package common
 
 import (
+	"context"
 	"fmt"
+	"math/big"
 	"path"
 	"testing"
+	"time"
 
 	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/common"
+	tdriver "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/dbtest"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/stretchr/testify/assert"
 )
 
 func initTransactionsDB(driverName common.SQLDriverType, dataSourceName, tablePrefix string, maxOpenConns int) (*TransactionDB, error) {
@@ -26,7 +32,7 @@ func initTransactionsDB(driverName common.SQLDriverType, dataSourceName, tablePr
 		DataSource:   dataSourceName,
 		TablePrefix:  tablePrefix,
 		CreateSchema: true,
-	}, NewTokenInterpreter(common.NewInterpreter()))
+	}, NewTokenInterpreter(common.NewInterpreter()), sizeReporter(driverName))
 	if err != nil {
 		return nil, err
 	}
@@ -48,6 +54,73 @@ func TestTransactionsSqlite(t *testing.T) {
 	}
 }
 
+func TestTransactionTableSizes(t *testing.T) {
+	db, err := initTransactionsDB(sql2.SQLite, fmt.Sprintf("file:%s?_pragma=busy_timeout(20000)", path.Join(t.TempDir(), "db.sqlite")), "sizes", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sizes, err := db.TableSizes()
+	assert.NoError(t, err)
+	assert.Len(t, sizes, 16)
+	for _, size := range sizes {
+		assert.Positive(t, size.Bytes, size.Table)
+		assert.Zero(t, size.Rows, size.Table)
+	}
+}
+
+func TestUpgradeTransactionSchema(t *testing.T) {
+	dataSource := fmt.Sprintf("file:%s?_pragma=busy_timeout(20000)", path.Join(t.TempDir(), "db.sqlite"))
+	sqlDB, err := NewSQLDBOpener("", "").OpenSQLDB(sql2.SQLite, dataSource, 10, false)
+	assert.NoError(t, err)
+	defer sqlDB.Close()
+
+	// a requests table created before requested_at was added, with a token request
+	tables, err := GetTableNames("old")
+	assert.NoError(t, err)
+	_, err = sqlDB.Exec(fmt.Sprintf("CREATE TABLE %s (tx_id TEXT NOT NULL PRIMARY KEY, request BYTEA NOT NULL, status INT NOT NULL, status_message TEXT NOT NULL, application_metadata JSONB NOT NULL, pp_hash BYTEA NOT NULL)", tables.Requests))
+	assert.NoError(t, err)
+	_, err = sqlDB.Exec(fmt.Sprintf("INSERT INTO %s (tx_id, request, status, status_message, application_metadata, pp_hash) VALUES ('tx1', x'01', 1, '', '{}', x'01')", tables.Requests))
+	assert.NoError(t, err)
+
+	db, err := initTransactionsDB(sql2.SQLite, dataSource, "old", 10)
+	assert.NoError(t, err)
+	defer db.Close()
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("tx2", []byte("request"), nil, []byte("pp")))
+	assert.NoError(t, w.Commit())
+
+	// the old token request has no time, it is selected only without From
+	it, err := db.QueryTokenRequests(driver.QueryTokenRequestsParams{})
+	assert.NoError(t, err)
+	times := map[string]time.Time{}
+	for {
+		r, err := it.Next()
+		assert.NoError(t, err)
+		if r == nil {
+			break
+		}
+		times[r.TxID] = r.Timestamp
+	}
+	it.Close()
+	assert.Len(t, times, 2)
+	assert.True(t, times["tx1"].IsZero())
+	assert.False(t, times["tx2"].IsZero())
+
+	from := times["tx2"].Add(-time.Minute)
+	it, err = db.QueryTokenRequests(driver.QueryTokenRequestsParams{From: &from})
+	assert.NoError(t, err)
+	defer it.Close()
+	r, err := it.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "tx2", r.TxID)
+	r, err = it.Next()
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+}
+
 func TestTransactionsSqliteMemory(t *testing.T) {
 	for _, c := range dbtest.TokenTransactionDBCases {
 		db, err := initTransactionsDB(sql2.SQLite, "file:tmp?_pragma=busy_timeout(20000)&mode=memory&cache=shared", c.Name, 10)
@@ -76,3 +149,93 @@ func TestTransactionsPostgres(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteExpiredTransactionsSqlite(t *testing.T) {
+	db, err := initTransactionsDB(sql2.SQLite, fmt.Sprintf("file:%s?_pragma=busy_timeout(20000)", path.Join(t.TempDir(), "db.sqlite")), "expiry", 10)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	w, err := db.BeginAtomicWrite()
+	assert.NoError(t, err)
+	assert.NoError(t, w.AddTokenRequest("tx1", []byte("request"), map[string][]byte{}, tdriver.PPHash("pp")))
+	assert.NoError(t, w.AddTransaction(&driver.TransactionRecord{
+		TxID:         "tx1",
+		ActionType:   driver.Transfer,
+		SenderEID:    "bob",
+		RecipientEID: "alice",
+		TokenType:    "magic",
+		Amount:       big.NewInt(10),
+		Timestamp:    time.Now(),
+		Status:       driver.Pending,
+	}))
+	assert.NoError(t, w.AddValidationRecord("tx1", nil))
+	assert.NoError(t, w.AddWallet("tx1", "alice"))
+	assert.NoError(t, w.Commit())
+
+	deleted, err := db.DeleteExpired(time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+
+	// wallet, transaction, validation, and the request no longer referenced
+	deleted, err = db.DeleteExpired(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), deleted)
+	request, err := db.GetTokenRequest("tx1")
+	assert.NoError(t, err)
+	assert.Nil(t, request)
+}
+
+func TestTransactionsEncryptionSqlite(t *testing.T) {
+	db, err := initTransactionsDB(sql2.SQLite, fmt.Sprintf("file:%s?_pragma=busy_timeout(20000)", path.Join(t.TempDir(), "db.sqlite")), "encryption", 10)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	storedRequest := func(txID string) []byte {
+		var request []byte
+		assert.NoError(t, db.db.QueryRow(fmt.Sprintf("SELECT request FROM %s WHERE tx_id = $1", db.table.Requests), txID).Scan(&request))
+		return request
+	}
+	add := func(txID string) {
+		w, err := db.BeginAtomicWrite()
+		assert.NoError(t, err)
+		assert.NoError(t, w.AddTokenRequest(txID, []byte(txID+"request"), map[string][]byte{}, tdriver.PPHash("pp")))
+		assert.NoError(t, w.Commit())
+	}
+	checkRequests := func() {
+		for _, txID := range []string{"tx1", "tx2"} {
+			request, err := db.GetTokenRequest(txID)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte(txID+"request"), request)
+		}
+	}
+
+	// requests stored before encryption is enabled stay readable
+	add("tx1")
+	db.SetColumnCipher(&testCipher{active: "k1"})
+	add("tx2")
+	assert.Equal(t, []byte("tx1request"), storedRequest("tx1"))
+	assert.Equal(t, []byte("enc:k1:tx2request"), storedRequest("tx2"))
+	checkRequests()
+
+	n, err := db.ReEncrypt(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []byte("enc:k1:tx1request"), storedRequest("tx1"))
+	checkRequests()
+
+	// rotation
+	db.SetColumnCipher(&testCipher{active: "k2"})
+	n, err = db.ReEncrypt(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []byte("enc:k2:tx1request"), storedRequest("tx1"))
+	assert.Equal(t, []byte("enc:k2:tx2request"), storedRequest("tx2"))
+	checkRequests()
+}
+
+func sizeReporter(driverName common.SQLDriverType) SizeReporter {
+	if driverName == sql2.Postgres {
+		return NewPostgresSizeReporter()
+	}
+	return NewSQLiteSizeReporter()
+}
@@ -18,15 +18,16 @@ import (
 )
 
 type walletTables struct {
-	Wallets string
+	Wallets            string
+	LongTermIdentities string
 }
 
 type WalletDB struct {
-	db    *sql.DB
+	db    *DB
 	table walletTables
 }
 
-func newWalletDB(db *sql.DB, tables walletTables) *WalletDB {
+func newWalletDB(db *DB, tables walletTables) *WalletDB {
 	return &WalletDB{
 		db:    db,
 		table: tables,
@@ -39,7 +40,7 @@ func NewWalletDB(db *sql.DB, opts NewDBOpts) (driver.WalletDB, error) {
 		return nil, errors.Wrapf(err, "failed to get table names [%s]", opts.TablePrefix)
 	}
 
-	walletDB := newWalletDB(db, walletTables{Wallets: tables.Wallets})
+	walletDB := newWalletDB(NewDB(db, opts.StatementTimeout), walletTables{Wallets: tables.Wallets, LongTermIdentities: tables.LongTermIdentities})
 	if opts.CreateSchema {
 		if err = common.InitSchema(db, []string{walletDB.GetSchema()}...); err != nil {
 			return nil, errors.Wrapf(err, "failed to create schema")
@@ -128,6 +129,34 @@ func (db *WalletDB) IdentityExists(identity token.Identity, wID driver.WalletID,
 	return result != ""
 }
 
+func (db *WalletDB) StoreLongTermIdentity(identity token.Identity, wID driver.WalletID, roleID int) error {
+	query := fmt.Sprintf("INSERT INTO %s (wallet_id, role_id, identity, created_at) VALUES ($1, $2, $3, $4)", db.table.LongTermIdentities)
+	logger.Debug(query)
+
+	if _, err := db.db.Exec(query, wID, roleID, []byte(identity), time.Now().UTC()); err != nil {
+		if isUniqueViolation(err) {
+			logger.Debugf("long-term identity of wallet [%s] already stored", wID)
+			return nil
+		}
+		return errors.Wrapf(err, "failed storing long-term identity of wallet [%s]", wID)
+	}
+	return nil
+}
+
+func (db *WalletDB) GetLongTermIdentity(wID driver.WalletID, roleID int) (token.Identity, error) {
+	query := fmt.Sprintf("SELECT identity FROM %s WHERE wallet_id=$1 AND role_id=$2", db.table.LongTermIdentities)
+	logger.Debug(query)
+
+	var identity []byte
+	if err := db.db.QueryRow(query, wID, roleID).Scan(&identity); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed loading long-term identity of wallet [%s]", wID)
+	}
+	return identity, nil
+}
+
 func (db *WalletDB) GetSchema() string {
 	return fmt.Sprintf(`
 		-- Wallets
@@ -143,12 +172,22 @@ func (db *WalletDB) GetSchema() string {
 		CREATE INDEX IF NOT EXISTS idx_identity_hash_%s ON %s ( identity_hash );
 		CREATE INDEX IF NOT EXISTS idx_identity_hash_and_wallet_and_role%s ON %s ( identity_hash, wallet_id, role_id );
 		CREATE INDEX IF NOT EXISTS idx_identity_hash_and_role%s ON %s ( identity_hash, role_id );
-		CREATE INDEX IF NOT EXISTS idx_role_id_%s ON %s ( role_id )
+		CREATE INDEX IF NOT EXISTS idx_role_id_%s ON %s ( role_id );
+
+		-- LongTermIdentities
+		CREATE TABLE IF NOT EXISTS %s (
+			wallet_id TEXT NOT NULL,
+			role_id INT NOT NULL,
+			identity BYTEA NOT NULL,
+			created_at TIMESTAMP,
+			PRIMARY KEY(wallet_id, role_id)
+		)
 		`,
 		db.table.Wallets,
 		db.table.Wallets, db.table.Wallets,
 		db.table.Wallets, db.table.Wallets,
 		db.table.Wallets, db.table.Wallets,
 		db.table.Wallets, db.table.Wallets,
+		db.table.LongTermIdentities,
 	)
 }
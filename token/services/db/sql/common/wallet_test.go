@@ -81,6 +81,7 @@ var WalletCases = []struct {
 }{
 	{"TDuplicate", TDuplicate},
 	{"TWalletIdentities", TWalletIdentities},
+	{"TLongTermIdentities", TLongTermIdentities},
 }
 
 func TDuplicate(t *testing.T, db *WalletDB) {
@@ -115,3 +116,26 @@ func TWalletIdentities(t *testing.T, db *WalletDB) {
 	assert.NoError(t, err)
 	assert.Equal(t, []driver.WalletID{"alice_wallet"}, ids)
 }
+
+func TLongTermIdentities(t *testing.T, db *WalletDB) {
+	id, err := db.GetLongTermIdentity("alice_wallet", 0)
+	assert.NoError(t, err)
+	assert.Nil(t, id)
+
+	assert.NoError(t, db.StoreLongTermIdentity([]byte("alice"), "alice_wallet", 0))
+	// the first identity stored is kept
+	assert.NoError(t, db.StoreLongTermIdentity([]byte("alice2"), "alice_wallet", 0))
+	assert.NoError(t, db.StoreLongTermIdentity([]byte("bob"), "alice_wallet", 1))
+
+	id, err = db.GetLongTermIdentity("alice_wallet", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", string(id))
+	id, err = db.GetLongTermIdentity("alice_wallet", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", string(id))
+
+	// they are not wallet bindings
+	ids, err := db.GetWalletIDs(1)
+	assert.NoError(t, err)
+	assert.NotContains(t, ids, "alice_wallet")
+}
@@ -15,8 +15,10 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
 	dbdriver "github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/oracle"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/postgres"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/sqlite"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/sqlserver"
 	"github.com/pkg/errors"
 )
 
@@ -55,7 +57,11 @@ func (d *unityDriver[V]) Open(cp dbdriver.ConfigProvider, tmsID token.TMSID) (V,
 	if !ok {
 		return utils.Zero[V](), errors.New("constructor not found")
 	}
-	return constructor(sqlDB, common.NewDBOptsFromOpts(*opts))
+	dbOpts, err := d.dbOpener.DBOpts(cp, tmsID, opts)
+	if err != nil {
+		return utils.Zero[V](), err
+	}
+	return constructor(sqlDB, dbOpts)
 }
 
 type IdentityDBDriver struct {
@@ -79,12 +85,16 @@ func NewDBDrivers() (db.NamedDriver[dbdriver.TTXDBDriver], db.NamedDriver[dbdriv
 			sql3.Postgres: postgres.NewTransactionDB,
 		}),
 		newUnityDriver[dbdriver.TokenDB, dbdriver.TokenDBDriver](root, constructors[dbdriver.TokenDB]{
-			sql3.SQLite:   sqlite.NewTokenDB,
-			sql3.Postgres: postgres.NewTokenDB,
+			sql3.SQLite:      sqlite.NewTokenDB,
+			sql3.Postgres:    postgres.NewTokenDB,
+			common.Oracle:    oracle.NewTokenDB,
+			common.SQLServer: sqlserver.NewTokenDB,
 		}),
 		newUnityDriver[dbdriver.TokenNotifier, dbdriver.TokenNotifierDriver](root, constructors[dbdriver.TokenNotifier]{
-			sql3.SQLite:   sqlite.NewTokenNotifier,
-			sql3.Postgres: postgres.NewTokenNotifier,
+			sql3.SQLite:      sqlite.NewTokenNotifier,
+			sql3.Postgres:    postgres.NewTokenNotifier,
+			common.Oracle:    oracle.NewTokenNotifier,
+			common.SQLServer: sqlserver.NewTokenNotifier,
 		}),
 		newUnityDriver[dbdriver.TokenLockDB, dbdriver.TokenLockDBDriver](root, constructors[dbdriver.TokenLockDB]{
 			sql3.SQLite:   sqlite.NewTokenLockDB,
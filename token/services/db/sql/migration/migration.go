@@ -0,0 +1,358 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package migration moves the tables of a TMS between databases, for instance to merge the databases of the
+// `sql` persistence, one per store, into the single database of the `unity` persistence, or the reverse.
+package migration
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	db2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db"
+	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
+	common2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/oracle"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/postgres"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/sqlite"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/sqlserver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/pkg/errors"
+)
+
+var logger = logging.MustGetLogger("token-sdk.sql.migration")
+
+// Store identifies the tables of one of the stores of a TMS
+type Store string
+
+const (
+	TokenStore       Store = "tokendb"
+	TransactionStore Store = "ttxdb"
+	// AuditStore has the same tables as TransactionStore
+	AuditStore     Store = "auditdb"
+	IdentityStore  Store = "identitydb"
+	WalletStore    Store = "walletdb"
+	TokenLockStore Store = "tokenlockdb"
+)
+
+// Database is a database holding the tables of some stores of a TMS
+type Database struct {
+	Driver common2.SQLDriverType
+	DB     *sql.DB
+	Stores []Store
+}
+
+// TableReport describes the copy of a table
+type TableReport struct {
+	Table string
+	// Rows is the number of rows in the target table after the copy
+	Rows int64
+	// Hash is a digest of the rows of the target table, independent of their order
+	Hash string
+}
+
+// Report describes a migration
+type Report struct {
+	TablePrefix string
+	Tables      []TableReport
+}
+
+// Migrate copies the tables of the passed TMS from the sources to the target.
+// Only the tables of the stores of the target are copied, from the sources holding the same stores.
+// The target schema is created if it does not exist, and the target tables must be empty.
+// After the copy, the row count and the hash of each target table are checked against those of the sources.
+//
+// Merging the `sql` databases of a TMS into a `unity` database is one call with all the databases as sources.
+// Splitting is one call per `sql` database, with the `unity` database as the only source.
+// The ttxdb and the auditdb have the same tables in a `unity` database. Merging both fails
+// if they hold the same transaction.
+func Migrate(tmsID token.TMSID, target Database, sources ...Database) (*Report, error) {
+	prefix := db2.EscapeForTableName(tmsID.Network, tmsID.Channel, tmsID.Namespace)
+	tables, err := common.GetTableNames(prefix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid table prefix [%s]", prefix)
+	}
+	if err := createSchema(target, prefix); err != nil {
+		return nil, errors.WithMessagef(err, "failed creating target schema for [%s]", tmsID)
+	}
+
+	report := &Report{TablePrefix: prefix}
+	for _, targetTable := range tablesOf(tables, target.Stores) {
+		rows, hash, err := digest(target.DB, targetTable)
+		if err != nil {
+			return nil, err
+		}
+		if rows != 0 {
+			return nil, errors.Errorf("target table [%s] is not empty, found [%d] rows", targetTable, rows)
+		}
+
+		var expectedRows int64
+		expectedHash := new(big.Int)
+		for _, source := range sources {
+			if !contains(tablesOf(tables, source.Stores), targetTable) {
+				continue
+			}
+			rows, hash, err := digest(source.DB, targetTable)
+			if err != nil {
+				return nil, err
+			}
+			if err := copyTable(source.DB, target, targetTable); err != nil {
+				return nil, errors.WithMessagef(err, "failed copying [%s]", targetTable)
+			}
+			expectedRows += rows
+			addHash(expectedHash, hash)
+		}
+
+		rows, hash, err = digest(target.DB, targetTable)
+		if err != nil {
+			return nil, err
+		}
+		if rows != expectedRows {
+			return nil, errors.Errorf("row count mismatch for [%s]: expected [%d], got [%d]", targetTable, expectedRows, rows)
+		}
+		if hash.Cmp(expectedHash) != 0 {
+			return nil, errors.Errorf("content mismatch for [%s]", targetTable)
+		}
+		logger.Infof("migrated table [%s], [%d] rows", targetTable, rows)
+		report.Tables = append(report.Tables, TableReport{Table: targetTable, Rows: rows, Hash: hex.EncodeToString(hash.Bytes())})
+	}
+	return report, nil
+}
+
+// tablesOf returns the tables of the passed stores, the referenced tables before those referencing them
+func tablesOf(tables common.TableNames, stores []Store) []string {
+	var res []string
+	add := func(names ...string) {
+		for _, name := range names {
+			if !contains(res, name) {
+				res = append(res, name)
+			}
+		}
+	}
+	for _, store := range stores {
+		switch store {
+		case TokenStore:
+			add(tables.Tokens, tables.Ownership, tables.PublicParams, tables.Certifications, tables.TokenAttributes, tables.TokenSerials, tables.TokenIntents)
+		case TransactionStore, AuditStore:
+			add(tables.Requests, tables.Transactions, tables.Movements, tables.MovementCorrections, tables.Validations, tables.TransactionEndorseAck,
+				tables.IssuerAttributions, tables.StatusOverrides, tables.CompensationFailures, tables.IdempotencyKeys, tables.AuditResponses,
+				tables.FundsWitnesses, tables.References, tables.TxWallets, tables.ExportOutbox, tables.Pseudonyms,
+				tables.AnonymityLevels)
+		case IdentityStore:
+			add(tables.IdentityConfigurations, tables.IdentityInfo, tables.Signers, tables.Recipients)
+		case WalletStore:
+			add(tables.Wallets, tables.LongTermIdentities)
+		case TokenLockStore:
+			add(tables.TokenLocks)
+		}
+	}
+	return res
+}
+
+// createSchema creates the tables of the stores of the passed database by opening them as the drivers do
+func createSchema(d Database, prefix string) error {
+	opts := common.NewDBOpts{TablePrefix: prefix, CreateSchema: true}
+	for _, store := range d.Stores {
+		var err error
+		switch d.Driver {
+		case sql2.SQLite:
+			err = createSQLiteSchema(d.DB, opts, store)
+		case sql2.Postgres:
+			err = createPostgresSchema(d.DB, opts, store)
+		case common.Oracle:
+			if store != TokenStore {
+				return errors.Errorf("store [%s] not supported by [%s]", store, d.Driver)
+			}
+			_, err = oracle.NewTokenDB(d.DB, opts)
+		case common.SQLServer:
+			if store != TokenStore {
+				return errors.Errorf("store [%s] not supported by [%s]", store, d.Driver)
+			}
+			_, err = sqlserver.NewTokenDB(d.DB, opts)
+		default:
+			return errors.Errorf("driver [%s] not supported", d.Driver)
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "failed creating schema of [%s]", store)
+		}
+	}
+	return nil
+}
+
+func createSQLiteSchema(db *sql.DB, opts common.NewDBOpts, store Store) error {
+	var err error
+	switch store {
+	case TokenStore:
+		_, err = sqlite.NewTokenDB(db, opts)
+	case TransactionStore:
+		_, err = sqlite.NewTransactionDB(db, opts)
+	case AuditStore:
+		_, err = sqlite.NewAuditTransactionDB(db, opts)
+	case IdentityStore:
+		_, err = sqlite.NewIdentityDB(db, opts)
+	case WalletStore:
+		_, err = sqlite.NewWalletDB(db, opts)
+	case TokenLockStore:
+		_, err = sqlite.NewTokenLockDB(db, opts)
+	default:
+		err = errors.Errorf("unknown store [%s]", store)
+	}
+	return err
+}
+
+func createPostgresSchema(db *sql.DB, opts common.NewDBOpts, store Store) error {
+	var err error
+	switch store {
+	case TokenStore:
+		_, err = postgres.NewTokenDB(db, opts)
+	case TransactionStore:
+		_, err = postgres.NewTransactionDB(db, opts)
+	case AuditStore:
+		_, err = postgres.NewAuditTransactionDB(db, opts)
+	case IdentityStore:
+		_, err = postgres.NewIdentityDB(db, opts)
+	case WalletStore:
+		_, err = postgres.NewWalletDB(db, opts)
+	case TokenLockStore:
+		_, err = postgres.NewTokenLockDB(db, opts)
+	default:
+		err = errors.Errorf("unknown store [%s]", store)
+	}
+	return err
+}
+
+// copyTable copies all the rows of the passed table from src to dst, in a single transaction
+func copyTable(src *sql.DB, dst Database, table string) error {
+	// the table names are derived from the TMS id and are not user input
+	rows, err := src.Query(fmt.Sprintf("SELECT * FROM %s", table))
+	if err != nil {
+		return errors.Wrapf(err, "failed reading [%s]", table)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return errors.Wrapf(err, "failed reading the columns of [%s]", table)
+	}
+	placeholders := make([]string, len(columns))
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+	dialect := common.DialectOf(dst.Driver)
+
+	tx, err := dst.DB.Begin()
+	if err != nil {
+		return errors.Wrapf(err, "failed starting transaction")
+	}
+	for rows.Next() {
+		values, err := scan(rows, len(columns))
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		query, args := dialect.Statement(insert, values)
+		if _, err := tx.Exec(query, args...); err != nil {
+			_ = tx.Rollback()
+			return errors.Wrapf(err, "failed inserting into [%s]", table)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = tx.Rollback()
+		return errors.Wrapf(err, "failed reading [%s]", table)
+	}
+	return tx.Commit()
+}
+
+// digest returns the number of rows of the passed table and the sum of the hashes of its rows
+func digest(db *sql.DB, table string) (int64, *big.Int, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT * FROM %s", table))
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "failed reading [%s]", table)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "failed reading the columns of [%s]", table)
+	}
+	var count int64
+	sum := new(big.Int)
+	for rows.Next() {
+		values, err := scan(rows, len(columns))
+		if err != nil {
+			return 0, nil, err
+		}
+		addHash(sum, new(big.Int).SetBytes(hashRow(values)))
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, errors.Wrapf(err, "failed reading [%s]", table)
+	}
+	return count, sum, nil
+}
+
+var modulus = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// addHash adds the passed hash to sum, modulo 2^256, so that the result does not depend on the order of the rows
+func addHash(sum *big.Int, hash *big.Int) {
+	sum.Add(sum, hash)
+	sum.Mod(sum, modulus)
+}
+
+func scan(rows *sql.Rows, n int) ([]any, error) {
+	values := make([]any, n)
+	ptrs := make([]any, n)
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, errors.Wrapf(err, "failed scanning row")
+	}
+	return values, nil
+}
+
+// hashRow hashes a canonical encoding of the passed values.
+// Text and binary values are encoded the same way, as drivers differ in how they return them.
+func hashRow(values []any) []byte {
+	h := sha256.New()
+	for _, v := range values {
+		var b []byte
+		switch v := v.(type) {
+		case nil:
+			b = nil
+		case []byte:
+			b = v
+		case string:
+			b = []byte(v)
+		case time.Time:
+			b = []byte(v.UTC().Format(time.RFC3339Nano))
+		default:
+			b = []byte(fmt.Sprintf("%v", v))
+		}
+		length := make([]byte, 8)
+		binary.BigEndian.PutUint64(length, uint64(len(b)))
+		h.Write(length)
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
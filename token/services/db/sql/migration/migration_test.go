@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package migration
+
+import (
+	"database/sql"
+	"fmt"
+	"path"
+	"testing"
+	"time"
+
+	db2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db"
+	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
+	sqlite2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/sqlite"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/sqlite"
+	"github.com/stretchr/testify/assert"
+)
+
+func openDB(t *testing.T, name string) *sql.DB {
+	db, err := sqlite2.OpenDB(fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", path.Join(t.TempDir(), name)), 1, 1, time.Minute, false)
+	assert.NoError(t, err)
+	return db
+}
+
+func TestMergeAndSplit(t *testing.T) {
+	tmsID := token.TMSID{Network: "n", Channel: "c", Namespace: "ns"}
+	opts := common.NewDBOpts{TablePrefix: db2.EscapeForTableName("n", "c", "ns"), CreateSchema: true}
+
+	// one database per store
+	tokenDB := openDB(t, "tokendb.sqlite")
+	tdb, err := sqlite.NewTokenDB(tokenDB, opts)
+	assert.NoError(t, err)
+	assert.NoError(t, tdb.StorePublicParams([]byte("pp1")))
+	assert.NoError(t, tdb.StorePublicParams([]byte("pp2")))
+	walletDB := openDB(t, "walletdb.sqlite")
+	wdb, err := sqlite.NewWalletDB(walletDB, opts)
+	assert.NoError(t, err)
+	assert.NoError(t, wdb.StoreIdentity([]byte("alice"), "alice", "alice-wallet", 0, nil))
+
+	// merge
+	unity := openDB(t, "unity.sqlite")
+	report, err := Migrate(tmsID,
+		Database{Driver: sql2.SQLite, DB: unity, Stores: []Store{TokenStore, WalletStore}},
+		Database{Driver: sql2.SQLite, DB: tokenDB, Stores: []Store{TokenStore}},
+		Database{Driver: sql2.SQLite, DB: walletDB, Stores: []Store{WalletStore}},
+	)
+	assert.NoError(t, err)
+	rows := map[string]int64{}
+	for _, table := range report.Tables {
+		rows[table.Table] = table.Rows
+	}
+	tables, err := common.GetTableNames(report.TablePrefix)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), rows[tables.PublicParams])
+	assert.Equal(t, int64(1), rows[tables.Wallets])
+
+	// the target must be empty
+	_, err = Migrate(tmsID,
+		Database{Driver: sql2.SQLite, DB: unity, Stores: []Store{WalletStore}},
+		Database{Driver: sql2.SQLite, DB: walletDB, Stores: []Store{WalletStore}},
+	)
+	assert.ErrorContains(t, err, "is not empty")
+
+	// split
+	split := openDB(t, "split.sqlite")
+	_, err = Migrate(tmsID,
+		Database{Driver: sql2.SQLite, DB: split, Stores: []Store{WalletStore}},
+		Database{Driver: sql2.SQLite, DB: unity, Stores: []Store{TokenStore, WalletStore}},
+	)
+	assert.NoError(t, err)
+	wdb, err = sqlite.NewWalletDB(split, common.NewDBOpts{TablePrefix: opts.TablePrefix})
+	assert.NoError(t, err)
+	wID, err := wdb.GetWalletID([]byte("alice"), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice-wallet", wID)
+}
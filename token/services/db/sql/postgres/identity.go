@@ -9,13 +9,12 @@ package postgres
 import (
 	"database/sql"
 
-	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/postgres"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/common"
 )
 
 func OpenIdentityDB(k common.Opts) (driver.IdentityDB, error) {
-	db, err := postgres.OpenDB(k.DataSource, k.MaxOpenConns, k.MaxIdleConns, k.MaxIdleTime)
+	db, err := common.OpenDB(k)
 	if err != nil {
 		return nil, err
 	}
@@ -11,7 +11,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/postgres"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
@@ -23,7 +22,7 @@ type TokenLockDB struct {
 }
 
 func OpenTokenLockDB(k common.Opts) (driver.TokenLockDB, error) {
-	db, err := postgres.OpenDB(k.DataSource, k.MaxOpenConns, k.MaxIdleConns, k.MaxIdleTime)
+	db, err := common.OpenDB(k)
 	if err != nil {
 		return nil, err
 	}
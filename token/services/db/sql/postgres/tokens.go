@@ -17,7 +17,7 @@ import (
 )
 
 func NewTokenDB(db *sql.DB, opts common.NewDBOpts) (driver.TokenDB, error) {
-	return common.NewTokenDB(db, opts, common.NewTokenInterpreter(postgres.NewInterpreter()))
+	return common.NewTokenDB(db, opts, common.NewTokenInterpreter(postgres.NewInterpreter()), common.NewPostgresQueryPlanner(), common.NewPostgresSizeReporter())
 }
 
 type TokenNotifier struct {
@@ -29,11 +29,19 @@ func NewTokenNotifier(db *sql.DB, opts common.NewDBOpts) (driver.TokenNotifier,
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get table names")
 	}
-	notifier := postgres.NewNotifier(db, tables.Tokens, opts.DataSource, postgres.AllOperations, *postgres.NewSimplePrimaryKey("tx_id"), *postgres.NewSimplePrimaryKey("idx"))
-	if opts.CreateSchema {
-		if err = common2.InitSchema(db, notifier.GetSchema()); err != nil {
-			return nil, err
+	// a sharded tokens table is notified by each of its shards
+	notifiers := common.ShardedNotifier{}
+	for _, table := range common.TokenTableNames(tables.Tokens, opts.Shards) {
+		notifier := postgres.NewNotifier(db, table, opts.DataSource, postgres.AllOperations, *postgres.NewSimplePrimaryKey("tx_id"), *postgres.NewSimplePrimaryKey("idx"))
+		if opts.CreateSchema {
+			if err = common2.InitSchema(db, notifier.GetSchema()); err != nil {
+				return nil, err
+			}
 		}
+		notifiers = append(notifiers, notifier)
 	}
-	return notifier, nil
+	if len(notifiers) == 1 {
+		return notifiers[0], nil
+	}
+	return notifiers, nil
 }
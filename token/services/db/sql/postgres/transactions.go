@@ -15,7 +15,7 @@ import (
 )
 
 func OpenAuditTransactionDB(k common.Opts) (driver.AuditTransactionDB, error) {
-	db, err := postgres.OpenDB(k.DataSource, k.MaxOpenConns, k.MaxIdleConns, k.MaxIdleTime)
+	db, err := common.OpenDB(k)
 	if err != nil {
 		return nil, err
 	}
@@ -23,11 +23,11 @@ func OpenAuditTransactionDB(k common.Opts) (driver.AuditTransactionDB, error) {
 }
 
 func NewAuditTransactionDB(db *sql.DB, opts common.NewDBOpts) (driver.AuditTransactionDB, error) {
-	return common.NewAuditTransactionDB(db, opts, common.NewTokenInterpreter(postgres.NewInterpreter()))
+	return common.NewAuditTransactionDB(db, opts, common.NewTokenInterpreter(postgres.NewInterpreter()), common.NewPostgresSizeReporter())
 }
 
 func OpenTransactionDB(k common.Opts) (driver.TokenTransactionDB, error) {
-	db, err := postgres.OpenDB(k.DataSource, k.MaxOpenConns, k.MaxIdleConns, k.MaxIdleTime)
+	db, err := common.OpenDB(k)
 	if err != nil {
 		return nil, err
 	}
@@ -35,5 +35,5 @@ func OpenTransactionDB(k common.Opts) (driver.TokenTransactionDB, error) {
 }
 
 func NewTransactionDB(db *sql.DB, opts common.NewDBOpts) (driver.TokenTransactionDB, error) {
-	return common.NewTransactionDB(db, opts, common.NewTokenInterpreter(postgres.NewInterpreter()))
+	return common.NewTransactionDB(db, opts, common.NewTokenInterpreter(postgres.NewInterpreter()), common.NewPostgresSizeReporter())
 }
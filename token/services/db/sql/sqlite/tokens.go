@@ -10,13 +10,17 @@ import (
 	"database/sql"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/notifier"
+	sql2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/sqlite"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/common"
 )
 
 func NewTokenDB(db *sql.DB, opts common.NewDBOpts) (driver.TokenDB, error) {
-	return common.NewTokenDB(db, opts, common.NewTokenInterpreter(sqlite.NewInterpreter()))
+	if err := opts.Schema.Validate(sql2.SQLite); err != nil {
+		return nil, err
+	}
+	return common.NewTokenDB(db, opts, common.NewTokenInterpreter(sqlite.NewInterpreter()), common.NewSQLiteQueryPlanner(), common.NewSQLiteSizeReporter())
 }
 
 func NewTokenNotifier(*sql.DB, common.NewDBOpts) (driver.TokenNotifier, error) {
@@ -0,0 +1,31 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sqlserver
+
+import (
+	"database/sql"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/notifier"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/common"
+)
+
+// NewTokenDB returns a new TokenDB backed by SQL Server.
+// The passed database must have been opened with common.OpenDialectDB and common.SQLServerDialect.
+func NewTokenDB(db *sql.DB, opts common.NewDBOpts) (driver.TokenDB, error) {
+	d := common.SQLServerDialect()
+	if err := opts.Schema.Validate(d.Driver()); err != nil {
+		return nil, err
+	}
+	opts.Dialect = d
+	return common.NewTokenDB(db, opts, d.TokenInterpreter(), d.QueryPlanner(), d.SizeReporter())
+}
+
+// NewTokenNotifier returns an in-process notifier, SQL Server has no notification mechanism the token DB relies on
+func NewTokenNotifier(*sql.DB, common.NewDBOpts) (driver.TokenNotifier, error) {
+	return notifier.NewNotifier(), nil
+}
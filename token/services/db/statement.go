@@ -0,0 +1,76 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/metrics"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+)
+
+var (
+	timedOutStatements = metrics.CounterOpts{
+		Namespace:    "db",
+		Name:         "timed_out_statements",
+		Help:         "The number of statements interrupted by the statement timeout.",
+		LabelNames:   []string{"store", "network", "channel", "namespace"},
+		StatsdFormat: "%{#fqname}.%{store}.%{network}.%{channel}.%{namespace}",
+	}
+	cancelledStatements = metrics.CounterOpts{
+		Namespace:    "db",
+		Name:         "cancelled_statements",
+		Help:         "The number of statements cancelled by their caller.",
+		LabelNames:   []string{"store", "network", "channel", "namespace"},
+		StatsdFormat: "%{#fqname}.%{store}.%{network}.%{channel}.%{namespace}",
+	}
+)
+
+// StatementMetrics counts the statements of the databases interrupted before completion
+type StatementMetrics struct {
+	TimedOutStatements  metrics.Counter
+	CancelledStatements metrics.Counter
+}
+
+func NewStatementMetrics(p metrics.Provider) *StatementMetrics {
+	return &StatementMetrics{
+		TimedOutStatements:  p.NewCounter(timedOutStatements),
+		CancelledStatements: p.NewCounter(cancelledStatements),
+	}
+}
+
+// Observer returns the observer counting the interrupted statements of the passed store of the passed TMS
+func (m *StatementMetrics) Observer(store string, tmsID token.TMSID) driver.StatementObserver {
+	labels := []string{"store", store, "network", tmsID.Network, "channel", tmsID.Channel, "namespace", tmsID.Namespace}
+	return &statementObserver{
+		timedOut:  m.TimedOutStatements.With(labels...),
+		cancelled: m.CancelledStatements.With(labels...),
+	}
+}
+
+type statementObserver struct {
+	timedOut  metrics.Counter
+	cancelled metrics.Counter
+}
+
+func (o *statementObserver) TimedOut() {
+	o.timedOut.Add(1)
+}
+
+func (o *statementObserver) Cancelled() {
+	o.cancelled.Add(1)
+}
+
+// SetStatementObserver sets the passed observer on the passed database driver, if it reports its interrupted statements.
+// It returns false otherwise.
+func SetStatementObserver(d any, o driver.StatementObserver) bool {
+	s, ok := d.(driver.ObservedDB)
+	if !ok {
+		return false
+	}
+	s.SetStatementObserver(o)
+	return true
+}
@@ -9,12 +9,19 @@ package db
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
 	"go.opentelemetry.io/otel/trace"
 )
 
+var logger = logging.MustGetLogger("token-sdk.db")
+
+// DefaultListenerQueueSize is the number of events a listener can lag behind before the next events are dropped
+const DefaultListenerQueueSize = 100
+
 type StatusEvent struct {
 	Ctx               context.Context
 	TxID              string
@@ -22,31 +29,113 @@ type StatusEvent struct {
 	ValidationMessage string
 }
 
+// ListenerStats describes the health of a status listener
+type ListenerStats struct {
+	// TxID is the transaction the listener waits for
+	TxID string
+	// Queued is the number of events waiting to be delivered
+	Queued int
+	// Delivered is the number of events delivered
+	Delivered uint64
+	// Dropped is the number of events dropped because the queue was full
+	Dropped uint64
+	// Lag is the time the last delivered event waited in the queue
+	Lag time.Duration
+}
+
+// Slow returns true if the listener has dropped events, or its queue is more than half full
+func (s ListenerStats) Slow(queueSize int) bool {
+	return s.Dropped > 0 || s.Queued > queueSize/2
+}
+
+type queuedEvent struct {
+	event      StatusEvent
+	enqueuedAt time.Time
+}
+
+// listener delivers the events of its queue to the channel of the subscriber, in order
+type listener struct {
+	txID  string
+	ch    chan StatusEvent
+	queue chan queuedEvent
+	done  chan struct{}
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	lag       atomic.Int64
+}
+
+func (l *listener) run() {
+	for {
+		select {
+		case <-l.done:
+			return
+		case qe := <-l.queue:
+			select {
+			case <-l.done:
+				return
+			case l.ch <- qe.event:
+				l.delivered.Add(1)
+				l.lag.Store(int64(time.Since(qe.enqueuedAt)))
+			}
+		}
+	}
+}
+
+func (l *listener) stats() ListenerStats {
+	return ListenerStats{
+		TxID:      l.txID,
+		Queued:    len(l.queue),
+		Delivered: l.delivered.Load(),
+		Dropped:   l.dropped.Load(),
+		Lag:       time.Duration(l.lag.Load()),
+	}
+}
+
+// StatusSupport notifies the listeners of the status changes of the transactions.
+// Each listener has its own queue, therefore, a slow listener does not block Notify, and so the processing of commits,
+// nor the other listeners. When the queue of a listener is full, the next events for it are dropped and counted.
+// A listener receives its events in the order of the Notify calls, skipping the dropped ones.
 type StatusSupport struct {
-	listeners      map[string][]chan StatusEvent
+	listeners      map[string][]*listener
 	mutex          sync.RWMutex
 	pollingTimeout time.Duration
+	queueSize      int
+	dropped        atomic.Uint64
 }
 
 func NewStatusSupport() *StatusSupport {
+	return NewStatusSupportWithQueueSize(DefaultListenerQueueSize)
+}
+
+// NewStatusSupportWithQueueSize returns a StatusSupport whose listeners queue up to the passed number of events
+func NewStatusSupportWithQueueSize(queueSize int) *StatusSupport {
+	if queueSize <= 0 {
+		queueSize = DefaultListenerQueueSize
+	}
 	return &StatusSupport{
-		listeners:      map[string][]chan StatusEvent{},
+		listeners:      map[string][]*listener{},
 		pollingTimeout: 1 * time.Second,
+		queueSize:      queueSize,
 	}
 }
 
 func (c *StatusSupport) AddStatusListener(txID string, ch chan StatusEvent) {
+	l := &listener{
+		txID:  txID,
+		ch:    ch,
+		queue: make(chan queuedEvent, c.queueSize),
+		done:  make(chan struct{}),
+	}
+	go l.run()
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-
-	ls, ok := c.listeners[txID]
-	if !ok {
-		ls = []chan StatusEvent{}
-	}
-	ls = append(ls, ch)
-	c.listeners[txID] = ls
+	c.listeners[txID] = append(c.listeners[txID], l)
 }
 
+// DeleteStatusListener removes the listener of the passed channel.
+// The events still in its queue are not delivered.
 func (c *StatusSupport) DeleteStatusListener(txID string, ch chan StatusEvent) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -56,30 +145,59 @@ func (c *StatusSupport) DeleteStatusListener(txID string, ch chan StatusEvent) {
 		return
 	}
 	for i, l := range ls {
-		if l == ch {
+		if l.ch == ch {
+			close(l.done)
 			ls = append(ls[:i], ls[i+1:]...)
-			c.listeners[txID] = ls
+			if len(ls) == 0 {
+				delete(c.listeners, txID)
+			} else {
+				c.listeners[txID] = ls
+			}
 			return
 		}
 	}
 }
 
+// Notify enqueues the passed event for the listeners of its transaction, without waiting for the delivery
 func (c *StatusSupport) Notify(event StatusEvent) {
 	span := trace.SpanFromContext(event.Ctx)
 	span.AddEvent("start_notify")
 	defer span.AddEvent("end_notify")
-	c.mutex.RLock()
-	listeners := c.listeners[event.TxID]
-	if len(listeners) == 0 {
-		c.mutex.RUnlock()
-		return
+
+	// the lock is held while enqueueing, so that the listeners receive the events in the order of the Notify calls
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	qe := queuedEvent{event: event, enqueuedAt: time.Now()}
+	for _, l := range c.listeners[event.TxID] {
+		select {
+		case l.queue <- qe:
+		default:
+			l.dropped.Add(1)
+			c.dropped.Add(1)
+			logger.Warnf("status listener for [%s] is lagging, event [%d] dropped", event.TxID, event.ValidationCode)
+		}
 	}
-	// clone listeners and release lock
-	clone := make([]chan StatusEvent, len(listeners))
-	copy(clone, listeners)
-	c.mutex.RUnlock()
+}
 
-	for _, listener := range clone {
-		listener <- event
+// ListenerStats returns the health of the registered listeners
+func (c *StatusSupport) ListenerStats() []ListenerStats {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	var stats []ListenerStats
+	for _, ls := range c.listeners {
+		for _, l := range ls {
+			stats = append(stats, l.stats())
+		}
 	}
+	return stats
+}
+
+// DroppedEvents returns the number of events dropped since the creation, over all the listeners
+func (c *StatusSupport) DroppedEvents() uint64 {
+	return c.dropped.Load()
+}
+
+// ListenerQueueSize returns the number of events a listener can queue
+func (c *StatusSupport) ListenerQueueSize() int {
+	return c.queueSize
 }
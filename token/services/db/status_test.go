@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusSupportSlowListener(t *testing.T) {
+	s := NewStatusSupportWithQueueSize(2)
+	slow := make(chan StatusEvent)
+	fast := make(chan StatusEvent, 10)
+	s.AddStatusListener("tx1", slow)
+	s.AddStatusListener("tx1", fast)
+	defer s.DeleteStatusListener("tx1", slow)
+	defer s.DeleteStatusListener("tx1", fast)
+
+	// the slow listener does not block the notifications, nor the fast listener
+	for i := 0; i < 5; i++ {
+		s.Notify(StatusEvent{Ctx: context.Background(), TxID: "tx1", ValidationCode: driver.TxStatus(i)})
+		select {
+		case event := <-fast:
+			assert.Equal(t, driver.TxStatus(i), event.ValidationCode)
+		case <-time.After(5 * time.Second):
+			t.Fatal("fast listener did not receive the events")
+		}
+	}
+
+	// the slow listener receives the first events in order, at most one held by the delivery plus the queue
+	first := <-slow
+	assert.Equal(t, driver.TxStatus(0), first.ValidationCode)
+	assert.Eventually(t, func() bool { return s.DroppedEvents() > 0 }, 5*time.Second, 10*time.Millisecond)
+
+	var slowStats ListenerStats
+	for _, stats := range s.ListenerStats() {
+		if stats.Dropped > 0 {
+			slowStats = stats
+		}
+	}
+	assert.Equal(t, "tx1", slowStats.TxID)
+	assert.True(t, slowStats.Slow(s.ListenerQueueSize()))
+	assert.Equal(t, s.DroppedEvents(), slowStats.Dropped)
+	assert.Len(t, s.ListenerStats(), 2)
+
+	// events of other transactions are not delivered
+	s.Notify(StatusEvent{Ctx: context.Background(), TxID: "tx2"})
+	assert.Len(t, fast, 0)
+}
+
+func TestStatusSupportDeleteListener(t *testing.T) {
+	s := NewStatusSupport()
+	ch := make(chan StatusEvent)
+	s.AddStatusListener("tx1", ch)
+	s.Notify(StatusEvent{Ctx: context.Background(), TxID: "tx1"})
+	s.DeleteStatusListener("tx1", ch)
+	assert.Empty(t, s.ListenerStats())
+	// notifying with no listeners is a no-op
+	s.Notify(StatusEvent{Ctx: context.Background(), TxID: "tx1"})
+}
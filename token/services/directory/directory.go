@@ -0,0 +1,216 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package directory
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/pkg/errors"
+)
+
+const recordPrefix = "directory.record"
+
+var (
+	// ErrNotFound is returned when no record is published under a business identifier
+	ErrNotFound = errors.New("record not found")
+	// ErrStale is returned when a record is not newer than the one already published
+	ErrStale = errors.New("record is stale")
+	// ErrNotOwner is returned when a record is published under a business identifier owned by another party
+	ErrNotOwner = errors.New("business identifier owned by another party")
+)
+
+// KVS models the key-value store the records are persisted in
+type KVS interface {
+	Exists(id string) bool
+	Put(id string, state interface{}) error
+	Get(id string, state interface{}) error
+}
+
+// VerifierProvider returns the verifier of the signatures of an identity
+type VerifierProvider interface {
+	GetVerifier(identity view.Identity) (view2.Verifier, error)
+}
+
+// Record tells payers how to reach a party known by a business identifier
+type Record struct {
+	TMSID token.TMSID
+	// BusinessID is the identifier payers know the party by, for instance an account number
+	BusinessID string
+	// EnrollmentID is the enrollment ID of the wallet the party receives tokens in
+	EnrollmentID string
+	// Recipient is the identity payers pass to ttx.RequestRecipientIdentity to get a recipient identity of the party
+	Recipient view.Identity
+	// TokenTypes lists the token types the party accepts, empty means any
+	TokenTypes []string
+	// AuditInfo is the audit info of the long-term identity of the wallet
+	AuditInfo []byte
+	// Signer is the identity of the FSC node of the party, it signs the record
+	Signer view.Identity
+	// PublishedAt orders the records of the same business identifier, the newest wins
+	PublishedAt time.Time
+}
+
+// Accepts returns true if the party accepts tokens of the passed type
+func (r *Record) Accepts(tokenType string) bool {
+	return len(r.TokenTypes) == 0 || slices.Contains(r.TokenTypes, tokenType)
+}
+
+// SignedRecord carries a record together with the signature of its signer
+type SignedRecord struct {
+	Record    []byte
+	Signature []byte
+}
+
+// NewRecord returns a record for the passed wallet.
+// The enrollment ID and the audit info are taken from the long-term identity of the wallet.
+func NewRecord(sp token.ServiceProvider, tmsID token.TMSID, businessID string, wallet string, recipient view.Identity, tokenTypes ...string) (*Record, error) {
+	w := token.GetManagementService(sp, token.WithTMSID(tmsID)).WalletManager().OwnerWallet(wallet)
+	if w == nil {
+		return nil, errors.Errorf("wallet [%s:%s] not found", wallet, tmsID)
+	}
+	id, err := w.GetRecipientIdentity()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get the recipient identity of wallet [%s]", wallet)
+	}
+	auditInfo, err := w.GetAuditInfo(id)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get the audit info of wallet [%s]", wallet)
+	}
+	return &Record{
+		TMSID:        tmsID,
+		BusinessID:   businessID,
+		EnrollmentID: w.EnrollmentID(),
+		Recipient:    recipient,
+		TokenTypes:   tokenTypes,
+		AuditInfo:    auditInfo,
+	}, nil
+}
+
+// Sign signs the passed record with the default identity of the node, which becomes the signer of the record
+func Sign(sp token.ServiceProvider, record *Record) (*SignedRecord, error) {
+	me := view2.GetIdentityProvider(sp).DefaultIdentity()
+	signer, err := view2.GetSigService(sp).GetSigner(me)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get signer for default identity")
+	}
+	record.Signer = me
+	if record.PublishedAt.IsZero() {
+		record.PublishedAt = time.Now().UTC()
+	}
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal record of [%s]", record.BusinessID)
+	}
+	sigma, err := signer.Sign(raw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to sign record of [%s]", record.BusinessID)
+	}
+	return &SignedRecord{Record: raw, Signature: sigma}, nil
+}
+
+// Verify verifies the signature of the passed signed record, and returns its record
+func Verify(verifiers VerifierProvider, signed *SignedRecord) (*Record, error) {
+	if signed == nil {
+		return nil, errors.New("no record")
+	}
+	record := &Record{}
+	if err := json.Unmarshal(signed.Record, record); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal record")
+	}
+	if len(record.BusinessID) == 0 {
+		return nil, errors.New("record has no business identifier")
+	}
+	verifier, err := verifiers.GetVerifier(record.Signer)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get verifier for the signer of the record of [%s]", record.BusinessID)
+	}
+	if err := verifier.Verify(signed.Record, signed.Signature); err != nil {
+		return nil, errors.WithMessagef(err, "invalid signature on the record of [%s]", record.BusinessID)
+	}
+	return record, nil
+}
+
+// Directory persists the signed records, one for each business identifier of a TMS
+type Directory struct {
+	kvs       KVS
+	verifiers VerifierProvider
+	lock      sync.Mutex
+}
+
+// NewDirectory returns a new Directory storing in the passed KVS the records verified with the passed verifiers
+func NewDirectory(kvs KVS, verifiers VerifierProvider) *Directory {
+	return &Directory{kvs: kvs, verifiers: verifiers}
+}
+
+func (d *Directory) key(tmsID token.TMSID, businessID string) (string, error) {
+	k, err := kvs.CreateCompositeKey(recordPrefix, []string{tmsID.String(), businessID})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to generate key for [%s]", businessID)
+	}
+	return k, nil
+}
+
+// Publish stores the passed signed record, and returns its record.
+// The record must be newer than the published one, if any, and signed by the same signer.
+func (d *Directory) Publish(signed *SignedRecord) (*Record, error) {
+	record, err := Verify(d.verifiers, signed)
+	if err != nil {
+		return nil, err
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	k, err := d.key(record.TMSID, record.BusinessID)
+	if err != nil {
+		return nil, err
+	}
+	if d.kvs.Exists(k) {
+		current := &SignedRecord{}
+		if err := d.kvs.Get(k, current); err != nil {
+			return nil, errors.Wrapf(err, "failed to load the record of [%s]", record.BusinessID)
+		}
+		published := &Record{}
+		if err := json.Unmarshal(current.Record, published); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal the record of [%s]", record.BusinessID)
+		}
+		if !published.Signer.Equal(record.Signer) {
+			return nil, errors.Wrapf(ErrNotOwner, "[%s] is owned by [%s]", record.BusinessID, published.Signer)
+		}
+		if !record.PublishedAt.After(published.PublishedAt) {
+			return nil, errors.Wrapf(ErrStale, "[%s] has a record published at [%s]", record.BusinessID, published.PublishedAt)
+		}
+	}
+	if err := d.kvs.Put(k, signed); err != nil {
+		return nil, errors.Wrapf(err, "failed to store the record of [%s]", record.BusinessID)
+	}
+	return record, nil
+}
+
+// Resolve returns the signed record published under the passed business identifier
+func (d *Directory) Resolve(tmsID token.TMSID, businessID string) (*SignedRecord, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	k, err := d.key(tmsID, businessID)
+	if err != nil {
+		return nil, err
+	}
+	if !d.kvs.Exists(k) {
+		return nil, errors.Wrapf(ErrNotFound, "[%s:%s]", tmsID, businessID)
+	}
+	signed := &SignedRecord{}
+	if err := d.kvs.Get(k, signed); err != nil {
+		return nil, errors.Wrapf(err, "failed to load the record of [%s]", businessID)
+	}
+	return signed, nil
+}
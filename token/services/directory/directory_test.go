@@ -0,0 +1,133 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package directory
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type memKVS map[string][]byte
+
+func (m memKVS) Exists(id string) bool {
+	_, ok := m[id]
+	return ok
+}
+
+func (m memKVS) Put(id string, state interface{}) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	m[id] = raw
+	return nil
+}
+
+func (m memKVS) Get(id string, state interface{}) error {
+	raw, ok := m[id]
+	if !ok {
+		return errors.Errorf("[%s] not found", id)
+	}
+	return json.Unmarshal(raw, state)
+}
+
+// verifier accepts the signatures made of the signer followed by the message
+type verifier struct {
+	signer view.Identity
+}
+
+func (v *verifier) Verify(message, sigma []byte) error {
+	if !bytes.Equal(sigma, append(append([]byte{}, v.signer...), message...)) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+type verifiers struct{}
+
+func (verifiers) GetVerifier(identity view.Identity) (view2.Verifier, error) {
+	return &verifier{signer: identity}, nil
+}
+
+func sign(t *testing.T, record *Record) *SignedRecord {
+	raw, err := json.Marshal(record)
+	assert.NoError(t, err)
+	return &SignedRecord{Record: raw, Signature: append(append([]byte{}, record.Signer...), raw...)}
+}
+
+func TestDirectory(t *testing.T) {
+	tmsID := token.TMSID{Network: "n", Channel: "c", Namespace: "ns"}
+	now := time.Now().UTC()
+	record := &Record{
+		TMSID:        tmsID,
+		BusinessID:   "IT60X0542811101000000123456",
+		EnrollmentID: "alice",
+		Recipient:    view.Identity("alice"),
+		TokenTypes:   []string{"EUR"},
+		AuditInfo:    []byte("audit info"),
+		Signer:       view.Identity("alice.node"),
+		PublishedAt:  now,
+	}
+	d := NewDirectory(memKVS{}, verifiers{})
+
+	// nothing published yet
+	_, err := d.Resolve(tmsID, record.BusinessID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// publish and resolve
+	published, err := d.Publish(sign(t, record))
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", published.EnrollmentID)
+	signed, err := d.Resolve(tmsID, record.BusinessID)
+	assert.NoError(t, err)
+	resolved, err := Verify(verifiers{}, signed)
+	assert.NoError(t, err)
+	assert.Equal(t, record.Recipient, resolved.Recipient)
+	assert.True(t, resolved.Accepts("EUR"))
+	assert.False(t, resolved.Accepts("USD"))
+
+	// other TMSs do not see the record
+	_, err = d.Resolve(token.TMSID{Network: "n", Channel: "c", Namespace: "other"}, record.BusinessID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// a tampered record is rejected
+	tampered := sign(t, record)
+	tampered.Signature = []byte("forged")
+	_, err = d.Publish(tampered)
+	assert.Error(t, err)
+
+	// a record not newer than the published one is rejected
+	_, err = d.Publish(sign(t, record))
+	assert.ErrorIs(t, err, ErrStale)
+
+	// another party cannot take over the business identifier
+	other := *record
+	other.Signer = view.Identity("mallory.node")
+	other.PublishedAt = now.Add(time.Minute)
+	_, err = d.Publish(sign(t, &other))
+	assert.ErrorIs(t, err, ErrNotOwner)
+
+	// the party updates its record
+	updated := *record
+	updated.TokenTypes = nil
+	updated.PublishedAt = now.Add(time.Minute)
+	_, err = d.Publish(sign(t, &updated))
+	assert.NoError(t, err)
+	signed, err = d.Resolve(tmsID, record.BusinessID)
+	assert.NoError(t, err)
+	resolved, err = Verify(verifiers{}, signed)
+	assert.NoError(t, err)
+	assert.True(t, resolved.Accepts("USD"))
+}
@@ -0,0 +1,269 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package directory
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	"github.com/pkg/errors"
+)
+
+var logger = logging.MustGetLogger("token-sdk.directory")
+
+const messageTimeout = time.Minute
+
+// ErrUnauthorized is returned when a record is published by a node other than its signer
+var ErrUnauthorized = errors.New("unauthorized")
+
+// PublishRequest asks a directory node to publish a signed record
+type PublishRequest struct {
+	Record *SignedRecord
+}
+
+// ResolveRequest asks a directory node for the record published under a business identifier
+type ResolveRequest struct {
+	TMSID      token.TMSID
+	BusinessID string
+}
+
+// RecordResponse carries the published or the resolved record
+type RecordResponse struct {
+	Record *SignedRecord
+}
+
+// ResponderRegistry registers responder views
+type ResponderRegistry interface {
+	RegisterResponder(responder view.View, initiatedBy interface{}) error
+}
+
+// InstallDirectoryViews registers, on the directory node, the views publishing and resolving the records
+func InstallDirectoryViews(viewRegistry ResponderRegistry) error {
+	s := &server{}
+	if err := viewRegistry.RegisterResponder(&ServePublishView{server: s}, &PublishView{}); err != nil {
+		return err
+	}
+	return viewRegistry.RegisterResponder(&ServeResolveView{server: s}, &ResolveView{})
+}
+
+func getKVS(context view.Context) (KVS, error) {
+	kvss, err := context.GetService(&kvs.KVS{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get KVS from context")
+	}
+	return kvss.(*kvs.KVS), nil
+}
+
+func send(context view.Context, session view.Session, msg interface{}) error {
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed marshalling message")
+	}
+	return session.SendWithContext(context.Context(), raw)
+}
+
+func receive(session view.Session, msg interface{}) error {
+	raw, err := ttx.ReadMessage(session, messageTimeout)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, msg)
+}
+
+func sendError(context view.Context, err error) error {
+	if sendErr := context.Session().SendError([]byte(err.Error())); sendErr != nil {
+		logger.Warnf("failed to notify [%s] of [%s]: [%s]", context.Session().Info().Caller, err, sendErr)
+	}
+	return err
+}
+
+// server holds the directory shared by the responder views
+type server struct {
+	once      sync.Once
+	directory *Directory
+	err       error
+}
+
+func (s *server) get(context view.Context) (*Directory, error) {
+	s.once.Do(func() {
+		kvss, err := getKVS(context)
+		if err != nil {
+			s.err = err
+			return
+		}
+		s.directory = NewDirectory(kvss, view2.GetSigService(context))
+	})
+	return s.directory, s.err
+}
+
+// PublishView publishes a signed record on a directory node
+type PublishView struct {
+	directory view.Identity
+	record    *SignedRecord
+}
+
+// NewPublishView returns a new PublishView publishing the passed signed record on the passed directory node
+func NewPublishView(directory view.Identity, record *SignedRecord) *PublishView {
+	return &PublishView{directory: directory, record: record}
+}
+
+func (p *PublishView) Call(context view.Context) (interface{}, error) {
+	session, err := context.GetSession(p, p.directory)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting session with [%s]", p.directory)
+	}
+	defer session.Close()
+	if err := send(context, session, &PublishRequest{Record: p.record}); err != nil {
+		return nil, errors.WithMessagef(err, "failed sending record to [%s]", p.directory)
+	}
+	res := &RecordResponse{}
+	if err := receive(session, res); err != nil {
+		return nil, errors.WithMessagef(err, "failed publishing record on [%s]", p.directory)
+	}
+	return nil, nil
+}
+
+// ServePublishView is the directory side of PublishView.
+// The record is published only if the caller is its signer.
+type ServePublishView struct {
+	server *server
+}
+
+func (s *ServePublishView) Call(context view.Context) (interface{}, error) {
+	session := context.Session()
+	req := &PublishRequest{}
+	if err := receive(session, req); err != nil {
+		return nil, errors.WithMessage(err, "failed receiving publish request")
+	}
+	directory, err := s.server.get(context)
+	if err != nil {
+		return nil, sendError(context, err)
+	}
+	record, err := Verify(view2.GetSigService(context), req.Record)
+	if err != nil {
+		return nil, sendError(context, err)
+	}
+	caller := session.Info().Caller
+	if !record.Signer.Equal(caller) {
+		return nil, sendError(context, errors.Wrapf(ErrUnauthorized, "caller [%s] is not the signer of the record of [%s]", caller, record.BusinessID))
+	}
+	if _, err := directory.Publish(req.Record); err != nil {
+		return nil, sendError(context, err)
+	}
+	logger.Infof("published record of [%s] for [%s] signed by [%s]", record.BusinessID, record.EnrollmentID, record.Signer)
+	if err := send(context, session, &RecordResponse{Record: req.Record}); err != nil {
+		return nil, errors.WithMessagef(err, "failed acknowledging record of [%s]", record.BusinessID)
+	}
+	return nil, nil
+}
+
+// ResolveView resolves a business identifier on a directory node.
+// It returns the verified record.
+type ResolveView struct {
+	directory view.Identity
+	request   *ResolveRequest
+}
+
+// NewResolveView returns a new ResolveView asking the passed directory node for the record of the passed business identifier
+func NewResolveView(directory view.Identity, tmsID token.TMSID, businessID string) *ResolveView {
+	return &ResolveView{directory: directory, request: &ResolveRequest{TMSID: tmsID, BusinessID: businessID}}
+}
+
+func (r *ResolveView) Call(context view.Context) (interface{}, error) {
+	session, err := context.GetSession(r, r.directory)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting session with [%s]", r.directory)
+	}
+	defer session.Close()
+	if err := send(context, session, r.request); err != nil {
+		return nil, errors.WithMessagef(err, "failed sending resolve request to [%s]", r.directory)
+	}
+	res := &RecordResponse{}
+	if err := receive(session, res); err != nil {
+		return nil, errors.WithMessagef(err, "failed resolving [%s] on [%s]", r.request.BusinessID, r.directory)
+	}
+	// the directory is not trusted, the record must be signed by the party
+	record, err := Verify(view2.GetSigService(context), res.Record)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid record for [%s] from [%s]", r.request.BusinessID, r.directory)
+	}
+	if record.BusinessID != r.request.BusinessID || !record.TMSID.Equal(r.request.TMSID) {
+		return nil, errors.Errorf("[%s] returned the record of [%s:%s] for [%s:%s]", r.directory, record.TMSID, record.BusinessID, r.request.TMSID, r.request.BusinessID)
+	}
+	return record, nil
+}
+
+// ServeResolveView is the directory side of ResolveView
+type ServeResolveView struct {
+	server *server
+}
+
+func (s *ServeResolveView) Call(context view.Context) (interface{}, error) {
+	session := context.Session()
+	req := &ResolveRequest{}
+	if err := receive(session, req); err != nil {
+		return nil, errors.WithMessage(err, "failed receiving resolve request")
+	}
+	directory, err := s.server.get(context)
+	if err != nil {
+		return nil, sendError(context, err)
+	}
+	record, err := directory.Resolve(req.TMSID, req.BusinessID)
+	if err != nil {
+		return nil, sendError(context, err)
+	}
+	if err := send(context, session, &RecordResponse{Record: record}); err != nil {
+		return nil, errors.WithMessagef(err, "failed sending record of [%s]", req.BusinessID)
+	}
+	return nil, nil
+}
+
+// Resolve returns the record published on the passed directory node under the passed business identifier
+func Resolve(context view.Context, directory view.Identity, tmsID token.TMSID, businessID string) (*Record, error) {
+	boxed, err := context.RunView(NewResolveView(directory, tmsID, businessID))
+	if err != nil {
+		return nil, err
+	}
+	return boxed.(*Record), nil
+}
+
+// RequestRecipientIdentity resolves the passed business identifier on the passed directory node,
+// and requests a recipient identity to the party with ttx.RequestRecipientIdentity.
+// If a token type is passed, the party must accept it.
+// The enrollment ID of the returned identity must match the one of the record.
+func RequestRecipientIdentity(context view.Context, directory view.Identity, businessID string, tokenType string, opts ...token.ServiceOption) (view.Identity, *Record, error) {
+	options, err := ttx.CompileServiceOptions(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	record, err := Resolve(context, directory, options.TMSID(), businessID)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed resolving [%s]", businessID)
+	}
+	if len(tokenType) != 0 && !record.Accepts(tokenType) {
+		return nil, nil, errors.Errorf("[%s] does not accept tokens of type [%s]", businessID, tokenType)
+	}
+	id, err := ttx.RequestRecipientIdentity(context, record.Recipient, opts...)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed requesting recipient identity of [%s]", businessID)
+	}
+	eID, err := token.GetManagementService(context, opts...).WalletManager().GetEnrollmentID(id)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed getting enrollment ID of the recipient identity of [%s]", businessID)
+	}
+	if eID != record.EnrollmentID {
+		return nil, nil, errors.Errorf("recipient identity of [%s] has enrollment ID [%s], expected [%s]", businessID, eID, record.EnrollmentID)
+	}
+	return id, record, nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package erasure
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/pkg/errors"
+)
+
+var (
+	logger      = logging.MustGetLogger("token-sdk.erasure")
+	serviceType = reflect.TypeOf((*Service)(nil))
+)
+
+// PseudonymPrefix prefixes the pseudonyms replacing the erased enrollment IDs
+const PseudonymPrefix = "erased:"
+
+// TransactionDB is a database storing enrollment IDs in its transaction and movement records, like the ttxdb and the auditdb
+type TransactionDB interface {
+	EraseEnrollmentID(eID string, pseudonym string) (*driver.ErasedRecords, error)
+}
+
+// TokenDB is a database binding tokens to the wallets owning them
+type TokenDB interface {
+	EraseOwnership(walletID string) (*driver.ErasedOwnership, error)
+}
+
+// DBs are the databases of a TMS an erasure goes through. The databases that are not available are nil.
+type DBs struct {
+	TokenDB       TokenDB
+	TransactionDB TransactionDB
+	AuditDB       TransactionDB
+}
+
+// DBProvider returns the databases of a TMS
+type DBProvider = func(tmsID token.TMSID) (*DBs, error)
+
+// WalletProvider returns the ids of the owner wallets of a TMS bound to an enrollment ID
+type WalletProvider = func(tmsID token.TMSID, eID string) ([]string, error)
+
+// WalletReport tells what the erasure did to the tokens of an owner wallet
+type WalletReport struct {
+	WalletID string
+	// Removed is the number of spent tokens whose ownership by the wallet was removed
+	Removed int64
+	// Retained is the number of unspent tokens the wallet still owns, the ledger still refers to them as spendable
+	Retained int64
+}
+
+// Report is the outcome of the erasure of an enrollment ID in a TMS.
+// It does not contain the enrollment ID: the pseudonym cannot be linked back to it.
+type Report struct {
+	TMSID token.TMSID
+	Time  time.Time
+	// Pseudonym replaces the enrollment ID in the transaction and movement records
+	Pseudonym string
+	// TransactionDB counts the records of the ttxdb in which the enrollment ID was replaced
+	TransactionDB *driver.ErasedRecords
+	// AuditDB counts the records of the auditdb in which the enrollment ID was replaced, nil if there is no auditdb
+	AuditDB *driver.ErasedRecords
+	// Wallets lists the owner wallets bound to the enrollment ID
+	Wallets []WalletReport
+}
+
+// Retained returns the number of unspent tokens still bound to the owner wallets of the enrollment ID
+func (r *Report) Retained() int64 {
+	var total int64
+	for _, w := range r.Wallets {
+		total += w.Retained
+	}
+	return total
+}
+
+// Service erases the personal identifiers bound to an enrollment ID from the databases of the TMSs.
+// The enrollment ID is replaced by a random pseudonym in the ttxdb and in the auditdb,
+// and the ownership of the spent tokens by the wallets of the enrollment ID is removed from the token db.
+// The amounts, the token requests, and the tokens as stored on the ledger are kept for auditability.
+type Service struct {
+	dbs          DBProvider
+	wallets      WalletProvider
+	now          func() time.Time
+	newPseudonym func() (string, error)
+}
+
+// NewService returns a new Service over the databases of the passed managers
+func NewService(tokenDBs *tokendb.Manager, ttxDBs *ttxdb.Manager, auditDBs *auditdb.Manager, tmsProvider *token.ManagementServiceProvider) *Service {
+	return newService(func(tmsID token.TMSID) (*DBs, error) {
+		tokenDB, err := tokenDBs.DBByTMSId(tmsID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get token db for [%s]", tmsID)
+		}
+		ttxDB, err := ttxDBs.DBByTMSId(tmsID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get ttxdb for [%s]", tmsID)
+		}
+		auditDB, err := auditDBs.DBByTMSId(tmsID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get auditdb for [%s]", tmsID)
+		}
+		return &DBs{TokenDB: tokenDB, TransactionDB: ttxDB, AuditDB: auditDB}, nil
+	}, func(tmsID token.TMSID, eID string) ([]string, error) {
+		tms, err := tmsProvider.GetManagementService(token.WithTMSID(tmsID))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get tms [%s]", tmsID)
+		}
+		ids, err := tms.WalletManager().OwnerWalletIDs()
+		if err != nil {
+			return nil, err
+		}
+		var res []string
+		for _, id := range ids {
+			if w := tms.WalletManager().OwnerWallet(id); w != nil && w.EnrollmentID() == eID {
+				res = append(res, id)
+			}
+		}
+		return res, nil
+	})
+}
+
+func newService(dbs DBProvider, wallets WalletProvider) *Service {
+	return &Service{
+		dbs:     dbs,
+		wallets: wallets,
+		now:     time.Now,
+		newPseudonym: func() (string, error) {
+			id, err := uuid.GenerateUUID()
+			if err != nil {
+				return "", errors.Wrapf(err, "failed generating pseudonym")
+			}
+			return PseudonymPrefix + id, nil
+		},
+	}
+}
+
+// GetService returns the Service registered in the passed service provider
+func GetService(sp token.ServiceProvider) (*Service, error) {
+	s, err := sp.GetService(serviceType)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting erasure service")
+	}
+	return s.(*Service), nil
+}
+
+// Erase erases the passed enrollment ID from the databases of the passed TMS, and returns the erasure report.
+// Erasing the same enrollment ID again only erases what has been stored in the meantime, under a new pseudonym.
+// The unspent tokens of the enrollment ID are retained, the erasure can be run again once they are spent.
+func (s *Service) Erase(tmsID token.TMSID, eID string) (*Report, error) {
+	if len(eID) == 0 {
+		return nil, errors.New("the erasure requires an enrollment ID")
+	}
+	dbs, err := s.dbs(tmsID)
+	if err != nil {
+		return nil, err
+	}
+	walletIDs, err := s.wallets(tmsID, eID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get the wallets of the enrollment ID")
+	}
+	pseudonym, err := s.newPseudonym()
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{TMSID: tmsID, Time: s.now(), Pseudonym: pseudonym}
+
+	for _, walletID := range walletIDs {
+		erased, err := dbs.TokenDB.EraseOwnership(walletID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to erase the ownership of wallet [%s]", walletID)
+		}
+		report.Wallets = append(report.Wallets, WalletReport{WalletID: walletID, Removed: erased.Removed, Retained: erased.Retained})
+	}
+	if report.TransactionDB, err = dbs.TransactionDB.EraseEnrollmentID(eID, pseudonym); err != nil {
+		return nil, errors.WithMessagef(err, "failed to erase the enrollment ID from the ttxdb")
+	}
+	if dbs.AuditDB != nil {
+		if report.AuditDB, err = dbs.AuditDB.EraseEnrollmentID(eID, pseudonym); err != nil {
+			return nil, errors.WithMessagef(err, "failed to erase the enrollment ID from the auditdb")
+		}
+	}
+	logger.Infof("erased enrollment ID as [%s] in [%s], [%d] unspent tokens retained", pseudonym, tmsID, report.Retained())
+	return report, nil
+}
+
+// EraseView is a maintenance view erasing an enrollment ID from the databases of a TMS, it returns the erasure Report
+type EraseView struct {
+	TMSID        token.TMSID
+	EnrollmentID string
+}
+
+func NewEraseView(tmsID token.TMSID, eID string) *EraseView {
+	return &EraseView{TMSID: tmsID, EnrollmentID: eID}
+}
+
+func (e *EraseView) Call(context view.Context) (interface{}, error) {
+	s, err := GetService(context)
+	if err != nil {
+		return nil, err
+	}
+	return s.Erase(e.TMSID, e.EnrollmentID)
+}
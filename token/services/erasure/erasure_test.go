@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package erasure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type transactionDB struct {
+	erased map[string]string
+	res    *driver.ErasedRecords
+	err    error
+}
+
+func (db *transactionDB) EraseEnrollmentID(eID string, pseudonym string) (*driver.ErasedRecords, error) {
+	if db.err != nil {
+		return nil, db.err
+	}
+	db.erased[eID] = pseudonym
+	return db.res, nil
+}
+
+type tokenDB struct {
+	res map[string]*driver.ErasedOwnership
+}
+
+func (db *tokenDB) EraseOwnership(walletID string) (*driver.ErasedOwnership, error) {
+	res, ok := db.res[walletID]
+	if !ok {
+		return nil, errors.Errorf("unknown wallet [%s]", walletID)
+	}
+	return res, nil
+}
+
+func TestErase(t *testing.T) {
+	tmsID := token.TMSID{Network: "n", Channel: "c", Namespace: "ns"}
+	ttxDB := &transactionDB{erased: map[string]string{}, res: &driver.ErasedRecords{Transactions: 3, Movements: 2}}
+	auditDB := &transactionDB{erased: map[string]string{}, res: &driver.ErasedRecords{Transactions: 4, Movements: 2}}
+	tokenDB := &tokenDB{res: map[string]*driver.ErasedOwnership{
+		"alice":   {Removed: 5, Retained: 1},
+		"alice.2": {Removed: 2},
+	}}
+	dbs := &DBs{TokenDB: tokenDB, TransactionDB: ttxDB, AuditDB: auditDB}
+	s := newService(func(token.TMSID) (*DBs, error) { return dbs, nil }, func(_ token.TMSID, eID string) ([]string, error) {
+		if eID == "alice" {
+			return []string{"alice", "alice.2"}, nil
+		}
+		return nil, nil
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	_, err := s.Erase(tmsID, "")
+	assert.Error(t, err)
+
+	r, err := s.Erase(tmsID, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, tmsID, r.TMSID)
+	assert.Equal(t, now, r.Time)
+	assert.Contains(t, r.Pseudonym, PseudonymPrefix)
+	assert.Equal(t, &driver.ErasedRecords{Transactions: 3, Movements: 2}, r.TransactionDB)
+	assert.Equal(t, &driver.ErasedRecords{Transactions: 4, Movements: 2}, r.AuditDB)
+	assert.Equal(t, []WalletReport{{WalletID: "alice", Removed: 5, Retained: 1}, {WalletID: "alice.2", Removed: 2}}, r.Wallets)
+	assert.Equal(t, int64(1), r.Retained())
+	// the same pseudonym is used in all the databases
+	assert.Equal(t, map[string]string{"alice": r.Pseudonym}, ttxDB.erased)
+	assert.Equal(t, map[string]string{"alice": r.Pseudonym}, auditDB.erased)
+
+	// each erasure gets its own pseudonym
+	r2, err := s.Erase(tmsID, "alice")
+	assert.NoError(t, err)
+	assert.NotEqual(t, r.Pseudonym, r2.Pseudonym)
+
+	// an enrollment ID without wallets on this node is erased from the transaction records only
+	dbs.AuditDB = nil
+	r, err = s.Erase(tmsID, "bob")
+	assert.NoError(t, err)
+	assert.Empty(t, r.Wallets)
+	assert.Nil(t, r.AuditDB)
+	assert.Equal(t, r.Pseudonym, ttxDB.erased["bob"])
+
+	// errors are returned
+	ttxDB.err = errors.New("boom")
+	_, err = s.Erase(tmsID, "bob")
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package features
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/pkg/errors"
+)
+
+var (
+	logger      = logging.MustGetLogger("token-sdk.features")
+	serviceType = reflect.TypeOf((*Service)(nil))
+)
+
+// Flag names a behavior that can be turned on or off per TMS
+type Flag string
+
+const (
+	// Consolidation lets the SplitTransferView merge the tokens of the sender wallet when a payment needs more inputs than allowed
+	Consolidation Flag = "consolidation"
+	// Pruning lets the databases delete, when they are opened, the rows older than their configured ttl
+	Pruning Flag = "pruning"
+	// LazySelector makes the token selector query the token database at each selection, instead of using the configured fetcher strategy
+	LazySelector Flag = "selector.lazy"
+	// RequestCompression makes the node compress the token requests it sends to the other parties and to the endorsers.
+	// All the nodes and the chaincode of the network must be able to decompress them before it is turned on.
+	RequestCompression Flag = "request.compression"
+	// FundsWitness makes ttx.RecordFundsWitness store a signed snapshot of the funds of a wallet when its token selection fails
+	FundsWitness Flag = "selector.witness"
+	// PendingSelector makes the node store the tokens its pending transactions create for it, and the token selector spend them,
+	// so that transactions can be chained without waiting for finality
+	PendingSelector Flag = "selector.pending"
+)
+
+// defaults are the values of the known flags when neither the configuration nor an override sets them.
+// Unknown flags are off by default.
+var defaults = map[Flag]bool{
+	Consolidation:      true,
+	Pruning:            true,
+	LazySelector:       false,
+	RequestCompression: false,
+	FundsWitness:       false,
+	PendingSelector:    false,
+}
+
+// ConfigService returns the configuration of a TMS
+type ConfigService interface {
+	ConfigurationFor(network, channel, namespace string) (driver.Configuration, error)
+}
+
+// Service tells which flags are on for a TMS.
+// The value of a flag is, by priority, the runtime override, the configuration key `features.<flag>` of the TMS, and the default.
+type Service struct {
+	configs ConfigService
+
+	lock      sync.RWMutex
+	overrides map[token.TMSID]map[Flag]bool
+}
+
+// NewService returns a new Service that reads the flags from the passed configuration service
+func NewService(configs ConfigService) *Service {
+	return &Service{
+		configs:   configs,
+		overrides: map[token.TMSID]map[Flag]bool{},
+	}
+}
+
+// GetService returns the Service registered in the passed service provider
+func GetService(sp token.ServiceProvider) (*Service, error) {
+	s, err := sp.GetService(serviceType)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting feature flag service")
+	}
+	return s.(*Service), nil
+}
+
+// Enabled returns true if the passed flag is on for the passed TMS
+func (s *Service) Enabled(tmsID token.TMSID, flag Flag) bool {
+	s.lock.RLock()
+	enabled, ok := s.overrides[tmsID][flag]
+	s.lock.RUnlock()
+	if ok {
+		return enabled
+	}
+	c, err := s.configs.ConfigurationFor(tmsID.Network, tmsID.Channel, tmsID.Namespace)
+	if err != nil {
+		logger.Warnf("failed getting configuration for [%s], using the default of [%s]: [%s]", tmsID, flag, err)
+		return defaults[flag]
+	}
+	return EnabledIn(c, flag)
+}
+
+// Override sets the passed flag for the passed TMS until the node restarts, or the override is cleared
+func (s *Service) Override(tmsID token.TMSID, flag Flag, enabled bool) {
+	logger.Infof("override feature [%s] for [%s]: [%v]", flag, tmsID, enabled)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.overrides[tmsID]; !ok {
+		s.overrides[tmsID] = map[Flag]bool{}
+	}
+	s.overrides[tmsID][flag] = enabled
+}
+
+// ClearOverride makes the passed flag for the passed TMS take again its configured value
+func (s *Service) ClearOverride(tmsID token.TMSID, flag Flag) {
+	logger.Infof("clear override of feature [%s] for [%s]", flag, tmsID)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.overrides[tmsID], flag)
+}
+
+// Flags returns the known flags, and the overridden ones, with their value for the passed TMS
+func (s *Service) Flags(tmsID token.TMSID) map[Flag]bool {
+	flags := map[Flag]bool{}
+	for flag := range defaults {
+		flags[flag] = false
+	}
+	s.lock.RLock()
+	for flag := range s.overrides[tmsID] {
+		flags[flag] = false
+	}
+	s.lock.RUnlock()
+	for flag := range flags {
+		flags[flag] = s.Enabled(tmsID, flag)
+	}
+	return flags
+}
+
+// EnabledIn returns true if the passed flag is on in the passed TMS configuration, ignoring the runtime overrides.
+// It is meant for the services that read their configuration before the Service is available.
+func EnabledIn(c driver.Configuration, flag Flag) bool {
+	key := "features." + string(flag)
+	if !c.IsSet(key) {
+		return defaults[flag]
+	}
+	return c.GetBool(key)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package features
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/core/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	config2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService(t *testing.T) {
+	cp, err := config.NewProvider("./testdata")
+	assert.NoError(t, err)
+	s := NewService(config2.NewService(cp))
+
+	configured := token.TMSID{Network: "n1", Channel: "c1", Namespace: "ns1"}
+	unconfigured := token.TMSID{Network: "n2", Channel: "c2", Namespace: "ns2"}
+	unknown := token.TMSID{Network: "n3", Channel: "c3", Namespace: "ns3"}
+
+	// configuration, then defaults
+	assert.False(t, s.Enabled(configured, Consolidation))
+	assert.True(t, s.Enabled(configured, LazySelector))
+	assert.True(t, s.Enabled(configured, Pruning))
+	assert.True(t, s.Enabled(unconfigured, Consolidation))
+	assert.False(t, s.Enabled(unconfigured, LazySelector))
+	assert.True(t, s.Enabled(unknown, Consolidation))
+	assert.False(t, s.Enabled(unknown, "custom"))
+
+	// overrides apply to one tms only, until cleared
+	s.Override(configured, Consolidation, true)
+	s.Override(configured, "custom", true)
+	assert.True(t, s.Enabled(configured, Consolidation))
+	assert.True(t, s.Enabled(unconfigured, Consolidation))
+	assert.Equal(t, map[Flag]bool{
+		Consolidation:      true,
+		Pruning:            true,
+		LazySelector:       true,
+		RequestCompression: false,
+		FundsWitness:       false,
+		PendingSelector:    false,
+		"custom":           true,
+	}, s.Flags(configured))
+	assert.Equal(t, map[Flag]bool{
+		Consolidation:      true,
+		Pruning:            true,
+		LazySelector:       false,
+		RequestCompression: false,
+		FundsWitness:       false,
+		PendingSelector:    false,
+	}, s.Flags(unconfigured))
+
+	s.ClearOverride(configured, Consolidation)
+	assert.False(t, s.Enabled(configured, Consolidation))
+	s.ClearOverride(unconfigured, Consolidation)
+	assert.True(t, s.Enabled(unconfigured, Consolidation))
+}
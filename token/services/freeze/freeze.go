@@ -0,0 +1,160 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package freeze
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+var (
+	logger      = logging.MustGetLogger("token-sdk.freeze")
+	serviceType = reflect.TypeOf((*Service)(nil))
+
+	// ErrFrozen is returned when a token type is frozen on this node
+	ErrFrozen = errors.New("token type frozen")
+)
+
+// Freeze describes a frozen token type
+type Freeze struct {
+	// TokenType is the frozen token type
+	TokenType string
+	// Reason is the reason given by the operator
+	Reason string
+	// Since is the time the token type was frozen
+	Since time.Time
+}
+
+// Service keeps the token types whose transfers are disabled on this node, for all the TMSs.
+// Operators freeze a token type during an incident with that asset, for instance, with its public parameters or its policies.
+// While frozen, the token type cannot be selected, transferred, redeemed, or accepted, but it can still be queried.
+// The freezes last until the node restarts, or the token type is unfrozen.
+type Service struct {
+	lock   sync.RWMutex
+	frozen map[string]Freeze
+}
+
+// NewService returns a new Service with no frozen token type
+func NewService() *Service {
+	return &Service{frozen: map[string]Freeze{}}
+}
+
+// GetService returns the Service registered in the passed service provider
+func GetService(sp token.ServiceProvider) (*Service, error) {
+	s, err := sp.GetService(serviceType)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting freeze service")
+	}
+	return s.(*Service), nil
+}
+
+// Freeze disables the transfers of the passed token type, for the passed reason
+func (s *Service) Freeze(tokenType string, reason string) {
+	logger.Warnf("freeze token type [%s]: [%s]", tokenType, reason)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.frozen[tokenType] = Freeze{TokenType: tokenType, Reason: reason, Since: time.Now()}
+}
+
+// Unfreeze enables again the transfers of the passed token type
+func (s *Service) Unfreeze(tokenType string) {
+	logger.Infof("unfreeze token type [%s]", tokenType)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.frozen, tokenType)
+}
+
+// Frozen returns the frozen token types, sorted by token type
+func (s *Service) Frozen() []Freeze {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	freezes := make([]Freeze, 0, len(s.frozen))
+	for _, f := range s.frozen {
+		freezes = append(freezes, f)
+	}
+	sort.Slice(freezes, func(i, j int) bool { return freezes[i].TokenType < freezes[j].TokenType })
+	return freezes
+}
+
+// Check returns an error matching ErrFrozen, if any of the passed token types is frozen
+func (s *Service) Check(tokenTypes ...string) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, tokenType := range tokenTypes {
+		if f, ok := s.frozen[tokenType]; ok {
+			return errors.Wrapf(ErrFrozen, "[%s] since [%s]: %s", tokenType, f.Since.Format(time.RFC3339), f.Reason)
+		}
+	}
+	return nil
+}
+
+// SelectorManagerProvider returns selector managers whose selectors refuse the frozen token types
+type SelectorManagerProvider struct {
+	token.SelectorManagerProvider
+	service *Service
+}
+
+// NewSelectorManagerProvider wraps the passed provider so that its selectors refuse the token types frozen in the passed service
+func NewSelectorManagerProvider(provider token.SelectorManagerProvider, service *Service) *SelectorManagerProvider {
+	return &SelectorManagerProvider{SelectorManagerProvider: provider, service: service}
+}
+
+func (p *SelectorManagerProvider) SelectorManager(tms *token.ManagementService) (token.SelectorManager, error) {
+	sm, err := p.SelectorManagerProvider.SelectorManager(tms)
+	if err != nil {
+		return nil, err
+	}
+	return &selectorManager{SelectorManager: sm, service: p.service}, nil
+}
+
+type selectorManager struct {
+	token.SelectorManager
+	service *Service
+}
+
+func (m *selectorManager) NewSelector(id string) (token.Selector, error) {
+	s, err := m.SelectorManager.NewSelector(id)
+	if err != nil {
+		return nil, err
+	}
+	return &selector{Selector: s, service: m.service}, nil
+}
+
+type selector struct {
+	token.Selector
+	service *Service
+}
+
+func (s *selector) Select(ownerFilter token.OwnerFilter, q, tokenType string) ([]*token2.ID, token2.Quantity, error) {
+	if err := s.service.Check(tokenType); err != nil {
+		return nil, nil, err
+	}
+	return s.Selector.Select(ownerFilter, q, tokenType)
+}
+
+func (s *selector) Preview(ownerFilter token.OwnerFilter, q, tokenType string) (*token.SelectionPreview, error) {
+	if err := s.service.Check(tokenType); err != nil {
+		return nil, err
+	}
+	return s.Selector.Preview(ownerFilter, q, tokenType)
+}
+
+func (s *selector) SelectMany(ownerFilter token.OwnerFilter, quantities map[string]string) (map[string]*token.SelectedTokens, error) {
+	for tokenType := range quantities {
+		if err := s.service.Check(tokenType); err != nil {
+			return nil, err
+		}
+	}
+	return s.Selector.SelectMany(ownerFilter, quantities)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package freeze
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSelector struct {
+	selected int
+}
+
+func (f *fakeSelector) Select(token.OwnerFilter, string, string) ([]*token2.ID, token2.Quantity, error) {
+	f.selected++
+	return []*token2.ID{{TxId: "tx1"}}, nil, nil
+}
+
+func (f *fakeSelector) Preview(token.OwnerFilter, string, string) (*token.SelectionPreview, error) {
+	f.selected++
+	return &token.SelectionPreview{}, nil
+}
+
+func (f *fakeSelector) SelectMany(token.OwnerFilter, map[string]string) (map[string]*token.SelectedTokens, error) {
+	f.selected++
+	return map[string]*token.SelectedTokens{}, nil
+}
+
+func (f *fakeSelector) Close() error { return nil }
+
+func TestService(t *testing.T) {
+	s := NewService()
+	assert.NoError(t, s.Check("USD", "EUR"))
+	assert.Empty(t, s.Frozen())
+
+	s.Freeze("USD", "incident 42")
+	s.Freeze("CHF", "audit")
+	err := s.Check("EUR", "USD")
+	assert.True(t, errors.Is(err, ErrFrozen))
+	assert.Contains(t, err.Error(), "[USD]")
+	assert.Contains(t, err.Error(), "incident 42")
+	assert.NoError(t, s.Check("EUR"))
+	frozen := s.Frozen()
+	assert.Len(t, frozen, 2)
+	assert.Equal(t, "CHF", frozen[0].TokenType)
+	assert.Equal(t, "USD", frozen[1].TokenType)
+	assert.Equal(t, "incident 42", frozen[1].Reason)
+
+	s.Unfreeze("USD")
+	assert.NoError(t, s.Check("USD"))
+	assert.Len(t, s.Frozen(), 1)
+}
+
+func TestSelector(t *testing.T) {
+	s := NewService()
+	fake := &fakeSelector{}
+	sel := &selector{Selector: fake, service: s}
+	s.Freeze("USD", "incident 42")
+
+	_, _, err := sel.Select(nil, "10", "USD")
+	assert.True(t, errors.Is(err, ErrFrozen))
+	_, err = sel.Preview(nil, "10", "USD")
+	assert.True(t, errors.Is(err, ErrFrozen))
+	_, err = sel.SelectMany(nil, map[string]string{"EUR": "10", "USD": "10"})
+	assert.True(t, errors.Is(err, ErrFrozen))
+	assert.Equal(t, 0, fake.selected)
+
+	// the other token types are selected as usual
+	ids, _, err := sel.Select(nil, "10", "EUR")
+	assert.NoError(t, err)
+	assert.Len(t, ids, 1)
+	_, err = sel.SelectMany(nil, map[string]string{"EUR": "10"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.selected)
+
+	s.Unfreeze("USD")
+	_, _, err = sel.Select(nil, "10", "USD")
+	assert.NoError(t, err)
+}
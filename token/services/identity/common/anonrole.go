@@ -23,6 +23,7 @@ type localMembership interface {
 	GetIdentifier(id driver.Identity) (string, error)
 	GetDefaultIdentifier() string
 	RegisterIdentity(config driver.IdentityConfiguration) error
+	IdentityConfiguration(id string) (driver.IdentityConfiguration, error)
 	IDs() ([]string, error)
 }
 
@@ -81,6 +82,11 @@ func (r *AnonymousRole) RegisterIdentity(config driver.IdentityConfiguration) er
 	return r.localMembership.RegisterIdentity(config)
 }
 
+// IdentityConfiguration returns the configuration of the identity with the passed identifier
+func (r *AnonymousRole) IdentityConfiguration(id string) (driver.IdentityConfiguration, error) {
+	return r.localMembership.IdentityConfiguration(id)
+}
+
 func (r *AnonymousRole) IdentityIDs() ([]string, error) {
 	return r.localMembership.IDs()
 }
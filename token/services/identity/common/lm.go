@@ -138,6 +138,30 @@ func (l *LocalMembership) RegisterIdentity(idConfig driver.IdentityConfiguration
 	return l.registerIdentityConfiguration(&idConfig, l.getDefaultIdentifier() == "")
 }
 
+// IdentityConfiguration returns the stored configuration of the identity with the passed identifier
+func (l *LocalMembership) IdentityConfiguration(id string) (driver.IdentityConfiguration, error) {
+	it, err := l.identityDB.IteratorConfigurations(l.IdentityType)
+	if err != nil {
+		return driver.IdentityConfiguration{}, errors.WithMessage(err, "failed to get registered identities from kvs")
+	}
+	defer it.Close()
+	for it.HasNext() {
+		item, err := it.Next()
+		if err != nil {
+			return driver.IdentityConfiguration{}, err
+		}
+		if item.ID == id {
+			return driver.IdentityConfiguration{
+				ID:     item.ID,
+				URL:    item.URL,
+				Config: item.Config,
+				Raw:    item.Raw,
+			}, nil
+		}
+	}
+	return driver.IdentityConfiguration{}, errors.Errorf("identity configuration not found for [%s]", id)
+}
+
 func (l *LocalMembership) IDs() ([]string, error) {
 	l.localIdentitiesMutex.RLock()
 	defer l.localIdentitiesMutex.RUnlock()
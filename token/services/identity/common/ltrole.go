@@ -180,6 +180,11 @@ func (r *LongTermRole) RegisterIdentity(config driver.IdentityConfiguration) err
 	return r.localMembership.RegisterIdentity(config)
 }
 
+// IdentityConfiguration returns the configuration of the identity with the passed identifier
+func (r *LongTermRole) IdentityConfiguration(id string) (driver.IdentityConfiguration, error) {
+	return r.localMembership.IdentityConfiguration(id)
+}
+
 func (r *LongTermRole) IdentityIDs() ([]string, error) {
 	return r.localMembership.IDs()
 }
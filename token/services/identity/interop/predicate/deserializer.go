@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package predicate
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/predicate"
+	"github.com/pkg/errors"
+)
+
+type VerifierDES interface {
+	DeserializeVerifier(id driver.Identity) (driver.Verifier, error)
+}
+
+// AttributesDES returns the certified attributes of an identity, once verified
+type AttributesDES interface {
+	DeserializeAttributes(id driver.Identity) (map[string]string, error)
+}
+
+// TypedIdentityDeserializer deserializes the verifiers of the identities carrying a predicate script.
+// The verifier of a script is the verifier of its owner, returned only if the owner carries the attributes required by the predicate.
+type TypedIdentityDeserializer struct {
+	VerifierDeserializer VerifierDES
+	// OwnerType is the type of the owners whose attributes AttributesDeserializer deserializes
+	OwnerType              identity.Type
+	AttributesDeserializer AttributesDES
+}
+
+func NewTypedIdentityDeserializer(verifierDeserializer VerifierDES, ownerType identity.Type, attributesDeserializer AttributesDES) *TypedIdentityDeserializer {
+	return &TypedIdentityDeserializer{
+		VerifierDeserializer:   verifierDeserializer,
+		OwnerType:              ownerType,
+		AttributesDeserializer: attributesDeserializer,
+	}
+}
+
+func (t *TypedIdentityDeserializer) DeserializeVerifier(typ string, raw []byte) (driver.Verifier, error) {
+	if typ != predicate.ScriptType {
+		return nil, errors.Errorf("cannot deserializer type [%s], expected [%s]", typ, predicate.ScriptType)
+	}
+	script := &predicate.Script{}
+	if err := json.Unmarshal(raw, script); err != nil {
+		return nil, errors.Errorf("failed to unmarshal TypedIdentity as a predicate script")
+	}
+	if err := script.Validate(); err != nil {
+		return nil, errors.WithMessagef(err, "invalid predicate script")
+	}
+	owner, err := identity.UnmarshalTypedIdentity(script.Owner)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal the owner in the predicate script")
+	}
+	if owner.Type != t.OwnerType {
+		return nil, errors.Errorf("owner of type [%s] cannot satisfy a predicate, expected [%s]", owner.Type, t.OwnerType)
+	}
+	attributes, err := t.AttributesDeserializer.DeserializeAttributes(owner.Identity)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to verify the attributes of the owner in the predicate script")
+	}
+	if err := script.Predicate.Match(attributes); err != nil {
+		return nil, errors.WithMessagef(err, "owner does not satisfy predicate [%s]", &script.Predicate)
+	}
+	v, err := t.VerifierDeserializer.DeserializeVerifier(script.Owner)
+	if err != nil {
+		return nil, errors.Errorf("failed to unmarshal the identity of the owner in the predicate script")
+	}
+	return v, nil
+}
+
+func (t *TypedIdentityDeserializer) Recipients(id driver.Identity, typ string, raw []byte) ([]driver.Identity, error) {
+	if typ != predicate.ScriptType {
+		return nil, errors.New("unknown identity type")
+	}
+	script := &predicate.Script{}
+	if err := json.Unmarshal(raw, script); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal predicate script")
+	}
+	return []driver.Identity{script.Owner}, nil
+}
+
+// GetOwnerAuditInfo returns the audit info of the owner in the script.
+// The auditors match it against the owner, as done for the tokens owned directly by an identity.
+func (t *TypedIdentityDeserializer) GetOwnerAuditInfo(id driver.Identity, typ string, raw []byte, p driver.AuditInfoProvider) ([][]byte, error) {
+	if typ != predicate.ScriptType {
+		return nil, errors.Errorf("invalid type, got [%s], expected [%s]", typ, predicate.ScriptType)
+	}
+	script := &predicate.Script{}
+	if err := json.Unmarshal(raw, script); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal predicate script")
+	}
+	auditInfo, err := p.GetAuditInfo(script.Owner)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting audit info for predicate script [%s]", id.String())
+	}
+	return [][]byte{auditInfo}, nil
+}
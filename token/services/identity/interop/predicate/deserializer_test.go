@@ -0,0 +1,100 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package predicate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/predicate"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type verifier struct {
+	owner string
+}
+
+func (v *verifier) Verify(message, sigma []byte) error {
+	return nil
+}
+
+type verifierDES struct{}
+
+func (d *verifierDES) DeserializeVerifier(id driver.Identity) (driver.Verifier, error) {
+	owner, err := identity.UnmarshalTypedIdentity(id)
+	if err != nil {
+		return nil, err
+	}
+	return &verifier{owner: string(owner.Identity)}, nil
+}
+
+// attributesDES returns the attributes of the known owners, as if their proofs were verified
+type attributesDES map[string]map[string]string
+
+func (d attributesDES) DeserializeAttributes(id driver.Identity) (map[string]string, error) {
+	attributes, ok := d[string(id)]
+	if !ok {
+		return nil, errors.Errorf("invalid proof for [%s]", string(id))
+	}
+	return attributes, nil
+}
+
+func script(t *testing.T, ownerType identity.Type, owner string, p predicate.Predicate) []byte {
+	id, err := identity.WrapWithType(ownerType, []byte(owner))
+	assert.NoError(t, err)
+	raw, err := json.Marshal(&predicate.Script{Owner: id, Predicate: p})
+	assert.NoError(t, err)
+	return raw
+}
+
+func TestDeserializeVerifier(t *testing.T) {
+	d := NewTypedIdentityDeserializer(&verifierDES{}, "idemix", attributesDES{
+		"alice": {"ou": "kyc-2", "role": "MEMBER"},
+		"bob":   {"ou": "kyc-1", "role": "MEMBER"},
+	})
+	p := predicate.Predicate{Attributes: map[string][]string{"ou": {"kyc-2", "kyc-3"}}}
+
+	// the verifier of the script is the one of its owner
+	v, err := d.DeserializeVerifier(predicate.ScriptType, script(t, "idemix", "alice", p))
+	assert.NoError(t, err)
+	assert.Equal(t, &verifier{owner: "alice"}, v)
+
+	// the owner must satisfy the predicate with its verified attributes
+	_, err = d.DeserializeVerifier(predicate.ScriptType, script(t, "idemix", "bob", p))
+	assert.EqualError(t, err, "owner does not satisfy predicate [ou in [kyc-2,kyc-3]]: attribute [ou] has value [kyc-1], expected one of [kyc-2,kyc-3]")
+	_, err = d.DeserializeVerifier(predicate.ScriptType, script(t, "idemix", "charlie", p))
+	assert.EqualError(t, err, "failed to verify the attributes of the owner in the predicate script: invalid proof for [charlie]")
+
+	// the owner must be of the type whose attributes are certified
+	_, err = d.DeserializeVerifier(predicate.ScriptType, script(t, "x509", "alice", p))
+	assert.EqualError(t, err, "owner of type [x509] cannot satisfy a predicate, expected [idemix]")
+
+	// the script must be valid
+	_, err = d.DeserializeVerifier(predicate.ScriptType, script(t, "idemix", "alice", predicate.Predicate{Attributes: map[string][]string{"kyc": {"level-2"}}}))
+	assert.EqualError(t, err, "invalid predicate script: invalid predicate: attribute [kyc] not supported, expected one of [ou,role]")
+	_, err = d.DeserializeVerifier(predicate.ScriptType, []byte("not a script"))
+	assert.EqualError(t, err, "failed to unmarshal TypedIdentity as a predicate script")
+	_, err = d.DeserializeVerifier("htlc", script(t, "idemix", "alice", p))
+	assert.EqualError(t, err, "cannot deserializer type [htlc], expected [predicate]")
+}
+
+func TestRecipients(t *testing.T) {
+	d := NewTypedIdentityDeserializer(&verifierDES{}, "idemix", attributesDES{})
+	raw := script(t, "idemix", "alice", predicate.Predicate{Attributes: map[string][]string{"ou": {"kyc-2"}}})
+	owner, err := identity.WrapWithType("idemix", []byte("alice"))
+	assert.NoError(t, err)
+
+	recipients, err := d.Recipients(nil, predicate.ScriptType, raw)
+	assert.NoError(t, err)
+	assert.Equal(t, []driver.Identity{owner}, recipients)
+
+	_, err = d.Recipients(nil, "htlc", raw)
+	assert.EqualError(t, err, "unknown identity type")
+}
@@ -130,6 +130,35 @@ func (s *WalletDB) LoadMeta(identity driver2.Identity, wID driver.WalletID, role
 	return meta, nil
 }
 
+func (s *WalletDB) StoreLongTermIdentity(identity driver2.Identity, wID driver.WalletID, roleID int) error {
+	k, err := kvs.CreateCompositeKey("walletLongTermIdentity", []string{s.tmsID.String(), strconv.Itoa(roleID), wID})
+	if err != nil {
+		return errors.Wrapf(err, "failed to create key")
+	}
+	if s.kvs.Exists(k) {
+		return nil
+	}
+	if err := s.kvs.Put(k, []byte(identity)); err != nil {
+		return errors.WithMessagef(err, "failed to store long-term identity of wallet [%s]", wID)
+	}
+	return nil
+}
+
+func (s *WalletDB) GetLongTermIdentity(wID driver.WalletID, roleID int) (driver2.Identity, error) {
+	k, err := kvs.CreateCompositeKey("walletLongTermIdentity", []string{s.tmsID.String(), strconv.Itoa(roleID), wID})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create key")
+	}
+	if !s.kvs.Exists(k) {
+		return nil, nil
+	}
+	var identity []byte
+	if err := s.kvs.Get(k, &identity); err != nil {
+		return nil, errors.WithMessagef(err, "failed to load long-term identity of wallet [%s]", wID)
+	}
+	return identity, nil
+}
+
 type IdentityDB struct {
 	kvs   KVS
 	tmsID token.TMSID
@@ -252,6 +281,53 @@ func (s *IdentityDB) GetSignerInfo(identity []byte) ([]byte, error) {
 	return res, nil
 }
 
+// recipientIdentity is the kvs record of a recipient identity learned from a counterparty
+type recipientIdentity struct {
+	Identity []byte
+	WalletID string
+}
+
+func (s *IdentityDB) StoreRecipientIdentity(id []byte, enrollmentID string, walletID driver.WalletID) error {
+	k, err := kvs.CreateCompositeKey("token-sdk", []string{"msp", s.tmsID.String(), "recipient", enrollmentID, driver2.Identity(id).UniqueID()})
+	if err != nil {
+		return errors.Wrapf(err, "failed to create key")
+	}
+	if len(walletID) == 0 && s.kvs.Exists(k) {
+		// keep the wallet already known
+		return nil
+	}
+	if err := s.kvs.Put(k, &recipientIdentity{Identity: id, WalletID: walletID}); err != nil {
+		return errors.WithMessagef(err, "failed to store recipient identity [%s]", driver2.Identity(id))
+	}
+	return nil
+}
+
+func (s *IdentityDB) GetRecipientIdentities(enrollmentID string) ([]driver.RecipientIdentity, error) {
+	it, err := s.kvs.GetByPartialCompositeID("token-sdk", []string{"msp", s.tmsID.String(), "recipient", enrollmentID})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get recipient identities from kvs")
+	}
+	defer it.Close()
+	var res []driver.RecipientIdentity
+	for it.HasNext() {
+		var r recipientIdentity
+		if _, err := it.Next(&r); err != nil {
+			return nil, errors.Wrapf(err, "failed to get next recipient identity from iterator")
+		}
+		auditInfo, err := s.GetAuditInfo(r.Identity)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get audit info for [%s]", driver2.Identity(r.Identity))
+		}
+		res = append(res, driver.RecipientIdentity{
+			Identity:     r.Identity,
+			EnrollmentID: enrollmentID,
+			WalletID:     r.WalletID,
+			AuditInfo:    auditInfo,
+		})
+	}
+	return res, nil
+}
+
 type IdentityConfigurationsIterator struct {
 	kvs.Iterator
 }
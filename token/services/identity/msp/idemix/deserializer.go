@@ -103,6 +103,16 @@ func (i *Deserializer) DeserializeVerifierAgainstNymEID(raw []byte, nymEID []byt
 	}, nil
 }
 
+// DeserializeAttributes returns the certified attributes of the passed identity, once its proof has been verified.
+// The proof binds the disclosed attributes to a credential of the issuer.
+func (i *Deserializer) DeserializeAttributes(raw driver.Identity) (map[string]string, error) {
+	identity, err := i.Deserialize(raw, true)
+	if err != nil {
+		return nil, err
+	}
+	return msp2.CertifiedAttributes(identity.OU, identity.Role), nil
+}
+
 func (i *Deserializer) DeserializeSigner(raw []byte) (driver.Signer, error) {
 	return nil, errors.New("not supported")
 }
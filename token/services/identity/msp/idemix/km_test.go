@@ -100,6 +100,13 @@ func TestIdentityWithEidRhNymPolicy(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NoError(t, verifier.Verify([]byte("hello world!!!"), sigma))
 
+	attributes, err := (&idemix.Deserializer{Deserializer: p.Deserializer}).DeserializeAttributes(id)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{msp.AttributeOU: "idemixorg.example.com", msp.AttributeRole: "ADMIN"}, attributes)
+	unverified, err := msp.IdentityAttributes(id)
+	assert.NoError(t, err)
+	assert.Equal(t, attributes, unverified)
+
 	keyStore, err = msp.NewKeyStore(math.FP256BN_AMCL, backend)
 	assert.NoError(t, err)
 	cryptoProvider, err = msp.NewBCCSP(keyStore, math.FP256BN_AMCL, false)
@@ -7,7 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package msp
 
 import (
+	"github.com/hyperledger-labs/fabric-smart-client/pkg/utils/proto"
 	m "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
 )
 
 // RoleAttribute : Represents a IdemixRole
@@ -56,3 +58,41 @@ func GetIdemixRoleFromMSPRoleValue(role int) int {
 		return MEMBER.getValue()
 	}
 }
+
+const (
+	// AttributeOU is the name of the certified attribute carrying the organizational unit of an identity
+	AttributeOU = "ou"
+	// AttributeRole is the name of the certified attribute carrying the role of an identity
+	AttributeRole = "role"
+)
+
+// CertifiedAttributes returns the certified attributes disclosed by an identity with the passed organizational unit and role.
+// The role is the name of the MSP role type (e.g. `MEMBER`).
+func CertifiedAttributes(ou *m.OrganizationUnit, role *m.MSPRole) map[string]string {
+	return map[string]string{
+		AttributeOU:   ou.GetOrganizationalUnitIdentifier(),
+		AttributeRole: role.GetRole().String(),
+	}
+}
+
+// IdentityAttributes returns the certified attributes disclosed by the passed serialized identity, without verifying its proof.
+// Use Deserializer.Deserialize, with validity check, to get attributes that have been proven.
+func IdentityAttributes(raw []byte) (map[string]string, error) {
+	si := &m.SerializedIdentity{}
+	if err := proto.Unmarshal(raw, si); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal to msp.SerializedIdentity{}")
+	}
+	serialized := &m.SerializedIdemixIdentity{}
+	if err := proto.Unmarshal(si.IdBytes, serialized); err != nil {
+		return nil, errors.Wrap(err, "could not deserialize a SerializedIdemixIdentity")
+	}
+	ou := &m.OrganizationUnit{}
+	if err := proto.Unmarshal(serialized.Ou, ou); err != nil {
+		return nil, errors.Wrap(err, "cannot deserialize the OU of the identity")
+	}
+	role := &m.MSPRole{}
+	if err := proto.Unmarshal(serialized.Role, role); err != nil {
+		return nil, errors.Wrap(err, "cannot deserialize the role of the identity")
+	}
+	return CertifiedAttributes(ou, role), nil
+}
@@ -46,6 +46,8 @@ type sigService interface {
 type Storage interface {
 	GetAuditInfo(id []byte) ([]byte, error)
 	StoreIdentityData(id []byte, identityAudit []byte, tokenMetadata []byte, tokenMetadataAudit []byte) error
+	StoreRecipientIdentity(id []byte, enrollmentID string, walletID string) error
+	GetRecipientIdentities(enrollmentID string) ([]driver.RecipientIdentity, error)
 }
 
 type Binder interface {
@@ -138,6 +140,32 @@ func (p *Provider) RegisterRecipientIdentity(id driver.Identity) error {
 	return nil
 }
 
+// BindRecipientIdentity persists the passed recipient identity with the enrollment ID found in its audit info.
+// Identities whose enrollment ID cannot be extracted are skipped.
+func (p *Provider) BindRecipientIdentity(id driver.Identity, walletID string) error {
+	auditInfo, err := p.Storage.GetAuditInfo(id)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting audit info for [%s]", id)
+	}
+	if len(auditInfo) == 0 {
+		logger.Debugf("no audit info for recipient identity [%s], skip binding", id)
+		return nil
+	}
+	eID, err := p.enrollmentIDUnmarshaler.GetEnrollmentID(id, auditInfo)
+	if err != nil {
+		logger.Debugf("cannot extract enrollment ID for recipient identity [%s], skip binding: [%s]", id, err)
+		return nil
+	}
+	if err := p.Storage.StoreRecipientIdentity(id, eID, walletID); err != nil {
+		return errors.WithMessagef(err, "failed storing recipient identity [%s]", id)
+	}
+	return nil
+}
+
+func (p *Provider) RecipientIdentities(enrollmentID string) ([]driver.RecipientIdentity, error) {
+	return p.Storage.GetRecipientIdentities(enrollmentID)
+}
+
 func (p *Provider) GetSigner(identity driver.Identity) (driver.Signer, error) {
 	found := false
 	defer func() {
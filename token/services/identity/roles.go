@@ -22,6 +22,8 @@ type Role interface {
 	GetIdentityInfo(id string) (driver.IdentityInfo, error)
 	// RegisterIdentity registers the given identity
 	RegisterIdentity(config driver.IdentityConfiguration) error
+	// IdentityConfiguration returns the configuration, credentials included, of the identity with the passed identifier
+	IdentityConfiguration(id string) (driver.IdentityConfiguration, error)
 	// IdentityIDs returns the identifiers contained in this role
 	IdentityIDs() ([]string, error)
 }
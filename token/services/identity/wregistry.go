@@ -42,6 +42,11 @@ func (r *WalletRegistry) RegisterIdentity(config driver.IdentityConfiguration) e
 	return r.Role.RegisterIdentity(config)
 }
 
+// IdentityConfiguration returns the configuration of the long-term identity with the passed identifier
+func (r *WalletRegistry) IdentityConfiguration(id string) (driver.IdentityConfiguration, error) {
+	return r.Role.IdentityConfiguration(id)
+}
+
 // Lookup searches the wallet corresponding to the passed id.
 // If a wallet is found, Lookup returns the wallet and its identifier.
 // If no wallet is found, Lookup returns the identity info and a potential wallet identifier for the passed id, if anything is found
@@ -203,6 +208,17 @@ func (r *WalletRegistry) GetIdentityMetadata(identity driver.Identity, wID strin
 	return json.Unmarshal(raw, &meta)
 }
 
+// BindLongTermIdentity stores the passed identity as the one the passed wallet reuses across transfers.
+// An identity already stored for the wallet is kept.
+func (r *WalletRegistry) BindLongTermIdentity(identity driver.Identity, wID string) error {
+	return r.Storage.StoreLongTermIdentity(identity, wID, int(r.Role.ID()))
+}
+
+// LongTermIdentity returns the identity the passed wallet reuses across transfers, nil if there is none yet
+func (r *WalletRegistry) LongTermIdentity(wID string) (driver.Identity, error) {
+	return r.Storage.GetLongTermIdentity(wID, int(r.Role.ID()))
+}
+
 // GetWalletID returns the wallet identifier bound to the passed identity
 func (r *WalletRegistry) GetWalletID(identity driver.Identity) (string, error) {
 	wID, err := r.Storage.GetWalletID(identity, int(r.Role.ID()))
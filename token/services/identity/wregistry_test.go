@@ -60,6 +60,11 @@ func (f *fakeRole) RegisterIdentity(config driver.IdentityConfiguration) error {
 	panic("implement me")
 }
 
+func (f *fakeRole) IdentityConfiguration(id string) (driver.IdentityConfiguration, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
 func (f *fakeRole) IdentityIDs() ([]string, error) {
 	//TODO implement me
 	panic("implement me")
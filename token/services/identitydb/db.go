@@ -7,6 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package identitydb
 
 import (
+	"context"
+	"errors"
+
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
@@ -62,6 +65,12 @@ func NewManager(drivers []db.NamedDriver[driver.IdentityDBDriver], cp driver.Con
 	}
 }
 
+// EnableStatementMetrics makes the identity and the wallet dbs count their interrupted statements in the passed metrics
+func (m *Manager) EnableStatementMetrics(metrics *db.StatementMetrics) {
+	m.identityManager.EnableStatementMetrics("identitydb", metrics)
+	m.walletManager.EnableStatementMetrics("walletdb", metrics)
+}
+
 func (m *Manager) IdentityDBByTMSId(tmsID token.TMSID) (driver.IdentityDB, error) {
 	return m.identityManager.DBByTMSId(tmsID)
 }
@@ -69,3 +78,8 @@ func (m *Manager) IdentityDBByTMSId(tmsID token.TMSID) (driver.IdentityDB, error
 func (m *Manager) WalletDBByTMSId(tmsID token.TMSID) (driver.WalletDB, error) {
 	return m.walletManager.DBByTMSId(tmsID)
 }
+
+// Stop closes the identity and wallet databases
+func (m *Manager) Stop(ctx context.Context) error {
+	return errors.Join(m.identityManager.Stop(ctx), m.walletManager.Stop(ctx))
+}
@@ -0,0 +1,75 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// ClaimView claims, with the passed preimage, an htlc-token received by a wallet
+type ClaimView struct {
+	tmsID    token.TMSID
+	wallet   string
+	id       *token2.ID
+	preImage []byte
+	opts     []ttx.TxOption
+}
+
+// NewClaimView returns a view claiming, with the passed preimage, the htlc-token with the passed id received by the passed wallet.
+// If the id is nil, exactly one htlc-token of the wallet must match the preimage.
+// The passed options are used to create the transaction, for instance, to set the auditor.
+func NewClaimView(tmsID token.TMSID, wallet string, id *token2.ID, preImage []byte, opts ...ttx.TxOption) *ClaimView {
+	return &ClaimView{tmsID: tmsID, wallet: wallet, id: id, preImage: preImage, opts: opts}
+}
+
+// Call returns the id of the claim transaction, once committed
+func (c *ClaimView) Call(context view.Context) (interface{}, error) {
+	claimWallet := GetWallet(context, c.wallet, token.WithTMSID(c.tmsID))
+	if claimWallet == nil {
+		return nil, errors.Errorf("wallet [%s] not found", c.wallet)
+	}
+	matched, err := Wallet(context, claimWallet).ListByPreImage(c.preImage)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to list htlc-tokens matching the preimage")
+	}
+	var tok *token2.UnspentToken
+	if c.id == nil {
+		if matched.Count() != 1 {
+			return nil, errors.Errorf("expected only one htlc-token to match, got [%d]", matched.Count())
+		}
+		tok = matched.At(0)
+	} else {
+		for _, t := range matched.Tokens {
+			if t.Id.Equal(*c.id) {
+				tok = t
+				break
+			}
+		}
+		if tok == nil {
+			return nil, errors.Errorf("htlc-token [%s] not found or not matching the preimage", c.id)
+		}
+	}
+
+	tx, err := NewAnonymousTransaction(context, append(c.opts, ttx.WithTMSID(c.tmsID))...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to create an htlc transaction")
+	}
+	if err := tx.Claim(claimWallet, tok, c.preImage); err != nil {
+		return nil, errors.WithMessagef(err, "failed adding a claim for [%s]", tok.Id)
+	}
+	if _, err := context.RunView(NewCollectEndorsementsView(tx)); err != nil {
+		return nil, errors.WithMessagef(err, "failed to collect endorsements on htlc transaction [%s]", tx.ID())
+	}
+	if _, err := context.RunView(NewOrderingAndFinalityView(tx)); err != nil {
+		return nil, errors.WithMessagef(err, "failed to commit htlc transaction [%s]", tx.ID())
+	}
+	return tx.ID(), nil
+}
@@ -0,0 +1,193 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"context"
+	errors2 "errors"
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/metrics"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+var (
+	spKey = reflect.TypeOf((*Metrics)(nil))
+
+	lockedValue = metrics.GaugeOpts{
+		Namespace:    "htlc",
+		Name:         "locked_value",
+		Help:         "The value locked in outstanding htlc-tokens of a wallet, by direction and token type.",
+		LabelNames:   []string{"network", "channel", "namespace", "wallet", "direction", "token_type"},
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}.%{wallet}.%{direction}.%{token_type}",
+	}
+	outstandingLocks = metrics.GaugeOpts{
+		Namespace:    "htlc",
+		Name:         "outstanding_locks",
+		Help:         "The number of outstanding htlc-tokens of a wallet, by direction and token type.",
+		LabelNames:   []string{"network", "channel", "namespace", "wallet", "direction", "token_type"},
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}.%{wallet}.%{direction}.%{token_type}",
+	}
+)
+
+type Metrics struct {
+	LockedValue      metrics.Gauge
+	OutstandingLocks metrics.Gauge
+
+	mutex sync.Mutex
+	// reported contains, for each wallet, the label values of the gauges set so far
+	reported map[walletLabels]map[exposureKey]struct{}
+}
+
+func NewMetrics(p metrics.Provider) *Metrics {
+	return &Metrics{
+		LockedValue:      p.NewGauge(lockedValue),
+		OutstandingLocks: p.NewGauge(outstandingLocks),
+		reported:         map[walletLabels]map[exposureKey]struct{}{},
+	}
+}
+
+// GetMetrics returns the htlc metrics registered in the passed service provider, nil if not available
+func GetMetrics(sp token.ServiceProvider) *Metrics {
+	s, err := sp.GetService(spKey)
+	if err != nil {
+		logger.Debugf("htlc metrics not available: [%s]", err)
+		return nil
+	}
+	return s.(*Metrics)
+}
+
+// walletLabels identifies the gauges of a wallet
+type walletLabels struct {
+	network   string
+	channel   string
+	namespace string
+	wallet    string
+}
+
+func newWalletLabels(tms *token.ManagementService, walletID string) walletLabels {
+	return walletLabels{network: tms.Network(), channel: tms.Channel(), namespace: tms.Namespace(), wallet: walletID}
+}
+
+type exposureKey struct {
+	direction LockDirection
+	tokenType string
+}
+
+type exposure struct {
+	locks int
+	value token2.Quantity
+}
+
+// observe sets the gauges of the passed wallet to the passed exposures.
+// The gauges previously set for the directions and token types in scope, and missing in the passed exposures, are set to zero.
+func (m *Metrics) observe(wallet walletLabels, exposures map[exposureKey]*exposure, inScope func(exposureKey) bool) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	reported, ok := m.reported[wallet]
+	if !ok {
+		reported = map[exposureKey]struct{}{}
+		m.reported[wallet] = reported
+	}
+	for key := range reported {
+		if _, ok := exposures[key]; !ok && inScope(key) {
+			m.set(wallet, key, 0, 0)
+		}
+	}
+	for key, e := range exposures {
+		value, _ := new(big.Float).SetInt(e.value.ToBigInt()).Float64()
+		m.set(wallet, key, e.locks, value)
+		reported[key] = struct{}{}
+	}
+}
+
+func (m *Metrics) set(wallet walletLabels, key exposureKey, locks int, value float64) {
+	labels := []string{
+		"network", wallet.network,
+		"channel", wallet.channel,
+		"namespace", wallet.namespace,
+		"wallet", wallet.wallet,
+		"direction", string(key.direction),
+		"token_type", key.tokenType,
+	}
+	m.OutstandingLocks.With(labels...).Set(float64(locks))
+	m.LockedValue.With(labels...).Set(value)
+}
+
+// CollectedWallet identifies a wallet whose gauges are refreshed by a MetricsCollector
+type CollectedWallet struct {
+	TMSID  token.TMSID
+	Wallet string
+}
+
+// MetricsCollector refreshes the locked value gauges of a set of wallets every interval,
+// so that the locks, claims, and reclaims are reflected in the gauges even if nobody lists the outstanding locks.
+type MetricsCollector struct {
+	wallets  []CollectedWallet
+	interval time.Duration
+
+	refresh func(wallet CollectedWallet) error
+}
+
+// NewMetricsCollector returns a new MetricsCollector of the passed wallets that refreshes the gauges every interval
+func NewMetricsCollector(sp token.ServiceProvider, interval time.Duration, wallets ...CollectedWallet) *MetricsCollector {
+	return &MetricsCollector{
+		wallets:  wallets,
+		interval: interval,
+		refresh: func(wallet CollectedWallet) error {
+			w := GetWallet(sp, wallet.Wallet, token.WithTMSID(wallet.TMSID))
+			if w == nil {
+				return errors.Errorf("wallet [%s] not found in [%s]", wallet.Wallet, wallet.TMSID)
+			}
+			hw := Wallet(sp, w)
+			if hw == nil {
+				return errors.Errorf("htlc wallet [%s] not available in [%s]", wallet.Wallet, wallet.TMSID)
+			}
+			_, err := hw.ListOutstanding(OutstandingFilter{})
+			return err
+		},
+	}
+}
+
+// Collect refreshes the gauges of all the wallets.
+// A wallet that cannot be refreshed does not prevent the refresh of the others, the errors are returned together.
+func (c *MetricsCollector) Collect() error {
+	var errs []error
+	for _, wallet := range c.wallets {
+		if err := c.refresh(wallet); err != nil {
+			errs = append(errs, errors.WithMessagef(err, "failed to refresh the htlc gauges of [%s]", wallet.Wallet))
+		}
+	}
+	return errors2.Join(errs...)
+}
+
+// Start collects every interval, until the passed context is done
+func (c *MetricsCollector) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			if err := c.Collect(); err != nil {
+				logger.Warnf("failed to collect htlc metrics: [%s]", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,120 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/metrics"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// gaugeValues records the values set on a gauge, by the concatenation of the label values
+type gaugeValues map[string]float64
+
+type recordingGauge struct {
+	values gaugeValues
+	labels []string
+}
+
+func (g *recordingGauge) With(labelValues ...string) metrics.Gauge {
+	return &recordingGauge{values: g.values, labels: labelValues}
+}
+
+func (g *recordingGauge) Add(delta float64) { g.values[g.key()] += delta }
+
+func (g *recordingGauge) Set(value float64) { g.values[g.key()] = value }
+
+func (g *recordingGauge) key() string {
+	var values []string
+	for i := 1; i < len(g.labels); i += 2 {
+		values = append(values, g.labels[i])
+	}
+	return strings.Join(values, "/")
+}
+
+func TestMetricsObserve(t *testing.T) {
+	locks, value := gaugeValues{}, gaugeValues{}
+	m := &Metrics{
+		OutstandingLocks: &recordingGauge{values: locks},
+		LockedValue:      &recordingGauge{values: value},
+		reported:         map[walletLabels]map[exposureKey]struct{}{},
+	}
+	alice := walletLabels{network: "n", channel: "c", namespace: "ns", wallet: "alice"}
+	all := func(exposureKey) bool { return true }
+
+	// a lock sent and a lock received
+	m.observe(alice, map[exposureKey]*exposure{
+		{direction: Sent, tokenType: "USD"}:     {locks: 2, value: token2.NewQuantityFromUInt64(30)},
+		{direction: Received, tokenType: "EUR"}: {locks: 1, value: token2.NewQuantityFromUInt64(5)},
+	}, all)
+	assert.Equal(t, gaugeValues{"n/c/ns/alice/sent/USD": 2, "n/c/ns/alice/received/EUR": 1}, locks)
+	assert.Equal(t, gaugeValues{"n/c/ns/alice/sent/USD": 30, "n/c/ns/alice/received/EUR": 5}, value)
+
+	// the received lock is claimed, the gauges out of scope are left untouched
+	m.observe(alice, map[exposureKey]*exposure{}, func(key exposureKey) bool { return key.direction == Received })
+	assert.Equal(t, gaugeValues{"n/c/ns/alice/sent/USD": 2, "n/c/ns/alice/received/EUR": 0}, locks)
+	assert.Equal(t, gaugeValues{"n/c/ns/alice/sent/USD": 30, "n/c/ns/alice/received/EUR": 0}, value)
+
+	// the same wallet id in another namespace has its own gauges
+	m.observe(walletLabels{network: "n", channel: "c", namespace: "ns2", wallet: "alice"}, map[exposureKey]*exposure{}, all)
+	assert.Equal(t, float64(2), locks["n/c/ns/alice/sent/USD"])
+
+	// one of the sent locks is reclaimed
+	m.observe(alice, map[exposureKey]*exposure{
+		{direction: Sent, tokenType: "USD"}: {locks: 1, value: token2.NewQuantityFromUInt64(10)},
+	}, all)
+	assert.Equal(t, gaugeValues{"n/c/ns/alice/sent/USD": 1, "n/c/ns/alice/received/EUR": 0}, locks)
+	assert.Equal(t, gaugeValues{"n/c/ns/alice/sent/USD": 10, "n/c/ns/alice/received/EUR": 0}, value)
+
+	// no metrics, no gauges
+	var disabled *Metrics
+	disabled.observe(alice, map[exposureKey]*exposure{}, all)
+}
+
+func TestMetricsCollector(t *testing.T) {
+	alice := CollectedWallet{TMSID: token.TMSID{Network: "alpha"}, Wallet: "alice"}
+	bob := CollectedWallet{TMSID: token.TMSID{Network: "beta"}, Wallet: "bob"}
+
+	var mutex sync.Mutex
+	var refreshed []string
+	c := &MetricsCollector{
+		wallets:  []CollectedWallet{alice, bob},
+		interval: 10 * time.Millisecond,
+		refresh: func(wallet CollectedWallet) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			refreshed = append(refreshed, wallet.Wallet)
+			if wallet.Wallet == "alice" {
+				return errors.New("vault not available")
+			}
+			return nil
+		},
+	}
+
+	// a failing wallet does not prevent the refresh of the others
+	err := c.Collect()
+	assert.ErrorContains(t, err, "failed to refresh the htlc gauges of [alice]: vault not available")
+	assert.Equal(t, []string{"alice", "bob"}, refreshed)
+
+	// the collector refreshes every interval until the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Start(ctx)
+	assert.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(refreshed) >= 6
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+}
@@ -0,0 +1,143 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"bytes"
+	"crypto"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/interop/encoding"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// LockDirection tells whether an htlc-token has been locked by the wallet or for the wallet
+type LockDirection string
+
+const (
+	// AnyDirection matches both sent and received locks
+	AnyDirection LockDirection = ""
+	// Sent is the direction of the locks whose sender is in the wallet
+	Sent LockDirection = "sent"
+	// Received is the direction of the locks whose recipient is in the wallet
+	Received LockDirection = "received"
+)
+
+// OutstandingFilter selects the outstanding locks to list
+type OutstandingFilter struct {
+	// TokenType, if not empty, selects the locks of this token type
+	TokenType string
+	// Direction selects the sent or the received locks, AnyDirection selects both
+	Direction LockDirection
+	// Hash, if not empty, selects the locks with this hash
+	Hash []byte
+}
+
+// OutstandingLock describes an htlc-token that has been neither claimed nor reclaimed yet
+type OutstandingLock struct {
+	ID        *token2.ID
+	Direction LockDirection
+	Hash      []byte
+	// HashFunc and HashEncoding are the functions that compute the hash from the preimage
+	HashFunc     crypto.Hash
+	HashEncoding encoding.Encoding
+	Deadline     time.Time
+	// Expired is true if the deadline has passed, the lock can then only be reclaimed by the sender
+	Expired bool
+	// Counterparty is the recipient of a sent lock, or the sender of a received lock
+	Counterparty view.Identity
+	TokenType    string
+	Quantity     token2.Quantity
+}
+
+// ListOutstanding returns the htlc-tokens, sent or received by this wallet, that have been neither claimed nor reclaimed yet,
+// and that match the passed filter.
+// The sender does not learn when a lock is claimed, therefore, sent locks are listed until removed with DeleteClaimedSentTokens.
+// If the filter selects locks by type or direction only, the locked value gauges of the wallet are updated accordingly.
+func (w *OwnerWallet) ListOutstanding(filter OutstandingFilter) ([]*OutstandingLock, error) {
+	precision := w.wallet.TMS().PublicParametersManager().PublicParameters().Precision()
+	var locks []*OutstandingLock
+	exposures := map[exposureKey]*exposure{}
+	for _, direction := range []LockDirection{Sent, Received} {
+		if filter.Direction != AnyDirection && filter.Direction != direction {
+			continue
+		}
+		dirLocks, err := w.listOutstanding(direction, filter, precision)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to list %s locks", direction)
+		}
+		for _, l := range dirLocks {
+			key := exposureKey{direction: direction, tokenType: l.TokenType}
+			e, ok := exposures[key]
+			if !ok {
+				e = &exposure{value: token2.NewZeroQuantity(precision)}
+				exposures[key] = e
+			}
+			e.locks++
+			e.value = e.value.Add(l.Quantity)
+		}
+		locks = append(locks, dirLocks...)
+	}
+
+	if len(filter.Hash) == 0 {
+		w.metrics.observe(newWalletLabels(w.wallet.TMS(), w.wallet.ID()), exposures, func(key exposureKey) bool {
+			return (filter.Direction == AnyDirection || filter.Direction == key.direction) &&
+				(len(filter.TokenType) == 0 || filter.TokenType == key.tokenType)
+		})
+	}
+	return locks, nil
+}
+
+func (w *OwnerWallet) listOutstanding(direction LockDirection, filter OutstandingFilter, precision uint64) ([]*OutstandingLock, error) {
+	// the iterator invokes the selector on the script of a token right before returning it
+	var script *Script
+	it, err := w.filterIterator(filter.TokenType, direction == Sent, func(_ *token2.UnspentToken, s *Script) (bool, error) {
+		if len(filter.Hash) != 0 && !bytes.Equal(filter.Hash, s.HashInfo.Hash) {
+			return false, nil
+		}
+		script = s
+		return true, nil
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get an iterator of htlc-tokens")
+	}
+	defer it.Close()
+
+	now := time.Now()
+	var locks []*OutstandingLock
+	for {
+		tok, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get next htlc-token")
+		}
+		if tok == nil {
+			return locks, nil
+		}
+		q, err := token2.ToQuantity(tok.Quantity, precision)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid quantity in token [%s]", tok.Id)
+		}
+		counterparty := script.Recipient
+		if direction == Received {
+			counterparty = script.Sender
+		}
+		locks = append(locks, &OutstandingLock{
+			ID:           tok.Id,
+			Direction:    direction,
+			Hash:         script.HashInfo.Hash,
+			HashFunc:     script.HashInfo.HashFunc,
+			HashEncoding: script.HashInfo.HashEncoding,
+			Deadline:     script.Deadline,
+			Expired:      script.Deadline.Before(now),
+			Counterparty: counterparty,
+			TokenType:    tok.Type,
+			Quantity:     q,
+		})
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/pkg/errors"
+)
+
+const secretPrefix = "htlc.secret"
+
+// KVS models the key-value store the revealed secrets are persisted in
+type KVS interface {
+	Exists(id string) bool
+	Put(id string, state interface{}) error
+	Get(id string, state interface{}) error
+}
+
+// Secret is a preimage revealed on a ledger
+type Secret struct {
+	Hash       []byte
+	PreImage   []byte
+	RevealedAt time.Time
+}
+
+// SecretRegistry persists the preimages revealed on the ledgers, indexed by hash,
+// so that the locks with the same hash can be claimed even after a restart
+type SecretRegistry struct {
+	kvs KVS
+}
+
+// NewSecretRegistry returns a new SecretRegistry persisted in the passed KVS
+func NewSecretRegistry(kvs KVS) *SecretRegistry {
+	return &SecretRegistry{kvs: kvs}
+}
+
+// GetSecretRegistry returns a SecretRegistry persisted in the KVS of the passed service provider
+func GetSecretRegistry(sp token.ServiceProvider) (*SecretRegistry, error) {
+	kvss, err := sp.GetService(&kvs.KVS{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get KVS from context")
+	}
+	return NewSecretRegistry(kvss.(*kvs.KVS)), nil
+}
+
+func (r *SecretRegistry) key(hash []byte) (string, error) {
+	k, err := kvs.CreateCompositeKey(secretPrefix, []string{hex.EncodeToString(hash)})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to generate key for secret [%x]", hash)
+	}
+	return k, nil
+}
+
+// Put stores the preimage of the passed hash
+func (r *SecretRegistry) Put(hash []byte, preImage []byte) error {
+	k, err := r.key(hash)
+	if err != nil {
+		return err
+	}
+	if err := r.kvs.Put(k, &Secret{Hash: hash, PreImage: preImage, RevealedAt: time.Now()}); err != nil {
+		return errors.Wrapf(err, "failed to store secret [%x]", hash)
+	}
+	return nil
+}
+
+// Get returns the secret of the passed hash, or nil if it has not been revealed yet
+func (r *SecretRegistry) Get(hash []byte) (*Secret, error) {
+	k, err := r.key(hash)
+	if err != nil {
+		return nil, err
+	}
+	if !r.kvs.Exists(k) {
+		return nil, nil
+	}
+	secret := &Secret{}
+	if err := r.kvs.Get(k, secret); err != nil {
+		return nil, errors.Wrapf(err, "failed to load secret [%x]", hash)
+	}
+	return secret, nil
+}
@@ -39,6 +39,7 @@ type OwnerWallet struct {
 	queryEngine QueryEngine
 	vault       TokenVault
 	bufferSize  int
+	metrics     *Metrics
 }
 
 // ListTokensAsSender returns a list of non-expired htlc-tokens whose sender id is in this wallet
@@ -335,6 +336,7 @@ func Wallet(sp token.ServiceProvider, wallet *token.OwnerWallet) *OwnerWallet {
 		vault:       vault,
 		queryEngine: vault.QueryEngine(),
 		bufferSize:  100,
+		metrics:     GetMetrics(sp),
 	}
 }
 
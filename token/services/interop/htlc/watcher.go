@@ -0,0 +1,162 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	"github.com/pkg/errors"
+)
+
+// SwapLegs identifies the wallets involved in the swaps watched by a Watcher.
+// In a swap, the wallet sends a lock on one ledger and receives, on another ledger, a lock with the same hash.
+// When the counterparty claims the sent lock, it reveals the preimage that claims the received lock.
+type SwapLegs struct {
+	// ClaimTMSID and ClaimWallet identify the wallet that received the locks to claim
+	ClaimTMSID  token.TMSID
+	ClaimWallet string
+	// RevealTMSID and RevealWallet identify the wallet that sent the locks whose claims reveal the preimages
+	RevealTMSID  token.TMSID
+	RevealWallet string
+}
+
+// ViewManager initiates views
+type ViewManager interface {
+	InitiateView(view view.View, ctx context.Context) (interface{}, error)
+}
+
+// Watcher monitors the ledger of the sent locks for the preimages revealed by the counterparty,
+// persists them in a SecretRegistry, and claims the received locks with the same hashes.
+type Watcher struct {
+	viewManager ViewManager
+	registry    *SecretRegistry
+	legs        SwapLegs
+	interval    time.Duration
+	opts        []ttx.TxOption
+
+	listLocks func(tmsID token.TMSID, wallet string, direction LockDirection) ([]*OutstandingLock, error)
+	scan      func(lock *OutstandingLock) ([]byte, error)
+}
+
+// NewWatcher returns a new Watcher of the passed legs that scans the ledger every interval.
+// The passed options are used to create the claim transactions, for instance, to set the auditor.
+func NewWatcher(sp token.ServiceProvider, viewManager ViewManager, registry *SecretRegistry, legs SwapLegs, interval time.Duration, opts ...ttx.TxOption) *Watcher {
+	return &Watcher{
+		viewManager: viewManager,
+		registry:    registry,
+		legs:        legs,
+		interval:    interval,
+		opts:        opts,
+		listLocks: func(tmsID token.TMSID, wallet string, direction LockDirection) ([]*OutstandingLock, error) {
+			w := GetWallet(sp, wallet, token.WithTMSID(tmsID))
+			if w == nil {
+				return nil, errors.Errorf("wallet [%s] not found in [%s]", wallet, tmsID)
+			}
+			return Wallet(sp, w).ListOutstanding(OutstandingFilter{Direction: direction})
+		},
+		scan: func(lock *OutstandingLock) ([]byte, error) {
+			return ScanForPreImage(sp, lock.Hash, lock.HashFunc, lock.HashEncoding, interval, token.WithTMSID(legs.RevealTMSID), WithStopOnLastTransaction())
+		},
+	}
+}
+
+// Watch looks for the preimages of the received locks that are not expired yet, and claims the locks whose preimage is known.
+// A preimage is known if it is in the registry, or if it has been revealed by the claim of the sent lock with the same hash.
+// It returns the ids of the committed claim transactions.
+func (w *Watcher) Watch(ctx context.Context) ([]string, error) {
+	received, err := w.listLocks(w.legs.ClaimTMSID, w.legs.ClaimWallet, Received)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to list received locks")
+	}
+	var claimable []*OutstandingLock
+	for _, lock := range received {
+		if !lock.Expired {
+			claimable = append(claimable, lock)
+		}
+	}
+	if len(claimable) == 0 {
+		return nil, nil
+	}
+	sent, err := w.listLocks(w.legs.RevealTMSID, w.legs.RevealWallet, Sent)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to list sent locks")
+	}
+	sentByHash := make(map[string]*OutstandingLock, len(sent))
+	for _, lock := range sent {
+		sentByHash[string(lock.Hash)] = lock
+	}
+
+	var txIDs []string
+	for _, lock := range claimable {
+		preImage, err := w.preImage(lock, sentByHash[string(lock.Hash)])
+		if err != nil {
+			return txIDs, err
+		}
+		if len(preImage) == 0 {
+			continue
+		}
+		logger.Infof("claiming htlc-token [%s] with the revealed preimage of [%x]", lock.ID, lock.Hash)
+		res, err := w.viewManager.InitiateView(NewClaimView(w.legs.ClaimTMSID, w.legs.ClaimWallet, lock.ID, preImage, w.opts...), ctx)
+		if err != nil {
+			// the claim is tried again at the next round, as long as the lock does not expire
+			logger.Warnf("failed to claim htlc-token [%s]: [%s]", lock.ID, err)
+			continue
+		}
+		txIDs = append(txIDs, res.(string))
+	}
+	return txIDs, nil
+}
+
+// preImage returns the preimage of the passed received lock, if known, scanning the ledger for the claim of the passed sent lock.
+// It returns nil if the preimage is not known yet.
+func (w *Watcher) preImage(received, sent *OutstandingLock) ([]byte, error) {
+	secret, err := w.registry.Get(received.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if secret != nil {
+		return secret.PreImage, nil
+	}
+	if sent == nil {
+		// the counterparty leg is not held by this wallet
+		return nil, nil
+	}
+	preImage, err := w.scan(sent)
+	if err != nil || len(preImage) == 0 {
+		logger.Debugf("preimage of [%x] not revealed yet: [%v]", sent.Hash, err)
+		return nil, nil
+	}
+	if err := w.registry.Put(received.Hash, preImage); err != nil {
+		return nil, err
+	}
+	return preImage, nil
+}
+
+// Start watches every interval, until the passed context is done
+func (w *Watcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			txIDs, err := w.Watch(ctx)
+			if err != nil {
+				logger.Warnf("failed to watch htlc-tokens of [%s]: [%s]", w.legs.ClaimWallet, err)
+			} else if len(txIDs) > 0 {
+				logger.Infof("claimed htlc-tokens of [%s] with transactions [%v]", w.legs.ClaimWallet, txIDs)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
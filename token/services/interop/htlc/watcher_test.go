@@ -0,0 +1,132 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package htlc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type memKVS map[string][]byte
+
+func (m memKVS) Exists(id string) bool {
+	_, ok := m[id]
+	return ok
+}
+
+func (m memKVS) Put(id string, state interface{}) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	m[id] = raw
+	return nil
+}
+
+func (m memKVS) Get(id string, state interface{}) error {
+	raw, ok := m[id]
+	if !ok {
+		return errors.Errorf("[%s] not found", id)
+	}
+	return json.Unmarshal(raw, state)
+}
+
+type viewManager struct {
+	claims []*ClaimView
+	err    error
+}
+
+func (m *viewManager) InitiateView(v view.View, _ context.Context) (interface{}, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	c := v.(*ClaimView)
+	m.claims = append(m.claims, c)
+	return "tx-" + c.id.TxId, nil
+}
+
+func TestWatcher(t *testing.T) {
+	legs := SwapLegs{
+		ClaimTMSID:   token.TMSID{Network: "beta"},
+		ClaimWallet:  "alice.beta",
+		RevealTMSID:  token.TMSID{Network: "alpha"},
+		RevealWallet: "alice.alpha",
+	}
+	locks := map[LockDirection][]*OutstandingLock{
+		Received: {
+			{ID: &token2.ID{TxId: "r1"}, Hash: []byte("h1")},
+			{ID: &token2.ID{TxId: "r2"}, Hash: []byte("h2")},
+			{ID: &token2.ID{TxId: "r3"}, Hash: []byte("h3"), Expired: true},
+		},
+		Sent: {
+			{ID: &token2.ID{TxId: "s1"}, Hash: []byte("h1")},
+			{ID: &token2.ID{TxId: "s3"}, Hash: []byte("h3")},
+		},
+	}
+	revealed := map[string][]byte{}
+	var scans []string
+	manager := &viewManager{err: errors.New("network down")}
+	registry := NewSecretRegistry(memKVS{})
+	w := NewWatcher(nil, manager, registry, legs, 0)
+	w.listLocks = func(tmsID token.TMSID, wallet string, direction LockDirection) ([]*OutstandingLock, error) {
+		if direction == Received {
+			assert.Equal(t, legs.ClaimTMSID, tmsID)
+			assert.Equal(t, legs.ClaimWallet, wallet)
+		} else {
+			assert.Equal(t, legs.RevealTMSID, tmsID)
+			assert.Equal(t, legs.RevealWallet, wallet)
+		}
+		return locks[direction], nil
+	}
+	w.scan = func(lock *OutstandingLock) ([]byte, error) {
+		scans = append(scans, lock.ID.TxId)
+		preImage, ok := revealed[string(lock.Hash)]
+		if !ok {
+			return nil, errors.New("timeout")
+		}
+		return preImage, nil
+	}
+
+	// nothing revealed yet, the received lock without a sent counterpart is not scanned for
+	txIDs, err := w.Watch(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, txIDs)
+	assert.Equal(t, []string{"s1"}, scans)
+
+	// the preimage is revealed, but the claim fails
+	revealed["h1"] = []byte("p1")
+	_, err = w.Watch(context.Background())
+	assert.NoError(t, err)
+	secret, err := registry.Get([]byte("h1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("p1"), secret.PreImage)
+
+	// the claim is tried again with the persisted preimage, without scanning again
+	scans = nil
+	manager.err = nil
+	txIDs, err = w.Watch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx-r1"}, txIDs)
+	assert.Empty(t, scans)
+	assert.Len(t, manager.claims, 1)
+	assert.Equal(t, legs.ClaimTMSID, manager.claims[0].tmsID)
+	assert.Equal(t, []byte("p1"), manager.claims[0].preImage)
+
+	// a secret already known claims the locks without a sent counterpart
+	assert.NoError(t, registry.Put([]byte("h2"), []byte("p2")))
+	locks[Received] = locks[Received][1:]
+	txIDs, err = w.Watch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx-r2"}, txIDs)
+}
@@ -0,0 +1,11 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package predicate
+
+import "github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+
+var logger = logging.MustGetLogger("token-sdk.predicate")
@@ -0,0 +1,215 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package predicate
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/msp"
+	idemix "github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/msp/idemix/msp"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ScriptType is the type of the identities carrying a predicate script
+	ScriptType = "predicate"
+)
+
+// Predicate lists the certified attributes the identity spending a token must carry.
+// For each attribute name, the identity must carry one of the listed values.
+// Only the attributes certified by idemix credentials are supported, namely
+// idemix.AttributeOU and idemix.AttributeRole, see SupportedAttributes.
+type Predicate struct {
+	Attributes map[string][]string
+}
+
+// SupportedAttributes are the names of the attributes a predicate can require
+var SupportedAttributes = []string{idemix.AttributeOU, idemix.AttributeRole}
+
+// Validate checks that the predicate requires at least an attribute, each attribute is supported,
+// and each attribute has at least a value
+func (p *Predicate) Validate() error {
+	if len(p.Attributes) == 0 {
+		return errors.New("predicate without attributes")
+	}
+	for name, values := range p.Attributes {
+		if !slices.Contains(SupportedAttributes, name) {
+			return errors.Errorf("attribute [%s] not supported, expected one of [%s]", name, strings.Join(SupportedAttributes, ","))
+		}
+		if len(values) == 0 {
+			return errors.Errorf("no value allowed for attribute [%s]", name)
+		}
+	}
+	return nil
+}
+
+// Match returns nil if the passed attributes satisfy the predicate
+func (p *Predicate) Match(attributes map[string]string) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	for name, values := range p.Attributes {
+		value, ok := attributes[name]
+		if !ok {
+			return errors.Errorf("attribute [%s] not certified", name)
+		}
+		found := false
+		for _, v := range values {
+			if v == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("attribute [%s] has value [%s], expected one of [%s]", name, value, strings.Join(values, ","))
+		}
+	}
+	return nil
+}
+
+func (p *Predicate) String() string {
+	names := make([]string, 0, len(p.Attributes))
+	for name := range p.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	clauses := make([]string, len(names))
+	for i, name := range names {
+		clauses[i] = name + " in [" + strings.Join(p.Attributes[name], ",") + "]"
+	}
+	return strings.Join(clauses, " and ")
+}
+
+// Script contains the owner of a token and the predicate the owner must satisfy to spend it
+type Script struct {
+	Owner     view.Identity
+	Predicate Predicate
+}
+
+// Validate checks that the owner is set and the predicate is valid
+func (s *Script) Validate() error {
+	if s.Owner.IsNone() {
+		return errors.New("owner not set")
+	}
+	if err := s.Predicate.Validate(); err != nil {
+		return errors.WithMessagef(err, "invalid predicate")
+	}
+	return nil
+}
+
+// Attributes returns the attributes the passed owner identity discloses, without verifying them.
+// The validators verify the attributes when the token is spent.
+func Attributes(owner view.Identity) (map[string]string, error) {
+	ro, err := identity.UnmarshalTypedIdentity(owner)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal owner")
+	}
+	if ro.Type != msp.IdemixIdentity {
+		return nil, errors.Errorf("identity type [%s] does not carry certified attributes, expected [%s]", ro.Type, msp.IdemixIdentity)
+	}
+	return idemix.IdentityAttributes(ro.Identity)
+}
+
+// Wrap returns the identity that encodes the script with the passed owner and predicate.
+// It returns an error if the owner does not satisfy the predicate, the tokens owned by the script would not be spendable.
+func Wrap(owner view.Identity, predicate Predicate) (view.Identity, error) {
+	script := &Script{Owner: owner, Predicate: predicate}
+	if err := script.Validate(); err != nil {
+		return nil, errors.WithMessagef(err, "invalid script")
+	}
+	attributes, err := Attributes(owner)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get the attributes of the owner")
+	}
+	if err := predicate.Match(attributes); err != nil {
+		return nil, errors.WithMessagef(err, "owner does not satisfy predicate [%s]", &predicate)
+	}
+	raw, err := json.Marshal(script)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal predicate script")
+	}
+	return identity.WrapWithType(ScriptType, raw)
+}
+
+// Unwrap returns the script encoded in the passed identity
+func Unwrap(id view.Identity) (*Script, error) {
+	owner, err := identity.UnmarshalTypedIdentity(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal owner")
+	}
+	if owner.Type != ScriptType {
+		return nil, errors.Errorf("invalid owner type [%s], expected predicate script", owner.Type)
+	}
+	script := &Script{}
+	if err := json.Unmarshal(owner.Identity, script); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal TypedIdentity as a predicate script")
+	}
+	return script, nil
+}
+
+// ScriptAuth implements the Authorization interface for this script
+type ScriptAuth struct {
+	WalletService driver.WalletService
+}
+
+func NewScriptAuth(walletService driver.WalletService) *ScriptAuth {
+	return &ScriptAuth{WalletService: walletService}
+}
+
+// AmIAnAuditor returns false for script ownership
+func (s *ScriptAuth) AmIAnAuditor() bool {
+	return false
+}
+
+// IsMine returns true if the owner is in one of the owner wallets.
+// It returns an empty wallet id, the tokens are not selected for the transfers of the owner wallet,
+// they are spent with Transfer.
+func (s *ScriptAuth) IsMine(tok *token3.Token) (string, []string, bool) {
+	script, err := Unwrap(tok.Owner)
+	if err != nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, [%s]", view.Identity(tok.Owner), tok.Type, tok.Quantity, err)
+		return "", nil, false
+	}
+	if err := script.Validate(); err != nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, invalid content [%s]", view.Identity(tok.Owner), tok.Type, tok.Quantity, err)
+		return "", nil, false
+	}
+	wallet, err := s.WalletService.OwnerWallet(script.Owner)
+	if err != nil {
+		logger.Debugf("Is Mine [%s,%s,%s]? No, owner not in a wallet", view.Identity(tok.Owner), tok.Type, tok.Quantity)
+		return "", nil, false
+	}
+	logger.Debugf("Is Mine [%s,%s,%s]? Yes", view.Identity(tok.Owner), tok.Type, tok.Quantity)
+	return "", []string{ownerWallet(wallet)}, true
+}
+
+func (s *ScriptAuth) Issued(issuer driver.Identity, tok *token3.Token) bool {
+	return false
+}
+
+func (s *ScriptAuth) OwnerType(raw []byte) (string, []byte, error) {
+	owner, err := identity.UnmarshalTypedIdentity(raw)
+	if err != nil {
+		return "", nil, err
+	}
+	return owner.Type, owner.Identity, nil
+}
+
+type wallet interface {
+	ID() string
+}
+
+func ownerWallet(w wallet) string {
+	return "predicate.owner" + w.ID()
+}
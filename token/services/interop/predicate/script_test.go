@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package predicate
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/pkg/utils/proto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/msp"
+	idemix "github.com/hyperledger-labs/fabric-token-sdk/token/services/identity/msp/idemix/msp"
+	m "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/stretchr/testify/assert"
+)
+
+// idemixOwner returns an idemix owner identity disclosing the passed organizational unit and role, without a proof
+func idemixOwner(t *testing.T, ou string, role m.MSPRole_MSPRoleType) []byte {
+	rawOU, err := proto.Marshal(&m.OrganizationUnit{OrganizationalUnitIdentifier: ou})
+	assert.NoError(t, err)
+	rawRole, err := proto.Marshal(&m.MSPRole{Role: role})
+	assert.NoError(t, err)
+	idBytes, err := proto.Marshal(&m.SerializedIdemixIdentity{Ou: rawOU, Role: rawRole})
+	assert.NoError(t, err)
+	raw, err := proto.Marshal(&m.SerializedIdentity{Mspid: "idemix", IdBytes: idBytes})
+	assert.NoError(t, err)
+	owner, err := identity.WrapWithType(msp.IdemixIdentity, raw)
+	assert.NoError(t, err)
+	return owner
+}
+
+func TestPredicateMatch(t *testing.T) {
+	p := &Predicate{Attributes: map[string][]string{
+		idemix.AttributeOU:   {"kyc-2", "kyc-3"},
+		idemix.AttributeRole: {"MEMBER"},
+	}}
+	assert.Equal(t, "ou in [kyc-2,kyc-3] and role in [MEMBER]", p.String())
+
+	assert.NoError(t, p.Match(map[string]string{"ou": "kyc-3", "role": "MEMBER"}))
+	// the attributes not in the predicate are ignored
+	assert.NoError(t, p.Match(map[string]string{"ou": "kyc-2", "role": "MEMBER", "eid": "alice"}))
+
+	assert.EqualError(t, p.Match(map[string]string{"ou": "kyc-1", "role": "MEMBER"}), "attribute [ou] has value [kyc-1], expected one of [kyc-2,kyc-3]")
+	assert.EqualError(t, p.Match(map[string]string{"ou": "kyc-2", "role": "ADMIN"}), "attribute [role] has value [ADMIN], expected one of [MEMBER]")
+	assert.EqualError(t, p.Match(map[string]string{"ou": "kyc-2"}), "attribute [role] not certified")
+	assert.EqualError(t, p.Match(nil), "attribute [ou] not certified")
+}
+
+func TestPredicateValidate(t *testing.T) {
+	assert.NoError(t, (&Predicate{Attributes: map[string][]string{"ou": {"kyc-2"}}}).Validate())
+
+	// an invalid predicate matches nothing
+	for predicate, expected := range map[*Predicate]string{
+		{}: "predicate without attributes",
+		{Attributes: map[string][]string{"ou": {}}}:           "no value allowed for attribute [ou]",
+		{Attributes: map[string][]string{"kyc": {"level-2"}}}: "attribute [kyc] not supported, expected one of [ou,role]",
+	} {
+		assert.EqualError(t, predicate.Validate(), expected)
+		assert.EqualError(t, predicate.Match(map[string]string{"ou": "kyc-2", "kyc": "level-2"}), expected)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	p := Predicate{Attributes: map[string][]string{"ou": {"kyc-2"}, "role": {"MEMBER"}}}
+	owner := idemixOwner(t, "kyc-2", m.MSPRole_MEMBER)
+
+	id, err := Wrap(owner, p)
+	assert.NoError(t, err)
+	script, err := Unwrap(id)
+	assert.NoError(t, err)
+	assert.Equal(t, &Script{Owner: owner, Predicate: p}, script)
+
+	// the tokens of an owner not satisfying the predicate would not be spendable
+	_, err = Wrap(idemixOwner(t, "kyc-1", m.MSPRole_MEMBER), p)
+	assert.EqualError(t, err, "owner does not satisfy predicate [ou in [kyc-2] and role in [MEMBER]]: attribute [ou] has value [kyc-1], expected one of [kyc-2]")
+	_, err = Wrap(idemixOwner(t, "kyc-2", m.MSPRole_CLIENT), p)
+	assert.ErrorContains(t, err, "attribute [role] has value [CLIENT], expected one of [MEMBER]")
+
+	// only idemix owners carry certified attributes
+	x509Owner, err := identity.WrapWithType(msp.X509Identity, []byte("alice"))
+	assert.NoError(t, err)
+	_, err = Wrap(x509Owner, p)
+	assert.ErrorContains(t, err, "identity type [x509] does not carry certified attributes, expected [idemix]")
+
+	_, err = Wrap(owner, Predicate{Attributes: map[string][]string{"kyc": {"level-2"}}})
+	assert.ErrorContains(t, err, "invalid script: invalid predicate: attribute [kyc] not supported")
+	_, err = Wrap(nil, p)
+	assert.ErrorContains(t, err, "invalid script: owner not set")
+
+	// a script is unwrapped only from a predicate identity
+	_, err = Unwrap(owner)
+	assert.EqualError(t, err, "invalid owner type [idemix], expected predicate script")
+}
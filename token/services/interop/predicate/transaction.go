@@ -0,0 +1,49 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package predicate
+
+import (
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Transfer appends to the passed transaction a transfer action that spends the passed token, owned by a predicate script,
+// in favour of the passed recipients. The owner in the script must be in the passed wallet.
+// The validators check that the owner carries the certified attributes required by the predicate.
+func Transfer(sp token.ServiceProvider, tx *ttx.Transaction, wallet *token.OwnerWallet, tok *token2.UnspentToken, values []uint64, recipients []view.Identity, opts ...token.TransferOption) error {
+	script, err := Unwrap(tok.Owner)
+	if err != nil {
+		return err
+	}
+	if err := script.Validate(); err != nil {
+		return errors.WithMessagef(err, "invalid predicate script")
+	}
+
+	// the owner signs for the script
+	sigService := tx.TokenService().SigService()
+	signer, err := sigService.GetSigner(script.Owner)
+	if err != nil {
+		return errors.WithMessagef(err, "owner of the predicate script is not in a local wallet")
+	}
+	verifier, err := sigService.OwnerVerifier(script.Owner)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("registering signer for predicate script...")
+	if err := sigService.RegisterSigner(tok.Owner, signer, verifier); err != nil {
+		return err
+	}
+	if err := view2.GetEndpointService(sp).Bind(script.Owner, tok.Owner); err != nil {
+		return err
+	}
+
+	return tx.Transfer(wallet, tok.Type, values, recipients, append(opts, token.WithTokenIDs(tok.Id))...)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package predicate
+
+import (
+	"context"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// SelectOwnerWallet returns the first owner wallet of the passed TMS whose identities satisfy the passed predicate.
+// The certified attributes are the same for all the identities of a wallet, one recipient identity per wallet is inspected.
+// The recipient responds to a request of recipient identity with the returned wallet,
+// the sender wraps the received identity with Wrap.
+func SelectOwnerWallet(tms *token.ManagementService, predicate Predicate) (*token.OwnerWallet, error) {
+	if err := predicate.Validate(); err != nil {
+		return nil, errors.WithMessagef(err, "invalid predicate")
+	}
+	ids, err := tms.WalletManager().OwnerWalletIDs()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get owner wallets")
+	}
+	for _, id := range ids {
+		w := tms.WalletManager().OwnerWallet(id)
+		if w == nil || w.Remote() {
+			continue
+		}
+		recipient, err := w.GetRecipientIdentity()
+		if err != nil {
+			logger.Debugf("skip wallet [%s], failed to get recipient identity [%s]", id, err)
+			continue
+		}
+		attributes, err := Attributes(recipient)
+		if err != nil {
+			logger.Debugf("skip wallet [%s], failed to get attributes [%s]", id, err)
+			continue
+		}
+		if err := predicate.Match(attributes); err != nil {
+			logger.Debugf("skip wallet [%s], [%s]", id, err)
+			continue
+		}
+		return w, nil
+	}
+	return nil, errors.Errorf("no owner wallet satisfies predicate [%s]", &predicate)
+}
+
+// ListTokens returns the unspent tokens, of the passed type if not empty, owned by predicate scripts whose owner is in the passed wallet
+func ListTokens(sp token.ServiceProvider, wallet *token.OwnerWallet, tokenType string) (*token2.UnspentTokens, error) {
+	tms := wallet.TMS()
+	nw := network.GetInstance(sp, tms.Network(), tms.Channel())
+	if nw == nil {
+		return nil, errors.Errorf("cannot load network [%s:%s]", tms.Network(), tms.Channel())
+	}
+	vault, err := nw.TokenVault(tms.Namespace())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get vault for [%s:%s:%s]", tms.Network(), tms.Channel(), tms.Namespace())
+	}
+	it, err := vault.QueryEngine().UnspentTokensIteratorBy(context.TODO(), ownerWallet(wallet), tokenType)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get iterator over unspent tokens")
+	}
+	defer it.Close()
+	var tokens []*token2.UnspentToken
+	for {
+		tok, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get next unspent token")
+		}
+		if tok == nil {
+			return &token2.UnspentTokens{Tokens: tokens}, nil
+		}
+		tokens = append(tokens, tok)
+	}
+}
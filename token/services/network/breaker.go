@@ -0,0 +1,149 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package network
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultBreakerFailureThreshold is the default number of consecutive failures that opens a circuit breaker
+	DefaultBreakerFailureThreshold = 5
+	// DefaultBreakerOpenTimeout is the default time an open circuit breaker waits before letting a probe call through
+	DefaultBreakerOpenTimeout = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned when a call is rejected because the backend it targets is considered degraded
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets all calls through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all calls
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe call through, whose outcome closes or opens the breaker again
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops calling a backend after a number of consecutive failures.
+// Once open, the calls fail fast with ErrCircuitOpen until the open timeout elapses.
+// Then, a single probe call is let through: if it succeeds, the breaker closes, otherwise it opens again.
+type CircuitBreaker struct {
+	name             string
+	failureThreshold int
+	openTimeout      time.Duration
+	now              func() time.Time
+
+	lock      sync.Mutex
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	lastError error
+}
+
+// NewCircuitBreaker returns a new closed circuit breaker with the passed name.
+// Non-positive arguments are replaced by the defaults.
+func NewCircuitBreaker(name string, failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	if failureThreshold <= 0 {
+		failureThreshold = DefaultBreakerFailureThreshold
+	}
+	if openTimeout <= 0 {
+		openTimeout = DefaultBreakerOpenTimeout
+	}
+	return &CircuitBreaker{
+		name:             name,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		now:              time.Now,
+	}
+}
+
+// Name returns the name of this breaker
+func (b *CircuitBreaker) Name() string {
+	return b.name
+}
+
+// State returns the current state of this breaker and the last failure recorded, if any
+func (b *CircuitBreaker) State() (BreakerState, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		return BreakerHalfOpen, b.lastError
+	}
+	return b.state, b.lastError
+}
+
+// Do calls the passed function, unless the breaker is open.
+// Cancellations of the caller's context are not counted as failures.
+func (b *CircuitBreaker) Do(f func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := f()
+	b.record(err)
+	return err
+}
+
+func (b *CircuitBreaker) allow() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return errors.Wrapf(ErrCircuitOpen, "[%s] unavailable since [%s], last error [%v]", b.name, b.openedAt.Format(time.RFC3339), b.lastError)
+		}
+		logger.Infof("circuit breaker [%s]: probing backend", b.name)
+		b.state = BreakerHalfOpen
+		return nil
+	case BreakerHalfOpen:
+		return errors.Wrapf(ErrCircuitOpen, "[%s] unavailable, probe in progress, last error [%v]", b.name, b.lastError)
+	default:
+		return nil
+	}
+}
+
+func (b *CircuitBreaker) record(err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if err == nil || errors.Is(err, context.Canceled) {
+		if b.state != BreakerClosed {
+			logger.Infof("circuit breaker [%s]: backend recovered", b.name)
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	b.lastError = err
+	if b.state == BreakerHalfOpen || b.failures >= b.failureThreshold {
+		if b.state != BreakerOpen {
+			logger.Warnf("circuit breaker [%s]: opening after [%d] consecutive failures, last error [%s]", b.name, b.failures, err)
+		}
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := NewCircuitBreaker("broadcast", 2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	failure := errors.New("orderer unreachable")
+	calls := 0
+	fail := func() error { calls++; return failure }
+	succeed := func() error { calls++; return nil }
+
+	// consecutive failures open the breaker
+	assert.ErrorIs(t, b.Do(fail), failure)
+	assert.NoError(t, b.Do(succeed))
+	assert.ErrorIs(t, b.Do(fail), failure)
+	assert.ErrorIs(t, b.Do(fail), failure)
+	state, lastErr := b.State()
+	assert.Equal(t, BreakerOpen, state)
+	assert.Equal(t, failure, lastErr)
+
+	// open breaker fails fast
+	assert.ErrorIs(t, b.Do(succeed), ErrCircuitOpen)
+	assert.Equal(t, 4, calls)
+
+	// after the timeout, a failed probe opens the breaker again
+	now = now.Add(time.Minute)
+	state, _ = b.State()
+	assert.Equal(t, BreakerHalfOpen, state)
+	assert.ErrorIs(t, b.Do(fail), failure)
+	assert.ErrorIs(t, b.Do(succeed), ErrCircuitOpen)
+
+	// a successful probe closes it
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Do(succeed))
+	state, _ = b.State()
+	assert.Equal(t, BreakerClosed, state)
+	assert.Equal(t, 6, calls)
+}
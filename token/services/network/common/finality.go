@@ -7,12 +7,12 @@ SPDX-License-Identifier: Apache-2.0
 package common
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"fmt"
 	"time"
 
-	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
@@ -112,22 +112,52 @@ func (t *FinalityListener) runOnStatus(ctx context.Context, txID string, status
 		t.logger.Errorf("<message> [%s]: [%s]", txID, err)
 		return fmt.Errorf("<message> [%s]: [%s]", txID, err)
 	}
+	if txStatus == driver.Deleted {
+		span.AddEvent("discard_pending_tokens")
+		if err := t.discardPending(newCtx, txID); err != nil {
+			t.logger.Errorf("failed to discard pending tokens of [%s]: [%s]", txID, err)
+			return err
+		}
+	}
 	t.logger.Debugf("tx status changed for tx [%s]: [%s] done", txID, status)
 	return nil
 }
 
+// discardPending deletes the pending tokens created by the passed transaction,
+// and marks as deleted the transactions that spent them, and those that spent their pending tokens in turn
+func (t *FinalityListener) discardPending(ctx context.Context, txID string) error {
+	spentBy, err := t.tokens.DiscardPending(txID)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to delete pending tokens of [%s]", txID)
+	}
+	for _, child := range spentBy {
+		t.logger.Infof("tx [%s] spends the pending tokens of the deleted tx [%s], delete it", child, txID)
+		if err := t.ttxDB.SetStatus(ctx, child, driver.Deleted, fmt.Sprintf("spends the tokens of deleted transaction [%s]", txID)); err != nil {
+			return errors.WithMessagef(err, "failed to delete tx [%s]", child)
+		}
+		if err := t.discardPending(ctx, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (t *FinalityListener) checkTokenRequest(txID string, request *token.Request, reference []byte) error {
 	trToSign, err := request.MarshalToSign()
 	if err != nil {
 		return errors.Errorf("can't get request hash '%s'", txID)
 	}
-	if base64.StdEncoding.EncodeToString(reference) != hash.Hashable(trToSign).String() {
-		t.logger.Errorf("tx [%s], tr hashes [%s][%s]", txID, base64.StdEncoding.EncodeToString(reference), hash.Hashable(trToSign))
+	trHash, err := request.TokenService.PublicParametersManager().PublicParameters().HashAlgorithm().Hash(trToSign)
+	if err != nil {
+		return errors.WithMessagef(err, "can't get request hash '%s'", txID)
+	}
+	if !bytes.Equal(reference, trHash) {
+		t.logger.Errorf("tx [%s], tr hashes [%s][%s]", txID, base64.StdEncoding.EncodeToString(reference), base64.StdEncoding.EncodeToString(trHash))
 		// no further processing of the tokens of these transactions
 		return errors.Errorf(
 			"token requests do not match, tr hashes [%s][%s]",
 			base64.StdEncoding.EncodeToString(reference),
-			hash.Hashable(trToSign),
+			base64.StdEncoding.EncodeToString(trHash),
 		)
 	}
 	return nil
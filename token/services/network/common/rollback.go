@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	driver2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
+	"github.com/pkg/errors"
+)
+
+type statusDB interface {
+	SetStatus(ctx context.Context, txID string, status driver.TxStatus, message string) error
+}
+
+// BlockReverter reverses the token deltas of the transactions of a rolled back block, like tokens.Tokens
+type BlockReverter interface {
+	RollbackBlock(ctx context.Context, tmsID token.TMSID, blockNum uint64, txIDs []string) error
+}
+
+// ResubscribeFunc registers again the finality listener of a transaction set back to pending
+type ResubscribeFunc = func(txID string) error
+
+// RollbackListener sets the transactions of the blocks rolled back by the ledger back to pending in the passed transaction db,
+// so that they are processed again when committed in a new block.
+// If a reverter is passed, the token deltas of the transactions are reversed first.
+// The token db of a TMS is shared, therefore, a single listener per TMS is given the reverter.
+type RollbackListener struct {
+	logger      logging.Logger
+	tmsID       token.TMSID
+	ttxDB       statusDB
+	reverter    BlockReverter
+	resubscribe ResubscribeFunc
+}
+
+func NewRollbackListener(logger logging.Logger, tmsID token.TMSID, ttxDB statusDB, reverter BlockReverter, resubscribe ResubscribeFunc) *RollbackListener {
+	return &RollbackListener{
+		logger:      logger,
+		tmsID:       tmsID,
+		ttxDB:       ttxDB,
+		reverter:    reverter,
+		resubscribe: resubscribe,
+	}
+}
+
+func (r *RollbackListener) OnRollback(ctx context.Context, blockNum uint64, txIDs []string) error {
+	r.logger.Infof("block [%d] rolled back, resetting [%d] transactions", blockNum, len(txIDs))
+	if r.reverter != nil {
+		if err := r.reverter.RollbackBlock(ctx, r.tmsID, blockNum, txIDs); err != nil {
+			return errors.WithMessagef(err, "failed to roll back block [%d]", blockNum)
+		}
+	}
+	message := fmt.Sprintf("rolled back with block [%d]", blockNum)
+	for _, txID := range txIDs {
+		if err := r.ttxDB.SetStatus(ctx, txID, driver.Pending, message); err != nil {
+			return errors.WithMessagef(err, "failed to set status of [%s] rolled back with block [%d]", txID, blockNum)
+		}
+		if err := r.resubscribe(txID); err != nil {
+			return errors.WithMessagef(err, "failed to resubscribe to the finality of [%s] rolled back with block [%d]", txID, blockNum)
+		}
+	}
+	return nil
+}
+
+// RollbackListeners keeps the rollback listeners of a network by namespace.
+// It lets a network driver implement driver.RollbackNotifier.
+type RollbackListeners struct {
+	mutex     sync.RWMutex
+	listeners map[string][]driver2.RollbackListener
+}
+
+func NewRollbackListeners() *RollbackListeners {
+	return &RollbackListeners{listeners: map[string][]driver2.RollbackListener{}}
+}
+
+// AddRollbackListener registers a listener for the blocks rolled back that contain transactions of the passed namespace
+func (l *RollbackListeners) AddRollbackListener(namespace string, listener driver2.RollbackListener) error {
+	if listener == nil {
+		return errors.New("nil rollback listener")
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.listeners[namespace] = append(l.listeners[namespace], listener)
+	return nil
+}
+
+// NotifyRollback notifies the listeners of the passed namespace, in the order they were registered,
+// that the passed block was rolled back. It stops at the first failing listener.
+func (l *RollbackListeners) NotifyRollback(ctx context.Context, namespace string, blockNum uint64, txIDs []string) error {
+	l.mutex.RLock()
+	listeners := append([]driver2.RollbackListener{}, l.listeners[namespace]...)
+	l.mutex.RUnlock()
+	for _, listener := range listeners {
+		if err := listener.OnRollback(ctx, blockNum, txIDs); err != nil {
+			return errors.WithMessagef(err, "failed to notify rollback of block [%d] in namespace [%s]", blockNum, namespace)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type statuses map[string]driver.TxStatus
+
+func (s statuses) SetStatus(_ context.Context, txID string, status driver.TxStatus, _ string) error {
+	s[txID] = status
+	return nil
+}
+
+type reverter struct {
+	tmsID  token.TMSID
+	blocks []uint64
+	err    error
+}
+
+func (r *reverter) RollbackBlock(_ context.Context, tmsID token.TMSID, blockNum uint64, _ []string) error {
+	r.tmsID = tmsID
+	r.blocks = append(r.blocks, blockNum)
+	return r.err
+}
+
+func TestRollbackListener(t *testing.T) {
+	logger := logging.MustGetLogger("test")
+	tmsID := token.TMSID{Network: "n", Namespace: "ns"}
+	db := statuses{"tx1": driver.Confirmed, "tx2": driver.Confirmed}
+	var resubscribed []string
+	resubscribe := func(txID string) error {
+		resubscribed = append(resubscribed, txID)
+		return nil
+	}
+
+	// the token deltas are reversed, then the transactions are pending again
+	r := &reverter{}
+	l := NewRollbackListener(logger, tmsID, db, r, resubscribe)
+	assert.NoError(t, l.OnRollback(context.TODO(), 10, []string{"tx1", "tx2"}))
+	assert.Equal(t, tmsID, r.tmsID)
+	assert.Equal(t, []uint64{10}, r.blocks)
+	assert.Equal(t, statuses{"tx1": driver.Pending, "tx2": driver.Pending}, db)
+	assert.Equal(t, []string{"tx1", "tx2"}, resubscribed)
+
+	// without a reverter, the statuses only are reset
+	db["tx1"] = driver.Confirmed
+	resubscribed = nil
+	l = NewRollbackListener(logger, tmsID, db, nil, resubscribe)
+	assert.NoError(t, l.OnRollback(context.TODO(), 11, []string{"tx1"}))
+	assert.Equal(t, driver.Pending, db["tx1"])
+	assert.Equal(t, []string{"tx1"}, resubscribed)
+
+	// the statuses are not reset if the deltas cannot be reversed
+	db["tx1"] = driver.Confirmed
+	r.err = errors.New("boom")
+	l = NewRollbackListener(logger, tmsID, db, r, resubscribe)
+	assert.Error(t, l.OnRollback(context.TODO(), 12, []string{"tx1"}))
+	assert.Equal(t, driver.Confirmed, db["tx1"])
+
+	// a failed resubscription is reported
+	r.err = nil
+	l = NewRollbackListener(logger, tmsID, db, r, func(string) error { return errors.New("boom") })
+	assert.Error(t, l.OnRollback(context.TODO(), 13, []string{"tx1"}))
+}
+
+type recordingListener struct {
+	name   string
+	calls  *[]string
+	failed bool
+}
+
+func (l *recordingListener) OnRollback(context.Context, uint64, []string) error {
+	*l.calls = append(*l.calls, l.name)
+	if l.failed {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func TestRollbackListeners(t *testing.T) {
+	var calls []string
+	listeners := NewRollbackListeners()
+	assert.Error(t, listeners.AddRollbackListener("ns", nil))
+	assert.NoError(t, listeners.AddRollbackListener("ns", &recordingListener{name: "ttx", calls: &calls}))
+	assert.NoError(t, listeners.AddRollbackListener("ns", &recordingListener{name: "auditor", calls: &calls}))
+	assert.NoError(t, listeners.AddRollbackListener("other", &recordingListener{name: "other", calls: &calls}))
+
+	// the listeners of the namespace only are notified, in order
+	assert.NoError(t, listeners.NotifyRollback(context.TODO(), "ns", 1, []string{"tx1"}))
+	assert.Equal(t, []string{"ttx", "auditor"}, calls)
+	calls = nil
+	assert.NoError(t, listeners.NotifyRollback(context.TODO(), "unknown", 1, []string{"tx1"}))
+	assert.Empty(t, calls)
+
+	// the notification stops at the first failure
+	assert.NoError(t, listeners.AddRollbackListener("failing", &recordingListener{name: "first", calls: &calls, failed: true}))
+	assert.NoError(t, listeners.AddRollbackListener("failing", &recordingListener{name: "second", calls: &calls}))
+	assert.Error(t, listeners.NotifyRollback(context.TODO(), "failing", 1, []string{"tx1"}))
+	assert.Equal(t, []string{"first"}, calls)
+}
@@ -30,6 +30,7 @@ const (
 	InputSerialNumberPrefix      = "sn"
 	IssueActionMetadataPrefix    = "iam"
 	TransferActionMetadataPrefix = "tam"
+	SerialsKeyPrefix             = "ser"
 )
 
 type Translator struct {
@@ -88,6 +89,11 @@ func (t *Translator) CreateTransferActionMetadataKey(key string) (translator.Key
 	return createCompositeKey(TransferActionMetadataPrefix, []string{key})
 }
 
+func (t *Translator) CreateSerialsKey(class string) (translator.Key, error) {
+	h := sha256.Sum256([]byte(class))
+	return createCompositeKey(SerialsKeyPrefix, []string{hex.EncodeToString(h[:])})
+}
+
 // createCompositeKey and its related functions and consts copied from core/chaincode/shim/chaincode.go
 func createCompositeKey(objectType string, attributes []string) (translator.Key, error) {
 	if err := validateCompositeKeyAttribute(objectType); err != nil {
@@ -25,6 +25,14 @@ type IssueAction interface {
 	IsGraphHiding() bool
 }
 
+// SerialsIssueAction is implemented by the issue actions whose outputs can carry serial ranges in the clear.
+// The translator records on the ledger the serial ranges issued for each class,
+// and fails if the ranges of the action overlap those already issued.
+type SerialsIssueAction interface {
+	// GetIssuedSerials returns the serial ranges carried by the outputs of the action
+	GetIssuedSerials() []token.SerialRange
+}
+
 //go:generate counterfeiter -o mock/transfer_action.go -fake-name TransferAction . TransferAction
 
 // TransferAction is the action used to transfer tokens
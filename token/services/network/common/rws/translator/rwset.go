@@ -42,6 +42,8 @@ type KeyTranslator interface {
 	CreateTransferActionMetadataKey(subkey string) (Key, error)
 	// GetTransferMetadataSubKey returns the subkey in the given transfer action metadata key
 	GetTransferMetadataSubKey(k string) (Key, error)
+	// CreateSerialsKey creates the key for the serial ranges issued so far of the passed class
+	CreateSerialsKey(class string) (Key, error)
 }
 
 // RWSet interface, used to read from, and write to, a rwset.
@@ -199,6 +201,14 @@ func (h *HashedKeyTranslator) CreateTransferActionMetadataKey(key Key) (Key, err
 	return h.hash(8, k)
 }
 
+func (h *HashedKeyTranslator) CreateSerialsKey(class string) (Key, error) {
+	k, err := h.KT.CreateSerialsKey(class)
+	if err != nil {
+		return "", err
+	}
+	return h.hash(9, k)
+}
+
 func (h *HashedKeyTranslator) hash(code byte, k string) (Key, error) {
 	hf := sha256.New()
 	hf.Write([]byte{code})
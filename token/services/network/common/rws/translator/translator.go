@@ -7,8 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package translator
 
 import (
-	"crypto/sha256"
+	"encoding/json"
 
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
@@ -24,9 +25,14 @@ type Translator struct {
 	RWSet         ExRWSet
 	KeyTranslator KeyTranslator
 	TxID          string
+	// HashAlgorithm hashes the token requests and the public parameters committed, SHA256 if empty.
+	// It must be the one selected by the public parameters.
+	HashAlgorithm driver.HashAlgorithm
 	// SpentIDs the spent IDs added so far
 	SpentIDs []string
 	counter  uint64
+	// serials are the serial ranges issued for each class, including those of the action being written
+	serials map[string][]token.SerialRange
 }
 
 func New(txID string, rws ExRWSet, keyTranslator KeyTranslator) *Translator {
@@ -69,15 +75,10 @@ func (w *Translator) CommitTokenRequest(raw []byte, storeHash bool) ([]byte, err
 	}
 	var h []byte
 	if storeHash {
-		hash := sha256.New()
-		n, err := hash.Write(raw)
-		if n != len(raw) {
-			return nil, errors.Errorf("failed to write token request, hash failure '%s'", w.TxID)
-		}
+		raw, err = w.HashAlgorithm.Hash(raw)
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to write token request, hash failure '%s'", w.TxID)
+			return nil, errors.WithMessagef(err, "failed to write token request, hash failure '%s'", w.TxID)
 		}
-		raw = hash.Sum(nil)
 		h = raw
 	}
 	err = w.RWSet.SetState(key, raw)
@@ -205,10 +206,54 @@ func (w *Translator) checkAction(tokenAction interface{}) error {
 
 func (w *Translator) checkIssue(issue IssueAction) error {
 	// check outputs
-	// as long as the transaction id is unique, there is nothing to check here
+	// as long as the transaction id is unique, there is nothing to check here, but for the serials
+	sa, ok := issue.(SerialsIssueAction)
+	if !ok {
+		return nil
+	}
+	issued := map[string][]token.SerialRange{}
+	for _, r := range sa.GetIssuedSerials() {
+		issued[r.Class] = append(issued[r.Class], r)
+	}
+	for class, ranges := range issued {
+		current, err := w.issuedSerials(class)
+		if err != nil {
+			return err
+		}
+		merged, err := token.MergeSerialRanges(append(ranges, current...))
+		if err != nil {
+			return errors.WithMessagef(err, "invalid issue: serials of class [%s] already issued", class)
+		}
+		w.serials[class] = merged
+	}
 	return nil
 }
 
+// issuedSerials returns the serial ranges of the passed class issued so far
+func (w *Translator) issuedSerials(class string) ([]token.SerialRange, error) {
+	if w.serials == nil {
+		w.serials = map[string][]token.SerialRange{}
+	}
+	if ranges, ok := w.serials[class]; ok {
+		return ranges, nil
+	}
+	key, err := w.KeyTranslator.CreateSerialsKey(class)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating serials key")
+	}
+	raw, err := w.RWSet.GetState(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading serials of class [%s]", class)
+	}
+	var ranges []token.SerialRange
+	if len(raw) != 0 {
+		if err := json.Unmarshal(raw, &ranges); err != nil {
+			return nil, errors.Wrapf(err, "failed unmarshalling serials of class [%s]", class)
+		}
+	}
+	return ranges, nil
+}
+
 func (w *Translator) checkTransfer(t TransferAction) error {
 	// check inputs
 
@@ -286,15 +331,10 @@ func (w *Translator) commitSetupAction(setup SetupAction) error {
 	if err != nil {
 		return err
 	}
-	hash := sha256.New()
-	n, err := hash.Write(raw)
-	if n != len(raw) {
-		panic("hash failure")
-	}
+	digest, err := w.HashAlgorithm.Hash(raw)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	digest := hash.Sum(nil)
 
 	err = w.RWSet.SetState(setupHashKey, digest)
 	if err != nil {
@@ -350,6 +390,28 @@ func (w *Translator) commitIssueAction(issueAction IssueAction) error {
 		}
 	}
 
+	// store the serials issued so far, checked by checkIssue
+	if sa, ok := issueAction.(SerialsIssueAction); ok {
+		classes := map[string]struct{}{}
+		for _, r := range sa.GetIssuedSerials() {
+			if _, ok := classes[r.Class]; ok {
+				continue
+			}
+			classes[r.Class] = struct{}{}
+			k, err := w.KeyTranslator.CreateSerialsKey(r.Class)
+			if err != nil {
+				return errors.Wrapf(err, "failed creating serials key")
+			}
+			raw, err := json.Marshal(w.serials[r.Class])
+			if err != nil {
+				return errors.Wrapf(err, "failed marshalling serials of class [%s]", r.Class)
+			}
+			if err := w.RWSet.SetState(k, raw); err != nil {
+				return err
+			}
+		}
+	}
+
 	w.counter = w.counter + uint64(len(outputs))
 	return nil
 }
@@ -97,6 +97,60 @@ var _ = Describe("Translator", func() {
 		})
 	})
 
+	Describe("Issue of serials", func() {
+		var (
+			serialsIssue *serialsIssueAction
+			serialsKey   string
+		)
+		BeforeEach(func() {
+			fakeissue.GetSerializedOutputsReturns([][]byte{[]byte("output-1")}, nil)
+			fakeissue.NumOutputsReturns(1)
+			serialsIssue = &serialsIssueAction{
+				IssueAction: fakeissue,
+				serials:     []token.SerialRange{{Class: "TICKET", From: 11, To: 20}},
+			}
+			var err error
+			serialsKey, err = keyTranslator.CreateSerialsKey("TICKET")
+			Expect(err).NotTo(HaveOccurred())
+		})
+		When("the serials were not issued", func() {
+			BeforeEach(func() {
+				fakeRWSet.GetStateReturns([]byte(`[{"Class":"TICKET","From":1,"To":10},{"Class":"TICKET","From":31,"To":40}]`), nil)
+			})
+			It("records the merged serials", func() {
+				Expect(writer.Write(serialsIssue)).To(Succeed())
+
+				_, key := fakeRWSet.GetStateArgsForCall(0)
+				Expect(key).To(Equal(serialsKey))
+				Expect(fakeRWSet.SetStateCallCount()).To(Equal(3))
+				_, key, value := fakeRWSet.SetStateArgsForCall(2)
+				Expect(key).To(Equal(serialsKey))
+				Expect(value).To(MatchJSON(`[{"Class":"TICKET","From":1,"To":20},{"Class":"TICKET","From":31,"To":40}]`))
+			})
+		})
+		When("the serials were already issued", func() {
+			BeforeEach(func() {
+				fakeRWSet.GetStateReturns([]byte(`[{"Class":"TICKET","From":15,"To":30}]`), nil)
+			})
+			It("issue fails", func() {
+				err := writer.Write(serialsIssue)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("serials of class [TICKET] already issued"))
+				Expect(fakeRWSet.SetStateCallCount()).To(Equal(0))
+			})
+		})
+		When("a previous action of the transaction issued the serials", func() {
+			It("issue fails", func() {
+				Expect(writer.Write(serialsIssue)).To(Succeed())
+				err := writer.Write(serialsIssue)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("serial ranges [TICKET#11-20] and [TICKET#11-20] overlap"))
+				// the ledger is read once per class
+				Expect(fakeRWSet.GetStateCallCount()).To(Equal(1))
+			})
+		})
+	})
+
 	Describe("Transfer: transaction graph revealed", func() {
 		BeforeEach(func() {
 			faketransfer.SerializeOutputAtReturnsOnCall(0, []byte("output-1"), nil)
@@ -318,3 +372,13 @@ var _ = Describe("Translator", func() {
 		})
 	})
 })
+
+// serialsIssueAction is an issue action that carries serial ranges
+type serialsIssueAction struct {
+	*mock.IssueAction
+	serials []token.SerialRange
+}
+
+func (a *serialsIssueAction) GetIssuedSerials() []token.SerialRange {
+	return a.serials
+}
@@ -0,0 +1,90 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+var logger = logging.MustGetLogger("token-sdk.network.common")
+
+// DefaultTokenQueryBatchSize is the default maximum number of token ids sent to the ledger in a single query
+const DefaultTokenQueryBatchSize = 100
+
+type batchedTokenQueryExecutorProvider struct {
+	provider  driver.TokenQueryExecutorProvider
+	batchSize int
+}
+
+// NewBatchedTokenQueryExecutorProvider returns a provider of executors that decorate those returned by the passed provider
+// with batching. See NewBatchedTokenQueryExecutor.
+func NewBatchedTokenQueryExecutorProvider(provider driver.TokenQueryExecutorProvider, batchSize int) *batchedTokenQueryExecutorProvider {
+	return &batchedTokenQueryExecutorProvider{provider: provider, batchSize: batchSize}
+}
+
+func (p *batchedTokenQueryExecutorProvider) GetExecutor(network, channel string) (driver.TokenQueryExecutor, error) {
+	executor, err := p.provider.GetExecutor(network, channel)
+	if err != nil {
+		return nil, err
+	}
+	return NewBatchedTokenQueryExecutor(executor, p.batchSize), nil
+}
+
+// BatchedTokenQueryExecutor queries the ledger for the content of the passed tokens in batches of bounded size.
+// A token id repeated in the same query is sent to the ledger once.
+// Nothing is kept across queries: a spent token is deleted from the ledger,
+// and callers, such as the ownership verifier, rely on the query not returning it anymore.
+type BatchedTokenQueryExecutor struct {
+	executor  driver.TokenQueryExecutor
+	batchSize int
+}
+
+// NewBatchedTokenQueryExecutor returns a new BatchedTokenQueryExecutor for the passed executor.
+// A non-positive batchSize is replaced by DefaultTokenQueryBatchSize.
+func NewBatchedTokenQueryExecutor(executor driver.TokenQueryExecutor, batchSize int) *BatchedTokenQueryExecutor {
+	if batchSize <= 0 {
+		batchSize = DefaultTokenQueryBatchSize
+	}
+	return &BatchedTokenQueryExecutor{executor: executor, batchSize: batchSize}
+}
+
+// QueryTokens returns the ledger content of the passed token ids, in the same order
+func (e *BatchedTokenQueryExecutor) QueryTokens(context view.Context, namespace string, IDs []*token.ID) ([][]byte, error) {
+	// positions of each distinct id in the passed ids
+	positions := make(map[token.ID][]int, len(IDs))
+	var distinct []*token.ID
+	for i, id := range IDs {
+		if _, ok := positions[*id]; !ok {
+			distinct = append(distinct, id)
+		}
+		positions[*id] = append(positions[*id], i)
+	}
+	logger.Debugf("query tokens, [%d] ids, [%d] distinct", len(IDs), len(distinct))
+
+	res := make([][]byte, len(IDs))
+	for start := 0; start < len(distinct); start += e.batchSize {
+		end := min(start+e.batchSize, len(distinct))
+		batch := distinct[start:end]
+		contents, err := e.executor.QueryTokens(context, namespace, batch)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed querying batch [%d:%d] of tokens", start, end)
+		}
+		if len(contents) != len(batch) {
+			return nil, errors.Errorf("expected [%d] tokens from the ledger, got [%d]", len(batch), len(contents))
+		}
+		for j, content := range contents {
+			for _, pos := range positions[*batch[j]] {
+				res[pos] = content
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingExecutor answers with the content of the tokens not spent, and counts the queries
+type countingExecutor struct {
+	calls   int
+	queried int
+	spent   map[token.ID]bool
+}
+
+func (e *countingExecutor) QueryTokens(_ view.Context, _ string, IDs []*token.ID) ([][]byte, error) {
+	e.calls++
+	e.queried += len(IDs)
+	res := make([][]byte, len(IDs))
+	for i, id := range IDs {
+		if id.Index == 99 || e.spent[*id] {
+			continue
+		}
+		res[i] = []byte(fmt.Sprintf("content_%s", id))
+	}
+	return res, nil
+}
+
+func TestBatchedTokenQueryExecutor(t *testing.T) {
+	executor := &countingExecutor{spent: map[token.ID]bool{}}
+	c := NewBatchedTokenQueryExecutor(executor, 2)
+
+	// the ids are queried in batches, a repeated id is queried once
+	ids := []*token.ID{{TxId: "a", Index: 0}, {TxId: "a", Index: 1}, {TxId: "b", Index: 0}, {TxId: "c", Index: 99}, {TxId: "a", Index: 0}}
+	res, err := c.QueryTokens(nil, "ns", ids)
+	assert.NoError(t, err)
+	assert.Len(t, res, 5)
+	for i, id := range ids[:3] {
+		assert.Equal(t, []byte(fmt.Sprintf("content_%s", id)), res[i])
+	}
+	assert.Nil(t, res[3])
+	assert.Equal(t, []byte("content_[a:0]"), res[4])
+	assert.Equal(t, 2, executor.calls)
+	assert.Equal(t, 4, executor.queried)
+
+	// nothing is kept across queries, a spent token is not returned anymore
+	executor.spent[token.ID{TxId: "a", Index: 0}] = true
+	res, err = c.QueryTokens(nil, "ns", []*token.ID{{TxId: "a", Index: 0}, {TxId: "b", Index: 0}})
+	assert.NoError(t, err)
+	assert.Nil(t, res[0])
+	assert.Equal(t, []byte("content_[b:0]"), res[1])
+	assert.Equal(t, 3, executor.calls)
+	assert.Equal(t, 6, executor.queried)
+}
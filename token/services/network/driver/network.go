@@ -10,11 +10,14 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token"
+	driver2 "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
 )
 
 // FinalityListener is the interface that must be implemented to receive transaction status change notifications
@@ -23,6 +26,36 @@ type FinalityListener interface {
 	OnStatus(ctx context.Context, txID string, status int, message string, tokenRequestHash []byte)
 }
 
+// RollbackListener is the interface that must be implemented to receive the notifications of the blocks rolled back by the ledger
+type RollbackListener interface {
+	// OnRollback is called when the ledger rolls back a committed block.
+	// The transaction ids are those of the block, in the order they appear in it.
+	OnRollback(ctx context.Context, blockNum uint64, txIDs []string) error
+}
+
+// RollbackNotifier is implemented by the networks whose ledger can roll back committed blocks.
+// Fabric finality is final, therefore, its driver does not implement it.
+type RollbackNotifier interface {
+	// AddRollbackListener registers a listener for the blocks rolled back that contain transactions of the passed namespace
+	AddRollbackListener(namespace string, listener RollbackListener) error
+}
+
+// ComponentHealth is the health of a component of the network backend, such as the peers or the ordering service
+type ComponentHealth struct {
+	// Name identifies the component
+	Name string `json:"name"`
+	// Healthy is true if the component is reachable and working
+	Healthy bool `json:"healthy"`
+	// Error describes why the component is not healthy, if so
+	Error string `json:"error,omitempty"`
+}
+
+// HealthChecker is implemented by the networks that can probe their backend
+type HealthChecker interface {
+	// Health probes the backend and returns the health of its components
+	Health(ctx context.Context) ([]ComponentHealth, error)
+}
+
 type TransientMap = map[string][]byte
 
 type TxID struct {
@@ -34,6 +67,56 @@ func (t *TxID) String() string {
 	return fmt.Sprintf("[%s:%s]", base64.StdEncoding.EncodeToString(t.Nonce), base64.StdEncoding.EncodeToString(t.Creator))
 }
 
+// ErrNamespaceInfoNotSupported is returned by Network.NamespaceInfo when the token namespace cannot be queried for its info
+var ErrNamespaceInfoNotSupported = errors.New("namespace info queries not supported")
+
+// UnknownFunctionMessage returns the error message of a token chaincode invoked with a function it does not implement
+func UnknownFunctionMessage(function string) string {
+	return fmt.Sprintf("function [%s] not recognized", function)
+}
+
+// NamespaceInfo describes the token namespace deployed on the network
+type NamespaceInfo struct {
+	// Version is the version of the deployed token chaincode, if known
+	Version string `json:"version"`
+	// PublicParamsIdentifiers are the identifiers of the public parameters the namespace supports
+	PublicParamsIdentifiers []string `json:"public_params_identifiers"`
+	// Capabilities are the optional behaviors the namespace supports, empty if unknown
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// HasCapability returns true if the namespace advertises the passed capability
+func (i *NamespaceInfo) HasCapability(capability string) bool {
+	return slices.Contains(i.Capabilities, capability)
+}
+
+// NamespaceInfoFromPublicParams returns the namespace info derivable from the passed public parameters, as stored on the ledger.
+// The namespace version is left unknown.
+func NamespaceInfoFromPublicParams(raw []byte) (*NamespaceInfo, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("empty public parameters")
+	}
+	pp := &driver2.SerializedPublicParameters{}
+	if err := pp.Deserialize(raw); err != nil {
+		return nil, errors.Wrapf(err, "failed deserializing public parameters")
+	}
+	return &NamespaceInfo{PublicParamsIdentifiers: []string{pp.Identifier}}, nil
+}
+
+// CheckCompatibility returns an error if the namespace does not support public parameters with the passed identifier.
+// If the namespace does not advertise the supported public parameters, compatibility cannot be assessed and nil is returned.
+func (i *NamespaceInfo) CheckCompatibility(ppIdentifier string) error {
+	if len(i.PublicParamsIdentifiers) == 0 {
+		return nil
+	}
+	for _, id := range i.PublicParamsIdentifiers {
+		if id == ppIdentifier {
+			return nil
+		}
+	}
+	return errors.Errorf("public parameters [%s] not supported by the namespace version [%s], supported [%v]", ppIdentifier, i.Version, i.PublicParamsIdentifiers)
+}
+
 // Network models a backend that stores tokens
 type Network interface {
 	// Name returns the name of the network
@@ -68,6 +151,11 @@ type Network interface {
 	// If namespace is not supported, the argument is ignored.
 	FetchPublicParameters(namespace string) ([]byte, error)
 
+	// NamespaceInfo returns the version of the token namespace deployed on the network and the public parameters it supports.
+	// It returns an error wrapping ErrNamespaceInfoNotSupported if the namespace does not support the query.
+	// If namespace is not supported, the argument is ignored.
+	NamespaceInfo(namespace string) (*NamespaceInfo, error)
+
 	// QueryTokens retrieves the token content for the passed token ids
 	QueryTokens(context view.Context, namespace string, IDs []*token.ID) ([][]byte, error)
 
@@ -78,7 +78,7 @@ func NewGenericDriver(
 		tracerProvider,
 		identityProvider,
 		NewChaincodePublicParamsFetcher(viewManager),
-		NewTokenExecutorProvider(),
+		common.NewBatchedTokenQueryExecutorProvider(NewTokenExecutorProvider(), common.DefaultTokenQueryBatchSize),
 		NewSpentTokenExecutorProvider(keyTranslator),
 		keyTranslator,
 		NewCommitterBasedFLMProvider(fnsProvider, tracerProvider, keyTranslator),
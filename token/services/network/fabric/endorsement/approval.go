@@ -16,10 +16,12 @@ import (
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
 	driver2 "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/compression"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/common/rws/translator"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/hyperledger/fabric/protoutil"
 	"github.com/pkg/errors"
 )
 
@@ -58,7 +60,11 @@ func (r *RequestApprovalView) Call(context view.Context) (interface{}, error) {
 	if err := tx.SetTransientState("tmsID", tms.ID()); err != nil {
 		return nil, errors.WithMessagef(err, "failed to set TMS ID transient")
 	}
-	if err := tx.SetTransient("token_request", r.RequestRaw); err != nil {
+	requestRaw, err := compressRequest(context, tms.ID(), r.RequestRaw)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.SetTransient("token_request", requestRaw); err != nil {
 		return nil, errors.WithMessagef(err, "failed to set token request transient")
 	}
 	if len(r.RequestAnchor) != 0 {
@@ -99,7 +105,8 @@ type Translator interface {
 	Write(action any) error
 }
 
-type TranslatorProviderFunc = func(txID string, namespace string, rws *fabric2.RWSet) (Translator, error)
+// TranslatorProviderFunc returns the translator of the passed transaction, hashing with the passed algorithm
+type TranslatorProviderFunc = func(txID string, namespace string, rws *fabric2.RWSet, hashAlgorithm driver2.HashAlgorithm) (Translator, error)
 
 type RequestApprovalResponderView struct {
 	keyTranslator translator.KeyTranslator
@@ -135,6 +142,11 @@ func (r *RequestApprovalResponderView) Call(context view.Context) (interface{},
 	if len(requestRaw) == 0 {
 		return nil, errors.Errorf("failed to get token request from transient [%s], it is empty", tx.ID())
 	}
+	// the requester might have compressed the token request
+	requestRaw, err = compression.Decompress(requestRaw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to decompress token request [%s]", tx.ID())
+	}
 	requestAnchor := string(tx.GetTransient("RequestAnchor"))
 	if len(requestAnchor) == 0 {
 		requestAnchor = tx.ID()
@@ -203,7 +215,7 @@ func (r *RequestApprovalResponderView) translate(
 ) error {
 	// prepare the rws as usual
 	txID := tx.ID()
-	w, err := r.getTranslator(txID, tms.Namespace(), rws)
+	w, err := r.getTranslator(txID, tms.Namespace(), rws, tms.PublicParametersManager().PublicParameters().HashAlgorithm())
 	if err != nil {
 		return errors.Wrapf(err, "failed to get translator for tx [%s]", tx.ID())
 	}
@@ -237,8 +249,12 @@ func (r *RequestApprovalResponderView) validate(
 	if err != nil {
 		return nil, nil, errors.WithMessagef(err, "failed to get validator [%s:%s]", tms.Network(), tms.Channel())
 	}
+	timestamp, err := txTimestamp(tx)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed to get timestamp of [%s]", tx.ID())
+	}
 	logger.Debugf("Unmarshal and verify with metadata for TX [%s]", tx.ID())
-	actions, meta, err := validator.UnmarshallAndVerifyWithMetadata(context.Context(), token2.NewLedgerFromGetter(getState), anchor, requestRaw)
+	actions, meta, err := validator.UnmarshallAndVerifyWithMetadata(driver2.WithTxTimestamp(context.Context(), timestamp), token2.NewLedgerFromGetter(getState), anchor, requestRaw)
 	if err != nil {
 		return nil, nil, errors.WithMessagef(err, "failed to verify token request for [%s]", tx.ID())
 	}
@@ -297,3 +313,20 @@ func (rwset *RWSWrapper) GetState(namespace string, key string) ([]byte, error)
 func (rwset *RWSWrapper) DeleteState(namespace string, key string) error {
 	return rwset.Stub.DeleteState(namespace, key)
 }
+
+// txTimestamp returns the timestamp the creator of the passed transaction set in the header of its proposal,
+// the same for all the endorsers
+func txTimestamp(tx *endorser.Transaction) (time.Time, error) {
+	header, err := protoutil.UnmarshalHeader(tx.Transaction.Proposal().Header())
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to unmarshal proposal header")
+	}
+	channelHeader, err := protoutil.UnmarshalChannelHeader(header.ChannelHeader)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to unmarshal channel header")
+	}
+	if channelHeader.Timestamp == nil {
+		return time.Time{}, errors.New("no timestamp in channel header")
+	}
+	return channelHeader.Timestamp.AsTime(), nil
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/services/chaincode"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/compression"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
+	"github.com/pkg/errors"
 )
 
 const InvokeFunction = "invoke"
@@ -25,6 +27,10 @@ func NewChaincodeEndorsementService(tmsID token2.TMSID) *ChaincodeEndorsementSer
 }
 
 func (e *ChaincodeEndorsementService) Endorse(context view.Context, requestRaw []byte, signer view.Identity, txID driver.TxID) (driver.Envelope, error) {
+	requestRaw, err := compressRequest(context, e.TMSID, requestRaw)
+	if err != nil {
+		return nil, err
+	}
 	env, err := chaincode.NewEndorseView(
 		e.TMSID.Namespace,
 		InvokeFunction,
@@ -47,3 +53,19 @@ func (e *ChaincodeEndorsementService) Endorse(context view.Context, requestRaw [
 	}
 	return env, nil
 }
+
+// compressRequest compresses the passed token request, if the compression of the token requests is on for the passed TMS,
+// and the token namespace advertises it can decompress them, see compression.Service.
+// The chaincode and the endorsers decompress it transparently.
+func compressRequest(sp token2.ServiceProvider, tmsID token2.TMSID, requestRaw []byte) ([]byte, error) {
+	c, err := compression.GetService(sp)
+	if err != nil {
+		logger.Debugf("compression not available, token request sent uncompressed: [%s]", err)
+		return requestRaw, nil
+	}
+	compressed, err := c.CompressRequest(tmsID, requestRaw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to compress token request")
+	}
+	return compressed, nil
+}
@@ -15,6 +15,7 @@ import (
 	driver2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/driver"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token"
+	driver3 "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/common/rws/translator"
@@ -96,12 +97,14 @@ func (l *loader) load(tmsID token2.TMSID) (Service, error) {
 		l.viewManager,
 		l.identityProvider,
 		l.keyTranslator,
-		func(txID string, namespace string, rws *fabric.RWSet) (Translator, error) {
-			return translator.New(
+		func(txID string, namespace string, rws *fabric.RWSet, hashAlgorithm driver3.HashAlgorithm) (Translator, error) {
+			w := translator.New(
 				txID,
 				translator.NewRWSetWrapper(&RWSWrapper{Stub: rws}, namespace, txID),
 				l.keyTranslator,
-			), nil
+			)
+			w.HashAlgorithm = hashAlgorithm
+			return w, nil
 		},
 	)
 }
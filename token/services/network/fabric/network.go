@@ -9,12 +9,14 @@ package fabric
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/common/utils/lazy"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/services/chaincode"
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracing"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
@@ -38,6 +40,7 @@ const (
 	QueryPublicParamsFunction = "queryPublicParams"
 	QueryTokensFunctions      = "queryTokens"
 	AreTokensSpent            = "areTokensSpent"
+	QueryNamespaceInfo        = "queryNamespaceInfo"
 	maxRetries                = 3
 	retryWaitDuration         = 1 * time.Second
 )
@@ -337,6 +340,34 @@ func (n *Network) FetchPublicParameters(namespace string) ([]byte, error) {
 	return n.defaultPublicParamsFetcher.Fetch(n.Name(), n.Channel(), namespace)
 }
 
+// NamespaceInfo queries the token chaincode for its version and supported public parameters.
+// It returns an error wrapping driver.ErrNamespaceInfoNotSupported if the token chaincode does not implement the query.
+func (n *Network) NamespaceInfo(namespace string) (*driver.NamespaceInfo, error) {
+	resBoxed, err := n.viewManager.InitiateView(
+		chaincode.NewQueryView(
+			namespace,
+			QueryNamespaceInfo,
+		).WithNetwork(n.Name()).WithChannel(n.Channel()),
+		context.TODO(),
+	)
+	if err != nil {
+		// the chaincode error reaches this node as a message only
+		if strings.Contains(err.Error(), driver.UnknownFunctionMessage(QueryNamespaceInfo)) {
+			return nil, errors.Wrapf(driver.ErrNamespaceInfoNotSupported, "token chaincode at [%s]", namespace)
+		}
+		return nil, errors.WithMessagef(err, "failed to query the token chaincode for namespace info [%s]", namespace)
+	}
+	raw, ok := resBoxed.([]byte)
+	if !ok {
+		return nil, errors.Errorf("expected []byte from TCC, got [%T]", resBoxed)
+	}
+	info := &driver.NamespaceInfo{}
+	if err := json.Unmarshal(raw, info); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal namespace info")
+	}
+	return info, nil
+}
+
 func (n *Network) QueryTokens(context view.Context, namespace string, IDs []*token.ID) ([][]byte, error) {
 	return n.tokenQueryExecutor.QueryTokens(context, namespace, IDs)
 }
@@ -433,6 +464,34 @@ func (n *Network) LookupTransferMetadataKey(namespace string, startingTxID strin
 	return keyValue, nil
 }
 
+// Health checks that the channel is available and that the peers answer a ledger query.
+// The ordering service cannot be probed without submitting a transaction, its health is tracked by the broadcasts.
+func (n *Network) Health(ctx context.Context) ([]driver.ComponentHealth, error) {
+	channel := driver.ComponentHealth{Name: "channel", Healthy: true}
+	if _, err := n.n.Channel(n.ch.Name()); err != nil {
+		channel.Healthy = false
+		channel.Error = err.Error()
+	}
+
+	peers := driver.ComponentHealth{Name: "peers", Healthy: true}
+	done := make(chan error, 1)
+	go func() {
+		_, err := n.ch.Ledger().GetBlockByNumber(0)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			peers.Healthy = false
+			peers.Error = errors.Wrapf(err, "failed querying the ledger of channel [%s]", n.ch.Name()).Error()
+		}
+	case <-ctx.Done():
+		peers.Healthy = false
+		peers.Error = errors.Wrapf(ctx.Err(), "no answer from the peers of channel [%s]", n.ch.Name()).Error()
+	}
+	return []driver.ComponentHealth{channel, peers}, nil
+}
+
 func (n *Network) Ledger() (driver.Ledger, error) {
 	return n.ledger, nil
 }
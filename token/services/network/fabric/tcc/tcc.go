@@ -17,9 +17,12 @@ import (
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/compression"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/common/rws/keys"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/common/rws/translator"
+	driver2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
@@ -33,8 +36,12 @@ const (
 	QueryPublicParamsFunction = "queryPublicParams"
 	QueryTokensFunctions      = "queryTokens"
 	AreTokensSpent            = "areTokensSpent"
+	QueryNamespaceInfo        = "queryNamespaceInfo"
 
 	PublicParamsPathVarEnv = "PUBLIC_PARAMS_FILE_PATH"
+
+	// Version is the version of this token chaincode
+	Version = "1.0"
 )
 
 type Agent interface {
@@ -121,6 +128,11 @@ func (cc *TokenChaincode) Invoke(stub shim.ChaincodeStubInterface) (res pb.Respo
 			if !ok {
 				return shim.Error("failed getting token request, entry not found")
 			}
+			// the token request might have been compressed by the requester
+			tokenRequest, err = compression.Decompress(tokenRequest)
+			if err != nil {
+				return shim.Error(fmt.Sprintf("failed decompressing token request: %s", err))
+			}
 			return cc.ProcessRequest(tokenRequest, stub)
 		case QueryPublicParamsFunction:
 			return cc.QueryPublicParams(stub)
@@ -134,8 +146,10 @@ func (cc *TokenChaincode) Invoke(stub shim.ChaincodeStubInterface) (res pb.Respo
 				return shim.Error("request to check if tokens are spent is empty")
 			}
 			return cc.AreTokensSpent(args[1], stub)
+		case QueryNamespaceInfo:
+			return cc.QueryNamespaceInfo(stub)
 		default:
-			return shim.Error(fmt.Sprintf("function [%s] not recognized", f))
+			return shim.Error(driver2.UnknownFunctionMessage(f))
 		}
 	}
 }
@@ -192,6 +206,14 @@ func (cc *TokenChaincode) Initialize(builtInParams string) error {
 	return nil
 }
 
+// hashAlgorithm returns the hash algorithm selected by the public parameters, SHA256 by default
+func (cc *TokenChaincode) hashAlgorithm() driver.HashAlgorithm {
+	if hpp, ok := cc.PublicParameters.(driver.HashingPublicParameters); ok {
+		return hpp.HashAlgorithm()
+	}
+	return driver.SHA256
+}
+
 func (cc *TokenChaincode) ReadParamsFromFile() string {
 	publicParamsPath := os.Getenv(PublicParamsPathVarEnv)
 	if publicParamsPath == "" {
@@ -217,9 +239,13 @@ func (cc *TokenChaincode) ProcessRequest(raw []byte, stub shim.ChaincodeStubInte
 		return shim.Error(err.Error())
 	}
 
-	// Verify
+	// Verify, at the timestamp the client set in the transaction
+	timestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return shim.Error("failed to get transaction timestamp: " + err.Error())
+	}
 	actions, attributes, err := validator.UnmarshallAndVerifyWithMetadata(
-		context.Background(),
+		driver.WithTxTimestamp(context.Background(), timestamp.AsTime()),
 		&ledger{stub: stub, keyTranslator: &keys.Translator{}},
 		stub.GetTxID(),
 		raw,
@@ -230,6 +256,7 @@ func (cc *TokenChaincode) ProcessRequest(raw []byte, stub shim.ChaincodeStubInte
 
 	// Write
 	w := translator.New(stub.GetTxID(), translator.NewRWSetWrapper(&rwsWrapper{stub: stub}, "", stub.GetTxID()), &keys.Translator{})
+	w.HashAlgorithm = cc.hashAlgorithm()
 	for _, action := range actions {
 		err = w.Write(action)
 		if err != nil {
@@ -318,6 +345,31 @@ func (cc *TokenChaincode) AreTokensSpent(idsRaw []byte, stub shim.ChaincodeStubI
 	return shim.Success(raw)
 }
 
+// QueryNamespaceInfo returns the version of this chaincode, the identifier of the public parameters it has been deployed with,
+// and the capabilities it supports
+func (cc *TokenChaincode) QueryNamespaceInfo(stub shim.ChaincodeStubInterface) pb.Response {
+	w := translator.New(stub.GetTxID(), translator.NewRWSetWrapper(&rwsWrapper{stub: stub}, "", stub.GetTxID()), &keys.Translator{})
+	raw, err := w.ReadSetupParameters()
+	if err != nil {
+		return shim.Error("failed to retrieve public parameters: " + err.Error())
+	}
+	if len(raw) == 0 {
+		return shim.Error("need to initialize public parameters")
+	}
+	info, err := driver2.NamespaceInfoFromPublicParams(raw)
+	if err != nil {
+		return shim.Error("failed to parse public parameters: " + err.Error())
+	}
+	info.Version = Version
+	info.Capabilities = []string{compression.Capability}
+	res, err := json.Marshal(info)
+	if err != nil {
+		logger.Errorf("failed marshalling namespace info: [%s]", err)
+		return shim.Error(fmt.Sprintf("failed marshalling namespace info: [%s]", err))
+	}
+	return shim.Success(res)
+}
+
 type ledger struct {
 	stub          shim.ChaincodeStubInterface
 	keyTranslator translator.KeyTranslator
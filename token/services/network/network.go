@@ -35,12 +35,21 @@ var logger = logging.MustGetLogger("token-sdk.network")
 
 type UnspentTokensIterator = driver.UnspentTokensIterator
 
+// NamespaceInfo describes the token namespace deployed on the network
+type NamespaceInfo = driver.NamespaceInfo
+
 // FinalityListener is the interface that must be implemented to receive transaction status change notifications
 type FinalityListener interface {
 	// OnStatus is called when the status of a transaction changes
 	OnStatus(ctx context.Context, txID string, status int, message string, tokenRequestHash []byte)
 }
 
+// RollbackListener is the interface that must be implemented to receive the notifications of the blocks rolled back by the ledger
+type RollbackListener = driver.RollbackListener
+
+// ErrRollbackNotSupported is returned when the ledger of the network cannot roll back committed blocks
+var ErrRollbackNotSupported = errors.New("the network does not support rollbacks")
+
 type GetFunc func() (view.Identity, []byte, error)
 
 type TxID struct {
@@ -158,17 +167,60 @@ func (v *TokenVault) CertificationStorage() driver.CertificationStorage {
 	return v.v.CertificationStorage()
 }
 
+// PruneReason tells why an unspent token of the vault is invalid
+type PruneReason string
+
+const (
+	// NotOnLedger marks a token whose transaction is not valid on the ledger
+	NotOnLedger PruneReason = "not on ledger"
+	// DoubleSpent marks a token whose transaction is valid on the ledger, but that has been spent, by this node or by another, without the vault knowing
+	DoubleSpent PruneReason = "double-spent"
+)
+
+// PrunedToken is an invalid unspent token, with the reason it is pruned for
+type PrunedToken struct {
+	ID     *token2.ID
+	Reason PruneReason
+}
+
+// PruneReport lists the invalid unspent tokens found by PruneInvalidUnspentTokensWithReport.
+// If DryRun is true, the tokens have not been deleted.
+type PruneReport struct {
+	DryRun bool
+	Tokens []*PrunedToken
+}
+
+// IDs returns the ids of the tokens in the report
+func (r *PruneReport) IDs() []*token2.ID {
+	ids := make([]*token2.ID, len(r.Tokens))
+	for i, tok := range r.Tokens {
+		ids[i] = tok.ID
+	}
+	return ids
+}
+
 // PruneInvalidUnspentTokens checks that each unspent token is actually available on the ledger.
 // Those that are not available are deleted.
 // The function returns the list of deleted token ids
 func (v *TokenVault) PruneInvalidUnspentTokens(context view.Context) ([]*token2.ID, error) {
+	report, err := v.PruneInvalidUnspentTokensWithReport(context, false)
+	if err != nil {
+		return nil, err
+	}
+	return report.IDs(), nil
+}
+
+// PruneInvalidUnspentTokensWithReport checks that each unspent token is actually available on the ledger.
+// It returns a report of the invalid tokens, with the reason each one is invalid for.
+// If dryRun is true, the invalid tokens are only reported, otherwise they are deleted.
+func (v *TokenVault) PruneInvalidUnspentTokensWithReport(context view.Context, dryRun bool) (*PruneReport, error) {
 	it, err := v.QueryEngine().UnspentTokensIterator()
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed to get an iterator of unspent tokens")
 	}
 	defer it.Close()
 
-	var deleted []*token2.ID
+	report := &PruneReport{DryRun: dryRun}
 	tms := token.GetManagementService(context, token.WithTMS(v.n.Name(), v.n.Channel(), v.ns))
 	var buffer []*token2.UnspentToken
 	bufferSize := 50
@@ -182,29 +234,29 @@ func (v *TokenVault) PruneInvalidUnspentTokens(context view.Context) ([]*token2.
 		}
 		buffer = append(buffer, tok)
 		if len(buffer) > bufferSize {
-			newDeleted, err := v.deleteTokens(context, tms, buffer)
+			pruned, err := v.pruneTokens(context, tms, buffer, dryRun)
 			if err != nil {
 				return nil, errors.WithMessagef(err, "failed to process tokens [%v]", buffer)
 			}
-			deleted = append(deleted, newDeleted...)
+			report.Tokens = append(report.Tokens, pruned...)
 			buffer = nil
 		}
 	}
-	newDeleted, err := v.deleteTokens(context, tms, buffer)
+	pruned, err := v.pruneTokens(context, tms, buffer, dryRun)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to process tokens [%v]", buffer)
 	}
-	deleted = append(deleted, newDeleted...)
+	report.Tokens = append(report.Tokens, pruned...)
 
-	return deleted, nil
+	return report, nil
 }
 
 func (v *TokenVault) DeleteTokens(ids ...*token2.ID) error {
 	return v.v.DeleteTokens(ids...)
 }
 
-func (v *TokenVault) deleteTokens(context view.Context, tms *token.ManagementService, tokens []*token2.UnspentToken) ([]*token2.ID, error) {
-	logger.Debugf("delete tokens from vault [%d][%v]", len(tokens), tokens)
+func (v *TokenVault) pruneTokens(context view.Context, tms *token.ManagementService, tokens []*token2.UnspentToken, dryRun bool) ([]*PrunedToken, error) {
+	logger.Debugf("prune tokens from vault [%d][%v], dry-run [%v]", len(tokens), tokens, dryRun)
 	if len(tokens) == 0 {
 		return nil, nil
 	}
@@ -223,21 +275,65 @@ func (v *TokenVault) deleteTokens(context view.Context, tms *token.ManagementSer
 		return nil, errors.WithMessagef(err, "cannot fetch spent flags from network [%s:%s] for ids [%v]", tms.Network(), tms.Channel(), ids)
 	}
 
-	// remove the tokens flagged as spent
-	var toDelete []*token2.ID
+	// collect the tokens flagged as spent
+	var spentIDs []*token2.ID
 	for i, tok := range tokens {
 		if spent[i] {
-			logger.Debugf("token [%s] is spent", tok.Id)
-			toDelete = append(toDelete, tok.Id)
-		} else {
-			logger.Debugf("token [%s] is not spent", tok.Id)
+			spentIDs = append(spentIDs, tok.Id)
+			continue
 		}
+		logger.Debugf("token [%s] is not spent", tok.Id)
+	}
+	if len(spentIDs) == 0 {
+		return nil, nil
+	}
+	reasons, err := v.spentReasons(spentIDs)
+	if err != nil {
+		return nil, err
+	}
+	pruned := make([]*PrunedToken, len(spentIDs))
+	for i, id := range spentIDs {
+		logger.Debugf("token [%s] is spent, [%s]", id, reasons[i])
+		pruned[i] = &PrunedToken{ID: id, Reason: reasons[i]}
+	}
+	if dryRun || len(pruned) == 0 {
+		return pruned, nil
+	}
+	toDelete := make([]*token2.ID, len(pruned))
+	for i, tok := range pruned {
+		toDelete[i] = tok.ID
 	}
 	if err := v.v.DeleteTokens(toDelete...); err != nil {
 		return nil, errors.WithMessagef(err, "failed to remove token ids [%v]", toDelete)
 	}
 
-	return toDelete, nil
+	return pruned, nil
+}
+
+// spentReasons tells, for each token flagged as spent by the network, whether it has never been committed, or has been spent.
+// The ledger is queried once per transaction.
+func (v *TokenVault) spentReasons(ids []*token2.ID) ([]PruneReason, error) {
+	ledger, err := v.n.Ledger()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get ledger of network [%s:%s]", v.n.Name(), v.n.Channel())
+	}
+	txIDs := make([]string, len(ids))
+	for i, id := range ids {
+		txIDs[i] = id.TxId
+	}
+	statuses, err := ledger.Statuses(txIDs...)
+	if err != nil {
+		return nil, err
+	}
+	reasons := make([]PruneReason, len(ids))
+	for i, id := range ids {
+		if statuses[id.TxId] != Valid {
+			reasons[i] = NotOnLedger
+			continue
+		}
+		reasons[i] = DoubleSpent
+	}
+	return reasons, nil
 }
 
 type LocalMembership struct {
@@ -264,9 +360,75 @@ func (l *Ledger) Status(id string) (ValidationCode, string, error) {
 	return ValidationCode(vc), "", nil
 }
 
+// Statuses returns the status of the passed transactions, by transaction id.
+// Each transaction is queried once, even if passed more than once.
+func (l *Ledger) Statuses(ids ...string) (map[string]ValidationCode, error) {
+	statuses := make(map[string]ValidationCode, len(ids))
+	for _, id := range ids {
+		if _, ok := statuses[id]; ok {
+			continue
+		}
+		vc, _, err := l.Status(id)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get status of [%s] from the ledger", id)
+		}
+		statuses[id] = vc
+	}
+	return statuses, nil
+}
+
 // Network provides access to the remote network
 type Network struct {
 	n driver.Network
+
+	broadcastBreaker   *CircuitBreaker
+	queryTokensBreaker *CircuitBreaker
+}
+
+func newNetwork(n driver.Network) *Network {
+	return &Network{
+		n:                  n,
+		broadcastBreaker:   NewCircuitBreaker("broadcast", DefaultBreakerFailureThreshold, DefaultBreakerOpenTimeout),
+		queryTokensBreaker: NewCircuitBreaker("query-tokens", DefaultBreakerFailureThreshold, DefaultBreakerOpenTimeout),
+	}
+}
+
+// ComponentHealth is the health of a component of the network backend
+type ComponentHealth = driver.ComponentHealth
+
+// HealthReport describes the health of the network backend
+type HealthReport struct {
+	// Healthy is true if all the components are healthy
+	Healthy bool `json:"healthy"`
+	// Components lists the health of each component probed
+	Components []ComponentHealth `json:"components"`
+}
+
+// Health probes the network backend, if the driver supports it, and reports the state of the circuit breakers
+// guarding the calls to the ordering service and to the token queries.
+func (n *Network) Health(ctx context.Context) (*HealthReport, error) {
+	report := &HealthReport{Healthy: true}
+	if hc, ok := n.n.(driver.HealthChecker); ok {
+		components, err := hc.Health(ctx)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed probing network [%s:%s]", n.Name(), n.Channel())
+		}
+		report.Components = append(report.Components, components...)
+	}
+	for _, b := range []*CircuitBreaker{n.broadcastBreaker, n.queryTokensBreaker} {
+		state, lastErr := b.State()
+		c := ComponentHealth{Name: b.Name(), Healthy: state == BreakerClosed}
+		if !c.Healthy && lastErr != nil {
+			c.Error = fmt.Sprintf("circuit breaker %s: %s", state, lastErr)
+		}
+		report.Components = append(report.Components, c)
+	}
+	for _, c := range report.Components {
+		if !c.Healthy {
+			report.Healthy = false
+		}
+	}
+	return report, nil
 }
 
 // Name returns the name of the network
@@ -297,14 +459,17 @@ func (n *Network) TokenVault(namespace string) (*TokenVault, error) {
 	return &TokenVault{n: n, v: v, ns: namespace}, nil
 }
 
-// Broadcast sends the given blob to the network
+// Broadcast sends the given blob to the network.
+// It fails fast with an error wrapping ErrCircuitOpen if the recent broadcasts failed.
 func (n *Network) Broadcast(context context.Context, blob interface{}) error {
-	switch b := blob.(type) {
-	case *Envelope:
-		return n.n.Broadcast(context, b.e)
-	default:
-		return n.n.Broadcast(context, b)
-	}
+	return n.broadcastBreaker.Do(func() error {
+		switch b := blob.(type) {
+		case *Envelope:
+			return n.n.Broadcast(context, b.e)
+		default:
+			return n.n.Broadcast(context, b)
+		}
+	})
 }
 
 // AnonymousIdentity returns a fresh anonymous identity
@@ -347,8 +512,31 @@ func (n *Network) FetchPublicParameters(namespace string) ([]byte, error) {
 }
 
 // QueryTokens returns the tokens corresponding to the given token ids int the given namespace
+// NamespaceInfo returns the version of the token namespace deployed on the network and the public parameters it supports.
+// If the namespace does not support the query, the info is derived from the public parameters it stores, the version is unknown.
+func (n *Network) NamespaceInfo(namespace string) (*NamespaceInfo, error) {
+	info, err := n.n.NamespaceInfo(namespace)
+	if !errors.Is(err, driver.ErrNamespaceInfoNotSupported) {
+		return info, err
+	}
+	logger.Warnf("namespace [%s] does not support namespace info queries, fallback to public parameters: [%s]", namespace, err)
+	ppRaw, err := n.n.FetchPublicParameters(namespace)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed fetching public parameters for [%s]", namespace)
+	}
+	return driver.NamespaceInfoFromPublicParams(ppRaw)
+}
+
+// QueryTokens retrieves the token content for the passed token ids.
+// It fails fast with an error wrapping ErrCircuitOpen if the recent queries failed.
 func (n *Network) QueryTokens(context view.Context, namespace string, IDs []*token2.ID) ([][]byte, error) {
-	return n.n.QueryTokens(context, namespace, IDs)
+	var res [][]byte
+	err := n.queryTokensBreaker.Do(func() error {
+		var err error
+		res, err = n.n.QueryTokens(context, namespace, IDs)
+		return err
+	})
+	return res, err
 }
 
 // AreTokensSpent retrieves the spent flag for the passed ids
@@ -375,6 +563,16 @@ func (n *Network) RemoveFinalityListener(id string, listener FinalityListener) e
 	return n.n.RemoveFinalityListener(id, listener)
 }
 
+// AddRollbackListener registers a listener for the blocks rolled back by the ledger.
+// It returns ErrRollbackNotSupported if the ledger of the network cannot roll back committed blocks.
+func (n *Network) AddRollbackListener(namespace string, listener RollbackListener) error {
+	rn, ok := n.n.(driver.RollbackNotifier)
+	if !ok {
+		return ErrRollbackNotSupported
+	}
+	return rn.AddRollbackListener(namespace, listener)
+}
+
 // LookupTransferMetadataKey searches for a transfer metadata key containing the passed sub-key starting from the passed transaction id in the given namespace.
 // The operation gets canceled if the passed timeout gets reached or, if stopOnLastTx is true, when the last transaction in the vault is reached.
 func (n *Network) LookupTransferMetadataKey(namespace, startingTxID, key string, timeout time.Duration, stopOnLastTx bool, opts ...token.ServiceOption) ([]byte, error) {
@@ -454,7 +652,7 @@ func (np *Provider) newNetwork(network string, channel string) (*Network, error)
 			continue
 		}
 		logger.Debugf("new network [%s:%s]", network, channel)
-		return &Network{n: nw}, nil
+		return newNetwork(nw), nil
 	}
 	return nil, errors.Errorf("no network driver found for [%s:%s], errs [%v]", network, channel, errs)
 }
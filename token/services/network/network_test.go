@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package network
+
+import (
+	"testing"
+
+	tdriver "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// namespaceNetwork is a network answering namespace info queries with the passed values
+type namespaceNetwork struct {
+	driver.Network
+	info    *driver.NamespaceInfo
+	infoErr error
+	pp      []byte
+}
+
+func (n *namespaceNetwork) NamespaceInfo(string) (*driver.NamespaceInfo, error) {
+	return n.info, n.infoErr
+}
+
+func (n *namespaceNetwork) FetchPublicParameters(string) ([]byte, error) {
+	if n.pp == nil {
+		return nil, errors.New("public parameters not found")
+	}
+	return n.pp, nil
+}
+
+func TestNamespaceInfo(t *testing.T) {
+	pp, err := tdriver.Marshal(&tdriver.SerializedPublicParameters{Identifier: "zkatdlog", Raw: []byte("raw")})
+	assert.NoError(t, err)
+
+	// the namespace answers
+	info := &driver.NamespaceInfo{Version: "v2", PublicParamsIdentifiers: []string{"zkatdlog", "fabtoken"}}
+	res, err := newNetwork(&namespaceNetwork{info: info, pp: pp}).NamespaceInfo("ns")
+	assert.NoError(t, err)
+	assert.Equal(t, info, res)
+
+	// the namespace does not support the query, the stored public parameters are used
+	unsupported := errors.Wrap(driver.ErrNamespaceInfoNotSupported, "token chaincode at [ns]")
+	res, err = newNetwork(&namespaceNetwork{infoErr: unsupported, pp: pp}).NamespaceInfo("ns")
+	assert.NoError(t, err)
+	assert.Equal(t, &driver.NamespaceInfo{PublicParamsIdentifiers: []string{"zkatdlog"}}, res)
+	_, err = newNetwork(&namespaceNetwork{infoErr: unsupported}).NamespaceInfo("ns")
+	assert.ErrorContains(t, err, "public parameters not found")
+
+	// any other error is returned
+	unreachable := errors.New("peer unreachable")
+	_, err = newNetwork(&namespaceNetwork{infoErr: unreachable, pp: pp}).NamespaceInfo("ns")
+	assert.ErrorIs(t, err, unreachable)
+}
+
+// countingLedger returns the passed statuses and counts the queries by transaction id
+type countingLedger struct {
+	statuses map[string]driver.ValidationCode
+	queries  map[string]int
+}
+
+func (l *countingLedger) Status(id string) (driver.ValidationCode, error) {
+	l.queries[id]++
+	vc, ok := l.statuses[id]
+	if !ok {
+		return driver.Unknown, errors.Errorf("transaction [%s] not found", id)
+	}
+	return vc, nil
+}
+
+type ledgerNetwork struct {
+	driver.Network
+	ledger *countingLedger
+}
+
+func (n *ledgerNetwork) Ledger() (driver.Ledger, error) { return n.ledger, nil }
+
+func TestSpentReasons(t *testing.T) {
+	ledger := &countingLedger{
+		statuses: map[string]driver.ValidationCode{"tx1": driver.Valid, "tx2": driver.Invalid},
+		queries:  map[string]int{},
+	}
+	v := &TokenVault{n: newNetwork(&ledgerNetwork{ledger: ledger})}
+
+	reasons, err := v.spentReasons([]*token2.ID{{TxId: "tx1", Index: 0}, {TxId: "tx2", Index: 0}, {TxId: "tx1", Index: 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, []PruneReason{DoubleSpent, NotOnLedger, DoubleSpent}, reasons)
+	// the tokens of the same transaction share one query
+	assert.Equal(t, map[string]int{"tx1": 1, "tx2": 1}, ledger.queries)
+
+	_, err = v.spentReasons([]*token2.ID{{TxId: "tx3"}})
+	assert.ErrorContains(t, err, "failed to get status of [tx3] from the ledger")
+}
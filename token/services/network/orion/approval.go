@@ -169,7 +169,7 @@ func (r *RequestApprovalResponderView) process(context view.Context, request *Ap
 	validateErr := runner.RunWithErrors(func() (bool, error) {
 		span.AddEvent("try_validate")
 		var retry bool
-		envelopeRaw, retry, err = r.validate(context, request, validator)
+		envelopeRaw, retry, err = r.validate(context, request, validator, driver.HashAlgorithmOf(pp))
 		if err == nil {
 			return true, nil
 		}
@@ -207,7 +207,7 @@ func (r *RequestApprovalResponderView) process(context view.Context, request *Ap
 	return envelopeRaw, nil
 }
 
-func (r *RequestApprovalResponderView) validate(context view.Context, request *ApprovalRequest, validator driver.Validator) ([]byte, bool, error) {
+func (r *RequestApprovalResponderView) validate(context view.Context, request *ApprovalRequest, validator driver.Validator, hashAlgorithm driver.HashAlgorithm) ([]byte, bool, error) {
 	span := context.StartSpan("tx_request_validation")
 	defer span.End()
 	sm, err := r.dbManager.GetSessionManager(request.Network)
@@ -223,8 +223,9 @@ func (r *RequestApprovalResponderView) validate(context view.Context, request *A
 		return nil, true, errors.Wrapf(err, "failed to get query executor for orion network [%s]", request.Network)
 	}
 	span.AddEvent("validate_request")
+	// the custodian is the only validator of the transaction, its clock is the reference
 	actions, attributes, err := token.NewValidator(validator).UnmarshallAndVerifyWithMetadata(
-		context.Context(),
+		driver.WithTxTimestamp(context.Context(), time.Now()),
 		&LedgerWrapper{qe: qe, keyTranslator: &translator.HashedKeyTranslator{KT: &keys.Translator{}}},
 		request.TxID,
 		request.Request,
@@ -244,6 +245,7 @@ func (r *RequestApprovalResponderView) validate(context view.Context, request *A
 		tx: tx,
 	}
 	t := translator.New(request.TxID, translator.NewRWSetWrapper(rws, "", request.TxID), &translator.HashedKeyTranslator{KT: &keys.Translator{}})
+	t.HashAlgorithm = hashAlgorithm
 	for _, action := range actions {
 		err = t.Write(action)
 		if err != nil {
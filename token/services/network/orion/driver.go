@@ -52,7 +52,7 @@ func NewOrionDriver(
 		identityProvider,
 		filterProvider,
 		tmsProvider,
-		NewTokenExecutorProvider(),
+		common.NewBatchedTokenQueryExecutorProvider(NewTokenExecutorProvider(), common.DefaultTokenQueryBatchSize),
 		NewSpentTokenExecutorProvider(keyTranslator),
 		tracerProvider,
 		keyTranslator,
@@ -49,6 +49,7 @@ type Network struct {
 	dbManager           *DBManager
 	flm                 FinalityListenerManager
 	keyTranslator       translator.KeyTranslator
+	rollbackListeners   *common2.RollbackListeners
 }
 
 func NewNetwork(
@@ -91,6 +92,7 @@ func NewNetwork(
 		dbManager:               dbManager,
 		flm:                     flm,
 		keyTranslator:           keyTranslator,
+		rollbackListeners:       common2.NewRollbackListeners(),
 	}
 }
 
@@ -231,6 +233,16 @@ func (n *Network) FetchPublicParameters(namespace string) ([]byte, error) {
 	return pp.([]byte), nil
 }
 
+// NamespaceInfo returns the namespace info derivable from the public parameters stored in the passed namespace.
+// The namespace version is unknown.
+func (n *Network) NamespaceInfo(namespace string) (*driver.NamespaceInfo, error) {
+	ppRaw, err := n.FetchPublicParameters(namespace)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed fetching public parameters for [%s]", namespace)
+	}
+	return driver.NamespaceInfoFromPublicParams(ppRaw)
+}
+
 func (n *Network) QueryTokens(context view.Context, namespace string, IDs []*token.ID) ([][]byte, error) {
 	return n.tokenQueryExecutor.QueryTokens(context, namespace, IDs)
 }
@@ -254,6 +266,18 @@ func (n *Network) RemoveFinalityListener(txID string, listener driver.FinalityLi
 	return n.flm.RemoveFinalityListener(txID, listener)
 }
 
+// AddRollbackListener registers a listener for the blocks rolled back that contain transactions of the passed namespace
+func (n *Network) AddRollbackListener(namespace string, listener driver.RollbackListener) error {
+	return n.rollbackListeners.AddRollbackListener(namespace, listener)
+}
+
+// NotifyRollback notifies the rollback listeners of the passed namespace that the passed block was rolled back.
+// Orion does not roll back blocks by itself, it is invoked when the database is restored to a block before the passed one,
+// once for each block lost by the restore, the last first.
+func (n *Network) NotifyRollback(ctx context.Context, namespace string, blockNum uint64, txIDs []string) error {
+	return n.rollbackListeners.NotifyRollback(ctx, namespace, blockNum, txIDs)
+}
+
 func (n *Network) LookupTransferMetadataKey(namespace string, startingTxID string, key string, timeout time.Duration, _ bool) ([]byte, error) {
 	k, err := n.keyTranslator.CreateTransferActionMetadataKey(key)
 	if err != nil {
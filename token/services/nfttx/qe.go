@@ -7,10 +7,14 @@ SPDX-License-Identifier: Apache-2.0
 package nfttx
 
 import (
+	"context"
 	"encoding/base64"
+	"strings"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/nfttx/marshaller"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 	"github.com/thedevsaddam/gojsonq"
@@ -29,59 +33,120 @@ type selector interface {
 	Filter(filter Filter, q string) ([]*token2.ID, error)
 }
 
+// attributeQuerier returns the unspent tokens having an attribute with a given value
+type attributeQuerier interface {
+	UnspentTokensIteratorByAttribute(ctx context.Context, walletID, key, value string) (driver.UnspentTokensIterator, error)
+}
+
 type QueryExecutor struct {
 	selector
 	vault
-	precision uint64
+	attributes attributeQuerier
+	wallet     string
+	precision  uint64
 }
 
 func NewQueryExecutor(sp token.ServiceProvider, wallet string, precision uint64, opts ...token.ServiceOption) (*QueryExecutor, error) {
 	tms := token.GetManagementService(sp, opts...)
+	if tms == nil {
+		return nil, errors.New("failed to get token management service")
+	}
 	qe := tms.Vault().NewQueryEngine()
-	return &QueryExecutor{
+	executor := &QueryExecutor{
 		selector: NewFilter(
 			wallet,
 			qe,
 			tms.PublicParametersManager().PublicParameters().Precision(),
 		),
 		vault:     qe,
+		wallet:    wallet,
 		precision: precision,
-	}, nil
+	}
+	tokenDB, err := tokendb.GetByTMSId(sp, tms.ID())
+	if err != nil {
+		logger.Warnf("token db not available for [%s], queries scan the unspent tokens: [%s]", tms.ID(), err)
+		return executor, nil
+	}
+	executor.attributes = tokenDB
+	return executor, nil
 }
 
+// QueryByKey loads into the passed state the unspent token of the wallet whose state has the passed key set to the passed value.
+// It returns ErrNoResults if there is no such token.
 func (s *QueryExecutor) QueryByKey(state interface{}, key string, value string) error {
-	ids, err := s.selector.Filter(&jsonFilter{
-		q:     gojsonq.New(),
-		key:   key,
-		value: value,
-	}, "1")
+	_, err := s.QueryTokenByKey(state, key, value)
+	return err
+}
+
+// QueryTokenByKey does as QueryByKey and returns the id of the token, to transfer it with Transaction.TransferByID.
+// A top-level key is looked up in the attributes stored with the tokens, a nested key, whose path is separated by dots,
+// is looked up by scanning the unspent tokens of the wallet.
+// The scan is also the fallback when no token has the attribute, to find the tokens stored without attributes.
+func (s *QueryExecutor) QueryTokenByKey(state interface{}, key string, value string) (*token2.ID, error) {
+	ids, err := s.idsByKey(key, value)
 	if err != nil {
 		if errors.Cause(err) == ErrNoResults {
-			return ErrNoResults
+			return nil, ErrNoResults
 		}
-		return errors.Wrap(err, "failed to filter")
+		return nil, errors.Wrap(err, "failed to filter")
 	}
 	tokens, err := s.vault.GetTokens(ids...)
 	if err != nil {
-		return errors.Wrap(err, "failed to get tokens")
+		return nil, errors.Wrap(err, "failed to get tokens")
 	}
-	for _, t := range tokens {
+	for i, t := range tokens {
 		q, err := token2.ToQuantity(t.Quantity, s.precision)
 		if err != nil {
-			return errors.Wrap(err, "failed to convert quantity")
+			return nil, errors.Wrap(err, "failed to convert quantity")
 		}
-		if q.Cmp(token2.NewOneQuantity(s.precision)) == 0 {
-			// this is the token
-			decoded, err := base64.StdEncoding.DecodeString(t.Type)
-			if err != nil {
-				return errors.Wrap(err, "failed to decode type")
-			}
-			if err := marshaller.Unmarshal(decoded, state); err == nil {
-				return errors.Wrap(err, "failed to unmarshal state")
-			}
+		if q.Cmp(token2.NewOneQuantity(s.precision)) != 0 {
+			continue
 		}
+		// this is the token
+		decoded, err := base64.StdEncoding.DecodeString(t.Type)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode type")
+		}
+		if err := marshaller.Unmarshal(decoded, state); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal state")
+		}
+		return ids[i], nil
 	}
-	return ErrNoResults
+	return nil, ErrNoResults
+}
+
+func (s *QueryExecutor) idsByKey(key string, value string) ([]*token2.ID, error) {
+	if strings.Contains(key, ".") || s.attributes == nil {
+		return s.scanByKey(key, value)
+	}
+	it, err := s.attributes.UnspentTokensIteratorByAttribute(context.TODO(), s.wallet, key, value)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query tokens by attribute [%s]", key)
+	}
+	defer it.Close()
+	var ids []*token2.ID
+	for {
+		t, err := it.Next()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to iterate over tokens")
+		}
+		if t == nil {
+			break
+		}
+		ids = append(ids, t.Id)
+	}
+	if len(ids) == 0 {
+		return s.scanByKey(key, value)
+	}
+	return ids, nil
+}
+
+func (s *QueryExecutor) scanByKey(key string, value string) ([]*token2.ID, error) {
+	return s.selector.Filter(&jsonFilter{
+		q:     gojsonq.New(),
+		key:   key,
+		value: value,
+	}, "1")
 }
 
 type jsonFilter struct {
@@ -43,7 +43,7 @@ func (o *OutputStream) StateAt(index int, state interface{}) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to decode type")
 	}
-	if err := marshaller.Unmarshal(decoded, state); err == nil {
+	if err := marshaller.Unmarshal(decoded, state); err != nil {
 		return errors.Wrap(err, "failed to unmarshal state")
 	}
 	return nil
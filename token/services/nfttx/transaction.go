@@ -7,13 +7,16 @@ SPDX-License-Identifier: Apache-2.0
 package nfttx
 
 import (
+	"context"
 	"encoding/base64"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/nfttx/marshaller"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttx"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
 )
@@ -29,8 +32,14 @@ func WithAuditor(auditor view.Identity) TxOption {
 	}
 }
 
+// LinearIDAttribute is the attribute of a state that holds its linear id.
+// Issue refuses a state whose linear id is the one of a token already stored in the token db.
+const LinearIDAttribute = "LinearID"
+
 type Transaction struct {
 	*ttx.Transaction
+	// sp gives access to the token db to check the uniqueness of the issued states, it is nil for wrapped transactions
+	sp token.ServiceProvider
 }
 
 func NewAnonymousTransaction(sp view.Context, opts ...TxOption) (*Transaction, error) {
@@ -44,9 +53,11 @@ func NewAnonymousTransaction(sp view.Context, opts ...TxOption) (*Transaction, e
 		return nil, err
 	}
 
-	return &Transaction{Transaction: tx}, nil
+	return &Transaction{Transaction: tx, sp: sp}, nil
 }
 
+// Wrap returns the passed transaction as a non-fungible token transaction.
+// The uniqueness of the states issued with the returned transaction is not checked against the token db.
 func Wrap(tx *ttx.Transaction) *Transaction {
 	return &Transaction{Transaction: tx}
 }
@@ -73,7 +84,7 @@ func ReceiveTransaction(context view.Context) (*Transaction, error) {
 		return nil, errors.WithMessagef(err, "invalid transaction %s", cctx.ID())
 	}
 
-	return &Transaction{Transaction: cctx}, nil
+	return &Transaction{Transaction: cctx, sp: context}, nil
 }
 
 func (t *Transaction) Issue(wallet *token.IssuerWallet, state interface{}, recipient view.Identity, opts ...token.IssueOption) error {
@@ -88,6 +99,9 @@ func (t *Transaction) Issue(wallet *token.IssuerWallet, state interface{}, recip
 		return errors.Wrap(err, "failed to marshal state")
 	}
 	stateJSONStr := base64.StdEncoding.EncodeToString(stateJSON)
+	if err := t.checkUnique(stateJSONStr); err != nil {
+		return err
+	}
 
 	// Issue
 	return t.Transaction.Issue(wallet, recipient, stateJSONStr, 1, opts...)
@@ -104,6 +118,15 @@ func (t *Transaction) Transfer(wallet *OwnerWallet, state interface{}, recipient
 	return t.Transaction.Transfer(wallet.OwnerWallet, stateJSONStr, []uint64{1}, []view.Identity{recipient}, opts...)
 }
 
+// TransferByID transfers to the passed recipient the token with the passed id, whose new state is the passed one.
+// The token is spent whole, the id is usually obtained with OwnerWallet.QueryTokenByKey.
+func (t *Transaction) TransferByID(wallet *OwnerWallet, id *token2.ID, state interface{}, recipient view.Identity, opts ...token.TransferOption) error {
+	if id == nil {
+		return errors.New("token id is nil")
+	}
+	return t.Transfer(wallet, state, recipient, append(opts, token.WithTokenIDs(id))...)
+}
+
 func (t *Transaction) Outputs() (*OutputStream, error) {
 	os, err := t.Transaction.Outputs()
 	if err != nil {
@@ -112,6 +135,38 @@ func (t *Transaction) Outputs() (*OutputStream, error) {
 	return &OutputStream{OutputStream: os}, nil
 }
 
+// checkUnique returns an error if the linear id of the passed token type is the one of a token in the token db,
+// or if another output of this transaction already has the same type
+func (t *Transaction) checkUnique(tokenType string) error {
+	outputs, err := t.Transaction.Outputs()
+	if err != nil {
+		return errors.WithMessage(err, "failed to get outputs")
+	}
+	if outputs.ByType(tokenType).Count() != 0 {
+		return errors.New("state already issued in this transaction")
+	}
+	if t.sp == nil {
+		logger.Debugf("no service provider, skip the uniqueness check against the token db")
+		return nil
+	}
+	attributes, ok := token2.NFTAttributes(tokenType)
+	if !ok || len(attributes[LinearIDAttribute]) == 0 {
+		return nil
+	}
+	tokenDB, err := tokendb.GetByTMSId(t.sp, t.TMSID())
+	if err != nil {
+		return errors.WithMessage(err, "failed to get token db")
+	}
+	exists, err := tokenDB.ExistsTokenWithAttribute(context.TODO(), LinearIDAttribute, attributes[LinearIDAttribute])
+	if err != nil {
+		return errors.WithMessagef(err, "failed to check uniqueness of [%s]", attributes[LinearIDAttribute])
+	}
+	if exists {
+		return errors.Errorf("a token with linear id [%s] already exists", attributes[LinearIDAttribute])
+	}
+	return nil
+}
+
 func (t *Transaction) setStateID(s interface{}) (string, error) {
 	logger.Debugf("setStateID %v...", s)
 	defer logger.Debugf("setStateID...done")
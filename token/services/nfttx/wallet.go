@@ -9,6 +9,7 @@ package nfttx
 import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 )
 
@@ -26,6 +27,15 @@ func (o *OwnerWallet) QueryByKey(state interface{}, key string, value string) er
 	return qe.QueryByKey(state, key, value)
 }
 
+// QueryTokenByKey does as QueryByKey and returns the id of the token, to transfer it with Transaction.TransferByID
+func (o *OwnerWallet) QueryTokenByKey(state interface{}, key string, value string) (*token2.ID, error) {
+	qe, err := NewQueryExecutor(o.ServiceProvider, o.OwnerWallet.ID(), o.Precision)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to create query executor")
+	}
+	return qe.QueryTokenByKey(state, key, value)
+}
+
 // WithType returns a list token option that filter by the passed token type.
 // If the passed token type is the empty string, all token types are selected.
 func WithType(tokenType string) token.ListTokensOption {
@@ -0,0 +1,327 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package ops exposes, as views, the routine operations on the token services of a node:
+// listing the wallets and their balances, the pending transactions, running the consistency checks,
+// pruning the invalid tokens, exporting the transaction history, and exporting the audit bundles.
+// The views are meant to be called through the REST API of the node, for instance by the tokenctl command.
+package ops
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	driver2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/driver"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb/bundle"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selfcheck"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// EnabledKey is the key, in the configuration of the node, that enables the operations views
+const EnabledKey = "token.ops.enabled"
+
+// The identifiers the operations views are registered with
+const (
+	WalletsView  = "tokenctl.wallets"
+	BalancesView = "tokenctl.balances"
+	PendingView  = "tokenctl.pending"
+	CheckView    = "tokenctl.check"
+	PruneView    = "tokenctl.prune"
+	HistoryView  = "tokenctl.history"
+	// AuditBundleView is available on auditor nodes only
+	AuditBundleView = "tokenctl.audit-bundle"
+)
+
+// defaultCheckSampleSize is the number of unspent tokens checked against the ttxdb by CheckView
+const defaultCheckSampleSize = 100
+
+// Request is the input of the operations views
+type Request struct {
+	// TMSID selects the TMS, the default one if empty
+	TMSID token.TMSID `json:"tmsID"`
+	// Wallet restricts the balances and the history to a wallet, if not empty
+	Wallet string `json:"wallet,omitempty"`
+	// TokenType restricts the balances to a token type, if not empty
+	TokenType string `json:"tokenType,omitempty"`
+	// DryRun makes PruneView report the invalid tokens without deleting them
+	DryRun bool `json:"dryRun,omitempty"`
+	// From and To bound the exported history, if not nil
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+	// RecipientKey is the PEM encoded P-256 public key the audit bundle is encrypted for
+	RecipientKey string `json:"recipientKey,omitempty"`
+}
+
+// Balance is the balance of a wallet for a token type
+type Balance struct {
+	Wallet    string `json:"wallet"`
+	TokenType string `json:"tokenType"`
+	// Quantity is the decimal balance
+	Quantity string `json:"quantity"`
+}
+
+// Registry is where the operations views are registered
+type Registry interface {
+	RegisterFactory(id string, factory driver2.Factory) error
+}
+
+// InstallViews registers the operations views in the passed registry
+func InstallViews(registry Registry) error {
+	views := map[string]func(*Request) view.View{
+		WalletsView:     func(r *Request) view.View { return &walletsView{r} },
+		BalancesView:    func(r *Request) view.View { return &balancesView{r} },
+		PendingView:     func(r *Request) view.View { return &pendingView{r} },
+		CheckView:       func(r *Request) view.View { return &checkView{r} },
+		PruneView:       func(r *Request) view.View { return &pruneView{r} },
+		HistoryView:     func(r *Request) view.View { return &historyView{r} },
+		AuditBundleView: func(r *Request) view.View { return &auditBundleView{r} },
+	}
+	for id, newView := range views {
+		if err := registry.RegisterFactory(id, factory(newView)); err != nil {
+			return errors.WithMessagef(err, "failed to register view [%s]", id)
+		}
+	}
+	return nil
+}
+
+// factory creates the views from the JSON of their Request
+type factory func(*Request) view.View
+
+func (f factory) NewView(in []byte) (view.View, error) {
+	r := &Request{}
+	if len(in) != 0 {
+		if err := json.Unmarshal(in, r); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal request")
+		}
+	}
+	return f(r), nil
+}
+
+func (r *Request) tms(context view.Context) (*token.ManagementService, error) {
+	tms, err := token.GetManagementServiceProvider(context).GetManagementService(token.WithTMSID(r.TMSID))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get tms [%s]", r.TMSID)
+	}
+	return tms, nil
+}
+
+// walletsView returns the ids of the owner wallets
+type walletsView struct{ *Request }
+
+func (v *walletsView) Call(context view.Context) (interface{}, error) {
+	tms, err := v.tms(context)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := tms.WalletManager().OwnerWalletIDs()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to list wallets")
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// balancesView returns the balances of the owner wallets, by token type
+type balancesView struct{ *Request }
+
+func (v *balancesView) Call(context view.Context) (interface{}, error) {
+	tms, err := v.tms(context)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{v.Wallet}
+	if len(v.Wallet) == 0 {
+		if ids, err = tms.WalletManager().OwnerWalletIDs(); err != nil {
+			return nil, errors.WithMessagef(err, "failed to list wallets")
+		}
+		sort.Strings(ids)
+	}
+	precision := tms.PublicParametersManager().PublicParameters().Precision()
+	var balances []Balance
+	for _, id := range ids {
+		wallet := tms.WalletManager().OwnerWallet(id)
+		if wallet == nil {
+			return nil, errors.Errorf("wallet [%s] not found", id)
+		}
+		var opts []token.ListTokensOption
+		if len(v.TokenType) != 0 {
+			opts = append(opts, token.WithType(v.TokenType))
+		}
+		unspent, err := wallet.ListUnspentTokens(opts...)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to list the tokens of [%s]", id)
+		}
+		sums := map[string]token2.Quantity{}
+		for _, tok := range unspent.Tokens {
+			q, err := token2.ToQuantity(tok.Quantity, precision)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "invalid quantity of token [%s]", tok.Id)
+			}
+			if sum, ok := sums[tok.Type]; ok {
+				q = sum.Add(q)
+			}
+			sums[tok.Type] = q
+		}
+		types := make([]string, 0, len(sums))
+		for typ := range sums {
+			types = append(types, typ)
+		}
+		sort.Strings(types)
+		for _, typ := range types {
+			balances = append(balances, Balance{Wallet: id, TokenType: typ, Quantity: sums[typ].Decimal()})
+		}
+	}
+	return balances, nil
+}
+
+// pendingView returns the records of the pending transactions
+type pendingView struct{ *Request }
+
+func (v *pendingView) Call(context view.Context) (interface{}, error) {
+	return v.transactions(context, ttxdb.QueryTransactionsParams{Statuses: []driver.TxStatus{driver.Pending}})
+}
+
+// historyView returns the records of the transactions, in the time range and of the wallet of the request
+type historyView struct{ *Request }
+
+func (v *historyView) Call(context view.Context) (interface{}, error) {
+	params := ttxdb.QueryTransactionsParams{From: v.From, To: v.To}
+	if len(v.Wallet) != 0 {
+		tms, err := v.tms(context)
+		if err != nil {
+			return nil, err
+		}
+		wallet := tms.WalletManager().OwnerWallet(v.Wallet)
+		if wallet == nil {
+			return nil, errors.Errorf("wallet [%s] not found", v.Wallet)
+		}
+		// the records refer to the wallets by enrollment id
+		params.SenderWallet = wallet.EnrollmentID()
+		params.RecipientWallet = wallet.EnrollmentID()
+	}
+	return v.transactions(context, params)
+}
+
+func (r *Request) transactions(context view.Context, params ttxdb.QueryTransactionsParams) ([]*driver.TransactionRecord, error) {
+	tms, err := r.tms(context)
+	if err != nil {
+		return nil, err
+	}
+	db, err := ttxdb.GetByTMSId(context, tms.ID())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get ttxdb of [%s]", tms.ID())
+	}
+	it, err := db.Transactions(params)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query transactions")
+	}
+	defer it.Close()
+	records := []*driver.TransactionRecord{}
+	for {
+		record, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to read transactions")
+		}
+		if record == nil {
+			return records, nil
+		}
+		records = append(records, record)
+	}
+}
+
+// checkView runs the consistency checks of the self-check on the TMS of the request
+type checkView struct{ *Request }
+
+func (v *checkView) Call(context view.Context) (interface{}, error) {
+	tms, err := v.tms(context)
+	if err != nil {
+		return nil, err
+	}
+	tmsID := tms.ID()
+	net := network.GetInstance(context, tmsID.Network, tmsID.Channel)
+	if net == nil {
+		return nil, errors.Errorf("network [%s:%s] not found", tmsID.Network, tmsID.Channel)
+	}
+	tokenDB, err := tokendb.GetByTMSId(context, tmsID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get tokendb of [%s]", tmsID)
+	}
+	ttxDB, err := ttxdb.GetByTMSId(context, tmsID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get ttxdb of [%s]", tmsID)
+	}
+	checker := &selfcheck.Checker{SampleSize: defaultCheckSampleSize}
+	return checker.Check(tmsID, tms.PublicParametersManager().PublicParamsHash(), tms.PublicParametersManager().PublicParameters().HashAlgorithm(), tokenDB, ttxDB, net)
+}
+
+// pruneView deletes, unless in dry-run, the unspent tokens that are not valid on the ledger
+type pruneView struct{ *Request }
+
+func (v *pruneView) Call(context view.Context) (interface{}, error) {
+	tms, err := v.tms(context)
+	if err != nil {
+		return nil, err
+	}
+	tmsID := tms.ID()
+	net := network.GetInstance(context, tmsID.Network, tmsID.Channel)
+	if net == nil {
+		return nil, errors.Errorf("network [%s:%s] not found", tmsID.Network, tmsID.Channel)
+	}
+	vault, err := net.TokenVault(tmsID.Namespace)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get token vault of [%s]", tmsID)
+	}
+	return vault.PruneInvalidUnspentTokensWithReport(context, v.DryRun)
+}
+
+// auditBundleView returns the audit records in the time range of the request,
+// sealed in a bundle for the holder of the recipient key
+type auditBundleView struct{ *Request }
+
+func (v *auditBundleView) Call(context view.Context) (interface{}, error) {
+	recipient, err := bundle.ParsePublicKey([]byte(v.RecipientKey))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid recipient key")
+	}
+	tms, err := v.tms(context)
+	if err != nil {
+		return nil, err
+	}
+	db, err := auditdb.GetByTMSId(context, tms.ID())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get auditdb of [%s]", tms.ID())
+	}
+	pp, err := tms.PublicParametersManager().PublicParameters().Serialize()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to serialize public parameters")
+	}
+	b, err := bundle.New(db, tms.ID(), pp, v.From, v.To)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to create audit bundle")
+	}
+	w := tms.WalletManager().AuditorWallet("")
+	if w == nil {
+		return nil, errors.Errorf("no auditor wallet found in [%s]", tms.ID())
+	}
+	id, err := w.GetAuditorIdentity()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get auditor identity")
+	}
+	signer, err := w.GetSigner(id)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get signer of the auditor identity")
+	}
+	return bundle.Seal(b, recipient, id, signer)
+}
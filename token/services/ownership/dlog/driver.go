@@ -0,0 +1,179 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dlog
+
+import (
+	"encoding/json"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/rp"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ownership/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Driver proves ownership of zkatdlog tokens without disclosing their quantities.
+// Given the commitments C_i = G_0^H(type) G_1^v_i G_2^r_i of the tokens, and the claimed amount X,
+// both parties compute C = \prod C_i / (G_0^{n H(type)} G_1^X) = G_1^{\sum v_i - X} G_2^{\sum r_i}.
+// The proof is a range proof showing that C commits to a value in [0, 2^BitLength), therefore \sum v_i >= X.
+type Driver struct{}
+
+func NewDriver() *Driver {
+	return &Driver{}
+}
+
+func (d *Driver) NewProver(pp *token.PublicParameters) (driver.Prover, error) {
+	dpp, err := publicParams(pp)
+	if err != nil {
+		return nil, err
+	}
+	return &Prover{pp: dpp}, nil
+}
+
+func (d *Driver) NewVerifier(pp *token.PublicParameters) (driver.Verifier, error) {
+	dpp, err := publicParams(pp)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{pp: dpp}, nil
+}
+
+func publicParams(pp *token.PublicParameters) (*crypto.PublicParams, error) {
+	dpp, ok := pp.PublicParameters.(*crypto.PublicParams)
+	if !ok {
+		return nil, errors.Errorf("invalid public parameters type, expected [%T], got [%T]", dpp, pp.PublicParameters)
+	}
+	return dpp, nil
+}
+
+type Prover struct {
+	pp *crypto.PublicParams
+}
+
+func (p *Prover) Prove(tokenType string, amount uint64, tokens []*driver.Token) ([]byte, error) {
+	c := math.Curves[p.pp.Curve]
+	var sum uint64
+	bf := c.NewZrFromInt(0)
+	for i, tok := range tokens {
+		output := &token3.Token{}
+		if err := output.Deserialize(tok.Output); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal token [%s]", tok.ID)
+		}
+		meta := &token3.Metadata{}
+		if err := meta.Deserialize(tok.Metadata); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal metadata of token [%s]", tok.ID)
+		}
+		inTheClear, err := output.GetTokenInTheClear(meta, p.pp)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid opening of token [%s]", tok.ID)
+		}
+		if inTheClear.Type != tokenType {
+			return nil, errors.Errorf("invalid token [%d]: expected type [%s], got [%s]", i, tokenType, inTheClear.Type)
+		}
+		q, err := token2.ToQuantity(inTheClear.Quantity, p.pp.Precision())
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid quantity of token [%s]", tok.ID)
+		}
+		sum += q.ToBigInt().Uint64()
+		bf = c.ModAdd(bf, meta.BlindingFactor, c.GroupOrder)
+	}
+	if sum < amount {
+		return nil, errors.Errorf("insufficient amount: the tokens hold [%d], less than [%d]", sum, amount)
+	}
+	if sum-amount >= 1<<p.pp.RangeProofParams.BitLength {
+		return nil, errors.Errorf("the tokens exceed the amount by more than the range proof can show, use fewer tokens")
+	}
+	outputs := make([][]byte, len(tokens))
+	for i, tok := range tokens {
+		outputs[i] = tok.Output
+	}
+	com, err := excess(p.pp, tokenType, amount, outputs)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := rp.NewRangeProver(
+		com,
+		sum-amount,
+		p.pp.PedersenGenerators[1:],
+		bf,
+		p.pp.RangeProofParams.LeftGenerators,
+		p.pp.RangeProofParams.RightGenerators,
+		p.pp.RangeProofParams.P,
+		p.pp.RangeProofParams.Q,
+		p.pp.RangeProofParams.NumberOfRounds,
+		p.pp.RangeProofParams.BitLength,
+		c,
+	).Prove()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate range proof")
+	}
+	return json.Marshal(proof)
+}
+
+type Verifier struct {
+	pp *crypto.PublicParams
+}
+
+func (v *Verifier) Verify(tokenType string, amount uint64, outputs [][]byte, raw []byte) ([]token.Identity, error) {
+	proof := &rp.RangeProof{}
+	if err := json.Unmarshal(raw, proof); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal range proof")
+	}
+	com, err := excess(v.pp, tokenType, amount, outputs)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.NewRangeVerifier(
+		com,
+		v.pp.PedersenGenerators[1:],
+		v.pp.RangeProofParams.LeftGenerators,
+		v.pp.RangeProofParams.RightGenerators,
+		v.pp.RangeProofParams.P,
+		v.pp.RangeProofParams.Q,
+		v.pp.RangeProofParams.NumberOfRounds,
+		v.pp.RangeProofParams.BitLength,
+		math.Curves[v.pp.Curve],
+	).Verify(proof); err != nil {
+		return nil, errors.Wrap(err, "invalid range proof")
+	}
+	owners := make([]token.Identity, len(outputs))
+	for i, raw := range outputs {
+		output := &token3.Token{}
+		if err := output.Deserialize(raw); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal output [%d]", i)
+		}
+		owners[i] = output.Owner
+	}
+	return owners, nil
+}
+
+// excess returns the commitment to the quantity held by the passed outputs in excess of the passed amount,
+// assuming the outputs are of the passed type
+func excess(pp *crypto.PublicParams, tokenType string, amount uint64, outputs [][]byte) (*math.G1, error) {
+	if len(outputs) == 0 {
+		return nil, errors.New("no outputs")
+	}
+	c := math.Curves[pp.Curve]
+	com := c.NewG1()
+	for i, raw := range outputs {
+		output := &token3.Token{}
+		if err := output.Deserialize(raw); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal output [%d]", i)
+		}
+		if output.Data == nil {
+			return nil, errors.Errorf("invalid output [%d]: nil commitment", i)
+		}
+		com.Add(output.Data)
+	}
+	typeHash := c.HashToZr([]byte(tokenType))
+	com.Sub(pp.PedersenGenerators[0].Mul(c.ModMul(typeHash, c.NewZrFromInt(int64(len(outputs))), c.GroupOrder)))
+	com.Sub(pp.PedersenGenerators[1].Mul(c.NewZrFromInt(int64(amount))))
+	return com, nil
+}
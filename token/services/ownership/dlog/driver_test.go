@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dlog
+
+import (
+	"testing"
+
+	math "github.com/IBM/mathlib"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ownership/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProveAndVerify(t *testing.T) {
+	pp, err := crypto.Setup(32, nil, math.FP256BN_AMCL)
+	assert.NoError(t, err)
+	c := math.Curves[pp.Curve]
+	coms, witnesses, err := token3.GetTokensWithWitness([]uint64{30, 50}, "USD", pp.PedersenGenerators, c)
+	assert.NoError(t, err)
+
+	tokens := make([]*driver.Token, len(coms))
+	outputs := make([][]byte, len(coms))
+	for i, com := range coms {
+		outputs[i], err = (&token3.Token{Owner: []byte("alice"), Data: com}).Serialize()
+		assert.NoError(t, err)
+		meta, err := (&token3.Metadata{
+			Type:           "USD",
+			Value:          c.NewZrFromInt(int64(witnesses[i].Value)),
+			BlindingFactor: witnesses[i].BlindingFactor,
+		}).Serialize()
+		assert.NoError(t, err)
+		tokens[i] = &driver.Token{ID: &token2.ID{TxId: "tx", Index: uint64(i)}, Output: outputs[i], Metadata: meta}
+	}
+
+	d := NewDriver()
+	prover, err := d.NewProver(&token.PublicParameters{PublicParameters: pp})
+	assert.NoError(t, err)
+	verifier, err := d.NewVerifier(&token.PublicParameters{PublicParameters: pp})
+	assert.NoError(t, err)
+
+	proof, err := prover.Prove("USD", 70, tokens)
+	assert.NoError(t, err)
+	owners, err := verifier.Verify("USD", 70, outputs, proof)
+	assert.NoError(t, err)
+	assert.Equal(t, []token.Identity{token.Identity("alice"), token.Identity("alice")}, owners)
+
+	// the proof does not support a larger amount, a different type, or a subset of the outputs
+	_, err = verifier.Verify("USD", 90, outputs, proof)
+	assert.Error(t, err)
+	_, err = verifier.Verify("EUR", 70, outputs, proof)
+	assert.Error(t, err)
+	_, err = verifier.Verify("USD", 70, outputs[:1], proof)
+	assert.Error(t, err)
+
+	_, err = prover.Prove("USD", 90, tokens)
+	assert.ErrorContains(t, err, "insufficient amount")
+}
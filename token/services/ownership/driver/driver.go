@@ -0,0 +1,41 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package driver
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// Token is an unspent token used to prove ownership
+type Token struct {
+	ID *token2.ID
+	// Output is the token as stored on the ledger
+	Output []byte
+	// Metadata is the opening of Output, known to the owner only
+	Metadata []byte
+}
+
+// Prover produces the driver-specific part of an ownership proof
+type Prover interface {
+	// Prove returns a proof that the passed tokens hold, together, at least the passed amount of the passed type
+	Prove(tokenType string, amount uint64, tokens []*Token) ([]byte, error)
+}
+
+// Verifier verifies the driver-specific part of an ownership proof
+type Verifier interface {
+	// Verify checks that the passed proof shows that the passed outputs, as stored on the ledger, hold, together,
+	// at least the passed amount of the passed type.
+	// It returns the owners of the outputs, in the same order.
+	Verify(tokenType string, amount uint64, outputs [][]byte, proof []byte) ([]token.Identity, error)
+}
+
+// Driver creates provers and verifiers for the public parameters of a token driver
+type Driver interface {
+	NewProver(pp *token.PublicParameters) (Prover, error)
+	NewVerifier(pp *token.PublicParameters) (Verifier, error)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ownership
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/drivers"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ownership/dlog"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ownership/driver"
+	fabtoken2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/ownership/fabtoken"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+var logger = logging.MustGetLogger("token-sdk.ownership")
+
+var holder = drivers.NewHolder[driver.Driver]()
+
+func init() {
+	Register(fabtoken.PublicParameters, fabtoken2.NewDriver())
+	Register(crypto.DLogPublicParameters, dlog.NewDriver())
+}
+
+// Register makes the passed driver available for the public parameters with the passed identifier
+func Register(name string, driver driver.Driver) { holder.Register(name, driver) }
+
+func Drivers() []string { return holder.DriverNames() }
+
+func getDriver(tms *token.ManagementService) (driver.Driver, *token.PublicParameters, error) {
+	pp := tms.PublicParametersManager().PublicParameters()
+	if pp == nil {
+		return nil, nil, errors.Errorf("no public parameters for [%s]", tms.ID())
+	}
+	d, ok := holder.Get(pp.Identifier())
+	if !ok {
+		return nil, nil, errors.Errorf("ownership driver [%s] not found", pp.Identifier())
+	}
+	return d, pp, nil
+}
+
+// Statement is the claim an ownership proof supports:
+// the prover owns at least Amount of TokenType at ledger height Height.
+type Statement struct {
+	TokenType string
+	Amount    uint64
+	Height    uint64
+	// Nonce is chosen by the verifier, it prevents the replay of a proof
+	Nonce []byte
+}
+
+// Proof shows that the owners of the listed tokens hold, together, at least the amount of the statement.
+// The owners sign the digest of the proof, see Digest.
+type Proof struct {
+	Statement Statement
+	TMSID     token.TMSID
+	Tokens    []*token2.ID
+	// Proof is the driver-specific proof on the outputs of Tokens
+	Proof []byte
+	// Signatures are the signatures of the owners of Tokens, in the same order
+	Signatures [][]byte
+}
+
+// Digest returns the message signed by the owners of the tokens
+func (p *Proof) Digest() ([]byte, error) {
+	raw, err := json.Marshal(&Proof{Statement: p.Statement, TMSID: p.TMSID, Tokens: p.Tokens, Proof: p.Proof})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal proof")
+	}
+	digest := sha256.Sum256(raw)
+	return digest[:], nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ownership
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ownership/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Prover produces ownership proofs for the wallets of a TMS
+type Prover struct {
+	tms     *token.ManagementService
+	tokenDB *tokendb.DB
+}
+
+// NewProver returns a new Prover for the passed TMS
+func NewProver(sp token.ServiceProvider, tms *token.ManagementService) (*Prover, error) {
+	tokenDB, err := tokendb.GetByTMSId(sp, tms.ID())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get tokendb for [%s]", tms.ID())
+	}
+	return &Prover{tms: tms, tokenDB: tokenDB}, nil
+}
+
+// Prove returns a proof that the passed wallet owns at least the amount of the passed statement.
+// The proof uses the fewest unspent tokens of the wallet, in the order they are listed, whose sum covers the amount.
+func (p *Prover) Prove(ctx context.Context, wallet *token.OwnerWallet, statement Statement) (*Proof, error) {
+	if wallet == nil {
+		return nil, errors.New("no wallet")
+	}
+	d, pp, err := getDriver(p.tms)
+	if err != nil {
+		return nil, err
+	}
+	prover, err := d.NewProver(pp)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to create prover for [%s]", p.tms.ID())
+	}
+
+	unspent, err := p.selectTokens(wallet, statement, pp.Precision())
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]*token2.ID, len(unspent))
+	for i, tok := range unspent {
+		ids[i] = tok.Id
+	}
+	outputs, metas, err := p.tokenDB.GetTokenInfoAndOutputs(ctx, ids)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get outputs of [%v]", ids)
+	}
+	tokens := make([]*driver.Token, len(ids))
+	for i, id := range ids {
+		tokens[i] = &driver.Token{ID: id, Output: outputs[i], Metadata: metas[i]}
+	}
+	raw, err := prover.Prove(statement.TokenType, statement.Amount, tokens)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to prove ownership of [%d] [%s]", statement.Amount, statement.TokenType)
+	}
+
+	proof := &Proof{Statement: statement, TMSID: p.tms.ID(), Tokens: ids, Proof: raw}
+	digest, err := proof.Digest()
+	if err != nil {
+		return nil, err
+	}
+	proof.Signatures = make([][]byte, len(unspent))
+	for i, tok := range unspent {
+		signer, err := wallet.GetSigner(tok.Owner)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get signer for the owner of [%s]", tok.Id)
+		}
+		if proof.Signatures[i], err = signer.Sign(digest); err != nil {
+			return nil, errors.WithMessagef(err, "failed to sign for the owner of [%s]", tok.Id)
+		}
+	}
+	logger.Debugf("proved ownership of [%d] [%s] with [%d] tokens", statement.Amount, statement.TokenType, len(ids))
+	return proof, nil
+}
+
+func (p *Prover) selectTokens(wallet *token.OwnerWallet, statement Statement, precision uint64) ([]*token2.UnspentToken, error) {
+	it, err := wallet.ListUnspentTokensIterator(token.WithType(statement.TokenType))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to list tokens of wallet [%s]", wallet.ID())
+	}
+	defer it.Close()
+	amount := new(big.Int).SetUint64(statement.Amount)
+	sum := token2.NewZeroQuantity(precision)
+	var selected []*token2.UnspentToken
+	for sum.ToBigInt().Cmp(amount) < 0 || len(selected) == 0 {
+		tok, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get next token of wallet [%s]", wallet.ID())
+		}
+		if tok == nil {
+			return nil, errors.Errorf("wallet [%s] holds [%s] [%s], less than [%d]", wallet.ID(), sum.Decimal(), statement.TokenType, statement.Amount)
+		}
+		q, err := token2.ToQuantity(tok.Quantity, precision)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid quantity of [%s]", tok.Id)
+		}
+		sum = sum.Add(q)
+		selected = append(selected, tok)
+	}
+	return selected, nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ownership
+
+import (
+	"bytes"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Verifier verifies the ownership proofs of a TMS, on behalf of third parties like lenders
+type Verifier struct {
+	tms *token.ManagementService
+}
+
+// NewVerifier returns a new Verifier for the passed TMS
+func NewVerifier(tms *token.ManagementService) *Verifier {
+	return &Verifier{tms: tms}
+}
+
+// Verify checks that the passed proof supports the expected statement.
+// The outputs of the tokens are fetched from the ledger, therefore the tokens must be unspent at the time of the verification.
+// The network does not expose past ledger heights: a proof for height H holds if the tokens are still unspent when verified.
+func (v *Verifier) Verify(context view.Context, proof *Proof, expected Statement) error {
+	if proof == nil {
+		return errors.New("no proof")
+	}
+	if !proof.TMSID.Equal(v.tms.ID()) {
+		return errors.Errorf("proof for [%s], expected [%s]", proof.TMSID, v.tms.ID())
+	}
+	st := proof.Statement
+	if st.TokenType != expected.TokenType || st.Amount != expected.Amount || st.Height != expected.Height || !bytes.Equal(st.Nonce, expected.Nonce) {
+		return errors.Errorf("proof for a different statement")
+	}
+	if len(proof.Tokens) == 0 || len(proof.Signatures) != len(proof.Tokens) {
+		return errors.Errorf("expected a signature for each of the [%d] tokens, got [%d]", len(proof.Tokens), len(proof.Signatures))
+	}
+	seen := map[token2.ID]struct{}{}
+	for _, id := range proof.Tokens {
+		if id == nil {
+			return errors.New("nil token id")
+		}
+		if _, ok := seen[*id]; ok {
+			return errors.Errorf("token [%s] appears more than once", id)
+		}
+		seen[*id] = struct{}{}
+	}
+	d, pp, err := getDriver(v.tms)
+	if err != nil {
+		return err
+	}
+	verifier, err := d.NewVerifier(pp)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to create verifier for [%s]", v.tms.ID())
+	}
+
+	net := network.GetInstance(context, v.tms.Network(), v.tms.Channel())
+	if net == nil {
+		return errors.Errorf("cannot find network [%s:%s]", v.tms.Network(), v.tms.Channel())
+	}
+	outputs, err := net.QueryTokens(context, v.tms.Namespace(), proof.Tokens)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to query tokens [%v]", proof.Tokens)
+	}
+	owners, err := verifier.Verify(st.TokenType, st.Amount, outputs, proof.Proof)
+	if err != nil {
+		return errors.WithMessage(err, "invalid ownership proof")
+	}
+
+	digest, err := proof.Digest()
+	if err != nil {
+		return err
+	}
+	sigService := v.tms.SigService()
+	for i, owner := range owners {
+		ver, err := sigService.OwnerVerifier(owner)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to get verifier for the owner of [%s]", proof.Tokens[i])
+		}
+		if err := ver.Verify(digest, proof.Signatures[i]); err != nil {
+			return errors.WithMessagef(err, "invalid signature of the owner of [%s]", proof.Tokens[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package provenance
+
+import (
+	"reflect"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+var (
+	logger      = logging.MustGetLogger("token-sdk.provenance")
+	serviceType = reflect.TypeOf((*Service)(nil))
+)
+
+// MaxDepth is the maximum number of transactions a provenance walks back
+const MaxDepth = 100
+
+// TokenDB is a database storing the tokens of the wallets of the node, like the token db
+type TokenDB interface {
+	QueryTokenDetails(params driver.QueryTokenDetailsParams) ([]driver.TokenDetails, error)
+}
+
+// TransactionDB is a database storing token requests and transaction records, like the ttxdb and the auditdb
+type TransactionDB interface {
+	GetTokenRequest(txID string) ([]byte, error)
+	Transactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error)
+}
+
+// DBs are the databases of a TMS a provenance goes through. The auditdb is nil if not available.
+type DBs struct {
+	TokenDB       TokenDB
+	TransactionDB TransactionDB
+	AuditDB       TransactionDB
+}
+
+// DBProvider returns the databases of a TMS
+type DBProvider = func(tmsID token.TMSID) (*DBs, error)
+
+// TokenNode is a token of a provenance graph
+type TokenNode struct {
+	ID token2.ID
+	// Details are the details stored in the token db, nil if the token is not owned by a wallet of this node
+	Details *driver.TokenDetails
+}
+
+// TransactionNode is a transaction of a provenance graph, it spends its inputs and creates tokens
+type TransactionNode struct {
+	TxID string
+	// Inputs are the tokens spent by the transaction, none for an issue
+	Inputs []token2.ID
+	// Records are the transaction records stored for the transaction, by the ttxdb or by the auditdb
+	Records []*driver.TransactionRecord
+	// Unlinked is true if some inputs of the transaction are unknown,
+	// because the driver hides them or the token request was filtered for this node
+	Unlinked bool
+	// Expanded is false if the transactions creating the inputs were not walked because of the depth
+	Expanded bool
+}
+
+// Graph is the provenance of a token: the transactions it descends from, up to a depth.
+// Tokens and Transactions are listed in the order they are walked, starting from the root.
+type Graph struct {
+	Root         token2.ID
+	Depth        int
+	Tokens       []*TokenNode
+	Transactions []*TransactionNode
+}
+
+// Token returns the node of the passed token, nil if not in the graph
+func (g *Graph) Token(id token2.ID) *TokenNode {
+	for _, t := range g.Tokens {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// Transaction returns the node of the passed transaction, nil if not in the graph
+func (g *Graph) Transaction(txID string) *TransactionNode {
+	for _, t := range g.Transactions {
+		if t.TxID == txID {
+			return t
+		}
+	}
+	return nil
+}
+
+// Service walks back the provenance of the tokens of the TMSs.
+// The inputs of a transaction are given by the token ids in the metadata of its token request,
+// the auditdb one if available, and by the tokens of the token db spent by the transaction.
+// Therefore, the provenance is complete only for the drivers not hiding the transaction graph, such as fabtoken,
+// and for the auditors, whose token requests are not filtered.
+type Service struct {
+	dbs DBProvider
+}
+
+// NewService returns a new Service over the databases of the passed managers
+func NewService(tokenDBs *tokendb.Manager, ttxDBs *ttxdb.Manager, auditDBs *auditdb.Manager) *Service {
+	return newService(func(tmsID token.TMSID) (*DBs, error) {
+		tokenDB, err := tokenDBs.DBByTMSId(tmsID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get token db for [%s]", tmsID)
+		}
+		ttxDB, err := ttxDBs.DBByTMSId(tmsID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get ttxdb for [%s]", tmsID)
+		}
+		auditDB, err := auditDBs.DBByTMSId(tmsID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get auditdb for [%s]", tmsID)
+		}
+		return &DBs{TokenDB: tokenDB, TransactionDB: ttxDB, AuditDB: auditDB}, nil
+	})
+}
+
+func newService(dbs DBProvider) *Service {
+	return &Service{dbs: dbs}
+}
+
+// GetService returns the Service registered in the passed service provider
+func GetService(sp token.ServiceProvider) (*Service, error) {
+	s, err := sp.GetService(serviceType)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting provenance service")
+	}
+	return s.(*Service), nil
+}
+
+// Provenance returns the provenance graph of the passed token, walking back at most depth transactions from it
+func (s *Service) Provenance(tmsID token.TMSID, tokenID token2.ID, depth int) (*Graph, error) {
+	if depth <= 0 || depth > MaxDepth {
+		return nil, errors.Errorf("the depth must be between 1 and %d, got [%d]", MaxDepth, depth)
+	}
+	dbs, err := s.dbs(tmsID)
+	if err != nil {
+		return nil, err
+	}
+	w := &walker{dbs: dbs, graph: &Graph{Root: tokenID, Depth: depth}, tokens: map[token2.ID]bool{}, txs: map[string]bool{}}
+	if err := w.addTokens([]token2.ID{tokenID}); err != nil {
+		return nil, err
+	}
+	// breadth first, one level of transactions at a time
+	level := []string{tokenID.TxId}
+	for d := 1; d <= depth && len(level) != 0; d++ {
+		var next []string
+		for _, txID := range level {
+			if w.txs[txID] {
+				continue
+			}
+			w.txs[txID] = true
+			tx, err := w.transaction(txID)
+			if err != nil {
+				return nil, err
+			}
+			tx.Expanded = d < depth
+			w.graph.Transactions = append(w.graph.Transactions, tx)
+			if err := w.addTokens(tx.Inputs); err != nil {
+				return nil, err
+			}
+			if tx.Expanded {
+				for _, in := range tx.Inputs {
+					next = append(next, in.TxId)
+				}
+			}
+		}
+		level = next
+	}
+	logger.Debugf("provenance of [%s] in [%s]: [%d] tokens, [%d] transactions", tokenID, tmsID, len(w.graph.Tokens), len(w.graph.Transactions))
+	return w.graph, nil
+}
+
+type walker struct {
+	dbs    *DBs
+	graph  *Graph
+	tokens map[token2.ID]bool
+	txs    map[string]bool
+}
+
+// addTokens adds to the graph the passed tokens not added yet, with their details if stored in the token db
+func (w *walker) addTokens(ids []token2.ID) error {
+	var added []*token2.ID
+	nodes := map[token2.ID]*TokenNode{}
+	for _, id := range ids {
+		if w.tokens[id] {
+			continue
+		}
+		w.tokens[id] = true
+		node := &TokenNode{ID: id}
+		w.graph.Tokens = append(w.graph.Tokens, node)
+		nodes[id] = node
+		added = append(added, &token2.ID{TxId: id.TxId, Index: id.Index})
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	details, err := w.dbs.TokenDB.QueryTokenDetails(driver.QueryTokenDetailsParams{IDs: added, IncludeDeleted: true})
+	if err != nil {
+		return errors.WithMessagef(err, "failed to query token details")
+	}
+	for i := range details {
+		// a token owned by several wallets gets the details of the first one
+		if node := nodes[token2.ID{TxId: details[i].TxID, Index: details[i].Index}]; node != nil && node.Details == nil {
+			node.Details = &details[i]
+		}
+	}
+	return nil
+}
+
+// transaction returns the node of the passed transaction, with its inputs and records
+func (w *walker) transaction(txID string) (*TransactionNode, error) {
+	tx := &TransactionNode{TxID: txID}
+	inputs := map[token2.ID]bool{}
+	addInput := func(id token2.ID) {
+		if !inputs[id] {
+			inputs[id] = true
+			tx.Inputs = append(tx.Inputs, id)
+		}
+	}
+
+	// the token request, the one of the auditdb first since it is not filtered
+	db := w.dbs.TransactionDB
+	raw, err := db.GetTokenRequest(txID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get token request [%s]", txID)
+	}
+	if w.dbs.AuditDB != nil {
+		auditRaw, err := w.dbs.AuditDB.GetTokenRequest(txID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get audited token request [%s]", txID)
+		}
+		if len(auditRaw) != 0 {
+			raw, db = auditRaw, w.dbs.AuditDB
+		}
+	}
+	if len(raw) != 0 {
+		request := token.NewRequest(nil, txID)
+		if err := request.FromBytes(raw); err != nil {
+			return nil, errors.WithMessagef(err, "failed to unmarshal token request [%s]", txID)
+		}
+		// the metadata lists the ids of the spent tokens, unless the driver hides them
+		tx.Unlinked = len(request.Metadata.Transfers) != len(request.Actions.Transfers)
+		for i, transfer := range request.Metadata.Transfers {
+			if len(transfer.TokenIDs) < len(transfer.Senders) {
+				logger.Debugf("inputs of transfer [%d] of [%s] are unknown", i, txID)
+				tx.Unlinked = true
+			}
+			for _, id := range transfer.TokenIDs {
+				if id == nil {
+					tx.Unlinked = true
+					continue
+				}
+				addInput(*id)
+			}
+		}
+	} else {
+		// without the token request, the tokens of this node spent by the transaction are known only
+		tx.Unlinked = true
+	}
+
+	// the tokens of the token db spent by the transaction
+	spent, err := w.dbs.TokenDB.QueryTokenDetails(driver.QueryTokenDetailsParams{SpentBy: []string{txID}, IncludeDeleted: true})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query the tokens spent by [%s]", txID)
+	}
+	for _, d := range spent {
+		addInput(token2.ID{TxId: d.TxID, Index: d.Index})
+	}
+
+	it, err := db.Transactions(driver.QueryTransactionsParams{IDs: []string{txID}})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query the records of [%s]", txID)
+	}
+	defer it.Close()
+	for {
+		record, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to read the records of [%s]", txID)
+		}
+		if record == nil {
+			return tx, nil
+		}
+		tx.Records = append(tx.Records, record)
+	}
+}
+
+// ProvenanceView returns the provenance Graph of a token
+type ProvenanceView struct {
+	TMSID   token.TMSID
+	TokenID token2.ID
+	Depth   int
+}
+
+func NewProvenanceView(tmsID token.TMSID, tokenID token2.ID, depth int) *ProvenanceView {
+	return &ProvenanceView{TMSID: tmsID, TokenID: tokenID, Depth: depth}
+}
+
+func (p *ProvenanceView) Call(context view.Context) (interface{}, error) {
+	s, err := GetService(context)
+	if err != nil {
+		return nil, err
+	}
+	return s.Provenance(p.TMSID, p.TokenID, p.Depth)
+}
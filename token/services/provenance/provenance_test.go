@@ -0,0 +1,170 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package provenance
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/common/utils/collections"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	tdriver "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+type tokenDB struct {
+	tokens []driver.TokenDetails
+}
+
+func (db *tokenDB) QueryTokenDetails(params driver.QueryTokenDetailsParams) ([]driver.TokenDetails, error) {
+	var res []driver.TokenDetails
+	for _, d := range db.tokens {
+		for _, id := range params.IDs {
+			if d.TxID == id.TxId && d.Index == id.Index {
+				res = append(res, d)
+			}
+		}
+		for _, txID := range params.SpentBy {
+			if d.SpentBy == txID {
+				res = append(res, d)
+			}
+		}
+	}
+	return res, nil
+}
+
+type transactionDB struct {
+	requests map[string][]byte
+	records  map[string][]*driver.TransactionRecord
+}
+
+func (db *transactionDB) GetTokenRequest(txID string) ([]byte, error) {
+	return db.requests[txID], nil
+}
+
+func (db *transactionDB) Transactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error) {
+	var res []*driver.TransactionRecord
+	for _, txID := range params.IDs {
+		res = append(res, db.records[txID]...)
+	}
+	return collections.NewSliceIterator(res), nil
+}
+
+// request returns a token request with a transfer spending the passed tokens, an issue if none
+func request(t *testing.T, txID string, inputs ...*token2.ID) []byte {
+	r := token.NewRequest(nil, txID)
+	if len(inputs) != 0 {
+		r.Actions.Transfers = [][]byte{[]byte("transfer")}
+		senders := make([]tdriver.Identity, len(inputs))
+		for i := range senders {
+			senders[i] = tdriver.Identity("sender")
+		}
+		r.Metadata.Transfers = []tdriver.TransferMetadata{{TokenIDs: inputs, Senders: senders}}
+	} else {
+		r.Actions.Issues = [][]byte{[]byte("issue")}
+	}
+	raw, err := r.Bytes()
+	assert.NoError(t, err)
+	return raw
+}
+
+// hidden returns a token request with a transfer not revealing the tokens it spends
+func hidden(t *testing.T, txID string) []byte {
+	r := token.NewRequest(nil, txID)
+	r.Actions.Transfers = [][]byte{[]byte("transfer")}
+	r.Metadata.Transfers = []tdriver.TransferMetadata{{Senders: []tdriver.Identity{tdriver.Identity("sender")}}}
+	raw, err := r.Bytes()
+	assert.NoError(t, err)
+	return raw
+}
+
+func TestProvenance(t *testing.T) {
+	// i1 and i2 issue, t1 spends their outputs, t2 spends the first output of t1, t3 hides its inputs
+	ttxDB := &transactionDB{
+		requests: map[string][]byte{
+			"t2": request(t, "t2", &token2.ID{TxId: "t1", Index: 0}),
+			"t3": hidden(t, "t3"),
+		},
+		records: map[string][]*driver.TransactionRecord{
+			"t2": {{TxID: "t2", TokenType: "USD", SenderEID: "alice", RecipientEID: "bob"}},
+		},
+	}
+	auditDB := &transactionDB{
+		requests: map[string][]byte{
+			"i1": request(t, "i1"),
+			"t1": request(t, "t1", &token2.ID{TxId: "i1", Index: 0}, &token2.ID{TxId: "i2", Index: 0}),
+		},
+		records: map[string][]*driver.TransactionRecord{
+			"t1": {{TxID: "t1", TokenType: "USD", RecipientEID: "alice"}},
+		},
+	}
+	tokenDB := &tokenDB{tokens: []driver.TokenDetails{
+		{TxID: "t2", Index: 0, OwnerEnrollment: "bob", Type: "USD", Amount: 10},
+		{TxID: "t1", Index: 0, OwnerEnrollment: "bob", Type: "USD", Amount: 10, IsSpent: true, SpentBy: "t2"},
+		// t3 spends a token of this node
+		{TxID: "t2", Index: 1, Type: "USD", Amount: 5, IsSpent: true, SpentBy: "t3"},
+	}}
+	tmsID := token.TMSID{Network: "n", Channel: "c", Namespace: "ns"}
+	dbs := &DBs{TokenDB: tokenDB, TransactionDB: ttxDB, AuditDB: auditDB}
+	s := newService(func(token.TMSID) (*DBs, error) { return dbs, nil })
+
+	// full provenance
+	root := token2.ID{TxId: "t2", Index: 0}
+	g, err := s.Provenance(tmsID, root, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, root, g.Root)
+	assert.Len(t, g.Transactions, 4)
+	assert.Len(t, g.Tokens, 4)
+	assert.Equal(t, uint64(10), g.Token(root).Details.Amount)
+	assert.NotNil(t, g.Token(token2.ID{TxId: "t1", Index: 0}).Details)
+	assert.Nil(t, g.Token(token2.ID{TxId: "i1", Index: 0}).Details)
+
+	t2 := g.Transaction("t2")
+	assert.Equal(t, []token2.ID{{TxId: "t1", Index: 0}}, t2.Inputs)
+	assert.Equal(t, "alice", t2.Records[0].SenderEID)
+	assert.False(t, t2.Unlinked)
+	assert.True(t, t2.Expanded)
+
+	// the auditdb request and records are preferred
+	t1 := g.Transaction("t1")
+	assert.Equal(t, []token2.ID{{TxId: "i1", Index: 0}, {TxId: "i2", Index: 0}}, t1.Inputs)
+	assert.Len(t, t1.Records, 1)
+	assert.False(t, t1.Unlinked)
+
+	i1 := g.Transaction("i1")
+	assert.Empty(t, i1.Inputs)
+	assert.False(t, i1.Unlinked)
+	// no request for i2
+	assert.True(t, g.Transaction("i2").Unlinked)
+
+	// limited depth
+	g, err = s.Provenance(tmsID, root, 1)
+	assert.NoError(t, err)
+	assert.Len(t, g.Transactions, 1)
+	assert.Len(t, g.Tokens, 2)
+	assert.False(t, g.Transaction("t2").Expanded)
+
+	// the inputs of t3 are hidden, only the token of this node it spends is known
+	g, err = s.Provenance(tmsID, token2.ID{TxId: "t3", Index: 0}, 1)
+	assert.NoError(t, err)
+	t3 := g.Transaction("t3")
+	assert.Equal(t, []token2.ID{{TxId: "t2", Index: 1}}, t3.Inputs)
+	assert.True(t, t3.Unlinked)
+
+	// without auditdb
+	dbs.AuditDB = nil
+	g, err = s.Provenance(tmsID, root, 10)
+	assert.NoError(t, err)
+	assert.True(t, g.Transaction("t1").Unlinked)
+	assert.Empty(t, g.Transaction("t1").Inputs)
+
+	_, err = s.Provenance(tmsID, root, 0)
+	assert.Error(t, err)
+	_, err = s.Provenance(tmsID, root, MaxDepth+1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package search
+
+import (
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/pkg/errors"
+)
+
+// ErrEmptyTerm is returned when the search term is empty
+var ErrEmptyTerm = errors.New("empty search term")
+
+// Source is the database a hit comes from
+type Source string
+
+const (
+	// TokenSource is the token db
+	TokenSource Source = "tokendb"
+	// TransactionSource is the ttxdb, storing the transactions of the owner wallets
+	TransactionSource Source = "ttxdb"
+	// AuditSource is the auditdb, storing the transactions audited by the node
+	AuditSource Source = "auditdb"
+)
+
+// Match is the field that matched the search term
+type Match string
+
+const (
+	// MatchTxID is used when the term is a prefix of the transaction id
+	MatchTxID Match = "tx_id"
+	// MatchEnrollmentID is used when the term is the enrollment id of a sender, a recipient or the wallet of a token
+	MatchEnrollmentID Match = "enrollment_id"
+	// MatchTokenType is used when the term is the token type
+	MatchTokenType Match = "token_type"
+)
+
+// Hit is a result of a search.
+// A hit from the ttxdb or the auditdb carries the records of a transaction, a hit from the token db carries a token.
+type Hit struct {
+	Source Source
+	Match  Match
+	TxID   string
+	// Transactions are the records of the transaction, set for the hits of the ttxdb and the auditdb
+	Transactions []*driver.TransactionRecord
+	// Token is the token, set for the hits of the token db
+	Token *driver.TokenDetails
+}
+
+// TokenDB is the token db as seen by the search
+type TokenDB interface {
+	QueryTokenDetails(params driver.QueryTokenDetailsParams) ([]driver.TokenDetails, error)
+}
+
+// TransactionDB is the ttxdb, or the auditdb, as seen by the search
+type TransactionDB interface {
+	Transactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error)
+}
+
+// Service searches a free-form term across the token db, the ttxdb and the auditdb of a TMS,
+// so that an operator console needs a single integration to locate transactions and tokens.
+type Service struct {
+	tokenDB TokenDB
+	ttxDB   TransactionDB
+	auditDB TransactionDB
+}
+
+// NewService returns a new Service for the passed databases. Nil databases are skipped.
+func NewService(tokenDB TokenDB, ttxDB TransactionDB, auditDB TransactionDB) *Service {
+	return &Service{tokenDB: tokenDB, ttxDB: ttxDB, auditDB: auditDB}
+}
+
+// GetService returns the Service over the databases of the passed TMS
+func GetService(sp token.ServiceProvider, tmsID token.TMSID) (*Service, error) {
+	tokenDB, err := tokendb.GetByTMSId(sp, tmsID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get token db for [%s]", tmsID)
+	}
+	ttxDB, err := ttxdb.GetByTMSId(sp, tmsID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get ttxdb for [%s]", tmsID)
+	}
+	auditDB, err := auditdb.GetByTMSId(sp, tmsID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get auditdb for [%s]", tmsID)
+	}
+	return NewService(tokenDB, ttxDB, auditDB), nil
+}
+
+// Search returns the hits for the passed term, which can be a prefix of a transaction id, an enrollment id, or a token type.
+// The hits of the ttxdb come first, then those of the auditdb and of the token db.
+// At most limit hits are returned, if limit is positive.
+func (s *Service) Search(term string, limit int) ([]Hit, error) {
+	term = strings.TrimSpace(term)
+	if len(term) == 0 {
+		return nil, ErrEmptyTerm
+	}
+	r := &results{limit: limit}
+	for _, tdb := range []struct {
+		source Source
+		db     TransactionDB
+	}{{TransactionSource, s.ttxDB}, {AuditSource, s.auditDB}} {
+		if tdb.db == nil || r.full() {
+			continue
+		}
+		if err := r.searchTransactions(tdb.source, tdb.db, term); err != nil {
+			return nil, errors.WithMessagef(err, "failed to search [%s]", tdb.source)
+		}
+	}
+	if s.tokenDB != nil && !r.full() {
+		if err := r.searchTokens(s.tokenDB, term); err != nil {
+			return nil, errors.WithMessagef(err, "failed to search [%s]", TokenSource)
+		}
+	}
+	return r.hits, nil
+}
+
+type results struct {
+	limit int
+	hits  []Hit
+	// txIDs are the ids of the transactions matched by prefix
+	txIDs []string
+}
+
+func (r *results) full() bool {
+	return r.limit > 0 && len(r.hits) >= r.limit
+}
+
+func (r *results) searchTransactions(source Source, db TransactionDB, term string) error {
+	// a transaction is reported once, with the first field that matched
+	hits := map[string]int{}
+	for _, q := range []struct {
+		match  Match
+		params driver.QueryTransactionsParams
+	}{
+		{MatchTxID, driver.QueryTransactionsParams{IDPrefix: term}},
+		{MatchEnrollmentID, driver.QueryTransactionsParams{EnrollmentIDs: []string{term}}},
+		{MatchTokenType, driver.QueryTransactionsParams{TokenTypes: []string{term}}},
+	} {
+		it, err := db.Transactions(q.params)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to query transactions by [%s]", q.match)
+		}
+		err = r.collectTransactions(source, q.match, it, hits, term)
+		it.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *results) collectTransactions(source Source, match Match, it driver.TransactionIterator, hits map[string]int, term string) error {
+	for {
+		record, err := it.Next()
+		if err != nil {
+			return errors.WithMessagef(err, "failed to get next transaction record")
+		}
+		if record == nil {
+			return nil
+		}
+		// '%' and '_' act as wildcards in the prefix query
+		if match == MatchTxID && !strings.HasPrefix(record.TxID, term) {
+			continue
+		}
+		if i, ok := hits[record.TxID]; ok {
+			if r.hits[i].Match == match {
+				r.hits[i].Transactions = append(r.hits[i].Transactions, record)
+			}
+			continue
+		}
+		if r.full() {
+			continue
+		}
+		hits[record.TxID] = len(r.hits)
+		r.hits = append(r.hits, Hit{Source: source, Match: match, TxID: record.TxID, Transactions: []*driver.TransactionRecord{record}})
+		if match == MatchTxID {
+			r.txIDs = append(r.txIDs, record.TxID)
+		}
+	}
+}
+
+func (r *results) searchTokens(db TokenDB, term string) error {
+	filters := []driver.TokenFilter{{TokenType: term}, {WalletID: term}}
+	if len(r.txIDs) > 0 {
+		filters = append(filters, driver.TokenFilter{TransactionIDs: r.txIDs})
+	}
+	tokens, err := db.QueryTokenDetails(driver.QueryTokenDetailsParams{IncludeDeleted: true, AnyOf: filters})
+	if err != nil {
+		return errors.WithMessagef(err, "failed to query tokens")
+	}
+	for i := range tokens {
+		if r.full() {
+			return nil
+		}
+		tok := &tokens[i]
+		match := MatchEnrollmentID
+		switch {
+		case strings.HasPrefix(tok.TxID, term):
+			match = MatchTxID
+		case tok.Type == term:
+			match = MatchTokenType
+		}
+		r.hits = append(r.hits, Hit{Source: TokenSource, Match: match, TxID: tok.TxID, Token: tok})
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package search
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+type transactionDB []*driver.TransactionRecord
+
+func (db transactionDB) Transactions(params driver.QueryTransactionsParams) (driver.TransactionIterator, error) {
+	var records []*driver.TransactionRecord
+	for _, r := range db {
+		switch {
+		case len(params.IDPrefix) > 0 && strings.HasPrefix(r.TxID, params.IDPrefix),
+			slices.Contains(params.EnrollmentIDs, r.SenderEID) || slices.Contains(params.EnrollmentIDs, r.RecipientEID),
+			slices.Contains(params.TokenTypes, r.TokenType):
+			records = append(records, r)
+		}
+	}
+	return &transactionIterator{records: records}, nil
+}
+
+type transactionIterator struct{ records []*driver.TransactionRecord }
+
+func (it *transactionIterator) Close() {}
+
+func (it *transactionIterator) Next() (*driver.TransactionRecord, error) {
+	if len(it.records) == 0 {
+		return nil, nil
+	}
+	next := it.records[0]
+	it.records = it.records[1:]
+	return next, nil
+}
+
+type tokenDB map[string][]driver.TokenDetails
+
+func (db tokenDB) QueryTokenDetails(params driver.QueryTokenDetailsParams) ([]driver.TokenDetails, error) {
+	var tokens []driver.TokenDetails
+	for wallet, ts := range db {
+		for _, tok := range ts {
+			for _, f := range params.AnyOf {
+				if f.WalletID == wallet || f.TokenType == tok.Type || slices.Contains(f.TransactionIDs, tok.TxID) {
+					tokens = append(tokens, tok)
+					break
+				}
+			}
+		}
+	}
+	return tokens, nil
+}
+
+func TestSearch(t *testing.T) {
+	ttxDB := transactionDB{
+		{TxID: "abc1", SenderEID: "alice", RecipientEID: "bob", TokenType: "USD"},
+		{TxID: "abc1", SenderEID: "alice", RecipientEID: "alice", TokenType: "USD"},
+		{TxID: "def2", SenderEID: "bob", RecipientEID: "charlie", TokenType: "EUR"},
+	}
+	auditDB := transactionDB{
+		{TxID: "abc9", SenderEID: "dan", RecipientEID: "erin", TokenType: "EUR"},
+	}
+	tokens := tokenDB{
+		"bob":     {{TxID: "abc1", Index: 0, Type: "USD"}},
+		"charlie": {{TxID: "def2", Index: 0, Type: "EUR"}},
+	}
+	s := NewService(tokens, ttxDB, auditDB)
+
+	_, err := s.Search(" ", 0)
+	assert.ErrorIs(t, err, ErrEmptyTerm)
+
+	// transaction id prefix
+	hits, err := s.Search("abc", 0)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 3)
+	assert.Equal(t, Hit{Source: TransactionSource, Match: MatchTxID, TxID: "abc1", Transactions: ttxDB[:2]}, hits[0])
+	assert.Equal(t, Hit{Source: AuditSource, Match: MatchTxID, TxID: "abc9", Transactions: auditDB}, hits[1])
+	assert.Equal(t, TokenSource, hits[2].Source)
+	assert.Equal(t, MatchTxID, hits[2].Match)
+	assert.Equal(t, "abc1", hits[2].Token.TxID)
+
+	// enrollment id, matching a sender, a recipient and a wallet
+	hits, err = s.Search("bob", 0)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 3)
+	assert.Equal(t, "abc1", hits[0].TxID)
+	assert.Equal(t, "def2", hits[1].TxID)
+	for _, hit := range hits {
+		assert.Equal(t, MatchEnrollmentID, hit.Match)
+	}
+
+	// token type
+	hits, err = s.Search("EUR", 0)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 3)
+	for _, hit := range hits {
+		assert.Equal(t, MatchTokenType, hit.Match)
+	}
+
+	// limit
+	hits, err = s.Search("EUR", 2)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 2)
+	assert.Equal(t, AuditSource, hits[1].Source)
+
+	// missing databases are skipped
+	hits, err = NewService(nil, ttxDB, nil).Search("abc", 0)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+}
@@ -39,6 +39,12 @@ type extendedSelector struct {
 func (s *extendedSelector) Select(ownerFilter token.OwnerFilter, q, tokenType string) ([]*token2.ID, token2.Quantity, error) {
 	return s.Selector.Select(ownerFilter, q, tokenType)
 }
+func (s *extendedSelector) Preview(ownerFilter token.OwnerFilter, q, tokenType string) (*token.SelectionPreview, error) {
+	return s.Selector.Preview(ownerFilter, q, tokenType)
+}
+func (s *extendedSelector) SelectMany(ownerFilter token.OwnerFilter, quantities map[string]string) (map[string]*token.SelectedTokens, error) {
+	return s.Selector.SelectMany(ownerFilter, quantities)
+}
 func (s *extendedSelector) Close() error { return s.Selector.Close() }
 
 func (s *extendedSelector) Unselect(id ...*token2.ID) {
@@ -16,6 +16,7 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/common/metrics"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/features"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
@@ -33,6 +34,10 @@ type TokenDB interface {
 	SpendableTokensIteratorBy(ctx context.Context, walletID string, typ string) (driver.SpendableTokensIterator, error)
 }
 
+type PendingTokenDB interface {
+	SpendablePendingTokensIteratorBy(ctx context.Context, walletID string, typ string) (driver.SpendableTokensIterator, error)
+}
+
 type enhancedIterator[T any] interface {
 	HasNext() bool
 }
@@ -58,11 +63,17 @@ type FetcherProvider interface {
 
 type fetchFunc func(db *tokendb.DB, notifier *tokendb.Notifier, m *Metrics) tokenFetcher
 
+// FeatureFlags tells which features are on for a TMS
+type FeatureFlags interface {
+	Enabled(tmsID token.TMSID, flag features.Flag) bool
+}
+
 type fetcherProvider struct {
 	dbManager       *tokendb.Manager
 	notifierManager *tokendb.NotifierManager
 	metrics         *Metrics
 	fetch           fetchFunc
+	flags           FeatureFlags
 }
 
 var fetchers = map[FetcherStrategy]fetchFunc{
@@ -71,7 +82,10 @@ var fetchers = map[FetcherStrategy]fetchFunc{
 	},
 }
 
-func NewFetcherProvider(dbManager *tokendb.Manager, notifierManager *tokendb.NotifierManager, metricsProvider metrics.Provider, strategy FetcherStrategy) *fetcherProvider {
+// NewFetcherProvider returns a provider of fetchers with the passed strategy.
+// The TMSs with the features.LazySelector flag on, when their fetcher is created, get a lazy fetcher instead.
+// The fetchers of the TMSs with the features.PendingSelector flag on return the pending tokens too.
+func NewFetcherProvider(dbManager *tokendb.Manager, notifierManager *tokendb.NotifierManager, metricsProvider metrics.Provider, strategy FetcherStrategy, flags FeatureFlags) *fetcherProvider {
 	fetcher, ok := fetchers[strategy]
 	if !ok {
 		panic("undefined fetcher strategy: " + strategy)
@@ -81,6 +95,7 @@ func NewFetcherProvider(dbManager *tokendb.Manager, notifierManager *tokendb.Not
 		notifierManager: notifierManager,
 		metrics:         newMetrics(metricsProvider),
 		fetch:           fetcher,
+		flags:           flags,
 	}
 }
 
@@ -94,7 +109,66 @@ func (p *fetcherProvider) GetFetcher(tmsID token.TMSID) (tokenFetcher, error) {
 		return nil, err
 	}
 
-	return p.fetch(tokenDB, tokenNotifier, p.metrics), nil
+	var fetcher tokenFetcher
+	if p.flags != nil && p.flags.Enabled(tmsID, features.LazySelector) {
+		logger.Debugf("lazy selector enabled for [%s]", tmsID)
+		fetcher = NewLazyFetcher(tokenDB)
+	} else {
+		fetcher = p.fetch(tokenDB, tokenNotifier, p.metrics)
+	}
+	if p.flags != nil && p.flags.Enabled(tmsID, features.PendingSelector) {
+		logger.Debugf("pending selector enabled for [%s]", tmsID)
+		fetcher = NewPendingFetcher(fetcher, tokenDB)
+	}
+	return fetcher, nil
+}
+
+// pendingFetcher returns the pending tokens after those returned by the underlying fetcher,
+// so that the tokens of final transactions are spent first
+type pendingFetcher struct {
+	fetcher tokenFetcher
+	tokenDB PendingTokenDB
+}
+
+func NewPendingFetcher(fetcher tokenFetcher, tokenDB PendingTokenDB) *pendingFetcher {
+	return &pendingFetcher{fetcher: fetcher, tokenDB: tokenDB}
+}
+
+func (f *pendingFetcher) UnspentTokensIteratorBy(walletID, currency string) (iterator[*token2.UnspentTokenInWallet], error) {
+	it, err := f.fetcher.UnspentTokensIteratorBy(walletID, currency)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := f.tokenDB.SpendablePendingTokensIteratorBy(context.TODO(), walletID, currency)
+	if err != nil {
+		it.Close()
+		return nil, err
+	}
+	return &concatIterator{its: []iterator[*token2.UnspentTokenInWallet]{it, pending}}, nil
+}
+
+// concatIterator returns the elements of the passed iterators, one iterator after the other
+type concatIterator struct {
+	its []iterator[*token2.UnspentTokenInWallet]
+}
+
+func (it *concatIterator) Next() (*token2.UnspentTokenInWallet, error) {
+	for len(it.its) > 0 {
+		t, err := it.its[0].Next()
+		if err != nil || t != nil {
+			return t, err
+		}
+		it.its[0].Close()
+		it.its = it.its[1:]
+	}
+	return nil, nil
+}
+
+func (it *concatIterator) Close() {
+	for _, i := range it.its {
+		i.Close()
+	}
+	it.its = nil
 }
 
 // mixedFetcher combines both eager and lazy strategies
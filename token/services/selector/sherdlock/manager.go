@@ -11,6 +11,7 @@ import (
 
 	lazy2 "github.com/hyperledger-labs/fabric-smart-client/platform/common/utils/lazy"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/utils/types/transaction"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
@@ -89,6 +90,10 @@ func (m *manager) cleaner() {
 	for range ticker.C {
 		logger.Debugf("release token locks older than [%s]", m.leaseExpiry)
 		if err := m.locker.Cleanup(m.leaseExpiry); err != nil {
+			if errors.Is(err, db.ErrStopped) {
+				logger.Infof("token lock db stopped, stop releasing expired token locks")
+				return
+			}
 			logger.Errorf("failed to release token locks: [%s]", err)
 		}
 	}
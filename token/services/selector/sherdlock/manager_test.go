@@ -61,6 +61,12 @@ func TestInsufficientTokensManyReplicas(t *testing.T) {
 	testutils.TestInsufficientTokensManyReplicas(t, replicas)
 }
 
+func TestSelectManyOneReplica(t *testing.T) {
+	replicas, terminate := startManagers(t, 1, NoBackoff, 5)
+	defer terminate()
+	testutils.TestSelectManyOneReplica(t, replicas[0])
+}
+
 // Set up
 
 func startManagers(t *testing.T, number int, backoff time.Duration, maxRetries int) ([]testutils.EnhancedManager, func()) {
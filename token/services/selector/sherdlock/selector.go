@@ -9,6 +9,7 @@ package sherdlock
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/common/utils/collections"
@@ -71,6 +72,18 @@ func (m *stubbornSelector) Select(owner token.OwnerFilter, q, currency string) (
 	return nil, nil, errors.Wrapf(token.SelectorInsufficientFunds, "aborted too many times and no other process unlocked or added tokens")
 }
 
+func (m *stubbornSelector) SelectMany(owner token.OwnerFilter, quantities map[string]string) (map[string]*token.SelectedTokens, error) {
+	for retriesAfterBackoff := 0; retriesAfterBackoff <= m.maxRetriesAfterBackoff; retriesAfterBackoff++ {
+		if selection, err := m.selector.SelectMany(owner, quantities); err == nil || !errors.Is(err, token.SelectorSufficientButLockedFunds) {
+			return selection, err
+		}
+		backoffDuration := time.Duration(rand.Int63n(int64(m.backoffInterval)))
+		m.logger.Debugf("Multi-type token selection aborted. Backoff for %v before retrying to select.", backoffDuration)
+		time.Sleep(backoffDuration)
+	}
+	return nil, errors.Wrapf(token.SelectorInsufficientFunds, "aborted too many times and no other process unlocked or added tokens")
+}
+
 func NewStubbornSelector(logger logging.Logger, tokenDB tokenFetcher, lockDB tokenLocker, precision uint64, backoff time.Duration, retries int) *stubbornSelector {
 	return &stubbornSelector{
 		selector:               NewSelector(logger, tokenDB, lockDB, precision),
@@ -98,18 +111,17 @@ func (s *selector) Select(owner token.OwnerFilter, q, currency string) ([]*token
 		return nil, nil, errors.Wrapf(err, "failed to create quantity")
 	}
 	sum, selected, tokensLockedByOthersExist, immediateRetries := token2.NewZeroQuantity(s.precision), collections.NewSet[*token2.ID](), true, 0
+	// lockedByOthers is the sum of the tokens locked by other processes found in the last pass over the tokens
+	lockedByOthers := token2.NewZeroQuantity(s.precision)
 	for {
 		if t, err := s.cache.Next(); err != nil {
 			err2 := s.locker.UnlockAll()
 			return nil, nil, errors.Wrapf(err, "failed to get tokens for [%s:%s] - unlock: %v", owner.ID(), currency, err2)
 		} else if t == nil {
 			if !tokensLockedByOthersExist {
-				return nil, nil, errors.Wrapf(
-					token.SelectorInsufficientFunds,
-					"insufficient funds, only [%s] tokens of type [%s] are available, but [%s] were requested and no other process has any tokens locked",
-					sum.Decimal(),
-					currency,
-					quantity.Decimal(),
+				return nil, nil, errors.WithMessage(
+					token.NewInsufficientFundsError(token.SelectorInsufficientFunds, currency, quantity, sum, token2.NewZeroQuantity(s.precision), selected.ToSlice()),
+					"no other process has any tokens locked",
 				)
 			}
 
@@ -124,7 +136,7 @@ func (s *selector) Select(owner token.OwnerFilter, q, currency string) ([]*token
 				// we retry to fetch, in case the other process did not spend and unlocked the token meanwhile.
 				// We do not unlock our tokens, yet.
 				// After some retries, we unlock the tokens and return a token.SelectorInsufficientFunds error
-				return nil, nil, token.SelectorSufficientButLockedFunds
+				return nil, nil, token.NewInsufficientFundsError(token.SelectorSufficientButLockedFunds, currency, quantity, sum, lockedByOthers, selected.ToSlice())
 			}
 
 			s.logger.Debugf("Fetch all non-deleted tokens from the DB and refresh the token cache.")
@@ -135,9 +147,13 @@ func (s *selector) Select(owner token.OwnerFilter, q, currency string) ([]*token
 
 			immediateRetries++
 			tokensLockedByOthersExist = false
+			lockedByOthers = token2.NewZeroQuantity(s.precision)
 		} else if locked := s.locker.TryLock(t.Id); !locked {
 			s.logger.Debugf("Tried to lock token [%v], but it was already locked by another process", t)
 			tokensLockedByOthersExist = true
+			if q, err := token2.ToQuantity(t.Quantity, s.precision); err == nil {
+				lockedByOthers.Add(q)
+			}
 		} else {
 			s.logger.Debugf("Got the lock on token [%v]", t)
 			q, err := token2.ToQuantity(t.Quantity, s.precision)
@@ -155,6 +171,126 @@ func (s *selector) Select(owner token.OwnerFilter, q, currency string) ([]*token
 	}
 }
 
+// Preview returns the tokens Select would return, without locking them.
+// Tokens locked by other transactions are not skipped because the lock database cannot be queried without locking.
+func (s *selector) Preview(owner token.OwnerFilter, q, currency string) (*token.SelectionPreview, error) {
+	if s.isClosed() {
+		return nil, errors.Errorf("selector is already closed")
+	}
+	quantity, err := token2.ToQuantity(q, s.precision)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create quantity")
+	}
+	it, err := s.fetcher.UnspentTokensIteratorBy(owner.ID(), currency)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get tokens for [%s:%s]", owner.ID(), currency)
+	}
+	defer it.Close()
+
+	sum, selected := token2.NewZeroQuantity(s.precision), make([]*token2.ID, 0)
+	for sum.Cmp(quantity) < 0 {
+		t, err := it.Next()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get tokens for [%s:%s]", owner.ID(), currency)
+		}
+		if t == nil {
+			break
+		}
+		q, err := token2.ToQuantity(t.Quantity, s.precision)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid token [%s] found", t.Id)
+		}
+		sum.Add(q)
+		selected = append(selected, t.Id)
+	}
+	return token.NewSelectionPreview(selected, sum, quantity, s.precision)
+}
+
+// SelectMany selects the tokens of all the requested types with a single query over the tokens of the owner.
+// If any of the requested quantities cannot be covered, all the tokens locked by this selector are unlocked.
+func (s *selector) SelectMany(owner token.OwnerFilter, quantities map[string]string) (map[string]*token.SelectedTokens, error) {
+	if s.isClosed() {
+		return nil, errors.Errorf("selector is already closed")
+	}
+	if len(quantities) == 0 {
+		return nil, errors.Errorf("no token type requested")
+	}
+	targets := make(map[string]token2.Quantity, len(quantities))
+	selection := make(map[string]*token.SelectedTokens, len(quantities))
+	for tokenType, q := range quantities {
+		quantity, err := token2.ToQuantity(q, s.precision)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create quantity for [%s]", tokenType)
+		}
+		targets[tokenType] = quantity
+		selection[tokenType] = &token.SelectedTokens{Total: token2.NewZeroQuantity(s.precision)}
+	}
+
+	// an empty type matches the tokens of any type
+	it, err := s.fetcher.UnspentTokensIteratorBy(owner.ID(), "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get tokens for [%s]", owner.ID())
+	}
+	defer it.Close()
+
+	// lockedByOthers tells, for each type, if tokens locked by other processes were found
+	missing, lockedByOthers := 0, map[string]bool{}
+	for tokenType, target := range targets {
+		if selection[tokenType].Total.Cmp(target) < 0 {
+			missing++
+		}
+	}
+	for missing > 0 {
+		t, err := it.Next()
+		if err != nil {
+			err2 := s.locker.UnlockAll()
+			return nil, errors.Wrapf(err, "failed to get tokens for [%s] - unlock: %v", owner.ID(), err2)
+		}
+		if t == nil {
+			break
+		}
+		target, ok := targets[t.Type]
+		if !ok || selection[t.Type].Total.Cmp(target) >= 0 {
+			continue
+		}
+		if locked := s.locker.TryLock(t.Id); !locked {
+			s.logger.Debugf("Tried to lock token [%v], but it was already locked by another process", t)
+			lockedByOthers[t.Type] = true
+			continue
+		}
+		q, err := token2.ToQuantity(t.Quantity, s.precision)
+		if err != nil {
+			err2 := s.locker.UnlockAll()
+			return nil, errors.Wrapf(err, "invalid token [%s] found - unlock: %v", t.Id, err2)
+		}
+		selected := selection[t.Type]
+		selected.Tokens = append(selected.Tokens, t.Id)
+		selected.Total.Add(q)
+		if selected.Total.Cmp(target) >= 0 {
+			missing--
+		}
+	}
+	if missing == 0 {
+		return selection, nil
+	}
+
+	if err := s.locker.UnlockAll(); err != nil {
+		return nil, errors.Wrapf(err, "failed to unlock tokens after an incomplete selection")
+	}
+	// the funds are insufficient if there is a type not covered even counting the tokens locked by others
+	tokenTypes := collections.Keys(targets)
+	sort.Strings(tokenTypes)
+	for _, tokenType := range tokenTypes {
+		if selection[tokenType].Total.Cmp(targets[tokenType]) < 0 && !lockedByOthers[tokenType] {
+			return nil, errors.WithMessage(
+				token.NewInsufficientFundsError(token.SelectorInsufficientFunds, tokenType, targets[tokenType], selection[tokenType].Total, token2.NewZeroQuantity(s.precision), selection[tokenType].Tokens),
+				"no other process has any tokens locked",
+			)
+		}
+	}
+	return nil, token.SelectorSufficientButLockedFunds
+}
+
 func (s *selector) Close() error {
 	if s.isClosed() {
 		return errors.New("selector is already closed")
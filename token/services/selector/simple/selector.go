@@ -8,6 +8,8 @@ package simple
 
 import (
 	"context"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
@@ -53,6 +55,157 @@ func (s *selector) Select(ownerFilter token.OwnerFilter, q, tokenType string) ([
 
 func (s *selector) Close() error { return nil }
 
+// Preview returns the tokens Select would return, without locking them.
+// Tokens currently locked by other transactions are skipped.
+func (s *selector) Preview(ownerFilter token.OwnerFilter, q, tokenType string) (*token.SelectionPreview, error) {
+	if ownerFilter == nil || len(ownerFilter.ID()) == 0 {
+		return nil, errors.Errorf("no owner filter specified")
+	}
+	target, err := token2.ToQuantity(q, s.precision)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert quantity")
+	}
+	unspentTokens, err := s.queryService.UnspentTokensIteratorBy(context.TODO(), ownerFilter.ID(), tokenType)
+	if err != nil {
+		return nil, errors.Wrap(err, "token selection preview failed")
+	}
+	defer unspentTokens.Close()
+
+	sum := token2.NewZeroQuantity(s.precision)
+	var selected []*token2.ID
+	for target.Cmp(sum) > 0 {
+		t, err := unspentTokens.Next()
+		if err != nil {
+			return nil, errors.Wrap(err, "token selection preview failed")
+		}
+		if t == nil {
+			break
+		}
+		if s.locker.IsLocked(t.Id) {
+			continue
+		}
+		q, err := token2.ToQuantity(t.Quantity, s.precision)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to convert quantity")
+		}
+		selected = append(selected, t.Id)
+		sum = sum.Add(q)
+	}
+	return token.NewSelectionPreview(selected, sum, target, s.precision)
+}
+
+// SelectMany selects the tokens of all the requested types with a single query over the tokens of the owner.
+// If any of the requested quantities cannot be covered, the tokens locked so far are unlocked.
+func (s *selector) SelectMany(ownerFilter token.OwnerFilter, quantities map[string]string) (map[string]*token.SelectedTokens, error) {
+	if ownerFilter == nil || len(ownerFilter.ID()) == 0 {
+		return nil, errors.Errorf("no owner filter specified")
+	}
+	if len(quantities) == 0 {
+		return nil, errors.Errorf("no token type requested")
+	}
+	targets := make(map[string]token2.Quantity, len(quantities))
+	for tokenType, q := range quantities {
+		target, err := token2.ToQuantity(q, s.precision)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to convert quantity for [%s]", tokenType)
+		}
+		targets[tokenType] = target
+	}
+
+	for i := 0; ; i++ {
+		selection, missing, err := s.selectManyOnce(ownerFilter.ID(), targets, s.numRetry == 1 || i > 0)
+		if err != nil || selection != nil {
+			return selection, err
+		}
+		if len(missing) != 0 {
+			return nil, errors.WithMessagef(token.SelectorInsufficientFunds, "token selection failed: insufficient funds of type [%s]", strings.Join(missing, ", "))
+		}
+		if i+1 >= s.numRetry {
+			return nil, errors.WithMessagef(token.SelectorSufficientButLockedFunds, "token selection failed: sufficient but partially locked funds for [%v]", quantities)
+		}
+		logger.Debugf("token selection: let's wait [%v] before retry...", s.timeout)
+		time.Sleep(s.timeout)
+	}
+}
+
+// selectManyOnce runs a single pass over the tokens of the passed wallet.
+// It returns a nil selection if the targets are not covered, together with
+// the types that are not covered even counting the tokens locked by others.
+func (s *selector) selectManyOnce(walletID string, targets map[string]token2.Quantity, reclaim bool) (map[string]*token.SelectedTokens, []string, error) {
+	// an empty type matches the tokens of any type
+	unspentTokens, err := s.queryService.UnspentTokensIteratorBy(context.TODO(), walletID, "")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "token selection failed")
+	}
+	defer unspentTokens.Close()
+
+	selection := make(map[string]*token.SelectedTokens, len(targets))
+	missing := 0
+	for tokenType, target := range targets {
+		selection[tokenType] = &token.SelectedTokens{Total: token2.NewZeroQuantity(s.precision)}
+		if selection[tokenType].Total.Cmp(target) < 0 {
+			missing++
+		}
+	}
+	var locked []*token2.ID
+	potential := make(map[string]token2.Quantity, len(targets))
+	for missing > 0 {
+		t, err := unspentTokens.Next()
+		if err != nil {
+			s.locker.UnlockIDs(locked...)
+			return nil, nil, errors.Wrap(err, "token selection failed")
+		}
+		if t == nil {
+			break
+		}
+		target, ok := targets[t.Type]
+		if !ok || selection[t.Type].Total.Cmp(target) >= 0 {
+			continue
+		}
+		q, err := token2.ToQuantity(t.Quantity, s.precision)
+		if err != nil {
+			s.locker.UnlockIDs(locked...)
+			return nil, nil, errors.Wrap(err, "failed to convert quantity")
+		}
+		if _, err := s.locker.Lock(t.Id, s.txID, reclaim); err != nil {
+			logger.Debugf("token [%s,%v] cannot be locked [%s]", q, t.Type, err)
+			if potential[t.Type] == nil {
+				potential[t.Type] = token2.NewZeroQuantity(s.precision)
+			}
+			potential[t.Type] = potential[t.Type].Add(q)
+			continue
+		}
+		locked = append(locked, t.Id)
+		selected := selection[t.Type]
+		selected.Tokens = append(selected.Tokens, t.Id)
+		selected.Total = selected.Total.Add(q)
+		if selected.Total.Cmp(target) >= 0 {
+			missing--
+		}
+	}
+	if missing == 0 {
+		err := s.concurrencyCheck(locked)
+		if err == nil {
+			return selection, nil, nil
+		}
+		logger.Errorf("concurrency issue, some of the tokens might not exist anymore [%s]", err)
+	}
+	s.locker.UnlockIDs(locked...)
+
+	var missingTypes []string
+	for tokenType, target := range targets {
+		sum := token2.NewZeroQuantity(s.precision).Add(selection[tokenType].Total)
+		if p, ok := potential[tokenType]; ok {
+			sum = sum.Add(p)
+		}
+		if sum.Cmp(target) < 0 {
+			missingTypes = append(missingTypes, tokenType)
+		}
+	}
+	sort.Strings(missingTypes)
+	return nil, missingTypes, nil
+}
+
 func (s *selector) concurrencyCheck(ids []*token2.ID) error {
 	_, err := s.queryService.GetTokens(ids...)
 	return err
@@ -62,6 +215,7 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 	var toBeSpent []*token2.ID
 	var sum token2.Quantity
 	var potentialSumWithLocked token2.Quantity
+	var lockedBy map[string]token2.Quantity
 	target, err := token2.ToQuantity(q, s.precision)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to convert quantity")
@@ -89,6 +243,7 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 		// First select only certified
 		sum = token2.NewZeroQuantity(s.precision)
 		potentialSumWithLocked = token2.NewZeroQuantity(s.precision)
+		lockedBy = map[string]token2.Quantity{}
 		toBeSpent = nil
 		var toBeCertified []*token2.ID
 
@@ -112,8 +267,15 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 			}
 
 			// lock the token
-			if _, err := s.locker.Lock(t.Id, s.txID, reclaim); err != nil {
+			if lockerTxID, err := s.locker.Lock(t.Id, s.txID, reclaim); err != nil {
 				potentialSumWithLocked = potentialSumWithLocked.Add(q)
+				if len(lockerTxID) != 0 {
+					l, ok := lockedBy[lockerTxID]
+					if !ok {
+						l = token2.NewZeroQuantity(s.precision)
+					}
+					lockedBy[lockerTxID] = l.Add(q)
+				}
 
 				if logger.IsEnabledFor(zapcore.DebugLevel) {
 					logger.Debugf("token [%s,%v] cannot be locked [%s]", q, tokenType, err)
@@ -162,21 +324,18 @@ func (s *selector) selectByID(ownerFilter token.OwnerFilter, q string, tokenType
 				)
 			}
 
+			cause := token.SelectorInsufficientFunds
 			if target.Cmp(potentialSumWithLocked) <= 0 && potentialSumWithLocked.Cmp(sum) != 0 {
 				// funds are potentially enough but they are locked
 				logger.Debugf("token selection: it is time to fail but how, sufficient funds but locked")
-				return nil, nil, errors.WithMessagef(
-					token.SelectorSufficientButLockedFunds,
-					"token selection failed: sufficient but partially locked funds, potential [%s] tokens of type [%s] are available", potentialSumWithLocked.Decimal(), tokenType,
-				)
+				cause = token.SelectorSufficientButLockedFunds
+			} else {
+				logger.Debugf("token selection: it is time to fail but how, insufficient funds")
 			}
-
-			// funds are insufficient
-			logger.Debugf("token selection: it is time to fail but how, insufficient funds")
-			return nil, nil, errors.WithMessagef(
-				token.SelectorInsufficientFunds,
-				"token selection failed: insufficient funds, only [%s] tokens of type [%s] are available", sum.Decimal(), tokenType,
-			)
+			locked := token2.NewZeroQuantity(s.precision).Add(potentialSumWithLocked).Sub(sum)
+			fundsErr := token.NewInsufficientFundsError(cause, tokenType, target, sum, locked, toBeSpent)
+			fundsErr.LockedBy = lockedBy
+			return nil, nil, errors.WithMessage(fundsErr, "token selection failed")
 		}
 
 		logger.Debugf("token selection: let's wait [%v] before retry...", s.timeout)
@@ -116,6 +116,46 @@ func TestInsufficientTokensManyReplicas(t *testing.T, replicas []EnhancedManager
 	assert.Equal(t, 0, sum.Cmp(newToken(1)))
 }
 
+func TestSelectManyOneReplica(t *testing.T, replica EnhancedManager) {
+	// Create 2 tokens of value CHF1 each and 2 tokens of value EUR5 each
+	unspentTokens := createDefaultTokens(newToken(1), newToken(1))
+	unspentTokens = append(unspentTokens, createTypedTokens("EUR", newToken(5), newToken(5))...)
+	err := storeTokens(replica, unspentTokens)
+	assert.NoError(t, err)
+
+	// The replica asks for CHF2 and EUR5 in one go
+	txID := newTxID()
+	sel, err := replica.NewSelector(txID)
+	assert.NoError(t, err)
+	defer replica.Close(txID)
+	selection, err := sel.SelectMany(defaultTokenFilter, map[string]string{defaultCurrency: newToken(2).Hex(), "EUR": newToken(5).Hex()})
+	assert.NoError(t, err)
+	assert.Len(t, selection[defaultCurrency].Tokens, 2)
+	assert.Equal(t, 0, selection[defaultCurrency].Total.Cmp(newToken(2)))
+	assert.Len(t, selection["EUR"].Tokens, 1)
+
+	// Another transaction asks for EUR5 and USD1. There are no USD, therefore, the EUR token is not kept locked
+	txID = newTxID()
+	sel, err = replica.NewSelector(txID)
+	assert.NoError(t, err)
+	defer replica.Close(txID)
+	_, err = sel.SelectMany(defaultTokenFilter, map[string]string{"EUR": newToken(5).Hex(), "USD": newToken(1).Hex()})
+	assert.ErrorIs(t, err, token2.SelectorInsufficientFunds)
+	var fundsErr *token2.InsufficientFundsError
+	assert.ErrorAs(t, err, &fundsErr)
+	assert.Equal(t, "USD", fundsErr.TokenType)
+	assert.Equal(t, "0", fundsErr.Available.Decimal())
+	assert.Empty(t, fundsErr.Spendable)
+
+	txID = newTxID()
+	sel, err = replica.NewSelector(txID)
+	assert.NoError(t, err)
+	defer replica.Close(txID)
+	selection, err = sel.SelectMany(defaultTokenFilter, map[string]string{"EUR": newToken(5).Hex()})
+	assert.NoError(t, err)
+	assert.Len(t, selection["EUR"].Tokens, 1)
+}
+
 // Enhanced manager
 
 type enhancedManager struct {
@@ -249,6 +289,14 @@ func createDefaultTokens(quantities ...token.Quantity) []token.UnspentToken {
 	return createTokens(map[transaction.ID][]token.Quantity{newTxID(): quantities})
 }
 
+func createTypedTokens(tokenType string, quantities ...token.Quantity) []token.UnspentToken {
+	unspentTokens := createDefaultTokens(quantities...)
+	for i := range unspentTokens {
+		unspentTokens[i].Type = tokenType
+	}
+	return unspentTokens
+}
+
 func createTokens(txs map[transaction.ID][]token.Quantity) []token.UnspentToken {
 	unspentTokens := make([]token.UnspentToken, 0)
 	for txID, quantities := range txs {
@@ -0,0 +1,227 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package selfcheck
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	driver2 "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+// Severity tells how serious a finding is
+type Severity int
+
+const (
+	// Info reports the outcome of a check that passed or could not run
+	Info Severity = iota
+	// Warning reports an inconsistency that does not prevent the node from serving
+	Warning
+	// Critical reports an inconsistency that can corrupt the local state or produce invalid transactions
+	Critical
+)
+
+var severityNames = map[Severity]string{
+	Info:     "INFO",
+	Warning:  "WARNING",
+	Critical: "CRITICAL",
+}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+const (
+	// SchemaCheck verifies that the live schema of the token db supports the queries of this version
+	SchemaCheck = "schema"
+	// PublicParamsCheck verifies that the public parameters are stored and match those on the ledger
+	PublicParamsCheck = "public_params"
+	// TokensCheck verifies, on a sample, that the unspent tokens come from transactions that are not deleted
+	TokensCheck = "tokens"
+)
+
+// Finding is the outcome of a check
+type Finding struct {
+	Check    string
+	Severity Severity
+	Message  string
+}
+
+// Report collects the findings of the self-check of a TMS
+type Report struct {
+	TMSID    token.TMSID
+	Findings []Finding
+}
+
+func (r *Report) add(check string, severity Severity, format string, args ...any) {
+	r.Findings = append(r.Findings, Finding{Check: check, Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+// Critical returns true if the report contains at least a critical finding
+func (r *Report) Critical() bool {
+	for _, f := range r.Findings {
+		if f.Severity == Critical {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Report) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("self-check report for tms [%s]:", r.TMSID))
+	for _, f := range r.Findings {
+		sb.WriteString(fmt.Sprintf("\n  [%s][%s] %s", f.Severity, f.Check, f.Message))
+	}
+	return sb.String()
+}
+
+// TokenDB is the token db as seen by the self-check
+type TokenDB interface {
+	ExplainQueries() ([]driver.QueryPlan, error)
+	PublicParams() ([]byte, error)
+	UnspentTokensIterator() (driver2.UnspentTokensIterator, error)
+}
+
+// TransactionDB is the ttxdb as seen by the self-check
+type TransactionDB interface {
+	GetStatus(txID string) (driver.TxStatus, string, error)
+}
+
+// Ledger gives access to the public parameters committed on the ledger
+type Ledger interface {
+	FetchPublicParameters(namespace string) ([]byte, error)
+}
+
+// Checker verifies the consistency of the local state of a TMS
+type Checker struct {
+	// SampleSize is the maximum number of unspent tokens checked against the ttxdb
+	SampleSize int
+}
+
+// Check runs the checks for the passed TMS and returns their findings.
+// ppHash is the hash of the public parameters the TMS is using, computed with the passed hash algorithm.
+// An error is returned only if a check cannot be run because of the databases.
+func (c *Checker) Check(tmsID token.TMSID, ppHash driver2.PPHash, hashAlgorithm driver2.HashAlgorithm, tokenDB TokenDB, ttxDB TransactionDB, ledger Ledger) (*Report, error) {
+	r := &Report{TMSID: tmsID}
+	c.checkSchema(r, tokenDB)
+	if err := c.checkPublicParams(r, ppHash, hashAlgorithm, tokenDB, ledger); err != nil {
+		return nil, err
+	}
+	if err := c.checkTokens(r, tokenDB, ttxDB); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (c *Checker) checkSchema(r *Report, tokenDB TokenDB) {
+	// there is no explicit schema version, the schema is compatible if the canonical queries of this version can be planned
+	_, err := tokenDB.ExplainQueries()
+	switch {
+	case errors.Is(err, driver.ErrQueryPlansNotSupported):
+		r.add(SchemaCheck, Info, "skipped, the database does not support query plans")
+	case err != nil:
+		r.add(SchemaCheck, Critical, "the live schema does not support the queries of this version: %s", err)
+	default:
+		r.add(SchemaCheck, Info, "the live schema supports the queries of this version")
+	}
+}
+
+func (c *Checker) checkPublicParams(r *Report, ppHash driver2.PPHash, hashAlgorithm driver2.HashAlgorithm, tokenDB TokenDB, ledger Ledger) error {
+	stored, err := tokenDB.PublicParams()
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get stored public parameters")
+	}
+	if len(stored) == 0 {
+		// a node that has not yet processed the block with the public parameters has none
+		r.add(PublicParamsCheck, Warning, "no public parameters stored")
+		return nil
+	}
+	storedHash, err := hashAlgorithm.Hash(stored)
+	if err != nil {
+		r.add(PublicParamsCheck, Critical, "cannot hash the stored public parameters: %s", err)
+		return nil
+	}
+	if len(ppHash) != 0 && !bytes.Equal(storedHash, ppHash) {
+		r.add(PublicParamsCheck, Critical, "the public parameters in use [%s] differ from the stored ones [%s]", encode(ppHash), encode(storedHash))
+	}
+
+	onLedger, err := ledger.FetchPublicParameters(r.TMSID.Namespace)
+	if err != nil {
+		r.add(PublicParamsCheck, Warning, "cannot fetch public parameters from the ledger: %s", err)
+		return nil
+	}
+	ledgerHash, err := hashAlgorithm.Hash(onLedger)
+	if err != nil {
+		r.add(PublicParamsCheck, Critical, "cannot hash the public parameters on the ledger: %s", err)
+		return nil
+	}
+	if !bytes.Equal(storedHash, ledgerHash) {
+		// it happens also when the node has not yet processed the block updating the public parameters
+		r.add(PublicParamsCheck, Warning, "the stored public parameters [%s] differ from those on the ledger [%s]", encode(storedHash), encode(ledgerHash))
+		return nil
+	}
+	r.add(PublicParamsCheck, Info, "the stored public parameters [%s] match those on the ledger", encode(storedHash))
+	return nil
+}
+
+func (c *Checker) checkTokens(r *Report, tokenDB TokenDB, ttxDB TransactionDB) error {
+	if c.SampleSize <= 0 {
+		r.add(TokensCheck, Info, "skipped, sample size is zero")
+		return nil
+	}
+	it, err := tokenDB.UnspentTokensIterator()
+	if err != nil {
+		return errors.WithMessagef(err, "failed to iterate over unspent tokens")
+	}
+	defer it.Close()
+
+	statuses := map[string]driver.TxStatus{}
+	sampled, pending, unknown := 0, 0, 0
+	for sampled < c.SampleSize {
+		tok, err := it.Next()
+		if err != nil {
+			return errors.WithMessagef(err, "failed to get next unspent token")
+		}
+		if tok == nil {
+			break
+		}
+		sampled++
+		txID := tok.Id.TxId
+		status, ok := statuses[txID]
+		if !ok {
+			status, _, err = ttxDB.GetStatus(txID)
+			if err != nil {
+				return errors.WithMessagef(err, "failed to get status of [%s]", txID)
+			}
+			statuses[txID] = status
+		}
+		switch status {
+		case driver.Deleted:
+			r.add(TokensCheck, Critical, "unspent token [%s:%d] comes from a transaction marked as deleted", txID, tok.Id.Index)
+		case driver.Pending:
+			pending++
+		case driver.Unknown:
+			// tokens can be received from transactions this node did not take part in
+			unknown++
+		}
+	}
+	if pending > 0 {
+		r.add(TokensCheck, Warning, "[%d] sampled unspent tokens come from transactions still pending in the ttxdb", pending)
+	}
+	r.add(TokensCheck, Info, "sampled [%d] unspent tokens from [%d] transactions, [%d] unknown to the ttxdb", sampled, len(statuses), unknown)
+	return nil
+}
+
+func encode(h []byte) string {
+	return base64.StdEncoding.EncodeToString(h)
+}
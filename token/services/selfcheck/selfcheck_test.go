@@ -0,0 +1,160 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package selfcheck
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	driver2 "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type tokenDB struct {
+	explainErr error
+	pp         []byte
+	unspent    []*token2.UnspentToken
+}
+
+func (db *tokenDB) ExplainQueries() ([]driver.QueryPlan, error) { return nil, db.explainErr }
+
+func (db *tokenDB) PublicParams() ([]byte, error) { return db.pp, nil }
+
+func (db *tokenDB) UnspentTokensIterator() (driver2.UnspentTokensIterator, error) {
+	return &unspentIterator{tokens: db.unspent}, nil
+}
+
+type unspentIterator struct{ tokens []*token2.UnspentToken }
+
+func (it *unspentIterator) Close() {}
+
+func (it *unspentIterator) Next() (*token2.UnspentToken, error) {
+	if len(it.tokens) == 0 {
+		return nil, nil
+	}
+	next := it.tokens[0]
+	it.tokens = it.tokens[1:]
+	return next, nil
+}
+
+type ttxDB map[string]driver.TxStatus
+
+func (db ttxDB) GetStatus(txID string) (driver.TxStatus, string, error) { return db[txID], "", nil }
+
+type ledger []byte
+
+func (l ledger) FetchPublicParameters(string) ([]byte, error) { return l, nil }
+
+func unspent(txIDs ...string) []*token2.UnspentToken {
+	tokens := make([]*token2.UnspentToken, len(txIDs))
+	for i, txID := range txIDs {
+		tokens[i] = &token2.UnspentToken{Id: &token2.ID{TxId: txID, Index: uint64(i)}}
+	}
+	return tokens
+}
+
+func findings(r *Report, severity Severity) []string {
+	var checks []string
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			checks = append(checks, f.Check)
+		}
+	}
+	return checks
+}
+
+func TestCheck(t *testing.T) {
+	pp := []byte("pp")
+	ppHash := hash.Hashable(pp).Raw()
+	statuses := ttxDB{"tx1": driver.Confirmed, "tx2": driver.Pending, "tx3": driver.Deleted}
+
+	cases := []struct {
+		name       string
+		ppHash     driver2.PPHash
+		tokenDB    *tokenDB
+		ledger     ledger
+		sampleSize int
+		critical   []string
+		warning    []string
+	}{
+		{
+			name:       "consistent",
+			ppHash:     ppHash,
+			tokenDB:    &tokenDB{pp: pp, unspent: unspent("tx1", "tx1", "tx4")},
+			ledger:     pp,
+			sampleSize: 10,
+		},
+		{
+			name:       "schema not supported",
+			ppHash:     ppHash,
+			tokenDB:    &tokenDB{pp: pp, explainErr: driver.ErrQueryPlansNotSupported},
+			ledger:     pp,
+			sampleSize: 10,
+		},
+		{
+			name:       "schema mismatch",
+			ppHash:     ppHash,
+			tokenDB:    &tokenDB{pp: pp, explainErr: errors.New("no such column: spendable")},
+			ledger:     pp,
+			sampleSize: 10,
+			critical:   []string{SchemaCheck},
+		},
+		{
+			name:       "no public params",
+			tokenDB:    &tokenDB{},
+			ledger:     pp,
+			sampleSize: 10,
+			warning:    []string{PublicParamsCheck},
+		},
+		{
+			name:       "public params behind the ledger",
+			ppHash:     ppHash,
+			tokenDB:    &tokenDB{pp: pp},
+			ledger:     []byte("pp2"),
+			sampleSize: 10,
+			warning:    []string{PublicParamsCheck},
+		},
+		{
+			name:       "public params in use not stored",
+			ppHash:     hash.Hashable("pp2").Raw(),
+			tokenDB:    &tokenDB{pp: pp},
+			ledger:     pp,
+			sampleSize: 10,
+			critical:   []string{PublicParamsCheck},
+		},
+		{
+			name:       "tokens of pending and deleted transactions",
+			ppHash:     ppHash,
+			tokenDB:    &tokenDB{pp: pp, unspent: unspent("tx1", "tx2", "tx3")},
+			ledger:     pp,
+			sampleSize: 10,
+			critical:   []string{TokensCheck},
+			warning:    []string{TokensCheck},
+		},
+		{
+			name:       "deleted transaction out of the sample",
+			ppHash:     ppHash,
+			tokenDB:    &tokenDB{pp: pp, unspent: unspent("tx1", "tx1", "tx3")},
+			ledger:     pp,
+			sampleSize: 2,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checker := &Checker{SampleSize: c.sampleSize}
+			r, err := checker.Check(token.TMSID{Network: "n", Channel: "c", Namespace: "ns"}, c.ppHash, driver2.SHA256, c.tokenDB, statuses, c.ledger)
+			assert.NoError(t, err)
+			assert.Equal(t, c.critical, findings(r, Critical), r.String())
+			assert.Equal(t, c.warning, findings(r, Warning), r.String())
+			assert.Equal(t, len(c.critical) != 0, r.Critical())
+		})
+	}
+}
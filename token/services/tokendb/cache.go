@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokendb
+
+import (
+	"context"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+type balanceKey struct {
+	ownerEID string
+	typ      string
+}
+
+// cachingTokenDB is a driver.TokenDB that caches the results of Balance and PublicParams.
+// The cached results are invalidated by the writes going through it, therefore, each method writing tokens is overridden.
+type cachingTokenDB struct {
+	driver.TokenDB
+	balances     *db.QueryCache[balanceKey, uint64]
+	publicParams *db.QueryCache[struct{}, []byte]
+}
+
+func newCachingTokenDB(tokenDB driver.TokenDB, opts db.QueryCacheOpts) *cachingTokenDB {
+	return &cachingTokenDB{
+		TokenDB:      tokenDB,
+		balances:     db.NewQueryCache[balanceKey, uint64](opts.Balance),
+		publicParams: db.NewQueryCache[struct{}, []byte](opts.PublicParams),
+	}
+}
+
+func (c *cachingTokenDB) Balance(ownerEID, typ string) (uint64, error) {
+	return c.balances.Load(balanceKey{ownerEID: ownerEID, typ: typ}, func() (uint64, error) {
+		return c.TokenDB.Balance(ownerEID, typ)
+	}, func(uint64) bool { return true })
+}
+
+func (c *cachingTokenDB) PublicParams() ([]byte, error) {
+	return c.publicParams.Load(struct{}{}, c.TokenDB.PublicParams, func(raw []byte) bool { return raw != nil })
+}
+
+func (c *cachingTokenDB) StorePublicParams(raw []byte) error {
+	defer c.publicParams.InvalidateAll()
+	return c.TokenDB.StorePublicParams(raw)
+}
+
+func (c *cachingTokenDB) DeleteTokens(deletedBy string, toDelete ...*token2.ID) error {
+	defer c.balances.InvalidateAll()
+	return c.TokenDB.DeleteTokens(deletedBy, toDelete...)
+}
+
+func (c *cachingTokenDB) SpendPendingTokens(spentBy string, ids ...*token2.ID) error {
+	defer c.balances.InvalidateAll()
+	return c.TokenDB.SpendPendingTokens(spentBy, ids...)
+}
+
+func (c *cachingTokenDB) DeletePendingTokens(txID string) ([]string, error) {
+	defer c.balances.InvalidateAll()
+	return c.TokenDB.DeletePendingTokens(txID)
+}
+
+func (c *cachingTokenDB) EraseOwnership(walletID string) (*driver.ErasedOwnership, error) {
+	defer c.balances.InvalidateAll()
+	return c.TokenDB.EraseOwnership(walletID)
+}
+
+func (c *cachingTokenDB) NewTokenDBTransaction(ctx context.Context) (driver.TokenDBTransaction, error) {
+	tx, err := c.TokenDB.NewTokenDBTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &cachingTokenDBTransaction{TokenDBTransaction: tx, balances: c.balances}, nil
+}
+
+// Close closes the underlying database, if it supports it
+func (c *cachingTokenDB) Close() error {
+	switch d := c.TokenDB.(type) {
+	case interface{ Close() error }:
+		return d.Close()
+	case interface{ Close() }:
+		d.Close()
+	}
+	return nil
+}
+
+// cachingTokenDBTransaction invalidates the cached balances once committed
+type cachingTokenDBTransaction struct {
+	driver.TokenDBTransaction
+	balances *db.QueryCache[balanceKey, uint64]
+}
+
+func (t *cachingTokenDBTransaction) Commit() error {
+	// the tokens stored or deleted by the transaction might belong to any owner
+	defer t.balances.InvalidateAll()
+	return t.TokenDBTransaction.Commit()
+}
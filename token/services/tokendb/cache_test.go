@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokendb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+// balanceDB returns as balance the number of writes it went through
+type balanceDB struct {
+	driver.TokenDB
+	writes uint64
+}
+
+func (b *balanceDB) Balance(string, string) (uint64, error) { return b.writes, nil }
+
+func (b *balanceDB) DeleteTokens(string, ...*token2.ID) error { b.writes++; return nil }
+
+func (b *balanceDB) SpendPendingTokens(string, ...*token2.ID) error { b.writes++; return nil }
+
+func (b *balanceDB) DeletePendingTokens(string) ([]string, error) { b.writes++; return nil, nil }
+
+func (b *balanceDB) EraseOwnership(string) (*driver.ErasedOwnership, error) {
+	b.writes++
+	return &driver.ErasedOwnership{}, nil
+}
+
+func (b *balanceDB) NewTokenDBTransaction(context.Context) (driver.TokenDBTransaction, error) {
+	return &balanceTx{db: b}, nil
+}
+
+type balanceTx struct {
+	driver.TokenDBTransaction
+	db *balanceDB
+}
+
+func (t *balanceTx) Commit() error { t.db.writes++; return nil }
+
+func TestCachingTokenDB(t *testing.T) {
+	c := newCachingTokenDB(&balanceDB{}, db.QueryCacheOpts{Balance: db.MethodCacheOpts{Enabled: true}})
+	balance := func() uint64 {
+		b, err := c.Balance("alice", "USD")
+		assert.NoError(t, err)
+		return b
+	}
+	assert.Equal(t, uint64(0), balance())
+
+	writes := map[string]func() error{
+		"DeleteTokens":        func() error { return c.DeleteTokens("tx1") },
+		"SpendPendingTokens":  func() error { return c.SpendPendingTokens("tx1") },
+		"DeletePendingTokens": func() error { _, err := c.DeletePendingTokens("tx1"); return err },
+		"EraseOwnership":      func() error { _, err := c.EraseOwnership("alice"); return err },
+		"Commit": func() error {
+			tx, err := c.NewTokenDBTransaction(context.TODO())
+			if err != nil {
+				return err
+			}
+			return tx.Commit()
+		},
+	}
+	expected := uint64(0)
+	for name, write := range writes {
+		// the balance is served from the cache until a write invalidates it
+		c.TokenDB.(*balanceDB).writes += 10
+		assert.Equal(t, expected, balance(), name)
+		expected += 10
+
+		assert.NoError(t, write(), name)
+		expected++
+		assert.Equal(t, expected, balance(), name)
+	}
+}
@@ -9,10 +9,13 @@ package tokendb
 import (
 	"context"
 	"reflect"
+	"sync"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 )
 
@@ -32,7 +35,10 @@ func NewNotifierHolder(drivers []db.NamedDriver[driver.TokenNotifierDriver]) *No
 	return db.NewDriverHolder(func(p driver.TokenNotifier) *Notifier { return &Notifier{p} }, drivers...)
 }
 
-var managerType = reflect.TypeOf((*Manager)(nil))
+var (
+	managerType = reflect.TypeOf((*Manager)(nil))
+	logger      = logging.MustGetLogger("token-sdk.tokendb")
+)
 
 func NewHolder(drivers []db.NamedDriver[driver.TokenDBDriver]) *Holder {
 	return db.NewDriverHolder(newDB, drivers...)
@@ -52,21 +58,241 @@ func GetByTMSId(sp token.ServiceProvider, tmsID token.TMSID) (*DB, error) {
 
 type TokenRecord = driver.TokenRecord
 
+type TokenIntent = driver.TokenIntent
+
+type TokenDBTransaction = driver.TokenDBTransaction
+
 type Transaction struct {
 	driver.TokenDBTransaction
+	done func()
+}
+
+// Commit commits this transaction
+func (t *Transaction) Commit() error {
+	defer t.done()
+	return t.TokenDBTransaction.Commit()
 }
 
-// DB is a database that stores token transactions related information
+// Rollback rollbacks this transaction
+func (t *Transaction) Rollback() error {
+	defer t.done()
+	return t.TokenDBTransaction.Rollback()
+}
+
+// DB is a database that stores token transactions related information.
+// It overrides all the writes of driver.TokenDB, for them to count as in-flight writes,
+// and, except for the db transactions started by the caller, to go through the commit pipeline, if enabled.
 type DB struct {
 	driver.TokenDB
+	// writes tracks the in-flight writes to drain on shutdown
+	writes db.WriteGate
+	// pipeline, if set, applies all the writes
+	pipeline *Pipeline
 }
 
+// NewTransaction starts a new transaction on the database.
+// The transaction counts as an in-flight write until it is committed or rolled back.
+// It bypasses the commit pipeline, use Apply for the writes to go through it.
 func (d *DB) NewTransaction(ctx context.Context) (*Transaction, error) {
+	if err := d.writes.Enter(); err != nil {
+		return nil, errors.WithMessagef(err, "cannot start transaction")
+	}
 	tx, err := d.TokenDB.NewTokenDBTransaction(ctx)
+	if err != nil {
+		d.writes.Exit()
+		return nil, err
+	}
+	return &Transaction{TokenDBTransaction: tx, done: sync.OnceFunc(d.writes.Exit)}, nil
+}
+
+// NewTokenDBTransaction is NewTransaction, for the callers of the driver interface to be tracked as in-flight writes as well
+func (d *DB) NewTokenDBTransaction(ctx context.Context) (driver.TokenDBTransaction, error) {
+	tx, err := d.NewTransaction(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &Transaction{TokenDBTransaction: tx}, nil
+	return tx, nil
+}
+
+// StoreCertifications stores the passed certifications
+func (d *DB) StoreCertifications(certifications map[*token2.ID][]byte) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot store certifications")
+	}
+	defer d.writes.Exit()
+	return d.exec(func() error { return d.TokenDB.StoreCertifications(certifications) })
+}
+
+// DeleteTokens marks the passed tokens as deleted by the passed transaction
+func (d *DB) DeleteTokens(deletedBy string, toDelete ...*token2.ID) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot delete tokens for [%s]", deletedBy)
+	}
+	defer d.writes.Exit()
+	return d.exec(func() error { return d.TokenDB.DeleteTokens(deletedBy, toDelete...) })
+}
+
+// EraseOwnership removes the ownership by the passed wallet of the spent tokens. The unspent tokens are retained.
+func (d *DB) EraseOwnership(walletID string) (*driver.ErasedOwnership, error) {
+	if err := d.writes.Enter(); err != nil {
+		return nil, errors.WithMessagef(err, "cannot erase ownership of [%s]", walletID)
+	}
+	defer d.writes.Exit()
+	var res *driver.ErasedOwnership
+	err := d.exec(func() error {
+		var err error
+		res, err = d.TokenDB.EraseOwnership(walletID)
+		return err
+	})
+	return res, err
+}
+
+// SpendPendingTokens marks the passed tokens, if pending, as spent by the passed transaction
+func (d *DB) SpendPendingTokens(spentBy string, ids ...*token2.ID) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot spend pending tokens for [%s]", spentBy)
+	}
+	defer d.writes.Exit()
+	return d.exec(func() error { return d.TokenDB.SpendPendingTokens(spentBy, ids...) })
+}
+
+// DeletePendingTokens removes the pending tokens created by the passed transaction, and returns the ids of the transactions that spent them
+func (d *DB) DeletePendingTokens(txID string) ([]string, error) {
+	if err := d.writes.Enter(); err != nil {
+		return nil, errors.WithMessagef(err, "cannot delete pending tokens of [%s]", txID)
+	}
+	defer d.writes.Exit()
+	var spenders []string
+	err := d.exec(func() (err error) {
+		spenders, err = d.TokenDB.DeletePendingTokens(txID)
+		return err
+	})
+	return spenders, err
+}
+
+// StorePublicParams stores the passed public parameters
+func (d *DB) StorePublicParams(raw []byte) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot store public parameters")
+	}
+	defer d.writes.Exit()
+	return d.exec(func() error { return d.TokenDB.StorePublicParams(raw) })
+}
+
+// AddIntent records the intent to apply the token request of the passed transaction
+func (d *DB) AddIntent(txID string, request []byte) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot record intent for [%s]", txID)
+	}
+	defer d.writes.Exit()
+	return d.exec(func() error { return d.TokenDB.AddIntent(txID, request) })
+}
+
+// MarkIntentApplied keeps the intent of the passed transaction as a journal entry
+func (d *DB) MarkIntentApplied(txID string) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot mark intent for [%s]", txID)
+	}
+	defer d.writes.Exit()
+	return d.exec(func() error { return d.TokenDB.MarkIntentApplied(txID) })
+}
+
+// DeleteIntent clears the intent of the passed transaction
+func (d *DB) DeleteIntent(txID string) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot clear intent for [%s]", txID)
+	}
+	defer d.writes.Exit()
+	return d.exec(func() error { return d.TokenDB.DeleteIntent(txID) })
+}
+
+// Apply applies the passed job in a db transaction, committed if the job succeeds.
+// With the commit pipeline enabled, the job is applied by the worker of the pipeline, possibly in the same db transaction
+// as the jobs of other callers, therefore, a job can run more than once.
+func (d *DB) Apply(ctx context.Context, job Job) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot apply job")
+	}
+	defer d.writes.Exit()
+	if d.pipeline != nil {
+		return d.pipeline.Submit(ctx, job)
+	}
+	return applyJob(ctx, d.TokenDB, job)
+}
+
+// exec runs the passed write, on the worker of the commit pipeline if enabled
+func (d *DB) exec(f func() error) error {
+	if d.pipeline == nil {
+		return f()
+	}
+	return d.pipeline.Exec(context.Background(), f)
+}
+
+// Drain makes the database reject new writes and waits for the in-flight ones to complete or for the context to expire
+func (d *DB) Drain(ctx context.Context) error {
+	return d.writes.Drain(ctx)
+}
+
+// Resume makes the database accept writes again after a Drain
+func (d *DB) Resume() {
+	d.writes.Resume()
+}
+
+// SetReadOnly makes the database reject writes, or accept them again
+func (d *DB) SetReadOnly(readOnly bool) {
+	d.writes.SetReadOnly(readOnly)
+}
+
+// SetStatementObserver makes the database notify the passed observer of the interrupted statements.
+// It must be called before EnableQueryCache.
+func (d *DB) SetStatementObserver(o driver.StatementObserver) {
+	db.SetStatementObserver(d.TokenDB, o)
+}
+
+// EnableEncryption makes the database encrypt the token metadata with the passed cipher.
+// It must be called before EnableQueryCache.
+func (d *DB) EnableEncryption(c driver.ColumnCipher) error {
+	return db.SetColumnCipher(d.TokenDB, c)
+}
+
+// TableSizes returns the disk usage of the tables of the database.
+// It returns an error wrapping driver.ErrTableSizesNotSupported if the database cannot report it.
+func (d *DB) TableSizes() ([]driver.TableSize, error) {
+	return db.TableSizes(d.TokenDB)
+}
+
+// ReEncrypt re-encrypts with the active key the token metadata stored in clear or with a retired key
+func (d *DB) ReEncrypt(ctx context.Context, batchSize int) (int, error) {
+	return db.ReEncrypt(ctx, d.TokenDB, &d.writes, batchSize)
+}
+
+// EnableQueryCache makes the database cache the results of Balance and PublicParams, as enabled in the passed options
+func (d *DB) EnableQueryCache(opts db.QueryCacheOpts) {
+	if !opts.Balance.Enabled && !opts.PublicParams.Enabled {
+		return
+	}
+	d.TokenDB = newCachingTokenDB(d.TokenDB, opts)
+}
+
+// EnableCommitPipeline makes a single worker apply all the writes to the database, in batches of up to the configured size.
+// It must be called after EnableQueryCache, for the writes of the pipeline to invalidate the cache.
+func (d *DB) EnableCommitPipeline(opts db.CommitPipelineOpts) {
+	d.pipeline = NewPipeline(d.TokenDB, opts.GetMaxBatch())
+	d.pipeline.Start()
+}
+
+// Close stops the commit pipeline, if enabled, and closes the database, if the underlying driver supports it
+func (d *DB) Close() error {
+	if d.pipeline != nil {
+		d.pipeline.Stop()
+	}
+	switch c := d.TokenDB.(type) {
+	case interface{ Close() error }:
+		return c.Close()
+	case interface{ Close() }:
+		c.Close()
+	}
+	return nil
 }
 
 func newDB(p driver.TokenDB) *DB {
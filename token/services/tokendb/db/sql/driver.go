@@ -12,8 +12,10 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
 	dbdriver "github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/oracle"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/postgres"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/sqlite"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/sqlserver"
 )
 
 const (
@@ -24,15 +26,24 @@ const (
 
 func NewDBDriver() *db.SQLDriver[dbdriver.TokenDB] {
 	return db.NewSQLDriver(func(cp dbdriver.ConfigProvider, tmsID token.TMSID) (dbdriver.TokenDB, error) {
-		sqlDB, opts, err := common.NewSQLDBOpener(OptsKey, EnvVarKey).OpenWithOpts(cp, tmsID)
+		opener := common.NewSQLDBOpener(OptsKey, EnvVarKey)
+		sqlDB, opts, err := opener.OpenWithOpts(cp, tmsID)
+		if err != nil {
+			return nil, err
+		}
+		dbOpts, err := opener.DBOpts(cp, tmsID, opts)
 		if err != nil {
 			return nil, err
 		}
 		switch opts.Driver {
 		case sql.SQLite:
-			return sqlite.NewTokenDB(sqlDB, common.NewDBOptsFromOpts(*opts))
+			return sqlite.NewTokenDB(sqlDB, dbOpts)
 		case sql.Postgres:
-			return postgres.NewTokenDB(sqlDB, common.NewDBOptsFromOpts(*opts))
+			return postgres.NewTokenDB(sqlDB, dbOpts)
+		case common.Oracle:
+			return oracle.NewTokenDB(sqlDB, dbOpts)
+		case common.SQLServer:
+			return sqlserver.NewTokenDB(sqlDB, dbOpts)
 		}
 		panic("undefined")
 	})
@@ -40,19 +51,28 @@ func NewDBDriver() *db.SQLDriver[dbdriver.TokenDB] {
 
 func NewNotifierDriver() dbdriver.TokenNotifierDriver {
 	return db.NewSQLDriver(func(cp dbdriver.ConfigProvider, tmsID token.TMSID) (dbdriver.TokenNotifier, error) {
-		sqlDB, opts, err := common.NewSQLDBOpener(OptsKey, EnvVarKey).OpenWithOpts(cp, tmsID)
+		opener := common.NewSQLDBOpener(OptsKey, EnvVarKey)
+		sqlDB, opts, err := opener.OpenWithOpts(cp, tmsID)
+		if err != nil {
+			return nil, err
+		}
+		dbOpts, err := opener.DBOpts(cp, tmsID, opts)
 		if err != nil {
 			return nil, err
 		}
 		switch opts.Driver {
 		case sql.SQLite:
-			return sqlite.NewTokenNotifier(sqlDB, common.NewDBOptsFromOpts(*opts))
+			return sqlite.NewTokenNotifier(sqlDB, dbOpts)
 		case sql.Postgres:
 			// Make sure the schema for the table is created
-			if _, err := postgres.NewTokenDB(sqlDB, common.NewDBOptsFromOpts(*opts)); err != nil {
+			if _, err := postgres.NewTokenDB(sqlDB, dbOpts); err != nil {
 				panic(err)
 			}
-			return postgres.NewTokenNotifier(sqlDB, common.NewDBOptsFromOpts(*opts))
+			return postgres.NewTokenNotifier(sqlDB, dbOpts)
+		case common.Oracle:
+			return oracle.NewTokenNotifier(sqlDB, dbOpts)
+		case common.SQLServer:
+			return sqlserver.NewTokenNotifier(sqlDB, dbOpts)
 		}
 		panic("undefined")
 	})
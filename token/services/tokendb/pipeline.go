@@ -0,0 +1,199 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokendb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+// ErrPipelineStopped is returned when a write is submitted to a stopped pipeline
+var ErrPipelineStopped = errors.New("commit pipeline stopped")
+
+// errCommit marks the failures of the commit of a db transaction, whose outcome is unknown
+var errCommit = errors.New("failed to commit db transaction")
+
+// Job is a write applied by the pipeline in the passed db transaction.
+// A job can run more than once, when the batch it belongs to is retried, only its last run is committed.
+type Job func(ctx context.Context, tx driver.TokenDBTransaction) error
+
+type pipelineRequest struct {
+	ctx context.Context
+	// job is applied in a db transaction, possibly with the jobs of other requests
+	job Job
+	// exec runs alone, between two batches
+	exec func() error
+	done chan error
+}
+
+// Pipeline applies, with a single worker, the writes to a token db.
+// The finality listener, the pruning jobs, and the manual repairs submit their writes to the pipeline,
+// that applies them one batch at a time, therefore, they never contend with each other at the database level.
+// Reads do not go through the pipeline and remain concurrent.
+type Pipeline struct {
+	db       driver.TokenDB
+	maxBatch int
+	requests chan *pipelineRequest
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewPipeline returns a new pipeline applying the writes to the passed db, up to maxBatch jobs per db transaction.
+// The pipeline applies nothing until it is started.
+func NewPipeline(db driver.TokenDB, maxBatch int) *Pipeline {
+	if maxBatch <= 0 {
+		maxBatch = 1
+	}
+	return &Pipeline{
+		db:       db,
+		maxBatch: maxBatch,
+		requests: make(chan *pipelineRequest),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Start starts the worker of the pipeline
+func (p *Pipeline) Start() {
+	go p.run()
+}
+
+// Stop makes the pipeline reject new writes, and waits for the worker to complete the writes it accepted
+func (p *Pipeline) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.stopped
+}
+
+// Submit applies the passed job in a db transaction, possibly shared with the jobs submitted concurrently.
+// It returns once the transaction is committed, or with the error of the job.
+// The failure of a job does not make the jobs batched with it fail, they are applied again one by one.
+// If the commit of a batch fails instead, the jobs may or may not have been applied, therefore,
+// they are not applied again and all of them get the error of the commit.
+func (p *Pipeline) Submit(ctx context.Context, job Job) error {
+	return p.submit(ctx, &pipelineRequest{ctx: ctx, job: job, done: make(chan error, 1)})
+}
+
+// Exec runs the passed function on the worker, alone.
+// It is meant for the writes that the db performs in their own transaction, like DeleteTokens.
+func (p *Pipeline) Exec(ctx context.Context, f func() error) error {
+	return p.submit(ctx, &pipelineRequest{ctx: ctx, exec: f, done: make(chan error, 1)})
+}
+
+func (p *Pipeline) submit(ctx context.Context, r *pipelineRequest) error {
+	select {
+	case p.requests <- r:
+	case <-p.stop:
+		return ErrPipelineStopped
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "write not submitted")
+	}
+	// once accepted, the caller waits for the worker: a write whose context expires before the worker gets to it is not applied,
+	// and its caller gets the error of the context
+	return <-r.done
+}
+
+func (p *Pipeline) run() {
+	defer close(p.stopped)
+	for {
+		select {
+		case <-p.stop:
+			return
+		case r := <-p.requests:
+			if r.exec != nil {
+				r.done <- r.exec()
+				continue
+			}
+			batch, next := p.collect(r)
+			p.apply(batch)
+			if next != nil {
+				next.done <- next.exec()
+			}
+		}
+	}
+}
+
+// collect gathers the jobs already waiting to be submitted, after the passed one, up to the maximum batch size.
+// The batch ends at the first exec request, that is returned to be run after it.
+func (p *Pipeline) collect(first *pipelineRequest) ([]*pipelineRequest, *pipelineRequest) {
+	batch := []*pipelineRequest{first}
+	for len(batch) < p.maxBatch {
+		select {
+		case r := <-p.requests:
+			if r.exec != nil {
+				return batch, r
+			}
+			batch = append(batch, r)
+		default:
+			return batch, nil
+		}
+	}
+	return batch, nil
+}
+
+func (p *Pipeline) apply(batch []*pipelineRequest) {
+	if len(batch) == 1 {
+		batch[0].done <- applyJob(batch[0].ctx, p.db, batch[0].job)
+		return
+	}
+	jobsFailed := false
+	err := applyJob(context.Background(), p.db, func(ctx context.Context, tx driver.TokenDBTransaction) error {
+		for _, r := range batch {
+			if err := r.job(r.ctx, tx); err != nil {
+				jobsFailed = true
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		for _, r := range batch {
+			r.done <- nil
+		}
+		return
+	}
+	if !jobsFailed && errors.Is(err, errCommit) {
+		// the outcome of the commit is unknown, applying the jobs again could apply them twice
+		logger.Errorf("commit of a batch of [%d] jobs failed [%s], the jobs are not applied again", len(batch), err)
+		for _, r := range batch {
+			r.done <- err
+		}
+		return
+	}
+	// one of the jobs failed, or the transaction could not start, and nothing has been applied:
+	// the jobs are applied one by one for each to get its own outcome
+	logger.Debugf("batch of [%d] jobs failed [%s], applying them one by one", len(batch), err)
+	for _, r := range batch {
+		r.done <- applyJob(r.ctx, p.db, r.job)
+	}
+}
+
+// applyJob applies the passed job in a new db transaction of the passed db, committed if the job succeeds
+func applyJob(ctx context.Context, db driver.TokenDB, job Job) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Wrapf(err, "write not applied")
+	}
+	tx, err := db.NewTokenDBTransaction(ctx)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to start db transaction")
+	}
+	if err := job(ctx, tx); err != nil {
+		if err1 := tx.Rollback(); err1 != nil {
+			logger.Errorf("failed to roll back db transaction [%s]", err1)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", errCommit, err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokendb
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// memDB records the tokens stored by the committed transactions.
+// If commitErr is set, the commits store the tokens and then fail with it, as a commit whose acknowledgement is lost.
+type memDB struct {
+	driver.TokenDB
+	lock      sync.Mutex
+	tokens    []string
+	commitErr error
+}
+
+func (m *memDB) NewTokenDBTransaction(context.Context) (driver.TokenDBTransaction, error) {
+	return &memTx{db: m}, nil
+}
+
+type memTx struct {
+	driver.TokenDBTransaction
+	db     *memDB
+	tokens []string
+}
+
+func (t *memTx) StoreToken(_ context.Context, tr driver.TokenRecord, _ []string) error {
+	t.tokens = append(t.tokens, tr.TxID)
+	return nil
+}
+
+func (t *memTx) Commit() error {
+	t.db.lock.Lock()
+	defer t.db.lock.Unlock()
+	t.db.tokens = append(t.db.tokens, t.tokens...)
+	return t.db.commitErr
+}
+
+func (t *memTx) Rollback() error { return nil }
+
+func store(txID string) Job {
+	return func(ctx context.Context, tx driver.TokenDBTransaction) error {
+		return tx.StoreToken(ctx, driver.TokenRecord{TxID: txID}, nil)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	db := &memDB{}
+	p := NewPipeline(db, 10)
+
+	// the jobs submitted while the worker is busy are batched
+	busy := make(chan struct{})
+	execDone := make(chan error)
+	p.Start()
+	go func() {
+		execDone <- p.Exec(context.Background(), func() error {
+			<-busy
+			return nil
+		})
+	}()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i == 2 {
+				errs[i] = p.Submit(context.Background(), func(context.Context, driver.TokenDBTransaction) error {
+					return errors.New("invalid")
+				})
+				return
+			}
+			errs[i] = p.Submit(context.Background(), store(string(rune('a'+i))))
+		}(i)
+	}
+	close(busy)
+	assert.NoError(t, <-execDone)
+	wg.Wait()
+
+	// the failing job does not make the others fail
+	for i, err := range errs {
+		if i == 2 {
+			assert.EqualError(t, err, "invalid")
+			continue
+		}
+		assert.NoError(t, err)
+	}
+	assert.ElementsMatch(t, []string{"a", "b", "d", "e"}, db.tokens)
+
+	// once stopped, no write is accepted
+	p.Stop()
+	assert.ErrorIs(t, p.Submit(context.Background(), store("f")), ErrPipelineStopped)
+	assert.ErrorIs(t, p.Exec(context.Background(), func() error { return nil }), ErrPipelineStopped)
+}
+
+func request(ctx context.Context, job Job) *pipelineRequest {
+	return &pipelineRequest{ctx: ctx, job: job, done: make(chan error, 1)}
+}
+
+func TestPipelineApply(t *testing.T) {
+	// a job fails, nothing has been committed, the others are applied one by one
+	db := &memDB{}
+	p := NewPipeline(db, 10)
+	batch := []*pipelineRequest{
+		request(context.Background(), store("a")),
+		request(context.Background(), func(context.Context, driver.TokenDBTransaction) error { return errors.New("invalid") }),
+		request(context.Background(), store("c")),
+	}
+	p.apply(batch)
+	assert.NoError(t, <-batch[0].done)
+	assert.EqualError(t, <-batch[1].done, "invalid")
+	assert.NoError(t, <-batch[2].done)
+	assert.Equal(t, []string{"a", "c"}, db.tokens)
+
+	// the commit fails, its outcome is unknown, the jobs are not applied again
+	db = &memDB{commitErr: errors.New("connection reset")}
+	p = NewPipeline(db, 10)
+	batch = []*pipelineRequest{
+		request(context.Background(), store("a")),
+		request(context.Background(), store("b")),
+	}
+	p.apply(batch)
+	for _, r := range batch {
+		err := <-r.done
+		assert.ErrorIs(t, err, errCommit)
+		assert.ErrorContains(t, err, "connection reset")
+	}
+	assert.Equal(t, []string{"a", "b"}, db.tokens)
+
+	// a job whose context has expired is not applied
+	db = &memDB{}
+	p = NewPipeline(db, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	batch = []*pipelineRequest{request(ctx, store("a"))}
+	p.apply(batch)
+	assert.ErrorIs(t, <-batch[0].done, context.Canceled)
+	assert.Empty(t, db.tokens)
+}
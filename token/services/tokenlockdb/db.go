@@ -7,8 +7,14 @@ SPDX-License-Identifier: Apache-2.0
 package tokenlockdb
 
 import (
+	"context"
+	"time"
+
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/utils/types/transaction"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
 )
 
 type (
@@ -20,6 +26,49 @@ func NewHolder(drivers []db.NamedDriver[driver.TokenLockDBDriver]) Holder {
 	return db.NewDriverHolder[*DB, driver.TokenLockDB, driver.TokenLockDBDriver](newDB, drivers...)
 }
 
-type DB struct{ driver.TokenLockDB }
+type DB struct {
+	driver.TokenLockDB
+	// writes tracks the in-flight locks and cleanups to drain on shutdown.
+	// Unlocks are not tracked so that in-flight transactions can still release their locks.
+	writes db.WriteGate
+}
 
 func newDB(p driver.TokenLockDB) *DB { return &DB{TokenLockDB: p} }
+
+// Lock locks a specific token for the consumer TX
+func (d *DB) Lock(tokenID *token.ID, consumerTxID transaction.ID) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot lock token for [%s]", consumerTxID)
+	}
+	defer d.writes.Exit()
+	return d.TokenLockDB.Lock(tokenID, consumerTxID)
+}
+
+// Cleanup removes the expired locks
+func (d *DB) Cleanup(leaseExpiry time.Duration) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot cleanup locks")
+	}
+	defer d.writes.Exit()
+	return d.TokenLockDB.Cleanup(leaseExpiry)
+}
+
+// Drain makes the database reject new locks and waits for the in-flight ones to complete or for the context to expire
+func (d *DB) Drain(ctx context.Context) error {
+	return d.writes.Drain(ctx)
+}
+
+// Resume makes the database accept locks again after a Drain
+func (d *DB) Resume() {
+	d.writes.Resume()
+}
+
+// SetReadOnly makes the database reject locks, or accept them again
+func (d *DB) SetReadOnly(readOnly bool) {
+	d.writes.SetReadOnly(readOnly)
+}
+
+// SetStatementObserver makes the database notify the passed observer of the interrupted statements
+func (d *DB) SetStatementObserver(o driver.StatementObserver) {
+	db.SetStatementObserver(d.TokenLockDB, o)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokens
+
+import (
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// Certification describes the certification of a token, as listed by ListCertifications
+type Certification struct {
+	// TokenID is the id of the token
+	TokenID *token2.ID
+	// StoredAt is the time the certification was stored, zero if the token is not certified
+	StoredAt time.Time
+	// Size is the size, in bytes, of the certification, zero if the token is not certified
+	Size int
+	// Valid is true if the certification verifies against the current public parameters
+	Valid bool
+}
+
+// ListCertifications returns a page of the certifications stored in the token db, or, if params.Missing is set,
+// of the unspent tokens owned by this node that are not certified, and that the selector cannot spend yet.
+// Each certification is verified against the current public parameters.
+// The next page starts after the token id of the last returned certification.
+func (t *Tokens) ListCertifications(params driver.QueryCertificationsParams) ([]*Certification, error) {
+	records, err := t.Storage.tokenDB.ListCertifications(params)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to list certifications")
+	}
+	var cm *token.CertificationManager
+	if !params.Missing {
+		tms, err := t.TMSProvider.GetManagementService(token.WithTMSID(t.Storage.tmsID))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get tms for [%s]", t.Storage.tmsID)
+		}
+		cm = tms.CertificationManager()
+	}
+	certifications := make([]*Certification, len(records))
+	for i, r := range records {
+		certifications[i] = &Certification{
+			TokenID:  r.TokenID,
+			StoredAt: r.StoredAt,
+			Size:     len(r.Certification),
+		}
+		if cm == nil || len(r.Certification) == 0 {
+			continue
+		}
+		// one at a time, so that an invalid certification does not hide the valid ones
+		if _, err := cm.VerifyCertifications([]*token2.ID{r.TokenID}, [][]byte{r.Certification}); err != nil {
+			logger.Debugf("certification of [%s] is not valid: [%s]", r.TokenID, err)
+			continue
+		}
+		certifications[i].Valid = true
+	}
+	return certifications, nil
+}
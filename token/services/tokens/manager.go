@@ -7,12 +7,15 @@ SPDX-License-Identifier: Apache-2.0
 package tokens
 
 import (
+	"context"
+	errors2 "errors"
 	"reflect"
 	"sync"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/cache/secondcache"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/events"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
 	"github.com/pkg/errors"
 )
@@ -31,8 +34,9 @@ type Manager struct {
 	dbProvider  DBProvider
 	notifier    events.Publisher
 
-	mutex  sync.Mutex
-	tokens map[string]*Tokens
+	mutex   sync.Mutex
+	tokens  map[string]*Tokens
+	stopped bool
 }
 
 // NewManager creates a new Tokens manager.
@@ -56,6 +60,9 @@ func (cm *Manager) Tokens(tmsID token.TMSID) (*Tokens, error) {
 
 	id := tmsID.String()
 	logger.Debugf("get ttxdb for [%s]", id)
+	if cm.stopped {
+		return nil, errors.Wrapf(db.ErrStopped, "cannot get tokens for [%s]", id)
+	}
 	c, ok := cm.tokens[id]
 	if !ok {
 		var err error
@@ -68,6 +75,39 @@ func (cm *Manager) Tokens(tmsID token.TMSID) (*Tokens, error) {
 	return c, nil
 }
 
+// Start makes the Tokens accept writes again after a Stop
+func (cm *Manager) Start(context.Context) error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.stopped = false
+	for _, t := range cm.tokens {
+		t.Resume()
+	}
+	return nil
+}
+
+// Stop makes the Tokens reject new writes and waits for the in-flight ones,
+// like the tokens of the transactions being committed, to complete or for the context to expire.
+// The token databases are not closed, they are owned by the tokendb manager.
+func (cm *Manager) Stop(ctx context.Context) error {
+	cm.mutex.Lock()
+	cm.stopped = true
+	tokens := make(map[string]*Tokens, len(cm.tokens))
+	for id, t := range cm.tokens {
+		tokens[id] = t
+	}
+	cm.mutex.Unlock()
+
+	var errs []error
+	for id, t := range tokens {
+		if err := t.Drain(ctx); err != nil {
+			errs = append(errs, errors.WithMessagef(err, "failed draining tokens for [%s]", id))
+		}
+	}
+	return errors2.Join(errs...)
+}
+
 func (cm *Manager) newTokens(tmsID token.TMSID) (*Tokens, error) {
 	db, err := cm.dbProvider.DBByTMSId(tmsID)
 	if err != nil {
@@ -0,0 +1,217 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokens
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	dbdriver "github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// ConflictType tells why a simulated request would not be applied
+type ConflictType int
+
+const (
+	// AlreadyApplied is the conflict of a request whose transaction is already in the token db or already simulated
+	AlreadyApplied ConflictType = iota
+	// SpentInput is the conflict of a request spending a token already spent, in the token db or by a simulated request
+	SpentInput
+)
+
+// Conflict is the reason why a simulated request would not be applied
+type Conflict struct {
+	Type ConflictType
+	// TxID is the id of the conflicting request
+	TxID string
+	// TokenID is the spent input, if Type is SpentInput
+	TokenID *token2.ID
+	// SpentBy is the transaction that spent the input, if known
+	SpentBy string
+}
+
+// SimulationResult is the outcome of the simulation of a request
+type SimulationResult struct {
+	TxID string
+	// Spent are the inputs the request spends
+	Spent []*token2.ID
+	// Added are the outputs the request stores
+	Added []*token2.ID
+	// Conflicts lists why the request would not be applied. The request is applied to the simulation only if empty.
+	Conflicts []Conflict
+}
+
+// SimulationDB is the content of the token db a simulation starts from
+type SimulationDB interface {
+	TransactionExists(ctx context.Context, id string) (bool, error)
+	QueryTokenDetails(params dbdriver.QueryTokenDetailsParams) ([]dbdriver.TokenDetails, error)
+	Balance(walletID, typ string) (uint64, error)
+}
+
+// simulatedToken is a token stored by a simulated request
+type simulatedToken struct {
+	tokenType string
+	amount    uint64
+	// wallets are the wallets of this node owning the token
+	wallets []string
+	spentBy string
+}
+
+// spentToken is a token of the token db spent by a simulated request
+type spentToken struct {
+	spentBy string
+	// owned are the wallets of this node owning the token, with the amount and type
+	owned []dbdriver.TokenDetails
+}
+
+// Simulation applies token requests to an in-memory overlay of the token db, without committing them.
+// It predicts the balances of the wallets of this node, and the conflicts of the requests, for instance for pre-trade risk checks.
+// The inputs not owned by the wallets of this node are not checked, because the token db does not know if they are spent.
+type Simulation struct {
+	tokens *Tokens
+	tmsID  token.TMSID
+	db     SimulationDB
+
+	lock    sync.Mutex
+	applied map[string]bool
+	added   map[token2.ID]*simulatedToken
+	spent   map[token2.ID]*spentToken
+}
+
+// NewSimulation returns a new simulation over the current content of the token db of the passed TMS
+func (t *Tokens) NewSimulation(tmsID token.TMSID) *Simulation {
+	return newSimulation(t, tmsID, t.Storage.tokenDB)
+}
+
+func newSimulation(t *Tokens, tmsID token.TMSID, db SimulationDB) *Simulation {
+	return &Simulation{
+		tokens:  t,
+		tmsID:   tmsID,
+		db:      db,
+		applied: map[string]bool{},
+		added:   map[token2.ID]*simulatedToken{},
+		spent:   map[token2.ID]*spentToken{},
+	}
+}
+
+// Apply simulates the application of the passed request, as Tokens.Append would do once the transaction is committed.
+// The request is applied to the simulation only if it has no conflicts, as the ledger would invalidate it otherwise.
+func (s *Simulation) Apply(ctx context.Context, txID string, request *token.Request) (*SimulationResult, error) {
+	if request == nil || request.Metadata == nil {
+		return &SimulationResult{TxID: txID}, nil
+	}
+	toSpend, toAppend, err := s.tokens.extractActions(s.tmsID, txID, request)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "transaction [%s], failed to extract actions", txID)
+	}
+	return s.apply(ctx, txID, toSpend, toAppend)
+}
+
+func (s *Simulation) apply(ctx context.Context, txID string, toSpend []*token2.ID, toAppend []TokenToAppend) (*SimulationResult, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := &SimulationResult{TxID: txID, Spent: toSpend}
+	exists, err := s.db.TransactionExists(ctx, txID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "transaction [%s], failed to check existence in db", txID)
+	}
+	if exists || s.applied[txID] {
+		res.Conflicts = append(res.Conflicts, Conflict{Type: AlreadyApplied, TxID: txID})
+		return res, nil
+	}
+
+	// the inputs not stored by a simulated request are looked up in the token db
+	var lookup []*token2.ID
+	for _, id := range toSpend {
+		if sp, ok := s.spent[*id]; ok {
+			res.Conflicts = append(res.Conflicts, Conflict{Type: SpentInput, TxID: txID, TokenID: id, SpentBy: sp.spentBy})
+			continue
+		}
+		if added, ok := s.added[*id]; ok {
+			if len(added.spentBy) != 0 {
+				res.Conflicts = append(res.Conflicts, Conflict{Type: SpentInput, TxID: txID, TokenID: id, SpentBy: added.spentBy})
+			}
+			continue
+		}
+		lookup = append(lookup, id)
+	}
+	owned := map[token2.ID][]dbdriver.TokenDetails{}
+	if len(lookup) != 0 {
+		details, err := s.db.QueryTokenDetails(dbdriver.QueryTokenDetailsParams{IDs: lookup, IncludeDeleted: true})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "transaction [%s], failed to query inputs", txID)
+		}
+		for _, d := range details {
+			id := token2.ID{TxId: d.TxID, Index: d.Index}
+			if d.IsSpent {
+				res.Conflicts = append(res.Conflicts, Conflict{Type: SpentInput, TxID: txID, TokenID: &id, SpentBy: d.SpentBy})
+				continue
+			}
+			owned[id] = append(owned[id], d)
+		}
+	}
+	if len(res.Conflicts) != 0 {
+		return res, nil
+	}
+
+	// no conflicts, apply the request
+	s.applied[txID] = true
+	for _, id := range toSpend {
+		if added, ok := s.added[*id]; ok {
+			added.spentBy = txID
+			continue
+		}
+		s.spent[*id] = &spentToken{spentBy: txID, owned: owned[*id]}
+	}
+	for _, tta := range toAppend {
+		q, err := token2.ToQuantity(tta.tok.Quantity, tta.precision)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot covert [%s] with precision [%d]", tta.tok.Quantity, tta.precision)
+		}
+		t := &simulatedToken{tokenType: tta.tok.Type, amount: q.ToBigInt().Uint64()}
+		if tta.flags.Mine {
+			t.wallets = tta.owners
+			if len(tta.ownerWalletID) != 0 && !slices.Contains(t.wallets, tta.ownerWalletID) {
+				t.wallets = append(slices.Clone(t.wallets), tta.ownerWalletID)
+			}
+		}
+		id := token2.ID{TxId: tta.txID, Index: tta.index}
+		s.added[id] = t
+		res.Added = append(res.Added, &id)
+	}
+	return res, nil
+}
+
+// Balance returns the balance the passed wallet would have, for the passed token type, once the simulated requests are applied
+func (s *Simulation) Balance(walletID, tokenType string) (uint64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	balance, err := s.db.Balance(walletID, tokenType)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed to get balance of [%s:%s]", walletID, tokenType)
+	}
+	for _, sp := range s.spent {
+		for _, d := range sp.owned {
+			if d.OwnerEnrollment == walletID && d.Type == tokenType {
+				// the token db might have changed in the meantime
+				balance -= min(balance, d.Amount)
+			}
+		}
+	}
+	for _, t := range s.added {
+		if len(t.spentBy) == 0 && t.tokenType == tokenType && slices.Contains(t.wallets, walletID) {
+			balance += t.amount
+		}
+	}
+	return balance, nil
+}
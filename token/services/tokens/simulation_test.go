@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokens
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	dbdriver "github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/test-go/testify/assert"
+)
+
+type simulationDBMock struct {
+	txIDs  []string
+	tokens []dbdriver.TokenDetails
+}
+
+func (db *simulationDBMock) TransactionExists(_ context.Context, id string) (bool, error) {
+	return slices.Contains(db.txIDs, id), nil
+}
+
+func (db *simulationDBMock) QueryTokenDetails(params dbdriver.QueryTokenDetailsParams) ([]dbdriver.TokenDetails, error) {
+	var res []dbdriver.TokenDetails
+	for _, d := range db.tokens {
+		for _, id := range params.IDs {
+			if d.TxID == id.TxId && d.Index == id.Index && (params.IncludeDeleted || !d.IsSpent) {
+				res = append(res, d)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (db *simulationDBMock) Balance(walletID, typ string) (uint64, error) {
+	var sum uint64
+	for _, d := range db.tokens {
+		if d.OwnerEnrollment == walletID && d.Type == typ && !d.IsSpent {
+			sum += d.Amount
+		}
+	}
+	return sum, nil
+}
+
+func output(txID string, index uint64, wallet string, typ string, quantity string) TokenToAppend {
+	return TokenToAppend{
+		txID:          txID,
+		index:         index,
+		tok:           &token2.Token{Owner: []byte(wallet), Type: typ, Quantity: quantity},
+		ownerWalletID: wallet,
+		owners:        []string{wallet},
+		precision:     64,
+		flags:         Flags{Mine: len(wallet) != 0},
+	}
+}
+
+func TestSimulation(t *testing.T) {
+	ctx := context.Background()
+	db := &simulationDBMock{
+		txIDs: []string{"tx0"},
+		tokens: []dbdriver.TokenDetails{
+			{TxID: "tx0", Index: 0, OwnerEnrollment: "alice", Type: "USD", Amount: 10},
+			{TxID: "tx0", Index: 1, OwnerEnrollment: "alice", Type: "USD", Amount: 5},
+			{TxID: "tx00", Index: 0, OwnerEnrollment: "alice", Type: "USD", Amount: 7, IsSpent: true, SpentBy: "tx01"},
+		},
+	}
+	s := newSimulation(&Tokens{}, token.TMSID{Network: "n"}, db)
+
+	balance, err := s.Balance("alice", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(15), balance)
+
+	// alice pays 8 to bob, a wallet of another node, and gets the change
+	res, err := s.apply(ctx, "tx1", []*token2.ID{{TxId: "tx0", Index: 0}}, []TokenToAppend{
+		output("tx1", 0, "", "USD", "0x8"),
+		output("tx1", 1, "alice", "USD", "0x2"),
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Conflicts)
+	assert.Len(t, res.Added, 2)
+	balance, err = s.Balance("alice", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), balance)
+
+	// the token db is not modified
+	balance, err = db.Balance("alice", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(15), balance)
+
+	// spending the same input again conflicts, and the request is not applied
+	res, err = s.apply(ctx, "tx2", []*token2.ID{{TxId: "tx0", Index: 0}, {TxId: "tx0", Index: 1}}, []TokenToAppend{
+		output("tx2", 0, "alice", "USD", "0xf"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Conflict{{Type: SpentInput, TxID: "tx2", TokenID: &token2.ID{TxId: "tx0", Index: 0}, SpentBy: "tx1"}}, res.Conflicts)
+	balance, err = s.Balance("alice", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), balance)
+
+	// spending a token spent in the token db conflicts
+	res, err = s.apply(ctx, "tx3", []*token2.ID{{TxId: "tx00", Index: 0}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Conflict{{Type: SpentInput, TxID: "tx3", TokenID: &token2.ID{TxId: "tx00", Index: 0}, SpentBy: "tx01"}}, res.Conflicts)
+
+	// a committed or simulated transaction conflicts
+	res, err = s.apply(ctx, "tx0", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Conflict{{Type: AlreadyApplied, TxID: "tx0"}}, res.Conflicts)
+	res, err = s.apply(ctx, "tx1", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Conflict{{Type: AlreadyApplied, TxID: "tx1"}}, res.Conflicts)
+
+	// the outputs of a simulated request can be spent
+	res, err = s.apply(ctx, "tx4", []*token2.ID{{TxId: "tx1", Index: 1}, {TxId: "tx0", Index: 1}}, []TokenToAppend{
+		output("tx4", 0, "alice", "EUR", "0x7"),
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Conflicts)
+	balance, err = s.Balance("alice", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), balance)
+	balance, err = s.Balance("alice", "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), balance)
+	res, err = s.apply(ctx, "tx5", []*token2.ID{{TxId: "tx1", Index: 1}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Conflict{{Type: SpentInput, TxID: "tx5", TokenID: &token2.ID{TxId: "tx1", Index: 1}, SpentBy: "tx4"}}, res.Conflicts)
+}
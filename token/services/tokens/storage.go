@@ -28,6 +28,8 @@ type Flags struct {
 	Mine    bool
 	Auditor bool
 	Issuer  bool
+	// Pending marks the tokens created by transactions not final yet
+	Pending bool
 }
 
 type DBStorage struct {
@@ -52,10 +54,62 @@ func (d *DBStorage) NewTransaction(ctx context.Context) (*transaction, error) {
 	return NewTransaction(d.notifier, tx, d.tmsID)
 }
 
+// Apply applies the passed function in a db transaction, committed if the function succeeds.
+// With the commit pipeline of the token db enabled, the function can run more than once.
+// The events of the function are published once the db transaction is committed, and only for its last run.
+func (d *DBStorage) Apply(ctx context.Context, f func(ctx context.Context, ts *transaction) error) error {
+	var ts *transaction
+	err := d.tokenDB.Apply(ctx, func(ctx context.Context, tx tokendb.TokenDBTransaction) error {
+		var err error
+		ts, err = NewTransaction(d.notifier, tx, d.tmsID)
+		if err != nil {
+			return err
+		}
+		return f(ctx, ts)
+	})
+	if err != nil {
+		return err
+	}
+	ts.publish()
+	return nil
+}
+
 func (d *DBStorage) TransactionExists(ctx context.Context, id string) (bool, error) {
 	return d.tokenDB.TransactionExists(ctx, id)
 }
 
+func (d *DBStorage) PendingTransactionExists(ctx context.Context, id string) (bool, error) {
+	return d.tokenDB.PendingTransactionExists(ctx, id)
+}
+
+func (d *DBStorage) SpendPendingTokens(spentBy string, ids []*token2.ID) error {
+	return d.tokenDB.SpendPendingTokens(spentBy, ids...)
+}
+
+func (d *DBStorage) DeletePendingTokens(txID string) ([]string, error) {
+	return d.tokenDB.DeletePendingTokens(txID)
+}
+
+func (d *DBStorage) AddIntent(txID string, request []byte) error {
+	return d.tokenDB.AddIntent(txID, request)
+}
+
+func (d *DBStorage) DeleteIntent(txID string) error {
+	return d.tokenDB.DeleteIntent(txID)
+}
+
+func (d *DBStorage) Intents() ([]tokendb.TokenIntent, error) {
+	return d.tokenDB.Intents()
+}
+
+func (d *DBStorage) MarkIntentApplied(txID string) error {
+	return d.tokenDB.MarkIntentApplied(txID)
+}
+
+func (d *DBStorage) Intent(txID string) (*tokendb.TokenIntent, error) {
+	return d.tokenDB.Intent(txID)
+}
+
 func (d *DBStorage) StorePublicParams(raw []byte) error {
 	return d.tokenDB.StorePublicParams(raw)
 }
@@ -66,6 +120,7 @@ type TokenToAppend struct {
 	tok                   *token2.Token
 	tokenOnLedger         []byte
 	tokenOnLedgerMetadata []byte
+	ledgerFormat          string
 	ownerType             string
 	ownerIdentity         token.Identity
 	ownerWalletID         string
@@ -77,11 +132,13 @@ type TokenToAppend struct {
 
 type transaction struct {
 	notifier events.Publisher
-	tx       *tokendb.Transaction
+	tx       tokendb.TokenDBTransaction
 	tmsID    token.TMSID
+	// events are published once the db transaction is committed
+	events []*TokenProcessorEvent
 }
 
-func NewTransaction(notifier events.Publisher, tx *tokendb.Transaction, tmsID token.TMSID) (*transaction, error) {
+func NewTransaction(notifier events.Publisher, tx tokendb.TokenDBTransaction, tmsID token.TMSID) (*transaction, error) {
 	return &transaction{
 		notifier: notifier,
 		tx:       tx,
@@ -126,6 +183,17 @@ func (t *transaction) DeleteTokens(ctx context.Context, deletedBy string, ids []
 	return nil
 }
 
+// DeletePendingTokens removes the pending tokens created by the passed transaction.
+// It returns, by index, the ids of the transactions that spent them.
+func (t *transaction) DeletePendingTokens(ctx context.Context, txID string) (map[uint64]string, error) {
+	return t.tx.DeletePendingTokens(ctx, txID)
+}
+
+// RevertDeltas removes the tokens created by the passed transaction at the passed indexes, and unspends the passed tokens it spent
+func (t *transaction) RevertDeltas(ctx context.Context, txID string, created []uint64, spent []*token2.ID) error {
+	return t.tx.RevertDeltas(ctx, txID, created, spent)
+}
+
 func (t *transaction) AppendToken(ctx context.Context, tta TokenToAppend) error {
 	span := trace.SpanFromContext(ctx)
 	q, err := token2.ToQuantity(tta.tok.Quantity, tta.precision)
@@ -133,6 +201,16 @@ func (t *transaction) AppendToken(ctx context.Context, tta TokenToAppend) error
 		return errors.Wrapf(err, "cannot covert [%s] with precision [%d]", tta.tok.Quantity, tta.precision)
 	}
 
+	// the attributes of a non-fungible token, and the serials of a serial token, make it queryable by them
+	var attributes map[string]string
+	if q.Cmp(token2.NewOneQuantity(tta.precision)) == 0 {
+		attributes, _ = token2.NFTAttributes(tta.tok.Type)
+	}
+	var serials *token2.SerialRange
+	if r, ok := token2.ParseSerialType(tta.tok.Type); ok {
+		serials = &r
+	}
+
 	span.AddEvent("store_token")
 	err = t.tx.StoreToken(ctx,
 		tokendb.TokenRecord{
@@ -145,12 +223,16 @@ func (t *transaction) AppendToken(ctx context.Context, tta TokenToAppend) error
 			OwnerWalletID:  tta.ownerWalletID,
 			Ledger:         tta.tokenOnLedger,
 			LedgerMetadata: tta.tokenOnLedgerMetadata,
+			LedgerFormat:   tta.ledgerFormat,
 			Quantity:       tta.tok.Quantity,
 			Type:           tta.tok.Type,
 			Amount:         q.ToBigInt().Uint64(),
 			Owner:          tta.flags.Mine,
 			Auditor:        tta.flags.Auditor,
 			Issuer:         tta.flags.Issuer,
+			Pending:        tta.flags.Pending,
+			Attributes:     attributes,
+			Serials:        serials,
 		},
 		tta.owners,
 	)
@@ -158,6 +240,10 @@ func (t *transaction) AppendToken(ctx context.Context, tta TokenToAppend) error
 		return errors.Wrapf(err, "cannot store token in db")
 	}
 
+	if tta.flags.Pending {
+		// the owners are notified once the transaction is final
+		return nil
+	}
 	span.AddEvent("notify_owners")
 	for _, id := range tta.owners {
 		if len(id) == 0 {
@@ -169,30 +255,46 @@ func (t *transaction) AppendToken(ctx context.Context, tta TokenToAppend) error
 	return nil
 }
 
+// Notify queues a token event, published once the db transaction is committed.
+// The events of a transaction rolled back are dropped.
 func (t *transaction) Notify(topic string, tmsID token.TMSID, walletID, tokenType, txID string, index uint64) {
-	if t.notifier == nil {
-		logger.Warnf("cannot notify others!")
-		return
-	}
-
-	e := NewTokenProcessorEvent(topic, &TokenMessage{
+	t.events = append(t.events, NewTokenProcessorEvent(topic, &TokenMessage{
 		TMSID:     tmsID,
 		WalletID:  walletID,
 		TokenType: tokenType,
 		TxID:      txID,
 		Index:     index,
-	})
+	}))
+}
 
-	logger.Debugf("Publish new event %v", e)
-	t.notifier.Publish(e)
+// publish publishes the queued events
+func (t *transaction) publish() {
+	events := t.events
+	t.events = nil
+	if len(events) == 0 {
+		return
+	}
+	if t.notifier == nil {
+		logger.Warnf("cannot notify others!")
+		return
+	}
+	for _, e := range events {
+		logger.Debugf("Publish new event %v", e)
+		t.notifier.Publish(e)
+	}
 }
 
 func (t *transaction) Rollback() error {
+	t.events = nil
 	return t.tx.Rollback()
 }
 
 func (t *transaction) Commit() error {
-	return t.tx.Commit()
+	if err := t.tx.Commit(); err != nil {
+		return err
+	}
+	t.publish()
+	return nil
 }
 
 type TokenProcessorEvent struct {
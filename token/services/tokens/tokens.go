@@ -8,12 +8,13 @@ package tokens
 
 import (
 	"context"
-	"runtime/debug"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/trace"
@@ -56,10 +57,28 @@ type Tokens struct {
 	Storage     *DBStorage
 
 	RequestsCache Cache
+
+	// writes tracks the in-flight writes to drain on shutdown
+	writes db.WriteGate
+	// watches tracks the subscriptions to the changes of ownership of tokens
+	watches watches
+	// journal is true if the intents of the applied requests are kept, to reverse them if their block is rolled back
+	journal bool
+}
+
+// KeepJournal makes the service keep the intents of the requests it applies as journal entries,
+// so that RollbackBlock can reverse them. It is invoked when the network of the TMS can roll back committed blocks.
+// The journal entries are purged with the expired rows, see DeleteExpired of the SQL token db.
+func (t *Tokens) KeepJournal() {
+	t.journal = true
 }
 
 func (t *Tokens) Append(ctx context.Context, tmsID token.TMSID, txID string, request *token.Request) (err error) {
 	span := trace.SpanFromContext(ctx)
+	if err := t.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "transaction [%s], cannot append", txID)
+	}
+	defer t.writes.Exit()
 	if request == nil {
 		logger.Debugf("transaction [%s], no request found, skip it", txID)
 		return nil
@@ -86,44 +105,134 @@ func (t *Tokens) Append(ctx context.Context, tmsID token.TMSID, txID string, req
 		return errors.WithMessagef(err, "transaction [%s], failed to extract actions", txID)
 	}
 
-	logger.Debugf("transaction [%s] start db transaction", txID)
-	span.AddEvent("create_new_tx")
-	ts, err := t.Storage.NewTransaction(ctx)
+	// record the intent to apply the request, so that the request can be applied again if the node crashes before the commit
+	span.AddEvent("add_intent")
+	requestRaw, err := request.Bytes()
 	if err != nil {
-		return errors.WithMessagef(err, "transaction [%s], failed to start db transaction", txID)
+		return errors.WithMessagef(err, "transaction [%s], failed to marshal request", txID)
+	}
+	if err := t.Storage.AddIntent(txID, requestRaw); err != nil {
+		return errors.WithMessagef(err, "transaction [%s], failed to record intent", txID)
 	}
 	defer func() {
-		if err == nil {
-			return
-		}
-		span.RecordError(err)
-		if err1 := ts.Rollback(); err1 != nil {
-			logger.Errorf("error rolling back [%s][%s]", err1, debug.Stack())
-		} else {
-			logger.Infof("transaction [%s] rolled back", txID)
+		// the outcome is known, commit or failure reported to the caller, the intent is no longer needed, but as a journal entry
+		if err1 := t.clearIntent(txID, err == nil); err1 != nil {
+			logger.Warnf("transaction [%s], failed to clear intent [%s]", txID, err1)
 		}
 	}()
-	span.AddEvent("append_tokens")
-	for _, tta := range toAppend {
-		err = ts.AppendToken(ctx, tta)
-		if err != nil {
-			return errors.WithMessagef(err, "transaction [%s], failed to append token", txID)
-		}
-	}
-	span.AddEvent("delete_tokens")
-	err = ts.DeleteTokens(ctx, txID, toSpend)
+
+	span.AddEvent("check_pending_tokens")
+	pending, err := t.Storage.PendingTransactionExists(ctx, txID)
 	if err != nil {
-		return errors.WithMessagef(err, "transaction [%s], failed to delete tokens", txID)
+		return errors.WithMessagef(err, "transaction [%s], failed to check pending tokens", txID)
 	}
-	span.AddEvent("commit")
-	if err = ts.Commit(); err != nil {
+
+	logger.Debugf("transaction [%s] apply db transaction", txID)
+	span.AddEvent("apply_tx")
+	err = t.Storage.Apply(ctx, func(ctx context.Context, ts *transaction) error {
+		// the tokens stored while the transaction was pending are replaced by the final ones,
+		// and the final ones are spent by the transactions that spent the pending ones
+		spent := map[uint64]string{}
+		if pending {
+			span.AddEvent("delete_pending_tokens")
+			var err error
+			if spent, err = ts.DeletePendingTokens(ctx, txID); err != nil {
+				return errors.WithMessagef(err, "failed to delete pending tokens")
+			}
+		}
+		span.AddEvent("append_tokens")
+		for _, tta := range toAppend {
+			if err := ts.AppendToken(ctx, tta); err != nil {
+				return errors.WithMessagef(err, "failed to append token")
+			}
+			if spentBy, ok := spent[tta.index]; ok {
+				if err := ts.DeleteToken(ctx, txID, tta.index, spentBy); err != nil {
+					return errors.WithMessagef(err, "failed to spend token replacing pending token")
+				}
+			}
+		}
+		span.AddEvent("delete_tokens")
+		if err := ts.DeleteTokens(ctx, txID, toSpend); err != nil {
+			return errors.WithMessagef(err, "failed to delete tokens")
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
 		return errors.WithMessagef(err, "transaction [%s], failed to commit tokens to database", txID)
 	}
 	logger.Debugf("transaction [%s], committed tokens [%d:%d] to database", txID, len(toAppend), len(toSpend))
 
+	outputs := make([]*token2.Token, len(toAppend))
+	for i, tta := range toAppend {
+		outputs[i] = tta.tok
+	}
+	t.watches.notify(TokenSpent, txID, toSpend, outputs)
+
+	return nil
+}
+
+// AppendPending stores, as pending, the tokens owned by this node that the passed transaction creates,
+// and marks the pending tokens it spends as spent by it.
+// The selectors can then spend the pending tokens in chained transactions, see features.PendingSelector.
+// When the transaction gets final, Append replaces its pending tokens with the final ones, or DiscardPending deletes them.
+func (t *Tokens) AppendPending(ctx context.Context, tmsID token.TMSID, txID string, request *token.Request) (err error) {
+	if err := t.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "transaction [%s], cannot append pending tokens", txID)
+	}
+	defer t.writes.Exit()
+	if request == nil || request.Metadata == nil {
+		logger.Debugf("transaction [%s], no request or metadata found, skip it", txID)
+		return nil
+	}
+	exists, err := t.Storage.TransactionExists(ctx, txID)
+	if err != nil {
+		return errors.WithMessagef(err, "transaction [%s], failed to check existence in db", txID)
+	}
+	if exists {
+		logger.Debugf("transaction [%s], already final, skipping", txID)
+		return nil
+	}
+	toSpend, toAppend, err := t.getActions(tmsID, txID, request)
+	if err != nil {
+		return errors.WithMessagef(err, "transaction [%s], failed to extract actions", txID)
+	}
+
+	appended := 0
+	err = t.Storage.Apply(ctx, func(ctx context.Context, ts *transaction) error {
+		appended = 0
+		for _, tta := range toAppend {
+			if !tta.flags.Mine {
+				continue
+			}
+			tta.flags = Flags{Mine: true, Pending: true}
+			if err := ts.AppendToken(ctx, tta); err != nil {
+				return errors.WithMessagef(err, "failed to append pending token")
+			}
+			appended++
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "transaction [%s], failed to commit pending tokens to database", txID)
+	}
+	if err = t.Storage.SpendPendingTokens(txID, toSpend); err != nil {
+		return errors.WithMessagef(err, "transaction [%s], failed to spend pending tokens", txID)
+	}
+	logger.Debugf("transaction [%s], committed [%d] pending tokens to database", txID, appended)
 	return nil
 }
 
+// DiscardPending deletes the pending tokens created by the passed transaction, that is not going to be final.
+// It returns the ids of the transactions that spent them, which cannot be final either.
+func (t *Tokens) DiscardPending(txID string) ([]string, error) {
+	if err := t.writes.Enter(); err != nil {
+		return nil, errors.WithMessagef(err, "transaction [%s], cannot discard pending tokens", txID)
+	}
+	defer t.writes.Exit()
+	return t.Storage.DeletePendingTokens(txID)
+}
+
 func (t *Tokens) AppendRaw(ctx context.Context, tmsID token.TMSID, txID string, requestRaw []byte) (err error) {
 	logger.Debugf("get tms for [%s]", txID)
 	tms, err := t.TMSProvider.GetManagementService(token.WithTMSID(tmsID))
@@ -139,6 +248,38 @@ func (t *Tokens) AppendRaw(ctx context.Context, tmsID token.TMSID, txID string,
 	return t.Append(ctx, tmsID, txID, tr)
 }
 
+// Recover applies again the token requests whose intent was recorded but not cleared,
+// that is, those of the transactions being applied when the node stopped.
+// Applying a request is idempotent, therefore, requests whose application was committed are not applied twice.
+// It returns the ids of the recovered transactions.
+func (t *Tokens) Recover(ctx context.Context, tmsID token.TMSID) ([]string, error) {
+	intents, err := t.Storage.Intents()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get intents")
+	}
+	recovered := make([]string, 0, len(intents))
+	for _, intent := range intents {
+		logger.Infof("transaction [%s], recovering request recorded at [%s]", intent.TxID, intent.StoredAt)
+		if err := t.AppendRaw(ctx, tmsID, intent.TxID, intent.Request); err != nil {
+			return recovered, errors.WithMessagef(err, "transaction [%s], failed to recover", intent.TxID)
+		}
+		// the request might have been already committed, in which case Append does not clear the intent
+		if err := t.clearIntent(intent.TxID, true); err != nil {
+			return recovered, errors.WithMessagef(err, "transaction [%s], failed to clear intent", intent.TxID)
+		}
+		recovered = append(recovered, intent.TxID)
+	}
+	return recovered, nil
+}
+
+// clearIntent deletes the intent of the passed transaction, or marks it as applied if the journal is kept and the request was applied
+func (t *Tokens) clearIntent(txID string, applied bool) error {
+	if t.journal && applied {
+		return t.Storage.MarkIntentApplied(txID)
+	}
+	return t.Storage.DeleteIntent(txID)
+}
+
 func (t *Tokens) CacheRequest(tmsID token.TMSID, request *token.Request) error {
 	toSpend, toAppend, err := t.extractActions(tmsID, request.Anchor, request)
 	if err != nil {
@@ -173,15 +314,105 @@ func (t *Tokens) AppendTransaction(ctx context.Context, tx Transaction) (err err
 	}, tx.ID(), tx.Request())
 }
 
+// RollbackBlock reverses the token deltas of the passed transactions, those of a block rolled back by the ledger.
+// The deltas come from the journal kept with KeepJournal: the tokens created by a transaction are removed
+// and the tokens it spent are unspent again, therefore, the transaction can be appended again once committed in a new block.
+// The transactions are reverted in reverse order, the last of the block first.
+// The transactions without a journal entry did not change the token db and are skipped.
+// It is invoked by the network drivers whose ledger can roll back committed blocks.
+func (t *Tokens) RollbackBlock(ctx context.Context, tmsID token.TMSID, blockNum uint64, txIDs []string) error {
+	if err := t.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "block [%d], cannot roll back", blockNum)
+	}
+	defer t.writes.Exit()
+	for i := len(txIDs) - 1; i >= 0; i-- {
+		txID := txIDs[i]
+		intent, err := t.Storage.Intent(txID)
+		if err != nil {
+			return errors.WithMessagef(err, "block [%d], failed to get journal entry of [%s]", blockNum, txID)
+		}
+		if intent == nil || !intent.Applied {
+			logger.Debugf("block [%d], transaction [%s] has no journal entry, skip it", blockNum, txID)
+			continue
+		}
+		toSpend, toAppend, err := t.journalDeltas(tmsID, intent)
+		if err != nil {
+			return errors.WithMessagef(err, "block [%d], failed to get deltas of [%s]", blockNum, txID)
+		}
+		created := make([]uint64, len(toAppend))
+		for j, tta := range toAppend {
+			created[j] = tta.index
+		}
+		err = t.Storage.Apply(ctx, func(ctx context.Context, ts *transaction) error {
+			return ts.RevertDeltas(ctx, txID, created, toSpend)
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "block [%d], failed to revert transaction [%s]", blockNum, txID)
+		}
+		// the transaction is appended again, with a new intent, once committed in a new block
+		if err := t.Storage.DeleteIntent(txID); err != nil {
+			return errors.WithMessagef(err, "block [%d], failed to clear journal entry of [%s]", blockNum, txID)
+		}
+		logger.Infof("block [%d], transaction [%s] reverted", blockNum, txID)
+	}
+	return nil
+}
+
+// journalDeltas returns the tokens spent and created by the request of the passed journal entry
+func (t *Tokens) journalDeltas(tmsID token.TMSID, intent *tokendb.TokenIntent) ([]*token2.ID, []TokenToAppend, error) {
+	if entry, ok := t.RequestsCache.Get(intent.TxID); ok {
+		return entry.ToSpend, entry.ToAppend, nil
+	}
+	tms, err := t.TMSProvider.GetManagementService(token.WithTMSID(tmsID))
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed getting token management service [%s]", tmsID)
+	}
+	request, err := tms.NewFullRequestFromBytes(intent.Request)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed unmarshal token request [%s]", intent.TxID)
+	}
+	return t.extractActions(tmsID, intent.TxID, request)
+}
+
 // StorePublicParams stores the passed public parameters in the token db
 func (t *Tokens) StorePublicParams(raw []byte) error {
+	if err := t.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot store public parameters")
+	}
+	defer t.writes.Exit()
 	return t.Storage.StorePublicParams(raw)
 }
 
 // DeleteToken marks the entries corresponding to the passed token ids as deleted.
 // The deletion is attributed to the passed deletedBy argument.
 func (t *Tokens) DeleteToken(deletedBy string, ids ...*token2.ID) (err error) {
-	return t.Storage.tokenDB.DeleteTokens(deletedBy, ids...)
+	if err := t.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot delete tokens for [%s]", deletedBy)
+	}
+	defer t.writes.Exit()
+	if err := t.Storage.tokenDB.DeleteTokens(deletedBy, ids...); err != nil {
+		return err
+	}
+	t.watches.notify(TokenDeleted, deletedBy, ids, nil)
+	return nil
+}
+
+// WatchTokens subscribes to the changes of ownership of the tokens with the passed ids.
+// The returned channel receives an event when a watched token is spent by a transaction committed to the token db, or deleted.
+// It is closed when all the watched tokens have been consumed or when the returned cancel function is called.
+// Only the changes that happen after the call are reported, the caller checks in the token db the tokens already spent.
+func (t *Tokens) WatchTokens(ids []*token2.ID) (<-chan TokenEvent, func()) {
+	return t.watches.add(ids)
+}
+
+// Drain makes the service reject new writes and waits for the in-flight ones to complete or for the context to expire
+func (t *Tokens) Drain(ctx context.Context) error {
+	return t.writes.Drain(ctx)
+}
+
+// Resume makes the service accept writes again after a Drain
+func (t *Tokens) Resume() {
+	t.writes.Resume()
 }
 
 func (t *Tokens) getActions(tmsID token.TMSID, txID string, request *token.Request) ([]*token2.ID, []TokenToAppend, error) {
@@ -201,8 +432,10 @@ func (t *Tokens) extractActions(tmsID token.TMSID, txID string, request *token.R
 	}
 
 	logger.Debugf("transaction [%s on (%s)] is known, extract tokens", txID, tms.ID())
-	graphHiding := tms.PublicParametersManager().PublicParameters().GraphHiding()
-	precision := tms.PublicParametersManager().PublicParameters().Precision()
+	pp := tms.PublicParametersManager().PublicParameters()
+	graphHiding := pp.GraphHiding()
+	precision := pp.Precision()
+	ledgerFormat := pp.LedgerFormat()
 	auth := tms.Authorization()
 	auditorFlag := auth.AmIAnAuditor()
 	if auditorFlag {
@@ -218,12 +451,12 @@ func (t *Tokens) extractActions(tmsID token.TMSID, txID string, request *token.R
 	if err != nil {
 		return nil, nil, errors.WithMessagef(err, "failed to get request's outputs")
 	}
-	toSpend, toAppend := t.parse(auth, txID, md, is, os, auditorFlag, precision, graphHiding)
+	toSpend, toAppend := t.parse(auth, txID, md, is, os, auditorFlag, precision, graphHiding, ledgerFormat)
 	return toSpend, toAppend, nil
 }
 
 // parse returns the tokens to store and spend as the result of a transaction
-func (t *Tokens) parse(auth driver.Authorization, txID string, md MetaData, is *token.InputStream, os *token.OutputStream, auditorFlag bool, precision uint64, graphHiding bool) (toSpend []*token2.ID, toAppend []TokenToAppend) {
+func (t *Tokens) parse(auth driver.Authorization, txID string, md MetaData, is *token.InputStream, os *token.OutputStream, auditorFlag bool, precision uint64, graphHiding bool, ledgerFormat string) (toSpend []*token2.ID, toAppend []TokenToAppend) {
 	if graphHiding {
 		ids := md.SpentTokenID()
 		logger.Debugf("transaction [%s] with graph hiding, delete inputs [%v]", txID, ids)
@@ -291,6 +524,7 @@ func (t *Tokens) parse(auth driver.Authorization, txID string, md MetaData, is *
 			tok:                   tok,
 			tokenOnLedger:         output.LedgerOutput,
 			tokenOnLedgerMetadata: tokenOnLedgerMetadata,
+			ledgerFormat:          ledgerFormat,
 			ownerType:             ownerType,
 			ownerIdentity:         ownerIdentity,
 			ownerWalletID:         ownerWalletID,
@@ -7,12 +7,24 @@ SPDX-License-Identifier: Apache-2.0
 package tokens
 
 import (
+	"context"
+	"fmt"
+	"path"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/cache/secondcache"
+	sqlite2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/sql/sqlite"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/events"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	dbdriver "github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/sql/sqlite"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
 	"github.com/test-go/testify/assert"
 )
 
@@ -88,7 +100,7 @@ func TestParse(t *testing.T) {
 	is := token.NewInputStream(qsMock{}, []*token.Input{input1}, 64)
 	os := token.NewOutputStream([]*token.Output{output1}, 64)
 
-	spend, store := tokens.parse(&authMock{}, "tx1", md, is, os, false, 64, false)
+	spend, store := tokens.parse(&authMock{}, "tx1", md, is, os, false, 64, false, "fabtoken")
 
 	assert.Len(t, spend, 1)
 	assert.Equal(t, "in", spend[0].TxId)
@@ -102,12 +114,13 @@ func TestParse(t *testing.T) {
 	assert.Equal(t, false, store[0].flags.Auditor)
 	assert.Equal(t, false, store[0].flags.Issuer)
 	assert.Equal(t, uint64(64), store[0].precision)
+	assert.Equal(t, "fabtoken", store[0].ledgerFormat)
 	assert.Equal(t, output1.Type, store[0].tok.Type)
 
 	// no ledger output -> spend
 	output1.LedgerOutput = []byte{}
 	os = token.NewOutputStream([]*token.Output{output1}, 64)
-	spend, store = tokens.parse(&authMock{}, "tx1", md, is, os, false, 64, false)
+	spend, store = tokens.parse(&authMock{}, "tx1", md, is, os, false, 64, false, "fabtoken")
 	assert.Len(t, spend, 2)
 	assert.Len(t, store, 0)
 
@@ -151,7 +164,7 @@ func TestParse(t *testing.T) {
 	is = token.NewInputStream(qsMock{}, []*token.Input{input1, input2}, 64)
 	os = token.NewOutputStream([]*token.Output{output1, output2}, 64)
 
-	spend, store = tokens.parse(&authMock{}, "tx2", md, is, os, false, 64, false)
+	spend, store = tokens.parse(&authMock{}, "tx2", md, is, os, false, 64, false, "fabtoken")
 	assert.Len(t, spend, 2)
 	assert.Equal(t, "in1", spend[0].TxId)
 	assert.Equal(t, uint64(1), spend[0].Index)
@@ -169,3 +182,197 @@ func TestParse(t *testing.T) {
 	assert.Equal(t, output2.Index, store[1].index)
 	assert.Equal(t, output2.Type, store[1].tok.Type)
 }
+
+func TestWatchTokens(t *testing.T) {
+	tokens := &Tokens{}
+	a := &token2.ID{TxId: "a", Index: 0}
+	b := &token2.ID{TxId: "b", Index: 1}
+	outputs := []*token2.Token{{Owner: []byte("bob"), Type: "USD", Quantity: "0x1"}}
+
+	events, cancel := tokens.WatchTokens([]*token2.ID{a, b, a})
+	defer cancel()
+	other, cancelOther := tokens.WatchTokens([]*token2.ID{b})
+
+	tokens.watches.notify(TokenSpent, "tx1", []*token2.ID{a, {TxId: "c"}}, outputs)
+	assert.Equal(t, TokenEvent{ID: *a, Type: TokenSpent, TxID: "tx1", Outputs: outputs}, <-events)
+
+	// a canceled subscription is closed and receives nothing more
+	cancelOther()
+	_, ok := <-other
+	assert.False(t, ok)
+
+	tokens.watches.notify(TokenDeleted, "deleter", []*token2.ID{b}, nil)
+	assert.Equal(t, TokenEvent{ID: *b, Type: TokenDeleted, TxID: "deleter"}, <-events)
+	// all watched tokens consumed
+	_, ok = <-events
+	assert.False(t, ok)
+	assert.Empty(t, tokens.watches.byID)
+
+	// nothing to watch
+	empty, cancelEmpty := tokens.WatchTokens(nil)
+	_, ok = <-empty
+	assert.False(t, ok)
+	cancelEmpty()
+}
+
+// newSQLiteTokens returns a Tokens over a sqlite token db, where alice owns the token tx1:0
+func newSQLiteTokens(t *testing.T, tmsID token.TMSID) (*Tokens, *tokendb.DB) {
+	sqlDB, err := sqlite2.OpenDB(fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", path.Join(t.TempDir(), "tokens.sqlite")), 1, 1, time.Minute, false)
+	assert.NoError(t, err)
+	tdb, err := sqlite.NewTokenDB(sqlDB, common.NewDBOpts{TablePrefix: "test", CreateSchema: true})
+	assert.NoError(t, err)
+	db := &tokendb.DB{TokenDB: tdb}
+	tx, err := tdb.NewTokenDBTransaction(context.TODO())
+	assert.NoError(t, err)
+	assert.NoError(t, tx.StoreToken(context.TODO(), dbdriver.TokenRecord{
+		TxID:           "tx1",
+		OwnerRaw:       []byte("alice"),
+		OwnerType:      "x509",
+		OwnerIdentity:  []byte{},
+		OwnerWalletID:  "alice",
+		Ledger:         []byte("ledger"),
+		LedgerMetadata: []byte{},
+		Quantity:       "0x0a",
+		Amount:         10,
+		Type:           "ABC",
+		Owner:          true,
+	}, []string{"alice"}))
+	assert.NoError(t, tx.Commit())
+	storage, err := NewDBStorage(nil, db, tmsID)
+	assert.NoError(t, err)
+	return &Tokens{Storage: storage, RequestsCache: secondcache.NewTyped[*CacheEntry](10)}, db
+}
+
+func TestRollbackBlock(t *testing.T) {
+	ctx := context.TODO()
+	tmsID := token.TMSID{Network: "n", Channel: "c", Namespace: "ns"}
+	tokens, db := newSQLiteTokens(t, tmsID)
+	tokens.KeepJournal()
+
+	// tx2 gives the token of alice to bob
+	request := token.NewRequest(nil, "tx2")
+	tokens.RequestsCache.Add("tx2", &CacheEntry{
+		Request: request,
+		ToSpend: []*token2.ID{{TxId: "tx1", Index: 0}},
+		ToAppend: []TokenToAppend{{
+			txID:                  "tx2",
+			tok:                   &token2.Token{Owner: []byte("bob"), Type: "ABC", Quantity: "0x0a"},
+			tokenOnLedger:         []byte("ledger"),
+			tokenOnLedgerMetadata: []byte{},
+			ownerType:             "x509",
+			ownerIdentity:         []byte("bob"),
+			ownerWalletID:         "bob",
+			owners:                []string{"bob"},
+			precision:             64,
+			flags:                 Flags{Mine: true},
+		}},
+	})
+	balance := func(walletID string) uint64 {
+		b, err := db.Balance(walletID, "ABC")
+		assert.NoError(t, err)
+		return b
+	}
+	assert.NoError(t, tokens.Append(ctx, tmsID, "tx2", request))
+	assert.Equal(t, uint64(0), balance("alice"))
+	assert.Equal(t, uint64(10), balance("bob"))
+
+	// the intent is kept as a journal entry, not to be recovered
+	intent, err := db.Intent("tx2")
+	assert.NoError(t, err)
+	assert.True(t, intent.Applied)
+	intents, err := db.Intents()
+	assert.NoError(t, err)
+	assert.Empty(t, intents)
+
+	// the block is rolled back, tx3 did not change the token db
+	assert.NoError(t, tokens.RollbackBlock(ctx, tmsID, 5, []string{"tx2", "tx3"}))
+	assert.Equal(t, uint64(10), balance("alice"))
+	assert.Equal(t, uint64(0), balance("bob"))
+	intent, err = db.Intent("tx2")
+	assert.NoError(t, err)
+	assert.Nil(t, intent)
+
+	// committed in a new block, tx2 is appended again
+	assert.NoError(t, tokens.Append(ctx, tmsID, "tx2", request))
+	assert.Equal(t, uint64(0), balance("alice"))
+	assert.Equal(t, uint64(10), balance("bob"))
+
+	// without the journal, the intents are cleared and nothing is reverted
+	tokens, db = newSQLiteTokens(t, tmsID)
+	tokens.RequestsCache.Add("tx2", &CacheEntry{Request: request, ToSpend: []*token2.ID{{TxId: "tx1", Index: 0}}})
+	assert.NoError(t, tokens.Append(ctx, tmsID, "tx2", request))
+	intent, err = db.Intent("tx2")
+	assert.NoError(t, err)
+	assert.Nil(t, intent)
+	assert.NoError(t, tokens.RollbackBlock(ctx, tmsID, 5, []string{"tx2"}))
+	assert.Equal(t, uint64(0), balance("alice"))
+}
+
+// publisher records the published events
+type publisher struct {
+	events []events.Event
+}
+
+func (p *publisher) Publish(e events.Event) {
+	p.events = append(p.events, e)
+}
+
+func TestNotifyAfterCommit(t *testing.T) {
+	ctx := context.TODO()
+	tmsID := token.TMSID{Network: "n", Channel: "c", Namespace: "ns"}
+	_, db := newSQLiteTokens(t, tmsID)
+	p := &publisher{}
+	storage, err := NewDBStorage(p, db, tmsID)
+	assert.NoError(t, err)
+	tta := func(txID string) TokenToAppend {
+		return TokenToAppend{
+			txID:                  txID,
+			tok:                   &token2.Token{Owner: []byte("bob"), Type: "ABC", Quantity: "0x0a"},
+			tokenOnLedger:         []byte("ledger"),
+			tokenOnLedgerMetadata: []byte{},
+			ownerType:             "x509",
+			ownerIdentity:         []byte("bob"),
+			ownerWalletID:         "bob",
+			owners:                []string{"bob"},
+			precision:             64,
+			flags:                 Flags{Mine: true},
+		}
+	}
+
+	// the events of a job are published once its db transaction is committed
+	assert.NoError(t, storage.Apply(ctx, func(ctx context.Context, ts *transaction) error {
+		if err := ts.AppendToken(ctx, tta("tx2")); err != nil {
+			return err
+		}
+		assert.Empty(t, p.events)
+		return nil
+	}))
+	assert.Equal(t, []events.Event{NewTokenProcessorEvent(AddToken, &TokenMessage{TMSID: tmsID, WalletID: "bob", TokenType: "ABC", TxID: "tx2"})}, p.events)
+
+	// the events of a job that fails are dropped with its db transaction
+	p.events = nil
+	assert.Error(t, storage.Apply(ctx, func(ctx context.Context, ts *transaction) error {
+		if err := ts.DeleteToken(ctx, "tx2", 0, "tx3"); err != nil {
+			return err
+		}
+		return errors.New("failed")
+	}))
+	assert.Empty(t, p.events)
+
+	// the same holds for the db transactions started by the caller
+	ts, err := storage.NewTransaction(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, ts.AppendToken(ctx, tta("tx3")))
+	assert.NoError(t, ts.Rollback())
+	assert.Empty(t, p.events)
+	ts, err = storage.NewTransaction(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, ts.DeleteToken(ctx, "tx2", 0, "tx3"))
+	assert.Empty(t, p.events)
+	assert.NoError(t, ts.Commit())
+	// an event for each owner record of the token
+	assert.NotEmpty(t, p.events)
+	for _, e := range p.events {
+		assert.Equal(t, NewTokenProcessorEvent(DeleteToken, &TokenMessage{TMSID: tmsID, WalletID: "bob", TokenType: "ABC", TxID: "tx2"}), e)
+	}
+}
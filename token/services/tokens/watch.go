@@ -0,0 +1,128 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokens
+
+import (
+	"sync"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// TokenEventType is the type of event a watched token goes through
+type TokenEventType string
+
+const (
+	// TokenSpent is emitted when a transaction that spends the token is committed to the token db
+	TokenSpent TokenEventType = "spent"
+	// TokenDeleted is emitted when the token is deleted from the token db without a transaction, e.g. when found spent on the ledger
+	TokenDeleted TokenEventType = "deleted"
+)
+
+// TokenEvent reports the change of ownership of a watched token
+type TokenEvent struct {
+	// ID is the id of the watched token
+	ID   token2.ID
+	Type TokenEventType
+	// TxID is the id of the transaction that spent the token, or who deleted it
+	TxID string
+	// Outputs are the tokens created by the spending transaction that this node stores, with their new owners.
+	// It is empty for TokenDeleted.
+	Outputs []*token2.Token
+}
+
+// watch is the subscription of a WatchTokens call
+type watch struct {
+	ch      chan TokenEvent
+	pending map[token2.ID]struct{}
+}
+
+// watches tracks the subscriptions to the changes of ownership of tokens.
+// The zero value is ready to use.
+type watches struct {
+	mutex sync.Mutex
+	byID  map[token2.ID][]*watch
+}
+
+// add returns a subscription to the passed token ids, and the function to cancel it
+func (w *watches) add(ids []*token2.ID) (<-chan TokenEvent, func()) {
+	wt := &watch{
+		// a token is consumed once, every event fits in the buffer
+		ch:      make(chan TokenEvent, len(ids)),
+		pending: make(map[token2.ID]struct{}, len(ids)),
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.byID == nil {
+		w.byID = map[token2.ID][]*watch{}
+	}
+	for _, id := range ids {
+		if id == nil {
+			continue
+		}
+		if _, ok := wt.pending[*id]; ok {
+			continue
+		}
+		wt.pending[*id] = struct{}{}
+		w.byID[*id] = append(w.byID[*id], wt)
+	}
+	if len(wt.pending) == 0 {
+		close(wt.ch)
+	}
+	return wt.ch, func() { w.cancel(wt) }
+}
+
+func (w *watches) cancel(wt *watch) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(wt.pending) == 0 {
+		// already closed
+		return
+	}
+	for id := range wt.pending {
+		w.remove(id, wt)
+	}
+	wt.pending = nil
+	close(wt.ch)
+}
+
+// notify delivers an event of the passed type for each watched token among the passed ids.
+// A subscription whose tokens have all been consumed is closed.
+func (w *watches) notify(typ TokenEventType, txID string, ids []*token2.ID, outputs []*token2.Token) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(w.byID) == 0 {
+		return
+	}
+	for _, id := range ids {
+		if id == nil {
+			continue
+		}
+		for _, wt := range w.byID[*id] {
+			wt.ch <- TokenEvent{ID: *id, Type: typ, TxID: txID, Outputs: outputs}
+			delete(wt.pending, *id)
+			if len(wt.pending) == 0 {
+				close(wt.ch)
+			}
+		}
+		delete(w.byID, *id)
+	}
+}
+
+func (w *watches) remove(id token2.ID, wt *watch) {
+	list := w.byID[id]
+	for i, other := range list {
+		if other == wt {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(w.byID, id)
+		return
+	}
+	w.byID[id] = list
+}
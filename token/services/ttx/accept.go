@@ -33,6 +33,12 @@ func NewAcceptView(tx *Transaction, opts ...EndorsementsOpt) *AcceptView {
 }
 
 func (s *AcceptView) Call(context view.Context) (interface{}, error) {
+	if err := s.checkNotFrozen(); err != nil {
+		return nil, err
+	}
+	if err := s.checkAcceptancePolicy(context); err != nil {
+		return nil, err
+	}
 	if err := s.respondToSignatureRequests(context); err != nil {
 		return nil, err
 	}
@@ -87,6 +93,43 @@ func (s *AcceptView) Call(context view.Context) (interface{}, error) {
 	return s.tx, nil
 }
 
+// checkNotFrozen returns an error matching freeze.ErrFrozen, if the transaction moves token types frozen on this node
+func (s *AcceptView) checkNotFrozen() error {
+	if s.tx.freeze == nil {
+		return nil
+	}
+	inputs, outputs, err := s.tx.InputsAndOutputs()
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get inputs and outputs of transaction [%s]", s.tx.ID())
+	}
+	if err := s.tx.freeze.Check(append(inputs.TokenTypes(), outputs.TokenTypes()...)...); err != nil {
+		logger.Warnf("transaction [%s] refused: [%s]", s.tx.ID(), err)
+		return errors.WithMessagef(err, "cannot accept transaction [%s]", s.tx.ID())
+	}
+	return nil
+}
+
+// checkAcceptancePolicy evaluates the acceptance policy, if any, on the transaction.
+// A rejected transaction is recorded in the ttxdb as deleted, with the reason as status message.
+func (s *AcceptView) checkAcceptancePolicy(context view.Context) error {
+	if s.options.AcceptancePolicy == nil {
+		return nil
+	}
+	transfer, err := newIncomingTransfer(s.tx, context.Session().Info().Caller)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to evaluate acceptance policy")
+	}
+	reason := s.options.AcceptancePolicy.Accept(transfer)
+	if reason == nil {
+		return nil
+	}
+	logger.Warnf("transaction [%s] rejected by acceptance policy: [%s]", s.tx.ID(), reason)
+	if err := NewOwner(context, s.tx.TokenService()).appendRejected(context.Context(), s.tx, rejectionMessage(reason)); err != nil {
+		logger.Errorf("failed recording the rejection of transaction [%s]: [%s]", s.tx.ID(), err)
+	}
+	return errors.Wrapf(ErrTransferRejected, "transaction [%s]: %s", s.tx.ID(), reason)
+}
+
 func (s *AcceptView) respondToSignatureRequests(context view.Context) error {
 	requestsToBeSigned, err := requestsToBeSigned(s.tx.TokenRequest)
 	if err != nil {
@@ -141,6 +184,9 @@ func (s *AcceptView) respondToSignatureRequests(context view.Context) error {
 		if err != nil {
 			return errors.Wrapf(err, "cannot find signer for [%s]", signatureRequest.Signer.UniqueID())
 		}
+		if err := checkInputsBeforeSigning(context, s.tx, signatureRequest.Signer); err != nil {
+			return errors.WithMessagef(err, "refusing to sign [%s]", s.tx.ID())
+		}
 		sigma, err := signer.Sign(signatureRequest.MessageToSign())
 		if err != nil {
 			return errors.Wrapf(err, "failed signing request")
@@ -165,6 +211,9 @@ func (s *AcceptView) respondToSignatureRequests(context view.Context) error {
 		}
 		// TODO: check that the token requests match
 		s.tx = tx
+		if err := s.checkAcceptancePolicy(context); err != nil {
+			return err
+		}
 		if logger.IsEnabledFor(zapcore.DebugLevel) {
 			logger.Debugf("wait the transaction to be sent back [%s], received", s.tx.ID())
 		}
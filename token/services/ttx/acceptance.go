@@ -0,0 +1,111 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/pkg/errors"
+)
+
+// ErrTransferRejected is returned by the AcceptView when the acceptance policy rejects the incoming transfer
+var ErrTransferRejected = errors.New("incoming transfer rejected")
+
+// IncomingTransfer describes what a transaction transfers to the wallets of this node
+type IncomingTransfer struct {
+	// TxID is the id of the transaction
+	TxID string
+	// Caller is the identity of the node that sent the transaction
+	Caller view.Identity
+	// Senders are the owners of the inputs visible to this node
+	Senders []token.Identity
+	// Outputs are the outputs owned by the wallets of this node
+	Outputs *token.OutputStream
+}
+
+// AcceptancePolicy decides whether a recipient accepts an incoming transfer.
+// It is evaluated by the AcceptView before any signature is produced.
+type AcceptancePolicy interface {
+	// Accept returns nil if the transfer is accepted, otherwise an error describing why it is rejected
+	Accept(transfer *IncomingTransfer) error
+}
+
+// RulesPolicy is an AcceptancePolicy enforcing common rules. Empty rules are not enforced.
+type RulesPolicy struct {
+	// AllowedTokenTypes lists the token types that can be received
+	AllowedTokenTypes []string
+	// MaxIncomingAmount bounds, by token type, the quantity that can be received with a single transaction
+	MaxIncomingAmount map[string]*big.Int
+	// SanctionedSenders lists the identities, of nodes or of token owners, transfers are not accepted from
+	SanctionedSenders []token.Identity
+}
+
+func (p *RulesPolicy) Accept(transfer *IncomingTransfer) error {
+	for _, sanctioned := range p.SanctionedSenders {
+		if sanctioned.Equal(transfer.Caller) {
+			return errors.Errorf("caller [%s] is sanctioned", transfer.Caller)
+		}
+		for _, sender := range transfer.Senders {
+			if sanctioned.Equal(sender) {
+				return errors.Errorf("sender [%s] is sanctioned", sender)
+			}
+		}
+	}
+	for _, tokenType := range transfer.Outputs.TokenTypes() {
+		if len(p.AllowedTokenTypes) != 0 && !slices.Contains(p.AllowedTokenTypes, tokenType) {
+			return errors.Errorf("token type [%s] is not allowed", tokenType)
+		}
+		limit, ok := p.MaxIncomingAmount[tokenType]
+		if !ok {
+			continue
+		}
+		if amount := transfer.Outputs.ByType(tokenType).Sum(); amount.Cmp(limit) > 0 {
+			return errors.Errorf("incoming amount [%s] of [%s] exceeds the limit [%s]", amount, tokenType, limit)
+		}
+	}
+	return nil
+}
+
+// WithAcceptancePolicy makes the AcceptView evaluate the passed policy before accepting the transaction
+func WithAcceptancePolicy(policy AcceptancePolicy) EndorsementsOpt {
+	return func(o *EndorsementsOpts) error {
+		o.AcceptancePolicy = policy
+		return nil
+	}
+}
+
+// newIncomingTransfer extracts from the passed transaction what it transfers to the wallets of this node
+func newIncomingTransfer(tx *Transaction, caller view.Identity) (*IncomingTransfer, error) {
+	inputs, outputs, err := tx.InputsAndOutputs()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get inputs and outputs of [%s]", tx.ID())
+	}
+	wm := tx.TokenService().WalletManager()
+	var senders []token.Identity
+	for _, input := range inputs.Inputs() {
+		if wm.OwnerWallet(input.Owner) == nil {
+			senders = append(senders, input.Owner)
+		}
+	}
+	return &IncomingTransfer{
+		TxID:    tx.ID(),
+		Caller:  caller,
+		Senders: senders,
+		Outputs: outputs.Filter(func(o *token.Output) bool {
+			return wm.OwnerWallet(o.Owner) != nil
+		}),
+	}, nil
+}
+
+// rejectionMessage is the message recorded in the ttxdb for a transaction rejected by the acceptance policy
+func rejectionMessage(reason error) string {
+	return fmt.Sprintf("rejected by acceptance policy: %s", reason)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRulesPolicyAccept(t *testing.T) {
+	transfer := &IncomingTransfer{
+		TxID:    "tx1",
+		Caller:  token.Identity("caller"),
+		Senders: []token.Identity{token.Identity("alice")},
+		Outputs: token.NewOutputStream([]*token.Output{
+			{Type: "USD", Quantity: token2.NewQuantityFromUInt64(60)},
+			{Type: "USD", Quantity: token2.NewQuantityFromUInt64(40)},
+			{Type: "EUR", Quantity: token2.NewQuantityFromUInt64(10)},
+		}, 64),
+	}
+
+	for name, c := range map[string]struct {
+		policy *RulesPolicy
+		err    string
+	}{
+		"empty rules": {
+			policy: &RulesPolicy{},
+		},
+		"all rules satisfied": {
+			policy: &RulesPolicy{
+				AllowedTokenTypes: []string{"USD", "EUR"},
+				MaxIncomingAmount: map[string]*big.Int{"USD": big.NewInt(100)},
+				SanctionedSenders: []token.Identity{token.Identity("mallory")},
+			},
+		},
+		"sanctioned caller": {
+			policy: &RulesPolicy{SanctionedSenders: []token.Identity{token.Identity("caller")}},
+			err:    "caller [",
+		},
+		"sanctioned sender": {
+			policy: &RulesPolicy{SanctionedSenders: []token.Identity{token.Identity("alice")}},
+			err:    "sender [",
+		},
+		"token type not allowed": {
+			policy: &RulesPolicy{AllowedTokenTypes: []string{"USD"}},
+			err:    "token type [EUR] is not allowed",
+		},
+		"limit exceeded by the sum of the outputs": {
+			policy: &RulesPolicy{MaxIncomingAmount: map[string]*big.Int{"USD": big.NewInt(99)}},
+			err:    "incoming amount [100] of [USD] exceeds the limit [99]",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := c.policy.Accept(transfer)
+			if len(c.err) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, c.err)
+		})
+	}
+}
@@ -7,7 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package ttx
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"time"
 
@@ -43,12 +45,18 @@ func NewAuditor(sp token.ServiceProvider, w *token.AuditorWallet) (*TxAuditor, e
 	if err != nil {
 		return nil, err
 	}
+	return NewAuditorWithDBs(w, backend, auditDB, ttxDB), nil
+}
+
+// NewAuditorWithDBs returns a new auditor service for the passed wallet over the passed auditor, auditdb and ttxdb.
+// Unlike NewAuditor, it does not resolve its dependencies through a service provider.
+func NewAuditorWithDBs(w *token.AuditorWallet, backend *auditor.Auditor, auditDB *auditdb.DB, ttxDB *ttxdb.DB) *TxAuditor {
 	return &TxAuditor{
 		w:                       w,
 		auditor:                 backend,
 		auditDB:                 auditDB,
 		transactionInfoProvider: newTransactionInfoProvider(w.TMS(), ttxDB),
-	}, nil
+	}
 }
 
 func (a *TxAuditor) Validate(tx *Transaction) error {
@@ -56,7 +64,7 @@ func (a *TxAuditor) Validate(tx *Transaction) error {
 }
 
 func (a *TxAuditor) Audit(tx *Transaction) (*token.InputStream, *token.OutputStream, error) {
-	return a.auditor.Audit(tx)
+	return a.auditor.Audit(tx.Context, tx)
 }
 
 // Release unlocks the passed enrollment IDs.
@@ -84,10 +92,69 @@ func (a *TxAuditor) SetStatus(ctx context.Context, txID string, status driver.Tx
 	return a.auditDB.SetStatus(ctx, txID, status, message)
 }
 
+// OverrideStatus sets the status of the audit records with the passed transaction id, outside the finality listener,
+// and records who performed the override and why
+func (a *TxAuditor) OverrideStatus(ctx context.Context, txID string, status driver.TxStatus, message string, override auditdb.StatusOverride) error {
+	return a.auditDB.OverrideStatus(ctx, txID, status, message, override)
+}
+
+// StatusOverrides returns the status overrides of the audit records matching the passed params
+func (a *TxAuditor) StatusOverrides(params auditdb.QueryStatusOverridesParams) ([]*auditdb.StatusOverrideRecord, error) {
+	return a.auditDB.StatusOverrides(params)
+}
+
+// CorrectMovement appends the passed correction to an audited movement, the audit records are never edited
+func (a *TxAuditor) CorrectMovement(correction auditdb.MovementCorrection) (*auditdb.MovementCorrectionRecord, error) {
+	return a.auditDB.CorrectMovement(correction)
+}
+
+// MovementCorrections returns the corrections of the audited movements matching the passed params, the oldest first
+func (a *TxAuditor) MovementCorrections(params auditdb.QueryMovementCorrectionsParams) ([]*auditdb.MovementCorrectionRecord, error) {
+	return a.auditDB.MovementCorrections(params)
+}
+
+// TxIDsByReference returns the ids of the audited transactions bound to the passed hash of an external document, the oldest first
+func (a *TxAuditor) TxIDsByReference(reference []byte) ([]string, error) {
+	return a.auditDB.TxIDsByReference(reference)
+}
+
 func (a *TxAuditor) GetTokenRequest(txID string) ([]byte, error) {
 	return a.auditor.GetTokenRequest(txID)
 }
 
+// GetTokenRequests returns the token requests bound to the passed transaction ids, indexed by transaction id.
+// The transaction ids that are not found are missing in the returned map.
+func (a *TxAuditor) GetTokenRequests(txIDs []string) (map[string][]byte, error) {
+	return a.auditor.GetTokenRequests(txIDs)
+}
+
+// TokenRequests returns an iterator over the audited token requests matching the passed params, with their application metadata
+func (a *TxAuditor) TokenRequests(params auditdb.QueryTokenRequestsParams) (driver.TokenRequestIterator, error) {
+	return a.auditor.TokenRequests(params)
+}
+
+// SetIssuerResolver sets the resolver used to attribute issuance, possibly by anonymous issuers, to registered issuers
+func (a *TxAuditor) SetIssuerResolver(resolver auditor.IssuerResolver) {
+	a.auditor.SetIssuerResolver(resolver)
+}
+
+// IssuerAttributions returns the issuer attribution records matching the passed params.
+// Issuance that could not be attributed is reported with an empty issuer id.
+func (a *TxAuditor) IssuerAttributions(params auditor.QueryIssuerAttributionsParams) ([]*auditor.IssuerAttributionRecord, error) {
+	return a.auditor.IssuerAttributions(params)
+}
+
+// RegisterInspector adds the passed inspector to those run over the decrypted metadata of the transactions under audit.
+// The results of the inspectors are stored with the records of the transactions.
+func (a *TxAuditor) RegisterInspector(inspector auditor.MetadataInspector) error {
+	return a.auditor.RegisterInspector(inspector)
+}
+
+// Inspections returns the results of the inspectors stored for the passed transaction, by inspector name
+func (a *TxAuditor) Inspections(txID string) (map[string]*auditor.InspectionResult, error) {
+	return a.auditor.Inspections(txID)
+}
+
 type RegisterAuditorView struct {
 	TMSID     token.TMSID
 	AuditView view.View
@@ -121,6 +188,12 @@ func (r *RegisterAuditorView) Call(context view.Context) (interface{}, error) {
 	if err := net.ProcessNamespace(tms.Namespace()); err != nil {
 		return nil, errors.WithMessagef(err, "failed to register namespace for processing [%s]", tms.Network())
 	}
+	// deliver the audit responses that were not sent before the last shutdown
+	auditDB, err := auditdb.GetByTMSId(context, tms.ID())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get auditdb for [%s]", tms.ID())
+	}
+	go resendAuditResponses(view2.GetManager(context), auditDB)
 	return nil, nil
 }
 
@@ -138,6 +211,9 @@ func (a *AuditingViewInitiator) Call(context view.Context) (interface{}, error)
 	defer span.End()
 	var err error
 	var session view.Session
+	// an auditor restarting before sending back its response delivers it through the ReceiveAuditResponseView
+	responses, done := auditResponses.wait(a.tx.ID())
+	defer done()
 	span.AddEvent("start_session")
 	if a.local {
 		session, err = a.startLocal(context)
@@ -151,7 +227,7 @@ func (a *AuditingViewInitiator) Call(context view.Context) (interface{}, error)
 	// Receive signature
 	logger.Debugf("Receiving signature for [%s]", a.tx.ID())
 	span.AddEvent("start_receiving")
-	msg, err := ReadMessage(session, time.Minute)
+	msg, session, err := readAuditResponse(session, responses, time.Minute)
 	if err != nil {
 		span.RecordError(err)
 		return nil, errors.WithMessage(err, "failed to read audit event")
@@ -188,6 +264,7 @@ func (a *AuditingViewInitiator) Call(context view.Context) (interface{}, error)
 		}
 	}
 	if !validAuditing {
+		session.Close()
 		return nil, errors.Errorf("failed verifying auditor signature [%s][%s]", hash.Hashable(signed).String(), a.tx.TokenRequest.Anchor)
 	}
 	span.AddEvent("append_auditor_signature")
@@ -274,12 +351,50 @@ func NewAuditApproveView(w *token.AuditorWallet, tx *Transaction) *AuditApproveV
 func (a *AuditApproveView) Call(context view.Context) (interface{}, error) {
 	span := context.StartSpan("audit_approve_view")
 	defer span.End()
-	// Append audit records
-	if err := auditor.New(context, a.w).Append(a.tx); err != nil {
+	backend := auditor.New(context, a.w)
+	raw, err := a.tx.MarshallToAudit()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling tx [%s] to audit", a.tx.ID())
+	}
+	messageHash := sha256.Sum256(raw)
+
+	// An audit response already stored for this transaction means that the requester is retrying.
+	// Send back the same response, provided that the requester asks to audit the same message.
+	stored, err := backend.AuditResponse(a.tx.ID())
+	if err != nil {
+		backend.Release(a.tx)
+		return nil, errors.WithMessagef(err, "failed looking up audit response for transaction %s", a.tx.ID())
+	}
+	if stored != nil {
+		backend.Release(a.tx)
+		if !bytes.Equal(stored.MessageHash, messageHash[:]) {
+			return nil, errors.Errorf("transaction %s has already been audited with a different content", a.tx.ID())
+		}
+		logger.Infof("transaction [%s] already audited, sending back the stored response", a.tx.ID())
+		span.AddEvent("resend_stored_response")
+		if err := a.sendBack(context, backend, stored.Response); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	// Sign, and append the audit records together with the response,
+	// so that the response can be delivered again if the auditor fails before sending it
+	sigma, err := a.sign(context, raw)
+	if err != nil {
+		backend.Release(a.tx)
+		return nil, err
+	}
+	response := &auditor.AuditResponseRecord{
+		Requester:   context.Session().Info().Caller,
+		MessageHash: messageHash[:],
+		Response:    sigma,
+	}
+	if err := backend.AppendWithResponse(a.tx, response); err != nil {
 		return nil, errors.Wrapf(err, "failed appending audit records for transaction %s", a.tx.ID())
 	}
 
-	if err := a.signAndSendBack(context); err != nil {
+	if err := a.sendBack(context, backend, sigma); err != nil {
 		return nil, err
 	}
 
@@ -301,57 +416,61 @@ func (a *AuditApproveView) Call(context view.Context) (interface{}, error) {
 	return nil, nil
 }
 
-func (a *AuditApproveView) signAndSendBack(context view.Context) error {
+func (a *AuditApproveView) sign(context view.Context, raw []byte) ([]byte, error) {
 	span := trace.SpanFromContext(context.Context())
-	logger.Debugf("Signing and sending back transaction... [%s]", a.tx.ID())
-	// Sign
+	logger.Debugf("Signing transaction... [%s]", a.tx.ID())
 	aid, err := a.w.GetAuditorIdentity()
 	if err != nil {
-		return errors.WithMessagef(err, "failed getting auditor identity for node [%s]", context.Me())
+		return nil, errors.WithMessagef(err, "failed getting auditor identity for node [%s]", context.Me())
 	}
 	signer, err := a.w.GetSigner(aid)
 	if err != nil {
-		return errors.WithMessagef(err, "failed getting signing identity for auditor identity [%s]", aid)
+		return nil, errors.WithMessagef(err, "failed getting signing identity for auditor identity [%s]", aid)
 	}
 
 	logger.Debug("signer at auditor", signer, aid)
-
-	raw, err := a.tx.MarshallToAudit()
-	if err != nil {
-		return errors.Wrapf(err, "failed marshalling tx [%s] to audit", a.tx.ID())
-	}
 	if logger.IsEnabledFor(zapcore.DebugLevel) {
 		logger.Debugf("Audit Approve [%s][%s][%s]", aid.UniqueID(), hash.Hashable(raw).String(), a.tx.TokenRequest.Anchor)
 	}
 	span.AddEvent("sign_tx")
 	sigma, err := signer.Sign(raw)
 	if err != nil {
-		return errors.Wrapf(err, "failed sign audit message for tx [%s]", a.tx.ID())
+		return nil, errors.Wrapf(err, "failed sign audit message for tx [%s]", a.tx.ID())
 	}
+	return sigma, nil
+}
+
+func (a *AuditApproveView) sendBack(context view.Context, backend *auditor.Auditor, sigma []byte) error {
+	span := trace.SpanFromContext(context.Context())
 	logger.Debug("auditor sending sigma back", hash.Hashable(sigma))
 	session := context.Session()
 	span.AddEvent("send_back_tx")
 	if err := session.Send(sigma); err != nil {
 		return errors.WithMessagef(err, "failed sending back auditor signature")
 	}
+	if err := backend.MarkAuditResponseSent(a.tx.ID()); err != nil {
+		logger.Warnf("failed marking audit response [%s] as sent, it will be sent again on restart: [%s]", a.tx.ID(), err)
+	}
 
 	logger.Debugf("Signing and sending back transaction...done [%s]", a.tx.ID())
 
 	span.AddEvent("wait_envelope")
-	if err := a.waitEnvelope(context); err != nil {
+	if err := waitAuditedEnvelope(context, a.tx.ID()); err != nil {
 		return errors.WithMessagef(err, "failed obtaining auditor signature")
 	}
 	return nil
 }
 
-func (a *AuditApproveView) waitEnvelope(context view.Context) error {
+// waitAuditedEnvelope waits for the envelope of the audited transaction on the session of the passed context,
+// and acknowledges it
+func waitAuditedEnvelope(context view.Context, txID string) error {
 	span := trace.SpanFromContext(context.Context())
-	logger.Debugf("Waiting for envelope... [%s]", a.tx.ID())
+	logger.Debugf("Waiting for envelope... [%s]", txID)
 	tx, err := ReceiveTransaction(context, WithNoTransactionVerification())
 	if err != nil {
 		return errors.Wrapf(err, "failed to receive transaction with network envelope")
 	}
-	logger.Debugf("Waiting for envelope...transaction received[%s]", a.tx.ID())
+	logger.Debugf("Waiting for envelope...transaction received[%s]", txID)
 
 	// Processes
 	if logger.IsEnabledFor(zapcore.DebugLevel) {
@@ -387,7 +506,7 @@ func (a *AuditApproveView) waitEnvelope(context view.Context) error {
 		return errors.WithMessage(err, "failed sending ack")
 	}
 
-	logger.Debugf("Waiting for envelope...done [%s]", a.tx.ID())
+	logger.Debugf("Waiting for envelope...done [%s]", txID)
 
 	return nil
 }
@@ -0,0 +1,239 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditdb"
+	"github.com/pkg/errors"
+)
+
+// releaseTimeout bounds how long the ReceiveAuditResponseView keeps the auditor's session open
+// for the AuditingViewInitiator that took it over
+const releaseTimeout = 5 * time.Minute
+
+// AuditResponseMessage carries an audit response that the auditor sends again after a restart
+type AuditResponseMessage struct {
+	TxID     string
+	Response []byte
+}
+
+// AuditResponseAck is the reply of the requester to an AuditResponseMessage.
+// Accepted is true if an AuditingViewInitiator was waiting for the response.
+// In this case, the requester continues the auditing protocol on the same session.
+type AuditResponseAck struct {
+	Accepted bool
+}
+
+// ResponderRegistry registers responder views
+type ResponderRegistry interface {
+	RegisterResponder(responder view.View, initiatedBy interface{}) error
+}
+
+// InstallViews registers the views the requesters of an audit need to receive the responses the auditor sends again after a restart
+func InstallViews(viewRegistry ResponderRegistry) error {
+	return viewRegistry.RegisterResponder(&ReceiveAuditResponseView{}, &ResendAuditResponseView{})
+}
+
+// auditResponseDelivery is an audit response received outside the auditing session
+type auditResponseDelivery struct {
+	response []byte
+	session  view.Session
+	release  chan struct{}
+}
+
+// auditResponseBox dispatches the audit responses sent again by the auditors to the AuditingViewInitiators waiting for them
+type auditResponseBox struct {
+	lock    sync.Mutex
+	waiting map[string]chan *auditResponseDelivery
+}
+
+var auditResponses = &auditResponseBox{waiting: map[string]chan *auditResponseDelivery{}}
+
+// wait registers the caller as waiting for the audit response of the passed transaction.
+// The returned function must be invoked when the caller stops waiting.
+func (b *auditResponseBox) wait(txID string) (<-chan *auditResponseDelivery, func()) {
+	ch := make(chan *auditResponseDelivery, 1)
+	b.lock.Lock()
+	b.waiting[txID] = ch
+	b.lock.Unlock()
+	return ch, func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		if b.waiting[txID] == ch {
+			delete(b.waiting, txID)
+		}
+	}
+}
+
+// deliver hands the passed response to the caller waiting for it, if any.
+// Only the first delivery for a transaction is accepted.
+func (b *auditResponseBox) deliver(txID string, d *auditResponseDelivery) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ch, ok := b.waiting[txID]
+	if !ok {
+		return false
+	}
+	delete(b.waiting, txID)
+	ch <- d
+	return true
+}
+
+// readAuditResponse returns the audit response received on the passed session, or through the passed channel.
+// In the latter case, the returned session is the one the response has been received on.
+func readAuditResponse(session view.Session, responses <-chan *auditResponseDelivery, timeout time.Duration) ([]byte, view.Session, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case msg := <-session.Receive():
+		if msg == nil {
+			return nil, nil, errors.New("received nil tx")
+		}
+		if msg.Status == view.ERROR {
+			return nil, nil, errors.New(string(msg.Payload))
+		}
+		return msg.Payload, session, nil
+	case d := <-responses:
+		logger.Infof("audit response received after the auditor restarted")
+		return d.response, &releasingSession{Session: d.session, release: d.release}, nil
+	case <-timer.C:
+		return nil, nil, errors.New("timeout reached")
+	}
+}
+
+// releasingSession lets the ReceiveAuditResponseView, whose session it wraps, terminate when the session is closed
+type releasingSession struct {
+	view.Session
+	release chan struct{}
+	once    sync.Once
+}
+
+func (s *releasingSession) Close() {
+	s.once.Do(func() { close(s.release) })
+}
+
+// resendAuditResponses sends the audit responses not yet delivered to their requesters
+func resendAuditResponses(manager *view2.Manager, auditDB *auditdb.DB) {
+	pending, err := auditDB.PendingAuditResponses()
+	if err != nil {
+		logger.Errorf("failed loading pending audit responses: [%s]", err)
+		return
+	}
+	for _, record := range pending {
+		if record.Requester.IsNone() {
+			// the auditor audited its own transaction, there is no one to deliver the response to
+			if err := auditDB.MarkAuditResponseSent(record.TxID); err != nil {
+				logger.Warnf("failed marking audit response [%s] as sent: [%s]", record.TxID, err)
+			}
+			continue
+		}
+		logger.Infof("sending again audit response [%s] to [%s]", record.TxID, record.Requester)
+		if _, err := manager.InitiateView(NewResendAuditResponseView(auditDB, record), context.Background()); err != nil {
+			logger.Warnf("failed sending again audit response [%s]: [%s]", record.TxID, err)
+		}
+	}
+}
+
+// ResendAuditResponseView sends again an audit response to its requester
+type ResendAuditResponseView struct {
+	auditDB *auditdb.DB
+	record  *auditdb.AuditResponseRecord
+}
+
+func NewResendAuditResponseView(auditDB *auditdb.DB, record *auditdb.AuditResponseRecord) *ResendAuditResponseView {
+	return &ResendAuditResponseView{auditDB: auditDB, record: record}
+}
+
+func (r *ResendAuditResponseView) Call(context view.Context) (interface{}, error) {
+	session, err := context.GetSession(r, r.record.Requester)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting session with [%s]", r.record.Requester)
+	}
+	raw, err := json.Marshal(&AuditResponseMessage{TxID: r.record.TxID, Response: r.record.Response})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling audit response [%s]", r.record.TxID)
+	}
+	if err := session.SendWithContext(context.Context(), raw); err != nil {
+		return nil, errors.Wrapf(err, "failed sending audit response [%s]", r.record.TxID)
+	}
+	rawAck, err := ReadMessage(session, time.Minute)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed reading ack for audit response [%s]", r.record.TxID)
+	}
+	ack := &AuditResponseAck{}
+	if err := json.Unmarshal(rawAck, ack); err != nil {
+		return nil, errors.Wrapf(err, "failed unmarshalling ack for audit response [%s]", r.record.TxID)
+	}
+	if err := r.auditDB.MarkAuditResponseSent(r.record.TxID); err != nil {
+		return nil, errors.WithMessagef(err, "failed marking audit response [%s] as sent", r.record.TxID)
+	}
+	if !ack.Accepted {
+		// the requester is not waiting anymore, it gets the stored response again if it retries
+		return nil, nil
+	}
+	// the requester continues the auditing protocol on this session
+	if _, err := context.RunView(&waitAuditedEnvelopeView{txID: r.record.TxID}, view.AsResponder(session)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+type waitAuditedEnvelopeView struct {
+	txID string
+}
+
+func (w *waitAuditedEnvelopeView) Call(context view.Context) (interface{}, error) {
+	return nil, waitAuditedEnvelope(context, w.txID)
+}
+
+// ReceiveAuditResponseView receives an audit response sent again by the auditor.
+// The response is delivered to the AuditingViewInitiator waiting for it, if any, otherwise it is dropped.
+// Receiving the same response more than once is harmless.
+type ReceiveAuditResponseView struct{}
+
+func (r *ReceiveAuditResponseView) Call(context view.Context) (interface{}, error) {
+	session := context.Session()
+	raw, err := ReadMessage(session, time.Minute)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed reading audit response")
+	}
+	msg := &AuditResponseMessage{}
+	if err := json.Unmarshal(raw, msg); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling audit response")
+	}
+
+	d := &auditResponseDelivery{response: msg.Response, session: session, release: make(chan struct{})}
+	accepted := auditResponses.deliver(msg.TxID, d)
+	logger.Debugf("received audit response [%s], accepted [%v]", msg.TxID, accepted)
+	rawAck, err := json.Marshal(&AuditResponseAck{Accepted: accepted})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling ack for audit response [%s]", msg.TxID)
+	}
+	if err := session.SendWithContext(context.Context(), rawAck); err != nil {
+		return nil, errors.Wrapf(err, "failed sending ack for audit response [%s]", msg.TxID)
+	}
+	if !accepted {
+		return nil, nil
+	}
+	// keep the session open until the AuditingViewInitiator is done with it
+	timer := time.NewTimer(releaseTimeout)
+	defer timer.Stop()
+	select {
+	case <-d.release:
+	case <-timer.C:
+		logger.Warnf("audit session for [%s] not released in time", msg.TxID)
+	}
+	return nil, nil
+}
@@ -25,6 +25,21 @@ const txIdLabel tracing.LabelName = "tx_id"
 
 type QueryTransactionsParams = ttxdb.QueryTransactionsParams
 
+// CompensationHandler is invoked when a transaction fails to commit
+type CompensationHandler = ttxdb.CompensationHandler
+
+// StatusOverride describes who overrides the status of a transaction, and why
+type StatusOverride = ttxdb.StatusOverride
+
+// StatusOverrideRecord is the audit record of a status override
+type StatusOverrideRecord = ttxdb.StatusOverrideRecord
+
+// QueryStatusOverridesParams defines the parameters for querying status overrides
+type QueryStatusOverridesParams = ttxdb.QueryStatusOverridesParams
+
+// CompensationEvent carries the information about a transaction that failed to commit
+type CompensationEvent = ttxdb.CompensationEvent
+
 type NetworkProvider interface {
 	GetNetwork(network string, channel string) (*network.Network, error)
 }
@@ -39,6 +54,20 @@ type DB struct {
 	finalityTracer  trace.Tracer
 }
 
+// NewDB returns a new DB for the passed TMS over the passed ttxdb and tokens service.
+// Unlike Get, it does not resolve its dependencies through a service provider,
+// therefore, it is suited to applications wiring the token services by themselves.
+func NewDB(np NetworkProvider, tmsProvider TMSProvider, tmsID token.TMSID, ttxDB *ttxdb.DB, tokenDB *tokens.Tokens, finalityTracer trace.Tracer) *DB {
+	return &DB{
+		networkProvider: np,
+		tmsID:           tmsID,
+		ttxDB:           ttxDB,
+		tokenDB:         tokenDB,
+		tmsProvider:     tmsProvider,
+		finalityTracer:  finalityTracer,
+	}
+}
+
 // Append adds the passed transaction to the database
 func (a *DB) Append(tx *Transaction) error {
 	// append request to the db
@@ -65,6 +94,26 @@ func (a *DB) SetStatus(ctx context.Context, txID string, status driver.TxStatus,
 	return a.ttxDB.SetStatus(ctx, txID, status, message)
 }
 
+// OverrideStatus sets the status of the passed transaction outside the finality listener, and records who performed the override and why
+func (a *DB) OverrideStatus(ctx context.Context, txID string, status driver.TxStatus, message string, override StatusOverride) error {
+	return a.ttxDB.OverrideStatus(ctx, txID, status, message, override)
+}
+
+// StatusOverrides returns the status overrides matching the passed params
+func (a *DB) StatusOverrides(params QueryStatusOverridesParams) ([]*StatusOverrideRecord, error) {
+	return a.ttxDB.StatusOverrides(params)
+}
+
+// TxIDsByReference returns the ids of the transactions bound to the passed hash of an external document, the oldest first
+func (a *DB) TxIDsByReference(reference []byte) ([]string, error) {
+	return a.ttxDB.TxIDsByReference(reference)
+}
+
+// FundsWitnesses returns the funds witnesses matching the passed params, see RecordFundsWitness
+func (a *DB) FundsWitnesses(params QueryFundsWitnessesParams) ([]*FundsWitnessRecord, error) {
+	return a.ttxDB.FundsWitnesses(params)
+}
+
 // GetStatus return the status of the given transaction id.
 // It returns an error if no transaction with that id is found
 func (a *DB) GetStatus(txID string) (TxStatus, string, error) {
@@ -80,6 +129,12 @@ func (a *DB) GetTokenRequest(txID string) ([]byte, error) {
 	return a.ttxDB.GetTokenRequest(txID)
 }
 
+// GetTokenRequests returns the token requests bound to the passed transaction ids, indexed by transaction id.
+// The transaction ids that are not found are missing in the returned map.
+func (a *DB) GetTokenRequests(txIDs []string) (map[string][]byte, error) {
+	return a.ttxDB.GetTokenRequests(txIDs)
+}
+
 func (a *DB) AppendTransactionEndorseAck(txID string, id view.Identity, sigma []byte) error {
 	return a.ttxDB.AddTransactionEndorsementAck(txID, id, sigma)
 }
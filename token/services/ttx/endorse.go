@@ -53,6 +53,10 @@ type CollectEndorsementsView struct {
 	tx       *Transaction
 	Opts     *EndorsementsOpts
 	sessions map[string]view.Session
+	flows    *flowStore
+
+	// checkInputs verifies the inputs of the transaction before a local signer signs it
+	checkInputs func(sp token.ServiceProvider, tx *Transaction, signer view.Identity) error
 }
 
 // NewCollectEndorsementsView returns an instance of the CollectEndorsementsView struct.
@@ -68,7 +72,7 @@ func NewCollectEndorsementsView(tx *Transaction, opts ...EndorsementsOpt) *Colle
 	if err != nil {
 		panic(err)
 	}
-	return &CollectEndorsementsView{tx: tx, Opts: options, sessions: map[string]view.Session{}}
+	return &CollectEndorsementsView{tx: tx, Opts: options, sessions: map[string]view.Session{}, checkInputs: checkInputsBeforeSigning}
 }
 
 // Call executes the view.
@@ -82,6 +86,13 @@ func NewCollectEndorsementsView(tx *Transaction, opts ...EndorsementsOpt) *Colle
 func (c *CollectEndorsementsView) Call(context view.Context) (interface{}, error) {
 	metrics := GetMetrics(context)
 
+	// the signatures collected remotely are persisted, so that the flow can be resumed if a session drops
+	flows, err := getFlowStore(context, initiatorRole)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting flow store")
+	}
+	c.flows = flows
+
 	externalWallets := make(map[string]ExternalWalletSigner)
 	// 1. First collect signatures on the token request
 	issueSigmas, err := c.requestSignaturesOnIssues(context, externalWallets)
@@ -133,6 +144,11 @@ func (c *CollectEndorsementsView) Call(context view.Context) (interface{}, error
 		return nil, errors.WithMessage(err, "failed cleaning up audit")
 	}
 
+	// The flow is completed, the persisted state is not needed anymore
+	if err := c.flows.Delete(c.tx.ID()); err != nil {
+		logger.Warnf("failed to delete flow state of [%s]: [%s]", c.tx.ID(), err)
+	}
+
 	if logger.IsEnabledFor(zapcore.DebugLevel) {
 		logger.Debugf("CollectEndorsementsView done.")
 	}
@@ -193,7 +209,7 @@ func (c *CollectEndorsementsView) requestSignatures(signers []view.Identity, ver
 			if logger.IsEnabledFor(zapcore.DebugLevel) {
 				logger.Debugf("found signer for party [%s], request local signature", party)
 			}
-			sigma, err := c.signLocal(party, signer, signatureRequest)
+			sigma, err := c.signLocal(context, party, signer, signatureRequest)
 			if err != nil {
 				return nil, errors.WithMessagef(err, "failed signing local for party [%s]", party)
 			}
@@ -208,7 +224,7 @@ func (c *CollectEndorsementsView) requestSignatures(signers []view.Identity, ver
 			}
 			ews := c.Opts.ExternalWalletSigner(w.ID())
 			if ews == nil {
-				return nil, errors.Errorf("no external wallet signer found for [%s][%s]", w.ID(), party)
+				return nil, errors.Wrapf(token.ErrWatchOnlyWallet, "no external wallet signer found for [%s][%s]", w.ID(), party)
 			}
 			externalWallets[w.ID()] = ews
 			sigma, err := c.signExternal(party, ews, signatureRequest)
@@ -233,7 +249,12 @@ func (c *CollectEndorsementsView) requestSignatures(signers []view.Identity, ver
 	return sigmas, nil
 }
 
-func (c *CollectEndorsementsView) signLocal(party view.Identity, signer token.Signer, signatureRequest *SignatureRequest) ([]byte, error) {
+// signLocal signs the passed request with the passed local signer,
+// after checking, as a responder would do, that the inputs owned by the party are unspent tokens of its wallet.
+func (c *CollectEndorsementsView) signLocal(context view.Context, party view.Identity, signer token.Signer, signatureRequest *SignatureRequest) ([]byte, error) {
+	if err := c.checkInputs(context, c.tx, party); err != nil {
+		return nil, errors.WithMessagef(err, "refusing to sign [%s]", c.tx.ID())
+	}
 	if logger.IsEnabledFor(zapcore.DebugLevel) {
 		logger.Debugf("signing [%s][%s]", hash.Hashable(signatureRequest.Request).String(), c.tx.ID())
 		logger.Debugf("signing tx-id [%s,nonce=%s]", c.tx.ID(), base64.StdEncoding.EncodeToString(c.tx.TxID.Nonce))
@@ -273,6 +294,25 @@ func (c *CollectEndorsementsView) signExternal(party view.Identity, signer Exter
 }
 
 func (c *CollectEndorsementsView) signRemote(context view.Context, party view.Identity, signatureRequest *SignatureRequest, verifierGetter verifierGetterFunc) ([]byte, error) {
+	verifier, err := verifierGetter(party)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting verifier for [%s]", party)
+	}
+	// reuse the signature collected before the flow got interrupted, if still valid
+	if c.flows != nil {
+		state, err := c.flows.Load(c.tx.ID())
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed loading flow state")
+		}
+		if sigma, ok := state.Signature(party, signatureRequest.MessageToSign()); ok {
+			if err := verifier.Verify(signatureRequest.MessageToSign(), sigma); err == nil {
+				logger.Debugf("reuse signature from [%s] collected before for txid [%s]", party, c.tx.ID())
+				return sigma, nil
+			}
+			logger.Warnf("signature from [%s] collected before for txid [%s] is not valid anymore, request it again", party, c.tx.ID())
+		}
+	}
+
 	session, err := context.GetSession(context.Initiator(), party)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed getting session")
@@ -291,10 +331,6 @@ func (c *CollectEndorsementsView) signRemote(context view.Context, party view.Id
 		return nil, errors.Wrap(err, "failed reading message")
 	}
 
-	verifier, err := verifierGetter(party)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed getting verifier for [%s]", party)
-	}
 	if logger.IsEnabledFor(zapcore.DebugLevel) {
 		logger.Debugf("verify signature [%s][%s][%s] for txid [%s]",
 			hash.Hashable(signatureRequest.MessageToSign()).String(),
@@ -317,6 +353,12 @@ func (c *CollectEndorsementsView) signRemote(context view.Context, party view.Id
 		)
 	}
 
+	if c.flows != nil {
+		if err := c.flows.AddSignature(c.tx.ID(), party, signatureRequest.MessageToSign(), sigma); err != nil {
+			return nil, errors.WithMessagef(err, "failed persisting signature from [%s]", party)
+		}
+	}
+
 	return sigma, nil
 }
 
@@ -678,6 +720,19 @@ func (f *ReceiveTransactionView) unmarshalAsSignatureRequest(context view.Contex
 
 type EndorseView struct {
 	tx *Transaction
+	// preValidator, if set, is asked to pre-validate the token request before anything is signed
+	preValidator view.Identity
+}
+
+// EndorseOpt configures an EndorseView
+type EndorseOpt func(*EndorseView)
+
+// WithPreValidator makes the EndorseView ask the passed counterparty to pre-validate the token request,
+// see PreValidateView, before signing anything. The EndorseView fails if the verdict is invalid.
+func WithPreValidator(validator view.Identity) EndorseOpt {
+	return func(v *EndorseView) {
+		v.preValidator = validator
+	}
 }
 
 // NewEndorseView returns an instance of the endorseView.
@@ -687,8 +742,12 @@ type EndorseView struct {
 // 3. After, it waits to receive the Transaction. The Transaction is validated and stored locally
 // to be processed at time of committing.
 // 4. It sends back an ack.
-func NewEndorseView(tx *Transaction) *EndorseView {
-	return &EndorseView{tx: tx}
+func NewEndorseView(tx *Transaction, opts ...EndorseOpt) *EndorseView {
+	v := &EndorseView{tx: tx}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
 }
 
 // Call executes the view.
@@ -699,6 +758,12 @@ func NewEndorseView(tx *Transaction) *EndorseView {
 // to be processed at time of committing.
 // 4. It sends back an ack.
 func (s *EndorseView) Call(context view.Context) (interface{}, error) {
+	if !s.preValidator.IsNone() {
+		if err := s.preValidate(context); err != nil {
+			return nil, err
+		}
+	}
+
 	// Process signature requests
 	logger.Debugf("chec expected numer of requests to sign for txid [%s]", s.tx.ID())
 	requestsToBeSigned, err := requestsToBeSigned(s.tx.Request())
@@ -708,6 +773,16 @@ func (s *EndorseView) Call(context view.Context) (interface{}, error) {
 
 	logger.Debugf("expect [%d] requests to sign for txid [%s]", len(requestsToBeSigned), s.tx.ID())
 
+	// the signatures sent back are persisted, so that they can be sent again if the initiator resumes the flow
+	flows, err := getFlowStore(context, responderRole)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting flow store")
+	}
+	state, err := flows.Load(s.tx.ID())
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed loading flow state")
+	}
+
 	session := context.Session()
 	for range requestsToBeSigned {
 		if logger.IsEnabledFor(zapcore.DebugLevel) {
@@ -733,9 +808,21 @@ func (s *EndorseView) Call(context view.Context) (interface{}, error) {
 		if err != nil {
 			return nil, errors.Wrapf(err, "cannot find signer for [%s]", signatureRequest.Signer.UniqueID())
 		}
-		sigma, err := signer.Sign(signatureRequest.MessageToSign())
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed signing request")
+		if err := checkInputsBeforeSigning(context, s.tx, signatureRequest.Signer); err != nil {
+			return nil, errors.WithMessagef(err, "refusing to sign [%s]", s.tx.ID())
+		}
+		// the signature is sent again only if the message to sign is the same
+		sigma, ok := state.Signature(signatureRequest.Signer, signatureRequest.MessageToSign())
+		if ok {
+			logger.Debugf("resend signature [%s] produced before for txid [%s]", signatureRequest.Signer, s.tx.ID())
+		} else {
+			sigma, err = signer.Sign(signatureRequest.MessageToSign())
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed signing request")
+			}
+			if err := flows.AddSignature(s.tx.ID(), signatureRequest.Signer, signatureRequest.MessageToSign(), sigma); err != nil {
+				return nil, errors.WithMessagef(err, "failed persisting signature")
+			}
 		}
 		if logger.IsEnabledFor(zapcore.DebugLevel) {
 			logger.Debugf("Send back signature [%s][%s]", signatureRequest.Signer, hash.Hashable(sigma))
@@ -775,6 +862,9 @@ func (s *EndorseView) Call(context view.Context) (interface{}, error) {
 	if err := session.SendWithContext(context.Context(), sigma); err != nil {
 		return nil, errors.WithMessage(err, "failed sending ack")
 	}
+	if err := flows.Delete(s.tx.ID()); err != nil {
+		logger.Warnf("failed to delete flow state of [%s]: [%s]", s.tx.ID(), err)
+	}
 
 	// cache the token request into the tokens db
 	t, err := tokens.GetService(context, s.tx.TMSID())
@@ -788,6 +878,18 @@ func (s *EndorseView) Call(context view.Context) (interface{}, error) {
 	return s.tx, nil
 }
 
+func (s *EndorseView) preValidate(context view.Context) error {
+	res, err := context.RunView(NewPreValidateView(s.preValidator, s.tx.TokenRequest))
+	if err != nil {
+		return errors.WithMessagef(err, "failed pre-validating [%s]", s.tx.ID())
+	}
+	verdict := res.(*PreValidationVerdict)
+	if !verdict.Valid {
+		return errors.Errorf("refusing to endorse [%s], pre-validation failed: %s", s.tx.ID(), verdict.Reason)
+	}
+	return nil
+}
+
 func (s *EndorseView) receiveTransaction(context view.Context) (*Transaction, []byte, error) {
 	if logger.IsEnabledFor(zapcore.DebugLevel) {
 		logger.Debugf("Receive transaction with envelope...")
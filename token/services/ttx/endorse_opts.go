@@ -16,6 +16,8 @@ type EndorsementsOpts struct {
 	SkipDistributeEnv bool
 	// External Signers
 	ExternalWalletSigners map[string]ExternalWalletSigner
+	// AcceptancePolicy, if set, is evaluated by the AcceptView before accepting the transaction
+	AcceptancePolicy AcceptancePolicy
 }
 
 func (o *EndorsementsOpts) ExternalWalletSigner(id string) ExternalWalletSigner {
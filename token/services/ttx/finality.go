@@ -63,7 +63,13 @@ func (f *finalityView) Call(ctx view.Context) (interface{}, error) {
 	if timeout == 0 {
 		timeout = 5 * time.Minute
 	}
-	return f.call(ctx, txID, tmsID, timeout)
+	res, err := f.call(ctx, txID, tmsID, timeout)
+	if err != nil {
+		options.Progress.report(txID, Failed, err)
+		return nil, err
+	}
+	options.Progress.report(txID, Committed, nil)
+	return res, nil
 }
 
 func (f *finalityView) call(ctx view.Context, txID string, tmsID token.TMSID, timeout time.Duration) (interface{}, error) {
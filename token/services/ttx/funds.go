@@ -0,0 +1,249 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"encoding/json"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/features"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// AddPendingIncoming sets, if the passed error is a token.InsufficientFundsError, the quantity the passed wallet
+// is going to receive from pending transactions, as recorded in the transaction db of the owner.
+// It returns the passed error, therefore it can be used in place: return ttx.AddPendingIncoming(context, wallet, err).
+// If the transaction db cannot be queried, the error is returned unchanged.
+func AddPendingIncoming(sp token.ServiceProvider, wallet *token.OwnerWallet, err error) error {
+	var fundsErr *token.InsufficientFundsError
+	if wallet == nil || !errors.As(err, &fundsErr) {
+		return err
+	}
+	pending, queryErr := pendingIncoming(sp, wallet, fundsErr.TokenType)
+	if queryErr != nil {
+		logger.Warnf("failed to compute pending incoming funds of wallet [%s]: [%s]", wallet.ID(), queryErr)
+		return err
+	}
+	fundsErr.PendingIncoming = pending
+	return err
+}
+
+// pendingIncoming returns the sum of the tokens of the passed type that the passed wallet receives from other
+// enrollment IDs in pending transactions
+func pendingIncoming(sp token.ServiceProvider, wallet *token.OwnerWallet, tokenType string) (token2.Quantity, error) {
+	tms := wallet.TMS()
+	db, err := ttxdb.GetByTMSId(sp, tms.ID())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get ttxdb for [%s]", tms.ID())
+	}
+	eID := wallet.EnrollmentID()
+	it, err := db.Transactions(QueryTransactionsParams{
+		RecipientWallet: eID,
+		Statuses:        []TxStatus{Pending},
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query pending transactions")
+	}
+	defer it.Close()
+
+	precision := tms.PublicParametersManager().PublicParameters().Precision()
+	sum := token2.NewZeroQuantity(precision)
+	for {
+		r, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to iterate over pending transactions")
+		}
+		if r == nil {
+			return sum, nil
+		}
+		// the change of the transactions the wallet sends goes back to the wallet itself
+		if r.RecipientEID != eID || r.SenderEID == eID || r.TokenType != tokenType || r.Amount.Sign() <= 0 {
+			continue
+		}
+		q, err := token2.ToQuantity(r.Amount.String(), precision)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid amount in transaction [%s]", r.TxID)
+		}
+		sum = sum.Add(q)
+	}
+}
+
+// FundsWitnessRecord is a signed snapshot of the funds of a wallet whose token selection failed
+type FundsWitnessRecord = ttxdb.FundsWitnessRecord
+
+// QueryFundsWitnessesParams defines the parameters for querying funds witnesses
+type QueryFundsWitnessesParams = ttxdb.QueryFundsWitnessesParams
+
+// FundsSnapshot is the state of the funds of a wallet when its token selection concluded that the funds were insufficient.
+// The quantities are in decimal format.
+type FundsSnapshot struct {
+	WalletID     string
+	EnrollmentID string
+	TokenType    string
+	// Cause is the error returned by the selection
+	Cause     string
+	Requested string
+	// Available is the sum of the tokens that were not locked by other transactions
+	Available string
+	// Locked is the sum of the tokens locked by other transactions
+	Locked string
+	// LockedBy maps the ids of the transactions locking tokens to the quantity they locked, if known
+	LockedBy map[string]string
+	// PendingIncoming is the sum of the tokens the wallet was going to receive from pending transactions
+	PendingIncoming string
+	// Balance is the balance of the wallet for the token type, as queried from the vault
+	Balance uint64
+	// Spendable are the tokens that could be spent
+	Spendable []*token2.ID
+	// PendingTransactions are the pending transactions of the wallet for the token type, as recorded in the ttxdb
+	PendingTransactions []PendingTransaction
+	Timestamp           time.Time
+}
+
+// PendingTransaction is a pending transaction record listed in a FundsSnapshot
+type PendingTransaction struct {
+	TxID         string
+	SenderEID    string
+	RecipientEID string
+	Amount       string
+}
+
+// RecordFundsWitness stores in the ttxdb, if the passed error is a token.InsufficientFundsError and the feature flag
+// features.FundsWitness is on for the TMS of the wallet, a FundsSnapshot signed by the default identity of the node.
+// The witness lets a later dispute about a failed payment be investigated with the state seen at the time of the failure.
+// It returns the passed error, therefore it can be used in place: return ttx.RecordFundsWitness(context, wallet, txID, err).
+// If the witness cannot be stored, the error is returned unchanged.
+func RecordFundsWitness(sp token.ServiceProvider, wallet *token.OwnerWallet, txID string, err error) error {
+	var fundsErr *token.InsufficientFundsError
+	if wallet == nil || !errors.As(err, &fundsErr) {
+		return err
+	}
+	tmsID := wallet.TMS().ID()
+	flags, flagsErr := features.GetService(sp)
+	if flagsErr != nil || !flags.Enabled(tmsID, features.FundsWitness) {
+		return err
+	}
+	if witnessErr := recordFundsWitness(sp, wallet, txID, fundsErr); witnessErr != nil {
+		logger.Warnf("failed to record funds witness of wallet [%s]: [%s]", wallet.ID(), witnessErr)
+	}
+	return err
+}
+
+func recordFundsWitness(sp token.ServiceProvider, wallet *token.OwnerWallet, txID string, fundsErr *token.InsufficientFundsError) error {
+	tmsID := wallet.TMS().ID()
+	db, err := ttxdb.GetByTMSId(sp, tmsID)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get ttxdb for [%s]", tmsID)
+	}
+	if fundsErr.PendingIncoming == nil {
+		if pending, err := pendingIncoming(sp, wallet, fundsErr.TokenType); err == nil {
+			fundsErr.PendingIncoming = pending
+		}
+	}
+	snapshot := &FundsSnapshot{
+		WalletID:        wallet.ID(),
+		EnrollmentID:    wallet.EnrollmentID(),
+		TokenType:       fundsErr.TokenType,
+		Cause:           fundsErr.Error(),
+		Requested:       decimal(fundsErr.Requested),
+		Available:       decimal(fundsErr.Available),
+		Locked:          decimal(fundsErr.Locked),
+		LockedBy:        map[string]string{},
+		PendingIncoming: decimal(fundsErr.PendingIncoming),
+		Spendable:       fundsErr.Spendable,
+		Timestamp:       time.Now().UTC(),
+	}
+	for lockerID, q := range fundsErr.LockedBy {
+		snapshot.LockedBy[lockerID] = decimal(q)
+	}
+	snapshot.Balance, err = wallet.Balance(token.WithType(fundsErr.TokenType))
+	if err != nil {
+		return errors.WithMessagef(err, "failed to query balance")
+	}
+	snapshot.PendingTransactions, err = pendingTransactions(db, wallet.EnrollmentID(), fundsErr.TokenType)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal funds snapshot")
+	}
+	me := view2.GetIdentityProvider(sp).DefaultIdentity()
+	signer, err := view2.GetSigService(sp).GetSigner(me)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get signer for default identity")
+	}
+	sigma, err := signer.Sign(raw)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to sign funds snapshot")
+	}
+	return db.AddFundsWitness(&FundsWitnessRecord{
+		TxID:      txID,
+		WalletID:  wallet.ID(),
+		TokenType: fundsErr.TokenType,
+		Snapshot:  raw,
+		Signer:    me,
+		Signature: sigma,
+	})
+}
+
+// VerifyFundsWitness verifies the signature of the passed funds witness, and returns its snapshot
+func VerifyFundsWitness(sp token.ServiceProvider, record *FundsWitnessRecord) (*FundsSnapshot, error) {
+	verifier, err := view2.GetSigService(sp).GetVerifier(record.Signer)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get verifier for the signer of witness [%s]", record.ID)
+	}
+	if err := verifier.Verify(record.Snapshot, record.Signature); err != nil {
+		return nil, errors.WithMessagef(err, "invalid signature on witness [%s]", record.ID)
+	}
+	snapshot := &FundsSnapshot{}
+	if err := json.Unmarshal(record.Snapshot, snapshot); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal snapshot of witness [%s]", record.ID)
+	}
+	return snapshot, nil
+}
+
+// pendingTransactions returns the pending transaction records of the passed enrollment id for the passed token type
+func pendingTransactions(db *ttxdb.DB, eID string, tokenType string) ([]PendingTransaction, error) {
+	it, err := db.Transactions(QueryTransactionsParams{
+		EnrollmentIDs: []string{eID},
+		TokenTypes:    []string{tokenType},
+		Statuses:      []TxStatus{Pending},
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query pending transactions")
+	}
+	defer it.Close()
+	var res []PendingTransaction
+	for {
+		r, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to iterate over pending transactions")
+		}
+		if r == nil {
+			return res, nil
+		}
+		res = append(res, PendingTransaction{
+			TxID:         r.TxID,
+			SenderEID:    r.SenderEID,
+			RecipientEID: r.RecipientEID,
+			Amount:       r.Amount.String(),
+		})
+	}
+}
+
+func decimal(q token2.Quantity) string {
+	if q == nil {
+		return ""
+	}
+	return q.Decimal()
+}
@@ -0,0 +1,47 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/pkg/errors"
+)
+
+// ErrIdempotencyKeyInUse is returned when a pending or confirmed transaction is already bound to the requested idempotency key
+var ErrIdempotencyKeyInUse = driver.ErrIdempotencyKeyInUse
+
+// IdempotencyKeyError tells which transaction is already bound to an idempotency key.
+// It is returned when the transaction is created and, if another transaction took the key meanwhile, when it is stored.
+type IdempotencyKeyError = driver.IdempotencyKeyError
+
+// ExistingTxID returns the id of the transaction already bound to the idempotency key, if the passed error
+// reports that the key is in use. The id is empty if the transaction is not known.
+func ExistingTxID(err error) (string, bool) {
+	var keyErr *IdempotencyKeyError
+	if errors.As(err, &keyErr) {
+		return keyErr.TxID, true
+	}
+	return "", false
+}
+
+// checkIdempotencyKey returns an IdempotencyKeyError if a pending or confirmed transaction is already bound to the passed key
+func checkIdempotencyKey(sp token.ServiceProvider, tmsID token.TMSID, key string) error {
+	db, err := ttxdb.GetByTMSId(sp, tmsID)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get ttxdb for [%s]", tmsID)
+	}
+	txID, err := db.TxIDByIdempotencyKey(key)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to look up idempotency key [%s]", key)
+	}
+	if len(txID) != 0 {
+		return &IdempotencyKeyError{Key: key, TxID: txID}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidInput is returned when an input of a transaction to be signed does not match the token db of the signer
+var ErrInvalidInput = errors.New("invalid input")
+
+// checkInputsBeforeSigning verifies, before the passed signer signs the passed transaction, that
+// each input owned by the signer is an unspent token of the wallet of the signer in the token db,
+// with the type and the amount stated by the metadata of the request.
+// This protects the signer from an assembler that wraps into the request tokens other than those the signer agreed to spend.
+// Inputs owned by other identities are not checked.
+func checkInputsBeforeSigning(sp token.ServiceProvider, tx *Transaction, signer view.Identity) error {
+	inputs, err := tx.TokenRequest.Inputs()
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting inputs of [%s]", tx.ID())
+	}
+	var owned []*token.Input
+	for _, input := range inputs.Inputs() {
+		if input.Owner.Equal(signer) {
+			owned = append(owned, input)
+		}
+	}
+	if len(owned) == 0 {
+		return nil
+	}
+
+	wallet := tx.TokenService().WalletManager().OwnerWallet(signer)
+	if wallet == nil {
+		return errors.Wrapf(ErrInvalidInput, "no owner wallet for signer [%s] of [%s]", signer, tx.ID())
+	}
+	ids := make([]*token2.ID, len(owned))
+	for i, input := range owned {
+		if input.Id == nil {
+			return errors.Wrapf(ErrInvalidInput, "missing id of input [%d] owned by [%s] in [%s]", i, signer, tx.ID())
+		}
+		ids[i] = input.Id
+	}
+	tokenDB, err := tokendb.GetByTMSId(sp, tx.TMSID())
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get token db for [%s]", tx.TMSID())
+	}
+	details, err := tokenDB.QueryTokenDetails(driver.QueryTokenDetailsParams{WalletID: wallet.ID(), IDs: ids})
+	if err != nil {
+		return errors.WithMessagef(err, "failed to query inputs of [%s]", tx.ID())
+	}
+	return matchInputs(tx.ID(), wallet.ID(), owned, details)
+}
+
+// matchInputs checks that each of the passed inputs is one of the passed unspent tokens of the passed wallet,
+// with the same type and amount.
+func matchInputs(txID, walletID string, owned []*token.Input, details []driver.TokenDetails) error {
+	unspent := make(map[token2.ID]driver.TokenDetails, len(details))
+	for _, d := range details {
+		unspent[token2.ID{TxId: d.TxID, Index: d.Index}] = d
+	}
+	for _, input := range owned {
+		d, ok := unspent[*input.Id]
+		if !ok {
+			return errors.Wrapf(ErrInvalidInput, "input [%s] of [%s] is not an unspent token of wallet [%s]", input.Id, txID, walletID)
+		}
+		if d.Type != input.Type {
+			return errors.Wrapf(ErrInvalidInput, "input [%s] of [%s] has type [%s], expected [%s]", input.Id, txID, input.Type, d.Type)
+		}
+		if input.Quantity == nil {
+			return errors.Wrapf(ErrInvalidInput, "input [%s] of [%s] has no quantity", input.Id, txID)
+		}
+		if q := input.Quantity.ToBigInt(); !q.IsUint64() || q.Uint64() != d.Amount {
+			return errors.Wrapf(ErrInvalidInput, "input [%s] of [%s] has amount [%s], expected [%d]", input.Id, txID, q, d.Amount)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchInputs(t *testing.T) {
+	details := []driver.TokenDetails{
+		{TxID: "tx0", Index: 0, Type: "USD", Amount: 10},
+		{TxID: "tx0", Index: 1, Type: "EUR", Amount: 5},
+	}
+	input := func(index uint64, typ string, q uint64) *token.Input {
+		return &token.Input{Id: &token2.ID{TxId: "tx0", Index: index}, Type: typ, Quantity: token2.NewQuantityFromUInt64(q)}
+	}
+
+	for name, c := range map[string]struct {
+		inputs []*token.Input
+		err    string
+	}{
+		"inputs match": {
+			inputs: []*token.Input{input(0, "USD", 10), input(1, "EUR", 5)},
+		},
+		"not an unspent token": {
+			inputs: []*token.Input{input(2, "USD", 10)},
+			err:    "input [[tx0:2]] of [tx1] is not an unspent token of wallet [alice]",
+		},
+		"wrong type": {
+			inputs: []*token.Input{input(0, "EUR", 10)},
+			err:    "has type [EUR], expected [USD]",
+		},
+		"wrong amount": {
+			inputs: []*token.Input{input(1, "EUR", 50)},
+			err:    "has amount [50], expected [5]",
+		},
+		"missing quantity": {
+			inputs: []*token.Input{{Id: &token2.ID{TxId: "tx0"}, Type: "USD"}},
+			err:    "has no quantity",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := matchInputs("tx1", "alice", c.inputs, details)
+			if len(c.err) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidInput)
+			assert.ErrorContains(t, err, c.err)
+		})
+	}
+}
+
+// countingSigner signs any message with a fixed signature and counts the signatures
+type countingSigner struct {
+	signatures int
+}
+
+func (s *countingSigner) Sign([]byte) ([]byte, error) {
+	s.signatures++
+	return []byte("sigma"), nil
+}
+
+func TestSignLocalChecksInputs(t *testing.T) {
+	tx := &Transaction{Payload: &Payload{ID: "tx1"}}
+	request := &SignatureRequest{TX: []byte("tx"), Request: []byte("request"), TxID: []byte("tx1"), Signer: view.Identity("alice")}
+
+	// the inputs of the party match, the request is signed
+	var checked []view.Identity
+	signer := &countingSigner{}
+	c := &CollectEndorsementsView{tx: tx, checkInputs: func(_ token.ServiceProvider, checkedTx *Transaction, party view.Identity) error {
+		assert.Equal(t, tx, checkedTx)
+		checked = append(checked, party)
+		return nil
+	}}
+	sigma, err := c.signLocal(nil, view.Identity("alice"), signer, request)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("sigma"), sigma)
+	assert.Equal(t, []view.Identity{view.Identity("alice")}, checked)
+	assert.Equal(t, 1, signer.signatures)
+
+	// the inputs of the party do not match, the request is not signed
+	c.checkInputs = func(token.ServiceProvider, *Transaction, view.Identity) error {
+		return errors.Wrapf(ErrInvalidInput, "input [[tx0:0]] of [tx1] is not an unspent token of wallet [alice]")
+	}
+	_, err = c.signLocal(nil, view.Identity("alice"), signer, request)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	assert.ErrorContains(t, err, "refusing to sign [tx1]")
+	assert.Equal(t, 1, signer.signatures)
+}
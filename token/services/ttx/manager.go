@@ -13,6 +13,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/tracing"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokens"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
@@ -90,17 +91,10 @@ func (m *Manager) newDB(tmsID token.TMSID) (*DB, error) {
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to get ttxdb for [%s]", tmsID)
 	}
-	wrapper := &DB{
-		networkProvider: m.networkProvider,
-		tmsID:           tmsID,
-		tmsProvider:     m.tmsProvider,
-		ttxDB:           ttxDB,
-		tokenDB:         tokenDB,
-		finalityTracer: m.tracerProvider.Tracer("db", tracing.WithMetricsOpts(tracing.MetricsOpts{
-			Namespace:  "tokensdk",
-			LabelNames: []tracing.LabelName{txIdLabel},
-		})),
-	}
+	wrapper := NewDB(m.networkProvider, m.tmsProvider, tmsID, ttxDB, tokenDB, m.tracerProvider.Tracer("db", tracing.WithMetricsOpts(tracing.MetricsOpts{
+		Namespace:  "tokensdk",
+		LabelNames: []tracing.LabelName{txIdLabel},
+	})))
 	_, err = m.networkProvider.GetNetwork(tmsID.Network, tmsID.Channel)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to get network instance for [%s:%s]", tmsID.Network, tmsID.Channel)
@@ -141,6 +135,19 @@ func (m *Manager) RestoreTMS(tmsID token.TMSID) error {
 		counter++
 	}
 	logger.Debugf("checked [%d] token requests", counter)
+
+	// the transactions of the blocks rolled back by the ledger are reverted in the token db, once per TMS, and become pending again
+	rollbackListener := common.NewRollbackListener(logger, tmsID, db.ttxDB, db.tokenDB, func(txID string) error {
+		return net.AddFinalityListener(tmsID.Namespace, txID, common.NewFinalityListener(logger, db.tmsProvider, db.tmsID, db.ttxDB, db.tokenDB, db.finalityTracer))
+	})
+	if err := net.AddRollbackListener(tmsID.Namespace, rollbackListener); err != nil {
+		if errors.Is(err, network.ErrRollbackNotSupported) {
+			return nil
+		}
+		return errors.WithMessagef(err, "failed to subscribe rollback listener to network [%s:%s]", tmsID.Network, tmsID.Channel)
+	}
+	// the deltas of the applied requests are journaled to be reversed
+	db.tokenDB.KeepJournal()
 	return nil
 }
 
@@ -11,6 +11,7 @@ import (
 	"sort"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/compression"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	"go.uber.org/zap/zapcore"
 
@@ -117,6 +118,12 @@ func marshal(t *Transaction, eIDs ...string) ([]byte, error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to marshal token request")
 		}
+		if t.compression != nil && t.TMS != nil {
+			tokenRequestRaw, err = t.compression.CompressRequest(t.TMS.ID(), tokenRequestRaw)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to compress token request")
+			}
+		}
 	}
 
 	var envRaw []byte
@@ -170,7 +177,12 @@ func unmarshal(getNetwork GetNetworkFunc, p *Payload, raw []byte) error {
 		p.Transient = meta
 	}
 	if len(ser.TokenRequest) != 0 {
-		if err := p.TokenRequest.FromBytes(ser.TokenRequest); err != nil {
+		// the token request might have been compressed by the sender
+		tokenRequestRaw, err := compression.Decompress(ser.TokenRequest)
+		if err != nil {
+			return errors.Wrap(err, "failed decompressing token request")
+		}
+		if err := p.TokenRequest.FromBytes(tokenRequestRaw); err != nil {
 			return errors.Wrap(err, "failed unmarshalling token request")
 		}
 	}
@@ -37,12 +37,20 @@ var (
 		LabelNames:   []string{"network", "channel", "namespace"},
 		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}",
 	}
+	rateLimitedTransfers = metrics.CounterOpts{
+		Namespace:    "ttx",
+		Name:         "rate_limited_transfers",
+		Help:         "The number of transfers refused by the rate limiter.",
+		LabelNames:   []string{"network", "channel", "namespace", "wallet", "limit"},
+		StatsdFormat: "%{#fqname}.%{network}.%{channel}.%{namespace}.%{wallet}.%{limit}",
+	}
 )
 
 type Metrics struct {
 	EndorsedTransactions      metrics.Counter
 	AuditApprovedTransactions metrics.Counter
 	AcceptedTransactions      metrics.Counter
+	RateLimitedTransfers      metrics.Counter
 }
 
 func NewMetrics(p metrics.Provider) *Metrics {
@@ -50,6 +58,7 @@ func NewMetrics(p metrics.Provider) *Metrics {
 		EndorsedTransactions:      p.NewCounter(endorsedTransactions),
 		AuditApprovedTransactions: p.NewCounter(auditApprovedTransactions),
 		AcceptedTransactions:      p.NewCounter(acceptedTransactions),
+		RateLimitedTransfers:      p.NewCounter(rateLimitedTransfers),
 	}
 }
 
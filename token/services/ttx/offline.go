@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+)
+
+// Export returns the serialization of a fully assembled transaction.
+// The serialization carries the token request, with all the collected signatures,
+// and the endorsed envelope, ready to be submitted later, possibly by another node, via Import.
+func (t *Transaction) Export() ([]byte, error) {
+	if t.Payload.Envelope == nil {
+		return nil, errors.Errorf("transaction [%s] has not been endorsed yet", t.ID())
+	}
+	if len(t.TokenRequest.Actions.Signatures) == 0 {
+		return nil, errors.Errorf("transaction [%s] has not been signed yet", t.ID())
+	}
+	return t.Bytes()
+}
+
+// Import reconstructs a transaction exported with Export.
+// The transaction is checked for well-formedness and, if not already known, appended to the local
+// transaction database so that the outcome of its submission can be tracked.
+// The returned transaction can be submitted using the ordering views.
+func Import(context view.Context, raw []byte) (*Transaction, error) {
+	tx, err := NewTransactionFromBytes(context, raw)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed unmarshalling exported transaction")
+	}
+	if tx.Payload.Envelope == nil || len(tx.Payload.Envelope.TxID()) == 0 {
+		return nil, errors.Errorf("transaction [%s] does not carry an endorsed envelope", tx.ID())
+	}
+	if err := tx.IsValid(); err != nil {
+		return nil, errors.WithMessagef(err, "invalid transaction [%s]", tx.ID())
+	}
+
+	owner := NewOwner(context, tx.TokenService())
+	status, _, err := owner.GetStatus(tx.ID())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting status of transaction [%s]", tx.ID())
+	}
+	if status != Unknown {
+		logger.Debugf("transaction [%s] already known with status [%s]", tx.ID(), TxStatusMessage[status])
+		return tx, nil
+	}
+	if err := owner.Append(tx); err != nil {
+		return nil, errors.WithMessagef(err, "failed appending transaction [%s]", tx.ID())
+	}
+	return tx, nil
+}
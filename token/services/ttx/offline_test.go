@@ -0,0 +1,172 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"context"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// txIDEnvelope is an envelope whose serialization is its transaction id
+type txIDEnvelope struct {
+	txID string
+}
+
+func (e *txIDEnvelope) Bytes() ([]byte, error) { return []byte(e.txID), nil }
+
+func (e *txIDEnvelope) FromBytes(raw []byte) error {
+	e.txID = string(raw)
+	return nil
+}
+
+func (e *txIDEnvelope) TxID() string { return e.txID }
+
+func (e *txIDEnvelope) String() string { return e.txID }
+
+type envelopeNetwork struct {
+	driver.Network
+}
+
+func (n *envelopeNetwork) NewEnvelope() driver.Envelope { return &txIDEnvelope{} }
+
+type envelopeDriver struct{}
+
+func (envelopeDriver) New(string, string) (driver.Network, error) { return &envelopeNetwork{}, nil }
+
+// importContext provides a network creating txIDEnvelopes, and no TMS
+type importContext struct {
+	viewContext
+	networks *network.Provider
+}
+
+func newImportContext() *importContext {
+	networks := network.NewProvider()
+	networks.RegisterDriver(envelopeDriver{})
+	return &importContext{networks: networks}
+}
+
+func (c *importContext) Context() context.Context { return context.Background() }
+
+func (c *importContext) OnError(func()) {}
+
+func (c *importContext) GetService(v interface{}) (interface{}, error) {
+	switch v.(type) {
+	case *network.Provider:
+		return c.networks, nil
+	case *token.ManagementServiceProvider:
+		return token.NewManagementServiceProvider(logging.MustGetLogger("test"), nil, unknownTMSNormalizer{}, nil, nil, nil), nil
+	default:
+		return nil, errors.Errorf("service [%T] not found", v)
+	}
+}
+
+// newEndorsedTransaction returns a transaction for tx1, signed and endorsed, in the network of the passed context
+func newEndorsedTransaction(t *testing.T, ctx *importContext) *Transaction {
+	nw, err := ctx.networks.GetNetwork("n1", "c1")
+	assert.NoError(t, err)
+	envelope := nw.NewEnvelope()
+	assert.NoError(t, envelope.FromBytes([]byte("tx1")))
+
+	request := token.NewRequest(nil, "tx1")
+	request.Actions.Transfers = [][]byte{[]byte("transfer")}
+	request.Actions.Signatures = [][]byte{[]byte("alice's signature")}
+	return &Transaction{Payload: &Payload{
+		ID:           "tx1",
+		Network:      "n1",
+		Channel:      "c1",
+		Namespace:    "ns",
+		Transient:    map[string][]byte{},
+		TokenRequest: request,
+		Envelope:     envelope,
+	}}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := newImportContext()
+	tx := newEndorsedTransaction(t, ctx)
+
+	raw, err := tx.Export()
+	assert.NoError(t, err)
+	imported, err := NewTransactionFromBytes(ctx, raw)
+	assert.NoError(t, err)
+	assert.Equal(t, "tx1", imported.ID())
+	assert.Equal(t, "ns", imported.Namespace())
+	assert.Equal(t, tx.TokenRequest.Actions.Transfers, imported.TokenRequest.Actions.Transfers)
+	assert.Equal(t, tx.TokenRequest.Actions.Signatures, imported.TokenRequest.Actions.Signatures)
+	assert.Equal(t, "tx1", imported.Payload.Envelope.TxID())
+
+	// the serialization is stable
+	again, err := imported.Export()
+	assert.NoError(t, err)
+	assert.Equal(t, raw, again)
+}
+
+func TestExportIncomplete(t *testing.T) {
+	ctx := newImportContext()
+
+	tx := newEndorsedTransaction(t, ctx)
+	tx.Payload.Envelope = nil
+	_, err := tx.Export()
+	assert.ErrorContains(t, err, "transaction [tx1] has not been endorsed yet")
+
+	tx = newEndorsedTransaction(t, ctx)
+	tx.TokenRequest.Actions.Signatures = nil
+	_, err = tx.Export()
+	assert.ErrorContains(t, err, "transaction [tx1] has not been signed yet")
+}
+
+func TestImportMalformed(t *testing.T) {
+	ctx := newImportContext()
+	request, err := token.NewRequest(nil, "tx1").Bytes()
+	assert.NoError(t, err)
+	otherRequest, err := token.NewRequest(nil, "tx2").Bytes()
+	assert.NoError(t, err)
+	serialize := func(ser TransactionSer) []byte {
+		raw, err := asn1.Marshal(ser)
+		assert.NoError(t, err)
+		return raw
+	}
+
+	for name, c := range map[string]struct {
+		raw []byte
+		err string
+	}{
+		"not a transaction": {
+			raw: []byte("not a transaction"),
+			err: "failed unmarshalling exported transaction",
+		},
+		"truncated transaction": {
+			raw: serialize(TransactionSer{ID: "tx1", Network: "n1", TokenRequest: request, Envelope: []byte("tx1")})[:20],
+			err: "failed unmarshalling exported transaction",
+		},
+		"malformed token request": {
+			raw: serialize(TransactionSer{ID: "tx1", Network: "n1", TokenRequest: []byte("not a request")}),
+			err: "failed unmarshalling token request",
+		},
+		"mismatching token request": {
+			raw: serialize(TransactionSer{ID: "tx1", Network: "n1", TokenRequest: otherRequest, Envelope: []byte("tx1")}),
+			err: "transaction ids do not match [tx1][tx2]",
+		},
+		"not endorsed": {
+			raw: serialize(TransactionSer{ID: "tx1", Network: "n1", TokenRequest: request}),
+			err: "transaction [tx1] does not carry an endorsed envelope",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Import(ctx, c.raw)
+			assert.ErrorContains(t, err, c.err)
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/pkg/errors"
 )
 
 type TxOptions struct {
@@ -23,6 +24,9 @@ type TxOptions struct {
 	Transaction               *Transaction
 	NetworkTxID               network.TxID
 	NoCachingRequest          bool
+	IdempotencyKey            string
+	Reference                 []byte
+	Progress                  ProgressFunc
 }
 
 func compile(opts ...TxOption) (*TxOptions, error) {
@@ -125,3 +129,33 @@ func WithNetworkTxID(id network.TxID) TxOption {
 		return nil
 	}
 }
+
+// WithIdempotencyKey binds the new transaction to the passed key, chosen by the application.
+// If a pending or confirmed transaction is already bound to the same key, NewTransaction fails with an error
+// from which ExistingTxID extracts the id of that transaction, so that a retry does not spend the tokens twice.
+func WithIdempotencyKey(key string) TxOption {
+	return func(o *TxOptions) error {
+		o.IdempotencyKey = key
+		return nil
+	}
+}
+
+// WithReference binds the new transaction to the passed hash of an external document, such as a trade confirmation.
+// The parties and the auditor sign the reference together with the token request, see token.Request.BindReference.
+func WithReference(reference []byte) TxOption {
+	return func(o *TxOptions) error {
+		if len(reference) == 0 {
+			return errors.New("empty reference")
+		}
+		o.Reference = reference
+		return nil
+	}
+}
+
+// WithProgress reports to the passed function the progress of the transaction through ordering and finality
+func WithProgress(progress ProgressFunc) TxOption {
+	return func(o *TxOptions) error {
+		o.Progress = progress
+		return nil
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/features"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokens"
 	"github.com/pkg/errors"
@@ -39,9 +40,15 @@ func (o *orderingView) Call(context view.Context) (interface{}, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to compile options")
 	}
+	if options.Transaction == nil {
+		return nil, errors.Errorf("transaction is nil")
+	}
+	options.Progress.report(options.Transaction.ID(), Submitted, nil)
 	if err := o.broadcast(context, options.Transaction); err != nil {
+		options.Progress.report(options.Transaction.ID(), Failed, err)
 		return nil, err
 	}
+	options.Progress.report(options.Transaction.ID(), Ordered, nil)
 
 	// cache the token request into the tokens db
 	t, err := tokens.GetService(context, options.Transaction.TMSID())
@@ -53,6 +60,14 @@ func (o *orderingView) Call(context view.Context) (interface{}, error) {
 			logger.Warnf("failed to cache token request [%s], this might cause delay, investigate when possible: [%s]", options.Transaction.TokenRequest.Anchor, err)
 		}
 	}
+
+	// store the tokens the transaction creates for this node, so that the selector can spend them before finality
+	tmsID := options.Transaction.TMSID()
+	if flags, err := features.GetService(context); err == nil && flags.Enabled(tmsID, features.PendingSelector) {
+		if err := t.AppendPending(context.Context(), tmsID, options.Transaction.ID(), options.Transaction.TokenRequest); err != nil {
+			logger.Warnf("failed to store the pending tokens of [%s], they can be spent only once final: [%s]", options.Transaction.ID(), err)
+		}
+	}
 	return nil, nil
 }
 
@@ -71,8 +86,8 @@ func (o *orderingView) broadcast(context view.Context, transaction *Transaction)
 }
 
 type orderingAndFinalityView struct {
-	tx      *Transaction
-	timeout time.Duration
+	tx   *Transaction
+	opts []TxOption
 }
 
 // NewOrderingAndFinalityView returns a new instance of the orderingAndFinalityView struct.
@@ -89,7 +104,16 @@ func NewOrderingAndFinalityView(tx *Transaction) *orderingAndFinalityView {
 // 1. It broadcasts the token transaction to the proper backend.
 // 2. It waits for finality of the token transaction.
 func NewOrderingAndFinalityWithTimeoutView(tx *Transaction, timeout time.Duration) *orderingAndFinalityView {
-	return &orderingAndFinalityView{tx: tx, timeout: timeout}
+	return NewOrderingAndFinalityWithOpts(tx, WithTimeout(timeout))
+}
+
+// NewOrderingAndFinalityWithOpts returns a new instance of the orderingAndFinalityView struct.
+// The view does the following:
+// 1. It broadcasts the token transaction to the proper backend.
+// 2. It waits for finality of the token transaction, for the timeout set with WithTimeout, ten minutes if not set.
+// The progress is reported to the function set with WithProgress, if any.
+func NewOrderingAndFinalityWithOpts(tx *Transaction, opts ...TxOption) *orderingAndFinalityView {
+	return &orderingAndFinalityView{tx: tx, opts: append([]TxOption{WithTimeout(finalityTimeout)}, opts...)}
 }
 
 // Call executes the view.
@@ -98,8 +122,8 @@ func NewOrderingAndFinalityWithTimeoutView(tx *Transaction, timeout time.Duratio
 // 2. It waits for finality of the token transaction.
 // It returns in case the operation is not completed before the passed timeout.
 func (o *orderingAndFinalityView) Call(ctx view.Context) (interface{}, error) {
-	if _, err := ctx.RunView(NewOrderingView(o.tx)); err != nil {
+	if _, err := ctx.RunView(NewOrderingView(o.tx, o.opts...)); err != nil {
 		return nil, err
 	}
-	return ctx.RunView(NewFinalityView(o.tx, WithTimeout(o.timeout)))
+	return ctx.RunView(NewFinalityView(o.tx, o.opts...))
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
 )
 
 type TxOwner struct {
@@ -22,11 +23,15 @@ type TxOwner struct {
 
 // NewOwner returns a new owner service.
 func NewOwner(sp token.ServiceProvider, tms *token.ManagementService) *TxOwner {
-	backend := New(sp, tms)
+	return NewOwnerWithDB(tms, New(sp, tms))
+}
+
+// NewOwnerWithDB returns a new owner service for the passed TMS over the passed DB, see NewDB.
+func NewOwnerWithDB(tms *token.ManagementService, db *DB) *TxOwner {
 	return &TxOwner{
 		tms:                     tms,
-		owner:                   backend,
-		transactionInfoProvider: newTransactionInfoProvider(tms, backend),
+		owner:                   db,
+		transactionInfoProvider: newTransactionInfoProvider(tms, db),
 	}
 }
 
@@ -50,6 +55,28 @@ func (a *TxOwner) SetStatus(ctx context.Context, txID string, status driver.TxSt
 	return a.owner.SetStatus(ctx, txID, status, message)
 }
 
+// OverrideStatus sets the status of the passed transaction outside the finality listener, for instance to repair it,
+// and records who performed the override and why
+func (a *TxOwner) OverrideStatus(ctx context.Context, txID string, status driver.TxStatus, message string, override StatusOverride) error {
+	return a.owner.OverrideStatus(ctx, txID, status, message, override)
+}
+
+// StatusOverrides returns the status overrides matching the passed params, the oldest first
+func (a *TxOwner) StatusOverrides(params QueryStatusOverridesParams) ([]*StatusOverrideRecord, error) {
+	return a.owner.StatusOverrides(params)
+}
+
+// TxIDsByReference returns the ids of the transactions bound to the passed hash of an external document, the oldest first.
+// See WithReference.
+func (a *TxOwner) TxIDsByReference(reference []byte) ([]string, error) {
+	return a.owner.TxIDsByReference(reference)
+}
+
+// FundsWitnesses returns the funds witnesses matching the passed params, the oldest first, see RecordFundsWitness
+func (a *TxOwner) FundsWitnesses(params QueryFundsWitnessesParams) ([]*FundsWitnessRecord, error) {
+	return a.owner.FundsWitnesses(params)
+}
+
 // GetStatus return the status of the given transaction id.
 // It returns an error if no transaction with that id is found
 func (a *TxOwner) GetStatus(txID string) (TxStatus, string, error) {
@@ -60,6 +87,43 @@ func (a *TxOwner) GetTokenRequest(txID string) ([]byte, error) {
 	return a.owner.GetTokenRequest(txID)
 }
 
+// GetTokenRequests returns the token requests bound to the passed transaction ids, indexed by transaction id.
+// The transaction ids that are not found are missing in the returned map.
+func (a *TxOwner) GetTokenRequests(txIDs []string) (map[string][]byte, error) {
+	return a.owner.GetTokenRequests(txIDs)
+}
+
+// AddCompensationHandler registers a handler to be invoked when a transaction fails to commit.
+// The handler receives the transaction records and the ids of the tokens the transaction was spending.
+func (a *TxOwner) AddCompensationHandler(handler CompensationHandler) {
+	a.owner.ttxDB.AddCompensationHandler(handler)
+}
+
+// CompensationFailures returns the errors of the compensation handlers that failed, by transaction id
+func (a *TxOwner) CompensationFailures() (map[string]error, error) {
+	return a.owner.ttxDB.CompensationFailures()
+}
+
+// RetryCompensation invokes again the compensation handlers for the passed failed transaction
+func (a *TxOwner) RetryCompensation(ctx context.Context, txID string) error {
+	return a.owner.ttxDB.RetryCompensation(ctx, txID)
+}
+
+// RetryCompensations invokes again the compensation handlers for all the failed transactions, for instance at startup.
+// It returns the errors of the compensations failing again, by transaction id.
+func (a *TxOwner) RetryCompensations(ctx context.Context) (map[string]error, error) {
+	return a.owner.ttxDB.RetryCompensations(ctx)
+}
+
+// appendRejected records the passed transaction as deleted, with the passed message, without listening to its finality.
+// The transaction has not been submitted, then the compensation handlers are not invoked.
+func (a *TxOwner) appendRejected(ctx context.Context, tx *Transaction, message string) error {
+	if err := a.owner.ttxDB.AppendTransactionRecord(tx.Request()); err != nil {
+		return errors.WithMessagef(err, "failed appending request %s", tx.ID())
+	}
+	return a.owner.ttxDB.Reject(ctx, tx.ID(), message)
+}
+
 func (a *TxOwner) appendTransactionEndorseAck(tx *Transaction, id view.Identity, sigma []byte) error {
 	return a.owner.AppendTransactionEndorseAck(tx.ID(), id, sigma)
 }
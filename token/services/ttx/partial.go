@@ -0,0 +1,262 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/compression"
+	"github.com/pkg/errors"
+)
+
+// PartiallySignedTransactionVersion is the version of the PartiallySignedTransaction format
+const PartiallySignedTransactionVersion = 1
+
+// PartialSignature is a signature required from a watch-only wallet
+type PartialSignature struct {
+	// WalletID is the identifier of the watch-only wallet the party belongs to
+	WalletID string
+	// Party is the identity that must sign
+	Party view.Identity
+	// Message is the message to sign
+	Message []byte
+	// Sigma is the signature, empty until the party signs
+	Sigma []byte
+}
+
+// PartiallySignedTransaction is a serializable token transaction that spends tokens of watch-only wallets.
+// An online node assembles the transaction and exports it, the signatures of the watch-only wallets are then added offline,
+// and the completed signatures are finally imported by the online node that can continue with the endorsement.
+type PartiallySignedTransaction struct {
+	// Version is the version of the format
+	Version int
+	// TxID is the transaction id
+	TxID string
+	// Transaction is the serialized transaction, as returned by Transaction.Bytes
+	Transaction []byte
+	// Signatures are the signatures required from the watch-only wallets
+	Signatures []*PartialSignature
+}
+
+// NewPartiallySignedTransaction returns a PartiallySignedTransaction listing the signatures
+// the passed transaction requires from the watch-only wallets of this node.
+// It returns an error if no such signature is required.
+func NewPartiallySignedTransaction(tx *Transaction) (*PartiallySignedTransaction, error) {
+	requestRaw, err := tx.TokenRequest.MarshalToSign()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed marshalling request to sign for [%s]", tx.ID())
+	}
+	txRaw, err := tx.Bytes()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed marshalling transaction [%s]", tx.ID())
+	}
+	p := &PartiallySignedTransaction{
+		Version:     PartiallySignedTransactionVersion,
+		TxID:        tx.ID(),
+		Transaction: txRaw,
+	}
+	seen := map[string]bool{}
+	for _, party := range tx.TokenRequest.TransferSigners() {
+		if seen[party.UniqueID()] {
+			continue
+		}
+		seen[party.UniqueID()] = true
+		w := tx.TokenService().WalletManager().OwnerWallet(party)
+		if w == nil || !w.Remote() {
+			continue
+		}
+		sr := &SignatureRequest{Request: requestRaw, TxID: []byte(tx.ID()), Signer: party}
+		p.Signatures = append(p.Signatures, &PartialSignature{
+			WalletID: w.ID(),
+			Party:    party,
+			Message:  sr.MessageToSign(),
+		})
+	}
+	if len(p.Signatures) == 0 {
+		return nil, errors.Errorf("transaction [%s] does not require signatures from watch-only wallets", tx.ID())
+	}
+	return p, nil
+}
+
+// NewPartiallySignedTransactionFromBytes unmarshals a PartiallySignedTransaction serialized with Bytes
+func NewPartiallySignedTransactionFromBytes(raw []byte) (*PartiallySignedTransaction, error) {
+	p := &PartiallySignedTransaction{}
+	if err := json.Unmarshal(raw, p); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling partially signed transaction")
+	}
+	if p.Version != PartiallySignedTransactionVersion {
+		return nil, errors.Errorf("unsupported partially signed transaction version [%d]", p.Version)
+	}
+	return p, nil
+}
+
+// Bytes returns the serialization of this PartiallySignedTransaction
+func (p *PartiallySignedTransaction) Bytes() ([]byte, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling partially signed transaction [%s]", p.TxID)
+	}
+	return raw, nil
+}
+
+// RequestMarshaller returns the serialization of a token request its signers sign, as token.Request.MarshalToSign does
+type RequestMarshaller = func(request *token.Request) ([]byte, error)
+
+// TMSRequestMarshaller returns a RequestMarshaller serializing the token requests with the passed TMS
+func TMSRequestMarshaller(tms *token.ManagementService) RequestMarshaller {
+	return func(request *token.Request) ([]byte, error) {
+		request.SetTokenService(tms)
+		return request.MarshalToSign()
+	}
+}
+
+// Sign adds the signatures of the parties the passed signer provider has a signer for.
+// This is meant to be executed offline, where the keys of the watch-only wallets are stored.
+// The messages to sign are rebuilt from the embedded transaction with the passed marshaller,
+// Sign fails without signing if a message to sign does not match.
+// It returns the number of signatures added.
+func (p *PartiallySignedTransaction) Sign(m RequestMarshaller, sp SignerProvider) (int, error) {
+	request, err := p.tokenRequest()
+	if err != nil {
+		return 0, err
+	}
+	requestRaw, err := m(request)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed marshalling request to sign for [%s]", p.TxID)
+	}
+	for _, s := range p.Signatures {
+		sr := &SignatureRequest{Request: requestRaw, TxID: []byte(p.TxID), Signer: s.Party}
+		if !bytes.Equal(s.Message, sr.MessageToSign()) {
+			return 0, errors.Errorf("message to sign for party [%s] does not match transaction [%s]", s.Party, p.TxID)
+		}
+	}
+
+	n := 0
+	for _, s := range p.Signatures {
+		if len(s.Sigma) != 0 {
+			continue
+		}
+		signer, err := sp.GetSigner(s.Party)
+		if err != nil || signer == nil {
+			logger.Debugf("no signer for party [%s] in [%s], skipping", s.Party, p.TxID)
+			continue
+		}
+		sigma, err := signer.Sign(s.Message)
+		if err != nil {
+			return n, errors.Wrapf(err, "failed signing for party [%s] in [%s]", s.Party, p.TxID)
+		}
+		s.Sigma = sigma
+		n++
+	}
+	return n, nil
+}
+
+// tokenRequest returns the token request of the embedded transaction, it must be bound to the transaction id
+func (p *PartiallySignedTransaction) tokenRequest() (*token.Request, error) {
+	var ser TransactionSer
+	if _, err := asn1.Unmarshal(p.Transaction, &ser); err != nil {
+		return nil, errors.Wrapf(err, "failed unmarshalling transaction [%s]", p.TxID)
+	}
+	if ser.ID != p.TxID {
+		return nil, errors.Errorf("transaction id [%s] does not match [%s]", ser.ID, p.TxID)
+	}
+	raw, err := compression.Decompress(ser.TokenRequest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed decompressing token request of [%s]", p.TxID)
+	}
+	request := token.NewRequest(nil, p.TxID)
+	if err := request.FromBytes(raw); err != nil {
+		return nil, errors.Wrapf(err, "failed unmarshalling token request of [%s]", p.TxID)
+	}
+	if request.Anchor != p.TxID {
+		return nil, errors.Errorf("token request is bound to [%s], not [%s]", request.Anchor, p.TxID)
+	}
+	return request, nil
+}
+
+// Merge copies into this PartiallySignedTransaction the signatures contained in the passed one.
+// It is used when the signatures are added by several offline signers.
+func (p *PartiallySignedTransaction) Merge(other *PartiallySignedTransaction) error {
+	if p.TxID != other.TxID {
+		return errors.Errorf("cannot merge partially signed transactions with different ids [%s][%s]", p.TxID, other.TxID)
+	}
+	for _, o := range other.Signatures {
+		if len(o.Sigma) == 0 {
+			continue
+		}
+		s, err := p.signature(o.Party, o.Message)
+		if err != nil {
+			return err
+		}
+		s.Sigma = o.Sigma
+	}
+	return nil
+}
+
+// Complete returns true if all the required signatures have been added
+func (p *PartiallySignedTransaction) Complete() bool {
+	for _, s := range p.Signatures {
+		if len(s.Sigma) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *PartiallySignedTransaction) signature(party view.Identity, message []byte) (*PartialSignature, error) {
+	for _, s := range p.Signatures {
+		if !s.Party.Equal(party) {
+			continue
+		}
+		if !bytes.Equal(s.Message, message) {
+			return nil, errors.Errorf("message to sign for party [%s] does not match in [%s]", party, p.TxID)
+		}
+		return s, nil
+	}
+	return nil, errors.Errorf("party [%s] not found in [%s]", party, p.TxID)
+}
+
+// WithPartiallySignedTransaction makes the CollectEndorsementsView use the signatures contained in the passed
+// PartiallySignedTransaction for the watch-only wallets it lists.
+func WithPartiallySignedTransaction(p *PartiallySignedTransaction) EndorsementsOpt {
+	return func(o *EndorsementsOpts) error {
+		if !p.Complete() {
+			return errors.Errorf("partially signed transaction [%s] is not complete", p.TxID)
+		}
+		signer := &partialSignatureSigner{p: p}
+		for _, s := range p.Signatures {
+			if err := WithExternalWalletSigner(s.WalletID, signer)(o); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// partialSignatureSigner is an ExternalWalletSigner that returns the signatures of a PartiallySignedTransaction
+type partialSignatureSigner struct {
+	p *PartiallySignedTransaction
+}
+
+func (s *partialSignatureSigner) Sign(party view.Identity, message []byte) ([]byte, error) {
+	sig, err := s.p.signature(party, message)
+	if err != nil {
+		return nil, err
+	}
+	if len(sig.Sigma) == 0 {
+		return nil, errors.Errorf("party [%s] has not signed [%s] yet", party, s.p.TxID)
+	}
+	return sig.Sigma, nil
+}
+
+func (s *partialSignatureSigner) Done() error {
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"encoding/asn1"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type prefixSigner struct{ prefix string }
+
+func (s *prefixSigner) Sign(message []byte) ([]byte, error) {
+	return append([]byte(s.prefix), message...), nil
+}
+
+type signerProvider map[string]token.Signer
+
+func (p signerProvider) GetSigner(party view.Identity) (token.Signer, error) {
+	s, ok := p[string(party)]
+	if !ok {
+		return nil, errors.Errorf("no signer for [%s]", party)
+	}
+	return s, nil
+}
+
+// actionsMarshaller serializes the actions of the requests, as the drivers do
+func actionsMarshaller(request *token.Request) ([]byte, error) {
+	return request.Actions.Bytes()
+}
+
+// newPartiallySigned returns a PartiallySignedTransaction for tx1, requiring the signatures of alice and bob
+func newPartiallySigned(t *testing.T) *PartiallySignedTransaction {
+	request := token.NewRequest(nil, "tx1")
+	request.Actions.Transfers = [][]byte{[]byte("transfer")}
+	requestRaw, err := request.Bytes()
+	assert.NoError(t, err)
+	txRaw, err := asn1.Marshal(TransactionSer{ID: "tx1", TokenRequest: requestRaw})
+	assert.NoError(t, err)
+	toSign, err := actionsMarshaller(request)
+	assert.NoError(t, err)
+
+	p := &PartiallySignedTransaction{Version: PartiallySignedTransactionVersion, TxID: "tx1", Transaction: txRaw}
+	for _, party := range []string{"alice", "bob"} {
+		sr := &SignatureRequest{Request: toSign, TxID: []byte("tx1"), Signer: view.Identity(party)}
+		p.Signatures = append(p.Signatures, &PartialSignature{WalletID: party, Party: view.Identity(party), Message: sr.MessageToSign()})
+	}
+	return p
+}
+
+func TestPartiallySignedTransaction(t *testing.T) {
+	p := newPartiallySigned(t)
+	assert.False(t, p.Complete())
+
+	// serialization
+	raw, err := p.Bytes()
+	assert.NoError(t, err)
+	p2, err := NewPartiallySignedTransactionFromBytes(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, p, p2)
+	_, err = NewPartiallySignedTransactionFromBytes([]byte("{\"Version\":2}"))
+	assert.Error(t, err)
+	_, err = NewPartiallySignedTransactionFromBytes([]byte("not json"))
+	assert.Error(t, err)
+
+	// alice and bob sign on different offline nodes
+	n, err := p.Sign(actionsMarshaller, signerProvider{"alice": &prefixSigner{"alice:"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.False(t, p.Complete())
+	n, err = p2.Sign(actionsMarshaller, signerProvider{"bob": &prefixSigner{"bob:"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.False(t, p2.Complete())
+
+	assert.NoError(t, p.Merge(p2))
+	assert.True(t, p.Complete())
+	assert.Equal(t, append([]byte("bob:"), p.Signatures[1].Message...), p.Signatures[1].Sigma)
+
+	// the signatures are used by the endorsement
+	signer := &partialSignatureSigner{p: p}
+	sigma, err := signer.Sign(view.Identity("alice"), p.Signatures[0].Message)
+	assert.NoError(t, err)
+	assert.Equal(t, p.Signatures[0].Sigma, sigma)
+	_, err = signer.Sign(view.Identity("alice"), []byte("another message"))
+	assert.Error(t, err)
+
+	// merging another transaction or another message fails
+	other := newPartiallySigned(t)
+	other.TxID = "tx2"
+	assert.Error(t, p.Merge(other))
+	other = newPartiallySigned(t)
+	other.Signatures[0].Message = []byte("another message")
+	other.Signatures[0].Sigma = []byte("sigma")
+	assert.Error(t, p.Merge(other))
+}
+
+func TestPartiallySignedTransactionTampered(t *testing.T) {
+	// the message to sign is not the one of the transaction
+	p := newPartiallySigned(t)
+	p.Signatures[1].Message = []byte("pay mallory")
+	n, err := p.Sign(actionsMarshaller, signerProvider{"alice": &prefixSigner{"alice:"}, "bob": &prefixSigner{"bob:"}})
+	assert.Error(t, err)
+	assert.Equal(t, 0, n)
+	assert.Empty(t, p.Signatures[0].Sigma)
+	assert.Empty(t, p.Signatures[1].Sigma)
+
+	// the transaction is bound to another id
+	p = newPartiallySigned(t)
+	p.TxID = "tx2"
+	_, err = p.Sign(actionsMarshaller, signerProvider{"alice": &prefixSigner{"alice:"}})
+	assert.Error(t, err)
+
+	// the transaction is not valid
+	p = newPartiallySigned(t)
+	p.Transaction = []byte("garbage")
+	_, err = p.Sign(actionsMarshaller, signerProvider{"alice": &prefixSigner{"alice:"}})
+	assert.Error(t, err)
+}
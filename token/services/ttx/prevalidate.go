@@ -0,0 +1,149 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// PreValidationRequest asks a counterparty to pre-validate a token request
+type PreValidationRequest struct {
+	TMSID token.TMSID
+	// Request is the token request, as marshalled by token.Request.Bytes
+	Request []byte
+}
+
+// PreValidationVerdict is the outcome of a pre-validation.
+// If Valid is false, Reason explains why the token request has been rejected,
+// otherwise, the remaining fields report what could not be checked yet.
+type PreValidationVerdict struct {
+	Valid  bool
+	Reason string
+	// MissingSigners are the identities whose signatures are still to be collected
+	MissingSigners []token.Identity
+	// UnknownInputs are the inputs not in the vault of the counterparty, whose existence could not be checked
+	UnknownInputs []*token2.ID
+}
+
+// InstallPreValidationViews registers the view that pre-validates the token requests of the counterparties.
+// The node that installs it offers a pre-validation service to any party that can open a session with it.
+func InstallPreValidationViews(viewRegistry ResponderRegistry) error {
+	return viewRegistry.RegisterResponder(&PreValidationResponderView{}, &PreValidateView{})
+}
+
+// PreValidateView asks a counterparty to validate a token request against its current public parameters.
+// It returns the PreValidationVerdict of the counterparty, an invalid verdict is not an error.
+type PreValidateView struct {
+	validator view.Identity
+	request   *token.Request
+}
+
+// NewPreValidateView returns a new PreValidateView asking the passed counterparty to pre-validate the passed token request
+func NewPreValidateView(validator view.Identity, request *token.Request) *PreValidateView {
+	return &PreValidateView{validator: validator, request: request}
+}
+
+func (p *PreValidateView) Call(context view.Context) (interface{}, error) {
+	raw, err := p.request.Bytes()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to marshal request [%s]", p.request.Anchor)
+	}
+	msg, err := Marshal(&PreValidationRequest{TMSID: p.request.TokenService.ID(), Request: raw})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal pre-validation request [%s]", p.request.Anchor)
+	}
+	session, err := context.GetSession(p, p.validator)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting session with [%s]", p.validator)
+	}
+	defer session.Close()
+	if err := session.SendWithContext(context.Context(), msg); err != nil {
+		return nil, errors.Wrapf(err, "failed sending pre-validation request [%s]", p.request.Anchor)
+	}
+	res, err := ReadMessage(session, time.Minute)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed receiving pre-validation verdict [%s]", p.request.Anchor)
+	}
+	verdict := &PreValidationVerdict{}
+	if err := Unmarshal(res, verdict); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal pre-validation verdict [%s]", p.request.Anchor)
+	}
+	return verdict, nil
+}
+
+// PreValidationResponderView is the counterparty side of PreValidateView.
+// It dry-runs the received token request, see token.Validator.DryRun, therefore, it does not write to the vault.
+// The requester always gets a verdict: if the request cannot be pre-validated, the verdict is a rejection with the reason.
+type PreValidationResponderView struct{}
+
+func (p *PreValidationResponderView) Call(context view.Context) (interface{}, error) {
+	session := context.Session()
+	verdict, err := p.receive(context, session)
+	if err != nil {
+		// the requester is waiting for a verdict, it gets a rejection instead of a timeout
+		verdict = &PreValidationVerdict{Reason: err.Error()}
+	}
+	res, marshalErr := Marshal(verdict)
+	if marshalErr != nil {
+		return nil, errors.Wrap(marshalErr, "failed to marshal pre-validation verdict")
+	}
+	if sendErr := session.SendWithContext(context.Context(), res); sendErr != nil {
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed sending rejection [%s]", sendErr)
+		}
+		return nil, errors.Wrap(sendErr, "failed sending pre-validation verdict")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return verdict, nil
+}
+
+// receive reads the pre-validation request from the passed session and returns the verdict on it
+func (p *PreValidationResponderView) receive(context view.Context, session view.Session) (*PreValidationVerdict, error) {
+	msg, err := ReadMessage(session, time.Minute)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed receiving pre-validation request")
+	}
+	req := &PreValidationRequest{}
+	if err := Unmarshal(msg, req); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal pre-validation request")
+	}
+	return p.preValidate(context, req)
+}
+
+// preValidate returns the verdict on the passed request.
+// It returns an error only if the verdict cannot be produced, such as when the TMS is unknown.
+func (p *PreValidationResponderView) preValidate(context view.Context, req *PreValidationRequest) (*PreValidationVerdict, error) {
+	tms := token.GetManagementService(context, token.WithTMSID(req.TMSID))
+	if tms == nil {
+		return nil, errors.Errorf("cannot find tms for [%s]", req.TMSID)
+	}
+	validator, err := tms.Validator()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get validator for [%s]", req.TMSID)
+	}
+	request, err := token.NewFullRequestFromBytes(tms, req.Request)
+	if err != nil {
+		return &PreValidationVerdict{Reason: err.Error()}, nil
+	}
+	report, err := validator.DryRun(context.Context(), request)
+	if err != nil {
+		logger.Debugf("pre-validation of [%s] failed: [%s]", request.Anchor, err)
+		return &PreValidationVerdict{Reason: err.Error()}, nil
+	}
+	return &PreValidationVerdict{
+		Valid:          true,
+		MissingSigners: report.MissingSigners,
+		UnknownInputs:  report.UnknownInputs,
+	}, nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/logging"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSession delivers the passed messages and records the ones sent
+type fakeSession struct {
+	view.Session
+	in      chan *view.Message
+	sent    [][]byte
+	sendErr error
+}
+
+func newFakeSession(msgs ...*view.Message) *fakeSession {
+	in := make(chan *view.Message, len(msgs))
+	for _, msg := range msgs {
+		in <- msg
+	}
+	return &fakeSession{in: in}
+}
+
+func (s *fakeSession) Receive() <-chan *view.Message { return s.in }
+
+func (s *fakeSession) SendWithContext(_ context.Context, payload []byte) error {
+	if s.sendErr != nil {
+		return s.sendErr
+	}
+	s.sent = append(s.sent, payload)
+	return nil
+}
+
+// unknownTMSNormalizer fails the lookup of any TMS
+type unknownTMSNormalizer struct{}
+
+func (unknownTMSNormalizer) Normalize(*token.ServiceOptions) (*token.ServiceOptions, error) {
+	return nil, errors.New("unknown tms")
+}
+
+// viewContext lets responderContext embed view.Context and still define the Context method
+type viewContext = view.Context
+
+// responderContext is the context of a responder with no TMS
+type responderContext struct {
+	viewContext
+	session view.Session
+}
+
+func (c *responderContext) Session() view.Session { return c.session }
+
+func (c *responderContext) Context() context.Context { return context.Background() }
+
+func (c *responderContext) GetService(interface{}) (interface{}, error) {
+	return token.NewManagementServiceProvider(logging.MustGetLogger("test"), nil, unknownTMSNormalizer{}, nil, nil, nil), nil
+}
+
+func TestPreValidationResponderRejects(t *testing.T) {
+	request, err := Marshal(&PreValidationRequest{TMSID: token.TMSID{Network: "n1"}, Request: []byte("request")})
+	assert.NoError(t, err)
+
+	for name, msg := range map[string]*view.Message{
+		"error from the requester": {Status: view.ERROR, Payload: []byte("aborted")},
+		"malformed request":        {Payload: []byte("not a request")},
+		"unknown tms":              {Payload: request},
+	} {
+		t.Run(name, func(t *testing.T) {
+			session := newFakeSession(msg)
+			_, err := (&PreValidationResponderView{}).Call(&responderContext{session: session})
+			assert.Error(t, err)
+
+			// the requester gets a rejection, not a timeout
+			assert.Len(t, session.sent, 1)
+			verdict := &PreValidationVerdict{}
+			assert.NoError(t, Unmarshal(session.sent[0], verdict))
+			assert.False(t, verdict.Valid)
+			assert.NotEmpty(t, verdict.Reason)
+		})
+	}
+
+	// the rejection cannot be sent either
+	session := newFakeSession(&view.Message{Payload: []byte("not a request")})
+	session.sendErr = errors.New("connection lost")
+	_, err = (&PreValidationResponderView{}).Call(&responderContext{session: session})
+	assert.ErrorContains(t, err, "failed to unmarshal pre-validation request")
+	assert.ErrorContains(t, err, "connection lost")
+}
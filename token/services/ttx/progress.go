@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"context"
+	"sync"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+)
+
+// Stage is a step of a transaction through ordering and finality
+type Stage int
+
+const (
+	// Submitted means that the transaction is being broadcast to the ordering service
+	Submitted Stage = iota + 1
+	// Ordered means that the ordering service accepted the transaction
+	Ordered
+	// Committed means that the transaction is final and valid
+	Committed
+	// Failed means that the transaction could not be ordered, is not valid, or did not reach finality in time
+	Failed
+)
+
+var stageNames = map[Stage]string{
+	Submitted: "Submitted",
+	Ordered:   "Ordered",
+	Committed: "Committed",
+	Failed:    "Failed",
+}
+
+func (s Stage) String() string {
+	if name, ok := stageNames[s]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// ProgressFunc is called each time a transaction reaches a new stage.
+// The error is set for the Failed stage only.
+type ProgressFunc func(txID string, stage Stage, err error)
+
+func (f ProgressFunc) report(txID string, stage Stage, err error) {
+	if f == nil {
+		return
+	}
+	f(txID, stage, err)
+}
+
+// viewInitiator initiates views
+type viewInitiator interface {
+	InitiateView(view view2.View, ctx context.Context) (interface{}, error)
+}
+
+// Submission is the handle of a transaction submitted with SubmitAsync.
+// Its stage can be polled with Stage, or its finality awaited with Wait.
+type Submission struct {
+	txID     string
+	progress ProgressFunc
+
+	lock  sync.RWMutex
+	stage Stage
+	err   error
+	done  chan struct{}
+}
+
+// SubmitAsync broadcasts the passed transaction and waits for its finality in the background.
+// It returns immediately a handle to follow the progress of the transaction.
+// The options are those of NewOrderingAndFinalityWithOpts, in particular, WithTimeout sets the finality timeout,
+// and WithProgress a function called at each stage.
+func SubmitAsync(context view.Context, tx *Transaction, opts ...TxOption) (*Submission, error) {
+	return submitAsync(view2.GetManager(context), tx, opts...)
+}
+
+func submitAsync(initiator viewInitiator, tx *Transaction, opts ...TxOption) (*Submission, error) {
+	if tx == nil {
+		return nil, errors.New("transaction is nil")
+	}
+	options, err := compile(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to compile options")
+	}
+	s := &Submission{txID: tx.ID(), progress: options.Progress, done: make(chan struct{})}
+	go func() {
+		// the submission outlives the view that started it, therefore, it does not inherit its context
+		_, err := initiator.InitiateView(NewOrderingAndFinalityWithOpts(tx, append(opts, WithProgress(s.update))...), context.Background())
+		s.complete(err)
+	}()
+	return s, nil
+}
+
+func (s *Submission) update(txID string, stage Stage, err error) {
+	s.lock.Lock()
+	s.stage = stage
+	s.lock.Unlock()
+	s.progress.report(txID, stage, err)
+}
+
+func (s *Submission) complete(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err != nil {
+		s.err = err
+		s.stage = Failed
+	} else {
+		s.stage = Committed
+	}
+	close(s.done)
+}
+
+// TxID returns the id of the submitted transaction
+func (s *Submission) TxID() string {
+	return s.txID
+}
+
+// Stage returns the last stage reached by the transaction, zero if it has not been broadcast yet
+func (s *Submission) Stage() Stage {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.stage
+}
+
+// Done returns a channel closed once the transaction is committed or failed
+func (s *Submission) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the reason the transaction failed, nil if it has not failed (yet)
+func (s *Submission) Err() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.err
+}
+
+// Wait waits until the transaction is committed or failed, or until the passed context is done.
+// In the latter case, the transaction continues in the background.
+func (s *Submission) Wait(ctx context.Context) error {
+	select {
+	case <-s.done:
+		return s.Err()
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "stopped waiting for the finality of [%s]", s.txID)
+	}
+}
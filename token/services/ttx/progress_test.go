@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeInitiator reports the stages of the views it runs, and fails them with the passed error, if any
+type fakeInitiator struct {
+	release chan struct{}
+	err     error
+	timeout time.Duration
+}
+
+func (f *fakeInitiator) InitiateView(v view2.View, _ context.Context) (interface{}, error) {
+	o := v.(*orderingAndFinalityView)
+	options, err := compile(o.opts...)
+	if err != nil {
+		return nil, err
+	}
+	f.timeout = options.Timeout
+	options.Progress.report(o.tx.ID(), Submitted, nil)
+	options.Progress.report(o.tx.ID(), Ordered, nil)
+	<-f.release
+	if f.err != nil {
+		options.Progress.report(o.tx.ID(), Failed, f.err)
+		return nil, f.err
+	}
+	options.Progress.report(o.tx.ID(), Committed, nil)
+	return nil, nil
+}
+
+func TestSubmitAsync(t *testing.T) {
+	tx := &Transaction{Payload: &Payload{ID: "tx1"}}
+
+	var lock sync.Mutex
+	var stages []Stage
+	initiator := &fakeInitiator{release: make(chan struct{})}
+	s, err := submitAsync(initiator, tx, WithTimeout(time.Minute), WithProgress(func(txID string, stage Stage, err error) {
+		assert.Equal(t, "tx1", txID)
+		lock.Lock()
+		stages = append(stages, stage)
+		lock.Unlock()
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "tx1", s.TxID())
+
+	// the submission does not block
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, s.Wait(ctx))
+	assert.Equal(t, Ordered, s.Stage())
+	assert.Equal(t, time.Minute, initiator.timeout)
+
+	close(initiator.release)
+	assert.NoError(t, s.Wait(context.Background()))
+	assert.Equal(t, Committed, s.Stage())
+	assert.Equal(t, []Stage{Submitted, Ordered, Committed}, stages)
+
+	// a failure
+	initiator = &fakeInitiator{release: make(chan struct{}), err: errors.New("invalid")}
+	close(initiator.release)
+	s, err = submitAsync(initiator, tx)
+	assert.NoError(t, err)
+	<-s.Done()
+	assert.Equal(t, Failed, s.Stage())
+	assert.EqualError(t, s.Err(), "invalid")
+	assert.Equal(t, finalityTimeout, initiator.timeout)
+}
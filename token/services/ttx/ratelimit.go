@@ -0,0 +1,260 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/pkg/errors"
+)
+
+// RateLimitKey is the key, in the TMS configuration, of the rate limits of the outgoing transfers
+const RateLimitKey = "services.ttx.rateLimit"
+
+const (
+	// TransfersLimit is the label of the limit on the number of transfers per minute
+	TransfersLimit = "transfers"
+	// ValueLimit is the label of the limit on the value transferred per hour
+	ValueLimit = "value"
+)
+
+var (
+	rateLimiterType = reflect.TypeOf((*RateLimiter)(nil))
+
+	// ErrRateLimited is returned when a transfer exceeds the rate limits of its wallet
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// RateLimit bounds the outgoing transfers of a wallet
+type RateLimit struct {
+	// TransfersPerMinute is the maximum number of transfers and redeems per minute, 0 means no limit
+	TransfersPerMinute int `yaml:"transfersPerMinute,omitempty"`
+	// ValuePerHour is the maximum value transferred or redeemed per hour, by token type.
+	// The token types not listed have no limit.
+	ValuePerHour map[string]uint64 `yaml:"valuePerHour,omitempty"`
+}
+
+// RateLimitConfig is the configuration of the rate limits of a TMS
+type RateLimitConfig struct {
+	// TransfersPerMinute is the default of RateLimit.TransfersPerMinute
+	TransfersPerMinute int `yaml:"transfersPerMinute,omitempty"`
+	// ValuePerHour is the default of RateLimit.ValuePerHour
+	ValuePerHour map[string]uint64 `yaml:"valuePerHour,omitempty"`
+	// Wallets replaces the defaults for the listed wallets
+	Wallets map[string]RateLimit `yaml:"wallets,omitempty"`
+}
+
+// Limit returns the rate limit of the passed wallet
+func (c *RateLimitConfig) Limit(walletID string) RateLimit {
+	if l, ok := c.Wallets[walletID]; ok {
+		return l
+	}
+	return RateLimit{TransfersPerMinute: c.TransfersPerMinute, ValuePerHour: c.ValuePerHour}
+}
+
+// RateLimitError is returned when a transfer exceeds the rate limits of its wallet, it matches ErrRateLimited
+type RateLimitError struct {
+	// Wallet is the id of the wallet
+	Wallet string
+	// Limit is the exceeded limit, TransfersLimit or ValueLimit
+	Limit string
+	// TokenType is the token type of the transfer
+	TokenType string
+	// RetryAfter is the time after which the transfer would fit the limit, 0 if it never will
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter == 0 {
+		return fmt.Sprintf("%s: wallet [%s] exceeds the %s limit for [%s]", ErrRateLimited, e.Wallet, e.Limit, e.TokenType)
+	}
+	return fmt.Sprintf("%s: wallet [%s] exceeds the %s limit for [%s], retry after [%s]", ErrRateLimited, e.Wallet, e.Limit, e.TokenType, e.RetryAfter)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RateLimiter bounds, per wallet, the number of transfers per minute and the value transferred per hour.
+// It is a safety brake against application bugs draining a wallet: transfers and redeems exceeding the limits
+// are refused when added to a transaction. The limits are read from the configuration of each TMS, under RateLimitKey.
+// The usage is kept in memory, and is reset when the node restarts.
+type RateLimiter struct {
+	metrics *Metrics
+	now     func() time.Time
+
+	lock    sync.Mutex
+	configs map[string]*RateLimitConfig
+	usages  map[string]*usage
+}
+
+// NewRateLimiter returns a new RateLimiter reporting the refused transfers with the passed metrics
+func NewRateLimiter(metrics *Metrics) *RateLimiter {
+	return &RateLimiter{
+		metrics: metrics,
+		now:     time.Now,
+		configs: map[string]*RateLimitConfig{},
+		usages:  map[string]*usage{},
+	}
+}
+
+// GetRateLimiter returns the RateLimiter registered in the passed service provider
+func GetRateLimiter(sp token.ServiceProvider) (*RateLimiter, error) {
+	s, err := sp.GetService(rateLimiterType)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting rate limiter")
+	}
+	return s.(*RateLimiter), nil
+}
+
+// Reserve accounts a transfer of the passed value from the passed wallet of the passed TMS.
+// It returns an error matching ErrRateLimited if the transfer exceeds the limits of the wallet.
+// Otherwise, it returns a function that gives the reservation back, to be called if the transfer is not carried out.
+func (l *RateLimiter) Reserve(tms *token.ManagementService, walletID string, tokenType string, value uint64) (func(), error) {
+	config, err := l.config(tms)
+	if err != nil {
+		return nil, err
+	}
+	limit := config.Limit(walletID)
+	if limit.TransfersPerMinute <= 0 && len(limit.ValuePerHour) == 0 {
+		return func() {}, nil
+	}
+	tmsID := tms.ID()
+	release, err := l.reserve(tmsID.String()+":"+walletID, limit, tokenType, value)
+	if err != nil {
+		var rle *RateLimitError
+		if errors.As(err, &rle) {
+			rle.Wallet = walletID
+		}
+		if rle != nil && l.metrics != nil {
+			l.metrics.RateLimitedTransfers.With(
+				"network", tmsID.Network,
+				"channel", tmsID.Channel,
+				"namespace", tmsID.Namespace,
+				"wallet", walletID,
+				"limit", rle.Limit,
+			).Add(1)
+		}
+		logger.Warnf("transfer of [%d] [%s] from [%s] refused: [%s]", value, tokenType, walletID, err)
+		return nil, err
+	}
+	return release, nil
+}
+
+func (l *RateLimiter) config(tms *token.ManagementService) (*RateLimitConfig, error) {
+	key := tms.ID().String()
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if c, ok := l.configs[key]; ok {
+		return c, nil
+	}
+	c := &RateLimitConfig{}
+	if tms.Configuration().IsSet(RateLimitKey) {
+		if err := tms.Configuration().UnmarshalKey(RateLimitKey, c); err != nil {
+			return nil, errors.WithMessagef(err, "failed to load [%s]", RateLimitKey)
+		}
+	}
+	l.configs[key] = c
+	return c, nil
+}
+
+func (l *RateLimiter) reserve(key string, limit RateLimit, tokenType string, value uint64) (func(), error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	u, ok := l.usages[key]
+	if !ok {
+		u = &usage{}
+		l.usages[key] = u
+	}
+	now := l.now()
+	u.prune(now.Add(-time.Hour))
+	if err := u.check(now, limit, tokenType, value); err != nil {
+		return nil, err
+	}
+	t := &transfer{at: now, tokenType: tokenType, value: value}
+	u.transfers = append(u.transfers, t)
+	return func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		u.remove(t)
+	}, nil
+}
+
+type transfer struct {
+	at        time.Time
+	tokenType string
+	value     uint64
+}
+
+// usage holds the transfers of a wallet in the last hour, in order
+type usage struct {
+	transfers []*transfer
+}
+
+func (u *usage) prune(before time.Time) {
+	i := 0
+	for i < len(u.transfers) && !u.transfers[i].at.After(before) {
+		i++
+	}
+	u.transfers = u.transfers[i:]
+}
+
+func (u *usage) remove(t *transfer) {
+	for i, tr := range u.transfers {
+		if tr == t {
+			u.transfers = append(u.transfers[:i], u.transfers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (u *usage) check(now time.Time, limit RateLimit, tokenType string, value uint64) *RateLimitError {
+	if limit.TransfersPerMinute > 0 {
+		var lastMinute []*transfer
+		for _, t := range u.transfers {
+			if t.at.After(now.Add(-time.Minute)) {
+				lastMinute = append(lastMinute, t)
+			}
+		}
+		if len(lastMinute) >= limit.TransfersPerMinute {
+			// the transfer fits once enough transfers leave the window
+			oldest := lastMinute[len(lastMinute)-limit.TransfersPerMinute]
+			return &RateLimitError{Limit: TransfersLimit, TokenType: tokenType, RetryAfter: oldest.at.Add(time.Minute).Sub(now)}
+		}
+	}
+	max, ok := limit.ValuePerHour[tokenType]
+	if !ok {
+		return nil
+	}
+	if value > max {
+		return &RateLimitError{Limit: ValueLimit, TokenType: tokenType}
+	}
+	var sum uint64
+	for _, t := range u.transfers {
+		if t.tokenType == tokenType {
+			sum += t.value
+		}
+	}
+	if sum+value <= max {
+		return nil
+	}
+	// the transfer fits once enough value leaves the window
+	for _, t := range u.transfers {
+		if t.tokenType != tokenType {
+			continue
+		}
+		sum -= t.value
+		if sum+value <= max {
+			return &RateLimitError{Limit: ValueLimit, TokenType: tokenType, RetryAfter: t.at.Add(time.Hour).Sub(now)}
+		}
+	}
+	return &RateLimitError{Limit: ValueLimit, TokenType: tokenType}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(nil)
+	l.now = func() time.Time { return now }
+	limit := RateLimit{TransfersPerMinute: 2, ValuePerHour: map[string]uint64{"USD": 100}}
+
+	_, err := l.reserve("alice", limit, "USD", 40)
+	assert.NoError(t, err)
+	now = now.Add(10 * time.Second)
+	release, err := l.reserve("alice", limit, "EUR", 1000)
+	assert.NoError(t, err)
+
+	// third transfer in the same minute
+	now = now.Add(10 * time.Second)
+	_, err = l.reserve("alice", limit, "USD", 1)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	rle := &RateLimitError{}
+	assert.True(t, errors.As(err, &rle))
+	assert.Equal(t, TransfersLimit, rle.Limit)
+	assert.Equal(t, 40*time.Second, rle.RetryAfter)
+
+	// the other wallets are not affected
+	_, err = l.reserve("bob", limit, "USD", 1)
+	assert.NoError(t, err)
+
+	// a released reservation frees its slot
+	release()
+	_, err = l.reserve("alice", limit, "USD", 50)
+	assert.NoError(t, err)
+
+	// value exceeding the hourly limit
+	now = now.Add(time.Minute)
+	_, err = l.reserve("alice", limit, "USD", 20)
+	assert.True(t, errors.As(err, &rle))
+	assert.Equal(t, ValueLimit, rle.Limit)
+	assert.Equal(t, time.Hour-80*time.Second, rle.RetryAfter)
+	_, err = l.reserve("alice", limit, "USD", 101)
+	assert.True(t, errors.As(err, &rle))
+	assert.Equal(t, time.Duration(0), rle.RetryAfter)
+
+	// once the window has passed
+	now = now.Add(time.Hour)
+	_, err = l.reserve("alice", limit, "USD", 100)
+	assert.NoError(t, err)
+}
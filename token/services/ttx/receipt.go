@@ -0,0 +1,165 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	tdriver "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+var actionTypeNames = map[driver.ActionType]string{
+	driver.Issue:    "issue",
+	driver.Transfer: "transfer",
+	driver.Redeem:   "redeem",
+}
+
+// ReceiptEntry is a movement of tokens in a receipt
+type ReceiptEntry struct {
+	// ActionType is issue, transfer, or redeem
+	ActionType string `json:"actionType"`
+	// Sender is the enrollment id of the sender, empty if not visible to the node
+	Sender string `json:"sender,omitempty"`
+	// Recipient is the enrollment id of the recipient, empty if not visible to the node
+	Recipient string `json:"recipient,omitempty"`
+	// TokenType is the type of the moved tokens
+	TokenType string `json:"tokenType"`
+	// Amount is the decimal amount of the moved tokens
+	Amount string `json:"amount"`
+}
+
+// Receipt is a compact proof that a transaction is confirmed, meant to be shared with the counterparties
+type Receipt struct {
+	// TxID is the id of the transaction
+	TxID string `json:"txID"`
+	// Network, Channel, and Namespace identify the TMS of the transaction
+	Network   string `json:"network"`
+	Channel   string `json:"channel,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	// Timestamp is the time the transaction was recorded by the node
+	Timestamp time.Time `json:"timestamp"`
+	// Anchor is the anchor that binds the token request to the ledger transaction
+	Anchor string `json:"anchor"`
+	// RequestHash is the hash of the actions of the token request, as committed on the ledger
+	RequestHash []byte `json:"requestHash"`
+	// HashAlgorithm is the hash algorithm of RequestHash, selected by the public parameters, sha256 if empty
+	HashAlgorithm tdriver.HashAlgorithm `json:"hashAlgorithm,omitempty"`
+	// Reference is the hash of the external document bound to the transaction, if any, see WithReference
+	Reference []byte `json:"reference,omitempty"`
+	// Entries are the movements of tokens, the change given back to the senders excluded
+	Entries []ReceiptEntry `json:"entries"`
+	// AuditorSignatures are the signatures of the auditor over the token request, if audited
+	AuditorSignatures [][]byte `json:"auditorSignatures,omitempty"`
+}
+
+// NewReceipt returns the receipt of the passed request, whose movements are described by the passed transaction records
+func NewReceipt(tmsID token.TMSID, request *token.Request, records []*driver.TransactionRecord) (*Receipt, error) {
+	if request == nil || request.Actions == nil {
+		return nil, errors.New("no token request")
+	}
+	raw, err := request.Actions.Bytes()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal token request [%s]", request.Anchor)
+	}
+	hashAlgorithm := tdriver.SHA256
+	if request.TokenService != nil {
+		hashAlgorithm = request.TokenService.PublicParametersManager().PublicParameters().HashAlgorithm()
+	}
+	hash, err := hashAlgorithm.Hash(raw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to hash token request [%s]", request.Anchor)
+	}
+	r := &Receipt{
+		TxID:              request.Anchor,
+		Network:           tmsID.Network,
+		Channel:           tmsID.Channel,
+		Namespace:         tmsID.Namespace,
+		Anchor:            request.Anchor,
+		RequestHash:       hash,
+		HashAlgorithm:     hashAlgorithm,
+		Reference:         request.Actions.Reference,
+		AuditorSignatures: request.Actions.AuditorSignatures,
+		Entries:           []ReceiptEntry{},
+	}
+	for _, record := range records {
+		if r.Timestamp.IsZero() || record.Timestamp.Before(r.Timestamp) {
+			r.Timestamp = record.Timestamp
+		}
+		if record.ActionType != driver.Issue && record.SenderEID == record.RecipientEID {
+			continue
+		}
+		amount := "0"
+		if record.Amount != nil {
+			amount = record.Amount.String()
+		}
+		r.Entries = append(r.Entries, ReceiptEntry{
+			ActionType: actionTypeNames[record.ActionType],
+			Sender:     record.SenderEID,
+			Recipient:  record.RecipientEID,
+			TokenType:  record.TokenType,
+			Amount:     amount,
+		})
+	}
+	return r, nil
+}
+
+// JSON returns the receipt in JSON
+func (r *Receipt) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// ReceiptFromJSON returns the receipt in the passed JSON, see Receipt.JSON
+func ReceiptFromJSON(raw []byte) (*Receipt, error) {
+	r := &Receipt{}
+	if err := json.Unmarshal(raw, r); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal receipt")
+	}
+	return r, nil
+}
+
+// GetReceipt returns the receipt of the passed transaction, that must be confirmed
+func (a *TxOwner) GetReceipt(txID string) (*Receipt, error) {
+	status, _, err := a.owner.GetStatus(txID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get status of [%s]", txID)
+	}
+	if status != Confirmed {
+		return nil, errors.Errorf("transaction [%s] is not confirmed, status [%s]", txID, TxStatusMessage[status])
+	}
+	raw, err := a.owner.GetTokenRequest(txID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get token request of [%s]", txID)
+	}
+	if len(raw) == 0 {
+		return nil, errors.Errorf("no token request found for [%s]", txID)
+	}
+	request, err := a.tms.NewFullRequestFromBytes(raw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to unmarshal token request of [%s]", txID)
+	}
+	it, err := a.owner.ttxDB.Transactions(QueryTransactionsParams{IDs: []string{txID}})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query transaction records of [%s]", txID)
+	}
+	defer it.Close()
+	var records []*driver.TransactionRecord
+	for {
+		record, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to read transaction records of [%s]", txID)
+		}
+		if record == nil {
+			break
+		}
+		records = append(records, record)
+	}
+	return NewReceipt(a.tms.ID(), request, records)
+}
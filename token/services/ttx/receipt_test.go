@@ -0,0 +1,51 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	tdriver "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceipt(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	request := token.NewRequest(nil, "tx1")
+	request.Actions.AuditorSignatures = [][]byte{[]byte("sigma")}
+	records := []*driver.TransactionRecord{
+		{TxID: "tx1", ActionType: driver.Transfer, SenderEID: "alice", RecipientEID: "bob", TokenType: "USD", Amount: big.NewInt(30), Timestamp: now.Add(time.Second)},
+		// the change is left out
+		{TxID: "tx1", ActionType: driver.Transfer, SenderEID: "alice", RecipientEID: "alice", TokenType: "USD", Amount: big.NewInt(70), Timestamp: now},
+		{TxID: "tx1", ActionType: driver.Redeem, SenderEID: "alice", TokenType: "USD", Amount: big.NewInt(5), Timestamp: now},
+	}
+
+	r, err := NewReceipt(token.TMSID{Network: "n", Channel: "c", Namespace: "ns"}, request, records)
+	assert.NoError(t, err)
+	assert.Equal(t, "tx1", r.TxID)
+	assert.Equal(t, now, r.Timestamp)
+	assert.Len(t, r.RequestHash, 32)
+	assert.Equal(t, tdriver.SHA256, r.HashAlgorithm)
+	assert.Equal(t, [][]byte{[]byte("sigma")}, r.AuditorSignatures)
+	assert.Equal(t, []ReceiptEntry{
+		{ActionType: "transfer", Sender: "alice", Recipient: "bob", TokenType: "USD", Amount: "30"},
+		{ActionType: "redeem", Sender: "alice", TokenType: "USD", Amount: "5"},
+	}, r.Entries)
+
+	raw, err := r.JSON()
+	assert.NoError(t, err)
+	r2, err := ReceiptFromJSON(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, r, r2)
+
+	_, err = NewReceipt(token.TMSID{}, nil, records)
+	assert.Error(t, err)
+}
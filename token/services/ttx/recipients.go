@@ -13,6 +13,7 @@ import (
 	session2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/session"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
 	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
 )
@@ -38,6 +39,25 @@ func WithRecipientData(recipientData *RecipientData) token.ServiceOption {
 	}
 }
 
+// WithAnonymityLevel is used to select the anonymity level of the recipient identity to request
+func WithAnonymityLevel(level token.AnonymityLevel) token.ServiceOption {
+	return func(options *token.ServiceOptions) error {
+		if options.Params == nil {
+			options.Params = map[string]interface{}{}
+		}
+		options.Params["AnonymityLevel"] = level
+		return nil
+	}
+}
+
+func getAnonymityLevel(opts *token.ServiceOptions) token.AnonymityLevel {
+	level, ok := opts.Params["AnonymityLevel"].(token.AnonymityLevel)
+	if !ok {
+		return token.DefaultAnonymity
+	}
+	return level
+}
+
 func getRecipientData(opts *token.ServiceOptions) *RecipientData {
 	rdBoxed, ok := opts.Params["RecipientData"]
 	if !ok {
@@ -63,9 +83,10 @@ func (r *ExchangeRecipientRequest) FromBytes(raw []byte) error {
 }
 
 type RecipientRequest struct {
-	TMSID         token.TMSID
-	WalletID      []byte
-	RecipientData *RecipientData
+	TMSID          token.TMSID
+	WalletID       []byte
+	RecipientData  *RecipientData
+	AnonymityLevel token.AnonymityLevel `json:",omitempty"`
 }
 
 func (r *RecipientRequest) Bytes() ([]byte, error) {
@@ -80,11 +101,13 @@ type RequestRecipientIdentityView struct {
 	TMSID              token.TMSID
 	Other              view.Identity
 	OtherRecipientData *RecipientData
+	AnonymityLevel     token.AnonymityLevel
 }
 
 // RequestRecipientIdentity executes the RequestRecipientIdentityView.
 // The sender contacts the recipient's FSC node identified via the passed view identity.
 // The sender gets back the identity the recipient wants to use to assign ownership of tokens.
+// Use WithAnonymityLevel to request a long-term identity or a fresh pseudonym.
 func RequestRecipientIdentity(context view.Context, recipient view.Identity, opts ...token.ServiceOption) (view.Identity, error) {
 	options, err := CompileServiceOptions(opts...)
 	if err != nil {
@@ -94,6 +117,7 @@ func RequestRecipientIdentity(context view.Context, recipient view.Identity, opt
 		TMSID:              options.TMSID(),
 		Other:              recipient,
 		OtherRecipientData: getRecipientData(options),
+		AnonymityLevel:     getAnonymityLevel(options),
 	})
 	if err != nil {
 		return nil, err
@@ -112,7 +136,14 @@ func (f *RequestRecipientIdentityView) Call(context view.Context) (interface{},
 	if isRemoteRecipient := f.OtherRecipientData != nil; isRemoteRecipient {
 		return f.OtherRecipientData.Identity, nil
 	}
-	return w.GetRecipientIdentity()
+	id, err := w.GetRecipientIdentity(token.WithAnonymityLevel(f.AnonymityLevel))
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAnonymityLevel(context, w, id, f.AnonymityLevel); err != nil {
+		return nil, err
+	}
+	return id, nil
 }
 
 func (f *RequestRecipientIdentityView) callWithRecipientData(context view.Context) (interface{}, error) {
@@ -128,9 +159,10 @@ func (f *RequestRecipientIdentityView) callWithRecipientData(context view.Contex
 
 	// Ask for identity
 	rr := &RecipientRequest{
-		TMSID:         f.TMSID,
-		WalletID:      f.Other,
-		RecipientData: f.OtherRecipientData,
+		TMSID:          f.TMSID,
+		WalletID:       f.Other,
+		RecipientData:  f.OtherRecipientData,
+		AnonymityLevel: f.AnonymityLevel,
 	}
 	rrRaw, err := rr.Bytes()
 	if err != nil {
@@ -162,6 +194,9 @@ func (f *RequestRecipientIdentityView) callWithRecipientData(context view.Contex
 		logger.Errorf("failed to register recipient identity: [%s]", err)
 		return nil, errors.Wrapf(err, "failed to register recipient identity")
 	}
+	if err := wm.BindRecipientIdentity(recipientData.Identity, string(f.Other)); err != nil {
+		return nil, errors.Wrapf(err, "failed to bind recipient identity to wallet [%s]", f.Other)
+	}
 
 	// Update the Endpoint Resolver
 	if logger.IsEnabledFor(zapcore.DebugLevel) {
@@ -248,11 +283,15 @@ func (s *RespondRequestRecipientIdentityView) Call(context view.Context) (interf
 	} else {
 		span.AddEvent("generate_identity")
 		// otherwise generate one fresh
-		recipientIdentity, err = w.GetRecipientIdentity()
+		recipientIdentity, err = w.GetRecipientIdentity(token.WithAnonymityLevel(recipientRequest.AnonymityLevel))
 		if err != nil {
 			logger.Errorf("failed to get recipient identity: [%s]", err)
 			return nil, errors.Wrapf(err, "failed to get recipient identity")
 		}
+		if err := recordAnonymityLevel(context, w, recipientIdentity, recipientRequest.AnonymityLevel); err != nil {
+			logger.Errorf("failed to record anonymity level: [%s]", err)
+			return nil, err
+		}
 		auditInfo, err := w.GetAuditInfo(recipientIdentity)
 		if err != nil {
 			logger.Errorf("failed to get audit info: [%s]", err)
@@ -298,6 +337,22 @@ func (s *RespondRequestRecipientIdentityView) Call(context view.Context) (interf
 	return recipientIdentity, nil
 }
 
+// recordAnonymityLevel records in the ttxdb the anonymity level the passed recipient identity has been derived with,
+// so that the transactions giving outputs to the identity carry it, see ttxdb.RecipientAnonymityLevelMetadataPrefix
+func recordAnonymityLevel(sp token.ServiceProvider, w *token.OwnerWallet, id view.Identity, level token.AnonymityLevel) error {
+	if level == token.DefaultAnonymity {
+		return nil
+	}
+	db, err := ttxdb.GetByTMSId(sp, w.TMS().ID())
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get ttxdb for [%s]", w.TMS().ID())
+	}
+	if err := db.AddRecipientAnonymityLevel(id, w.ID(), level); err != nil {
+		return errors.WithMessagef(err, "failed to record anonymity level of recipient identity in wallet [%s]", w.ID())
+	}
+	return nil
+}
+
 type ExchangeRecipientIdentitiesView struct {
 	TMSID  token.TMSID
 	Wallet string
@@ -392,6 +447,9 @@ func (f *ExchangeRecipientIdentitiesView) Call(context view.Context) (interface{
 		if err := ts.WalletManager().RegisterRecipientIdentity(recipientData); err != nil {
 			return nil, err
 		}
+		if err := ts.WalletManager().BindRecipientIdentity(recipientData.Identity, string(f.Other)); err != nil {
+			return nil, errors.Wrapf(err, "failed to bind recipient identity to wallet [%s]", f.Other)
+		}
 
 		// Update the Endpoint Resolver
 		if logger.IsEnabledFor(zapcore.DebugLevel) {
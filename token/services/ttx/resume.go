@@ -0,0 +1,303 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/pkg/errors"
+)
+
+const (
+	flowStatePrefix = "ttx.flow"
+	initiatorRole   = "initiator"
+	responderRole   = "responder"
+
+	// FlowStateTTL is how long the state of an interrupted flow is kept to resume it.
+	// Expired states are ignored, and deleted by DeleteExpiredFlowStates.
+	FlowStateTTL = 24 * time.Hour
+	// flowSweepInterval is how often the flows sweep the expired states
+	flowSweepInterval = time.Hour
+)
+
+// ErrNoFlowState is returned when a flow is resumed but no state has been persisted for its exchange
+var ErrNoFlowState = errors.New("no flow state persisted for the exchange")
+
+// FlowSignature is a signature persisted by an endorsement flow, with the hash of the message it signs
+type FlowSignature struct {
+	MessageHash []byte
+	Sigma       []byte
+}
+
+// FlowState is the state of an endorsement flow persisted to survive the loss of the underlying sessions.
+// The exchange id is the id of the transaction being endorsed.
+type FlowState struct {
+	ExchangeID string
+	// Signatures are the signatures on the token request, indexed by the unique id of the signer
+	Signatures map[string]*FlowSignature
+	// UpdatedAt is the last time the state was persisted
+	UpdatedAt time.Time
+}
+
+// Signature returns the signature of the passed signer on the passed message, if persisted.
+// A signature on another message, for instance of a token request modified since, is not returned.
+func (s *FlowState) Signature(signer view.Identity, message []byte) ([]byte, bool) {
+	sig, ok := s.Signatures[signer.UniqueID()]
+	if !ok || sig == nil || !bytes.Equal(sig.MessageHash, messageHash(message)) {
+		return nil, false
+	}
+	return sig.Sigma, true
+}
+
+func (s *FlowState) expired(now time.Time) bool {
+	return now.Sub(s.UpdatedAt) > FlowStateTTL
+}
+
+func messageHash(message []byte) []byte {
+	h := sha256.Sum256(message)
+	return h[:]
+}
+
+var (
+	// lastFlowSweep is the last time the flows swept the expired states
+	lastFlowSweep     time.Time
+	lastFlowSweepLock sync.Mutex
+)
+
+// flowStore persists the flow states in the KVS, separating the states of the initiator from those of the responders
+type flowStore struct {
+	kvs  *kvs.KVS
+	role string
+}
+
+func getFlowStore(context token.ServiceProvider, role string) (*flowStore, error) {
+	kvss, err := context.GetService(&kvs.KVS{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get KVS from context")
+	}
+	store := &flowStore{kvs: kvss.(*kvs.KVS), role: role}
+	store.sweep()
+	return store, nil
+}
+
+func (s *flowStore) key(exchangeID string) (string, error) {
+	k, err := kvs.CreateCompositeKey(flowStatePrefix, []string{s.role, exchangeID})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to generate key for flow state [%s]", exchangeID)
+	}
+	return k, nil
+}
+
+// Exists returns true if a state, not expired, has been persisted for the passed exchange
+func (s *flowStore) Exists(exchangeID string) bool {
+	state, err := s.Load(exchangeID)
+	return err == nil && !state.UpdatedAt.IsZero()
+}
+
+// Load returns the state persisted for the passed exchange, or an empty state if none exists or it expired
+func (s *flowStore) Load(exchangeID string) (*FlowState, error) {
+	k, err := s.key(exchangeID)
+	if err != nil {
+		return nil, err
+	}
+	empty := &FlowState{ExchangeID: exchangeID, Signatures: map[string]*FlowSignature{}}
+	if !s.kvs.Exists(k) {
+		return empty, nil
+	}
+	state := &FlowState{}
+	if err := s.kvs.Get(k, state); err != nil {
+		return nil, errors.Wrapf(err, "failed to load flow state [%s]", exchangeID)
+	}
+	if state.expired(time.Now()) {
+		logger.Debugf("flow state of [%s] expired, ignore it", exchangeID)
+		return empty, nil
+	}
+	if state.Signatures == nil {
+		state.Signatures = map[string]*FlowSignature{}
+	}
+	return state, nil
+}
+
+// AddSignature persists the signature of the passed signer on the passed message for the passed exchange
+func (s *flowStore) AddSignature(exchangeID string, signer view.Identity, message []byte, sigma []byte) error {
+	state, err := s.Load(exchangeID)
+	if err != nil {
+		return err
+	}
+	state.Signatures[signer.UniqueID()] = &FlowSignature{MessageHash: messageHash(message), Sigma: sigma}
+	state.UpdatedAt = time.Now()
+	k, err := s.key(exchangeID)
+	if err != nil {
+		return err
+	}
+	if err := s.kvs.Put(k, state); err != nil {
+		return errors.Wrapf(err, "failed to store flow state [%s]", exchangeID)
+	}
+	return nil
+}
+
+// Delete removes the state of the passed exchange, once the flow is completed
+func (s *flowStore) Delete(exchangeID string) error {
+	k, err := s.key(exchangeID)
+	if err != nil {
+		return err
+	}
+	if !s.kvs.Exists(k) {
+		return nil
+	}
+	if err := s.kvs.Delete(k); err != nil {
+		return errors.Wrapf(err, "failed to delete flow state [%s]", exchangeID)
+	}
+	return nil
+}
+
+// DeleteExpired removes the states persisted before the passed time, and returns how many were removed
+func (s *flowStore) DeleteExpired(before time.Time) (int, error) {
+	it, err := s.kvs.GetByPartialCompositeID(flowStatePrefix, []string{s.role})
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to list [%s] flow states", s.role)
+	}
+	var expired []string
+	for it.HasNext() {
+		state := &FlowState{}
+		k, err := it.Next(state)
+		if err != nil {
+			logger.Warnf("failed to read flow state [%s], skip it: [%s]", k, err)
+			continue
+		}
+		if state.UpdatedAt.Before(before) {
+			expired = append(expired, k)
+		}
+	}
+	if err := it.Close(); err != nil {
+		return 0, errors.Wrapf(err, "failed to close flow states iterator")
+	}
+	for i, k := range expired {
+		if err := s.kvs.Delete(k); err != nil {
+			return i, errors.Wrapf(err, "failed to delete flow state [%s]", k)
+		}
+	}
+	return len(expired), nil
+}
+
+// sweep deletes the expired states of all the roles, if not done in the last flowSweepInterval.
+// The states of the flows that failed and were not resumed are removed this way.
+func (s *flowStore) sweep() {
+	lastFlowSweepLock.Lock()
+	if time.Since(lastFlowSweep) < flowSweepInterval {
+		lastFlowSweepLock.Unlock()
+		return
+	}
+	lastFlowSweep = time.Now()
+	lastFlowSweepLock.Unlock()
+
+	if _, err := deleteExpiredFlowStates(s.kvs, time.Now().Add(-FlowStateTTL)); err != nil {
+		logger.Warnf("failed to sweep expired flow states: [%s]", err)
+	}
+}
+
+// DeleteExpiredFlowStates removes the states of the flows, initiator and responder side, older than FlowStateTTL.
+// It returns the number of states removed.
+// The flows call it at most once every hour, applications can call it to sweep at other times.
+func DeleteExpiredFlowStates(context token.ServiceProvider) (int, error) {
+	kvss, err := context.GetService(&kvs.KVS{})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get KVS from context")
+	}
+	return deleteExpiredFlowStates(kvss.(*kvs.KVS), time.Now().Add(-FlowStateTTL))
+}
+
+func deleteExpiredFlowStates(kvss *kvs.KVS, before time.Time) (int, error) {
+	deleted := 0
+	for _, role := range []string{initiatorRole, responderRole} {
+		n, err := (&flowStore{kvs: kvss, role: role}).DeleteExpired(before)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	if deleted != 0 {
+		logger.Infof("deleted [%d] expired flow states", deleted)
+	}
+	return deleted, nil
+}
+
+// GetInitiatorFlowState returns the state persisted by the initiator of the endorsement flow of the passed transaction.
+// It returns ErrNoFlowState if there is none.
+func GetInitiatorFlowState(context token.ServiceProvider, txID string) (*FlowState, error) {
+	return getFlowState(context, initiatorRole, txID)
+}
+
+// GetResponderFlowState returns the state persisted by a responder of the endorsement flow of the passed transaction.
+// It returns ErrNoFlowState if there is none.
+func GetResponderFlowState(context token.ServiceProvider, txID string) (*FlowState, error) {
+	return getFlowState(context, responderRole, txID)
+}
+
+func getFlowState(context token.ServiceProvider, role string, txID string) (*FlowState, error) {
+	store, err := getFlowStore(context, role)
+	if err != nil {
+		return nil, err
+	}
+	if !store.Exists(txID) {
+		return nil, errors.Wrapf(ErrNoFlowState, "[%s] flow of [%s]", role, txID)
+	}
+	return store.Load(txID)
+}
+
+// ResumeCollectEndorsementsView resumes an endorsement flow interrupted by the loss of a session.
+// The signatures already collected are not requested again, the remaining steps are executed as usual.
+type ResumeCollectEndorsementsView struct {
+	*CollectEndorsementsView
+}
+
+// NewResumeCollectEndorsementsView returns a view resuming the endorsement flow of the passed transaction.
+// The passed transaction must be the one whose flow got interrupted.
+func NewResumeCollectEndorsementsView(tx *Transaction, opts ...EndorsementsOpt) *ResumeCollectEndorsementsView {
+	return &ResumeCollectEndorsementsView{CollectEndorsementsView: NewCollectEndorsementsView(tx, opts...)}
+}
+
+func (r *ResumeCollectEndorsementsView) Call(context view.Context) (interface{}, error) {
+	store, err := getFlowStore(context, initiatorRole)
+	if err != nil {
+		return nil, err
+	}
+	if !store.Exists(r.tx.ID()) {
+		return nil, errors.Wrapf(ErrNoFlowState, "cannot resume endorsement of [%s]", r.tx.ID())
+	}
+	logger.Infof("resuming endorsement of [%s]", r.tx.ID())
+	return r.CollectEndorsementsView.Call(context)
+}
+
+// ResumeEndorseView is the responder counterpart of ResumeCollectEndorsementsView.
+// The signatures already produced for the transaction are sent back again, instead of generating new ones.
+type ResumeEndorseView struct {
+	*EndorseView
+}
+
+// NewResumeEndorseView returns a view resuming the responder side of the endorsement flow of the passed transaction.
+func NewResumeEndorseView(tx *Transaction) *ResumeEndorseView {
+	return &ResumeEndorseView{EndorseView: NewEndorseView(tx)}
+}
+
+func (r *ResumeEndorseView) Call(context view.Context) (interface{}, error) {
+	store, err := getFlowStore(context, responderRole)
+	if err != nil {
+		return nil, err
+	}
+	if !store.Exists(r.tx.ID()) {
+		return nil, errors.Wrapf(ErrNoFlowState, "cannot resume endorsement of [%s]", r.tx.ID())
+	}
+	logger.Infof("resuming endorsement of [%s] as responder", r.tx.ID())
+	return r.EndorseView.Call(context)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	mem "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/db/driver/memory"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs/mock"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type kvsProvider struct{ kvs *kvs.KVS }
+
+func (p *kvsProvider) GetService(v interface{}) (interface{}, error) {
+	if reflect.TypeOf(v) != reflect.TypeOf(&kvs.KVS{}) {
+		return nil, errors.Errorf("no service [%T]", v)
+	}
+	return p.kvs, nil
+}
+
+func newKVSProvider(t *testing.T) *kvsProvider {
+	backend, err := kvs.NewWithConfig(&mem.Driver{}, "_default", &mock.ConfigProvider{})
+	assert.NoError(t, err)
+	return &kvsProvider{kvs: backend}
+}
+
+func TestFlowStore(t *testing.T) {
+	sp := newKVSProvider(t)
+	initiator, err := getFlowStore(sp, initiatorRole)
+	assert.NoError(t, err)
+	responder, err := getFlowStore(sp, responderRole)
+	assert.NoError(t, err)
+	alice, bob := view.Identity("alice"), view.Identity("bob")
+
+	_, err = GetInitiatorFlowState(sp, "tx1")
+	assert.True(t, errors.Is(err, ErrNoFlowState))
+
+	// the signatures are returned for the message they sign only
+	assert.NoError(t, initiator.AddSignature("tx1", alice, []byte("message"), []byte("sigma-alice")))
+	assert.NoError(t, initiator.AddSignature("tx1", bob, []byte("message"), []byte("sigma-bob")))
+	state, err := GetInitiatorFlowState(sp, "tx1")
+	assert.NoError(t, err)
+	sigma, ok := state.Signature(alice, []byte("message"))
+	assert.True(t, ok)
+	assert.Equal(t, []byte("sigma-alice"), sigma)
+	_, ok = state.Signature(alice, []byte("another message"))
+	assert.False(t, ok)
+	_, ok = state.Signature(view.Identity("charlie"), []byte("message"))
+	assert.False(t, ok)
+
+	// the roles are separated
+	_, err = GetResponderFlowState(sp, "tx1")
+	assert.True(t, errors.Is(err, ErrNoFlowState))
+	assert.NoError(t, responder.AddSignature("tx1", alice, []byte("message"), []byte("sigma-alice")))
+	assert.True(t, responder.Exists("tx1"))
+
+	assert.NoError(t, initiator.Delete("tx1"))
+	assert.NoError(t, initiator.Delete("tx1"))
+	assert.False(t, initiator.Exists("tx1"))
+	assert.True(t, responder.Exists("tx1"))
+}
+
+func TestFlowStoreExpiry(t *testing.T) {
+	sp := newKVSProvider(t)
+	initiator, err := getFlowStore(sp, initiatorRole)
+	assert.NoError(t, err)
+	responder, err := getFlowStore(sp, responderRole)
+	assert.NoError(t, err)
+	alice := view.Identity("alice")
+
+	// the state of a flow that failed and was never resumed
+	assert.NoError(t, initiator.AddSignature("tx1", alice, []byte("message"), []byte("sigma")))
+	assert.NoError(t, responder.AddSignature("tx1", alice, []byte("message"), []byte("sigma")))
+	assert.NoError(t, initiator.AddSignature("tx2", alice, []byte("message"), []byte("sigma")))
+	k, err := initiator.key("tx1")
+	assert.NoError(t, err)
+	state, err := initiator.Load("tx1")
+	assert.NoError(t, err)
+	state.UpdatedAt = time.Now().Add(-FlowStateTTL - time.Minute)
+	assert.NoError(t, sp.kvs.Put(k, state))
+	k, err = responder.key("tx1")
+	assert.NoError(t, err)
+	assert.NoError(t, sp.kvs.Put(k, state))
+
+	// expired states are ignored, and swept
+	assert.False(t, initiator.Exists("tx1"))
+	state, err = initiator.Load("tx1")
+	assert.NoError(t, err)
+	assert.Empty(t, state.Signatures)
+	n, err := DeleteExpiredFlowStates(sp)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	k, err = initiator.key("tx1")
+	assert.NoError(t, err)
+	assert.False(t, sp.kvs.Exists(k))
+	assert.True(t, initiator.Exists("tx2"))
+	n, err = DeleteExpiredFlowStates(sp)
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"context"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tokendb"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// IssueSerials appends a new Issue operation to the TokenRequest inside this transaction that issues
+// a single token carrying the passed serials to the passed receiver.
+// Use SerialsIssued to check before that the serials were not issued already.
+func (t *Transaction) IssueSerials(wallet *token.IssuerWallet, receiver view.Identity, serials token2.SerialRange, opts ...token.IssueOption) error {
+	if err := serials.Validate(); err != nil {
+		return errors.WithMessagef(err, "invalid serials")
+	}
+	return t.Issue(wallet, receiver, serials.Type(), serials.Count(), opts...)
+}
+
+// TransferSerials appends a new Transfer operation to the TokenRequest inside this transaction that transfers
+// the passed serials to the passed recipient, spending the serial tokens with the passed ids.
+// The ids of the tokens of a wallet carrying the serials are returned by SerialTokenIDs.
+func (t *Transaction) TransferSerials(wallet *token.OwnerWallet, ids []*token2.ID, serials token2.SerialRange, recipient view.Identity, opts ...token.TransferOption) error {
+	_, err := t.TokenRequest.TransferSerials(t.Context, wallet, ids, serials, recipient, opts...)
+	return err
+}
+
+// SerialTokenIDs returns the ids of the unspent tokens of the passed wallet that carry serials of the passed range
+func SerialTokenIDs(sp token.ServiceProvider, wallet *token.OwnerWallet, serials token2.SerialRange) ([]*token2.ID, error) {
+	tokenDB, err := tokendb.GetByTMSId(sp, wallet.TMS().ID())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get token db for [%s]", wallet.TMS().ID())
+	}
+	it, err := tokenDB.UnspentTokensIteratorBySerialRange(context.TODO(), wallet.ID(), serials)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to query tokens with serials [%s]", serials)
+	}
+	defer it.Close()
+	var ids []*token2.ID
+	for {
+		tok, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to iterate over tokens with serials [%s]", serials)
+		}
+		if tok == nil {
+			return ids, nil
+		}
+		ids = append(ids, tok.Id)
+	}
+}
+
+// SerialsIssued returns true if a token carrying serials of the passed range is in the token db of the passed TMS, spent or not.
+// An issuer calls it before issuing the serials, since the validators cannot check the serials issued by other transactions.
+func SerialsIssued(sp token.ServiceProvider, tmsID token.TMSID, serials token2.SerialRange) (bool, error) {
+	tokenDB, err := tokendb.GetByTMSId(sp, tmsID)
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed to get token db for [%s]", tmsID)
+	}
+	return tokenDB.ExistsSerials(context.TODO(), serials)
+}
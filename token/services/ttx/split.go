@@ -0,0 +1,281 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"sort"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/features"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// SplitStepKind is the kind of transaction assembled by the SplitTransferView
+type SplitStepKind int
+
+const (
+	// ConsolidationStep merges tokens of the sender wallet into a single token owned by the same wallet
+	ConsolidationStep SplitStepKind = iota
+	// PaymentStep pays a batch of recipients
+	PaymentStep
+)
+
+// SplitProgress reports the progress of a SplitTransferView
+type SplitProgress struct {
+	// Kind is the kind of the transaction just committed
+	Kind SplitStepKind
+	// TxID is the id of the transaction just committed
+	TxID string
+	// PaidRecipients is the number of recipients paid so far
+	PaidRecipients int
+	// TotalRecipients is the number of recipients to pay
+	TotalRecipients int
+}
+
+// SplitProgressListener is notified every time a transaction assembled by the SplitTransferView is committed
+type SplitProgressListener interface {
+	OnProgress(progress *SplitProgress)
+}
+
+// SplitTransferView pays the passed recipients with as many chained transactions as needed
+// to respect the request limits of the token management service (see token.ManagementService.RequestLimits).
+// Recipients are paid in batches such that each transaction, change included, does not exceed the maximum number of outputs.
+// When a batch requires more inputs than allowed, the view first consolidates the smallest tokens of the sender wallet
+// by transferring them to the wallet itself.
+// Each transaction is committed before the next one is assembled.
+// The view returns the ids of the committed transactions, in order.
+type SplitTransferView struct {
+	wallet    *token.OwnerWallet
+	tokenType string
+	payouts   []Payout
+	txOpts    []TxOption
+	listener  SplitProgressListener
+
+	// listUnspent, consolidateTokens, and payBatch access the wallet and the ledger
+	listUnspent       func() ([]*token2.UnspentToken, error)
+	consolidateTokens func(context view.Context, inputs []*token2.UnspentToken, precision uint64) (string, error)
+	payBatch          func(context view.Context, batch []Payout, inputs []*token2.UnspentToken) (string, error)
+}
+
+// NewSplitTransferView returns a new SplitTransferView that pays the passed payouts from the passed wallet.
+// The passed options are used to create each transaction.
+func NewSplitTransferView(wallet *token.OwnerWallet, tokenType string, payouts []Payout, opts ...TxOption) *SplitTransferView {
+	s := &SplitTransferView{
+		wallet:    wallet,
+		tokenType: tokenType,
+		payouts:   payouts,
+		txOpts:    opts,
+	}
+	s.listUnspent = s.unspentTokens
+	s.consolidateTokens = s.consolidate
+	s.payBatch = s.pay
+	return s
+}
+
+// WithProgressListener sets the listener to notify about the progress of the view
+func (s *SplitTransferView) WithProgressListener(listener SplitProgressListener) *SplitTransferView {
+	s.listener = listener
+	return s
+}
+
+func (s *SplitTransferView) Call(context view.Context) (interface{}, error) {
+	if len(s.payouts) == 0 {
+		return nil, errors.New("no payouts specified")
+	}
+	tms := s.wallet.TMS()
+	limits, err := tms.RequestLimits()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting request limits")
+	}
+	if limits.MaxInputs == 1 {
+		return nil, errors.New("cannot consolidate tokens with at most one input per request")
+	}
+	if limits.MaxOutputs == 1 {
+		return nil, errors.New("cannot pay with change with at most one output per request")
+	}
+	precision := tms.PublicParametersManager().PublicParameters().Precision()
+	flags, err := features.GetService(context)
+	if err != nil {
+		return nil, err
+	}
+	return s.split(context, tms.ID(), limits, precision, flags.Enabled(tms.ID(), features.Consolidation))
+}
+
+// split pays the recipients in batches, consolidating the tokens of the wallet when needed.
+// Each batch is paid with the tokens unspent after the previous one, the change included.
+func (s *SplitTransferView) split(context view.Context, tmsID token.TMSID, limits *token.RequestLimits, precision uint64, consolidation bool) ([]string, error) {
+	var txIDs []string
+	paid := 0
+	for _, batch := range batchPayouts(s.payouts, limits.MaxOutputs) {
+		need := token2.NewZeroQuantity(precision)
+		for _, payout := range batch {
+			q, err := token2.UInt64ToQuantity(payout.Amount, precision)
+			if err != nil {
+				return txIDs, errors.WithMessagef(err, "invalid amount [%d]", payout.Amount)
+			}
+			need = need.Add(q)
+		}
+
+		// consolidate until the batch can be paid with the allowed number of inputs
+		var inputs []*token2.UnspentToken
+		for {
+			unspent, err := s.listUnspent()
+			if err != nil {
+				return txIDs, errors.WithMessagef(err, "failed listing unspent tokens")
+			}
+			inputs, err = selectLargestFirst(unspent, need, precision)
+			if err != nil {
+				return txIDs, err
+			}
+			if limits.MaxInputs == 0 || uint64(len(inputs)) <= limits.MaxInputs {
+				break
+			}
+			if !consolidation {
+				return txIDs, errors.Errorf("paying recipients [%d:%d] needs [%d] inputs, more than the allowed [%d], and consolidation is disabled for [%s]",
+					paid, paid+len(batch), len(inputs), limits.MaxInputs, tmsID)
+			}
+			// merge the smallest selected tokens
+			txID, err := s.consolidateTokens(context, inputs[uint64(len(inputs))-limits.MaxInputs:], precision)
+			if err != nil {
+				return txIDs, errors.WithMessagef(err, "failed consolidating tokens")
+			}
+			txIDs = append(txIDs, txID)
+			s.notify(ConsolidationStep, txID, paid)
+		}
+
+		txID, err := s.payBatch(context, batch, inputs)
+		if err != nil {
+			return txIDs, errors.WithMessagef(err, "failed paying recipients [%d:%d]", paid, paid+len(batch))
+		}
+		txIDs = append(txIDs, txID)
+		paid += len(batch)
+		s.notify(PaymentStep, txID, paid)
+	}
+	return txIDs, nil
+}
+
+func (s *SplitTransferView) unspentTokens() ([]*token2.UnspentToken, error) {
+	unspent, err := s.wallet.ListUnspentTokens(token.WithType(s.tokenType))
+	if err != nil {
+		return nil, err
+	}
+	return unspent.Tokens, nil
+}
+
+func (s *SplitTransferView) consolidate(context view.Context, inputs []*token2.UnspentToken, precision uint64) (string, error) {
+	sum := token2.NewZeroQuantity(precision)
+	ids := make([]*token2.ID, len(inputs))
+	for i, input := range inputs {
+		q, err := token2.ToQuantity(input.Quantity, precision)
+		if err != nil {
+			return "", errors.WithMessagef(err, "invalid quantity of token [%s]", input.Id)
+		}
+		sum = sum.Add(q)
+		ids[i] = input.Id
+	}
+	if sum.ToBigInt().BitLen() > 64 {
+		return "", errors.Errorf("consolidated quantity [%s] does not fit 64 bits", sum.Decimal())
+	}
+	recipient, err := s.wallet.GetRecipientIdentity()
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed getting recipient identity")
+	}
+	return s.run(context, func(tx *Transaction) error {
+		return tx.Transfer(s.wallet, s.tokenType, []uint64{sum.ToBigInt().Uint64()}, []view.Identity{recipient}, token.WithTokenIDs(ids...))
+	})
+}
+
+func (s *SplitTransferView) pay(context view.Context, batch []Payout, inputs []*token2.UnspentToken) (string, error) {
+	ids := make([]*token2.ID, len(inputs))
+	for i, input := range inputs {
+		ids[i] = input.Id
+	}
+	return s.run(context, func(tx *Transaction) error {
+		return tx.Payouts(s.wallet, s.tokenType, batch, token.WithTokenIDs(ids...))
+	})
+}
+
+// run assembles a transaction with the passed function, collects the endorsements, and waits for its finality
+func (s *SplitTransferView) run(context view.Context, assemble func(tx *Transaction) error) (string, error) {
+	tx, err := NewAnonymousTransaction(context, s.txOpts...)
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed creating transaction")
+	}
+	if err := assemble(tx); err != nil {
+		return "", errors.WithMessagef(err, "failed assembling transaction [%s]", tx.ID())
+	}
+	if _, err := context.RunView(NewCollectEndorsementsView(tx)); err != nil {
+		return "", errors.WithMessagef(err, "failed collecting endorsements for [%s]", tx.ID())
+	}
+	if _, err := context.RunView(NewOrderingAndFinalityView(tx)); err != nil {
+		return "", errors.WithMessagef(err, "failed committing [%s]", tx.ID())
+	}
+	return tx.ID(), nil
+}
+
+func (s *SplitTransferView) notify(kind SplitStepKind, txID string, paid int) {
+	logger.Debugf("split transfer: committed [%d] transaction [%s], paid [%d/%d]", kind, txID, paid, len(s.payouts))
+	if s.listener == nil {
+		return
+	}
+	s.listener.OnProgress(&SplitProgress{
+		Kind:            kind,
+		TxID:            txID,
+		PaidRecipients:  paid,
+		TotalRecipients: len(s.payouts),
+	})
+}
+
+// batchPayouts splits the payouts in batches leaving room for the change output
+func batchPayouts(payouts []Payout, maxOutputs uint64) [][]Payout {
+	if maxOutputs == 0 {
+		return [][]Payout{payouts}
+	}
+	size := int(maxOutputs - 1)
+	var batches [][]Payout
+	for start := 0; start < len(payouts); start += size {
+		end := min(start+size, len(payouts))
+		batches = append(batches, payouts[start:end])
+	}
+	return batches
+}
+
+// selectLargestFirst selects the largest tokens until their sum covers the passed amount.
+// The selected tokens are returned from the largest to the smallest.
+func selectLargestFirst(tokens []*token2.UnspentToken, need token2.Quantity, precision uint64) ([]*token2.UnspentToken, error) {
+	type candidate struct {
+		token *token2.UnspentToken
+		q     token2.Quantity
+	}
+	candidates := make([]candidate, len(tokens))
+	for i, t := range tokens {
+		q, err := token2.ToQuantity(t.Quantity, precision)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid quantity of token [%s]", t.Id)
+		}
+		candidates[i] = candidate{token: t, q: q}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q.Cmp(candidates[j].q) > 0
+	})
+
+	sum := token2.NewZeroQuantity(precision)
+	var selected []*token2.UnspentToken
+	for _, c := range candidates {
+		if sum.Cmp(need) >= 0 {
+			break
+		}
+		selected = append(selected, c.token)
+		sum = sum.Add(c.q)
+	}
+	if sum.Cmp(need) < 0 {
+		return nil, errors.Wrapf(token.SelectorInsufficientFunds, "need [%s], available [%s]", need.Decimal(), sum.Decimal())
+	}
+	return selected, nil
+}
@@ -0,0 +1,207 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchPayouts(t *testing.T) {
+	payouts := func(n int) []Payout {
+		res := make([]Payout, n)
+		for i := range res {
+			res[i] = Payout{Amount: uint64(i + 1)}
+		}
+		return res
+	}
+	sizes := func(batches [][]Payout) []int {
+		var res []int
+		for _, batch := range batches {
+			res = append(res, len(batch))
+		}
+		return res
+	}
+
+	// no limit, a single batch
+	assert.Equal(t, []int{5}, sizes(batchPayouts(payouts(5), 0)))
+	// one output is left for the change
+	assert.Equal(t, []int{2, 2, 1}, sizes(batchPayouts(payouts(5), 3)))
+	assert.Equal(t, []int{2, 2}, sizes(batchPayouts(payouts(4), 3)))
+	assert.Equal(t, []int{1, 1, 1}, sizes(batchPayouts(payouts(3), 2)))
+	assert.Equal(t, []int{3}, sizes(batchPayouts(payouts(3), 10)))
+
+	// the batches keep the order of the payouts
+	batches := batchPayouts(payouts(5), 3)
+	assert.Equal(t, []Payout{{Amount: 1}, {Amount: 2}}, batches[0])
+	assert.Equal(t, []Payout{{Amount: 5}}, batches[2])
+}
+
+func TestSelectLargestFirst(t *testing.T) {
+	tokens := []*token2.UnspentToken{
+		{Id: &token2.ID{TxId: "tx0", Index: 0}, Quantity: "5"},
+		{Id: &token2.ID{TxId: "tx0", Index: 1}, Quantity: "20"},
+		{Id: &token2.ID{TxId: "tx0", Index: 2}, Quantity: "0x0a"},
+	}
+
+	selected, err := selectLargestFirst(tokens, token2.NewQuantityFromUInt64(25), 64)
+	assert.NoError(t, err)
+	assert.Equal(t, []*token2.UnspentToken{tokens[1], tokens[2]}, selected)
+
+	selected, err = selectLargestFirst(tokens, token2.NewQuantityFromUInt64(35), 64)
+	assert.NoError(t, err)
+	assert.Equal(t, []*token2.UnspentToken{tokens[1], tokens[2], tokens[0]}, selected)
+
+	_, err = selectLargestFirst(tokens, token2.NewQuantityFromUInt64(36), 64)
+	assert.True(t, errors.Is(err, token.SelectorInsufficientFunds))
+	assert.ErrorContains(t, err, "need [36], available [35]")
+
+	_, err = selectLargestFirst([]*token2.UnspentToken{{Id: &token2.ID{TxId: "tx0"}, Quantity: "ten"}}, token2.NewQuantityFromUInt64(1), 64)
+	assert.ErrorContains(t, err, "invalid quantity of token")
+}
+
+// splitLedger is a wallet with a single token type, whose transactions commit immediately
+type splitLedger struct {
+	unspent        []*token2.UnspentToken
+	txs            int
+	consolidations [][]*token2.UnspentToken
+	payments       [][]*token2.UnspentToken
+}
+
+func (l *splitLedger) spend(inputs []*token2.UnspentToken) uint64 {
+	var sum uint64
+	for _, input := range inputs {
+		for i, u := range l.unspent {
+			if u.Id.Equal(*input.Id) {
+				l.unspent = append(l.unspent[:i], l.unspent[i+1:]...)
+				break
+			}
+		}
+		q, _ := strconv.ParseUint(input.Quantity, 0, 64)
+		sum += q
+	}
+	return sum
+}
+
+func (l *splitLedger) commit(outputs int, change uint64) string {
+	l.txs++
+	txID := fmt.Sprintf("tx%d", l.txs)
+	if change > 0 {
+		l.unspent = append(l.unspent, &token2.UnspentToken{Id: &token2.ID{TxId: txID, Index: uint64(outputs)}, Quantity: strconv.FormatUint(change, 10)})
+	}
+	return txID
+}
+
+func (l *splitLedger) view(payouts []Payout) *SplitTransferView {
+	s := &SplitTransferView{payouts: payouts}
+	s.listUnspent = func() ([]*token2.UnspentToken, error) {
+		return append([]*token2.UnspentToken{}, l.unspent...), nil
+	}
+	s.consolidateTokens = func(_ view.Context, inputs []*token2.UnspentToken, _ uint64) (string, error) {
+		l.consolidations = append(l.consolidations, inputs)
+		return l.commit(0, l.spend(inputs)), nil
+	}
+	s.payBatch = func(_ view.Context, batch []Payout, inputs []*token2.UnspentToken) (string, error) {
+		l.payments = append(l.payments, inputs)
+		sum := l.spend(inputs)
+		for _, payout := range batch {
+			sum -= payout.Amount
+		}
+		return l.commit(len(batch), sum), nil
+	}
+	return s
+}
+
+type splitProgressRecorder struct {
+	progress []SplitProgress
+}
+
+func (r *splitProgressRecorder) OnProgress(progress *SplitProgress) {
+	r.progress = append(r.progress, *progress)
+}
+
+func TestSplitTransferChainsChange(t *testing.T) {
+	ledger := &splitLedger{unspent: []*token2.UnspentToken{{Id: &token2.ID{TxId: "tx0"}, Quantity: "100"}}}
+	payouts := []Payout{{Amount: 10}, {Amount: 10}, {Amount: 10}, {Amount: 10}, {Amount: 10}}
+	recorder := &splitProgressRecorder{}
+	s := ledger.view(payouts).WithProgressListener(recorder)
+
+	txIDs, err := s.split(nil, token.TMSID{}, &token.RequestLimits{MaxOutputs: 3}, 64, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx1", "tx2", "tx3"}, txIDs)
+	// each batch spends the change of the previous one
+	assert.Equal(t, &token2.ID{TxId: "tx0"}, ledger.payments[0][0].Id)
+	assert.Equal(t, &token2.ID{TxId: "tx1", Index: 2}, ledger.payments[1][0].Id)
+	assert.Equal(t, "80", ledger.payments[1][0].Quantity)
+	assert.Equal(t, &token2.ID{TxId: "tx2", Index: 2}, ledger.payments[2][0].Id)
+	assert.Equal(t, []*token2.UnspentToken{{Id: &token2.ID{TxId: "tx3", Index: 1}, Quantity: "50"}}, ledger.unspent)
+	assert.Empty(t, ledger.consolidations)
+
+	assert.Equal(t, []SplitProgress{
+		{Kind: PaymentStep, TxID: "tx1", PaidRecipients: 2, TotalRecipients: 5},
+		{Kind: PaymentStep, TxID: "tx2", PaidRecipients: 4, TotalRecipients: 5},
+		{Kind: PaymentStep, TxID: "tx3", PaidRecipients: 5, TotalRecipients: 5},
+	}, recorder.progress)
+}
+
+func TestSplitTransferConsolidates(t *testing.T) {
+	newLedger := func() *splitLedger {
+		ledger := &splitLedger{}
+		for i := 0; i < 5; i++ {
+			ledger.unspent = append(ledger.unspent, &token2.UnspentToken{Id: &token2.ID{TxId: "tx0", Index: uint64(i)}, Quantity: "2"})
+		}
+		return ledger
+	}
+	limits := &token.RequestLimits{MaxInputs: 2, MaxOutputs: 3}
+
+	// paying 9 needs 5 inputs, the smallest are merged until 2 inputs suffice
+	ledger := newLedger()
+	recorder := &splitProgressRecorder{}
+	txIDs, err := ledger.view([]Payout{{Amount: 9}}).WithProgressListener(recorder).split(nil, token.TMSID{}, limits, 64, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx1", "tx2", "tx3", "tx4"}, txIDs)
+	assert.Len(t, ledger.consolidations, 3)
+	for _, inputs := range append(ledger.consolidations, ledger.payments...) {
+		assert.LessOrEqual(t, len(inputs), 2)
+	}
+	assert.Equal(t, []SplitStepKind{ConsolidationStep, ConsolidationStep, ConsolidationStep, PaymentStep},
+		[]SplitStepKind{recorder.progress[0].Kind, recorder.progress[1].Kind, recorder.progress[2].Kind, recorder.progress[3].Kind})
+	assert.Equal(t, []*token2.UnspentToken{{Id: &token2.ID{TxId: "tx4", Index: 1}, Quantity: "1"}}, ledger.unspent)
+
+	// without consolidation, the view stops before committing anything
+	ledger = newLedger()
+	txIDs, err = ledger.view([]Payout{{Amount: 9}}).split(nil, token.TMSID{Network: "n1"}, limits, 64, false)
+	assert.ErrorContains(t, err, "paying recipients [0:1] needs [5] inputs, more than the allowed [2], and consolidation is disabled")
+	assert.Empty(t, txIDs)
+	assert.Equal(t, 0, ledger.txs)
+}
+
+func TestSplitTransferInsufficientFunds(t *testing.T) {
+	ledger := &splitLedger{unspent: []*token2.UnspentToken{{Id: &token2.ID{TxId: "tx0"}, Quantity: "25"}}}
+	payouts := []Payout{{Amount: 10}, {Amount: 10}, {Amount: 10}}
+
+	// the batches paid before the funds run out stay committed
+	txIDs, err := ledger.view(payouts).split(nil, token.TMSID{}, &token.RequestLimits{MaxOutputs: 2}, 64, true)
+	assert.True(t, errors.Is(err, token.SelectorInsufficientFunds))
+	assert.ErrorContains(t, err, "need [10], available [5]")
+	assert.Equal(t, []string{"tx1", "tx2"}, txIDs)
+	assert.Equal(t, []*token2.UnspentToken{{Id: &token2.ID{TxId: "tx2", Index: 1}, Quantity: "5"}}, ledger.unspent)
+
+	// the whole batch must be covered
+	ledger = &splitLedger{unspent: []*token2.UnspentToken{{Id: &token2.ID{TxId: "tx0"}, Quantity: "25"}}}
+	txIDs, err = ledger.view(payouts).split(nil, token.TMSID{}, &token.RequestLimits{}, 64, true)
+	assert.True(t, errors.Is(err, token.SelectorInsufficientFunds))
+	assert.Empty(t, txIDs)
+	assert.Equal(t, 0, ledger.txs)
+}
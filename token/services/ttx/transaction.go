@@ -11,7 +11,10 @@ import (
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/compression"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/freeze"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
 	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
 )
@@ -40,6 +43,13 @@ type Transaction struct {
 	NetworkProvider GetNetworkFunc
 	Opts            *TxOptions
 	Context         context.Context
+
+	// compression compresses the token request when the transaction is marshalled, if nil the request is not compressed
+	compression *compression.Service
+	// freeze tells which token types cannot be transferred, if nil no token type is frozen
+	freeze *freeze.Service
+	// limiter bounds the outgoing transfers of each wallet, if nil the transfers are not limited
+	limiter *RateLimiter
 }
 
 // NewAnonymousTransaction returns a new anonymous token transaction customized with the passed opts
@@ -73,6 +83,11 @@ func NewTransaction(context view.Context, signer view.Identity, opts ...TxOption
 		context,
 		token.WithTMSID(txOpts.TMSID),
 	)
+	if len(txOpts.IdempotencyKey) != 0 {
+		if err := checkIdempotencyKey(context, tms.ID(), txOpts.IdempotencyKey); err != nil {
+			return nil, err
+		}
+	}
 	networkService := network.GetInstance(context, tms.Network(), tms.Channel())
 	networkProvider := network.GetProvider(context).GetNetwork
 
@@ -91,6 +106,14 @@ func NewTransaction(context view.Context, signer view.Identity, opts ...TxOption
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed init token request")
 	}
+	if len(txOpts.IdempotencyKey) != 0 {
+		tr.SetApplicationMetadata(ttxdb.IdempotencyKeyMetadata, []byte(txOpts.IdempotencyKey))
+	}
+	if len(txOpts.Reference) != 0 {
+		if err := tr.BindReference(txOpts.Reference); err != nil {
+			return nil, errors.WithMessage(err, "failed binding reference")
+		}
+	}
 
 	tx := &Transaction{
 		Payload: &Payload{
@@ -108,6 +131,9 @@ func NewTransaction(context view.Context, signer view.Identity, opts ...TxOption
 		NetworkProvider: networkProvider,
 		Opts:            txOpts,
 		Context:         context.Context(),
+		compression:     getCompression(context),
+		freeze:          getFreeze(context),
+		limiter:         getRateLimiter(context),
 	}
 	context.OnError(tx.Release)
 	return tx, nil
@@ -119,7 +145,10 @@ func NewTransactionFromBytes(context view.Context, raw []byte) (*Transaction, er
 			Transient:    map[string][]byte{},
 			TokenRequest: token.NewRequest(nil, ""),
 		},
-		Context: context.Context(),
+		Context:     context.Context(),
+		compression: getCompression(context),
+		freeze:      getFreeze(context),
+		limiter:     getRateLimiter(context),
 	}
 	networkProvider := network.GetProvider(context).GetNetwork
 	if err := unmarshal(networkProvider, tx.Payload, raw); err != nil {
@@ -174,6 +203,54 @@ func ReceiveTransaction(context view.Context, opts ...TxOption) (*Transaction, e
 	return cctx, nil
 }
 
+// getCompression returns the compression service, nil if not available
+func getCompression(sp token.ServiceProvider) *compression.Service {
+	s, err := compression.GetService(sp)
+	if err != nil {
+		logger.Debugf("compression not available, token requests are sent uncompressed: [%s]", err)
+		return nil
+	}
+	return s
+}
+
+// getFreeze returns the freeze service, nil if not available
+func getFreeze(sp token.ServiceProvider) *freeze.Service {
+	s, err := freeze.GetService(sp)
+	if err != nil {
+		logger.Debugf("freeze not available, no token type is frozen: [%s]", err)
+		return nil
+	}
+	return s
+}
+
+// getRateLimiter returns the rate limiter, nil if not available
+func getRateLimiter(sp token.ServiceProvider) *RateLimiter {
+	l, err := GetRateLimiter(sp)
+	if err != nil {
+		logger.Debugf("rate limiter not available, transfers are not limited: [%s]", err)
+		return nil
+	}
+	return l
+}
+
+// reserveRate accounts an outgoing transfer of the passed value from the passed wallet.
+// It returns an error matching ErrRateLimited if the transfer exceeds the limits of the wallet,
+// otherwise a function giving the reservation back.
+func (t *Transaction) reserveRate(wallet *token.OwnerWallet, tokenType string, value uint64) (func(), error) {
+	if t.limiter == nil || wallet == nil {
+		return func() {}, nil
+	}
+	return t.limiter.Reserve(t.TMS, wallet.ID(), tokenType, value)
+}
+
+// checkNotFrozen returns an error matching freeze.ErrFrozen, if the passed token type is frozen on this node
+func (t *Transaction) checkNotFrozen(tokenType string) error {
+	if t.freeze == nil {
+		return nil
+	}
+	return t.freeze.Check(tokenType)
+}
+
 // ID returns the ID of this transaction. It is equal to the underlying transaction's ID.
 func (t *Transaction) ID() string {
 	return t.Payload.ID
@@ -208,6 +285,95 @@ func (t *Transaction) Bytes(eIDs ...string) ([]byte, error) {
 	return marshal(t, eIDs...)
 }
 
+const (
+	// DefaultEnvelopeOverhead estimates the bytes the envelope adds to the read-write set of a token transaction:
+	// the channel and signature headers, the identity and the signature of the submitter, and the endorsements, with the auditor signature.
+	DefaultEnvelopeOverhead = 8 * 1024
+	// DefaultSignatureOverhead estimates the bytes of a signature not collected yet in the token request
+	DefaultSignatureOverhead = 512
+)
+
+// SizeEstimateOpts configures the estimate of the size of a transaction
+type SizeEstimateOpts struct {
+	// EnvelopeOverhead is the bytes the envelope adds to the read-write set of the transaction
+	EnvelopeOverhead int
+	// SignatureOverhead is the bytes of a signature not collected yet in the token request
+	SignatureOverhead int
+}
+
+// SizeEstimateOpt is a function that configures a SizeEstimateOpts
+type SizeEstimateOpt func(*SizeEstimateOpts) error
+
+// WithEnvelopeOverhead sets the bytes the envelope adds to the read-write set of the transaction.
+// Applications whose networks have more endorsers than usual can raise it.
+func WithEnvelopeOverhead(overhead int) SizeEstimateOpt {
+	return func(o *SizeEstimateOpts) error {
+		if overhead < 0 {
+			return errors.Errorf("invalid envelope overhead [%d]", overhead)
+		}
+		o.EnvelopeOverhead = overhead
+		return nil
+	}
+}
+
+// WithSignatureOverhead sets the bytes of a signature not collected yet in the token request
+func WithSignatureOverhead(overhead int) SizeEstimateOpt {
+	return func(o *SizeEstimateOpts) error {
+		if overhead < 0 {
+			return errors.Errorf("invalid signature overhead [%d]", overhead)
+		}
+		o.SignatureOverhead = overhead
+		return nil
+	}
+}
+
+// EstimateSize returns an estimate, in bytes, of the transaction that will be broadcast to the ordering service.
+// If the envelope has been already assembled, that is, after the endorsements have been collected, the size of the envelope is returned.
+// Otherwise, the estimate adds up:
+// the serialized token request, with the proofs and the signatures collected so far;
+// the actions once more, because their outputs and metadata are also written in the read-write set under their own keys;
+// the signature overhead for each issuer or sender that has not signed yet, DefaultSignatureOverhead unless set with WithSignatureOverhead;
+// the envelope overhead, DefaultEnvelopeOverhead unless set with WithEnvelopeOverhead.
+// The metadata of the token request are never sent to the ledger and are not counted.
+// Applications can compare the estimate with the maximum message size of the ordering service, and split the payment in advance.
+func (t *Transaction) EstimateSize(opts ...SizeEstimateOpt) (int, error) {
+	options := &SizeEstimateOpts{EnvelopeOverhead: DefaultEnvelopeOverhead, SignatureOverhead: DefaultSignatureOverhead}
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return 0, err
+		}
+	}
+	if t.Payload.Envelope != nil {
+		raw, err := t.Payload.Envelope.Bytes()
+		if err != nil {
+			return 0, errors.WithMessagef(err, "failed marshalling envelope of tx [%s]", t.ID())
+		}
+		return len(raw), nil
+	}
+	raw, err := t.TokenRequest.RequestToBytes()
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed marshalling token request of tx [%s]", t.ID())
+	}
+	size := len(raw) + options.EnvelopeOverhead
+	actions := t.TokenRequest.Actions
+	for _, action := range actions.Issues {
+		size += len(action)
+	}
+	for _, action := range actions.Transfers {
+		size += len(action)
+	}
+	signed := 0
+	for _, sigma := range actions.Signatures {
+		if len(sigma) != 0 {
+			signed++
+		}
+	}
+	if missing := len(t.TokenRequest.IssueSigners()) + len(t.TokenRequest.TransferSigners()) - signed; missing > 0 {
+		size += missing * options.SignatureOverhead
+	}
+	return size, nil
+}
+
 // Issue appends a new Issue operation to the TokenRequest inside this transaction
 func (t *Transaction) Issue(wallet *token.IssuerWallet, receiver view.Identity, typ string, q uint64, opts ...token.IssueOption) error {
 	_, err := t.TokenRequest.Issue(t.Context, wallet, receiver, typ, q, opts...)
@@ -216,12 +382,64 @@ func (t *Transaction) Issue(wallet *token.IssuerWallet, receiver view.Identity,
 
 // Transfer appends a new Transfer operation to the TokenRequest inside this transaction
 func (t *Transaction) Transfer(wallet *token.OwnerWallet, typ string, values []uint64, owners []view.Identity, opts ...token.TransferOption) error {
-	_, err := t.TokenRequest.Transfer(t.Context, wallet, typ, values, owners, opts...)
-	return err
+	if err := t.checkNotFrozen(typ); err != nil {
+		return errors.WithMessagef(err, "cannot transfer")
+	}
+	var total uint64
+	for _, v := range values {
+		total += v
+	}
+	release, err := t.reserveRate(wallet, typ, total)
+	if err != nil {
+		return errors.WithMessagef(err, "cannot transfer")
+	}
+	if _, err := t.TokenRequest.Transfer(t.Context, wallet, typ, values, owners, opts...); err != nil {
+		release()
+		return err
+	}
+	return nil
+}
+
+// Payout is a (recipient, amount) pair of a multi-recipient transfer
+type Payout struct {
+	// Recipient is the identity that will own the output
+	Recipient view.Identity
+	// Amount is the quantity assigned to the recipient
+	Amount uint64
+}
+
+// Payouts appends a single Transfer operation to the TokenRequest inside this transaction that pays
+// all the passed recipients at once. Inputs are selected in one pass for the total amount, and the rest, if any,
+// goes back to the passed wallet.
+func (t *Transaction) Payouts(wallet *token.OwnerWallet, typ string, payouts []Payout, opts ...token.TransferOption) error {
+	if len(payouts) == 0 {
+		return errors.New("no payouts specified")
+	}
+	values := make([]uint64, len(payouts))
+	owners := make([]view.Identity, len(payouts))
+	for i, p := range payouts {
+		if p.Recipient.IsNone() {
+			return errors.Errorf("recipient of payout [%d] is not defined", i)
+		}
+		values[i] = p.Amount
+		owners[i] = p.Recipient
+	}
+	return t.Transfer(wallet, typ, values, owners, opts...)
 }
 
 func (t *Transaction) Redeem(wallet *token.OwnerWallet, typ string, value uint64, opts ...token.TransferOption) error {
-	return t.TokenRequest.Redeem(t.Context, wallet, typ, value, opts...)
+	if err := t.checkNotFrozen(typ); err != nil {
+		return errors.WithMessagef(err, "cannot redeem")
+	}
+	release, err := t.reserveRate(wallet, typ, value)
+	if err != nil {
+		return errors.WithMessagef(err, "cannot redeem")
+	}
+	if err := t.TokenRequest.Redeem(t.Context, wallet, typ, value, opts...); err != nil {
+		release()
+		return err
+	}
+	return nil
 }
 
 func (t *Transaction) Outputs() (*token.OutputStream, error) {
@@ -0,0 +1,58 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttx
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateSize(t *testing.T) {
+	request := &token.Request{
+		Anchor: "tx1",
+		Actions: &driver.TokenRequest{
+			Issues:    [][]byte{make([]byte, 100)},
+			Transfers: [][]byte{make([]byte, 300)},
+		},
+		Metadata: &driver.TokenRequestMetadata{
+			Issues: []driver.IssueMetadata{{Issuer: driver.Identity("issuer")}},
+			Transfers: []driver.TransferMetadata{{
+				Senders:   []driver.Identity{driver.Identity("alice"), driver.Identity("bob")},
+				Outputs:   [][]byte{make([]byte, 100000)},
+				Receivers: []driver.Identity{driver.Identity("charlie")},
+			}},
+		},
+	}
+	tx := &Transaction{Payload: &Payload{ID: "tx1", TokenRequest: request}}
+	raw, err := request.RequestToBytes()
+	assert.NoError(t, err)
+
+	// no signature collected yet: the actions are counted twice, with three missing signatures and the envelope
+	size, err := tx.EstimateSize()
+	assert.NoError(t, err)
+	assert.Equal(t, len(raw)+400+3*DefaultSignatureOverhead+DefaultEnvelopeOverhead, size)
+	// the metadata, never sent to the ledger, are not counted
+	assert.Less(t, size, 100000)
+
+	// the signatures collected replace their estimates
+	request.Actions.Signatures = [][]byte{make([]byte, 70), make([]byte, 70), nil}
+	raw, err = request.RequestToBytes()
+	assert.NoError(t, err)
+	size, err = tx.EstimateSize()
+	assert.NoError(t, err)
+	assert.Equal(t, len(raw)+400+DefaultSignatureOverhead+DefaultEnvelopeOverhead, size)
+
+	// the overheads can be set per estimate
+	size, err = tx.EstimateSize(WithEnvelopeOverhead(16*1024), WithSignatureOverhead(1024))
+	assert.NoError(t, err)
+	assert.Equal(t, len(raw)+400+1024+16*1024, size)
+	_, err = tx.EstimateSize(WithEnvelopeOverhead(-1))
+	assert.ErrorContains(t, err, "invalid envelope overhead [-1]")
+}
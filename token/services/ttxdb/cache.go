@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttxdb
+
+import (
+	"context"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+)
+
+type statusEntry struct {
+	status  driver.TxStatus
+	message string
+}
+
+// cachingTransactionDB is a driver.TokenTransactionDB that caches the results of GetStatus and GetTokenRequest.
+// Unknown transactions and missing token requests are not cached, they might be added later.
+type cachingTransactionDB struct {
+	driver.TokenTransactionDB
+	statuses *db.QueryCache[string, statusEntry]
+	requests *db.QueryCache[string, []byte]
+}
+
+func newCachingTransactionDB(ttxDB driver.TokenTransactionDB, opts db.QueryCacheOpts) *cachingTransactionDB {
+	return &cachingTransactionDB{
+		TokenTransactionDB: ttxDB,
+		statuses:           db.NewQueryCache[string, statusEntry](opts.GetStatus),
+		requests:           db.NewQueryCache[string, []byte](opts.GetTokenRequest),
+	}
+}
+
+func (c *cachingTransactionDB) GetStatus(txID string) (driver.TxStatus, string, error) {
+	e, err := c.statuses.Load(txID, func() (statusEntry, error) {
+		status, message, err := c.TokenTransactionDB.GetStatus(txID)
+		return statusEntry{status: status, message: message}, err
+	}, func(e statusEntry) bool { return e.status != driver.Unknown })
+	return e.status, e.message, err
+}
+
+func (c *cachingTransactionDB) SetStatus(ctx context.Context, txID string, status driver.TxStatus, message string) error {
+	defer c.statuses.Invalidate(txID)
+	return c.TokenTransactionDB.SetStatus(ctx, txID, status, message)
+}
+
+func (c *cachingTransactionDB) OverrideStatus(ctx context.Context, txID string, status driver.TxStatus, message string, override driver.StatusOverride) error {
+	defer c.statuses.Invalidate(txID)
+	return c.TokenTransactionDB.OverrideStatus(ctx, txID, status, message, override)
+}
+
+func (c *cachingTransactionDB) GetTokenRequests(txIDs []string) (map[string][]byte, error) {
+	return c.requests.LoadAll(txIDs, c.TokenTransactionDB.GetTokenRequests, func(raw []byte) bool { return raw != nil })
+}
+
+func (c *cachingTransactionDB) GetTokenRequest(txID string) ([]byte, error) {
+	return c.requests.Load(txID, func() ([]byte, error) {
+		return c.TokenTransactionDB.GetTokenRequest(txID)
+	}, func(raw []byte) bool { return raw != nil })
+}
@@ -0,0 +1,182 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttxdb
+
+import (
+	"context"
+	errors2 "errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+)
+
+// CompensationEvent carries the information about a transaction that failed to commit
+type CompensationEvent struct {
+	Ctx context.Context
+	// TxID is the id of the failed transaction
+	TxID string
+	// Status is the status the transaction has been moved to
+	Status TxStatus
+	// Message is the status message, if any
+	Message string
+	// Records are the transaction records of the failed transaction
+	Records []*TransactionRecord
+	// TokenRequest is the serialized token request of the failed transaction, if available
+	TokenRequest []byte
+	// Inputs are the ids of the tokens the failed transaction was spending.
+	// These tokens are still unspent and the locks held on them by the transaction are released.
+	Inputs []*token2.ID
+}
+
+// CompensationHandler is invoked when a transaction is marked as failed.
+// Handlers are invoked at least once per failure, therefore they must be idempotent.
+// The handlers run after the status is committed, an error returned by a handler is logged and
+// the failure is recorded in the database, so that it survives a restart and can be retried
+// with RetryCompensation or RetryCompensations.
+type CompensationHandler interface {
+	OnTransactionFailure(event *CompensationEvent) error
+}
+
+// errFailureNotRecorded is returned when a failed compensation could not be recorded, and would then be lost
+var errFailureNotRecorded = errors.New("failed recording the compensation failure")
+
+// AddCompensationHandler registers a handler to be invoked when a transaction is marked as Deleted
+func (d *DB) AddCompensationHandler(handler CompensationHandler) {
+	d.compensationLock.Lock()
+	defer d.compensationLock.Unlock()
+	d.compensationHandlers = append(d.compensationHandlers, handler)
+}
+
+// CompensationFailures returns the errors of the compensation handlers that failed, by transaction id.
+// A transaction is removed once RetryCompensation succeeds for it.
+func (d *DB) CompensationFailures() (map[string]error, error) {
+	failures, err := d.db.QueryCompensationFailures()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying compensation failures")
+	}
+	res := make(map[string]error, len(failures))
+	for txID, message := range failures {
+		res[txID] = errors.New(message)
+	}
+	return res, nil
+}
+
+// RetryCompensation invokes again the compensation handlers for the passed transaction, whose status must be Deleted.
+// The handlers run all again, they are idempotent.
+func (d *DB) RetryCompensation(ctx context.Context, txID string) error {
+	status, message, err := d.GetStatus(txID)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting status of [%s]", txID)
+	}
+	if status != Deleted {
+		return errors.Errorf("transaction [%s] is not deleted, its status is [%s]", txID, TxStatusMessage[status])
+	}
+	return d.compensate(ctx, txID, status, message)
+}
+
+// RetryCompensations invokes again the compensation handlers for all the recorded compensation failures,
+// for instance at startup, once the handlers are registered.
+// It returns the errors of the compensations failing again, by transaction id.
+func (d *DB) RetryCompensations(ctx context.Context) (map[string]error, error) {
+	failures, err := d.db.QueryCompensationFailures()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying compensation failures")
+	}
+	res := map[string]error{}
+	for txID := range failures {
+		if err := d.RetryCompensation(ctx, txID); err != nil {
+			res[txID] = err
+		}
+	}
+	return res, nil
+}
+
+// compensate invokes the compensation handlers for the passed transaction.
+// A failure is logged and recorded in the database, the remaining handlers are invoked anyway.
+// If the failure cannot be recorded, the returned error wraps errFailureNotRecorded.
+func (d *DB) compensate(ctx context.Context, txID string, status TxStatus, message string) error {
+	d.compensationLock.RLock()
+	handlers := make([]CompensationHandler, len(d.compensationHandlers))
+	copy(handlers, d.compensationHandlers)
+	d.compensationLock.RUnlock()
+	if len(handlers) == 0 {
+		return nil
+	}
+
+	var errs []error
+	event, err := d.compensationEvent(ctx, txID, status, message)
+	if err != nil {
+		errs = append(errs, errors.WithMessagef(err, "failed preparing compensation event for [%s]", txID))
+	} else {
+		for _, handler := range handlers {
+			if err := handler.OnTransactionFailure(event); err != nil {
+				errs = append(errs, errors.WithMessagef(err, "compensation handler failed for [%s]", txID))
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		if err := d.db.DeleteCompensationFailure(txID); err != nil {
+			// the compensation is retried once more, the handlers are idempotent
+			logger.Warnf("failed clearing the compensation failure of [%s]: %s", txID, err)
+		}
+		return nil
+	}
+	err = errors2.Join(errs...)
+	logger.Errorf("compensation of [%s] failed, retry with RetryCompensation: %s", txID, err)
+	if err1 := d.db.AddCompensationFailure(txID, err.Error()); err1 != nil {
+		return errors2.Join(err, errors.Wrapf(errFailureNotRecorded, "[%s]: %s", txID, err1))
+	}
+	return err
+}
+
+func (d *DB) compensationEvent(ctx context.Context, txID string, status TxStatus, message string) (*CompensationEvent, error) {
+	event := &CompensationEvent{
+		Ctx:     ctx,
+		TxID:    txID,
+		Status:  status,
+		Message: message,
+	}
+
+	it, err := d.db.QueryTransactions(QueryTransactionsParams{IDs: []string{txID}})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying transaction records")
+	}
+	defer it.Close()
+	for {
+		record, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed reading transaction records")
+		}
+		if record == nil {
+			break
+		}
+		event.Records = append(event.Records, record)
+	}
+
+	raw, err := d.GetTokenRequest(txID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting token request")
+	}
+	if len(raw) == 0 {
+		return event, nil
+	}
+	event.TokenRequest = raw
+	request := token.NewRequest(nil, txID)
+	if err := request.FromBytes(raw); err != nil {
+		return nil, errors.WithMessagef(err, "failed unmarshalling token request")
+	}
+	for _, transfer := range request.Metadata.Transfers {
+		for _, id := range transfer.TokenIDs {
+			if id != nil {
+				event.Inputs = append(event.Inputs, id)
+			}
+		}
+	}
+	return event, nil
+}
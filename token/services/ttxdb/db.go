@@ -10,6 +10,8 @@ import (
 	"context"
 	"math/big"
 	"reflect"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/cache/secondcache"
@@ -61,6 +63,15 @@ const (
 	Deleted = driver.Deleted
 )
 
+// IdempotencyKeyMetadata is the application metadata key carrying the idempotency key of a transaction, if any.
+// AppendTransactionRecord binds the key to the transaction.
+const IdempotencyKeyMetadata = "ttx.idempotency.key"
+
+// RecipientAnonymityLevelMetadataPrefix is the prefix of the application metadata keys, stored with a token request,
+// recording the anonymity level of the outputs received by the identities of the local wallets.
+// The key ends with the index of the output, see AddRecipientAnonymityLevel.
+const RecipientAnonymityLevelMetadataPrefix = "token.recipient_anonymity_level."
+
 // TxStatusMessage maps TxStatus to string
 var TxStatusMessage = driver.TxStatusMessage
 
@@ -162,6 +173,11 @@ type DB struct {
 	*db.StatusSupport
 	db    driver.TokenTransactionDB
 	cache Cache
+	// writes tracks the in-flight writes to drain on shutdown
+	writes db.WriteGate
+
+	compensationLock     sync.RWMutex
+	compensationHandlers []CompensationHandler
 }
 
 func newDB(p driver.TokenTransactionDB) *DB {
@@ -181,6 +197,24 @@ type QueryTokenRequestsParams = driver.QueryTokenRequestsParams
 // QueryValidationRecordsParams defines the parameters for querying movements
 type QueryValidationRecordsParams = driver.QueryValidationRecordsParams
 
+// StatusOverride describes who overrides the status of a transaction, and why
+type StatusOverride = driver.StatusOverride
+
+// StatusOverrideRecord is the audit record of a status override
+type StatusOverrideRecord = driver.StatusOverrideRecord
+
+// QueryStatusOverridesParams defines the parameters for querying status overrides
+type QueryStatusOverridesParams = driver.QueryStatusOverridesParams
+
+// FundsWitnessRecord is a signed snapshot of the funds of a wallet whose token selection failed
+type FundsWitnessRecord = driver.FundsWitnessRecord
+
+// QueryFundsWitnessesParams defines the parameters for querying funds witnesses
+type QueryFundsWitnessesParams = driver.QueryFundsWitnessesParams
+
+// ErasedRecords counts the records in which the erasure of an enrollment ID replaced it with a pseudonym
+type ErasedRecords = driver.ErasedRecords
+
 // Transactions returns an iterators of transaction records filtered by the given params.
 func (d *DB) Transactions(params QueryTransactionsParams) (driver.TransactionIterator, error) {
 	return d.db.QueryTransactions(params)
@@ -202,6 +236,10 @@ func (d *DB) ValidationRecords(params QueryValidationRecordsParams) (*Validation
 
 // AppendTransactionRecord appends the transaction records corresponding to the passed token request.
 func (d *DB) AppendTransactionRecord(req *token.Request) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot append transaction record [%s]", req.Anchor)
+	}
+	defer d.writes.Exit()
 	logger.Debugf("appending new transaction record... [%s]", req.Anchor)
 
 	ins, outs, err := req.InputsAndOutputs()
@@ -222,6 +260,10 @@ func (d *DB) AppendTransactionRecord(req *token.Request) error {
 	if err != nil {
 		return errors.WithMessage(err, "failed parsing transactions from audit record")
 	}
+	applicationMetadata, err := d.withRecipientAnonymityLevels(req.Metadata.Application, outs)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting anonymity levels of the outputs of [%s]", req.Anchor)
+	}
 
 	logger.Debugf("storing new records... [%d,%d]", len(raw), len(txs))
 	w, err := d.db.BeginAtomicWrite()
@@ -232,7 +274,7 @@ func (d *DB) AppendTransactionRecord(req *token.Request) error {
 	if err := w.AddTokenRequest(
 		record.Anchor,
 		raw,
-		req.Metadata.Application,
+		applicationMetadata,
 		req.TokenService.PublicParametersManager().PublicParamsHash(),
 	); err != nil {
 		w.Rollback()
@@ -244,6 +286,24 @@ func (d *DB) AppendTransactionRecord(req *token.Request) error {
 			return errors.WithMessagef(err, "append transactions for txid [%s] failed", record.Anchor)
 		}
 	}
+	if key := req.ApplicationMetadata(IdempotencyKeyMetadata); len(key) != 0 {
+		if err := w.AddIdempotencyKey(record.Anchor, string(key)); err != nil {
+			w.Rollback()
+			return errors.WithMessagef(err, "append idempotency key for txid [%s] failed", record.Anchor)
+		}
+	}
+	if reference := req.Reference(); len(reference) != 0 {
+		if err := w.AddReference(record.Anchor, reference); err != nil {
+			w.Rollback()
+			return errors.WithMessagef(err, "append reference for txid [%s] failed", record.Anchor)
+		}
+	}
+	if walletID := req.SigningWallet(); len(walletID) != 0 {
+		if err := w.AddWallet(record.Anchor, walletID); err != nil {
+			w.Rollback()
+			return errors.WithMessagef(err, "append wallet for txid [%s] failed", record.Anchor)
+		}
+	}
 	if err := w.Commit(); err != nil {
 		return errors.WithMessagef(err, "committing tx for txid [%s] failed", record.Anchor)
 	}
@@ -252,10 +312,80 @@ func (d *DB) AppendTransactionRecord(req *token.Request) error {
 	return nil
 }
 
-// SetStatus sets the status of the audit records with the passed transaction id to the passed status
+// AddRecipientAnonymityLevel records the anonymity level the passed recipient identity has been derived with by the passed local wallet.
+// The default level is not recorded. The level of an identity is recorded once, the first one is kept.
+// AppendTransactionRecord records the level in the application metadata of the transactions giving outputs to the identity.
+func (d *DB) AddRecipientAnonymityLevel(identity token.Identity, walletID string, level token.AnonymityLevel) error {
+	if level == token.DefaultAnonymity {
+		return nil
+	}
+	return d.db.AddRecipientAnonymityLevel(identity, walletID, level.String())
+}
+
+// withRecipientAnonymityLevels returns the passed application metadata with the anonymity levels recorded for the owners of the passed outputs.
+// The passed metadata is not modified.
+func (d *DB) withRecipientAnonymityLevels(metadata map[string][]byte, outs *token.OutputStream) (map[string][]byte, error) {
+	var owners []token.Identity
+	for _, out := range outs.Outputs() {
+		if !out.Owner.IsNone() {
+			owners = append(owners, out.Owner)
+		}
+	}
+	levels, err := d.db.GetRecipientAnonymityLevels(owners)
+	if err != nil || len(levels) == 0 {
+		return metadata, err
+	}
+	res := make(map[string][]byte, len(metadata)+len(levels))
+	for k, v := range metadata {
+		res[k] = v
+	}
+	for _, out := range outs.Outputs() {
+		if level, ok := levels[out.Owner.UniqueID()]; ok {
+			res[RecipientAnonymityLevelMetadataPrefix+strconv.FormatUint(out.Index, 10)] = []byte(level)
+		}
+	}
+	return res, nil
+}
+
+// SetStatus sets the status of the audit records with the passed transaction id to the passed status.
+// If the status is Deleted, the registered compensation handlers are invoked once the status is committed.
+// Their failures do not fail SetStatus, they are recorded and returned by CompensationFailures.
+// SetStatus fails only if a failure cannot be recorded.
 func (d *DB) SetStatus(ctx context.Context, txID string, status driver.TxStatus, message string) error {
+	return d.setStatus(ctx, txID, status, message, true, func() error {
+		return d.db.SetStatus(ctx, txID, status, message)
+	})
+}
+
+// Reject sets the status of the passed transaction to Deleted, as SetStatus does, without invoking the compensation handlers.
+// It records a transaction rejected before it is submitted, there is nothing to compensate.
+func (d *DB) Reject(ctx context.Context, txID string, message string) error {
+	return d.setStatus(ctx, txID, Deleted, message, false, func() error {
+		return d.db.SetStatus(ctx, txID, Deleted, message)
+	})
+}
+
+// OverrideStatus sets the status of the passed transaction as SetStatus does, and records the override in the status overrides.
+// It must be used, instead of SetStatus, to change the status outside the finality listener, for instance to repair a transaction.
+func (d *DB) OverrideStatus(ctx context.Context, txID string, status driver.TxStatus, message string, override StatusOverride) error {
+	logger.Infof("override status [%s][%s] by [%s]: %s", txID, status, override.Operator, override.Reason)
+	return d.setStatus(ctx, txID, status, message, true, func() error {
+		return d.db.OverrideStatus(ctx, txID, status, message, override)
+	})
+}
+
+// StatusOverrides returns the status overrides matching the passed params, the oldest first
+func (d *DB) StatusOverrides(params QueryStatusOverridesParams) ([]*StatusOverrideRecord, error) {
+	return d.db.QueryStatusOverrides(params)
+}
+
+func (d *DB) setStatus(ctx context.Context, txID string, status driver.TxStatus, message string, compensate bool, update func() error) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot set status [%s]", txID)
+	}
+	defer d.writes.Exit()
 	logger.Debugf("set status [%s][%s]...", txID, status)
-	if err := d.db.SetStatus(ctx, txID, status, message); err != nil {
+	if err := update(); err != nil {
 		return errors.Wrapf(err, "failed setting status [%s][%s]", txID, driver.TxStatusMessage[status])
 	}
 
@@ -265,6 +395,12 @@ func (d *DB) SetStatus(ctx context.Context, txID string, status driver.TxStatus,
 		TxID:           txID,
 		ValidationCode: status,
 	})
+	if compensate && status == Deleted {
+		// the status is committed, a recorded compensation failure is retried later
+		if err := d.compensate(ctx, txID, status, message); errors.Is(err, errFailureNotRecorded) {
+			return errors.WithMessagef(err, "status [%s][%s] set, but its failed compensation is lost", txID, driver.TxStatusMessage[status])
+		}
+	}
 	logger.Debugf("set status [%s][%s] done", txID, driver.TxStatusMessage[status])
 	return nil
 }
@@ -281,6 +417,28 @@ func (d *DB) GetStatus(txID string) (TxStatus, string, error) {
 	return status, message, nil
 }
 
+// TxIDByIdempotencyKey returns the id of the pending or confirmed transaction bound to the passed idempotency key.
+// It returns an empty string if there is no such transaction.
+func (d *DB) TxIDByIdempotencyKey(key string) (string, error) {
+	return d.db.GetTxIDByIdempotencyKey(key)
+}
+
+// TxIDsByReference returns the ids of the transactions bound to the passed hash of an external document, the oldest first.
+// See token.Request.BindReference.
+func (d *DB) TxIDsByReference(reference []byte) ([]string, error) {
+	return d.db.GetTxIDsByReference(reference)
+}
+
+// EraseEnrollmentID replaces the passed enrollment ID with the passed pseudonym in the transaction and movement records.
+// The amounts and the token requests are kept.
+func (d *DB) EraseEnrollmentID(eID string, pseudonym string) (*ErasedRecords, error) {
+	if err := d.writes.Enter(); err != nil {
+		return nil, errors.WithMessagef(err, "cannot erase enrollment ID")
+	}
+	defer d.writes.Exit()
+	return d.db.EraseEnrollmentID(eID, pseudonym)
+}
+
 // GetTokenRequest returns the token request bound to the passed transaction id, if available.
 func (d *DB) GetTokenRequest(txID string) ([]byte, error) {
 	res, ok := d.cache.Get(txID)
@@ -290,11 +448,54 @@ func (d *DB) GetTokenRequest(txID string) ([]byte, error) {
 	return d.db.GetTokenRequest(txID)
 }
 
+// GetTokenRequests returns the token requests bound to the passed transaction ids, indexed by transaction id.
+// The transaction ids that are not found are missing in the returned map.
+func (d *DB) GetTokenRequests(txIDs []string) (map[string][]byte, error) {
+	res := make(map[string][]byte, len(txIDs))
+	missing := make([]string, 0, len(txIDs))
+	for _, txID := range txIDs {
+		if raw, ok := d.cache.Get(txID); ok {
+			res[txID] = raw
+		} else {
+			missing = append(missing, txID)
+		}
+	}
+	if len(missing) == 0 {
+		return res, nil
+	}
+	found, err := d.db.GetTokenRequests(missing)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting token requests")
+	}
+	for txID, raw := range found {
+		res[txID] = raw
+	}
+	return res, nil
+}
+
 // AddTransactionEndorsementAck records the signature of a given endorser for a given transaction
 func (d *DB) AddTransactionEndorsementAck(txID string, id token.Identity, sigma []byte) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot add endorsement ack [%s]", txID)
+	}
+	defer d.writes.Exit()
 	return d.db.AddTransactionEndorsementAck(txID, id, sigma)
 }
 
+// AddFundsWitness stores the passed funds witness
+func (d *DB) AddFundsWitness(record *FundsWitnessRecord) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot add funds witness of [%s]", record.WalletID)
+	}
+	defer d.writes.Exit()
+	return d.db.AddFundsWitness(record)
+}
+
+// FundsWitnesses returns the funds witnesses matching the passed params, the oldest first
+func (d *DB) FundsWitnesses(params QueryFundsWitnessesParams) ([]*FundsWitnessRecord, error) {
+	return d.db.QueryFundsWitnesses(params)
+}
+
 // GetTransactionEndorsementAcks returns the endorsement signatures for the given transaction id
 func (d *DB) GetTransactionEndorsementAcks(txID string) (map[string][]byte, error) {
 	return d.db.GetTransactionEndorsementAcks(txID)
@@ -302,6 +503,10 @@ func (d *DB) GetTransactionEndorsementAcks(txID string) (map[string][]byte, erro
 
 // AppendValidationRecord appends the given validation metadata related to the given transaction id
 func (d *DB) AppendValidationRecord(txID string, tokenRequest []byte, meta map[string][]byte, ppHash driver2.PPHash) error {
+	if err := d.writes.Enter(); err != nil {
+		return errors.WithMessagef(err, "cannot append validation record [%s]", txID)
+	}
+	defer d.writes.Exit()
 	logger.Debugf("appending new validation record... [%s]", txID)
 
 	w, err := d.db.BeginAtomicWrite()
@@ -325,6 +530,57 @@ func (d *DB) AppendValidationRecord(txID string, tokenRequest []byte, meta map[s
 	return nil
 }
 
+// Drain makes the database reject new writes and waits for the in-flight ones to complete or for the context to expire
+func (d *DB) Drain(ctx context.Context) error {
+	return d.writes.Drain(ctx)
+}
+
+// Resume makes the database accept writes again after a Drain
+func (d *DB) Resume() {
+	d.writes.Resume()
+}
+
+// SetReadOnly makes the database reject writes, or accept them again
+func (d *DB) SetReadOnly(readOnly bool) {
+	d.writes.SetReadOnly(readOnly)
+}
+
+// SetStatementObserver makes the database notify the passed observer of the interrupted statements.
+// It must be called before EnableQueryCache.
+func (d *DB) SetStatementObserver(o driver.StatementObserver) {
+	db.SetStatementObserver(d.db, o)
+}
+
+// EnableEncryption makes the database encrypt the token requests with the passed cipher.
+// It must be called before EnableQueryCache.
+func (d *DB) EnableEncryption(c driver.ColumnCipher) error {
+	return db.SetColumnCipher(d.db, c)
+}
+
+// TableSizes returns the disk usage of the tables of the database.
+// It returns an error wrapping driver.ErrTableSizesNotSupported if the database cannot report it.
+func (d *DB) TableSizes() ([]driver.TableSize, error) {
+	return db.TableSizes(d.db)
+}
+
+// ReEncrypt re-encrypts with the active key the token requests stored in clear or with a retired key
+func (d *DB) ReEncrypt(ctx context.Context, batchSize int) (int, error) {
+	return db.ReEncrypt(ctx, d.db, &d.writes, batchSize)
+}
+
+// EnableQueryCache makes the database cache the results of GetStatus and GetTokenRequest, as enabled in the passed options
+func (d *DB) EnableQueryCache(opts db.QueryCacheOpts) {
+	if !opts.GetStatus.Enabled && !opts.GetTokenRequest.Enabled {
+		return
+	}
+	d.db = newCachingTransactionDB(d.db, opts)
+}
+
+// Close closes the database
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
 // TransactionRecords is a pure function that converts an AuditRecord for storage in the database.
 func TransactionRecords(record *token.AuditRecord, timestamp time.Time) (txs []TransactionRecord, err error) {
 	inputs := record.Inputs
@@ -361,8 +617,11 @@ func TransactionRecords(record *token.AuditRecord, timestamp time.Time) (txs []T
 		outTT := ous.TokenTypes()
 		for _, outEID := range outEIDs {
 			for _, tokenType := range outTT {
-				received := ous.ByEnrollmentID(outEID).ByType(tokenType).Sum()
-				if received.Cmp(big.NewInt(0)) <= 0 {
+				received, err := ous.ByEnrollmentID(outEID).ByType(tokenType).SumQuantity()
+				if err != nil {
+					return nil, errors.WithMessagef(err, "failed computing amount received by [%s] for tx [%s]", outEID, record.Anchor)
+				}
+				if received.ToBigInt().Sign() <= 0 {
 					continue
 				}
 
@@ -380,7 +639,7 @@ func TransactionRecords(record *token.AuditRecord, timestamp time.Time) (txs []T
 					SenderEID:    inEID,
 					RecipientEID: outEID,
 					TokenType:    tokenType,
-					Amount:       received,
+					Amount:       received.ToBigInt(),
 					Status:       driver.Pending,
 					ActionType:   tt,
 					Timestamp:    timestamp,
@@ -407,10 +666,11 @@ func Movements(record *token.AuditRecord, created time.Time) (mv []MovementRecor
 
 	for _, eID := range eIDs {
 		for _, tokenType := range tokenTypes {
-			received := outputs.ByEnrollmentID(eID).ByType(tokenType).Sum()
-			sent := inputs.ByEnrollmentID(eID).ByType(tokenType).Sum()
-			diff := received.Sub(received, sent)
-			if sent == received {
+			diff, err := movement(inputs.ByEnrollmentID(eID).ByType(tokenType), outputs.ByEnrollmentID(eID).ByType(tokenType))
+			if err != nil {
+				return nil, errors.WithMessagef(err, "failed computing movement of [%s] for tx [%s]", eID, record.Anchor)
+			}
+			if diff.Sign() == 0 {
 				continue
 			}
 
@@ -430,6 +690,35 @@ func Movements(record *token.AuditRecord, created time.Time) (mv []MovementRecor
 	return
 }
 
+// movement returns the amount received minus the amount sent, computed with checked arithmetic
+func movement(sent *token.InputStream, received *token.OutputStream) (*big.Int, error) {
+	in, err := sent.SumQuantity()
+	if err != nil {
+		return nil, err
+	}
+	out, err := received.SumQuantity()
+	if err != nil {
+		return nil, err
+	}
+	cmp, err := out.CheckedCmp(in)
+	if err != nil {
+		return nil, err
+	}
+	if cmp >= 0 {
+		diff, err := out.CheckedSub(in)
+		if err != nil {
+			return nil, err
+		}
+		return diff.ToBigInt(), nil
+	}
+	diff, err := in.CheckedSub(out)
+	if err != nil {
+		return nil, err
+	}
+	neg := diff.ToBigInt()
+	return neg.Neg(neg), nil
+}
+
 // joinIOEIDs joins enrollment IDs of inputs and outputs
 func joinIOEIDs(record *token.AuditRecord) []string {
 	iEIDs := record.Inputs.EnrollmentIDs()
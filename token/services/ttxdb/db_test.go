@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package ttxdb_test
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"sync"
@@ -16,6 +17,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/core/config"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	driver2 "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/sdk/db"
 	config2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/config"
 	db3 "github.com/hyperledger-labs/fabric-token-sdk/token/services/db"
@@ -23,6 +25,7 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
 	ttxdb2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb/db/sql"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	_ "modernc.org/sqlite"
 )
@@ -42,6 +45,76 @@ func TestDB(t *testing.T) {
 	assert.NoError(t, err)
 
 	TEndorserAcks(t, db1, db2)
+	TCompensation(t, db1)
+	TValidationProfiles(t, db1)
+
+	replica, err := manager.DBByTMSId(token.TMSID{Network: "replica"})
+	assert.NoError(t, err)
+	TReadOnly(t, manager, replica)
+
+	cached, err := manager.DBByTMSId(token.TMSID{Network: "cached"})
+	assert.NoError(t, err)
+	TQueryCache(t, cached)
+
+	TStop(t, manager, db1)
+}
+
+func TReadOnly(t *testing.T, manager *ttxdb.Manager, replica *ttxdb.DB) {
+	err := replica.AddTransactionEndorsementAck("1", []byte("alice"), []byte("sigma"))
+	assert.ErrorIs(t, err, db3.ErrReadOnly)
+	err = replica.SetStatus(context.Background(), "1", driver.Confirmed, "")
+	assert.ErrorIs(t, err, db3.ErrReadOnly)
+
+	// queries keep working, and the databases share the tables
+	acks, err := replica.GetTransactionEndorsementAcks("1")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, acks)
+
+	// resuming does not lift read-only
+	assert.NoError(t, manager.Start(context.Background()))
+	err = replica.AddTransactionEndorsementAck("1", []byte("alice"), []byte("sigma"))
+	assert.ErrorIs(t, err, db3.ErrReadOnly)
+}
+
+func TQueryCache(t *testing.T, cached *ttxdb.DB) {
+	ctx := context.Background()
+	// unknown transactions are not cached
+	status, _, err := cached.GetStatus("cached_tx")
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Unknown, status)
+	request, err := cached.GetTokenRequest("cached_tx")
+	assert.NoError(t, err)
+	assert.Nil(t, request)
+
+	assert.NoError(t, cached.AppendValidationRecord("cached_tx", []byte("request"), nil, []byte("pp")))
+	status, _, err = cached.GetStatus("cached_tx")
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Pending, status)
+	request, err = cached.GetTokenRequest("cached_tx")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("request"), request)
+
+	// setting the status invalidates the cached one
+	assert.NoError(t, cached.SetStatus(ctx, "cached_tx", driver.Confirmed, "done"))
+	status, message, err := cached.GetStatus("cached_tx")
+	assert.NoError(t, err)
+	assert.Equal(t, driver.Confirmed, status)
+	assert.Equal(t, "done", message)
+}
+
+func TStop(t *testing.T, manager *ttxdb.Manager, db1 *ttxdb.DB) {
+	ctx := context.Background()
+	assert.NoError(t, manager.Drain(ctx))
+	err := db1.AddTransactionEndorsementAck("3", []byte("alice"), []byte("sigma"))
+	assert.ErrorIs(t, err, db3.ErrStopped)
+
+	assert.NoError(t, manager.Start(ctx))
+	assert.NoError(t, db1.AddTransactionEndorsementAck("3", []byte("alice"), []byte("sigma")))
+
+	assert.NoError(t, manager.Stop(ctx))
+	_, err = manager.DBByTMSId(token.TMSID{Network: "pineapple"})
+	assert.ErrorIs(t, err, db3.ErrStopped)
+	assert.ErrorIs(t, manager.Start(ctx), db3.ErrStopped)
 }
 
 func TEndorserAcks(t *testing.T, db1, db2 *ttxdb.DB) {
@@ -249,6 +322,14 @@ func TestMovementRecords(t *testing.T) {
 			Status:       driver.Pending,
 		},
 	}, recs)
+
+	// Precision mismatch
+	input = simpleTransfer()
+	input.Outputs = token.NewOutputStream(input.Outputs.Outputs(), 128)
+	_, err = ttxdb.Movements(&input, now)
+	assert.ErrorIs(t, err, token2.ErrPrecisionMismatch)
+	_, err = ttxdb.TransactionRecords(&input, now)
+	assert.ErrorIs(t, err, token2.ErrPrecisionMismatch)
 }
 
 func simpleTransfer() token.AuditRecord {
@@ -316,3 +397,105 @@ func redeem() token.AuditRecord {
 		Outputs: token.NewOutputStream([]*token.Output{output1}, 64),
 	}
 }
+
+type compensationRecorder struct {
+	events []*ttxdb.CompensationEvent
+	err    error
+}
+
+func (c *compensationRecorder) OnTransactionFailure(event *ttxdb.CompensationEvent) error {
+	c.events = append(c.events, event)
+	return c.err
+}
+
+func TCompensation(t *testing.T, db *ttxdb.DB) {
+	recorder := &compensationRecorder{}
+	db.AddCompensationHandler(recorder)
+
+	inputs := []*token2.ID{{TxId: "tx0", Index: 0}, {TxId: "tx0", Index: 1}}
+	request := token.NewRequest(nil, "compensate")
+	request.Metadata.Transfers = []driver2.TransferMetadata{{TokenIDs: inputs}}
+	raw, err := request.Bytes()
+	assert.NoError(t, err)
+	assert.NoError(t, db.AppendValidationRecord("compensate", raw, nil, []byte("pp_hash")))
+
+	// confirmations do not trigger compensation
+	assert.NoError(t, db.SetStatus(context.Background(), "compensate", ttxdb.Confirmed, ""))
+	assert.Empty(t, recorder.events)
+
+	assert.NoError(t, db.SetStatus(context.Background(), "compensate", ttxdb.Deleted, "invalid"))
+	assert.Len(t, recorder.events, 1)
+	event := recorder.events[0]
+	assert.Equal(t, "compensate", event.TxID)
+	assert.Equal(t, ttxdb.Deleted, event.Status)
+	assert.Equal(t, "invalid", event.Message)
+	assert.Equal(t, raw, event.TokenRequest)
+	assert.Equal(t, inputs, event.Inputs)
+	failures, err := db.CompensationFailures()
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+
+	// a failing handler does not fail the committed status, nor the other handlers
+	failing := &compensationRecorder{err: errors.New("ledger unreachable")}
+	db.AddCompensationHandler(failing)
+	last := &compensationRecorder{}
+	db.AddCompensationHandler(last)
+	assert.NoError(t, db.AppendValidationRecord("compensate_failing", raw, nil, []byte("pp_hash")))
+	assert.NoError(t, db.SetStatus(context.Background(), "compensate_failing", ttxdb.Deleted, "invalid"))
+	status, _, err := db.GetStatus("compensate_failing")
+	assert.NoError(t, err)
+	assert.Equal(t, ttxdb.Deleted, status)
+	assert.Len(t, last.events, 1)
+	failures, err = db.CompensationFailures()
+	assert.NoError(t, err)
+	assert.Len(t, failures, 1)
+	assert.ErrorContains(t, failures["compensate_failing"], "ledger unreachable")
+
+	// the retry succeeds once the handler does
+	assert.Error(t, db.RetryCompensation(context.Background(), "compensate_failing"))
+	retried, err := db.RetryCompensations(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, retried, 1)
+	assert.ErrorContains(t, retried["compensate_failing"], "ledger unreachable")
+	failing.err = nil
+	retried, err = db.RetryCompensations(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, retried)
+	assert.Len(t, failing.events, 4)
+	failures, err = db.CompensationFailures()
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+	assert.NoError(t, db.RetryCompensation(context.Background(), "compensate_failing"))
+
+	// only deleted transactions are compensated
+	assert.ErrorContains(t, db.RetryCompensation(context.Background(), "not_appended"), "is not deleted")
+
+	// a rejected transaction has nothing to compensate
+	compensated := len(recorder.events)
+	assert.NoError(t, db.AppendValidationRecord("rejected", raw, nil, []byte("pp_hash")))
+	assert.NoError(t, db.Reject(context.Background(), "rejected", "rejected by acceptance policy"))
+	status, message, err := db.GetStatus("rejected")
+	assert.NoError(t, err)
+	assert.Equal(t, ttxdb.Deleted, status)
+	assert.Equal(t, "rejected by acceptance policy", message)
+	assert.Len(t, recorder.events, compensated)
+}
+
+func TValidationProfiles(t *testing.T, db *ttxdb.DB) {
+	profile := &driver2.ValidationProfile{
+		SignatureChecks:   3 * time.Millisecond,
+		ProofVerification: 5 * time.Millisecond,
+		LedgerLookups:     time.Millisecond,
+		Total:             9 * time.Millisecond,
+	}
+	meta := driver2.ValidationAttributes{}
+	profile.SetTo(meta)
+	assert.NoError(t, db.AppendValidationRecord("profiled", []byte("request"), meta, []byte("pp_hash")))
+	assert.NoError(t, db.AppendValidationRecord("not_profiled", []byte("request"), map[string][]byte{"key": []byte("value")}, []byte("pp_hash")))
+
+	profiles, err := db.ValidationProfiles(ttxdb.QueryValidationRecordsParams{})
+	assert.NoError(t, err)
+	assert.Len(t, profiles, 1)
+	assert.Equal(t, "profiled", profiles[0].TxID)
+	assert.Equal(t, profile, profiles[0].Profile)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttxdb
+
+import (
+	"time"
+
+	driver2 "github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/pkg/errors"
+)
+
+// ValidationProfile reports where the time spent validating a token request went
+type ValidationProfile = driver2.ValidationProfile
+
+// ValidationProfileRecord is the validation profile of a given transaction
+type ValidationProfileRecord struct {
+	// TxID is the transaction ID
+	TxID string
+	// Timestamp is the time the validation record was stored
+	Timestamp time.Time
+	// Status is the status of the transaction
+	Status TxStatus
+	// Profile reports the validation timings
+	Profile *ValidationProfile
+}
+
+// ValidationProfiles returns the validation profiles of the validation records matching the given params.
+// Validation records that carry no timing information are skipped.
+func (d *DB) ValidationProfiles(params QueryValidationRecordsParams) ([]*ValidationProfileRecord, error) {
+	it, err := d.ValidationRecords(params)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying validation records")
+	}
+	defer it.Close()
+
+	var res []*ValidationProfileRecord
+	for {
+		record, err := it.Next()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed reading validation records")
+		}
+		if record == nil {
+			return res, nil
+		}
+		profile, ok, err := driver2.ValidationProfileFromAttributes(record.Metadata)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed parsing validation profile of [%s]", record.TxID)
+		}
+		if !ok {
+			continue
+		}
+		res = append(res, &ValidationProfileRecord{
+			TxID:      record.TxID,
+			Timestamp: record.Timestamp,
+			Status:    record.Status,
+			Profile:   profile,
+		})
+	}
+}
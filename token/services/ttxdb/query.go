@@ -0,0 +1,161 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttxdb
+
+import (
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/db/driver"
+	"github.com/pkg/errors"
+)
+
+// QueryBuilder assembles QueryTransactionsParams.
+// Errors are collected while building and returned by Build, therefore,
+// the methods can be chained freely.
+type QueryBuilder struct {
+	params QueryTransactionsParams
+	errs   []error
+}
+
+// Query returns a new QueryBuilder selecting all transactions.
+func Query() *QueryBuilder {
+	return &QueryBuilder{}
+}
+
+// WithIDs restricts the query to the passed transaction ids.
+func (b *QueryBuilder) WithIDs(ids ...string) *QueryBuilder {
+	for _, id := range ids {
+		if len(id) == 0 {
+			b.errs = append(b.errs, errors.New("empty transaction id"))
+			continue
+		}
+		b.params.IDs = append(b.params.IDs, id)
+	}
+	return b
+}
+
+// WithIDPrefix restricts the query to the transactions whose id starts with the passed prefix.
+func (b *QueryBuilder) WithIDPrefix(prefix string) *QueryBuilder {
+	if len(prefix) == 0 {
+		b.errs = append(b.errs, errors.New("empty transaction id prefix"))
+		return b
+	}
+	b.params.IDPrefix = prefix
+	return b
+}
+
+// From restricts the query to the transactions stored at or after the passed time.
+func (b *QueryBuilder) From(t time.Time) *QueryBuilder {
+	if t.IsZero() {
+		b.errs = append(b.errs, errors.New("zero start time"))
+		return b
+	}
+	b.params.From = &t
+	return b
+}
+
+// To restricts the query to the transactions stored at or before the passed time.
+func (b *QueryBuilder) To(t time.Time) *QueryBuilder {
+	if t.IsZero() {
+		b.errs = append(b.errs, errors.New("zero end time"))
+		return b
+	}
+	b.params.To = &t
+	return b
+}
+
+// WithStatus restricts the query to the transactions having one of the passed statuses.
+func (b *QueryBuilder) WithStatus(statuses ...TxStatus) *QueryBuilder {
+	for _, status := range statuses {
+		if _, ok := TxStatusMessage[status]; !ok {
+			b.errs = append(b.errs, errors.Errorf("unknown transaction status [%d]", status))
+			continue
+		}
+		b.params.Statuses = append(b.params.Statuses, status)
+	}
+	return b
+}
+
+// WithActionTypes restricts the query to the transactions performing one of the passed action types.
+func (b *QueryBuilder) WithActionTypes(actionTypes ...ActionType) *QueryBuilder {
+	for _, actionType := range actionTypes {
+		switch actionType {
+		case driver.Issue, driver.Transfer, driver.Redeem:
+			b.params.ActionTypes = append(b.params.ActionTypes, actionType)
+		default:
+			b.errs = append(b.errs, errors.Errorf("unknown action type [%d]", actionType))
+		}
+	}
+	return b
+}
+
+// WithEnrollmentIDs restricts the query to the transactions whose sender or recipient has one of the passed enrollment ids.
+func (b *QueryBuilder) WithEnrollmentIDs(eIDs ...string) *QueryBuilder {
+	for _, eID := range eIDs {
+		if len(eID) == 0 {
+			b.errs = append(b.errs, errors.New("empty enrollment id"))
+			continue
+		}
+		b.params.EnrollmentIDs = append(b.params.EnrollmentIDs, eID)
+	}
+	return b
+}
+
+// WithTokenTypes restricts the query to the transactions moving one of the passed token types.
+func (b *QueryBuilder) WithTokenTypes(tokenTypes ...string) *QueryBuilder {
+	for _, tokenType := range tokenTypes {
+		if len(tokenType) == 0 {
+			b.errs = append(b.errs, errors.New("empty token type"))
+			continue
+		}
+		b.params.TokenTypes = append(b.params.TokenTypes, tokenType)
+	}
+	return b
+}
+
+// WithWallets restricts the query to the transactions whose sender is the passed sender wallet
+// or whose recipient is the passed recipient wallet.
+// Both wallets are required, the query ignores a sender or a recipient wallet alone.
+func (b *QueryBuilder) WithWallets(sender, recipient string) *QueryBuilder {
+	if len(sender) == 0 || len(recipient) == 0 {
+		b.errs = append(b.errs, errors.Errorf("both sender and recipient wallets are required, got [%s] and [%s]", sender, recipient))
+		return b
+	}
+	b.params.SenderWallet = sender
+	b.params.RecipientWallet = recipient
+	return b
+}
+
+// ExcludeToSelf filters out the transactions whose sender and recipient have the same enrollment id.
+func (b *QueryBuilder) ExcludeToSelf() *QueryBuilder {
+	b.params.ExcludeToSelf = true
+	return b
+}
+
+// Build returns the assembled QueryTransactionsParams.
+// It returns an error if any of the passed values is invalid or if the passed values cannot be combined.
+func (b *QueryBuilder) Build() (QueryTransactionsParams, error) {
+	if len(b.errs) != 0 {
+		return QueryTransactionsParams{}, errors.Wrapf(b.errs[0], "invalid query")
+	}
+	if b.params.From != nil && b.params.To != nil && b.params.From.After(*b.params.To) {
+		return QueryTransactionsParams{}, errors.Errorf("invalid query: start time [%s] is after end time [%s]", b.params.From, b.params.To)
+	}
+	if len(b.params.IDs) != 0 && len(b.params.IDPrefix) != 0 {
+		return QueryTransactionsParams{}, errors.New("invalid query: transaction ids and transaction id prefix cannot be combined")
+	}
+	return b.params, nil
+}
+
+// Transactions builds the query and runs it against the passed DB.
+func (b *QueryBuilder) Transactions(db *DB) (driver.TransactionIterator, error) {
+	params, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return db.Transactions(params)
+}
@@ -0,0 +1,60 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ttxdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	t1 := time.Now().Add(-time.Hour)
+	t2 := time.Now()
+
+	params, err := ttxdb.Query().
+		From(t1).
+		To(t2).
+		WithStatus(ttxdb.Confirmed).
+		WithActionTypes(ttxdb.Transfer, ttxdb.Redeem).
+		WithEnrollmentIDs("alice").
+		WithTokenTypes("USD").
+		WithWallets("alice", "bob").
+		ExcludeToSelf().
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, t1, *params.From)
+	assert.Equal(t, t2, *params.To)
+	assert.Equal(t, []ttxdb.TxStatus{ttxdb.Confirmed}, params.Statuses)
+	assert.Equal(t, []ttxdb.ActionType{ttxdb.Transfer, ttxdb.Redeem}, params.ActionTypes)
+	assert.Equal(t, []string{"alice"}, params.EnrollmentIDs)
+	assert.Equal(t, []string{"USD"}, params.TokenTypes)
+	assert.Equal(t, "alice", params.SenderWallet)
+	assert.Equal(t, "bob", params.RecipientWallet)
+	assert.True(t, params.ExcludeToSelf)
+
+	params, err = ttxdb.Query().Build()
+	assert.NoError(t, err)
+	assert.Equal(t, ttxdb.QueryTransactionsParams{}, params)
+
+	_, err = ttxdb.Query().From(t2).To(t1).Build()
+	assert.ErrorContains(t, err, "is after end time")
+	_, err = ttxdb.Query().WithIDs("tx1").WithIDPrefix("tx").Build()
+	assert.ErrorContains(t, err, "cannot be combined")
+	_, err = ttxdb.Query().WithStatus(ttxdb.TxStatus(42)).Build()
+	assert.ErrorContains(t, err, "unknown transaction status [42]")
+	_, err = ttxdb.Query().WithActionTypes(ttxdb.ActionType(42)).Build()
+	assert.ErrorContains(t, err, "unknown action type [42]")
+	_, err = ttxdb.Query().WithWallets("alice", "").Build()
+	assert.ErrorContains(t, err, "both sender and recipient wallets are required")
+	_, err = ttxdb.Query().WithEnrollmentIDs("").Build()
+	assert.ErrorContains(t, err, "empty enrollment id")
+	_, err = ttxdb.Query().From(time.Time{}).Build()
+	assert.ErrorContains(t, err, "zero start time")
+}
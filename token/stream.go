@@ -99,6 +99,19 @@ func (o *OutputStream) Sum() *big.Int {
 	return sum
 }
 
+// SumQuantity returns the sum of the quantity of all outputs in the OutputStream, at the precision of the stream.
+// It returns an error if an output has a different precision, or if the sum does not fit the precision.
+func (o *OutputStream) SumQuantity() (token.Quantity, error) {
+	sum := token.NewZeroQuantity(o.Precision)
+	for i, output := range o.outputs {
+		var err error
+		if sum, err = sum.CheckedAdd(output.Quantity); err != nil {
+			return nil, errors.WithMessagef(err, "failed summing output [%d]", i)
+		}
+	}
+	return sum, nil
+}
+
 // At returns the output at the passed index.
 func (o *OutputStream) At(i int) *Output {
 	return o.outputs[i]
@@ -328,6 +341,19 @@ func (is *InputStream) Sum() *big.Int {
 	return sum
 }
 
+// SumQuantity returns the sum of the quantities of the inputs, at the precision of the stream.
+// It returns an error if an input has a different precision, or if the sum does not fit the precision.
+func (is *InputStream) SumQuantity() (token.Quantity, error) {
+	sum := token.NewZeroQuantity(is.precision)
+	for i, input := range is.inputs {
+		var err error
+		if sum, err = sum.CheckedAdd(input.Quantity); err != nil {
+			return nil, errors.WithMessagef(err, "failed summing input [%d]", i)
+		}
+	}
+	return sum, nil
+}
+
 // Inputs returns the inputs in this InputStream.
 func (is *InputStream) Inputs() []*Input {
 	return is.inputs
@@ -180,8 +180,16 @@ func (t *ManagementService) Namespace() string {
 	return t.namespace
 }
 
-// NewRequest returns a new Token Request whose anchor is the passed id
+// NewRequest returns a new Token Request whose anchor is the passed id.
+// It returns ErrReadOnly if the TMS is read-only.
 func (t *ManagementService) NewRequest(id string) (*Request, error) {
+	readOnly, err := t.ReadOnly()
+	if err != nil {
+		return nil, err
+	}
+	if readOnly {
+		return nil, errors.Wrapf(ErrReadOnly, "cannot assemble token request [%s] for [%s]", id, t.ID())
+	}
 	return NewRequest(t, id), nil
 }
 
@@ -216,7 +224,7 @@ func (t *ManagementService) Validator() (*Validator, error) {
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to get validator")
 	}
-	return &Validator{backend: v}, nil
+	return &Validator{backend: v, vault: t.vault}, nil
 }
 
 // Vault returns the Token Vault for this TMS
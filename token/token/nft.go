@@ -0,0 +1,59 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+)
+
+// NFTAttributes returns the attributes of the non-fungible token whose type is the passed one.
+// The type of a non-fungible token is the base64 encoding of a JSON object, its state.
+// The attributes are the top-level fields of the state with a string, number, or boolean value, in their textual form.
+// The second return value is false if the type does not encode a JSON object.
+func NFTAttributes(tokenType string) (map[string]string, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(tokenType)
+	if err != nil || !bytes.HasPrefix(bytes.TrimSpace(decoded), []byte("{")) {
+		return nil, false
+	}
+	d := json.NewDecoder(bytes.NewReader(decoded))
+	d.UseNumber()
+	fields := map[string]interface{}{}
+	if err := d.Decode(&fields); err != nil {
+		return nil, false
+	}
+	attributes := make(map[string]string, len(fields))
+	for key, value := range fields {
+		switch v := value.(type) {
+		case string:
+			attributes[key] = v
+		case json.Number:
+			attributes[key] = v.String()
+		case bool:
+			attributes[key] = strconv.FormatBool(v)
+		}
+	}
+	return attributes, true
+}
+
+// IsNFT returns true if the passed token type is the type of non-fungible token, as understood by NFTAttributes
+func IsNFT(tokenType string) bool {
+	_, ok := NFTAttributes(tokenType)
+	return ok
+}
+
+// NFTIssueKey returns the key of the issue action metadata that marks the issuance of the non-fungible token
+// whose type is the passed one. The metadata keys of an issue action are written to the ledger and must not exist yet,
+// then a non-fungible token can be issued only once.
+func NFTIssueKey(tokenType string) string {
+	h := sha256.Sum256([]byte(tokenType))
+	return "nft." + hex.EncodeToString(h[:])
+}
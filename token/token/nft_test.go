@@ -0,0 +1,39 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNFTAttributes(t *testing.T) {
+	typ := base64.StdEncoding.EncodeToString([]byte(`{"LinearID":"house-1","Address":"5th Avenue","Valuation":100000000000000000001,"Sold":false,"Owners":["alice"],"Extra":{"k":"v"}}`))
+	attributes, ok := token.NFTAttributes(typ)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"LinearID":  "house-1",
+		"Address":   "5th Avenue",
+		"Valuation": "100000000000000000001",
+		"Sold":      "false",
+	}, attributes)
+	assert.True(t, token.IsNFT(typ))
+
+	for _, typ := range []string{
+		"USD",
+		"ABCD",
+		base64.StdEncoding.EncodeToString([]byte(`["not","an","object"]`)),
+		base64.StdEncoding.EncodeToString([]byte(`{"broken":`)),
+	} {
+		_, ok := token.NFTAttributes(typ)
+		assert.False(t, ok, typ)
+		assert.False(t, token.IsNFT(typ), typ)
+	}
+}
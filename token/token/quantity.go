@@ -14,6 +14,15 @@ import (
 	"github.com/pkg/errors"
 )
 
+var (
+	// ErrPrecisionMismatch is returned by the checked operations on quantities of different precision
+	ErrPrecisionMismatch = errors.New("precision mismatch")
+	// ErrOverflow is returned by the checked operations whose result does not fit the precision of the operands
+	ErrOverflow = errors.New("overflow")
+	// ErrUnderflow is returned by the checked subtraction whose result is negative
+	ErrUnderflow = errors.New("underflow")
+)
+
 // Quantity models an immutable token quantity and its basic operations.
 type Quantity interface {
 
@@ -33,6 +42,18 @@ type Quantity interface {
 	//
 	Cmp(b Quantity) int
 
+	// CheckedAdd returns a new quantity equal to this + b, leaving this unchanged.
+	// It returns ErrPrecisionMismatch if b has a different precision, and ErrOverflow if the sum does not fit the precision.
+	CheckedAdd(b Quantity) (Quantity, error)
+
+	// CheckedSub returns a new quantity equal to this - b, leaving this unchanged.
+	// It returns ErrPrecisionMismatch if b has a different precision, and ErrUnderflow if b is larger than this.
+	CheckedSub(b Quantity) (Quantity, error)
+
+	// CheckedCmp compares this and b as Cmp does.
+	// It returns ErrPrecisionMismatch if b has a different precision.
+	CheckedCmp(b Quantity) (int, error)
+
 	// Hex returns the hexadecimal representation of this quantity
 	Hex() string
 
@@ -184,6 +205,48 @@ func (q *BigQuantity) Cmp(b Quantity) int {
 	return q.Int.Cmp(bq.Int)
 }
 
+func (q *BigQuantity) CheckedAdd(b Quantity) (Quantity, error) {
+	bq, err := q.checkPrecision(b)
+	if err != nil {
+		return nil, err
+	}
+	sum := big.NewInt(0).Add(q.Int, bq.Int)
+	if sum.BitLen() > int(q.Precision) {
+		return nil, errors.Wrapf(ErrOverflow, "%s + %s exceeds precision %d", q.Text(10), bq.Text(10), q.Precision)
+	}
+	return &BigQuantity{Int: sum, Precision: q.Precision}, nil
+}
+
+func (q *BigQuantity) CheckedSub(b Quantity) (Quantity, error) {
+	bq, err := q.checkPrecision(b)
+	if err != nil {
+		return nil, err
+	}
+	if q.Int.Cmp(bq.Int) < 0 {
+		return nil, errors.Wrapf(ErrUnderflow, "%s < %s", q.Text(10), bq.Text(10))
+	}
+	return &BigQuantity{Int: big.NewInt(0).Sub(q.Int, bq.Int), Precision: q.Precision}, nil
+}
+
+func (q *BigQuantity) CheckedCmp(b Quantity) (int, error) {
+	bq, err := q.checkPrecision(b)
+	if err != nil {
+		return 0, err
+	}
+	return q.Int.Cmp(bq.Int), nil
+}
+
+func (q *BigQuantity) checkPrecision(b Quantity) (*BigQuantity, error) {
+	bq, ok := b.(*BigQuantity)
+	if !ok {
+		return nil, errors.Wrapf(ErrPrecisionMismatch, "expected a quantity of precision %d, got %T", q.Precision, b)
+	}
+	if bq.Precision != q.Precision {
+		return nil, errors.Wrapf(ErrPrecisionMismatch, "expected a quantity of precision %d, got %d", q.Precision, bq.Precision)
+	}
+	return bq, nil
+}
+
 func (q *BigQuantity) Hex() string {
 	return "0x" + q.Int.Text(16)
 }
@@ -255,6 +318,44 @@ func (q *UInt64Quantity) Cmp(b Quantity) int {
 	return 0
 }
 
+func (q *UInt64Quantity) CheckedAdd(b Quantity) (Quantity, error) {
+	bq, err := q.checkPrecision(b)
+	if err != nil {
+		return nil, err
+	}
+	sum := q.Value + bq.Value
+	if sum < q.Value {
+		return nil, errors.Wrapf(ErrOverflow, "%d + %d exceeds precision 64", q.Value, bq.Value)
+	}
+	return &UInt64Quantity{Value: sum}, nil
+}
+
+func (q *UInt64Quantity) CheckedSub(b Quantity) (Quantity, error) {
+	bq, err := q.checkPrecision(b)
+	if err != nil {
+		return nil, err
+	}
+	if bq.Value > q.Value {
+		return nil, errors.Wrapf(ErrUnderflow, "%d < %d", q.Value, bq.Value)
+	}
+	return &UInt64Quantity{Value: q.Value - bq.Value}, nil
+}
+
+func (q *UInt64Quantity) CheckedCmp(b Quantity) (int, error) {
+	if _, err := q.checkPrecision(b); err != nil {
+		return 0, err
+	}
+	return q.Cmp(b), nil
+}
+
+func (q *UInt64Quantity) checkPrecision(b Quantity) (*UInt64Quantity, error) {
+	bq, ok := b.(*UInt64Quantity)
+	if !ok {
+		return nil, errors.Wrapf(ErrPrecisionMismatch, "expected a quantity of precision 64, got %T", b)
+	}
+	return bq, nil
+}
+
 func (q *UInt64Quantity) Hex() string {
 	return "0x" + strconv.FormatUint(q.Value, 16)
 }
@@ -253,6 +253,104 @@ func TestUInt64Quantity_Sub_Panic(t *testing.T) {
 	})
 }
 
+func TestCheckedOperations(t *testing.T) {
+	for _, precision := range []uint64{64, 128} {
+		a, err := token.UInt64ToQuantity(10, precision)
+		assert.NoError(t, err)
+		b, err := token.UInt64ToQuantity(3, precision)
+		assert.NoError(t, err)
+
+		sum, err := a.CheckedAdd(b)
+		assert.NoError(t, err)
+		assert.Equal(t, "13", sum.Decimal())
+		diff, err := a.CheckedSub(b)
+		assert.NoError(t, err)
+		assert.Equal(t, "7", diff.Decimal())
+		cmp, err := a.CheckedCmp(b)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, cmp)
+		// the operands are left unchanged
+		assert.Equal(t, "10", a.Decimal())
+		assert.Equal(t, "3", b.Decimal())
+
+		_, err = b.CheckedSub(a)
+		assert.ErrorIs(t, err, token.ErrUnderflow)
+	}
+
+	// overflow
+	_, err := token.NewQuantityFromUInt64(math.MaxUint64).CheckedAdd(token.NewOneQuantity(64))
+	assert.ErrorIs(t, err, token.ErrOverflow)
+	q, err := token.NewUBigQuantity("3", 2)
+	assert.NoError(t, err)
+	_, err = q.CheckedAdd(token.NewOneQuantity(2))
+	assert.ErrorIs(t, err, token.ErrOverflow)
+
+	// precision mismatch
+	_, err = token.NewOneQuantity(64).CheckedAdd(token.NewOneQuantity(128))
+	assert.ErrorIs(t, err, token.ErrPrecisionMismatch)
+	_, err = token.NewOneQuantity(128).CheckedSub(token.NewOneQuantity(64))
+	assert.ErrorIs(t, err, token.ErrPrecisionMismatch)
+	_, err = token.NewOneQuantity(128).CheckedCmp(token.NewOneQuantity(256))
+	assert.ErrorIs(t, err, token.ErrPrecisionMismatch)
+}
+
+func FuzzToQuantityDecimal(f *testing.F) {
+	for _, seed := range []string{"0", "1", "100", "18446744073709551615", "18446744073709551616", "-1", "", "1e3", "0x10"} {
+		f.Add(seed, uint64(64))
+		f.Add(seed, uint64(128))
+	}
+	f.Fuzz(func(t *testing.T, s string, precision uint64) {
+		fuzzQuantity(t, s, precision%300)
+	})
+}
+
+func FuzzToQuantityHex(f *testing.F) {
+	for _, seed := range []string{"0x0", "0x1", "0xabc", "0xffffffffffffffff", "0x10000000000000000", "0x", "0xg", "-0x1"} {
+		f.Add(seed, uint64(64))
+		f.Add(seed, uint64(128))
+	}
+	f.Fuzz(func(t *testing.T, s string, precision uint64) {
+		fuzzQuantity(t, s, precision%300)
+	})
+}
+
+// fuzzQuantity checks that a parsed quantity survives the round trip through its representations,
+// and that the checked operations do not panic
+func fuzzQuantity(t *testing.T, s string, precision uint64) {
+	q, err := token.ToQuantity(s, precision)
+	if err != nil {
+		return
+	}
+	assert.LessOrEqual(t, q.ToBigInt().BitLen(), int(precision))
+	assert.GreaterOrEqual(t, q.ToBigInt().Sign(), 0)
+
+	fromHex, err := token.ToQuantity(q.Hex(), precision)
+	assert.NoError(t, err)
+	fromDecimal, err := token.ToQuantity(q.Decimal(), precision)
+	assert.NoError(t, err)
+	cmp, err := q.CheckedCmp(fromHex)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cmp)
+	cmp, err = q.CheckedCmp(fromDecimal)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, cmp)
+
+	sum, err := q.CheckedAdd(q)
+	if err != nil {
+		assert.ErrorIs(t, err, token.ErrOverflow)
+	} else {
+		diff, err := sum.CheckedSub(q)
+		assert.NoError(t, err)
+		assert.Equal(t, q.Decimal(), diff.Decimal())
+	}
+	_, err = token.NewZeroQuantity(precision).CheckedSub(q)
+	if q.ToBigInt().Sign() > 0 {
+		assert.ErrorIs(t, err, token.ErrUnderflow)
+	} else {
+		assert.NoError(t, err)
+	}
+}
+
 func TestNewUBigQuantity_ValidInput(t *testing.T) {
 	q, err := token.NewUBigQuantity("123456789", 64)
 
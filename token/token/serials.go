@@ -0,0 +1,175 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// serialSeparator separates the class from the serial range in the type of a serial token
+const serialSeparator = "#"
+
+// SerialRange is the range of serial numbers [From, To] of the units of a token class.
+// A token carrying a serial range has type `<class>#<from>-<to>` and quantity equal to the number of serials in the range.
+// The units of a class share the class, not the type: a transfer of a subset of the range splits the token into tokens
+// of different types, as far as the serials are conserved.
+type SerialRange struct {
+	// Class is the type shared by the units
+	Class string
+	// From is the first serial of the range
+	From uint64
+	// To is the last serial of the range, included
+	To uint64
+}
+
+// NewSerialRange returns the range [from, to] of the passed class
+func NewSerialRange(class string, from, to uint64) (SerialRange, error) {
+	r := SerialRange{Class: class, From: from, To: to}
+	if err := r.Validate(); err != nil {
+		return SerialRange{}, err
+	}
+	return r, nil
+}
+
+// ParseSerialType returns the serial range carried by a token of the passed type.
+// The second return value is false if the passed type is not the type of a serial token.
+func ParseSerialType(tokenType string) (SerialRange, bool) {
+	i := strings.LastIndex(tokenType, serialSeparator)
+	if i <= 0 {
+		return SerialRange{}, false
+	}
+	from, to, ok := strings.Cut(tokenType[i+len(serialSeparator):], "-")
+	if !ok {
+		return SerialRange{}, false
+	}
+	f, err := strconv.ParseUint(from, 10, 64)
+	if err != nil {
+		return SerialRange{}, false
+	}
+	t, err := strconv.ParseUint(to, 10, 64)
+	if err != nil {
+		return SerialRange{}, false
+	}
+	r := SerialRange{Class: tokenType[:i], From: f, To: t}
+	// only the canonical form is a serial type, so that a range has a single type
+	if r.Validate() != nil || r.Type() != tokenType {
+		return SerialRange{}, false
+	}
+	return r, true
+}
+
+// IsSerial returns true if the passed token type is the type of a serial token, as understood by ParseSerialType
+func IsSerial(tokenType string) bool {
+	_, ok := ParseSerialType(tokenType)
+	return ok
+}
+
+// Validate returns an error if the range is empty, it has no class, or its serials exceed math.MaxInt64.
+// The bound lets the databases store the serials as signed 64-bit integers.
+func (r SerialRange) Validate() error {
+	if len(r.Class) == 0 {
+		return errors.New("serial range without class")
+	}
+	if r.From > r.To {
+		return errors.Errorf("invalid serial range [%d-%d]", r.From, r.To)
+	}
+	if r.To > math.MaxInt64 {
+		return errors.Errorf("serial [%d] too large", r.To)
+	}
+	return nil
+}
+
+// Type returns the type of the token carrying this range
+func (r SerialRange) Type() string {
+	return fmt.Sprintf("%s%s%d-%d", r.Class, serialSeparator, r.From, r.To)
+}
+
+// Count returns the number of serials in the range
+func (r SerialRange) Count() uint64 {
+	return r.To - r.From + 1
+}
+
+// Overlaps returns true if the passed range has serials in common with this range
+func (r SerialRange) Overlaps(o SerialRange) bool {
+	return r.Class == o.Class && r.From <= o.To && o.From <= r.To
+}
+
+// Contains returns true if all the serials of the passed range are in this range
+func (r SerialRange) Contains(o SerialRange) bool {
+	return r.Class == o.Class && r.From <= o.From && o.To <= r.To
+}
+
+func (r SerialRange) String() string {
+	return r.Type()
+}
+
+// MergeSerialRanges returns the passed ranges sorted and with the adjacent ranges of the same class merged.
+// It returns an error if two ranges overlap.
+func MergeSerialRanges(ranges []SerialRange) ([]SerialRange, error) {
+	sorted := make([]SerialRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Class != sorted[j].Class {
+			return sorted[i].Class < sorted[j].Class
+		}
+		return sorted[i].From < sorted[j].From
+	})
+	var merged []SerialRange
+	for _, r := range sorted {
+		if len(merged) == 0 {
+			merged = append(merged, r)
+			continue
+		}
+		last := &merged[len(merged)-1]
+		switch {
+		case last.Overlaps(r):
+			return nil, errors.Errorf("serial ranges [%s] and [%s] overlap", last, r)
+		case last.Class == r.Class && last.To+1 == r.From:
+			last.To = r.To
+		default:
+			merged = append(merged, r)
+		}
+	}
+	return merged, nil
+}
+
+// SubtractSerialRange returns the ranges with the serials of the passed ranges that are not in the passed subset.
+// It returns an error if the passed ranges overlap, or they do not contain all the serials of the subset.
+func SubtractSerialRange(ranges []SerialRange, subset SerialRange) ([]SerialRange, error) {
+	merged, err := MergeSerialRanges(ranges)
+	if err != nil {
+		return nil, err
+	}
+	var rest []SerialRange
+	found := false
+	for _, r := range merged {
+		if !r.Contains(subset) {
+			if r.Overlaps(subset) {
+				return nil, errors.Errorf("serial range [%s] is not contiguous in the passed ranges", subset)
+			}
+			rest = append(rest, r)
+			continue
+		}
+		found = true
+		if r.From < subset.From {
+			rest = append(rest, SerialRange{Class: r.Class, From: r.From, To: subset.From - 1})
+		}
+		if subset.To < r.To {
+			rest = append(rest, SerialRange{Class: r.Class, From: subset.To + 1, To: r.To})
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("serial range [%s] is not in the passed ranges", subset)
+	}
+	return rest, nil
+}
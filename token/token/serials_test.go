@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerialRange(t *testing.T) {
+	r, err := token.NewSerialRange("TICKET#2024", 10, 19)
+	assert.NoError(t, err)
+	assert.Equal(t, "TICKET#2024#10-19", r.Type())
+	assert.Equal(t, uint64(10), r.Count())
+
+	parsed, ok := token.ParseSerialType(r.Type())
+	assert.True(t, ok)
+	assert.Equal(t, r, parsed)
+	assert.True(t, token.IsSerial(r.Type()))
+
+	for _, typ := range []string{"USD", "#1-2", "T#2-1", "T#1", "T#a-2", "T#01-2", "T#1-02", "T#-1-2"} {
+		assert.False(t, token.IsSerial(typ), typ)
+	}
+	_, err = token.NewSerialRange("", 1, 2)
+	assert.Error(t, err)
+	_, err = token.NewSerialRange("T", 0, math.MaxUint64)
+	assert.Error(t, err)
+	_, err = token.NewSerialRange("T", 0, math.MaxInt64)
+	assert.NoError(t, err)
+
+	assert.True(t, r.Overlaps(token.SerialRange{Class: r.Class, From: 19, To: 25}))
+	assert.False(t, r.Overlaps(token.SerialRange{Class: r.Class, From: 20, To: 25}))
+	assert.False(t, r.Overlaps(token.SerialRange{Class: "OTHER", From: 10, To: 19}))
+	assert.True(t, r.Contains(token.SerialRange{Class: r.Class, From: 12, To: 19}))
+	assert.False(t, r.Contains(token.SerialRange{Class: r.Class, From: 12, To: 20}))
+}
+
+func TestMergeAndSubtractSerialRanges(t *testing.T) {
+	ranges := []token.SerialRange{
+		{Class: "T", From: 5, To: 9},
+		{Class: "T", From: 1, To: 4},
+		{Class: "T", From: 20, To: 29},
+		{Class: "S", From: 1, To: 1},
+	}
+	merged, err := token.MergeSerialRanges(ranges)
+	assert.NoError(t, err)
+	assert.Equal(t, []token.SerialRange{
+		{Class: "S", From: 1, To: 1},
+		{Class: "T", From: 1, To: 9},
+		{Class: "T", From: 20, To: 29},
+	}, merged)
+
+	_, err = token.MergeSerialRanges([]token.SerialRange{{Class: "T", From: 1, To: 5}, {Class: "T", From: 5, To: 6}})
+	assert.Error(t, err)
+
+	rest, err := token.SubtractSerialRange(ranges, token.SerialRange{Class: "T", From: 3, To: 7})
+	assert.NoError(t, err)
+	assert.Equal(t, []token.SerialRange{
+		{Class: "S", From: 1, To: 1},
+		{Class: "T", From: 1, To: 2},
+		{Class: "T", From: 8, To: 9},
+		{Class: "T", From: 20, To: 29},
+	}, rest)
+
+	rest, err = token.SubtractSerialRange(ranges, token.SerialRange{Class: "T", From: 20, To: 29})
+	assert.NoError(t, err)
+	assert.Len(t, rest, 2)
+
+	// not covered, or not contiguous
+	_, err = token.SubtractSerialRange(ranges, token.SerialRange{Class: "T", From: 30, To: 31})
+	assert.Error(t, err)
+	_, err = token.SubtractSerialRange(ranges, token.SerialRange{Class: "T", From: 8, To: 21})
+	assert.Error(t, err)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
 )
 
 // Ledger models a read-only ledger
@@ -19,6 +20,18 @@ type Ledger = driver.ValidatorLedger
 // Validator validates a token request
 type Validator struct {
 	backend driver.Validator
+	// vault is the local vault of the TMS, used by DryRun
+	vault *Vault
+}
+
+// DryRunReport is the outcome of a successful dry run of a token request
+type DryRunReport struct {
+	// Actions are the validated actions
+	Actions []interface{}
+	// MissingSigners are the identities whose signatures are still to be collected, in the order the validator checks them
+	MissingSigners []Identity
+	// UnknownInputs are the inputs not in the local vault, such as the tokens of other parties, whose existence could not be checked
+	UnknownInputs []*token.ID
 }
 
 func NewValidator(backend driver.Validator) *Validator {
@@ -55,6 +68,77 @@ func (c *Validator) UnmarshallAndVerifyWithMetadata(ctx context.Context, ledger
 	return res, meta, nil
 }
 
+// DryRun validates the passed request, without submitting it, against the current public parameters and the local vault.
+// Unlike the validation at commit time, missing signatures are not an error: they are listed in the report,
+// therefore, the request can be checked before collecting them.
+// The inputs found in the local vault must be unspent, the other inputs are listed in the report.
+func (c *Validator) DryRun(ctx context.Context, request *Request) (*DryRunReport, error) {
+	dr, ok := c.backend.(driver.DryRunValidator)
+	if !ok {
+		return nil, errors.Errorf("validator [%T] does not support dry runs", c.backend)
+	}
+	raw, err := request.RequestToBytes()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to marshal request [%s]", request.Anchor)
+	}
+	actions, missing, err := dr.DryRunTokenRequestFromRaw(ctx, c.getLocalState, request.Anchor, raw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "dry run of request [%s] failed", request.Anchor)
+	}
+	report := &DryRunReport{Actions: actions, MissingSigners: missing}
+	if c.vault == nil {
+		return report, nil
+	}
+
+	var inputs []*token.ID
+	for _, action := range actions {
+		if ta, ok := action.(driver.TransferAction); ok {
+			inputs = append(inputs, ta.GetInputs()...)
+		}
+	}
+	qe := c.vault.v.QueryEngine()
+	exist, err := qe.ExistAll(inputs)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to look up the inputs of request [%s]", request.Anchor)
+	}
+	var known []*token.ID
+	for i, id := range inputs {
+		if exist[i] {
+			known = append(known, id)
+		} else {
+			report.UnknownInputs = append(report.UnknownInputs, id)
+		}
+	}
+	spentBy, spent, err := qe.WhoDeletedTokens(known...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to check the inputs of request [%s]", request.Anchor)
+	}
+	for i, id := range known {
+		if spent[i] {
+			return nil, errors.Errorf("dry run of request [%s] failed: input [%s] already spent by [%s]", request.Anchor, id, spentBy[i])
+		}
+	}
+	return report, nil
+}
+
+// getLocalState returns the output stored in the local vault for the passed token, or nil if the token is not there
+func (c *Validator) getLocalState(id token.ID) ([]byte, error) {
+	if c.vault == nil {
+		return nil, nil
+	}
+	qe := c.vault.v.QueryEngine()
+	exist, err := qe.ExistAll([]*token.ID{&id})
+	if err != nil || !exist[0] {
+		return nil, err
+	}
+	var output []byte
+	err = qe.GetTokenOutputs([]*token.ID{&id}, func(_ *token.ID, raw []byte) error {
+		output = raw
+		return nil
+	})
+	return output, err
+}
+
 type stateGetter struct {
 	f driver.GetStateFnc
 }
@@ -10,7 +10,9 @@ import (
 	"context"
 	"testing"
 
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/mock"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
@@ -116,3 +118,55 @@ func TestValidator_UnmarshallAndVerifyWithMetadata_Error(t *testing.T) {
 	assert.Nil(t, actions)
 	assert.Nil(t, metadata)
 }
+
+type dryRunValidator struct {
+	mock.Validator
+	actions []interface{}
+	missing []driver.Identity
+	err     error
+}
+
+func (v *dryRunValidator) DryRunTokenRequestFromRaw(context.Context, driver.GetStateFnc, string, []byte) ([]interface{}, []driver.Identity, error) {
+	return v.actions, v.missing, v.err
+}
+
+type queryEngineVault struct {
+	qe *mock.QueryEngine
+}
+
+func (v *queryEngineVault) QueryEngine() driver.QueryEngine { return v.qe }
+
+func (v *queryEngineVault) CertificationStorage() driver.CertificationStorage { return nil }
+
+func TestValidator_DryRun(t *testing.T) {
+	known, foreign := &token.ID{TxId: "tx1"}, &token.ID{TxId: "tx2"}
+	transfer := &mock.TransferAction{}
+	transfer.GetInputsReturns([]*token.ID{known, foreign})
+	qe := &mock.QueryEngine{}
+	qe.ExistAllReturns([]bool{true, false}, nil)
+	qe.WhoDeletedTokensReturns([]string{""}, []bool{false}, nil)
+	backend := &dryRunValidator{actions: []interface{}{transfer}, missing: []driver.Identity{driver.Identity("alice")}}
+	validator := &Validator{backend: backend, vault: &Vault{v: &queryEngineVault{qe: qe}}}
+	request := &Request{Anchor: "tx3", Actions: &driver.TokenRequest{}}
+
+	report, err := validator.DryRun(context.TODO(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{transfer}, report.Actions)
+	assert.Equal(t, []Identity{Identity("alice")}, report.MissingSigners)
+	assert.Equal(t, []*token.ID{foreign}, report.UnknownInputs)
+	assert.Equal(t, []*token.ID{known}, qe.WhoDeletedTokensArgsForCall(0))
+
+	// an input spent in the local vault fails the dry run
+	qe.WhoDeletedTokensReturns([]string{"tx0"}, []bool{true}, nil)
+	_, err = validator.DryRun(context.TODO(), request)
+	assert.ErrorContains(t, err, "already spent by [tx0]")
+
+	// so does a policy violation
+	backend.err = errors.New("too many inputs")
+	_, err = validator.DryRun(context.TODO(), request)
+	assert.ErrorContains(t, err, "too many inputs")
+
+	// validators without dry runs are reported
+	_, err = (&Validator{backend: &mock.Validator{}}).DryRun(context.TODO(), request)
+	assert.ErrorContains(t, err, "does not support dry runs")
+}
@@ -45,6 +45,49 @@ func WithContext(ctx context.Context) ListTokensOption {
 
 type IdentityConfiguration = driver.IdentityConfiguration
 
+// ErrWatchOnlyWallet is returned when a watch-only wallet is asked to sign.
+// A watch-only wallet is registered with public material only, it tracks tokens but does not hold the secret keys to spend them.
+var ErrWatchOnlyWallet = errors.New("wallet is watch-only")
+
+// AnonymityLevel selects how an owner wallet derives a recipient identity
+type AnonymityLevel = driver.AnonymityLevel
+
+const (
+	// DefaultAnonymity lets the wallet apply its default behaviour
+	DefaultAnonymity = driver.DefaultAnonymity
+	// LongTermIdentity reuses the same identity across transfers
+	LongTermIdentity = driver.LongTermIdentity
+	// FreshPseudonym derives a fresh pseudonym
+	FreshPseudonym = driver.FreshPseudonym
+)
+
+// RecipientIdentityOptions options for deriving a recipient identity
+type RecipientIdentityOptions = driver.RecipientIdentityOptions
+
+// RecipientIdentityOption is a function that configures a RecipientIdentityOptions
+type RecipientIdentityOption func(*RecipientIdentityOptions) error
+
+// WithAnonymityLevel returns a recipient identity option that selects the passed anonymity level
+func WithAnonymityLevel(level AnonymityLevel) RecipientIdentityOption {
+	return func(o *RecipientIdentityOptions) error {
+		o.AnonymityLevel = level
+		return nil
+	}
+}
+
+func compileRecipientIdentityOptions(opts ...RecipientIdentityOption) (*RecipientIdentityOptions, error) {
+	options := &RecipientIdentityOptions{}
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return nil, err
+		}
+	}
+	return options, nil
+}
+
+// RecipientIdentity models a recipient identity learned from a counterparty
+type RecipientIdentity = driver.RecipientIdentity
+
 // WalletManager defines the interface for managing wallets.
 type WalletManager struct {
 	walletService     driver.WalletService
@@ -82,6 +125,18 @@ func (wm *WalletManager) RegisterRecipientIdentity(data *RecipientData) error {
 	return wm.walletService.RegisterRecipientIdentity(data)
 }
 
+// BindRecipientIdentity binds the passed recipient identity, already registered via RegisterRecipientIdentity,
+// to the counterparty wallet it has been obtained from
+func (wm *WalletManager) BindRecipientIdentity(id Identity, walletID string) error {
+	return wm.managementService.tms.IdentityProvider().BindRecipientIdentity(id, walletID)
+}
+
+// RecipientIdentities returns the recipient identities learned from counterparties with the passed enrollment ID.
+// The identities are persisted, therefore they survive restarts.
+func (wm *WalletManager) RecipientIdentities(enrollmentID string) ([]RecipientIdentity, error) {
+	return wm.managementService.tms.IdentityProvider().RecipientIdentities(enrollmentID)
+}
+
 // Wallet returns the wallet bound to the passed identity, if any is available.
 // If no wallet is found, it returns nil.
 func (wm *WalletManager) Wallet(identity Identity) *Wallet {
@@ -241,9 +296,13 @@ type OwnerWallet struct {
 }
 
 // GetRecipientIdentity returns the owner identity. This can be a long term identity or a pseudonym depending
-// on the underlying token driver.
-func (o *OwnerWallet) GetRecipientIdentity() (Identity, error) {
-	return o.w.GetRecipientIdentity()
+// on the underlying token driver and the requested anonymity level.
+func (o *OwnerWallet) GetRecipientIdentity(opts ...RecipientIdentityOption) (Identity, error) {
+	options, err := compileRecipientIdentityOptions(opts...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to compile options")
+	}
+	return o.w.GetRecipientIdentity(options)
 }
 
 // GetAuditInfo returns auditing information for the passed identity
@@ -262,7 +321,11 @@ func (o *OwnerWallet) GetTokenMetadataAuditInfo(token []byte) ([]byte, error) {
 }
 
 // GetSigner returns the signer bound to the passed owner identity.
+// It returns ErrWatchOnlyWallet if this wallet is watch-only.
 func (o *OwnerWallet) GetSigner(identity Identity) (driver.Signer, error) {
+	if o.w.Remote() {
+		return nil, errors.Wrapf(ErrWatchOnlyWallet, "cannot get signer for [%s] from wallet [%s]", identity, o.ID())
+	}
 	return o.w.GetSigner(identity)
 }
 
@@ -318,7 +381,8 @@ func (o *OwnerWallet) RegisterRecipient(data *RecipientData) error {
 	return o.w.RegisterRecipient(data)
 }
 
-// Remote returns true if this wallet is verify only, meaning that the corresponding secret key is external to this wallet
+// Remote returns true if this wallet is verify only, meaning that the corresponding secret key is external to this wallet.
+// A remote wallet is watch-only: its tokens, balance and history are tracked, but spending requires an external signer.
 func (o *OwnerWallet) Remote() bool {
 	return o.w.Remote()
 }
@@ -331,8 +395,49 @@ type IssuerWallet struct {
 
 // GetIssuerIdentity returns the issuer identity. This can be a long term identity or a pseudonym depending
 // on the underlying token driver.
+// If the wallet holds several issuance keys, the wallet's policy selects one among those
+// registered as issuers in the current public parameters.
 func (i *IssuerWallet) GetIssuerIdentity(tokenType string) (Identity, error) {
-	return i.w.GetIssuerIdentity(tokenType)
+	mw, ok := i.w.(driver.MultiKeyIssuerWallet)
+	if !ok {
+		return i.w.GetIssuerIdentity(tokenType)
+	}
+	var registered []Identity
+	if pp := i.managementService.PublicParametersManager().PublicParameters(); pp != nil {
+		if ipp, ok := pp.PublicParameters.(driver.IssuersPublicParameters); ok {
+			registered = ipp.IssuerIDs()
+		}
+	}
+	return mw.SelectIssuerIdentity(tokenType, registered)
+}
+
+// AddIssuerKey adds the passed identity as an issuance key of this wallet.
+// If token types are passed, the key is dedicated to them.
+func (i *IssuerWallet) AddIssuerKey(identity Identity, tokenTypes ...string) error {
+	mw, ok := i.w.(driver.MultiKeyIssuerWallet)
+	if !ok {
+		return errors.Errorf("wallet [%s] does not support multiple issuer keys", i.ID())
+	}
+	return mw.AddIssuerKey(&driver.IssuerKey{Identity: identity, TokenTypes: tokenTypes})
+}
+
+// IssuerKeys returns the issuance keys of this wallet, the most recently added first
+func (i *IssuerWallet) IssuerKeys() []*driver.IssuerKey {
+	mw, ok := i.w.(driver.MultiKeyIssuerWallet)
+	if !ok {
+		return nil
+	}
+	return mw.IssuerKeys()
+}
+
+// SetIssuerKeyPolicy sets the policy selecting the issuance key of a token type
+func (i *IssuerWallet) SetIssuerKeyPolicy(policy driver.IssuerKeyPolicy) error {
+	mw, ok := i.w.(driver.MultiKeyIssuerWallet)
+	if !ok {
+		return errors.Errorf("wallet [%s] does not support multiple issuer keys", i.ID())
+	}
+	mw.SetIssuerKeyPolicy(policy)
+	return nil
 }
 
 // GetSigner returns the signer bound to the passed issuer identity.
@@ -0,0 +1,241 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	walletBackupVersion byte = 1
+	walletBackupSaltLen      = 16
+	walletBackupKeyLen       = 32
+	// scrypt parameters, as recommended for interactive logins
+	walletBackupScryptN = 1 << 15
+	walletBackupScryptR = 8
+	walletBackupScryptP = 1
+)
+
+// ErrInvalidBackupPassphrase is returned when a wallet backup bundle cannot be decrypted with the passed passphrase
+var ErrInvalidBackupPassphrase = errors.New("invalid passphrase or corrupted wallet backup")
+
+// WalletBackup is the content of a wallet backup bundle
+type WalletBackup struct {
+	// WalletID is the identifier of the backed up wallet
+	WalletID string
+	// Configuration is the identity configuration the wallet was registered with
+	Configuration IdentityConfiguration
+	// Files contains the credential files found at the configuration URL, indexed by their path relative to it.
+	// It is empty when the configuration carries the credentials in Raw.
+	Files map[string][]byte
+	// Tokens are the IDs of the unspent tokens owned by the wallet at the time of the backup
+	Tokens []*token.ID
+}
+
+// Backup exports the owner wallet bound to the passed identifier in a bundle encrypted with the passed passphrase.
+// The bundle contains the identity configuration and credentials of the wallet together with the IDs of the tokens it owns.
+// Keys that the wallet keeps in an external key store (e.g. an HSM) are not exported.
+func (wm *WalletManager) Backup(walletID string, passphrase string) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	w, err := wm.walletService.OwnerWallet(walletID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get owner wallet [%s]", walletID)
+	}
+	conf, err := wm.walletService.OwnerIdentityConfiguration(w.ID())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get identity configuration for wallet [%s]", w.ID())
+	}
+	backup := &WalletBackup{
+		WalletID:      w.ID(),
+		Configuration: conf,
+	}
+	if len(conf.Raw) == 0 && len(conf.URL) != 0 {
+		backup.Files, err = readBackupFiles(conf.URL)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to read credentials of wallet [%s]", w.ID())
+		}
+	}
+	unspent, err := w.ListTokens(&ListTokensOptions{})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to list unspent tokens of wallet [%s]", w.ID())
+	}
+	for _, tok := range unspent.Tokens {
+		backup.Tokens = append(backup.Tokens, tok.Id)
+	}
+
+	raw, err := json.Marshal(backup)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal backup of wallet [%s]", w.ID())
+	}
+	return sealWalletBackup(raw, passphrase)
+}
+
+// Restore decrypts the passed bundle, produced by Backup, and registers the wallet it contains as an owner wallet.
+// The credential files, if any, are written under dir, in a subdirectory named after the wallet.
+// It fails if a wallet with the same identifier already exists.
+// The returned backup lists the IDs of the tokens owned by the wallet at the time of the backup,
+// the application can use them to reconcile the token database of this node.
+func (wm *WalletManager) Restore(bundle []byte, passphrase string, dir string) (*WalletBackup, error) {
+	raw, err := openWalletBackup(bundle, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	backup := &WalletBackup{}
+	if err := json.Unmarshal(raw, backup); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal wallet backup")
+	}
+	if len(backup.WalletID) == 0 {
+		return nil, errors.New("wallet backup does not contain a wallet identifier")
+	}
+	if _, err := wm.walletService.OwnerWallet(backup.WalletID); err == nil {
+		return nil, errors.Errorf("wallet [%s] already exists", backup.WalletID)
+	}
+
+	conf := backup.Configuration
+	if len(backup.Files) != 0 {
+		if len(dir) == 0 {
+			return nil, errors.Errorf("wallet [%s] has credential files, a destination directory is required", backup.WalletID)
+		}
+		conf.URL = filepath.Join(dir, backup.WalletID)
+		if err := writeBackupFiles(conf.URL, backup.Files); err != nil {
+			return nil, errors.WithMessagef(err, "failed to write credentials of wallet [%s]", backup.WalletID)
+		}
+	}
+	if err := wm.walletService.RegisterOwnerIdentity(conf); err != nil {
+		return nil, errors.WithMessagef(err, "failed to register wallet [%s]", backup.WalletID)
+	}
+	backup.Configuration = conf
+	return backup, nil
+}
+
+func readBackupFiles(root string) (map[string][]byte, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat [%s]", root)
+	}
+	if !info.IsDir() {
+		raw, err := os.ReadFile(root)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read [%s]", root)
+		}
+		return map[string][]byte{filepath.Base(root): raw}, nil
+	}
+	files := map[string][]byte{}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = raw
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to walk [%s]", root)
+	}
+	return files, nil
+}
+
+func writeBackupFiles(root string, files map[string][]byte) error {
+	for rel, raw := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return errors.Errorf("invalid file path [%s] in wallet backup", rel)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return errors.Wrapf(err, "failed to create directory for [%s]", path)
+		}
+		if err := os.WriteFile(path, raw, 0o600); err != nil {
+			return errors.Wrapf(err, "failed to write [%s]", path)
+		}
+	}
+	return nil
+}
+
+// sealWalletBackup encrypts the passed plaintext with a key derived from the passphrase.
+// The output is version | salt | nonce | ciphertext.
+func sealWalletBackup(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, walletBackupSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "failed to generate salt")
+	}
+	aead, err := walletBackupAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+	out := make([]byte, 0, 1+len(salt)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, walletBackupVersion)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	header := out[:1]
+	return aead.Seal(out, nonce, plaintext, header), nil
+}
+
+// openWalletBackup reverts sealWalletBackup
+func openWalletBackup(bundle []byte, passphrase string) ([]byte, error) {
+	if len(bundle) < 1+walletBackupSaltLen {
+		return nil, errors.New("wallet backup too short")
+	}
+	if bundle[0] != walletBackupVersion {
+		return nil, errors.Errorf("unsupported wallet backup version [%d]", bundle[0])
+	}
+	salt := bundle[1 : 1+walletBackupSaltLen]
+	aead, err := walletBackupAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	rest := bundle[1+walletBackupSaltLen:]
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("wallet backup too short")
+	}
+	plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], bundle[:1])
+	if err != nil {
+		return nil, ErrInvalidBackupPassphrase
+	}
+	return plaintext, nil
+}
+
+func walletBackupAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, walletBackupScryptN, walletBackupScryptR, walletBackupScryptP, walletBackupKeyLen)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to derive key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create gcm")
+	}
+	return aead, nil
+}
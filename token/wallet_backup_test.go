@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver/mock"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealWalletBackup(t *testing.T) {
+	bundle, err := sealWalletBackup([]byte("hello"), "secret")
+	assert.NoError(t, err)
+	plaintext, err := openWalletBackup(bundle, "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), plaintext)
+
+	_, err = openWalletBackup(bundle, "wrong")
+	assert.ErrorIs(t, err, ErrInvalidBackupPassphrase)
+
+	bundle[len(bundle)-1] ^= 1
+	_, err = openWalletBackup(bundle, "secret")
+	assert.ErrorIs(t, err, ErrInvalidBackupPassphrase)
+
+	_, err = openWalletBackup([]byte{2}, "secret")
+	assert.Error(t, err)
+}
+
+func TestRestore(t *testing.T) {
+	src := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "keystore"), 0o700))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "keystore", "key"), []byte("sk"), 0o600))
+	files, err := readBackupFiles(src)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"keystore/key": []byte("sk")}, files)
+
+	raw, err := json.Marshal(&WalletBackup{
+		WalletID:      "alice",
+		Configuration: IdentityConfiguration{ID: "alice", URL: src},
+		Files:         files,
+		Tokens:        []*token.ID{{TxId: "tx1", Index: 0}},
+	})
+	assert.NoError(t, err)
+	bundle, err := sealWalletBackup(raw, "secret")
+	assert.NoError(t, err)
+
+	// the wallet exists already
+	ws := &mock.WalletService{}
+	wm := &WalletManager{walletService: ws}
+	_, err = wm.Restore(bundle, "secret", t.TempDir())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+
+	// wrong passphrase
+	ws.OwnerWalletReturns(nil, errors.New("not found"))
+	_, err = wm.Restore(bundle, "wrong", t.TempDir())
+	assert.ErrorIs(t, err, ErrInvalidBackupPassphrase)
+
+	dst := t.TempDir()
+	backup, err := wm.Restore(bundle, "secret", dst)
+	assert.NoError(t, err)
+	assert.Equal(t, []*token.ID{{TxId: "tx1", Index: 0}}, backup.Tokens)
+	assert.Equal(t, 1, ws.RegisterOwnerIdentityCallCount())
+	conf := ws.RegisterOwnerIdentityArgsForCall(0)
+	assert.Equal(t, "alice", conf.ID)
+	assert.Equal(t, filepath.Join(dst, "alice"), conf.URL)
+	sk, err := os.ReadFile(filepath.Join(dst, "alice", "keystore", "key"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("sk"), sk)
+
+	// files must stay in the destination directory
+	assert.Error(t, writeBackupFiles(dst, map[string][]byte{"../evil": []byte("x")}))
+}
@@ -0,0 +1,34 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package token
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+type remoteOwnerWallet struct {
+	driver.OwnerWallet
+}
+
+func (w *remoteOwnerWallet) ID() string {
+	return "alice"
+}
+
+func (w *remoteOwnerWallet) Remote() bool {
+	return true
+}
+
+func TestWatchOnlyWalletGetSigner(t *testing.T) {
+	dw := &remoteOwnerWallet{}
+	w := &OwnerWallet{Wallet: &Wallet{w: dw}, w: dw}
+	assert.True(t, w.Remote())
+	_, err := w.GetSigner(Identity("alice"))
+	assert.ErrorIs(t, err, ErrWatchOnlyWallet)
+}